@@ -7,7 +7,7 @@ import (
 	"time"
 
 	"github.com/cespare/xxhash/v2"
-	"github.com/sercand/kuberesolver/v3"
+	"github.com/sercand/kuberesolver/v5"
 	"github.com/spf13/cobra"
 	"google.golang.org/grpc/balancer"
 	_ "google.golang.org/grpc/xds"
@@ -15,8 +15,10 @@ import (
 	log "github.com/authzed/spicedb/internal/logging"
 	consistentbalancer "github.com/authzed/spicedb/pkg/balancer"
 	"github.com/authzed/spicedb/pkg/cmd"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
 	cmdutil "github.com/authzed/spicedb/pkg/cmd/server"
 	"github.com/authzed/spicedb/pkg/cmd/testserver"
+	"github.com/authzed/spicedb/pkg/datagen"
 )
 
 const (
@@ -63,12 +65,73 @@ func main() {
 	cmd.RegisterHeadFlags(headCmd)
 	rootCmd.AddCommand(headCmd)
 
+	// Add schema tooling commands
+	codegenCmd := cmd.NewCodegenCommand(rootCmd.Use)
+	cmd.RegisterCodegenFlags(codegenCmd)
+	rootCmd.AddCommand(codegenCmd)
+
+	validateCmd := cmd.NewValidateCommand(rootCmd.Use)
+	rootCmd.AddCommand(validateCmd)
+
+	var renameDatastoreConfig datastore.Config
+	renameRelationCmd := cmd.NewRenameRelationCommand(rootCmd.Use, &renameDatastoreConfig)
+	cmd.RegisterRenameRelationFlags(renameRelationCmd, &renameDatastoreConfig)
+	rootCmd.AddCommand(renameRelationCmd)
+
+	var deleteTenantDatastoreConfig datastore.Config
+	deleteTenantCmd := cmd.NewDeleteTenantCommand(rootCmd.Use, &deleteTenantDatastoreConfig)
+	cmd.RegisterDeleteTenantFlags(deleteTenantCmd, &deleteTenantDatastoreConfig)
+	rootCmd.AddCommand(deleteTenantCmd)
+
+	var translateDatastoreConfig datastore.Config
+	translateZedTokenCmd := cmd.NewTranslateZedTokenCommand(rootCmd.Use, &translateDatastoreConfig)
+	cmd.RegisterTranslateZedTokenFlags(translateZedTokenCmd, &translateDatastoreConfig)
+	rootCmd.AddCommand(translateZedTokenCmd)
+
+	var backupDatastoreConfig datastore.Config
+	backupCmd := cmd.NewBackupCommand(rootCmd.Use, &backupDatastoreConfig)
+	cmd.RegisterBackupFlags(backupCmd, &backupDatastoreConfig)
+	rootCmd.AddCommand(backupCmd)
+
+	var restoreDatastoreConfig datastore.Config
+	restoreCmd := cmd.NewRestoreCommand(rootCmd.Use, &restoreDatastoreConfig)
+	cmd.RegisterRestoreFlags(restoreCmd, &restoreDatastoreConfig)
+	rootCmd.AddCommand(restoreCmd)
+
+	datastoreCmd := cmd.NewDatastoreCommand(rootCmd.Use)
+	var gcDatastoreConfig datastore.Config
+	gcCmd := cmd.NewGCCommand(rootCmd.Use, &gcDatastoreConfig)
+	cmd.RegisterGCFlags(gcCmd, &gcDatastoreConfig)
+	datastoreCmd.AddCommand(gcCmd)
+	var generateDatastoreConfig datastore.Config
+	var generateConfig datagen.Config
+	generateCmd := cmd.NewGenerateCommand(rootCmd.Use, &generateDatastoreConfig, &generateConfig)
+	cmd.RegisterGenerateFlags(generateCmd, &generateDatastoreConfig, &generateConfig)
+	datastoreCmd.AddCommand(generateCmd)
+	rootCmd.AddCommand(datastoreCmd)
+
 	// Add server commands
 	var serverConfig cmdutil.Config
 	serveCmd := cmd.NewServeCommand(rootCmd.Use, &serverConfig)
 	cmd.RegisterServeFlags(serveCmd, &serverConfig)
 	rootCmd.AddCommand(serveCmd)
 
+	configCmd := cmd.NewConfigCommand(rootCmd.Use)
+	var printEffectiveConfig cmdutil.Config
+	printEffectiveCmd := cmd.NewPrintEffectiveConfigCommand(rootCmd.Use, &printEffectiveConfig)
+	cmd.RegisterPrintEffectiveConfigFlags(printEffectiveCmd, &printEffectiveConfig)
+	configCmd.AddCommand(printEffectiveCmd)
+	rootCmd.AddCommand(configCmd)
+
+	var doctorConfig cmdutil.Config
+	doctorCmd := cmd.NewDoctorCommand(rootCmd.Use, &doctorConfig)
+	cmd.RegisterDoctorFlags(doctorCmd, &doctorConfig)
+	rootCmd.AddCommand(doctorCmd)
+
+	benchCmd := cmd.NewBenchCommand(rootCmd.Use)
+	cmd.RegisterBenchFlags(benchCmd)
+	rootCmd.AddCommand(benchCmd)
+
 	devtoolsCmd := cmd.NewDevtoolsCommand(rootCmd.Use)
 	cmd.RegisterDevtoolsFlags(devtoolsCmd)
 	rootCmd.AddCommand(devtoolsCmd)
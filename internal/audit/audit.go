@@ -0,0 +1,294 @@
+// Package audit records structured audit events for API calls and delivers them to sinks.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/redaction"
+)
+
+var (
+	droppedEventsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "audit",
+		Name:      "dropped_events_total",
+		Help:      "The number of audit events dropped because the buffer of events was full.",
+	})
+
+	sinkErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "audit",
+		Name:      "sink_errors_total",
+		Help:      "The number of batches of audit events which could not be written, by sink.",
+	}, []string{"sink"})
+)
+
+const (
+	// eventBufferSize is the number of events buffered for the sinks, beyond which events are
+	// dropped rather than slowing down calls.
+	eventBufferSize = 10_000
+
+	// maxBatchSize is the maximum number of events written to the sinks at once.
+	maxBatchSize = 100
+)
+
+// Decision is the decision of a call evaluating a permission.
+type Decision string
+
+const (
+	// DecisionAllowed indicates that the subject has the permission.
+	DecisionAllowed Decision = "allowed"
+
+	// DecisionDenied indicates that the subject does not have the permission.
+	DecisionDenied Decision = "denied"
+
+	// DecisionConditional indicates that the subject has the permission depending on missing
+	// caveat context.
+	DecisionConditional Decision = "conditional"
+)
+
+// EventType is the type of an audit event.
+type EventType string
+
+// EventTypeRejectedConnection is the type of the events of calls rejected because they were made
+// from a network not allowed on the listener. The events of the API calls have no type.
+const EventTypeRejectedConnection EventType = "rejected-connection"
+
+// EventTypeAuthLockout is the type of the events of sources of calls locked out after too many
+// calls failing authentication, whose Peer or Principal is the locked out source.
+const EventTypeAuthLockout EventType = "auth-lockout"
+
+// Event is the audit event of a single API call.
+type Event struct {
+	// Time is the time at which the call completed.
+	Time time.Time `json:"time"`
+
+	// Type is the type of the event, if not an API call.
+	Type EventType `json:"type,omitempty"`
+
+	// Listener is the listener on which the call was rejected, for rejected connections.
+	Listener string `json:"listener,omitempty"`
+
+	// Peer is the address from which the call was made, which is the original address of the
+	// client when the server reads the PROXY protocol header of its load balancer.
+	Peer string `json:"peer,omitempty"`
+
+	// RequestID is the ID of the request of the call.
+	RequestID string `json:"requestId,omitempty"`
+
+	// Caller identifies the key with which the call was made, as a fingerprint of the key.
+	Caller string `json:"caller,omitempty"`
+
+	// Principal is the authenticated principal which made the call: `identity:` followed by the
+	// identity of its client certificate, `oidc:` followed by the subject of its OIDC token, or
+	// `key:` followed by the ID of its preshared key.
+	Principal string `json:"principal,omitempty"`
+
+	// Method is the full gRPC method of the call.
+	Method string `json:"method"`
+
+	// Mutating is whether the call could have changed relationships or the schema.
+	Mutating bool `json:"mutating"`
+
+	// Resources are the resources, relationship filters or relationship updates of the call.
+	Resources []string `json:"resources,omitempty"`
+
+	// Subject is the subject of the call, if any.
+	Subject string `json:"subject,omitempty"`
+
+	// Decision is the decision of the call, for calls evaluating a single permission.
+	Decision Decision `json:"decision,omitempty"`
+
+	// Revision is the ZedToken of the revision at which the call was evaluated or written.
+	Revision string `json:"revision,omitempty"`
+
+	// Code is the gRPC status code of the call.
+	Code string `json:"code"`
+
+	// Duration is the duration of the call.
+	Duration time.Duration `json:"durationNs"`
+}
+
+// Redaction configures which parts of events are replaced by fingerprints before being
+// recorded, so that events can still be correlated without exposing them.
+type Redaction struct {
+	// ResourceIDs redacts the IDs of resources.
+	ResourceIDs bool
+
+	// SubjectIDs redacts the IDs of subjects.
+	SubjectIDs bool
+}
+
+// ParseRedaction parses the names of the parts of events to redact, any of `resource-ids` and
+// `subject-ids`.
+func ParseRedaction(names []string) (Redaction, error) {
+	var redaction Redaction
+	for _, name := range names {
+		switch name {
+		case "resource-ids":
+			redaction.ResourceIDs = true
+		case "subject-ids":
+			redaction.SubjectIDs = true
+		default:
+			return Redaction{}, fmt.Errorf("unknown audit redaction %q, expected any of resource-ids and subject-ids", name)
+		}
+	}
+	return redaction, nil
+}
+
+// ResourceID returns the ID of a resource as recorded.
+func (r Redaction) ResourceID(id string) string {
+	if !r.ResourceIDs {
+		return id
+	}
+	return Fingerprint(id)
+}
+
+// SubjectID returns the ID of a subject as recorded.
+func (r Redaction) SubjectID(id string) string {
+	if !r.SubjectIDs || id == "*" {
+		return id
+	}
+	return Fingerprint(id)
+}
+
+// Fingerprint returns a short, stable fingerprint of a value which cannot be reversed into the
+// value, such as a key or a redacted ID.
+func Fingerprint(value string) string {
+	return redaction.Fingerprint(value)
+}
+
+// Sink is a destination of audit events.
+type Sink interface {
+	// Name is the name of the sink, used in logs and metrics.
+	Name() string
+
+	// Write writes the events to the sink.
+	Write(ctx context.Context, events []Event) error
+
+	// Close closes the sink.
+	Close() error
+}
+
+// Config is the configuration of a Logger.
+type Config struct {
+	// SampleRate is the fraction of calls which do not mutate anything and succeed that are
+	// recorded. Calls mutating relationships or the schema, and failed calls, are always recorded.
+	SampleRate float64
+
+	// Redaction configures the parts of events which are redacted.
+	Redaction Redaction
+}
+
+// Logger records audit events to sinks asynchronously, so that slow sinks do not slow down
+// calls. Events are dropped, and counted, if the sinks fall too far behind.
+type Logger struct {
+	config Config
+	sinks  []Sink
+	events chan Event
+}
+
+// NewLogger creates a new audit logger writing to the given sinks.
+func NewLogger(config Config, sinks ...Sink) *Logger {
+	return &Logger{
+		config: config,
+		sinks:  sinks,
+		events: make(chan Event, eventBufferSize),
+	}
+}
+
+// Redaction returns the redaction configured for the logger.
+func (l *Logger) Redaction() Redaction {
+	return l.config.Redaction
+}
+
+// Record records an event, subject to sampling.
+func (l *Logger) Record(event Event) {
+	if !event.Mutating && event.Code == "OK" && l.config.SampleRate < 1 && rand.Float64() >= l.config.SampleRate { // nolint:gosec
+		return
+	}
+
+	select {
+	case l.events <- event:
+	default:
+		droppedEventsCounter.Inc()
+	}
+}
+
+// QueueDepth returns the number of recorded events waiting to be written to the sinks.
+func (l *Logger) QueueDepth() int {
+	return len(l.events)
+}
+
+// Start writes recorded events to the sinks until the context is canceled, after which the
+// events already recorded are written and the sinks closed.
+func (l *Logger) Start(ctx context.Context) error {
+	log.Ctx(ctx).Info().
+		Int("sinks", len(l.sinks)).
+		Float64("sampleRate", l.config.SampleRate).
+		Msg("audit logger started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			l.drain()
+			for _, sink := range l.sinks {
+				if err := sink.Close(); err != nil {
+					log.Ctx(ctx).Warn().Err(err).Str("sink", sink.Name()).Msg("unable to close audit sink")
+				}
+			}
+
+			log.Ctx(ctx).Info().
+				Msg("shut down audit logger")
+			return ctx.Err()
+
+		case event := <-l.events:
+			l.write(ctx, l.batch(event))
+		}
+	}
+}
+
+// batch returns the given event followed by any other events already recorded.
+func (l *Logger) batch(first Event) []Event {
+	batch := []Event{first}
+	for len(batch) < maxBatchSize {
+		select {
+		case event := <-l.events:
+			batch = append(batch, event)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// drain writes the events already recorded, with a context which is not yet canceled.
+func (l *Logger) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for {
+		select {
+		case event := <-l.events:
+			l.write(ctx, l.batch(event))
+		default:
+			return
+		}
+	}
+}
+
+func (l *Logger) write(ctx context.Context, events []Event) {
+	for _, sink := range l.sinks {
+		if err := sink.Write(ctx, events); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("sink", sink.Name()).Int("events", len(events)).Msg("unable to write audit events")
+			sinkErrorsCounter.WithLabelValues(sink.Name()).Inc()
+		}
+	}
+}
@@ -0,0 +1,171 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recordingSink struct {
+	sync.Mutex
+	events []Event
+	closed bool
+}
+
+func (rs *recordingSink) Name() string { return "recording" }
+
+func (rs *recordingSink) Write(_ context.Context, events []Event) error {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.events = append(rs.events, events...)
+	return nil
+}
+
+func (rs *recordingSink) Close() error {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.closed = true
+	return nil
+}
+
+func TestLoggerSampling(t *testing.T) {
+	testCases := []struct {
+		name       string
+		sampleRate float64
+		event      Event
+		recorded   bool
+	}{
+		{"read recorded", 1, Event{Method: "check", Code: "OK"}, true},
+		{"read sampled out", 0, Event{Method: "check", Code: "OK"}, false},
+		{"write always recorded", 0, Event{Method: "write", Code: "OK", Mutating: true}, true},
+		{"failure always recorded", 0, Event{Method: "check", Code: "PermissionDenied"}, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			sink := &recordingSink{}
+			logger := NewLogger(Config{SampleRate: tc.sampleRate}, sink)
+			logger.Record(tc.event)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			cancel()
+			require.ErrorIs(logger.Start(ctx), context.Canceled)
+
+			if tc.recorded {
+				require.Equal([]Event{tc.event}, sink.events)
+			} else {
+				require.Empty(sink.events)
+			}
+			require.True(sink.closed)
+		})
+	}
+}
+
+func TestLoggerWritesWhileRunning(t *testing.T) {
+	require := require.New(t)
+
+	sink := &recordingSink{}
+	logger := NewLogger(Config{SampleRate: 1}, sink)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- logger.Start(ctx)
+	}()
+
+	for i := 0; i < 250; i++ {
+		logger.Record(Event{Method: "check", Code: "OK"})
+	}
+	require.Eventually(func() bool {
+		sink.Lock()
+		defer sink.Unlock()
+		return len(sink.events) == 250
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(<-done, context.Canceled)
+}
+
+func TestParseRedaction(t *testing.T) {
+	require := require.New(t)
+
+	redaction, err := ParseRedaction([]string{"subject-ids"})
+	require.NoError(err)
+	require.Equal(Redaction{SubjectIDs: true}, redaction)
+
+	require.Equal("masterplan", redaction.ResourceID("masterplan"))
+	require.Equal(Fingerprint("tom"), redaction.SubjectID("tom"))
+	require.NotContains(redaction.SubjectID("tom"), "tom")
+	require.Equal("*", redaction.SubjectID("*"))
+
+	_, err = ParseRedaction([]string{"caveats"})
+	require.Error(err)
+}
+
+func TestFileSink(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	sink, err := NewFileSink(path)
+	require.NoError(err)
+
+	events := []Event{
+		{Method: "/authzed.api.v1.PermissionsService/CheckPermission", Code: "OK", Decision: DecisionAllowed},
+		{Method: "/authzed.api.v1.PermissionsService/WriteRelationships", Code: "OK", Mutating: true},
+	}
+	require.NoError(sink.Write(context.Background(), events[:1]))
+	require.NoError(sink.Write(context.Background(), events[1:]))
+	require.NoError(sink.Close())
+
+	info, err := os.Stat(path)
+	require.NoError(err)
+	require.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	f, err := os.Open(path)
+	require.NoError(err)
+	defer f.Close()
+
+	var written []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var event Event
+		require.NoError(json.Unmarshal(scanner.Bytes(), &event))
+		written = append(written, event)
+	}
+	require.NoError(scanner.Err())
+	require.Equal(events, written)
+}
+
+func TestSyslogSink(t *testing.T) {
+	require := require.New(t)
+
+	_, err := NewSyslogSink("http://localhost:514", time.Second)
+	require.Error(err)
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	require.NoError(err)
+	defer conn.Close()
+
+	sink, err := NewSyslogSink("udp://"+conn.LocalAddr().String(), time.Second)
+	require.NoError(err)
+	defer sink.Close()
+
+	require.NoError(sink.Write(context.Background(), []Event{{Method: "check", Code: "OK", Time: time.Unix(0, 0)}}))
+
+	buf := make([]byte, 4096)
+	require.NoError(conn.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	n, err := conn.Read(buf)
+	require.NoError(err)
+	require.Regexp(`^<86>1 1970-01-01T00:00:00Z \S+ spicedb \d+ audit - \{.*"method":"check".*\}$`, string(buf[:n]))
+}
@@ -0,0 +1,233 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/authzed/spicedb/internal/changestream"
+)
+
+// FileSink writes events as JSON lines to a file.
+type FileSink struct {
+	sync.Mutex
+	out    io.Writer
+	closer io.Closer
+}
+
+// NewFileSink creates a sink appending events to the file at the given path, creating it if
+// necessary. The path `-` writes events to standard output.
+func NewFileSink(path string) (*FileSink, error) {
+	if path == "-" {
+		return &FileSink{out: os.Stdout}, nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log file: %w", err)
+	}
+	return &FileSink{out: f, closer: f}, nil
+}
+
+func (fs *FileSink) Name() string {
+	return "file"
+}
+
+func (fs *FileSink) Write(_ context.Context, events []Event) error {
+	fs.Lock()
+	defer fs.Unlock()
+
+	encoder := json.NewEncoder(fs.out)
+	for _, event := range events {
+		if err := encoder.Encode(event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fs *FileSink) Close() error {
+	if fs.closer == nil {
+		return nil
+	}
+	return fs.closer.Close()
+}
+
+const (
+	// syslogFacilityAuthPriv is the syslog facility for security and authorization messages.
+	syslogFacilityAuthPriv = 10
+
+	// syslogSeverityInfo is the syslog severity of informational messages.
+	syslogSeverityInfo = 6
+)
+
+// SyslogSink writes events as RFC 5424 syslog messages, each holding an event as JSON.
+type SyslogSink struct {
+	sync.Mutex
+	network  string
+	address  string
+	hostname string
+	timeout  time.Duration
+	dialer   net.Dialer
+	conn     net.Conn
+}
+
+// NewSyslogSink creates a sink writing to the syslog server at the given address, of the form
+// `udp://host:port`, `tcp://host:port` or `unix:///path/to/socket`. Connections are only made on
+// first write, and remade after any error.
+func NewSyslogSink(address string, timeout time.Duration) (*SyslogSink, error) {
+	parsed, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid syslog address: %w", err)
+	}
+
+	var network, addr string
+	switch parsed.Scheme {
+	case "udp", "tcp":
+		network, addr = parsed.Scheme, parsed.Host
+	case "unix", "unixgram":
+		network, addr = parsed.Scheme, parsed.Path
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %q, expected one of udp, tcp, unix or unixgram", parsed.Scheme)
+	}
+	if addr == "" {
+		return nil, fmt.Errorf("missing syslog address in %q", address)
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	return &SyslogSink{
+		network:  network,
+		address:  addr,
+		hostname: hostname,
+		timeout:  timeout,
+		dialer:   net.Dialer{Timeout: timeout},
+	}, nil
+}
+
+func (ss *SyslogSink) Name() string {
+	return "syslog"
+}
+
+func (ss *SyslogSink) Write(ctx context.Context, events []Event) error {
+	ss.Lock()
+	defer ss.Unlock()
+
+	if ss.conn == nil {
+		conn, err := ss.dialer.DialContext(ctx, ss.network, ss.address)
+		if err != nil {
+			return fmt.Errorf("unable to connect to syslog server: %w", err)
+		}
+		ss.conn = conn
+	}
+
+	for _, event := range events {
+		message, err := ss.format(event)
+		if err != nil {
+			return err
+		}
+
+		if ss.timeout > 0 {
+			if err := ss.conn.SetWriteDeadline(time.Now().Add(ss.timeout)); err != nil {
+				return ss.reset(err)
+			}
+		}
+		if _, err := ss.conn.Write(message); err != nil {
+			return ss.reset(err)
+		}
+	}
+	return nil
+}
+
+// format formats an event as a syslog message, octet-counted when sent over a stream.
+func (ss *SyslogSink) format(event Event) ([]byte, error) {
+	encoded, err := json.Marshal(event)
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("<%d>1 %s %s spicedb %d audit - %s",
+		syslogFacilityAuthPriv*8+syslogSeverityInfo,
+		event.Time.UTC().Format(time.RFC3339Nano),
+		ss.hostname,
+		os.Getpid(),
+		encoded,
+	)
+	if ss.network == "tcp" || ss.network == "unix" {
+		message = fmt.Sprintf("%d %s", len(message), message)
+	}
+	return []byte(message), nil
+}
+
+func (ss *SyslogSink) reset(err error) error {
+	ss.conn.Close()
+	ss.conn = nil
+	return fmt.Errorf("unable to write to syslog server: %w", err)
+}
+
+func (ss *SyslogSink) Close() error {
+	ss.Lock()
+	defer ss.Unlock()
+
+	if ss.conn == nil {
+		return nil
+	}
+	err := ss.conn.Close()
+	ss.conn = nil
+	return err
+}
+
+// KafkaSink publishes events as JSON messages to a Kafka topic, keyed by their caller.
+type KafkaSink struct {
+	publisher *changestream.KafkaPublisher
+}
+
+// NewKafkaSink creates a sink publishing to the given Kafka topic.
+func NewKafkaSink(brokers []string, topic string, timeout time.Duration) (*KafkaSink, error) {
+	publisher, err := changestream.NewKafkaPublisher(changestream.KafkaConfig{
+		Brokers: brokers,
+		Topic:   topic,
+		Timeout: timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &KafkaSink{publisher: publisher}, nil
+}
+
+func (ks *KafkaSink) Name() string {
+	return "kafka"
+}
+
+func (ks *KafkaSink) Write(ctx context.Context, events []Event) error {
+	keys := make([][]byte, 0, len(events))
+	values := make([][]byte, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, []byte(event.Caller))
+		values = append(values, value)
+	}
+	return ks.publisher.PublishMessages(ctx, keys, values)
+}
+
+func (ks *KafkaSink) Close() error {
+	return ks.publisher.Close()
+}
+
+var (
+	_ Sink = (*FileSink)(nil)
+	_ Sink = (*SyslogSink)(nil)
+	_ Sink = (*KafkaSink)(nil)
+)
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+const errInvalidBasicAuth = "invalid username or password"
+
+// LoadBasicAuthFile loads the credentials of a file in the htpasswd format, where each line is
+// a username and the bcrypt hash of its password, separated by a colon, such as the lines
+// written by `htpasswd -B`. Empty lines and lines starting with `#` are ignored.
+func LoadBasicAuthFile(path string) (map[string][]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open basic auth file: %w", err)
+	}
+	defer f.Close()
+
+	credentials := map[string][]byte{}
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		username, hash, found := strings.Cut(line, ":")
+		if !found || username == "" {
+			return nil, fmt.Errorf("invalid basic auth file: line %d must be a username and a password hash separated by a colon", lineNumber)
+		}
+		if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+			return nil, fmt.Errorf("invalid basic auth file: the password hash of %q on line %d must be a bcrypt hash: %w", username, lineNumber, err)
+		}
+		credentials[username] = []byte(hash)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("unable to read basic auth file: %w", err)
+	}
+	if len(credentials) == 0 {
+		return nil, fmt.Errorf("basic auth file %s holds no credentials", path)
+	}
+	return credentials, nil
+}
+
+// RequireHTTPBasicAuth wraps the given handler, requiring that HTTP requests authenticate with
+// the username and password of one of the given credentials, which map usernames to the bcrypt
+// hashes of their passwords.
+func RequireHTTPBasicAuth(credentials map[string][]byte, next http.Handler) http.Handler {
+	if len(credentials) == 0 {
+		panic("RequireHTTPBasicAuth was given no credentials")
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if !ok {
+			w.Header().Set("WWW-Authenticate", `Basic realm="spicedb"`)
+			http.Error(w, errInvalidBasicAuth, http.StatusUnauthorized)
+			return
+		}
+
+		hash, ok := credentials[username]
+		if !ok || bcrypt.CompareHashAndPassword(hash, []byte(password)) != nil {
+			w.Header().Set("WWW-Authenticate", `Basic realm="spicedb"`)
+			http.Error(w, errInvalidBasicAuth, http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestLoadBasicAuthFile(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	for _, tc := range []struct {
+		name     string
+		contents string
+		expected []string
+		err      string
+	}{
+		{"valid", "# prometheus\nprometheus:" + string(hash) + "\n\nadmin:" + string(hash) + "\n", []string{"admin", "prometheus"}, ""},
+		{"missing hash", "prometheus\n", nil, "line 1 must be a username and a password hash"},
+		{"plaintext password", "prometheus:secret\n", nil, "must be a bcrypt hash"},
+		{"empty", "# nobody\n", nil, "holds no credentials"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "htpasswd")
+			require.NoError(t, os.WriteFile(path, []byte(tc.contents), 0o600))
+
+			credentials, err := LoadBasicAuthFile(path)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+
+			usernames := make([]string, 0, len(credentials))
+			for username := range credentials {
+				usernames = append(usernames, username)
+			}
+			require.ElementsMatch(t, tc.expected, usernames)
+		})
+	}
+}
+
+func TestHTTPBasicAuth(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	handler := RequireHTTPBasicAuth(map[string][]byte{"prometheus": hash}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		name         string
+		username     string
+		password     string
+		expectedCode int
+	}{
+		{"valid", "prometheus", "secret", http.StatusOK},
+		{"wrong password", "prometheus", "wrong", http.StatusUnauthorized},
+		{"unknown user", "admin", "secret", http.StatusUnauthorized},
+		{"missing", "", "", http.StatusUnauthorized},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if tc.username != "" {
+				req.SetBasicAuth(tc.username, tc.password)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+			if tc.expectedCode == http.StatusUnauthorized {
+				require.Equal(t, `Basic realm="spicedb"`, recorder.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+)
+
+// presharedKeyCallerHashLength is the number of hex characters of the hash of a preshared key
+// naming its caller, which identify the key without revealing it.
+const presharedKeyCallerHashLength = 12
+
+// PresharedKeyCaller returns the name of the caller making calls with a preshared key, which is
+// `key:` followed by a prefix of the SHA-256 hash of the key, so that it can be reported without
+// revealing the key.
+func PresharedKeyCaller(presharedKey string) string {
+	hash := sha256.Sum256([]byte(presharedKey))
+	return "key:" + hex.EncodeToString(hash[:])[:presharedKeyCallerHashLength]
+}
+
+// CallerFromContext returns the name of the caller of an authenticated call, for accounting:
+// `identity:` followed by the identity of its client certificate, `oidc:` followed by the subject
+// of its OIDC token, or the PresharedKeyCaller of its preshared key. It returns an empty string
+// if the call has no credentials.
+func CallerFromContext(ctx context.Context) string {
+	if identity, ok := ClientIdentityFromContext(ctx); ok {
+		return "identity:" + identity
+	}
+	if claims, ok := OIDCClaimsFromContext(ctx); ok {
+		return "oidc:" + claims.Subject
+	}
+	if key, err := grpcauth.AuthFromMD(ctx, "bearer"); err == nil && key != "" {
+		return PresharedKeyCaller(key)
+	}
+	return ""
+}
+
+// unaccountedServices are the services whose calls are not accounted to their callers.
+var unaccountedServices = []string{
+	"/grpc.reflection.v1.ServerReflection/",
+	"/grpc.reflection.v1alpha.ServerReflection/",
+	"/grpc.health.v1.Health/",
+}
+
+// AccountedCallerFromContext returns the CallerFromContext of a call to the given method, if the
+// call has one and is to be accounted to it.
+func AccountedCallerFromContext(ctx context.Context, fullMethod string) (string, bool) {
+	for _, service := range unaccountedServices {
+		if strings.HasPrefix(fullMethod, service) {
+			return "", false
+		}
+	}
+
+	caller := CallerFromContext(ctx)
+	return caller, caller != ""
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestAccountedCallerFromContext(t *testing.T) {
+	withKey := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer somekey"))
+
+	testCases := []struct {
+		name           string
+		ctx            context.Context
+		fullMethod     string
+		expectedCaller string
+		expectedOK     bool
+	}{
+		{"preshared key", withKey, "/authzed.api.v1.PermissionsService/CheckPermission", PresharedKeyCaller("somekey"), true},
+		{"no credentials", context.Background(), "/authzed.api.v1.PermissionsService/CheckPermission", "", false},
+		{"health", withKey, "/grpc.health.v1.Health/Check", "", false},
+		{"reflection", withKey, "/grpc.reflection.v1.ServerReflection/ServerReflectionInfo", "", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			caller, ok := AccountedCallerFromContext(tc.ctx, tc.fullMethod)
+			require.Equal(t, tc.expectedOK, ok)
+			require.Equal(t, tc.expectedCaller, caller)
+		})
+	}
+}
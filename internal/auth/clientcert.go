@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"context"
+	"crypto/x509"
+
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+const errMissingClientCertificate = "missing client certificate or bearer token"
+
+type clientIdentityKey struct{}
+
+// ContextWithClientIdentity returns a context holding the identity of the client certificate with
+// which the call was authenticated.
+func ContextWithClientIdentity(ctx context.Context, identity string) context.Context {
+	return context.WithValue(ctx, clientIdentityKey{}, identity)
+}
+
+// ClientIdentityFromContext returns the identity of the client certificate with which the call
+// was authenticated, if any.
+func ClientIdentityFromContext(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(string)
+	return identity, ok
+}
+
+// CertificateIdentities returns the identities of a client certificate: its URI SANs, such as its
+// SPIFFE ID, followed by its DNS SANs.
+func CertificateIdentities(cert *x509.Certificate) []string {
+	identities := make([]string, 0, len(cert.URIs)+len(cert.DNSNames))
+	for _, uri := range cert.URIs {
+		identities = append(identities, uri.String())
+	}
+	return append(identities, cert.DNSNames...)
+}
+
+// clientCertificate returns the client certificate of the connection of the call, if any. Client
+// certificates are verified by the TLS config of the server during the handshake.
+func clientCertificate(ctx context.Context) *x509.Certificate {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.PeerCertificates) == 0 {
+		return nil
+	}
+	return tlsInfo.State.PeerCertificates[0]
+}
+
+// RequireClientCertificateOrToken authenticates the calls made over connections with a client
+// certificate having one of the given identities by that identity, which is available from
+// ClientIdentityFromContext. Other calls are authenticated by the given auth func, or rejected if
+// it is nil.
+func RequireClientCertificateOrToken(identities []string, next grpcauth.AuthFunc) grpcauth.AuthFunc {
+	return func(ctx context.Context) (context.Context, error) {
+		if cert := clientCertificate(ctx); cert != nil {
+			for _, identity := range CertificateIdentities(cert) {
+				if slices.Contains(identities, identity) {
+					return ContextWithClientIdentity(ctx, identity), nil
+				}
+			}
+		}
+
+		if next == nil {
+			return nil, status.Errorf(codes.Unauthenticated, errMissingClientCertificate)
+		}
+		return next(ctx)
+	}
+}
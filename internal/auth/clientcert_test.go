@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net/url"
+	"testing"
+
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+func TestRequireClientCertificateOrToken(t *testing.T) {
+	spiffeID, err := url.Parse("spiffe://example.org/ns/prod/sa/billing")
+	require.NoError(t, err)
+
+	testcases := []struct {
+		name             string
+		cert             *x509.Certificate
+		withNext         bool
+		authzHeader      string
+		expectedStatus   codes.Code
+		expectedIdentity string
+	}{
+		{"authenticated by SPIFFE ID", &x509.Certificate{URIs: []*url.URL{spiffeID}}, false, "", codes.OK, "spiffe://example.org/ns/prod/sa/billing"},
+		{"authenticated by DNS SAN", &x509.Certificate{DNSNames: []string{"other.example.org", "billing.example.org"}}, false, "", codes.OK, "billing.example.org"},
+		{"SPIFFE ID preferred over DNS SAN", &x509.Certificate{URIs: []*url.URL{spiffeID}, DNSNames: []string{"billing.example.org"}}, false, "", codes.OK, "spiffe://example.org/ns/prod/sa/billing"},
+		{"unauthenticated due to unmapped certificate", &x509.Certificate{DNSNames: []string{"other.example.org"}}, false, "", codes.Unauthenticated, ""},
+		{"unauthenticated due to missing certificate", nil, false, "", codes.Unauthenticated, ""},
+		{"unmapped certificate falls back to token", &x509.Certificate{DNSNames: []string{"other.example.org"}}, true, "bearer one", codes.OK, ""},
+		{"missing certificate falls back to token", nil, true, "bearer one", codes.OK, ""},
+		{"denied by fallback due to unknown key", nil, true, "bearer two", codes.PermissionDenied, ""},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			var next func(context.Context) (context.Context, error)
+			if testcase.withNext {
+				next = RequirePresharedKey([]string{"one"}, nil)
+			}
+			f := RequireClientCertificateOrToken([]string{"spiffe://example.org/ns/prod/sa/billing", "billing.example.org"}, next)
+
+			ctx := context.Background()
+			if testcase.authzHeader != "" {
+				ctx = withTokenMetadata(testcase.authzHeader)
+			}
+			if testcase.cert != nil {
+				ctx = peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+					State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{testcase.cert}},
+				}})
+			}
+
+			ctx, err := f(ctx)
+			if testcase.expectedStatus != codes.OK {
+				require.Error(t, err)
+				grpcutil.RequireStatus(t, testcase.expectedStatus, err)
+				return
+			}
+			require.NoError(t, err)
+
+			identity, ok := ClientIdentityFromContext(ctx)
+			require.Equal(t, testcase.expectedIdentity != "", ok)
+			require.Equal(t, testcase.expectedIdentity, identity)
+		})
+	}
+}
@@ -0,0 +1,328 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	jose "gopkg.in/square/go-jose.v2"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+const (
+	// defaultJWKSRefreshInterval is the interval after which the signing keys of the issuer are
+	// fetched again, if not configured.
+	defaultJWKSRefreshInterval = time.Hour
+
+	// minJWKSRefreshInterval is the minimum interval between fetches of the signing keys of the
+	// issuer triggered by tokens signed with unknown keys, so that such tokens cannot be used to
+	// flood the issuer.
+	minJWKSRefreshInterval = time.Minute
+
+	// oidcFetchTimeout is the timeout of the refreshes of the signing keys of the issuer, and of
+	// the fetches of the default HTTP client.
+	oidcFetchTimeout = 10 * time.Second
+
+	errInvalidOIDCToken = "invalid OIDC token: %s"
+)
+
+// OIDCConfig configures the validation of the JWTs issued by an OIDC provider.
+type OIDCConfig struct {
+	// Issuer is the URL of the issuer of the tokens, whose OpenID configuration is discovered at
+	// `/.well-known/openid-configuration`.
+	Issuer string
+
+	// Audience is the audience the tokens must be issued for.
+	Audience string
+
+	// JWKSRefreshInterval is the interval after which the signing keys of the issuer are fetched
+	// again. Tokens signed with an unknown key also trigger a fetch.
+	JWKSRefreshInterval time.Duration
+
+	// HTTPClient is the client used to fetch the OpenID configuration and the signing keys of the
+	// issuer, or a client with a default timeout if nil.
+	HTTPClient *http.Client
+}
+
+// OIDCClaims are the claims of a validated OIDC token.
+type OIDCClaims struct {
+	// Subject is the `sub` claim of the token.
+	Subject string
+
+	// Claims are all the claims of the token.
+	Claims map[string]interface{}
+}
+
+type oidcClaimsKey struct{}
+
+// ContextWithOIDCClaims returns a context holding the claims of the OIDC token with which the
+// call was authenticated.
+func ContextWithOIDCClaims(ctx context.Context, claims *OIDCClaims) context.Context {
+	return context.WithValue(ctx, oidcClaimsKey{}, claims)
+}
+
+// OIDCClaimsFromContext returns the claims of the OIDC token with which the call was
+// authenticated, if any.
+func OIDCClaimsFromContext(ctx context.Context) (*OIDCClaims, bool) {
+	claims, ok := ctx.Value(oidcClaimsKey{}).(*OIDCClaims)
+	return claims, ok
+}
+
+// OIDCVerifier validates the JWTs issued by an OIDC provider against its signing keys, which are
+// refreshed periodically.
+type OIDCVerifier struct {
+	config  OIDCConfig
+	jwksURI string
+
+	mu          sync.Mutex
+	keys        map[string]jose.JSONWebKey
+	refreshedAt time.Time
+	refreshing  chan struct{}
+}
+
+// NewOIDCVerifier discovers the signing keys of the issuer and returns a verifier of its tokens.
+func NewOIDCVerifier(ctx context.Context, config OIDCConfig) (*OIDCVerifier, error) {
+	if config.Issuer == "" || config.Audience == "" {
+		return nil, errors.New("the issuer and the audience of OIDC tokens must be configured")
+	}
+	if config.JWKSRefreshInterval <= 0 {
+		config.JWKSRefreshInterval = defaultJWKSRefreshInterval
+	}
+	if config.HTTPClient == nil {
+		config.HTTPClient = &http.Client{Timeout: oidcFetchTimeout}
+	}
+
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, config.HTTPClient), config.Issuer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to discover the OpenID configuration of %s: %w", config.Issuer, err)
+	}
+
+	var discovery struct {
+		JWKSURI string `json:"jwks_uri"`
+	}
+	if err := provider.Claims(&discovery); err != nil {
+		return nil, fmt.Errorf("failed to discover the OpenID configuration of %s: %w", config.Issuer, err)
+	}
+	if discovery.JWKSURI == "" {
+		return nil, fmt.Errorf("the OpenID configuration of %s has no jwks_uri", config.Issuer)
+	}
+
+	v := &OIDCVerifier{config: config, jwksURI: discovery.JWKSURI}
+	keys, err := v.fetchKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	v.refreshedAt = time.Now()
+	return v, nil
+}
+
+// Verify validates the signature, issuer, audience and times of the token, returning its claims.
+func (v *OIDCVerifier) Verify(ctx context.Context, token string, now time.Time) (*OIDCClaims, error) {
+	verifier := oidc.NewVerifier(v.config.Issuer, timedKeySet{v, now}, &oidc.Config{
+		ClientID:             v.config.Audience,
+		SupportedSigningAlgs: supportedSigningAlgs,
+		Now:                  func() time.Time { return now },
+	})
+
+	idToken, err := verifier.Verify(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := map[string]interface{}{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("malformed token claims: %w", err)
+	}
+	return &OIDCClaims{Subject: idToken.Subject, Claims: claims}, nil
+}
+
+// supportedSigningAlgs are the algorithms with which the tokens may be signed. The HMAC algorithms
+// are excluded, since the signing keys of the issuer are public.
+var supportedSigningAlgs = []string{
+	oidc.RS256, oidc.RS384, oidc.RS512,
+	oidc.ES256, oidc.ES384, oidc.ES512,
+}
+
+// timedKeySet verifies the signatures of tokens with the signing keys of the verifier, as of the
+// time of the validation.
+type timedKeySet struct {
+	verifier *OIDCVerifier
+	now      time.Time
+}
+
+func (ks timedKeySet) VerifySignature(ctx context.Context, token string) ([]byte, error) {
+	jws, err := jose.ParseSigned(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed token: %w", err)
+	}
+	if len(jws.Signatures) != 1 {
+		return nil, errors.New("token without a single signature")
+	}
+
+	key, err := ks.verifier.key(ctx, jws.Signatures[0].Header.KeyID, ks.now)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := jws.Verify(&key)
+	if err != nil {
+		return nil, errors.New("invalid token signature")
+	}
+	return payload, nil
+}
+
+// key returns the signing key with the given ID. The keys are refreshed in the background once
+// stale, and an unknown key waits for a refresh, at most every minJWKSRefreshInterval.
+func (v *OIDCVerifier) key(ctx context.Context, kid string, now time.Time) (jose.JSONWebKey, error) {
+	v.mu.Lock()
+	key, ok := v.keys[kid]
+	sinceRefresh := now.Sub(v.refreshedAt)
+	var refreshed <-chan struct{} = v.refreshing
+	if sinceRefresh >= v.config.JWKSRefreshInterval || (!ok && sinceRefresh >= minJWKSRefreshInterval) {
+		refreshed = v.startRefreshLocked(now)
+	}
+	v.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+	if refreshed == nil {
+		return jose.JSONWebKey{}, fmt.Errorf("token signed with unknown key %q", kid)
+	}
+
+	select {
+	case <-refreshed:
+	case <-ctx.Done():
+		return jose.JSONWebKey{}, ctx.Err()
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	key, ok = v.keys[kid]
+	if !ok {
+		return jose.JSONWebKey{}, fmt.Errorf("token signed with unknown key %q", kid)
+	}
+	return key, nil
+}
+
+// startRefreshLocked starts fetching the signing keys of the issuer, unless a fetch is already in
+// progress, and returns a channel closed once the fetch is done. The fetch is not bound to the call
+// which triggered it, so that it is neither canceled with the call nor holds the lock while
+// waiting for the issuer. Must be called with the lock held.
+func (v *OIDCVerifier) startRefreshLocked(now time.Time) <-chan struct{} {
+	// Failed fetches are also rate limited, so that an unreachable issuer is not retried by every
+	// call.
+	v.refreshedAt = now
+	if v.refreshing != nil {
+		return v.refreshing
+	}
+
+	refreshing := make(chan struct{})
+	v.refreshing = refreshing
+	go func() {
+		defer close(refreshing)
+
+		ctx, cancel := context.WithTimeout(context.Background(), oidcFetchTimeout)
+		defer cancel()
+		keys, err := v.fetchKeys(ctx)
+
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		v.refreshing = nil
+		if err != nil {
+			// The keys of the last successful fetch remain in use until the issuer is reachable.
+			log.Warn().Err(err).Str("issuer", v.config.Issuer).Msg("failed to refresh the OIDC signing keys")
+			return
+		}
+		v.keys = keys
+	}()
+	return refreshing
+}
+
+// fetchKeys fetches the public signing keys of the issuer, skipping the keys which are not
+// supported.
+func (v *OIDCVerifier) fetchKeys(ctx context.Context) (map[string]jose.JSONWebKey, error) {
+	var jwks struct {
+		Keys []json.RawMessage `json:"keys"`
+	}
+	if err := getJSON(ctx, v.config.HTTPClient, v.jwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to fetch the signing keys of %s: %w", v.config.Issuer, err)
+	}
+
+	keys := make(map[string]jose.JSONWebKey, len(jwks.Keys))
+	for _, raw := range jwks.Keys {
+		var key jose.JSONWebKey
+		if err := key.UnmarshalJSON(raw); err != nil {
+			log.Warn().Err(err).Msg("skipping unsupported OIDC signing key")
+			continue
+		}
+		if key.Use != "" && key.Use != "sig" {
+			continue
+		}
+		if !key.IsPublic() || !key.Valid() {
+			log.Warn().Str("kid", key.KeyID).Msg("skipping OIDC signing key which is not a valid public key")
+			continue
+		}
+		keys[key.KeyID] = key
+	}
+	return keys, nil
+}
+
+func getJSON(ctx context.Context, client *http.Client, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %s from %s", resp.Status, url)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// RequirePresharedKeyOrOIDCToken requires that gRPC requests have a Bearer Token value either
+// equivalent to one of the provided preshared key(s), if any, within its validity window, or
+// which is an OIDC token valid for the verifier. The claims of OIDC tokens are available from OIDCClaimsFromContext.
+func RequirePresharedKeyOrOIDCToken(presharedKeys []string, validity map[string]PresharedKeyValidity, verifier *OIDCVerifier) grpcauth.AuthFunc {
+	matcher := newPresharedKeyMatcher("RequirePresharedKeyOrOIDCToken", presharedKeys, validity)
+
+	return func(ctx context.Context) (context.Context, error) {
+		token, err := grpcauth.AuthFromMD(ctx, "bearer")
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, errInvalidPresharedKey, err.Error())
+		}
+
+		if token == "" {
+			return nil, status.Errorf(codes.Unauthenticated, errMissingPresharedKey)
+		}
+
+		if matched, err := matcher.match(token, time.Now()); matched {
+			if err != nil {
+				return nil, status.Errorf(codes.PermissionDenied, errInvalidPresharedKey, err.Error())
+			}
+			return ctx, nil
+		}
+
+		claims, err := verifier.Verify(ctx, token, time.Now())
+		if err != nil {
+			return nil, status.Errorf(codes.Unauthenticated, errInvalidOIDCToken, err.Error())
+		}
+		return ContextWithOIDCClaims(ctx, claims), nil
+	}
+}
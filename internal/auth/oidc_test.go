@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+)
+
+type testIssuer struct {
+	server *httptest.Server
+
+	mu      sync.Mutex
+	keys    []map[string]string
+	blocked chan struct{}
+}
+
+func newTestIssuer(t *testing.T) *testIssuer {
+	issuer := &testIssuer{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   issuer.server.URL,
+			"jwks_uri": issuer.server.URL + "/keys",
+		})
+	})
+	mux.HandleFunc("/keys", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		blocked := issuer.blocked
+		issuer.mu.Unlock()
+		if blocked != nil {
+			<-blocked
+		}
+
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"keys": issuer.keys})
+	})
+	issuer.server = httptest.NewServer(mux)
+	t.Cleanup(issuer.server.Close)
+	return issuer
+}
+
+func (i *testIssuer) addRSAKey(kid string, key *rsa.PrivateKey) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keys = append(i.keys, map[string]string{
+		"kid": kid,
+		"kty": "RSA",
+		"use": "sig",
+		"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	})
+}
+
+func (i *testIssuer) addECKey(kid string, key *ecdsa.PrivateKey) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.keys = append(i.keys, map[string]string{
+		"kid": kid,
+		"kty": "EC",
+		"crv": "P-256",
+		"x":   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+		"y":   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+	})
+}
+
+func signToken(t *testing.T, alg, kid string, key crypto.Signer, claims map[string]interface{}) string {
+	header, err := json.Marshal(map[string]string{"alg": alg, "kid": kid, "typ": "JWT"})
+	require.NoError(t, err)
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+
+	var signature []byte
+	switch key := key.(type) {
+	case *rsa.PrivateKey:
+		signature, err = rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+		require.NoError(t, err)
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+		require.NoError(t, err)
+		signature = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case nil:
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestOIDCVerifier(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := newTestIssuer(t)
+	issuer.addRSAKey("rsa", rsaKey)
+	issuer.addECKey("ec", ecKey)
+
+	verifier, err := NewOIDCVerifier(context.Background(), OIDCConfig{Issuer: issuer.server.URL, Audience: "spicedb"})
+	require.NoError(t, err)
+
+	now := time.Now()
+	claims := func(overrides map[string]interface{}) map[string]interface{} {
+		claims := map[string]interface{}{
+			"iss": issuer.server.URL,
+			"aud": "spicedb",
+			"sub": "someservice",
+			"exp": now.Add(time.Hour).Unix(),
+		}
+		for name, value := range overrides {
+			if value == nil {
+				delete(claims, name)
+				continue
+			}
+			claims[name] = value
+		}
+		return claims
+	}
+
+	testCases := []struct {
+		name          string
+		token         string
+		expectedError string
+	}{
+		{"valid RS256", signToken(t, "RS256", "rsa", rsaKey, claims(nil)), ""},
+		{"valid ES256", signToken(t, "ES256", "ec", ecKey, claims(nil)), ""},
+		{"audience list", signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": []string{"other", "spicedb"}})), ""},
+		{"malformed", "not-a-token", "malformed jwt"},
+		{"unknown key", signToken(t, "RS256", "other", otherKey, claims(nil)), "unknown key"},
+		{"wrong key", signToken(t, "RS256", "rsa", otherKey, claims(nil)), "invalid token signature"},
+		{"algorithm mismatch", signToken(t, "ES256", "rsa", ecKey, claims(nil)), "invalid token signature"},
+		{"none algorithm", signToken(t, "none", "rsa", nil, claims(nil)), "unsupported algorithm"},
+		{"wrong issuer", signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": "https://example.com"})), "different provider"},
+		{"wrong audience", signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": "other"})), "expected audience"},
+		{"no expiration", signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": nil})), "expired"},
+		{"expired", signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": now.Add(-time.Hour).Unix()})), "expired"},
+		{"not yet valid", signToken(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"nbf": now.Add(time.Hour).Unix()})), "before the nbf"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			claims, err := verifier.Verify(context.Background(), tc.token, now)
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, "someservice", claims.Subject)
+		})
+	}
+}
+
+func TestOIDCVerifierKeyRotation(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := newTestIssuer(t)
+	issuer.addRSAKey("old", oldKey)
+
+	verifier, err := NewOIDCVerifier(context.Background(), OIDCConfig{Issuer: issuer.server.URL, Audience: "spicedb"})
+	require.NoError(t, err)
+
+	issuer.addRSAKey("new", newKey)
+	now := time.Now()
+	token := signToken(t, "RS256", "new", newKey, map[string]interface{}{
+		"iss": issuer.server.URL,
+		"aud": "spicedb",
+		"exp": now.Add(time.Hour).Unix(),
+	})
+
+	// The keys were just fetched, so the unknown key does not trigger a fetch yet.
+	_, err = verifier.Verify(context.Background(), token, now)
+	require.ErrorContains(t, err, "unknown key")
+
+	_, err = verifier.Verify(context.Background(), token, now.Add(minJWKSRefreshInterval))
+	require.NoError(t, err)
+}
+
+func TestOIDCVerifierSlowRefresh(t *testing.T) {
+	oldKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	newKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := newTestIssuer(t)
+	issuer.addRSAKey("old", oldKey)
+
+	verifier, err := NewOIDCVerifier(context.Background(), OIDCConfig{Issuer: issuer.server.URL, Audience: "spicedb"})
+	require.NoError(t, err)
+
+	unblock := make(chan struct{})
+	issuer.mu.Lock()
+	issuer.blocked = unblock
+	issuer.mu.Unlock()
+	issuer.addRSAKey("new", newKey)
+
+	now := time.Now().Add(minJWKSRefreshInterval)
+	sign := func(kid string, key *rsa.PrivateKey) string {
+		return signToken(t, "RS256", kid, key, map[string]interface{}{
+			"iss": issuer.server.URL,
+			"aud": "spicedb",
+			"exp": now.Add(time.Hour).Unix(),
+		})
+	}
+
+	// The call waiting for the unknown key gives up with its context, while the refresh goes on.
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = verifier.Verify(ctx, sign("new", newKey), now)
+	require.ErrorContains(t, err, context.DeadlineExceeded.Error())
+
+	// The known keys remain usable while the issuer is slow to respond.
+	_, err = verifier.Verify(context.Background(), sign("old", oldKey), now)
+	require.NoError(t, err)
+
+	close(unblock)
+	_, err = verifier.Verify(context.Background(), sign("new", newKey), now)
+	require.NoError(t, err)
+}
+
+func TestRequirePresharedKeyOrOIDCToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := newTestIssuer(t)
+	issuer.addRSAKey("rsa", key)
+
+	verifier, err := NewOIDCVerifier(context.Background(), OIDCConfig{Issuer: issuer.server.URL, Audience: "spicedb"})
+	require.NoError(t, err)
+
+	token := signToken(t, "RS256", "rsa", key, map[string]interface{}{
+		"iss": issuer.server.URL,
+		"aud": "spicedb",
+		"sub": "someservice",
+		"exp": time.Now().Add(time.Hour).Unix(),
+	})
+
+	testcases := []struct {
+		name            string
+		authzHeader     string
+		expectedStatus  codes.Code
+		expectedSubject string
+	}{
+		{"valid preshared key", "bearer one", codes.OK, ""},
+		{"valid OIDC token", "bearer " + token, codes.OK, "someservice"},
+		{"invalid OIDC token", "bearer " + token[:len(token)-4] + "AAAA", codes.Unauthenticated, ""},
+		{"unknown key", "bearer two", codes.Unauthenticated, ""},
+		{"missing key", "bearer ", codes.Unauthenticated, ""},
+	}
+
+	f := RequirePresharedKeyOrOIDCToken([]string{"one"}, nil, verifier)
+	for _, testcase := range testcases {
+		testcase := testcase
+		t.Run(testcase.name, func(t *testing.T) {
+			ctx, err := f(withTokenMetadata(testcase.authzHeader))
+			if testcase.expectedStatus != codes.OK {
+				grpcutil.RequireStatus(t, testcase.expectedStatus, err)
+				return
+			}
+			require.NoError(t, err)
+
+			claims, ok := OIDCClaimsFromContext(ctx)
+			require.Equal(t, testcase.expectedSubject != "", ok)
+			if ok {
+				require.Equal(t, testcase.expectedSubject, claims.Subject)
+			}
+		})
+	}
+}
@@ -3,8 +3,14 @@ package auth
 import (
 	"context"
 	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
 
 	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -16,18 +22,149 @@ const (
 
 var errInvalidToken = "invalid token"
 
-// RequirePresharedKey requires that gRPC requests have a Bearer Token value
-// equivalent to one of the provided preshared key(s).
-func RequirePresharedKey(presharedKeys []string) grpcauth.AuthFunc {
-	if len(presharedKeys) == 0 {
-		panic("RequirePresharedKey was given an empty preshared keys slice")
+var (
+	presharedKeyCallsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "auth",
+		Name:      "preshared_key_calls_total",
+		Help:      "The calls made with each preshared key, by the caller name of the key and by whether the key was valid, expired or not yet valid.",
+	}, []string{"key", "validity"})
+
+	presharedKeyExpiryGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "spicedb",
+		Subsystem: "auth",
+		Name:      "preshared_key_expiry_timestamp_seconds",
+		Help:      "The time at which each preshared key with a validity window expires, by the caller name of the key.",
+	}, []string{"key"})
+)
+
+const (
+	validityValid       = "valid"
+	validityExpired     = "expired"
+	validityNotYetValid = "not_yet_valid"
+)
+
+// PresharedKeyValidity is the window within which a preshared key authenticates requests. Keys
+// are rotated without a coordinated cutover by configuring the new key, valid from the time it
+// is created, alongside the old key, which expires once its callers have moved to the new key.
+type PresharedKeyValidity struct {
+	// Created is the time from which the key is valid, or zero if it is valid from any time.
+	Created time.Time
+
+	// Expires is the time from which the key is no longer valid, or zero if it never expires.
+	Expires time.Time
+}
+
+// ParsePresharedKeyValidity parses a validity window of the form
+// `created=2023-01-01T00:00:00Z|expires=2023-04-01T00:00:00Z`, where each time is optional.
+func ParsePresharedKeyValidity(value string) (PresharedKeyValidity, error) {
+	var validity PresharedKeyValidity
+	for _, bound := range strings.Split(value, "|") {
+		name, timeValue, ok := strings.Cut(bound, "=")
+		if !ok {
+			return PresharedKeyValidity{}, fmt.Errorf("invalid preshared key validity %q: expected a `|`-separated list of `created=time` and `expires=time`", value)
+		}
+
+		parsed, err := time.Parse(time.RFC3339, timeValue)
+		if err != nil {
+			return PresharedKeyValidity{}, fmt.Errorf("invalid preshared key validity %q: expected an RFC 3339 time for %s: %w", value, name, err)
+		}
+
+		switch name {
+		case "created":
+			validity.Created = parsed
+		case "expires":
+			validity.Expires = parsed
+		default:
+			return PresharedKeyValidity{}, fmt.Errorf("invalid preshared key validity %q: unknown bound %q, expected created or expires", value, name)
+		}
+	}
+
+	if !validity.Created.IsZero() && !validity.Expires.IsZero() && !validity.Expires.After(validity.Created) {
+		return PresharedKeyValidity{}, fmt.Errorf("invalid preshared key validity %q: the key must expire after it is created", value)
+	}
+	return validity, nil
+}
+
+// at returns whether the key is valid, expired or not yet valid at the given time.
+func (v PresharedKeyValidity) at(now time.Time) string {
+	switch {
+	case !v.Created.IsZero() && now.Before(v.Created):
+		return validityNotYetValid
+	case !v.Expires.IsZero() && !now.Before(v.Expires):
+		return validityExpired
+	default:
+		return validityValid
+	}
+}
+
+// ValidPresharedKey returns the first of the preshared keys which is valid at the given time,
+// such as to authenticate the requests made to other nodes, or false if none is.
+func ValidPresharedKey(presharedKeys []string, validity map[string]PresharedKeyValidity, now time.Time) (string, bool) {
+	for _, presharedKey := range presharedKeys {
+		if validity[presharedKey].at(now) == validityValid {
+			return presharedKey, true
+		}
 	}
+	return "", false
+}
+
+// presharedKeyMatcher matches tokens against preshared keys, within their validity windows.
+type presharedKeyMatcher struct {
+	presharedKeys []string
+	validity      map[string]PresharedKeyValidity
+	callers       []string
+}
 
+func newPresharedKeyMatcher(name string, presharedKeys []string, validity map[string]PresharedKeyValidity) *presharedKeyMatcher {
+	m := &presharedKeyMatcher{
+		presharedKeys: presharedKeys,
+		validity:      validity,
+		callers:       make([]string, 0, len(presharedKeys)),
+	}
 	for _, presharedKey := range presharedKeys {
 		if len(presharedKey) == 0 {
-			panic("RequirePresharedKey was given an empty preshared key")
+			panic(name + " was given an empty preshared key")
+		}
+
+		caller := PresharedKeyCaller(presharedKey)
+		m.callers = append(m.callers, caller)
+		if expires := validity[presharedKey].Expires; !expires.IsZero() {
+			presharedKeyExpiryGauge.WithLabelValues(caller).Set(float64(expires.Unix()))
+		}
+	}
+	return m
+}
+
+// match returns whether the token is one of the preshared keys and, if so, an error if the key
+// is not valid at the given time.
+func (m *presharedKeyMatcher) match(token string, now time.Time) (bool, error) {
+	for index, presharedKey := range m.presharedKeys {
+		if match := subtle.ConstantTimeCompare([]byte(presharedKey), []byte(token)); match != 1 {
+			continue
 		}
+
+		validity := m.validity[presharedKey].at(now)
+		presharedKeyCallsCounter.WithLabelValues(m.callers[index], validity).Inc()
+		switch validity {
+		case validityExpired:
+			return true, fmt.Errorf("key %s expired at %s", m.callers[index], m.validity[presharedKey].Expires.Format(time.RFC3339))
+		case validityNotYetValid:
+			return true, fmt.Errorf("key %s is not valid until %s", m.callers[index], m.validity[presharedKey].Created.Format(time.RFC3339))
+		default:
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// RequirePresharedKey requires that gRPC requests have a Bearer Token value
+// equivalent to one of the provided preshared key(s), within its validity window if any.
+func RequirePresharedKey(presharedKeys []string, validity map[string]PresharedKeyValidity) grpcauth.AuthFunc {
+	if len(presharedKeys) == 0 {
+		panic("RequirePresharedKey was given an empty preshared keys slice")
 	}
+	matcher := newPresharedKeyMatcher("RequirePresharedKey", presharedKeys, validity)
 
 	return func(ctx context.Context) (context.Context, error) {
 		token, err := grpcauth.AuthFromMD(ctx, "bearer")
@@ -39,12 +176,42 @@ func RequirePresharedKey(presharedKeys []string) grpcauth.AuthFunc {
 			return nil, status.Errorf(codes.Unauthenticated, errMissingPresharedKey)
 		}
 
-		for _, presharedKey := range presharedKeys {
-			if match := subtle.ConstantTimeCompare([]byte(presharedKey), []byte(token)); match == 1 {
-				return ctx, nil
+		if matched, err := matcher.match(token, time.Now()); matched {
+			if err != nil {
+				return nil, status.Errorf(codes.PermissionDenied, errInvalidPresharedKey, err.Error())
 			}
+			return ctx, nil
 		}
 
 		return nil, status.Errorf(codes.PermissionDenied, errInvalidPresharedKey, errInvalidToken)
 	}
 }
+
+// RequireHTTPPresharedKey wraps the given handler, requiring that HTTP requests have a Bearer
+// Token value equivalent to one of the provided preshared key(s), within its validity window if
+// any.
+func RequireHTTPPresharedKey(presharedKeys []string, validity map[string]PresharedKeyValidity, next http.Handler) http.Handler {
+	if len(presharedKeys) == 0 {
+		panic("RequireHTTPPresharedKey was given an empty preshared keys slice")
+	}
+	matcher := newPresharedKeyMatcher("RequireHTTPPresharedKey", presharedKeys, validity)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "bearer") || token == "" {
+			http.Error(w, errMissingPresharedKey, http.StatusUnauthorized)
+			return
+		}
+
+		if matched, err := matcher.match(token, time.Now()); matched {
+			if err != nil {
+				http.Error(w, fmt.Sprintf(errInvalidPresharedKey, err.Error()), http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		http.Error(w, fmt.Sprintf(errInvalidPresharedKey, errInvalidToken), http.StatusForbidden)
+	})
+}
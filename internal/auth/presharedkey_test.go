@@ -2,7 +2,10 @@ package auth
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/authzed/grpcutil"
 	metautils "github.com/grpc-ecosystem/go-grpc-middleware/v2/metadata"
@@ -12,24 +15,35 @@ import (
 )
 
 func TestPresharedKeys(t *testing.T) {
+	now := time.Now()
+	rotation := map[string]PresharedKeyValidity{
+		"one":   {Expires: now.Add(-time.Minute)},
+		"two":   {Created: now.Add(-time.Hour), Expires: now.Add(time.Hour)},
+		"three": {Created: now.Add(time.Hour)},
+	}
+
 	testcases := []struct {
 		name           string
 		presharedkeys  []string
+		validity       map[string]PresharedKeyValidity
 		withMetadata   bool
 		authzHeader    string
 		expectedStatus codes.Code
 	}{
-		{"valid request with the first key", []string{"one", "two"}, true, "bearer one", codes.OK},
-		{"valid request with the second key", []string{"one", "two"}, true, "bearer two", codes.OK},
-		{"denied due to unknown key", []string{"one", "two"}, true, "bearer three", codes.PermissionDenied},
-		{"unauthenticated due to missing key", []string{"one", "two"}, true, "bearer ", codes.Unauthenticated},
-		{"unauthenticated due to empty header", []string{"one", "two"}, true, "", codes.Unauthenticated},
-		{"unauthenticated due to missing metadata", []string{"one", "two"}, false, "", codes.Unauthenticated},
+		{"valid request with the first key", []string{"one", "two"}, nil, true, "bearer one", codes.OK},
+		{"valid request with the second key", []string{"one", "two"}, nil, true, "bearer two", codes.OK},
+		{"denied due to unknown key", []string{"one", "two"}, nil, true, "bearer three", codes.PermissionDenied},
+		{"unauthenticated due to missing key", []string{"one", "two"}, nil, true, "bearer ", codes.Unauthenticated},
+		{"unauthenticated due to empty header", []string{"one", "two"}, nil, true, "", codes.Unauthenticated},
+		{"unauthenticated due to missing metadata", []string{"one", "two"}, nil, false, "", codes.Unauthenticated},
+		{"denied due to expired key", []string{"one", "two", "three"}, rotation, true, "bearer one", codes.PermissionDenied},
+		{"valid request within the validity window", []string{"one", "two", "three"}, rotation, true, "bearer two", codes.OK},
+		{"denied due to key not yet valid", []string{"one", "two", "three"}, rotation, true, "bearer three", codes.PermissionDenied},
 	}
 
 	for _, testcase := range testcases {
 		t.Run(testcase.name, func(t *testing.T) {
-			f := RequirePresharedKey(testcase.presharedkeys)
+			f := RequirePresharedKey(testcase.presharedkeys, testcase.validity)
 			ctx := context.Background()
 			if testcase.withMetadata {
 				ctx = withTokenMetadata(testcase.authzHeader)
@@ -45,7 +59,88 @@ func TestPresharedKeys(t *testing.T) {
 	}
 }
 
+func TestHTTPPresharedKeys(t *testing.T) {
+	testcases := []struct {
+		name           string
+		authzHeader    string
+		expectedStatus int
+	}{
+		{"valid request with the first key", "Bearer one", http.StatusOK},
+		{"valid request with the second key", "bearer two", http.StatusOK},
+		{"denied due to unknown key", "Bearer three", http.StatusForbidden},
+		{"denied due to expired key", "Bearer four", http.StatusForbidden},
+		{"unauthenticated due to missing key", "Bearer ", http.StatusUnauthorized},
+		{"unauthenticated due to another scheme", "Basic one", http.StatusUnauthorized},
+		{"unauthenticated due to missing header", "", http.StatusUnauthorized},
+	}
+
+	validity := map[string]PresharedKeyValidity{"four": {Expires: time.Now().Add(-time.Minute)}}
+	handler := RequireHTTPPresharedKey([]string{"one", "two", "four"}, validity, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			if testcase.authzHeader != "" {
+				req.Header.Set("Authorization", testcase.authzHeader)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			require.Equal(t, testcase.expectedStatus, recorder.Code)
+		})
+	}
+}
+
 func withTokenMetadata(authzHeader string) context.Context {
 	md := metadata.Pairs("authorization", authzHeader)
 	return metautils.MD(md).ToIncoming(context.Background())
 }
+
+func TestParsePresharedKeyValidity(t *testing.T) {
+	created := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	expires := time.Date(2023, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	testcases := []struct {
+		name             string
+		value            string
+		expectedValidity PresharedKeyValidity
+		expectedErr      string
+	}{
+		{"created and expires", "created=2023-01-01T00:00:00Z|expires=2023-04-01T00:00:00Z", PresharedKeyValidity{Created: created, Expires: expires}, ""},
+		{"only expires", "expires=2023-04-01T00:00:00Z", PresharedKeyValidity{Expires: expires}, ""},
+		{"only created", "created=2023-01-01T00:00:00Z", PresharedKeyValidity{Created: created}, ""},
+		{"missing time", "expires", PresharedKeyValidity{}, "expected a `|`-separated list"},
+		{"invalid time", "expires=tomorrow", PresharedKeyValidity{}, "expected an RFC 3339 time for expires"},
+		{"unknown bound", "rotates=2023-04-01T00:00:00Z", PresharedKeyValidity{}, "unknown bound \"rotates\""},
+		{"expires before created", "created=2023-04-01T00:00:00Z|expires=2023-01-01T00:00:00Z", PresharedKeyValidity{}, "must expire after it is created"},
+	}
+
+	for _, testcase := range testcases {
+		t.Run(testcase.name, func(t *testing.T) {
+			validity, err := ParsePresharedKeyValidity(testcase.value)
+			if testcase.expectedErr != "" {
+				require.ErrorContains(t, err, testcase.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, testcase.expectedValidity, validity)
+		})
+	}
+}
+
+func TestValidPresharedKey(t *testing.T) {
+	now := time.Now()
+	validity := map[string]PresharedKeyValidity{
+		"old": {Expires: now.Add(-time.Minute)},
+		"new": {Created: now.Add(-time.Minute)},
+	}
+
+	key, ok := ValidPresharedKey([]string{"old", "new"}, validity, now)
+	require.True(t, ok)
+	require.Equal(t, "new", key)
+
+	_, ok = ValidPresharedKey([]string{"old"}, validity, now)
+	require.False(t, ok)
+}
@@ -0,0 +1,313 @@
+// Package backup writes the schema and relationships of a datastore at a revision to a compressed,
+// checksummed archive, and restores them from the archive into a datastore of any engine.
+//
+// An archive is a gzip-compressed stream of frames, each a kind byte followed by the uvarint
+// length of its payload and the payload itself:
+//
+//   - a header frame, holding the JSON-encoded Header;
+//   - a frame per caveat definition, namespace definition and relationship, in that order, holding
+//     its protobuf encoding;
+//   - a trailer frame, holding the JSON-encoded Trailer, with the number of each written and the
+//     SHA-256 checksum of all of the preceding frames.
+package backup
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"time"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// Version is the version of the archive format written by Write.
+const Version = 1
+
+// maxFrameSize is the maximum size of the payload of a frame read from an archive, which guards
+// against allocating for corrupted lengths.
+const maxFrameSize = 64 << 20
+
+type frameKind byte
+
+const (
+	headerFrame       frameKind = 'H'
+	caveatFrame       frameKind = 'C'
+	namespaceFrame    frameKind = 'N'
+	relationshipFrame frameKind = 'R'
+	trailerFrame      frameKind = 'T'
+)
+
+// Header describes the contents of an archive.
+type Header struct {
+	Version   int       `json:"version"`
+	Revision  string    `json:"revision"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Trailer records the number of definitions and relationships in an archive, along with the
+// checksum of its frames.
+type Trailer struct {
+	Caveats       uint64 `json:"caveats"`
+	Namespaces    uint64 `json:"namespaces"`
+	Relationships uint64 `json:"relationships"`
+	SHA256        string `json:"sha256"`
+}
+
+// Manifest is the header and trailer of an archive.
+type Manifest struct {
+	Header
+	Trailer
+}
+
+// ErrCorruptArchive is returned when an archive cannot be read or does not match its checksum.
+var ErrCorruptArchive = errors.New("corrupt backup archive")
+
+// Write writes the schema and all of the relationships of the datastore, as of the given
+// revision, to an archive.
+func Write(ctx context.Context, ds datastore.Datastore, revision datastore.Revision, w io.Writer) (Manifest, error) {
+	reader := ds.SnapshotReader(revision)
+
+	caveats, err := reader.ListCaveats(ctx)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to list caveats: %w", err)
+	}
+	namespaces, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	fw := newFrameWriter(gz)
+
+	manifest := Manifest{Header: Header{
+		Version:   Version,
+		Revision:  revision.String(),
+		CreatedAt: time.Now().UTC(),
+	}}
+	if err := fw.writeJSON(headerFrame, manifest.Header); err != nil {
+		return Manifest{}, err
+	}
+
+	for _, caveat := range caveats {
+		if err := fw.writeProto(caveatFrame, caveat); err != nil {
+			return Manifest{}, err
+		}
+		manifest.Caveats++
+	}
+	for _, namespace := range namespaces {
+		if err := fw.writeProto(namespaceFrame, namespace); err != nil {
+			return Manifest{}, err
+		}
+		manifest.Namespaces++
+	}
+
+	for _, namespace := range namespaces {
+		it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: namespace.Name})
+		if err != nil {
+			return Manifest{}, fmt.Errorf("failed to read relationships of %s: %w", namespace.Name, err)
+		}
+
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			if err := fw.writeProto(relationshipFrame, tpl); err != nil {
+				it.Close()
+				return Manifest{}, err
+			}
+			manifest.Relationships++
+		}
+		if it.Err() != nil {
+			it.Close()
+			return Manifest{}, fmt.Errorf("failed to read relationships of %s: %w", namespace.Name, it.Err())
+		}
+		it.Close()
+	}
+
+	manifest.SHA256 = hex.EncodeToString(fw.checksum.Sum(nil))
+	if err := fw.writeJSON(trailerFrame, manifest.Trailer); err != nil {
+		return Manifest{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return Manifest{}, fmt.Errorf("failed to write backup archive: %w", err)
+	}
+	return manifest, nil
+}
+
+// Verify reads an archive through, returning its manifest if it is complete and matches its
+// checksum.
+func Verify(r io.Reader) (Manifest, error) {
+	return readArchive(r, nil)
+}
+
+// readArchive reads an archive, calling the given function, if any, with each of its definitions
+// and relationships, and returns its manifest once its checksum is verified.
+func readArchive(r io.Reader, onFrame func(frameKind, []byte) error) (Manifest, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return Manifest{}, fmt.Errorf("%w: %s", ErrCorruptArchive, err)
+	}
+	defer gz.Close()
+
+	fr := newFrameReader(gz)
+
+	var manifest Manifest
+	kind, payload, err := fr.next()
+	if err != nil {
+		return Manifest{}, err
+	}
+	if kind != headerFrame {
+		return Manifest{}, fmt.Errorf("%w: missing header", ErrCorruptArchive)
+	}
+	if err := json.Unmarshal(payload, &manifest.Header); err != nil {
+		return Manifest{}, fmt.Errorf("%w: invalid header: %s", ErrCorruptArchive, err)
+	}
+	if manifest.Version != Version {
+		return Manifest{}, fmt.Errorf("unsupported backup archive version %d", manifest.Version)
+	}
+
+	var counted Trailer
+	for {
+		// The checksum covers all of the frames preceding the trailer.
+		sum := hex.EncodeToString(fr.checksum.Sum(nil))
+
+		kind, payload, err := fr.next()
+		if err != nil {
+			return Manifest{}, err
+		}
+
+		switch kind {
+		case caveatFrame:
+			counted.Caveats++
+		case namespaceFrame:
+			counted.Namespaces++
+		case relationshipFrame:
+			counted.Relationships++
+		case trailerFrame:
+			if err := json.Unmarshal(payload, &manifest.Trailer); err != nil {
+				return Manifest{}, fmt.Errorf("%w: invalid trailer: %s", ErrCorruptArchive, err)
+			}
+			counted.SHA256 = sum
+			if manifest.Trailer != counted {
+				return Manifest{}, fmt.Errorf("%w: contents do not match the checksum and counts of the trailer", ErrCorruptArchive)
+			}
+			return manifest, nil
+		default:
+			return Manifest{}, fmt.Errorf("%w: unknown frame kind %q", ErrCorruptArchive, kind)
+		}
+
+		if onFrame != nil {
+			if err := onFrame(kind, payload); err != nil {
+				return Manifest{}, err
+			}
+		}
+	}
+}
+
+type frameWriter struct {
+	w        io.Writer
+	checksum hash.Hash
+	prefix   [1 + binary.MaxVarintLen64]byte
+}
+
+func newFrameWriter(w io.Writer) *frameWriter {
+	checksum := sha256.New()
+	return &frameWriter{w: io.MultiWriter(w, checksum), checksum: checksum}
+}
+
+func (fw *frameWriter) write(kind frameKind, payload []byte) error {
+	fw.prefix[0] = byte(kind)
+	n := binary.PutUvarint(fw.prefix[1:], uint64(len(payload)))
+	if _, err := fw.w.Write(fw.prefix[:1+n]); err != nil {
+		return fmt.Errorf("failed to write backup archive: %w", err)
+	}
+	if _, err := fw.w.Write(payload); err != nil {
+		return fmt.Errorf("failed to write backup archive: %w", err)
+	}
+	return nil
+}
+
+func (fw *frameWriter) writeJSON(kind frameKind, value any) error {
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return fw.write(kind, payload)
+}
+
+type vtMessage interface {
+	MarshalVT() ([]byte, error)
+}
+
+func (fw *frameWriter) writeProto(kind frameKind, message vtMessage) error {
+	payload, err := message.MarshalVT()
+	if err != nil {
+		return err
+	}
+	return fw.write(kind, payload)
+}
+
+type frameReader struct {
+	r        *bufio.Reader
+	checksum hash.Hash
+	prefix   [1 + binary.MaxVarintLen64]byte
+}
+
+func newFrameReader(r io.Reader) *frameReader {
+	return &frameReader{r: bufio.NewReader(r), checksum: sha256.New()}
+}
+
+func (fr *frameReader) next() (frameKind, []byte, error) {
+	kind, err := fr.r.ReadByte()
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: truncated: %s", ErrCorruptArchive, err)
+	}
+	length, err := binary.ReadUvarint(fr.r)
+	if err != nil {
+		return 0, nil, fmt.Errorf("%w: truncated: %s", ErrCorruptArchive, err)
+	}
+	if length > maxFrameSize {
+		return 0, nil, fmt.Errorf("%w: frame of %d bytes exceeds the maximum size", ErrCorruptArchive, length)
+	}
+
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(fr.r, payload); err != nil {
+		return 0, nil, fmt.Errorf("%w: truncated: %s", ErrCorruptArchive, err)
+	}
+
+	fr.prefix[0] = kind
+	n := binary.PutUvarint(fr.prefix[1:], length)
+	fr.checksum.Write(fr.prefix[:1+n])
+	fr.checksum.Write(payload)
+	return frameKind(kind), payload, nil
+}
+
+func unmarshalCaveat(payload []byte) (*core.CaveatDefinition, error) {
+	caveat := &core.CaveatDefinition{}
+	if err := caveat.UnmarshalVT(payload); err != nil {
+		return nil, fmt.Errorf("%w: invalid caveat definition: %s", ErrCorruptArchive, err)
+	}
+	return caveat, nil
+}
+
+func unmarshalNamespace(payload []byte) (*core.NamespaceDefinition, error) {
+	namespace := &core.NamespaceDefinition{}
+	if err := namespace.UnmarshalVT(payload); err != nil {
+		return nil, fmt.Errorf("%w: invalid namespace definition: %s", ErrCorruptArchive, err)
+	}
+	return namespace, nil
+}
+
+func unmarshalRelationship(payload []byte) (*core.RelationTuple, error) {
+	tpl := &core.RelationTuple{}
+	if err := tpl.UnmarshalVT(payload); err != nil {
+		return nil, fmt.Errorf("%w: invalid relationship: %s", ErrCorruptArchive, err)
+	}
+	return tpl, nil
+}
@@ -0,0 +1,138 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	source, revision := testfixtures.StandardDatastoreWithCaveatedData(rawDS, require)
+
+	var archive bytes.Buffer
+	written, err := Write(ctx, source, revision, &archive)
+	require.NoError(err)
+	require.Equal(revision.String(), written.Revision)
+	require.Positive(written.Relationships)
+
+	verified, err := Verify(bytes.NewReader(archive.Bytes()))
+	require.NoError(err)
+	require.Equal(written.Trailer, verified.Trailer)
+
+	target, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	var progress []RestoreProgress
+	restored, err := Restore(ctx, target, bytes.NewReader(archive.Bytes()), 7, func(p RestoreProgress) {
+		progress = append(progress, p)
+	})
+	require.NoError(err)
+	require.Equal(written.Trailer, restored.Trailer)
+	require.Equal(written.Relationships, progress[len(progress)-1].RelationshipsWritten)
+
+	// Restoring again resumes over the existing schema, touching the relationships.
+	_, err = Restore(ctx, target, bytes.NewReader(archive.Bytes()), 100, nil)
+	require.NoError(err)
+
+	targetRevision, err := target.HeadRevision(ctx)
+	require.NoError(err)
+
+	var rearchived bytes.Buffer
+	rewritten, err := Write(ctx, target, targetRevision, &rearchived)
+	require.NoError(err)
+	require.Equal(written.Caveats, rewritten.Caveats)
+	require.Equal(written.Namespaces, rewritten.Namespaces)
+	require.Equal(written.Relationships, rewritten.Relationships)
+	require.Equal(readRelationships(t, source, revision), readRelationships(t, target, targetRevision))
+}
+
+func TestRestoreRefusesDifferentSchema(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	source, revision := testfixtures.StandardDatastoreWithCaveatedData(rawDS, require)
+
+	var archive bytes.Buffer
+	_, err = Write(ctx, source, revision, &archive)
+	require.NoError(err)
+
+	otherDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	target, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(otherDS, `
+definition user {}
+
+definition document {
+	relation viewer: user
+}`, nil, require)
+
+	_, err = Restore(ctx, target, bytes.NewReader(archive.Bytes()), 100, nil)
+	require.ErrorContains(err, "differs from that of the backup archive")
+}
+
+func TestVerifyCorruptArchive(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	source, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	var archive bytes.Buffer
+	_, err = Write(ctx, source, revision, &archive)
+	require.NoError(err)
+
+	for _, corrupt := range [][]byte{
+		archive.Bytes()[:archive.Len()/2],
+		[]byte("not an archive"),
+	} {
+		_, err := Verify(bytes.NewReader(corrupt))
+		require.True(errors.Is(err, ErrCorruptArchive), "unexpected error: %v", err)
+
+		target, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+		require.NoError(err)
+		_, err = Restore(ctx, target, bytes.NewReader(corrupt), 100, nil)
+		require.ErrorIs(err, ErrCorruptArchive)
+
+		// Nothing was written to the target.
+		headRevision, err := target.HeadRevision(ctx)
+		require.NoError(err)
+		namespaces, err := target.SnapshotReader(headRevision).ListNamespaces(ctx)
+		require.NoError(err)
+		require.Empty(namespaces)
+	}
+}
+
+func readRelationships(t *testing.T, ds datastore.Datastore, revision datastore.Revision) map[string]struct{} {
+	ctx := context.Background()
+	reader := ds.SnapshotReader(revision)
+
+	namespaces, err := reader.ListNamespaces(ctx)
+	require.NoError(t, err)
+
+	relationships := map[string]struct{}{}
+	for _, namespace := range namespaces {
+		it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: namespace.Name})
+		require.NoError(t, err)
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			relationships[tuple.String(tpl)] = struct{}{}
+		}
+		require.NoError(t, it.Err())
+		it.Close()
+	}
+	return relationships
+}
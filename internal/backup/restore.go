@@ -0,0 +1,206 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"google.golang.org/protobuf/proto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RestoreProgress is the progress of a restore.
+type RestoreProgress struct {
+	// SchemaWritten indicates that the caveat and namespace definitions have been written.
+	SchemaWritten bool
+
+	// RelationshipsWritten is the number of relationships written.
+	RelationshipsWritten uint64
+}
+
+// Restore restores the schema and relationships of an archive into the datastore, which must be
+// either empty or hold exactly the schema of the archive, such as from an interrupted restore. The
+// archive is verified before anything is written, after which the schema is written in a single
+// transaction and the relationships in transactions of batchSize each. As relationships are
+// touched rather than created, an interrupted restore can be resumed by restoring the same
+// archive again. The given function, if any, is called with the progress after each transaction.
+func Restore(ctx context.Context, ds datastore.Datastore, archive io.ReadSeeker, batchSize uint64, onProgress func(RestoreProgress)) (Manifest, error) {
+	if batchSize == 0 {
+		return Manifest{}, fmt.Errorf("batch size must be greater than zero")
+	}
+	if onProgress == nil {
+		onProgress = func(RestoreProgress) {}
+	}
+
+	manifest, err := Verify(archive)
+	if err != nil {
+		return Manifest{}, err
+	}
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return Manifest{}, fmt.Errorf("failed to rewind backup archive: %w", err)
+	}
+
+	log.Ctx(ctx).Info().
+		Str("revision", manifest.Revision).
+		Time("createdAt", manifest.CreatedAt).
+		Uint64("namespaces", manifest.Namespaces).
+		Uint64("caveats", manifest.Caveats).
+		Uint64("relationships", manifest.Relationships).
+		Msg("verified backup archive; restoring")
+
+	r := &restorer{ds: ds, batchSize: batchSize, onProgress: onProgress}
+	if _, err := readArchive(archive, func(kind frameKind, payload []byte) error {
+		return r.add(ctx, kind, payload)
+	}); err != nil {
+		return Manifest{}, err
+	}
+	if err := r.flush(ctx); err != nil {
+		return Manifest{}, err
+	}
+
+	log.Ctx(ctx).Info().
+		Str("revision", manifest.Revision).
+		Uint64("relationships", r.progress.RelationshipsWritten).
+		Msg("completed restore of backup archive")
+	return manifest, nil
+}
+
+type restorer struct {
+	ds         datastore.Datastore
+	batchSize  uint64
+	onProgress func(RestoreProgress)
+
+	caveats    []*core.CaveatDefinition
+	namespaces []*core.NamespaceDefinition
+	batch      []*core.RelationTupleUpdate
+	progress   RestoreProgress
+}
+
+func (r *restorer) add(ctx context.Context, kind frameKind, payload []byte) error {
+	switch kind {
+	case caveatFrame:
+		caveat, err := unmarshalCaveat(payload)
+		if err != nil {
+			return err
+		}
+		r.caveats = append(r.caveats, caveat)
+
+	case namespaceFrame:
+		namespace, err := unmarshalNamespace(payload)
+		if err != nil {
+			return err
+		}
+		r.namespaces = append(r.namespaces, namespace)
+
+	case relationshipFrame:
+		// The schema precedes all of the relationships in an archive.
+		if err := r.writeSchema(ctx); err != nil {
+			return err
+		}
+
+		tpl, err := unmarshalRelationship(payload)
+		if err != nil {
+			return err
+		}
+		r.batch = append(r.batch, tuple.Touch(tpl))
+		if uint64(len(r.batch)) >= r.batchSize {
+			return r.writeBatch(ctx)
+		}
+	}
+	return nil
+}
+
+func (r *restorer) flush(ctx context.Context) error {
+	if err := r.writeSchema(ctx); err != nil {
+		return err
+	}
+	return r.writeBatch(ctx)
+}
+
+// writeSchema writes the caveat and namespace definitions of the archive, unless already written.
+func (r *restorer) writeSchema(ctx context.Context) error {
+	if r.progress.SchemaWritten {
+		return nil
+	}
+
+	_, err := r.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		existingCaveats, err := rwt.ListCaveats(ctx)
+		if err != nil {
+			return err
+		}
+		existingNamespaces, err := rwt.ListNamespaces(ctx)
+		if err != nil {
+			return err
+		}
+
+		if len(existingCaveats) == 0 && len(existingNamespaces) == 0 {
+			if len(r.caveats) > 0 {
+				if err := rwt.WriteCaveats(ctx, r.caveats); err != nil {
+					return err
+				}
+			}
+			if len(r.namespaces) > 0 {
+				return rwt.WriteNamespaces(ctx, r.namespaces...)
+			}
+			return nil
+		}
+
+		if !sameDefinitions(existingCaveats, r.caveats) || !sameDefinitions(existingNamespaces, r.namespaces) {
+			return fmt.Errorf("the datastore already holds a schema which differs from that of the backup archive")
+		}
+		log.Ctx(ctx).Info().Msg("datastore already holds the schema of the backup archive; resuming restore")
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to restore schema: %w", err)
+	}
+
+	r.progress.SchemaWritten = true
+	r.onProgress(r.progress)
+	return nil
+}
+
+func (r *restorer) writeBatch(ctx context.Context) error {
+	if len(r.batch) == 0 {
+		return nil
+	}
+
+	if _, err := r.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, r.batch)
+	}); err != nil {
+		return fmt.Errorf("failed to restore relationships: %w", err)
+	}
+
+	r.progress.RelationshipsWritten += uint64(len(r.batch))
+	r.batch = nil
+	r.onProgress(r.progress)
+	return nil
+}
+
+type namedDefinition interface {
+	proto.Message
+	GetName() string
+}
+
+// sameDefinitions returns whether both lists hold the same definitions, in any order.
+func sameDefinitions[T namedDefinition](existing, restored []T) bool {
+	if len(existing) != len(restored) {
+		return false
+	}
+
+	byName := make(map[string]T, len(existing))
+	for _, definition := range existing {
+		byName[definition.GetName()] = definition
+	}
+	for _, definition := range restored {
+		found, ok := byName[definition.GetName()]
+		if !ok || !proto.Equal(found, definition) {
+			return false
+		}
+	}
+	return true
+}
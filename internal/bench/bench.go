@@ -0,0 +1,404 @@
+// Package bench generates a mix of CheckPermission, LookupResources and WriteRelationships calls
+// against a SpiceDB cluster at a target rate, with zipfian distributions of the resources and
+// subjects they name, and reports the latency percentiles and dispatch statistics of each kind.
+package bench
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/authzed/authzed-go/pkg/responsemeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Operation is a kind of call made by a benchmark.
+type Operation string
+
+const (
+	// Check calls CheckPermission for the permission of a resource for a subject.
+	Check Operation = "check"
+
+	// Lookup calls LookupResources for the resources on which a subject has the permission.
+	Lookup Operation = "lookup"
+
+	// Write calls WriteRelationships to touch the relation between a resource and a subject.
+	Write Operation = "write"
+)
+
+// Operations are all of the operations, in the order in which they are reported.
+var Operations = []Operation{Check, Lookup, Write}
+
+// Config configures a benchmark.
+type Config struct {
+	// ResourceType, Relation and Permission are the object definition of the resources, the
+	// relation written between them and the subjects, and the permission checked and looked up.
+	ResourceType string
+	Relation     string
+	Permission   string
+
+	// SubjectType is the object definition of the subjects.
+	SubjectType string
+
+	// Resources and Subjects are the numbers of distinct resources and subjects named by calls.
+	Resources uint64
+	Subjects  uint64
+
+	// ZipfS is the exponent, greater than 1, of the zipfian distributions of the resources and
+	// subjects named by calls; higher values concentrate the calls on fewer of them.
+	ZipfS float64
+
+	// Mix is the relative weight of each operation among the calls.
+	Mix map[Operation]int
+
+	// RPS is the target rate of calls per second, and Concurrency the maximum number of calls in
+	// flight at once.
+	RPS         float64
+	Concurrency int
+
+	// Duration is how long calls are made for.
+	Duration time.Duration
+
+	// Seed seeds the choice of operations, resources and subjects.
+	Seed int64
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	switch {
+	case c.ResourceType == "" || c.Relation == "" || c.Permission == "" || c.SubjectType == "":
+		return errors.New("the resource type, relation, permission and subject type are required")
+	case c.Resources == 0 || c.Subjects == 0:
+		return errors.New("the numbers of resources and subjects must be greater than zero")
+	case c.ZipfS <= 1:
+		return fmt.Errorf("the zipfian exponent must be greater than 1, got %v", c.ZipfS)
+	case c.RPS <= 0:
+		return errors.New("the target rate must be greater than zero")
+	case c.Concurrency <= 0:
+		return errors.New("the concurrency must be greater than zero")
+	case c.Duration <= 0:
+		return errors.New("the duration must be greater than zero")
+	}
+
+	total := 0
+	for op, weight := range c.Mix {
+		if op != Check && op != Lookup && op != Write {
+			return fmt.Errorf("unknown operation %q in the mix; must be one of %v", op, Operations)
+		}
+		if weight < 0 {
+			return fmt.Errorf("the weight of %s in the mix must not be negative", op)
+		}
+		total += weight
+	}
+	if total == 0 {
+		return errors.New("the mix must give a positive weight to at least one operation")
+	}
+	return nil
+}
+
+// call is a call chosen by the generator.
+type call struct {
+	op       Operation
+	resource uint64
+	subject  uint64
+}
+
+// generator chooses the operation, resource and subject of each call; it is not safe for
+// concurrent use.
+type generator struct {
+	ops       []Operation
+	weights   []int
+	total     int
+	rand      *rand.Rand
+	resources *rand.Zipf
+	subjects  *rand.Zipf
+}
+
+func newGenerator(config Config) *generator {
+	r := rand.New(rand.NewSource(config.Seed))
+	g := &generator{
+		rand:      r,
+		resources: rand.NewZipf(r, config.ZipfS, 1, config.Resources-1),
+		subjects:  rand.NewZipf(r, config.ZipfS, 1, config.Subjects-1),
+	}
+	for _, op := range Operations {
+		if weight := config.Mix[op]; weight > 0 {
+			g.ops = append(g.ops, op)
+			g.weights = append(g.weights, weight)
+			g.total += weight
+		}
+	}
+	return g
+}
+
+func (g *generator) next() call {
+	choice := g.rand.Intn(g.total)
+	op := g.ops[len(g.ops)-1]
+	for i, weight := range g.weights {
+		if choice < weight {
+			op = g.ops[i]
+			break
+		}
+		choice -= weight
+	}
+	return call{op: op, resource: g.resources.Uint64(), subject: g.subjects.Uint64()}
+}
+
+// OperationReport is the outcome of the calls of an operation.
+type OperationReport struct {
+	Operation Operation
+	Calls     uint64
+	Errors    uint64
+
+	// FirstError is the first error returned by a call, if any.
+	FirstError string
+
+	P50, P90, P99, P999, Max time.Duration
+
+	// DispatchCount and CachedDispatchCount are the totals, over the successful calls, of the
+	// dispatches they made and of those answered from the dispatch cache, as reported in their
+	// response trailers.
+	DispatchCount       uint64
+	CachedDispatchCount uint64
+}
+
+// Report is the outcome of a benchmark.
+type Report struct {
+	Duration time.Duration
+
+	// Skipped is the number of calls which were not made as the maximum number of calls were in
+	// flight, such that the target rate was not reached.
+	Skipped uint64
+
+	Operations []OperationReport
+}
+
+// Write writes the report as a table, followed by a warning if the target rate was not reached.
+func (r Report) Write(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "%-8s %10s %8s %10s %10s %10s %10s %10s %10s %14s %10s\n",
+		"op", "calls", "errors", "rps", "p50", "p90", "p99", "p99.9", "max", "dispatches/op", "cached"); err != nil {
+		return err
+	}
+
+	for _, op := range r.Operations {
+		cached := "-"
+		if all := op.DispatchCount + op.CachedDispatchCount; all > 0 {
+			cached = fmt.Sprintf("%.1f%%", 100*float64(op.CachedDispatchCount)/float64(all))
+		}
+		dispatches := "-"
+		if succeeded := op.Calls - op.Errors; succeeded > 0 {
+			dispatches = fmt.Sprintf("%.2f", float64(op.DispatchCount)/float64(succeeded))
+		}
+
+		if _, err := fmt.Fprintf(w, "%-8s %10d %8d %10.1f %10s %10s %10s %10s %10s %14s %10s\n",
+			op.Operation, op.Calls, op.Errors, float64(op.Calls)/r.Duration.Seconds(),
+			roundLatency(op.P50), roundLatency(op.P90), roundLatency(op.P99), roundLatency(op.P999), roundLatency(op.Max),
+			dispatches, cached); err != nil {
+			return err
+		}
+	}
+
+	for _, op := range r.Operations {
+		if op.FirstError != "" {
+			if _, err := fmt.Fprintf(w, "first %s error: %s\n", op.Operation, op.FirstError); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Skipped > 0 {
+		if _, err := fmt.Fprintf(w, "%d calls were skipped as the maximum number of calls were in flight; raise the concurrency or lower the target rate\n", r.Skipped); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func roundLatency(latency time.Duration) time.Duration {
+	if latency >= time.Second {
+		return latency.Round(time.Millisecond)
+	}
+	return latency.Round(time.Microsecond)
+}
+
+// recorder accumulates the outcomes of the calls of an operation.
+type recorder struct {
+	sync.Mutex
+	latencies  histogram
+	calls      uint64
+	errors     uint64
+	firstError string
+	dispatches uint64
+	cached     uint64
+}
+
+func (r *recorder) record(latency time.Duration, trailer metadata.MD, err error) {
+	r.Lock()
+	defer r.Unlock()
+
+	r.calls++
+	r.latencies.record(latency)
+	if err != nil {
+		r.errors++
+		if r.firstError == "" {
+			r.firstError = err.Error()
+		}
+		return
+	}
+
+	if dispatches, err := responsemeta.GetIntResponseTrailerMetadata(trailer, responsemeta.DispatchedOperationsCount); err == nil {
+		r.dispatches += uint64(dispatches)
+	}
+	if cached, err := responsemeta.GetIntResponseTrailerMetadata(trailer, responsemeta.CachedOperationsCount); err == nil {
+		r.cached += uint64(cached)
+	}
+}
+
+func (r *recorder) report(op Operation) OperationReport {
+	r.Lock()
+	defer r.Unlock()
+
+	return OperationReport{
+		Operation:           op,
+		Calls:               r.calls,
+		Errors:              r.errors,
+		FirstError:          r.firstError,
+		P50:                 r.latencies.percentile(0.5),
+		P90:                 r.latencies.percentile(0.9),
+		P99:                 r.latencies.percentile(0.99),
+		P999:                r.latencies.percentile(0.999),
+		Max:                 r.latencies.max,
+		DispatchCount:       r.dispatches,
+		CachedDispatchCount: r.cached,
+	}
+}
+
+// Run makes calls with the client as configured until the duration elapses or the context is
+// canceled, and reports their outcomes. Calls are started at the target rate, and skipped when
+// as many calls as the concurrency are already in flight.
+func Run(ctx context.Context, client v1.PermissionsServiceClient, config Config) (Report, error) {
+	if err := config.Validate(); err != nil {
+		return Report{}, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, config.Duration)
+	defer cancel()
+
+	recorders := make(map[Operation]*recorder, len(Operations))
+	for _, op := range Operations {
+		recorders[op] = &recorder{}
+	}
+
+	start := time.Now()
+	var skipped uint64
+	// A burst of a hundredth of a second of calls lets the limiter catch up with the target rate
+	// after the coarse sleeps of high rates.
+	burst := int(config.RPS / 100)
+	if burst < 1 {
+		burst = 1
+	}
+	limiter := rate.NewLimiter(rate.Limit(config.RPS), burst)
+	generator := newGenerator(config)
+
+	inFlight := make(chan struct{}, config.Concurrency)
+	var calls sync.WaitGroup
+	for limiter.Wait(ctx) == nil {
+		select {
+		case inFlight <- struct{}{}:
+		default:
+			skipped++
+			continue
+		}
+
+		calls.Add(1)
+		go func(c call) {
+			defer func() {
+				<-inFlight
+				calls.Done()
+			}()
+
+			callStart := time.Now()
+			trailer, err := makeCall(ctx, client, config, c)
+			if ctx.Err() != nil && interrupted(err) {
+				// Calls interrupted by the end of the run are not recorded.
+				return
+			}
+			recorders[c.op].record(time.Since(callStart), trailer, err)
+		}(generator.next())
+	}
+	calls.Wait()
+
+	report := Report{Duration: time.Since(start), Skipped: skipped}
+	for _, op := range Operations {
+		if config.Mix[op] > 0 {
+			report.Operations = append(report.Operations, recorders[op].report(op))
+		}
+	}
+	return report, nil
+}
+
+func interrupted(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	code := status.Code(err)
+	return code == codes.Canceled || code == codes.DeadlineExceeded
+}
+
+func makeCall(ctx context.Context, client v1.PermissionsServiceClient, config Config, c call) (metadata.MD, error) {
+	resource := &v1.ObjectReference{ObjectType: config.ResourceType, ObjectId: "bench_" + strconv.FormatUint(c.resource, 10)}
+	subject := &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: config.SubjectType, ObjectId: "bench_" + strconv.FormatUint(c.subject, 10)}}
+
+	var trailer metadata.MD
+	switch c.op {
+	case Check:
+		_, err := client.CheckPermission(ctx, &v1.CheckPermissionRequest{
+			Resource:   resource,
+			Permission: config.Permission,
+			Subject:    subject,
+		}, grpc.Trailer(&trailer))
+		return trailer, err
+
+	case Lookup:
+		stream, err := client.LookupResources(ctx, &v1.LookupResourcesRequest{
+			ResourceObjectType: config.ResourceType,
+			Permission:         config.Permission,
+			Subject:            subject,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for {
+			if _, err := stream.Recv(); errors.Is(err, io.EOF) {
+				return stream.Trailer(), nil
+			} else if err != nil {
+				return nil, err
+			}
+		}
+
+	case Write:
+		_, err := client.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+			Updates: []*v1.RelationshipUpdate{{
+				Operation: v1.RelationshipUpdate_OPERATION_TOUCH,
+				Relationship: &v1.Relationship{
+					Resource: resource,
+					Relation: config.Relation,
+					Subject:  subject,
+				},
+			}},
+		}, grpc.Trailer(&trailer))
+		return trailer, err
+
+	default:
+		return nil, fmt.Errorf("unknown operation %q", c.op)
+	}
+}
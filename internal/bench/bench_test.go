@@ -0,0 +1,216 @@
+package bench
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/authzed/authzed-go/pkg/responsemeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestHistogramPercentile(t *testing.T) {
+	var h histogram
+	require.Equal(t, time.Duration(0), h.percentile(0.5))
+
+	for i := 1; i <= 1000; i++ {
+		h.record(time.Duration(i) * time.Millisecond)
+	}
+
+	testCases := []struct {
+		fraction float64
+		expected time.Duration
+	}{
+		{0.5, 500 * time.Millisecond},
+		{0.9, 900 * time.Millisecond},
+		{0.99, 990 * time.Millisecond},
+		{1, time.Second},
+	}
+	for _, tc := range testCases {
+		actual := h.percentile(tc.fraction)
+		require.GreaterOrEqual(t, actual, tc.expected)
+		require.LessOrEqual(t, float64(actual), float64(tc.expected)*histogramGrowth)
+	}
+	require.Equal(t, time.Second, h.max)
+
+	h.record(time.Hour)
+	require.Equal(t, time.Hour, h.percentile(1))
+}
+
+func validConfig() Config {
+	return Config{
+		ResourceType: "resource",
+		Relation:     "viewer",
+		Permission:   "view",
+		SubjectType:  "user",
+		Resources:    100,
+		Subjects:     10,
+		ZipfS:        1.1,
+		Mix:          map[Operation]int{Check: 80, Lookup: 10, Write: 10},
+		RPS:          1000,
+		Concurrency:  4,
+		Duration:     time.Second,
+		Seed:         1,
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name   string
+		modify func(*Config)
+		valid  bool
+	}{
+		{"valid", func(*Config) {}, true},
+		{"missing permission", func(c *Config) { c.Permission = "" }, false},
+		{"no resources", func(c *Config) { c.Resources = 0 }, false},
+		{"flat distribution", func(c *Config) { c.ZipfS = 1 }, false},
+		{"no rate", func(c *Config) { c.RPS = 0 }, false},
+		{"no concurrency", func(c *Config) { c.Concurrency = 0 }, false},
+		{"no duration", func(c *Config) { c.Duration = 0 }, false},
+		{"unknown operation", func(c *Config) { c.Mix["delete"] = 1 }, false},
+		{"negative weight", func(c *Config) { c.Mix[Write] = -1 }, false},
+		{"empty mix", func(c *Config) { c.Mix = map[Operation]int{Check: 0} }, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			config := validConfig()
+			tc.modify(&config)
+			if tc.valid {
+				require.NoError(t, config.Validate())
+			} else {
+				require.Error(t, config.Validate())
+			}
+		})
+	}
+}
+
+func TestGenerator(t *testing.T) {
+	config := validConfig()
+	config.Mix = map[Operation]int{Check: 3, Write: 1}
+	g := newGenerator(config)
+
+	counts := map[Operation]int{}
+	resources := map[uint64]int{}
+	for i := 0; i < 10_000; i++ {
+		c := g.next()
+		counts[c.op]++
+		resources[c.resource]++
+		require.Less(t, c.resource, config.Resources)
+		require.Less(t, c.subject, config.Subjects)
+	}
+
+	require.Zero(t, counts[Lookup])
+	require.InDelta(t, 7500, counts[Check], 300)
+	require.InDelta(t, 2500, counts[Write], 300)
+
+	// The zipfian distribution names the first resources far more often than the last ones.
+	require.Greater(t, resources[0], 10*resources[config.Resources-1])
+}
+
+type fakeClient struct {
+	v1.PermissionsServiceClient
+
+	sync.Mutex
+	checks int
+	writes int
+}
+
+func dispatchTrailer(opts []grpc.CallOption) {
+	for _, opt := range opts {
+		if trailer, ok := opt.(grpc.TrailerCallOption); ok {
+			*trailer.TrailerAddr = metadata.Pairs(
+				string(responsemeta.DispatchedOperationsCount), "3",
+				string(responsemeta.CachedOperationsCount), "1",
+			)
+		}
+	}
+}
+
+func (c *fakeClient) CheckPermission(_ context.Context, _ *v1.CheckPermissionRequest, opts ...grpc.CallOption) (*v1.CheckPermissionResponse, error) {
+	c.Lock()
+	c.checks++
+	c.Unlock()
+	dispatchTrailer(opts)
+	return &v1.CheckPermissionResponse{}, nil
+}
+
+func (c *fakeClient) WriteRelationships(_ context.Context, _ *v1.WriteRelationshipsRequest, _ ...grpc.CallOption) (*v1.WriteRelationshipsResponse, error) {
+	c.Lock()
+	c.writes++
+	c.Unlock()
+	return nil, errors.New("write failed")
+}
+
+type fakeLookupStream struct {
+	v1.PermissionsService_LookupResourcesClient
+	remaining int
+}
+
+func (s *fakeLookupStream) Recv() (*v1.LookupResourcesResponse, error) {
+	if s.remaining == 0 {
+		return nil, io.EOF
+	}
+	s.remaining--
+	return &v1.LookupResourcesResponse{}, nil
+}
+
+func (s *fakeLookupStream) Trailer() metadata.MD {
+	return metadata.Pairs(string(responsemeta.DispatchedOperationsCount), "5")
+}
+
+func (c *fakeClient) LookupResources(_ context.Context, _ *v1.LookupResourcesRequest, _ ...grpc.CallOption) (v1.PermissionsService_LookupResourcesClient, error) {
+	return &fakeLookupStream{remaining: 2}, nil
+}
+
+func TestRun(t *testing.T) {
+	config := validConfig()
+	config.Duration = 200 * time.Millisecond
+
+	client := &fakeClient{}
+	report, err := Run(context.Background(), client, config)
+	require.NoError(t, err)
+	require.Len(t, report.Operations, 3)
+
+	byOp := map[Operation]OperationReport{}
+	for _, op := range report.Operations {
+		byOp[op.Operation] = op
+	}
+
+	check := byOp[Check]
+	require.Equal(t, uint64(client.checks), check.Calls)
+	require.Positive(t, check.Calls)
+	require.Zero(t, check.Errors)
+	require.Equal(t, 3*check.Calls, check.DispatchCount)
+	require.Equal(t, check.Calls, check.CachedDispatchCount)
+	require.LessOrEqual(t, check.P50, check.P99)
+	require.LessOrEqual(t, check.P99, check.Max)
+
+	write := byOp[Write]
+	require.Equal(t, uint64(client.writes), write.Calls)
+	require.Equal(t, write.Calls, write.Errors)
+	require.Equal(t, "write failed", write.FirstError)
+	require.Zero(t, write.DispatchCount)
+
+	lookup := byOp[Lookup]
+	require.Equal(t, 5*lookup.Calls, lookup.DispatchCount)
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out))
+	require.Contains(t, out.String(), "first write error: write failed")
+}
+
+func TestRunInvalidConfig(t *testing.T) {
+	config := validConfig()
+	config.RPS = 0
+	_, err := Run(context.Background(), &fakeClient{}, config)
+	require.Error(t, err)
+}
@@ -0,0 +1,77 @@
+package bench
+
+import (
+	"math"
+	"time"
+)
+
+const (
+	// histogramMin is the lowest latency distinguished by a histogram; lower latencies are
+	// recorded in its first bucket.
+	histogramMin = time.Microsecond
+
+	// histogramGrowth is the ratio between the bounds of consecutive buckets of a histogram,
+	// which bounds the relative error of its percentiles.
+	histogramGrowth = 1.02
+
+	// histogramBuckets is the number of buckets of a histogram, whose last bucket holds the
+	// latencies beyond about 100s.
+	histogramBuckets = 930
+)
+
+// histogram records latencies in buckets of exponentially growing bounds, so that percentiles can
+// be computed with a bounded relative error over runs of any length in constant memory.
+type histogram struct {
+	counts [histogramBuckets]uint64
+	total  uint64
+	max    time.Duration
+}
+
+func bucketOf(latency time.Duration) int {
+	if latency <= histogramMin {
+		return 0
+	}
+	bucket := int(math.Ceil(math.Log(float64(latency)/float64(histogramMin)) / math.Log(histogramGrowth)))
+	if bucket >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+	return bucket
+}
+
+// upperBound returns the upper bound of the latencies recorded in the bucket.
+func upperBound(bucket int) time.Duration {
+	return time.Duration(float64(histogramMin) * math.Pow(histogramGrowth, float64(bucket)))
+}
+
+func (h *histogram) record(latency time.Duration) {
+	h.counts[bucketOf(latency)]++
+	h.total++
+	if latency > h.max {
+		h.max = latency
+	}
+}
+
+// percentile returns the upper bound of the latencies of the given fraction of the recorded
+// calls, such as 0.99 for the 99th percentile, capped at the highest recorded latency.
+func (h *histogram) percentile(fraction float64) time.Duration {
+	if h.total == 0 {
+		return 0
+	}
+
+	rank := uint64(math.Ceil(fraction * float64(h.total)))
+	if rank == 0 {
+		rank = 1
+	}
+
+	var seen uint64
+	for bucket, count := range h.counts {
+		seen += count
+		if seen >= rank {
+			if bound := upperBound(bucket); bucket < histogramBuckets-1 && bound < h.max {
+				return bound
+			}
+			return h.max
+		}
+	}
+	return h.max
+}
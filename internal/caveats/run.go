@@ -3,6 +3,7 @@ package caveats
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"golang.org/x/exp/maps"
@@ -10,6 +11,7 @@ import (
 	"github.com/authzed/spicedb/pkg/caveats"
 	"github.com/authzed/spicedb/pkg/datastore"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/util"
 )
 
 // RunCaveatExpressionDebugOption are the options for running caveat expression evaluation
@@ -33,7 +35,24 @@ func RunCaveatExpression(
 	debugOption RunCaveatExpressionDebugOption,
 ) (ExpressionResult, error) {
 	env := caveats.NewEnvironment()
-	return runExpression(ctx, env, expr, context, reader, debugOption)
+	return runExpression(ctx, env, expr, context, reader, debugOption, false)
+}
+
+// RunCaveatExpressionForAllMissingVarNames runs a caveat expression over the given context and
+// returns the result, like RunCaveatExpression. However, rather than stopping at the first
+// partially applied caveat, the remaining caveats of the expression are still evaluated, so that
+// the MissingVarNames of a partial result are the parameters missing from every caveat on which the
+// result depends. As a consequence, an expression whose value is determined by the caveats which
+// could be fully applied, such as `partial && false`, is not partial.
+func RunCaveatExpressionForAllMissingVarNames(
+	ctx context.Context,
+	expr *v1.CaveatExpression,
+	context map[string]any,
+	reader datastore.CaveatReader,
+	debugOption RunCaveatExpressionDebugOption,
+) (ExpressionResult, error) {
+	env := caveats.NewEnvironment()
+	return runExpression(ctx, env, expr, context, reader, debugOption, true)
 }
 
 // ExpressionResult is the result of a caveat expression being run.
@@ -80,6 +99,34 @@ func (sr syntheticResult) ExpressionString() (string, error) {
 	return sr.exprString, nil
 }
 
+// partialResult is the partial result of an expression with every missing parameter of its
+// partially applied caveats.
+type partialResult struct {
+	missingVarNames []string
+	contextValues   map[string]any
+	exprString      string
+}
+
+func (pr partialResult) Value() bool {
+	return false
+}
+
+func (pr partialResult) IsPartial() bool {
+	return true
+}
+
+func (pr partialResult) MissingVarNames() ([]string, error) {
+	return pr.missingVarNames, nil
+}
+
+func (pr partialResult) ContextValues() map[string]any {
+	return pr.contextValues
+}
+
+func (pr partialResult) ExpressionString() (string, error) {
+	return pr.exprString, nil
+}
+
 func runExpression(
 	ctx context.Context,
 	env *caveats.Environment,
@@ -87,6 +134,7 @@ func runExpression(
 	context map[string]any,
 	reader datastore.CaveatReader,
 	debugOption RunCaveatExpressionDebugOption,
+	allMissingVarNames bool,
 ) (ExpressionResult, error) {
 	if expr.GetCaveat() != nil {
 		caveat, _, err := reader.ReadCaveatByName(ctx, expr.GetCaveat().CaveatName)
@@ -123,6 +171,24 @@ func runExpression(
 			return nil, err
 		}
 
+		if allMissingVarNames && result.IsPartial() {
+			// The evaluation stops at the first missing parameter, so the missing parameters are
+			// instead those referenced by the caveat and absent from the context.
+			var missingVarNames []string
+			for _, name := range compiled.ReferencedParameters(maps.Keys(caveat.ParameterTypes)).AsSlice() {
+				if _, ok := untypedFullContext[name]; !ok {
+					missingVarNames = append(missingVarNames, name)
+				}
+			}
+			sort.Strings(missingVarNames)
+
+			exprString, err := result.ExpressionString()
+			if err != nil {
+				return nil, err
+			}
+			return partialResult{missingVarNames, result.ContextValues(), exprString}, nil
+		}
+
 		return result, nil
 	}
 
@@ -151,14 +217,20 @@ func runExpression(
 		}
 	}
 
+	var partialChildren []ExpressionResult
 	for _, child := range cop.Children {
-		childResult, err := runExpression(ctx, env, child, context, reader, debugOption)
+		childResult, err := runExpression(ctx, env, child, context, reader, debugOption, allMissingVarNames)
 		if err != nil {
 			return nil, err
 		}
 
 		if childResult.IsPartial() {
-			return childResult, nil
+			if !allMissingVarNames || cop.Op == v1.CaveatOperation_NOT {
+				return childResult, nil
+			}
+
+			partialChildren = append(partialChildren, childResult)
+			continue
 		}
 
 		switch cop.Op {
@@ -214,6 +286,22 @@ func runExpression(
 		}
 	}
 
+	if len(partialChildren) > 0 {
+		missingVarNames := util.NewSet[string]()
+		for _, partialChild := range partialChildren {
+			childMissingVarNames, err := partialChild.MissingVarNames()
+			if err != nil {
+				return nil, err
+			}
+			missingVarNames.Extend(childMissingVarNames)
+			contextValues = combineMaps(contextValues, partialChild.ContextValues())
+		}
+
+		sorted := missingVarNames.AsSlice()
+		sort.Strings(sorted)
+		return partialResult{sorted, contextValues, buildExprString()}, nil
+	}
+
 	return syntheticResult{boolResult, contextValues, buildExprString()}, nil
 }
 
@@ -201,3 +201,145 @@ func TestRunCaveatExpressions(t *testing.T) {
 		})
 	}
 }
+
+func TestRunCaveatExpressionsForAllMissingVarNames(t *testing.T) {
+	tcs := []struct {
+		name                    string
+		expression              *v1.CaveatExpression
+		context                 map[string]any
+		expectedValue           bool
+		expectedMissingVarNames []string
+	}{
+		{
+			"fully applied",
+			caveatexpr("firstCaveat"),
+			map[string]any{
+				"first": "42",
+			},
+			true,
+			nil,
+		},
+		{
+			"every parameter of a caveat",
+			caveatexpr("multiCaveat"),
+			map[string]any{},
+			false,
+			[]string{"fifth", "fourth"},
+		},
+		{
+			"remaining parameter of a caveat",
+			caveatexpr("multiCaveat"),
+			map[string]any{
+				"fourth": "1",
+			},
+			false,
+			[]string{"fifth"},
+		},
+		{
+			"every caveat of nested ands",
+			caveatAnd(
+				caveatAnd(
+					caveatexpr("firstCaveat"),
+					caveatexpr("secondCaveat"),
+				),
+				caveatexpr("multiCaveat"),
+			),
+			map[string]any{
+				"fourth": "1",
+			},
+			false,
+			[]string{"fifth", "first", "second"},
+		},
+		{
+			"every caveat of an or",
+			caveatOr(
+				caveatexpr("firstCaveat"),
+				caveatexpr("secondCaveat"),
+			),
+			map[string]any{},
+			false,
+			[]string{"first", "second"},
+		},
+		{
+			"and determined by an applied caveat",
+			caveatAnd(
+				caveatexpr("firstCaveat"),
+				caveatexpr("secondCaveat"),
+			),
+			map[string]any{
+				"second": "hi",
+			},
+			false,
+			nil,
+		},
+		{
+			"or determined by an applied caveat",
+			caveatOr(
+				caveatexpr("firstCaveat"),
+				caveatexpr("secondCaveat"),
+			),
+			map[string]any{
+				"second": "hello",
+			},
+			true,
+			nil,
+		},
+		{
+			"nested",
+			caveatAnd(
+				caveatOr(
+					caveatexpr("firstCaveat"),
+					caveatexpr("secondCaveat"),
+				),
+				caveatInvert(
+					caveatexpr("thirdCaveat"),
+				),
+			),
+			map[string]any{
+				"second": "hi",
+			},
+			false,
+			[]string{"first", "third"},
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := require.New(t)
+
+			rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			req.NoError(err)
+
+			ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+				caveat firstCaveat(first int) {
+					first == 42
+				}
+
+				caveat secondCaveat(second string) {
+					second == 'hello'
+				}
+
+				caveat thirdCaveat(third bool) {
+					third
+				}
+
+				caveat multiCaveat(fourth int, fifth int) {
+					fourth == 1 && fifth == 2
+				}
+				`, nil, req)
+			headRevision, err := ds.HeadRevision(context.Background())
+			req.NoError(err)
+
+			result, err := caveats.RunCaveatExpressionForAllMissingVarNames(context.Background(), tc.expression, tc.context, ds.SnapshotReader(headRevision), caveats.RunCaveatExpressionNoDebugging)
+			req.NoError(err)
+			req.Equal(tc.expectedValue, result.Value())
+			req.Equal(tc.expectedMissingVarNames != nil, result.IsPartial())
+			if result.IsPartial() {
+				missingVarNames, err := result.MissingVarNames()
+				req.NoError(err)
+				req.Equal(tc.expectedMissingVarNames, missingVarNames)
+			}
+		})
+	}
+}
@@ -0,0 +1,295 @@
+// Package changestream publishes the changes found in the datastore's Watch stream to external
+// systems, so that consumers can follow them without running a Watch client of their own.
+package changestream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/protobuf/encoding/protojson"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+var (
+	publishedEventsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "changestream",
+		Name:      "published_events_total",
+		Help:      "The number of change events published, by publisher.",
+	}, []string{"publisher"})
+
+	publishErrorsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "changestream",
+		Name:      "publish_errors_total",
+		Help:      "The number of failed attempts to publish change events, by publisher.",
+	}, []string{"publisher"})
+)
+
+// checkpointInterval is the interval at which checkpoints are requested from the Watch stream,
+// so that the stored checkpoint advances even when no changes are published.
+const checkpointInterval = 30 * time.Second
+
+// Event is a single change to a relationship or to a definition of the schema, published to the
+// external system.
+type Event struct {
+	// Revision is the revision at which the change occurred.
+	Revision datastore.Revision
+
+	// Update is the change to the relationship, if the event is for a relationship.
+	Update *core.RelationTupleUpdate
+
+	// SchemaChange is the change to the definition, if the event is for a definition.
+	SchemaChange *datastore.SchemaChange
+}
+
+// PartitionKey returns the key by which the event is partitioned. Events for definitions are
+// partitioned by the name of the definition.
+func (e Event) PartitionKey(partitionBy PartitionBy) string {
+	if e.SchemaChange != nil {
+		return e.SchemaChange.Name()
+	}
+
+	resource := e.Update.Tuple.ResourceAndRelation
+	if partitionBy == PartitionByResource {
+		return resource.Namespace + ":" + resource.ObjectId
+	}
+	return resource.Namespace
+}
+
+// ID returns an identifier of the event, which is the same whenever the event is published
+// again.
+func (e Event) ID() string {
+	if e.SchemaChange != nil {
+		return fmt.Sprintf("%s:schema:%s", e.Revision, e.SchemaChange.Name())
+	}
+	return fmt.Sprintf("%s:%s:%s", e.Revision, e.Update.Operation, tuple.String(e.Update.Tuple))
+}
+
+// schemaChangeJSON is the JSON encoding of a change to a definition, with the definitions in
+// schema language.
+type schemaChangeJSON struct {
+	Name          string  `json:"name"`
+	OldDefinition *string `json:"oldDefinition"`
+	NewDefinition *string `json:"newDefinition"`
+}
+
+// MarshalJSON encodes the event with its revision as a ZedToken and either its update as an API
+// RelationshipUpdate or its schema change in schema language, such as:
+//
+//	{"revision": "GhUKEzE2NzA...", "update": {"operation": "OPERATION_TOUCH", "relationship": {...}}}
+//	{"revision": "GhUKEzE2NzA...", "schemaChange": {"name": "user", "oldDefinition": null, "newDefinition": "definition user {}"}}
+func (e Event) MarshalJSON() ([]byte, error) {
+	revision := zedtoken.NewFromRevision(e.Revision).Token
+	if e.SchemaChange != nil {
+		oldDefinition, err := definitionSource(e.SchemaChange.OldDefinition)
+		if err != nil {
+			return nil, err
+		}
+
+		newDefinition, err := definitionSource(e.SchemaChange.NewDefinition)
+		if err != nil {
+			return nil, err
+		}
+
+		return json.Marshal(struct {
+			Revision     string           `json:"revision"`
+			SchemaChange schemaChangeJSON `json:"schemaChange"`
+		}{revision, schemaChangeJSON{e.SchemaChange.Name(), oldDefinition, newDefinition}})
+	}
+
+	update, err := protojson.Marshal(tuple.UpdateToRelationshipUpdate(e.Update))
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(struct {
+		Revision string          `json:"revision"`
+		Update   json.RawMessage `json:"update"`
+	}{revision, update})
+}
+
+func definitionSource(def datastore.SchemaDefinition) (*string, error) {
+	var source string
+	var ok bool
+	switch typed := def.(type) {
+	case nil:
+		return nil, nil
+	case *core.NamespaceDefinition:
+		source, ok = generator.GenerateSource(typed)
+	case *core.CaveatDefinition:
+		source, ok = generator.GenerateCaveatSource(typed)
+	default:
+		return nil, fmt.Errorf("unknown definition type %T", def)
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("unable to generate source for definition %s", def.GetName())
+	}
+	return &source, nil
+}
+
+// PartitionBy is the part of a change by which its events are partitioned. Events with the same
+// partition key are always published in revision order.
+type PartitionBy int
+
+const (
+	// PartitionByNamespace partitions events by the type of their resource.
+	PartitionByNamespace PartitionBy = iota
+
+	// PartitionByResource partitions events by their resource.
+	PartitionByResource
+)
+
+// PartitionByNames are the names by which the partitioning of events can be configured.
+var PartitionByNames = map[string]PartitionBy{
+	"namespace": PartitionByNamespace,
+	"resource":  PartitionByResource,
+}
+
+// Publisher publishes change events to an external system.
+type Publisher interface {
+	// Name is the name of the publisher, used in logs and metrics.
+	Name() string
+
+	// Publish publishes the events, all of which occurred at the same revision, returning only
+	// once all have been acknowledged by the external system.
+	Publish(ctx context.Context, events []Event) error
+
+	// Close closes the publisher.
+	Close() error
+}
+
+// Streamer follows the Watch stream of a datastore and publishes all changes to a Publisher.
+// Delivery is at least once: the revision through which changes have been published is stored
+// in a checkpoint, from which the Watch stream is resumed on restart, and changes published
+// after the checkpoint was last stored are published again.
+type Streamer struct {
+	ds          datastore.Datastore
+	publisher   Publisher
+	checkpoints CheckpointStore
+	content     datastore.WatchContent
+	retryDelay  time.Duration
+}
+
+// NewStreamer creates a new streamer publishing the given kinds of changes, which waits for
+// retryDelay before restarting after errors.
+func NewStreamer(ds datastore.Datastore, publisher Publisher, checkpoints CheckpointStore, content datastore.WatchContent, retryDelay time.Duration) *Streamer {
+	return &Streamer{ds: ds, publisher: publisher, checkpoints: checkpoints, content: content, retryDelay: retryDelay}
+}
+
+// Start publishes changes until the context is canceled, restarting from the last checkpoint
+// whenever publishing or the Watch stream fails.
+func (s *Streamer) Start(ctx context.Context) error {
+	log.Ctx(ctx).Info().
+		Str("publisher", s.publisher.Name()).
+		Msg("change stream worker started")
+
+	defer func() {
+		if err := s.publisher.Close(); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("publisher", s.publisher.Name()).Msg("error closing change stream publisher")
+		}
+	}()
+
+	for {
+		err := s.follow(ctx)
+		if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			log.Ctx(ctx).Info().
+				Str("publisher", s.publisher.Name()).
+				Msg("shutting down change stream worker")
+			return ctx.Err()
+		}
+
+		log.Ctx(ctx).Warn().Err(err).
+			Str("publisher", s.publisher.Name()).
+			Msg("error publishing changes; restarting from last checkpoint")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(s.retryDelay):
+		}
+	}
+}
+
+// follow publishes the changes following the last checkpoint, returning on the first error.
+func (s *Streamer) follow(ctx context.Context) error {
+	revision, err := s.startRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changesChan, errChan := s.ds.Watch(watchCtx, revision, datastore.WatchOptions{
+		Content:            s.content,
+		CheckpointInterval: checkpointInterval,
+	})
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err := <-errChan:
+			return err
+
+		case revisionChanges, ok := <-changesChan:
+			if !ok {
+				return errors.New("watch stream closed")
+			}
+
+			if err := s.publish(ctx, revisionChanges); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *Streamer) startRevision(ctx context.Context) (datastore.Revision, error) {
+	token, err := s.checkpoints.Load(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load checkpoint: %w", err)
+	}
+
+	if token == "" {
+		return s.ds.HeadRevision(ctx)
+	}
+
+	return zedtoken.DecodeRevision(&v1.ZedToken{Token: token}, s.ds)
+}
+
+func (s *Streamer) publish(ctx context.Context, revisionChanges *datastore.RevisionChanges) error {
+	events := make([]Event, 0, len(revisionChanges.Changes)+len(revisionChanges.SchemaChanges))
+	for _, schemaChange := range revisionChanges.SchemaChanges {
+		events = append(events, Event{Revision: revisionChanges.Revision, SchemaChange: schemaChange})
+	}
+	for _, update := range revisionChanges.Changes {
+		events = append(events, Event{Revision: revisionChanges.Revision, Update: update})
+	}
+
+	if len(events) > 0 {
+		if err := s.publisher.Publish(ctx, events); err != nil {
+			publishErrorsCounter.WithLabelValues(s.publisher.Name()).Inc()
+			return fmt.Errorf("unable to publish changes: %w", err)
+		}
+		publishedEventsCounter.WithLabelValues(s.publisher.Name()).Add(float64(len(events)))
+	}
+
+	if err := s.checkpoints.Save(ctx, zedtoken.NewFromRevision(revisionChanges.Revision).Token); err != nil {
+		return fmt.Errorf("unable to save checkpoint: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package changestream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+type fakePublisher struct {
+	sync.Mutex
+	failures  int
+	published []string
+}
+
+func (fp *fakePublisher) Name() string { return "fake" }
+
+func (fp *fakePublisher) Publish(_ context.Context, events []Event) error {
+	fp.Lock()
+	defer fp.Unlock()
+
+	if fp.failures > 0 {
+		fp.failures--
+		return errors.New("publishing failed")
+	}
+
+	for _, event := range events {
+		fp.published = append(fp.published, tuple.String(event.Update.Tuple))
+	}
+	return nil
+}
+
+func (fp *fakePublisher) Close() error { return nil }
+
+func (fp *fakePublisher) Published() []string {
+	fp.Lock()
+	defer fp.Unlock()
+	return append([]string(nil), fp.published...)
+}
+
+func TestStreamer(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, revision := testfixtures.StandardDatastoreWithSchema(rawDS, require)
+
+	// The first attempt to publish fails, so the changes are published again from the checkpoint.
+	publisher := &fakePublisher{failures: 1}
+	checkpoints := NewMemoryCheckpointStore()
+	require.NoError(checkpoints.Save(context.Background(), zedtoken.NewFromRevision(revision).Token))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- NewStreamer(ds, publisher, checkpoints, datastore.WatchRelationships, 10*time.Millisecond).Start(ctx)
+	}()
+
+	written, err := common.WriteTuples(context.Background(), ds, core.RelationTupleUpdate_CREATE,
+		tuple.MustParse("document:firstdoc#viewer@user:tom"),
+		tuple.MustParse("document:seconddoc#viewer@user:fred"),
+	)
+	require.NoError(err)
+
+	require.Eventually(func() bool { return len(publisher.Published()) == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.ElementsMatch([]string{
+		"document:firstdoc#viewer@user:tom",
+		"document:seconddoc#viewer@user:fred",
+	}, publisher.Published())
+
+	require.Eventually(func() bool {
+		token, err := checkpoints.Load(context.Background())
+		return err == nil && token == zedtoken.NewFromRevision(written).Token
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(<-done, context.Canceled)
+}
+
+func TestFileCheckpointStore(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	store := NewFileCheckpointStore(filepath.Join(t.TempDir(), "checkpoint"))
+
+	token, err := store.Load(ctx)
+	require.NoError(err)
+	require.Empty(token)
+
+	require.NoError(store.Save(ctx, "first"))
+	require.NoError(store.Save(ctx, "second"))
+
+	token, err = store.Load(ctx)
+	require.NoError(err)
+	require.Equal("second", token)
+}
+
+func TestEvent(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	revision, err := rawDS.HeadRevision(context.Background())
+	require.NoError(err)
+
+	event := Event{Revision: revision, Update: tuple.Delete(tuple.MustParse("document:firstdoc#viewer@user:tom"))}
+	require.Equal("document", event.PartitionKey(PartitionByNamespace))
+	require.Equal("document:firstdoc", event.PartitionKey(PartitionByResource))
+
+	encoded, err := json.Marshal(event)
+	require.NoError(err)
+	require.JSONEq(`{
+		"revision": "`+zedtoken.NewFromRevision(revision).Token+`",
+		"update": {
+			"operation": "OPERATION_DELETE",
+			"relationship": {
+				"resource": {"objectType": "document", "objectId": "firstdoc"},
+				"relation": "viewer",
+				"subject": {"object": {"objectType": "user", "objectId": "tom"}}
+			}
+		}
+	}`, string(encoded))
+	require.Equal(revision.String()+":DELETE:document:firstdoc#viewer@user:tom", event.ID())
+
+	schemaEvent := Event{Revision: revision, SchemaChange: &datastore.SchemaChange{
+		OldDefinition: &core.NamespaceDefinition{Name: "user"},
+	}}
+	require.Equal("user", schemaEvent.PartitionKey(PartitionByResource))
+	require.Equal(revision.String()+":schema:user", schemaEvent.ID())
+
+	encoded, err = json.Marshal(schemaEvent)
+	require.NoError(err)
+	require.JSONEq(`{
+		"revision": "`+zedtoken.NewFromRevision(revision).Token+`",
+		"schemaChange": {"name": "user", "oldDefinition": "definition user {}", "newDefinition": null}
+	}`, string(encoded))
+}
@@ -0,0 +1,86 @@
+package changestream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// CheckpointStore stores the ZedToken of the revision through which changes have been published.
+type CheckpointStore interface {
+	// Load returns the stored ZedToken, or the empty string if none has been stored.
+	Load(ctx context.Context) (string, error)
+
+	// Save stores the ZedToken.
+	Save(ctx context.Context, token string) error
+}
+
+// NewMemoryCheckpointStore creates a checkpoint store which keeps the checkpoint in memory, so
+// that publishing restarts from the head revision whenever the process does.
+func NewMemoryCheckpointStore() CheckpointStore {
+	return &memoryCheckpointStore{}
+}
+
+type memoryCheckpointStore struct {
+	sync.Mutex
+	token string
+}
+
+func (mcs *memoryCheckpointStore) Load(_ context.Context) (string, error) {
+	mcs.Lock()
+	defer mcs.Unlock()
+	return mcs.token, nil
+}
+
+func (mcs *memoryCheckpointStore) Save(_ context.Context, token string) error {
+	mcs.Lock()
+	defer mcs.Unlock()
+	mcs.token = token
+	return nil
+}
+
+// NewFileCheckpointStore creates a checkpoint store which keeps the checkpoint in the file at the
+// given path.
+func NewFileCheckpointStore(path string) CheckpointStore {
+	return &fileCheckpointStore{path: path}
+}
+
+type fileCheckpointStore struct {
+	path string
+}
+
+func (fcs *fileCheckpointStore) Load(_ context.Context) (string, error) {
+	contents, err := os.ReadFile(fcs.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(contents)), nil
+}
+
+// Save writes the checkpoint to a temporary file which then replaces the checkpoint file, so that
+// the checkpoint file is never left partially written.
+func (fcs *fileCheckpointStore) Save(_ context.Context, token string) error {
+	temp, err := os.CreateTemp(filepath.Dir(fcs.path), filepath.Base(fcs.path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to create checkpoint file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.WriteString(token + "\n"); err != nil {
+		temp.Close()
+		return fmt.Errorf("unable to write checkpoint file: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("unable to write checkpoint file: %w", err)
+	}
+
+	return os.Rename(temp.Name(), fcs.path)
+}
@@ -0,0 +1,110 @@
+package changestream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+const kafkaClientID = "spicedb"
+
+// KafkaConfig is the configuration of a KafkaPublisher.
+type KafkaConfig struct {
+	// Brokers are the addresses of the brokers from which the metadata of the cluster is first
+	// loaded.
+	Brokers []string
+
+	// Topic is the topic to which events are published. It must already exist, unless the
+	// brokers create topics automatically.
+	Topic string
+
+	// PartitionBy is the part of a change by which its events are assigned partitions.
+	PartitionBy PartitionBy
+
+	// Timeout is the timeout for connecting to brokers and for producing each batch of messages,
+	// including the retries of the requests which failed.
+	Timeout time.Duration
+}
+
+// KafkaPublisher publishes events as JSON messages to a Kafka topic, keyed by their partition
+// key. Messages are assigned partitions by the murmur2 hash of their key, as by the Java client,
+// and are only considered published once acknowledged by all in-sync replicas.
+type KafkaPublisher struct {
+	config KafkaConfig
+	client *kgo.Client
+}
+
+// NewKafkaPublisher creates a new publisher for Kafka. Connections are only made on first
+// publish.
+func NewKafkaPublisher(config KafkaConfig) (*KafkaPublisher, error) {
+	if len(config.Brokers) == 0 {
+		return nil, errors.New("at least one kafka broker is required")
+	}
+	if config.Topic == "" {
+		return nil, errors.New("a kafka topic is required")
+	}
+
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(config.Brokers...),
+		kgo.ClientID(kafkaClientID),
+		kgo.DefaultProduceTopic(config.Topic),
+		kgo.RequiredAcks(kgo.AllISRAcks()),
+		kgo.RecordPartitioner(kgo.StickyKeyPartitioner(nil)),
+		kgo.DialTimeout(config.Timeout),
+		kgo.RecordDeliveryTimeout(config.Timeout),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("unable to create kafka client: %w", err)
+	}
+
+	return &KafkaPublisher{config: config, client: client}, nil
+}
+
+func (kp *KafkaPublisher) Name() string {
+	return "kafka"
+}
+
+func (kp *KafkaPublisher) Publish(ctx context.Context, events []Event) error {
+	records := make([]*kgo.Record, 0, len(events))
+	for _, event := range events {
+		value, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("unable to encode event: %w", err)
+		}
+		records = append(records, &kgo.Record{Key: []byte(event.PartitionKey(kp.config.PartitionBy)), Value: value})
+	}
+	return kp.produce(ctx, records)
+}
+
+// PublishMessages publishes messages with the given keys and values to the topic, partitioned by
+// key. The partitioning of the publisher only applies to events.
+func (kp *KafkaPublisher) PublishMessages(ctx context.Context, keys, values [][]byte) error {
+	if len(keys) != len(values) {
+		return fmt.Errorf("got %d keys for %d values", len(keys), len(values))
+	}
+
+	records := make([]*kgo.Record, 0, len(keys))
+	for index := range keys {
+		records = append(records, &kgo.Record{Key: keys[index], Value: values[index]})
+	}
+	return kp.produce(ctx, records)
+}
+
+// produce produces the records and waits for all of them to be acknowledged. The client retries
+// failed requests itself, reloading the metadata of the topic when leadership has moved, until
+// the timeout of the publisher.
+func (kp *KafkaPublisher) produce(ctx context.Context, records []*kgo.Record) error {
+	if err := kp.client.ProduceSync(ctx, records...).FirstErr(); err != nil {
+		return fmt.Errorf("unable to produce to kafka topic %s: %w", kp.config.Topic, err)
+	}
+	return nil
+}
+
+func (kp *KafkaPublisher) Close() error {
+	kp.client.Close()
+	return nil
+}
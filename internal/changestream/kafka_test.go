@@ -0,0 +1,334 @@
+package changestream
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+	"github.com/twmb/franz-go/pkg/kmsg"
+
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// fakeKafkaRecord is a record produced to a fakeKafkaBroker.
+type fakeKafkaRecord struct {
+	key   []byte
+	value []byte
+}
+
+// fakeKafkaBroker is a single Kafka broker leading all partitions of a topic, which records the
+// records produced to each partition. It only advertises the versions of the requests it serves.
+type fakeKafkaBroker struct {
+	sync.Mutex
+	listener   net.Listener
+	topic      string
+	partitions int32
+	errorCode  int16
+	produced   map[int32][]fakeKafkaRecord
+}
+
+func newFakeKafkaBroker(t *testing.T, topic string, partitions int32) *fakeKafkaBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	broker := &fakeKafkaBroker{listener: listener, topic: topic, partitions: partitions, produced: map[int32][]fakeKafkaRecord{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go broker.serve(t, conn)
+		}
+	}()
+	return broker
+}
+
+func (fkb *fakeKafkaBroker) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		request := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, request); err != nil {
+			return
+		}
+
+		// The request header holds the key and version of the request, the correlation ID and
+		// the client ID, followed by tagged fields for flexible versions.
+		apiKey := int16(binary.BigEndian.Uint16(request[0:]))
+		apiVersion := int16(binary.BigEndian.Uint16(request[2:]))
+		correlationID := binary.BigEndian.Uint32(request[4:])
+		clientIDLength := int(int16(binary.BigEndian.Uint16(request[8:])))
+		require.Equal(t, kafkaClientID, string(request[10:10+clientIDLength]))
+		body := request[10+clientIDLength:]
+
+		req := kmsg.RequestForKey(apiKey)
+		if req == nil {
+			t.Errorf("unexpected api key %d", apiKey)
+			return
+		}
+		req.SetVersion(apiVersion)
+		if req.IsFlexible() {
+			body = body[1:]
+		}
+		require.NoError(t, req.ReadFrom(body))
+
+		var resp kmsg.Response
+		switch req := req.(type) {
+		case *kmsg.ApiVersionsRequest:
+			resp = fkb.apiVersionsResponse()
+		case *kmsg.MetadataRequest:
+			resp = fkb.metadataResponse()
+		case *kmsg.InitProducerIDRequest:
+			initResp := kmsg.NewPtrInitProducerIDResponse()
+			initResp.ProducerID = 1
+			resp = initResp
+		case *kmsg.ProduceRequest:
+			resp = fkb.produceResponse(t, req)
+		default:
+			t.Errorf("unexpected request %T", req)
+			return
+		}
+		resp.SetVersion(apiVersion)
+
+		response := binary.BigEndian.AppendUint32(make([]byte, 4), correlationID)
+		// The response header of ApiVersions never has tagged fields, so that clients can read
+		// it whatever the version.
+		if resp.IsFlexible() && apiKey != kmsg.ApiVersions.Int16() {
+			response = append(response, 0)
+		}
+		response = resp.AppendTo(response)
+		binary.BigEndian.PutUint32(response, uint32(len(response)-4))
+		if _, err := conn.Write(response); err != nil {
+			return
+		}
+	}
+}
+
+func (fkb *fakeKafkaBroker) apiVersionsResponse() kmsg.Response {
+	resp := kmsg.NewPtrApiVersionsResponse()
+	for _, version := range []struct {
+		key        kmsg.Key
+		minVersion int16
+		maxVersion int16
+	}{
+		{kmsg.ApiVersions, 0, 3},
+		{kmsg.Metadata, 1, 7},
+		{kmsg.Produce, 3, 7},
+		{kmsg.InitProducerID, 0, 1},
+	} {
+		key := kmsg.NewApiVersionsResponseApiKey()
+		key.ApiKey = version.key.Int16()
+		key.MinVersion = version.minVersion
+		key.MaxVersion = version.maxVersion
+		resp.ApiKeys = append(resp.ApiKeys, key)
+	}
+	return resp
+}
+
+func (fkb *fakeKafkaBroker) metadataResponse() kmsg.Response {
+	host, port, _ := net.SplitHostPort(fkb.listener.Addr().String())
+	portNumber, _ := strconv.Atoi(port)
+
+	resp := kmsg.NewPtrMetadataResponse()
+	broker := kmsg.NewMetadataResponseBroker()
+	broker.NodeID = 1
+	broker.Host = host
+	broker.Port = int32(portNumber)
+	resp.Brokers = append(resp.Brokers, broker)
+	resp.ControllerID = 1
+
+	topic := kmsg.NewMetadataResponseTopic()
+	topic.Topic = kmsg.StringPtr(fkb.topic)
+	for partition := int32(0); partition < fkb.partitions; partition++ {
+		partitionMetadata := kmsg.NewMetadataResponseTopicPartition()
+		partitionMetadata.Partition = partition
+		partitionMetadata.Leader = 1
+		partitionMetadata.Replicas = []int32{1}
+		partitionMetadata.ISR = []int32{1}
+		topic.Partitions = append(topic.Partitions, partitionMetadata)
+	}
+	resp.Topics = append(resp.Topics, topic)
+	return resp
+}
+
+func (fkb *fakeKafkaBroker) produceResponse(t *testing.T, req *kmsg.ProduceRequest) kmsg.Response {
+	fkb.Lock()
+	defer fkb.Unlock()
+
+	require.Equal(t, int16(-1), req.Acks)
+
+	resp := kmsg.NewPtrProduceResponse()
+	for _, topic := range req.Topics {
+		require.Equal(t, fkb.topic, topic.Topic)
+
+		topicResp := kmsg.NewProduceResponseTopic()
+		topicResp.Topic = topic.Topic
+		for _, partition := range topic.Partitions {
+			if fkb.errorCode == 0 {
+				fkb.produced[partition.Partition] = append(fkb.produced[partition.Partition], decodeRecordBatch(t, partition.Records)...)
+			}
+
+			partitionResp := kmsg.NewProduceResponseTopicPartition()
+			partitionResp.Partition = partition.Partition
+			partitionResp.ErrorCode = fkb.errorCode
+			topicResp.Partitions = append(topicResp.Partitions, partitionResp)
+		}
+		resp.Topics = append(resp.Topics, topicResp)
+	}
+	return resp
+}
+
+// decodeRecordBatch decodes the records of a record batch, which are uncompressed or compressed
+// with snappy.
+func decodeRecordBatch(t *testing.T, encoded []byte) []fakeKafkaRecord {
+	batch := kmsg.NewRecordBatch()
+	require.NoError(t, batch.ReadFrom(encoded))
+
+	raw := batch.Records
+	switch codec := batch.Attributes & 0x07; codec {
+	case 0:
+	case 2:
+		decoded, err := s2.Decode(nil, raw)
+		require.NoError(t, err)
+		raw = decoded
+	default:
+		require.FailNow(t, "unexpected compression codec", "codec %d", codec)
+	}
+
+	records := make([]fakeKafkaRecord, 0, batch.NumRecords)
+	for i := int32(0); i < batch.NumRecords; i++ {
+		length, n := binary.Varint(raw)
+		record := kmsg.NewRecord()
+		require.NoError(t, record.ReadFrom(raw[:n+int(length)]))
+		records = append(records, fakeKafkaRecord{key: record.Key, value: record.Value})
+		raw = raw[n+int(length):]
+	}
+	return records
+}
+
+func (fkb *fakeKafkaBroker) producedKeys() map[int32][]string {
+	fkb.Lock()
+	defer fkb.Unlock()
+
+	keys := map[int32][]string{}
+	for partition, records := range fkb.produced {
+		for _, record := range records {
+			keys[partition] = append(keys[partition], string(record.key))
+		}
+	}
+	return keys
+}
+
+// kafkaPartitionFor returns the partition to which the Java client assigns a key.
+func kafkaPartitionFor(key []byte, partitions int) int32 {
+	return int32(kgo.StickyKeyPartitioner(nil).ForTopic("").Partition(&kgo.Record{Key: key}, partitions))
+}
+
+func TestKafkaPublisher(t *testing.T) {
+	testCases := []struct {
+		name         string
+		partitionBy  PartitionBy
+		expectedKeys map[int32][]string
+	}{
+		{
+			"by namespace",
+			PartitionByNamespace,
+			map[int32][]string{
+				kafkaPartitionFor([]byte("document"), 4): {"document", "document"},
+				kafkaPartitionFor([]byte("folder"), 4):   {"folder"},
+			},
+		},
+		{
+			"by resource",
+			PartitionByResource,
+			map[int32][]string{
+				kafkaPartitionFor([]byte("document:firstdoc"), 4):  {"document:firstdoc"},
+				kafkaPartitionFor([]byte("document:seconddoc"), 4): {"document:seconddoc"},
+				kafkaPartitionFor([]byte("folder:company"), 4):     {"folder:company"},
+			},
+		},
+	}
+
+	rev := revision.NewFromDecimal(decimal.NewFromInt(1234))
+	events := []Event{
+		{Revision: rev, Update: tuple.Touch(tuple.MustParse("document:firstdoc#viewer@user:tom"))},
+		{Revision: rev, Update: tuple.Touch(tuple.MustParse("folder:company#viewer@user:tom"))},
+		{Revision: rev, Update: tuple.Delete(tuple.MustParse("document:seconddoc#viewer@user:tom"))},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			broker := newFakeKafkaBroker(t, "changes", 4)
+			publisher, err := NewKafkaPublisher(KafkaConfig{
+				Brokers:     []string{broker.listener.Addr().String()},
+				Topic:       "changes",
+				PartitionBy: tc.partitionBy,
+				Timeout:     5 * time.Second,
+			})
+			require.NoError(err)
+			defer publisher.Close()
+
+			require.NoError(publisher.Publish(context.Background(), events))
+			require.Equal(tc.expectedKeys, broker.producedKeys())
+
+			for _, records := range broker.produced {
+				var decoded map[string]any
+				require.NoError(json.Unmarshal(records[0].value, &decoded))
+				require.Contains(decoded, "revision")
+				require.Contains(decoded, "update")
+			}
+
+			broker.Lock()
+			broker.errorCode = kerr.TopicAuthorizationFailed.Code
+			broker.Unlock()
+			require.ErrorIs(publisher.Publish(context.Background(), events), kerr.TopicAuthorizationFailed)
+		})
+	}
+}
+
+func TestKafkaPublisherPublishMessages(t *testing.T) {
+	require := require.New(t)
+
+	broker := newFakeKafkaBroker(t, "audit", 2)
+	publisher, err := NewKafkaPublisher(KafkaConfig{
+		Brokers: []string{broker.listener.Addr().String()},
+		Topic:   "audit",
+		Timeout: 5 * time.Second,
+	})
+	require.NoError(err)
+	defer publisher.Close()
+
+	require.NoError(publisher.PublishMessages(context.Background(),
+		[][]byte{[]byte("first"), []byte("second"), []byte("first")},
+		[][]byte{[]byte("1"), []byte("2"), []byte("3")},
+	))
+
+	expected := map[int32][]string{}
+	for _, key := range []string{"first", "second", "first"} {
+		partition := kafkaPartitionFor([]byte(key), 2)
+		expected[partition] = append(expected[partition], key)
+	}
+	require.Equal(expected, broker.producedKeys())
+
+	require.ErrorContains(publisher.PublishMessages(context.Background(), [][]byte{[]byte("first")}, nil), "got 1 keys for 0 values")
+}
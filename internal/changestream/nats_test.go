@@ -0,0 +1,217 @@
+package changestream
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// fakeNATSServer is a NATS server with a single JetStream stream bound to the subjects with a
+// prefix, which records the messages published and discards those with duplicate IDs.
+type fakeNATSServer struct {
+	sync.Mutex
+	listener      net.Listener
+	streamPrefix  string
+	connections   int
+	conns         []net.Conn
+	published     []string
+	seenIDs       map[string]bool
+	duplicates    int
+	connectFields map[string]any
+}
+
+func newFakeNATSServer(t *testing.T, streamPrefix string) *fakeNATSServer {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	server := &fakeNATSServer{listener: listener, streamPrefix: streamPrefix, seenIDs: map[string]bool{}}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			server.Lock()
+			server.connections++
+			server.conns = append(server.conns, conn)
+			server.Unlock()
+
+			go server.serve(t, conn)
+		}
+	}()
+	return server
+}
+
+func (fns *fakeNATSServer) url() string {
+	return "nats://secret@" + fns.listener.Addr().String()
+}
+
+// dropConnections closes all open connections, as when the server restarts.
+func (fns *fakeNATSServer) dropConnections() {
+	fns.Lock()
+	defer fns.Unlock()
+
+	for _, conn := range fns.conns {
+		conn.Close()
+	}
+	fns.conns = nil
+}
+
+func (fns *fakeNATSServer) serve(t *testing.T, conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	var sid string
+	if _, err := io.WriteString(conn, `INFO {"server_id":"fake","version":"2.9.0","headers":true,"max_payload":1048576}`+"\r\n"); err != nil {
+		return
+	}
+
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		fields := strings.Fields(line)
+		switch fields[0] {
+		case "CONNECT":
+			var connectFields map[string]any
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(strings.TrimSpace(line), "CONNECT ")), &connectFields))
+			fns.Lock()
+			fns.connectFields = connectFields
+			fns.Unlock()
+
+		case "PING":
+			_, _ = io.WriteString(conn, "PONG\r\n")
+
+		case "SUB":
+			require.Len(t, fields, 3)
+			sid = fields[2]
+
+		case "HPUB":
+			require.Len(t, fields, 5)
+			subject, reply := fields[1], fields[2]
+			headerLength, err := strconv.Atoi(fields[3])
+			require.NoError(t, err)
+			totalLength, err := strconv.Atoi(fields[4])
+			require.NoError(t, err)
+
+			message := make([]byte, totalLength+2)
+			if _, err := io.ReadFull(reader, message); err != nil {
+				return
+			}
+			headers := string(message[:headerLength])
+			require.True(t, strings.HasPrefix(headers, "NATS/1.0\r\n"))
+
+			var msgID string
+			for _, header := range strings.Split(headers, "\r\n") {
+				if strings.HasPrefix(header, "Nats-Msg-Id: ") {
+					msgID = strings.TrimPrefix(header, "Nats-Msg-Id: ")
+				}
+			}
+			require.NotEmpty(t, msgID)
+
+			if !strings.HasPrefix(subject, fns.streamPrefix) {
+				noResponders := "NATS/1.0 503\r\n\r\n"
+				_, _ = fmt.Fprintf(conn, "HMSG %s %s %d %d\r\n%s\r\n", reply, sid, len(noResponders), len(noResponders), noResponders)
+				continue
+			}
+
+			fns.Lock()
+			duplicate := fns.seenIDs[msgID]
+			if duplicate {
+				fns.duplicates++
+			} else {
+				fns.seenIDs[msgID] = true
+				fns.published = append(fns.published, subject)
+			}
+			ack := fmt.Sprintf(`{"stream":"CHANGES","seq":%d,"duplicate":%t}`, len(fns.published), duplicate)
+			fns.Unlock()
+
+			_, _ = fmt.Fprintf(conn, "PING\r\nMSG %s %s %d\r\n%s\r\n", reply, sid, len(ack), ack)
+		}
+	}
+}
+
+func (fns *fakeNATSServer) state() ([]string, int, int) {
+	fns.Lock()
+	defer fns.Unlock()
+	return append([]string(nil), fns.published...), fns.duplicates, fns.connections
+}
+
+func TestNATSPublisher(t *testing.T) {
+	require := require.New(t)
+
+	server := newFakeNATSServer(t, "spicedb.changes.")
+	publisher, err := NewNATSPublisher(NATSConfig{
+		URLs:          []string{"nats://127.0.0.1:1", server.url()},
+		SubjectPrefix: "spicedb.changes",
+		Subjects:      map[string]string{"folder": "spicedb.changes.folders"},
+		Timeout:       5 * time.Second,
+	})
+	require.NoError(err)
+	defer publisher.Close()
+
+	rev := revision.NewFromDecimal(decimal.NewFromInt(1234))
+	events := []Event{
+		{Revision: rev, SchemaChange: &datastore.SchemaChange{NewDefinition: &core.NamespaceDefinition{Name: "document"}}},
+		{Revision: rev, Update: tuple.Touch(tuple.MustParse("document:firstdoc#viewer@user:tom"))},
+		{Revision: rev, Update: tuple.Touch(tuple.MustParse("folder:company#viewer@user:tom"))},
+	}
+
+	require.NoError(publisher.Publish(context.Background(), events))
+	published, duplicates, connections := server.state()
+	require.Equal([]string{
+		"spicedb.changes.schema.document",
+		"spicedb.changes.relationships.document",
+		"spicedb.changes.folders",
+	}, published)
+	require.Equal(0, duplicates)
+	require.Equal(1, connections)
+
+	server.Lock()
+	require.Equal("secret", server.connectFields["auth_token"])
+	require.Equal(true, server.connectFields["headers"])
+	server.Unlock()
+
+	// The client reconnects after the connection is dropped, and the events published again are
+	// discarded by the stream as duplicates.
+	server.dropConnections()
+	require.Eventually(func() bool {
+		_, _, connections := server.state()
+		return connections == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	require.NoError(publisher.Publish(context.Background(), events))
+	published, duplicates, connections = server.state()
+	require.Len(published, 3)
+	require.Equal(3, duplicates)
+	require.Equal(2, connections)
+
+	_, err = NewNATSPublisher(NATSConfig{URLs: []string{server.url()}})
+	require.ErrorContains(err, "subject prefix is required")
+
+	// Subjects not bound to a stream fail to publish.
+	unbound, err := NewNATSPublisher(NATSConfig{URLs: []string{server.url()}, SubjectPrefix: "unbound", Timeout: 5 * time.Second})
+	require.NoError(err)
+	defer unbound.Close()
+	require.ErrorIs(unbound.Publish(context.Background(), events), nats.ErrNoResponders)
+}
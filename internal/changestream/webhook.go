@@ -0,0 +1,302 @@
+package changestream
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/errgroup"
+	"gopkg.in/yaml.v3"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+const (
+	// WebhookSignatureHeader is the header carrying the signature of a webhook payload, as
+	// computed by SignWebhookPayload.
+	WebhookSignatureHeader = "X-SpiceDB-Signature"
+
+	// WebhookTimestampHeader is the header carrying the Unix time at which a webhook payload was
+	// signed.
+	WebhookTimestampHeader = "X-SpiceDB-Timestamp"
+
+	// WebhookDeliveryHeader is the header carrying the ID of a webhook delivery, which is the
+	// same for every attempt to deliver the same events.
+	WebhookDeliveryHeader = "X-SpiceDB-Delivery"
+)
+
+var deadLetteredDeliveriesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "changestream",
+	Name:      "webhook_dead_lettered_deliveries_total",
+	Help:      "The number of webhook deliveries abandoned after failing every attempt, by endpoint.",
+}, []string{"endpoint"})
+
+// WebhookEndpoint is an endpoint to which events are delivered.
+type WebhookEndpoint struct {
+	// URL is the HTTPS URL to which events are posted.
+	URL string
+
+	// Secret is the secret with which payloads are signed.
+	Secret string
+
+	// Filter is the filter of the relationship changes delivered to the endpoint.
+	Filter datastore.WatchFilter
+}
+
+// WebhookConfig is the configuration of a WebhookPublisher.
+type WebhookConfig struct {
+	// Endpoints are the endpoints to which events are delivered.
+	Endpoints []WebhookEndpoint
+
+	// MaxAttempts is the number of attempts made to deliver events to an endpoint before they
+	// are dead-lettered.
+	MaxAttempts int
+
+	// InitialBackoff is the delay before the second attempt at a delivery, which doubles for
+	// each attempt after.
+	InitialBackoff time.Duration
+
+	// Timeout is the timeout for each attempt at a delivery.
+	Timeout time.Duration
+
+	// Client is the client with which deliveries are made. If nil, a default client is used.
+	Client *http.Client
+}
+
+// WebhookPublisher delivers events as signed JSON payloads posted to HTTPS endpoints, such as:
+//
+//	{"events": [{"revision": "GhUKEzE2NzA...", "update": {...}}]}
+//
+// Each endpoint receives the events matching its filter. Deliveries failing every attempt are
+// dead-lettered: they are logged and counted, and the stream moves on so that one failing endpoint
+// does not hold back the others.
+type WebhookPublisher struct {
+	config WebhookConfig
+	client *http.Client
+}
+
+// NewWebhookPublisher creates a new publisher for webhooks.
+func NewWebhookPublisher(config WebhookConfig) (*WebhookPublisher, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, errors.New("at least one webhook endpoint is required")
+	}
+	if config.MaxAttempts < 1 {
+		return nil, errors.New("at least one attempt at webhook delivery is required")
+	}
+
+	for _, endpoint := range config.Endpoints {
+		parsed, err := url.Parse(endpoint.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid webhook URL: %w", err)
+		}
+		if parsed.Scheme != "https" {
+			return nil, fmt.Errorf("webhook URL %s must use https", endpointLabel(parsed))
+		}
+		if endpoint.Secret == "" {
+			return nil, fmt.Errorf("webhook URL %s requires a secret", endpointLabel(parsed))
+		}
+	}
+
+	client := config.Client
+	if client == nil {
+		client = &http.Client{}
+	}
+	return &WebhookPublisher{config: config, client: client}, nil
+}
+
+func (wp *WebhookPublisher) Name() string {
+	return "webhook"
+}
+
+func (wp *WebhookPublisher) Publish(ctx context.Context, events []Event) error {
+	g, ctx := errgroup.WithContext(ctx)
+	for _, endpoint := range wp.config.Endpoints {
+		endpoint := endpoint
+		g.Go(func() error {
+			return wp.deliver(ctx, endpoint, events)
+		})
+	}
+	return g.Wait()
+}
+
+// deliver delivers the events matching the filter of the endpoint, returning an error only if the
+// context is canceled before the delivery succeeds or is dead-lettered.
+func (wp *WebhookPublisher) deliver(ctx context.Context, endpoint WebhookEndpoint, events []Event) error {
+	matching := make([]Event, 0, len(events))
+	for _, event := range events {
+		if event.Update != nil && endpoint.Filter.Matches(event.Update.Tuple) ||
+			event.SchemaChange != nil && endpoint.Filter.IsEmpty() {
+			matching = append(matching, event)
+		}
+	}
+	if len(matching) == 0 {
+		return nil
+	}
+
+	body, err := json.Marshal(struct {
+		Events []Event `json:"events"`
+	}{matching})
+	if err != nil {
+		return fmt.Errorf("unable to encode events: %w", err)
+	}
+
+	digest := sha256.Sum256(body)
+	deliveryID := hex.EncodeToString(digest[:16])
+
+	parsed, _ := url.Parse(endpoint.URL)
+	label := endpointLabel(parsed)
+
+	backoff := wp.config.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		retryable, err := wp.attempt(ctx, endpoint, deliveryID, body)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		logger := log.Ctx(ctx).Warn().Err(err).Str("endpoint", label).Str("delivery", deliveryID).Int("attempt", attempt)
+		if !retryable || attempt >= wp.config.MaxAttempts {
+			logger.Msg("webhook delivery failed; dead-lettering")
+			deadLetteredDeliveriesCounter.WithLabelValues(label).Inc()
+			return nil
+		}
+		logger.Msg("webhook delivery failed; retrying")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// attempt makes a single attempt at a delivery, returning whether a failure may succeed on
+// retry.
+func (wp *WebhookPublisher) attempt(ctx context.Context, endpoint WebhookEndpoint, deliveryID string, body []byte) (bool, error) {
+	if wp.config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, wp.config.Timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookDeliveryHeader, deliveryID)
+	req.Header.Set(WebhookTimestampHeader, timestamp)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(endpoint.Secret, timestamp, body))
+
+	resp, err := wp.client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout,
+		resp.StatusCode == http.StatusTooManyRequests,
+		resp.StatusCode >= 500:
+		return true, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+}
+
+func (wp *WebhookPublisher) Close() error {
+	wp.client.CloseIdleConnections()
+	return nil
+}
+
+// SignWebhookPayload returns the signature of a webhook payload signed at the given Unix
+// timestamp, which is `v1=` followed by the hex-encoded HMAC-SHA256, keyed by the secret, of the
+// timestamp, a period and the payload. Receivers should compare it in constant time and reject
+// stale timestamps.
+func SignWebhookPayload(secret, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// endpointLabel returns the URL of an endpoint without its user info and query, which may hold
+// credentials.
+func endpointLabel(u *url.URL) string {
+	return u.Scheme + "://" + u.Host + u.Path
+}
+
+// webhookEndpointsFile is the format of a file of webhook endpoints, such as:
+//
+//	endpoints:
+//	  - url: https://example.com/spicedb
+//	    secret: somesecret
+//	    filter:
+//	      resourceTypes: [document]
+//	      subjectTypes: [user]
+type webhookEndpointsFile struct {
+	Endpoints []struct {
+		URL    string `yaml:"url"`
+		Secret string `yaml:"secret"`
+		Filter struct {
+			ResourceTypes     []string `yaml:"resourceTypes"`
+			ResourceRelations []string `yaml:"resourceRelations"`
+			ResourceIDPrefix  string   `yaml:"resourceIDPrefix"`
+			SubjectTypes      []string `yaml:"subjectTypes"`
+		} `yaml:"filter"`
+	} `yaml:"endpoints"`
+}
+
+// LoadWebhookEndpoints loads the webhook endpoints registered in a YAML file.
+func LoadWebhookEndpoints(path string) ([]WebhookEndpoint, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read webhook endpoints: %w", err)
+	}
+
+	var file webhookEndpointsFile
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("unable to parse webhook endpoints: %w", err)
+	}
+
+	endpoints := make([]WebhookEndpoint, 0, len(file.Endpoints))
+	for _, endpoint := range file.Endpoints {
+		endpoints = append(endpoints, WebhookEndpoint{
+			URL:    endpoint.URL,
+			Secret: endpoint.Secret,
+			Filter: datastore.WatchFilter{
+				OptionalResourceTypes:     endpoint.Filter.ResourceTypes,
+				OptionalResourceRelations: endpoint.Filter.ResourceRelations,
+				OptionalResourceIDPrefix:  endpoint.Filter.ResourceIDPrefix,
+				OptionalSubjectTypes:      endpoint.Filter.SubjectTypes,
+			},
+		})
+	}
+	return endpoints, nil
+}
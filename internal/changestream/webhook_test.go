@@ -0,0 +1,224 @@
+package changestream
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// fakeWebhookEndpoint records the payloads delivered to it, failing with the given statuses
+// before succeeding.
+type fakeWebhookEndpoint struct {
+	sync.Mutex
+	failures   []int
+	attempts   int
+	deliveries [][]string
+	deliveryID []string
+}
+
+func (fwe *fakeWebhookEndpoint) handler(t *testing.T, secret string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, SignWebhookPayload(secret, r.Header.Get(WebhookTimestampHeader), body), r.Header.Get(WebhookSignatureHeader))
+
+		fwe.Lock()
+		defer fwe.Unlock()
+
+		fwe.attempts++
+		fwe.deliveryID = append(fwe.deliveryID, r.Header.Get(WebhookDeliveryHeader))
+		if len(fwe.failures) > 0 {
+			w.WriteHeader(fwe.failures[0])
+			fwe.failures = fwe.failures[1:]
+			return
+		}
+
+		var payload struct {
+			Events []struct {
+				Update struct {
+					Relationship struct {
+						Resource struct {
+							ObjectID string `json:"objectId"`
+						} `json:"resource"`
+					} `json:"relationship"`
+				} `json:"update"`
+			} `json:"events"`
+		}
+		require.NoError(t, json.Unmarshal(body, &payload))
+
+		var resourceIDs []string
+		for _, event := range payload.Events {
+			resourceIDs = append(resourceIDs, event.Update.Relationship.Resource.ObjectID)
+		}
+		fwe.deliveries = append(fwe.deliveries, resourceIDs)
+	}
+}
+
+func TestWebhookPublisher(t *testing.T) {
+	testCases := []struct {
+		name               string
+		filter             datastore.WatchFilter
+		failures           []int
+		expectedAttempts   int
+		expectedDeliveries [][]string
+		expectedDeadLetter float64
+	}{
+		{
+			"delivered",
+			datastore.WatchFilter{},
+			nil,
+			1,
+			[][]string{{"firstdoc", "company", "seconddoc"}},
+			0,
+		},
+		{
+			"filtered",
+			datastore.WatchFilter{OptionalResourceTypes: []string{"document"}},
+			nil,
+			1,
+			[][]string{{"firstdoc", "seconddoc"}},
+			0,
+		},
+		{
+			"nothing matching",
+			datastore.WatchFilter{OptionalResourceTypes: []string{"user"}},
+			nil,
+			0,
+			nil,
+			0,
+		},
+		{
+			"retried",
+			datastore.WatchFilter{},
+			[]int{http.StatusServiceUnavailable, http.StatusTooManyRequests},
+			3,
+			[][]string{{"firstdoc", "company", "seconddoc"}},
+			0,
+		},
+		{
+			"dead-lettered after every attempt",
+			datastore.WatchFilter{},
+			[]int{http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError},
+			3,
+			nil,
+			1,
+		},
+		{
+			"dead-lettered without retry",
+			datastore.WatchFilter{},
+			[]int{http.StatusBadRequest},
+			1,
+			nil,
+			1,
+		},
+	}
+
+	rev := revision.NewFromDecimal(decimal.NewFromInt(1234))
+	events := []Event{
+		{Revision: rev, Update: tuple.Touch(tuple.MustParse("document:firstdoc#viewer@user:tom"))},
+		{Revision: rev, Update: tuple.Touch(tuple.MustParse("folder:company#viewer@user:tom"))},
+		{Revision: rev, Update: tuple.Delete(tuple.MustParse("document:seconddoc#viewer@user:tom"))},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			endpoint := &fakeWebhookEndpoint{failures: tc.failures}
+			server := httptest.NewTLSServer(endpoint.handler(t, "somesecret"))
+			defer server.Close()
+			endpointURL := server.URL + "/" + strings.ReplaceAll(tc.name, " ", "-")
+
+			publisher, err := NewWebhookPublisher(WebhookConfig{
+				Endpoints:      []WebhookEndpoint{{URL: endpointURL, Secret: "somesecret", Filter: tc.filter}},
+				MaxAttempts:    3,
+				InitialBackoff: time.Millisecond,
+				Timeout:        5 * time.Second,
+				Client:         server.Client(),
+			})
+			require.NoError(err)
+			defer publisher.Close()
+
+			deadLettered := deadLetteredDeliveriesCounter.WithLabelValues(endpointURL)
+			require.NoError(publisher.Publish(context.Background(), events))
+
+			endpoint.Lock()
+			defer endpoint.Unlock()
+			require.Equal(tc.expectedAttempts, endpoint.attempts)
+			require.Equal(tc.expectedDeliveries, endpoint.deliveries)
+			require.Equal(tc.expectedDeadLetter, testutil.ToFloat64(deadLettered))
+
+			// Every attempt at the same delivery carries the same ID.
+			for _, deliveryID := range endpoint.deliveryID {
+				require.Equal(endpoint.deliveryID[0], deliveryID)
+			}
+		})
+	}
+}
+
+func TestNewWebhookPublisherValidation(t *testing.T) {
+	_, err := NewWebhookPublisher(WebhookConfig{
+		Endpoints:   []WebhookEndpoint{{URL: "http://example.com/hook", Secret: "somesecret"}},
+		MaxAttempts: 1,
+	})
+	require.ErrorContains(t, err, "must use https")
+
+	_, err = NewWebhookPublisher(WebhookConfig{
+		Endpoints:   []WebhookEndpoint{{URL: "https://example.com/hook"}},
+		MaxAttempts: 1,
+	})
+	require.ErrorContains(t, err, "requires a secret")
+}
+
+func TestLoadWebhookEndpoints(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "webhooks.yaml")
+	require.NoError(os.WriteFile(path, []byte(`
+endpoints:
+  - url: https://example.com/all
+    secret: first
+  - url: https://example.com/documents
+    secret: second
+    filter:
+      resourceTypes: [document]
+      resourceIDPrefix: team-
+      subjectTypes: [user]
+`), 0o600))
+
+	endpoints, err := LoadWebhookEndpoints(path)
+	require.NoError(err)
+	require.Equal([]WebhookEndpoint{
+		{URL: "https://example.com/all", Secret: "first"},
+		{
+			URL:    "https://example.com/documents",
+			Secret: "second",
+			Filter: datastore.WatchFilter{
+				OptionalResourceTypes:    []string{"document"},
+				OptionalResourceIDPrefix: "team-",
+				OptionalSubjectTypes:     []string{"user"},
+			},
+		},
+	}, endpoints)
+
+	require.NoError(os.WriteFile(path, []byte("endpoints:\n  - uri: https://example.com\n"), 0o600))
+	_, err = LoadWebhookEndpoints(path)
+	require.ErrorContains(err, "field uri not found")
+}
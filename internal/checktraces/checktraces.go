@@ -0,0 +1,188 @@
+// Package checktraces samples the evaluations of CheckPermission calls, capturing their full debug
+// traces, and ships them to a sink for offline analysis of the evaluation patterns of the schema.
+package checktraces
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+var (
+	droppedTracesCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "check_traces",
+		Name:      "dropped_total",
+		Help:      "The number of sampled check traces dropped because the buffer of traces was full.",
+	})
+
+	sinkErrorsCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "check_traces",
+		Name:      "sink_errors_total",
+		Help:      "The number of batches of sampled check traces which could not be shipped.",
+	})
+)
+
+const (
+	// traceBufferSize is the number of traces buffered for the sink, beyond which traces are
+	// dropped rather than slowing down calls.
+	traceBufferSize = 1_000
+
+	// maxBatchSize is the maximum number of traces shipped to the sink at once.
+	maxBatchSize = 50
+)
+
+// Trace is the sampled evaluation of a single check.
+type Trace struct {
+	// Time is the time at which the evaluation completed.
+	Time time.Time
+
+	// TraceID and SpanID identify the span of the call in which the check was evaluated, if it
+	// was sampled by the tracer.
+	TraceID []byte
+	SpanID  []byte
+
+	// Resource is the resource and permission checked, such as `document:readme#view`.
+	Resource string
+
+	// Subject is the subject checked, such as `user:tom`.
+	Subject string
+
+	// Result is the permissionship of the subject, such as `PERMISSIONSHIP_HAS_PERMISSION`.
+	Result string
+
+	// Revision is the ZedToken of the revision at which the check was evaluated.
+	Revision string
+
+	// Duration is the duration of the evaluation.
+	Duration time.Duration
+
+	// DispatchCount and CachedDispatchCount are the number of dispatches made by the evaluation,
+	// and the number of those answered by the dispatch cache.
+	DispatchCount       uint32
+	CachedDispatchCount uint32
+
+	// DepthRequired is the depth of the deepest dispatch of the evaluation.
+	DepthRequired uint32
+
+	// Debug is the full debug trace of the evaluation, along with the schema it used.
+	Debug *v1.DebugInformation
+}
+
+// Sink is a destination of sampled check traces.
+type Sink interface {
+	// Ship ships the traces to the sink.
+	Ship(ctx context.Context, traces []Trace) error
+
+	// Close closes the sink.
+	Close() error
+}
+
+// Shipper samples the evaluations of checks and ships their traces to a sink asynchronously, so
+// that a slow sink does not slow down calls. Traces are dropped, and counted, if the sink falls
+// too far behind.
+type Shipper struct {
+	sampleRate float64
+	sink       Sink
+	traces     chan Trace
+}
+
+// NewShipper creates a new shipper of the traces of the given fraction of checks to the sink.
+func NewShipper(sampleRate float64, sink Sink) *Shipper {
+	return &Shipper{
+		sampleRate: sampleRate,
+		sink:       sink,
+		traces:     make(chan Trace, traceBufferSize),
+	}
+}
+
+// Sample returns whether the next check is sampled, in which case it is evaluated with debugging
+// enabled and its trace shipped with Ship. It is safe to call on a nil Shipper.
+func (s *Shipper) Sample() bool {
+	if s == nil || s.sampleRate <= 0 {
+		return false
+	}
+	return s.sampleRate >= 1 || rand.Float64() < s.sampleRate // nolint:gosec
+}
+
+// Ship queues the trace of a sampled check to be shipped.
+func (s *Shipper) Ship(trace Trace) {
+	select {
+	case s.traces <- trace:
+	default:
+		droppedTracesCounter.Inc()
+	}
+}
+
+// QueueDepth returns the number of traces queued to be shipped.
+func (s *Shipper) QueueDepth() int {
+	return len(s.traces)
+}
+
+// Start ships queued traces to the sink until the context is canceled, after which the traces
+// already queued are shipped and the sink closed.
+func (s *Shipper) Start(ctx context.Context) error {
+	log.Ctx(ctx).Info().
+		Float64("sampleRate", s.sampleRate).
+		Msg("check trace shipper started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.drain()
+			if err := s.sink.Close(); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Msg("unable to close check trace sink")
+			}
+
+			log.Ctx(ctx).Info().
+				Msg("shut down check trace shipper")
+			return ctx.Err()
+
+		case trace := <-s.traces:
+			s.ship(ctx, s.batch(trace))
+		}
+	}
+}
+
+// batch returns the given trace followed by any other traces already queued.
+func (s *Shipper) batch(first Trace) []Trace {
+	batch := []Trace{first}
+	for len(batch) < maxBatchSize {
+		select {
+		case trace := <-s.traces:
+			batch = append(batch, trace)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// drain ships the traces already queued, with a context which is not yet canceled.
+func (s *Shipper) drain() {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	for {
+		select {
+		case trace := <-s.traces:
+			s.ship(ctx, s.batch(trace))
+		default:
+			return
+		}
+	}
+}
+
+func (s *Shipper) ship(ctx context.Context, traces []Trace) {
+	if err := s.sink.Ship(ctx, traces); err != nil {
+		log.Ctx(ctx).Warn().Err(err).Int("traces", len(traces)).Msg("unable to ship check traces")
+		sinkErrorsCounter.Inc()
+	}
+}
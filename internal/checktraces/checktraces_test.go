@@ -0,0 +1,142 @@
+package checktraces
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+type recordingSink struct {
+	sync.Mutex
+	traces []Trace
+	closed bool
+}
+
+func (rs *recordingSink) Ship(_ context.Context, traces []Trace) error {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.traces = append(rs.traces, traces...)
+	return nil
+}
+
+func (rs *recordingSink) Close() error {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.closed = true
+	return nil
+}
+
+func TestSample(t *testing.T) {
+	var nilShipper *Shipper
+	require.False(t, nilShipper.Sample())
+	require.False(t, NewShipper(0, &recordingSink{}).Sample())
+	require.True(t, NewShipper(1, &recordingSink{}).Sample())
+}
+
+func TestShipperShipsOnShutdown(t *testing.T) {
+	require := require.New(t)
+
+	sink := &recordingSink{}
+	shipper := NewShipper(1, sink)
+	shipper.Ship(Trace{Resource: "document:first#view"})
+	shipper.Ship(Trace{Resource: "document:second#view"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(shipper.Start(ctx), context.Canceled)
+
+	require.True(sink.closed)
+	require.Len(sink.traces, 2)
+	require.Equal("document:first#view", sink.traces[0].Resource)
+	require.Equal("document:second#view", sink.traces[1].Resource)
+}
+
+func TestOTLPSinkOverHTTP(t *testing.T) {
+	require := require.New(t)
+
+	var received *collectorpb.ExportLogsServiceRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal("/v1/logs", r.URL.Path)
+		require.Equal("secret", r.Header.Get("X-Api-Key"))
+
+		body, err := io.ReadAll(r.Body)
+		require.NoError(err)
+		received = &collectorpb.ExportLogsServiceRequest{}
+		require.NoError(proto.Unmarshal(body, received))
+
+		resp, err := proto.Marshal(&collectorpb.ExportLogsServiceResponse{})
+		require.NoError(err)
+		w.Header().Set("Content-Type", "application/x-protobuf")
+		_, _ = w.Write(resp)
+	}))
+	defer server.Close()
+
+	sink, err := NewOTLPSink(OTLPConfig{
+		Endpoint: server.URL,
+		Protocol: ProtocolHTTP,
+		Headers:  map[string]string{"X-Api-Key": "secret"},
+	})
+	require.NoError(err)
+	defer sink.Close()
+
+	debug := &v1.DebugInformation{
+		Check: &v1.CheckDebugTrace{
+			Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "first"},
+			Permission: "view",
+			Result:     v1.CheckDebugTrace_PERMISSIONSHIP_HAS_PERMISSION,
+		},
+		SchemaUsed: "definition document {}",
+	}
+	require.NoError(sink.Ship(context.Background(), []Trace{{
+		Time:          time.Now(),
+		TraceID:       []byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		Resource:      "document:first#view",
+		Subject:       "user:tom",
+		Result:        "PERMISSIONSHIP_HAS_PERMISSION",
+		Duration:      1500 * time.Microsecond,
+		DispatchCount: 3,
+		Debug:         debug,
+	}}))
+
+	require.NotNil(received)
+	require.Equal("service.name", received.ResourceLogs[0].Resource.Attributes[0].Key)
+
+	records := received.ResourceLogs[0].ScopeLogs[0].LogRecords
+	require.Len(records, 1)
+	require.Len(records[0].TraceId, 16)
+
+	attributes := make(map[string]interface{}, len(records[0].Attributes))
+	for _, attribute := range records[0].Attributes {
+		if attribute.Value.GetStringValue() != "" {
+			attributes[attribute.Key] = attribute.Value.GetStringValue()
+		} else {
+			attributes[attribute.Key] = attribute.Value.GetIntValue()
+		}
+	}
+	require.Equal("document:first#view", attributes["spicedb.check.resource"])
+	require.Equal("user:tom", attributes["spicedb.check.subject"])
+	require.Equal(int64(1500), attributes["spicedb.check.duration_us"])
+	require.Equal(int64(3), attributes["spicedb.check.dispatch_count"])
+
+	shipped := &v1.DebugInformation{}
+	require.NoError(protojson.Unmarshal([]byte(records[0].Body.GetStringValue()), shipped))
+	require.True(proto.Equal(debug, shipped))
+}
+
+func TestNewOTLPSinkErrors(t *testing.T) {
+	_, err := NewOTLPSink(OTLPConfig{})
+	require.ErrorContains(t, err, "an OTLP endpoint is required")
+
+	_, err = NewOTLPSink(OTLPConfig{Endpoint: "localhost:4317", Protocol: "http/json"})
+	require.ErrorContains(t, err, "unknown OTLP protocol")
+}
@@ -0,0 +1,270 @@
+package checktraces
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/sdk/resource"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/otlp"
+)
+
+const (
+	// ProtocolGRPC ships traces as OTLP logs over gRPC.
+	ProtocolGRPC = "grpc"
+
+	// ProtocolHTTP ships traces as OTLP logs over HTTP, encoded as protobuf.
+	ProtocolHTTP = "http/protobuf"
+
+	// shipTimeout is the timeout of each shipment.
+	shipTimeout = 10 * time.Second
+
+	// scopeName is the name of the instrumentation scope of the shipped logs.
+	scopeName = "github.com/authzed/spicedb/internal/checktraces"
+)
+
+// OTLPConfig configures the shipping of traces as OTLP logs.
+type OTLPConfig struct {
+	// Endpoint is the address of the collector for gRPC, or its URL for HTTP, to which `/v1/logs`
+	// is appended if it has no path.
+	Endpoint string
+
+	// Protocol is either ProtocolGRPC or ProtocolHTTP.
+	Protocol string
+
+	// Insecure disables TLS.
+	Insecure bool
+
+	// Headers are sent with each shipment, such as to authenticate to the collector.
+	Headers map[string]string
+
+	// ResourceAttributes describe the shipping server, in addition to its service name.
+	ResourceAttributes map[string]string
+}
+
+// OTLPSink ships traces as OTLP log records, each holding the debug trace of a check as JSON in
+// its body and the summary of the evaluation in its attributes.
+type OTLPSink struct {
+	config   OTLPConfig
+	resource *resourcepb.Resource
+	export   func(ctx context.Context, req *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsServiceResponse, error)
+	close    func() error
+}
+
+// NewOTLPSink creates a new sink of traces, or errors if the configuration was invalid.
+func NewOTLPSink(config OTLPConfig) (*OTLPSink, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("an OTLP endpoint is required to ship check traces")
+	}
+
+	s := &OTLPSink{
+		config:   config,
+		resource: resourceProto(otlp.NewResource(config.ResourceAttributes)),
+	}
+
+	switch config.Protocol {
+	case ProtocolGRPC, "":
+		if err := s.dialGRPC(); err != nil {
+			return nil, err
+		}
+	case ProtocolHTTP:
+		if err := s.configureHTTP(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q: must be %s or %s", config.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+	return s, nil
+}
+
+// resourceProto returns the OTLP resource of the given resource, whose attributes are strings.
+func resourceProto(res *resource.Resource) *resourcepb.Resource {
+	attrs := make([]*commonpb.KeyValue, 0, res.Len())
+	for _, kv := range res.Attributes() {
+		attrs = append(attrs, stringAttribute(string(kv.Key), kv.Value.Emit()))
+	}
+	return &resourcepb.Resource{Attributes: attrs}
+}
+
+func (s *OTLPSink) dialGRPC() error {
+	creds := credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	if s.config.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.Dial(s.config.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return fmt.Errorf("failed to connect to the OTLP endpoint: %w", err)
+	}
+
+	client := collectorpb.NewLogsServiceClient(conn)
+	s.export = func(ctx context.Context, req *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsServiceResponse, error) {
+		for key, value := range s.config.Headers {
+			ctx = metadata.AppendToOutgoingContext(ctx, key, value)
+		}
+		return client.Export(ctx, req)
+	}
+	s.close = conn.Close
+	return nil
+}
+
+func (s *OTLPSink) configureHTTP() error {
+	endpoint := s.config.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		if s.config.Insecure {
+			endpoint = "http://" + endpoint
+		} else {
+			endpoint = "https://" + endpoint
+		}
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	if endpointURL.Path == "" || endpointURL.Path == "/" {
+		endpointURL.Path = "/v1/logs"
+	}
+	endpoint = endpointURL.String()
+
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{MinVersion: tls.VersionTLS12},
+		},
+	}
+	s.export = func(ctx context.Context, req *collectorpb.ExportLogsServiceRequest) (*collectorpb.ExportLogsServiceResponse, error) {
+		body, err := proto.Marshal(req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal OTLP logs: %w", err)
+		}
+
+		r, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to create OTLP logs request: %w", err)
+		}
+		r.Header.Set("Content-Type", "application/x-protobuf")
+		for key, value := range s.config.Headers {
+			r.Header.Set(key, value)
+		}
+
+		resp, err := client.Do(r)
+		if err != nil {
+			return nil, fmt.Errorf("failed to send OTLP logs: %w", err)
+		}
+		defer resp.Body.Close()
+
+		respBody, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode/100 != 2 {
+			return nil, fmt.Errorf("unexpected OTLP logs response: %d: %s", resp.StatusCode, string(respBody))
+		}
+
+		exportResp := &collectorpb.ExportLogsServiceResponse{}
+		if err := proto.Unmarshal(respBody, exportResp); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal OTLP logs response: %w", err)
+		}
+		return exportResp, nil
+	}
+	s.close = func() error {
+		client.CloseIdleConnections()
+		return nil
+	}
+	return nil
+}
+
+func (s *OTLPSink) Ship(ctx context.Context, traces []Trace) error {
+	records := make([]*logspb.LogRecord, 0, len(traces))
+	for _, trace := range traces {
+		record, err := logRecord(trace)
+		if err != nil {
+			return err
+		}
+		records = append(records, record)
+	}
+
+	req := &collectorpb.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: s.resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: scopeName},
+				LogRecords: records,
+			}},
+		}},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, shipTimeout)
+	defer cancel()
+
+	resp, err := s.export(ctx, req)
+	if err != nil {
+		return err
+	}
+
+	if partial := resp.GetPartialSuccess(); partial.GetRejectedLogRecords() > 0 {
+		log.Warn().
+			Int64("rejected", partial.GetRejectedLogRecords()).
+			Str("reason", partial.GetErrorMessage()).
+			Msg("OTLP endpoint rejected some of the shipped check traces")
+	}
+	return nil
+}
+
+func (s *OTLPSink) Close() error {
+	return s.close()
+}
+
+// logRecord returns the OTLP log record of a trace.
+func logRecord(trace Trace) (*logspb.LogRecord, error) {
+	body, err := protojson.Marshal(trace.Debug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal check trace: %w", err)
+	}
+
+	return &logspb.LogRecord{
+		TimeUnixNano:   uint64(trace.Time.UnixNano()),
+		SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_INFO,
+		SeverityText:   "INFO",
+		Body:           &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: string(body)}},
+		Attributes: []*commonpb.KeyValue{
+			stringAttribute("spicedb.check.resource", trace.Resource),
+			stringAttribute("spicedb.check.subject", trace.Subject),
+			stringAttribute("spicedb.check.result", trace.Result),
+			stringAttribute("spicedb.check.revision", trace.Revision),
+			intAttribute("spicedb.check.duration_us", trace.Duration.Microseconds()),
+			intAttribute("spicedb.check.dispatch_count", int64(trace.DispatchCount)),
+			intAttribute("spicedb.check.cached_dispatch_count", int64(trace.CachedDispatchCount)),
+			intAttribute("spicedb.check.depth_required", int64(trace.DepthRequired)),
+		},
+		TraceId: trace.TraceID,
+		SpanId:  trace.SpanID,
+	}, nil
+}
+
+func stringAttribute(key, value string) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: value}},
+	}
+}
+
+func intAttribute(key string, value int64) *commonpb.KeyValue {
+	return &commonpb.KeyValue{
+		Key:   key,
+		Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: value}},
+	}
+}
@@ -0,0 +1,114 @@
+// Package clientaddr derives the IP address of the clients of the server, for the middleware
+// identifying their callers by address.
+//
+// The address of a connection is that of its peer, unless the connection is from a trusted load
+// balancer sending the PROXY protocol header, whose listener rewrites it to the address of the
+// original client. The calls of the REST gateway of the process carry the address of their HTTP
+// client, along with a token only known to the process, so that no other caller can claim an
+// address.
+package clientaddr
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// forwardedIPKey is the metadata key of the address of the client of a call of the gateway.
+	forwardedIPKey = "x-spicedb-client-ip"
+
+	// forwardedTokenKey is the metadata key of the token authenticating the forwarded address.
+	forwardedTokenKey = "x-spicedb-client-ip-token"
+)
+
+// gatewayToken authenticates the addresses forwarded by the gateway of the process.
+var gatewayToken = newToken()
+
+func newToken() string {
+	token := make([]byte, 32)
+	if _, err := rand.Read(token); err != nil {
+		panic(fmt.Sprintf("failed to generate the client address token: %v", err))
+	}
+	return hex.EncodeToString(token)
+}
+
+// Networks are the networks trusted to report the address of their clients.
+type Networks []*net.IPNet
+
+// ParseNetworks parses the networks in CIDR notation.
+func ParseNetworks(cidrs []string) (Networks, error) {
+	networks := make(Networks, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid network %q: %w", cidr, err)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// Contains returns whether the TCP address is within one of the networks.
+func (n Networks) Contains(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+	for _, network := range n {
+		if network.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// IP returns the IP address of the TCP address, or an empty string for other addresses.
+func IP(addr net.Addr) string {
+	if tcpAddr, ok := addr.(*net.TCPAddr); ok {
+		return tcpAddr.IP.String()
+	}
+	return ""
+}
+
+// GatewayAnnotator returns the metadata with which the REST gateway forwards the address of the
+// client of the HTTP request to the upstream server.
+func GatewayAnnotator(_ context.Context, r *http.Request) metadata.MD {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return nil
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil
+	}
+	return metadata.Pairs(forwardedIPKey, ip.String(), forwardedTokenKey, gatewayToken)
+}
+
+// FromContext returns the IP address of the client of the call: the address forwarded by the
+// gateway of the process for its calls, and otherwise the address of the peer of the call. It
+// returns an empty string if the call is not over TCP.
+func FromContext(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		tokens, ips := md.Get(forwardedTokenKey), md.Get(forwardedIPKey)
+		if len(tokens) == 1 && len(ips) == 1 && subtle.ConstantTimeCompare([]byte(tokens[0]), []byte(gatewayToken)) == 1 {
+			if ip := net.ParseIP(ips[0]); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	return IP(p.Addr)
+}
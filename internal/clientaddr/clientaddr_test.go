@@ -0,0 +1,58 @@
+package clientaddr
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func callContext(ip string, md metadata.MD) context.Context {
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 41234}})
+}
+
+func TestFromContext(t *testing.T) {
+	req := httptest.NewRequest("GET", "/v1/schema/read", nil)
+	req.RemoteAddr = "198.51.100.1:51234"
+	gateway := GatewayAnnotator(context.Background(), req)
+
+	testCases := []struct {
+		name     string
+		ip       string
+		md       metadata.MD
+		expected string
+	}{
+		{"direct call", "203.0.113.7", nil, "203.0.113.7"},
+		{"direct call with a forwarded address", "203.0.113.7", metadata.Pairs("x-forwarded-for", "198.51.100.1"), "203.0.113.7"},
+		{"local call with a forwarded address", "127.0.0.1", metadata.Pairs("x-forwarded-for", "198.51.100.1"), "127.0.0.1"},
+		{"call of the gateway", "127.0.0.1", gateway, "198.51.100.1"},
+		{"call with a forged address", "127.0.0.1", metadata.Pairs(forwardedIPKey, "198.51.100.1", forwardedTokenKey, "guess"), "127.0.0.1"},
+		{"call of the gateway with a second address", "127.0.0.1", metadata.Join(gateway, metadata.Pairs(forwardedIPKey, "192.0.2.1")), "127.0.0.1"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, FromContext(callContext(tc.ip, tc.md)))
+		})
+	}
+
+	require.Empty(t, FromContext(context.Background()))
+}
+
+func TestNetworks(t *testing.T) {
+	networks, err := ParseNetworks([]string{"10.0.0.0/8", " 2001:db8::/32 "})
+	require.NoError(t, err)
+	require.True(t, networks.Contains(&net.TCPAddr{IP: net.ParseIP("10.1.2.3")}))
+	require.True(t, networks.Contains(&net.TCPAddr{IP: net.ParseIP("2001:db8::1")}))
+	require.False(t, networks.Contains(&net.TCPAddr{IP: net.ParseIP("192.0.2.1")}))
+	require.False(t, networks.Contains(&net.UnixAddr{Name: "/tmp/spicedb.sock"}))
+
+	_, err = ParseNetworks([]string{"10.0.0.0"})
+	require.ErrorContains(t, err, `invalid network "10.0.0.0"`)
+}
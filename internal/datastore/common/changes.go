@@ -30,8 +30,9 @@ func mustRevisionFromKey(key revisionKey, ds revisionDecoder) datastore.Revision
 type Changes map[revisionKey]*changeRecord
 
 type changeRecord struct {
-	tupleTouches map[string]*core.RelationTuple
-	tupleDeletes map[string]*core.RelationTuple
+	tupleTouches  map[string]*core.RelationTuple
+	tupleDeletes  map[string]*core.RelationTuple
+	schemaChanges map[string]*datastore.SchemaChange
 }
 
 // NewChanges creates a new Changes object for change tracking and de-duplication.
@@ -46,15 +47,7 @@ func (ch Changes) AddChange(
 	tpl *core.RelationTuple,
 	op core.RelationTupleUpdate_Operation,
 ) {
-	rk := keyFromRevision(rev)
-	revisionChanges, ok := ch[rk]
-	if !ok {
-		revisionChanges = &changeRecord{
-			tupleTouches: make(map[string]*core.RelationTuple),
-			tupleDeletes: make(map[string]*core.RelationTuple),
-		}
-		ch[rk] = revisionChanges
-	}
+	revisionChanges := ch.recordForRevision(rev)
 
 	tplKey := tuple.String(tpl)
 
@@ -75,6 +68,44 @@ func (ch Changes) AddChange(
 	}
 }
 
+// AddChangedDefinition adds a definition of the schema written at the revision. A definition
+// deleted and written at the same revision is reported as a single change.
+func (ch Changes) AddChangedDefinition(rev datastore.Revision, def datastore.SchemaDefinition) {
+	ch.schemaChangeForDefinition(rev, def).NewDefinition = def
+}
+
+// AddDeletedDefinition adds a definition of the schema deleted or replaced at the revision.
+func (ch Changes) AddDeletedDefinition(rev datastore.Revision, def datastore.SchemaDefinition) {
+	ch.schemaChangeForDefinition(rev, def).OldDefinition = def
+}
+
+func (ch Changes) schemaChangeForDefinition(rev datastore.Revision, def datastore.SchemaDefinition) *datastore.SchemaChange {
+	revisionChanges := ch.recordForRevision(rev)
+
+	// Object and caveat definitions are kept apart, as they do not share a namespace of names.
+	defKey := fmt.Sprintf("%T:%s", def, def.GetName())
+	schemaChange, ok := revisionChanges.schemaChanges[defKey]
+	if !ok {
+		schemaChange = &datastore.SchemaChange{}
+		revisionChanges.schemaChanges[defKey] = schemaChange
+	}
+	return schemaChange
+}
+
+func (ch Changes) recordForRevision(rev datastore.Revision) *changeRecord {
+	rk := keyFromRevision(rev)
+	revisionChanges, ok := ch[rk]
+	if !ok {
+		revisionChanges = &changeRecord{
+			tupleTouches:  make(map[string]*core.RelationTuple),
+			tupleDeletes:  make(map[string]*core.RelationTuple),
+			schemaChanges: make(map[string]*datastore.SchemaChange),
+		}
+		ch[rk] = revisionChanges
+	}
+	return revisionChanges
+}
+
 // AsRevisionChanges returns the list of changes processed so far as a datastore watch
 // compatible, ordered, changelist.
 func (ch Changes) AsRevisionChanges(ds revisionDecoder) (changes []*datastore.RevisionChanges) {
@@ -110,6 +141,10 @@ func (ch Changes) AsRevisionChanges(ds revisionDecoder) (changes []*datastore.Re
 				Tuple:     tpl,
 			})
 		}
+		for _, schemaChange := range revisionChangeRecord.schemaChanges {
+			revisionChange.SchemaChanges = append(revisionChange.SchemaChanges, schemaChange)
+		}
+		datastore.SortSchemaChanges(revisionChange.SchemaChanges)
 		changes = append(changes, revisionChange)
 	}
 
@@ -194,6 +194,42 @@ func TestChanges(t *testing.T) {
 	}
 }
 
+func TestSchemaChanges(t *testing.T) {
+	require := require.New(t)
+
+	oldDocument := &core.NamespaceDefinition{Name: "document"}
+	newDocument := &core.NamespaceDefinition{Name: "document", Relation: []*core.Relation{{Name: "viewer"}}}
+	user := &core.NamespaceDefinition{Name: "user"}
+	userCaveat := &core.CaveatDefinition{Name: "user"}
+
+	ch := NewChanges()
+	ch.AddChangedDefinition(rev1, user)
+	ch.AddChangedDefinition(rev1, oldDocument)
+	ch.AddChangedDefinition(rev2, newDocument)
+	ch.AddDeletedDefinition(rev2, oldDocument)
+	ch.AddChangedDefinition(rev2, userCaveat)
+	ch.AddDeletedDefinition(rev2, user)
+
+	changes := ch.AsRevisionChanges(revision.DecimalDecoder{})
+	require.Len(changes, 2)
+
+	require.True(changes[0].Revision.Equal(rev1))
+	require.Empty(changes[0].Changes)
+	require.Equal([]*datastore.SchemaChange{
+		{NewDefinition: oldDocument},
+		{NewDefinition: user},
+	}, changes[0].SchemaChanges)
+
+	require.True(changes[1].Revision.Equal(rev2))
+	require.Empty(changes[1].Changes)
+	require.Len(changes[1].SchemaChanges, 3)
+	require.Equal(&datastore.SchemaChange{OldDefinition: oldDocument, NewDefinition: newDocument}, changes[1].SchemaChanges[0])
+	require.ElementsMatch([]*datastore.SchemaChange{
+		{NewDefinition: userCaveat},
+		{OldDefinition: user},
+	}, changes[1].SchemaChanges[1:])
+}
+
 func TestCanonicalize(t *testing.T) {
 	testCases := []struct {
 		name            string
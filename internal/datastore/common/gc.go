@@ -2,6 +2,10 @@ package common
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
@@ -40,8 +44,148 @@ var (
 		Name:      "gc_namespaces_total",
 		Help:      "The number of stale namespaces deleted by the datastore garbage collection.",
 	})
+
+	gcFailuresCounter = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "datastore",
+		Name:      "gc_failures_total",
+		Help:      "The number of datastore garbage collection runs which failed.",
+	})
+
+	gcLastSuccessGauge = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: "spicedb",
+		Subsystem: "datastore",
+		Name:      "gc_last_success_timestamp_seconds",
+		Help:      "The time at which the last successful datastore garbage collection run completed.",
+	})
+
+	gcBehindWindowGauge = prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: "spicedb",
+		Subsystem: "datastore",
+		Name:      "gc_behind_window_seconds",
+		Help:      "How far the garbage collection of the datastore is behind its window, which is the age beyond the window of the oldest data that may not have been collected. It normally stays below the interval between runs, and grows while runs fail.",
+	}, func() float64 {
+		return gcHistory.behindWindow(time.Now()).Seconds()
+	})
 )
 
+// gcHistorySize is the number of the most recent garbage collection runs kept in the history.
+const gcHistorySize = 20
+
+// GCRun is a run of the garbage collection of a datastore.
+type GCRun struct {
+	Started   time.Time      `json:"started"`
+	Duration  string         `json:"duration"`
+	Watermark string         `json:"watermark,omitempty"`
+	Collected DeletionCounts `json:"collected"`
+	Error     string         `json:"error,omitempty"`
+}
+
+// gcHistory holds the most recent garbage collection runs of the process, oldest first, along
+// with the start of the last successful run.
+var gcHistory = &gcRunHistory{processStart: time.Now()}
+
+type gcRunHistory struct {
+	sync.Mutex
+	runs             []GCRun
+	processStart     time.Time
+	lastSuccessStart time.Time
+}
+
+func (h *gcRunHistory) record(run GCRun) {
+	h.Lock()
+	defer h.Unlock()
+
+	if run.Error == "" {
+		h.lastSuccessStart = run.Started
+	}
+	h.runs = append(h.runs, run)
+	if len(h.runs) > gcHistorySize {
+		h.runs = h.runs[len(h.runs)-gcHistorySize:]
+	}
+}
+
+// behindWindow returns how far the garbage collection is behind its window: a run collects the
+// data older than the window at its start, so the data older than the window which may remain is
+// that which aged past the window since the start of the last successful run.
+func (h *gcRunHistory) behindWindow(now time.Time) time.Duration {
+	h.Lock()
+	defer h.Unlock()
+
+	if h.lastSuccessStart.IsZero() {
+		return now.Sub(h.processStart)
+	}
+	return now.Sub(h.lastSuccessStart)
+}
+
+// GCHistory returns the most recent garbage collection runs of the datastores of the process,
+// oldest first.
+func GCHistory() []GCRun {
+	gcHistory.Lock()
+	defer gcHistory.Unlock()
+
+	runs := make([]GCRun, len(gcHistory.runs))
+	copy(runs, gcHistory.runs)
+	return runs
+}
+
+// NewGCHistoryHandler returns an http.Handler serving the JSON-encoded GCHistory.
+func NewGCHistoryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(GCHistory()); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write garbage collection history")
+		}
+	})
+}
+
+// GCIntervalOverride overrides the interval of the garbage collection workers to which it is
+// given, such as when the interval is changed while they run.
+type GCIntervalOverride struct {
+	sync.Mutex
+	interval time.Duration
+	changed  chan struct{}
+}
+
+// NewGCIntervalOverride returns an override which, until it is set, leaves the garbage collection
+// workers at the interval with which they were started.
+func NewGCIntervalOverride() *GCIntervalOverride {
+	return &GCIntervalOverride{changed: make(chan struct{})}
+}
+
+// Set changes the interval between the passes of the garbage collection workers, overriding the
+// interval with which they were started. A zero interval restores the interval with which they
+// were started.
+func (o *GCIntervalOverride) Set(interval time.Duration) {
+	o.Lock()
+	defer o.Unlock()
+
+	o.interval = interval
+	close(o.changed)
+	o.changed = make(chan struct{})
+}
+
+// current returns the interval of a garbage collection worker started with the given interval,
+// along with a channel closed when the interval is next changed. A nil override never changes.
+func (o *GCIntervalOverride) current(startInterval time.Duration) (time.Duration, <-chan struct{}) {
+	if o == nil {
+		return startInterval, nil
+	}
+
+	o.Lock()
+	defer o.Unlock()
+
+	if o.interval > 0 {
+		return o.interval, o.changed
+	}
+	return startInterval, o.changed
+}
+
 // RegisterGCMetrics registers garbage collection metrics to the default
 // registry.
 func RegisterGCMetrics() error {
@@ -50,6 +194,9 @@ func RegisterGCMetrics() error {
 		gcRelationshipsCounter,
 		gcTransactionsCounter,
 		gcNamespacesCounter,
+		gcFailuresCounter,
+		gcLastSuccessGauge,
+		gcBehindWindowGauge,
 	} {
 		if err := prometheus.Register(metric); err != nil {
 			return err
@@ -68,36 +215,95 @@ type GarbageCollector interface {
 	DeleteBeforeTx(ctx context.Context, txID datastore.Revision) (DeletionCounts, error)
 }
 
+// BoundedGarbageCollector is implemented by the garbage collectors which can delete within the
+// bounds of DeletionOptions, such as for a single pass run out-of-band.
+type BoundedGarbageCollector interface {
+	GarbageCollector
+	DeleteBeforeTxWithOptions(ctx context.Context, txID datastore.Revision, opts DeletionOptions) (DeletionCounts, error)
+}
+
+// GCBacklogCounter is implemented by the garbage collectors which can count the transactions
+// committed before a time, all but the last of which garbage collection deletes once they are
+// older than its window.
+type GCBacklogCounter interface {
+	CountTxsBefore(ctx context.Context, before time.Time) (int64, error)
+}
+
+// DeletionOptions bounds the deletions of a garbage collection pass.
+type DeletionOptions struct {
+	// BatchSize is the maximum number of rows deleted by each statement; if zero, the default
+	// batch size of the datastore is used.
+	BatchSize uint64
+
+	// Deadline, if set, is the time after which no further batch is started.
+	Deadline time.Time
+
+	// Namespaces, if any, restricts the deletions to the relationships and the deleted versions of
+	// the given object definitions. Transactions are shared by all of the object definitions, so
+	// they are left for an unrestricted pass to delete.
+	Namespaces []string
+}
+
+// ErrGCDeadlineExceeded is returned by a garbage collection pass which reached the deadline of
+// its DeletionOptions before deleting all of the data it could delete.
+var ErrGCDeadlineExceeded = errors.New("garbage collection deadline exceeded")
+
+// BatchSizeOr returns the batch size of the options, or the given batch size if none was set.
+func (o DeletionOptions) BatchSizeOr(defaultBatchSize uint64) uint64 {
+	if o.BatchSize == 0 {
+		return defaultBatchSize
+	}
+	return o.BatchSize
+}
+
+// CheckDeadline returns ErrGCDeadlineExceeded if the deadline of the options has passed.
+func (o DeletionOptions) CheckDeadline() error {
+	if !o.Deadline.IsZero() && time.Now().After(o.Deadline) {
+		return ErrGCDeadlineExceeded
+	}
+	return nil
+}
+
 // DeletionCounts tracks the amount of deletions that occurred when calling
-// DeleteBeforeTx.
+// DeleteBeforeTx, along with the number of batches in which the rows were deleted.
 type DeletionCounts struct {
-	Relationships int64
-	Transactions  int64
-	Namespaces    int64
+	Relationships int64 `json:"relationships"`
+	Transactions  int64 `json:"transactions"`
+	Namespaces    int64 `json:"namespaces"`
+	Batches       int64 `json:"batches"`
 }
 
 func (g DeletionCounts) MarshalZerologObject(e *zerolog.Event) {
 	e.
 		Int64("relationships", g.Relationships).
 		Int64("transactions", g.Transactions).
-		Int64("namespaces", g.Namespaces)
+		Int64("namespaces", g.Namespaces).
+		Int64("batches", g.Batches)
 }
 
 // StartGarbageCollector loops forever until the context is canceled and
-// performs garbage collection on the provided interval.
-func StartGarbageCollector(ctx context.Context, gc GarbageCollector, interval, window, timeout time.Duration) error {
+// performs garbage collection on the provided interval, unless changed by the override, if any.
+func StartGarbageCollector(ctx context.Context, gc GarbageCollector, interval, window, timeout time.Duration, override *GCIntervalOverride) error {
 	log.Info().
 		Dur("interval", interval).
 		Msg("datastore garbage collection worker started")
 
 	for {
+		currentInterval, changed := override.current(interval)
+
 		select {
 		case <-ctx.Done():
 			log.Ctx(ctx).Info().
 				Msg("shutting down datastore garbage collection worker")
 			return ctx.Err()
 
-		case <-time.After(interval):
+		case <-changed:
+			newInterval, _ := override.current(interval)
+			log.Ctx(ctx).Info().
+				Dur("interval", newInterval).
+				Msg("datastore garbage collection interval changed")
+
+		case <-time.After(currentInterval):
 			err := collect(gc, window, timeout)
 			if err != nil {
 				log.Ctx(ctx).Warn().Err(err).
@@ -107,7 +313,7 @@ func StartGarbageCollector(ctx context.Context, gc GarbageCollector, interval, w
 	}
 }
 
-func collect(gc GarbageCollector, window, timeout time.Duration) error {
+func collect(gc GarbageCollector, window, timeout time.Duration) (err error) {
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
@@ -128,19 +334,44 @@ func collect(gc GarbageCollector, window, timeout time.Duration) error {
 		watermark datastore.Revision
 	)
 
+	log.Ctx(ctx).Info().
+		Stringer("window", window).
+		Msg("datastore garbage collection started")
+
 	defer func() {
 		collectionDuration := time.Since(startTime)
 		gcDurationHistogram.Observe(collectionDuration.Seconds())
 
-		log.Ctx(ctx).Debug().
-			Stringer("highestTxID", watermark).
-			Dur("duration", collectionDuration).
-			Interface("collected", collected).
-			Msg("datastore garbage collection completed")
-
 		gcRelationshipsCounter.Add(float64(collected.Relationships))
 		gcTransactionsCounter.Add(float64(collected.Transactions))
 		gcNamespacesCounter.Add(float64(collected.Namespaces))
+
+		run := GCRun{
+			Started:   startTime.UTC(),
+			Duration:  collectionDuration.String(),
+			Collected: collected,
+		}
+		if watermark != nil {
+			run.Watermark = watermark.String()
+		}
+
+		if err != nil {
+			gcFailuresCounter.Inc()
+			run.Error = err.Error()
+			log.Ctx(ctx).Warn().Err(err).
+				Str("highestTxID", run.Watermark).
+				Dur("duration", collectionDuration).
+				Object("collected", collected).
+				Msg("datastore garbage collection failed")
+		} else {
+			gcLastSuccessGauge.SetToCurrentTime()
+			log.Ctx(ctx).Info().
+				Str("highestTxID", run.Watermark).
+				Dur("duration", collectionDuration).
+				Object("collected", collected).
+				Msg("datastore garbage collection completed")
+		}
+		gcHistory.record(run)
 	}()
 
 	now, err := gc.Now(ctx)
@@ -156,3 +387,42 @@ func collect(gc GarbageCollector, window, timeout time.Duration) error {
 	collected, err = gc.DeleteBeforeTx(ctx, watermark)
 	return err
 }
+
+// CollectOnce runs a single garbage collection pass, within the bounds of the given options, of
+// the data which aged past the window. It returns the revision before which data was collected,
+// or datastore.NoRevision if there was none, along with the counts of the data deleted, which
+// are partial if an error is returned, such as ErrGCDeadlineExceeded.
+//
+// Unlike the passes of StartGarbageCollector, the pass is neither recorded in the history nor
+// in the metrics of the process, as it is meant to be run out-of-band of the serving processes.
+func CollectOnce(ctx context.Context, gc BoundedGarbageCollector, window time.Duration, opts DeletionOptions) (datastore.Revision, DeletionCounts, error) {
+	ready, err := gc.IsReady(ctx)
+	if err != nil {
+		return datastore.NoRevision, DeletionCounts{}, err
+	}
+	if !ready {
+		return datastore.NoRevision, DeletionCounts{}, errors.New("datastore is not ready for garbage collection")
+	}
+
+	now, err := gc.Now(ctx)
+	if err != nil {
+		return datastore.NoRevision, DeletionCounts{}, err
+	}
+
+	watermark, err := gc.TxIDBefore(ctx, now.Add(-1*window))
+	if err != nil {
+		return datastore.NoRevision, DeletionCounts{}, err
+	}
+	if watermark == datastore.NoRevision {
+		return datastore.NoRevision, DeletionCounts{}, nil
+	}
+
+	log.Ctx(ctx).Info().
+		Stringer("window", window).
+		Str("highestTxID", watermark.String()).
+		Strs("namespaces", opts.Namespaces).
+		Msg("datastore garbage collection pass started")
+
+	collected, err := gc.DeleteBeforeTxWithOptions(ctx, watermark, opts)
+	return watermark, collected, err
+}
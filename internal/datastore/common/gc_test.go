@@ -0,0 +1,136 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+)
+
+type fakeGC struct {
+	deleted   DeletionCounts
+	deleteErr error
+}
+
+func (*fakeGC) IsReady(context.Context) (bool, error) { return true, nil }
+
+func (*fakeGC) Now(context.Context) (time.Time, error) { return time.Now(), nil }
+
+func (*fakeGC) TxIDBefore(context.Context, time.Time) (datastore.Revision, error) {
+	return revision.NewFromDecimal(decimal.NewFromInt(42)), nil
+}
+
+func (gc *fakeGC) DeleteBeforeTx(context.Context, datastore.Revision) (DeletionCounts, error) {
+	return gc.deleted, gc.deleteErr
+}
+
+func (gc *fakeGC) DeleteBeforeTxWithOptions(_ context.Context, _ datastore.Revision, opts DeletionOptions) (DeletionCounts, error) {
+	if err := opts.CheckDeadline(); err != nil {
+		return DeletionCounts{}, err
+	}
+	return gc.deleted, gc.deleteErr
+}
+
+func TestCollectOnce(t *testing.T) {
+	require := require.New(t)
+	gcHistory = &gcRunHistory{processStart: time.Now()}
+
+	deleted := DeletionCounts{Relationships: 3, Batches: 1}
+	watermark, collected, err := CollectOnce(context.Background(), &fakeGC{deleted: deleted}, time.Hour, DeletionOptions{
+		BatchSize:  10,
+		Deadline:   time.Now().Add(time.Hour),
+		Namespaces: []string{"document"},
+	})
+	require.NoError(err)
+	require.Equal("42", watermark.String())
+	require.Equal(deleted, collected)
+
+	_, _, err = CollectOnce(context.Background(), &fakeGC{deleted: deleted}, time.Hour, DeletionOptions{
+		Deadline: time.Now().Add(-time.Second),
+	})
+	require.ErrorIs(err, ErrGCDeadlineExceeded)
+
+	// Out-of-band passes are not recorded in the history of the process.
+	require.Empty(GCHistory())
+}
+
+func TestDeletionOptionsBatchSize(t *testing.T) {
+	require.Equal(t, uint64(1000), DeletionOptions{}.BatchSizeOr(1000))
+	require.Equal(t, uint64(10), DeletionOptions{BatchSize: 10}.BatchSizeOr(1000))
+}
+
+func TestCollectRecordsHistory(t *testing.T) {
+	require := require.New(t)
+	gcHistory = &gcRunHistory{processStart: time.Now()}
+
+	start := time.Now()
+	succeeded := DeletionCounts{Relationships: 3, Transactions: 1, Batches: 2}
+	require.NoError(collect(&fakeGC{deleted: succeeded}, time.Hour, time.Minute))
+	require.Less(gcHistory.behindWindow(time.Now()), time.Since(start))
+
+	deleteErr := errors.New("connection reset")
+	require.ErrorIs(collect(&fakeGC{deleted: DeletionCounts{Relationships: 1, Batches: 1}, deleteErr: deleteErr}, time.Hour, time.Minute), deleteErr)
+
+	// The failed run does not advance the garbage collection.
+	behind := gcHistory.behindWindow(time.Now().Add(time.Hour))
+	require.Greater(behind, time.Hour)
+
+	recorder := httptest.NewRecorder()
+	NewGCHistoryHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/gc", nil))
+	require.Equal(http.StatusOK, recorder.Code)
+
+	var runs []GCRun
+	require.NoError(json.Unmarshal(recorder.Body.Bytes(), &runs))
+	require.Len(runs, 2)
+	require.Equal(succeeded, runs[0].Collected)
+	require.Equal("42", runs[0].Watermark)
+	require.Empty(runs[0].Error)
+	require.Equal(DeletionCounts{Relationships: 1, Batches: 1}, runs[1].Collected)
+	require.Equal("connection reset", runs[1].Error)
+}
+
+func TestGCHistoryIsBounded(t *testing.T) {
+	gcHistory = &gcRunHistory{processStart: time.Now()}
+	for i := 0; i < gcHistorySize+5; i++ {
+		gcHistory.record(GCRun{Started: time.Now()})
+	}
+	require.Len(t, GCHistory(), gcHistorySize)
+}
+
+func TestGCIntervalOverride(t *testing.T) {
+	var unset *GCIntervalOverride
+	interval, changed := unset.current(time.Minute)
+	require.Equal(t, time.Minute, interval)
+	require.Nil(t, changed)
+
+	override := NewGCIntervalOverride()
+	interval, changed = override.current(time.Minute)
+	require.Equal(t, time.Minute, interval)
+
+	override.Set(10 * time.Second)
+	select {
+	case <-changed:
+	default:
+		require.Fail(t, "the workers were not notified of the change of interval")
+	}
+	interval, _ = override.current(time.Minute)
+	require.Equal(t, 10*time.Second, interval)
+
+	// Another override, such as that of another datastore, is unaffected.
+	other := NewGCIntervalOverride()
+	interval, _ = other.current(time.Minute)
+	require.Equal(t, time.Minute, interval)
+
+	override.Set(0)
+	interval, _ = override.current(time.Minute)
+	require.Equal(t, time.Minute, interval)
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+var slowQueriesCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "datastore",
+	Name:      "slow_queries_total",
+	Help:      "The number of relationship queries exceeding the slow query threshold.",
+})
+
+// logIfSlow logs the relationship query if it took longer than the slow query threshold. The
+// query is logged with its placeholders, so that its shape is logged without the values of its
+// arguments, which may hold sensitive object IDs.
+func (tqs TupleQuerySplitter) logIfSlow(ctx context.Context, sql string, argCount int, resultCount int, duration time.Duration) {
+	if tqs.SlowQueryThreshold <= 0 || duration < tqs.SlowQueryThreshold {
+		return
+	}
+	slowQueriesCounter.Inc()
+
+	method := ""
+	if fullMethod, ok := grpc.Method(ctx); ok {
+		method = fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	}
+
+	event := log.Ctx(ctx).Warn().
+		Str("query", sql).
+		Int("args", argCount).
+		Int("results", resultCount).
+		Dur("duration", duration).
+		Str("method", method)
+	if tqs.Revision != nil {
+		event = event.Str("revision", tqs.Revision.String())
+	}
+	event.Msg("slow datastore query")
+}
@@ -0,0 +1,51 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+func TestSlowQueryLogging(t *testing.T) {
+	tests := []struct {
+		name         string
+		threshold    time.Duration
+		queryTime    time.Duration
+		expectedSlow float64
+	}{
+		{"disabled", 0, 2 * time.Millisecond, 0},
+		{"below threshold", time.Hour, 0, 0},
+		{"above threshold", time.Millisecond, 2 * time.Millisecond, 1},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+
+			splitter := TupleQuerySplitter{
+				Executor: func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error) {
+					time.Sleep(tt.queryTime)
+					return nil, nil
+				},
+				UsersetBatchSize:   10,
+				SlowQueryThreshold: tt.threshold,
+			}
+
+			before := testutil.ToFloat64(slowQueriesCounter)
+			filterer := NewSchemaQueryFilterer(SchemaInformation{
+				TableTuple:   "tuple",
+				ColNamespace: "ns",
+			}, sq.Select("*")).FilterToResourceType("document")
+			_, err := splitter.SplitAndExecuteQuery(context.Background(), filterer)
+			require.NoError(err)
+			require.Equal(tt.expectedSlow, testutil.ToFloat64(slowQueriesCounter)-before)
+		})
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"math"
 	"runtime"
+	"strings"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
@@ -244,6 +246,32 @@ func (sqf SchemaQueryFilterer) FilterWithCaveatName(caveatName string) SchemaQue
 	return sqf
 }
 
+// FilterToWatchFilter returns the query, limited to the changed relationships matching the watch
+// filter. The columns of the query are those of the schema.
+func FilterToWatchFilter(query sq.SelectBuilder, schema SchemaInformation, filter datastore.WatchFilter) sq.SelectBuilder {
+	if len(filter.OptionalResourceTypes) > 0 {
+		query = query.Where(sq.Eq{schema.ColNamespace: filter.OptionalResourceTypes})
+	}
+
+	if len(filter.OptionalResourceRelations) > 0 {
+		query = query.Where(sq.Eq{schema.ColRelation: filter.OptionalResourceRelations})
+	}
+
+	if filter.OptionalResourceIDPrefix != "" {
+		query = query.Where(sq.Like{schema.ColObjectID: likeEscaper.Replace(filter.OptionalResourceIDPrefix) + "%"})
+	}
+
+	if len(filter.OptionalSubjectTypes) > 0 {
+		query = query.Where(sq.Eq{schema.ColUsersetNamespace: filter.OptionalSubjectTypes})
+	}
+
+	return query
+}
+
+// likeEscaper escapes the characters with special meaning in a LIKE pattern, using the default
+// escape character shared by the SQL datastores.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
 // FilterToUsersets returns a new SchemaQueryFilterer that is limited to resources with subjects
 // in the specified list of usersets. Nil or empty usersets parameter does not affect the underlying
 // query.
@@ -277,6 +305,14 @@ func (sqf SchemaQueryFilterer) limit(limit uint64) SchemaQueryFilterer {
 type TupleQuerySplitter struct {
 	Executor         ExecuteQueryFunc
 	UsersetBatchSize uint16
+
+	// SlowQueryThreshold is the duration beyond which the queries are logged as slow, if
+	// positive.
+	SlowQueryThreshold time.Duration
+
+	// Revision is the revision at which the queries are executed, logged with slow queries, or
+	// nil if it is not known, as in read-write transactions.
+	Revision datastore.Revision
 }
 
 // SplitAndExecuteQuery is used to split up the usersets in a very large query and execute
@@ -311,10 +347,12 @@ func (tqs TupleQuerySplitter) SplitAndExecuteQuery(
 			return nil, err
 		}
 
+		start := time.Now()
 		queryTuples, err := tqs.Executor(ctx, sql, args)
 		if err != nil {
 			return nil, err
 		}
+		tqs.logIfSlow(ctx, sql, len(args), len(queryTuples), time.Since(start))
 
 		if len(queryTuples) > remainingLimit {
 			queryTuples = queryTuples[:remainingLimit]
@@ -309,3 +309,57 @@ func TestSchemaQueryFilterer(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterToWatchFilter(t *testing.T) {
+	tests := []struct {
+		name         string
+		filter       datastore.WatchFilter
+		expectedSQL  string
+		expectedArgs []any
+	}{
+		{
+			"empty filter",
+			datastore.WatchFilter{},
+			"SELECT *",
+			nil,
+		},
+		{
+			"resource types",
+			datastore.WatchFilter{OptionalResourceTypes: []string{"sometype", "anothertype"}},
+			"SELECT * WHERE ns IN (?,?)",
+			[]any{"sometype", "anothertype"},
+		},
+		{
+			"resource ID prefix",
+			datastore.WatchFilter{OptionalResourceIDPrefix: `some_prefix%\`},
+			"SELECT * WHERE object_id LIKE ?",
+			[]any{`some\_prefix\%\\%`},
+		},
+		{
+			"full filter",
+			datastore.WatchFilter{
+				OptionalResourceTypes:     []string{"sometype"},
+				OptionalResourceRelations: []string{"somerelation"},
+				OptionalResourceIDPrefix:  "someprefix",
+				OptionalSubjectTypes:      []string{"somesubjecttype", "anothersubjecttype"},
+			},
+			"SELECT * WHERE ns IN (?) AND relation IN (?) AND object_id LIKE ? AND subject_ns IN (?,?)",
+			[]any{"sometype", "somerelation", "someprefix%", "somesubjecttype", "anothersubjecttype"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			sql, args, err := FilterToWatchFilter(sq.Select("*"), SchemaInformation{
+				ColNamespace:        "ns",
+				ColObjectID:         "object_id",
+				ColRelation:         "relation",
+				ColUsersetNamespace: "subject_ns",
+			}, test.filter).ToSql()
+			require.NoError(t, err)
+			require.Equal(t, test.expectedSQL, sql)
+			require.Equal(t, test.expectedArgs, args)
+		})
+	}
+}
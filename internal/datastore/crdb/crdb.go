@@ -147,6 +147,7 @@ func newCRDBDatastore(url string, options ...Option) (datastore.Datastore, error
 		config.watchBufferLength,
 		keyer,
 		config.splitAtUsersetCount,
+		config.slowQueryThreshold,
 		executeWithMaxRetries(config.maxRetries),
 		config.disableStats,
 	}
@@ -194,13 +195,14 @@ type crdbDatastore struct {
 	*revisions.RemoteClockRevisions
 	revision.DecimalDecoder
 
-	dburl             string
-	pool              *pgxpool.Pool
-	watchBufferLength uint16
-	writeOverlapKeyer overlapKeyer
-	usersetBatchSize  uint16
-	execute           executeTxRetryFunc
-	disableStats      bool
+	dburl              string
+	pool               *pgxpool.Pool
+	watchBufferLength  uint16
+	writeOverlapKeyer  overlapKeyer
+	usersetBatchSize   uint16
+	slowQueryThreshold time.Duration
+	execute            executeTxRetryFunc
+	disableStats       bool
 }
 
 func (cds *crdbDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
@@ -230,8 +232,10 @@ func (cds *crdbDatastore) SnapshotReader(rev datastore.Revision) datastore.Reade
 	}
 
 	querySplitter := common.TupleQuerySplitter{
-		Executor:         pgxcommon.NewPGXExecutor(createTxFunc),
-		UsersetBatchSize: cds.usersetBatchSize,
+		Executor:           pgxcommon.NewPGXExecutor(createTxFunc),
+		UsersetBatchSize:   cds.usersetBatchSize,
+		SlowQueryThreshold: cds.slowQueryThreshold,
+		Revision:           rev,
 	}
 
 	return &crdbReader{createTxFunc, querySplitter, noOverlapKeyer, nil, cds.execute}
@@ -251,8 +255,9 @@ func (cds *crdbDatastore) ReadWriteTx(
 			}
 
 			querySplitter := common.TupleQuerySplitter{
-				Executor:         pgxcommon.NewPGXExecutor(longLivedTx),
-				UsersetBatchSize: cds.usersetBatchSize,
+				Executor:           pgxcommon.NewPGXExecutor(longLivedTx),
+				UsersetBatchSize:   cds.usersetBatchSize,
+				SlowQueryThreshold: cds.slowQueryThreshold,
 			}
 
 			rwt := &crdbReadWriteTXN{
@@ -157,7 +157,7 @@ func TestWatchFeatureDetection(t *testing.T) {
 				headRevision, err := ds.HeadRevision(ctx)
 				require.NoError(t, err)
 
-				_, errChan := ds.Watch(ctx, headRevision)
+				_, errChan := ds.Watch(ctx, headRevision, datastore.WatchOptions{})
 				err = <-errChan
 				require.NotNil(t, err)
 				require.Contains(t, err.Error(), "watch is currently disabled")
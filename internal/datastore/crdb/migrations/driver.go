@@ -90,4 +90,52 @@ func (apd *CRDBDriver) WriteVersion(ctx context.Context, tx pgx.Tx, version, rep
 	return nil
 }
 
-var _ migrate.Driver[*pgx.Conn, pgx.Tx] = &CRDBDriver{}
+// CheckPermissions checks that the current user can create tables in the current schema.
+func (apd *CRDBDriver) CheckPermissions(ctx context.Context) error {
+	var canCreate bool
+	if err := apd.db.QueryRow(ctx, "SELECT has_schema_privilege(current_schema(), 'CREATE')").Scan(&canCreate); err != nil {
+		return fmt.Errorf("unable to check schema privileges: %w", err)
+	}
+	if !canCreate {
+		return fmt.Errorf("the current user lacks the CREATE privilege on the current schema")
+	}
+	return nil
+}
+
+// headIndexes are the secondary indexes of the tables of the current schema after the head
+// migration.
+var headIndexes = []string{
+	"relation_tuple.ix_relation_tuple_by_subject",
+	"relation_tuple.ix_relation_tuple_by_subject_relation",
+}
+
+const queryIndexes = `SELECT tablename, indexname FROM pg_indexes WHERE schemaname = current_schema()`
+
+// MissingIndexes returns the secondary indexes created by the migrations up to the head revision
+// which are missing from the current schema.
+func (apd *CRDBDriver) MissingIndexes(ctx context.Context) ([]string, error) {
+	rows, err := apd.db.Query(ctx, queryIndexes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	present := make(map[string]struct{})
+	for rows.Next() {
+		var table, index string
+		if err := rows.Scan(&table, &index); err != nil {
+			return nil, fmt.Errorf("unable to list indexes: %w", err)
+		}
+		present[table+"."+index] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to list indexes: %w", err)
+	}
+	return migrate.MissingIndexes(headIndexes, present), nil
+}
+
+var (
+	_ migrate.Driver[*pgx.Conn, pgx.Tx] = &CRDBDriver{}
+	_ migrate.PermissionChecker         = &CRDBDriver{}
+	_ migrate.IndexChecker              = &CRDBDriver{}
+)
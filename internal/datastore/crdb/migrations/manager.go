@@ -13,3 +13,11 @@ var (
 
 // CRDBMigrations implements a migration manager for the CRDBDriver.
 var CRDBMigrations = migrate.NewManager[*CRDBDriver, *pgx.Conn, pgx.Tx]()
+
+// mustDescribe records the statements executed by the migration to a version, which are shown
+// when planning migrations.
+func mustDescribe(version string, statements ...string) {
+	if err := CRDBMigrations.Describe(version, func(*CRDBDriver) []string { return statements }); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -54,4 +54,13 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("initial",
+		createNamespaceConfig,
+		createRelationTuple,
+		createSchemaVersion,
+		insertEmptyVersion,
+		createReverseQueryIndex,
+		createReverseCheckIndex,
+	)
 }
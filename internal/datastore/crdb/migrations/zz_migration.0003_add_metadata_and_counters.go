@@ -35,4 +35,6 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-metadata-and-counters", createMetadataTable, createCounters, insertUniqueID)
 }
@@ -24,6 +24,8 @@ func init() {
 	if err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-caveats", createCaveatTable, addRelationshipCaveatContext)
 }
 
 func addCaveatFunc(ctx context.Context, conn *pgx.Conn) error {
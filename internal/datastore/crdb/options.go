@@ -19,6 +19,7 @@ type crdbOptions struct {
 	gcWindow                    time.Duration
 	maxRetries                  uint8
 	splitAtUsersetCount         uint16
+	slowQueryThreshold          time.Duration
 	overlapStrategy             string
 	overlapKey                  string
 	disableStats                bool
@@ -91,6 +92,16 @@ func SplitAtUsersetCount(splitAtUsersetCount uint16) Option {
 	}
 }
 
+// SlowQueryThreshold is the duration beyond which relationship queries are logged as slow, with
+// their SQL, the API method for which they were made and their revision.
+//
+// Slow queries are not logged by default.
+func SlowQueryThreshold(threshold time.Duration) Option {
+	return func(po *crdbOptions) {
+		po.slowQueryThreshold = threshold
+	}
+}
+
 // ConnHealthCheckInterval is the frequency at which both idle and max lifetime connections
 // are checked, and also the frequency at which the minimum number of connections is
 // checked. This happens asynchronously.
@@ -32,6 +32,19 @@ var (
 	).Suffix(fmt.Sprintf("ON CONFLICT (%[1]s) DO UPDATE SET %[2]s = %[3]s.%[2]s + EXCLUDED.%[2]s RETURNING cluster_logical_timestamp()", colID, colCount, tableCounters))
 )
 
+func (cds *crdbDatastore) UniqueID(ctx context.Context) (string, error) {
+	sql, args, err := queryReadUniqueID.ToSql()
+	if err != nil {
+		return "", fmt.Errorf("unable to prepare unique ID sql: %w", err)
+	}
+
+	var uniqueID string
+	if err := cds.pool.QueryRow(ctx, sql, args...).Scan(&uniqueID); err != nil {
+		return "", fmt.Errorf("unable to query unique ID: %w", err)
+	}
+	return uniqueID, nil
+}
+
 func (cds *crdbDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
 	sql, args, err := queryReadUniqueID.ToSql()
 	if err != nil {
@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"sort"
+	"time"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
 
@@ -24,7 +25,7 @@ type changeDetails struct {
 	}
 }
 
-func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
 	updates := make(chan *datastore.RevisionChanges, cds.watchBufferLength)
 	errs := make(chan error, 1)
 
@@ -39,6 +40,11 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 		return updates, errs
 	}
 
+	if options.IncludesSchema() {
+		errs <- datastore.NewWatchDisabledErr("watching schema changes is not supported by the CockroachDB datastore")
+		return updates, errs
+	}
+
 	interpolated := fmt.Sprintf(queryChangefeed, tableTuple, afterRevision)
 
 	go func() {
@@ -46,6 +52,7 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 		defer close(errs)
 
 		pendingChanges := make(map[string]*datastore.RevisionChanges)
+		lastReturned := time.Now()
 
 		changes, err := cds.pool.Query(ctx, interpolated)
 		if err != nil {
@@ -109,6 +116,18 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 						errs <- datastore.NewWatchDisconnectedErr()
 						return
 					}
+					lastReturned = time.Now()
+				}
+
+				// A resolved timestamp guarantees that all changes up to it have been received.
+				if options.CheckpointDue(lastReturned) {
+					select {
+					case updates <- &datastore.RevisionChanges{Revision: resolved, IsCheckpoint: true}:
+					default:
+						errs <- datastore.NewWatchDisconnectedErr()
+						return
+					}
+					lastReturned = time.Now()
 				}
 
 				continue
@@ -160,6 +179,12 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 				oneChange.Operation = core.RelationTupleUpdate_TOUCH
 			}
 
+			// Changefeeds cannot be filtered by the values of the rows, so the filter is applied
+			// to each change as it is received.
+			if !options.Filter.Matches(oneChange.Tuple) {
+				continue
+			}
+
 			pending, ok := pendingChanges[details.Updated]
 			if !ok {
 				pending = &datastore.RevisionChanges{
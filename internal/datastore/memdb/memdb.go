@@ -194,6 +194,14 @@ func (mdb *memdbDatastore) ReadWriteTx(
 		}
 		if tx != nil {
 			for _, change := range tx.Changes() {
+				if change.Table == tableNamespace || change.Table == tableCaveats {
+					schemaChange, err := schemaChangeFor(change)
+					if err != nil {
+						return datastore.NoRevision, err
+					}
+					newChanges.SchemaChanges = append(newChanges.SchemaChanges, schemaChange)
+				}
+
 				if change.Table == tableRelationship {
 					if change.After != nil {
 						rt, err := change.After.(*relationship).RelationTuple()
@@ -218,6 +226,8 @@ func (mdb *memdbDatastore) ReadWriteTx(
 				}
 			}
 
+			datastore.SortSchemaChanges(newChanges.SchemaChanges)
+
 			change := &changelog{
 				revisionNanos: newRevision.IntPart(),
 				changes:       newChanges,
@@ -243,6 +253,43 @@ func (mdb *memdbDatastore) ReadWriteTx(
 	return datastore.NoRevision, errors.New("serialization max retries exceeded")
 }
 
+// schemaChangeFor returns the change to a schema definition made by a change to a row of the
+// namespace or caveats table.
+func schemaChangeFor(change memdb.Change) (*datastore.SchemaChange, error) {
+	oldDefinition, err := schemaDefinitionFor(change.Before)
+	if err != nil {
+		return nil, err
+	}
+
+	newDefinition, err := schemaDefinitionFor(change.After)
+	if err != nil {
+		return nil, err
+	}
+
+	return &datastore.SchemaChange{OldDefinition: oldDefinition, NewDefinition: newDefinition}, nil
+}
+
+func schemaDefinitionFor(row any) (datastore.SchemaDefinition, error) {
+	switch found := row.(type) {
+	case *namespace:
+		loaded := &corev1.NamespaceDefinition{}
+		if err := loaded.UnmarshalVT(found.configBytes); err != nil {
+			return nil, fmt.Errorf("error reading changed namespace: %w", err)
+		}
+		return loaded, nil
+
+	case *caveat:
+		loaded, err := found.Unwrap()
+		if err != nil {
+			return nil, fmt.Errorf("error reading changed caveat: %w", err)
+		}
+		return loaded, nil
+
+	default:
+		return nil, nil
+	}
+}
+
 func (mdb *memdbDatastore) IsReady(ctx context.Context) (bool, error) {
 	mdb.RLock()
 	defer mdb.RUnlock()
@@ -4,8 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hashicorp/go-memdb"
+	"github.com/shopspring/decimal"
 
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
@@ -13,7 +15,7 @@ import (
 
 const errWatchError = "watch error: %w"
 
-func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
 	ar := afterRevision.(revision.Decimal)
 
 	updates := make(chan *datastore.RevisionChanges, mdb.watchBufferLength)
@@ -24,12 +26,13 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 		defer close(errs)
 
 		currentTxn := ar.IntPart()
+		lastReturned := time.Now()
 
 		for {
 			var stagedUpdates []*datastore.RevisionChanges
 			var watchChan <-chan struct{}
 			var err error
-			stagedUpdates, currentTxn, watchChan, err = mdb.loadChanges(ctx, currentTxn)
+			stagedUpdates, currentTxn, watchChan, err = mdb.loadChanges(ctx, currentTxn, options)
 			if err != nil {
 				errs <- err
 				return
@@ -43,15 +46,37 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 					errs <- datastore.NewWatchDisconnectedErr()
 					return
 				}
+				lastReturned = time.Now()
 			}
 
-			// Wait for new changes
+			if options.CheckpointDue(lastReturned) {
+				select {
+				case updates <- &datastore.RevisionChanges{
+					Revision:     revision.NewFromDecimal(decimal.NewFromInt(currentTxn)),
+					IsCheckpoint: true,
+				}:
+				default:
+					errs <- datastore.NewWatchDisconnectedErr()
+					return
+				}
+				lastReturned = time.Now()
+			}
+
+			// Wait for new changes, or for the next checkpoint to be due
 			ws := memdb.NewWatchSet()
 			ws.Add(watchChan)
 
-			err = ws.WatchCtx(ctx)
+			waitCtx, cancelWait := ctx, func() {}
+			if options.CheckpointInterval > 0 {
+				waitCtx, cancelWait = context.WithDeadline(ctx, lastReturned.Add(options.CheckpointInterval))
+			}
+
+			err = ws.WatchCtx(waitCtx)
+			cancelWait()
 			if err != nil {
 				switch {
+				case errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil:
+					continue
 				case errors.Is(err, context.Canceled):
 					errs <- datastore.NewWatchCanceledErr()
 				default:
@@ -65,7 +90,7 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 	return updates, errs
 }
 
-func (mdb *memdbDatastore) loadChanges(ctx context.Context, currentTxn int64) ([]*datastore.RevisionChanges, int64, <-chan struct{}, error) {
+func (mdb *memdbDatastore) loadChanges(ctx context.Context, currentTxn int64, options datastore.WatchOptions) ([]*datastore.RevisionChanges, int64, <-chan struct{}, error) {
 	mdb.RLock()
 	defer mdb.RUnlock()
 
@@ -81,8 +106,20 @@ func (mdb *memdbDatastore) loadChanges(ctx context.Context, currentTxn int64) ([
 	lastRevision := currentTxn
 	for changeRaw := it.Next(); changeRaw != nil; changeRaw = it.Next() {
 		change := changeRaw.(*changelog)
-		changes = append(changes, &change.changes)
 		lastRevision = change.revisionNanos
+
+		// The changelog entries are shared between watchers, so the returned changes are copied.
+		revisionChanges := &datastore.RevisionChanges{Revision: change.changes.Revision}
+		if options.IncludesRelationships() {
+			revisionChanges.Changes = options.Filter.FilterChanges(change.changes.Changes)
+		}
+		if options.IncludesSchema() {
+			revisionChanges.SchemaChanges = change.changes.SchemaChanges
+		}
+
+		if len(revisionChanges.Changes) > 0 || len(revisionChanges.SchemaChanges) > 0 {
+			changes = append(changes, revisionChanges)
+		}
 	}
 
 	watchChan, _, err := loadNewTxn.LastWatch(tableChangelog, indexRevision)
@@ -196,6 +196,7 @@ func newMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 		cancelGc:               cancelGc,
 		watchBufferLength:      config.watchBufferLength,
 		usersetBatchSize:       config.splitAtUsersetCount,
+		slowQueryThreshold:     config.slowQueryThreshold,
 		optimizedRevisionQuery: revisionQuery,
 		validTransactionQuery:  validTransactionQuery,
 		createTxn:              createTxn,
@@ -228,6 +229,7 @@ func newMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 				store.gcInterval,
 				store.gcWindow,
 				store.gcTimeout,
+				config.gcIntervalOverride,
 			)
 		})
 	} else {
@@ -251,8 +253,10 @@ func (mds *Datastore) SnapshotReader(revisionRaw datastore.Revision) datastore.R
 	}
 
 	querySplitter := common.TupleQuerySplitter{
-		Executor:         newMySQLExecutor(mds.db),
-		UsersetBatchSize: mds.usersetBatchSize,
+		Executor:           newMySQLExecutor(mds.db),
+		UsersetBatchSize:   mds.usersetBatchSize,
+		SlowQueryThreshold: mds.slowQueryThreshold,
+		Revision:           rev,
 	}
 
 	return &mysqlReader{
@@ -286,8 +290,9 @@ func (mds *Datastore) ReadWriteTx(
 			}
 
 			querySplitter := common.TupleQuerySplitter{
-				Executor:         newMySQLExecutor(tx),
-				UsersetBatchSize: mds.usersetBatchSize,
+				Executor:           newMySQLExecutor(tx),
+				UsersetBatchSize:   mds.usersetBatchSize,
+				SlowQueryThreshold: mds.slowQueryThreshold,
 			}
 
 			rwt := &mysqlReadWriteTXN{
@@ -412,6 +417,7 @@ type Datastore struct {
 	gcTimeout            time.Duration
 	watchBufferLength    uint16
 	usersetBatchSize     uint16
+	slowQueryThreshold   time.Duration
 	maxRetries           uint8
 
 	optimizedRevisionQuery string
@@ -14,7 +14,10 @@ import (
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
 
-var _ common.GarbageCollector = (*Datastore)(nil)
+var (
+	_ common.BoundedGarbageCollector = (*Datastore)(nil)
+	_ common.GCBacklogCounter        = (*Datastore)(nil)
+)
 
 // TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 func (mds *Datastore) Now(ctx context.Context) (time.Time, error) {
@@ -57,14 +60,40 @@ func (mds *Datastore) TxIDBefore(ctx context.Context, before time.Time) (datasto
 	return revision.NewFromDecimal(decimal.NewFromInt(value.Int64)), nil
 }
 
+func (mds *Datastore) CountTxsBefore(ctx context.Context, before time.Time) (int64, error) {
+	query, args, err := sb.Select("COUNT(*)").From(mds.driver.RelationTupleTransaction()).Where(sq.Lt{colTimestamp: before}).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := mds.db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
 // TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 // - implementation misses metrics
 func (mds *Datastore) DeleteBeforeTx(
 	ctx context.Context,
 	txID datastore.Revision,
+) (common.DeletionCounts, error) {
+	return mds.DeleteBeforeTxWithOptions(ctx, txID, common.DeletionOptions{})
+}
+
+func (mds *Datastore) DeleteBeforeTxWithOptions(
+	ctx context.Context,
+	txID datastore.Revision,
+	opts common.DeletionOptions,
 ) (removed common.DeletionCounts, err error) {
+	var deletedFilter sqlFilter = sq.LtOrEq{colDeletedTxn: txID}
+	if len(opts.Namespaces) > 0 {
+		deletedFilter = sq.And{deletedFilter, sq.Eq{colNamespace: opts.Namespaces}}
+	}
+
 	// Delete any relationship rows with deleted_transaction <= the transaction ID.
-	removed.Relationships, err = mds.batchDelete(ctx, mds.driver.RelationTuple(), sq.LtOrEq{colDeletedTxn: txID})
+	removed.Relationships, err = mds.batchDelete(ctx, mds.driver.RelationTuple(), deletedFilter, opts, &removed.Batches)
 	if err != nil {
 		return
 	}
@@ -73,27 +102,34 @@ func (mds *Datastore) DeleteBeforeTx(
 	//
 	// We don't delete the transaction itself to ensure there is always at least
 	// one transaction present.
-	removed.Transactions, err = mds.batchDelete(ctx, mds.driver.RelationTupleTransaction(), sq.Lt{colID: txID})
-	if err != nil {
-		return
+	if len(opts.Namespaces) == 0 {
+		removed.Transactions, err = mds.batchDelete(ctx, mds.driver.RelationTupleTransaction(), sq.Lt{colID: txID}, opts, &removed.Batches)
+		if err != nil {
+			return
+		}
 	}
 
 	// Delete any namespace rows with deleted_transaction <= the transaction ID.
-	removed.Namespaces, err = mds.batchDelete(ctx, mds.driver.Namespace(), sq.LtOrEq{colDeletedTxn: txID})
+	removed.Namespaces, err = mds.batchDelete(ctx, mds.driver.Namespace(), deletedFilter, opts, &removed.Batches)
 	return
 }
 
 // TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 // - query was reworked to make it compatible with Vitess
 // - API differences with PSQL driver
-func (mds *Datastore) batchDelete(ctx context.Context, tableName string, filter sqlFilter) (int64, error) {
-	query, args, err := sb.Delete(tableName).Where(filter).Limit(batchDeleteSize).ToSql()
+func (mds *Datastore) batchDelete(ctx context.Context, tableName string, filter sqlFilter, opts common.DeletionOptions, batches *int64) (int64, error) {
+	batchSize := opts.BatchSizeOr(batchDeleteSize)
+	query, args, err := sb.Delete(tableName).Where(filter).Limit(batchSize).ToSql()
 	if err != nil {
 		return -1, err
 	}
 
 	var deletedCount int64
 	for {
+		if err := opts.CheckDeadline(); err != nil {
+			return deletedCount, err
+		}
+
 		cr, err := mds.db.ExecContext(ctx, query, args...)
 		if err != nil {
 			return deletedCount, err
@@ -104,7 +140,12 @@ func (mds *Datastore) batchDelete(ctx context.Context, tableName string, filter
 			return deletedCount, err
 		}
 		deletedCount += rowsDeleted
-		if rowsDeleted < batchDeleteSize {
+		*batches++
+		log.Ctx(ctx).Trace().
+			Str("table", tableName).
+			Int64("rows", rowsDeleted).
+			Msg("datastore garbage collection batch deleted")
+		if rowsDeleted < int64(batchSize) {
 			break
 		}
 	}
@@ -31,3 +31,12 @@ func (e statementBatch) execute(ctx context.Context, wrapper TxWrapper) error {
 
 	return nil
 }
+
+// describe returns the statements of the batch for the given tables.
+func (e statementBatch) describe(tables *tables) []string {
+	statements := make([]string, 0, len(e.statements))
+	for _, stmt := range e.statements {
+		statements = append(statements, stmt(tables))
+	}
+	return statements
+}
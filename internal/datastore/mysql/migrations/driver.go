@@ -157,4 +157,103 @@ func (driver *MySQLDriver) Close(_ context.Context) error {
 	return driver.db.Close()
 }
 
-var _ migrate.Driver[Wrapper, TxWrapper] = &MySQLDriver{}
+// requiredPrivileges are the privileges on the database required to run migrations.
+var requiredPrivileges = []string{"SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "ALTER", "INDEX"}
+
+// queryPrivileges returns the privileges of the current user which apply to the current database,
+// whether granted globally or on the database.
+const queryPrivileges = `SELECT privilege_type FROM information_schema.user_privileges WHERE grantee = @grantee
+	UNION SELECT privilege_type FROM information_schema.schema_privileges WHERE grantee = @grantee AND table_schema = DATABASE()`
+
+// CheckPermissions checks that the current user has all of the privileges on the current database
+// required to run migrations.
+func (driver *MySQLDriver) CheckPermissions(ctx context.Context) error {
+	conn, err := driver.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to check privileges: %w", err)
+	}
+	defer common.LogOnError(ctx, conn.Close)
+
+	// Grantees are quoted as 'user'@'host', while CURRENT_USER() is user@host.
+	if _, err := conn.ExecContext(ctx, `SET @grantee = CONCAT("'", SUBSTRING_INDEX(CURRENT_USER(), '@', 1), "'@'", SUBSTRING_INDEX(CURRENT_USER(), '@', -1), "'")`); err != nil {
+		return fmt.Errorf("unable to check privileges: %w", err)
+	}
+
+	rows, err := conn.QueryContext(ctx, queryPrivileges)
+	if err != nil {
+		return fmt.Errorf("unable to check privileges: %w", err)
+	}
+	defer common.LogOnError(ctx, rows.Close)
+
+	granted := make(map[string]struct{}, len(requiredPrivileges))
+	for rows.Next() {
+		var privilege string
+		if err := rows.Scan(&privilege); err != nil {
+			return fmt.Errorf("unable to check privileges: %w", err)
+		}
+		granted[privilege] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("unable to check privileges: %w", err)
+	}
+
+	var missing []string
+	for _, privilege := range requiredPrivileges {
+		if _, ok := granted[privilege]; !ok {
+			missing = append(missing, privilege)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the current user lacks the privileges %v on the current database", missing)
+	}
+	return nil
+}
+
+// queryIndexes lists the indexes of the tables of the current database, of which the primary keys
+// are named PRIMARY rather than by their constraints.
+const queryIndexes = `SELECT DISTINCT table_name, index_name FROM information_schema.statistics
+	WHERE table_schema = DATABASE() AND index_name != 'PRIMARY'`
+
+// headIndexes returns the secondary indexes of the tables of the database after the head
+// migration.
+func (tn *tables) headIndexes() []string {
+	return []string{
+		tn.RelationTuple() + ".uq_relation_tuple_namespace",
+		tn.RelationTuple() + ".uq_relation_tuple_living",
+		tn.RelationTuple() + ".ix_relation_tuple_by_subject",
+		tn.RelationTuple() + ".ix_relation_tuple_by_subject_relation",
+		tn.RelationTuple() + ".ix_relation_tuple_by_deleted_transaction",
+		tn.RelationTupleTransaction() + ".ix_relation_tuple_transaction_by_timestamp",
+		tn.Namespace() + ".uq_namespace_living",
+		tn.Caveat() + ".uq_caveat",
+	}
+}
+
+// MissingIndexes returns the secondary indexes created by the migrations up to the head revision
+// which are missing from the current database.
+func (driver *MySQLDriver) MissingIndexes(ctx context.Context) ([]string, error) {
+	rows, err := driver.db.QueryContext(ctx, queryIndexes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list indexes: %w", err)
+	}
+	defer common.LogOnError(ctx, rows.Close)
+
+	present := make(map[string]struct{})
+	for rows.Next() {
+		var table, index string
+		if err := rows.Scan(&table, &index); err != nil {
+			return nil, fmt.Errorf("unable to list indexes: %w", err)
+		}
+		present[table+"."+index] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to list indexes: %w", err)
+	}
+	return migrate.MissingIndexes(driver.headIndexes(), present), nil
+}
+
+var (
+	_ migrate.Driver[Wrapper, TxWrapper] = &MySQLDriver{}
+	_ migrate.PermissionChecker          = &MySQLDriver{}
+	_ migrate.IndexChecker               = &MySQLDriver{}
+)
@@ -32,10 +32,18 @@ type TxWrapper struct {
 	tables *tables
 }
 
-func mustRegisterMigration(version, replaces string, up migrate.MigrationFunc[Wrapper], upTx migrate.TxMigrationFunc[TxWrapper]) {
-	if err := registerMigration(version, replaces, up, upTx); err != nil {
+// mustRegisterMigration registers a migration executing the batch of statements in a transaction,
+// describing them for migration plans.
+func mustRegisterMigration(version, replaces string, up migrate.MigrationFunc[Wrapper], batch statementBatch) {
+	if err := registerMigration(version, replaces, up, batch.execute); err != nil {
 		panic("failed to register migration  " + err.Error())
 	}
+
+	if err := Manager.Describe(version, func(driver *MySQLDriver) []string {
+		return batch.describe(driver.tables)
+	}); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
 }
 
 func registerMigration(version, replaces string, up migrate.MigrationFunc[Wrapper], upTx migrate.TxMigrationFunc[TxWrapper]) error {
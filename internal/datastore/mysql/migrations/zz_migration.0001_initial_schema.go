@@ -66,6 +66,6 @@ func init() {
 			createNamespaceConfig,
 			createRelationTuple,
 			createRelationTupleTransaction,
-		).execute,
+		),
 	)
 }
@@ -16,6 +16,6 @@ func init() {
 	mustRegisterMigration("add_unique_datastore_id", "initial", noNonatomicMigration,
 		newStatementBatch(
 			createMetadataTable,
-		).execute,
+		),
 	)
 }
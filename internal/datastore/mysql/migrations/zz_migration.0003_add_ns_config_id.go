@@ -21,6 +21,6 @@ func init() {
 		newStatementBatch(
 			dropNSConfigPK,
 			createNSConfigID,
-		).execute,
+		),
 	)
 }
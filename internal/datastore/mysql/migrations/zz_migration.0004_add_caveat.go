@@ -33,6 +33,6 @@ func init() {
 		newStatementBatch(
 			createCaveatTable,
 			addCaveatToRelationTuplesTable,
-		).execute,
+		),
 	)
 }
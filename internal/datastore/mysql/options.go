@@ -3,6 +3,8 @@ package mysql
 import (
 	"fmt"
 	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
 )
 
 const (
@@ -27,6 +29,7 @@ type mysqlOptions struct {
 	revisionQuantization        time.Duration
 	gcWindow                    time.Duration
 	gcInterval                  time.Duration
+	gcIntervalOverride          *common.GCIntervalOverride
 	gcMaxOperationTime          time.Duration
 	maxRevisionStalenessPercent float64
 	watchBufferLength           uint16
@@ -36,6 +39,7 @@ type mysqlOptions struct {
 	connMaxIdleTime             time.Duration
 	connMaxLifetime             time.Duration
 	splitAtUsersetCount         uint16
+	slowQueryThreshold          time.Duration
 	analyzeBeforeStats          bool
 	maxRetries                  uint8
 	lockWaitTimeoutSeconds      *uint8
@@ -129,6 +133,16 @@ func GCInterval(interval time.Duration) Option {
 	}
 }
 
+// GCIntervalOverride overrides the interval at which garbage collection will
+// occur while the datastore runs, such as when it is reloaded.
+//
+// By default, the interval is not overridden.
+func GCIntervalOverride(override *common.GCIntervalOverride) Option {
+	return func(mo *mysqlOptions) {
+		mo.gcIntervalOverride = override
+	}
+}
+
 // MaxRetries is the maximum number of times a retriable transaction will be
 // client-side retried.
 //
@@ -158,6 +172,16 @@ func SplitAtUsersetCount(splitAtUsersetCount uint16) Option {
 	}
 }
 
+// SlowQueryThreshold is the duration beyond which relationship queries are logged as slow, with
+// their SQL, the API method for which they were made and their revision.
+//
+// Slow queries are not logged by default.
+func SlowQueryThreshold(threshold time.Duration) Option {
+	return func(mo *mysqlOptions) {
+		mo.slowQueryThreshold = threshold
+	}
+}
+
 // WithEnablePrometheusStats marks whether Prometheus metrics provided by Go's database/sql package
 // are enabled.
 //
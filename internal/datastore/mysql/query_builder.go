@@ -25,6 +25,9 @@ type QueryBuilder struct {
 	QueryChangedQuery     sq.SelectBuilder
 	CountTupleQuery       sq.SelectBuilder
 
+	QueryChangedNamespacesQuery sq.SelectBuilder
+	QueryChangedCaveatsQuery    sq.SelectBuilder
+
 	WriteCaveatQuery  sq.InsertBuilder
 	ReadCaveatQuery   sq.SelectBuilder
 	ListCaveatsQuery  sq.SelectBuilder
@@ -44,6 +47,7 @@ func NewQueryBuilder(driver *migrations.MySQLDriver) *QueryBuilder {
 	builder.WriteNamespaceQuery = writeNamespace(driver.Namespace())
 	builder.ReadNamespaceQuery = readNamespace(driver.Namespace())
 	builder.DeleteNamespaceQuery = deleteNamespace(driver.Namespace())
+	builder.QueryChangedNamespacesQuery = queryChangedNamespaces(driver.Namespace())
 
 	// tuple builders
 	builder.QueryTupleIdsQuery = queryTupleIds(driver.RelationTuple())
@@ -60,10 +64,15 @@ func NewQueryBuilder(driver *migrations.MySQLDriver) *QueryBuilder {
 	builder.ListCaveatsQuery = listCaveats(driver.Caveat())
 	builder.WriteCaveatQuery = writeCaveat(driver.Caveat())
 	builder.DeleteCaveatQuery = deleteCaveat(driver.Caveat())
+	builder.QueryChangedCaveatsQuery = queryChangedCaveats(driver.Caveat())
 
 	return &builder
 }
 
+func queryChangedCaveats(tableCaveat string) sq.SelectBuilder {
+	return sb.Select(colCaveatDefinition, colCreatedTxn, colDeletedTxn).From(tableCaveat)
+}
+
 func listCaveats(tableCaveat string) sq.SelectBuilder {
 	return sb.Select(colCaveatDefinition).From(tableCaveat).OrderBy(colName)
 }
@@ -173,3 +182,7 @@ func queryChanged(tableTuple string) sq.SelectBuilder {
 		colDeletedTxn,
 	).From(tableTuple)
 }
+
+func queryChangedNamespaces(tableNamespace string) sq.SelectBuilder {
+	return sb.Select(colConfig, colCreatedTxn, colDeletedTxn).From(tableNamespace)
+}
@@ -82,6 +82,10 @@ func (mds *Datastore) Statistics(ctx context.Context) (datastore.Stats, error) {
 	}, nil
 }
 
+func (mds *Datastore) UniqueID(ctx context.Context) (string, error) {
+	return mds.getUniqueID(ctx)
+}
+
 func (mds *Datastore) getUniqueID(ctx context.Context) (string, error) {
 	sql, args, err := sb.Select(metadataUniqueIDColumn).From(mds.driver.Metadata()).ToSql()
 	if err != nil {
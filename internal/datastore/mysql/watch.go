@@ -3,6 +3,7 @@ package mysql
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
@@ -17,12 +18,12 @@ const (
 	watchSleep = 100 * time.Millisecond
 )
 
-// Watch notifies the caller about all changes to tuples.
+// Watch notifies the caller about all changes to tuples matching the filter of the options.
 //
 // All events following afterRevision will be sent to the caller.
 //
 // TODO (@vroldanbet) dupe from postgres datastore - need to refactor
-func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
 	afterRevision := afterRevisionRaw.(revision.Decimal)
 
 	updates := make(chan *datastore.RevisionChanges, mds.watchBufferLength)
@@ -34,10 +35,12 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revi
 
 		currentTxn := transactionFromRevision(afterRevision)
 
+		lastReturned := time.Now()
+
 		for {
 			var stagedUpdates []*datastore.RevisionChanges
 			var err error
-			stagedUpdates, currentTxn, err = mds.loadChanges(ctx, currentTxn)
+			stagedUpdates, currentTxn, err = mds.loadChanges(ctx, currentTxn, options)
 			if err != nil {
 				if errors.Is(ctx.Err(), context.Canceled) {
 					errs <- datastore.NewWatchCanceledErr()
@@ -55,6 +58,17 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revi
 					errs <- datastore.NewWatchDisconnectedErr()
 					return
 				}
+				lastReturned = time.Now()
+			}
+
+			if options.CheckpointDue(lastReturned) {
+				select {
+				case updates <- &datastore.RevisionChanges{Revision: revisionFromTransaction(currentTxn), IsCheckpoint: true}:
+				default:
+					errs <- datastore.NewWatchDisconnectedErr()
+					return
+				}
+				lastReturned = time.Now()
 			}
 
 			// If there were no changes, sleep a bit
@@ -79,6 +93,7 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revi
 func (mds *Datastore) loadChanges(
 	ctx context.Context,
 	afterRevision uint64,
+	options datastore.WatchOptions,
 ) (changes []*datastore.RevisionChanges, newRevision uint64, err error) {
 	newRevision, err = mds.loadRevision(ctx)
 	if err != nil {
@@ -89,7 +104,35 @@ func (mds *Datastore) loadChanges(
 		return
 	}
 
-	sql, args, err := mds.QueryChangedQuery.Where(sq.Or{
+	stagedChanges := common.NewChanges()
+
+	if options.IncludesRelationships() {
+		err = mds.loadRelationshipChanges(ctx, afterRevision, newRevision, options.Filter, stagedChanges)
+		if err != nil {
+			return
+		}
+	}
+
+	if options.IncludesSchema() {
+		err = mds.loadSchemaChanges(ctx, afterRevision, newRevision, stagedChanges)
+		if err != nil {
+			return
+		}
+	}
+
+	changes = stagedChanges.AsRevisionChanges(mds)
+
+	return
+}
+
+func (mds *Datastore) loadRelationshipChanges(
+	ctx context.Context,
+	afterRevision uint64,
+	newRevision uint64,
+	filter datastore.WatchFilter,
+	stagedChanges common.Changes,
+) (err error) {
+	sql, args, err := common.FilterToWatchFilter(mds.QueryChangedQuery, schema, filter).Where(sq.Or{
 		sq.And{
 			sq.Gt{colCreatedTxn: afterRevision},
 			sq.LtOrEq{colCreatedTxn: newRevision},
@@ -112,8 +155,6 @@ func (mds *Datastore) loadChanges(
 	}
 	defer common.LogOnError(ctx, rows.Close)
 
-	stagedChanges := common.NewChanges()
-
 	for rows.Next() {
 		nextTuple := &core.RelationTuple{
 			ResourceAndRelation: &core.ObjectAndRelation{},
@@ -152,11 +193,83 @@ func (mds *Datastore) loadChanges(
 			stagedChanges.AddChange(ctx, revisionFromTransaction(deletedTxn), nextTuple, core.RelationTupleUpdate_DELETE)
 		}
 	}
-	if err = rows.Err(); err != nil {
-		return
+	err = rows.Err()
+	return
+}
+
+// loadSchemaChanges loads the changes made to the namespace and caveat definitions in the
+// revision range. Definitions are never updated in place: a rewritten definition has its
+// current row deleted and a new row created in the same transaction.
+func (mds *Datastore) loadSchemaChanges(
+	ctx context.Context,
+	afterRevision uint64,
+	newRevision uint64,
+	stagedChanges common.Changes,
+) error {
+	if err := mds.loadChangedDefinitions(ctx, mds.QueryChangedNamespacesQuery, afterRevision, newRevision, stagedChanges, func(serialized []byte) (datastore.SchemaDefinition, error) {
+		loaded := &core.NamespaceDefinition{}
+		return loaded, loaded.UnmarshalVT(serialized)
+	}); err != nil {
+		return err
 	}
 
-	changes = stagedChanges.AsRevisionChanges(mds)
+	return mds.loadChangedDefinitions(ctx, mds.QueryChangedCaveatsQuery, afterRevision, newRevision, stagedChanges, func(serialized []byte) (datastore.SchemaDefinition, error) {
+		loaded := &core.CaveatDefinition{}
+		return loaded, loaded.UnmarshalVT(serialized)
+	})
+}
 
-	return
+func (mds *Datastore) loadChangedDefinitions(
+	ctx context.Context,
+	query sq.SelectBuilder,
+	afterRevision uint64,
+	newRevision uint64,
+	stagedChanges common.Changes,
+	decode func([]byte) (datastore.SchemaDefinition, error),
+) error {
+	sql, args, err := query.Where(sq.Or{
+		sq.And{
+			sq.Gt{colCreatedTxn: afterRevision},
+			sq.LtOrEq{colCreatedTxn: newRevision},
+		},
+		sq.And{
+			sq.Gt{colDeletedTxn: afterRevision},
+			sq.LtOrEq{colDeletedTxn: newRevision},
+		},
+	}).ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to prepare schema changes SQL: %w", err)
+	}
+
+	rows, err := mds.db.QueryContext(ctx, sql, args...)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return datastore.NewWatchCanceledErr()
+		}
+		return fmt.Errorf("unable to load schema changes: %w", err)
+	}
+	defer common.LogOnError(ctx, rows.Close)
+
+	for rows.Next() {
+		var serialized []byte
+		var createdTxn uint64
+		var deletedTxn uint64
+		if err := rows.Scan(&serialized, &createdTxn, &deletedTxn); err != nil {
+			return fmt.Errorf("unable to parse changed definition: %w", err)
+		}
+
+		def, err := decode(serialized)
+		if err != nil {
+			return fmt.Errorf("unable to decode changed definition: %w", err)
+		}
+
+		if createdTxn > afterRevision && createdTxn <= newRevision {
+			stagedChanges.AddChangedDefinition(revisionFromTransaction(createdTxn), def)
+		}
+
+		if deletedTxn > afterRevision && deletedTxn <= newRevision {
+			stagedChanges.AddDeletedDefinition(revisionFromTransaction(deletedTxn), def)
+		}
+	}
+	return rows.Err()
 }
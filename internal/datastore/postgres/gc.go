@@ -15,7 +15,8 @@ import (
 )
 
 var (
-	_ common.GarbageCollector = (*pgDatastore)(nil)
+	_ common.BoundedGarbageCollector = (*pgDatastore)(nil)
+	_ common.GCBacklogCounter        = (*pgDatastore)(nil)
 
 	relationTuplePKCols = []string{
 		colNamespace,
@@ -72,7 +73,28 @@ func (pgd *pgDatastore) TxIDBefore(ctx context.Context, before time.Time) (datas
 	return postgresRevision{value, xmin}, nil
 }
 
-func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revision) (removed common.DeletionCounts, err error) {
+func (pgd *pgDatastore) CountTxsBefore(ctx context.Context, before time.Time) (int64, error) {
+	sql, args, err := psql.Select("COUNT(*)").From(tableTransaction).Where(sq.Lt{colTimestamp: before}).ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	if err := pgd.dbpool.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revision) (common.DeletionCounts, error) {
+	return pgd.DeleteBeforeTxWithOptions(ctx, txID, common.DeletionOptions{})
+}
+
+func (pgd *pgDatastore) DeleteBeforeTxWithOptions(
+	ctx context.Context,
+	txID datastore.Revision,
+	opts common.DeletionOptions,
+) (removed common.DeletionCounts, err error) {
 	revision := txID.(postgresRevision)
 
 	minTxAlive := revision.tx
@@ -80,12 +102,20 @@ func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revis
 		minTxAlive = revision.xmin
 	}
 
+	var relationshipsFilter, namespacesFilter sqlFilter = sq.Lt{colDeletedXid: minTxAlive}, sq.Lt{colDeletedXid: minTxAlive}
+	if len(opts.Namespaces) > 0 {
+		relationshipsFilter = sq.And{relationshipsFilter, sq.Eq{colNamespace: opts.Namespaces}}
+		namespacesFilter = sq.And{namespacesFilter, sq.Eq{colNamespace: opts.Namespaces}}
+	}
+
 	// Delete any relationship rows that were already dead when this transaction started
 	removed.Relationships, err = pgd.batchDelete(
 		ctx,
 		tableTuple,
 		relationTuplePKCols,
-		sq.Lt{colDeletedXid: minTxAlive},
+		relationshipsFilter,
+		opts,
+		&removed.Batches,
 	)
 	if err != nil {
 		return
@@ -95,14 +125,18 @@ func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revis
 	//
 	// We don't delete the transaction itself to ensure there is always at least
 	// one transaction present.
-	removed.Transactions, err = pgd.batchDelete(
-		ctx,
-		tableTransaction,
-		transactionPKCols,
-		sq.Lt{colXID: revision.tx},
-	)
-	if err != nil {
-		return
+	if len(opts.Namespaces) == 0 {
+		removed.Transactions, err = pgd.batchDelete(
+			ctx,
+			tableTransaction,
+			transactionPKCols,
+			sq.Lt{colXID: revision.tx},
+			opts,
+			&removed.Batches,
+		)
+		if err != nil {
+			return
+		}
 	}
 
 	// Delete any namespace rows with deleted_transaction <= the transaction ID.
@@ -110,7 +144,9 @@ func (pgd *pgDatastore) DeleteBeforeTx(ctx context.Context, txID datastore.Revis
 		ctx,
 		tableNamespace,
 		namespacePKCols,
-		sq.Lt{colDeletedXid: minTxAlive},
+		namespacesFilter,
+		opts,
+		&removed.Batches,
 	)
 	if err != nil {
 		return
@@ -124,8 +160,11 @@ func (pgd *pgDatastore) batchDelete(
 	tableName string,
 	pkCols []string,
 	filter sqlFilter,
+	opts common.DeletionOptions,
+	batches *int64,
 ) (int64, error) {
-	sql, args, err := psql.Select(pkCols...).From(tableName).Where(filter).Limit(batchDeleteSize).ToSql()
+	batchSize := opts.BatchSizeOr(batchDeleteSize)
+	sql, args, err := psql.Select(pkCols...).From(tableName).Where(filter).Limit(batchSize).ToSql()
 	if err != nil {
 		return -1, err
 	}
@@ -139,6 +178,10 @@ func (pgd *pgDatastore) batchDelete(
 
 	var deletedCount int64
 	for {
+		if err := opts.CheckDeadline(); err != nil {
+			return deletedCount, err
+		}
+
 		cr, err := pgd.dbpool.Exec(ctx, query, args...)
 		if err != nil {
 			return deletedCount, err
@@ -146,7 +189,12 @@ func (pgd *pgDatastore) batchDelete(
 
 		rowsDeleted := cr.RowsAffected()
 		deletedCount += rowsDeleted
-		if rowsDeleted < batchDeleteSize {
+		*batches++
+		log.Ctx(ctx).Trace().
+			Str("table", tableName).
+			Int64("rows", rowsDeleted).
+			Msg("datastore garbage collection batch deleted")
+		if rowsDeleted < int64(batchSize) {
 			break
 		}
 	}
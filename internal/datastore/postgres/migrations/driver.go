@@ -88,4 +88,92 @@ func (apd *AlembicPostgresDriver) WriteVersion(ctx context.Context, tx pgx.Tx, v
 	return nil
 }
 
-var _ migrate.Driver[*pgx.Conn, pgx.Tx] = &AlembicPostgresDriver{}
+// queryUnownedTables returns the tables of the current schema which are not owned by a role of the
+// current user, and so cannot be altered by migrations.
+const queryUnownedTables = `SELECT tablename FROM pg_tables
+	WHERE schemaname = current_schema()
+	AND NOT pg_has_role(current_user, tableowner, 'MEMBER')
+	ORDER BY tablename`
+
+// CheckPermissions checks that the current user can create tables in the current schema and owns
+// all of its existing tables, which migrations alter.
+func (apd *AlembicPostgresDriver) CheckPermissions(ctx context.Context) error {
+	var canCreate *bool
+	if err := apd.db.QueryRow(ctx, "SELECT has_schema_privilege(current_schema(), 'CREATE')").Scan(&canCreate); err != nil {
+		return fmt.Errorf("unable to check schema privileges: %w", err)
+	}
+	if canCreate == nil {
+		return fmt.Errorf("no schema of the search path exists")
+	}
+	if !*canCreate {
+		return fmt.Errorf("the current user lacks the CREATE privilege on the current schema")
+	}
+
+	rows, err := apd.db.Query(ctx, queryUnownedTables)
+	if err != nil {
+		return fmt.Errorf("unable to check table ownership: %w", err)
+	}
+	defer rows.Close()
+
+	var unowned []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return fmt.Errorf("unable to check table ownership: %w", err)
+		}
+		unowned = append(unowned, table)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("unable to check table ownership: %w", err)
+	}
+	if len(unowned) > 0 {
+		return fmt.Errorf("the current user does not own tables %v, which migrations alter", unowned)
+	}
+	return nil
+}
+
+// headIndexes are the indexes of the tables of the current schema after the head migration,
+// including those of the constraints which were added using the indexes built concurrently.
+var headIndexes = []string{
+	"relation_tuple.pk_relation_tuple",
+	"relation_tuple.uq_relation_tuple_living_xid",
+	"relation_tuple.ix_relation_tuple_by_subject",
+	"relation_tuple.ix_relation_tuple_by_subject_relation",
+	"relation_tuple_transaction.pk_rttx",
+	"relation_tuple_transaction.ix_relation_tuple_transaction_by_timestamp",
+	"namespace_config.pk_namespace_config",
+	"namespace_config.uq_namespace_living_xid",
+	"caveat.pk_caveat_v2",
+	"caveat.uq_caveat_v2",
+}
+
+const queryIndexes = `SELECT tablename, indexname FROM pg_indexes WHERE schemaname = current_schema()`
+
+// MissingIndexes returns the indexes created by the migrations up to the head revision which are
+// missing from the current schema, such as those left invalid by a failed concurrent build.
+func (apd *AlembicPostgresDriver) MissingIndexes(ctx context.Context) ([]string, error) {
+	rows, err := apd.db.Query(ctx, queryIndexes)
+	if err != nil {
+		return nil, fmt.Errorf("unable to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	present := make(map[string]struct{})
+	for rows.Next() {
+		var table, index string
+		if err := rows.Scan(&table, &index); err != nil {
+			return nil, fmt.Errorf("unable to list indexes: %w", err)
+		}
+		present[table+"."+index] = struct{}{}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("unable to list indexes: %w", err)
+	}
+	return migrate.MissingIndexes(headIndexes, present), nil
+}
+
+var (
+	_ migrate.Driver[*pgx.Conn, pgx.Tx] = &AlembicPostgresDriver{}
+	_ migrate.PermissionChecker         = &AlembicPostgresDriver{}
+	_ migrate.IndexChecker              = &AlembicPostgresDriver{}
+)
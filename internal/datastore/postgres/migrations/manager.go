@@ -13,3 +13,11 @@ var (
 
 // DatabaseMigrations implements a migration manager for the Postgres Driver.
 var DatabaseMigrations = migrate.NewManager[*AlembicPostgresDriver, *pgx.Conn, pgx.Tx]()
+
+// mustDescribe records the statements executed by the migration to a version, which are shown
+// when planning migrations.
+func mustDescribe(version string, statements ...string) {
+	if err := DatabaseMigrations.Describe(version, func(*AlembicPostgresDriver) []string { return statements }); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -62,4 +62,13 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("1eaeba4b8a73",
+		createRelationTupleTransaction,
+		createNamespaceConfig,
+		createRelationTuple,
+		insertFirstTransaction,
+		createAlembicVersion,
+		insertEmptyVersion,
+	)
 }
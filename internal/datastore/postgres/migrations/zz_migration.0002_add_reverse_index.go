@@ -25,4 +25,6 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-reverse-index", createReverseQueryIndex, createReverseCheckIndex)
 }
@@ -30,4 +30,6 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-unique-living-ns", deleteAllButNewestNamespace, createUniqueLivingNamespaceConstraint)
 }
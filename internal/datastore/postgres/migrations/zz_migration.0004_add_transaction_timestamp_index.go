@@ -17,4 +17,6 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-transaction-timestamp-index", createIndexOnTupleTransactionTimestamp)
 }
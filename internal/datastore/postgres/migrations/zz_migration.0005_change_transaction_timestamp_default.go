@@ -19,4 +19,6 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("change-transaction-timestamp-default", alterTimestampDefaultValue)
 }
@@ -20,4 +20,6 @@ func init() {
 	); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-gc-index", createDeletedTransactionIndex)
 }
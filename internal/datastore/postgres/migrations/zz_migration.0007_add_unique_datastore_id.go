@@ -27,4 +27,6 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-unique-datastore-id", createUniqueIDTable, insertUniqueID)
 }
@@ -29,4 +29,6 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-ns-config-id", dropNSConfigPK, createNSConfigID)
 }
@@ -33,4 +33,6 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-caveats", caveatStatements...)
 }
@@ -53,4 +53,12 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-xid-columns",
+		addTransactionXIDColumns,
+		addTupleXIDColumns,
+		addNamespaceXIDColumns,
+		addCaveatXIDColumns,
+		addTransactionDefault,
+	)
 }
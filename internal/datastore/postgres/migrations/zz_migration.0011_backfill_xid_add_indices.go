@@ -3,6 +3,7 @@ package migrations
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
@@ -116,6 +117,17 @@ var dropBackfillIndices = []string{
 	"DROP INDEX ix_backfill_caveat_temp",
 }
 
+// backfillStatements returns the statements of the migration, where the backfills run repeatedly
+// until no rows are left to update.
+func backfillStatements() []string {
+	statements := append([]string{}, addBackfillIndices...)
+	for _, stmt := range backfills {
+		statements = append(statements, strings.ReplaceAll(stmt, "%d", "<migration-backfill-batch-size>"))
+	}
+	statements = append(statements, addXIDIndices...)
+	return append(statements, dropBackfillIndices...)
+}
+
 func init() {
 	if err := DatabaseMigrations.Register("backfill-xid-add-indices", "add-xid-columns",
 		func(ctx context.Context, conn *pgx.Conn) error {
@@ -154,4 +166,6 @@ func init() {
 	); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("backfill-xid-add-indices", backfillStatements()...)
 }
@@ -73,4 +73,6 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-xid-constraints", append([]string{fmt.Sprintf(dropNSConfigIDPkey, defaultNSConfigPkeyName)}, addXIDConstraints...)...)
 }
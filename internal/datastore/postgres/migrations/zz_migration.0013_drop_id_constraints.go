@@ -36,4 +36,6 @@ func init() {
 		}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("drop-id-constraints", dropIDConstraints...)
 }
@@ -3,6 +3,8 @@ package postgres
 import (
 	"fmt"
 	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
 )
 
 type postgresOptions struct {
@@ -17,8 +19,10 @@ type postgresOptions struct {
 	revisionQuantization time.Duration
 	gcWindow             time.Duration
 	gcInterval           time.Duration
+	gcIntervalOverride   *common.GCIntervalOverride
 	gcMaxOperationTime   time.Duration
 	splitAtUsersetCount  uint16
+	slowQueryThreshold   time.Duration
 	maxRetries           uint8
 
 	enablePrometheusStats   bool
@@ -107,6 +111,16 @@ func SplitAtUsersetCount(splitAtUsersetCount uint16) Option {
 	}
 }
 
+// SlowQueryThreshold is the duration beyond which relationship queries are logged as slow, with
+// their SQL, the API method for which they were made and their revision.
+//
+// Slow queries are not logged by default.
+func SlowQueryThreshold(threshold time.Duration) Option {
+	return func(po *postgresOptions) {
+		po.slowQueryThreshold = threshold
+	}
+}
+
 // ConnMaxIdleTime is the duration after which an idle connection will be
 // automatically closed by the health check.
 //
@@ -205,6 +219,16 @@ func GCInterval(interval time.Duration) Option {
 	}
 }
 
+// GCIntervalOverride overrides the interval at which garbage collection will
+// occur while the datastore runs, such as when it is reloaded.
+//
+// By default, the interval is not overridden.
+func GCIntervalOverride(override *common.GCIntervalOverride) Option {
+	return func(po *postgresOptions) {
+		po.gcIntervalOverride = override
+	}
+}
+
 // GCMaxOperationTime is the maximum operation time of a garbage collection
 // pass before it times out.
 //
@@ -217,6 +217,7 @@ func newPostgresDatastore(
 		gcTimeout:               config.gcMaxOperationTime,
 		analyzeBeforeStatistics: config.analyzeBeforeStatistics,
 		usersetBatchSize:        config.splitAtUsersetCount,
+		slowQueryThreshold:      config.slowQueryThreshold,
 		watchEnabled:            watchEnabled,
 		gcCtx:                   gcCtx,
 		cancelGc:                cancelGc,
@@ -236,6 +237,7 @@ func newPostgresDatastore(
 				datastore.gcInterval,
 				datastore.gcWindow,
 				datastore.gcTimeout,
+				config.gcIntervalOverride,
 			)
 		})
 	} else {
@@ -277,6 +279,7 @@ type pgDatastore struct {
 	gcInterval              time.Duration
 	gcTimeout               time.Duration
 	usersetBatchSize        uint16
+	slowQueryThreshold      time.Duration
 	analyzeBeforeStatistics bool
 	readTxOptions           pgx.TxOptions
 	maxRetries              uint8
@@ -306,8 +309,10 @@ func (pgd *pgDatastore) SnapshotReader(revRaw datastore.Revision) datastore.Read
 	}
 
 	querySplitter := common.TupleQuerySplitter{
-		Executor:         pgxcommon.NewPGXExecutor(createTxFunc),
-		UsersetBatchSize: pgd.usersetBatchSize,
+		Executor:           pgxcommon.NewPGXExecutor(createTxFunc),
+		UsersetBatchSize:   pgd.usersetBatchSize,
+		SlowQueryThreshold: pgd.slowQueryThreshold,
+		Revision:           rev,
 	}
 
 	return &pgReader{
@@ -340,8 +345,9 @@ func (pgd *pgDatastore) ReadWriteTx(
 			}
 
 			querySplitter := common.TupleQuerySplitter{
-				Executor:         pgxcommon.NewPGXExecutor(longLivedTx),
-				UsersetBatchSize: pgd.usersetBatchSize,
+				Executor:           pgxcommon.NewPGXExecutor(longLivedTx),
+				UsersetBatchSize:   pgd.usersetBatchSize,
+				SlowQueryThreshold: pgd.slowQueryThreshold,
 			}
 
 			rwt := &pgReadWriteTXN{
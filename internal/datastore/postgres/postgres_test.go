@@ -700,6 +700,7 @@ func WatchNotEnabledTest(t *testing.T, b testdatastore.RunningEngineForTest) {
 	_, errChan := ds.Watch(
 		context.Background(),
 		revision,
+		datastore.WatchOptions{},
 	)
 	err := <-errChan
 	require.NotNil(err)
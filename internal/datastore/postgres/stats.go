@@ -28,6 +28,19 @@ var (
 				Where(sq.Eq{colRelname: tableTuple})
 )
 
+func (pgd *pgDatastore) UniqueID(ctx context.Context) (string, error) {
+	idSQL, idArgs, err := queryUniqueID.ToSql()
+	if err != nil {
+		return "", fmt.Errorf("unable to generate query sql: %w", err)
+	}
+
+	var uniqueID string
+	if err := pgd.dbpool.QueryRow(ctx, idSQL, idArgs...).Scan(&uniqueID); err != nil {
+		return "", fmt.Errorf("unable to query unique ID: %w", err)
+	}
+	return uniqueID, nil
+}
+
 func (pgd *pgDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
 	idSQL, idArgs, err := queryUniqueID.ToSql()
 	if err != nil {
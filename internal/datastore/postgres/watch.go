@@ -42,11 +42,15 @@ var (
 		colCreatedXid,
 		colDeletedXid,
 	).From(tableTuple)
+
+	queryChangedNamespaces = psql.Select(colConfig, colCreatedXid).From(tableNamespace)
+	queryChangedCaveats    = psql.Select(colCaveatDefinition, colCreatedXid).From(tableCaveat)
 )
 
 func (pgd *pgDatastore) Watch(
 	ctx context.Context,
 	afterRevisionRaw datastore.Revision,
+	options datastore.WatchOptions,
 ) (<-chan *datastore.RevisionChanges, <-chan error) {
 	updates := make(chan *datastore.RevisionChanges, pgd.watchBufferLength)
 	errs := make(chan error, 1)
@@ -63,6 +67,7 @@ func (pgd *pgDatastore) Watch(
 		defer close(errs)
 
 		currentTxn := afterRevision.tx
+		lastReturned := time.Now()
 
 		for {
 			newTxns, err := pgd.getNewRevisions(ctx, currentTxn)
@@ -76,7 +81,7 @@ func (pgd *pgDatastore) Watch(
 			}
 
 			for _, revision := range newTxns {
-				changeToWrite, err := pgd.loadChanges(ctx, revision)
+				changeToWrite, err := pgd.loadChanges(ctx, revision, options)
 				if err != nil {
 					if errors.Is(ctx.Err(), context.Canceled) {
 						errs <- datastore.NewWatchCanceledErr()
@@ -86,6 +91,13 @@ func (pgd *pgDatastore) Watch(
 					return
 				}
 
+				// Revisions without any change matching the filter are skipped, leaving the
+				// checkpoints to report the progress of the watch.
+				if len(changeToWrite.Changes) == 0 && len(changeToWrite.SchemaChanges) == 0 {
+					currentTxn = revision
+					continue
+				}
+
 				select {
 				case updates <- changeToWrite:
 				default:
@@ -94,6 +106,17 @@ func (pgd *pgDatastore) Watch(
 				}
 
 				currentTxn = revision
+				lastReturned = time.Now()
+			}
+
+			if options.CheckpointDue(lastReturned) {
+				select {
+				case updates <- &datastore.RevisionChanges{Revision: postgresRevision{currentTxn, noXmin}, IsCheckpoint: true}:
+				default:
+					errs <- datastore.NewWatchDisconnectedErr()
+					return
+				}
+				lastReturned = time.Now()
 			}
 
 			if len(newTxns) == 0 {
@@ -139,21 +162,45 @@ func (pgd *pgDatastore) getNewRevisions(
 	return ids, nil
 }
 
-func (pgd *pgDatastore) loadChanges(ctx context.Context, revision xid8) (*datastore.RevisionChanges, error) {
-	sql, args, err := queryChanged.Where(sq.Or{
+func (pgd *pgDatastore) loadChanges(ctx context.Context, revision xid8, options datastore.WatchOptions) (*datastore.RevisionChanges, error) {
+	tracked := common.NewChanges()
+
+	if options.IncludesRelationships() {
+		if err := pgd.loadRelationshipChanges(ctx, revision, options.Filter, tracked); err != nil {
+			return nil, err
+		}
+	}
+
+	if options.IncludesSchema() {
+		if err := pgd.loadSchemaChanges(ctx, revision, tracked); err != nil {
+			return nil, err
+		}
+	}
+
+	reconciledChanges := tracked.AsRevisionChanges(pgd)
+	if len(reconciledChanges) == 0 {
+		return &datastore.RevisionChanges{
+			Revision: postgresRevision{revision, noXmin},
+		}, nil
+	}
+	return reconciledChanges[0], nil
+}
+
+func (pgd *pgDatastore) loadRelationshipChanges(ctx context.Context, revision xid8, filter datastore.WatchFilter, tracked common.Changes) error {
+	sql, args, err := common.FilterToWatchFilter(queryChanged, schema, filter).Where(sq.Or{
 		sq.Eq{colCreatedXid: revision},
 		sq.Eq{colDeletedXid: revision},
 	}).ToSql()
 	if err != nil {
-		return nil, fmt.Errorf("unable to prepare changes SQL: %w", err)
+		return fmt.Errorf("unable to prepare changes SQL: %w", err)
 	}
 
 	changes, err := pgd.dbpool.Query(ctx, sql, args...)
 	if err != nil {
-		return nil, fmt.Errorf("unable to load changes for XID: %w", err)
+		return fmt.Errorf("unable to load changes for XID: %w", err)
 	}
+	defer changes.Close()
 
-	tracked := common.NewChanges()
 	for changes.Next() {
 		nextTuple := &core.RelationTuple{
 			ResourceAndRelation: &core.ObjectAndRelation{},
@@ -175,13 +222,13 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revision xid8) (*datast
 			&createdXID,
 			&deletedXID,
 		); err != nil {
-			return nil, fmt.Errorf("unable to parse changed tuple: %w", err)
+			return fmt.Errorf("unable to parse changed tuple: %w", err)
 		}
 
 		if caveatName != "" {
 			contextStruct, err := structpb.NewStruct(caveatContext)
 			if err != nil {
-				return nil, fmt.Errorf("failed to read caveat context from update: %w", err)
+				return fmt.Errorf("failed to read caveat context from update: %w", err)
 			}
 			nextTuple.Caveat = &core.ContextualizedCaveat{
 				CaveatName: caveatName,
@@ -196,14 +243,71 @@ func (pgd *pgDatastore) loadChanges(ctx context.Context, revision xid8) (*datast
 		}
 	}
 	if changes.Err() != nil {
-		return nil, fmt.Errorf("unable to load changes for XID: %w", err)
+		return fmt.Errorf("unable to load changes for XID: %w", err)
 	}
 
-	reconciledChanges := tracked.AsRevisionChanges(pgd)
-	if len(reconciledChanges) == 0 {
-		return &datastore.RevisionChanges{
-			Revision: postgresRevision{revision, noXmin},
-		}, nil
+	return nil
+}
+
+// loadSchemaChanges loads the changes made to the namespace and caveat definitions at the
+// revision. Definitions are never updated in place: a rewritten definition has its current
+// row deleted and a new row created at the same revision.
+func (pgd *pgDatastore) loadSchemaChanges(ctx context.Context, revision xid8, tracked common.Changes) error {
+	if err := pgd.loadChangedDefinitions(ctx, queryChangedNamespaces, revision, tracked, func(serialized []byte) (datastore.SchemaDefinition, error) {
+		loaded := &core.NamespaceDefinition{}
+		return loaded, loaded.UnmarshalVT(serialized)
+	}); err != nil {
+		return err
 	}
-	return reconciledChanges[0], nil
+
+	return pgd.loadChangedDefinitions(ctx, queryChangedCaveats, revision, tracked, func(serialized []byte) (datastore.SchemaDefinition, error) {
+		loaded := &core.CaveatDefinition{}
+		return loaded, loaded.UnmarshalVT(serialized)
+	})
+}
+
+func (pgd *pgDatastore) loadChangedDefinitions(
+	ctx context.Context,
+	query sq.SelectBuilder,
+	revision xid8,
+	tracked common.Changes,
+	decode func([]byte) (datastore.SchemaDefinition, error),
+) error {
+	sql, args, err := query.Where(sq.Or{
+		sq.Eq{colCreatedXid: revision},
+		sq.Eq{colDeletedXid: revision},
+	}).ToSql()
+	if err != nil {
+		return fmt.Errorf("unable to prepare schema changes SQL: %w", err)
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return fmt.Errorf("unable to load schema changes for XID: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var serialized []byte
+		var createdXID xid8
+		if err := rows.Scan(&serialized, &createdXID); err != nil {
+			return fmt.Errorf("unable to parse changed definition: %w", err)
+		}
+
+		def, err := decode(serialized)
+		if err != nil {
+			return fmt.Errorf("unable to decode changed definition: %w", err)
+		}
+
+		if createdXID.Uint == revision.Uint {
+			tracked.AddChangedDefinition(postgresRevision{revision, noXmin}, def)
+		} else {
+			tracked.AddDeletedDefinition(postgresRevision{revision, noXmin}, def)
+		}
+	}
+	if rows.Err() != nil {
+		return fmt.Errorf("unable to load schema changes for XID: %w", rows.Err())
+	}
+
+	return nil
 }
@@ -58,8 +58,8 @@ func (p *ctxProxy) RevisionFromString(serialized string) (datastore.Revision, er
 	return p.delegate.RevisionFromString(serialized)
 }
 
-func (p *ctxProxy) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
-	return p.delegate.Watch(ctx, afterRevision)
+func (p *ctxProxy) Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
+	return p.delegate.Watch(ctx, afterRevision, options)
 }
 
 func (p *ctxProxy) Features(ctx context.Context) (*datastore.Features, error) {
@@ -70,6 +70,10 @@ func (p *ctxProxy) Statistics(ctx context.Context) (datastore.Stats, error) {
 	return p.delegate.Statistics(SeparateContextWithTracing(ctx))
 }
 
+func (p *ctxProxy) UniqueID(ctx context.Context) (string, error) {
+	return p.delegate.UniqueID(SeparateContextWithTracing(ctx))
+}
+
 func (p *ctxProxy) IsReady(ctx context.Context) (bool, error) {
 	return p.delegate.IsReady(SeparateContextWithTracing(ctx))
 }
@@ -0,0 +1,148 @@
+package proxy
+
+import (
+	"context"
+	"sync/atomic"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+type countingDatastore struct {
+	datastore.Datastore
+	queries *atomic.Uint64
+	rows    *atomic.Uint64
+}
+
+// NewCountingDatastore creates a proxy which counts the queries made to a downstream delegate
+// datastore through its readers and read-write transactions into the given counter. Queries
+// served by caches in the delegate are counted as well.
+func NewCountingDatastore(delegate datastore.Datastore, queries *atomic.Uint64) datastore.Datastore {
+	return countingDatastore{Datastore: delegate, queries: queries}
+}
+
+// NewRowCountingDatastore creates a proxy which counts the queries made to a downstream delegate
+// datastore as NewCountingDatastore, and also counts the relationships read by those queries into
+// the rows counter.
+func NewRowCountingDatastore(delegate datastore.Datastore, queries *atomic.Uint64, rows *atomic.Uint64) datastore.Datastore {
+	return countingDatastore{Datastore: delegate, queries: queries, rows: rows}
+}
+
+func (cd countingDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return countingReader{cd.Datastore.SnapshotReader(rev), cd.queries, cd.rows}
+}
+
+func (cd countingDatastore) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	return cd.Datastore.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return f(countingReadWriteTx{countingReader{rwt, cd.queries, cd.rows}, rwt})
+	})
+}
+
+type countingReader struct {
+	datastore.Reader
+	queries *atomic.Uint64
+	rows    *atomic.Uint64
+}
+
+func (cr countingReader) ReadCaveatByName(ctx context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
+	cr.queries.Add(1)
+	return cr.Reader.ReadCaveatByName(ctx, name)
+}
+
+func (cr countingReader) ListCaveats(ctx context.Context, caveatNamesForFiltering ...string) ([]*core.CaveatDefinition, error) {
+	cr.queries.Add(1)
+	return cr.Reader.ListCaveats(ctx, caveatNamesForFiltering...)
+}
+
+func (cr countingReader) QueryRelationships(
+	ctx context.Context,
+	filter datastore.RelationshipsFilter,
+	opts ...options.QueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	cr.queries.Add(1)
+	return cr.countRows(cr.Reader.QueryRelationships(ctx, filter, opts...))
+}
+
+func (cr countingReader) ReverseQueryRelationships(
+	ctx context.Context,
+	subjectsFilter datastore.SubjectsFilter,
+	opts ...options.ReverseQueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	cr.queries.Add(1)
+	return cr.countRows(cr.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...))
+}
+
+// countRows wraps the iterator of a query to count the relationships it reads, if rows are
+// counted.
+func (cr countingReader) countRows(it datastore.RelationshipIterator, err error) (datastore.RelationshipIterator, error) {
+	if err != nil || cr.rows == nil {
+		return it, err
+	}
+	return countingIterator{it, cr.rows}, nil
+}
+
+func (cr countingReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	cr.queries.Add(1)
+	return cr.Reader.ReadNamespace(ctx, nsName)
+}
+
+func (cr countingReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
+	cr.queries.Add(1)
+	return cr.Reader.ListNamespaces(ctx)
+}
+
+func (cr countingReader) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
+	cr.queries.Add(1)
+	return cr.Reader.LookupNamespaces(ctx, nsNames)
+}
+
+type countingReadWriteTx struct {
+	countingReader
+	rwt datastore.ReadWriteTransaction
+}
+
+func (crwt countingReadWriteTx) WriteCaveats(ctx context.Context, caveats []*core.CaveatDefinition) error {
+	crwt.queries.Add(1)
+	return crwt.rwt.WriteCaveats(ctx, caveats)
+}
+
+func (crwt countingReadWriteTx) DeleteCaveats(ctx context.Context, names []string) error {
+	crwt.queries.Add(1)
+	return crwt.rwt.DeleteCaveats(ctx, names)
+}
+
+func (crwt countingReadWriteTx) WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
+	crwt.queries.Add(1)
+	return crwt.rwt.WriteRelationships(ctx, mutations)
+}
+
+func (crwt countingReadWriteTx) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
+	crwt.queries.Add(1)
+	return crwt.rwt.DeleteRelationships(ctx, filter)
+}
+
+func (crwt countingReadWriteTx) WriteNamespaces(ctx context.Context, newConfigs ...*core.NamespaceDefinition) error {
+	crwt.queries.Add(1)
+	return crwt.rwt.WriteNamespaces(ctx, newConfigs...)
+}
+
+func (crwt countingReadWriteTx) DeleteNamespaces(ctx context.Context, nsNames ...string) error {
+	crwt.queries.Add(1)
+	return crwt.rwt.DeleteNamespaces(ctx, nsNames...)
+}
+
+type countingIterator struct {
+	datastore.RelationshipIterator
+	rows *atomic.Uint64
+}
+
+func (ci countingIterator) Next() *core.RelationTuple {
+	next := ci.RelationshipIterator.Next()
+	if next != nil {
+		ci.rows.Add(1)
+	}
+	return next
+}
@@ -0,0 +1,76 @@
+package proxy
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestCountingDatastore(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	delegate, rev := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	var queries atomic.Uint64
+	ds := NewCountingDatastore(delegate, &queries)
+	ctx := context.Background()
+	reader := ds.SnapshotReader(rev)
+
+	_, _, err = reader.ReadNamespace(ctx, "document")
+	require.NoError(err)
+	require.Equal(uint64(1), queries.Load())
+
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+	require.NoError(err)
+	it.Close()
+	require.Equal(uint64(2), queries.Load())
+
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if _, err := rwt.ListNamespaces(ctx); err != nil {
+			return err
+		}
+		return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{
+			tuple.Touch(tuple.MustParse("document:counted#viewer@user:tom")),
+		})
+	})
+	require.NoError(err)
+	require.Equal(uint64(4), queries.Load())
+}
+
+func TestRowCountingDatastore(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	delegate, rev := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	var queries, rows atomic.Uint64
+	ds := NewRowCountingDatastore(delegate, &queries, &rows)
+	ctx := context.Background()
+
+	it, err := ds.SnapshotReader(rev).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             "document",
+		OptionalResourceRelation: "owner",
+	})
+	require.NoError(err)
+
+	var read uint64
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		read++
+	}
+	it.Close()
+
+	require.Positive(read)
+	require.Equal(uint64(1), queries.Load())
+	require.Equal(read, rows.Load())
+}
@@ -0,0 +1,205 @@
+package proxy
+
+import (
+	"context"
+
+	"github.com/jzelinskie/stringz"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+type hypotheticalDatastore struct {
+	datastore.Datastore
+	touched map[string]*core.RelationTuple
+	deleted map[string]struct{}
+}
+
+// NewHypotheticalDatastore creates a proxy which reads from a downstream delegate datastore as if
+// the given relationship updates had been applied to it, at every revision. The updates are never
+// written to the delegate, and write operations to the proxy are disabled.
+func NewHypotheticalDatastore(delegate datastore.Datastore, updates []*core.RelationTupleUpdate) datastore.Datastore {
+	hd := hypotheticalDatastore{
+		Datastore: delegate,
+		touched:   make(map[string]*core.RelationTuple, len(updates)),
+		deleted:   make(map[string]struct{}, len(updates)),
+	}
+
+	for _, update := range updates {
+		key := tuple.String(update.Tuple)
+		switch update.Operation {
+		case core.RelationTupleUpdate_DELETE:
+			delete(hd.touched, key)
+			hd.deleted[key] = struct{}{}
+		default:
+			delete(hd.deleted, key)
+			hd.touched[key] = update.Tuple
+		}
+	}
+
+	return hd
+}
+
+func (hd hypotheticalDatastore) ReadWriteTx(context.Context, datastore.TxUserFunc) (datastore.Revision, error) {
+	return datastore.NoRevision, errReadOnly
+}
+
+func (hd hypotheticalDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return hypotheticalReader{hd.Datastore.SnapshotReader(rev), hd}
+}
+
+type hypotheticalReader struct {
+	datastore.Reader
+	hd hypotheticalDatastore
+}
+
+func (hr hypotheticalReader) QueryRelationships(
+	ctx context.Context,
+	filter datastore.RelationshipsFilter,
+	opts ...options.QueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	queryOpts := options.NewQueryOptionsWithOptions(opts...)
+
+	// The limit is applied once the hypothetical relationships are merged in.
+	it, err := hr.Reader.QueryRelationships(ctx, filter, options.SetUsersets(queryOpts.Usersets))
+	if err != nil {
+		return nil, err
+	}
+
+	var added []*core.RelationTuple
+	for _, tpl := range hr.hd.touched {
+		if relationshipsFilterMatches(filter, queryOpts.Usersets, tpl) {
+			added = append(added, tpl)
+		}
+	}
+
+	return &hypotheticalIterator{delegate: it, hd: hr.hd, added: added, limit: queryOpts.Limit}, nil
+}
+
+func (hr hypotheticalReader) ReverseQueryRelationships(
+	ctx context.Context,
+	subjectsFilter datastore.SubjectsFilter,
+	opts ...options.ReverseQueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
+
+	var delegateOpts []options.ReverseQueryOptionsOption
+	filter := datastore.RelationshipsFilter{OptionalSubjectsFilter: &subjectsFilter}
+	if queryOpts.ResRelation != nil {
+		delegateOpts = append(delegateOpts, options.WithResRelation(queryOpts.ResRelation))
+		filter.ResourceType = queryOpts.ResRelation.Namespace
+		filter.OptionalResourceRelation = queryOpts.ResRelation.Relation
+	}
+
+	it, err := hr.Reader.ReverseQueryRelationships(ctx, subjectsFilter, delegateOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	var added []*core.RelationTuple
+	for _, tpl := range hr.hd.touched {
+		if relationshipsFilterMatches(filter, nil, tpl) {
+			added = append(added, tpl)
+		}
+	}
+
+	return &hypotheticalIterator{delegate: it, hd: hr.hd, added: added, limit: queryOpts.ReverseLimit}, nil
+}
+
+// hypotheticalIterator returns the relationships of the delegate iterator which were neither
+// touched nor deleted, followed by the touched relationships matching the query.
+type hypotheticalIterator struct {
+	delegate datastore.RelationshipIterator
+	hd       hypotheticalDatastore
+	added    []*core.RelationTuple
+	limit    *uint64
+	count    uint64
+}
+
+func (hi *hypotheticalIterator) Next() *core.RelationTuple {
+	if hi.limit != nil && hi.count >= *hi.limit {
+		return nil
+	}
+
+	for tpl := hi.delegate.Next(); tpl != nil; tpl = hi.delegate.Next() {
+		key := tuple.String(tpl)
+		if _, ok := hi.hd.deleted[key]; ok {
+			continue
+		}
+		if _, ok := hi.hd.touched[key]; ok {
+			continue
+		}
+
+		hi.count++
+		return tpl
+	}
+
+	if hi.delegate.Err() != nil || len(hi.added) == 0 {
+		return nil
+	}
+
+	next := hi.added[0]
+	hi.added = hi.added[1:]
+	hi.count++
+	return next
+}
+
+func (hi *hypotheticalIterator) Err() error {
+	return hi.delegate.Err()
+}
+
+func (hi *hypotheticalIterator) Close() {
+	hi.delegate.Close()
+}
+
+// relationshipsFilterMatches returns whether the relationship matches the filter and, if any, one
+// of the usersets.
+func relationshipsFilterMatches(filter datastore.RelationshipsFilter, usersets []*core.ObjectAndRelation, tpl *core.RelationTuple) bool {
+	resource := tpl.ResourceAndRelation
+	subject := tpl.Subject
+
+	switch {
+	case filter.ResourceType != "" && filter.ResourceType != resource.Namespace:
+		return false
+	case len(filter.OptionalResourceIds) > 0 && !stringz.SliceContains(filter.OptionalResourceIds, resource.ObjectId):
+		return false
+	case filter.OptionalResourceRelation != "" && filter.OptionalResourceRelation != resource.Relation:
+		return false
+	case filter.OptionalCaveatName != "" && (tpl.Caveat == nil || tpl.Caveat.CaveatName != filter.OptionalCaveatName):
+		return false
+	}
+
+	if sf := filter.OptionalSubjectsFilter; sf != nil {
+		relations := make([]string, 0, 2)
+		if sf.RelationFilter.IncludeEllipsisRelation {
+			relations = append(relations, datastore.Ellipsis)
+		}
+		if sf.RelationFilter.NonEllipsisRelation != "" {
+			relations = append(relations, sf.RelationFilter.NonEllipsisRelation)
+		}
+
+		switch {
+		case sf.SubjectType != subject.Namespace:
+			return false
+		case len(sf.OptionalSubjectIds) > 0 && !stringz.SliceContains(sf.OptionalSubjectIds, subject.ObjectId):
+			return false
+		case len(relations) > 0 && !stringz.SliceContains(relations, subject.Relation):
+			return false
+		}
+	}
+
+	if len(usersets) == 0 {
+		return true
+	}
+
+	for _, userset := range usersets {
+		if userset.Namespace == subject.Namespace &&
+			userset.ObjectId == subject.ObjectId &&
+			userset.Relation == subject.Relation {
+			return true
+		}
+	}
+	return false
+}
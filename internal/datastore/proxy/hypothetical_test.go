@@ -0,0 +1,84 @@
+package proxy
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestHypotheticalDatastore(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	delegate, rev := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	ds := NewHypotheticalDatastore(delegate, []*core.RelationTupleUpdate{
+		tuple.Touch(tuple.MustParse("document:masterplan#viewer@user:villain")),
+		tuple.Delete(tuple.MustParse("document:masterplan#viewer@user:eng_lead")),
+		tuple.Touch(tuple.MustParse("document:healthplan#viewer@user:villain")),
+		tuple.Delete(tuple.MustParse("document:healthplan#viewer@user:villain")),
+	})
+	ctx := context.Background()
+	reader := ds.SnapshotReader(rev)
+
+	collect := func(it datastore.RelationshipIterator, err error) []string {
+		require.NoError(err)
+		defer it.Close()
+
+		var found []string
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			found = append(found, tuple.String(tpl))
+		}
+		require.NoError(it.Err())
+		sort.Strings(found)
+		return found
+	}
+
+	viewers := datastore.RelationshipsFilter{
+		ResourceType:             "document",
+		OptionalResourceIds:      []string{"masterplan", "healthplan"},
+		OptionalResourceRelation: "viewer",
+	}
+	require.Equal(
+		[]string{"document:masterplan#viewer@user:villain"},
+		collect(reader.QueryRelationships(ctx, viewers)),
+	)
+	require.Len(collect(reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:        "document",
+		OptionalResourceIds: []string{"masterplan"},
+	}, options.WithLimit(options.LimitOne))), 1)
+
+	require.Equal(
+		[]string{"document:masterplan#viewer@user:villain", "folder:isolated#viewer@user:villain"},
+		collect(reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+			SubjectType:        "user",
+			OptionalSubjectIds: []string{"villain"},
+		})),
+	)
+	require.Equal(
+		[]string{"document:masterplan#viewer@user:villain"},
+		collect(reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+			SubjectType:        "user",
+			OptionalSubjectIds: []string{"villain"},
+		}, options.WithResRelation(&options.ResourceRelation{Namespace: "document", Relation: "viewer"}))),
+	)
+
+	// The delegate is unchanged.
+	require.Equal(
+		[]string{"document:masterplan#viewer@user:eng_lead"},
+		collect(delegate.SnapshotReader(rev).QueryRelationships(ctx, viewers)),
+	)
+
+	_, err = ds.ReadWriteTx(ctx, func(datastore.ReadWriteTransaction) error { return nil })
+	require.ErrorAs(err, &datastore.ErrReadOnly{})
+}
@@ -86,8 +86,8 @@ func (p *observableProxy) RevisionFromString(serialized string) (datastore.Revis
 	return p.delegate.RevisionFromString(serialized)
 }
 
-func (p *observableProxy) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
-	return p.delegate.Watch(ctx, afterRevision)
+func (p *observableProxy) Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
+	return p.delegate.Watch(ctx, afterRevision, options)
 }
 
 func (p *observableProxy) Features(ctx context.Context) (*datastore.Features, error) {
@@ -106,6 +106,14 @@ func (p *observableProxy) Statistics(ctx context.Context) (datastore.Stats, erro
 	return p.delegate.Statistics(ctx)
 }
 
+func (p *observableProxy) UniqueID(ctx context.Context) (string, error) {
+	var span trace.Span
+	ctx, span = tracer.Start(ctx, "UniqueID")
+	defer span.End()
+
+	return p.delegate.UniqueID(ctx)
+}
+
 func (p *observableProxy) IsReady(ctx context.Context) (bool, error) {
 	var span trace.Span
 	ctx, span = tracer.Start(ctx, "IsReady")
@@ -54,8 +54,8 @@ func (dm *MockDatastore) RevisionFromString(s string) (datastore.Revision, error
 	return args.Get(0).(datastore.Revision), args.Error(1)
 }
 
-func (dm *MockDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
-	args := dm.Called(afterRevision)
+func (dm *MockDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
+	args := dm.Called(afterRevision, options)
 	return args.Get(0).(<-chan *datastore.RevisionChanges), args.Get(1).(<-chan error)
 }
 
@@ -74,6 +74,11 @@ func (dm *MockDatastore) Statistics(ctx context.Context) (datastore.Stats, error
 	return args.Get(0).(datastore.Stats), args.Error(1)
 }
 
+func (dm *MockDatastore) UniqueID(ctx context.Context) (string, error) {
+	args := dm.Called()
+	return args.String(0), args.Error(1)
+}
+
 func (dm *MockDatastore) Close() error {
 	args := dm.Called()
 	return args.Error(0)
@@ -117,12 +117,12 @@ func TestWatchPassthrough(t *testing.T) {
 	ds := NewReadonlyDatastore(delegate)
 	ctx := context.Background()
 
-	delegate.On("Watch", expectedRevision).Return(
+	delegate.On("Watch", expectedRevision, datastore.WatchOptions{}).Return(
 		make(<-chan *datastore.RevisionChanges),
 		make(<-chan error),
 	).Times(1)
 
-	ds.Watch(ctx, expectedRevision)
+	ds.Watch(ctx, expectedRevision, datastore.WatchOptions{})
 	delegate.AssertExpectations(t)
 }
 
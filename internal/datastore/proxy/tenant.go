@@ -0,0 +1,331 @@
+package proxy
+
+import (
+	"context"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+type tenantDatastore struct {
+	datastore.Datastore
+	tenant string
+}
+
+// NewTenantDatastore creates a proxy which restricts a downstream delegate datastore to the
+// definitions, caveats and relationships of a tenant, whose names and object types are prefixed
+// with `tenant/`. Everything outside of the tenant is hidden from reads, watches and statistics,
+// and writes outside of the tenant fail with an ErrOutsideTenant.
+func NewTenantDatastore(delegate datastore.Datastore, tenant string) datastore.Datastore {
+	return tenantDatastore{Datastore: delegate, tenant: tenant}
+}
+
+func (td tenantDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return tenantReader{td.Datastore.SnapshotReader(rev), td.tenant}
+}
+
+func (td tenantDatastore) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	return td.Datastore.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return f(tenantReadWriteTx{tenantReader{rwt, td.tenant}, rwt})
+	})
+}
+
+func (td tenantDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
+	changes, errs := td.Datastore.Watch(ctx, afterRevision, opts)
+
+	// The filtered changes are buffered as the delegate's, so that a watcher falling behind is
+	// still disconnected by the delegate.
+	filtered := make(chan *datastore.RevisionChanges, cap(changes))
+	go func() {
+		defer close(filtered)
+		for {
+			var revisionChanges *datastore.RevisionChanges
+			select {
+			case <-ctx.Done():
+				return
+			case received, ok := <-changes:
+				if !ok {
+					return
+				}
+				revisionChanges = received
+			}
+
+			scoped := &datastore.RevisionChanges{
+				Revision:     revisionChanges.Revision,
+				IsCheckpoint: revisionChanges.IsCheckpoint,
+			}
+			for _, change := range revisionChanges.Changes {
+				if inTenant(td.tenant, change.Tuple) {
+					scoped.Changes = append(scoped.Changes, change)
+				}
+			}
+			for _, change := range revisionChanges.SchemaChanges {
+				if hasTenantPrefix(td.tenant, change.Name()) {
+					scoped.SchemaChanges = append(scoped.SchemaChanges, change)
+				}
+			}
+
+			if len(scoped.Changes) == 0 && len(scoped.SchemaChanges) == 0 && !scoped.IsCheckpoint {
+				continue
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case filtered <- scoped:
+			}
+		}
+	}()
+	return filtered, errs
+}
+
+// Statistics returns the statistics of the object types and relationships of the tenant. As the
+// estimate of the delegate covers every tenant, the relationships of the tenant are counted.
+func (td tenantDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
+	uniqueID, err := td.Datastore.UniqueID(ctx)
+	if err != nil {
+		return datastore.Stats{}, err
+	}
+
+	headRevision, err := td.Datastore.HeadRevision(ctx)
+	if err != nil {
+		return datastore.Stats{}, err
+	}
+
+	reader := td.SnapshotReader(headRevision)
+	nsDefs, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return datastore.Stats{}, err
+	}
+
+	var relationshipCount uint64
+	for _, nsDef := range nsDefs {
+		it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: nsDef.Name})
+		if err != nil {
+			return datastore.Stats{}, err
+		}
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			relationshipCount++
+		}
+		err = it.Err()
+		it.Close()
+		if err != nil {
+			return datastore.Stats{}, err
+		}
+	}
+
+	return datastore.Stats{
+		UniqueID:                   uniqueID,
+		EstimatedRelationshipCount: relationshipCount,
+		ObjectTypeStatistics:       datastore.ComputeObjectTypeStats(nsDefs),
+	}, nil
+}
+
+type tenantReader struct {
+	datastore.Reader
+	tenant string
+}
+
+func (tr tenantReader) ReadCaveatByName(ctx context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
+	if !hasTenantPrefix(tr.tenant, name) {
+		return nil, datastore.NoRevision, datastore.NewCaveatNameNotFoundErr(name)
+	}
+	return tr.Reader.ReadCaveatByName(ctx, name)
+}
+
+func (tr tenantReader) ListCaveats(ctx context.Context, caveatNamesForFiltering ...string) ([]*core.CaveatDefinition, error) {
+	if len(caveatNamesForFiltering) > 0 {
+		caveatNamesForFiltering = tr.namesInTenant(caveatNamesForFiltering)
+		if len(caveatNamesForFiltering) == 0 {
+			return nil, nil
+		}
+	}
+
+	caveats, err := tr.Reader.ListCaveats(ctx, caveatNamesForFiltering...)
+	if err != nil {
+		return nil, err
+	}
+
+	inTenant := make([]*core.CaveatDefinition, 0, len(caveats))
+	for _, caveat := range caveats {
+		if hasTenantPrefix(tr.tenant, caveat.Name) {
+			inTenant = append(inTenant, caveat)
+		}
+	}
+	return inTenant, nil
+}
+
+func (tr tenantReader) QueryRelationships(
+	ctx context.Context,
+	filter datastore.RelationshipsFilter,
+	opts ...options.QueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	if !hasTenantPrefix(tr.tenant, filter.ResourceType) {
+		return datastore.NewSliceRelationshipIterator(nil), nil
+	}
+
+	it, err := tr.Reader.QueryRelationships(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantIterator{it, tr.tenant}, nil
+}
+
+func (tr tenantReader) ReverseQueryRelationships(
+	ctx context.Context,
+	subjectsFilter datastore.SubjectsFilter,
+	opts ...options.ReverseQueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
+	if !hasTenantPrefix(tr.tenant, subjectsFilter.SubjectType) ||
+		(queryOpts.ResRelation != nil && !hasTenantPrefix(tr.tenant, queryOpts.ResRelation.Namespace)) {
+		return datastore.NewSliceRelationshipIterator(nil), nil
+	}
+
+	it, err := tr.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &tenantIterator{it, tr.tenant}, nil
+}
+
+func (tr tenantReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	if !hasTenantPrefix(tr.tenant, nsName) {
+		return nil, datastore.NoRevision, datastore.NewNamespaceNotFoundErr(nsName)
+	}
+	return tr.Reader.ReadNamespace(ctx, nsName)
+}
+
+func (tr tenantReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
+	nsDefs, err := tr.Reader.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inTenant := make([]*core.NamespaceDefinition, 0, len(nsDefs))
+	for _, nsDef := range nsDefs {
+		if hasTenantPrefix(tr.tenant, nsDef.Name) {
+			inTenant = append(inTenant, nsDef)
+		}
+	}
+	return inTenant, nil
+}
+
+func (tr tenantReader) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
+	nsNames = tr.namesInTenant(nsNames)
+	if len(nsNames) == 0 {
+		return nil, nil
+	}
+	return tr.Reader.LookupNamespaces(ctx, nsNames)
+}
+
+// namesInTenant returns the names which are in the tenant.
+func (tr tenantReader) namesInTenant(names []string) []string {
+	inTenant := make([]string, 0, len(names))
+	for _, name := range names {
+		if hasTenantPrefix(tr.tenant, name) {
+			inTenant = append(inTenant, name)
+		}
+	}
+	return inTenant
+}
+
+type tenantReadWriteTx struct {
+	tenantReader
+	rwt datastore.ReadWriteTransaction
+}
+
+func (trwt tenantReadWriteTx) WriteCaveats(ctx context.Context, caveats []*core.CaveatDefinition) error {
+	for _, caveat := range caveats {
+		if !hasTenantPrefix(trwt.tenant, caveat.Name) {
+			return datastore.NewOutsideTenantErr(trwt.tenant, caveat.Name)
+		}
+	}
+	return trwt.rwt.WriteCaveats(ctx, caveats)
+}
+
+func (trwt tenantReadWriteTx) DeleteCaveats(ctx context.Context, names []string) error {
+	for _, name := range names {
+		if !hasTenantPrefix(trwt.tenant, name) {
+			return datastore.NewOutsideTenantErr(trwt.tenant, name)
+		}
+	}
+	return trwt.rwt.DeleteCaveats(ctx, names)
+}
+
+func (trwt tenantReadWriteTx) WriteRelationships(ctx context.Context, mutations []*core.RelationTupleUpdate) error {
+	for _, mutation := range mutations {
+		for _, name := range []string{
+			mutation.Tuple.ResourceAndRelation.Namespace,
+			mutation.Tuple.Subject.Namespace,
+			mutation.Tuple.Caveat.GetCaveatName(),
+		} {
+			if name != "" && !hasTenantPrefix(trwt.tenant, name) {
+				return datastore.NewOutsideTenantErr(trwt.tenant, name)
+			}
+		}
+	}
+	return trwt.rwt.WriteRelationships(ctx, mutations)
+}
+
+func (trwt tenantReadWriteTx) DeleteRelationships(ctx context.Context, filter *v1.RelationshipFilter) error {
+	if !hasTenantPrefix(trwt.tenant, filter.ResourceType) {
+		return datastore.NewOutsideTenantErr(trwt.tenant, filter.ResourceType)
+	}
+	return trwt.rwt.DeleteRelationships(ctx, filter)
+}
+
+func (trwt tenantReadWriteTx) WriteNamespaces(ctx context.Context, newConfigs ...*core.NamespaceDefinition) error {
+	for _, nsDef := range newConfigs {
+		if !hasTenantPrefix(trwt.tenant, nsDef.Name) {
+			return datastore.NewOutsideTenantErr(trwt.tenant, nsDef.Name)
+		}
+	}
+	return trwt.rwt.WriteNamespaces(ctx, newConfigs...)
+}
+
+func (trwt tenantReadWriteTx) DeleteNamespaces(ctx context.Context, nsNames ...string) error {
+	for _, nsName := range nsNames {
+		if !hasTenantPrefix(trwt.tenant, nsName) {
+			return datastore.NewOutsideTenantErr(trwt.tenant, nsName)
+		}
+	}
+	return trwt.rwt.DeleteNamespaces(ctx, nsNames...)
+}
+
+// tenantIterator returns the relationships of the delegate iterator which are in the tenant.
+type tenantIterator struct {
+	delegate datastore.RelationshipIterator
+	tenant   string
+}
+
+func (ti *tenantIterator) Next() *core.RelationTuple {
+	for tpl := ti.delegate.Next(); tpl != nil; tpl = ti.delegate.Next() {
+		if inTenant(ti.tenant, tpl) {
+			return tpl
+		}
+	}
+	return nil
+}
+
+func (ti *tenantIterator) Err() error {
+	return ti.delegate.Err()
+}
+
+func (ti *tenantIterator) Close() {
+	ti.delegate.Close()
+}
+
+// inTenant returns whether the object types of the relationship are in the tenant. Relationships
+// written outside of the tenant, which may refer to the object types of the tenant, are hidden.
+func inTenant(tenant string, tpl *core.RelationTuple) bool {
+	return hasTenantPrefix(tenant, tpl.ResourceAndRelation.Namespace) && hasTenantPrefix(tenant, tpl.Subject.Namespace)
+}
+
+func hasTenantPrefix(tenant string, name string) bool {
+	return strings.HasPrefix(name, tenant+"/")
+}
@@ -0,0 +1,128 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const tenantSchema = `
+	definition acme/user {}
+	definition acme/document {
+		relation viewer: acme/user | other/user
+	}
+
+	definition other/user {}
+	definition other/document {
+		relation viewer: other/user | acme/user
+	}
+`
+
+func TestTenantDatastore(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	delegate, rev := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, tenantSchema, []*core.RelationTuple{
+		tuple.MustParse("acme/document:first#viewer@acme/user:tom"),
+		tuple.MustParse("acme/document:first#viewer@other/user:sarah"),
+		tuple.MustParse("other/document:first#viewer@other/user:sarah"),
+		tuple.MustParse("other/document:first#viewer@acme/user:tom"),
+	}, require)
+
+	ds := NewTenantDatastore(delegate, "acme")
+	ctx := context.Background()
+	reader := ds.SnapshotReader(rev)
+
+	nsDefs, err := reader.ListNamespaces(ctx)
+	require.NoError(err)
+	names := make([]string, 0, len(nsDefs))
+	for _, nsDef := range nsDefs {
+		names = append(names, nsDef.Name)
+	}
+	require.ElementsMatch([]string{"acme/user", "acme/document"}, names)
+
+	_, _, err = reader.ReadNamespace(ctx, "other/document")
+	require.ErrorAs(err, &datastore.ErrNamespaceNotFound{})
+
+	nsDefs, err = reader.LookupNamespaces(ctx, []string{"acme/document", "other/document"})
+	require.NoError(err)
+	require.Len(nsDefs, 1)
+	require.Equal("acme/document", nsDefs[0].Name)
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: delegate}
+
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "acme/document"})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(it, tuple.MustParse("acme/document:first#viewer@acme/user:tom"))
+
+	it, err = reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "other/document"})
+	require.NoError(err)
+	tRequire.VerifyIteratorCount(it, 0)
+
+	it, err = reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{SubjectType: "acme/user"})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(it, tuple.MustParse("acme/document:first#viewer@acme/user:tom"))
+
+	for _, tc := range []struct {
+		name string
+		f    datastore.TxUserFunc
+	}{
+		{"write namespace", func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteNamespaces(ctx, &core.NamespaceDefinition{Name: "other/group"})
+		}},
+		{"delete namespace", func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.DeleteNamespaces(ctx, "other/document")
+		}},
+		{"write caveat", func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteCaveats(ctx, []*core.CaveatDefinition{{Name: "other/caveat"}})
+		}},
+		{"write relationship", func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(ctx, []*core.RelationTupleUpdate{
+				tuple.Touch(tuple.MustParse("acme/document:first#viewer@other/user:tom")),
+			})
+		}},
+	} {
+		_, err := ds.ReadWriteTx(ctx, tc.f)
+		require.ErrorAs(err, &datastore.ErrOutsideTenant{}, tc.name)
+	}
+
+	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_TOUCH, tuple.MustParse("acme/document:second#viewer@acme/user:tom"))
+	require.NoError(err)
+}
+
+func TestTenantDatastoreStatistics(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	delegate, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, tenantSchema, []*core.RelationTuple{
+		tuple.MustParse("acme/document:first#viewer@acme/user:tom"),
+		tuple.MustParse("acme/document:first#viewer@other/user:sarah"),
+		tuple.MustParse("other/document:first#viewer@other/user:sarah"),
+		tuple.MustParse("other/document:second#viewer@other/user:sarah"),
+		tuple.MustParse("other/document:first#viewer@acme/user:tom"),
+	}, require)
+
+	ctx := context.Background()
+	delegateStats, err := delegate.Statistics(ctx)
+	require.NoError(err)
+	require.Len(delegateStats.ObjectTypeStatistics, 4)
+
+	stats, err := NewTenantDatastore(delegate, "acme").Statistics(ctx)
+	require.NoError(err)
+	require.Equal(delegateStats.UniqueID, stats.UniqueID)
+	require.Equal(uint64(1), stats.EstimatedRelationshipCount)
+	require.ElementsMatch([]datastore.ObjectTypeStat{
+		{NumRelations: 0, NumPermissions: 0},
+		{NumRelations: 1, NumPermissions: 0},
+	}, stats.ObjectTypeStatistics)
+}
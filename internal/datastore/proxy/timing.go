@@ -0,0 +1,121 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// QueryObserver is notified of the latency and outcome of the read queries made to a datastore.
+type QueryObserver interface {
+	// ObserveQuery records a query which took the given latency, and failed with the given error
+	// if not nil.
+	ObserveQuery(latency time.Duration, err error)
+}
+
+type timingDatastore struct {
+	datastore.Datastore
+	observer QueryObserver
+}
+
+// NewTimingDatastore creates a proxy which reports the latency of the revision and read queries
+// made to a downstream delegate datastore to the given observer. Errors caused by the caller,
+// such as canceled contexts or missing definitions, are not reported as failures. The proxy
+// should wrap the datastore beneath any caching proxy, so that cache hits are not observed.
+func NewTimingDatastore(delegate datastore.Datastore, observer QueryObserver) datastore.Datastore {
+	return timingDatastore{Datastore: delegate, observer: observer}
+}
+
+// observe reports a query started at the given time.
+func observe(observer QueryObserver, start time.Time, err error) {
+	if errors.Is(err, context.Canceled) ||
+		errors.As(err, &datastore.ErrNamespaceNotFound{}) ||
+		errors.As(err, &datastore.ErrCaveatNameNotFound{}) {
+		err = nil
+	}
+	observer.ObserveQuery(time.Since(start), err)
+}
+
+func (td timingDatastore) OptimizedRevision(ctx context.Context) (datastore.Revision, error) {
+	start := time.Now()
+	rev, err := td.Datastore.OptimizedRevision(ctx)
+	observe(td.observer, start, err)
+	return rev, err
+}
+
+func (td timingDatastore) HeadRevision(ctx context.Context) (datastore.Revision, error) {
+	start := time.Now()
+	rev, err := td.Datastore.HeadRevision(ctx)
+	observe(td.observer, start, err)
+	return rev, err
+}
+
+func (td timingDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return timingReader{td.Datastore.SnapshotReader(rev), td.observer}
+}
+
+type timingReader struct {
+	datastore.Reader
+	observer QueryObserver
+}
+
+func (tr timingReader) ReadCaveatByName(ctx context.Context, name string) (*core.CaveatDefinition, datastore.Revision, error) {
+	start := time.Now()
+	caveat, rev, err := tr.Reader.ReadCaveatByName(ctx, name)
+	observe(tr.observer, start, err)
+	return caveat, rev, err
+}
+
+func (tr timingReader) ListCaveats(ctx context.Context, caveatNamesForFiltering ...string) ([]*core.CaveatDefinition, error) {
+	start := time.Now()
+	caveats, err := tr.Reader.ListCaveats(ctx, caveatNamesForFiltering...)
+	observe(tr.observer, start, err)
+	return caveats, err
+}
+
+func (tr timingReader) QueryRelationships(
+	ctx context.Context,
+	filter datastore.RelationshipsFilter,
+	opts ...options.QueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	start := time.Now()
+	iter, err := tr.Reader.QueryRelationships(ctx, filter, opts...)
+	observe(tr.observer, start, err)
+	return iter, err
+}
+
+func (tr timingReader) ReverseQueryRelationships(
+	ctx context.Context,
+	subjectsFilter datastore.SubjectsFilter,
+	opts ...options.ReverseQueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	start := time.Now()
+	iter, err := tr.Reader.ReverseQueryRelationships(ctx, subjectsFilter, opts...)
+	observe(tr.observer, start, err)
+	return iter, err
+}
+
+func (tr timingReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	start := time.Now()
+	ns, rev, err := tr.Reader.ReadNamespace(ctx, nsName)
+	observe(tr.observer, start, err)
+	return ns, rev, err
+}
+
+func (tr timingReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
+	start := time.Now()
+	namespaces, err := tr.Reader.ListNamespaces(ctx)
+	observe(tr.observer, start, err)
+	return namespaces, err
+}
+
+func (tr timingReader) LookupNamespaces(ctx context.Context, nsNames []string) ([]*core.NamespaceDefinition, error) {
+	start := time.Now()
+	namespaces, err := tr.Reader.LookupNamespaces(ctx, nsNames)
+	observe(tr.observer, start, err)
+	return namespaces, err
+}
@@ -0,0 +1,54 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+type recordingObserver struct {
+	errs []error
+}
+
+func (ro *recordingObserver) ObserveQuery(_ time.Duration, err error) {
+	ro.errs = append(ro.errs, err)
+}
+
+func TestTimingDatastore(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	delegate, rev := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	observer := &recordingObserver{}
+	ds := NewTimingDatastore(delegate, observer)
+	ctx := context.Background()
+
+	_, err = ds.HeadRevision(ctx)
+	require.NoError(err)
+	require.Len(observer.errs, 1)
+
+	reader := ds.SnapshotReader(rev)
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: "document"})
+	require.NoError(err)
+	it.Close()
+	require.Len(observer.errs, 2)
+
+	// Missing definitions are not failures of the datastore.
+	_, _, err = reader.ReadNamespace(ctx, "unknown")
+	require.Error(err)
+	require.Equal([]error{nil, nil, nil}, observer.errs)
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, _ = reader.ListNamespaces(canceled)
+	require.Len(observer.errs, 4)
+	require.NoError(observer.errs[3])
+}
@@ -2,11 +2,13 @@ package migrations
 
 import (
 	"context"
+	"fmt"
 	"os"
 
 	"cloud.google.com/go/spanner"
 	admin "cloud.google.com/go/spanner/admin/database/apiv1"
 	"google.golang.org/api/option"
+	iampb "google.golang.org/genproto/googleapis/iam/v1"
 	"google.golang.org/grpc/codes"
 
 	log "github.com/authzed/spicedb/internal/logging"
@@ -105,4 +107,74 @@ func (smd *SpannerMigrationDriver) Close(_ context.Context) error {
 	return nil
 }
 
-var _ migrate.Driver[Wrapper, *spanner.ReadWriteTransaction] = &SpannerMigrationDriver{}
+// requiredPermissions are the IAM permissions on the database required to run migrations.
+var requiredPermissions = []string{
+	"spanner.databases.read",
+	"spanner.databases.write",
+	"spanner.databases.updateDdl",
+}
+
+// CheckPermissions checks that the caller is granted all of the IAM permissions on the database
+// required to run migrations. The check is skipped against the emulator, which has no IAM.
+func (smd *SpannerMigrationDriver) CheckPermissions(ctx context.Context) error {
+	if os.Getenv(emulatorSettingKey) != "" {
+		log.Ctx(ctx).Info().Msg("skipping permission check against the spanner emulator")
+		return nil
+	}
+
+	resp, err := smd.adminClient.TestIamPermissions(ctx, &iampb.TestIamPermissionsRequest{
+		Resource:    smd.client.DatabaseName(),
+		Permissions: requiredPermissions,
+	})
+	if err != nil {
+		return fmt.Errorf("unable to check permissions: %w", err)
+	}
+
+	granted := make(map[string]struct{}, len(resp.Permissions))
+	for _, permission := range resp.Permissions {
+		granted[permission] = struct{}{}
+	}
+
+	var missing []string
+	for _, permission := range requiredPermissions {
+		if _, ok := granted[permission]; !ok {
+			missing = append(missing, permission)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("the caller lacks the permissions %v on the database", missing)
+	}
+	return nil
+}
+
+// headIndexes are the secondary indexes of the tables of the database after the head migration.
+var headIndexes = []string{
+	"relation_tuple.ix_relation_tuple_by_subject",
+	"relation_tuple.ix_relation_tuple_by_subject_relation",
+}
+
+// MissingIndexes returns the secondary indexes created by the migrations up to the head revision
+// which are missing from the database.
+func (smd *SpannerMigrationDriver) MissingIndexes(ctx context.Context) ([]string, error) {
+	present := make(map[string]struct{})
+	err := smd.client.Single().Query(ctx, spanner.Statement{
+		SQL: `SELECT TABLE_NAME, INDEX_NAME FROM INFORMATION_SCHEMA.INDEXES WHERE TABLE_SCHEMA = ''`,
+	}).Do(func(row *spanner.Row) error {
+		var table, index string
+		if err := row.Columns(&table, &index); err != nil {
+			return err
+		}
+		present[table+"."+index] = struct{}{}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list indexes: %w", err)
+	}
+	return migrate.MissingIndexes(headIndexes, present), nil
+}
+
+var (
+	_ migrate.Driver[Wrapper, *spanner.ReadWriteTransaction] = &SpannerMigrationDriver{}
+	_ migrate.PermissionChecker                              = &SpannerMigrationDriver{}
+	_ migrate.IndexChecker                                   = &SpannerMigrationDriver{}
+)
@@ -8,3 +8,11 @@ import (
 
 // SpannerMigrations implements a migration manager for the Spanner datastore.
 var SpannerMigrations = migrate.NewManager[*SpannerMigrationDriver, Wrapper, *spanner.ReadWriteTransaction]()
+
+// mustDescribe records the statements executed by the migration to a version, which are shown
+// when planning migrations.
+func mustDescribe(version string, statements ...string) {
+	if err := SpannerMigrations.Describe(version, func(*SpannerMigrationDriver) []string { return statements }); err != nil {
+		panic("failed to describe migration: " + err.Error())
+	}
+}
@@ -71,4 +71,14 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("initial",
+		createNamespaceConfig,
+		createRelationTuple,
+		createSchemaVersion,
+		createChangelog,
+		createReverseQueryIndex,
+		createReverseCheckIndex,
+		insertEmptyVersion,
+	)
 }
@@ -17,6 +17,9 @@ const (
 		id BYTES(2) NOT NULL,
 		count INT64 NOT NULL
 	) PRIMARY KEY (id)`
+
+	// insertUniqueID describes the mutation inserting the unique ID of the datastore.
+	insertUniqueID = `INSERT INTO metadata (unique_id) VALUES (@unique_id)`
 )
 
 func init() {
@@ -40,4 +43,6 @@ func init() {
 	}); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-metadata-and-counters", createMetadata, createCounters, insertUniqueID)
 }
@@ -43,4 +43,12 @@ func init() {
 	}, nil); err != nil {
 		panic("failed to register migration: " + err.Error())
 	}
+
+	mustDescribe("add-caveats",
+		createCaveatTable,
+		addRelationshipCaveatName,
+		addRelationshipCaveatContext,
+		addChangelogCaveatName,
+		addChangelogCaveatContext,
+	)
 }
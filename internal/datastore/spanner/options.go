@@ -15,6 +15,7 @@ type spannerOptions struct {
 	gcEnabled                   bool
 	credentialsFilePath         string
 	emulatorHost                string
+	slowQueryThreshold          time.Duration
 }
 
 const (
@@ -143,3 +144,13 @@ func GCEnabled(isGCEnabled bool) Option {
 		so.gcEnabled = isGCEnabled
 	}
 }
+
+// SlowQueryThreshold is the duration beyond which relationship queries are logged as slow, with
+// their SQL, the API method for which they were made and their revision.
+//
+// Slow queries are not logged by default.
+func SlowQueryThreshold(threshold time.Duration) Option {
+	return func(so *spannerOptions) {
+		so.slowQueryThreshold = threshold
+	}
+}
@@ -127,8 +127,10 @@ func (sd spannerDatastore) SnapshotReader(revisionRaw datastore.Revision) datast
 		return sd.client.Single().WithTimestampBound(spanner.ReadTimestamp(timestampFromRevision(revision)))
 	}
 	querySplitter := common.TupleQuerySplitter{
-		Executor:         queryExecutor(txSource),
-		UsersetBatchSize: usersetBatchsize,
+		Executor:           queryExecutor(txSource),
+		UsersetBatchSize:   usersetBatchsize,
+		SlowQueryThreshold: sd.config.slowQueryThreshold,
+		Revision:           revisionRaw,
 	}
 
 	return spannerReader{querySplitter, txSource}
@@ -144,8 +146,9 @@ func (sd spannerDatastore) ReadWriteTx(
 		}
 
 		querySplitter := common.TupleQuerySplitter{
-			Executor:         queryExecutor(txSource),
-			UsersetBatchSize: usersetBatchsize,
+			Executor:           queryExecutor(txSource),
+			UsersetBatchSize:   usersetBatchsize,
+			SlowQueryThreshold: sd.config.slowQueryThreshold,
 		}
 		rwt := spannerReadWriteTXN{spannerReader{querySplitter, txSource}, spannerRWT}
 		return fn(rwt)
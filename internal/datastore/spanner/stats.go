@@ -14,21 +14,31 @@ import (
 
 var queryRelationshipEstimate = fmt.Sprintf("SELECT SUM(%s) FROM %s", colCount, tableCounters)
 
-func (sd spannerDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
+func (sd spannerDatastore) UniqueID(ctx context.Context) (string, error) {
 	idRows := sd.client.Single().Read(
-		context.Background(),
+		ctx,
 		tableMetadata,
 		spanner.AllKeys(),
 		[]string{colUniqueID},
 	)
+	defer idRows.Stop()
+
 	idRow, err := idRows.Next()
 	if err != nil {
-		return datastore.Stats{}, fmt.Errorf("unable to read metadata table: %w", err)
+		return "", fmt.Errorf("unable to read metadata table: %w", err)
 	}
 
 	var uniqueID string
 	if err := idRow.Columns(&uniqueID); err != nil {
-		return datastore.Stats{}, fmt.Errorf("unable to read unique ID: %w", err)
+		return "", fmt.Errorf("unable to read unique ID: %w", err)
+	}
+	return uniqueID, nil
+}
+
+func (sd spannerDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
+	uniqueID, err := sd.UniqueID(ctx)
+	if err != nil {
+		return datastore.Stats{}, err
 	}
 
 	iter := sd.client.Single().Read(
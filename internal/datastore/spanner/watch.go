@@ -18,24 +18,43 @@ const (
 	watchSleep = 100 * time.Millisecond
 )
 
-var queryChanged = sql.Select(allChangelogCols...).From(tableChangelog)
+var (
+	queryChanged = sql.Select(allChangelogCols...).From(tableChangelog)
+
+	changelogSchema = common.SchemaInformation{
+		ColNamespace:        colChangeNamespace,
+		ColObjectID:         colChangeObjectID,
+		ColRelation:         colChangeRelation,
+		ColUsersetNamespace: colChangeUsersetNamespace,
+		ColUsersetObjectID:  colChangeUsersetObjectID,
+		ColUsersetRelation:  colChangeUsersetRelation,
+		ColCaveatName:       colChangeCaveatName,
+	}
+)
 
-func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
 	afterRevision := afterRevisionRaw.(revision.Decimal)
 
 	updates := make(chan *datastore.RevisionChanges, sd.config.watchBufferLength)
 	errs := make(chan error, 1)
 
+	if options.IncludesSchema() {
+		errs <- datastore.NewWatchDisabledErr("watching schema changes is not supported by the Spanner datastore")
+		return updates, errs
+	}
+
 	go func() {
 		defer close(updates)
 		defer close(errs)
 
 		currentTxn := timestampFromRevision(afterRevision)
 
+		lastReturned := time.Now()
+
 		for {
 			var stagedUpdates []*datastore.RevisionChanges
 			var err error
-			stagedUpdates, currentTxn, err = sd.loadChanges(ctx, currentTxn)
+			stagedUpdates, currentTxn, err = sd.loadChanges(ctx, currentTxn, options.Filter)
 			if err != nil {
 				if errors.Is(ctx.Err(), context.Canceled) {
 					errs <- datastore.NewWatchCanceledErr()
@@ -53,6 +72,17 @@ func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore
 					errs <- datastore.NewWatchDisconnectedErr()
 					return
 				}
+				lastReturned = time.Now()
+			}
+
+			if options.CheckpointDue(lastReturned) {
+				select {
+				case updates <- &datastore.RevisionChanges{Revision: revisionFromTimestamp(currentTxn), IsCheckpoint: true}:
+				default:
+					errs <- datastore.NewWatchDisconnectedErr()
+					return
+				}
+				lastReturned = time.Now()
 			}
 
 			// If there were no changes, sleep a bit
@@ -76,8 +106,9 @@ func (sd spannerDatastore) Watch(ctx context.Context, afterRevisionRaw datastore
 func (sd spannerDatastore) loadChanges(
 	ctx context.Context,
 	afterTimestamp time.Time,
+	filter datastore.WatchFilter,
 ) ([]*datastore.RevisionChanges, time.Time, error) {
-	sql, args, err := queryChanged.Where(sq.Gt{colChangeTS: afterTimestamp}).ToSql()
+	sql, args, err := common.FilterToWatchFilter(queryChanged, changelogSchema, filter).Where(sq.Gt{colChangeTS: afterTimestamp}).ToSql()
 	if err != nil {
 		return nil, afterTimestamp, err
 	}
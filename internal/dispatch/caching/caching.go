@@ -45,6 +45,8 @@ type Dispatcher struct {
 	cacheMisses      prometheus.CounterFunc
 	costAddedBytes   prometheus.CounterFunc
 	costEvictedBytes prometheus.CounterFunc
+
+	namespaceMetrics *namespaceMetrics
 }
 
 func DispatchTestCache(t testing.TB) cache.Cache {
@@ -139,6 +141,11 @@ func NewCachingDispatcher(cacheInst cache.Cache, prometheusSubsystem string, key
 		return float64(cacheInst.GetMetrics().CostEvicted())
 	})
 
+	namespaceMetrics := newNamespaceMetrics(prometheusSubsystem)
+	if notifier, ok := cacheInst.(cache.EvictionNotifier); ok {
+		notifier.NotifyEvictions(namespaceMetrics.removed)
+	}
+
 	if prometheusSubsystem != "" {
 		err := prometheus.Register(checkTotalCounter)
 		if err != nil {
@@ -190,6 +197,12 @@ func NewCachingDispatcher(cacheInst cache.Cache, prometheusSubsystem string, key
 		if err != nil {
 			return nil, fmt.Errorf(errCachingInitialization, err)
 		}
+
+		for _, collector := range namespaceMetrics.collectors() {
+			if err := prometheus.Register(collector); err != nil {
+				return nil, fmt.Errorf(errCachingInitialization, err)
+			}
+		}
 	}
 
 	if keyHandler == nil {
@@ -212,6 +225,7 @@ func NewCachingDispatcher(cacheInst cache.Cache, prometheusSubsystem string, key
 		cacheMisses:                        cacheMissesTotal,
 		costAddedBytes:                     costAddedBytes,
 		costEvictedBytes:                   costEvictedBytes,
+		namespaceMetrics:                   namespaceMetrics,
 	}, nil
 }
 
@@ -220,6 +234,13 @@ func (cd *Dispatcher) SetDelegate(delegate dispatch.Dispatcher) {
 	cd.d = delegate
 }
 
+// SetConcurrencyLimit sets the concurrency limit of the delegate, if it can be changed.
+func (cd *Dispatcher) SetConcurrencyLimit(limit uint16) {
+	if limited, ok := cd.d.(dispatch.ConcurrencyLimited); ok {
+		limited.SetConcurrencyLimit(limit)
+	}
+}
+
 // DispatchCheck implements dispatch.Check interface
 func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
 	cd.checkTotalCounter.Inc()
@@ -229,15 +250,18 @@ func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRe
 		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
 	}
 
+	namespace := req.GetResourceRelation().GetNamespace()
+
 	// Disable caching when debugging is enabled.
 	if cachedResultRaw, found := cd.c.Get(requestKey); found {
 		var response v1.DispatchCheckResponse
-		if err := response.UnmarshalVT(cachedResultRaw.([]byte)); err != nil {
+		if err := response.UnmarshalVT(cachedResultRaw.(*cacheEntry).results[0]); err != nil {
 			return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
 		}
 
 		if req.Metadata.DepthRemaining >= response.Metadata.DepthRequired {
 			cd.checkFromCacheCounter.Inc()
+			cd.namespaceMetrics.lookedUp(ctx, "check", namespace, true)
 			// If debugging is requested, add the req and the response to the trace.
 			if req.Debug == v1.DispatchCheckRequest_ENABLE_DEBUGGING {
 				response.Metadata.DebugInfo = &v1.DebugInformation{
@@ -252,6 +276,7 @@ func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRe
 			return &response, nil
 		}
 	}
+	cd.namespaceMetrics.lookedUp(ctx, "check", namespace, false)
 	computed, err := cd.d.DispatchCheck(ctx, req)
 
 	// We only want to cache the result if there was no error
@@ -266,7 +291,7 @@ func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRe
 			return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
 		}
 
-		cd.c.Set(requestKey, adjustedBytes, sliceSize(adjustedBytes))
+		cd.set(requestKey, newCacheEntry(namespace, [][]byte{adjustedBytes}))
 	}
 
 	// Return both the computed and err in ALL cases: computed contains resolved
@@ -289,18 +314,21 @@ func (cd *Dispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookup
 		return &v1.DispatchLookupResponse{Metadata: &v1.ResponseMeta{}}, err
 	}
 
+	namespace := req.GetObjectRelation().GetNamespace()
 	if cachedResultRaw, found := cd.c.Get(requestKey); found {
 		var response v1.DispatchLookupResponse
-		if err := response.UnmarshalVT(cachedResultRaw.([]byte)); err != nil {
+		if err := response.UnmarshalVT(cachedResultRaw.(*cacheEntry).results[0]); err != nil {
 			return &v1.DispatchLookupResponse{Metadata: &v1.ResponseMeta{}}, err
 		}
 
 		if req.Metadata.DepthRemaining >= response.Metadata.DepthRequired {
 			log.Trace().Object("cachedLookup", req).Int("resultCount", len(response.ResolvedResources)).Send()
 			cd.lookupFromCacheCounter.Inc()
+			cd.namespaceMetrics.lookedUp(ctx, "lookup", namespace, true)
 			return &response, nil
 		}
 	}
+	cd.namespaceMetrics.lookedUp(ctx, "lookup", namespace, false)
 	computed, err := cd.d.DispatchLookup(ctx, req)
 
 	// We only want to cache the result if there was no error.
@@ -317,7 +345,7 @@ func (cd *Dispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookup
 			return &v1.DispatchLookupResponse{Metadata: &v1.ResponseMeta{}}, err
 		}
 
-		cd.c.Set(requestKey, adjustedBytes, sliceSize(adjustedBytes))
+		cd.set(requestKey, newCacheEntry(namespace, [][]byte{adjustedBytes}))
 	}
 
 	// Return both the computed and err in ALL cases: computed contains resolved
@@ -334,9 +362,11 @@ func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResour
 		return err
 	}
 
+	namespace := req.GetResourceRelation().GetNamespace()
 	if cachedResultRaw, found := cd.c.Get(requestKey); found {
 		cd.reachableResourcesFromCacheCounter.Inc()
-		for _, slice := range cachedResultRaw.([][]byte) {
+		cd.namespaceMetrics.lookedUp(stream.Context(), "reachable_resources", namespace, true)
+		for _, slice := range cachedResultRaw.(*cacheEntry).results {
 			var response v1.DispatchReachableResourcesResponse
 			if err := response.UnmarshalVT(slice); err != nil {
 				return fmt.Errorf("could not publish cached reachable resources result: %w", err)
@@ -348,6 +378,7 @@ func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResour
 
 		return nil
 	}
+	cd.namespaceMetrics.lookedUp(stream.Context(), "reachable_resources", namespace, false)
 
 	var (
 		mu             sync.Mutex
@@ -379,15 +410,17 @@ func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResour
 		return err
 	}
 
-	var size int64
-	for _, slice := range toCacheResults {
-		size += sliceSize(slice)
-	}
-
-	cd.c.Set(requestKey, toCacheResults, size)
+	cd.set(requestKey, newCacheEntry(namespace, toCacheResults))
 	return nil
 }
 
+// set caches the entry, and records its size against its namespace.
+func (cd *Dispatcher) set(key interface{}, entry *cacheEntry) {
+	if cd.c.Set(key, entry, entry.size) {
+		cd.namespaceMetrics.added(entry)
+	}
+}
+
 func sliceSize(xs []byte) int64 {
 	// Slice Header + Slice Contents
 	return int64(int(unsafe.Sizeof(xs)) + len(xs))
@@ -402,9 +435,11 @@ func (cd *Dispatcher) DispatchLookupSubjects(req *v1.DispatchLookupSubjectsReque
 		return err
 	}
 
+	namespace := req.GetResourceRelation().GetNamespace()
 	if cachedResultRaw, found := cd.c.Get(requestKey); found {
 		cd.lookupSubjectsFromCacheCounter.Inc()
-		for _, slice := range cachedResultRaw.([][]byte) {
+		cd.namespaceMetrics.lookedUp(stream.Context(), "lookup_subjects", namespace, true)
+		for _, slice := range cachedResultRaw.(*cacheEntry).results {
 			var response v1.DispatchLookupSubjectsResponse
 			if err := response.UnmarshalVT(slice); err != nil {
 				return err
@@ -418,6 +453,7 @@ func (cd *Dispatcher) DispatchLookupSubjects(req *v1.DispatchLookupSubjectsReque
 		}
 		return nil
 	}
+	cd.namespaceMetrics.lookedUp(stream.Context(), "lookup_subjects", namespace, false)
 
 	var (
 		mu             sync.Mutex
@@ -449,12 +485,7 @@ func (cd *Dispatcher) DispatchLookupSubjects(req *v1.DispatchLookupSubjectsReque
 		return err
 	}
 
-	var size int64
-	for _, slice := range toCacheResults {
-		size += sliceSize(slice)
-	}
-
-	cd.c.Set(requestKey, toCacheResults, size)
+	cd.set(requestKey, newCacheEntry(namespace, toCacheResults))
 	return nil
 }
 
@@ -471,6 +502,9 @@ func (cd *Dispatcher) Close() error {
 	prometheus.Unregister(cd.cacheMisses)
 	prometheus.Unregister(cd.costAddedBytes)
 	prometheus.Unregister(cd.costEvictedBytes)
+	for _, collector := range cd.namespaceMetrics.collectors() {
+		prometheus.Unregister(collector)
+	}
 	if cache := cd.c; cache != nil {
 		cache.Close()
 	}
@@ -483,4 +517,7 @@ func (cd *Dispatcher) IsReady() bool {
 }
 
 // Always verify that we implement the interfaces
-var _ dispatch.Dispatcher = &Dispatcher{}
+var (
+	_ dispatch.Dispatcher         = &Dispatcher{}
+	_ dispatch.ConcurrencyLimited = &Dispatcher{}
+)
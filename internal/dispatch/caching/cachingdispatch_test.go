@@ -2,12 +2,15 @@ package caching
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -145,6 +148,64 @@ func TestMaxDepthCaching(t *testing.T) {
 	}
 }
 
+func TestNamespaceMetrics(t *testing.T) {
+	require := require.New(t)
+
+	delegate := delegateDispatchMock{&mock.Mock{}}
+	delegate.On("DispatchCheck", mock.Anything).Return(&v1.DispatchCheckResponse{
+		ResultsByResourceId: map[string]*v1.ResourceCheckResult{},
+		Metadata:            &v1.ResponseMeta{DispatchCount: 1},
+	}, nil)
+
+	dispatch, err := NewCachingDispatcher(DispatchTestCache(t), "", nil)
+	require.NoError(err)
+	dispatch.SetDelegate(delegate)
+	defer dispatch.Close()
+
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), &fakeTransportStream{method: "/authzed.api.v1.PermissionsService/CheckPermission"})
+	for _, namespace := range []string{"document", "document", "folder"} {
+		_, err := dispatch.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+			ResourceRelation: RR(namespace, "view"),
+			ResourceIds:      []string{"someid"},
+			Subject:          tuple.ParseSubjectONR("user:someuser#..."),
+			Metadata:         &v1.ResolverMeta{AtRevision: decimal.Zero.String(), DepthRemaining: 50},
+		})
+		require.NoError(err)
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	metrics := dispatch.namespaceMetrics
+	require.Equal(1.0, testutil.ToFloat64(metrics.lookups.WithLabelValues("CheckPermission", "check", "document", "hit")))
+	require.Equal(1.0, testutil.ToFloat64(metrics.lookups.WithLabelValues("CheckPermission", "check", "document", "miss")))
+	require.Equal(1.0, testutil.ToFloat64(metrics.lookups.WithLabelValues("CheckPermission", "check", "folder", "miss")))
+	require.Greater(testutil.ToFloat64(metrics.bytes.WithLabelValues("document")), 0.0)
+
+	// Removed entries are attributed to their namespace.
+	entry := newCacheEntry("document", [][]byte{[]byte("someresult")})
+	metrics.added(entry)
+	before := testutil.ToFloat64(metrics.bytes.WithLabelValues("document"))
+	metrics.removed(entry, entry.size, false)
+	require.Equal(1.0, testutil.ToFloat64(metrics.evictions.WithLabelValues("document")))
+	require.Equal(before-float64(entry.size), testutil.ToFloat64(metrics.bytes.WithLabelValues("document")))
+}
+
+func TestNamespaceLabelCardinality(t *testing.T) {
+	metrics := newNamespaceMetrics("")
+	for index := 0; index < maxNamespaceLabels; index++ {
+		namespace := fmt.Sprintf("namespace%d", index)
+		require.Equal(t, namespace, metrics.namespaceLabel(namespace))
+	}
+	require.Equal(t, otherNamespaceLabel, metrics.namespaceLabel("onetoomany"))
+	require.Equal(t, "namespace0", metrics.namespaceLabel("namespace0"))
+}
+
+type fakeTransportStream struct {
+	grpc.ServerTransportStream
+	method string
+}
+
+func (f *fakeTransportStream) Method() string { return f.method }
+
 type delegateDispatchMock struct {
 	*mock.Mock
 }
@@ -0,0 +1,136 @@
+package caching
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"google.golang.org/grpc"
+)
+
+const (
+	// maxNamespaceLabels is the number of distinct namespaces labeling the cache metrics, beyond
+	// which the namespaces are labeled otherNamespaceLabel, bounding the cardinality of the
+	// metrics for schemas with many definitions.
+	maxNamespaceLabels = 100
+
+	// otherNamespaceLabel labels the namespaces beyond the first maxNamespaceLabels. It is not a
+	// valid namespace name, and so cannot be confused with one.
+	otherNamespaceLabel = "_other"
+
+	// unknownMethodLabel labels the dispatches which are not made for a gRPC call.
+	unknownMethodLabel = "unknown"
+
+	cacheHit  = "hit"
+	cacheMiss = "miss"
+)
+
+// cacheEntry is a cached dispatch result, holding the namespace of its resources so that its
+// removal from the cache is attributed to that namespace.
+type cacheEntry struct {
+	namespace string
+	results   [][]byte
+	size      int64
+}
+
+func newCacheEntry(namespace string, results [][]byte) *cacheEntry {
+	var size int64
+	for _, result := range results {
+		size += sliceSize(result)
+	}
+	return &cacheEntry{namespace: namespace, results: results, size: size}
+}
+
+// namespaceMetrics measures the effectiveness of the cache by API method and by namespace.
+type namespaceMetrics struct {
+	lookups   *prometheus.CounterVec
+	evictions *prometheus.CounterVec
+	bytes     *prometheus.GaugeVec
+
+	mu         sync.RWMutex
+	namespaces map[string]struct{}
+}
+
+func newNamespaceMetrics(prometheusSubsystem string) *namespaceMetrics {
+	return &namespaceMetrics{
+		lookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "cache_lookups_total",
+			Help:      "Number of dispatches looked up in the cache, by API method, kind of dispatch, namespace and result (hit or miss).",
+		}, []string{"method", "dispatch", "namespace", "result"}),
+		evictions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "cache_evictions_total",
+			Help:      "Number of cached dispatch results evicted or rejected by the cache, by namespace.",
+		}, []string{"namespace"}),
+		bytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      "cache_namespace_bytes",
+			Help:      "Estimated size of the cached dispatch results, by namespace.",
+		}, []string{"namespace"}),
+		namespaces: make(map[string]struct{}, maxNamespaceLabels),
+	}
+}
+
+func (nm *namespaceMetrics) collectors() []prometheus.Collector {
+	return []prometheus.Collector{nm.lookups, nm.evictions, nm.bytes}
+}
+
+// namespaceLabel returns the label of the namespace, which is the namespace itself unless
+// maxNamespaceLabels other namespaces were already labeled.
+func (nm *namespaceMetrics) namespaceLabel(namespace string) string {
+	nm.mu.RLock()
+	_, ok := nm.namespaces[namespace]
+	count := len(nm.namespaces)
+	nm.mu.RUnlock()
+	if ok {
+		return namespace
+	}
+	if count >= maxNamespaceLabels {
+		return otherNamespaceLabel
+	}
+
+	nm.mu.Lock()
+	defer nm.mu.Unlock()
+	if _, ok := nm.namespaces[namespace]; !ok && len(nm.namespaces) >= maxNamespaceLabels {
+		return otherNamespaceLabel
+	}
+	nm.namespaces[namespace] = struct{}{}
+	return namespace
+}
+
+// lookedUp records the lookup of a dispatch of the given kind in the cache.
+func (nm *namespaceMetrics) lookedUp(ctx context.Context, dispatch string, namespace string, hit bool) {
+	method := unknownMethodLabel
+	if fullMethod, ok := grpc.Method(ctx); ok {
+		method = fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	}
+
+	result := cacheMiss
+	if hit {
+		result = cacheHit
+	}
+	nm.lookups.WithLabelValues(method, dispatch, nm.namespaceLabel(namespace), result).Inc()
+}
+
+// added records an entry set in the cache.
+func (nm *namespaceMetrics) added(entry *cacheEntry) {
+	nm.bytes.WithLabelValues(nm.namespaceLabel(entry.namespace)).Add(float64(entry.size))
+}
+
+// removed records an entry removed from the cache. Entries replaced by newer entries for the
+// same key are not reported by the cache, so the size of their namespace is overestimated.
+func (nm *namespaceMetrics) removed(value interface{}, _ int64, _ bool) {
+	entry, ok := value.(*cacheEntry)
+	if !ok {
+		return
+	}
+
+	label := nm.namespaceLabel(entry.namespace)
+	nm.evictions.WithLabelValues(label).Inc()
+	nm.bytes.WithLabelValues(label).Sub(float64(entry.size))
+}
@@ -8,6 +8,7 @@ import (
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 
+	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/pkg/datastore"
 	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/generator"
@@ -21,14 +22,14 @@ func ConvertDispatchDebugInformation(ctx context.Context, metadata *dispatch.Res
 		return nil, nil
 	}
 
-	namespaces, err := reader.ListNamespaces(ctx)
+	namespaces, err := namespace.ListLiveNamespaces(ctx, reader)
 	if err != nil {
 		return nil, err
 	}
 
 	schema := ""
-	for _, namespace := range namespaces {
-		generated, _ := generator.GenerateSource(namespace)
+	for _, nsDef := range namespaces {
+		generated, _ := generator.GenerateSource(nsDef)
 		schema += generated
 		schema += "\n\n"
 	}
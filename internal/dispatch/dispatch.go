@@ -29,6 +29,14 @@ type Dispatcher interface {
 	IsReady() bool
 }
 
+// ConcurrencyLimited is implemented by dispatchers whose concurrency limit can be changed while
+// they are in use.
+type ConcurrencyLimited interface {
+	// SetConcurrencyLimit sets the maximum number of goroutines created by each request or
+	// subrequest, applying to the requests started afterwards.
+	SetConcurrencyLimit(limit uint16)
+}
+
 // Check interface describes just the methods required to dispatch check requests.
 type Check interface {
 	// DispatchCheck submits a single check request and returns its result.
@@ -13,6 +13,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
 	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/internal/datastore/memdb"
@@ -297,3 +298,145 @@ func TestMaxDepthExpand(t *testing.T) {
 
 	require.Error(err)
 }
+
+func TestCaveatedExpand(t *testing.T) {
+	defer goleak.VerifyNone(t, goleakIgnores...)
+
+	caveated := func(node *core.RelationTupleTreeNode, caveatName string) *core.RelationTupleTreeNode {
+		node.Caveat = &core.ContextualizedCaveat{CaveatName: caveatName, Context: &structpb.Struct{}}
+		return node
+	}
+
+	caveatedLeaf := func(start *core.ObjectAndRelation, subjects []*core.ObjectAndRelation, caveatedSubjects ...*core.ObjectAndRelation) *core.RelationTupleTreeNode {
+		node := graph.Leaf(start, subjects...)
+		for _, subject := range caveatedSubjects {
+			node.GetLeafNode().CaveatedSubjects = append(node.GetLeafNode().CaveatedSubjects, &core.DirectSubject{
+				Subject: subject,
+				Caveat:  &core.ContextualizedCaveat{CaveatName: "somecaveat", Context: &structpb.Struct{}},
+			})
+		}
+		return node
+	}
+
+	testCases := []struct {
+		name          string
+		schema        string
+		relationships []*core.RelationTuple
+		start         *core.ObjectAndRelation
+		expansionMode v1.DispatchExpandRequest_ExpansionMode
+		expected      *core.RelationTupleTreeNode
+	}{
+		{
+			"caveated subjects",
+			`definition user {}
+
+			 caveat somecaveat(somecondition int) {
+				somecondition == 42
+			 }
+
+			 definition document {
+				relation viewer: user | user with somecaveat
+			 }`,
+			[]*core.RelationTuple{
+				tuple.WithCaveat(tuple.MustParse("document:first#viewer@user:tom"), "somecaveat"),
+				tuple.MustParse("document:first#viewer@user:sarah"),
+			},
+			ONR("document", "first", "viewer"),
+			v1.DispatchExpandRequest_SHALLOW,
+			caveatedLeaf(ONR("document", "first", "viewer"),
+				[]*core.ObjectAndRelation{ONR("user", "tom", "..."), ONR("user", "sarah", "...")},
+				ONR("user", "tom", "..."),
+			),
+		},
+		{
+			"caveated arrow",
+			`definition user {}
+
+			 caveat somecaveat(somecondition int) {
+				somecondition == 42
+			 }
+
+			 definition org {
+				relation viewer: user
+			 }
+
+			 definition document {
+				relation org: org with somecaveat
+				permission view = org->viewer
+			 }`,
+			[]*core.RelationTuple{
+				tuple.WithCaveat(tuple.MustParse("document:first#org@org:someorg"), "somecaveat"),
+				tuple.MustParse("org:someorg#viewer@user:tom"),
+			},
+			ONR("document", "first", "view"),
+			v1.DispatchExpandRequest_SHALLOW,
+			graph.Union(ONR("document", "first", "view"),
+				graph.Union(ONR("document", "first", "view"),
+					caveated(graph.Leaf(ONR("org", "someorg", "viewer"), ONR("user", "tom", "...")), "somecaveat"),
+				),
+			),
+		},
+		{
+			"caveated userset recursive",
+			`definition user {}
+
+			 caveat somecaveat(somecondition int) {
+				somecondition == 42
+			 }
+
+			 definition group {
+				relation member: user
+			 }
+
+			 definition document {
+				relation viewer: group#member with somecaveat
+			 }`,
+			[]*core.RelationTuple{
+				tuple.WithCaveat(tuple.MustParse("document:first#viewer@group:eng#member"), "somecaveat"),
+				tuple.MustParse("group:eng#member@user:tom"),
+			},
+			ONR("document", "first", "viewer"),
+			v1.DispatchExpandRequest_RECURSIVE,
+			graph.Union(ONR("document", "first", "viewer"),
+				caveated(graph.Leaf(ONR("group", "eng", "member"), ONR("user", "tom", "...")), "somecaveat"),
+				caveatedLeaf(ONR("document", "first", "viewer"),
+					[]*core.ObjectAndRelation{ONR("group", "eng", "member")},
+					ONR("group", "eng", "member"),
+				),
+			),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			dispatcher := NewLocalOnlyDispatcher(10)
+
+			ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(ds, tc.schema, tc.relationships, require)
+
+			ctx := datastoremw.ContextWithHandle(context.Background())
+			require.NoError(datastoremw.SetInContext(ctx, ds))
+
+			expandResult, err := dispatcher.DispatchExpand(ctx, &v1.DispatchExpandRequest{
+				ResourceAndRelation: tc.start,
+				Metadata: &v1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: 50,
+				},
+				ExpansionMode: tc.expansionMode,
+			})
+			require.NoError(err)
+
+			require.Empty(cmp.Diff(tc.expected, expandResult.TreeNode,
+				protocmp.Transform(),
+				protocmp.SortRepeated(func(a, b *core.ObjectAndRelation) bool {
+					return tuple.StringONR(a) < tuple.StringONR(b)
+				}),
+			))
+		})
+	}
+}
@@ -12,10 +12,11 @@ import (
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/graph"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/redaction"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
-	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 const errDispatch = "error dispatching request: %w"
@@ -61,11 +62,22 @@ type localDispatcher struct {
 	lookupSubjectsHandler     *graph.ConcurrentLookupSubjects
 }
 
+var _ dispatch.ConcurrencyLimited = &localDispatcher{}
+
+// SetConcurrencyLimit sets the maximum number of goroutines created by each request or subrequest
+// handled by the dispatcher.
+func (ld *localDispatcher) SetConcurrencyLimit(limit uint16) {
+	ld.checker.SetConcurrencyLimit(limit)
+	ld.lookupHandler.SetConcurrencyLimit(limit)
+	ld.reachableResourcesHandler.SetConcurrencyLimit(limit)
+	ld.lookupSubjectsHandler.SetConcurrencyLimit(limit)
+}
+
 func (ld *localDispatcher) loadNamespace(ctx context.Context, nsName string, revision datastore.Revision) (*core.NamespaceDefinition, error) {
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(revision)
 
 	// Load namespace and relation from the datastore
-	ns, _, err := ds.ReadNamespace(ctx, nsName)
+	ns, _, err := namespace.ReadLiveNamespace(ctx, ds, nsName)
 	if err != nil {
 		return nil, rewriteError(err)
 	}
@@ -99,7 +111,7 @@ type stringableOnr struct {
 }
 
 func (onr stringableOnr) String() string {
-	return tuple.StringONR(onr.ObjectAndRelation)
+	return redaction.ONR(onr.ObjectAndRelation)
 }
 
 type stringableRelRef struct {
@@ -114,7 +126,7 @@ func (rr stringableRelRef) String() string {
 func (ld *localDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
 	ctx, span := tracer.Start(ctx, "DispatchCheck", trace.WithAttributes(
 		attribute.Stringer("resource-type", stringableRelRef{req.ResourceRelation}),
-		attribute.StringSlice("resource-ids", req.ResourceIds),
+		attribute.StringSlice("resource-ids", redaction.IDs(req.ResourceIds)),
 		attribute.Stringer("subject", stringableOnr{req.Subject}),
 	))
 	defer span.End()
@@ -260,7 +272,7 @@ func (ld *localDispatcher) DispatchReachableResources(
 	ctx, span := tracer.Start(stream.Context(), "DispatchReachableResources", trace.WithAttributes(
 		attribute.Stringer("resource-type", stringableRelRef{req.ResourceRelation}),
 		attribute.Stringer("subject-type", stringableRelRef{req.SubjectRelation}),
-		attribute.StringSlice("subject-ids", req.SubjectIds),
+		attribute.StringSlice("subject-ids", redaction.IDs(req.SubjectIds)),
 	))
 	defer span.End()
 
@@ -290,7 +302,7 @@ func (ld *localDispatcher) DispatchLookupSubjects(
 	ctx, span := tracer.Start(stream.Context(), "DispatchLookupSubjects", trace.WithAttributes(
 		attribute.Stringer("resource-type", stringableRelRef{req.ResourceRelation}),
 		attribute.Stringer("subject-type", stringableRelRef{req.SubjectRelation}),
-		attribute.StringSlice("resource-ids", req.ResourceIds),
+		attribute.StringSlice("resource-ids", redaction.IDs(req.ResourceIds)),
 	))
 	defer span.End()
 
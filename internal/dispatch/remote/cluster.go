@@ -7,6 +7,7 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/connectivity"
+	_ "google.golang.org/grpc/health" // registers client side health checking
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/dispatch/keys"
@@ -15,6 +16,25 @@ import (
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
+// ServiceConfig is the service config of the connections to the peers of the dispatch cluster.
+// Dispatches are balanced over a consistent hashring of the peers whose health checks report the
+// dispatch service as serving, so that draining peers leave the hashring before they stop, and
+// dispatches failing because their peer became unavailable are retried on the new hashring.
+const ServiceConfig = `{
+	"loadBalancingPolicy": "` + balancer.BalancerName + `",
+	"healthCheckConfig": {"serviceName": "dispatch.v1.DispatchService"},
+	"methodConfig": [{
+		"name": [{"service": "dispatch.v1.DispatchService"}],
+		"retryPolicy": {
+			"maxAttempts": 4,
+			"initialBackoff": "0.05s",
+			"maxBackoff": "0.5s",
+			"backoffMultiplier": 2,
+			"retryableStatusCodes": ["UNAVAILABLE"]
+		}
+	}]
+}`
+
 type clusterClient interface {
 	DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest, opts ...grpc.CallOption) (*v1.DispatchCheckResponse, error)
 	DispatchExpand(ctx context.Context, req *v1.DispatchExpandRequest, opts ...grpc.CallOption) (*v1.DispatchExpandResponse, error)
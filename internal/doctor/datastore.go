@@ -0,0 +1,157 @@
+package doctor
+
+import (
+	"context"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/migrate"
+)
+
+const (
+	checkDatastore  = "datastore"
+	checkMigrations = "migrations"
+	checkIndexes    = "indexes"
+	checkClockSkew  = "clock skew"
+	checkGC         = "garbage collection"
+)
+
+// CheckDatastore checks that the datastore, of the given engine, is reachable and ready.
+func CheckDatastore(ctx context.Context, ds datastore.Datastore, engine string) Finding {
+	ready, err := ds.IsReady(ctx)
+	if err != nil {
+		return failure(checkDatastore, "check --datastore-conn-uri, the credentials it holds and that the datastore is reachable from this host",
+			"unable to reach the %s datastore: %s", engine, err)
+	}
+	if !ready {
+		return failure(checkDatastore, "migrate the datastore to the revision expected by this version of SpiceDB with `spicedb migrate head`",
+			"the %s datastore is reachable, but not ready to serve", engine)
+	}
+	return ok(checkDatastore, "the %s datastore is reachable and ready", engine)
+}
+
+// CheckMigrations checks that the datastore is migrated to the head revision of this version of
+// SpiceDB and, if so and the driver can list them, that the indexes created by the migrations are
+// present.
+func CheckMigrations[D migrate.Driver[C, T], C any, T any](ctx context.Context, driver D, manager *migrate.Manager[D, C, T]) []Finding {
+	head, err := manager.HeadRevision()
+	if err != nil {
+		return []Finding{failure(checkMigrations, "report the problem, as it is a bug of this version of SpiceDB", "invalid migrations: %s", err)}
+	}
+
+	current, err := driver.Version(ctx)
+	if err != nil {
+		return []Finding{
+			failure(checkMigrations, "check that the datastore is reachable and that its user can read the migration version", "unable to read the migration revision: %s", err),
+			skipped(checkIndexes, "the migration revision is unknown"),
+		}
+	}
+
+	var migrations Finding
+	switch compatible, _ := manager.IsHeadCompatible(current); {
+	case current == head:
+		migrations = ok(checkMigrations, "the datastore is migrated to %s, the head revision of this version of SpiceDB", head)
+	case current == migrate.None:
+		return []Finding{
+			failure(checkMigrations, "migrate the datastore with `spicedb migrate head`", "the datastore is not migrated"),
+			skipped(checkIndexes, "the datastore is not migrated"),
+		}
+	case compatible:
+		migrations = warning(checkMigrations, "complete the migration with `spicedb migrate head` once all of the nodes run this version of SpiceDB",
+			"the datastore is migrated to %s, one revision behind the head revision %s, which this version of SpiceDB is compatible with", current, head)
+	default:
+		return []Finding{
+			failure(checkMigrations, "migrate the datastore with `spicedb migrate head`, or upgrade SpiceDB if the datastore was migrated by a newer version",
+				"the datastore is migrated to %s, but this version of SpiceDB requires %s", current, head),
+			skipped(checkIndexes, "the datastore is not migrated to the head revision"),
+		}
+	}
+
+	checker, isChecker := any(driver).(migrate.IndexChecker)
+	switch {
+	case !isChecker:
+		return []Finding{migrations, skipped(checkIndexes, "the indexes of the datastore are not checked for this engine")}
+	case current != head:
+		return []Finding{migrations, skipped(checkIndexes, "the datastore is not migrated to the head revision")}
+	}
+
+	missing, err := checker.MissingIndexes(ctx)
+	switch {
+	case err != nil:
+		return []Finding{migrations, failure(checkIndexes, "check that the user of the datastore can read its catalog", "unable to list the indexes of the datastore: %s", err)}
+	case len(missing) > 0:
+		return []Finding{migrations, failure(checkIndexes, "recreate the missing indexes as defined by the migrations of SpiceDB; queries and garbage collection are slow without them",
+			"the indexes %v are missing", missing)}
+	default:
+		return []Finding{migrations, ok(checkIndexes, "all of the indexes created by the migrations are present")}
+	}
+}
+
+// clock is implemented by the datastores which can read their current time.
+type clock interface {
+	Now(ctx context.Context) (time.Time, error)
+}
+
+// CheckClockSkew checks that the clock of this host is within the given skew of that of the
+// datastore, allowing for the latency of reading the time of the datastore.
+func CheckClockSkew(ctx context.Context, ds datastore.Datastore, maxSkew time.Duration) Finding {
+	remoteClock, isClock := ds.(clock)
+	if !isClock {
+		return skipped(checkClockSkew, "the time of the datastore cannot be read for this engine")
+	}
+
+	before := time.Now()
+	remote, err := remoteClock.Now(ctx)
+	if err != nil {
+		return failure(checkClockSkew, "check that the datastore is reachable", "unable to read the time of the datastore: %s", err)
+	}
+	return clockSkewFinding(before, remote, time.Now(), maxSkew)
+}
+
+// clockSkewFinding compares the time of the datastore, read between the given local times, with
+// the local time halfway between them.
+func clockSkewFinding(before, remote, after time.Time, maxSkew time.Duration) Finding {
+	roundTrip := after.Sub(before)
+	skew := remote.Sub(before.Add(roundTrip / 2))
+	if skew < 0 {
+		skew = -skew
+	}
+
+	if skew-roundTrip/2 > maxSkew {
+		return warning(checkClockSkew, "synchronize the clocks of this host and of the datastore, such as with NTP; revisions and garbage collection depend on the time of the datastore",
+			"the clock of this host is %s apart from that of the datastore, beyond the maximum of %s", skew.Round(time.Millisecond), maxSkew)
+	}
+	return ok(checkClockSkew, "the clock of this host is within %s of that of the datastore", (skew + roundTrip/2).Round(time.Millisecond))
+}
+
+// CheckGC checks that garbage collection keeps up with its window: it deletes all but the last of
+// the transactions older than the window on each pass, so more than one transaction older than
+// the window by more than the given tolerance indicates that passes are failing or not running.
+func CheckGC(ctx context.Context, ds datastore.Datastore, window, tolerance time.Duration) Finding {
+	gc, isGC := ds.(common.GarbageCollector)
+	counter, isCounter := ds.(common.GCBacklogCounter)
+	if !isGC || !isCounter {
+		return skipped(checkGC, "garbage collection is not run by SpiceDB for this engine")
+	}
+
+	now, err := gc.Now(ctx)
+	if err != nil {
+		return failure(checkGC, "check that the datastore is reachable", "unable to read the time of the datastore: %s", err)
+	}
+
+	stale, err := counter.CountTxsBefore(ctx, now.Add(-window-tolerance))
+	if err != nil {
+		return failure(checkGC, "check that the datastore is reachable", "unable to count the transactions older than the window: %s", err)
+	}
+	return gcFinding(stale, window, tolerance)
+}
+
+func gcFinding(stale int64, window, tolerance time.Duration) Finding {
+	if stale > 1 {
+		return warning(checkGC, "check the logs and the spicedb_datastore_gc_failures_total metric of the serving nodes for failing passes, "+
+			"check that they run with a non-zero --datastore-gc-interval, or catch up with `spicedb datastore gc`",
+			"%d transactions are older than the window of %s by more than %s, so garbage collection is behind", stale, window, tolerance)
+	}
+	return ok(checkGC, "garbage collection is keeping up with its window of %s", window)
+}
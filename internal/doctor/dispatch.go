@@ -0,0 +1,57 @@
+package doctor
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+const checkDispatch = "dispatch"
+
+// CheckDispatchUpstream checks that the dispatch service of the cluster at the given address,
+// trusted with the CA certificates at the given path if any, is reachable and serving.
+func CheckDispatchUpstream(ctx context.Context, addr, caPath string) Finding {
+	if addr == "" {
+		return skipped(checkDispatch, "dispatching to other nodes is not configured")
+	}
+
+	transport := insecure.NewCredentials()
+	if caPath != "" {
+		contents, err := os.ReadFile(caPath)
+		if err != nil {
+			return failure(checkDispatch, "check --dispatch-upstream-ca-path", "unable to read the CA certificates of the dispatch cluster: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(contents) {
+			return failure(checkDispatch, "check --dispatch-upstream-ca-path", "the file %s holds no PEM-encoded CA certificate", caPath)
+		}
+		transport = credentials.NewTLS(&tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12})
+	}
+
+	conn, err := grpc.DialContext(ctx, addr, grpc.WithTransportCredentials(transport), grpc.WithBlock())
+	if err != nil {
+		return failure(checkDispatch, "check --dispatch-upstream-addr, that the dispatch port of the cluster is reachable from this host and, with TLS, its CA certificates",
+			"unable to connect to the dispatch cluster at %s: %s", addr, err)
+	}
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{
+		Service: dispatchv1.DispatchService_ServiceDesc.ServiceName,
+	})
+	if err != nil {
+		return failure(checkDispatch, "check that the nodes of the cluster run with --dispatch-cluster-enabled",
+			"unable to check the health of the dispatch cluster at %s: %s", addr, err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		return failure(checkDispatch, "check the logs of the nodes of the dispatch cluster", "the dispatch cluster at %s is %s", addr, resp.Status)
+	}
+	return ok(checkDispatch, "the dispatch cluster at %s is reachable and serving", addr)
+}
@@ -0,0 +1,105 @@
+// Package doctor diagnoses the environment in which SpiceDB runs, such as its datastore, its
+// dispatch cluster and its TLS certificates, reporting findings along with how to act on them.
+package doctor
+
+import (
+	"fmt"
+	"io"
+)
+
+// Status is the outcome of a check.
+type Status int
+
+const (
+	// StatusOK indicates that the check found no problem.
+	StatusOK Status = iota
+
+	// StatusSkipped indicates that the check does not apply to the configuration, or could not run
+	// as a check it depends on failed.
+	StatusSkipped
+
+	// StatusWarning indicates a problem which does not prevent SpiceDB from serving, but should be
+	// acted upon.
+	StatusWarning
+
+	// StatusFailure indicates a problem which prevents SpiceDB from serving correctly.
+	StatusFailure
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusOK:
+		return "ok"
+	case StatusSkipped:
+		return "skip"
+	case StatusWarning:
+		return "warn"
+	case StatusFailure:
+		return "FAIL"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Finding is the outcome of a check.
+type Finding struct {
+	// Check is the name of the check.
+	Check string
+
+	// Status is the outcome of the check.
+	Status Status
+
+	// Message describes what the check found.
+	Message string
+
+	// Remedy, for warnings and failures, describes how to resolve the problem.
+	Remedy string
+}
+
+func ok(check, format string, args ...any) Finding {
+	return Finding{Check: check, Status: StatusOK, Message: fmt.Sprintf(format, args...)}
+}
+
+func skipped(check, format string, args ...any) Finding {
+	return Finding{Check: check, Status: StatusSkipped, Message: fmt.Sprintf(format, args...)}
+}
+
+func warning(check, remedy, format string, args ...any) Finding {
+	return Finding{Check: check, Status: StatusWarning, Message: fmt.Sprintf(format, args...), Remedy: remedy}
+}
+
+func failure(check, remedy, format string, args ...any) Finding {
+	return Finding{Check: check, Status: StatusFailure, Message: fmt.Sprintf(format, args...), Remedy: remedy}
+}
+
+// Report is the findings of a run of the checks, in the order in which they ran.
+type Report []Finding
+
+// Count returns the number of findings with the given status.
+func (r Report) Count(status Status) int {
+	count := 0
+	for _, finding := range r {
+		if finding.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// Write writes the findings, one per line followed by the remedy of each problem, and a summary.
+func (r Report) Write(w io.Writer) error {
+	for _, finding := range r {
+		if _, err := fmt.Fprintf(w, "[%4s] %s: %s\n", finding.Status, finding.Check, finding.Message); err != nil {
+			return err
+		}
+		if finding.Remedy != "" && finding.Status >= StatusWarning {
+			if _, err := fmt.Fprintf(w, "       -> %s\n", finding.Remedy); err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := fmt.Fprintf(w, "\n%d check(s): %d ok, %d warning(s), %d failure(s), %d skipped\n",
+		len(r), r.Count(StatusOK), r.Count(StatusWarning), r.Count(StatusFailure), r.Count(StatusSkipped))
+	return err
+}
@@ -0,0 +1,145 @@
+package doctor
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeCertificate(t *testing.T, notBefore, notAfter time.Time) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "spicedb"},
+		NotBefore:    notBefore,
+		NotAfter:     notAfter,
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certPath, keyPath
+}
+
+func TestCheckCertificate(t *testing.T) {
+	now := time.Now()
+
+	testCases := []struct {
+		name      string
+		notBefore time.Time
+		notAfter  time.Time
+		noKey     bool
+		expected  Status
+	}{
+		{"valid", now.Add(-time.Hour), now.Add(365 * 24 * time.Hour), false, StatusOK},
+		{"expiring", now.Add(-time.Hour), now.Add(24 * time.Hour), false, StatusWarning},
+		{"expired", now.Add(-48 * time.Hour), now.Add(-time.Hour), false, StatusFailure},
+		{"not yet valid", now.Add(time.Hour), now.Add(48 * time.Hour), false, StatusFailure},
+		{"missing key", now.Add(-time.Hour), now.Add(365 * 24 * time.Hour), true, StatusFailure},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			certPath, keyPath := writeCertificate(t, tc.notBefore, tc.notAfter)
+			if tc.noKey {
+				keyPath = ""
+			}
+
+			finding := CheckCertificate("gRPC", certPath, keyPath, now, 30*24*time.Hour)
+			require.Equal(t, tc.expected, finding.Status, finding.Message)
+			require.Equal(t, checkTLS, finding.Check)
+			if tc.expected != StatusOK {
+				require.NotEmpty(t, finding.Remedy)
+			}
+
+			ca := CheckCA("the dispatch cluster", certPath, now, 30*24*time.Hour)
+			if !tc.noKey {
+				require.Equal(t, tc.expected, ca.Status, ca.Message)
+			}
+		})
+	}
+}
+
+func TestCheckCAInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(path, []byte("not a certificate"), 0o600))
+	require.Equal(t, StatusFailure, CheckCA("the dispatch cluster", path, time.Now(), time.Hour).Status)
+	require.Equal(t, StatusFailure, CheckCA("the dispatch cluster", filepath.Join(t.TempDir(), "missing.pem"), time.Now(), time.Hour).Status)
+}
+
+func TestClockSkewFinding(t *testing.T) {
+	before := time.Now()
+
+	testCases := []struct {
+		name      string
+		remote    time.Time
+		roundTrip time.Duration
+		expected  Status
+	}{
+		{"in sync", before.Add(5 * time.Millisecond), 10 * time.Millisecond, StatusOK},
+		{"ahead within maximum", before.Add(500 * time.Millisecond), 10 * time.Millisecond, StatusOK},
+		{"ahead beyond maximum", before.Add(3 * time.Second), 10 * time.Millisecond, StatusWarning},
+		{"behind beyond maximum", before.Add(-3 * time.Second), 10 * time.Millisecond, StatusWarning},
+		{"slow round trip", before.Add(1500 * time.Millisecond), 2 * time.Second, StatusOK},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			finding := clockSkewFinding(before, tc.remote, before.Add(tc.roundTrip), time.Second)
+			require.Equal(t, tc.expected, finding.Status, finding.Message)
+		})
+	}
+}
+
+func TestGCFinding(t *testing.T) {
+	require.Equal(t, StatusOK, gcFinding(0, time.Hour, time.Minute).Status)
+	require.Equal(t, StatusOK, gcFinding(1, time.Hour, time.Minute).Status)
+	require.Equal(t, StatusWarning, gcFinding(2, time.Hour, time.Minute).Status)
+}
+
+func TestReport(t *testing.T) {
+	report := Report{
+		ok(checkDatastore, "reachable"),
+		skipped(checkClockSkew, "not supported"),
+		warning(checkGC, "run gc", "behind"),
+		failure(checkDispatch, "check the address", "unreachable"),
+	}
+	require.Equal(t, 1, report.Count(StatusFailure))
+	require.Equal(t, 1, report.Count(StatusWarning))
+
+	var out bytes.Buffer
+	require.NoError(t, report.Write(&out))
+	require.Equal(t, `[  ok] datastore: reachable
+[skip] clock skew: not supported
+[warn] garbage collection: behind
+       -> run gc
+[FAIL] dispatch: unreachable
+       -> check the address
+
+4 check(s): 1 ok, 1 warning(s), 1 failure(s), 1 skipped
+`, out.String())
+}
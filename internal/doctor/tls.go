@@ -0,0 +1,88 @@
+package doctor
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"time"
+)
+
+const checkTLS = "tls"
+
+// CheckCertificate checks that the certificate and key at the given paths, used by the named
+// server, form a valid key pair whose certificate is valid at the given time and does not expire
+// within the given period.
+func CheckCertificate(server, certPath, keyPath string, now time.Time, expiryWarning time.Duration) Finding {
+	if keyPath == "" {
+		return failure(checkTLS, fmt.Sprintf("give the path of the key of the certificate, or remove the certificate of the %s server", server),
+			"the %s server has a certificate but no key", server)
+	}
+
+	pair, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err != nil {
+		return failure(checkTLS, "check that the paths point to the PEM-encoded certificate and its private key, readable by SpiceDB",
+			"the certificate %s and key %s of the %s server are not a valid key pair: %s", certPath, keyPath, server, err)
+	}
+
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return failure(checkTLS, "replace the certificate with a valid X.509 certificate",
+			"the certificate %s of the %s server is invalid: %s", certPath, server, err)
+	}
+	return checkValidity(fmt.Sprintf("the certificate %s of the %s server", certPath, server), leaf, now, expiryWarning)
+}
+
+// CheckCA checks that the file at the given path, used for the named purpose, holds CA
+// certificates which are all valid at the given time and do not expire within the given period.
+func CheckCA(purpose, caPath string, now time.Time, expiryWarning time.Duration) Finding {
+	contents, err := os.ReadFile(caPath)
+	if err != nil {
+		return failure(checkTLS, "check that the path points to a PEM-encoded file readable by SpiceDB",
+			"unable to read the CA certificates %s of %s: %s", caPath, purpose, err)
+	}
+
+	var certificates []*x509.Certificate
+	for block, rest := pem.Decode(contents); block != nil; block, rest = pem.Decode(rest) {
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return failure(checkTLS, "replace the file with valid PEM-encoded X.509 certificates",
+				"the CA certificates %s of %s are invalid: %s", caPath, purpose, err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return failure(checkTLS, "replace the file with valid PEM-encoded X.509 certificates",
+			"the file %s of the CA certificates of %s holds no certificate", caPath, purpose)
+	}
+
+	// Report the first certificate to expire, which is the most urgent to act upon.
+	first := certificates[0]
+	for _, certificate := range certificates[1:] {
+		if certificate.NotAfter.Before(first.NotAfter) {
+			first = certificate
+		}
+	}
+	return checkValidity(fmt.Sprintf("the CA certificates %s of %s", caPath, purpose), first, now, expiryWarning)
+}
+
+func checkValidity(subject string, certificate *x509.Certificate, now time.Time, expiryWarning time.Duration) Finding {
+	const remedy = "renew the certificate before it expires, and reload or restart SpiceDB to serve it"
+
+	switch {
+	case now.Before(certificate.NotBefore):
+		return failure(checkTLS, "check the clock of this host, or wait until the certificate is valid",
+			"%s is not valid until %s", subject, certificate.NotBefore.UTC().Format(time.RFC3339))
+	case !now.Before(certificate.NotAfter):
+		return failure(checkTLS, remedy, "%s expired at %s", subject, certificate.NotAfter.UTC().Format(time.RFC3339))
+	case certificate.NotAfter.Sub(now) < expiryWarning:
+		return warning(checkTLS, remedy, "%s expires in %s, at %s", subject,
+			certificate.NotAfter.Sub(now).Round(time.Minute), certificate.NotAfter.UTC().Format(time.RFC3339))
+	default:
+		return ok(checkTLS, "%s is valid until %s", subject, certificate.NotAfter.UTC().Format(time.RFC3339))
+	}
+}
@@ -0,0 +1,34 @@
+// Package fips reports whether SpiceDB was built in FIPS mode, in which its cryptography,
+// including TLS, is provided by the FIPS 140-2 validated BoringCrypto module.
+//
+// FIPS mode is enabled by building with the BoringCrypto toolchain experiment, which requires
+// cgo on linux/amd64 or linux/arm64:
+//
+//	GOEXPERIMENT=boringcrypto CGO_ENABLED=1 go build ./cmd/spicedb
+//
+// TLS is then restricted to FIPS-approved versions, cipher suites, curves and signature
+// algorithms. The hashes securing data, such as preshared key fingerprints, the HMAC signatures
+// of requests and webhooks and the cursors of streamed results, are SHA-256, which is approved
+// and provided by BoringCrypto. ZedTokens are encoded revisions, and carry no hash. The dispatch
+// cache keys, the consistent hashring of the dispatch cluster and the partitioning of change
+// stream records use non-cryptographic hashes, which distribute values rather than protect
+// them, and so are outside of the scope of FIPS 140-2.
+package fips
+
+import "errors"
+
+// Enabled returns whether SpiceDB was built in FIPS mode and its cryptography is provided by
+// BoringCrypto.
+func Enabled() bool {
+	return enabled()
+}
+
+// Verify returns an error if FIPS mode is required but SpiceDB was not built in FIPS mode, so
+// that a deployment requiring FIPS fails to start rather than run with non-validated
+// cryptography.
+func Verify(required bool) error {
+	if required && !Enabled() {
+		return errors.New("FIPS mode is required, but SpiceDB was not built with BoringCrypto: build it with GOEXPERIMENT=boringcrypto and CGO_ENABLED=1")
+	}
+	return nil
+}
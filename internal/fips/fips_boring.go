@@ -0,0 +1,15 @@
+//go:build boringcrypto
+// +build boringcrypto
+
+package fips
+
+import (
+	"crypto/boring"
+
+	// Restrict TLS to FIPS-approved settings.
+	_ "crypto/tls/fipsonly"
+)
+
+func enabled() bool {
+	return boring.Enabled()
+}
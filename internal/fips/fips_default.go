@@ -0,0 +1,8 @@
+//go:build !boringcrypto
+// +build !boringcrypto
+
+package fips
+
+func enabled() bool {
+	return false
+}
@@ -0,0 +1,16 @@
+//go:build !boringcrypto
+// +build !boringcrypto
+
+package fips
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	require.False(t, Enabled())
+	require.NoError(t, Verify(false))
+	require.ErrorContains(t, Verify(true), "FIPS mode is required")
+}
@@ -0,0 +1,54 @@
+package gateway
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// CookieAuthHandler returns a handler which authenticates the requests without an Authorization
+// header with the bearer token held by the cookie of the given name, so that browser apps can
+// keep the token in an HttpOnly cookie rather than in script-accessible storage.
+//
+// Since browsers attach cookies to the requests of any site, a request authenticated by its
+// cookie is rejected if it comes from another origin than the gateway, unless its origin is one
+// of the allowed origins. A `*` among the allowed origins does not allow all origins, since any
+// site could then make requests with the credentials of its visitors.
+func CookieAuthHandler(cookieName string, allowedOrigins []string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cookie, err := r.Cookie(cookieName)
+		if err != nil || cookie.Value == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !allowsCookieOrigin(r, allowedOrigins) {
+			http.Error(w, "credentials in cookies are not accepted from this origin", http.StatusForbidden)
+			return
+		}
+
+		r.Header.Set("Authorization", "Bearer "+cookie.Value)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// allowsCookieOrigin returns whether the request was made from the origin of the gateway, from
+// an allowed origin, or by a client which is not a browser and sent no origin.
+func allowsCookieOrigin(r *http.Request, allowedOrigins []string) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	if parsed, err := url.Parse(origin); err == nil && strings.EqualFold(parsed.Host, r.Host) {
+		return true
+	}
+	return slices.Contains(allowedOrigins, origin)
+}
@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCookieAuthHandler(t *testing.T) {
+	testCases := []struct {
+		name                  string
+		allowedOrigins        []string
+		headers               map[string]string
+		cookie                string
+		expectedStatus        int
+		expectedAuthorization string
+	}{
+		{"cookie without origin", nil, nil, "sometoken", http.StatusOK, "Bearer sometoken"},
+		{"cookie from the origin of the gateway", nil, map[string]string{"Origin": "https://spicedb.example.com"}, "sometoken", http.StatusOK, "Bearer sometoken"},
+		{"cookie from another origin", nil, map[string]string{"Origin": "https://evil.example.com"}, "sometoken", http.StatusForbidden, ""},
+		{"cookie from an allowed origin", []string{"https://admin.example.com"}, map[string]string{"Origin": "https://admin.example.com"}, "sometoken", http.StatusOK, "Bearer sometoken"},
+		{"cookie with a wildcard among the allowed origins", []string{"*"}, map[string]string{"Origin": "https://admin.example.com"}, "sometoken", http.StatusForbidden, ""},
+		{"authorization header takes precedence", nil, map[string]string{"Authorization": "Bearer othertoken", "Origin": "https://evil.example.com"}, "sometoken", http.StatusOK, "Bearer othertoken"},
+		{"no cookie", nil, map[string]string{"Origin": "https://evil.example.com"}, "", http.StatusOK, ""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var authorization string
+			handler := CookieAuthHandler("spicedb_token", tc.allowedOrigins, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "https://spicedb.example.com/v1/schema/read", nil)
+			for header, value := range tc.headers {
+				req.Header.Set(header, value)
+			}
+			if tc.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "spicedb_token", Value: tc.cookie})
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			require.Equal(t, tc.expectedStatus, recorder.Code)
+			require.Equal(t, tc.expectedAuthorization, authorization)
+		})
+	}
+}
@@ -7,7 +7,6 @@ import (
 	"io"
 	"net/http"
 
-	"github.com/authzed/authzed-go/proto"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/grpcutil"
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
@@ -22,6 +21,9 @@ import (
 	"google.golang.org/grpc/credentials/insecure"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/clientaddr"
+	"github.com/authzed/spicedb/internal/middleware/ratelimit"
 )
 
 var histogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
@@ -34,6 +36,11 @@ var histogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
 // NewHandler creates an REST gateway HTTP CloserHandler with the provided upstream
 // configuration.
 func NewHandler(ctx context.Context, upstreamAddr, upstreamTLSCertPath string) (*CloserHandler, error) {
+	schema, err := openAPISchema()
+	if err != nil {
+		return nil, err
+	}
+
 	opts := []grpc.DialOption{
 		grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
 		grpc.WithStreamInterceptor(otelgrpc.StreamClientInterceptor()),
@@ -49,7 +56,13 @@ func NewHandler(ctx context.Context, upstreamAddr, upstreamTLSCertPath string) (
 		return nil, err
 	}
 
-	gwMux := runtime.NewServeMux(runtime.WithMetadata(OtelAnnotator), runtime.WithHealthzEndpoint(healthpb.NewHealthClient(healthConn)))
+	muxOpts := []runtime.ServeMuxOption{
+		runtime.WithMetadata(OtelAnnotator),
+		runtime.WithMetadata(clientaddr.GatewayAnnotator),
+		runtime.WithHealthzEndpoint(healthpb.NewHealthClient(healthConn)),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeaderMatcher),
+	}
+	gwMux := runtime.NewServeMux(append(muxOpts, streamingMarshalers()...)...)
 	schemaConn, err := registerHandler(ctx, gwMux, upstreamAddr, opts, v1.RegisterSchemaServiceHandler)
 	if err != nil {
 		return nil, err
@@ -67,7 +80,8 @@ func NewHandler(ctx context.Context, upstreamAddr, upstreamTLSCertPath string) (
 
 	mux := http.NewServeMux()
 	mux.Handle("/openapi.json", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		_, _ = io.WriteString(w, proto.OpenAPISchema)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(schema)
 	}))
 	mux.Handle("/", gwMux)
 
@@ -144,3 +158,14 @@ func OtelAnnotator(ctx context.Context, r *http.Request) metadata.MD {
 	otelgrpc.Inject(ctx, &metadataCopy, defaultOtelOpts...)
 	return metadataCopy
 }
+
+// outgoingHeaderMatcher forwards the rate limit headers of the upstream responses as the standard
+// HTTP headers, and all other headers prefixed as by default.
+func outgoingHeaderMatcher(key string) (string, bool) {
+	switch key {
+	case ratelimit.LimitHeader, ratelimit.RemainingHeader, ratelimit.ResetHeader, ratelimit.RetryAfterHeader:
+		return key, true
+	default:
+		return runtime.MetadataHeaderPrefix + key, true
+	}
+}
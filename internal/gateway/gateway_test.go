@@ -2,15 +2,24 @@ package gateway
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
 	"testing"
 
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
 	"github.com/stretchr/testify/require"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/trace"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestOtelForwarding(t *testing.T) {
@@ -55,3 +64,95 @@ func TestCloseConnections(t *testing.T) {
 	// if connections are not closed, goleak would detect it
 	require.NoError(t, gatewayHandler.Close())
 }
+
+func TestOpenAPISchema(t *testing.T) {
+	require := require.New(t)
+
+	encoded, err := openAPISchema()
+	require.NoError(err)
+
+	var schema struct {
+		Paths map[string]map[string]struct {
+			OperationID string   `json:"operationId"`
+			Produces    []string `json:"produces"`
+		} `json:"paths"`
+	}
+	require.NoError(json.Unmarshal(encoded, &schema))
+
+	operations := map[string][]string{}
+	for path, item := range schema.Paths {
+		require.True(strings.HasPrefix(path, "/v1/"), "unexpected path %s", path)
+		for _, operation := range item {
+			operations[operation.OperationID] = operation.Produces
+		}
+	}
+
+	for _, service := range servedServices {
+		name := service.ServiceName[strings.LastIndex(service.ServiceName, ".")+1:]
+		for _, method := range service.Methods {
+			require.Contains(operations, name+"_"+method.MethodName)
+			require.Nil(operations[name+"_"+method.MethodName])
+		}
+		for _, stream := range service.Streams {
+			require.Contains(operations, name+"_"+stream.StreamName)
+			require.Equal([]string{"application/json", ndjsonContentType, eventStreamContentType}, operations[name+"_"+stream.StreamName])
+		}
+	}
+}
+
+func TestStreamingResponses(t *testing.T) {
+	testCases := []struct {
+		accept       string
+		expectedType string
+		expectedBody string
+	}{
+		{
+			"",
+			"application/json",
+			"{\"result\":\"first\"}\n{\"result\":\"second\"}\n{\"error\":{\"code\":5,\"message\":\"not found\",\"details\":[]}}\n",
+		},
+		{
+			ndjsonContentType,
+			ndjsonContentType,
+			"{\"result\":\"first\"}\n{\"result\":\"second\"}\n{\"error\":{\"code\":5,\"message\":\"not found\",\"details\":[]}}\n",
+		},
+		{
+			eventStreamContentType,
+			eventStreamContentType,
+			"data: {\"result\":\"first\"}\n\ndata: {\"result\":\"second\"}\n\nevent: error\ndata: {\"error\":{\"code\":5,\"message\":\"not found\",\"details\":[]}}\n\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.expectedType, func(t *testing.T) {
+			require := require.New(t)
+
+			mux := runtime.NewServeMux(streamingMarshalers()...)
+			req := httptest.NewRequest(http.MethodPost, "/v1/watch", nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			_, outbound := runtime.MarshalerForRequest(mux, req)
+
+			messages := []string{"first", "second"}
+			recv := func() (proto.Message, error) {
+				if len(messages) == 0 {
+					return nil, status.Error(codes.NotFound, "not found")
+				}
+				message := wrapperspb.String(messages[0])
+				messages = messages[1:]
+				return message, nil
+			}
+
+			recorder := httptest.NewRecorder()
+			ctx := runtime.NewServerMetadataContext(context.Background(), runtime.ServerMetadata{})
+			runtime.ForwardResponseStream(ctx, mux, outbound, recorder, req, recv)
+
+			require.Equal(tc.expectedType, recorder.Header().Get("Content-Type"))
+			// protojson randomly adds spaces between the members of objects.
+			body := regexp.MustCompile(`(":|,)\s+`).ReplaceAllString(recorder.Body.String(), "$1")
+			require.Equal(tc.expectedBody, body)
+		})
+	}
+}
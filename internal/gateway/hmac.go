@@ -0,0 +1,197 @@
+package gateway
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// The headers of a request signed with HMAC.
+const (
+	// SignatureKeyIDHeader is the ID of the key with which the request is signed.
+	SignatureKeyIDHeader = "X-SpiceDB-Signature-Key-Id"
+
+	// SignatureTimestampHeader is the time at which the request was signed, in seconds since the
+	// Unix epoch.
+	SignatureTimestampHeader = "X-SpiceDB-Signature-Timestamp"
+
+	// SignatureNonceHeader is a value unique to the request, so that it cannot be replayed.
+	SignatureNonceHeader = "X-SpiceDB-Signature-Nonce"
+
+	// SignatureHeader is the hex-encoded HMAC-SHA256 of the request, keyed with the preshared key
+	// of the key ID, as computed by SignRequest.
+	SignatureHeader = "X-SpiceDB-Signature"
+)
+
+const (
+	// DefaultSignatureMaxClockSkew is the default maximum difference between the time at which a
+	// request was signed and the time at which it is received.
+	DefaultSignatureMaxClockSkew = 5 * time.Minute
+
+	// maxSignatureNonces is the maximum number of nonces remembered to reject replayed requests,
+	// beyond which signed requests are rejected until the oldest nonces expire.
+	maxSignatureNonces = 1_000_000
+
+	// maxSignedBodySize is the maximum size of the body of a signed request.
+	maxSignedBodySize = 64 * 1024 * 1024
+)
+
+var signedRequestsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "rest_gateway",
+	Name:      "signed_requests_total",
+	Help:      "The requests to the REST gateway signed with HMAC, by whether their signature was accepted or the reason it was rejected.",
+}, []string{"result"})
+
+// SignatureVerifier verifies the requests to the REST gateway signed with HMAC instead of carrying
+// a bearer token, and forwards them upstream authenticated with the preshared key with which they
+// were signed. Requests are signed over their method, URI, body, timestamp and nonce: a request is
+// rejected if its timestamp is too far from the current time, or if its nonce was already used
+// with the key within that time, so that captured requests cannot be replayed.
+type SignatureVerifier struct {
+	presharedKeysByID map[string]string
+	maxClockSkew      time.Duration
+	now               func() time.Time
+
+	mu        sync.Mutex
+	nonces    map[string]time.Time
+	nextPrune time.Time
+}
+
+// NewSignatureVerifier creates a new verifier of the requests signed with the preshared keys, by
+// key ID, whose timestamps differ from the current time by at most maxClockSkew.
+func NewSignatureVerifier(presharedKeysByID map[string]string, maxClockSkew time.Duration) *SignatureVerifier {
+	if maxClockSkew <= 0 {
+		maxClockSkew = DefaultSignatureMaxClockSkew
+	}
+	return &SignatureVerifier{
+		presharedKeysByID: presharedKeysByID,
+		maxClockSkew:      maxClockSkew,
+		now:               time.Now,
+		nonces:            make(map[string]time.Time),
+	}
+}
+
+// SignRequest returns the signature of a request, as expected in the SignatureHeader.
+func SignRequest(presharedKey, method, requestURI, timestamp, nonce string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+	mac := hmac.New(sha256.New, []byte(presharedKey))
+	mac.Write([]byte(strings.Join([]string{method, requestURI, timestamp, nonce, hex.EncodeToString(bodyHash[:])}, "\n")))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Handler wraps the given handler, verifying the requests carrying a SignatureHeader and
+// authenticating them with the preshared key with which they were signed. Requests without a
+// signature are passed to the handler unchanged.
+func (v *SignatureVerifier) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(SignatureHeader) == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		presharedKey, status, err := v.verify(w, r)
+		if err != nil {
+			http.Error(w, "invalid request signature: "+err.Error(), status)
+			return
+		}
+
+		r.Header.Set("Authorization", "Bearer "+presharedKey)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// verify verifies the signature of the request, returning the preshared key with which it was
+// signed, or the HTTP status and error with which it is rejected.
+func (v *SignatureVerifier) verify(w http.ResponseWriter, r *http.Request) (string, int, error) {
+	if r.Header.Get("Authorization") != "" {
+		signedRequestsCounter.WithLabelValues("ambiguous").Inc()
+		return "", http.StatusBadRequest, fmt.Errorf("a signed request must not also carry an Authorization header")
+	}
+
+	keyID := r.Header.Get(SignatureKeyIDHeader)
+	timestamp := r.Header.Get(SignatureTimestampHeader)
+	nonce := r.Header.Get(SignatureNonceHeader)
+	if keyID == "" || timestamp == "" || nonce == "" {
+		signedRequestsCounter.WithLabelValues("incomplete").Inc()
+		return "", http.StatusUnauthorized, fmt.Errorf("the %s, %s and %s headers are required", SignatureKeyIDHeader, SignatureTimestampHeader, SignatureNonceHeader)
+	}
+
+	presharedKey, ok := v.presharedKeysByID[keyID]
+	if !ok {
+		signedRequestsCounter.WithLabelValues("unknown_key").Inc()
+		return "", http.StatusUnauthorized, fmt.Errorf("unknown key ID %q", keyID)
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		signedRequestsCounter.WithLabelValues("invalid_timestamp").Inc()
+		return "", http.StatusUnauthorized, fmt.Errorf("the %s header must be a number of seconds since the Unix epoch", SignatureTimestampHeader)
+	}
+	now := v.now()
+	signedAt := time.Unix(seconds, 0)
+	if signedAt.Before(now.Add(-v.maxClockSkew)) || signedAt.After(now.Add(v.maxClockSkew)) {
+		signedRequestsCounter.WithLabelValues("expired").Inc()
+		return "", http.StatusUnauthorized, fmt.Errorf("the request was signed more than %s from the current time", v.maxClockSkew)
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxSignedBodySize))
+	if err != nil {
+		signedRequestsCounter.WithLabelValues("invalid_body").Inc()
+		return "", http.StatusBadRequest, fmt.Errorf("unable to read the request body: %w", err)
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	expected := SignRequest(presharedKey, r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !hmac.Equal([]byte(expected), []byte(strings.ToLower(r.Header.Get(SignatureHeader)))) {
+		signedRequestsCounter.WithLabelValues("invalid_signature").Inc()
+		return "", http.StatusUnauthorized, fmt.Errorf("the signature does not match the request")
+	}
+
+	// The nonce is only remembered once the signature is verified, so that unsigned requests
+	// cannot fill the nonces.
+	if status, err := v.useNonce(keyID+"\x00"+nonce, signedAt.Add(v.maxClockSkew), now); err != nil {
+		return "", status, err
+	}
+
+	signedRequestsCounter.WithLabelValues("accepted").Inc()
+	return presharedKey, http.StatusOK, nil
+}
+
+// useNonce records the nonce as used until it expires, returning an error if it was already used.
+func (v *SignatureVerifier) useNonce(nonce string, expires, now time.Time) (int, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if _, ok := v.nonces[nonce]; ok {
+		signedRequestsCounter.WithLabelValues("replayed").Inc()
+		return http.StatusUnauthorized, fmt.Errorf("the request was already received")
+	}
+
+	if now.After(v.nextPrune) || len(v.nonces) >= maxSignatureNonces {
+		for used, usedExpires := range v.nonces {
+			if now.After(usedExpires) {
+				delete(v.nonces, used)
+			}
+		}
+		v.nextPrune = now.Add(v.maxClockSkew)
+	}
+	if len(v.nonces) >= maxSignatureNonces {
+		signedRequestsCounter.WithLabelValues("overloaded").Inc()
+		return http.StatusServiceUnavailable, fmt.Errorf("too many signed requests, retry later")
+	}
+
+	v.nonces[nonce] = expires
+	return http.StatusOK, nil
+}
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureVerifier(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	body := `{"consistency":{"fullyConsistent":true}}`
+
+	signed := func(key, nonce, timestamp, body string) map[string]string {
+		return map[string]string{
+			SignatureKeyIDHeader:     "webhooks",
+			SignatureTimestampHeader: timestamp,
+			SignatureNonceHeader:     nonce,
+			SignatureHeader:          SignRequest(key, http.MethodPost, "/v1/schema/read?x=1", timestamp, nonce, []byte(body)),
+		}
+	}
+
+	testCases := []struct {
+		name                  string
+		headers               map[string]string
+		expectedStatus        int
+		expectedAuthorization string
+	}{
+		{"unsigned request passes through", map[string]string{"Authorization": "Bearer other"}, http.StatusOK, "Bearer other"},
+		{"signed request", signed("somekey", "nonce1", timestamp, body), http.StatusOK, "Bearer somekey"},
+		{"replayed request", signed("somekey", "nonce1", timestamp, body), http.StatusUnauthorized, ""},
+		{"request signed with another key", signed("otherkey", "nonce2", timestamp, body), http.StatusUnauthorized, ""},
+		{"request with a modified body", signed("somekey", "nonce3", timestamp, `{}`), http.StatusUnauthorized, ""},
+		{"request signed too long ago", signed("somekey", "nonce4", strconv.FormatInt(now.Add(-10*time.Minute).Unix(), 10), body), http.StatusUnauthorized, ""},
+		{"request signed in the future", signed("somekey", "nonce5", strconv.FormatInt(now.Add(10*time.Minute).Unix(), 10), body), http.StatusUnauthorized, ""},
+		{"request signed within the clock skew", signed("somekey", "nonce6", strconv.FormatInt(now.Add(-time.Minute).Unix(), 10), body), http.StatusOK, "Bearer somekey"},
+		{"request without a nonce", signed("somekey", "", timestamp, body), http.StatusUnauthorized, ""},
+		{"request with an invalid timestamp", signed("somekey", "nonce7", "yesterday", body), http.StatusUnauthorized, ""},
+		{"request with an unknown key ID", map[string]string{
+			SignatureKeyIDHeader:     "unknown",
+			SignatureTimestampHeader: timestamp,
+			SignatureNonceHeader:     "nonce8",
+			SignatureHeader:          SignRequest("somekey", http.MethodPost, "/v1/schema/read?x=1", timestamp, "nonce8", []byte(body)),
+		}, http.StatusUnauthorized, ""},
+		{"signed request with a bearer token", func() map[string]string {
+			headers := signed("somekey", "nonce9", timestamp, body)
+			headers["Authorization"] = "Bearer somekey"
+			return headers
+		}(), http.StatusBadRequest, ""},
+	}
+
+	verifier := NewSignatureVerifier(map[string]string{"webhooks": "somekey"}, 5*time.Minute)
+	verifier.now = func() time.Time { return now }
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var authorization, receivedBody string
+			handler := verifier.Handler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				authorization = r.Header.Get("Authorization")
+				read, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				receivedBody = string(read)
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/schema/read?x=1", strings.NewReader(body))
+			for header, value := range tc.headers {
+				req.Header.Set(header, value)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			require.Equal(t, tc.expectedStatus, recorder.Code, recorder.Body.String())
+			if tc.expectedStatus == http.StatusOK {
+				require.Equal(t, tc.expectedAuthorization, authorization)
+				require.Equal(t, body, receivedBody)
+			}
+		})
+	}
+}
+
+func TestSignatureNoncesExpire(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	verifier := NewSignatureVerifier(map[string]string{"webhooks": "somekey"}, time.Minute)
+
+	_, err := verifier.useNonce("nonce", now.Add(time.Minute), now)
+	require.NoError(t, err)
+	_, err = verifier.useNonce("nonce", now.Add(time.Minute), now)
+	require.Error(t, err)
+
+	// Once a nonce expires, requests reusing it are rejected by their timestamp instead.
+	later := now.Add(3 * time.Minute)
+	_, err = verifier.useNonce("other", later.Add(time.Minute), later)
+	require.NoError(t, err)
+	require.Len(t, verifier.nonces, 1)
+}
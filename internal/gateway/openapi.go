@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/authzed/authzed-go/proto"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc"
+)
+
+// servedServices are the services whose methods are served by the gateway.
+var servedServices = []grpc.ServiceDesc{
+	v1.SchemaService_ServiceDesc,
+	v1.PermissionsService_ServiceDesc,
+	v1.WatchService_ServiceDesc,
+}
+
+// openAPISchema generates the OpenAPI document of the gateway from the document of the API,
+// keeping only the operations of the served services and declaring the media types in which
+// streaming operations can respond.
+func openAPISchema() ([]byte, error) {
+	var schema map[string]interface{}
+	if err := json.Unmarshal([]byte(proto.OpenAPISchema), &schema); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI schema of the API: %w", err)
+	}
+
+	served := map[string]bool{}
+	streaming := map[string]bool{}
+	for _, service := range servedServices {
+		name := service.ServiceName[strings.LastIndex(service.ServiceName, ".")+1:]
+		for _, method := range service.Methods {
+			served[name+"_"+method.MethodName] = true
+		}
+		for _, stream := range service.Streams {
+			served[name+"_"+stream.StreamName] = true
+			streaming[name+"_"+stream.StreamName] = stream.ServerStreams
+		}
+	}
+
+	paths, _ := schema["paths"].(map[string]interface{})
+	for path, item := range paths {
+		operations, _ := item.(map[string]interface{})
+		for method, value := range operations {
+			operation, _ := value.(map[string]interface{})
+			operationID, _ := operation["operationId"].(string)
+			if !served[operationID] {
+				delete(operations, method)
+				continue
+			}
+			if streaming[operationID] {
+				operation["produces"] = []string{"application/json", ndjsonContentType, eventStreamContentType}
+				description, _ := operation["description"].(string)
+				operation["description"] = strings.TrimSpace(fmt.Sprintf(
+					"%s\n\nResults are streamed as JSON objects, one per line for `%s`, or as server-sent events for `%s`, as chosen by the Accept header.",
+					description, ndjsonContentType, eventStreamContentType,
+				))
+			}
+		}
+		if len(operations) == 0 {
+			delete(paths, path)
+		}
+	}
+
+	return json.Marshal(schema)
+}
@@ -0,0 +1,98 @@
+package gateway
+
+import (
+	"bytes"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ndjsonContentType is the media type of streaming responses sent as newline-delimited JSON.
+	ndjsonContentType = "application/x-ndjson"
+
+	// eventStreamContentType is the media type of streaming responses sent as server-sent events.
+	eventStreamContentType = "text/event-stream"
+)
+
+// streamingMarshalers returns the options registering the marshalers of the streaming media types,
+// chosen by the Accept header of a request.
+func streamingMarshalers() []runtime.ServeMuxOption {
+	return []runtime.ServeMuxOption{
+		runtime.WithMarshalerOption(ndjsonContentType, &ndjsonMarshaler{newJSONMarshaler()}),
+		runtime.WithMarshalerOption(eventStreamContentType, &eventStreamMarshaler{newJSONMarshaler()}),
+	}
+}
+
+// newJSONMarshaler returns a marshaler matching the default marshaler of the gateway.
+func newJSONMarshaler() *runtime.JSONPb {
+	return &runtime.JSONPb{
+		MarshalOptions: protojson.MarshalOptions{
+			EmitUnpopulated: true,
+		},
+		UnmarshalOptions: protojson.UnmarshalOptions{
+			DiscardUnknown: true,
+		},
+	}
+}
+
+// ndjsonMarshaler writes every message of a streaming response as a line of JSON.
+type ndjsonMarshaler struct {
+	*runtime.JSONPb
+}
+
+func (m *ndjsonMarshaler) ContentType(_ interface{}) string {
+	return ndjsonContentType
+}
+
+func (m *ndjsonMarshaler) Delimiter() []byte {
+	return []byte("\n")
+}
+
+// eventStreamMarshaler writes every message of a streaming response as a server-sent event, whose
+// data is the message as JSON. Results are sent as `message` events, the default event type, and
+// errors as `error` events.
+type eventStreamMarshaler struct {
+	*runtime.JSONPb
+}
+
+func (m *eventStreamMarshaler) ContentType(_ interface{}) string {
+	return eventStreamContentType
+}
+
+// Marshal returns a whole event, so that responses of unary calls are complete events too.
+func (m *eventStreamMarshaler) Marshal(v interface{}) ([]byte, error) {
+	data, err := m.JSONPb.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var event bytes.Buffer
+	if isErrorChunk(v) {
+		event.WriteString("event: error\n")
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		event.WriteString("data: ")
+		event.Write(line)
+		event.WriteString("\n")
+	}
+	event.WriteString("\n")
+	return event.Bytes(), nil
+}
+
+// Delimiter is empty, as every event is already terminated by Marshal.
+func (m *eventStreamMarshaler) Delimiter() []byte {
+	return nil
+}
+
+// isErrorChunk returns whether the value is the chunk the gateway sends when a streaming call
+// fails.
+func isErrorChunk(v interface{}) bool {
+	chunk, ok := v.(map[string]proto.Message)
+	if !ok {
+		return false
+	}
+	_, ok = chunk["error"]
+	return ok
+}
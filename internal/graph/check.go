@@ -21,14 +21,22 @@ import (
 
 // NewConcurrentChecker creates an instance of ConcurrentChecker.
 func NewConcurrentChecker(d dispatch.Check, concurrencyLimit uint16) *ConcurrentChecker {
-	return &ConcurrentChecker{d, concurrencyLimit}
+	cc := &ConcurrentChecker{d: d}
+	cc.concurrencyLimit.set(concurrencyLimit)
+	return cc
 }
 
 // ConcurrentChecker exposes a method to perform Check requests, and delegates subproblems to the
 // provided dispatch.Check instance.
 type ConcurrentChecker struct {
 	d                dispatch.Check
-	concurrencyLimit uint16
+	concurrencyLimit concurrencyLimit
+}
+
+// SetConcurrencyLimit sets the maximum number of goroutines created by each check for its
+// subproblems, applying to the checks started afterwards.
+func (cc *ConcurrentChecker) SetConcurrencyLimit(limit uint16) {
+	cc.concurrencyLimit.set(limit)
 }
 
 // ValidatedCheckRequest represents a request after it has been validated and parsed for internal
@@ -242,7 +250,7 @@ func (cc *ConcurrentChecker) checkDirect(ctx context.Context, crc currentRequest
 		}
 
 		return mapFoundResources(childResult, dd.resourceType, relationshipsBySubjectONR)
-	}, cc.concurrencyLimit)
+	}, cc.concurrencyLimit.get())
 
 	return combineResultWithFoundResources(result, foundResources)
 }
@@ -273,11 +281,11 @@ func mapFoundResources(result CheckResult, resourceType *core.RelationReference,
 func (cc *ConcurrentChecker) checkUsersetRewrite(ctx context.Context, crc currentRequestContext, rewrite *core.UsersetRewrite) CheckResult {
 	switch rw := rewrite.RewriteOperation.(type) {
 	case *core.UsersetRewrite_Union:
-		return union(ctx, crc, rw.Union.Child, cc.runSetOperation, cc.concurrencyLimit)
+		return union(ctx, crc, rw.Union.Child, cc.runSetOperation, cc.concurrencyLimit.get())
 	case *core.UsersetRewrite_Intersection:
-		return all(ctx, crc, rw.Intersection.Child, cc.runSetOperation, cc.concurrencyLimit)
+		return all(ctx, crc, rw.Intersection.Child, cc.runSetOperation, cc.concurrencyLimit.get())
 	case *core.UsersetRewrite_Exclusion:
-		return difference(ctx, crc, rw.Exclusion.Child, cc.runSetOperation, cc.concurrencyLimit)
+		return difference(ctx, crc, rw.Exclusion.Child, cc.runSetOperation, cc.concurrencyLimit.get())
 	default:
 		return checkResultError(fmt.Errorf("unknown userset rewrite operator"), emptyMetadata)
 	}
@@ -434,7 +442,7 @@ func (cc *ConcurrentChecker) checkTupleToUserset(ctx context.Context, crc curren
 
 			return mapFoundResources(childResult, dd.resourceType, relationshipsBySubjectONR)
 		},
-		cc.concurrencyLimit,
+		cc.concurrencyLimit.get(),
 	)
 }
 
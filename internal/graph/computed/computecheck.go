@@ -19,6 +19,11 @@ type CheckParameters struct {
 	AtRevision         datastore.Revision
 	MaximumDepth       uint32
 	IsDebuggingEnabled bool
+
+	// ReturnAllMissingContext, if true, makes the MissingExprFields of a CAVEATED_MEMBER result
+	// hold the parameters missing from every caveat on which the result depends, rather than only
+	// from the first partially applied caveat.
+	ReturnAllMissingContext bool
 }
 
 // ComputeCheck computes a check result for the given resource and subject, computing any
@@ -103,7 +108,12 @@ func computeCaveatedCheckResult(ctx context.Context, params CheckParameters, res
 	ds := datastoremw.MustFromContext(ctx)
 	reader := ds.SnapshotReader(params.AtRevision)
 
-	caveatResult, err := cexpr.RunCaveatExpression(ctx, result.Expression, params.CaveatContext, reader, cexpr.RunCaveatExpressionNoDebugging)
+	runCaveatExpression := cexpr.RunCaveatExpression
+	if params.ReturnAllMissingContext {
+		runCaveatExpression = cexpr.RunCaveatExpressionForAllMissingVarNames
+	}
+
+	caveatResult, err := runCaveatExpression(ctx, result.Expression, params.CaveatContext, reader, cexpr.RunCaveatExpressionNoDebugging)
 	if err != nil {
 		return nil, err
 	}
@@ -67,12 +67,21 @@ func (ce *ConcurrentExpander) expandDirect(
 		defer it.Close()
 
 		var foundNonTerminalUsersets []*core.ObjectAndRelation
+		var foundNonTerminalCaveats []*core.ContextualizedCaveat
 		var foundTerminalUsersets []*core.ObjectAndRelation
+		var foundCaveatedSubjects []*core.DirectSubject
 		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
 			if tpl.Subject.Relation == Ellipsis {
 				foundTerminalUsersets = append(foundTerminalUsersets, tpl.Subject)
 			} else {
 				foundNonTerminalUsersets = append(foundNonTerminalUsersets, tpl.Subject)
+				foundNonTerminalCaveats = append(foundNonTerminalCaveats, tpl.Caveat)
+			}
+			if tpl.Caveat != nil {
+				foundCaveatedSubjects = append(foundCaveatedSubjects, &core.DirectSubject{
+					Subject: tpl.Subject,
+					Caveat:  tpl.Caveat,
+				})
 			}
 		}
 		if it.Err() != nil {
@@ -87,7 +96,8 @@ func (ce *ConcurrentExpander) expandDirect(
 				&core.RelationTupleTreeNode{
 					NodeType: &core.RelationTupleTreeNode_LeafNode{
 						LeafNode: &core.DirectSubjects{
-							Subjects: append(foundTerminalUsersets, foundNonTerminalUsersets...),
+							Subjects:         append(foundTerminalUsersets, foundNonTerminalUsersets...),
+							CaveatedSubjects: foundCaveatedSubjects,
 						},
 					},
 					Expanded: req.ResourceAndRelation,
@@ -100,15 +110,15 @@ func (ce *ConcurrentExpander) expandDirect(
 		// Otherwise, recursively issue expansion and collect the results from that, plus the
 		// found terminals together.
 		var requestsToDispatch []ReduceableExpandFunc
-		for _, nonTerminalUser := range foundNonTerminalUsersets {
-			requestsToDispatch = append(requestsToDispatch, ce.dispatch(ValidatedExpandRequest{
+		for index, nonTerminalUser := range foundNonTerminalUsersets {
+			requestsToDispatch = append(requestsToDispatch, expandWithCaveat(ce.dispatch(ValidatedExpandRequest{
 				&v1.DispatchExpandRequest{
 					ResourceAndRelation: nonTerminalUser,
 					Metadata:            decrementDepth(req.Metadata),
 					ExpansionMode:       req.ExpansionMode,
 				},
 				req.Revision,
-			}))
+			}), foundNonTerminalCaveats[index]))
 		}
 
 		result := expandAny(ctx, req.ResourceAndRelation, requestsToDispatch)
@@ -121,7 +131,8 @@ func (ce *ConcurrentExpander) expandDirect(
 		unionNode.ChildNodes = append(unionNode.ChildNodes, &core.RelationTupleTreeNode{
 			NodeType: &core.RelationTupleTreeNode_LeafNode{
 				LeafNode: &core.DirectSubjects{
-					Subjects: append(foundTerminalUsersets, foundNonTerminalUsersets...),
+					Subjects:         append(foundTerminalUsersets, foundNonTerminalUsersets...),
+					CaveatedSubjects: foundCaveatedSubjects,
 				},
 			},
 			Expanded: req.ResourceAndRelation,
@@ -236,7 +247,7 @@ func (ce *ConcurrentExpander) expandTupleToUserset(ctx context.Context, req Vali
 
 		var requestsToDispatch []ReduceableExpandFunc
 		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
-			requestsToDispatch = append(requestsToDispatch, ce.expandComputedUserset(ctx, req, ttu.ComputedUserset, tpl))
+			requestsToDispatch = append(requestsToDispatch, expandWithCaveat(ce.expandComputedUserset(ctx, req, ttu.ComputedUserset, tpl), tpl.Caveat))
 		}
 		if it.Err() != nil {
 			resultChan <- expandResultError(NewExpansionFailureErr(it.Err()), emptyMetadata)
@@ -318,6 +329,30 @@ func emptyExpansion(start *core.ObjectAndRelation) ReduceableExpandFunc {
 	}
 }
 
+// expandWithCaveat sets the caveat of the node expanded by the request to that of the relationship
+// through which the node was reached, if the relationship is caveated.
+func expandWithCaveat(request ReduceableExpandFunc, caveat *core.ContextualizedCaveat) ReduceableExpandFunc {
+	if caveat == nil {
+		return request
+	}
+
+	return func(ctx context.Context, resultChan chan<- ExpandResult) {
+		result := expandOne(ctx, request)
+		if result.Err != nil {
+			resultChan <- result
+			return
+		}
+
+		// The node is copied rather than modified, as dispatched responses may be shared.
+		node := result.Resp.TreeNode
+		resultChan <- expandResult(&core.RelationTupleTreeNode{
+			NodeType: node.NodeType,
+			Expanded: node.Expanded,
+			Caveat:   caveat,
+		}, result.Resp.Metadata)
+	}
+}
+
 // expandError returns the error.
 func expandError(err error) ReduceableExpandFunc {
 	return func(ctx context.Context, resultChan chan<- ExpandResult) {
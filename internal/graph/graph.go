@@ -2,6 +2,7 @@ package graph
 
 import (
 	"context"
+	"sync/atomic"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
@@ -20,6 +21,20 @@ const maxDispatchChunkSize = datastore.FilterMaximumIDCount
 // must be less than or equal to the maximum ID count for filters in the datastore.
 var progressiveDispatchChunkSizes = []int{5, 10, 25, 50, maxDispatchChunkSize}
 
+// concurrencyLimit is the maximum number of goroutines created by an operation for its
+// subproblems. It can be changed while operations run, and applies to those started afterwards.
+type concurrencyLimit struct {
+	limit atomic.Uint32
+}
+
+func (cl *concurrencyLimit) get() uint16 {
+	return uint16(cl.limit.Load())
+}
+
+func (cl *concurrencyLimit) set(limit uint16) {
+	cl.limit.Store(uint32(limit))
+}
+
 // CheckResult is the data that is returned by a single check or sub-check.
 type CheckResult struct {
 	Resp *v1.DispatchCheckResponse
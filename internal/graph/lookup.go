@@ -15,7 +15,9 @@ import (
 
 // NewConcurrentLookup creates and instance of ConcurrentLookup.
 func NewConcurrentLookup(c dispatch.Check, r dispatch.ReachableResources, concurrencyLimit uint16) *ConcurrentLookup {
-	return &ConcurrentLookup{c, r, concurrencyLimit}
+	cl := &ConcurrentLookup{c: c, r: r}
+	cl.concurrencyLimit.set(concurrencyLimit)
+	return cl
 }
 
 // ConcurrentLookup exposes a method to perform Lookup requests, and delegates subproblems to the
@@ -23,7 +25,13 @@ func NewConcurrentLookup(c dispatch.Check, r dispatch.ReachableResources, concur
 type ConcurrentLookup struct {
 	c                dispatch.Check
 	r                dispatch.ReachableResources
-	concurrencyLimit uint16
+	concurrencyLimit concurrencyLimit
+}
+
+// SetConcurrencyLimit sets the maximum number of goroutines created by each lookup for its
+// subproblems, applying to the lookups started afterwards.
+func (cl *ConcurrentLookup) SetConcurrencyLimit(limit uint16) {
+	cl.concurrencyLimit.set(limit)
 }
 
 // ValidatedLookupRequest represents a request after it has been validated and parsed for internal
@@ -86,7 +94,7 @@ func (cl *ConcurrentLookup) LookupViaReachability(ctx context.Context, req Valid
 	cancelCtx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
-	checker := newParallelChecker(cancelCtx, cancel, cl.c, req, cl.concurrencyLimit)
+	checker := newParallelChecker(cancelCtx, cancel, cl.c, req, cl.concurrencyLimit.get())
 	stream := &collectingStream{checker, req, cancelCtx, 0, 0, 0, sync.Mutex{}}
 
 	// Start the checker.
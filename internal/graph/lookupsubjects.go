@@ -28,12 +28,20 @@ type ValidatedLookupSubjectsRequest struct {
 
 // NewConcurrentLookupSubjects creates an instance of ConcurrentLookupSubjects.
 func NewConcurrentLookupSubjects(d dispatch.LookupSubjects, concurrencyLimit uint16) *ConcurrentLookupSubjects {
-	return &ConcurrentLookupSubjects{d, concurrencyLimit}
+	cl := &ConcurrentLookupSubjects{d: d}
+	cl.concurrencyLimit.set(concurrencyLimit)
+	return cl
 }
 
 type ConcurrentLookupSubjects struct {
 	d                dispatch.LookupSubjects
-	concurrencyLimit uint16
+	concurrencyLimit concurrencyLimit
+}
+
+// SetConcurrencyLimit sets the maximum number of goroutines created by each lookup of subjects
+// for its subproblems, applying to the lookups started afterwards.
+func (cl *ConcurrentLookupSubjects) SetConcurrencyLimit(limit uint16) {
+	cl.concurrencyLimit.set(limit)
 }
 
 func (cl *ConcurrentLookupSubjects) LookupSubjects(
@@ -271,7 +279,7 @@ func (cl *ConcurrentLookupSubjects) lookupSetOperation(
 	defer checkCancel()
 
 	g, subCtx := errgroup.WithContext(cancelCtx)
-	g.SetLimit(int(cl.concurrencyLimit))
+	g.SetLimit(int(cl.concurrencyLimit.get()))
 
 	for index, childOneof := range so.Child {
 		stream := reducer.ForIndex(subCtx, index)
@@ -327,7 +335,7 @@ func (cl *ConcurrentLookupSubjects) dispatchTo(
 	defer checkCancel()
 
 	g, subCtx := errgroup.WithContext(cancelCtx)
-	g.SetLimit(int(cl.concurrencyLimit))
+	g.SetLimit(int(cl.concurrencyLimit.get()))
 
 	toDispatchByType.ForEachType(func(resourceType *core.RelationReference, foundSubjects datasets.SubjectSet) {
 		slice := foundSubjects.AsSlice()
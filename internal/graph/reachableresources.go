@@ -20,14 +20,22 @@ import (
 
 // NewConcurrentReachableResources creates an instance of ConcurrentReachableResources.
 func NewConcurrentReachableResources(d dispatch.ReachableResources, concurrencyLimit uint16) *ConcurrentReachableResources {
-	return &ConcurrentReachableResources{d, concurrencyLimit}
+	crr := &ConcurrentReachableResources{d: d}
+	crr.concurrencyLimit.set(concurrencyLimit)
+	return crr
 }
 
 // ConcurrentReachableResources exposes a method to perform ReachableResources requests, and
 // delegates subproblems to the provided dispatch.ReachableResources instance.
 type ConcurrentReachableResources struct {
 	d                dispatch.ReachableResources
-	concurrencyLimit uint16
+	concurrencyLimit concurrencyLimit
+}
+
+// SetConcurrencyLimit sets the maximum number of goroutines created by each lookup of reachable
+// resources for its subproblems, applying to the lookups started afterwards.
+func (crr *ConcurrentReachableResources) SetConcurrencyLimit(limit uint16) {
+	crr.concurrencyLimit.set(limit)
 }
 
 // ValidatedReachableResourcesRequest represents a request after it has been validated and parsed for internal
@@ -101,7 +109,7 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 	defer checkCancel()
 
 	g, subCtx := errgroup.WithContext(cancelCtx)
-	g.SetLimit(int(crr.concurrencyLimit))
+	g.SetLimit(int(crr.concurrencyLimit.get()))
 
 	// For each entrypoint, load the necessary data and re-dispatch if a subproblem was found.
 	for _, entrypoint := range entrypoints {
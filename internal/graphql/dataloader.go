@@ -0,0 +1,75 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+)
+
+// BatchFunc loads the values of the given keys at once, returning them by key.
+type BatchFunc[K comparable, V any] func(ctx context.Context, keys []K) (map[K]V, error)
+
+// Loader batches the loads of values made while executing a request: Load returns a Thunk, and
+// the first thunk called loads the keys of all the loads made so far with a single call to the
+// batch function. As graphql-go resolves all the fields of a level before calling their thunks,
+// the loads of sibling fields, and of the same field across the items of a list, are batched.
+// Loaded values are cached for the lifetime of the loader, which should therefore be created for
+// each request. A Loader is not safe for concurrent use, as the fields of a request are resolved
+// one at a time.
+type Loader[K comparable, V any] struct {
+	batch   BatchFunc[K, V]
+	pending []K
+	queued  map[K]struct{}
+	loaded  map[K]loadResult[V]
+}
+
+type loadResult[V any] struct {
+	value V
+	err   error
+}
+
+// NewLoader returns a new loader of the values returned by the batch function.
+func NewLoader[K comparable, V any](batch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		batch:  batch,
+		queued: map[K]struct{}{},
+		loaded: map[K]loadResult[V]{},
+	}
+}
+
+// Load returns a thunk returning the value of the key.
+func (l *Loader[K, V]) Load(ctx context.Context, key K) Thunk {
+	_, loaded := l.loaded[key]
+	if _, queued := l.queued[key]; !loaded && !queued {
+		l.pending = append(l.pending, key)
+		l.queued[key] = struct{}{}
+	}
+
+	return func() (any, error) {
+		if _, ok := l.loaded[key]; !ok {
+			l.dispatch(ctx)
+		}
+
+		result := l.loaded[key]
+		return result.value, result.err
+	}
+}
+
+// dispatch loads the pending keys.
+func (l *Loader[K, V]) dispatch(ctx context.Context) {
+	keys := l.pending
+	l.pending = nil
+	l.queued = map[K]struct{}{}
+
+	values, err := l.batch(ctx, keys)
+	for _, key := range keys {
+		value, ok := values[key]
+		switch {
+		case err != nil:
+			l.loaded[key] = loadResult[V]{err: err}
+		case !ok:
+			l.loaded[key] = loadResult[V]{err: fmt.Errorf("no value was loaded for %v", key)}
+		default:
+			l.loaded[key] = loadResult[V]{value: value}
+		}
+	}
+}
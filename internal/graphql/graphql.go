@@ -0,0 +1,62 @@
+// Package graphql serves read-only GraphQL queries over HTTP, executed with graphql-go against
+// the schemas defined in Go.
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+)
+
+// rootValueKey is the key of the root value of a request in the root object given to graphql-go.
+const rootValueKey = "root"
+
+// Thunk resolves the value of a field later, once the fields resolved before it have been
+// resolved in turn. Resolvers return thunks to batch the loads of sibling fields, and of the same
+// field across the items of a list, with a Loader. It is the signature of the deferred
+// resolutions of graphql-go, and must therefore not be a named type.
+type Thunk = func() (any, error)
+
+// Request is a GraphQL request, in its standard JSON encoding.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// execute executes the request against the schema, resolving the root query fields from the
+// given root value.
+func execute(ctx context.Context, schema *graphql.Schema, req Request, root any) *graphql.Result {
+	return graphql.Do(graphql.Params{
+		Schema:         *schema,
+		RequestString:  req.Query,
+		RootObject:     map[string]any{rootValueKey: root},
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        ctx,
+	})
+}
+
+// rootValue returns the root value of the request of a root query field.
+func rootValue[T any](p graphql.ResolveParams) T {
+	return p.Source.(map[string]any)[rootValueKey].(T)
+}
+
+// mustNewSchema returns the schema of query operations with the given root type, panicking if it
+// is invalid.
+func mustNewSchema(query *graphql.Object) *graphql.Schema {
+	schema, err := graphql.NewSchema(graphql.SchemaConfig{Query: query})
+	if err != nil {
+		panic(fmt.Sprintf("invalid GraphQL schema: %s", err))
+	}
+	return &schema
+}
+
+// nameArgument is the argument of the fields looking up a single item by name.
+var nameArgument = graphql.FieldConfigArgument{
+	"name": {Type: graphql.NewNonNull(graphql.String)},
+}
+
+// stringList is the type of the fields of a list of strings.
+var stringList = graphql.NewList(graphql.NewNonNull(graphql.String))
@@ -0,0 +1,74 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+// maxRequestBodySize is the maximum size of the body of a GraphQL request.
+const maxRequestBodySize = 1 << 20
+
+// NewHandler returns an http.Handler which executes GraphQL requests against the schema, as per
+// the GraphQL over HTTP conventions: requests are read from the query parameters of GET requests
+// and from the JSON body of POST requests. The root value of each request is returned by rootFunc.
+func NewHandler(schema *graphql.Schema, rootFunc func(ctx context.Context) (any, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req, err := readRequest(r)
+		if err != nil {
+			writeResponse(w, r, http.StatusBadRequest, &graphql.Result{Errors: gqlerrors.FormatErrors(err)})
+			return
+		}
+
+		root, err := rootFunc(r.Context())
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Msg("failed to load GraphQL root value")
+			writeResponse(w, r, http.StatusInternalServerError, &graphql.Result{Errors: []gqlerrors.FormattedError{gqlerrors.NewFormattedError("internal error")}})
+			return
+		}
+
+		writeResponse(w, r, http.StatusOK, execute(r.Context(), schema, req, root))
+	})
+}
+
+func readRequest(r *http.Request) (Request, error) {
+	var req Request
+	switch r.Method {
+	case http.MethodGet:
+		query := r.URL.Query()
+		req.Query = query.Get("query")
+		req.OperationName = query.Get("operationName")
+		if variables := query.Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				return req, fmt.Errorf("invalid variables: %w", err)
+			}
+		}
+
+	case http.MethodPost:
+		if err := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxRequestBodySize)).Decode(&req); err != nil {
+			return req, fmt.Errorf("invalid request body: %w", err)
+		}
+
+	default:
+		return req, fmt.Errorf("unsupported method %s", r.Method)
+	}
+
+	if req.Query == "" {
+		return req, fmt.Errorf("missing query")
+	}
+	return req, nil
+}
+
+func writeResponse(w http.ResponseWriter, r *http.Request, status int, resp *graphql.Result) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write GraphQL response")
+	}
+}
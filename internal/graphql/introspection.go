@@ -0,0 +1,530 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/graphql-go/graphql"
+	"golang.org/x/exp/maps"
+
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	tenantmw "github.com/authzed/spicedb/internal/middleware/tenant"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/caveats"
+	caveattypes "github.com/authzed/spicedb/pkg/caveats/types"
+	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	iv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// NewSchemaHandler returns an http.Handler serving read-only GraphQL queries over the schema
+// found in the datastore at its head revision, authenticated with one of the preshared keys within
+// its validity window, if any, or with the key of a created tenant. The requests made with the key
+// of a tenant only see the schema of the tenant.
+//
+// For example:
+//
+//	{
+//	  definition(name: "document") {
+//	    relations { name allowedTypes { definition relation wildcard caveat } }
+//	    permissions { name expression }
+//	  }
+//	}
+func NewSchemaHandler(ds datastore.Datastore, presharedKeys []string, validity map[string]auth.PresharedKeyValidity, tenants *tenantmw.Registry) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestDS := ds
+		if tenant, ok := tenantmw.FromHTTPRequest(r, tenants); ok {
+			requestDS = proxy.NewTenantDatastore(ds, tenant)
+		}
+
+		NewHandler(IntrospectionSchema, func(ctx context.Context) (any, error) {
+			return loadSchemaSnapshot(ctx, requestDS)
+		}).ServeHTTP(w, r)
+	})
+	return tenantmw.HTTPAuthHandler(tenants, handler, auth.RequireHTTPPresharedKey(presharedKeys, validity, handler))
+}
+
+// schemaSnapshot is the schema found in the datastore at a revision.
+type schemaSnapshot struct {
+	revision     datastore.Revision
+	definitions  []definition
+	caveatDefs   []*core.CaveatDefinition
+	dependencies *namespace.DependencyReport
+}
+
+// definition is an object definition, along with its dependencies.
+type definition struct {
+	nsDef        *core.NamespaceDefinition
+	dependencies *namespace.DefinitionDependencies
+}
+
+func loadSchemaSnapshot(ctx context.Context, ds datastore.Datastore) (*schemaSnapshot, error) {
+	revision, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := ds.SnapshotReader(revision)
+	objectDefs, err := namespace.ListLiveNamespaces(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	caveatDefs, err := reader.ListCaveats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if caveatDefs == nil {
+		caveatDefs = []*core.CaveatDefinition{}
+	}
+
+	sort.Slice(objectDefs, func(i, j int) bool { return objectDefs[i].Name < objectDefs[j].Name })
+	sort.Slice(caveatDefs, func(i, j int) bool { return caveatDefs[i].Name < caveatDefs[j].Name })
+
+	dependencies := namespace.ComputeDependencyReport(objectDefs)
+	definitions := make([]definition, 0, len(objectDefs))
+	for _, nsDef := range objectDefs {
+		definitionDependencies, _ := dependencies.ForDefinition(nsDef.Name)
+		definitions = append(definitions, definition{nsDef: nsDef, dependencies: definitionDependencies})
+	}
+
+	return &schemaSnapshot{
+		revision:     revision,
+		definitions:  definitions,
+		caveatDefs:   caveatDefs,
+		dependencies: dependencies,
+	}, nil
+}
+
+// relation is a relation or permission under an object definition.
+type relation struct {
+	definitionName string
+	relation       *core.Relation
+}
+
+// caveatParameter is a parameter of a caveat definition.
+type caveatParameter struct {
+	name          string
+	parameterType string
+}
+
+var allowedTypeType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "AllowedType",
+	Fields: graphql.Fields{
+		"definition": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*core.AllowedRelation).Namespace, nil
+			},
+		},
+		"relation": {
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				relationName := p.Source.(*core.AllowedRelation).GetRelation()
+				if relationName == "" || relationName == generator.Ellipsis {
+					return nil, nil
+				}
+				return relationName, nil
+			},
+		},
+		"wildcard": {
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*core.AllowedRelation).GetPublicWildcard() != nil, nil
+			},
+		},
+		"caveat": {
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if caveat := p.Source.(*core.AllowedRelation).RequiredCaveat; caveat != nil {
+					return caveat.CaveatName, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var relationType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Relation",
+	Fields: graphql.Fields{
+		"name":              relationNameField,
+		"comments":          relationCommentsField,
+		"deprecated":        relationDeprecatedField,
+		"deprecationReason": relationDeprecationReasonField,
+		"allowedTypes": {
+			Type: graphql.NewList(graphql.NewNonNull(allowedTypeType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if allowed := p.Source.(relation).relation.GetTypeInformation().GetAllowedDirectRelations(); allowed != nil {
+					return allowed, nil
+				}
+				return []*core.AllowedRelation{}, nil
+			},
+		},
+		"syntheticSources": {
+			Type: stringList,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if sources, ok := nspkg.GetSyntheticSources(p.Source.(relation).relation); ok {
+					return sources, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var permissionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Permission",
+	Fields: graphql.Fields{
+		"name":              relationNameField,
+		"comments":          relationCommentsField,
+		"deprecated":        relationDeprecatedField,
+		"deprecationReason": relationDeprecationReasonField,
+		"expression": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				rel := p.Source.(relation)
+				expression, ok := generator.GenerateRewriteSource(rel.relation.UsersetRewrite)
+				if !ok {
+					return nil, fmt.Errorf("failed to generate the expression of permission `%s` under definition `%s`", rel.relation.Name, rel.definitionName)
+				}
+				return expression, nil
+			},
+		},
+	},
+})
+
+var (
+	relationNameField = &graphql.Field{
+		Type: graphql.NewNonNull(graphql.String),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return p.Source.(relation).relation.Name, nil
+		},
+	}
+
+	relationCommentsField = &graphql.Field{
+		Type: stringList,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			return nspkg.GetUserComments(p.Source.(relation).relation.Metadata), nil
+		},
+	}
+
+	relationDeprecatedField = &graphql.Field{
+		Type: graphql.NewNonNull(graphql.Boolean),
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			_, ok := nspkg.GetDeprecation(p.Source.(relation).relation.Metadata)
+			return ok, nil
+		},
+	}
+
+	relationDeprecationReasonField = &graphql.Field{
+		Type: graphql.String,
+		Resolve: func(p graphql.ResolveParams) (any, error) {
+			if reason, ok := nspkg.GetDeprecation(p.Source.(relation).relation.Metadata); ok {
+				return reason, nil
+			}
+			return nil, nil
+		},
+	}
+)
+
+var dependencyType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Dependency",
+	Fields: graphql.Fields{
+		"kind": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(namespace.Dependency).Kind.String(), nil
+			},
+		},
+		"fromDefinition": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(namespace.Dependency).From.Namespace, nil
+			},
+		},
+		"fromRelation": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(namespace.Dependency).From.Relation, nil
+			},
+		},
+		"toDefinition": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(namespace.Dependency).To.Namespace, nil
+			},
+		},
+		"toRelation": {
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if relationName := p.Source.(namespace.Dependency).To.Relation; relationName != generator.Ellipsis {
+					return relationName, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var definitionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Definition",
+	Fields: graphql.Fields{
+		"name": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(definition).nsDef.Name, nil
+			},
+		},
+		"comments": {
+			Type: stringList,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return nspkg.GetUserComments(p.Source.(definition).nsDef.Metadata), nil
+			},
+		},
+		"deprecated": {
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				_, ok := nspkg.GetDeprecation(p.Source.(definition).nsDef.Metadata)
+				return ok, nil
+			},
+		},
+		"deprecationReason": {
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if reason, ok := nspkg.GetDeprecation(p.Source.(definition).nsDef.Metadata); ok {
+					return reason, nil
+				}
+				return nil, nil
+			},
+		},
+		"source": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				nsDef := p.Source.(definition).nsDef
+				generated, ok := generator.GenerateSource(nsDef)
+				if !ok {
+					return nil, fmt.Errorf("failed to generate the source of definition `%s`", nsDef.Name)
+				}
+				return generated, nil
+			},
+		},
+		"references": {
+			Type: stringList,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(definition).dependencies.References, nil
+			},
+		},
+		"referencedBy": {
+			Type: stringList,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(definition).dependencies.ReferencedBy, nil
+			},
+		},
+		"outgoingDependencies": {
+			Type: graphql.NewList(graphql.NewNonNull(dependencyType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return nonNilDependencies(p.Source.(definition).dependencies.Outgoing), nil
+			},
+		},
+		"incomingDependencies": {
+			Type: graphql.NewList(graphql.NewNonNull(dependencyType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return nonNilDependencies(p.Source.(definition).dependencies.Incoming), nil
+			},
+		},
+		"relations": {
+			Type: graphql.NewList(graphql.NewNonNull(relationType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return relationsOfKind(p.Source.(definition).nsDef, false), nil
+			},
+		},
+		"relation": {
+			Type: relationType,
+			Args: nameArgument,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return findRelation(relationsOfKind(p.Source.(definition).nsDef, false), p.Args["name"].(string)), nil
+			},
+		},
+		"permissions": {
+			Type: graphql.NewList(graphql.NewNonNull(permissionType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return relationsOfKind(p.Source.(definition).nsDef, true), nil
+			},
+		},
+		"permission": {
+			Type: permissionType,
+			Args: nameArgument,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return findRelation(relationsOfKind(p.Source.(definition).nsDef, true), p.Args["name"].(string)), nil
+			},
+		},
+	},
+})
+
+var caveatParameterType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CaveatParameter",
+	Fields: graphql.Fields{
+		"name": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(caveatParameter).name, nil
+			},
+		},
+		"type": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(caveatParameter).parameterType, nil
+			},
+		},
+	},
+})
+
+var caveatType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Caveat",
+	Fields: graphql.Fields{
+		"name": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*core.CaveatDefinition).Name, nil
+			},
+		},
+		"comments": {
+			Type: stringList,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return nspkg.GetUserComments(p.Source.(*core.CaveatDefinition).Metadata), nil
+			},
+		},
+		"parameters": {
+			Type: graphql.NewList(graphql.NewNonNull(caveatParameterType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				caveatDef := p.Source.(*core.CaveatDefinition)
+				names := maps.Keys(caveatDef.ParameterTypes)
+				sort.Strings(names)
+
+				parameters := make([]caveatParameter, 0, len(names))
+				for _, name := range names {
+					decoded, err := caveattypes.DecodeParameterType(caveatDef.ParameterTypes[name])
+					if err != nil {
+						return nil, fmt.Errorf("invalid type for parameter `%s` of caveat `%s`: %w", name, caveatDef.Name, err)
+					}
+					parameters = append(parameters, caveatParameter{name: name, parameterType: decoded.String()})
+				}
+				return parameters, nil
+			},
+		},
+		"expression": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				caveatDef := p.Source.(*core.CaveatDefinition)
+				deserialized, err := caveats.DeserializeCaveat(caveatDef.SerializedExpression)
+				if err != nil {
+					return nil, fmt.Errorf("invalid expression for caveat `%s`: %w", caveatDef.Name, err)
+				}
+				return deserialized.ExprString()
+			},
+		},
+		"source": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				caveatDef := p.Source.(*core.CaveatDefinition)
+				generated, ok := generator.GenerateCaveatSource(caveatDef)
+				if !ok {
+					return nil, fmt.Errorf("failed to generate the source of caveat `%s`", caveatDef.Name)
+				}
+				return generated, nil
+			},
+		},
+	},
+})
+
+// IntrospectionSchema is the GraphQL schema over which the object and caveat definitions of a
+// schema, and the dependencies between the object definitions, can be queried.
+var IntrospectionSchema = mustNewSchema(graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"zedToken": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return zedtoken.NewFromRevision(rootValue[*schemaSnapshot](p).revision).Token, nil
+			},
+		},
+		"definitions": {
+			Type: graphql.NewList(graphql.NewNonNull(definitionType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return rootValue[*schemaSnapshot](p).definitions, nil
+			},
+		},
+		"definition": {
+			Type: definitionType,
+			Args: nameArgument,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				for _, objectDef := range rootValue[*schemaSnapshot](p).definitions {
+					if objectDef.nsDef.Name == p.Args["name"] {
+						return objectDef, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+		"dependencyCycles": {
+			Type: graphql.NewList(graphql.NewNonNull(stringList)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return rootValue[*schemaSnapshot](p).dependencies.Cycles, nil
+			},
+		},
+		"caveats": {
+			Type: graphql.NewList(graphql.NewNonNull(caveatType)),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return rootValue[*schemaSnapshot](p).caveatDefs, nil
+			},
+		},
+		"caveat": {
+			Type: caveatType,
+			Args: nameArgument,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				for _, caveatDef := range rootValue[*schemaSnapshot](p).caveatDefs {
+					if caveatDef.Name == p.Args["name"] {
+						return caveatDef, nil
+					}
+				}
+				return nil, nil
+			},
+		},
+	},
+}))
+
+// relationsOfKind returns either the relations or the permissions of the object definition, in
+// the order defined.
+func relationsOfKind(nsDef *core.NamespaceDefinition, permissions bool) []relation {
+	found := []relation{}
+	for _, rel := range nsDef.Relation {
+		if (nspkg.GetRelationKind(rel) == iv1.RelationMetadata_PERMISSION) == permissions {
+			found = append(found, relation{definitionName: nsDef.Name, relation: rel})
+		}
+	}
+	return found
+}
+
+func nonNilDependencies(dependencies []namespace.Dependency) []namespace.Dependency {
+	if dependencies == nil {
+		return []namespace.Dependency{}
+	}
+	return dependencies
+}
+
+func findRelation(relations []relation, name string) any {
+	for _, rel := range relations {
+		if rel.relation.Name == name {
+			return rel
+		}
+	}
+	return nil
+}
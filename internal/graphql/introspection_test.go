@@ -0,0 +1,199 @@
+package graphql
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tenantmw "github.com/authzed/spicedb/internal/middleware/tenant"
+	"github.com/authzed/spicedb/internal/testfixtures"
+)
+
+const introspectionTestSchema = `
+	caveat only_on_tuesday(day_of_week string) {
+		day_of_week == 'tuesday'
+	}
+
+	definition user {}
+
+	// A document.
+	definition document {
+		relation owner: user
+		relation viewer: user | user:* | user with only_on_tuesday
+
+		/** @deprecated use owner */
+		relation editor: user
+
+		relation any_member: user = owner + editor
+
+		permission view = viewer + owner - nil
+	}
+`
+
+func TestSchemaHandler(t *testing.T) {
+	testCases := []struct {
+		name             string
+		query            string
+		expectedResponse string
+	}{
+		{
+			"definitions",
+			`{ definitions { name comments } }`,
+			`{"data":{"definitions":[
+				{"name":"document","comments":["// A document."]},
+				{"name":"user","comments":[]}
+			]}}`,
+		},
+		{
+			"relations and permissions",
+			`{
+				definition(name: "document") {
+					relations { name deprecated deprecationReason syntheticSources allowedTypes { definition relation wildcard caveat } }
+					permissions { name expression }
+				}
+			}`,
+			`{"data":{"definition":{
+				"relations":[
+					{"name":"owner","deprecated":false,"deprecationReason":null,"syntheticSources":null,"allowedTypes":[
+						{"definition":"user","relation":null,"wildcard":false,"caveat":null}
+					]},
+					{"name":"viewer","deprecated":false,"deprecationReason":null,"syntheticSources":null,"allowedTypes":[
+						{"definition":"user","relation":null,"wildcard":false,"caveat":null},
+						{"definition":"user","relation":null,"wildcard":true,"caveat":null},
+						{"definition":"user","relation":null,"wildcard":false,"caveat":"only_on_tuesday"}
+					]},
+					{"name":"editor","deprecated":true,"deprecationReason":"use owner","syntheticSources":null,"allowedTypes":[
+						{"definition":"user","relation":null,"wildcard":false,"caveat":null}
+					]},
+					{"name":"any_member","deprecated":false,"deprecationReason":null,"syntheticSources":["owner","editor"],"allowedTypes":[
+						{"definition":"user","relation":null,"wildcard":false,"caveat":null}
+					]}
+				],
+				"permissions":[{"name":"view","expression":"viewer + owner - nil"}]
+			}}}`,
+		},
+		{
+			"single relation and permission",
+			`{ definition(name: "document") { relation(name: "owner") { name } permission(name: "owner") { name } } }`,
+			`{"data":{"definition":{"relation":{"name":"owner"},"permission":null}}}`,
+		},
+		{
+			"unknown definition",
+			`{ definition(name: "unknown") { name } }`,
+			`{"data":{"definition":null}}`,
+		},
+		{
+			"dependencies",
+			`{
+				definition(name: "user") {
+					referencedBy
+					incomingDependencies { kind fromDefinition fromRelation toRelation }
+				}
+				dependencyCycles
+			}`,
+			`{"data":{"definition":{
+				"referencedBy":["document"],
+				"incomingDependencies":[
+					{"kind":"subject_type","fromDefinition":"document","fromRelation":"owner","toRelation":null},
+					{"kind":"subject_type","fromDefinition":"document","fromRelation":"viewer","toRelation":null},
+					{"kind":"subject_type","fromDefinition":"document","fromRelation":"viewer","toRelation":"*"},
+					{"kind":"subject_type","fromDefinition":"document","fromRelation":"viewer","toRelation":null},
+					{"kind":"subject_type","fromDefinition":"document","fromRelation":"editor","toRelation":null},
+					{"kind":"subject_type","fromDefinition":"document","fromRelation":"any_member","toRelation":null}
+				]
+			},
+			"dependencyCycles":[]}}`,
+		},
+		{
+			"unknown field",
+			`{ definitions { name owner } }`,
+			`{"data":null,"errors":[{"message":"Cannot query field \"owner\" on type \"Definition\".","locations":[{"line":1,"column":22}]}]}`,
+		},
+		{
+			"mutation",
+			`mutation { definitions { name } }`,
+			`{"data":null,"errors":[{"message":"Schema is not configured for mutations","locations":[{"line":1,"column":1}]}]}`,
+		},
+		{
+			"caveats",
+			`{ caveats { name parameters { name type } expression } }`,
+			`{"data":{"caveats":[
+				{"name":"only_on_tuesday","parameters":[{"name":"day_of_week","type":"string"}],"expression":"day_of_week == \"tuesday\""}
+			]}}`,
+		},
+	}
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, introspectionTestSchema, nil, require.New(t))
+	tenants, err := tenantmw.NewRegistry(map[string]string{"acmekey": "acme"}, "")
+	require.NoError(t, err)
+	handler := NewSchemaHandler(ds, []string{"somekey", "acmekey"}, nil, tenants)
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			body := `{"query": ` + jsonString(t, tc.query) + `}`
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			req.Header.Set("Authorization", "Bearer somekey")
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			require.Equal(t, http.StatusOK, recorder.Code)
+			require.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+			require.JSONEq(t, tc.expectedResponse, recorder.Body.String())
+		})
+	}
+
+	t.Run("get", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/?query="+url.QueryEscape(`query ($name: String!) { definition(name: $name) { name } }`)+
+			"&variables="+url.QueryEscape(`{"name": "user"}`), nil)
+		req.Header.Set("Authorization", "Bearer somekey")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.JSONEq(t, `{"data":{"definition":{"name":"user"}}}`, recorder.Body.String())
+	})
+
+	t.Run("missing query", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{}`))
+		req.Header.Set("Authorization", "Bearer somekey")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusBadRequest, recorder.Code)
+		require.JSONEq(t, `{"data":null,"errors":[{"message":"missing query","locations":[]}]}`, recorder.Body.String())
+	})
+
+	t.Run("tenant", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"query": "{ definitions { name } caveats { name } }"}`))
+		req.Header.Set("Authorization", "Bearer acmekey")
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		require.JSONEq(t, `{"data":{"definitions":[],"caveats":[]}}`, recorder.Body.String())
+	})
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"query": "{ definitions { name } }"}`))
+
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		require.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+}
+
+func jsonString(t *testing.T, value string) string {
+	encoded, err := json.Marshal(value)
+	require.NoError(t, err)
+	return string(encoded)
+}
@@ -0,0 +1,584 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/graphql-go/graphql"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc/metadata"
+
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+)
+
+const (
+	// checkBatchSize is the maximum number of resources checked with a single call.
+	checkBatchSize = 100
+
+	// maxConcurrentChecks is the maximum number of calls made concurrently to load checks.
+	maxConcurrentChecks = 10
+)
+
+// NewPermissionsHandler returns an http.Handler serving GraphQL queries over the permissions API,
+// made with the given client on behalf of the caller: the Authorization header of requests is
+// forwarded to the API, which authenticates them.
+//
+// Checks are batched: checks of the same permission for the same subject are made with a single
+// LookupResources call restricted to the checked resources, and other checks are made
+// concurrently. For example, the following query makes two calls, rather than one per document:
+//
+//	{
+//	  lookupResources(resourceType: "document", permission: "view", subjectType: "user", subjectId: "tom") {
+//	    resourceId
+//	    canEdit: check(permission: "edit") { hasPermission }
+//	  }
+//	}
+func NewPermissionsHandler(client v1.PermissionsServiceClient) http.Handler {
+	handler := NewHandler(PermissionsSchema, func(ctx context.Context) (any, error) {
+		return newPermissionsRoot(client), nil
+	})
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", authorization)
+		}
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// permissionsRoot is the root value of a request over the permissions API.
+type permissionsRoot struct {
+	client v1.PermissionsServiceClient
+	checks *Loader[checkKey, checkResult]
+}
+
+func newPermissionsRoot(client v1.PermissionsServiceClient) *permissionsRoot {
+	root := &permissionsRoot{client: client}
+	root.checks = NewLoader(root.loadChecks)
+	return root
+}
+
+// consistency is the consistency requested by the arguments of a field.
+type consistency struct {
+	zedToken        string
+	fullyConsistent bool
+}
+
+func consistencyFromArgs(args map[string]any) (consistency, error) {
+	zedToken, _ := args["zedToken"].(string)
+	fullyConsistent, _ := args["fullyConsistent"].(bool)
+	if zedToken != "" && fullyConsistent {
+		return consistency{}, fmt.Errorf("only one of `zedToken` and `fullyConsistent` may be given")
+	}
+	return consistency{zedToken: zedToken, fullyConsistent: fullyConsistent}, nil
+}
+
+func (c consistency) toProto() *v1.Consistency {
+	switch {
+	case c.fullyConsistent:
+		return &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+	case c.zedToken != "":
+		return &v1.Consistency{Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: &v1.ZedToken{Token: c.zedToken}}}
+	default:
+		return nil
+	}
+}
+
+// subject is the subject of a check or lookup.
+type subject struct {
+	subjectType     string
+	subjectID       string
+	subjectRelation string
+}
+
+func subjectFromArgs(args map[string]any) subject {
+	subjectRelation, _ := args["subjectRelation"].(string)
+	return subject{
+		subjectType:     args["subjectType"].(string),
+		subjectID:       args["subjectId"].(string),
+		subjectRelation: subjectRelation,
+	}
+}
+
+func (s subject) toProto() *v1.SubjectReference {
+	return &v1.SubjectReference{
+		Object:           &v1.ObjectReference{ObjectType: s.subjectType, ObjectId: s.subjectID},
+		OptionalRelation: s.subjectRelation,
+	}
+}
+
+// checkKey is a check of a permission on a resource for a subject.
+type checkKey struct {
+	resourceType string
+	resourceID   string
+	permission   string
+	subject      subject
+	consistency  consistency
+}
+
+// checkResult is the result of a check.
+type checkResult struct {
+	permissionship v1.CheckPermissionResponse_Permissionship
+	checkedAt      string
+}
+
+// loadChecks makes the given checks, grouping those of the same permission for the same subject
+// into calls to LookupResources restricted to the checked resources.
+func (pr *permissionsRoot) loadChecks(ctx context.Context, keys []checkKey) (map[checkKey]checkResult, error) {
+	type group struct {
+		resourceType string
+		permission   string
+		subject      subject
+		consistency  consistency
+	}
+
+	var groups []group
+	resourceIDs := map[group][]string{}
+	for _, key := range keys {
+		g := group{key.resourceType, key.permission, key.subject, key.consistency}
+		if _, ok := resourceIDs[g]; !ok {
+			groups = append(groups, g)
+		}
+		resourceIDs[g] = append(resourceIDs[g], key.resourceID)
+	}
+
+	var lock sync.Mutex
+	loaded := make(map[checkKey]checkResult, len(keys))
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.SetLimit(maxConcurrentChecks)
+	for _, g := range groups {
+		g := g
+		ids := resourceIDs[g]
+		sort.Strings(ids)
+		for len(ids) > 0 {
+			batch := ids
+			if len(batch) > checkBatchSize {
+				batch = batch[:checkBatchSize]
+			}
+			ids = ids[len(batch):]
+
+			eg.Go(func() error {
+				results, err := pr.checkResources(ctx, g.resourceType, batch, g.permission, g.subject, g.consistency)
+				if err != nil {
+					return err
+				}
+
+				lock.Lock()
+				defer lock.Unlock()
+				for key, result := range results {
+					loaded[key] = result
+				}
+				return nil
+			})
+		}
+	}
+	return loaded, eg.Wait()
+}
+
+// checkResources checks the permission on the given resources for the subject, with a single call
+// to CheckPermission for a single resource, or to LookupResources restricted to the resources.
+func (pr *permissionsRoot) checkResources(ctx context.Context, resourceType string, resourceIDs []string, permission string, s subject, c consistency) (map[checkKey]checkResult, error) {
+	keyOf := func(resourceID string) checkKey {
+		return checkKey{resourceType: resourceType, resourceID: resourceID, permission: permission, subject: s, consistency: c}
+	}
+
+	if len(resourceIDs) == 1 {
+		resp, err := pr.client.CheckPermission(ctx, &v1.CheckPermissionRequest{
+			Consistency: c.toProto(),
+			Resource:    &v1.ObjectReference{ObjectType: resourceType, ObjectId: resourceIDs[0]},
+			Permission:  permission,
+			Subject:     s.toProto(),
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return map[checkKey]checkResult{keyOf(resourceIDs[0]): {
+			permissionship: resp.Permissionship,
+			checkedAt:      resp.CheckedAt.GetToken(),
+		}}, nil
+	}
+
+	ctx = metadata.AppendToOutgoingContext(ctx, string(v1svc.RequestLookupCandidates), strings.Join(resourceIDs, ","))
+	stream, err := pr.client.LookupResources(ctx, &v1.LookupResourcesRequest{
+		Consistency:        c.toProto(),
+		ResourceObjectType: resourceType,
+		Permission:         permission,
+		Subject:            s.toProto(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	results := make(map[checkKey]checkResult, len(resourceIDs))
+	var checkedAt string
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		checkedAt = resp.LookedUpAt.GetToken()
+		permissionship := v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION
+		if resp.Permissionship == v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION {
+			permissionship = v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+		}
+		results[keyOf(resp.ResourceObjectId)] = checkResult{permissionship: permissionship, checkedAt: checkedAt}
+	}
+
+	for _, resourceID := range resourceIDs {
+		if _, ok := results[keyOf(resourceID)]; !ok {
+			results[keyOf(resourceID)] = checkResult{
+				permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+				checkedAt:      checkedAt,
+			}
+		}
+	}
+	return results, nil
+}
+
+// lookedUpResource is a resource found by a lookup, along with the lookup.
+type lookedUpResource struct {
+	root         *permissionsRoot
+	response     *v1.LookupResourcesResponse
+	subject      subject
+	consistency  consistency
+	resourceType string
+}
+
+var consistencyArguments = graphql.FieldConfigArgument{
+	"zedToken":        {Type: graphql.String},
+	"fullyConsistent": {Type: graphql.Boolean},
+}
+
+// withConsistencyArguments returns the given arguments along with the consistency arguments.
+func withConsistencyArguments(args graphql.FieldConfigArgument) graphql.FieldConfigArgument {
+	for name, arg := range consistencyArguments {
+		args[name] = arg
+	}
+	return args
+}
+
+var checkResultType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "CheckResult",
+	Fields: graphql.Fields{
+		"permissionship": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(checkResult).permissionship.String(), nil
+			},
+		},
+		"hasPermission": {
+			Type: graphql.NewNonNull(graphql.Boolean),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(checkResult).permissionship == v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, nil
+			},
+		},
+		"checkedAt": {
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if checkedAt := p.Source.(checkResult).checkedAt; checkedAt != "" {
+					return checkedAt, nil
+				}
+				return nil, nil
+			},
+		},
+	},
+})
+
+var lookedUpResourceType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "LookedUpResource",
+	Fields: graphql.Fields{
+		"resourceId": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(lookedUpResource).response.ResourceObjectId, nil
+			},
+		},
+		"permissionship": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(lookedUpResource).response.Permissionship.String(), nil
+			},
+		},
+		"lookedUpAt": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(lookedUpResource).response.LookedUpAt.GetToken(), nil
+			},
+		},
+		"check": {
+			Type: checkResultType,
+			Args: graphql.FieldConfigArgument{"permission": {Type: graphql.NewNonNull(graphql.String)}},
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				resource := p.Source.(lookedUpResource)
+				return resource.root.checks.Load(p.Context, checkKey{
+					resourceType: resource.resourceType,
+					resourceID:   resource.response.ResourceObjectId,
+					permission:   p.Args["permission"].(string),
+					subject:      resource.subject,
+					consistency:  resource.consistency,
+				}), nil
+			},
+		},
+	},
+})
+
+var lookedUpSubjectType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "LookedUpSubject",
+	Fields: graphql.Fields{
+		"subjectId": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*v1.LookupSubjectsResponse).SubjectObjectId, nil
+			},
+		},
+		"excludedSubjectIds": {
+			Type: stringList,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if excluded := p.Source.(*v1.LookupSubjectsResponse).ExcludedSubjectIds; excluded != nil {
+					return excluded, nil
+				}
+				return []string{}, nil
+			},
+		},
+		"lookedUpAt": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*v1.LookupSubjectsResponse).LookedUpAt.GetToken(), nil
+			},
+		},
+	},
+})
+
+var relationshipType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Relationship",
+	Fields: graphql.Fields{
+		"resourceType": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*v1.ReadRelationshipsResponse).Relationship.Resource.ObjectType, nil
+			},
+		},
+		"resourceId": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*v1.ReadRelationshipsResponse).Relationship.Resource.ObjectId, nil
+			},
+		},
+		"relation": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*v1.ReadRelationshipsResponse).Relationship.Relation, nil
+			},
+		},
+		"subjectType": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*v1.ReadRelationshipsResponse).Relationship.Subject.Object.ObjectType, nil
+			},
+		},
+		"subjectId": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*v1.ReadRelationshipsResponse).Relationship.Subject.Object.ObjectId, nil
+			},
+		},
+		"subjectRelation": {
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if relation := p.Source.(*v1.ReadRelationshipsResponse).Relationship.Subject.OptionalRelation; relation != "" {
+					return relation, nil
+				}
+				return nil, nil
+			},
+		},
+		"caveat": {
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				if caveat := p.Source.(*v1.ReadRelationshipsResponse).Relationship.OptionalCaveat; caveat != nil {
+					return caveat.CaveatName, nil
+				}
+				return nil, nil
+			},
+		},
+		"readAt": {
+			Type: graphql.NewNonNull(graphql.String),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(*v1.ReadRelationshipsResponse).ReadAt.GetToken(), nil
+			},
+		},
+	},
+})
+
+// PermissionsSchema is the GraphQL schema over which permissions can be checked and looked up,
+// and relationships read, with the permissions API.
+var PermissionsSchema = mustNewSchema(graphql.NewObject(graphql.ObjectConfig{
+	Name: "Query",
+	Fields: graphql.Fields{
+		"check": {
+			Type: checkResultType,
+			Args: withConsistencyArguments(graphql.FieldConfigArgument{
+				"resourceType":    {Type: graphql.NewNonNull(graphql.String)},
+				"resourceId":      {Type: graphql.NewNonNull(graphql.String)},
+				"permission":      {Type: graphql.NewNonNull(graphql.String)},
+				"subjectType":     {Type: graphql.NewNonNull(graphql.String)},
+				"subjectId":       {Type: graphql.NewNonNull(graphql.String)},
+				"subjectRelation": {Type: graphql.String},
+			}),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				c, err := consistencyFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+
+				return rootValue[*permissionsRoot](p).checks.Load(p.Context, checkKey{
+					resourceType: p.Args["resourceType"].(string),
+					resourceID:   p.Args["resourceId"].(string),
+					permission:   p.Args["permission"].(string),
+					subject:      subjectFromArgs(p.Args),
+					consistency:  c,
+				}), nil
+			},
+		},
+		"lookupResources": {
+			Type: graphql.NewList(graphql.NewNonNull(lookedUpResourceType)),
+			Args: withConsistencyArguments(graphql.FieldConfigArgument{
+				"resourceType":    {Type: graphql.NewNonNull(graphql.String)},
+				"permission":      {Type: graphql.NewNonNull(graphql.String)},
+				"subjectType":     {Type: graphql.NewNonNull(graphql.String)},
+				"subjectId":       {Type: graphql.NewNonNull(graphql.String)},
+				"subjectRelation": {Type: graphql.String},
+			}),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				root := rootValue[*permissionsRoot](p)
+				c, err := consistencyFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+
+				s := subjectFromArgs(p.Args)
+				stream, err := root.client.LookupResources(p.Context, &v1.LookupResourcesRequest{
+					Consistency:        c.toProto(),
+					ResourceObjectType: p.Args["resourceType"].(string),
+					Permission:         p.Args["permission"].(string),
+					Subject:            s.toProto(),
+				})
+				if err != nil {
+					return nil, err
+				}
+
+				responses, err := receiveAll[v1.LookupResourcesResponse](stream.Recv)
+				if err != nil {
+					return nil, err
+				}
+
+				resources := make([]lookedUpResource, 0, len(responses))
+				for _, resp := range responses {
+					resources = append(resources, lookedUpResource{
+						root:         root,
+						response:     resp,
+						subject:      s,
+						consistency:  c,
+						resourceType: p.Args["resourceType"].(string),
+					})
+				}
+				return resources, nil
+			},
+		},
+		"lookupSubjects": {
+			Type: graphql.NewList(graphql.NewNonNull(lookedUpSubjectType)),
+			Args: withConsistencyArguments(graphql.FieldConfigArgument{
+				"resourceType":    {Type: graphql.NewNonNull(graphql.String)},
+				"resourceId":      {Type: graphql.NewNonNull(graphql.String)},
+				"permission":      {Type: graphql.NewNonNull(graphql.String)},
+				"subjectType":     {Type: graphql.NewNonNull(graphql.String)},
+				"subjectRelation": {Type: graphql.String},
+			}),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				c, err := consistencyFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+
+				subjectRelation, _ := p.Args["subjectRelation"].(string)
+				stream, err := rootValue[*permissionsRoot](p).client.LookupSubjects(p.Context, &v1.LookupSubjectsRequest{
+					Consistency:             c.toProto(),
+					Resource:                &v1.ObjectReference{ObjectType: p.Args["resourceType"].(string), ObjectId: p.Args["resourceId"].(string)},
+					Permission:              p.Args["permission"].(string),
+					SubjectObjectType:       p.Args["subjectType"].(string),
+					OptionalSubjectRelation: subjectRelation,
+				})
+				if err != nil {
+					return nil, err
+				}
+				return receiveAll[v1.LookupSubjectsResponse](stream.Recv)
+			},
+		},
+		"readRelationships": {
+			Type: graphql.NewList(graphql.NewNonNull(relationshipType)),
+			Args: withConsistencyArguments(graphql.FieldConfigArgument{
+				"resourceType":    {Type: graphql.NewNonNull(graphql.String)},
+				"resourceId":      {Type: graphql.String},
+				"relation":        {Type: graphql.String},
+				"subjectType":     {Type: graphql.String},
+				"subjectId":       {Type: graphql.String},
+				"subjectRelation": {Type: graphql.String},
+			}),
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				c, err := consistencyFromArgs(p.Args)
+				if err != nil {
+					return nil, err
+				}
+
+				filter := &v1.RelationshipFilter{ResourceType: p.Args["resourceType"].(string)}
+				filter.OptionalResourceId, _ = p.Args["resourceId"].(string)
+				filter.OptionalRelation, _ = p.Args["relation"].(string)
+				if subjectType, ok := p.Args["subjectType"].(string); ok {
+					filter.OptionalSubjectFilter = &v1.SubjectFilter{SubjectType: subjectType}
+					filter.OptionalSubjectFilter.OptionalSubjectId, _ = p.Args["subjectId"].(string)
+					if subjectRelation, ok := p.Args["subjectRelation"].(string); ok {
+						filter.OptionalSubjectFilter.OptionalRelation = &v1.SubjectFilter_RelationFilter{Relation: subjectRelation}
+					}
+				} else if p.Args["subjectId"] != nil || p.Args["subjectRelation"] != nil {
+					return nil, fmt.Errorf("`subjectType` is required to filter on the subject")
+				}
+
+				stream, err := rootValue[*permissionsRoot](p).client.ReadRelationships(p.Context, &v1.ReadRelationshipsRequest{
+					Consistency:        c.toProto(),
+					RelationshipFilter: filter,
+				})
+				if err != nil {
+					return nil, err
+				}
+				return receiveAll[v1.ReadRelationshipsResponse](stream.Recv)
+			},
+		},
+	},
+}))
+
+// receiveAll receives the responses of a stream until its end.
+func receiveAll[T any](recv func() (*T, error)) ([]*T, error) {
+	responses := []*T{}
+	for {
+		resp, err := recv()
+		if errors.Is(err, io.EOF) {
+			return responses, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		responses = append(responses, resp)
+	}
+}
@@ -0,0 +1,242 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/exp/maps"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+)
+
+// fakePermissionsClient serves the permissions of a subject over documents, recording the calls
+// made.
+type fakePermissionsClient struct {
+	v1.PermissionsServiceClient
+
+	// permissions are the permissions of the subject, by document.
+	permissions map[string][]string
+
+	lock  sync.Mutex
+	calls []string
+}
+
+func (c *fakePermissionsClient) record(ctx context.Context, call string) error {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	if authorization := md.Get("authorization"); len(authorization) != 1 || authorization[0] != "Bearer somekey" {
+		return status.Error(codes.Unauthenticated, "missing preshared key")
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.calls = append(c.calls, call)
+	return nil
+}
+
+func (c *fakePermissionsClient) hasPermission(resourceID, permission string) bool {
+	for _, p := range c.permissions[resourceID] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *fakePermissionsClient) CheckPermission(ctx context.Context, req *v1.CheckPermissionRequest, _ ...grpc.CallOption) (*v1.CheckPermissionResponse, error) {
+	if err := c.record(ctx, fmt.Sprintf("check %s#%s", req.Resource.ObjectId, req.Permission)); err != nil {
+		return nil, err
+	}
+
+	permissionship := v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION
+	if c.hasPermission(req.Resource.ObjectId, req.Permission) {
+		permissionship = v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION
+	}
+	return &v1.CheckPermissionResponse{
+		CheckedAt:      &v1.ZedToken{Token: "token"},
+		Permissionship: permissionship,
+	}, nil
+}
+
+func (c *fakePermissionsClient) LookupResources(ctx context.Context, req *v1.LookupResourcesRequest, _ ...grpc.CallOption) (v1.PermissionsService_LookupResourcesClient, error) {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	candidates := md.Get(string(v1svc.RequestLookupCandidates))
+	if err := c.record(ctx, fmt.Sprintf("lookup %s %v", req.Permission, candidates)); err != nil {
+		return nil, err
+	}
+
+	var resourceIDs []string
+	if len(candidates) > 0 {
+		resourceIDs = strings.Split(candidates[0], ",")
+	} else {
+		resourceIDs = maps.Keys(c.permissions)
+		sort.Strings(resourceIDs)
+	}
+
+	var responses []*v1.LookupResourcesResponse
+	for _, resourceID := range resourceIDs {
+		if c.hasPermission(resourceID, req.Permission) {
+			responses = append(responses, &v1.LookupResourcesResponse{
+				LookedUpAt:       &v1.ZedToken{Token: "token"},
+				ResourceObjectId: resourceID,
+				Permissionship:   v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION,
+			})
+		}
+	}
+	return &fakeLookupResourcesClient{responses: responses}, nil
+}
+
+type fakeLookupResourcesClient struct {
+	grpc.ClientStream
+	responses []*v1.LookupResourcesResponse
+}
+
+func (c *fakeLookupResourcesClient) Recv() (*v1.LookupResourcesResponse, error) {
+	if len(c.responses) == 0 {
+		return nil, io.EOF
+	}
+	resp := c.responses[0]
+	c.responses = c.responses[1:]
+	return resp, nil
+}
+
+func TestPermissionsHandler(t *testing.T) {
+	testCases := []struct {
+		name             string
+		authorization    string
+		query            string
+		expectedResponse string
+		expectedCalls    []string
+	}{
+		{
+			"single check",
+			"Bearer somekey",
+			`{ check(resourceType: "document", resourceId: "first", permission: "edit", subjectType: "user", subjectId: "tom") { permissionship hasPermission checkedAt } }`,
+			`{"data":{"check":{"permissionship":"PERMISSIONSHIP_HAS_PERMISSION","hasPermission":true,"checkedAt":"token"}}}`,
+			[]string{"check first#edit"},
+		},
+		{
+			"batched checks",
+			"Bearer somekey",
+			`{
+				first: check(resourceType: "document", resourceId: "first", permission: "edit", subjectType: "user", subjectId: "tom") { hasPermission }
+				second: check(resourceType: "document", resourceId: "second", permission: "edit", subjectType: "user", subjectId: "tom") { hasPermission }
+				again: check(resourceType: "document", resourceId: "first", permission: "edit", subjectType: "user", subjectId: "tom") { hasPermission }
+				view: check(resourceType: "document", resourceId: "second", permission: "view", subjectType: "user", subjectId: "tom") { hasPermission }
+			}`,
+			`{"data":{
+				"first":{"hasPermission":true},
+				"second":{"hasPermission":false},
+				"again":{"hasPermission":true},
+				"view":{"hasPermission":true}
+			}}`,
+			[]string{"lookup edit [first,second]", "check second#view"},
+		},
+		{
+			"checks of looked up resources",
+			"Bearer somekey",
+			`{
+				lookupResources(resourceType: "document", permission: "view", subjectType: "user", subjectId: "tom") {
+					resourceId
+					canEdit: check(permission: "edit") { hasPermission }
+				}
+			}`,
+			`{"data":{"lookupResources":[
+				{"resourceId":"first","canEdit":{"hasPermission":true}},
+				{"resourceId":"second","canEdit":{"hasPermission":false}}
+			]}}`,
+			[]string{"lookup view []", "lookup edit [first,second]"},
+		},
+		{
+			"conflicting consistency",
+			"Bearer somekey",
+			`{ check(resourceType: "document", resourceId: "first", permission: "edit", subjectType: "user", subjectId: "tom", zedToken: "token", fullyConsistent: true) { hasPermission } }`,
+			`{"data":{"check":null},"errors":[{"message":"only one of ` + "`zedToken` and `fullyConsistent`" + ` may be given","locations":[{"line":1,"column":3}],"path":["check"]}]}`,
+			nil,
+		},
+		{
+			"unauthenticated",
+			"",
+			`{ check(resourceType: "document", resourceId: "first", permission: "edit", subjectType: "user", subjectId: "tom") { hasPermission } }`,
+			`{"data":{"check":null},"errors":[{"message":"rpc error: code = Unauthenticated desc = missing preshared key","locations":[{"line":1,"column":3}],"path":["check"]}]}`,
+			nil,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			client := &fakePermissionsClient{permissions: map[string][]string{
+				"first":  {"view", "edit"},
+				"second": {"view"},
+			}}
+
+			body := `{"query": ` + jsonString(t, tc.query) + `}`
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+
+			recorder := httptest.NewRecorder()
+			NewPermissionsHandler(client).ServeHTTP(recorder, req)
+			require.Equal(http.StatusOK, recorder.Code)
+			require.JSONEq(tc.expectedResponse, recorder.Body.String())
+			require.ElementsMatch(tc.expectedCalls, client.calls)
+		})
+	}
+}
+
+func TestLoader(t *testing.T) {
+	require := require.New(t)
+
+	var batches [][]int
+	loader := NewLoader(func(ctx context.Context, keys []int) (map[int]string, error) {
+		batches = append(batches, keys)
+		values := map[int]string{}
+		for _, key := range keys {
+			if key > 0 {
+				values[key] = fmt.Sprint(key)
+			}
+		}
+		return values, nil
+	})
+
+	first := loader.Load(context.Background(), 1)
+	second := loader.Load(context.Background(), 2)
+	missing := loader.Load(context.Background(), -1)
+	again := loader.Load(context.Background(), 1)
+
+	value, err := first()
+	require.NoError(err)
+	require.Equal("1", value)
+
+	value, err = second()
+	require.NoError(err)
+	require.Equal("2", value)
+
+	_, err = missing()
+	require.Error(err)
+
+	value, err = again()
+	require.NoError(err)
+	require.Equal("1", value)
+
+	value, err = loader.Load(context.Background(), 2)()
+	require.NoError(err)
+	require.Equal("2", value)
+
+	require.Equal([][]int{{1, 2, -1}}, batches)
+}
@@ -0,0 +1,134 @@
+// Package grpcweb serves gRPC-Web and the Connect protocol alongside gRPC, by transcoding their
+// requests into gRPC requests handled by a gRPC server, so that browsers and plain HTTP clients
+// can call the API without a separate proxy.
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"connectrpc.com/vanguard/vanguardgrpc"
+	"google.golang.org/grpc"
+)
+
+const (
+	grpcContentType        = "application/grpc"
+	grpcWebContentType     = "application/grpc-web"
+	grpcWebTextContentType = "application/grpc-web-text"
+
+	// maxRequestSize is the maximum size of the body of a gRPC-Web text request, matching the
+	// default maximum size of messages received by gRPC servers.
+	maxRequestSize = 4 * 1024 * 1024
+)
+
+// connectContentTypes are the content types of the requests of the Connect protocol, for unary
+// and streaming calls.
+var connectContentTypes = map[string]bool{
+	"application/proto":         true,
+	"application/json":          true,
+	"application/connect+proto": true,
+	"application/connect+json":  true,
+}
+
+// NewHandler returns a handler serving gRPC requests with the given gRPC server, as well as
+// gRPC-Web and Connect requests transcoded into gRPC requests. The gRPC server must be served
+// over HTTP/2 for gRPC requests, while gRPC-Web and Connect requests may also use HTTP/1.1.
+func NewHandler(grpcServer *grpc.Server) (http.Handler, error) {
+	transcoder, err := vanguardgrpc.NewTranscoder(grpcServer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gRPC-Web and Connect transcoder: %w", err)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch {
+		case strings.HasPrefix(mediaType, grpcWebTextContentType):
+			serveGRPCWebText(transcoder, w, r, mediaType)
+		case strings.HasPrefix(mediaType, grpcWebContentType), connectContentTypes[mediaType]:
+			transcoder.ServeHTTP(w, r)
+		case strings.HasPrefix(mediaType, grpcContentType):
+			grpcServer.ServeHTTP(w, r)
+		default:
+			http.Error(w, fmt.Sprintf("unsupported content type %q", mediaType), http.StatusUnsupportedMediaType)
+		}
+	}), nil
+}
+
+// serveGRPCWebText serves a gRPC-Web request of the text variant, which vanguard does not
+// support, by decoding it into a binary gRPC-Web request and encoding its response in base64.
+func serveGRPCWebText(transcoder http.Handler, w http.ResponseWriter, r *http.Request, mediaType string) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestSize))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if body, err = decodeBase64Chunks(body); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	codec := strings.TrimPrefix(mediaType, grpcWebTextContentType)
+	req := r.Clone(r.Context())
+	req.Header.Set("Content-Type", grpcWebContentType+codec)
+	req.Header.Del("Content-Length")
+	req.ContentLength = int64(len(body))
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	transcoder.ServeHTTP(&base64ResponseWriter{ResponseWriter: w, contentType: grpcWebTextContentType + codec}, req)
+}
+
+// base64ResponseWriter encodes each write of a response in base64, as padded chunks, and sets
+// the content type of the response.
+type base64ResponseWriter struct {
+	http.ResponseWriter
+	contentType string
+	wroteHeader bool
+}
+
+func (w *base64ResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.Header().Set("Content-Type", w.contentType)
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *base64ResponseWriter) Write(p []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if _, err := w.ResponseWriter.Write([]byte(base64.StdEncoding.EncodeToString(p))); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *base64ResponseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// decodeBase64Chunks decodes base64 text made of one or more padded chunks, as sent by
+// gRPC-Web clients streaming their requests.
+func decodeBase64Chunks(text []byte) ([]byte, error) {
+	text = bytes.Join(bytes.Fields(text), nil)
+	if len(text)%4 != 0 {
+		return nil, fmt.Errorf("invalid base64 body of %d bytes", len(text))
+	}
+
+	decoded := make([]byte, 0, len(text)/4*3)
+	var group [3]byte
+	for i := 0; i < len(text); i += 4 {
+		n, err := base64.StdEncoding.Decode(group[:], text[i:i+4])
+		if err != nil {
+			return nil, fmt.Errorf("invalid base64 body: %w", err)
+		}
+		decoded = append(decoded, group[:n]...)
+	}
+	return decoded, nil
+}
@@ -0,0 +1,275 @@
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/testing/testpb"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// grpcWebTrailerFlag marks the frame holding the trailers at the end of a gRPC-Web response.
+	grpcWebTrailerFlag = 0x80
+
+	// connectEndStreamFlag marks the message ending a Connect streaming response.
+	connectEndStreamFlag = 0x02
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	grpcServer := grpc.NewServer()
+	testpb.RegisterTestServiceServer(grpcServer, &testpb.TestPingService{T: t})
+
+	handler, err := NewHandler(grpcServer)
+	require.NoError(t, err)
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func post(t *testing.T, url, contentType string, body []byte) *http.Response {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", contentType)
+	// Connect clients send the version of the protocol, which identifies their unary calls.
+	req.Header.Set("Connect-Protocol-Version", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// appendFrame appends a frame of the given message to the given buffer.
+func appendFrame(buf []byte, flags byte, message []byte) []byte {
+	var prefix [5]byte
+	prefix[0] = flags
+	binary.BigEndian.PutUint32(prefix[1:], uint32(len(message)))
+	return append(append(buf, prefix[:]...), message...)
+}
+
+type frame struct {
+	flags   byte
+	message []byte
+}
+
+func readFrames(t *testing.T, body []byte) []frame {
+	var frames []frame
+	for len(body) > 0 {
+		require.GreaterOrEqual(t, len(body), 5)
+		size := int(binary.BigEndian.Uint32(body[1:5]))
+		require.GreaterOrEqual(t, len(body), 5+size)
+		frames = append(frames, frame{body[0], body[5 : 5+size]})
+		body = body[5+size:]
+	}
+	return frames
+}
+
+func TestGRPCWeb(t *testing.T) {
+	testCases := []struct {
+		name        string
+		contentType string
+		text        bool
+	}{
+		{"binary", "application/grpc-web+proto", false},
+		{"binary without codec", "application/grpc-web", false},
+		{"text", "application/grpc-web-text", true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			server := newTestServer(t)
+
+			request, err := proto.Marshal(&testpb.PingListRequest{Value: "hello"})
+			require.NoError(err)
+			body := appendFrame(nil, 0, request)
+			if tc.text {
+				body = []byte(base64.StdEncoding.EncodeToString(body))
+			}
+
+			resp := post(t, server.URL+"/testing.testpb.v1.TestService/PingList", tc.contentType, body)
+			require.Equal(http.StatusOK, resp.StatusCode)
+			require.True(strings.HasPrefix(resp.Header.Get("Content-Type"), tc.contentType))
+
+			body, err = io.ReadAll(resp.Body)
+			require.NoError(err)
+			if tc.text {
+				body, err = decodeBase64Chunks(body)
+				require.NoError(err)
+			}
+
+			frames := readFrames(t, body)
+			require.Len(frames, testpb.ListResponseCount+1)
+			for i, frame := range frames[:testpb.ListResponseCount] {
+				require.Equal(byte(0), frame.flags)
+				message := &testpb.PingListResponse{}
+				require.NoError(proto.Unmarshal(frame.message, message))
+				require.Equal("hello", message.Value)
+				require.Equal(int32(i), message.Counter)
+			}
+
+			trailer := frames[testpb.ListResponseCount]
+			require.Equal(byte(grpcWebTrailerFlag), trailer.flags)
+			require.Contains(strings.ToLower(string(trailer.message)), "grpc-status: 0\r\n")
+		})
+	}
+}
+
+func TestGRPCWebError(t *testing.T) {
+	require := require.New(t)
+	server := newTestServer(t)
+
+	request, err := proto.Marshal(&testpb.PingErrorRequest{ErrorCodeReturned: 5})
+	require.NoError(err)
+
+	resp := post(t, server.URL+"/testing.testpb.v1.TestService/PingError", "application/grpc-web+proto", appendFrame(nil, 0, request))
+	require.Equal(http.StatusOK, resp.StatusCode)
+
+	// Responses without messages carry their status in their headers.
+	require.Equal("5", resp.Header.Get("Grpc-Status"))
+	require.Equal("Userspace error.", resp.Header.Get("Grpc-Message"))
+}
+
+func TestConnectUnary(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		contentType    string
+		body           []byte
+		expectedStatus int
+		expectedBody   string
+	}{
+		{
+			"json",
+			"/testing.testpb.v1.TestService/Ping",
+			"application/json",
+			[]byte(`{"value": "hello"}`),
+			http.StatusOK,
+			`{"value":"hello","counter":0}`,
+		},
+		{
+			"json error",
+			"/testing.testpb.v1.TestService/PingError",
+			"application/json",
+			[]byte(`{"errorCodeReturned": 5}`),
+			http.StatusNotFound,
+			`{"code":"not_found","message":"Userspace error."}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			server := newTestServer(t)
+
+			resp := post(t, server.URL+tc.path, tc.contentType, tc.body)
+			require.Equal(tc.expectedStatus, resp.StatusCode)
+			require.Equal("application/json", resp.Header.Get("Content-Type"))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(err)
+			require.JSONEq(tc.expectedBody, string(body))
+		})
+	}
+}
+
+func TestConnectUnaryProto(t *testing.T) {
+	require := require.New(t)
+	server := newTestServer(t)
+
+	request, err := proto.Marshal(&testpb.PingRequest{Value: "hello"})
+	require.NoError(err)
+
+	resp := post(t, server.URL+"/testing.testpb.v1.TestService/Ping", "application/proto", request)
+	require.Equal(http.StatusOK, resp.StatusCode)
+	require.Equal("application/proto", resp.Header.Get("Content-Type"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(err)
+	message := &testpb.PingResponse{}
+	require.NoError(proto.Unmarshal(body, message))
+	require.Equal("hello", message.Value)
+}
+
+func TestConnectStreaming(t *testing.T) {
+	testCases := []struct {
+		name          string
+		request       string
+		expectedCount int
+		expectedEnd   string
+	}{
+		{"success", `{"value": "hello"}`, testpb.ListResponseCount, `{}`},
+		{"error", `{"value": "hello", "errorCodeReturned": 9}`, 0, `{"error":{"code":"failed_precondition","message":"foobar"}}`},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			server := newTestServer(t)
+
+			resp := post(t, server.URL+"/testing.testpb.v1.TestService/PingList", "application/connect+json", appendFrame(nil, 0, []byte(tc.request)))
+			require.Equal(http.StatusOK, resp.StatusCode)
+			require.Equal("application/connect+json", resp.Header.Get("Content-Type"))
+
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(err)
+			frames := readFrames(t, body)
+			require.Len(frames, tc.expectedCount+1)
+
+			for i, frame := range frames[:tc.expectedCount] {
+				require.Equal(byte(0), frame.flags)
+				var message map[string]interface{}
+				require.NoError(json.Unmarshal(frame.message, &message))
+				require.Equal("hello", message["value"])
+				if i > 0 {
+					require.Equal(float64(i), message["counter"])
+				}
+			}
+
+			end := frames[tc.expectedCount]
+			require.Equal(byte(connectEndStreamFlag), end.flags)
+			require.JSONEq(tc.expectedEnd, string(end.message))
+		})
+	}
+}
+
+func TestConnectUnknownMethod(t *testing.T) {
+	require := require.New(t)
+	server := newTestServer(t)
+
+	resp := post(t, server.URL+"/testing.testpb.v1.TestService/Unknown", "application/json", []byte(`{}`))
+	require.Equal(http.StatusNotFound, resp.StatusCode)
+}
+
+func TestUnsupportedContentType(t *testing.T) {
+	require := require.New(t)
+	server := newTestServer(t)
+
+	resp := post(t, server.URL+"/testing.testpb.v1.TestService/Ping", "text/plain", nil)
+	require.Equal(http.StatusUnsupportedMediaType, resp.StatusCode)
+}
+
+func TestDecodeBase64Chunks(t *testing.T) {
+	require := require.New(t)
+
+	decoded, err := decodeBase64Chunks([]byte(base64.StdEncoding.EncodeToString([]byte("ab")) + base64.StdEncoding.EncodeToString([]byte("cde"))))
+	require.NoError(err)
+	require.Equal("abcde", string(decoded))
+
+	_, err = decodeBase64Chunks([]byte("abc"))
+	require.Error(err)
+}
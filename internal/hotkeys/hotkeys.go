@@ -0,0 +1,156 @@
+// Package hotkeys detects the resources, subjects and namespaces most frequently checked, as
+// candidates for materialization or for overriding the caching policy.
+package hotkeys
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/redaction"
+)
+
+const (
+	kindResource  = "resource"
+	kindSubject   = "subject"
+	kindNamespace = "namespace"
+)
+
+var hotKeyChecksGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	Namespace: "spicedb",
+	Subsystem: "hotkeys",
+	Name:      "checks",
+	Help:      "The estimated, exponentially decayed, number of checks of each of the most frequently checked resources, subjects and namespaces, by kind and rank (1 for the most checked), as of the last decay.",
+}, []string{"kind", "rank"})
+
+// Report holds the most frequently checked keys of each kind, highest first.
+type Report struct {
+	HalfLife   string     `json:"halfLife"`
+	Resources  []KeyCount `json:"resources"`
+	Subjects   []KeyCount `json:"subjects"`
+	Namespaces []KeyCount `json:"namespaces"`
+}
+
+// Detector estimates how frequently resources, subjects and namespaces are checked in a fixed
+// amount of memory, and tracks the most frequently checked of each. Counts are halved at every
+// interval of its Start, so that they reflect recent checks. It is internally synchronized.
+type Detector struct {
+	sync.Mutex
+	halfLife   time.Duration
+	resources  *topK
+	subjects   *topK
+	namespaces *topK
+}
+
+// NewDetector creates a new detector tracking the k most frequently checked keys of each kind,
+// whose counts are halved at every halfLife.
+func NewDetector(k int, halfLife time.Duration) *Detector {
+	return &Detector{
+		halfLife:   halfLife,
+		resources:  newTopK(k),
+		subjects:   newTopK(k),
+		namespaces: newTopK(k),
+	}
+}
+
+// RecordCheck counts a check of the resource, such as `document:somedoc`, of the given namespace
+// for the subject, such as `user:tom`. It is safe to call on a nil Detector.
+func (d *Detector) RecordCheck(namespace, resource, subject string) {
+	if d == nil {
+		return
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	d.resources.add(resource)
+	d.subjects.add(subject)
+	d.namespaces.add(namespace)
+}
+
+// Report returns the most frequently checked keys of each kind, where the IDs of the resources
+// and subjects are redacted as in the rest of the telemetry.
+func (d *Detector) Report() Report {
+	d.Lock()
+	defer d.Unlock()
+
+	return Report{
+		HalfLife:   d.halfLife.String(),
+		Resources:  redactKeys(d.resources.keys()),
+		Subjects:   redactKeys(d.subjects.keys()),
+		Namespaces: d.namespaces.keys(),
+	}
+}
+
+func redactKeys(keys []KeyCount) []KeyCount {
+	for i := range keys {
+		keys[i].Key = redaction.Text(keys[i].Key)
+	}
+	return keys
+}
+
+// decay publishes the counts of the most frequently checked keys as metrics, then halves all
+// counts. The metrics are labeled with the ranks of the keys rather than the keys themselves, so
+// that they neither expose IDs nor grow a series for every key which is ever hot.
+func (d *Detector) decay() {
+	report := d.Report()
+
+	hotKeyChecksGauge.Reset()
+	for kind, keys := range map[string][]KeyCount{
+		kindResource:  report.Resources,
+		kindSubject:   report.Subjects,
+		kindNamespace: report.Namespaces,
+	} {
+		for rank, key := range keys {
+			hotKeyChecksGauge.WithLabelValues(kind, strconv.Itoa(rank+1)).Set(float64(key.Count))
+		}
+	}
+
+	d.Lock()
+	defer d.Unlock()
+
+	d.resources.decay()
+	d.subjects.decay()
+	d.namespaces.decay()
+}
+
+// Start decays the counts of the detector at every half-life until the context is canceled.
+func (d *Detector) Start(ctx context.Context) error {
+	log.Ctx(ctx).Info().
+		Stringer("halfLife", d.halfLife).
+		Msg("hot key detection started")
+
+	ticker := time.NewTicker(d.halfLife)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			d.decay()
+		}
+	}
+}
+
+// NewHandler returns an http.Handler serving the JSON-encoded Report of the detector.
+func NewHandler(detector *Detector) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(detector.Report()); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write hot keys")
+		}
+	})
+}
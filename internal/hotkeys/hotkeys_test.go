@@ -0,0 +1,91 @@
+package hotkeys
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/redaction"
+)
+
+func TestTopK(t *testing.T) {
+	require := require.New(t)
+
+	top := newTopK(3)
+	for i := 0; i < 10000; i++ {
+		top.add(fmt.Sprintf("document:cold%d", i))
+		switch {
+		case i%2 == 0:
+			top.add("document:hottest")
+		case i%3 == 0:
+			top.add("document:hotter")
+		case i%5 == 0:
+			top.add("document:hot")
+		}
+	}
+
+	keys := top.keys()
+	require.Len(keys, 3)
+	require.Equal("document:hottest", keys[0].Key)
+	require.Equal("document:hotter", keys[1].Key)
+	require.Equal("document:hot", keys[2].Key)
+
+	// Counts are never underestimated, and overestimated by a small fraction of all counts.
+	require.GreaterOrEqual(keys[0].Count, uint64(5000))
+	require.Less(keys[0].Count, uint64(5000+20000/100))
+
+	top.decay()
+	require.Equal(keys[0].Count/2, top.keys()[0].Count)
+}
+
+func TestDetector(t *testing.T) {
+	require := require.New(t)
+
+	var nilDetector *Detector
+	nilDetector.RecordCheck("document", "document:first", "user:tom")
+
+	detector := NewDetector(2, time.Minute)
+	detector.RecordCheck("document", "document:first", "user:tom")
+	detector.RecordCheck("document", "document:first", "user:fred")
+	detector.RecordCheck("folder", "folder:root", "user:tom")
+
+	recorder := httptest.NewRecorder()
+	NewHandler(detector).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/hotkeys", nil))
+	require.Equal(http.StatusOK, recorder.Code)
+
+	var report Report
+	require.NoError(json.Unmarshal(recorder.Body.Bytes(), &report))
+	require.Equal("1m0s", report.HalfLife)
+	require.Equal([]KeyCount{{"document:first", 2}, {"folder:root", 1}}, report.Resources)
+	require.Equal([]KeyCount{{"user:tom", 2}, {"user:fred", 1}}, report.Subjects)
+	require.Equal([]KeyCount{{"document", 2}, {"folder", 1}}, report.Namespaces)
+
+	detector.decay()
+	require.Equal(float64(2), testutil.ToFloat64(hotKeyChecksGauge.WithLabelValues(kindResource, "1")))
+	require.Equal(float64(1), testutil.ToFloat64(hotKeyChecksGauge.WithLabelValues(kindResource, "2")))
+	require.Equal([]KeyCount{{"document:first", 1}}, detector.Report().Resources)
+}
+
+func TestDetectorRedactsIDs(t *testing.T) {
+	require := require.New(t)
+	redaction.SetMode(redaction.ModeHash)
+	defer redaction.SetMode(redaction.ModeNone)
+
+	detector := NewDetector(2, time.Minute)
+	detector.RecordCheck("document", "document:first", "user:tom")
+	detector.RecordCheck("document", "document:first", "group:eng#member")
+
+	report := detector.Report()
+	require.Equal([]KeyCount{{"document:" + redaction.Fingerprint("first"), 2}}, report.Resources)
+	require.ElementsMatch([]KeyCount{
+		{"user:" + redaction.Fingerprint("tom"), 1},
+		{"group:" + redaction.Fingerprint("eng") + "#member", 1},
+	}, report.Subjects)
+	require.Equal([]KeyCount{{"document", 2}}, report.Namespaces)
+}
@@ -0,0 +1,98 @@
+package hotkeys
+
+import (
+	"sort"
+
+	"github.com/cespare/xxhash/v2"
+)
+
+const (
+	// sketchWidth is the number of counters in each row of a sketch. The count of a key is
+	// overestimated by at most 2/sketchWidth of all counts with a probability of 1-2^-sketchDepth.
+	sketchWidth = 2048
+
+	// sketchDepth is the number of rows of a sketch.
+	sketchDepth = 4
+)
+
+// KeyCount is the estimated count of a key.
+type KeyCount struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+}
+
+// topK estimates the counts of keys with a count-min sketch, and tracks the keys with the highest
+// estimates. It is not synchronized.
+type topK struct {
+	k      int
+	counts [sketchDepth][sketchWidth]uint64
+	top    map[string]uint64
+}
+
+func newTopK(k int) *topK {
+	return &topK{k: k, top: make(map[string]uint64, k+1)}
+}
+
+// add counts an occurrence of the key.
+func (t *topK) add(key string) {
+	// The indexes of the key in each row are derived from a single hash, as in
+	// "Less Hashing, Same Performance" by Kirsch and Mitzenmacher.
+	hash := xxhash.Sum64String(key)
+	low, high := uint32(hash), uint32(hash>>32)
+
+	estimate := ^uint64(0)
+	for row := uint32(0); row < sketchDepth; row++ {
+		index := (low + row*high) % sketchWidth
+		t.counts[row][index]++
+		if count := t.counts[row][index]; count < estimate {
+			estimate = count
+		}
+	}
+
+	if _, ok := t.top[key]; ok || len(t.top) < t.k {
+		t.top[key] = estimate
+		return
+	}
+
+	minKey, minCount := "", ^uint64(0)
+	for topKey, count := range t.top {
+		if count < minCount {
+			minKey, minCount = topKey, count
+		}
+	}
+	if estimate > minCount {
+		delete(t.top, minKey)
+		t.top[key] = estimate
+	}
+}
+
+// decay halves all counts, so that the counts of keys no longer occurring fade away.
+func (t *topK) decay() {
+	for row := range t.counts {
+		for index := range t.counts[row] {
+			t.counts[row][index] /= 2
+		}
+	}
+	for key, count := range t.top {
+		if count /= 2; count == 0 {
+			delete(t.top, key)
+			continue
+		}
+		t.top[key] = count
+	}
+}
+
+// keys returns the keys with the highest estimated counts, highest first.
+func (t *topK) keys() []KeyCount {
+	keys := make([]KeyCount, 0, len(t.top))
+	for key, count := range t.top {
+		keys = append(keys, KeyCount{Key: key, Count: count})
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Count == keys[j].Count {
+			return keys[i].Key < keys[j].Key
+		}
+		return keys[i].Count > keys[j].Count
+	})
+	return keys
+}
@@ -0,0 +1,261 @@
+// Package accounting provides middleware accounting the requests, dispatched subproblems and
+// datastore rows scanned of each caller of the API, for chargeback or showback of the usage of
+// SpiceDB to the teams owning each API key.
+package accounting
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+)
+
+// Usage is the usage of a caller.
+type Usage struct {
+	Requests    uint64 `json:"requests"`
+	Dispatches  uint64 `json:"dispatches"`
+	RowsScanned uint64 `json:"rowsScanned"`
+}
+
+func (u *Usage) add(usage Usage) {
+	u.Requests += usage.Requests
+	u.Dispatches += usage.Dispatches
+	u.RowsScanned += usage.RowsScanned
+}
+
+// CallerUsage is the usage of a caller, named as by auth.CallerFromContext.
+type CallerUsage struct {
+	Caller string `json:"caller"`
+	Usage
+}
+
+// Report is the usage of the callers over a period of time.
+type Report struct {
+	PeriodStart time.Time     `json:"periodStart"`
+	PeriodEnd   time.Time     `json:"periodEnd"`
+	Callers     []CallerUsage `json:"callers"`
+}
+
+// Accountant accounts the usage of each caller of the API, both since the accountant started and
+// since the usage was last exported. The usage is local to the node.
+type Accountant struct {
+	mu          sync.Mutex
+	start       time.Time
+	total       map[string]*Usage
+	periodStart time.Time
+	period      map[string]*Usage
+}
+
+// NewAccountant creates a new accountant of the usage of callers.
+func NewAccountant() *Accountant {
+	now := time.Now()
+	return &Accountant{
+		start:       now,
+		total:       map[string]*Usage{},
+		periodStart: now,
+		period:      map[string]*Usage{},
+	}
+}
+
+// Record adds to the usage of the caller.
+func (a *Accountant) Record(caller string, usage Usage) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	for _, byCaller := range []map[string]*Usage{a.total, a.period} {
+		current, ok := byCaller[caller]
+		if !ok {
+			current = &Usage{}
+			byCaller[caller] = current
+		}
+		current.add(usage)
+	}
+}
+
+// Report returns the usage of the callers since the accountant started, ordered by caller.
+func (a *Accountant) Report(now time.Time) Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return newReport(a.start, now, a.total)
+}
+
+// rollPeriod returns the usage of the callers since the usage was last exported, and starts a new
+// period.
+func (a *Accountant) rollPeriod(now time.Time) Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	report := newReport(a.periodStart, now, a.period)
+	a.periodStart = now
+	a.period = map[string]*Usage{}
+	return report
+}
+
+func newReport(start, end time.Time, byCaller map[string]*Usage) Report {
+	report := Report{
+		PeriodStart: start.UTC(),
+		PeriodEnd:   end.UTC(),
+		Callers:     make([]CallerUsage, 0, len(byCaller)),
+	}
+	for caller, usage := range byCaller {
+		report.Callers = append(report.Callers, CallerUsage{Caller: caller, Usage: *usage})
+	}
+	sort.Slice(report.Callers, func(i, j int) bool {
+		return report.Callers[i].Caller < report.Callers[j].Caller
+	})
+	return report
+}
+
+// ExportRecord is a line of the export file, holding the usage of a caller over a period.
+type ExportRecord struct {
+	PeriodStart time.Time `json:"periodStart"`
+	PeriodEnd   time.Time `json:"periodEnd"`
+	CallerUsage
+}
+
+// Export appends the usage of each caller since the previous export to the file at the given
+// path, as JSON lines, and starts a new period. Callers without usage in the period are not
+// exported.
+func (a *Accountant) Export(path string, now time.Time) error {
+	report := a.rollPeriod(now)
+	if len(report.Callers) == 0 {
+		return nil
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("unable to open usage export file: %w", err)
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	for _, callerUsage := range report.Callers {
+		if err := encoder.Encode(ExportRecord{
+			PeriodStart: report.PeriodStart,
+			PeriodEnd:   report.PeriodEnd,
+			CallerUsage: callerUsage,
+		}); err != nil {
+			return fmt.Errorf("unable to write usage export file: %w", err)
+		}
+	}
+	return f.Sync()
+}
+
+// StartExport exports the usage of the callers to the file at the given path at every interval
+// until the context is canceled, after which the usage since the last export is exported.
+func (a *Accountant) StartExport(ctx context.Context, path string, interval time.Duration) error {
+	log.Ctx(ctx).Info().
+		Str("path", path).
+		Stringer("interval", interval).
+		Msg("usage export scheduled")
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := a.Export(path, time.Now()); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Msg("failed to export usage")
+			}
+			return ctx.Err()
+
+		case now := <-ticker.C:
+			if err := a.Export(path, now); err != nil {
+				log.Ctx(ctx).Warn().Err(err).Msg("failed to export usage")
+			}
+		}
+	}
+}
+
+// NewHandler returns an http.Handler serving the JSON-encoded usage Report of the accountant,
+// optionally limited to the caller given by the `caller` query parameter.
+func NewHandler(accountant *Accountant) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		report := accountant.Report(time.Now())
+		if caller := r.URL.Query().Get("caller"); caller != "" {
+			filtered := make([]CallerUsage, 0, 1)
+			for _, callerUsage := range report.Callers {
+				if callerUsage.Caller == caller {
+					filtered = append(filtered, callerUsage)
+				}
+			}
+			report.Callers = filtered
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write usage")
+		}
+	})
+}
+
+// end accounts a call made by the caller, along with its dispatches and the rows it scanned.
+func (a *Accountant) end(ctx context.Context, caller string, rows uint64) {
+	usage := Usage{Requests: 1, RowsScanned: rows}
+	if responseMeta := usagemetrics.FromContext(ctx); responseMeta != nil {
+		usage.Dispatches = uint64(responseMeta.DispatchCount)
+	}
+	a.Record(caller, usage)
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that accounts the usage of the
+// callers of the API. The interceptor must follow the datastore middleware.
+func UnaryServerInterceptor(accountant *Accountant) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		caller, ok := auth.AccountedCallerFromContext(ctx, info.FullMethod)
+		if accountant == nil || !ok {
+			return handler(ctx, req)
+		}
+
+		var queries, rows atomic.Uint64
+		ctx = usagemetrics.ContextWithHandleIfMissing(ctx)
+		if err := datastoremw.SetInContext(ctx, proxy.NewRowCountingDatastore(datastoremw.MustFromContext(ctx), &queries, &rows)); err != nil {
+			return nil, err
+		}
+		defer func() { accountant.end(ctx, caller, rows.Load()) }()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that accounts the usage of the
+// callers of the API. The interceptor must follow the datastore middleware.
+func StreamServerInterceptor(accountant *Accountant) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		caller, ok := auth.AccountedCallerFromContext(stream.Context(), info.FullMethod)
+		if accountant == nil || !ok {
+			return handler(srv, stream)
+		}
+
+		var queries, rows atomic.Uint64
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = usagemetrics.ContextWithHandleIfMissing(wrapped.WrappedContext)
+		if err := datastoremw.SetInContext(wrapped.WrappedContext, proxy.NewRowCountingDatastore(datastoremw.MustFromContext(stream.Context()), &queries, &rows)); err != nil {
+			return err
+		}
+		defer func() { accountant.end(wrapped.WrappedContext, caller, rows.Load()) }()
+
+		return handler(srv, wrapped)
+	}
+}
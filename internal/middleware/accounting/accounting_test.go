@@ -0,0 +1,137 @@
+package accounting
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ds, rev := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	accountant := NewAccountant()
+	interceptor := UnaryServerInterceptor(accountant)
+
+	call := func(key, fullMethod string) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer "+key))
+		ctx = datastoremw.ContextWithDatastore(ctx, ds)
+
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: fullMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			it, err := datastoremw.MustFromContext(ctx).SnapshotReader(rev).QueryRelationships(ctx, datastore.RelationshipsFilter{
+				ResourceType:             "document",
+				OptionalResourceRelation: "owner",
+			})
+			if err != nil {
+				return nil, err
+			}
+			defer it.Close()
+			for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			}
+
+			usagemetrics.SetInContext(ctx, &dispatch.ResponseMeta{DispatchCount: 3})
+			return nil, nil
+		})
+		require.NoError(err)
+	}
+
+	call("firstkey", "/authzed.api.v1.PermissionsService/CheckPermission")
+	call("firstkey", "/authzed.api.v1.PermissionsService/CheckPermission")
+	call("secondkey", "/authzed.api.v1.PermissionsService/CheckPermission")
+	call("secondkey", "/grpc.health.v1.Health/Check")
+
+	report := accountant.Report(time.Now())
+	require.Len(report.Callers, 2)
+
+	byCaller := make(map[string]Usage, len(report.Callers))
+	for _, callerUsage := range report.Callers {
+		byCaller[callerUsage.Caller] = callerUsage.Usage
+	}
+
+	first := byCaller[auth.PresharedKeyCaller("firstkey")]
+	second := byCaller[auth.PresharedKeyCaller("secondkey")]
+	require.Equal(uint64(2), first.Requests)
+	require.Equal(uint64(6), first.Dispatches)
+	require.Positive(first.RowsScanned)
+	require.Equal(Usage{Requests: 1, Dispatches: 3, RowsScanned: first.RowsScanned / 2}, second)
+}
+
+func TestExport(t *testing.T) {
+	require := require.New(t)
+
+	path := filepath.Join(t.TempDir(), "usage.jsonl")
+	accountant := NewAccountant()
+
+	accountant.Record("key:first", Usage{Requests: 1, Dispatches: 2, RowsScanned: 3})
+	accountant.Record("key:second", Usage{Requests: 1})
+	require.NoError(accountant.Export(path, time.Now()))
+
+	// Exports without usage since the previous export append nothing.
+	require.NoError(accountant.Export(path, time.Now()))
+
+	accountant.Record("key:first", Usage{Requests: 1})
+	require.NoError(accountant.Export(path, time.Now()))
+
+	f, err := os.Open(path)
+	require.NoError(err)
+	defer f.Close()
+
+	var records []ExportRecord
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var record ExportRecord
+		require.NoError(json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+	require.NoError(scanner.Err())
+
+	require.Len(records, 3)
+	require.Equal("key:first", records[0].Caller)
+	require.Equal(Usage{Requests: 1, Dispatches: 2, RowsScanned: 3}, records[0].Usage)
+	require.Equal("key:second", records[1].Caller)
+	require.Equal("key:first", records[2].Caller)
+	require.Equal(Usage{Requests: 1}, records[2].Usage)
+	require.Equal(records[0].PeriodEnd, records[1].PeriodEnd)
+	require.False(records[2].PeriodStart.Before(records[0].PeriodEnd))
+
+	// The report holds the usage since the accountant started, whatever the exports.
+	report := accountant.Report(time.Now())
+	require.Equal(Usage{Requests: 2, Dispatches: 2, RowsScanned: 3}, report.Callers[0].Usage)
+}
+
+func TestHandler(t *testing.T) {
+	require := require.New(t)
+
+	accountant := NewAccountant()
+	accountant.Record("key:first", Usage{Requests: 1})
+	accountant.Record("key:second", Usage{Requests: 2})
+
+	recorder := httptest.NewRecorder()
+	NewHandler(accountant).ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/usage?caller=key:second", nil))
+	require.Equal(http.StatusOK, recorder.Code)
+
+	var report Report
+	require.NoError(json.Unmarshal(recorder.Body.Bytes(), &report))
+	require.Equal([]CallerUsage{{Caller: "key:second", Usage: Usage{Requests: 2}}}, report.Callers)
+}
@@ -0,0 +1,299 @@
+// Package admission provides middleware throttling the expensive API calls while the datastore
+// is degraded, so that its remaining capacity is left to the cheaper calls such as checks.
+package admission
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/middleware/loadshed"
+	"github.com/authzed/spicedb/internal/middleware/ratelimit"
+)
+
+var (
+	limitGauge = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: "spicedb",
+		Subsystem: "admission",
+		Name:      "expensive_call_limit",
+		Help:      "The number of expensive calls admitted concurrently while the datastore is degraded, or 0 if they are not throttled.",
+	})
+
+	throttledCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "admission",
+		Name:      "throttled_calls_total",
+		Help:      "The expensive calls rejected because the datastore was degraded, by method.",
+	}, []string{"method"})
+)
+
+// DefaultExpensiveMethods are the methods throttled while the datastore is degraded, unless
+// overridden.
+var DefaultExpensiveMethods = []string{
+	"/authzed.api.v1.PermissionsService/LookupResources",
+	"/authzed.api.v1.PermissionsService/LookupSubjects",
+	"/authzed.api.v1.PermissionsService/ExpandPermissionTree",
+}
+
+// minSamples is the number of queries observed in an interval below which the datastore is not
+// considered degraded by its latency or error rate.
+const minSamples = 10
+
+// Option instances control how the middleware is initialized.
+type Option func(*Controller)
+
+// WithLatencyThreshold sets the average latency of the datastore queries above which the
+// datastore is degraded.
+//
+// default: disabled
+func WithLatencyThreshold(latency time.Duration) Option {
+	return func(c *Controller) {
+		c.latencyThreshold = latency
+	}
+}
+
+// WithErrorRateThreshold sets the fraction of failed datastore queries above which the datastore
+// is degraded.
+//
+// default: disabled
+func WithErrorRateThreshold(rate float64) Option {
+	return func(c *Controller) {
+		c.errorRateThreshold = rate
+	}
+}
+
+// WithExpensiveMethods sets the methods throttled while the datastore is degraded. Each method is
+// either the full gRPC method, such as `/authzed.api.v1.PermissionsService/LookupResources`, or
+// only its name, such as `LookupResources`.
+//
+// default: DefaultExpensiveMethods
+func WithExpensiveMethods(methods []string) Option {
+	return func(c *Controller) {
+		c.expensiveMethods = map[string]struct{}{}
+		for _, method := range methods {
+			c.expensiveMethods[method] = struct{}{}
+		}
+	}
+}
+
+// WithMinConcurrency sets the number of expensive calls always admitted concurrently, however
+// degraded the datastore.
+//
+// default: 1
+func WithMinConcurrency(calls int) Option {
+	return func(c *Controller) {
+		c.minConcurrency = calls
+	}
+}
+
+// WithInterval sets the interval over which the datastore queries are measured before the limit
+// of expensive calls is adjusted. The callers of throttled calls are told to retry them after
+// the interval.
+//
+// default: 1s
+func WithInterval(interval time.Duration) Option {
+	return func(c *Controller) {
+		c.interval = interval
+	}
+}
+
+// Controller measures the latency and error rate of the datastore queries and, while the
+// datastore is degraded, limits the number of expensive calls in flight. The limit is halved for
+// every interval the datastore stays degraded, and raised by a quarter for every interval it is
+// healthy, until the expensive calls in flight no longer reach it and it is lifted.
+type Controller struct {
+	latencyThreshold   time.Duration
+	errorRateThreshold float64
+	expensiveMethods   map[string]struct{}
+	minConcurrency     int
+	interval           time.Duration
+
+	mu          sync.Mutex
+	windowStart time.Time
+	queries     int
+	failures    int
+	latency     time.Duration
+	inFlight    int
+
+	// limit is the number of expensive calls admitted concurrently, or 0 if they are not
+	// throttled.
+	limit int
+}
+
+// NewController creates a new controller of the admission of expensive calls.
+func NewController(opts ...Option) *Controller {
+	c := &Controller{
+		minConcurrency: 1,
+		interval:       time.Second,
+	}
+	WithExpensiveMethods(DefaultExpensiveMethods)(c)
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// ObserveQuery records a datastore query which took the given latency, and failed with the
+// given error if not nil.
+func (c *Controller) ObserveQuery(latency time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.queries++
+	c.latency += latency
+	if err != nil {
+		c.failures++
+	}
+}
+
+// Limit returns the number of expensive calls admitted concurrently, or 0 if they are not
+// throttled, after adjusting it if the current interval has ended.
+func (c *Controller) Limit(now time.Time) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.adjustLocked(now)
+	return c.limit
+}
+
+// adjustLocked adjusts the limit of expensive calls to the queries observed in the interval, if
+// it has ended, and starts the next interval.
+func (c *Controller) adjustLocked(now time.Time) {
+	if c.windowStart.IsZero() {
+		c.windowStart = now
+		return
+	}
+	if now.Sub(c.windowStart) < c.interval {
+		return
+	}
+
+	degraded := false
+	if c.queries >= minSamples {
+		averageLatency := c.latency / time.Duration(c.queries)
+		errorRate := float64(c.failures) / float64(c.queries)
+		degraded = (c.latencyThreshold > 0 && averageLatency > c.latencyThreshold) ||
+			(c.errorRateThreshold > 0 && errorRate > c.errorRateThreshold)
+
+		if degraded && c.limit == 0 {
+			log.Warn().
+				Dur("averageLatency", averageLatency).
+				Float64("errorRate", errorRate).
+				Int("inFlight", c.inFlight).
+				Msg("datastore is degraded, throttling expensive calls")
+		}
+	}
+
+	previous := c.limit
+	switch {
+	case degraded && c.limit == 0:
+		c.limit = maxInt(c.minConcurrency, c.inFlight/2)
+	case degraded:
+		c.limit = maxInt(c.minConcurrency, c.limit/2)
+	case c.limit > 0 && c.inFlight < c.limit/2:
+		c.limit = 0
+		log.Info().Msg("datastore recovered, no longer throttling expensive calls")
+	case c.limit > 0:
+		c.limit += int(math.Max(1, float64(c.limit)/4))
+	}
+	if c.limit != previous {
+		limitGauge.Set(float64(c.limit))
+	}
+
+	c.windowStart = now
+	c.queries = 0
+	c.failures = 0
+	c.latency = 0
+}
+
+// expensive returns whether the method is throttled while the datastore is degraded.
+func (c *Controller) expensive(fullMethod string) bool {
+	if _, ok := c.expensiveMethods[fullMethod]; ok {
+		return true
+	}
+	_, ok := c.expensiveMethods[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]
+	return ok
+}
+
+// begin admits the call if it is not an expensive call, or if the expensive calls in flight are
+// below the limit, returning a function to call when the call ends.
+func (c *Controller) begin(ctx context.Context, fullMethod string, now time.Time) (func(), error) {
+	if c == nil || !c.expensive(fullMethod) || loadshed.PriorityFromContext(ctx) == loadshed.Critical {
+		return func() {}, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.adjustLocked(now)
+	if c.limit > 0 && c.inFlight >= c.limit {
+		throttledCounter.WithLabelValues(fullMethod).Inc()
+		return nil, status.Errorf(codes.ResourceExhausted, "the datastore is degraded and expensive calls are throttled: retry after %s seconds", c.retryAfter())
+	}
+
+	c.inFlight++
+	return func() {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.inFlight--
+	}, nil
+}
+
+// retryAfter formats the interval as a number of seconds, rounded up, as in the Retry-After
+// header.
+func (c *Controller) retryAfter() string {
+	return strconv.FormatInt(int64(math.Ceil(c.interval.Seconds())), 10)
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that, if the controller is not
+// nil, rejects the expensive calls throttled while the datastore is degraded with a
+// ResourceExhausted error and a Retry-After response header. Critical calls are never throttled.
+func UnaryServerInterceptor(controller *Controller) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		done, err := controller.begin(ctx, info.FullMethod, time.Now())
+		if err != nil {
+			if err := grpc.SetHeader(ctx, metadata.Pairs(ratelimit.RetryAfterHeader, controller.retryAfter())); err != nil {
+				return nil, err
+			}
+			return nil, err
+		}
+		defer done()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that, if the controller is not
+// nil, rejects the expensive calls throttled while the datastore is degraded with a
+// ResourceExhausted error and a Retry-After response header. Critical calls are never throttled.
+func StreamServerInterceptor(controller *Controller) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		done, err := controller.begin(stream.Context(), info.FullMethod, time.Now())
+		if err != nil {
+			if err := stream.SetHeader(metadata.Pairs(ratelimit.RetryAfterHeader, controller.retryAfter())); err != nil {
+				return err
+			}
+			return err
+		}
+		defer done()
+
+		return handler(srv, stream)
+	}
+}
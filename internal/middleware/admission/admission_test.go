@@ -0,0 +1,111 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/middleware/loadshed"
+)
+
+func observe(c *Controller, queries int, latency time.Duration, failures int) {
+	for i := 0; i < queries; i++ {
+		var err error
+		if i < failures {
+			err = errors.New("connection reset")
+		}
+		c.ObserveQuery(latency, err)
+	}
+}
+
+func TestLimit(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Now()
+	c := NewController(WithLatencyThreshold(100*time.Millisecond), WithErrorRateThreshold(0.2), WithMinConcurrency(2))
+	require.Equal(0, c.Limit(start))
+	c.inFlight = 20
+
+	// Too few queries to tell whether the datastore is degraded.
+	observe(c, 5, time.Second, 5)
+	require.Equal(0, c.Limit(start.Add(time.Second)))
+
+	// A slow datastore throttles the expensive calls to half of those in flight.
+	observe(c, 10, 200*time.Millisecond, 0)
+	require.Equal(0, c.Limit(start.Add(1500*time.Millisecond)))
+	require.Equal(10, c.Limit(start.Add(2*time.Second)))
+
+	// Failing queries keep halving the limit, down to the minimum.
+	observe(c, 10, time.Millisecond, 3)
+	require.Equal(5, c.Limit(start.Add(3*time.Second)))
+	observe(c, 10, time.Millisecond, 3)
+	require.Equal(2, c.Limit(start.Add(4*time.Second)))
+	observe(c, 10, time.Millisecond, 3)
+	require.Equal(2, c.Limit(start.Add(5*time.Second)))
+
+	// A healthy datastore raises the limit while the expensive calls reach it.
+	observe(c, 10, time.Millisecond, 0)
+	require.Equal(3, c.Limit(start.Add(6*time.Second)))
+	observe(c, 10, time.Millisecond, 0)
+	require.Equal(4, c.Limit(start.Add(7*time.Second)))
+
+	// The limit is lifted once the expensive calls no longer reach it.
+	c.inFlight = 1
+	require.Equal(0, c.Limit(start.Add(8*time.Second)))
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	testCases := []struct {
+		name         string
+		method       string
+		priority     loadshed.Priority
+		expectedCode codes.Code
+	}{
+		{"expensive call", "/authzed.api.v1.PermissionsService/LookupResources", loadshed.Normal, codes.ResourceExhausted},
+		{"expensive call by name", "/authzed.api.v1.PermissionsService/ExpandPermissionTree", loadshed.High, codes.ResourceExhausted},
+		{"critical expensive call", "/authzed.api.v1.PermissionsService/LookupResources", loadshed.Critical, codes.OK},
+		{"cheap call", "/authzed.api.v1.PermissionsService/CheckPermission", loadshed.Low, codes.OK},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			c := NewController(
+				WithLatencyThreshold(100*time.Millisecond),
+				WithExpensiveMethods([]string{"/authzed.api.v1.PermissionsService/LookupResources", "ExpandPermissionTree"}),
+			)
+			c.limit = 1
+			c.inFlight = 1
+			c.windowStart = time.Now()
+
+			stream := &testTransportStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			ctx = loadshed.ContextWithPriority(ctx, tc.priority)
+			_, err := UnaryServerInterceptor(c)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			require.Equal(t, 1, c.inFlight)
+			if tc.expectedCode == codes.ResourceExhausted {
+				require.Equal(t, []string{"1"}, stream.header.Get("retry-after"))
+			}
+		})
+	}
+}
+
+type testTransportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *testTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
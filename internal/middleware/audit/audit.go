@@ -0,0 +1,210 @@
+// Package audit provides middleware recording an audit event for every API call.
+package audit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/audit"
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/middleware/consistency"
+	"github.com/authzed/spicedb/pkg/middleware/requestid"
+	jobsv1 "github.com/authzed/spicedb/pkg/proto/jobs/v1"
+)
+
+// UnaryServerInterceptor returns a new unary server interceptor that records an audit event for
+// every call to the given logger.
+func UnaryServerInterceptor(logger *audit.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		logger.Record(NewEvent(ctx, logger.Redaction(), info.FullMethod, req, resp, err, start))
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that records an audit event
+// for every call to the given logger.
+func StreamServerInterceptor(logger *audit.Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		wrapper := &recvWrapper{ServerStream: stream}
+		err := handler(srv, wrapper)
+		logger.Record(NewEvent(stream.Context(), logger.Redaction(), info.FullMethod, wrapper.req, nil, err, start))
+		return err
+	}
+}
+
+// recvWrapper captures the first message received on a stream, which is the request of the
+// server streaming calls of the API.
+type recvWrapper struct {
+	grpc.ServerStream
+	req interface{}
+}
+
+func (s *recvWrapper) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.req == nil {
+		s.req = m
+	}
+	return nil
+}
+
+// NewEvent returns the audit event of a call which started at the given time, with the IDs of its
+// objects redacted as configured.
+func NewEvent(ctx context.Context, redaction audit.Redaction, method string, req, resp interface{}, err error, start time.Time) audit.Event {
+	event := audit.Event{
+		Time:     time.Now(),
+		Method:   method,
+		Code:     status.Code(err).String(),
+		Duration: time.Since(start),
+	}
+
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if requestIDs := md.Get(requestid.RequestIDMetadataKey); len(requestIDs) > 0 {
+			event.RequestID = requestIDs[0]
+		}
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		event.Peer = p.Addr.String()
+	}
+
+	// Only a fingerprint of the key is recorded, so that the log does not hold credentials.
+	if token, err := grpcauth.AuthFromMD(ctx, "bearer"); err == nil && token != "" {
+		event.Caller = audit.Fingerprint(token)
+	}
+	event.Principal = auth.CallerFromContext(ctx)
+
+	switch req := req.(type) {
+	case *v1.CheckPermissionRequest:
+		event.Resources = []string{objectString(redaction, req.Resource, req.Permission)}
+		event.Subject = subjectString(redaction, req.Subject)
+	case *v1.ExpandPermissionTreeRequest:
+		event.Resources = []string{objectString(redaction, req.Resource, req.Permission)}
+	case *v1.LookupResourcesRequest:
+		event.Resources = []string{req.ResourceObjectType + "#" + req.Permission}
+		event.Subject = subjectString(redaction, req.Subject)
+	case *v1.LookupSubjectsRequest:
+		event.Resources = []string{objectString(redaction, req.Resource, req.Permission)}
+		event.Subject = req.SubjectObjectType
+		if req.OptionalSubjectRelation != "" {
+			event.Subject += "#" + req.OptionalSubjectRelation
+		}
+	case *v1.ReadRelationshipsRequest:
+		event.Resources = []string{filterString(redaction, req.RelationshipFilter)}
+	case *v1.WriteRelationshipsRequest:
+		event.Mutating = true
+		event.Resources = make([]string, 0, len(req.Updates))
+		for _, update := range req.Updates {
+			event.Resources = append(event.Resources, fmt.Sprintf("%s %s@%s",
+				update.Operation.String(),
+				objectString(redaction, update.Relationship.GetResource(), update.Relationship.GetRelation()),
+				subjectString(redaction, update.Relationship.GetSubject()),
+			))
+		}
+	case *v1.DeleteRelationshipsRequest:
+		event.Mutating = true
+		event.Resources = []string{filterString(redaction, req.RelationshipFilter)}
+	case *jobsv1.SubmitDeleteJobRequest:
+		event.Mutating = true
+		event.Resources = make([]string, 0, len(req.RelationshipFilters))
+		for _, filter := range req.RelationshipFilters {
+			event.Resources = append(event.Resources, filterString(redaction, filter))
+		}
+	case *jobsv1.CancelDeleteJobRequest:
+		event.Mutating = true
+	case *v1.WriteSchemaRequest:
+		event.Mutating = true
+		// The schema is recorded as its fingerprint, so that the schema written can be identified
+		// without filling the log with its text.
+		event.Resources = []string{"schema:" + audit.Fingerprint(req.Schema)}
+	case *v1.WatchRequest:
+		event.Resources = req.OptionalObjectTypes
+	}
+
+	var revision *v1.ZedToken
+	switch resp := resp.(type) {
+	case *v1.CheckPermissionResponse:
+		revision = resp.CheckedAt
+		switch resp.Permissionship {
+		case v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION:
+			event.Decision = audit.DecisionAllowed
+		case v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION:
+			event.Decision = audit.DecisionDenied
+		case v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION:
+			event.Decision = audit.DecisionConditional
+		}
+	case *v1.ExpandPermissionTreeResponse:
+		revision = resp.ExpandedAt
+	case *v1.WriteRelationshipsResponse:
+		revision = resp.WrittenAt
+	case *v1.DeleteRelationshipsResponse:
+		revision = resp.DeletedAt
+	case *jobsv1.SubmitDeleteJobResponse:
+		revision = resp.SubmittedAt
+	}
+	if revision == nil {
+		if consistency.RevisionFromContext(ctx) != nil {
+			_, revision = consistency.MustRevisionFromContext(ctx)
+		}
+	}
+	if revision != nil {
+		event.Revision = revision.Token
+	}
+
+	return event
+}
+
+func objectString(redaction audit.Redaction, object *v1.ObjectReference, relation string) string {
+	if object == nil {
+		return ""
+	}
+	return fmt.Sprintf("%s:%s#%s", object.ObjectType, redaction.ResourceID(object.ObjectId), relation)
+}
+
+func subjectString(redaction audit.Redaction, subject *v1.SubjectReference) string {
+	if subject.GetObject() == nil {
+		return ""
+	}
+
+	formatted := subject.Object.ObjectType + ":" + redaction.SubjectID(subject.Object.ObjectId)
+	if subject.OptionalRelation != "" {
+		formatted += "#" + subject.OptionalRelation
+	}
+	return formatted
+}
+
+func filterString(redaction audit.Redaction, filter *v1.RelationshipFilter) string {
+	if filter == nil {
+		return ""
+	}
+
+	formatted := filter.ResourceType
+	if filter.OptionalResourceId != "" {
+		formatted += ":" + redaction.ResourceID(filter.OptionalResourceId)
+	}
+	if filter.OptionalRelation != "" {
+		formatted += "#" + filter.OptionalRelation
+	}
+	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil {
+		formatted += "@" + subjectFilter.SubjectType
+		if subjectFilter.OptionalSubjectId != "" {
+			formatted += ":" + redaction.SubjectID(subjectFilter.OptionalSubjectId)
+		}
+		if subjectFilter.OptionalRelation != nil && subjectFilter.OptionalRelation.Relation != "" {
+			formatted += "#" + subjectFilter.OptionalRelation.Relation
+		}
+	}
+	return formatted
+}
@@ -0,0 +1,206 @@
+package audit
+
+import (
+	"context"
+	"net"
+	"sync"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/audit"
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/pkg/middleware/requestid"
+	jobsv1 "github.com/authzed/spicedb/pkg/proto/jobs/v1"
+)
+
+type recordingSink struct {
+	sync.Mutex
+	events []audit.Event
+}
+
+func (rs *recordingSink) Name() string { return "recording" }
+
+func (rs *recordingSink) Write(_ context.Context, events []audit.Event) error {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.events = append(rs.events, events...)
+	return nil
+}
+
+func (rs *recordingSink) Close() error { return nil }
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	testCases := []struct {
+		name      string
+		redaction audit.Redaction
+		req       interface{}
+		resp      interface{}
+		err       error
+		expected  audit.Event
+	}{
+		{
+			"allowed check",
+			audit.Redaction{},
+			&v1.CheckPermissionRequest{
+				Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "masterplan"},
+				Permission: "view",
+				Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}},
+			},
+			&v1.CheckPermissionResponse{
+				CheckedAt:      &v1.ZedToken{Token: "sometoken"},
+				Permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+			},
+			nil,
+			audit.Event{
+				Method:    "/authzed.api.v1.PermissionsService/CheckPermission",
+				Resources: []string{"document:masterplan#view"},
+				Subject:   "user:tom",
+				Decision:  audit.DecisionAllowed,
+				Revision:  "sometoken",
+				Code:      "OK",
+			},
+		},
+		{
+			"redacted check",
+			audit.Redaction{ResourceIDs: true, SubjectIDs: true},
+			&v1.CheckPermissionRequest{
+				Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "masterplan"},
+				Permission: "view",
+				Subject:    &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}},
+			},
+			&v1.CheckPermissionResponse{
+				Permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+			},
+			nil,
+			audit.Event{
+				Method:    "/authzed.api.v1.PermissionsService/CheckPermission",
+				Resources: []string{"document:" + audit.Fingerprint("masterplan") + "#view"},
+				Subject:   "user:" + audit.Fingerprint("tom"),
+				Decision:  audit.DecisionDenied,
+				Code:      "OK",
+			},
+		},
+		{
+			"write",
+			audit.Redaction{},
+			&v1.WriteRelationshipsRequest{
+				Updates: []*v1.RelationshipUpdate{{
+					Operation: v1.RelationshipUpdate_OPERATION_TOUCH,
+					Relationship: &v1.Relationship{
+						Resource: &v1.ObjectReference{ObjectType: "document", ObjectId: "masterplan"},
+						Relation: "viewer",
+						Subject:  &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "group", ObjectId: "eng"}, OptionalRelation: "member"},
+					},
+				}},
+			},
+			nil,
+			status.Error(codes.FailedPrecondition, "precondition failed"),
+			audit.Event{
+				Method:    "/authzed.api.v1.PermissionsService/WriteRelationships",
+				Mutating:  true,
+				Resources: []string{"OPERATION_TOUCH document:masterplan#viewer@group:eng#member"},
+				Code:      "FailedPrecondition",
+			},
+		},
+		{
+			"delete",
+			audit.Redaction{SubjectIDs: true},
+			&v1.DeleteRelationshipsRequest{
+				RelationshipFilter: &v1.RelationshipFilter{
+					ResourceType:          "document",
+					OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: "tom"},
+				},
+			},
+			&v1.DeleteRelationshipsResponse{DeletedAt: &v1.ZedToken{Token: "deletedtoken"}},
+			nil,
+			audit.Event{
+				Method:    "/authzed.api.v1.PermissionsService/DeleteRelationships",
+				Mutating:  true,
+				Resources: []string{"document@user:" + audit.Fingerprint("tom")},
+				Revision:  "deletedtoken",
+				Code:      "OK",
+			},
+		},
+		{
+			"delete job",
+			audit.Redaction{},
+			&jobsv1.SubmitDeleteJobRequest{
+				RelationshipFilters: []*v1.RelationshipFilter{
+					{ResourceType: "document", OptionalRelation: "viewer"},
+					{ResourceType: "folder"},
+				},
+			},
+			&jobsv1.SubmitDeleteJobResponse{SubmittedAt: &v1.ZedToken{Token: "submittedtoken"}},
+			nil,
+			audit.Event{
+				Method:    "/jobs.v1.DeleteJobService/SubmitDeleteJob",
+				Mutating:  true,
+				Resources: []string{"document#viewer", "folder"},
+				Revision:  "submittedtoken",
+				Code:      "OK",
+			},
+		},
+		{
+			"write schema",
+			audit.Redaction{},
+			&v1.WriteSchemaRequest{Schema: "definition user {}"},
+			&v1.WriteSchemaResponse{},
+			nil,
+			audit.Event{
+				Method:    "/authzed.api.v1.SchemaService/WriteSchema",
+				Mutating:  true,
+				Resources: []string{"schema:" + audit.Fingerprint("definition user {}")},
+				Code:      "OK",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			sink := &recordingSink{}
+			logger := audit.NewLogger(audit.Config{SampleRate: 1, Redaction: tc.redaction}, sink)
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(
+				"authorization", "bearer somesecretkey",
+				requestid.RequestIDMetadataKey, "somerequest",
+			))
+			ctx = peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 41234}})
+			interceptor := UnaryServerInterceptor(logger)
+			resp, err := interceptor(ctx, tc.req, &grpc.UnaryServerInfo{FullMethod: tc.expected.Method}, func(context.Context, interface{}) (interface{}, error) {
+				return tc.resp, tc.err
+			})
+			require.Equal(tc.resp, resp)
+			require.Equal(tc.err, err)
+
+			runCtx, cancel := context.WithCancel(context.Background())
+			cancel()
+			require.ErrorIs(logger.Start(runCtx), context.Canceled)
+
+			require.Len(sink.events, 1)
+			event := sink.events[0]
+			require.Equal("somerequest", event.RequestID)
+			require.Equal(audit.Fingerprint("somesecretkey"), event.Caller)
+			require.Equal(auth.PresharedKeyCaller("somesecretkey"), event.Principal)
+			require.Equal("203.0.113.7:41234", event.Peer)
+			require.NotZero(event.Time)
+
+			tc.expected.Time = event.Time
+			tc.expected.Duration = event.Duration
+			tc.expected.RequestID = event.RequestID
+			tc.expected.Caller = event.Caller
+			tc.expected.Principal = event.Principal
+			tc.expected.Peer = event.Peer
+			require.Equal(tc.expected, event)
+		})
+	}
+}
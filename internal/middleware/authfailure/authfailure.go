@@ -0,0 +1,263 @@
+// Package authfailure provides middleware throttling the sources of calls failing
+// authentication, so that credential-stuffing attempts can neither consume the resources of the
+// server nor flood its logs.
+package authfailure
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/audit"
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/clientaddr"
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+const (
+	// SourceClientIP is the kind of the sources of calls identified by their client IP address.
+	SourceClientIP = "ip"
+
+	// SourceKey is the kind of the sources of calls identified by the credential they present.
+	SourceKey = "key"
+)
+
+// maxTrackedSources is the maximum number of sources whose failures are tracked at once, beyond
+// which the failures of new sources are not tracked until tracked sources expire.
+const maxTrackedSources = 100_000
+
+var (
+	failuresCounter = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "auth",
+		Name:      "failures_total",
+		Help:      "The calls which failed authentication.",
+	})
+
+	lockoutsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "auth",
+		Name:      "lockouts_total",
+		Help:      "The sources of calls locked out after too many calls failing authentication, by kind of source.",
+	}, []string{"source"})
+
+	rejectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "auth",
+		Name:      "locked_out_calls_total",
+		Help:      "The calls rejected without authentication because their source was locked out, by kind of source.",
+	}, []string{"source"})
+)
+
+// Config configures the throttling of the sources of calls failing authentication.
+type Config struct {
+	// MaxFailures is the number of calls failing authentication within Window after which their
+	// source is locked out.
+	MaxFailures int
+
+	// Window is the period over which the failures of a source are counted.
+	Window time.Duration
+
+	// Lockout is how long the calls of a source are rejected once it is locked out.
+	Lockout time.Duration
+
+	// LockOutClientIPs is whether client IP addresses are locked out along with credentials. As
+	// every call from a locked out address is rejected, including those with valid credentials
+	// from other clients behind the same proxy or NAT, addresses are only tracked when set.
+	LockOutClientIPs bool
+}
+
+// Throttle counts the calls failing authentication by presented credential and, if enabled, by
+// client IP address, and locks out the sources with too many failures, whose calls are then
+// rejected without being authenticated or logged. Credentials are tracked by their hash, so that
+// neither valid credentials nor distinct tokens sharing a prefix are confused.
+type Throttle struct {
+	config Config
+	logger *audit.Logger
+	now    func() time.Time
+
+	mu       sync.Mutex
+	bySource map[source]*failures
+}
+
+type source struct {
+	kind  string
+	value string
+}
+
+type failures struct {
+	count       int
+	windowStart time.Time
+	lockedUntil time.Time
+}
+
+// NewThrottle creates a new throttle of the sources of calls failing authentication, or returns
+// nil if MaxFailures is not positive. Lockouts are recorded to the audit logger, if not nil.
+func NewThrottle(config Config, logger *audit.Logger) *Throttle {
+	if config.MaxFailures <= 0 {
+		return nil
+	}
+	return &Throttle{
+		config:   config,
+		logger:   logger,
+		now:      time.Now,
+		bySource: map[source]*failures{},
+	}
+}
+
+// AuthFunc returns an auth function which authenticates calls with the given one and records
+// the calls failing authentication against their sources. Only Unauthenticated errors are
+// failures: a PermissionDenied error is returned for authenticated callers, such as those
+// presenting an expired key, which are not guessing credentials.
+func (t *Throttle) AuthFunc(authFunc grpcauth.AuthFunc) grpcauth.AuthFunc {
+	if t == nil {
+		return authFunc
+	}
+	return func(ctx context.Context) (context.Context, error) {
+		newCtx, err := authFunc(ctx)
+		if status.Code(err) == codes.Unauthenticated {
+			t.recordFailure(ctx)
+		}
+		return newCtx, err
+	}
+}
+
+// sources returns the tracked sources of the call in the context.
+func (t *Throttle) sources(ctx context.Context) []source {
+	var found []source
+	if ip := clientaddr.FromContext(ctx); ip != "" && t.config.LockOutClientIPs {
+		found = append(found, source{kind: SourceClientIP, value: ip})
+	}
+	if key, err := grpcauth.AuthFromMD(ctx, "bearer"); err == nil && key != "" {
+		found = append(found, source{kind: SourceKey, value: auth.PresharedKeyCaller(key)})
+	}
+	return found
+}
+
+func (t *Throttle) recordFailure(ctx context.Context) {
+	failuresCounter.Inc()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, s := range t.sources(ctx) {
+		f, ok := t.bySource[s]
+		if !ok {
+			if len(t.bySource) >= maxTrackedSources {
+				t.prune(now)
+				if len(t.bySource) >= maxTrackedSources {
+					continue
+				}
+			}
+			f = &failures{windowStart: now}
+			t.bySource[s] = f
+		}
+
+		if now.Sub(f.windowStart) >= t.config.Window {
+			f.count, f.windowStart = 0, now
+		}
+		f.count++
+		if f.count >= t.config.MaxFailures && !now.Before(f.lockedUntil) {
+			f.lockedUntil = now.Add(t.config.Lockout)
+			t.lockOut(ctx, s)
+		}
+	}
+}
+
+// lockOut reports that the source was locked out. Must be called with the lock held.
+func (t *Throttle) lockOut(ctx context.Context, s source) {
+	lockoutsCounter.WithLabelValues(s.kind).Inc()
+
+	log.Ctx(ctx).Warn().
+		Str("source", s.kind).
+		Str("value", s.value).
+		Int("failures", t.config.MaxFailures).
+		Dur("lockout", t.config.Lockout).
+		Msg("locked out source of calls failing authentication")
+
+	if t.logger != nil {
+		event := audit.Event{
+			Time: t.now(),
+			Type: audit.EventTypeAuthLockout,
+			Code: codes.Unauthenticated.String(),
+		}
+		if s.kind == SourceClientIP {
+			event.Peer = s.value
+		} else {
+			event.Principal = s.value
+		}
+		t.logger.Record(event)
+	}
+}
+
+// prune removes the sources which are neither locked out nor within their window. Must be
+// called with the lock held.
+func (t *Throttle) prune(now time.Time) {
+	for s, f := range t.bySource {
+		if now.Sub(f.windowStart) >= t.config.Window && !now.Before(f.lockedUntil) {
+			delete(t.bySource, s)
+		}
+	}
+}
+
+// lockedOut returns the kind of the source of the call which is locked out, if any, and how long
+// it remains locked out.
+func (t *Throttle) lockedOut(ctx context.Context) (string, time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	for _, s := range t.sources(ctx) {
+		if f, ok := t.bySource[s]; ok && now.Before(f.lockedUntil) {
+			return s.kind, f.lockedUntil.Sub(now), true
+		}
+	}
+	return "", 0, false
+}
+
+// check returns an Unauthenticated error if the source of the call is locked out.
+func (t *Throttle) check(ctx context.Context) error {
+	if t == nil {
+		return nil
+	}
+
+	kind, remaining, ok := t.lockedOut(ctx)
+	if !ok {
+		return nil
+	}
+	rejectedCounter.WithLabelValues(kind).Inc()
+	return status.Errorf(codes.Unauthenticated, "too many calls failed authentication: retry after %d seconds", int64(math.Ceil(remaining.Seconds())))
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that, if the throttle is not nil,
+// rejects the calls of locked out sources with an Unauthenticated error. It must run before the
+// other interceptors, so that the calls it rejects are neither authenticated nor logged.
+func UnaryServerInterceptor(t *Throttle) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := t.check(ctx); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that, if the throttle is not
+// nil, rejects the calls of locked out sources with an Unauthenticated error. It must run before
+// the other interceptors, so that the calls it rejects are neither authenticated nor logged.
+func StreamServerInterceptor(t *Throttle) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := t.check(stream.Context()); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,137 @@
+package authfailure
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func callContext(ip string, key string) context.Context {
+	md := metadata.MD{}
+	if key != "" {
+		md.Set("authorization", "bearer "+key)
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), md)
+	return peer.NewContext(ctx, &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 41234}})
+}
+
+// throttledCall returns a function making a call authenticated by a key of the given throttle,
+// which returns whether the call was handled and its error.
+func throttledCall(throttle *Throttle) func(ctx context.Context) (bool, error) {
+	authFunc := throttle.AuthFunc(func(ctx context.Context) (context.Context, error) {
+		key, err := grpcauth.AuthFromMD(ctx, "bearer")
+		switch {
+		case err != nil:
+			return nil, status.Error(codes.Unauthenticated, "missing key")
+		case key == "validkey-0000":
+			return ctx, nil
+		case key == "expiredkey":
+			return nil, status.Error(codes.PermissionDenied, "expired key")
+		default:
+			return nil, status.Error(codes.Unauthenticated, "invalid key")
+		}
+	})
+	interceptor := UnaryServerInterceptor(throttle)
+	return func(ctx context.Context) (bool, error) {
+		handled := false
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/CheckPermission"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			handled = true
+			_, err := authFunc(ctx)
+			return nil, err
+		})
+		return handled, err
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle := NewThrottle(Config{MaxFailures: 3, Window: time.Minute, Lockout: 5 * time.Minute}, nil)
+	throttle.now = func() time.Time { return now }
+	call := throttledCall(throttle)
+
+	requireHandled := func(ctx context.Context, expectedCode codes.Code) {
+		t.Helper()
+		handled, err := call(ctx)
+		require.True(t, handled)
+		require.Equal(t, expectedCode, status.Code(err))
+	}
+	requireLockedOut := func(ctx context.Context, remaining string) {
+		t.Helper()
+		handled, err := call(ctx)
+		require.False(t, handled, "the call of a locked out source must not be handled")
+		require.ErrorContains(t, err, "too many calls failed authentication: retry after "+remaining+" seconds")
+	}
+
+	// Failures spread over more than the window do not lock out their key.
+	requireHandled(callContext("203.0.113.7", "guess-1"), codes.Unauthenticated)
+	requireHandled(callContext("203.0.113.7", "guess-1"), codes.Unauthenticated)
+	now = now.Add(2 * time.Minute)
+	requireHandled(callContext("203.0.113.7", "guess-1"), codes.Unauthenticated)
+
+	// The third failure within the window locks out the key from any address, but neither the
+	// address nor the other keys sharing its prefix.
+	requireHandled(callContext("203.0.113.7", "guess-1"), codes.Unauthenticated)
+	requireHandled(callContext("203.0.113.7", "guess-1"), codes.Unauthenticated)
+	requireLockedOut(callContext("203.0.113.7", "guess-1"), "300")
+	requireLockedOut(callContext("198.51.100.9", "guess-1"), "300")
+	requireHandled(callContext("203.0.113.7", "validkey-0000"), codes.OK)
+	requireHandled(callContext("203.0.113.7", "guess-2"), codes.Unauthenticated)
+
+	// The key recovers after the lockout.
+	now = now.Add(5 * time.Minute)
+	requireHandled(callContext("203.0.113.7", "guess-1"), codes.Unauthenticated)
+
+	// Calls without a key are not locked out, as their addresses are not tracked, nor are the
+	// keys of authenticated calls denied permission.
+	for index := 0; index < 5; index++ {
+		requireHandled(callContext("203.0.113.7", ""), codes.Unauthenticated)
+		requireHandled(callContext("203.0.113.7", "expiredkey"), codes.PermissionDenied)
+	}
+}
+
+func TestThrottleClientIPs(t *testing.T) {
+	now := time.Date(2023, 1, 1, 12, 0, 0, 0, time.UTC)
+	throttle := NewThrottle(Config{MaxFailures: 3, Window: time.Minute, Lockout: 5 * time.Minute, LockOutClientIPs: true}, nil)
+	throttle.now = func() time.Time { return now }
+	call := throttledCall(throttle)
+
+	for _, key := range []string{"guess-1", "guess-2", ""} {
+		handled, err := call(callContext("203.0.113.7", key))
+		require.True(t, handled)
+		require.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	// The locked out address is rejected even with a valid key, while other addresses are
+	// unaffected.
+	handled, err := call(callContext("203.0.113.7", "validkey-0000"))
+	require.False(t, handled)
+	require.ErrorContains(t, err, "too many calls failed authentication: retry after 300 seconds")
+
+	handled, err = call(callContext("203.0.113.8", "validkey-0000"))
+	require.True(t, handled)
+	require.NoError(t, err)
+
+	now = now.Add(5 * time.Minute)
+	handled, err = call(callContext("203.0.113.7", "validkey-0000"))
+	require.True(t, handled)
+	require.NoError(t, err)
+}
+
+func TestNilThrottle(t *testing.T) {
+	throttle := NewThrottle(Config{}, nil)
+	require.Nil(t, throttle)
+
+	_, err := UnaryServerInterceptor(throttle)(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, nil
+	})
+	require.NoError(t, err)
+}
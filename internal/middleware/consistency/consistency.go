@@ -5,20 +5,38 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/authzed/spicedb/internal/services/shared"
 
+	"github.com/authzed/authzed-go/pkg/requestmeta"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	log "github.com/authzed/spicedb/internal/logging"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
+// RequestFreshnessDeadline, if specified in the request header of a call with at_least_as_fresh
+// consistency, asks SpiceDB to wait at most the given duration for the datastore to reach the
+// revision of the ZedToken, failing with ErrRevisionUnavailable if it does not. Without it, a
+// ZedToken newer than the datastore is used as is, and reads at its revision may block.
+// Value: a duration, such as `250ms`
+const RequestFreshnessDeadline requestmeta.RequestMetadataHeaderKey = "io.spicedb.freshnessdeadline"
+
+// freshnessPollInterval is the interval at which the head revision is polled while waiting for
+// the datastore to reach a requested revision.
+const freshnessPollInterval = 10 * time.Millisecond
+
 type hasConsistency interface {
 	GetConsistency() *v1.Consistency
 }
@@ -29,6 +47,13 @@ var revisionKey ctxKeyType = struct{}{}
 
 var errInvalidZedToken = errors.New("invalid revision requested")
 
+var staleReadFailoversCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "consistency",
+	Name:      "stale_read_failovers_total",
+	Help:      "The number of minimize_latency calls evaluated at the head revision because the datastore lagged beyond the staleness bound.",
+})
+
 type revisionHandle struct {
 	revision datastore.Revision
 }
@@ -57,15 +82,24 @@ func MustRevisionFromContext(ctx context.Context) (datastore.Revision, *v1.ZedTo
 		panic("consistency middleware did not inject revision")
 	}
 
-	return rev, zedtoken.NewFromRevision(rev)
+	return rev, zedtoken.NewFromRevisionForDatastore(rev, datastoremw.UniqueIDFromContext(ctx))
 }
 
 // AddRevisionToContext adds a revision to the given context, based on the consistency block found
 // in the given request (if applicable).
 func AddRevisionToContext(ctx context.Context, req interface{}, ds datastore.Datastore) error {
+	return addRevisionToContext(ctx, req, ds, MinimizeLatency, nil, stalenessBound{})
+}
+
+// addRevisionToContext adds a revision to the given context, based on the consistency block found
+// in the given request, or the given default consistency if the request does not specify one.
+// If a session revision is given, the revision is at least as fresh as it, unless the request
+// asks for an exact snapshot. Minimize latency calls are evaluated at the head revision instead
+// when the datastore lags beyond the staleness bound.
+func addRevisionToContext(ctx context.Context, req interface{}, ds datastore.Datastore, defaultConsistency DefaultConsistency, sessionRevision datastore.Revision, staleness stalenessBound) error {
 	switch req := req.(type) {
 	case hasConsistency:
-		return addRevisionToContextFromConsistency(ctx, req, ds)
+		return addRevisionToContextFromConsistency(ctx, req, ds, defaultConsistency, sessionRevision, staleness)
 	default:
 		return addHeadRevision(ctx, ds)
 	}
@@ -88,7 +122,7 @@ func addHeadRevision(ctx context.Context, ds datastore.Datastore) error {
 
 // addRevisionToContextFromConsistency adds a revision to the given context, based on the consistency block found
 // in the given request (if applicable).
-func addRevisionToContextFromConsistency(ctx context.Context, req hasConsistency, ds datastore.Datastore) error {
+func addRevisionToContextFromConsistency(ctx context.Context, req hasConsistency, ds datastore.Datastore, defaultConsistency DefaultConsistency, sessionRevision datastore.Revision, staleness stalenessBound) error {
 	handle := ctx.Value(revisionKey)
 	if handle == nil {
 		return nil
@@ -96,15 +130,28 @@ func addRevisionToContextFromConsistency(ctx context.Context, req hasConsistency
 
 	var revision datastore.Revision
 	consistency := req.GetConsistency()
+	if consistency == nil && defaultConsistency == FullyConsistent {
+		consistency = &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+	}
 
 	switch {
+	case (consistency == nil || consistency.GetMinimizeLatency()) && staleness.exceeded():
+		// Minimize Latency, while the datastore lags beyond the staleness bound: Use the
+		// datastore's synchronized revision, read from the primary.
+		staleReadFailoversCounter.Inc()
+		databaseRev, err := ds.HeadRevision(ctx)
+		if err != nil {
+			return rewriteDatastoreError(ctx, err)
+		}
+		revision = atLeastSessionRevision(databaseRev, sessionRevision)
+
 	case consistency == nil || consistency.GetMinimizeLatency():
 		// Minimize Latency: Use the datastore's current revision, whatever it may be.
 		databaseRev, err := ds.OptimizedRevision(ctx)
 		if err != nil {
 			return rewriteDatastoreError(ctx, err)
 		}
-		revision = databaseRev
+		revision = atLeastSessionRevision(databaseRev, sessionRevision)
 
 	case consistency.GetFullyConsistent():
 		// Fully Consistent: Use the datastore's synchronized revision.
@@ -117,17 +164,22 @@ func addRevisionToContextFromConsistency(ctx context.Context, req hasConsistency
 	case consistency.GetAtLeastAsFresh() != nil:
 		// At least as fresh as: Pick one of the datastore's revision and that specified, which
 		// ever is later.
-		picked, err := pickBestRevision(ctx, consistency.GetAtLeastAsFresh(), ds)
+		deadline, err := freshnessDeadline(ctx)
+		if err != nil {
+			return err
+		}
+
+		picked, err := pickBestRevision(ctx, consistency.GetAtLeastAsFresh(), ds, deadline)
 		if err != nil {
 			return rewriteDatastoreError(ctx, err)
 		}
-		revision = picked
+		revision = atLeastSessionRevision(picked, sessionRevision)
 
 	case consistency.GetAtExactSnapshot() != nil:
 		// Exact snapshot: Use the revision as encoded in the zed token.
-		requestedRev, err := zedtoken.DecodeRevision(consistency.GetAtExactSnapshot(), ds)
+		requestedRev, err := decodeRevision(ctx, consistency.GetAtExactSnapshot(), ds)
 		if err != nil {
-			return errInvalidZedToken
+			return err
 		}
 
 		err = ds.CheckRevision(ctx, requestedRev)
@@ -152,7 +204,8 @@ var bypassServiceWhitelist = map[string]struct{}{
 
 // UnaryServerInterceptor returns a new unary server interceptor that performs per-request exchange of
 // the specified consistency configuration for the revision at which to perform the request.
-func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+func UnaryServerInterceptor(opts ...Option) grpc.UnaryServerInterceptor {
+	defaults := newDefaults(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 		for bypass := range bypassServiceWhitelist {
 			if strings.HasPrefix(info.FullMethod, bypass) {
@@ -161,31 +214,45 @@ func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 		}
 		ds := datastoremw.MustFromContext(ctx)
 		newCtx := ContextWithHandle(ctx)
-		if err := AddRevisionToContext(newCtx, req, ds); err != nil {
+		if err := addRevisionToContext(newCtx, req, ds, defaults.forCall(ctx, info.FullMethod), defaults.sessionRevision(ctx), defaults.staleness); err != nil {
 			return nil, err
 		}
 
-		return handler(newCtx, req)
+		resp, err := handler(newCtx, req)
+		if err == nil {
+			defaults.recordWrite(ctx, resp, ds)
+		}
+		return resp, err
 	}
 }
 
 // StreamServerInterceptor returns a new stream server interceptor that performs per-request exchange of
 // the specified consistency configuration for the revision at which to perform the request.
-func StreamServerInterceptor() grpc.StreamServerInterceptor {
+func StreamServerInterceptor(opts ...Option) grpc.StreamServerInterceptor {
+	defaults := newDefaults(opts)
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		for bypass := range bypassServiceWhitelist {
 			if strings.HasPrefix(info.FullMethod, bypass) {
 				return handler(srv, stream)
 			}
 		}
-		wrapper := &recvWrapper{stream, ContextWithHandle(stream.Context())}
+		wrapper := &recvWrapper{
+			stream,
+			ContextWithHandle(stream.Context()),
+			defaults.forCall(stream.Context(), info.FullMethod),
+			defaults.sessionRevision(stream.Context()),
+			defaults.staleness,
+		}
 		return handler(srv, wrapper)
 	}
 }
 
 type recvWrapper struct {
 	grpc.ServerStream
-	ctx context.Context
+	ctx                context.Context
+	defaultConsistency DefaultConsistency
+	sessionRevision    datastore.Revision
+	staleness          stalenessBound
 }
 
 func (s *recvWrapper) Context() context.Context {
@@ -198,14 +265,14 @@ func (s *recvWrapper) RecvMsg(m interface{}) error {
 	}
 	ds := datastoremw.MustFromContext(s.ctx)
 
-	if err := AddRevisionToContext(s.ctx, m, ds); err != nil {
+	if err := addRevisionToContext(s.ctx, m, ds, s.defaultConsistency, s.sessionRevision, s.staleness); err != nil {
 		return err
 	}
 
 	return nil
 }
 
-func pickBestRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.Datastore) (datastore.Revision, error) {
+func pickBestRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.Datastore, deadline time.Duration) (datastore.Revision, error) {
 	// Calculate a revision as we see fit
 	databaseRev, err := ds.OptimizedRevision(ctx)
 	if err != nil {
@@ -213,20 +280,133 @@ func pickBestRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.
 	}
 
 	if requested != nil {
-		requestedRev, err := zedtoken.DecodeRevision(requested, ds)
+		requestedRev, err := decodeRevision(ctx, requested, ds)
 		if err != nil {
-			return datastore.NoRevision, errInvalidZedToken
+			return datastore.NoRevision, err
 		}
 
 		if databaseRev.GreaterThan(requestedRev) {
 			return databaseRev, nil
 		}
+
+		if deadline > 0 && !databaseRev.Equal(requestedRev) {
+			if err := waitForRevision(ctx, ds, requestedRev, deadline); err != nil {
+				return datastore.NoRevision, err
+			}
+		}
 		return requestedRev, nil
 	}
 
 	return databaseRev, nil
 }
 
+// atLeastSessionRevision returns the session revision, if any, when it is later than the picked
+// revision, so that the writes of the session are visible; the picked revision otherwise.
+func atLeastSessionRevision(picked datastore.Revision, sessionRevision datastore.Revision) datastore.Revision {
+	if sessionRevision != nil && sessionRevision.GreaterThan(picked) {
+		return sessionRevision
+	}
+	return picked
+}
+
+// decodeRevision decodes the revision of a zedtoken used against the datastore of the call,
+// rejecting the tokens of other datastores.
+func decodeRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.Datastore) (datastore.Revision, error) {
+	requestedRev, err := zedtoken.DecodeRevisionForDatastore(requested, ds, datastoremw.UniqueIDFromContext(ctx))
+	if errors.As(err, &zedtoken.ErrDatastoreMismatch{}) {
+		return datastore.NoRevision, status.Errorf(codes.InvalidArgument, "invalid zedtoken: %s", err)
+	} else if err != nil {
+		return datastore.NoRevision, errInvalidZedToken
+	}
+	return requestedRev, nil
+}
+
+// freshnessDeadline returns the deadline requested in the RequestFreshnessDeadline header, or
+// zero if none.
+func freshnessDeadline(ctx context.Context) (time.Duration, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, nil
+	}
+
+	values := md.Get(string(RequestFreshnessDeadline))
+	if len(values) == 0 {
+		return 0, nil
+	}
+
+	deadline, err := time.ParseDuration(values[0])
+	if err != nil || deadline <= 0 {
+		return 0, status.Errorf(codes.InvalidArgument, "freshness deadline must be a positive duration, got: %q", values[0])
+	}
+	return deadline, nil
+}
+
+// waitForRevision waits for the head revision of the datastore to reach the requested revision,
+// for at most the deadline.
+func waitForRevision(ctx context.Context, ds datastore.Datastore, requested datastore.Revision, deadline time.Duration) error {
+	timer := time.NewTimer(deadline)
+	defer timer.Stop()
+
+	for {
+		headRev, err := ds.HeadRevision(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !requested.GreaterThan(headRev) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			return NewRevisionUnavailableErr(requested, headRev, deadline)
+		case <-time.After(freshnessPollInterval):
+		}
+	}
+}
+
+// ErrRevisionUnavailable occurs when the datastore does not reach the revision of an
+// at_least_as_fresh ZedToken within the requested freshness deadline.
+type ErrRevisionUnavailable struct {
+	error
+	requested datastore.Revision
+	head      datastore.Revision
+	deadline  time.Duration
+}
+
+// NewRevisionUnavailableErr constructs a new revision unavailable error.
+func NewRevisionUnavailableErr(requested, head datastore.Revision, deadline time.Duration) ErrRevisionUnavailable {
+	return ErrRevisionUnavailable{
+		error:     fmt.Errorf("datastore did not reach the requested revision within %s", deadline),
+		requested: requested,
+		head:      head,
+		deadline:  deadline,
+	}
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrRevisionUnavailable) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Stringer("requested", err.requested).Stringer("head", err.head).Dur("deadline", err.deadline)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrRevisionUnavailable) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.FailedPrecondition,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"requested_revision": err.requested.String(),
+				"head_revision":      err.head.String(),
+				"freshness_deadline": err.deadline.String(),
+			},
+		),
+	)
+}
+
 func rewriteDatastoreError(ctx context.Context, err error) error {
 	// Check if the error can be directly used.
 	if _, ok := status.FromError(err); ok {
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"io"
 	"testing"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/testing/testpb"
@@ -13,9 +14,13 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
@@ -98,6 +103,57 @@ func TestAddRevisionToContextAtLeastAsFresh(t *testing.T) {
 	ds.AssertExpectations(t)
 }
 
+func TestAddRevisionToContextFreshnessDeadline(t *testing.T) {
+	testCases := []struct {
+		name          string
+		deadline      string
+		heads         []revision.Decimal
+		expectedCode  codes.Code
+		expectedError string
+	}{
+		{"head already fresh", "100ms", []revision.Decimal{head}, codes.OK, ""},
+		{"head catches up", "1s", []revision.Decimal{optimized, optimized, head}, codes.OK, ""},
+		{"head never fresh", "30ms", []revision.Decimal{optimized}, codes.FailedPrecondition, "did not reach the requested revision"},
+		{"invalid deadline", "soon", nil, codes.InvalidArgument, "must be a positive duration"},
+		{"negative deadline", "-1s", nil, codes.InvalidArgument, "must be a positive duration"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ds := &proxy_test.MockDatastore{}
+			if len(tc.heads) > 0 {
+				ds.On("OptimizedRevision").Return(optimized, nil).Once()
+				ds.On("RevisionFromString", exact.String()).Return(exact, nil).Once()
+				for _, headRev := range tc.heads[:len(tc.heads)-1] {
+					ds.On("HeadRevision").Return(headRev, nil).Once()
+				}
+				ds.On("HeadRevision").Return(tc.heads[len(tc.heads)-1], nil)
+			}
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(string(RequestFreshnessDeadline), tc.deadline))
+			updated := ContextWithHandle(ctx)
+			err := AddRevisionToContext(updated, &v1.CheckPermissionRequest{
+				Consistency: &v1.Consistency{
+					Requirement: &v1.Consistency_AtLeastAsFresh{
+						AtLeastAsFresh: zedtoken.NewFromRevision(exact),
+					},
+				},
+			}, ds)
+			if tc.expectedCode == codes.OK {
+				require.NoError(err)
+				require.True(exact.Equal(RevisionFromContext(updated)))
+			} else {
+				require.Equal(tc.expectedCode, status.Code(err))
+				require.ErrorContains(err, tc.expectedError)
+			}
+			ds.AssertExpectations(t)
+		})
+	}
+}
+
 func TestAddRevisionToContextAtValidExactSnapshot(t *testing.T) {
 	require := require.New(t)
 
@@ -199,3 +255,129 @@ func (s *ConsistencyTestSuite) TestValidPasses_ServerStream() {
 		assert.NoError(s.T(), err, "no error on messages sent occurred")
 	}
 }
+
+func TestDefaultConsistency(t *testing.T) {
+	const checkMethod = "/authzed.api.v1.PermissionsService/CheckPermission"
+
+	opts := []Option{
+		WithMethodDefaultConsistency("CheckPermission", FullyConsistent),
+		WithMethodDefaultConsistency("/authzed.api.v1.PermissionsService/ReadRelationships", FullyConsistent),
+		WithKeyDefaultConsistency("latencykey", MinimizeLatency),
+		WithKeyDefaultConsistency("consistentkey", FullyConsistent),
+	}
+
+	testCases := []struct {
+		name        string
+		opts        []Option
+		method      string
+		key         string
+		consistency *v1.Consistency
+		expected    datastore.Revision
+	}{
+		{"no defaults", nil, checkMethod, "", nil, optimized},
+		{"global default", []Option{WithDefaultConsistency(FullyConsistent)}, checkMethod, "", nil, head},
+		{"method name default", opts, checkMethod, "", nil, head},
+		{"full method default", opts, "/authzed.api.v1.PermissionsService/ReadRelationships", "", nil, head},
+		{"other method", opts, "/authzed.api.v1.PermissionsService/LookupResources", "", nil, optimized},
+		{"key overrides method", opts, checkMethod, "latencykey", nil, optimized},
+		{"key default", opts, "/authzed.api.v1.PermissionsService/LookupResources", "consistentkey", nil, head},
+		{"unknown key", opts, "/authzed.api.v1.PermissionsService/LookupResources", "otherkey", nil, optimized},
+		{
+			"requested consistency wins",
+			opts,
+			checkMethod,
+			"consistentkey",
+			&v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}},
+			optimized,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ds := &proxy_test.MockDatastore{}
+			ds.On("OptimizedRevision").Return(optimized, nil).Maybe()
+			ds.On("HeadRevision").Return(head, nil).Maybe()
+
+			md := metadata.MD{}
+			if tc.key != "" {
+				md.Set("authorization", "bearer "+tc.key)
+			}
+			ctx := datastoremw.ContextWithDatastore(metadata.NewIncomingContext(context.Background(), md), ds)
+
+			var picked datastore.Revision
+			_, err := UnaryServerInterceptor(tc.opts...)(ctx, &v1.CheckPermissionRequest{Consistency: tc.consistency}, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				picked = RevisionFromContext(ctx)
+				return nil, nil
+			})
+			require.NoError(err)
+			require.True(tc.expected.Equal(picked), "expected %s, got %s", tc.expected, picked)
+		})
+	}
+}
+
+func TestMaxStaleness(t *testing.T) {
+	lagOf := func(lag time.Duration, measured bool) func() (time.Duration, bool) {
+		return func() (time.Duration, bool) { return lag, measured }
+	}
+
+	testCases := []struct {
+		name        string
+		opts        []Option
+		consistency *v1.Consistency
+		expected    datastore.Revision
+	}{
+		{"unbounded", nil, nil, optimized},
+		{"within bound", []Option{WithMaxStaleness(5*time.Second, lagOf(time.Second, true))}, nil, optimized},
+		{"lag unknown", []Option{WithMaxStaleness(5*time.Second, lagOf(time.Minute, false))}, nil, optimized},
+		{"beyond bound", []Option{WithMaxStaleness(5*time.Second, lagOf(time.Minute, true))}, nil, head},
+		{
+			"beyond bound with minimize latency",
+			[]Option{WithMaxStaleness(5*time.Second, lagOf(time.Minute, true))},
+			&v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}},
+			head,
+		},
+		{
+			"beyond bound with exact snapshot",
+			[]Option{WithMaxStaleness(5*time.Second, lagOf(time.Minute, true))},
+			&v1.Consistency{Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: zedtoken.NewFromRevision(exact)}},
+			exact,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ds := &proxy_test.MockDatastore{}
+			ds.On("OptimizedRevision").Return(optimized, nil).Maybe()
+			ds.On("HeadRevision").Return(head, nil).Maybe()
+			ds.On("RevisionFromString", exact.String()).Return(exact, nil).Maybe()
+			ds.On("CheckRevision", exact).Return(nil).Maybe()
+
+			ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+			var picked datastore.Revision
+			_, err := UnaryServerInterceptor(tc.opts...)(ctx, &v1.CheckPermissionRequest{Consistency: tc.consistency}, &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/CheckPermission"}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				picked = RevisionFromContext(ctx)
+				return nil, nil
+			})
+			require.NoError(err)
+			require.True(tc.expected.Equal(picked), "expected %s, got %s", tc.expected, picked)
+		})
+	}
+}
+
+func TestParseDefaultConsistency(t *testing.T) {
+	require := require.New(t)
+
+	consistency, err := ParseDefaultConsistency("fully_consistent")
+	require.NoError(err)
+	require.Equal(FullyConsistent, consistency)
+
+	_, err = ParseDefaultConsistency("at_exact_snapshot")
+	require.Error(err)
+}
@@ -0,0 +1,155 @@
+package consistency
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+)
+
+// DefaultConsistency is a consistency used for calls which do not specify one.
+type DefaultConsistency int
+
+const (
+	// MinimizeLatency evaluates calls at the revision of the datastore chosen for its latency.
+	MinimizeLatency DefaultConsistency = iota
+
+	// FullyConsistent evaluates calls at the head revision of the datastore.
+	FullyConsistent
+)
+
+// DefaultConsistencyNames maps the names of default consistencies to the consistencies.
+var DefaultConsistencyNames = map[string]DefaultConsistency{
+	"minimize_latency": MinimizeLatency,
+	"fully_consistent": FullyConsistent,
+}
+
+// ParseDefaultConsistency parses the name of a default consistency.
+func ParseDefaultConsistency(name string) (DefaultConsistency, error) {
+	consistency, ok := DefaultConsistencyNames[name]
+	if !ok {
+		return MinimizeLatency, fmt.Errorf("unknown default consistency %q, expected one of minimize_latency or fully_consistent", name)
+	}
+	return consistency, nil
+}
+
+// Option instances control how the middleware is initialized.
+type Option func(*defaults)
+
+// WithDefaultConsistency sets the consistency of calls which do not specify one, unless
+// overridden for their method or key.
+//
+// default: MinimizeLatency
+func WithDefaultConsistency(consistency DefaultConsistency) Option {
+	return func(d *defaults) {
+		d.consistency = consistency
+	}
+}
+
+// WithMethodDefaultConsistency sets the consistency of calls to a method which do not specify
+// one, unless overridden for their key. The method is either the full gRPC method, such as
+// `/authzed.api.v1.PermissionsService/CheckPermission`, or only its name, such as
+// `CheckPermission`.
+func WithMethodDefaultConsistency(method string, consistency DefaultConsistency) Option {
+	return func(d *defaults) {
+		d.byMethod[method] = consistency
+	}
+}
+
+// WithKeyDefaultConsistency sets the consistency of calls made with a preshared key which do
+// not specify one.
+func WithKeyDefaultConsistency(presharedKey string, consistency DefaultConsistency) Option {
+	return func(d *defaults) {
+		d.byKey[presharedKey] = consistency
+	}
+}
+
+// WithSessions enables read-your-writes sessions, tracked by the given sessions.
+//
+// default: disabled
+func WithSessions(sessions *Sessions) Option {
+	return func(d *defaults) {
+		d.sessions = sessions
+	}
+}
+
+// WithSessionHeader sets the request header naming the read-your-writes session of a call, such
+// as a header holding the ID of the end user on whose behalf the call is made, so that the reads
+// of each end user see their own writes without changes to the app.
+//
+// default: RequestSession
+func WithSessionHeader(header string) Option {
+	return func(d *defaults) {
+		d.sessionHeader = strings.ToLower(header)
+	}
+}
+
+// WithMaxStaleness bounds how far the revision of minimize_latency calls, such as one read from
+// replicas, may trail the head revision of the datastore, according to the last lag returned by
+// the given function. While the lag exceeds the bound, such calls are evaluated at the head
+// revision, read from the primary, instead.
+//
+// default: unbounded
+func WithMaxStaleness(maxStaleness time.Duration, lag func() (time.Duration, bool)) Option {
+	return func(d *defaults) {
+		d.staleness = stalenessBound{maxStaleness: maxStaleness, lag: lag}
+	}
+}
+
+type defaults struct {
+	consistency   DefaultConsistency
+	byMethod      map[string]DefaultConsistency
+	byKey         map[string]DefaultConsistency
+	sessions      *Sessions
+	sessionHeader string
+	staleness     stalenessBound
+}
+
+// stalenessBound bounds the lag of the revision of minimize_latency calls.
+type stalenessBound struct {
+	maxStaleness time.Duration
+	lag          func() (time.Duration, bool)
+}
+
+// exceeded returns whether the datastore was last known to lag beyond the bound.
+func (b stalenessBound) exceeded() bool {
+	if b.maxStaleness <= 0 || b.lag == nil {
+		return false
+	}
+
+	lag, ok := b.lag()
+	return ok && lag > b.maxStaleness
+}
+
+func newDefaults(opts []Option) *defaults {
+	d := &defaults{
+		byMethod:      map[string]DefaultConsistency{},
+		byKey:         map[string]DefaultConsistency{},
+		sessionHeader: string(RequestSession),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// forCall returns the default consistency of a call to the given method.
+func (d *defaults) forCall(ctx context.Context, fullMethod string) DefaultConsistency {
+	if len(d.byKey) > 0 {
+		if key, err := grpcauth.AuthFromMD(ctx, "bearer"); err == nil {
+			if consistency, ok := d.byKey[key]; ok {
+				return consistency
+			}
+		}
+	}
+
+	if consistency, ok := d.byMethod[fullMethod]; ok {
+		return consistency
+	}
+	if consistency, ok := d.byMethod[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]; ok {
+		return consistency
+	}
+	return d.consistency
+}
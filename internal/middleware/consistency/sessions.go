@@ -0,0 +1,170 @@
+package consistency
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// RequestSession, if specified in the request header of a call, names the read-your-writes
+// session of the call, when sessions are enabled and no other session header is configured. The revisions written by calls in a session
+// are tracked, and calls in the session with minimize_latency or at_least_as_fresh consistency
+// are evaluated at a revision at least as fresh as the latest revision written in the session.
+// Sessions are tracked in memory by each SpiceDB node, and so should be routed to the same node.
+// Value: an opaque identifier chosen by the client, such as a user session ID
+const RequestSession requestmeta.RequestMetadataHeaderKey = "io.spicedb.session"
+
+type hasWrittenAt interface {
+	GetWrittenAt() *v1.ZedToken
+}
+
+type hasDeletedAt interface {
+	GetDeletedAt() *v1.ZedToken
+}
+
+// Sessions tracks the latest revision written in each read-your-writes session. Sessions expire
+// once no write has been made in them for their time to live, and the least recently written
+// sessions are dropped once the maximum number of sessions is reached.
+type Sessions struct {
+	ttl         time.Duration
+	maxSessions int
+
+	mu        sync.Mutex
+	bySession map[string]sessionRevision
+}
+
+type sessionRevision struct {
+	revision  datastore.Revision
+	expiresAt time.Time
+}
+
+// NewSessions creates a new tracker of read-your-writes sessions.
+func NewSessions(ttl time.Duration, maxSessions int) *Sessions {
+	return &Sessions{
+		ttl:         ttl,
+		maxSessions: maxSessions,
+		bySession:   map[string]sessionRevision{},
+	}
+}
+
+// Record records that the revision was written in the session.
+func (s *Sessions) Record(session string, revision datastore.Revision) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	existing, ok := s.bySession[session]
+	if ok && now.Before(existing.expiresAt) && existing.revision.GreaterThan(revision) {
+		revision = existing.revision
+	}
+
+	if !ok && len(s.bySession) >= s.maxSessions {
+		s.evict(now)
+	}
+
+	s.bySession[session] = sessionRevision{revision: revision, expiresAt: now.Add(s.ttl)}
+}
+
+// Revision returns the latest revision written in the session, if any.
+func (s *Sessions) Revision(session string) (datastore.Revision, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.bySession[session]
+	if !ok || !time.Now().Before(existing.expiresAt) {
+		return datastore.NoRevision, false
+	}
+	return existing.revision, true
+}
+
+// evict removes the expired sessions or, if none have expired, the session which expires first.
+// Must be called with the lock held.
+func (s *Sessions) evict(now time.Time) {
+	var oldest string
+	var oldestExpiresAt time.Time
+	for session, existing := range s.bySession {
+		if !now.Before(existing.expiresAt) {
+			delete(s.bySession, session)
+			continue
+		}
+
+		if oldest == "" || existing.expiresAt.Before(oldestExpiresAt) {
+			oldest, oldestExpiresAt = session, existing.expiresAt
+		}
+	}
+
+	if len(s.bySession) >= s.maxSessions {
+		delete(s.bySession, oldest)
+	}
+}
+
+// sessionFromContext returns the read-your-writes session of the call, named by the given
+// request header, if any.
+func sessionFromContext(ctx context.Context, header string) (string, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	values := md.Get(header)
+	if len(values) == 0 || values[0] == "" {
+		return "", false
+	}
+	return values[0], true
+}
+
+// sessionRevision returns the latest revision written in the session of the call, if sessions
+// are enabled and the call is made in a session.
+func (d *defaults) sessionRevision(ctx context.Context) datastore.Revision {
+	if d.sessions == nil {
+		return nil
+	}
+
+	session, ok := sessionFromContext(ctx, d.sessionHeader)
+	if !ok {
+		return nil
+	}
+
+	revision, ok := d.sessions.Revision(session)
+	if !ok {
+		return nil
+	}
+	return revision
+}
+
+// recordWrite records the revision written by a call, found in its response, in the session of
+// the call, if sessions are enabled and the call is made in a session.
+func (d *defaults) recordWrite(ctx context.Context, resp interface{}, ds datastore.Datastore) {
+	if d.sessions == nil {
+		return
+	}
+
+	session, ok := sessionFromContext(ctx, d.sessionHeader)
+	if !ok {
+		return
+	}
+
+	var written *v1.ZedToken
+	switch resp := resp.(type) {
+	case hasWrittenAt:
+		written = resp.GetWrittenAt()
+	case hasDeletedAt:
+		written = resp.GetDeletedAt()
+	}
+	if written == nil {
+		return
+	}
+
+	revision, err := zedtoken.DecodeRevision(written, ds)
+	if err != nil {
+		return
+	}
+	d.sessions.Record(session, revision)
+}
@@ -0,0 +1,144 @@
+package consistency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func TestSessions(t *testing.T) {
+	require := require.New(t)
+
+	sessions := NewSessions(time.Hour, 2)
+	_, ok := sessions.Revision("first")
+	require.False(ok)
+
+	sessions.Record("first", exact)
+	sessions.Record("first", optimized)
+	revision, ok := sessions.Revision("first")
+	require.True(ok)
+	require.True(exact.Equal(revision), "an older write must not lower the revision of the session")
+
+	sessions.Record("second", head)
+	sessions.Record("third", optimized)
+	_, ok = sessions.Revision("first")
+	require.False(ok, "the session expiring first must be dropped once at capacity")
+
+	revision, ok = sessions.Revision("second")
+	require.True(ok)
+	require.True(head.Equal(revision))
+
+	expiring := NewSessions(10*time.Millisecond, 10)
+	expiring.Record("first", exact)
+	require.Eventually(func() bool {
+		_, ok := expiring.Revision("first")
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+
+	expiring.Record("first", optimized)
+	revision, ok = expiring.Revision("first")
+	require.True(ok)
+	require.True(optimized.Equal(revision), "an expired session must start over")
+}
+
+func TestSessionsMiddleware(t *testing.T) {
+	const (
+		writeMethod = "/authzed.api.v1.PermissionsService/WriteRelationships"
+		checkMethod = "/authzed.api.v1.PermissionsService/CheckPermission"
+	)
+
+	ds := &proxy_test.MockDatastore{}
+	ds.On("OptimizedRevision").Return(optimized, nil)
+	ds.On("HeadRevision").Return(head, nil)
+	ds.On("RevisionFromString", exact.String()).Return(exact, nil)
+	ds.On("RevisionFromString", zero.String()).Return(zero, nil)
+
+	interceptor := UnaryServerInterceptor(WithSessions(NewSessions(time.Hour, 10)))
+	call := func(session string, method string, req interface{}, resp interface{}) datastore.Revision {
+		md := metadata.MD{}
+		if session != "" {
+			md.Set(string(RequestSession), session)
+		}
+		ctx := datastoremw.ContextWithDatastore(metadata.NewIncomingContext(context.Background(), md), ds)
+
+		var picked datastore.Revision
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			picked = RevisionFromContext(ctx)
+			return resp, nil
+		})
+		require.NoError(t, err)
+		return picked
+	}
+
+	call("first", writeMethod, &v1.WriteRelationshipsRequest{}, &v1.WriteRelationshipsResponse{WrittenAt: zedtoken.NewFromRevision(exact)})
+
+	testCases := []struct {
+		name        string
+		session     string
+		consistency *v1.Consistency
+		expected    datastore.Revision
+	}{
+		{"no session", "", nil, optimized},
+		{"other session", "second", nil, optimized},
+		{"default consistency", "first", nil, exact},
+		{"minimize latency", "first", &v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}}, exact},
+		{"at least as fresh", "first", &v1.Consistency{Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: zedtoken.NewFromRevision(zero)}}, exact},
+		{"fully consistent", "first", &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}, head},
+		{"exact snapshot", "first", &v1.Consistency{Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: zedtoken.NewFromRevision(zero)}}, zero},
+	}
+
+	ds.On("CheckRevision", zero).Return(nil)
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			picked := call(tc.session, checkMethod, &v1.CheckPermissionRequest{Consistency: tc.consistency}, &v1.CheckPermissionResponse{})
+			require.True(t, tc.expected.Equal(picked), "expected %s, got %s", tc.expected, picked)
+		})
+	}
+}
+
+func TestSessionHeader(t *testing.T) {
+	const (
+		writeMethod = "/authzed.api.v1.PermissionsService/WriteRelationships"
+		checkMethod = "/authzed.api.v1.PermissionsService/CheckPermission"
+	)
+
+	ds := &proxy_test.MockDatastore{}
+	ds.On("OptimizedRevision").Return(optimized, nil)
+	ds.On("HeadRevision").Return(head, nil)
+	ds.On("RevisionFromString", exact.String()).Return(exact, nil)
+
+	interceptor := UnaryServerInterceptor(WithSessions(NewSessions(time.Hour, 10)), WithSessionHeader("X-End-User"))
+	call := func(md metadata.MD, method string, req interface{}, resp interface{}) datastore.Revision {
+		ctx := datastoremw.ContextWithDatastore(metadata.NewIncomingContext(context.Background(), md), ds)
+
+		var picked datastore.Revision
+		_, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, _ interface{}) (interface{}, error) {
+			picked = RevisionFromContext(ctx)
+			return resp, nil
+		})
+		require.NoError(t, err)
+		return picked
+	}
+
+	call(metadata.Pairs("x-end-user", "alice"), writeMethod, &v1.WriteRelationshipsRequest{}, &v1.WriteRelationshipsResponse{WrittenAt: zedtoken.NewFromRevision(exact)})
+
+	picked := call(metadata.Pairs("x-end-user", "alice"), checkMethod, &v1.CheckPermissionRequest{}, &v1.CheckPermissionResponse{})
+	require.True(t, exact.Equal(picked), "the reads of the end user must see their writes")
+
+	picked = call(metadata.Pairs("x-end-user", "bob"), checkMethod, &v1.CheckPermissionRequest{}, &v1.CheckPermissionResponse{})
+	require.True(t, optimized.Equal(picked), "the reads of other end users must not be upgraded")
+
+	picked = call(metadata.Pairs(string(RequestSession), "alice"), checkMethod, &v1.CheckPermissionRequest{}, &v1.CheckPermissionResponse{})
+	require.True(t, optimized.Equal(picked), "the default session header must be ignored once another is configured")
+}
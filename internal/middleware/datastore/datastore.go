@@ -2,10 +2,12 @@ package datastore
 
 import (
 	"context"
+	"sync"
 
 	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
 	"google.golang.org/grpc"
 
+	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/pkg/datastore"
 )
 
@@ -15,6 +17,37 @@ var datastoreKey ctxKeyType = struct{}{}
 
 type datastoreHandle struct {
 	datastore datastore.Datastore
+	uniqueID  *uniqueID
+}
+
+// uniqueID reads the unique ID of a datastore on first use and caches it, as it never changes.
+// A failed read is retried on the next use, so that the datastore being unavailable at startup
+// does not leave it unknown.
+type uniqueID struct {
+	datastore datastore.Datastore
+
+	lock sync.Mutex
+	id   string
+}
+
+func (u *uniqueID) get(ctx context.Context) string {
+	u.lock.Lock()
+	id := u.id
+	u.lock.Unlock()
+	if id != "" {
+		return id
+	}
+
+	id, err := u.datastore.UniqueID(ctx)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("unable to read the unique ID of the datastore")
+		return ""
+	}
+
+	u.lock.Lock()
+	u.id = id
+	u.lock.Unlock()
+	return id
 }
 
 // ContextWithHandle adds a placeholder to a context that will later be
@@ -43,6 +76,19 @@ func MustFromContext(ctx context.Context) datastore.Datastore {
 	return datastore
 }
 
+// UniqueIDFromContext returns the unique ID of the datastore selected in the context, or an
+// empty string if it is unknown, which is the case when the datastore was not added by the
+// middleware or its unique ID could not be read.
+func UniqueIDFromContext(ctx context.Context) string {
+	if c := ctx.Value(datastoreKey); c != nil {
+		handle := c.(*datastoreHandle)
+		if handle.uniqueID != nil {
+			return handle.uniqueID.get(ctx)
+		}
+	}
+	return ""
+}
+
 // SetInContext adds a datastore to the given context
 func SetInContext(ctx context.Context, datastore datastore.Datastore) error {
 	handle := ctx.Value(datastoreKey)
@@ -61,11 +107,9 @@ func ContextWithDatastore(ctx context.Context, datastore datastore.Datastore) co
 // UnaryServerInterceptor returns a new unary server interceptor that adds the
 // datastore to the context
 func UnaryServerInterceptor(datastore datastore.Datastore) grpc.UnaryServerInterceptor {
+	id := &uniqueID{datastore: datastore}
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
-		newCtx := ContextWithHandle(ctx)
-		if err := SetInContext(newCtx, datastore); err != nil {
-			return nil, err
-		}
+		newCtx := context.WithValue(ctx, datastoreKey, &datastoreHandle{datastore: datastore, uniqueID: id})
 
 		return handler(newCtx, req)
 	}
@@ -74,12 +118,10 @@ func UnaryServerInterceptor(datastore datastore.Datastore) grpc.UnaryServerInter
 // StreamServerInterceptor returns a new stream server interceptor that adds the
 // datastore to the context
 func StreamServerInterceptor(datastore datastore.Datastore) grpc.StreamServerInterceptor {
+	id := &uniqueID{datastore: datastore}
 	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 		wrapped := middleware.WrapServerStream(stream)
-		wrapped.WrappedContext = ContextWithHandle(wrapped.WrappedContext)
-		if err := SetInContext(wrapped.WrappedContext, datastore); err != nil {
-			return err
-		}
+		wrapped.WrappedContext = context.WithValue(wrapped.WrappedContext, datastoreKey, &datastoreHandle{datastore: datastore, uniqueID: id})
 		return handler(srv, wrapped)
 	}
 }
@@ -0,0 +1,102 @@
+// Package disabledmethods provides middleware rejecting the calls to the methods of the API which
+// are disabled, so that locked-down deployments can restrict the API to the methods they use.
+package disabledmethods
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// apiServices are the services of the API whose methods can be disabled.
+var apiServices = []grpc.ServiceDesc{
+	v1.PermissionsService_ServiceDesc,
+	v1.SchemaService_ServiceDesc,
+	v1.WatchService_ServiceDesc,
+}
+
+// Methods is the set of the full gRPC methods which are disabled.
+type Methods map[string]struct{}
+
+// Parse parses the methods to disable, each either a method, as its full gRPC method such as
+// `/authzed.api.v1.PermissionsService/DeleteRelationships` or only its name such as
+// `DeleteRelationships`, or a whole service, as its full name such as
+// `authzed.api.v1.SchemaService` or only its name such as `SchemaService`. It returns nil if no
+// method is disabled.
+func Parse(names []string) (Methods, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	methodsByName := make(map[string][]string)
+	for _, service := range apiServices {
+		var serviceMethods []string
+		for _, method := range service.Methods {
+			serviceMethods = append(serviceMethods, "/"+service.ServiceName+"/"+method.MethodName)
+		}
+		for _, stream := range service.Streams {
+			serviceMethods = append(serviceMethods, "/"+service.ServiceName+"/"+stream.StreamName)
+		}
+
+		shortServiceName := service.ServiceName[len("authzed.api.v1."):]
+		methodsByName[service.ServiceName] = serviceMethods
+		methodsByName[shortServiceName] = serviceMethods
+		for _, fullMethod := range serviceMethods {
+			shortMethod := fullMethod[len(service.ServiceName)+2:]
+			methodsByName[fullMethod] = []string{fullMethod}
+			methodsByName[shortMethod] = append(methodsByName[shortMethod], fullMethod)
+		}
+	}
+
+	disabled := make(Methods)
+	for _, name := range names {
+		fullMethods, ok := methodsByName[name]
+		if !ok {
+			known := make([]string, 0, len(methodsByName))
+			for knownName := range methodsByName {
+				known = append(known, knownName)
+			}
+			sort.Strings(known)
+			return nil, fmt.Errorf("unknown API method or service %q to disable, expected one of %v", name, known)
+		}
+		for _, fullMethod := range fullMethods {
+			disabled[fullMethod] = struct{}{}
+		}
+	}
+	return disabled, nil
+}
+
+// check returns an Unimplemented error if the method is disabled.
+func (m Methods) check(fullMethod string) error {
+	if _, ok := m[fullMethod]; ok {
+		return status.Errorf(codes.Unimplemented, "method %s is disabled on this server", fullMethod)
+	}
+	return nil
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that rejects the calls to the
+// disabled methods with an Unimplemented error.
+func UnaryServerInterceptor(disabled Methods) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := disabled.check(info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that rejects the calls to the
+// disabled methods with an Unimplemented error.
+func StreamServerInterceptor(disabled Methods) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := disabled.check(info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
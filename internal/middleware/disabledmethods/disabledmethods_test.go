@@ -0,0 +1,86 @@
+package disabledmethods
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		name        string
+		names       []string
+		expected    []string
+		expectedErr string
+	}{
+		{"nothing disabled", nil, nil, ""},
+		{"short method name", []string{"DeleteRelationships"}, []string{"/authzed.api.v1.PermissionsService/DeleteRelationships"}, ""},
+		{
+			"full method name",
+			[]string{"/authzed.api.v1.PermissionsService/ExpandPermissionTree"},
+			[]string{"/authzed.api.v1.PermissionsService/ExpandPermissionTree"},
+			"",
+		},
+		{
+			"short service name",
+			[]string{"SchemaService"},
+			[]string{"/authzed.api.v1.SchemaService/ReadSchema", "/authzed.api.v1.SchemaService/WriteSchema"},
+			"",
+		},
+		{"full service name", []string{"authzed.api.v1.WatchService"}, []string{"/authzed.api.v1.WatchService/Watch"}, ""},
+		{"unknown method", []string{"ExportBulkRelationships"}, nil, "unknown API method or service \"ExportBulkRelationships\""},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			disabled, err := Parse(tc.names)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			var methods []string
+			for method := range disabled {
+				methods = append(methods, method)
+			}
+			require.ElementsMatch(t, tc.expected, methods)
+		})
+	}
+}
+
+func TestInterceptors(t *testing.T) {
+	disabled, err := Parse([]string{"DeleteRelationships", "Watch"})
+	require.NoError(t, err)
+
+	unary := UnaryServerInterceptor(disabled)
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	_, err = unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/DeleteRelationships"}, handler)
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+
+	resp, err := unary(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/CheckPermission"}, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+
+	stream := StreamServerInterceptor(disabled)
+	streamHandler := func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	}
+
+	err = stream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/authzed.api.v1.WatchService/Watch"}, streamHandler)
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+
+	err = stream(nil, nil, &grpc.StreamServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/LookupResources"}, streamHandler)
+	require.NoError(t, err)
+
+	_, err = UnaryServerInterceptor(nil)(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/DeleteRelationships"}, handler)
+	require.NoError(t, err)
+}
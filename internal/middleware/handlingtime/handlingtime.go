@@ -0,0 +1,71 @@
+// Package handlingtime provides middleware recording the handling time of gRPC calls in the
+// `grpc_server_handling_seconds` histogram, with the IDs of the traces of sampled calls attached
+// as exemplars, so that the latency of the API and dispatch servers can be followed to the
+// traces of representative calls.
+package handlingtime
+
+import (
+	"context"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+// TraceIDLabel is the label of exemplars holding the ID of their trace.
+const TraceIDLabel = "trace_id"
+
+// handlingTimeHistogram has the name, labels and buckets of the histogram of
+// go-grpc-prometheus, which cannot attach exemplars, so that it replaces it in existing
+// dashboards.
+var handlingTimeHistogram = promauto.NewHistogramVec(prometheus.HistogramOpts{
+	Name:    "grpc_server_handling_seconds",
+	Help:    "Histogram of response latency (seconds) of gRPC that had been application-level handled by the server.",
+	Buckets: []float64{.006, .010, .018, .024, .032, .042, .056, .075, .100, .178, .316, .562, 1.000},
+}, []string{"grpc_type", "grpc_service", "grpc_method"})
+
+// TraceExemplar returns the exemplar labels of the trace of the context, or nil if it has no
+// sampled trace.
+func TraceExemplar(ctx context.Context) prometheus.Labels {
+	spanCtx := trace.SpanContextFromContext(ctx)
+	if !spanCtx.IsValid() || !spanCtx.IsSampled() {
+		return nil
+	}
+	return prometheus.Labels{TraceIDLabel: spanCtx.TraceID().String()}
+}
+
+type reporter struct{}
+
+func (r *reporter) ServerReporter(ctx context.Context, callMeta interceptors.CallMeta) (interceptors.Reporter, context.Context) {
+	return &serverReporter{ctx: ctx, callMeta: callMeta}, ctx
+}
+
+type serverReporter struct {
+	interceptors.NoopReporter
+	ctx      context.Context
+	callMeta interceptors.CallMeta
+}
+
+func (r *serverReporter) PostCall(_ error, duration time.Duration) {
+	observer := handlingTimeHistogram.WithLabelValues(string(r.callMeta.Typ), r.callMeta.Service, r.callMeta.Method)
+	if exemplar := TraceExemplar(r.ctx); exemplar != nil {
+		observer.(prometheus.ExemplarObserver).ObserveWithExemplar(duration.Seconds(), exemplar)
+		return
+	}
+	observer.Observe(duration.Seconds())
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that records the handling time
+// of calls. It must run after the interceptor starting their traces.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return interceptors.UnaryServerInterceptor(&reporter{})
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that records the handling time
+// of calls. It must run after the interceptor starting their traces.
+func StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return interceptors.StreamServerInterceptor(&reporter{})
+}
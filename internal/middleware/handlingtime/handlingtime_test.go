@@ -0,0 +1,80 @@
+package handlingtime
+
+import (
+	"context"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+)
+
+func TestHandlingTimeExemplars(t *testing.T) {
+	traceID, err := trace.TraceIDFromHex("4bf92f3577b34da6a3ce929d0e0e4736")
+	require.NoError(t, err)
+	spanID, err := trace.SpanIDFromHex("00f067aa0ba902b7")
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name             string
+		flags            trace.TraceFlags
+		method           string
+		expectedExemplar bool
+	}{
+		{"sampled trace", trace.FlagsSampled, "CheckPermission", true},
+		{"unsampled trace", 0, "ExpandPermissionTree", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := trace.ContextWithSpanContext(context.Background(), trace.NewSpanContext(trace.SpanContextConfig{
+				TraceID:    traceID,
+				SpanID:     spanID,
+				TraceFlags: tc.flags,
+			}))
+
+			_, err := UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/" + tc.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			families, err := prometheus.DefaultGatherer.Gather()
+			require.NoError(t, err)
+
+			var exemplarTraceIDs []string
+			var count uint64
+			for _, family := range families {
+				if family.GetName() != "grpc_server_handling_seconds" {
+					continue
+				}
+				for _, m := range family.Metric {
+					labels := map[string]string{}
+					for _, label := range m.Label {
+						labels[label.GetName()] = label.GetValue()
+					}
+					if labels["grpc_method"] != tc.method {
+						continue
+					}
+					require.Equal(t, "unary", labels["grpc_type"])
+					require.Equal(t, "authzed.api.v1.PermissionsService", labels["grpc_service"])
+
+					count += m.GetHistogram().GetSampleCount()
+					for _, b := range m.GetHistogram().Bucket {
+						if e := b.GetExemplar(); e != nil {
+							exemplarTraceIDs = append(exemplarTraceIDs, e.Label[0].GetValue())
+						}
+					}
+				}
+			}
+
+			require.Equal(t, uint64(1), count)
+			if tc.expectedExemplar {
+				require.Equal(t, []string{traceID.String()}, exemplarTraceIDs)
+			} else {
+				require.Empty(t, exemplarTraceIDs)
+			}
+		})
+	}
+}
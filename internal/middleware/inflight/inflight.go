@@ -0,0 +1,130 @@
+// Package inflight provides middleware tracking the calls being handled by the server, such as
+// to introspect the calls in flight while debugging.
+package inflight
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/auth"
+)
+
+// Call is a call being handled.
+type Call struct {
+	Method  string    `json:"method"`
+	Caller  string    `json:"caller,omitempty"`
+	Stream  bool      `json:"stream"`
+	Started time.Time `json:"started"`
+}
+
+// Tracker tracks the calls being handled. It is internally synchronized.
+type Tracker struct {
+	mu     sync.Mutex
+	nextID uint64
+	calls  map[uint64]Call
+}
+
+// NewTracker creates a new tracker of the calls being handled.
+func NewTracker() *Tracker {
+	return &Tracker{calls: map[uint64]Call{}}
+}
+
+// begin tracks a call until the returned function is called.
+func (t *Tracker) begin(call Call) func() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	id := t.nextID
+	t.nextID++
+	t.calls[id] = call
+
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.calls, id)
+	}
+}
+
+// Calls returns the calls being handled, in the order in which they started.
+func (t *Tracker) Calls() []Call {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ids := make([]uint64, 0, len(t.calls))
+	for id := range t.calls {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	calls := make([]Call, 0, len(ids))
+	for _, id := range ids {
+		calls = append(calls, t.calls[id])
+	}
+	return calls
+}
+
+// CountsByMethod returns the number of calls being handled for each method.
+func (t *Tracker) CountsByMethod() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	counts := make(map[string]int, len(t.calls))
+	for _, call := range t.calls {
+		counts[call.Method]++
+	}
+	return counts
+}
+
+var bypassServiceWhitelist = map[string]struct{}{
+	"/grpc.reflection.v1alpha.ServerReflection/": {},
+	"/grpc.health.v1.Health/":                    {},
+}
+
+func bypassed(fullMethod string) bool {
+	for bypass := range bypassServiceWhitelist {
+		if strings.HasPrefix(fullMethod, bypass) {
+			return true
+		}
+	}
+	return false
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that tracks the calls being
+// handled. The interceptor must follow the auth middleware for the callers to be known.
+func UnaryServerInterceptor(tracker *Tracker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if tracker == nil || bypassed(info.FullMethod) {
+			return handler(ctx, req)
+		}
+
+		defer tracker.begin(Call{
+			Method:  info.FullMethod,
+			Caller:  auth.CallerFromContext(ctx),
+			Started: time.Now(),
+		})()
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that tracks the calls being
+// handled. The interceptor must follow the auth middleware for the callers to be known.
+func StreamServerInterceptor(tracker *Tracker) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if tracker == nil || bypassed(info.FullMethod) {
+			return handler(srv, stream)
+		}
+
+		defer tracker.begin(Call{
+			Method:  info.FullMethod,
+			Caller:  auth.CallerFromContext(stream.Context()),
+			Stream:  true,
+			Started: time.Now(),
+		})()
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,52 @@
+package inflight
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/auth"
+)
+
+type testServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s testServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestInterceptors(t *testing.T) {
+	require := require.New(t)
+
+	tracker := NewTracker()
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer somekey"))
+
+	const checkMethod = "/authzed.api.v1.PermissionsService/CheckPermission"
+	const watchMethod = "/authzed.api.v1.WatchService/Watch"
+
+	err := StreamServerInterceptor(tracker)(nil, testServerStream{ctx: ctx}, &grpc.StreamServerInfo{FullMethod: watchMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		_, err := UnaryServerInterceptor(tracker)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: checkMethod}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, err := UnaryServerInterceptor(tracker)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				require.Equal(map[string]int{checkMethod: 1, watchMethod: 1}, tracker.CountsByMethod())
+
+				calls := tracker.Calls()
+				require.Len(calls, 2)
+				require.Equal(watchMethod, calls[0].Method)
+				require.True(calls[0].Stream)
+				require.Equal(auth.PresharedKeyCaller("somekey"), calls[0].Caller)
+				require.Equal(checkMethod, calls[1].Method)
+				require.False(calls[1].Stream)
+				return nil, nil
+			})
+			return nil, err
+		})
+		return err
+	})
+	require.NoError(err)
+	require.Empty(tracker.Calls())
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package loadshed
+
+import "time"
+
+// processCPUTime returns false, as the CPU time of the process is only measured on Unix systems.
+func processCPUTime() (time.Duration, bool) {
+	return 0, false
+}
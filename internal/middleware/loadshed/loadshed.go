@@ -0,0 +1,357 @@
+// Package loadshed provides middleware propagating the priority of API calls through dispatch,
+// and shedding the calls of the lowest priorities first when the node is overloaded.
+package loadshed
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/middleware/ratelimit"
+)
+
+// RequestPriority, if specified in the request header of a call, sets the priority of the call
+// and of the dispatches made to answer it. When the node is overloaded, the calls of the lowest
+// priorities are shed first. Calls without a priority have the `normal` priority.
+// Value: one of `low`, `normal`, `high` or `critical`
+const RequestPriority requestmeta.RequestMetadataHeaderKey = "io.spicedb.priority"
+
+var shedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "loadshed",
+	Name:      "shed_calls_total",
+	Help:      "The calls rejected because the node was overloaded, by priority.",
+}, []string{"priority"})
+
+// Priority is the priority of a call.
+type Priority int
+
+const (
+	// Low is the priority of calls which are shed first, such as those of batch jobs.
+	Low Priority = iota
+
+	// Normal is the priority of calls which do not specify one.
+	Normal
+
+	// High is the priority of calls which are shed only under heavy overload.
+	High
+
+	// Critical is the priority of calls which are never shed.
+	Critical
+)
+
+var priorityNames = map[string]Priority{
+	"low":      Low,
+	"normal":   Normal,
+	"high":     High,
+	"critical": Critical,
+}
+
+// shedLoadByPriority is the load, relative to the thresholds, from which the calls of each
+// priority are shed. Critical calls are never shed.
+var shedLoadByPriority = map[Priority]float64{
+	Low:    1,
+	Normal: 1.25,
+	High:   1.5,
+}
+
+// ParsePriority parses a priority, which is one of `low`, `normal`, `high` or `critical`.
+func ParsePriority(value string) (Priority, error) {
+	priority, ok := priorityNames[value]
+	if !ok {
+		return Normal, fmt.Errorf("unknown priority %q, expected one of low, normal, high or critical", value)
+	}
+	return priority, nil
+}
+
+func (p Priority) String() string {
+	for name, priority := range priorityNames {
+		if priority == p {
+			return name
+		}
+	}
+	return strconv.Itoa(int(p))
+}
+
+type priorityKey struct{}
+
+// ContextWithPriority returns a context holding the priority of the call.
+func ContextWithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFromContext returns the priority of the call, which is Normal if unspecified.
+func PriorityFromContext(ctx context.Context) Priority {
+	if priority, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return priority
+	}
+	return Normal
+}
+
+// Option instances control how the middleware is initialized.
+type Option func(*Shedder)
+
+// WithCPUThreshold sets the fraction of the CPUs available to the process, as set by GOMAXPROCS,
+// used by the node above which it is overloaded. The CPU usage is only measured on Unix systems.
+//
+// default: disabled
+func WithCPUThreshold(fraction float64) Option {
+	return func(s *Shedder) {
+		s.cpuThreshold = fraction
+	}
+}
+
+// WithGoroutineThreshold sets the number of goroutines of the node above which it is overloaded.
+//
+// default: disabled
+func WithGoroutineThreshold(goroutines int) Option {
+	return func(s *Shedder) {
+		s.goroutineThreshold = goroutines
+	}
+}
+
+// WithInFlightThreshold sets the number of calls in flight on the node, including dispatches,
+// above which it is overloaded.
+//
+// default: disabled
+func WithInFlightThreshold(calls int) Option {
+	return func(s *Shedder) {
+		s.inFlightThreshold = calls
+	}
+}
+
+// WithRetryAfter sets the time after which the callers of shed calls are told to retry them.
+//
+// default: 1s
+func WithRetryAfter(retryAfter time.Duration) Option {
+	return func(s *Shedder) {
+		s.retryAfter = retryAfter
+	}
+}
+
+// WithSampleInterval sets the minimum interval between the measurements of the CPU usage and
+// goroutines of the node.
+//
+// default: 1s
+func WithSampleInterval(interval time.Duration) Option {
+	return func(s *Shedder) {
+		s.sampleInterval = interval
+	}
+}
+
+// Shedder measures the load of the node against its thresholds, and sheds the calls of the
+// lowest priorities first when the load exceeds them.
+type Shedder struct {
+	cpuThreshold       float64
+	goroutineThreshold int
+	inFlightThreshold  int
+	retryAfter         time.Duration
+	sampleInterval     time.Duration
+
+	// cpuTime and goroutines measure the node, and are replaced in tests.
+	cpuTime    func() (time.Duration, bool)
+	goroutines func() int
+
+	inFlight atomic.Int64
+
+	mu             sync.Mutex
+	sampledAt      time.Time
+	sampledCPUTime time.Duration
+	sampledLoad    float64
+}
+
+// NewShedder creates a new shedder of calls.
+func NewShedder(opts ...Option) *Shedder {
+	s := &Shedder{
+		retryAfter:     time.Second,
+		sampleInterval: time.Second,
+		cpuTime:        processCPUTime,
+		goroutines:     runtime.NumGoroutine,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Load returns the load of the node relative to its thresholds: the highest ratio of a
+// measurement to its threshold, so that the node is overloaded from a load of 1.
+func (s *Shedder) Load(now time.Time) float64 {
+	load := s.sample(now)
+	if s.inFlightThreshold > 0 {
+		load = math.Max(load, float64(s.inFlight.Load())/float64(s.inFlightThreshold))
+	}
+	return load
+}
+
+// sample returns the load of the CPU usage and goroutines of the node, measured at most once per
+// sample interval.
+func (s *Shedder) sample(now time.Time) float64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.sampledAt.IsZero() && now.Sub(s.sampledAt) < s.sampleInterval {
+		return s.sampledLoad
+	}
+
+	var load float64
+	if s.goroutineThreshold > 0 {
+		load = float64(s.goroutines()) / float64(s.goroutineThreshold)
+	}
+	if s.cpuThreshold > 0 {
+		if cpuTime, ok := s.cpuTime(); ok {
+			if !s.sampledAt.IsZero() && now.After(s.sampledAt) {
+				usage := float64(cpuTime-s.sampledCPUTime) / float64(now.Sub(s.sampledAt)) / float64(runtime.GOMAXPROCS(0))
+				load = math.Max(load, usage/s.cpuThreshold)
+			}
+			s.sampledCPUTime = cpuTime
+		}
+	}
+
+	s.sampledAt = now
+	s.sampledLoad = load
+	return load
+}
+
+// admit returns a ResourceExhausted error if a call of the given priority must be shed under the
+// current load.
+func (s *Shedder) admit(priority Priority, now time.Time) error {
+	shedLoad, ok := shedLoadByPriority[priority]
+	if !ok {
+		return nil
+	}
+
+	if load := s.Load(now); load >= shedLoad {
+		shedCounter.WithLabelValues(priority.String()).Inc()
+		return status.Errorf(codes.ResourceExhausted, "the node is overloaded and shed the call of %s priority: retry after %s seconds", priority, ratelimit.FormatSeconds(s.retryAfter))
+	}
+	return nil
+}
+
+var bypassServiceWhitelist = map[string]struct{}{
+	"/grpc.reflection.v1alpha.ServerReflection/": {},
+	"/grpc.health.v1.Health/":                    {},
+}
+
+// priorityFromMD returns the priority requested in the request headers of the call.
+func priorityFromMD(ctx context.Context) (Priority, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get(string(RequestPriority))
+	if len(values) == 0 {
+		return Normal, nil
+	}
+
+	priority, err := ParsePriority(values[0])
+	if err != nil {
+		return Normal, status.Errorf(codes.InvalidArgument, "invalid %s header: %s", RequestPriority, err)
+	}
+	return priority, nil
+}
+
+// begin reads the priority of the call into the context, and admits the call under the current
+// load, returning a function to call when the call ends.
+func (s *Shedder) begin(ctx context.Context, fullMethod string) (context.Context, func(), metadata.MD, error) {
+	for bypass := range bypassServiceWhitelist {
+		if strings.HasPrefix(fullMethod, bypass) {
+			return ctx, func() {}, nil, nil
+		}
+	}
+
+	priority, err := priorityFromMD(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	ctx = ContextWithPriority(ctx, priority)
+	if s == nil {
+		return ctx, func() {}, nil, nil
+	}
+
+	if err := s.admit(priority, time.Now()); err != nil {
+		return nil, nil, metadata.Pairs(ratelimit.RetryAfterHeader, ratelimit.FormatSeconds(s.retryAfter)), err
+	}
+
+	s.inFlight.Add(1)
+	return ctx, func() { s.inFlight.Add(-1) }, nil, nil
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that reads the priority of the
+// calls from their request headers and, if the shedder is not nil, rejects the calls shed under
+// the load of the node with a ResourceExhausted error and a Retry-After response header.
+func UnaryServerInterceptor(shedder *Shedder) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		newCtx, done, header, err := shedder.begin(ctx, info.FullMethod)
+		if header != nil {
+			if err := grpc.SetHeader(ctx, header); err != nil {
+				return nil, err
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+
+		return handler(newCtx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that reads the priority of the
+// calls from their request headers and, if the shedder is not nil, rejects the calls shed under
+// the load of the node with a ResourceExhausted error and a Retry-After response header.
+func StreamServerInterceptor(shedder *Shedder) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		newCtx, done, header, err := shedder.begin(stream.Context(), info.FullMethod)
+		if header != nil {
+			if err := stream.SetHeader(header); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+		defer done()
+
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = newCtx
+		return handler(srv, wrapped)
+	}
+}
+
+// outgoingContext returns the context of a dispatch, carrying the priority of the call in its
+// request headers.
+func outgoingContext(ctx context.Context) context.Context {
+	if priority := PriorityFromContext(ctx); priority != Normal {
+		return metadata.AppendToOutgoingContext(ctx, string(RequestPriority), priority.String())
+	}
+	return ctx
+}
+
+// UnaryClientInterceptor returns a new unary client interceptor that propagates the priority of
+// the call in the context to the request headers of dispatches.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		return invoker(outgoingContext(ctx), method, req, reply, cc, opts...)
+	}
+}
+
+// StreamClientInterceptor returns a new stream client interceptor that propagates the priority of
+// the call in the context to the request headers of dispatches.
+func StreamClientInterceptor() grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		return streamer(outgoingContext(ctx), desc, cc, method, opts...)
+	}
+}
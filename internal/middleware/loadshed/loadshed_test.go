@@ -0,0 +1,118 @@
+package loadshed
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestLoad(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Now()
+	cpuTime := time.Duration(0)
+	goroutines := 10
+	shedder := NewShedder(WithCPUThreshold(0.5), WithGoroutineThreshold(100), WithInFlightThreshold(4))
+	shedder.cpuTime = func() (time.Duration, bool) { return cpuTime, true }
+	shedder.goroutines = func() int { return goroutines }
+
+	// The CPU usage is only known from the second sample.
+	require.InDelta(0.1, shedder.Load(start), 0.001)
+
+	// The measurements are sampled at most once per second.
+	goroutines = 150
+	require.InDelta(0.1, shedder.Load(start.Add(500*time.Millisecond)), 0.001)
+	require.InDelta(1.5, shedder.Load(start.Add(time.Second)), 0.001)
+
+	goroutines = 10
+	shedder.inFlight.Store(3)
+	require.InDelta(0.75, shedder.Load(start.Add(2*time.Second)), 0.001)
+	shedder.inFlight.Store(0)
+
+	// Use all of the available CPUs for a second.
+	cpuTime += time.Duration(float64(time.Second) * float64(runtime.GOMAXPROCS(0)))
+	require.InDelta(2, shedder.Load(start.Add(3*time.Second)), 0.001)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	testCases := []struct {
+		name             string
+		priority         string
+		goroutines       int
+		expectedCode     codes.Code
+		expectedPriority Priority
+	}{
+		{"not overloaded", "", 50, codes.OK, Normal},
+		{"low priority when overloaded", "low", 100, codes.ResourceExhausted, Low},
+		{"normal priority when overloaded", "", 100, codes.OK, Normal},
+		{"normal priority when more overloaded", "", 130, codes.ResourceExhausted, Normal},
+		{"high priority when more overloaded", "high", 130, codes.OK, High},
+		{"critical priority when heavily overloaded", "critical", 1000, codes.OK, Critical},
+		{"invalid priority", "urgent", 50, codes.InvalidArgument, Normal},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			shedder := NewShedder(WithGoroutineThreshold(100))
+			shedder.goroutines = func() int { return tc.goroutines }
+
+			stream := &testTransportStream{}
+			ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+			if tc.priority != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(string(RequestPriority), tc.priority))
+			}
+
+			var priority Priority
+			_, err := UnaryServerInterceptor(shedder)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.PermissionsService/CheckPermission"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				priority = PriorityFromContext(ctx)
+				require.Equal(t, int64(1), shedder.inFlight.Load())
+				return nil, nil
+			})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			require.Equal(t, int64(0), shedder.inFlight.Load())
+			if tc.expectedCode == codes.OK {
+				require.Equal(t, tc.expectedPriority, priority)
+			}
+			if tc.expectedCode == codes.ResourceExhausted {
+				require.Equal(t, []string{"1"}, stream.header.Get("retry-after"))
+			}
+		})
+	}
+}
+
+type testTransportStream struct {
+	grpc.ServerTransportStream
+	header metadata.MD
+}
+
+func (s *testTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestUnaryClientInterceptor(t *testing.T) {
+	for _, priority := range []Priority{Low, Normal, Critical} {
+		priority := priority
+		t.Run(priority.String(), func(t *testing.T) {
+			ctx := ContextWithPriority(context.Background(), priority)
+			err := UnaryClientInterceptor()(ctx, "/dispatch.v1.DispatchService/DispatchCheck", nil, nil, nil, func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				md, _ := metadata.FromOutgoingContext(ctx)
+				if priority == Normal {
+					require.Empty(t, md.Get(string(RequestPriority)))
+				} else {
+					require.Equal(t, []string{priority.String()}, md.Get(string(RequestPriority)))
+				}
+				return nil
+			})
+			require.NoError(t, err)
+		})
+	}
+}
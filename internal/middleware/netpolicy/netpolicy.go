@@ -0,0 +1,169 @@
+// Package netpolicy provides middleware restricting the calls accepted by a listener to those
+// made from allowed networks, for deployments without a service mesh enforcing a network policy.
+package netpolicy
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/audit"
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+var rejectedCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "netpolicy",
+	Name:      "rejected_calls_total",
+	Help:      "The calls rejected because they were made from a network not allowed on the listener, by listener.",
+}, []string{"listener"})
+
+// Allowlist is the set of networks from which the calls accepted by a listener may be made.
+type Allowlist struct {
+	listener string
+	networks []*net.IPNet
+	logger   *audit.Logger
+}
+
+// NewAllowlist creates the allowlist of the listener with the given name from CIDR ranges, such
+// as `10.0.0.0/8` or `fd00::/8`, or returns nil if no range is given, in which case calls from
+// all networks are accepted. Rejected calls are recorded to the audit logger, if not nil.
+func NewAllowlist(listener string, cidrs []string, logger *audit.Logger) (*Allowlist, error) {
+	if len(cidrs) == 0 {
+		return nil, nil
+	}
+
+	allowlist := &Allowlist{listener: listener, logger: logger}
+	for _, cidr := range cidrs {
+		_, network, err := net.ParseCIDR(strings.TrimSpace(cidr))
+		if err != nil {
+			return nil, fmt.Errorf("invalid allowed network %q for the %s listener: %w", cidr, listener, err)
+		}
+		allowlist.networks = append(allowlist.networks, network)
+	}
+	return allowlist, nil
+}
+
+// Allows returns whether calls made from the address are accepted. Addresses which are not IP
+// addresses, such as those of unix sockets, are local and always allowed.
+func (a *Allowlist) Allows(addr net.Addr) bool {
+	if a == nil {
+		return true
+	}
+
+	var ip net.IP
+	switch addr := addr.(type) {
+	case *net.TCPAddr:
+		ip = addr.IP
+	case *net.UDPAddr:
+		ip = addr.IP
+	case *net.IPAddr:
+		ip = addr.IP
+	default:
+		return true
+	}
+	return a.allowsIP(ip)
+}
+
+func (a *Allowlist) allowsIP(ip net.IP) bool {
+	for _, network := range a.networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// reject records the call to the method which was rejected because it was made from the peer.
+func (a *Allowlist) reject(ctx context.Context, method, peer string) {
+	rejectedCounter.WithLabelValues(a.listener).Inc()
+	log.Ctx(ctx).Debug().
+		Str("listener", a.listener).
+		Str("peer", peer).
+		Str("method", method).
+		Msg("rejected call from a network not allowed")
+
+	if a.logger != nil {
+		a.logger.Record(audit.Event{
+			Time:     time.Now(),
+			Type:     audit.EventTypeRejectedConnection,
+			Listener: a.listener,
+			Peer:     peer,
+			Method:   method,
+			Code:     codes.PermissionDenied.String(),
+		})
+	}
+}
+
+// check returns a PermissionDenied error if the call in the context was made from a network not
+// allowed by the allowlist.
+func (a *Allowlist) check(ctx context.Context, method string) error {
+	if a == nil {
+		return nil
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		a.reject(ctx, method, "")
+		return status.Errorf(codes.PermissionDenied, "calls from an unknown address are not allowed on the %s listener", a.listener)
+	}
+	if !a.Allows(p.Addr) {
+		a.reject(ctx, method, p.Addr.String())
+		return status.Errorf(codes.PermissionDenied, "calls from %s are not allowed on the %s listener", p.Addr, a.listener)
+	}
+	return nil
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that, if the allowlist is not
+// nil, rejects the calls made from networks it does not allow with a PermissionDenied error.
+func UnaryServerInterceptor(allowlist *Allowlist) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := allowlist.check(ctx, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that, if the allowlist is not
+// nil, rejects the calls made from networks it does not allow with a PermissionDenied error.
+func StreamServerInterceptor(allowlist *Allowlist) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := allowlist.check(stream.Context(), info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
+
+// HTTPHandler returns a handler that, if the allowlist is not nil, rejects the requests made from
+// networks it does not allow with a 403 Forbidden response, and passes the others to the handler.
+// As for calls, requests which were not made from an IP address are always allowed.
+func HTTPHandler(allowlist *Allowlist, handler http.Handler) http.Handler {
+	if allowlist == nil {
+		return handler
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		if ip := net.ParseIP(host); ip != nil && !allowlist.allowsIP(ip) {
+			allowlist.reject(r.Context(), r.URL.Path, r.RemoteAddr)
+			http.Error(w, fmt.Sprintf("requests from %s are not allowed on the %s listener", r.RemoteAddr, allowlist.listener), http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,139 @@
+package netpolicy
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/audit"
+)
+
+type recordingSink struct {
+	sync.Mutex
+	events []audit.Event
+}
+
+func (rs *recordingSink) Name() string { return "recording" }
+
+func (rs *recordingSink) Write(_ context.Context, events []audit.Event) error {
+	rs.Lock()
+	defer rs.Unlock()
+	rs.events = append(rs.events, events...)
+	return nil
+}
+
+func (rs *recordingSink) Close() error { return nil }
+
+func TestNewAllowlist(t *testing.T) {
+	allowlist, err := NewAllowlist("grpc", nil, nil)
+	require.NoError(t, err)
+	require.Nil(t, allowlist)
+	require.True(t, allowlist.Allows(&net.TCPAddr{IP: net.ParseIP("192.0.2.1")}))
+
+	_, err = NewAllowlist("grpc", []string{"10.0.0.0"}, nil)
+	require.ErrorContains(t, err, "invalid allowed network \"10.0.0.0\" for the grpc listener")
+}
+
+func TestAllows(t *testing.T) {
+	allowlist, err := NewAllowlist("grpc", []string{"10.0.0.0/8", " 192.168.1.0/24", "fd00::/8"}, nil)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name    string
+		addr    net.Addr
+		allowed bool
+	}{
+		{"in first range", &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 1234}, true},
+		{"in second range", &net.TCPAddr{IP: net.ParseIP("192.168.1.20"), Port: 1234}, true},
+		{"outside ranges", &net.TCPAddr{IP: net.ParseIP("192.168.2.20"), Port: 1234}, false},
+		{"ipv6 in range", &net.TCPAddr{IP: net.ParseIP("fd12::1"), Port: 1234}, true},
+		{"ipv6 outside ranges", &net.TCPAddr{IP: net.ParseIP("2001:db8::1"), Port: 1234}, false},
+		{"ipv4-mapped ipv6 in range", &net.TCPAddr{IP: net.ParseIP("::ffff:10.0.0.1"), Port: 1234}, true},
+		{"unix socket", &net.UnixAddr{Name: "/tmp/spicedb.sock", Net: "unix"}, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.allowed, allowlist.Allows(tc.addr))
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	sink := &recordingSink{}
+	logger := audit.NewLogger(audit.Config{SampleRate: 1}, sink)
+	allowlist, err := NewAllowlist("dispatch", []string{"10.0.0.0/8"}, logger)
+	require.NoError(t, err)
+
+	interceptor := UnaryServerInterceptor(allowlist)
+	info := &grpc.UnaryServerInfo{FullMethod: "/dispatch.v1.DispatchService/DispatchCheck"}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return "ok", nil
+	}
+
+	allowedCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 1234}})
+	resp, err := interceptor(allowedCtx, nil, info, handler)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+
+	rejectedCtx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 1234}})
+	_, err = interceptor(rejectedCtx, nil, info, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = interceptor(context.Background(), nil, info, handler)
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.ErrorIs(t, logger.Start(ctx), context.Canceled)
+
+	require.Len(t, sink.events, 2)
+	event := sink.events[0]
+	require.Equal(t, audit.EventTypeRejectedConnection, event.Type)
+	require.Equal(t, "dispatch", event.Listener)
+	require.Equal(t, "192.0.2.1:1234", event.Peer)
+	require.Equal(t, info.FullMethod, event.Method)
+	require.Equal(t, "PermissionDenied", event.Code)
+	require.Empty(t, sink.events[1].Peer)
+}
+
+func TestHTTPHandler(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	allowlist, err := NewAllowlist("metrics", []string{"127.0.0.0/8"}, nil)
+	require.NoError(t, err)
+	wrapped := HTTPHandler(allowlist, handler)
+
+	testCases := []struct {
+		name         string
+		remoteAddr   string
+		expectedCode int
+	}{
+		{"allowed", "127.0.0.1:4567", http.StatusOK},
+		{"rejected", "192.0.2.1:4567", http.StatusForbidden},
+		{"not an ip address", "@", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			req.RemoteAddr = tc.remoteAddr
+			recorder := httptest.NewRecorder()
+			wrapped.ServeHTTP(recorder, req)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
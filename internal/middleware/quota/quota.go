@@ -0,0 +1,427 @@
+// Package quota provides middleware accounting the requests, dispatches and datastore queries of
+// each caller of the API against budgets over fixed windows of time.
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+)
+
+const (
+	requestsResource   = "requests"
+	dispatchesResource = "dispatches"
+	queriesResource    = "queries"
+)
+
+var (
+	usageCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "quota",
+		Name:      "usage_total",
+		Help:      "The requests, dispatches and datastore queries of the callers of the API accounted against their budgets.",
+	}, []string{"caller", "resource"})
+
+	overBudgetCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "quota",
+		Name:      "over_budget_calls_total",
+		Help:      "The calls of the callers of the API made over their budgets, by the action taken on them.",
+	}, []string{"caller", "resource", "action"})
+)
+
+// Budget is the maximum usage of a caller within a window. Zero limits are unlimited.
+type Budget struct {
+	Requests         uint64 `json:"requests,omitempty"`
+	Dispatches       uint64 `json:"dispatches,omitempty"`
+	DatastoreQueries uint64 `json:"datastoreQueries,omitempty"`
+}
+
+// ParseBudget parses a budget of the form `requests=1000|dispatches=50000|queries=20000`, where
+// each limit is optional.
+func ParseBudget(value string) (Budget, error) {
+	var budget Budget
+	for _, limit := range strings.Split(value, "|") {
+		resource, countValue, ok := strings.Cut(limit, "=")
+		if !ok {
+			return Budget{}, fmt.Errorf("invalid budget %q: expected a `|`-separated list of `resource=count`", value)
+		}
+
+		count, err := strconv.ParseUint(countValue, 10, 64)
+		if err != nil || count == 0 {
+			return Budget{}, fmt.Errorf("invalid budget %q: expected a positive count of %s", value, resource)
+		}
+
+		switch resource {
+		case requestsResource:
+			budget.Requests = count
+		case dispatchesResource:
+			budget.Dispatches = count
+		case queriesResource:
+			budget.DatastoreQueries = count
+		default:
+			return Budget{}, fmt.Errorf("invalid budget %q: unknown resource %q, expected one of %s, %s or %s", value, resource, requestsResource, dispatchesResource, queriesResource)
+		}
+	}
+	return budget, nil
+}
+
+// exhausted returns the resource of the budget exhausted by the usage, if any.
+func (b Budget) exhausted(usage Usage) (string, bool) {
+	switch {
+	case b.Requests > 0 && usage.Requests >= b.Requests:
+		return requestsResource, true
+	case b.Dispatches > 0 && usage.Dispatches >= b.Dispatches:
+		return dispatchesResource, true
+	case b.DatastoreQueries > 0 && usage.DatastoreQueries >= b.DatastoreQueries:
+		return queriesResource, true
+	default:
+		return "", false
+	}
+}
+
+// Usage is the usage of a caller within a window.
+type Usage struct {
+	Requests         uint64 `json:"requests"`
+	Dispatches       uint64 `json:"dispatches"`
+	DatastoreQueries uint64 `json:"datastoreQueries"`
+}
+
+// Action is the action taken on the calls of callers over budget.
+type Action int
+
+const (
+	// Reject rejects the calls of callers over budget with a ResourceExhausted error.
+	Reject Action = iota
+
+	// Deprioritize lets the calls of callers over budget proceed, but only a limited number of
+	// them at once, across all callers over budget.
+	Deprioritize
+)
+
+var actionNames = map[string]Action{
+	"reject":       Reject,
+	"deprioritize": Deprioritize,
+}
+
+// ParseAction parses an action, which is either `reject` or `deprioritize`.
+func ParseAction(value string) (Action, error) {
+	action, ok := actionNames[value]
+	if !ok {
+		return Reject, fmt.Errorf("unknown over-budget action %q, expected one of reject or deprioritize", value)
+	}
+	return action, nil
+}
+
+func (a Action) String() string {
+	for name, action := range actionNames {
+		if action == a {
+			return name
+		}
+	}
+	return strconv.Itoa(int(a))
+}
+
+// Option instances control how the middleware is initialized.
+type Option func(*Tracker)
+
+// WithWindow sets the length of the windows of time over which the usage of each caller is
+// accounted. Windows are aligned to the Unix epoch, so that all nodes share the same windows.
+//
+// default: 1h
+func WithWindow(window time.Duration) Option {
+	return func(t *Tracker) {
+		t.window = window
+	}
+}
+
+// WithDefaultBudget sets the budget of each caller, unless overridden for the caller.
+//
+// default: unlimited
+func WithDefaultBudget(budget Budget) Option {
+	return func(t *Tracker) {
+		t.defaultBudget = &budget
+	}
+}
+
+// WithCallerBudget sets the budget of a caller, named as by auth.CallerFromContext.
+func WithCallerBudget(caller string, budget Budget) Option {
+	return func(t *Tracker) {
+		t.byCaller[caller] = budget
+	}
+}
+
+// WithOverBudgetAction sets the action taken on the calls of callers over budget.
+//
+// default: Reject
+func WithOverBudgetAction(action Action) Option {
+	return func(t *Tracker) {
+		t.action = action
+	}
+}
+
+// WithDeprioritizedConcurrency sets the maximum number of calls of callers over budget running
+// at once when they are deprioritized.
+//
+// default: 1
+func WithDeprioritizedConcurrency(concurrency int) Option {
+	return func(t *Tracker) {
+		t.deprioritizedConcurrency = concurrency
+	}
+}
+
+// Tracker accounts the usage of each caller within the current window against its budget. The
+// usage is local to the node.
+type Tracker struct {
+	window                   time.Duration
+	defaultBudget            *Budget
+	byCaller                 map[string]Budget
+	action                   Action
+	deprioritizedConcurrency int
+	deprioritized            chan struct{}
+
+	mu          sync.Mutex
+	windowStart time.Time
+	usage       map[string]*Usage
+}
+
+// NewTracker creates a new tracker of the usage of callers.
+func NewTracker(opts ...Option) *Tracker {
+	t := &Tracker{
+		window:                   time.Hour,
+		byCaller:                 map[string]Budget{},
+		deprioritizedConcurrency: 1,
+		usage:                    map[string]*Usage{},
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	t.deprioritized = make(chan struct{}, t.deprioritizedConcurrency)
+	return t
+}
+
+// budget returns the budget of the caller, if any.
+func (t *Tracker) budget(caller string) *Budget {
+	if budget, ok := t.byCaller[caller]; ok {
+		return &budget
+	}
+	return t.defaultBudget
+}
+
+// roll starts a new window if the current one has ended. Must be called with the lock held.
+func (t *Tracker) roll(now time.Time) {
+	if windowStart := now.Truncate(t.window); windowStart.After(t.windowStart) {
+		t.windowStart = windowStart
+		t.usage = map[string]*Usage{}
+	}
+}
+
+// Record adds to the usage of the caller in the window of the given time.
+func (t *Tracker) Record(caller string, usage Usage, now time.Time) {
+	usageCounter.WithLabelValues(caller, requestsResource).Add(float64(usage.Requests))
+	usageCounter.WithLabelValues(caller, dispatchesResource).Add(float64(usage.Dispatches))
+	usageCounter.WithLabelValues(caller, queriesResource).Add(float64(usage.DatastoreQueries))
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.roll(now)
+	current, ok := t.usage[caller]
+	if !ok {
+		current = &Usage{}
+		t.usage[caller] = current
+	}
+	current.Requests += usage.Requests
+	current.Dispatches += usage.Dispatches
+	current.DatastoreQueries += usage.DatastoreQueries
+}
+
+// Exhausted returns the resource of the budget of the caller which is exhausted in the window of
+// the given time, if any.
+func (t *Tracker) Exhausted(caller string, now time.Time) (string, bool) {
+	budget := t.budget(caller)
+	if budget == nil {
+		return "", false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.roll(now)
+	usage, ok := t.usage[caller]
+	if !ok {
+		return "", false
+	}
+	return budget.exhausted(*usage)
+}
+
+// Report is the usage of the callers within a window.
+type Report struct {
+	WindowStart time.Time      `json:"windowStart"`
+	WindowEnd   time.Time      `json:"windowEnd"`
+	Callers     []CallerReport `json:"callers"`
+}
+
+// CallerReport is the usage of a caller within a window, along with its budget.
+type CallerReport struct {
+	Caller     string  `json:"caller"`
+	Usage      Usage   `json:"usage"`
+	Budget     *Budget `json:"budget,omitempty"`
+	OverBudget bool    `json:"overBudget"`
+}
+
+// Report returns the usage of the callers which made calls or have a budget in the window of the
+// given time, ordered by caller.
+func (t *Tracker) Report(now time.Time) Report {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.roll(now)
+	report := Report{
+		WindowStart: t.windowStart.UTC(),
+		WindowEnd:   t.windowStart.Add(t.window).UTC(),
+		Callers:     make([]CallerReport, 0, len(t.usage)),
+	}
+
+	callers := make(map[string]Usage, len(t.usage)+len(t.byCaller))
+	for caller := range t.byCaller {
+		callers[caller] = Usage{}
+	}
+	for caller, usage := range t.usage {
+		callers[caller] = *usage
+	}
+	for caller, usage := range callers {
+		callerReport := CallerReport{Caller: caller, Usage: usage, Budget: t.budget(caller)}
+		if callerReport.Budget != nil {
+			_, callerReport.OverBudget = callerReport.Budget.exhausted(usage)
+		}
+		report.Callers = append(report.Callers, callerReport)
+	}
+	sort.Slice(report.Callers, func(i, j int) bool {
+		return report.Callers[i].Caller < report.Callers[j].Caller
+	})
+	return report
+}
+
+// NewHandler returns an http.Handler serving the JSON-encoded usage Report of the tracker.
+func NewHandler(tracker *Tracker) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(tracker.Report(time.Now())); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write quota usage")
+		}
+	})
+}
+
+// begin accounts the request of a call made by the caller, returning a function to call when
+// the call ends, and a ResourceExhausted error if the call is rejected for being over budget.
+func (t *Tracker) begin(ctx context.Context, caller string) (func(), error) {
+	now := time.Now()
+	resource, exhausted := t.Exhausted(caller, now)
+	if !exhausted {
+		t.Record(caller, Usage{Requests: 1}, now)
+		return func() {}, nil
+	}
+
+	overBudgetCounter.WithLabelValues(caller, resource, t.action.String()).Inc()
+	if t.action == Reject {
+		return nil, status.Errorf(codes.ResourceExhausted, "the %s budget of the caller is exhausted until %s", resource, now.Truncate(t.window).Add(t.window).UTC().Format(time.RFC3339))
+	}
+
+	select {
+	case t.deprioritized <- struct{}{}:
+	case <-ctx.Done():
+		return nil, status.FromContextError(ctx.Err()).Err()
+	}
+	t.Record(caller, Usage{Requests: 1}, now)
+	return func() { <-t.deprioritized }, nil
+}
+
+// end accounts the dispatches and datastore queries of a call made by the caller.
+func (t *Tracker) end(ctx context.Context, caller string, queries uint64) {
+	usage := Usage{DatastoreQueries: queries}
+	if responseMeta := usagemetrics.FromContext(ctx); responseMeta != nil {
+		usage.Dispatches = uint64(responseMeta.DispatchCount)
+	}
+	t.Record(caller, usage, time.Now())
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that accounts the usage of the
+// callers of the API against their budgets, and rejects or deprioritizes the calls of callers
+// over budget. The interceptor must follow the datastore middleware.
+func UnaryServerInterceptor(tracker *Tracker) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		caller, ok := auth.AccountedCallerFromContext(ctx, info.FullMethod)
+		if tracker == nil || !ok {
+			return handler(ctx, req)
+		}
+
+		done, err := tracker.begin(ctx, caller)
+		if err != nil {
+			return nil, err
+		}
+		defer done()
+
+		var queries atomic.Uint64
+		ctx = usagemetrics.ContextWithHandleIfMissing(ctx)
+		if err := datastoremw.SetInContext(ctx, proxy.NewCountingDatastore(datastoremw.MustFromContext(ctx), &queries)); err != nil {
+			return nil, err
+		}
+		defer func() { tracker.end(ctx, caller, queries.Load()) }()
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that accounts the usage of the
+// callers of the API against their budgets, and rejects or deprioritizes the calls of callers
+// over budget. The interceptor must follow the datastore middleware.
+func StreamServerInterceptor(tracker *Tracker) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		caller, ok := auth.AccountedCallerFromContext(stream.Context(), info.FullMethod)
+		if tracker == nil || !ok {
+			return handler(srv, stream)
+		}
+
+		done, err := tracker.begin(stream.Context(), caller)
+		if err != nil {
+			return err
+		}
+		defer done()
+
+		var queries atomic.Uint64
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = usagemetrics.ContextWithHandleIfMissing(wrapped.WrappedContext)
+		if err := datastoremw.SetInContext(wrapped.WrappedContext, proxy.NewCountingDatastore(datastoremw.MustFromContext(stream.Context()), &queries)); err != nil {
+			return err
+		}
+		defer func() { tracker.end(wrapped.WrappedContext, caller, queries.Load()) }()
+
+		return handler(srv, wrapped)
+	}
+}
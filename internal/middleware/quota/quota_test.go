@@ -0,0 +1,142 @@
+package quota
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func TestParseBudget(t *testing.T) {
+	testCases := []struct {
+		value         string
+		expected      Budget
+		expectedError string
+	}{
+		{"requests=100", Budget{Requests: 100}, ""},
+		{"requests=100|dispatches=5000|queries=2000", Budget{Requests: 100, Dispatches: 5000, DatastoreQueries: 2000}, ""},
+		{"queries=1", Budget{DatastoreQueries: 1}, ""},
+		{"", Budget{}, "expected a `|`-separated list"},
+		{"requests", Budget{}, "expected a `|`-separated list"},
+		{"requests=0", Budget{}, "expected a positive count of requests"},
+		{"requests=many", Budget{}, "expected a positive count of requests"},
+		{"writes=10", Budget{}, "unknown resource"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			budget, err := ParseBudget(tc.value)
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, budget)
+		})
+	}
+}
+
+func TestTracker(t *testing.T) {
+	require := require.New(t)
+
+	start := time.Date(2022, 11, 1, 10, 0, 0, 0, time.UTC)
+	tracker := NewTracker(
+		WithWindow(time.Hour),
+		WithDefaultBudget(Budget{Requests: 2}),
+		WithCallerBudget("key:limited", Budget{DatastoreQueries: 10}),
+	)
+
+	tracker.Record("oidc:someone", Usage{Requests: 1, Dispatches: 3}, start)
+	_, exhausted := tracker.Exhausted("oidc:someone", start)
+	require.False(exhausted)
+
+	tracker.Record("oidc:someone", Usage{Requests: 1}, start.Add(time.Minute))
+	resource, exhausted := tracker.Exhausted("oidc:someone", start.Add(time.Minute))
+	require.True(exhausted)
+	require.Equal(requestsResource, resource)
+
+	tracker.Record("key:limited", Usage{Requests: 5, DatastoreQueries: 10}, start)
+	resource, exhausted = tracker.Exhausted("key:limited", start)
+	require.True(exhausted)
+	require.Equal(queriesResource, resource)
+
+	report := tracker.Report(start.Add(time.Minute))
+	require.Equal(start, report.WindowStart)
+	require.Equal(start.Add(time.Hour), report.WindowEnd)
+	require.Equal([]CallerReport{
+		{Caller: "key:limited", Usage: Usage{Requests: 5, DatastoreQueries: 10}, Budget: &Budget{DatastoreQueries: 10}, OverBudget: true},
+		{Caller: "oidc:someone", Usage: Usage{Requests: 2, Dispatches: 3}, Budget: &Budget{Requests: 2}, OverBudget: true},
+	}, report.Callers)
+
+	// The usage is reset in the next window.
+	_, exhausted = tracker.Exhausted("oidc:someone", start.Add(time.Hour))
+	require.False(exhausted)
+	require.Equal([]CallerReport{
+		{Caller: "key:limited", Budget: &Budget{DatastoreQueries: 10}},
+	}, tracker.Report(start.Add(time.Hour)).Callers)
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		action        Action
+		expectedCodes []codes.Code
+	}{
+		{"reject", Reject, []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted}},
+		{"deprioritize", Deprioritize, []codes.Code{codes.OK, codes.OK, codes.OK}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			tracker := NewTracker(WithDefaultBudget(Budget{Dispatches: 10}), WithOverBudgetAction(tc.action))
+			interceptor := UnaryServerInterceptor(tracker)
+			caller := auth.PresharedKeyCaller("somekey")
+
+			for _, expectedCode := range tc.expectedCodes {
+				ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer somekey"))
+				ctx = datastoremw.ContextWithDatastore(ctx, ds)
+
+				_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/authzed.api.v1.SchemaService/ReadSchema"}, func(ctx context.Context, req interface{}) (interface{}, error) {
+					ds := datastoremw.MustFromContext(ctx)
+					rev, err := ds.HeadRevision(ctx)
+					if err != nil {
+						return nil, err
+					}
+					if _, err := ds.SnapshotReader(rev).ListNamespaces(ctx); err != nil {
+						return nil, err
+					}
+					usagemetrics.SetInContext(ctx, &dispatch.ResponseMeta{DispatchCount: 5})
+					return nil, nil
+				})
+				require.Equal(t, expectedCode, status.Code(err))
+			}
+
+			report := tracker.Report(time.Now())
+			require.Len(t, report.Callers, 1)
+			require.Equal(t, caller, report.Callers[0].Caller)
+			require.True(t, report.Callers[0].OverBudget)
+
+			calls := uint64(len(tc.expectedCodes))
+			if tc.action == Reject {
+				calls--
+			}
+			require.Equal(t, Usage{Requests: calls, Dispatches: 5 * calls, DatastoreQueries: calls}, report.Callers[0].Usage)
+		})
+	}
+}
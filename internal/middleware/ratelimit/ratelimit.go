@@ -0,0 +1,375 @@
+// Package ratelimit provides middleware limiting the rate of API calls with token buckets.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/clientaddr"
+)
+
+const (
+	// LimitHeader is the response header holding the burst of the most restrictive token bucket
+	// applied to the call.
+	LimitHeader = "ratelimit-limit"
+
+	// RemainingHeader is the response header holding the number of calls which can be made
+	// immediately after the call before it is limited.
+	RemainingHeader = "ratelimit-remaining"
+
+	// ResetHeader is the response header holding the number of seconds until the most
+	// restrictive token bucket applied to the call is full again.
+	ResetHeader = "ratelimit-reset"
+
+	// RetryAfterHeader is the response header of a limited call holding the number of seconds
+	// after which the call can be retried.
+	RetryAfterHeader = "retry-after"
+)
+
+// Limit is the rate of a token bucket, along with its burst, which is the maximum number of calls
+// which can be made at once.
+type Limit struct {
+	PerSecond float64
+	Burst     uint32
+}
+
+// ParseLimit parses a limit of the form `rate[:burst]`, where the rate is the number of calls per
+// second. The burst defaults to the rate, rounded up.
+func ParseLimit(value string) (Limit, error) {
+	rateValue, burstValue, hasBurst := strings.Cut(value, ":")
+
+	perSecond, err := strconv.ParseFloat(rateValue, 64)
+	if err != nil || perSecond <= 0 || math.IsInf(perSecond, 0) {
+		return Limit{}, fmt.Errorf("invalid rate limit %q: expected a positive number of calls per second, optionally followed by `:` and a burst", value)
+	}
+
+	burst := uint64(math.Ceil(perSecond))
+	if hasBurst {
+		burst, err = strconv.ParseUint(burstValue, 10, 32)
+		if err != nil || burst == 0 {
+			return Limit{}, fmt.Errorf("invalid rate limit %q: expected a positive burst after `:`", value)
+		}
+	}
+	if burst > math.MaxUint32 {
+		burst = math.MaxUint32
+	}
+	return Limit{PerSecond: perSecond, Burst: uint32(burst)}, nil
+}
+
+// Option instances control how the middleware is initialized.
+type Option func(*Limiter)
+
+// WithDefaultLimit sets the limit of the calls made with each key, unless overridden for the key.
+//
+// default: unlimited
+func WithDefaultLimit(limit Limit) Option {
+	return func(l *Limiter) {
+		l.defaultLimit = &limit
+	}
+}
+
+// WithKeyLimit sets the limit of the calls made with a preshared key.
+func WithKeyLimit(presharedKey string, limit Limit) Option {
+	return func(l *Limiter) {
+		l.byKey[presharedKey] = limit
+	}
+}
+
+// WithMethodLimit sets the limit of the calls to a method made with each key, in addition to the
+// limit of the key. The method is either the full gRPC method, such as
+// `/authzed.api.v1.PermissionsService/CheckPermission`, or only its name, such as
+// `CheckPermission`.
+func WithMethodLimit(method string, limit Limit) Option {
+	return func(l *Limiter) {
+		l.byMethod[method] = limit
+	}
+}
+
+// WithClientIPLimit sets the limit of the calls made from each client IP address, in addition to
+// the limits of the key, so that clients sharing a key, or calling without one, cannot starve the
+// others. The client IP address is the original address of the client when the server is behind
+// a load balancer using the PROXY protocol.
+//
+// default: unlimited
+func WithClientIPLimit(limit Limit) Option {
+	return func(l *Limiter) {
+		l.clientIPLimit = &limit
+	}
+}
+
+// Limiter limits the rate of calls with a token bucket for each key, a token bucket for each key
+// and limited method, and a token bucket for each client IP address. The calls of a key do not
+// consume the tokens of other keys, so that no caller can starve the others.
+type Limiter struct {
+	mu            sync.Mutex
+	defaultLimit  *Limit
+	clientIPLimit *Limit
+	byKey         map[string]Limit
+	byMethod      map[string]Limit
+	buckets       map[bucketKey]*bucket
+}
+
+type bucketKey struct {
+	key      string
+	method   string
+	clientIP string
+}
+
+// NewLimiter creates a new limiter of calls.
+func NewLimiter(opts ...Option) *Limiter {
+	l := &Limiter{
+		byKey:    map[string]Limit{},
+		byMethod: map[string]Limit{},
+		buckets:  map[bucketKey]*bucket{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// Reconfigure replaces the limits of the limiter with those of the given options. The token
+// buckets of the calls whose limits are unchanged keep their tokens, and the others keep at most
+// the burst of their new limit.
+func (l *Limiter) Reconfigure(opts ...Option) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.defaultLimit = nil
+	l.clientIPLimit = nil
+	l.byKey = map[string]Limit{}
+	l.byMethod = map[string]Limit{}
+	for _, opt := range opts {
+		opt(l)
+	}
+}
+
+// Decision is the outcome of a call checked against the limiter.
+type Decision struct {
+	// Allowed is whether the call may proceed.
+	Allowed bool
+
+	// Limit is the burst of the most restrictive token bucket applied to the call.
+	Limit uint32
+
+	// Remaining is the number of calls which can be made immediately after the call.
+	Remaining uint32
+
+	// Reset is the time until the most restrictive token bucket is full again.
+	Reset time.Duration
+
+	// RetryAfter is the time after which a call which is not allowed can be retried.
+	RetryAfter time.Duration
+}
+
+// Take takes a token for a call to the given method made with the given key from each token
+// bucket applied to the call. If any bucket is empty, no token is taken and the call is not
+// allowed. Calls to which no limit applies are always allowed, and have a nil decision.
+func (l *Limiter) Take(key string, fullMethod string, now time.Time) *Decision {
+	return l.TakeFrom(key, "", fullMethod, now)
+}
+
+// TakeFrom is Take for a call made from the given client IP address, which also takes a token
+// from the bucket of the address, if a limit applies to client IP addresses. The address is
+// ignored if empty.
+func (l *Limiter) TakeFrom(key string, clientIP string, fullMethod string, now time.Time) *Decision {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var keyLimit *Limit
+	if limit, ok := l.byKey[key]; ok {
+		keyLimit = &limit
+	} else if l.defaultLimit != nil {
+		keyLimit = l.defaultLimit
+	}
+	methodName, methodLimit := l.methodLimit(fullMethod)
+	var clientIPLimit *Limit
+	if clientIP != "" {
+		clientIPLimit = l.clientIPLimit
+	}
+	if keyLimit == nil && methodLimit == nil && clientIPLimit == nil {
+		return nil
+	}
+
+	var buckets []*bucket
+	if keyLimit != nil {
+		buckets = append(buckets, l.bucket(bucketKey{key: key}, *keyLimit, now))
+	}
+	if methodLimit != nil {
+		buckets = append(buckets, l.bucket(bucketKey{key: key, method: methodName}, *methodLimit, now))
+	}
+	if clientIPLimit != nil {
+		buckets = append(buckets, l.bucket(bucketKey{clientIP: clientIP}, *clientIPLimit, now))
+	}
+
+	decision := &Decision{Allowed: true}
+	for _, b := range buckets {
+		b.refill(now)
+		if b.tokens < 1 {
+			decision.Allowed = false
+			if wait := b.durationUntil(1); wait > decision.RetryAfter {
+				decision.RetryAfter = wait
+			}
+		}
+	}
+
+	var mostRestrictive *bucket
+	for _, b := range buckets {
+		if decision.Allowed {
+			b.tokens--
+		}
+		if mostRestrictive == nil || b.tokens < mostRestrictive.tokens {
+			mostRestrictive = b
+		}
+	}
+
+	decision.Limit = mostRestrictive.limit.Burst
+	decision.Remaining = uint32(math.Max(0, math.Floor(mostRestrictive.tokens)))
+	decision.Reset = mostRestrictive.durationUntil(float64(mostRestrictive.limit.Burst))
+	return decision
+}
+
+// methodLimit returns the name under which the limit of the method is configured, along with the
+// limit, if any. Must be called with the lock held.
+func (l *Limiter) methodLimit(fullMethod string) (string, *Limit) {
+	if limit, ok := l.byMethod[fullMethod]; ok {
+		return fullMethod, &limit
+	}
+
+	methodName := fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+	if limit, ok := l.byMethod[methodName]; ok {
+		return methodName, &limit
+	}
+	return "", nil
+}
+
+// bucket returns the token bucket of the given key, creating a full bucket if it does not exist,
+// and applying the limit to the bucket if it changed. Must be called with the lock held.
+func (l *Limiter) bucket(key bucketKey, limit Limit, now time.Time) *bucket {
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: limit, tokens: float64(limit.Burst), last: now}
+		l.buckets[key] = b
+	}
+	if b.limit != limit {
+		b.refill(now)
+		b.limit = limit
+		b.tokens = math.Min(b.tokens, float64(limit.Burst))
+	}
+	return b
+}
+
+type bucket struct {
+	limit  Limit
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	if now.After(b.last) {
+		b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.PerSecond)
+		b.last = now
+	}
+}
+
+// durationUntil returns the time until the bucket holds the given number of tokens.
+func (b *bucket) durationUntil(tokens float64) time.Duration {
+	if b.tokens >= tokens {
+		return 0
+	}
+	return time.Duration((tokens - b.tokens) / b.limit.PerSecond * float64(time.Second))
+}
+
+var bypassServiceWhitelist = map[string]struct{}{
+	"/grpc.reflection.v1alpha.ServerReflection/": {},
+	"/grpc.health.v1.Health/":                    {},
+}
+
+// check takes a token for the call, returning the response headers of the call and a
+// ResourceExhausted error if the call is limited.
+func (l *Limiter) check(ctx context.Context, fullMethod string) (metadata.MD, error) {
+	for bypass := range bypassServiceWhitelist {
+		if strings.HasPrefix(fullMethod, bypass) {
+			return nil, nil
+		}
+	}
+
+	key, _ := grpcauth.AuthFromMD(ctx, "bearer")
+	if claims, ok := auth.OIDCClaimsFromContext(ctx); ok {
+		// OIDC tokens are short-lived, so the calls made with them are limited by their subject.
+		key = "oidc:" + claims.Subject
+	}
+	if identity, ok := auth.ClientIdentityFromContext(ctx); ok {
+		key = "identity:" + identity
+	}
+	decision := l.TakeFrom(key, clientaddr.FromContext(ctx), fullMethod, time.Now())
+	if decision == nil {
+		return nil, nil
+	}
+
+	header := metadata.Pairs(
+		LimitHeader, strconv.FormatUint(uint64(decision.Limit), 10),
+		RemainingHeader, strconv.FormatUint(uint64(decision.Remaining), 10),
+		ResetHeader, FormatSeconds(decision.Reset),
+	)
+	if decision.Allowed {
+		return header, nil
+	}
+
+	header.Set(RetryAfterHeader, FormatSeconds(decision.RetryAfter))
+	return header, status.Errorf(codes.ResourceExhausted, "rate limit exceeded for %s: retry after %s seconds", fullMethod, FormatSeconds(decision.RetryAfter))
+}
+
+// FormatSeconds formats the duration as a number of seconds, rounded up, as in the RetryAfterHeader
+// and ResetHeader headers.
+func FormatSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that rejects the calls exceeding
+// the limits of the limiter with a ResourceExhausted error, and returns the state of the limits
+// applied to each call in its response headers.
+func UnaryServerInterceptor(limiter *Limiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		header, err := limiter.check(ctx, info.FullMethod)
+		if header != nil {
+			if err := grpc.SetHeader(ctx, header); err != nil {
+				return nil, err
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that rejects the calls
+// exceeding the limits of the limiter with a ResourceExhausted error, and returns the state of the
+// limits applied to each call in its response headers.
+func StreamServerInterceptor(limiter *Limiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		header, err := limiter.check(stream.Context(), info.FullMethod)
+		if header != nil {
+			if err := stream.SetHeader(header); err != nil {
+				return err
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return handler(srv, stream)
+	}
+}
@@ -0,0 +1,146 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLimit(t *testing.T) {
+	testCases := []struct {
+		value         string
+		expected      Limit
+		expectedError string
+	}{
+		{"100", Limit{PerSecond: 100, Burst: 100}, ""},
+		{"0.5", Limit{PerSecond: 0.5, Burst: 1}, ""},
+		{"10:50", Limit{PerSecond: 10, Burst: 50}, ""},
+		{"", Limit{}, "expected a positive number of calls per second"},
+		{"0", Limit{}, "expected a positive number of calls per second"},
+		{"-1:5", Limit{}, "expected a positive number of calls per second"},
+		{"10:0", Limit{}, "expected a positive burst"},
+		{"10:many", Limit{}, "expected a positive burst"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			limit, err := ParseLimit(tc.value)
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, limit)
+		})
+	}
+}
+
+func TestTake(t *testing.T) {
+	type call struct {
+		key      string
+		method   string
+		after    time.Duration
+		expected *Decision
+	}
+
+	testCases := []struct {
+		name  string
+		opts  []Option
+		calls []call
+	}{
+		{
+			"unlimited",
+			nil,
+			[]call{{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, nil}},
+		},
+		{
+			"default limit per key",
+			[]Option{WithDefaultLimit(Limit{PerSecond: 1, Burst: 2})},
+			[]call{
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, &Decision{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}},
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, &Decision{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}},
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, &Decision{Allowed: false, Limit: 2, Remaining: 0, Reset: 2 * time.Second, RetryAfter: time.Second}},
+				{"otherkey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, &Decision{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}},
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 500 * time.Millisecond, &Decision{Allowed: false, Limit: 2, Remaining: 0, Reset: 1500 * time.Millisecond, RetryAfter: 500 * time.Millisecond}},
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 500 * time.Millisecond, &Decision{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}},
+			},
+		},
+		{
+			"key limit overrides default limit",
+			[]Option{WithDefaultLimit(Limit{PerSecond: 1, Burst: 2}), WithKeyLimit("somekey", Limit{PerSecond: 1, Burst: 1})},
+			[]call{
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, &Decision{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Second}},
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, &Decision{Allowed: false, Limit: 1, Remaining: 0, Reset: time.Second, RetryAfter: time.Second}},
+				{"otherkey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, &Decision{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}},
+			},
+		},
+		{
+			"method limit in addition to key limit",
+			[]Option{WithDefaultLimit(Limit{PerSecond: 10, Burst: 10}), WithMethodLimit("LookupResources", Limit{PerSecond: 1, Burst: 1})},
+			[]call{
+				{"somekey", "/authzed.api.v1.PermissionsService/LookupResources", 0, &Decision{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Second}},
+				{"somekey", "/authzed.api.v1.PermissionsService/LookupResources", 0, &Decision{Allowed: false, Limit: 1, Remaining: 0, Reset: time.Second, RetryAfter: time.Second}},
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, &Decision{Allowed: true, Limit: 10, Remaining: 8, Reset: 200 * time.Millisecond}},
+				{"otherkey", "/authzed.api.v1.PermissionsService/LookupResources", 0, &Decision{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Second}},
+			},
+		},
+		{
+			"method limit by full method",
+			[]Option{WithMethodLimit("/authzed.api.v1.PermissionsService/LookupResources", Limit{PerSecond: 1, Burst: 1})},
+			[]call{
+				{"somekey", "/authzed.api.v1.PermissionsService/LookupResources", 0, &Decision{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Second}},
+				{"somekey", "/authzed.api.v1.PermissionsService/CheckPermission", 0, nil},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			limiter := NewLimiter(tc.opts...)
+			now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+			for index, call := range tc.calls {
+				now = now.Add(call.after)
+				require.Equal(t, call.expected, limiter.Take(call.key, call.method, now), "call %d", index)
+			}
+		})
+	}
+}
+
+func TestReconfigure(t *testing.T) {
+	require := require.New(t)
+
+	limiter := NewLimiter(WithDefaultLimit(Limit{PerSecond: 1, Burst: 5}))
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	method := "/authzed.api.v1.PermissionsService/CheckPermission"
+	require.Equal(&Decision{Allowed: true, Limit: 5, Remaining: 4, Reset: time.Second}, limiter.Take("somekey", method, now))
+
+	// The bucket keeps at most the burst of the new limit.
+	limiter.Reconfigure(WithDefaultLimit(Limit{PerSecond: 1, Burst: 2}))
+	require.Equal(&Decision{Allowed: true, Limit: 2, Remaining: 1, Reset: time.Second}, limiter.Take("somekey", method, now))
+	require.Equal(&Decision{Allowed: true, Limit: 2, Remaining: 0, Reset: 2 * time.Second}, limiter.Take("somekey", method, now))
+	require.False(limiter.Take("somekey", method, now).Allowed)
+
+	// Limits which are no longer configured no longer apply.
+	limiter.Reconfigure(WithMethodLimit("LookupResources", Limit{PerSecond: 1, Burst: 1}))
+	require.Nil(limiter.Take("somekey", method, now))
+	require.Equal(&Decision{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Second}, limiter.Take("somekey", "/authzed.api.v1.PermissionsService/LookupResources", now))
+}
+
+func TestClientIPLimit(t *testing.T) {
+	require := require.New(t)
+
+	limiter := NewLimiter(WithDefaultLimit(Limit{PerSecond: 10, Burst: 10}), WithClientIPLimit(Limit{PerSecond: 1, Burst: 1}))
+	now := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	method := "/authzed.api.v1.PermissionsService/CheckPermission"
+
+	// The calls from the same address share its bucket, whatever their key.
+	require.Equal(&Decision{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Second}, limiter.TakeFrom("somekey", "10.0.0.1", method, now))
+	require.False(limiter.TakeFrom("otherkey", "10.0.0.1", method, now).Allowed)
+	require.Equal(&Decision{Allowed: true, Limit: 1, Remaining: 0, Reset: time.Second}, limiter.TakeFrom("", "10.0.0.2", method, now))
+
+	// Calls whose address is unknown are only limited by their key.
+	require.Equal(&Decision{Allowed: true, Limit: 10, Remaining: 8, Reset: 200 * time.Millisecond}, limiter.Take("somekey", method, now))
+}
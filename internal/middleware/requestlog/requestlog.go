@@ -0,0 +1,204 @@
+// Package requestlog provides middleware emitting a sampled structured log line for API calls,
+// describing how each call was evaluated.
+package requestlog
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/audit"
+	"github.com/authzed/spicedb/internal/auth"
+	auditmw "github.com/authzed/spicedb/internal/middleware/audit"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/redaction"
+)
+
+// defaultConsistency is the consistency logged for calls which do not request one, and are
+// evaluated with the default consistency of the server.
+const defaultConsistency = "default"
+
+type hasConsistency interface {
+	GetConsistency() *v1.Consistency
+}
+
+// Option instances control how the middleware is initialized.
+type Option func(*Logger)
+
+// WithSampleRate sets the fraction of the successful calls which are logged, unless overridden
+// for their method. Failed calls are always logged.
+//
+// default: 0
+func WithSampleRate(rate float64) Option {
+	return func(l *Logger) {
+		l.sampleRate = rate
+	}
+}
+
+// WithMethodSampleRate sets the fraction of the successful calls to a method which are logged.
+// The method is either the full gRPC method, such as
+// `/authzed.api.v1.PermissionsService/CheckPermission`, or only its name, such as
+// `CheckPermission`.
+func WithMethodSampleRate(method string, rate float64) Option {
+	return func(l *Logger) {
+		l.sampleRateByMethod[method] = rate
+	}
+}
+
+// WithRedaction sets the parts of the logged calls which are replaced by fingerprints.
+//
+// default: nothing is redacted
+func WithRedaction(redaction audit.Redaction) Option {
+	return func(l *Logger) {
+		l.redaction = redaction
+	}
+}
+
+// Logger logs a sample of the API calls.
+type Logger struct {
+	logger             zerolog.Logger
+	sampleRate         float64
+	sampleRateByMethod map[string]float64
+	redaction          audit.Redaction
+}
+
+// NewLogger creates a new logger of the API calls writing to the given logger.
+func NewLogger(logger zerolog.Logger, opts ...Option) *Logger {
+	l := &Logger{
+		logger:             logger,
+		sampleRateByMethod: map[string]float64{},
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// sampled returns whether a call to the method ending with the given error is logged.
+func (l *Logger) sampled(fullMethod string, err error) bool {
+	if status.Code(err) != codes.OK {
+		return true
+	}
+
+	rate, ok := l.sampleRateByMethod[fullMethod]
+	if !ok {
+		rate, ok = l.sampleRateByMethod[fullMethod[strings.LastIndex(fullMethod, "/")+1:]]
+	}
+	if !ok {
+		rate = l.sampleRate
+	}
+	return rate >= 1 || (rate > 0 && rand.Float64() < rate) // nolint:gosec
+}
+
+// log logs a call which started at the given time, if sampled.
+func (l *Logger) log(ctx context.Context, fullMethod string, req, resp interface{}, err error, start time.Time) {
+	if !l.sampled(fullMethod, err) {
+		return
+	}
+
+	event := auditmw.NewEvent(ctx, l.redaction, fullMethod, req, resp, err, start)
+	logEvent := l.logger.Info().
+		Str("method", fullMethod).
+		Str("caller", auth.CallerFromContext(ctx)).
+		Str("consistency", consistencyName(req)).
+		Str("revision", event.Revision).
+		Dur("latency", event.Duration).
+		Str("code", event.Code)
+
+	if event.RequestID != "" {
+		logEvent = logEvent.Str("requestID", event.RequestID)
+	}
+	if responseMeta := usagemetrics.FromContext(ctx); responseMeta != nil {
+		logEvent = logEvent.
+			Uint32("dispatchCount", responseMeta.DispatchCount).
+			Uint32("cachedDispatchCount", responseMeta.CachedDispatchCount)
+	}
+	if event.Decision != "" {
+		logEvent = logEvent.Str("decision", string(event.Decision))
+	}
+	if len(event.Resources) > 0 {
+		resources := make([]string, 0, len(event.Resources))
+		for _, resource := range event.Resources {
+			resources = append(resources, redaction.Text(resource))
+		}
+		logEvent = logEvent.Strs("resources", resources)
+	}
+	if event.Subject != "" {
+		logEvent = logEvent.Str("subject", redaction.Text(event.Subject))
+	}
+	logEvent.Msg("api call")
+}
+
+// consistencyName returns the name of the consistency requested by the call.
+func consistencyName(req interface{}) string {
+	withConsistency, ok := req.(hasConsistency)
+	if !ok {
+		return ""
+	}
+
+	switch withConsistency.GetConsistency().GetRequirement().(type) {
+	case *v1.Consistency_MinimizeLatency:
+		return "minimize_latency"
+	case *v1.Consistency_AtLeastAsFresh:
+		return "at_least_as_fresh"
+	case *v1.Consistency_AtExactSnapshot:
+		return "at_exact_snapshot"
+	case *v1.Consistency_FullyConsistent:
+		return "fully_consistent"
+	default:
+		return defaultConsistency
+	}
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that logs a sample of the calls
+// to the given logger. The interceptor must follow the consistency middleware for the revisions of
+// the calls to be known.
+func UnaryServerInterceptor(logger *Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx = usagemetrics.ContextWithHandleIfMissing(ctx)
+		resp, err := handler(ctx, req)
+		logger.log(ctx, info.FullMethod, req, resp, err, start)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that logs a sample of the calls
+// to the given logger. The interceptor must follow the consistency middleware for the revisions of
+// the calls to be known.
+func StreamServerInterceptor(logger *Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		wrapped := middleware.WrapServerStream(stream)
+		wrapped.WrappedContext = usagemetrics.ContextWithHandleIfMissing(wrapped.WrappedContext)
+		wrapper := &recvWrapper{ServerStream: wrapped}
+		err := handler(srv, wrapper)
+		logger.log(wrapped.WrappedContext, info.FullMethod, wrapper.req, nil, err, start)
+		return err
+	}
+}
+
+// recvWrapper captures the first message received on a stream, which is the request of the
+// server streaming calls of the API.
+type recvWrapper struct {
+	grpc.ServerStream
+	req interface{}
+}
+
+func (s *recvWrapper) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if s.req == nil {
+		s.req = m
+	}
+	return nil
+}
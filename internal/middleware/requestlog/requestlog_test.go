@@ -0,0 +1,144 @@
+package requestlog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/audit"
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	checkRequest := &v1.CheckPermissionRequest{
+		Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+		Resource:    &v1.ObjectReference{ObjectType: "document", ObjectId: "masterplan"},
+		Permission:  "view",
+		Subject:     &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}},
+	}
+	checkResponse := &v1.CheckPermissionResponse{
+		CheckedAt:      &v1.ZedToken{Token: "sometoken"},
+		Permissionship: v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+	}
+
+	testCases := []struct {
+		name     string
+		opts     []Option
+		method   string
+		req      interface{}
+		resp     interface{}
+		err      error
+		expected map[string]interface{}
+	}{
+		{
+			"sampled check",
+			[]Option{WithSampleRate(1)},
+			"/authzed.api.v1.PermissionsService/CheckPermission",
+			checkRequest,
+			checkResponse,
+			nil,
+			map[string]interface{}{
+				"method":              "/authzed.api.v1.PermissionsService/CheckPermission",
+				"caller":              auth.PresharedKeyCaller("somekey"),
+				"consistency":         "fully_consistent",
+				"revision":            "sometoken",
+				"code":                "OK",
+				"dispatchCount":       float64(3),
+				"cachedDispatchCount": float64(1),
+				"decision":            "denied",
+				"resources":           []interface{}{"document:masterplan#view"},
+				"subject":             "user:tom",
+			},
+		},
+		{
+			"redacted check",
+			[]Option{WithSampleRate(1), WithRedaction(audit.Redaction{ResourceIDs: true, SubjectIDs: true})},
+			"/authzed.api.v1.PermissionsService/CheckPermission",
+			checkRequest,
+			checkResponse,
+			nil,
+			map[string]interface{}{
+				"resources": []interface{}{"document:" + audit.Fingerprint("masterplan") + "#view"},
+				"subject":   "user:" + audit.Fingerprint("tom"),
+			},
+		},
+		{
+			"default consistency",
+			[]Option{WithSampleRate(1)},
+			"/authzed.api.v1.PermissionsService/ReadRelationships",
+			&v1.ReadRelationshipsRequest{RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"}},
+			nil,
+			nil,
+			map[string]interface{}{
+				"consistency": "default",
+				"resources":   []interface{}{"document"},
+			},
+		},
+		{
+			"unsampled method",
+			[]Option{WithSampleRate(1), WithMethodSampleRate("CheckPermission", 0)},
+			"/authzed.api.v1.PermissionsService/CheckPermission",
+			checkRequest,
+			checkResponse,
+			nil,
+			nil,
+		},
+		{
+			"sampled method",
+			[]Option{WithMethodSampleRate("/authzed.api.v1.PermissionsService/CheckPermission", 1)},
+			"/authzed.api.v1.PermissionsService/CheckPermission",
+			checkRequest,
+			checkResponse,
+			nil,
+			map[string]interface{}{"decision": "denied"},
+		},
+		{
+			"failed call",
+			nil,
+			"/authzed.api.v1.PermissionsService/CheckPermission",
+			checkRequest,
+			nil,
+			status.Error(codes.FailedPrecondition, "some error"),
+			map[string]interface{}{"code": "FailedPrecondition"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := NewLogger(zerolog.New(&buf), tc.opts...)
+
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer somekey"))
+			_, err := UnaryServerInterceptor(logger)(ctx, tc.req, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				usagemetrics.SetInContext(ctx, &dispatch.ResponseMeta{DispatchCount: 3, CachedDispatchCount: 1})
+				return tc.resp, tc.err
+			})
+			require.Equal(t, tc.err, err)
+
+			if tc.expected == nil {
+				require.Empty(t, buf.String())
+				return
+			}
+
+			var logged map[string]interface{}
+			require.NoError(t, json.Unmarshal(buf.Bytes(), &logged))
+			require.Equal(t, "api call", logged["message"])
+			require.Contains(t, logged, "latency")
+			for key, value := range tc.expected {
+				require.Equal(t, value, logged[key], key)
+			}
+		})
+	}
+}
@@ -0,0 +1,377 @@
+// Package scope provides middleware restricting the calls made with a preshared key, an OIDC
+// token or a client certificate to the scope of their caller.
+package scope
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"golang.org/x/exp/slices"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/auth"
+	consistencymw "github.com/authzed/spicedb/internal/middleware/consistency"
+	loadshedmw "github.com/authzed/spicedb/internal/middleware/loadshed"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	jobsv1 "github.com/authzed/spicedb/pkg/proto/jobs/v1"
+	watchv1 "github.com/authzed/spicedb/pkg/proto/watch/v1"
+)
+
+const (
+	permissionsService = "/authzed.api.v1.PermissionsService/"
+	schemaService      = "/authzed.api.v1.SchemaService/"
+	watchService       = "/authzed.api.v1.WatchService/"
+	deleteJobService   = "/jobs.v1.DeleteJobService/"
+	watchEventsService = "/watch.v1.WatchService/"
+
+	namespacesScopePrefix = "namespaces:"
+)
+
+// Kind is a kind of scope of a caller.
+type Kind int
+
+const (
+	// ReadOnly allows the calls which read permissions, relationships and the schema.
+	ReadOnly Kind = iota
+
+	// WriteRelationshipsOnly allows only the calls which write and delete relationships.
+	WriteRelationshipsOnly
+
+	// SchemaAdmin allows only the calls which read and write the schema.
+	SchemaAdmin
+
+	// Namespaces allows the calls of the permissions, watch and delete job services which only
+	// address resources of the object types of the scope, without the changes to the schema.
+	Namespaces
+
+	// Unrestricted allows all calls.
+	Unrestricted
+)
+
+// scopeNames maps the names of the kinds of scopes without parameters to the kinds.
+var scopeNames = map[string]Kind{
+	"read-only":           ReadOnly,
+	"write-relationships": WriteRelationshipsOnly,
+	"schema-admin":        SchemaAdmin,
+	"all":                 Unrestricted,
+}
+
+// methodsByKind are the methods allowed by the kinds of scopes which restrict methods.
+var methodsByKind = map[Kind][]string{
+	ReadOnly: {
+		permissionsService + "CheckPermission",
+		permissionsService + "ExpandPermissionTree",
+		permissionsService + "LookupResources",
+		permissionsService + "LookupSubjects",
+		permissionsService + "ReadRelationships",
+		schemaService + "ReadSchema",
+		watchService + "Watch",
+		watchEventsService + "Watch",
+	},
+	WriteRelationshipsOnly: {
+		permissionsService + "WriteRelationships",
+		permissionsService + "DeleteRelationships",
+		deleteJobService + "SubmitDeleteJob",
+		deleteJobService + "GetDeleteJob",
+		deleteJobService + "CancelDeleteJob",
+	},
+	SchemaAdmin: {
+		schemaService + "ReadSchema",
+		schemaService + "WriteSchema",
+	},
+}
+
+// namespacesScopeHeaders are the request headers which may be given in the calls made with a key
+// of a Namespaces scope, as they cannot address resources of other object types.
+var namespacesScopeHeaders = []string{
+	string(consistencymw.RequestFreshnessDeadline),
+	string(consistencymw.RequestSession),
+	string(loadshedmw.RequestPriority),
+	string(v1svc.RequestReflectCaveats),
+	string(v1svc.RequestReflectDeprecations),
+	string(v1svc.RequestLookupCaveatProjection),
+	string(v1svc.RequestAllMissingContext),
+	string(v1svc.RequestExpandPageSize),
+	string(v1svc.RequestExpandCursor),
+	string(v1svc.RequestExpandCaveats),
+	string(v1svc.RequestStreamCursor),
+	string(v1svc.RequestResponseFieldMask),
+	string(v1svc.RequestLookupCandidates),
+	string(v1svc.RequestWatchResourceRelations),
+	string(v1svc.RequestWatchResourceIDPrefix),
+	string(v1svc.RequestWatchSubjectTypes),
+}
+
+// Scope is the scope of a caller.
+type Scope struct {
+	Kind Kind
+
+	// ObjectTypes are the object types of the resources addressable with a Namespaces scope.
+	ObjectTypes []string
+}
+
+// ParseScope parses a scope, which is one of `read-only`, `write-relationships`, `schema-admin`,
+// `all`, or `namespaces:` followed by a `|`-separated list of object types.
+func ParseScope(value string) (Scope, error) {
+	if kind, ok := scopeNames[value]; ok {
+		return Scope{Kind: kind}, nil
+	}
+
+	if objectTypes := strings.TrimPrefix(value, namespacesScopePrefix); objectTypes != value {
+		scope := Scope{Kind: Namespaces}
+		for _, objectType := range strings.Split(objectTypes, "|") {
+			if objectType == "" {
+				return Scope{}, fmt.Errorf("invalid scope %q: expected a `|`-separated list of object types after `%s`", value, namespacesScopePrefix)
+			}
+			scope.ObjectTypes = append(scope.ObjectTypes, objectType)
+		}
+		return scope, nil
+	}
+
+	return Scope{}, fmt.Errorf("unknown scope %q, expected one of read-only, write-relationships, schema-admin, all or namespaces:type1|type2", value)
+}
+
+// allowsMethod returns an error if the scope does not allow calls to the method.
+func (s Scope) allowsMethod(fullMethod string) error {
+	switch s.Kind {
+	case Unrestricted:
+		return nil
+	case Namespaces:
+		if strings.HasPrefix(fullMethod, permissionsService) || strings.HasPrefix(fullMethod, watchService) || strings.HasPrefix(fullMethod, watchEventsService) || strings.HasPrefix(fullMethod, deleteJobService) {
+			return nil
+		}
+	default:
+		if slices.Contains(methodsByKind[s.Kind], fullMethod) {
+			return nil
+		}
+	}
+	return status.Errorf(codes.PermissionDenied, "the scope of the caller does not allow calls to %s", fullMethod)
+}
+
+// allowsHeaders returns an error if the scope does not allow the request headers of the call.
+func (s Scope) allowsHeaders(ctx context.Context) error {
+	if s.Kind != Namespaces {
+		return nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	for name := range md {
+		if strings.HasPrefix(name, "io.spicedb.") && !slices.Contains(namespacesScopeHeaders, name) {
+			return status.Errorf(codes.PermissionDenied, "the scope of the caller does not allow the request header %s", name)
+		}
+	}
+	return nil
+}
+
+// allowsRequest returns an error if the scope does not allow the object types of the resources
+// addressed by the request.
+func (s Scope) allowsRequest(req interface{}) error {
+	if s.Kind != Namespaces {
+		return nil
+	}
+
+	var objectTypes []string
+	switch req := req.(type) {
+	case *v1.CheckPermissionRequest:
+		objectTypes = []string{req.Resource.GetObjectType()}
+	case *v1.ExpandPermissionTreeRequest:
+		objectTypes = []string{req.Resource.GetObjectType()}
+	case *v1.LookupResourcesRequest:
+		objectTypes = []string{req.ResourceObjectType}
+	case *v1.LookupSubjectsRequest:
+		objectTypes = []string{req.Resource.GetObjectType()}
+	case *v1.ReadRelationshipsRequest:
+		objectTypes = []string{req.RelationshipFilter.GetResourceType()}
+	case *v1.WriteRelationshipsRequest:
+		for _, update := range req.Updates {
+			objectTypes = append(objectTypes, update.Relationship.GetResource().GetObjectType())
+		}
+		for _, precondition := range req.OptionalPreconditions {
+			objectTypes = append(objectTypes, precondition.Filter.GetResourceType())
+		}
+	case *v1.DeleteRelationshipsRequest:
+		objectTypes = []string{req.RelationshipFilter.GetResourceType()}
+		for _, precondition := range req.OptionalPreconditions {
+			objectTypes = append(objectTypes, precondition.Filter.GetResourceType())
+		}
+	case *jobsv1.SubmitDeleteJobRequest:
+		for _, filter := range req.RelationshipFilters {
+			objectTypes = append(objectTypes, filter.GetResourceType())
+		}
+	case *jobsv1.GetDeleteJobRequest, *jobsv1.CancelDeleteJobRequest:
+		// The jobs of a caller are only visible to it, and so were submitted within its scope.
+	case *v1.WatchRequest:
+		if len(req.OptionalObjectTypes) == 0 {
+			return status.Errorf(codes.PermissionDenied, "the scope of the caller requires the object types to watch")
+		}
+		objectTypes = req.OptionalObjectTypes
+	case *watchv1.WatchRequest:
+		if len(req.OptionalObjectTypes) == 0 {
+			return status.Errorf(codes.PermissionDenied, "the scope of the caller requires the object types to watch")
+		}
+		// The schema holds the definitions of all object types.
+		if req.IncludeSchemaChanges {
+			return status.Errorf(codes.PermissionDenied, "the scope of the caller does not allow watching the changes to the schema")
+		}
+		objectTypes = req.OptionalObjectTypes
+	default:
+		return status.Errorf(codes.PermissionDenied, "the scope of the caller does not allow %T", req)
+	}
+
+	for _, objectType := range objectTypes {
+		if !slices.Contains(s.ObjectTypes, objectType) {
+			return status.Errorf(codes.PermissionDenied, "the scope of the caller does not allow resources of type %s", objectType)
+		}
+	}
+	return nil
+}
+
+var bypassServiceWhitelist = map[string]struct{}{
+	"/grpc.reflection.v1alpha.ServerReflection/": {},
+	"/grpc.health.v1.Health/":                    {},
+}
+
+// Scopes are the scopes to which the callers of the API are restricted.
+type Scopes struct {
+	// ByKey are the scopes of the preshared keys restricted to a scope.
+	ByKey map[string]Scope
+
+	// OIDCClaim is the claim of OIDC tokens holding the scope of their calls. If empty, the calls
+	// made with OIDC tokens are unrestricted.
+	OIDCClaim string
+
+	// ByClientIdentity are the scopes of the identities of client certificates.
+	ByClientIdentity map[string]Scope
+}
+
+// forCall returns the scope of the call, if restricted. The scope of a call authenticated with a
+// client certificate is the scope of its identity, the scope of a call authenticated with an OIDC
+// token is given by the scope claim of the token, and the scope of other calls is the scope of
+// their preshared key.
+func (s Scopes) forCall(ctx context.Context, fullMethod string) (Scope, bool, error) {
+	for bypass := range bypassServiceWhitelist {
+		if strings.HasPrefix(fullMethod, bypass) {
+			return Scope{}, false, nil
+		}
+	}
+
+	if identity, ok := auth.ClientIdentityFromContext(ctx); ok {
+		scope, ok := s.ByClientIdentity[identity]
+		if !ok {
+			return Scope{}, false, status.Errorf(codes.PermissionDenied, "no scope configured for the client identity %s", identity)
+		}
+		return scope, true, nil
+	}
+
+	if claims, ok := auth.OIDCClaimsFromContext(ctx); ok {
+		if s.OIDCClaim == "" {
+			return Scope{}, false, nil
+		}
+
+		value, _ := claims.Claims[s.OIDCClaim].(string)
+		scope, err := ParseScope(value)
+		if err != nil {
+			return Scope{}, false, status.Errorf(codes.PermissionDenied, "the token has no valid %s claim: %s", s.OIDCClaim, err)
+		}
+		return scope, true, nil
+	}
+
+	if len(s.ByKey) == 0 {
+		return Scope{}, false, nil
+	}
+
+	key, err := grpcauth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		return Scope{}, false, nil
+	}
+
+	scope, ok := s.ByKey[key]
+	return scope, ok, nil
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that rejects the calls which are
+// outside of the scope of their caller with a PermissionDenied error.
+func UnaryServerInterceptor(scopes Scopes) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		scope, ok, err := scopes.forCall(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			if err := scope.allowsMethod(info.FullMethod); err != nil {
+				return nil, err
+			}
+			if err := scope.allowsHeaders(ctx); err != nil {
+				return nil, err
+			}
+			if err := scope.allowsRequest(req); err != nil {
+				return nil, err
+			}
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that rejects the calls which
+// are outside of the scope of their caller with a PermissionDenied error.
+func StreamServerInterceptor(scopes Scopes) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		scope, ok, err := scopes.forCall(stream.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return handler(srv, stream)
+		}
+
+		if err := scope.allowsMethod(info.FullMethod); err != nil {
+			return err
+		}
+		if err := scope.allowsHeaders(stream.Context()); err != nil {
+			return err
+		}
+		return handler(srv, &recvWrapper{ServerStream: stream, scope: scope})
+	}
+}
+
+// HTTPHandler returns an http.Handler that rejects with a 403 the requests made with a key whose
+// scope does not allow calls to the given method, which the handler is equivalent to, since the
+// HTTP APIs served outside of the gRPC server are not subject to its interceptors.
+func HTTPHandler(scopes Scopes, fullMethod string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := r.Context()
+		if authorization := r.Header.Get("Authorization"); authorization != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", authorization))
+		}
+
+		scope, ok, err := scopes.forCall(ctx, fullMethod)
+		if err == nil && ok {
+			err = scope.allowsMethod(fullMethod)
+		}
+		if err != nil {
+			http.Error(w, status.Convert(err).Message(), http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// recvWrapper checks each message received on a stream against the scope of the caller.
+type recvWrapper struct {
+	grpc.ServerStream
+	scope Scope
+}
+
+func (s *recvWrapper) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	return s.scope.allowsRequest(m)
+}
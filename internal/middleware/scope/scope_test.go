@@ -0,0 +1,275 @@
+package scope
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/internal/auth"
+	jobsv1 "github.com/authzed/spicedb/pkg/proto/jobs/v1"
+	watchv1 "github.com/authzed/spicedb/pkg/proto/watch/v1"
+)
+
+func TestParseScope(t *testing.T) {
+	testCases := []struct {
+		value         string
+		expected      Scope
+		expectedError string
+	}{
+		{"read-only", Scope{Kind: ReadOnly}, ""},
+		{"write-relationships", Scope{Kind: WriteRelationshipsOnly}, ""},
+		{"schema-admin", Scope{Kind: SchemaAdmin}, ""},
+		{"all", Scope{Kind: Unrestricted}, ""},
+		{"namespaces:document", Scope{Kind: Namespaces, ObjectTypes: []string{"document"}}, ""},
+		{"namespaces:document|tenant/folder", Scope{Kind: Namespaces, ObjectTypes: []string{"document", "tenant/folder"}}, ""},
+		{"namespaces:", Scope{}, "expected a `|`-separated list of object types"},
+		{"namespaces:document|", Scope{}, "expected a `|`-separated list of object types"},
+		{"admin", Scope{}, "unknown scope"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			scope, err := ParseScope(tc.value)
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, scope)
+		})
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	scopesByKey := map[string]Scope{
+		"readkey":   {Kind: ReadOnly},
+		"writekey":  {Kind: WriteRelationshipsOnly},
+		"schemakey": {Kind: SchemaAdmin},
+		"nskey":     {Kind: Namespaces, ObjectTypes: []string{"document"}},
+	}
+
+	check := func(resourceType string) *v1.CheckPermissionRequest {
+		return &v1.CheckPermissionRequest{
+			Resource: &v1.ObjectReference{ObjectType: resourceType, ObjectId: "readme"},
+			Subject:  &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}},
+		}
+	}
+	write := func(resourceType string) *v1.WriteRelationshipsRequest {
+		return &v1.WriteRelationshipsRequest{Updates: []*v1.RelationshipUpdate{{
+			Operation: v1.RelationshipUpdate_OPERATION_TOUCH,
+			Relationship: &v1.Relationship{
+				Resource: &v1.ObjectReference{ObjectType: resourceType, ObjectId: "readme"},
+				Relation: "viewer",
+				Subject:  &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}},
+			},
+		}}}
+	}
+
+	submitDeleteJob := func(resourceTypes ...string) *jobsv1.SubmitDeleteJobRequest {
+		req := &jobsv1.SubmitDeleteJobRequest{}
+		for _, resourceType := range resourceTypes {
+			req.RelationshipFilters = append(req.RelationshipFilters, &v1.RelationshipFilter{ResourceType: resourceType})
+		}
+		return req
+	}
+
+	testCases := []struct {
+		name         string
+		key          string
+		method       string
+		header       []string
+		req          interface{}
+		expectedCode codes.Code
+	}{
+		{"unscoped key", "otherkey", "/authzed.api.v1.SchemaService/WriteSchema", nil, &v1.WriteSchemaRequest{}, codes.OK},
+		{"read-only check", "readkey", "/authzed.api.v1.PermissionsService/CheckPermission", nil, check("folder"), codes.OK},
+		{"read-only write", "readkey", "/authzed.api.v1.PermissionsService/WriteRelationships", nil, write("document"), codes.PermissionDenied},
+		{"read-only schema write", "readkey", "/authzed.api.v1.SchemaService/WriteSchema", nil, &v1.WriteSchemaRequest{}, codes.PermissionDenied},
+		{"write-relationships write", "writekey", "/authzed.api.v1.PermissionsService/WriteRelationships", nil, write("folder"), codes.OK},
+		{"write-relationships check", "writekey", "/authzed.api.v1.PermissionsService/CheckPermission", nil, check("folder"), codes.PermissionDenied},
+		{"schema-admin schema write", "schemakey", "/authzed.api.v1.SchemaService/WriteSchema", nil, &v1.WriteSchemaRequest{}, codes.OK},
+		{"schema-admin check", "schemakey", "/authzed.api.v1.PermissionsService/CheckPermission", nil, check("document"), codes.PermissionDenied},
+		{"namespaces check in scope", "nskey", "/authzed.api.v1.PermissionsService/CheckPermission", nil, check("document"), codes.OK},
+		{"namespaces check out of scope", "nskey", "/authzed.api.v1.PermissionsService/CheckPermission", nil, check("folder"), codes.PermissionDenied},
+		{"namespaces write in scope", "nskey", "/authzed.api.v1.PermissionsService/WriteRelationships", nil, write("document"), codes.OK},
+		{"namespaces write out of scope", "nskey", "/authzed.api.v1.PermissionsService/WriteRelationships", nil, write("folder"), codes.PermissionDenied},
+		{"namespaces schema read", "nskey", "/authzed.api.v1.SchemaService/ReadSchema", nil, &v1.ReadSchemaRequest{}, codes.PermissionDenied},
+		{"namespaces allowed header", "nskey", "/authzed.api.v1.PermissionsService/CheckPermission", []string{"io.spicedb.reflectcaveats", ""}, check("document"), codes.OK},
+		{"namespaces disallowed header", "nskey", "/authzed.api.v1.PermissionsService/CheckPermission", []string{"io.spicedb.additionalchecks", "folder:plans#view@user:tom"}, check("document"), codes.PermissionDenied},
+		{"write-relationships delete job", "writekey", "/jobs.v1.DeleteJobService/SubmitDeleteJob", nil, submitDeleteJob("folder"), codes.OK},
+		{"read-only delete job", "readkey", "/jobs.v1.DeleteJobService/SubmitDeleteJob", nil, submitDeleteJob("folder"), codes.PermissionDenied},
+		{"namespaces delete job in scope", "nskey", "/jobs.v1.DeleteJobService/SubmitDeleteJob", nil, submitDeleteJob("document"), codes.OK},
+		{"namespaces delete job out of scope", "nskey", "/jobs.v1.DeleteJobService/SubmitDeleteJob", nil, submitDeleteJob("document", "folder"), codes.PermissionDenied},
+		{"namespaces delete job cancel", "nskey", "/jobs.v1.DeleteJobService/CancelDeleteJob", nil, &jobsv1.CancelDeleteJobRequest{JobId: "somejob"}, codes.OK},
+		{"health check", "writekey", "/grpc.health.v1.Health/Check", nil, nil, codes.OK},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			md := metadata.Pairs(append([]string{"authorization", "bearer " + tc.key}, tc.header...)...)
+			ctx := metadata.NewIncomingContext(context.Background(), md)
+
+			called := false
+			_, err := UnaryServerInterceptor(Scopes{ByKey: scopesByKey})(ctx, tc.req, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return nil, nil
+			})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+			require.Equal(t, tc.expectedCode == codes.OK, called)
+		})
+	}
+}
+
+func TestOIDCScopeClaim(t *testing.T) {
+	testCases := []struct {
+		name         string
+		scopeClaim   string
+		claims       map[string]interface{}
+		method       string
+		expectedCode codes.Code
+	}{
+		{"no scope claim configured", "", map[string]interface{}{}, "/authzed.api.v1.SchemaService/WriteSchema", codes.OK},
+		{"read-only claim", "spicedb_scope", map[string]interface{}{"spicedb_scope": "read-only"}, "/authzed.api.v1.SchemaService/ReadSchema", codes.OK},
+		{"read-only claim on write", "spicedb_scope", map[string]interface{}{"spicedb_scope": "read-only"}, "/authzed.api.v1.SchemaService/WriteSchema", codes.PermissionDenied},
+		{"all claim", "spicedb_scope", map[string]interface{}{"spicedb_scope": "all"}, "/authzed.api.v1.SchemaService/WriteSchema", codes.OK},
+		{"missing claim", "spicedb_scope", map[string]interface{}{}, "/authzed.api.v1.SchemaService/ReadSchema", codes.PermissionDenied},
+		{"invalid claim", "spicedb_scope", map[string]interface{}{"spicedb_scope": []interface{}{"read-only"}}, "/authzed.api.v1.SchemaService/ReadSchema", codes.PermissionDenied},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer some.oidc.token"))
+			ctx = auth.ContextWithOIDCClaims(ctx, &auth.OIDCClaims{Subject: "service", Claims: tc.claims})
+
+			// The key scopes do not apply to calls made with OIDC tokens.
+			scopesByKey := map[string]Scope{"some.oidc.token": {Kind: SchemaAdmin}}
+
+			_, err := UnaryServerInterceptor(Scopes{ByKey: scopesByKey, OIDCClaim: tc.scopeClaim})(ctx, &v1.ReadSchemaRequest{}, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestClientIdentityScope(t *testing.T) {
+	testCases := []struct {
+		name         string
+		identity     string
+		method       string
+		expectedCode codes.Code
+	}{
+		{"read-only identity", "spiffe://example.org/ns/prod/sa/billing", "/authzed.api.v1.SchemaService/ReadSchema", codes.OK},
+		{"read-only identity on write", "spiffe://example.org/ns/prod/sa/billing", "/authzed.api.v1.SchemaService/WriteSchema", codes.PermissionDenied},
+		{"schema-admin identity", "admin.example.org", "/authzed.api.v1.SchemaService/WriteSchema", codes.OK},
+		{"unmapped identity", "other.example.org", "/authzed.api.v1.SchemaService/ReadSchema", codes.PermissionDenied},
+	}
+
+	scopes := Scopes{ByClientIdentity: map[string]Scope{
+		"spiffe://example.org/ns/prod/sa/billing": {Kind: ReadOnly},
+		"admin.example.org":                       {Kind: SchemaAdmin},
+	}}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := auth.ContextWithClientIdentity(context.Background(), tc.identity)
+
+			_, err := UnaryServerInterceptor(scopes)(ctx, &v1.ReadSchemaRequest{}, &grpc.UnaryServerInfo{FullMethod: tc.method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+				return nil, nil
+			})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+		})
+	}
+}
+
+type testStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req proto.Message
+}
+
+func (s *testStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *testStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.req)
+	return nil
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	scopesByKey := map[string]Scope{"nskey": {Kind: Namespaces, ObjectTypes: []string{"document"}}}
+
+	testCases := []struct {
+		name         string
+		fullMethod   string
+		req          proto.Message
+		expectedCode codes.Code
+	}{
+		{"in scope", "/authzed.api.v1.WatchService/Watch", &v1.WatchRequest{OptionalObjectTypes: []string{"document"}}, codes.OK},
+		{"out of scope", "/authzed.api.v1.WatchService/Watch", &v1.WatchRequest{OptionalObjectTypes: []string{"document", "folder"}}, codes.PermissionDenied},
+		{"all object types", "/authzed.api.v1.WatchService/Watch", &v1.WatchRequest{}, codes.PermissionDenied},
+		{"events in scope", "/watch.v1.WatchService/Watch", &watchv1.WatchRequest{OptionalObjectTypes: []string{"document"}}, codes.OK},
+		{"events out of scope", "/watch.v1.WatchService/Watch", &watchv1.WatchRequest{OptionalObjectTypes: []string{"folder"}}, codes.PermissionDenied},
+		{"events schema changes", "/watch.v1.WatchService/Watch", &watchv1.WatchRequest{OptionalObjectTypes: []string{"document"}, IncludeSchemaChanges: true}, codes.PermissionDenied},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer nskey"))
+			stream := &testStream{ctx: ctx, req: tc.req}
+
+			err := StreamServerInterceptor(Scopes{ByKey: scopesByKey})(nil, stream, &grpc.StreamServerInfo{FullMethod: tc.fullMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+				return stream.RecvMsg(tc.req.ProtoReflect().New().Interface())
+			})
+			require.Equal(t, tc.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	scopes := Scopes{ByKey: map[string]Scope{
+		"readkey":  {Kind: ReadOnly},
+		"writekey": {Kind: WriteRelationshipsOnly},
+		"nskey":    {Kind: Namespaces, ObjectTypes: []string{"document"}},
+	}}
+	handler := HTTPHandler(scopes, "/authzed.api.v1.SchemaService/ReadSchema", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for _, tc := range []struct {
+		key          string
+		expectedCode int
+	}{
+		{"readkey", http.StatusOK},
+		{"writekey", http.StatusForbidden},
+		{"nskey", http.StatusForbidden},
+		{"unscopedkey", http.StatusOK},
+		{"", http.StatusOK},
+	} {
+		t.Run(tc.key, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", nil)
+			if tc.key != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.key)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package tenant
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+// registryRefreshInterval is how often the registry file is read again, so that the tenants
+// created and deleted through the other nodes sharing the file are seen.
+const registryRefreshInterval = 10 * time.Second
+
+var (
+	// ErrTenantExists is returned when creating a tenant which already exists.
+	ErrTenantExists = errors.New("tenant already exists")
+
+	// ErrStaticTenant is returned when deleting a tenant configured with --tenant-by-key, whose
+	// keys can only be removed from the flag.
+	ErrStaticTenant = errors.New("tenant is configured with --tenant-by-key")
+
+	// ErrNoRegistryFile is returned when creating or deleting a tenant without a registry file.
+	ErrNoRegistryFile = errors.New("no tenant registry file is configured")
+)
+
+// Registry holds the tenants to which preshared keys are restricted: the static tenants of
+// --tenant-by-key, and the tenants created through the tenant management API. The latter are
+// persisted to the registry file along with the SHA-256 hashes of their keys, so that the keys
+// themselves are only known to the creator of the tenant. All methods are safe on a nil
+// registry, which holds no tenants.
+type Registry struct {
+	static map[string]string
+	path   string
+
+	mu       sync.RWMutex
+	created  map[string][]string
+	byHashes map[string]string
+}
+
+// registryFile is the contents of the registry file.
+type registryFile struct {
+	// Tenants are the hex-encoded SHA-256 hashes of the keys of each created tenant.
+	Tenants map[string][]string `json:"tenants"`
+}
+
+// NewRegistry creates a registry of the static tenants of the given preshared keys and of the
+// tenants created in the registry file at the given path, if not empty, which is created when the
+// first tenant is. It returns nil if there are neither static tenants nor a registry file.
+func NewRegistry(tenantsByKey map[string]string, path string) (*Registry, error) {
+	if len(tenantsByKey) == 0 && path == "" {
+		return nil, nil
+	}
+
+	r := &Registry{static: tenantsByKey, path: path}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func hashKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
+
+// TenantOfKey returns the tenant to which the preshared key is restricted, if any.
+func (r *Registry) TenantOfKey(key string) (string, bool) {
+	if r == nil {
+		return "", false
+	}
+	if tenant, ok := r.static[key]; ok {
+		return tenant, true
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	tenant, ok := r.byHashes[hashKey(key)]
+	return tenant, ok
+}
+
+// authenticates returns whether the key is the key of a created tenant.
+func (r *Registry) authenticates(key string) bool {
+	if r == nil {
+		return false
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.byHashes[hashKey(key)]
+	return ok
+}
+
+// AuthFunc returns an auth function which authenticates the calls made with the key of a created
+// tenant, and the other calls with the given one.
+func (r *Registry) AuthFunc(authFunc grpcauth.AuthFunc) grpcauth.AuthFunc {
+	if r == nil || r.path == "" {
+		return authFunc
+	}
+	return func(ctx context.Context) (context.Context, error) {
+		if key, err := grpcauth.AuthFromMD(ctx, "bearer"); err == nil && r.authenticates(key) {
+			return ctx, nil
+		}
+		return authFunc(ctx)
+	}
+}
+
+// Tenants returns the names of the static and created tenants, sorted.
+func (r *Registry) Tenants() []string {
+	if r == nil {
+		return nil
+	}
+
+	names := map[string]struct{}{}
+	for _, tenant := range r.static {
+		names[tenant] = struct{}{}
+	}
+	r.mu.RLock()
+	for tenant := range r.created {
+		names[tenant] = struct{}{}
+	}
+	r.mu.RUnlock()
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	return sorted
+}
+
+// Create creates a tenant and returns its key, which is not stored and so cannot be retrieved
+// later.
+func (r *Registry) Create(tenant string) (string, error) {
+	if r == nil || r.path == "" {
+		return "", ErrNoRegistryFile
+	}
+	if err := ValidateTenantName(tenant); err != nil {
+		return "", err
+	}
+	for _, staticTenant := range r.static {
+		if staticTenant == tenant {
+			return "", ErrTenantExists
+		}
+	}
+
+	keyBytes := make([]byte, 32)
+	if _, err := rand.Read(keyBytes); err != nil {
+		return "", fmt.Errorf("failed to generate the key of the tenant: %w", err)
+	}
+	key := hex.EncodeToString(keyBytes)
+
+	err := r.update(func(created map[string][]string) error {
+		if _, ok := created[tenant]; ok {
+			return ErrTenantExists
+		}
+		created[tenant] = []string{hashKey(key)}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// Delete removes a created tenant, whose keys are then rejected, and returns whether it existed.
+// The definitions, caveats and relationships of the tenant are left to be deleted by the caller.
+func (r *Registry) Delete(tenant string) (bool, error) {
+	if r == nil || r.path == "" {
+		return false, ErrNoRegistryFile
+	}
+	for _, staticTenant := range r.static {
+		if staticTenant == tenant {
+			return false, ErrStaticTenant
+		}
+	}
+
+	var existed bool
+	err := r.update(func(created map[string][]string) error {
+		_, existed = created[tenant]
+		delete(created, tenant)
+		return nil
+	})
+	return existed, err
+}
+
+// update reads the registry file, applies the change to its tenants, and writes it back.
+func (r *Registry) update(change func(created map[string][]string) error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	file, err := readRegistryFile(r.path)
+	if err != nil {
+		return err
+	}
+	if err := change(file.Tenants); err != nil {
+		return err
+	}
+	if err := writeRegistryFile(r.path, file); err != nil {
+		return err
+	}
+	r.set(file)
+	return nil
+}
+
+// load reads the registry file, if any.
+func (r *Registry) load() error {
+	if r.path == "" {
+		return nil
+	}
+
+	file, err := readRegistryFile(r.path)
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.set(file)
+	return nil
+}
+
+// set replaces the created tenants with those of the file. Must be called with the lock held.
+func (r *Registry) set(file registryFile) {
+	r.created = file.Tenants
+	r.byHashes = make(map[string]string, len(file.Tenants))
+	for tenant, hashes := range file.Tenants {
+		for _, hash := range hashes {
+			r.byHashes[hash] = tenant
+		}
+	}
+}
+
+func readRegistryFile(path string) (registryFile, error) {
+	file := registryFile{Tenants: map[string][]string{}}
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return file, nil
+	}
+	if err != nil {
+		return registryFile{}, fmt.Errorf("unable to read tenant registry: %w", err)
+	}
+
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return registryFile{}, fmt.Errorf("unable to parse tenant registry: %w", err)
+	}
+	if file.Tenants == nil {
+		file.Tenants = map[string][]string{}
+	}
+	return file, nil
+}
+
+// writeRegistryFile replaces the registry file, through a temporary file renamed over it so that
+// it is never read partially written.
+func writeRegistryFile(path string, file registryFile) error {
+	contents, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("unable to write tenant registry: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(contents); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("unable to write tenant registry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("unable to write tenant registry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("unable to write tenant registry: %w", err)
+	}
+	return nil
+}
+
+// Start reads the registry file again every registryRefreshInterval, until the context is
+// canceled.
+func (r *Registry) Start(ctx context.Context) error {
+	ticker := time.NewTicker(registryRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := r.load(); err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("path", r.path).Msg("failed to read tenant registry")
+			}
+		}
+	}
+}
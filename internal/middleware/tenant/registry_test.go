@@ -0,0 +1,86 @@
+package tenant
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestRegistry(t *testing.T) {
+	require := require.New(t)
+
+	nilRegistry, err := NewRegistry(nil, "")
+	require.NoError(err)
+	require.Nil(nilRegistry)
+	_, ok := nilRegistry.TenantOfKey("somekey")
+	require.False(ok)
+
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	registry, err := NewRegistry(map[string]string{"statickey": "acme"}, path)
+	require.NoError(err)
+
+	tenant, ok := registry.TenantOfKey("statickey")
+	require.True(ok)
+	require.Equal("acme", tenant)
+
+	_, err = registry.Create("acme")
+	require.ErrorIs(err, ErrTenantExists)
+	_, err = registry.Create("Not A Tenant")
+	require.Error(err)
+
+	key, err := registry.Create("globex")
+	require.NoError(err)
+	tenant, ok = registry.TenantOfKey(key)
+	require.True(ok)
+	require.Equal("globex", tenant)
+	require.Equal([]string{"acme", "globex"}, registry.Tenants())
+
+	_, err = registry.Create("globex")
+	require.ErrorIs(err, ErrTenantExists)
+
+	// The keys of created tenants are authenticated, while the other keys are left to the given
+	// auth function.
+	authFunc := registry.AuthFunc(func(ctx context.Context) (context.Context, error) {
+		return nil, status.Error(codes.PermissionDenied, "invalid key")
+	})
+	withKey := func(key string) context.Context {
+		return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "bearer "+key))
+	}
+	_, err = authFunc(withKey(key))
+	require.NoError(err)
+	_, err = authFunc(withKey("statickey"))
+	require.Equal(codes.PermissionDenied, status.Code(err))
+
+	// The created tenants are persisted to the file, which holds no keys.
+	contents, err := os.ReadFile(path)
+	require.NoError(err)
+	require.NotContains(string(contents), key)
+
+	reopened, err := NewRegistry(nil, path)
+	require.NoError(err)
+	tenant, ok = reopened.TenantOfKey(key)
+	require.True(ok)
+	require.Equal("globex", tenant)
+
+	_, err = registry.Delete("acme")
+	require.ErrorIs(err, ErrStaticTenant)
+
+	existed, err := reopened.Delete("globex")
+	require.NoError(err)
+	require.True(existed)
+
+	// Other registries see the deletion once they read the file again.
+	require.NoError(registry.load())
+	_, ok = registry.TenantOfKey(key)
+	require.False(ok)
+
+	existed, err = registry.Delete("globex")
+	require.NoError(err)
+	require.False(existed)
+}
@@ -0,0 +1,110 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+
+	middleware "github.com/grpc-ecosystem/go-grpc-middleware/v2"
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+)
+
+// tenantNameRegex matches the names of tenants, which are the prefixes of the object types and
+// caveat names of the tenant.
+var tenantNameRegex = regexp.MustCompile(`^[a-z][a-z0-9_]{1,62}[a-z0-9]$`)
+
+// ValidateTenantName returns an error if the name is not a valid name of a tenant.
+func ValidateTenantName(name string) error {
+	if !tenantNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid tenant name `%s`: must match %s", name, tenantNameRegex)
+	}
+	return nil
+}
+
+type ctxKeyType struct{}
+
+var tenantKey ctxKeyType = struct{}{}
+
+// FromContext returns the tenant to which the call is restricted, if any.
+func FromContext(ctx context.Context) (string, bool) {
+	tenant, ok := ctx.Value(tenantKey).(string)
+	return tenant, ok
+}
+
+// tenantFromContext returns the tenant of the preshared key of the call, if any.
+func tenantFromContext(ctx context.Context, tenants *Registry) (string, bool) {
+	key, err := grpcauth.AuthFromMD(ctx, "bearer")
+	if err != nil {
+		return "", false
+	}
+
+	return tenants.TenantOfKey(key)
+}
+
+// FromHTTPRequest returns the tenant of the preshared key of the HTTP request, if any.
+func FromHTTPRequest(r *http.Request, tenants *Registry) (string, bool) {
+	key, ok := httpBearerToken(r)
+	if !ok {
+		return "", false
+	}
+
+	return tenants.TenantOfKey(key)
+}
+
+// HTTPAuthHandler returns an http.Handler serving the requests made with the key of a created
+// tenant with next, and the other requests with authenticated, which authenticates them itself.
+func HTTPAuthHandler(tenants *Registry, next http.Handler, authenticated http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if key, ok := httpBearerToken(r); ok && tenants.authenticates(key) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+func httpBearerToken(r *http.Request) (string, bool) {
+	scheme, key, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "bearer") || key == "" {
+		return "", false
+	}
+	return key, true
+}
+
+// UnaryServerInterceptor returns a new unary server interceptor that restricts the datastore of
+// the calls made with the preshared key of a tenant to the definitions, caveats and relationships
+// of the tenant.
+func UnaryServerInterceptor(tenants *Registry) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if tenant, ok := tenantFromContext(ctx, tenants); ok {
+			if err := datastoremw.SetInContext(ctx, proxy.NewTenantDatastore(datastoremw.MustFromContext(ctx), tenant)); err != nil {
+				return nil, err
+			}
+			ctx = context.WithValue(ctx, tenantKey, tenant)
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor returns a new stream server interceptor that restricts the datastore of
+// the calls made with the preshared key of a tenant to the definitions, caveats and relationships
+// of the tenant.
+func StreamServerInterceptor(tenants *Registry) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		wrapped := middleware.WrapServerStream(stream)
+		if tenant, ok := tenantFromContext(stream.Context(), tenants); ok {
+			if err := datastoremw.SetInContext(wrapped.WrappedContext, proxy.NewTenantDatastore(datastoremw.MustFromContext(stream.Context()), tenant)); err != nil {
+				return err
+			}
+			wrapped.WrappedContext = context.WithValue(wrapped.WrappedContext, tenantKey, tenant)
+		}
+		return handler(srv, wrapped)
+	}
+}
@@ -3,6 +3,7 @@ package usagemetrics
 import (
 	"context"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/authzed/authzed-go/pkg/responsemeta"
@@ -11,7 +12,9 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"google.golang.org/grpc"
 
+	"github.com/authzed/spicedb/internal/datastore/proxy"
 	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
@@ -32,27 +35,49 @@ var (
 	}, DispatchedCountLabels)
 )
 
+const (
+	// DatastoreQueriesCount is the response trailer of an API call holding the number of queries
+	// made to the datastore by the node which handled the call, excluding those made by the
+	// other nodes to which subproblems were dispatched.
+	// Value: an integer
+	DatastoreQueriesCount responsemeta.ResponseMetadataTrailerKey = "io.spicedb.respmeta.datastorequeriescount"
+
+	// EvaluationDuration is the response trailer of an API call holding the time taken by the
+	// server to handle the call.
+	// Value: a duration, such as `12.5ms`
+	EvaluationDuration responsemeta.ResponseMetadataTrailerKey = "io.spicedb.respmeta.evaluationduration"
+)
+
 type reporter struct{}
 
 func (r *reporter) ServerReporter(ctx context.Context, callMeta interceptors.CallMeta) (interceptors.Reporter, context.Context) {
 	_, methodName := interceptors.SplitMethodName(callMeta.FullMethod())
-	ctx = ContextWithHandle(ctx)
-	return &serverReporter{ctx: ctx, methodName: methodName}, ctx
+	ctx = ContextWithHandleIfMissing(ctx)
+	sr := &serverReporter{ctx: ctx, methodName: methodName}
+
+	// The queries made to the datastore are counted when the call has one.
+	if ds := datastoremw.FromContext(ctx); ds != nil {
+		if err := datastoremw.SetInContext(ctx, proxy.NewCountingDatastore(ds, &sr.queries)); err != nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("usagemetrics: could not count datastore queries")
+		}
+	}
+	return sr, ctx
 }
 
 type serverReporter struct {
 	interceptors.NoopReporter
 	ctx        context.Context
 	methodName string
+	queries    atomic.Uint64
 }
 
-func (r *serverReporter) PostCall(_ error, _ time.Duration) {
+func (r *serverReporter) PostCall(_ error, duration time.Duration) {
 	responseMeta := FromContext(r.ctx)
 	if responseMeta == nil {
 		responseMeta = &dispatch.ResponseMeta{}
 	}
 
-	err := annotateAndReportForMetadata(r.ctx, r.methodName, responseMeta)
+	err := annotateAndReportForMetadata(r.ctx, r.methodName, responseMeta, r.queries.Load(), duration)
 	// if context is cancelled, the stream will be closed, and gRPC will return ErrIllegalHeaderWrite
 	// this prevents logging unnecessary error messages
 	if r.ctx.Err() != nil {
@@ -65,25 +90,29 @@ func (r *serverReporter) PostCall(_ error, _ time.Duration) {
 
 // UnaryServerInterceptor implements a gRPC Middleware for reporting usage metrics
 // in both the trailer of the request, as well as to the registered prometheus
-// metrics.
+// metrics. The trailer holds the dispatch counts, the number of datastore queries
+// and the evaluation duration of the call.
 func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
 	return interceptors.UnaryServerInterceptor(&reporter{})
 }
 
 // StreamServerInterceptor implements a gRPC Middleware for reporting usage metrics
 // in both the trailer of the request, as well as to the registered prometheus
-// metrics
+// metrics. The trailer holds the dispatch counts, the number of datastore queries
+// and the evaluation duration of the call.
 func StreamServerInterceptor() grpc.StreamServerInterceptor {
 	return interceptors.StreamServerInterceptor(&reporter{})
 }
 
-func annotateAndReportForMetadata(ctx context.Context, methodName string, metadata *dispatch.ResponseMeta) error {
+func annotateAndReportForMetadata(ctx context.Context, methodName string, metadata *dispatch.ResponseMeta, queries uint64, duration time.Duration) error {
 	DispatchedCountHistogram.WithLabelValues(methodName, "false").Observe(float64(metadata.DispatchCount))
 	DispatchedCountHistogram.WithLabelValues(methodName, "true").Observe(float64(metadata.CachedDispatchCount))
 
 	return responsemeta.SetResponseTrailerMetadata(ctx, map[responsemeta.ResponseMetadataTrailerKey]string{
 		responsemeta.DispatchedOperationsCount: strconv.Itoa(int(metadata.DispatchCount)),
 		responsemeta.CachedOperationsCount:     strconv.Itoa(int(metadata.CachedDispatchCount)),
+		DatastoreQueriesCount:                  strconv.FormatUint(queries, 10),
+		EvaluationDuration:                     duration.String(),
 	})
 }
 
@@ -125,3 +154,13 @@ func ContextWithHandle(ctx context.Context) context.Context {
 	var handle metaHandle
 	return context.WithValue(ctx, metadataCtxKey, &handle)
 }
+
+// ContextWithHandleIfMissing returns a context with a location to store metadata returned from a
+// dispatched request, reusing the location of the given context if it has one, so that all
+// middleware of a call observe the same metadata.
+func ContextWithHandleIfMissing(ctx context.Context) context.Context {
+	if ctx.Value(metadataCtxKey) != nil {
+		return ctx
+	}
+	return ContextWithHandle(ctx)
+}
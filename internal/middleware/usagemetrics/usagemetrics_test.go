@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"testing"
+	"time"
 
 	"github.com/authzed/authzed-go/pkg/responsemeta"
 	"github.com/grpc-ecosystem/go-grpc-middleware/v2/testing/testpb"
@@ -15,6 +16,8 @@ import (
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
@@ -31,6 +34,15 @@ func (t testServer) PingEmpty(ctx context.Context, empty *testpb.PingEmptyReques
 }
 
 func (t testServer) Ping(ctx context.Context, request *testpb.PingRequest) (*testpb.PingResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+	headRevision, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := ds.SnapshotReader(headRevision).ListCaveats(ctx); err != nil {
+		return nil, err
+	}
+
 	SetInContext(ctx, &dispatch.ResponseMeta{
 		DispatchCount:       1,
 		CachedDispatchCount: 1,
@@ -74,12 +86,15 @@ type metricsMiddlewareTestSuite struct {
 }
 
 func TestMetricsMiddleware(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
 	s := &metricsMiddlewareTestSuite{
 		InterceptorTestSuite: &testpb.InterceptorTestSuite{
 			TestService: &testServer{},
 			ServerOpts: []grpc.ServerOption{
-				grpc.UnaryInterceptor(UnaryServerInterceptor()),
-				grpc.StreamInterceptor(StreamServerInterceptor()),
+				grpc.ChainUnaryInterceptor(datastoremw.UnaryServerInterceptor(ds), UnaryServerInterceptor()),
+				grpc.ChainStreamInterceptor(datastoremw.StreamServerInterceptor(ds), StreamServerInterceptor()),
 			},
 			ClientOpts: []grpc.DialOption{},
 		},
@@ -105,6 +120,16 @@ func (s *metricsMiddlewareTestSuite) TestTrailers_Unary() {
 	)
 	require.NoError(s.T(), err)
 	require.Equal(s.T(), 1, cachedCount)
+
+	queriesCount, err := responsemeta.GetIntResponseTrailerMetadata(trailerMD, DatastoreQueriesCount)
+	require.NoError(s.T(), err)
+	require.Equal(s.T(), 1, queriesCount)
+
+	duration, err := responsemeta.GetResponseTrailerMetadata(trailerMD, EvaluationDuration)
+	require.NoError(s.T(), err)
+	parsed, err := time.ParseDuration(duration)
+	require.NoError(s.T(), err)
+	require.Positive(s.T(), parsed)
 }
 
 func (s *metricsMiddlewareTestSuite) TestTrailers_Stream() {
@@ -0,0 +1,264 @@
+package namespace
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// AccessPathStepKind is the kind of a step in an access path.
+type AccessPathStepKind int
+
+const (
+	// AccessPathRewrite is a step from a permission to a relation or permission it references
+	// directly, such as `viewer` in `permission view = viewer`.
+	AccessPathRewrite AccessPathStepKind = iota
+
+	// AccessPathArrow is a step over an arrow, from a permission to the relation or permission
+	// on the subjects of a relation, such as `parent->view`.
+	AccessPathArrow
+
+	// AccessPathSubjectSet is a step from a relation to a subject set allowed on that relation,
+	// such as `group#member` in `relation viewer: group#member`.
+	AccessPathSubjectSet
+)
+
+// AccessPathStep is a single step in an access path.
+type AccessPathStep struct {
+	// Kind is the kind of the step.
+	Kind AccessPathStepKind
+
+	// Relation is the relation or permission reached by the step.
+	Relation *core.RelationReference
+
+	// TuplesetRelation is the relation on the left side of the arrow, if an AccessPathArrow.
+	TuplesetRelation string
+
+	// IsConditional is true if the step is found under an intersection or exclusion, which
+	// means that access is only granted by the path when the other branches allow it.
+	IsConditional bool
+}
+
+// AccessPath is a path through the schema, starting at a resource relation or permission, by
+// which a subject can be granted access.
+type AccessPath struct {
+	// Resource is the relation or permission at which the path starts.
+	Resource *core.RelationReference
+
+	// Steps are the steps taken from the resource relation or permission.
+	Steps []AccessPathStep
+
+	// Subject is the subject type, as allowed on the relation reached by the last step (or the
+	// resource, if there are no steps).
+	Subject *core.AllowedRelation
+}
+
+// IsConditional returns true if any of the steps of the path are conditional.
+func (ap AccessPath) IsConditional() bool {
+	for _, step := range ap.Steps {
+		if step.IsConditional {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns a human-readable form of the path, such as
+// `document#view -> parent->folder#view -> folder#viewer -> group#member -> user`.
+func (ap AccessPath) String() string {
+	parts := make([]string, 0, len(ap.Steps)+2)
+	parts = append(parts, tuple.StringRR(ap.Resource))
+	for _, step := range ap.Steps {
+		part := tuple.StringRR(step.Relation)
+		if step.Kind == AccessPathArrow {
+			part = fmt.Sprintf("%s->%s", step.TuplesetRelation, part)
+		}
+		if step.IsConditional {
+			part += " [conditional]"
+		}
+		parts = append(parts, part)
+	}
+	parts = append(parts, SourceForAllowedRelation(ap.Subject))
+	return strings.Join(parts, " -> ")
+}
+
+// AccessPathsForSubjectToResource returns all paths through the schema, starting at the given
+// resource relation or permission, by which a subject of the given type can be granted access.
+// The subject type's relation should be the ellipsis for direct subjects of the type.
+//
+// Paths which would revisit a relation or permission already found on the path (i.e. recursive
+// paths) are returned only up to the point of recursion.
+func (rg *ReachabilityGraph) AccessPathsForSubjectToResource(
+	ctx context.Context,
+	subjectType *core.RelationReference,
+	resourceType *core.RelationReference,
+) ([]AccessPath, error) {
+	if resourceType.Namespace != rg.ts.nsDef.Name {
+		return nil, fmt.Errorf("gave mismatching namespace name for resource type to reachability graph")
+	}
+
+	apc := &accessPathCollector{
+		ts:          rg.ts,
+		subjectType: subjectType,
+		resource:    resourceType,
+	}
+	if err := apc.collectForRelation(ctx, resourceType, nil, map[string]struct{}{}); err != nil {
+		return nil, err
+	}
+	return apc.collected, nil
+}
+
+type accessPathCollector struct {
+	ts          *TypeSystem
+	subjectType *core.RelationReference
+	resource    *core.RelationReference
+	collected   []AccessPath
+}
+
+func (apc *accessPathCollector) collectForRelation(
+	ctx context.Context,
+	current *core.RelationReference,
+	steps []AccessPathStep,
+	onPath map[string]struct{},
+) error {
+	key := relationKey(current.Namespace, current.Relation)
+	if _, ok := onPath[key]; ok {
+		return nil
+	}
+	onPath[key] = struct{}{}
+	defer delete(onPath, key)
+
+	ts, err := apc.ts.typeSystemForNamespace(ctx, current.Namespace)
+	if err != nil {
+		return err
+	}
+
+	relation, ok := ts.relationMap[current.Relation]
+	if !ok {
+		return NewRelationNotFoundErr(current.Namespace, current.Relation)
+	}
+
+	if rewrite := relation.GetUsersetRewrite(); rewrite != nil {
+		return apc.collectForRewrite(ctx, ts, current, rewrite, steps, false, onPath)
+	}
+
+	for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		if allowed.Namespace == apc.subjectType.Namespace {
+			matches := allowed.GetPublicWildcard() != nil && apc.subjectType.Relation == tuple.Ellipsis
+			matches = matches || allowed.GetRelation() == apc.subjectType.Relation
+			if matches {
+				apc.collected = append(apc.collected, AccessPath{
+					Resource: apc.resource,
+					Steps:    append([]AccessPathStep{}, steps...),
+					Subject:  allowed,
+				})
+			}
+		}
+
+		if allowed.GetRelation() != "" && allowed.GetRelation() != tuple.Ellipsis {
+			subjectSet := &core.RelationReference{Namespace: allowed.Namespace, Relation: allowed.GetRelation()}
+			nextSteps := append(steps[:len(steps):len(steps)], AccessPathStep{
+				Kind:     AccessPathSubjectSet,
+				Relation: subjectSet,
+			})
+			if err := apc.collectForRelation(ctx, subjectSet, nextSteps, onPath); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (apc *accessPathCollector) collectForRewrite(
+	ctx context.Context,
+	ts *TypeSystem,
+	current *core.RelationReference,
+	rewrite *core.UsersetRewrite,
+	steps []AccessPathStep,
+	isConditional bool,
+	onPath map[string]struct{},
+) error {
+	var children []*core.SetOperation_Child
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		children = rw.Union.Child
+	case *core.UsersetRewrite_Intersection:
+		children = rw.Intersection.Child
+		isConditional = true
+	case *core.UsersetRewrite_Exclusion:
+		children = rw.Exclusion.Child
+		isConditional = true
+	default:
+		return fmt.Errorf("unknown kind of userset rewrite in access path computation: %T", rw)
+	}
+
+	for _, childOneof := range children {
+		switch child := childOneof.ChildType.(type) {
+		case *core.SetOperation_Child_UsersetRewrite:
+			if err := apc.collectForRewrite(ctx, ts, current, child.UsersetRewrite, steps, isConditional, onPath); err != nil {
+				return err
+			}
+
+		case *core.SetOperation_Child_ComputedUserset:
+			next := &core.RelationReference{Namespace: current.Namespace, Relation: child.ComputedUserset.Relation}
+			nextSteps := append(steps[:len(steps):len(steps)], AccessPathStep{
+				Kind:          AccessPathRewrite,
+				Relation:      next,
+				IsConditional: isConditional,
+			})
+			if err := apc.collectForRelation(ctx, next, nextSteps, onPath); err != nil {
+				return err
+			}
+
+		case *core.SetOperation_Child_TupleToUserset:
+			tuplesetRelation := child.TupleToUserset.Tupleset.Relation
+			allowedTypes, err := ts.AllowedDirectRelationsAndWildcards(tuplesetRelation)
+			if err != nil {
+				return err
+			}
+
+			encountered := map[string]struct{}{}
+			for _, allowed := range allowedTypes {
+				if _, ok := encountered[allowed.Namespace]; ok {
+					continue
+				}
+				encountered[allowed.Namespace] = struct{}{}
+
+				targetTS, err := apc.ts.typeSystemForNamespace(ctx, allowed.Namespace)
+				if err != nil {
+					return err
+				}
+
+				if !targetTS.HasRelation(child.TupleToUserset.ComputedUserset.Relation) {
+					continue
+				}
+
+				next := &core.RelationReference{Namespace: allowed.Namespace, Relation: child.TupleToUserset.ComputedUserset.Relation}
+				nextSteps := append(steps[:len(steps):len(steps)], AccessPathStep{
+					Kind:             AccessPathArrow,
+					Relation:         next,
+					TuplesetRelation: tuplesetRelation,
+					IsConditional:    isConditional,
+				})
+				if err := apc.collectForRelation(ctx, next, nextSteps, onPath); err != nil {
+					return err
+				}
+			}
+
+		case *core.SetOperation_Child_XNil:
+			// Nil grants no access.
+
+		case *core.SetOperation_Child_XThis:
+			return fmt.Errorf("use of _this is unsupported; please rewrite your schema")
+
+		default:
+			return fmt.Errorf("unknown set operation child `%T` in access path computation", child)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,169 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+func TestAccessPathsForSubjectToResource(t *testing.T) {
+	testCases := []struct {
+		name                string
+		schema              string
+		resourceType        *core.RelationReference
+		subjectType         *core.RelationReference
+		expectedPaths       []string
+		expectedConditional []bool
+	}{
+		{
+			"direct relation",
+			`definition user {}
+
+			definition document {
+				relation viewer: user
+			}`,
+			rr("document", "viewer"),
+			rr("user", "..."),
+			[]string{"document#viewer -> user"},
+			[]bool{false},
+		},
+		{
+			"wildcard",
+			`definition user {}
+
+			definition document {
+				relation viewer: user:*
+				permission view = viewer
+			}`,
+			rr("document", "view"),
+			rr("user", "..."),
+			[]string{"document#view -> document#viewer -> user:*"},
+			[]bool{false},
+		},
+		{
+			"arrows and subject sets",
+			`definition user {}
+
+			definition group {
+				relation member: user | group#member
+			}
+
+			definition folder {
+				relation viewer: user | group#member
+				permission view = viewer
+			}
+
+			definition document {
+				relation parent: folder
+				relation viewer: user
+				permission view = viewer + parent->view
+			}`,
+			rr("document", "view"),
+			rr("user", "..."),
+			[]string{
+				"document#view -> document#viewer -> user",
+				"document#view -> parent->folder#view -> folder#viewer -> user",
+				"document#view -> parent->folder#view -> folder#viewer -> group#member -> user",
+			},
+			[]bool{false, false, false},
+		},
+		{
+			"subject set as subject type",
+			`definition user {}
+
+			definition group {
+				relation member: user
+			}
+
+			definition document {
+				relation viewer: user | group#member
+				permission view = viewer
+			}`,
+			rr("document", "view"),
+			rr("group", "member"),
+			[]string{"document#view -> document#viewer -> group#member"},
+			[]bool{false},
+		},
+		{
+			"intersection and exclusion",
+			`definition user {}
+
+			definition document {
+				relation viewer: user
+				relation allowed: user
+				relation banned: user
+				permission view = (viewer & allowed) - banned
+			}`,
+			rr("document", "view"),
+			rr("user", "..."),
+			[]string{
+				"document#view -> document#viewer [conditional] -> user",
+				"document#view -> document#allowed [conditional] -> user",
+				"document#view -> document#banned [conditional] -> user",
+			},
+			[]bool{true, true, true},
+		},
+		{
+			"unreachable subject type",
+			`definition user {}
+			definition team {}
+
+			definition document {
+				relation viewer: user
+				permission view = viewer
+			}`,
+			rr("document", "view"),
+			rr("team", "..."),
+			[]string{},
+			[]bool{},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			empty := ""
+			compiled, err := compiler.Compile(compiler.InputSchema{
+				Source:       input.Source("schema"),
+				SchemaString: tc.schema,
+			}, &empty)
+			require.NoError(err)
+
+			resolver := ResolverForPredefinedDefinitions(PredefinedElements{Namespaces: compiled.ObjectDefinitions})
+
+			var rts *ValidatedNamespaceTypeSystem
+			for _, nsDef := range compiled.ObjectDefinitions {
+				ts, err := NewNamespaceTypeSystem(nsDef, resolver)
+				require.NoError(err)
+
+				vts, err := ts.Validate(context.Background())
+				require.NoError(err)
+
+				if nsDef.Name == tc.resourceType.Namespace {
+					rts = vts
+				}
+			}
+			require.NotNil(rts)
+
+			paths, err := ReachabilityGraphFor(rts).AccessPathsForSubjectToResource(context.Background(), tc.subjectType, tc.resourceType)
+			require.NoError(err)
+
+			foundPaths := make([]string, 0, len(paths))
+			foundConditional := make([]bool, 0, len(paths))
+			for _, path := range paths {
+				foundPaths = append(foundPaths, path.String())
+				foundConditional = append(foundConditional, path.IsConditional())
+			}
+
+			require.Equal(tc.expectedPaths, foundPaths)
+			require.Equal(tc.expectedConditional, foundConditional)
+		})
+	}
+}
@@ -0,0 +1,317 @@
+package namespace
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DefaultSubjectsPerResource is the number of subjects assumed to be found per resource for a
+// relation when no statistics were provided for it.
+const DefaultSubjectsPerResource = 10
+
+// RelationStatistics holds statistics about the relationships stored for a relation, used to
+// estimate the fan-out of the permissions walking that relation.
+type RelationStatistics struct {
+	// MaxSubjectsPerResource is the largest number of subjects (or a high percentile thereof)
+	// found for a single resource on the relation.
+	MaxSubjectsPerResource uint64
+}
+
+// ComplexityBudget defines the limits against which the estimated complexity of each permission
+// is compared.
+type ComplexityBudget struct {
+	// MaxDepth is the maximum dispatch depth allowed, which should match the configured maximum
+	// dispatch depth of the server. Zero for no limit.
+	MaxDepth uint32
+
+	// MaxFanOut is the maximum number of dispatched subproblems allowed for a single check of the
+	// permission. Zero for no limit.
+	MaxFanOut uint64
+}
+
+// PermissionComplexity is the estimated worst-case complexity of checking a relation or
+// permission.
+type PermissionComplexity struct {
+	// Relation is the relation or permission.
+	Relation *core.RelationReference
+
+	// Depth is the estimated worst-case dispatch depth. Meaningless if Recursive is true.
+	Depth uint32
+
+	// FanOut is the estimated worst-case number of dispatched subproblems, saturating at
+	// math.MaxUint64. Meaningless if Recursive is true.
+	FanOut uint64
+
+	// Recursive is true if the relation or permission (transitively) references itself, in which
+	// case its depth and fan-out are bounded only by the data.
+	Recursive bool
+
+	// ExceedsDepthBudget is true if the permission is recursive or its depth exceeds the budget.
+	ExceedsDepthBudget bool
+
+	// ExceedsFanOutBudget is true if the permission is recursive or its fan-out exceeds the budget.
+	ExceedsFanOutBudget bool
+}
+
+// ExceedsBudget returns whether the permission is likely to exceed any part of the budget.
+func (pc PermissionComplexity) ExceedsBudget() bool {
+	return pc.ExceedsDepthBudget || pc.ExceedsFanOutBudget
+}
+
+// EstimatePermissionComplexity estimates the worst-case dispatch depth and fan-out for every
+// relation and permission defined in the given namespaces, using the given relationship statistics,
+// keyed by `namespace#relation`, and flags those which are likely to exceed the budget.
+//
+// The estimate is an upper bound: it assumes every branch of every expression is evaluated,
+// that every relation holds as many subjects as given in its statistics (or
+// DefaultSubjectsPerResource) and that no results are cached.
+func EstimatePermissionComplexity(
+	nsDefs []*core.NamespaceDefinition,
+	statistics map[string]RelationStatistics,
+	budget ComplexityBudget,
+) ([]PermissionComplexity, error) {
+	ce := &complexityEstimator{
+		relations:  make(map[complexityKey]*core.Relation),
+		statistics: statistics,
+		computed:   make(map[complexityKey]estimate),
+		inProgress: make(map[complexityKey]struct{}),
+	}
+
+	for _, nsDef := range nsDefs {
+		for _, relation := range nsDef.Relation {
+			ce.relations[complexityKey{nsDef.Name, relation.Name}] = relation
+		}
+	}
+
+	keys := make([]complexityKey, 0, len(ce.relations))
+	for key := range ce.relations {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].namespace == keys[j].namespace {
+			return keys[i].relation < keys[j].relation
+		}
+		return keys[i].namespace < keys[j].namespace
+	})
+
+	results := make([]PermissionComplexity, 0, len(keys))
+	for _, key := range keys {
+		est, err := ce.estimateRelation(key)
+		if err != nil {
+			return nil, err
+		}
+
+		results = append(results, PermissionComplexity{
+			Relation:            &core.RelationReference{Namespace: key.namespace, Relation: key.relation},
+			Depth:               est.depth,
+			FanOut:              est.fanOut,
+			Recursive:           est.recursive,
+			ExceedsDepthBudget:  est.recursive || (budget.MaxDepth > 0 && est.depth > budget.MaxDepth),
+			ExceedsFanOutBudget: est.recursive || (budget.MaxFanOut > 0 && est.fanOut > budget.MaxFanOut),
+		})
+	}
+
+	return results, nil
+}
+
+type complexityKey struct {
+	namespace string
+	relation  string
+}
+
+func (rk complexityKey) String() string {
+	return rk.namespace + "#" + rk.relation
+}
+
+type estimate struct {
+	depth     uint32
+	fanOut    uint64
+	recursive bool
+}
+
+func (e estimate) plus(other estimate) estimate {
+	depth := e.depth
+	if other.depth > depth {
+		depth = other.depth
+	}
+
+	return estimate{
+		depth:     depth,
+		fanOut:    saturatingAdd(e.fanOut, other.fanOut),
+		recursive: e.recursive || other.recursive,
+	}
+}
+
+type complexityEstimator struct {
+	relations  map[complexityKey]*core.Relation
+	statistics map[string]RelationStatistics
+	computed   map[complexityKey]estimate
+	inProgress map[complexityKey]struct{}
+}
+
+func (ce *complexityEstimator) subjectsPerResource(key complexityKey) uint64 {
+	if stats, ok := ce.statistics[key.String()]; ok {
+		return stats.MaxSubjectsPerResource
+	}
+	return DefaultSubjectsPerResource
+}
+
+// estimateRelation estimates the cost of checking the relation or permission with the given
+// key, not including the dispatch to the relation itself.
+func (ce *complexityEstimator) estimateRelation(key complexityKey) (estimate, error) {
+	if est, ok := ce.computed[key]; ok {
+		return est, nil
+	}
+
+	if _, ok := ce.inProgress[key]; ok {
+		return estimate{recursive: true}, nil
+	}
+
+	relation, ok := ce.relations[key]
+	if !ok {
+		return estimate{}, fmt.Errorf("unknown relation or permission `%s`", key)
+	}
+
+	ce.inProgress[key] = struct{}{}
+	defer delete(ce.inProgress, key)
+
+	var est estimate
+	var err error
+	if relation.UsersetRewrite == nil {
+		est, err = ce.estimateDirect(key, relation)
+	} else {
+		est, err = ce.estimateRewrite(key.namespace, relation.UsersetRewrite)
+	}
+	if err != nil {
+		return estimate{}, err
+	}
+
+	ce.computed[key] = est
+	return est, nil
+}
+
+// estimateDirect estimates the cost of checking a relation: a single query for the subjects
+// found, followed by a dispatch for each subject set found.
+func (ce *complexityEstimator) estimateDirect(key complexityKey, relation *core.Relation) (estimate, error) {
+	est := estimate{fanOut: 1}
+	for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		if allowed.GetPublicWildcard() != nil || allowed.GetRelation() == tuple.Ellipsis {
+			continue
+		}
+
+		subjectEst, err := ce.dispatchTo(complexityKey{allowed.Namespace, allowed.GetRelation()}, ce.subjectsPerResource(key))
+		if err != nil {
+			return estimate{}, err
+		}
+		est = est.plus(subjectEst)
+	}
+	return est, nil
+}
+
+// dispatchTo estimates the cost of dispatching to the relation with the given key once for each
+// of count resources.
+func (ce *complexityEstimator) dispatchTo(key complexityKey, count uint64) (estimate, error) {
+	target, err := ce.estimateRelation(key)
+	if err != nil {
+		return estimate{}, err
+	}
+
+	return estimate{
+		depth:     saturatingAddDepth(target.depth, 1),
+		fanOut:    saturatingMul(count, target.fanOut),
+		recursive: target.recursive,
+	}, nil
+}
+
+func (ce *complexityEstimator) estimateRewrite(namespaceName string, rewrite *core.UsersetRewrite) (estimate, error) {
+	var operation *core.SetOperation
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		operation = rw.Union
+	case *core.UsersetRewrite_Intersection:
+		operation = rw.Intersection
+	case *core.UsersetRewrite_Exclusion:
+		operation = rw.Exclusion
+	default:
+		return estimate{}, fmt.Errorf("unknown userset rewrite operation %T", rewrite.RewriteOperation)
+	}
+
+	est := estimate{}
+	for _, child := range operation.Child {
+		childEst, err := ce.estimateChild(namespaceName, child)
+		if err != nil {
+			return estimate{}, err
+		}
+		est = est.plus(childEst)
+	}
+	return est, nil
+}
+
+func (ce *complexityEstimator) estimateChild(namespaceName string, child *core.SetOperation_Child) (estimate, error) {
+	switch child := child.ChildType.(type) {
+	case *core.SetOperation_Child_XThis:
+		return estimate{fanOut: 1}, nil
+
+	case *core.SetOperation_Child_XNil:
+		return estimate{}, nil
+
+	case *core.SetOperation_Child_ComputedUserset:
+		return ce.dispatchTo(complexityKey{namespaceName, child.ComputedUserset.Relation}, 1)
+
+	case *core.SetOperation_Child_UsersetRewrite:
+		return ce.estimateRewrite(namespaceName, child.UsersetRewrite)
+
+	case *core.SetOperation_Child_TupleToUserset:
+		tuplesetKey := complexityKey{namespaceName, child.TupleToUserset.Tupleset.Relation}
+		tupleset, ok := ce.relations[tuplesetKey]
+		if !ok {
+			return estimate{}, fmt.Errorf("unknown relation `%s`", tuplesetKey)
+		}
+
+		// Loading the tupleset is a single query, after which a dispatch occurs to the computed
+		// relation on each subject found, if the subject's type defines that relation.
+		est := estimate{fanOut: 1}
+		count := ce.subjectsPerResource(tuplesetKey)
+		for _, allowed := range tupleset.GetTypeInformation().GetAllowedDirectRelations() {
+			targetKey := complexityKey{allowed.Namespace, child.TupleToUserset.ComputedUserset.Relation}
+			if _, ok := ce.relations[targetKey]; !ok {
+				continue
+			}
+
+			targetEst, err := ce.dispatchTo(targetKey, count)
+			if err != nil {
+				return estimate{}, err
+			}
+			est = est.plus(targetEst)
+		}
+		return est, nil
+
+	default:
+		return estimate{}, fmt.Errorf("unknown set operation child %T", child)
+	}
+}
+
+func saturatingAdd(a, b uint64) uint64 {
+	if a > math.MaxUint64-b {
+		return math.MaxUint64
+	}
+	return a + b
+}
+
+func saturatingMul(a, b uint64) uint64 {
+	if a != 0 && b > math.MaxUint64/a {
+		return math.MaxUint64
+	}
+	return a * b
+}
+
+func saturatingAddDepth(a, b uint32) uint32 {
+	if a > math.MaxUint32-b {
+		return math.MaxUint32
+	}
+	return a + b
+}
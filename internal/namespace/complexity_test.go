@@ -0,0 +1,187 @@
+package namespace
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestEstimatePermissionComplexity(t *testing.T) {
+	type expectedComplexity struct {
+		depth     uint32
+		fanOut    uint64
+		recursive bool
+		exceeds   bool
+	}
+
+	testCases := []struct {
+		name       string
+		schema     string
+		statistics map[string]RelationStatistics
+		budget     ComplexityBudget
+		expected   map[string]expectedComplexity
+	}{
+		{
+			"direct relations",
+			`definition user {}
+
+			definition document {
+				relation viewer: user | user:*
+				permission view = viewer
+			}`,
+			nil,
+			ComplexityBudget{},
+			map[string]expectedComplexity{
+				"document#viewer": {0, 1, false, false},
+				"document#view":   {1, 1, false, false},
+			},
+		},
+		{
+			"subject sets and arrows",
+			`definition user {}
+
+			definition group {
+				relation member: user | group#member
+			}
+
+			definition organization {
+				relation admin: user
+			}
+
+			definition document {
+				relation org: organization
+				relation viewer: user | group#member
+				permission view = viewer + org->admin
+			}`,
+			map[string]RelationStatistics{
+				"document#org": {MaxSubjectsPerResource: 2},
+			},
+			ComplexityBudget{MaxDepth: 50},
+			map[string]expectedComplexity{
+				"group#member":       {0, 0, true, true},
+				"organization#admin": {0, 1, false, false},
+				"document#org":       {0, 1, false, false},
+				"document#viewer":    {0, 0, true, true},
+				"document#view":      {0, 0, true, true},
+			},
+		},
+		{
+			"fan out exceeding budget",
+			`definition user {}
+
+			definition folder {
+				relation viewer: user
+			}
+
+			definition document {
+				relation parent: folder
+				relation editor: user
+				permission edit = editor
+				permission view = parent->viewer + edit
+			}`,
+			map[string]RelationStatistics{
+				"document#parent": {MaxSubjectsPerResource: 1000},
+			},
+			ComplexityBudget{MaxDepth: 50, MaxFanOut: 100},
+			map[string]expectedComplexity{
+				"folder#viewer":   {0, 1, false, false},
+				"document#parent": {0, 1, false, false},
+				"document#editor": {0, 1, false, false},
+				"document#edit":   {1, 1, false, false},
+				"document#view":   {2, 1002, false, true},
+			},
+		},
+		{
+			"depth exceeding budget",
+			`definition user {}
+
+			definition document {
+				relation viewer: user
+				permission first = viewer
+				permission second = first
+				permission third = second
+			}`,
+			nil,
+			ComplexityBudget{MaxDepth: 2},
+			map[string]expectedComplexity{
+				"document#viewer": {0, 1, false, false},
+				"document#first":  {1, 1, false, false},
+				"document#second": {2, 1, false, false},
+				"document#third":  {3, 1, false, true},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			empty := ""
+			compiled, err := compiler.Compile(compiler.InputSchema{
+				Source:       input.Source("schema"),
+				SchemaString: tc.schema,
+			}, &empty)
+			require.NoError(err)
+
+			results, err := EstimatePermissionComplexity(compiled.ObjectDefinitions, tc.statistics, tc.budget)
+			require.NoError(err)
+			require.Len(results, len(tc.expected))
+
+			for _, result := range results {
+				key := tuple.StringRR(result.Relation)
+				expected, ok := tc.expected[key]
+				require.True(ok, "missing expectation for %s", key)
+				require.Equal(expected.recursive, result.Recursive, key)
+				require.Equal(expected.exceeds, result.ExceedsBudget(), key)
+				if !expected.recursive {
+					require.Equal(expected.depth, result.Depth, key)
+					require.Equal(expected.fanOut, result.FanOut, key)
+				}
+			}
+		})
+	}
+}
+
+func TestEstimatePermissionComplexitySaturates(t *testing.T) {
+	require := require.New(t)
+
+	empty := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source: input.Source("schema"),
+		SchemaString: `definition user {}
+
+		definition first {
+			relation member: user
+		}
+
+		definition second {
+			relation parent: first
+			permission member = parent->member
+		}
+
+		definition third {
+			relation parent: second
+			permission member = parent->member
+		}`,
+	}, &empty)
+	require.NoError(err)
+
+	results, err := EstimatePermissionComplexity(compiled.ObjectDefinitions, map[string]RelationStatistics{
+		"second#parent": {MaxSubjectsPerResource: math.MaxUint64},
+		"third#parent":  {MaxSubjectsPerResource: math.MaxUint64},
+	}, ComplexityBudget{MaxFanOut: 1000})
+	require.NoError(err)
+
+	for _, result := range results {
+		if tuple.StringRR(result.Relation) == "third#member" {
+			require.Equal(uint64(math.MaxUint64), result.FanOut)
+			require.True(result.ExceedsFanOutBudget)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package namespace
+
+import (
+	"fmt"
+	"sort"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// DependencyKind is the kind of a reference from one definition to another.
+type DependencyKind int
+
+const (
+	// DependencySubjectType is a reference from a relation to a subject type it allows, such as
+	// `group#member` in `relation viewer: group#member`.
+	DependencySubjectType DependencyKind = iota
+
+	// DependencyArrow is a reference from a permission to the relation or permission walked by
+	// an arrow over the subjects of a relation, such as `folder#view` in `parent->view` where
+	// `relation parent: folder`.
+	DependencyArrow
+)
+
+func (dk DependencyKind) String() string {
+	switch dk {
+	case DependencySubjectType:
+		return "subject_type"
+	case DependencyArrow:
+		return "arrow"
+	default:
+		return fmt.Sprintf("DependencyKind(%d)", int(dk))
+	}
+}
+
+// Dependency is a reference from a relation or permission of one definition to another
+// definition.
+type Dependency struct {
+	// Kind is the kind of the reference.
+	Kind DependencyKind
+
+	// From is the relation or permission making the reference.
+	From *core.RelationReference
+
+	// To is the definition referenced and the relation referenced within it, which is the
+	// ellipsis for subject types without a relation and `*` for wildcards.
+	To *core.RelationReference
+}
+
+// String returns a human-readable form of the dependency, such as
+// `document#view -[arrow]-> folder#view`.
+func (d Dependency) String() string {
+	return fmt.Sprintf("%s -[%s]-> %s", tuple.StringRR(d.From), d.Kind, tuple.StringRR(d.To))
+}
+
+// DefinitionDependencies are the dependencies of a definition on other definitions, and of other
+// definitions on it.
+type DefinitionDependencies struct {
+	// Name is the name of the definition.
+	Name string
+
+	// References are the names of the other definitions referenced by the definition, sorted.
+	References []string
+
+	// ReferencedBy are the names of the other definitions referencing the definition, sorted.
+	ReferencedBy []string
+
+	// Outgoing are the references made by the definition to other definitions.
+	Outgoing []Dependency
+
+	// Incoming are the references made to the definition by other definitions.
+	Incoming []Dependency
+}
+
+// DependencyReport is a report of the dependencies between the definitions of a schema.
+type DependencyReport struct {
+	// Definitions are the dependencies of each definition, sorted by name.
+	Definitions []*DefinitionDependencies
+
+	// Cycles are the groups of definitions which (transitively) reference one another, each
+	// sorted by name. Definitions referencing only themselves are not considered cycles, as they
+	// have no bearing on how a schema can be split.
+	Cycles [][]string
+}
+
+// ForDefinition returns the dependencies of the definition with the given name, if found.
+func (dr *DependencyReport) ForDefinition(name string) (*DefinitionDependencies, bool) {
+	index := sort.Search(len(dr.Definitions), func(i int) bool { return dr.Definitions[i].Name >= name })
+	if index < len(dr.Definitions) && dr.Definitions[index].Name == name {
+		return dr.Definitions[index], true
+	}
+	return nil, false
+}
+
+// ComputeDependencyReport computes the dependencies between the given definitions. Only
+// references between different definitions are reported. Arrows are only reported to the
+// definitions which define the relation or permission walked.
+func ComputeDependencyReport(nsDefs []*core.NamespaceDefinition) *DependencyReport {
+	byName := make(map[string]*core.NamespaceDefinition, len(nsDefs))
+	for _, nsDef := range nsDefs {
+		byName[nsDef.Name] = nsDef
+	}
+
+	var dependencies []Dependency
+	for _, nsDef := range nsDefs {
+		for _, relation := range nsDef.Relation {
+			from := &core.RelationReference{Namespace: nsDef.Name, Relation: relation.Name}
+			for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+				to := &core.RelationReference{Namespace: allowed.Namespace, Relation: allowed.GetRelation()}
+				if allowed.GetPublicWildcard() != nil {
+					to.Relation = tuple.PublicWildcard
+				}
+				dependencies = append(dependencies, Dependency{Kind: DependencySubjectType, From: from, To: to})
+			}
+
+			if rewrite := relation.GetUsersetRewrite(); rewrite != nil {
+				dependencies = append(dependencies, arrowDependencies(byName, nsDef, from, rewrite)...)
+			}
+		}
+	}
+
+	perDefinition := make(map[string]*DefinitionDependencies, len(nsDefs))
+	forName := func(name string) *DefinitionDependencies {
+		if _, ok := perDefinition[name]; !ok {
+			perDefinition[name] = &DefinitionDependencies{Name: name, References: []string{}, ReferencedBy: []string{}}
+		}
+		return perDefinition[name]
+	}
+
+	for _, nsDef := range nsDefs {
+		forName(nsDef.Name)
+	}
+
+	references := map[string]map[string]struct{}{}
+	for _, dependency := range dependencies {
+		fromName, toName := dependency.From.Namespace, dependency.To.Namespace
+		if fromName == toName {
+			continue
+		}
+
+		forName(fromName).Outgoing = append(forName(fromName).Outgoing, dependency)
+		forName(toName).Incoming = append(forName(toName).Incoming, dependency)
+
+		if _, ok := references[fromName]; !ok {
+			references[fromName] = map[string]struct{}{}
+		}
+		if _, ok := references[fromName][toName]; !ok {
+			references[fromName][toName] = struct{}{}
+			forName(fromName).References = append(forName(fromName).References, toName)
+			forName(toName).ReferencedBy = append(forName(toName).ReferencedBy, fromName)
+		}
+	}
+
+	report := &DependencyReport{Definitions: make([]*DefinitionDependencies, 0, len(perDefinition))}
+	for _, definition := range perDefinition {
+		sort.Strings(definition.References)
+		sort.Strings(definition.ReferencedBy)
+		report.Definitions = append(report.Definitions, definition)
+	}
+	sort.Slice(report.Definitions, func(i, j int) bool { return report.Definitions[i].Name < report.Definitions[j].Name })
+
+	report.Cycles = findCycles(report.Definitions, references)
+	return report
+}
+
+// arrowDependencies returns the dependencies of the given permission over the arrows found in its
+// rewrite.
+func arrowDependencies(byName map[string]*core.NamespaceDefinition, nsDef *core.NamespaceDefinition, from *core.RelationReference, rewrite *core.UsersetRewrite) []Dependency {
+	var children []*core.SetOperation_Child
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		children = rw.Union.Child
+	case *core.UsersetRewrite_Intersection:
+		children = rw.Intersection.Child
+	case *core.UsersetRewrite_Exclusion:
+		children = rw.Exclusion.Child
+	}
+
+	var dependencies []Dependency
+	for _, childOneof := range children {
+		switch child := childOneof.ChildType.(type) {
+		case *core.SetOperation_Child_UsersetRewrite:
+			dependencies = append(dependencies, arrowDependencies(byName, nsDef, from, child.UsersetRewrite)...)
+
+		case *core.SetOperation_Child_TupleToUserset:
+			var tupleset *core.Relation
+			for _, relation := range nsDef.Relation {
+				if relation.Name == child.TupleToUserset.Tupleset.Relation {
+					tupleset = relation
+				}
+			}
+
+			encountered := map[string]struct{}{}
+			for _, allowed := range tupleset.GetTypeInformation().GetAllowedDirectRelations() {
+				if _, ok := encountered[allowed.Namespace]; ok {
+					continue
+				}
+				encountered[allowed.Namespace] = struct{}{}
+
+				if !definesRelation(byName[allowed.Namespace], child.TupleToUserset.ComputedUserset.Relation) {
+					continue
+				}
+
+				dependencies = append(dependencies, Dependency{
+					Kind: DependencyArrow,
+					From: from,
+					To:   &core.RelationReference{Namespace: allowed.Namespace, Relation: child.TupleToUserset.ComputedUserset.Relation},
+				})
+			}
+		}
+	}
+	return dependencies
+}
+
+func definesRelation(nsDef *core.NamespaceDefinition, relationName string) bool {
+	for _, relation := range nsDef.GetRelation() {
+		if relation.Name == relationName {
+			return true
+		}
+	}
+	return false
+}
+
+// findCycles returns the strongly connected components of more than one definition in the graph
+// of references, found via Tarjan's algorithm.
+func findCycles(definitions []*DefinitionDependencies, references map[string]map[string]struct{}) [][]string {
+	index := 0
+	indexes := map[string]int{}
+	lowLinks := map[string]int{}
+	onStack := map[string]bool{}
+	var stack []string
+	cycles := [][]string{}
+
+	var visit func(name string)
+	visit = func(name string) {
+		indexes[name] = index
+		lowLinks[name] = index
+		index++
+		stack = append(stack, name)
+		onStack[name] = true
+
+		referenced := make([]string, 0, len(references[name]))
+		for toName := range references[name] {
+			referenced = append(referenced, toName)
+		}
+		sort.Strings(referenced)
+
+		for _, toName := range referenced {
+			if _, ok := indexes[toName]; !ok {
+				visit(toName)
+				if lowLinks[toName] < lowLinks[name] {
+					lowLinks[name] = lowLinks[toName]
+				}
+			} else if onStack[toName] && indexes[toName] < lowLinks[name] {
+				lowLinks[name] = indexes[toName]
+			}
+		}
+
+		if lowLinks[name] != indexes[name] {
+			return
+		}
+
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == name {
+				break
+			}
+		}
+
+		if len(component) > 1 {
+			sort.Strings(component)
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, definition := range definitions {
+		if _, ok := indexes[definition.Name]; !ok {
+			visit(definition.Name)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return cycles[i][0] < cycles[j][0] })
+	return cycles
+}
@@ -0,0 +1,148 @@
+package namespace
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+func TestComputeDependencyReport(t *testing.T) {
+	type expectedDefinition struct {
+		references   []string
+		referencedBy []string
+		outgoing     []string
+	}
+
+	testCases := []struct {
+		name                string
+		schema              string
+		expectedDefinitions map[string]expectedDefinition
+		expectedCycles      [][]string
+	}{
+		{
+			"subject types",
+			`definition user {}
+
+			definition group {
+				relation member: user | user:* | group#member
+			}
+
+			definition document {
+				relation viewer: user | group#member
+			}`,
+			map[string]expectedDefinition{
+				"user": {[]string{}, []string{"document", "group"}, nil},
+				"group": {[]string{"user"}, []string{"document"}, []string{
+					"group#member -[subject_type]-> user#...",
+					"group#member -[subject_type]-> user#*",
+				}},
+				"document": {[]string{"group", "user"}, []string{}, []string{
+					"document#viewer -[subject_type]-> user#...",
+					"document#viewer -[subject_type]-> group#member",
+				}},
+			},
+			[][]string{},
+		},
+		{
+			"arrows",
+			`definition user {}
+
+			definition organization {
+				relation admin: user
+			}
+
+			definition folder {
+				relation viewer: user
+				permission view = viewer
+			}
+
+			definition document {
+				relation parent: folder | organization
+				permission view = parent->view
+				permission admin = nil + (parent->admin & parent->view)
+			}`,
+			map[string]expectedDefinition{
+				"user":         {[]string{}, []string{"folder", "organization"}, nil},
+				"organization": {[]string{"user"}, []string{"document"}, []string{"organization#admin -[subject_type]-> user#..."}},
+				"folder":       {[]string{"user"}, []string{"document"}, []string{"folder#viewer -[subject_type]-> user#..."}},
+				"document": {[]string{"folder", "organization"}, []string{}, []string{
+					"document#parent -[subject_type]-> folder#...",
+					"document#parent -[subject_type]-> organization#...",
+					"document#view -[arrow]-> folder#view",
+					"document#admin -[arrow]-> organization#admin",
+					"document#admin -[arrow]-> folder#view",
+				}},
+			},
+			[][]string{},
+		},
+		{
+			"cycles",
+			`definition user {}
+
+			definition team {
+				relation member: user | department#member
+			}
+
+			definition department {
+				relation member: user | division#member
+			}
+
+			definition division {
+				relation member: user
+				relation lead_team: team
+				permission leads = lead_team->member
+			}
+
+			definition project {
+				relation owner: project#owner | user
+			}`,
+			map[string]expectedDefinition{
+				"division": {[]string{"team", "user"}, []string{"department"}, []string{
+					"division#member -[subject_type]-> user#...",
+					"division#lead_team -[subject_type]-> team#...",
+					"division#leads -[arrow]-> team#member",
+				}},
+				"project": {[]string{"user"}, []string{}, []string{"project#owner -[subject_type]-> user#..."}},
+			},
+			[][]string{{"department", "division", "team"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			empty := ""
+			compiled, err := compiler.Compile(compiler.InputSchema{
+				Source:       input.Source("schema"),
+				SchemaString: tc.schema,
+			}, &empty)
+			require.NoError(err)
+
+			report := ComputeDependencyReport(compiled.ObjectDefinitions)
+			require.Len(report.Definitions, len(compiled.ObjectDefinitions))
+
+			for name, expected := range tc.expectedDefinitions {
+				found, ok := report.ForDefinition(name)
+				require.True(ok)
+				require.Equal(expected.references, found.References, name)
+				require.Equal(expected.referencedBy, found.ReferencedBy, name)
+
+				var outgoing []string
+				for _, dependency := range found.Outgoing {
+					outgoing = append(outgoing, dependency.String())
+				}
+				require.Equal(expected.outgoing, outgoing, name)
+			}
+
+			require.Equal(tc.expectedCycles, report.Cycles)
+
+			_, ok := report.ForDefinition("unknown")
+			require.False(ok)
+		})
+	}
+}
@@ -252,6 +252,46 @@ func (err ErrUnusedCaveatParameter) DetailsMetadata() map[string]string {
 	}
 }
 
+// ErrInvalidRelationRename occurs when a relation cannot be renamed.
+type ErrInvalidRelationRename struct {
+	error
+	namespaceName string
+	relationName  string
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrInvalidRelationRename) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("namespace", err.namespaceName).Str("relation", err.relationName)
+}
+
+// DetailsMetadata returns the metadata for details for this error.
+func (err ErrInvalidRelationRename) DetailsMetadata() map[string]string {
+	return map[string]string{
+		"definition_name": err.namespaceName,
+		"relation_name":   err.relationName,
+	}
+}
+
+// ErrInvalidSyntheticRelation occurs when a synthetic relation is invalid.
+type ErrInvalidSyntheticRelation struct {
+	error
+	namespaceName string
+	relationName  string
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrInvalidSyntheticRelation) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("namespace", err.namespaceName).Str("relation", err.relationName)
+}
+
+// DetailsMetadata returns the metadata for details for this error.
+func (err ErrInvalidSyntheticRelation) DetailsMetadata() map[string]string {
+	return map[string]string{
+		"definition_name": err.namespaceName,
+		"relation_name":   err.relationName,
+	}
+}
+
 // NewNamespaceNotFoundErr constructs a new namespace not found error.
 func NewNamespaceNotFoundErr(nsName string) error {
 	return ErrNamespaceNotFound{
@@ -352,6 +392,24 @@ func NewUnusedCaveatParameterErr(caveatName string, paramName string) error {
 	}
 }
 
+// NewInvalidRelationRenameErr constructs an error indicating that a relation cannot be renamed.
+func NewInvalidRelationRenameErr(nsName string, relationName string, reason string, args ...any) error {
+	return ErrInvalidRelationRename{
+		error:         fmt.Errorf("cannot rename relation `%s` under definition `%s`: %s", relationName, nsName, fmt.Sprintf(reason, args...)),
+		namespaceName: nsName,
+		relationName:  relationName,
+	}
+}
+
+// NewInvalidSyntheticRelationErr constructs an error indicating that a synthetic relation is invalid.
+func NewInvalidSyntheticRelationErr(nsName string, relationName string, reason string, args ...any) error {
+	return ErrInvalidSyntheticRelation{
+		error:         fmt.Errorf("invalid synthetic relation `%s` under definition `%s`: %s", relationName, nsName, fmt.Sprintf(reason, args...)),
+		namespaceName: nsName,
+		relationName:  relationName,
+	}
+}
+
 // asTypeError wraps another error in a type error.
 func asTypeError(wrapped error) error {
 	if wrapped == nil {
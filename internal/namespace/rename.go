@@ -0,0 +1,328 @@
+package namespace
+
+import (
+	"github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// StartRelationRename returns the object definitions which must be written to begin renaming the
+// relation `relationName` under definition `nsName` to `newName`.
+//
+// The new relation is added alongside the existing one with the same allowed types, every allowed
+// type referencing the existing relation as a subject relation gains a matching allowed type for
+// the new relation, and the existing relation is marked as being renamed, so that relationships
+// written for it are also written for the new relation. If the rename has already been started,
+// no definitions are returned.
+func StartRelationRename(objectDefs []*core.NamespaceDefinition, nsName, relationName, newName string) ([]*core.NamespaceDefinition, error) {
+	nsDef, relation, err := findRenamedRelation(objectDefs, nsName, relationName)
+	if err != nil {
+		return nil, err
+	}
+
+	if existingNewName, ok := namespace.GetRenaming(relation); ok {
+		if existingNewName != newName {
+			return nil, NewInvalidRelationRenameErr(nsName, relationName, "it is already being renamed to `%s`", existingNewName)
+		}
+		return nil, nil
+	}
+
+	if relation.UsersetRewrite != nil {
+		return nil, NewInvalidRelationRenameErr(nsName, relationName, "only relations can be renamed; permissions can be renamed by writing the schema")
+	}
+
+	for _, existing := range nsDef.Relation {
+		if existing.Name == newName {
+			return nil, NewInvalidRelationRenameErr(nsName, relationName, "relation or permission `%s` already exists", newName)
+		}
+	}
+
+	if err := ensureArrowsUnambiguous(objectDefs, nsName, relationName); err != nil {
+		return nil, err
+	}
+
+	changed := make([]*core.NamespaceDefinition, 0, len(objectDefs))
+	for _, objectDef := range objectDefs {
+		updated := objectDef.CloneVT()
+		hasChanges := updated.Name == nsName
+
+		for _, rel := range updated.Relation {
+			allowedRelations := rel.GetTypeInformation().GetAllowedDirectRelations()
+			withRenamed := make([]*core.AllowedRelation, 0, len(allowedRelations))
+			for _, allowed := range allowedRelations {
+				withRenamed = append(withRenamed, allowed)
+				if allowed.Namespace == nsName && allowed.GetRelation() == relationName {
+					renamed := allowed.CloneVT()
+					renamed.RelationOrWildcard = &core.AllowedRelation_Relation{Relation: newName}
+					withRenamed = append(withRenamed, renamed)
+					hasChanges = true
+				}
+			}
+
+			if rel.TypeInformation != nil {
+				rel.TypeInformation.AllowedDirectRelations = withRenamed
+			}
+		}
+
+		if updated.Name == nsName {
+			for index, rel := range updated.Relation {
+				if rel.Name != relationName {
+					continue
+				}
+
+				newRelation := rel.CloneVT()
+				newRelation.Name = newName
+				newRelation.SourcePosition = nil
+				if err := newRelation.Validate(); err != nil {
+					return nil, NewInvalidRelationRenameErr(nsName, relationName, "invalid new name `%s`", newName)
+				}
+
+				if err := namespace.MarkRenaming(rel, newName); err != nil {
+					return nil, err
+				}
+
+				updated.Relation = append(updated.Relation[:index+1], append([]*core.Relation{newRelation}, updated.Relation[index+1:]...)...)
+				break
+			}
+		}
+
+		if hasChanges {
+			changed = append(changed, updated)
+		}
+	}
+
+	return changed, nil
+}
+
+// FinishRelationRename returns the object definitions which must be written to complete renaming
+// the relation `relationName` under definition `nsName`, which must have been started by writing
+// the definitions returned by StartRelationRename.
+//
+// All references to the existing relation, in permissions, arrows and allowed types, are swapped
+// to the new relation, and the existing relation is removed.
+func FinishRelationRename(objectDefs []*core.NamespaceDefinition, nsName, relationName string) ([]*core.NamespaceDefinition, error) {
+	_, relation, err := findRenamedRelation(objectDefs, nsName, relationName)
+	if err != nil {
+		return nil, err
+	}
+
+	newName, ok := namespace.GetRenaming(relation)
+	if !ok {
+		return nil, NewInvalidRelationRenameErr(nsName, relationName, "it is not being renamed")
+	}
+
+	changed := make([]*core.NamespaceDefinition, 0, len(objectDefs))
+	for _, objectDef := range objectDefs {
+		updated := objectDef.CloneVT()
+		hasChanges := false
+
+		relations := make([]*core.Relation, 0, len(updated.Relation))
+		for _, rel := range updated.Relation {
+			if updated.Name == nsName && rel.Name == relationName {
+				hasChanges = true
+				continue
+			}
+
+			if rel.TypeInformation != nil {
+				allowedRelations := make([]*core.AllowedRelation, 0, len(rel.TypeInformation.AllowedDirectRelations))
+				for _, allowed := range rel.TypeInformation.AllowedDirectRelations {
+					if allowed.Namespace == nsName && allowed.GetRelation() == relationName {
+						hasChanges = true
+						continue
+					}
+					allowedRelations = append(allowedRelations, allowed)
+				}
+				rel.TypeInformation.AllowedDirectRelations = allowedRelations
+			}
+
+			if rel.UsersetRewrite != nil && renameInRewrite(objectDef, rel.UsersetRewrite, nsName, relationName, newName) {
+				hasChanges = true
+			}
+
+			if updated.Name == nsName && rel.AliasingRelation == relationName {
+				rel.AliasingRelation = newName
+				hasChanges = true
+			}
+
+			if updated.Name == nsName {
+				renamedSources, err := renameInSyntheticSources(rel, relationName, newName)
+				if err != nil {
+					return nil, err
+				}
+				hasChanges = hasChanges || renamedSources
+			}
+
+			relations = append(relations, rel)
+		}
+
+		if hasChanges {
+			updated.Relation = relations
+			changed = append(changed, updated)
+		}
+	}
+
+	return changed, nil
+}
+
+// renameInSyntheticSources swaps the renamed relation for the new relation in the sources of the
+// given relation *in place*, if it is synthetic, returning whether it was found.
+func renameInSyntheticSources(relation *core.Relation, relationName, newName string) (bool, error) {
+	sourceRelationNames, ok := namespace.GetSyntheticSources(relation)
+	if !ok {
+		return false, nil
+	}
+
+	renamed := false
+	for index, sourceRelationName := range sourceRelationNames {
+		if sourceRelationName == relationName {
+			sourceRelationNames[index] = newName
+			renamed = true
+		}
+	}
+
+	if !renamed {
+		return false, nil
+	}
+
+	namespace.ClearSynthetic(relation)
+	return true, namespace.MarkSynthetic(relation, sourceRelationNames)
+}
+
+func findRenamedRelation(objectDefs []*core.NamespaceDefinition, nsName, relationName string) (*core.NamespaceDefinition, *core.Relation, error) {
+	for _, objectDef := range objectDefs {
+		if objectDef.Name != nsName {
+			continue
+		}
+
+		for _, relation := range objectDef.Relation {
+			if relation.Name == relationName {
+				return objectDef, relation, nil
+			}
+		}
+		return nil, nil, NewRelationNotFoundErr(nsName, relationName)
+	}
+
+	return nil, nil, NewNamespaceNotFoundErr(nsName)
+}
+
+// ensureArrowsUnambiguous ensures that every arrow walking to the relation being renamed walks
+// only to that relation, so that the arrow can be swapped to the new relation without changing
+// the meaning of any other walk.
+func ensureArrowsUnambiguous(objectDefs []*core.NamespaceDefinition, nsName, relationName string) error {
+	relationsByNamespace := make(map[string]map[string]struct{}, len(objectDefs))
+	for _, objectDef := range objectDefs {
+		relationNames := make(map[string]struct{}, len(objectDef.Relation))
+		for _, rel := range objectDef.Relation {
+			relationNames[rel.Name] = struct{}{}
+		}
+		relationsByNamespace[objectDef.Name] = relationNames
+	}
+
+	for _, objectDef := range objectDefs {
+		for _, rel := range objectDef.Relation {
+			for _, arrow := range arrowsInRewrite(rel.UsersetRewrite) {
+				if arrow.ComputedUserset.Relation != relationName {
+					continue
+				}
+
+				arrowNamespaces := arrowTargetNamespaces(objectDef, arrow)
+				if _, ok := arrowNamespaces[nsName]; !ok {
+					continue
+				}
+
+				for targetNamespace := range arrowNamespaces {
+					if targetNamespace == nsName {
+						continue
+					}
+
+					if _, ok := relationsByNamespace[targetNamespace][relationName]; ok {
+						return NewInvalidRelationRenameErr(nsName, relationName, "arrow `%s->%s` under permission `%s#%s` also walks to `%s#%s`",
+							arrow.Tupleset.Relation, relationName, objectDef.Name, rel.Name, targetNamespace, relationName)
+					}
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// renameInRewrite swaps all references to the renamed relation found in the rewrite *in place*,
+// returning whether any were found. The rewrite must be a copy of one found in the given
+// definition.
+func renameInRewrite(objectDef *core.NamespaceDefinition, rewrite *core.UsersetRewrite, nsName, relationName, newName string) bool {
+	renamed := false
+	for _, childOneof := range rewriteChildren(rewrite) {
+		switch child := childOneof.ChildType.(type) {
+		case *core.SetOperation_Child_UsersetRewrite:
+			if renameInRewrite(objectDef, child.UsersetRewrite, nsName, relationName, newName) {
+				renamed = true
+			}
+
+		case *core.SetOperation_Child_ComputedUserset:
+			if objectDef.Name == nsName && child.ComputedUserset.Relation == relationName {
+				child.ComputedUserset.Relation = newName
+				renamed = true
+			}
+
+		case *core.SetOperation_Child_TupleToUserset:
+			if child.TupleToUserset.ComputedUserset.Relation == relationName {
+				if _, ok := arrowTargetNamespaces(objectDef, child.TupleToUserset)[nsName]; ok {
+					child.TupleToUserset.ComputedUserset.Relation = newName
+					renamed = true
+				}
+			}
+
+			if objectDef.Name == nsName && child.TupleToUserset.Tupleset.Relation == relationName {
+				child.TupleToUserset.Tupleset.Relation = newName
+				renamed = true
+			}
+		}
+	}
+	return renamed
+}
+
+// arrowTargetNamespaces returns the names of the namespaces to which the given arrow, found in the
+// given definition, walks.
+func arrowTargetNamespaces(objectDef *core.NamespaceDefinition, arrow *core.TupleToUserset) map[string]struct{} {
+	targets := map[string]struct{}{}
+	for _, rel := range objectDef.Relation {
+		if rel.Name != arrow.Tupleset.Relation {
+			continue
+		}
+
+		for _, allowed := range rel.GetTypeInformation().GetAllowedDirectRelations() {
+			targets[allowed.Namespace] = struct{}{}
+		}
+	}
+	return targets
+}
+
+func arrowsInRewrite(rewrite *core.UsersetRewrite) []*core.TupleToUserset {
+	if rewrite == nil {
+		return nil
+	}
+
+	var arrows []*core.TupleToUserset
+	for _, childOneof := range rewriteChildren(rewrite) {
+		switch child := childOneof.ChildType.(type) {
+		case *core.SetOperation_Child_UsersetRewrite:
+			arrows = append(arrows, arrowsInRewrite(child.UsersetRewrite)...)
+		case *core.SetOperation_Child_TupleToUserset:
+			arrows = append(arrows, child.TupleToUserset)
+		}
+	}
+	return arrows
+}
+
+func rewriteChildren(rewrite *core.UsersetRewrite) []*core.SetOperation_Child {
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		return rw.Union.Child
+	case *core.UsersetRewrite_Intersection:
+		return rw.Intersection.Child
+	case *core.UsersetRewrite_Exclusion:
+		return rw.Exclusion.Child
+	default:
+		return nil
+	}
+}
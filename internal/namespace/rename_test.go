@@ -0,0 +1,309 @@
+package namespace
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+func TestRelationRename(t *testing.T) {
+	testCases := []struct {
+		name             string
+		schema           string
+		nsName           string
+		relationName     string
+		newName          string
+		expectedError    string
+		expectedStarted  string
+		expectedFinished string
+	}{
+		{
+			"direct relation",
+			`definition user {}
+
+			definition document {
+				relation viewer: user
+				permission view = viewer
+			}`,
+			"document",
+			"viewer",
+			"reader",
+			"",
+			`definition document {
+	relation viewer: user
+	relation reader: user
+	permission view = viewer
+}
+
+definition user {}`,
+			`definition document {
+	relation reader: user
+	permission view = reader
+}
+
+definition user {}`,
+		},
+		{
+			"subject relation and arrows",
+			`definition user {}
+
+			definition group {
+				relation member: user | group#member
+				relation parent: group
+				permission view = member + parent->member
+			}
+
+			definition document {
+				relation viewer: user | group#member
+				relation owner: group
+				permission view = viewer + owner->member
+			}`,
+			"group",
+			"member",
+			"participant",
+			"",
+			`definition document {
+	relation viewer: user | group#member | group#participant
+	relation owner: group
+	permission view = viewer + owner->member
+}
+
+definition group {
+	relation member: user | group#member | group#participant
+	relation participant: user | group#member | group#participant
+	relation parent: group
+	permission view = member + parent->member
+}
+
+definition user {}`,
+			`definition document {
+	relation viewer: user | group#participant
+	relation owner: group
+	permission view = viewer + owner->participant
+}
+
+definition group {
+	relation participant: user | group#participant
+	relation parent: group
+	permission view = participant + parent->participant
+}
+
+definition user {}`,
+		},
+		{
+			"renamed tupleset",
+			`definition user {}
+
+			definition folder {
+				relation viewer: user
+			}
+
+			definition document {
+				relation parent: folder
+				permission view = parent->viewer
+			}`,
+			"document",
+			"parent",
+			"folder",
+			"",
+			`definition document {
+	relation parent: folder
+	relation folder: folder
+	permission view = parent->viewer
+}
+
+definition folder {
+	relation viewer: user
+}
+
+definition user {}`,
+			`definition document {
+	relation folder: folder
+	permission view = folder->viewer
+}
+
+definition folder {
+	relation viewer: user
+}
+
+definition user {}`,
+		},
+		{
+			"ambiguous arrow",
+			`definition user {}
+
+			definition folder {
+				relation viewer: user
+			}
+
+			definition organization {
+				relation viewer: user
+			}
+
+			definition document {
+				relation parent: folder | organization
+				permission view = parent->viewer
+			}`,
+			"folder",
+			"viewer",
+			"reader",
+			"cannot rename relation `viewer` under definition `folder`: arrow `parent->viewer` under permission `document#view` also walks to `organization#viewer`",
+			"",
+			"",
+		},
+		{
+			"permission",
+			`definition user {}
+
+			definition document {
+				relation viewer: user
+				permission view = viewer
+			}`,
+			"document",
+			"view",
+			"read",
+			"cannot rename relation `view` under definition `document`: only relations can be renamed; permissions can be renamed by writing the schema",
+			"",
+			"",
+		},
+		{
+			"existing name",
+			`definition user {}
+
+			definition document {
+				relation viewer: user
+				permission view = viewer
+			}`,
+			"document",
+			"viewer",
+			"view",
+			"cannot rename relation `viewer` under definition `document`: relation or permission `view` already exists",
+			"",
+			"",
+		},
+		{
+			"synthetic relation source",
+			`definition user {}
+
+			definition document {
+				relation viewer: user
+				relation editor: user
+				relation any_member: user = viewer + editor
+			}`,
+			"document",
+			"viewer",
+			"reader",
+			"",
+			`definition document {
+	relation viewer: user
+	relation reader: user
+	relation editor: user
+	relation any_member: user = viewer + editor
+}
+
+definition user {}`,
+			`definition document {
+	relation reader: user
+	relation editor: user
+	relation any_member: user = reader + editor
+}
+
+definition user {}`,
+		},
+		{
+			"invalid name",
+			`definition user {}
+
+			definition document {
+				relation viewer: user
+			}`,
+			"document",
+			"viewer",
+			"Reader",
+			"cannot rename relation `viewer` under definition `document`: invalid new name `Reader`",
+			"",
+			"",
+		},
+		{
+			"unknown relation",
+			`definition document {}`,
+			"document",
+			"viewer",
+			"reader",
+			"relation/permission `viewer` not found under definition `document`",
+			"",
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			empty := ""
+			compiled, err := compiler.Compile(compiler.InputSchema{
+				Source:       input.Source("schema"),
+				SchemaString: tc.schema,
+			}, &empty)
+			require.NoError(err)
+
+			changed, err := StartRelationRename(compiled.ObjectDefinitions, tc.nsName, tc.relationName, tc.newName)
+			if tc.expectedError != "" {
+				require.EqualError(err, tc.expectedError)
+				return
+			}
+			require.NoError(err)
+
+			started := withChangedDefinitions(compiled.ObjectDefinitions, changed)
+			require.Equal(tc.expectedStarted, generateSortedSchema(started))
+
+			// Starting again is a no-op.
+			changed, err = StartRelationRename(started, tc.nsName, tc.relationName, tc.newName)
+			require.NoError(err)
+			require.Empty(changed)
+
+			changed, err = FinishRelationRename(started, tc.nsName, tc.relationName)
+			require.NoError(err)
+			require.Equal(tc.expectedFinished, generateSortedSchema(withChangedDefinitions(started, changed)))
+		})
+	}
+}
+
+func withChangedDefinitions(objectDefs []*core.NamespaceDefinition, changed []*core.NamespaceDefinition) []*core.NamespaceDefinition {
+	byName := make(map[string]*core.NamespaceDefinition, len(changed))
+	for _, changedDef := range changed {
+		byName[changedDef.Name] = changedDef
+	}
+
+	updated := make([]*core.NamespaceDefinition, 0, len(objectDefs))
+	for _, objectDef := range objectDefs {
+		if changedDef, ok := byName[objectDef.Name]; ok {
+			updated = append(updated, changedDef)
+			continue
+		}
+		updated = append(updated, objectDef)
+	}
+	return updated
+}
+
+func generateSortedSchema(objectDefs []*core.NamespaceDefinition) string {
+	sorted := append([]*core.NamespaceDefinition{}, objectDefs...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	definitions := make([]compiler.SchemaDefinition, 0, len(sorted))
+	for _, objectDef := range sorted {
+		definitions = append(definitions, objectDef)
+	}
+
+	schema, _ := generator.GenerateSchema(definitions)
+	return schema
+}
@@ -126,6 +126,33 @@ func (nts *TypeSystem) IsPermission(relationName string) bool {
 	return nspkg.GetRelationKind(found) == iv1.RelationMetadata_PERMISSION
 }
 
+// IsDeprecated returns whether the definition or the given relation or permission within it has
+// been marked as deprecated in the schema and, if so, the reason given for the deprecation.
+func (nts *TypeSystem) IsDeprecated(relationName string) (string, bool) {
+	if reason, ok := nspkg.GetDeprecation(nts.nsDef.Metadata); ok {
+		return reason, true
+	}
+
+	found, ok := nts.relationMap[relationName]
+	if !ok {
+		return "", false
+	}
+
+	return nspkg.GetDeprecation(found.Metadata)
+}
+
+// IsSynthetic returns whether the given relation is a synthetic relation, whose relationships are
+// materialized by SpiceDB from those of its source relations.
+func (nts *TypeSystem) IsSynthetic(relationName string) bool {
+	found, ok := nts.relationMap[relationName]
+	if !ok {
+		return false
+	}
+
+	_, ok = nspkg.GetSyntheticSources(found)
+	return ok
+}
+
 // IsAllowedPublicNamespace returns whether the target namespace is defined as public on the source relation.
 func (nts *TypeSystem) IsAllowedPublicNamespace(sourceRelationName string, targetNamespaceName string) (AllowedPublicSubject, error) {
 	found, ok := nts.relationMap[sourceRelationName]
@@ -510,11 +537,79 @@ func (nts *TypeSystem) Validate(ctx context.Context) (*ValidatedNamespaceTypeSys
 				}
 			}
 		}
+
+		// Validate the source relations of synthetic relations.
+		if err := nts.validateSyntheticRelation(relation); err != nil {
+			return nil, err
+		}
 	}
 
 	return &ValidatedNamespaceTypeSystem{nts}, nil
 }
 
+// validateSyntheticRelation ensures that, if the relation is synthetic, its source relations are
+// direct relations in the same definition, none of which allow subject types not allowed by the
+// synthetic relation or caveated subject types.
+func (nts *TypeSystem) validateSyntheticRelation(relation *core.Relation) error {
+	sourceRelationNames, ok := nspkg.GetSyntheticSources(relation)
+	if !ok {
+		return nil
+	}
+
+	allowedSources := util.NewSet[string]()
+	for _, allowedRelation := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		allowedSources.Add(SourceForAllowedRelation(allowedRelation))
+	}
+
+	for _, sourceRelationName := range sourceRelationNames {
+		sourceRelation, ok := nts.relationMap[sourceRelationName]
+		if !ok {
+			return newTypeErrorWithSource(
+				NewRelationNotFoundErr(nts.nsDef.Name, sourceRelationName),
+				relation,
+				sourceRelationName,
+			)
+		}
+
+		if sourceRelation.UsersetRewrite != nil {
+			return newTypeErrorWithSource(
+				NewInvalidSyntheticRelationErr(nts.nsDef.Name, relation.Name, "`%s` is a permission, but only relations can be sources", sourceRelationName),
+				relation,
+				sourceRelationName,
+			)
+		}
+
+		if _, ok := nspkg.GetSyntheticSources(sourceRelation); ok {
+			return newTypeErrorWithSource(
+				NewInvalidSyntheticRelationErr(nts.nsDef.Name, relation.Name, "`%s` is itself a synthetic relation", sourceRelationName),
+				relation,
+				sourceRelationName,
+			)
+		}
+
+		for _, allowedRelation := range sourceRelation.GetTypeInformation().GetAllowedDirectRelations() {
+			source := SourceForAllowedRelation(allowedRelation)
+			if allowedRelation.GetRequiredCaveat() != nil {
+				return newTypeErrorWithSource(
+					NewInvalidSyntheticRelationErr(nts.nsDef.Name, relation.Name, "source relation `%s` allows caveated subject type `%s`", sourceRelationName, source),
+					relation,
+					sourceRelationName,
+				)
+			}
+
+			if !allowedSources.Has(source) {
+				return newTypeErrorWithSource(
+					NewInvalidSyntheticRelationErr(nts.nsDef.Name, relation.Name, "subject type `%s` of source relation `%s` is not allowed", source, sourceRelationName),
+					relation,
+					sourceRelationName,
+				)
+			}
+		}
+	}
+
+	return nil
+}
+
 // SourceForAllowedRelation returns the source code representation of an allowed relation.
 func SourceForAllowedRelation(allowedRelation *core.AllowedRelation) string {
 	caveatStr := ""
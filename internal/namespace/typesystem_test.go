@@ -13,6 +13,8 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/memdb"
 	"github.com/authzed/spicedb/pkg/datastore"
 	ns "github.com/authzed/spicedb/pkg/namespace"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
 )
 
 func TestTypeSystem(t *testing.T) {
@@ -382,3 +384,172 @@ func TestTypeSystem(t *testing.T) {
 		})
 	}
 }
+
+func TestIsDeprecated(t *testing.T) {
+	require := require.New(t)
+
+	empty := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source: input.Source("schema"),
+		SchemaString: `definition user {}
+
+/** @deprecated use document instead */
+definition olddocument {
+	relation viewer: user
+}
+
+definition document {
+	// @deprecated use viewer instead
+	relation reader: user
+
+	/**
+	 * the viewer of the document
+	 */
+	relation viewer: user
+
+	permission view = viewer + reader
+}`,
+	}, &empty)
+	require.NoError(err)
+
+	resolver := ResolverForPredefinedDefinitions(PredefinedElements{Namespaces: compiled.ObjectDefinitions})
+
+	oldts, err := NewNamespaceTypeSystem(compiled.ObjectDefinitions[1], resolver)
+	require.NoError(err)
+
+	reason, deprecated := oldts.IsDeprecated("viewer")
+	require.True(deprecated)
+	require.Equal("use document instead", reason)
+
+	ts, err := NewNamespaceTypeSystem(compiled.ObjectDefinitions[2], resolver)
+	require.NoError(err)
+
+	reason, deprecated = ts.IsDeprecated("reader")
+	require.True(deprecated)
+	require.Equal("use viewer instead", reason)
+
+	_, deprecated = ts.IsDeprecated("viewer")
+	require.False(deprecated)
+
+	_, deprecated = ts.IsDeprecated("view")
+	require.False(deprecated)
+
+	_, deprecated = ts.IsDeprecated("unknown")
+	require.False(deprecated)
+}
+
+func TestSyntheticRelations(t *testing.T) {
+	tcs := []struct {
+		name          string
+		schema        string
+		expectedError string
+	}{
+		{
+			"valid synthetic relation",
+			`definition user {}
+			definition team {
+				relation member: user
+			}
+			definition document {
+				relation admin: user
+				relation viewer: user | team#member
+				relation any_member: user | team#member = admin + viewer
+			}`,
+			"",
+		},
+		{
+			"unknown source relation",
+			`definition user {}
+			definition document {
+				relation admin: user
+				relation any_member: user = admin + viewer
+			}`,
+			"relation/permission `viewer` not found under definition `document`",
+		},
+		{
+			"permission source",
+			`definition user {}
+			definition document {
+				relation admin: user
+				permission view = admin
+				relation any_member: user = admin + view
+			}`,
+			"invalid synthetic relation `any_member` under definition `document`: `view` is a permission, but only relations can be sources",
+		},
+		{
+			"synthetic source",
+			`definition user {}
+			definition document {
+				relation admin: user
+				relation admins: user = admin
+				relation any_member: user = admins
+			}`,
+			"invalid synthetic relation `any_member` under definition `document`: `admins` is itself a synthetic relation",
+		},
+		{
+			"source subject type not allowed",
+			`definition user {}
+			definition team {
+				relation member: user
+			}
+			definition document {
+				relation admin: user
+				relation viewer: user | team#member
+				relation any_member: user = admin + viewer
+			}`,
+			"invalid synthetic relation `any_member` under definition `document`: subject type `team#member` of source relation `viewer` is not allowed",
+		},
+		{
+			"caveated source subject type",
+			`definition user {}
+			caveat somecaveat(somecondition int) {
+				somecondition == 42
+			}
+			definition document {
+				relation admin: user with somecaveat
+				relation any_member: user with somecaveat = admin
+			}`,
+			"invalid synthetic relation `any_member` under definition `document`: source relation `admin` allows caveated subject type `user with somecaveat`",
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			empty := ""
+			compiled, err := compiler.Compile(compiler.InputSchema{
+				Source:       input.Source("schema"),
+				SchemaString: tc.schema,
+			}, &empty)
+			require.NoError(err)
+
+			resolver := ResolverForPredefinedDefinitions(PredefinedElements{
+				Namespaces: compiled.ObjectDefinitions,
+				Caveats:    compiled.CaveatDefinitions,
+			})
+
+			var verr error
+			for _, nsDef := range compiled.ObjectDefinitions {
+				ts, err := NewNamespaceTypeSystem(nsDef, resolver)
+				require.NoError(err)
+
+				if _, verr = ts.Validate(context.Background()); verr != nil {
+					break
+				}
+
+				if nsDef.Name == "document" {
+					require.True(ts.IsSynthetic("any_member"))
+					require.False(ts.IsSynthetic("admin"))
+				}
+			}
+
+			if tc.expectedError == "" {
+				require.NoError(verr)
+			} else {
+				require.EqualError(verr, tc.expectedError)
+			}
+		})
+	}
+}
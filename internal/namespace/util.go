@@ -6,9 +6,44 @@ import (
 	"github.com/authzed/spicedb/pkg/util"
 
 	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
+// ReadLiveNamespace reads a namespace definition and the revision at which it was last written,
+// treating namespaces soft-deleted from the schema (tombstoned) as not found.
+//
+// Returns datastore.ErrNamespaceNotFound if the namespace cannot be found or is tombstoned.
+func ReadLiveNamespace(ctx context.Context, ds datastore.Reader, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	nsDef, lastWritten, err := ds.ReadNamespace(ctx, nsName)
+	if err != nil {
+		return nil, datastore.NoRevision, err
+	}
+
+	if nspkg.IsTombstoned(nsDef) {
+		return nil, datastore.NoRevision, datastore.NewNamespaceNotFoundErr(nsName)
+	}
+
+	return nsDef, lastWritten, nil
+}
+
+// ListLiveNamespaces lists the namespace definitions found in the datastore, excluding any which
+// have been soft-deleted from the schema (tombstoned).
+func ListLiveNamespaces(ctx context.Context, ds datastore.Reader) ([]*core.NamespaceDefinition, error) {
+	nsDefs, err := ds.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	live := make([]*core.NamespaceDefinition, 0, len(nsDefs))
+	for _, nsDef := range nsDefs {
+		if !nspkg.IsTombstoned(nsDef) {
+			live = append(live, nsDef)
+		}
+	}
+	return live, nil
+}
+
 // ReadNamespaceAndRelation checks that the specified namespace and relation exist in the
 // datastore.
 //
@@ -21,7 +56,7 @@ func ReadNamespaceAndRelation(
 	relation string,
 	ds datastore.Reader,
 ) (*core.NamespaceDefinition, *core.Relation, error) {
-	config, _, err := ds.ReadNamespace(ctx, namespace)
+	config, _, err := ReadLiveNamespace(ctx, ds, namespace)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -48,7 +83,7 @@ func CheckNamespaceAndRelation(
 	allowEllipsis bool,
 	ds datastore.Reader,
 ) error {
-	config, _, err := ds.ReadNamespace(ctx, namespace)
+	config, _, err := ReadLiveNamespace(ctx, ds, namespace)
 	if err != nil {
 		return err
 	}
@@ -72,7 +107,7 @@ func ReadNamespaceAndTypes(
 	nsName string,
 	ds datastore.Reader,
 ) (*core.NamespaceDefinition, *TypeSystem, error) {
-	nsDef, _, err := ds.ReadNamespace(ctx, nsName)
+	nsDef, _, err := ReadLiveNamespace(ctx, ds, nsName)
 	if err != nil {
 		return nil, nil, err
 	}
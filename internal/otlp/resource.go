@@ -0,0 +1,20 @@
+// Package otlp holds what the exports of telemetry over OTLP have in common.
+package otlp
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/resource"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+)
+
+// NewResource returns the resource describing the server with the given attributes, whose
+// service name defaults to `spicedb`.
+func NewResource(attrs map[string]string) *resource.Resource {
+	kvs := make([]attribute.KeyValue, 0, len(attrs)+1)
+	kvs = append(kvs, semconv.ServiceNameKey.String("spicedb"))
+	for key, value := range attrs {
+		// Attributes given later win over those with the same key given earlier.
+		kvs = append(kvs, attribute.String(key, value))
+	}
+	return resource.NewSchemaless(kvs...)
+}
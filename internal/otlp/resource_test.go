@@ -0,0 +1,38 @@
+package otlp
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewResource(t *testing.T) {
+	testCases := []struct {
+		name     string
+		attrs    map[string]string
+		expected map[string]string
+	}{
+		{"default service name", nil, map[string]string{"service.name": "spicedb"}},
+		{
+			"additional attributes",
+			map[string]string{"deployment.environment": "test"},
+			map[string]string{"service.name": "spicedb", "deployment.environment": "test"},
+		},
+		{
+			"service name",
+			map[string]string{"service.name": "authz", "deployment.environment": "test"},
+			map[string]string{"service.name": "authz", "deployment.environment": "test"},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			found := make(map[string]string)
+			for _, kv := range NewResource(tc.attrs).Attributes() {
+				found[string(kv.Key)] = kv.Value.AsString()
+			}
+			require.Equal(t, tc.expected, found)
+		})
+	}
+}
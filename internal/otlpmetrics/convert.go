@@ -0,0 +1,189 @@
+package otlpmetrics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// point is the last exported value of a series, from which the deltas of the next export are
+// computed.
+type point struct {
+	value   float64
+	count   uint64
+	sum     float64
+	buckets []uint64
+}
+
+// converter converts gathered Prometheus metric families into the metrics of the OpenTelemetry
+// SDK.
+type converter struct {
+	temporality metricdata.Temporality
+
+	// start is the start time of the cumulative series, and of the deltas of the first export.
+	start time.Time
+
+	// previous holds the exported values of the series for delta temporality, keyed by series,
+	// and previousAt the time at which they were exported.
+	previous   map[string]point
+	previousAt time.Time
+}
+
+// convert returns the metrics of the metric families at the given time, along with the values of
+// their series, which become the previous values once the metrics are exported. Summaries, which
+// the metrics of the SDK cannot represent, are skipped.
+func (c *converter) convert(families []*dto.MetricFamily, now time.Time) ([]metricdata.Metrics, map[string]point) {
+	current := make(map[string]point)
+	metrics := make([]metricdata.Metrics, 0, len(families))
+	for _, family := range families {
+		metric := metricdata.Metrics{
+			Name:        family.GetName(),
+			Description: family.GetHelp(),
+		}
+
+		switch family.GetType() {
+		case dto.MetricType_COUNTER:
+			sum := metricdata.Sum[float64]{Temporality: c.temporality, IsMonotonic: true}
+			for _, m := range family.Metric {
+				key := seriesKey(family.GetName(), m.Label)
+				value := m.GetCounter().GetValue()
+				current[key] = point{value: value}
+
+				start := c.start
+				if c.delta() {
+					start = c.lastExport()
+					if previous, ok := c.previous[key]; ok && value >= previous.value {
+						value -= previous.value
+					}
+				}
+				sum.DataPoints = append(sum.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: attributes(m.Label),
+					StartTime:  start,
+					Time:       now,
+					Value:      value,
+				})
+			}
+			metric.Data = sum
+
+		case dto.MetricType_GAUGE, dto.MetricType_UNTYPED:
+			gauge := metricdata.Gauge[float64]{}
+			for _, m := range family.Metric {
+				value := m.GetGauge().GetValue()
+				if family.GetType() == dto.MetricType_UNTYPED {
+					value = m.GetUntyped().GetValue()
+				}
+				gauge.DataPoints = append(gauge.DataPoints, metricdata.DataPoint[float64]{
+					Attributes: attributes(m.Label),
+					Time:       now,
+					Value:      value,
+				})
+			}
+			metric.Data = gauge
+
+		case dto.MetricType_HISTOGRAM:
+			histogram := metricdata.Histogram{Temporality: c.temporality}
+			for _, m := range family.Metric {
+				key := seriesKey(family.GetName(), m.Label)
+				h := m.GetHistogram()
+
+				// Prometheus buckets are cumulative, whereas OTLP buckets count the observations
+				// above the previous bound, with a last bucket for those above all bounds.
+				var bounds []float64
+				buckets := make([]uint64, 0, len(h.Bucket)+1)
+				var below uint64
+				for _, b := range h.Bucket {
+					if math.IsInf(b.GetUpperBound(), 1) {
+						continue
+					}
+					bounds = append(bounds, b.GetUpperBound())
+					buckets = append(buckets, b.GetCumulativeCount()-below)
+					below = b.GetCumulativeCount()
+				}
+				buckets = append(buckets, h.GetSampleCount()-below)
+
+				count, sampleSum := h.GetSampleCount(), h.GetSampleSum()
+				current[key] = point{count: count, sum: sampleSum, buckets: buckets}
+
+				start := c.start
+				if c.delta() {
+					start = c.lastExport()
+					if previous, ok := c.previous[key]; ok && count >= previous.count && len(previous.buckets) == len(buckets) {
+						count -= previous.count
+						sampleSum -= previous.sum
+						deltas := make([]uint64, len(buckets))
+						for index := range buckets {
+							deltas[index] = buckets[index] - previous.buckets[index]
+						}
+						buckets = deltas
+					}
+				}
+				histogram.DataPoints = append(histogram.DataPoints, metricdata.HistogramDataPoint{
+					Attributes:   attributes(m.Label),
+					StartTime:    start,
+					Time:         now,
+					Count:        count,
+					Sum:          sampleSum,
+					BucketCounts: buckets,
+					Bounds:       bounds,
+				})
+			}
+			metric.Data = histogram
+
+		default:
+			continue
+		}
+
+		metrics = append(metrics, metric)
+	}
+	return metrics, current
+}
+
+func (c *converter) delta() bool {
+	return c.temporality == metricdata.DeltaTemporality
+}
+
+// lastExport returns the time at which the previous values were exported, which starts the
+// deltas of the current export.
+func (c *converter) lastExport() time.Time {
+	if c.previous == nil {
+		return c.start
+	}
+	return c.previousAt
+}
+
+// commit records the values of the series once they are exported.
+func (c *converter) commit(current map[string]point, at time.Time) {
+	c.previous, c.previousAt = current, at
+}
+
+func seriesKey(name string, labels []*dto.LabelPair) string {
+	var sb strings.Builder
+	sb.WriteString(name)
+	for _, label := range sortedLabels(labels) {
+		sb.WriteByte(0xff)
+		sb.WriteString(label.GetName())
+		sb.WriteByte(0xfe)
+		sb.WriteString(label.GetValue())
+	}
+	return sb.String()
+}
+
+func sortedLabels(labels []*dto.LabelPair) []*dto.LabelPair {
+	sorted := make([]*dto.LabelPair, len(labels))
+	copy(sorted, labels)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].GetName() < sorted[j].GetName() })
+	return sorted
+}
+
+func attributes(labels []*dto.LabelPair) attribute.Set {
+	attrs := make([]attribute.KeyValue, 0, len(labels))
+	for _, label := range labels {
+		attrs = append(attrs, attribute.String(label.GetName(), label.GetValue()))
+	}
+	return attribute.NewSet(attrs...)
+}
@@ -0,0 +1,230 @@
+// Package otlpmetrics pushes the metrics served by the Prometheus endpoint of SpiceDB to an
+// OpenTelemetry collector or backend over OTLP, so that they can be collected without scraping.
+package otlpmetrics
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/sdk/instrumentation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/resource"
+	"google.golang.org/grpc/credentials"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/otlp"
+)
+
+const (
+	// ProtocolGRPC exports metrics with OTLP over gRPC.
+	ProtocolGRPC = "grpc"
+
+	// ProtocolHTTP exports metrics with OTLP over HTTP, encoded as protobuf.
+	ProtocolHTTP = "http/protobuf"
+
+	// TemporalityCumulative exports the values of counters and histograms since the start of the
+	// server.
+	TemporalityCumulative = "cumulative"
+
+	// TemporalityDelta exports the changes of the values of counters and histograms since their
+	// previous export.
+	TemporalityDelta = "delta"
+
+	// DefaultInterval is the default interval between exports.
+	DefaultInterval = time.Minute
+
+	// exportTimeout is the timeout of each export.
+	exportTimeout = 10 * time.Second
+
+	// scopeName is the name of the instrumentation scope of the exported metrics.
+	scopeName = "github.com/authzed/spicedb"
+)
+
+// Config configures the export of metrics over OTLP.
+type Config struct {
+	// Endpoint is the address of the collector for gRPC, or its URL for HTTP, to which `/v1/metrics`
+	// is appended if it has no path.
+	Endpoint string
+
+	// Protocol is either ProtocolGRPC or ProtocolHTTP.
+	Protocol string
+
+	// Insecure disables TLS.
+	Insecure bool
+
+	// Headers are sent with each export, such as to authenticate to the backend.
+	Headers map[string]string
+
+	// Interval is the interval between exports.
+	Interval time.Duration
+
+	// Temporality is either TemporalityCumulative or TemporalityDelta.
+	Temporality string
+
+	// ResourceAttributes describe the exporting server, in addition to its service name.
+	ResourceAttributes map[string]string
+}
+
+// Exporter periodically gathers metrics and exports them over OTLP, with the OTLP metric exporter
+// of the OpenTelemetry SDK.
+type Exporter struct {
+	config    Config
+	gatherer  prometheus.Gatherer
+	resource  *resource.Resource
+	converter *converter
+	exporter  sdkmetric.Exporter
+}
+
+// NewExporter creates a new exporter of the metrics of the gatherer, or errors if the
+// configuration was invalid.
+func NewExporter(config Config, gatherer prometheus.Gatherer) (*Exporter, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("an OTLP endpoint is required to export metrics")
+	}
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("invalid OTLP metrics export interval: %s", config.Interval)
+	}
+
+	var temporality metricdata.Temporality
+	switch config.Temporality {
+	case TemporalityCumulative, "":
+		temporality = metricdata.CumulativeTemporality
+	case TemporalityDelta:
+		temporality = metricdata.DeltaTemporality
+	default:
+		return nil, fmt.Errorf("unknown OTLP metrics temporality %q: must be %s or %s", config.Temporality, TemporalityCumulative, TemporalityDelta)
+	}
+
+	var exporter sdkmetric.Exporter
+	var err error
+	switch config.Protocol {
+	case ProtocolGRPC, "":
+		exporter, err = newGRPCExporter(config)
+	case ProtocolHTTP:
+		exporter, err = newHTTPExporter(config)
+	default:
+		return nil, fmt.Errorf("unknown OTLP protocol %q: must be %s or %s", config.Protocol, ProtocolGRPC, ProtocolHTTP)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP metrics exporter: %w", err)
+	}
+
+	return &Exporter{
+		config:    config,
+		gatherer:  gatherer,
+		resource:  otlp.NewResource(config.ResourceAttributes),
+		converter: &converter{temporality: temporality, start: time.Now()},
+		exporter:  exporter,
+	}, nil
+}
+
+func newGRPCExporter(config Config) (sdkmetric.Exporter, error) {
+	opts := []otlpmetricgrpc.Option{
+		otlpmetricgrpc.WithEndpoint(config.Endpoint),
+		otlpmetricgrpc.WithHeaders(config.Headers),
+		otlpmetricgrpc.WithTimeout(exportTimeout),
+	}
+	if config.Insecure {
+		opts = append(opts, otlpmetricgrpc.WithInsecure())
+	} else {
+		opts = append(opts, otlpmetricgrpc.WithTLSCredentials(credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})))
+	}
+	return otlpmetricgrpc.New(context.Background(), opts...)
+}
+
+func newHTTPExporter(config Config) (sdkmetric.Exporter, error) {
+	endpoint := config.Endpoint
+	if !strings.Contains(endpoint, "://") {
+		if config.Insecure {
+			endpoint = "http://" + endpoint
+		} else {
+			endpoint = "https://" + endpoint
+		}
+	}
+	endpointURL, err := url.Parse(endpoint)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OTLP endpoint: %w", err)
+	}
+	if endpointURL.Path == "" || endpointURL.Path == "/" {
+		endpointURL.Path = "/v1/metrics"
+	}
+
+	opts := []otlpmetrichttp.Option{
+		otlpmetrichttp.WithEndpoint(endpointURL.Host),
+		otlpmetrichttp.WithURLPath(endpointURL.Path),
+		otlpmetrichttp.WithHeaders(config.Headers),
+		otlpmetrichttp.WithTimeout(exportTimeout),
+	}
+	if endpointURL.Scheme == "http" {
+		opts = append(opts, otlpmetrichttp.WithInsecure())
+	} else {
+		opts = append(opts, otlpmetrichttp.WithTLSClientConfig(&tls.Config{MinVersion: tls.VersionTLS12}))
+	}
+	return otlpmetrichttp.New(context.Background(), opts...)
+}
+
+// Export gathers the metrics and exports them.
+func (e *Exporter) Export(ctx context.Context) error {
+	families, err := e.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("failed to gather metrics: %w", err)
+	}
+
+	now := time.Now()
+	metrics, current := e.converter.convert(families, now)
+	ctx, cancel := context.WithTimeout(ctx, exportTimeout)
+	defer cancel()
+
+	if err := e.exporter.Export(ctx, metricdata.ResourceMetrics{
+		Resource: e.resource,
+		ScopeMetrics: []metricdata.ScopeMetrics{{
+			Scope:   instrumentation.Scope{Name: scopeName},
+			Metrics: metrics,
+		}},
+	}); err != nil {
+		return err
+	}
+	e.converter.commit(current, now)
+	return nil
+}
+
+// Run exports the metrics at every interval until the context is canceled, when the metrics are
+// exported a last time.
+func (e *Exporter) Run(ctx context.Context) error {
+	defer func() {
+		if err := e.exporter.Shutdown(context.Background()); err != nil {
+			log.Warn().Err(err).Msg("failed to close the OTLP metrics exporter")
+		}
+	}()
+
+	log.Info().
+		Str("endpoint", e.config.Endpoint).
+		Stringer("interval", e.config.Interval).
+		Msg("OTLP metrics exporter scheduled")
+
+	ticker := time.NewTicker(e.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := e.Export(ctx); err != nil {
+				log.Warn().Err(err).Str("endpoint", e.config.Endpoint).Msg("failed to export OTLP metrics")
+			}
+
+		case <-ctx.Done():
+			if err := e.Export(context.Background()); err != nil {
+				log.Warn().Err(err).Str("endpoint", e.config.Endpoint).Msg("failed to export OTLP metrics")
+			}
+			return nil
+		}
+	}
+}
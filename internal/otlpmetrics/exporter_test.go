@@ -0,0 +1,131 @@
+package otlpmetrics
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestExportOverHTTP(t *testing.T) {
+	testCases := []struct {
+		temporality      string
+		expectedCounters []float64
+		expectedCounts   []uint64
+		expectedBuckets  [][]uint64
+	}{
+		{TemporalityCumulative, []float64{2, 5}, []uint64{2, 3}, [][]uint64{{1, 1, 0}, {1, 1, 1}}},
+		{TemporalityDelta, []float64{2, 3}, []uint64{2, 1}, [][]uint64{{1, 1, 0}, {0, 0, 1}}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.temporality, func(t *testing.T) {
+			require := require.New(t)
+
+			var requests []*collectorpb.ExportMetricsServiceRequest
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				require.Equal("/v1/metrics", r.URL.Path)
+				require.Equal("application/x-protobuf", r.Header.Get("Content-Type"))
+				require.Equal("secret", r.Header.Get("X-Api-Key"))
+
+				body, err := io.ReadAll(r.Body)
+				require.NoError(err)
+				req := &collectorpb.ExportMetricsServiceRequest{}
+				require.NoError(proto.Unmarshal(body, req))
+				requests = append(requests, req)
+
+				resp, err := proto.Marshal(&collectorpb.ExportMetricsServiceResponse{})
+				require.NoError(err)
+				w.Header().Set("Content-Type", "application/x-protobuf")
+				_, _ = w.Write(resp)
+			}))
+			defer server.Close()
+
+			registry := prometheus.NewRegistry()
+			counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_calls_total"}, []string{"method"})
+			histogram := prometheus.NewHistogram(prometheus.HistogramOpts{Name: "test_duration_seconds", Buckets: []float64{0.1, 1}})
+			registry.MustRegister(counter, histogram)
+
+			exporter, err := NewExporter(Config{
+				Endpoint:           server.URL,
+				Protocol:           ProtocolHTTP,
+				Headers:            map[string]string{"X-Api-Key": "secret"},
+				Interval:           DefaultInterval,
+				Temporality:        tc.temporality,
+				ResourceAttributes: map[string]string{"deployment.environment": "test"},
+			}, registry)
+			require.NoError(err)
+
+			counter.WithLabelValues("check").Add(2)
+			histogram.Observe(0.05)
+			histogram.Observe(0.5)
+			require.NoError(exporter.Export(context.Background()))
+
+			counter.WithLabelValues("check").Add(3)
+			histogram.Observe(5)
+			require.NoError(exporter.Export(context.Background()))
+
+			require.Len(requests, 2)
+			for index, req := range requests {
+				resource := make(map[string]string)
+				for _, attr := range req.ResourceMetrics[0].Resource.Attributes {
+					resource[attr.Key] = attr.Value.GetStringValue()
+				}
+				require.Equal(map[string]string{"service.name": "spicedb", "deployment.environment": "test"}, resource)
+
+				metrics := req.ResourceMetrics[0].ScopeMetrics[0].Metrics
+				require.Len(metrics, 2)
+
+				sum := metrics[0].GetSum()
+				require.Equal("test_calls_total", metrics[0].Name)
+				require.True(sum.IsMonotonic)
+				require.Equal(tc.expectedCounters[index], sum.DataPoints[0].GetAsDouble())
+				require.Equal("method", sum.DataPoints[0].Attributes[0].Key)
+
+				hist := metrics[1].GetHistogram()
+				require.Equal("test_duration_seconds", metrics[1].Name)
+				require.Equal([]float64{0.1, 1}, hist.DataPoints[0].ExplicitBounds)
+				require.Equal(tc.expectedCounts[index], hist.DataPoints[0].Count)
+				require.Equal(tc.expectedBuckets[index], hist.DataPoints[0].BucketCounts)
+
+				if tc.temporality == TemporalityDelta {
+					require.Equal(metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_DELTA, sum.AggregationTemporality)
+					if index > 0 {
+						require.Equal(requests[index-1].ResourceMetrics[0].ScopeMetrics[0].Metrics[0].GetSum().DataPoints[0].TimeUnixNano, sum.DataPoints[0].StartTimeUnixNano)
+					}
+				} else {
+					require.Equal(metricspb.AggregationTemporality_AGGREGATION_TEMPORALITY_CUMULATIVE, sum.AggregationTemporality)
+				}
+			}
+		})
+	}
+}
+
+func TestNewExporterErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        Config
+		expectedError string
+	}{
+		{"no endpoint", Config{Interval: DefaultInterval}, "an OTLP endpoint is required"},
+		{"no interval", Config{Endpoint: "localhost:4317"}, "invalid OTLP metrics export interval"},
+		{"unknown temporality", Config{Endpoint: "localhost:4317", Interval: DefaultInterval, Temporality: "monthly"}, "unknown OTLP metrics temporality"},
+		{"unknown protocol", Config{Endpoint: "localhost:4317", Interval: DefaultInterval, Protocol: "http/json"}, "unknown OTLP protocol"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewExporter(tc.config, prometheus.NewRegistry())
+			require.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
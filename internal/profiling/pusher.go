@@ -0,0 +1,235 @@
+// Package profiling continuously pushes the CPU and heap profiles of SpiceDB to a Pyroscope
+// server, so that regressions can be correlated with deployments without capturing profiles
+// during incidents.
+//
+// Parca, which scrapes profiles rather than accepting pushes, can instead be configured to
+// scrape the pprof endpoints served at /debug/pprof by the metrics server.
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"runtime/pprof"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+const (
+	// DefaultInterval is the default interval over which profiles are collected and pushed.
+	DefaultInterval = 10 * time.Second
+
+	// DefaultApplicationName is the default name of the application whose profiles are pushed.
+	DefaultApplicationName = "spicedb"
+
+	// cpuSampleRate is the sample rate of the CPU profiler of the Go runtime.
+	cpuSampleRate = 100
+
+	// pushTimeout is the timeout of each push.
+	pushTimeout = 10 * time.Second
+)
+
+var validLabelKey = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_.]*$`)
+
+// Config configures the pushing of profiles.
+type Config struct {
+	// Endpoint is the URL of the Pyroscope server.
+	Endpoint string
+
+	// ApplicationName is the name of the application whose profiles are pushed.
+	ApplicationName string
+
+	// Interval is the interval over which profiles are collected and pushed.
+	Interval time.Duration
+
+	// Headers are sent with each push, such as to authenticate to the server.
+	Headers map[string]string
+
+	// Labels label the pushed profiles, such as with the version or role of the server.
+	Labels map[string]string
+}
+
+// Pusher periodically collects CPU and heap profiles and pushes them to a Pyroscope server.
+type Pusher struct {
+	config    Config
+	ingestURL *url.URL
+	name      string
+	client    *http.Client
+
+	// prevHeap is the previous heap profile, from which the server computes the allocations
+	// made over each interval.
+	prevHeap []byte
+}
+
+// NewPusher creates a new pusher of profiles, or errors if the configuration was invalid.
+func NewPusher(config Config) (*Pusher, error) {
+	if config.Interval <= 0 {
+		return nil, fmt.Errorf("invalid profiling push interval: %s", config.Interval)
+	}
+	if config.ApplicationName == "" {
+		config.ApplicationName = DefaultApplicationName
+	}
+
+	ingestURL, err := url.Parse(config.Endpoint)
+	if err != nil || ingestURL.Scheme == "" || ingestURL.Host == "" {
+		return nil, fmt.Errorf("invalid profiling push endpoint: %q", config.Endpoint)
+	}
+	ingestURL.Path = path.Join(ingestURL.Path, "ingest")
+
+	keys := make([]string, 0, len(config.Labels))
+	for key, value := range config.Labels {
+		if !validLabelKey.MatchString(key) {
+			return nil, fmt.Errorf("invalid profile label name: %q", key)
+		}
+		if strings.ContainsAny(value, "{},=") {
+			return nil, fmt.Errorf("invalid value of profile label %s: %q", key, value)
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	labels := make([]string, 0, len(keys))
+	for _, key := range keys {
+		labels = append(labels, key+"="+config.Labels[key])
+	}
+
+	return &Pusher{
+		config:    config,
+		ingestURL: ingestURL,
+		name:      config.ApplicationName + "{" + strings.Join(labels, ",") + "}",
+		client:    &http.Client{Timeout: pushTimeout},
+	}, nil
+}
+
+// Run collects and pushes profiles at every interval until the context is canceled.
+func (p *Pusher) Run(ctx context.Context) error {
+	log.Info().
+		Str("endpoint", p.config.Endpoint).
+		Stringer("interval", p.config.Interval).
+		Msg("continuous profiling scheduled")
+
+	ticker := time.NewTicker(p.config.Interval)
+	defer ticker.Stop()
+
+	cpu := &bytes.Buffer{}
+	cpuStarted := p.startCPUProfile(cpu)
+	from := time.Now()
+	for {
+		select {
+		case <-ctx.Done():
+			if cpuStarted {
+				pprof.StopCPUProfile()
+			}
+			return nil
+
+		case until := <-ticker.C:
+			if cpuStarted {
+				pprof.StopCPUProfile()
+			}
+			collected := cpu
+			collectedCPU := cpuStarted
+
+			cpu = &bytes.Buffer{}
+			cpuStarted = p.startCPUProfile(cpu)
+
+			if err := p.push(ctx, from, until, collectedCPU, collected.Bytes()); err != nil {
+				log.Warn().Err(err).Str("endpoint", p.config.Endpoint).Msg("failed to push profiles")
+			}
+			from = until
+		}
+	}
+}
+
+// startCPUProfile starts collecting a CPU profile, unless one is already being collected, such
+// as through the pprof endpoint of the metrics server.
+func (p *Pusher) startCPUProfile(w io.Writer) bool {
+	if err := pprof.StartCPUProfile(w); err != nil {
+		log.Debug().Err(err).Msg("skipping CPU profile for this interval")
+		return false
+	}
+	return true
+}
+
+func (p *Pusher) push(ctx context.Context, from, until time.Time, hasCPU bool, cpu []byte) error {
+	if hasCPU {
+		if err := p.upload(ctx, from, until, cpu, nil); err != nil {
+			return fmt.Errorf("failed to push CPU profile: %w", err)
+		}
+	}
+
+	heap := &bytes.Buffer{}
+	if err := pprof.Lookup("heap").WriteTo(heap, 0); err != nil {
+		return fmt.Errorf("failed to collect heap profile: %w", err)
+	}
+	if err := p.upload(ctx, from, until, heap.Bytes(), p.prevHeap); err != nil {
+		return fmt.Errorf("failed to push heap profile: %w", err)
+	}
+	p.prevHeap = heap.Bytes()
+	return nil
+}
+
+// upload pushes a profile with the ingestion API of Pyroscope.
+func (p *Pusher) upload(ctx context.Context, from, until time.Time, profile, prevProfile []byte) error {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	if err := writeFormFile(writer, "profile", profile); err != nil {
+		return err
+	}
+	if prevProfile != nil {
+		if err := writeFormFile(writer, "prev_profile", prevProfile); err != nil {
+			return err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	ingestURL := *p.ingestURL
+	query := ingestURL.Query()
+	query.Set("name", p.name)
+	query.Set("from", strconv.FormatInt(from.Unix(), 10))
+	query.Set("until", strconv.FormatInt(until.Unix(), 10))
+	query.Set("spyName", "gospy")
+	query.Set("sampleRate", strconv.Itoa(cpuSampleRate))
+	ingestURL.RawQuery = query.Encode()
+
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, ingestURL.String(), body)
+	if err != nil {
+		return err
+	}
+	r.Header.Set("Content-Type", writer.FormDataContentType())
+	for key, value := range p.config.Headers {
+		r.Header.Set(key, value)
+	}
+
+	resp, err := p.client.Do(r)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("unexpected response: %d: %s", resp.StatusCode, string(respBody))
+	}
+	return nil
+}
+
+func writeFormFile(writer *multipart.Writer, field string, contents []byte) error {
+	fw, err := writer.CreateFormFile(field, field+".pprof")
+	if err != nil {
+		return err
+	}
+	_, err = fw.Write(contents)
+	return err
+}
@@ -0,0 +1,81 @@
+package profiling
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPusher(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		names []string
+		heaps int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/pyroscope/ingest", r.URL.Path)
+		require.Equal(t, "Bearer sometoken", r.Header.Get("Authorization"))
+
+		require.NoError(t, r.ParseMultipartForm(1<<20))
+		profile, _, err := r.FormFile("profile")
+		require.NoError(t, err)
+		contents, err := io.ReadAll(profile)
+		require.NoError(t, err)
+		require.NotEmpty(t, contents)
+
+		mu.Lock()
+		defer mu.Unlock()
+		names = append(names, r.URL.Query().Get("name"))
+		if _, _, err := r.FormFile("prev_profile"); err == nil {
+			heaps++
+		}
+	}))
+	defer server.Close()
+
+	pusher, err := NewPusher(Config{
+		Endpoint: server.URL + "/pyroscope",
+		Interval: 100 * time.Millisecond,
+		Headers:  map[string]string{"Authorization": "Bearer sometoken"},
+		Labels:   map[string]string{"version": "v1.2.3", "role": "api"},
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 350*time.Millisecond)
+	defer cancel()
+	require.NoError(t, pusher.Run(ctx))
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.GreaterOrEqual(t, len(names), 4)
+	require.Equal(t, "spicedb{role=api,version=v1.2.3}", names[0])
+
+	// The heap profiles after the first are pushed with the previous one.
+	require.GreaterOrEqual(t, heaps, 1)
+}
+
+func TestNewPusherErrors(t *testing.T) {
+	testCases := []struct {
+		name          string
+		config        Config
+		expectedError string
+	}{
+		{"no interval", Config{Endpoint: "http://pyroscope:4040"}, "invalid profiling push interval"},
+		{"no endpoint", Config{Interval: DefaultInterval}, "invalid profiling push endpoint"},
+		{"invalid label name", Config{Endpoint: "http://pyroscope:4040", Interval: DefaultInterval, Labels: map[string]string{"some-label": "value"}}, "invalid profile label name"},
+		{"invalid label value", Config{Endpoint: "http://pyroscope:4040", Interval: DefaultInterval, Labels: map[string]string{"role": "a,b"}}, "invalid value of profile label"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := NewPusher(tc.config)
+			require.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
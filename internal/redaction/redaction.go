@@ -0,0 +1,150 @@
+// Package redaction redacts the IDs of objects and subjects from the logs, traces and errors
+// emitted by SpiceDB, so that its telemetry can be shipped to third parties without exposing
+// the IDs, while keeping the types and relations for troubleshooting.
+package redaction
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// Mode is the mode of redaction of IDs.
+type Mode string
+
+const (
+	// ModeNone does not redact IDs.
+	ModeNone Mode = "none"
+
+	// ModeHash replaces IDs by a stable fingerprint, so that telemetry about the same object can
+	// still be correlated.
+	ModeHash Mode = "hash"
+
+	// ModeTruncate replaces IDs by their first characters.
+	ModeTruncate Mode = "truncate"
+)
+
+// truncatedLength is the maximum number of characters of an ID kept by ModeTruncate, which
+// keeps no more than half of the ID.
+const truncatedLength = 4
+
+// ParseMode parses the name of a mode of redaction, where the empty name is ModeNone.
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case "", ModeNone:
+		return ModeNone, nil
+	case ModeHash, ModeTruncate:
+		return Mode(name), nil
+	default:
+		return ModeNone, fmt.Errorf("unknown redaction mode %q, expected one of none, hash and truncate", name)
+	}
+}
+
+var currentMode atomic.Value
+
+// SetMode sets the mode of redaction of the whole process.
+func SetMode(mode Mode) {
+	currentMode.Store(mode)
+}
+
+// CurrentMode returns the mode of redaction of the whole process.
+func CurrentMode() Mode {
+	mode, ok := currentMode.Load().(Mode)
+	if !ok {
+		return ModeNone
+	}
+	return mode
+}
+
+// Enabled returns whether IDs are redacted.
+func Enabled() bool {
+	return CurrentMode() != ModeNone
+}
+
+// Fingerprint returns a short, stable fingerprint of a value which cannot be reversed into the
+// value, such as a key or a redacted ID.
+func Fingerprint(value string) string {
+	digest := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(digest[:8])
+}
+
+// ID returns the ID of an object or subject as emitted in telemetry. The wildcard is never
+// redacted.
+func ID(id string) string {
+	if id == tuple.PublicWildcard {
+		return id
+	}
+
+	switch CurrentMode() {
+	case ModeHash:
+		return Fingerprint(id)
+	case ModeTruncate:
+		return truncate(id)
+	default:
+		return id
+	}
+}
+
+func truncate(id string) string {
+	runes := []rune(id)
+	kept := len(runes) / 2
+	if kept > truncatedLength {
+		kept = truncatedLength
+	}
+	return string(runes[:kept]) + "…"
+}
+
+// IDs returns the IDs of objects or subjects as emitted in telemetry.
+func IDs(ids []string) []string {
+	if !Enabled() {
+		return ids
+	}
+
+	redacted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		redacted = append(redacted, ID(id))
+	}
+	return redacted
+}
+
+// ONR returns the string form of an object and relation as emitted in telemetry.
+func ONR(onr *core.ObjectAndRelation) string {
+	if onr == nil || !Enabled() {
+		return tuple.StringONR(onr)
+	}
+
+	return tuple.StringONR(&core.ObjectAndRelation{
+		Namespace: onr.Namespace,
+		ObjectId:  ID(onr.ObjectId),
+		Relation:  onr.Relation,
+	})
+}
+
+// objectReference matches the references to objects, such as `document:somedoc`, within text,
+// along with the character preceding them, if any. Fingerprints are matched as a whole so that
+// they are not redacted again.
+var objectReference = regexp.MustCompile(`(^|[^a-zA-Z0-9_./:\-])((?:[a-z][a-z0-9_]{1,61}[a-z0-9]/)?[a-z][a-z0-9_]{1,62}[a-z0-9]):(sha256:[0-9a-f]{16}|[a-zA-Z0-9/_|\-=+]+)`)
+
+// Text returns free text, such as an error message, as emitted in telemetry, where the IDs of the
+// objects referenced as `type:id` are redacted.
+func Text(text string) string {
+	if !Enabled() || !strings.Contains(text, ":") {
+		return text
+	}
+
+	return objectReference.ReplaceAllStringFunc(text, func(match string) string {
+		groups := objectReference.FindStringSubmatch(match)
+		preceding, objectType, id := groups[1], groups[2], groups[3]
+		if strings.HasPrefix(id, "sha256:") || strings.HasPrefix(id, "//") {
+			// Already redacted, or a URL.
+			return match
+		}
+		return preceding + objectType + ":" + ID(id)
+	})
+}
@@ -0,0 +1,184 @@
+package redaction
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	grpclog "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+func withMode(t *testing.T, mode Mode) {
+	previous := CurrentMode()
+	SetMode(mode)
+	t.Cleanup(func() { SetMode(previous) })
+}
+
+func TestParseMode(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected Mode
+		err      string
+	}{
+		{"", ModeNone, ""},
+		{"none", ModeNone, ""},
+		{"hash", ModeHash, ""},
+		{"truncate", ModeTruncate, ""},
+		{"encrypt", ModeNone, "unknown redaction mode"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mode, err := ParseMode(tc.name)
+			if tc.err != "" {
+				require.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, mode)
+		})
+	}
+}
+
+func TestID(t *testing.T) {
+	for _, tc := range []struct {
+		mode     Mode
+		id       string
+		expected string
+	}{
+		{ModeNone, "somedoc", "somedoc"},
+		{ModeHash, "somedoc", Fingerprint("somedoc")},
+		{ModeHash, "*", "*"},
+		{ModeTruncate, "somedocument", "some…"},
+		{ModeTruncate, "tom", "t…"},
+		{ModeTruncate, "a", "…"},
+		{ModeTruncate, "*", "*"},
+	} {
+		t.Run(string(tc.mode)+"/"+tc.id, func(t *testing.T) {
+			withMode(t, tc.mode)
+			require.Equal(t, tc.expected, ID(tc.id))
+		})
+	}
+}
+
+func TestONR(t *testing.T) {
+	withMode(t, ModeHash)
+
+	onr := &core.ObjectAndRelation{Namespace: "document", ObjectId: "somedoc", Relation: "view"}
+	require.Equal(t, "document:"+Fingerprint("somedoc")+"#view", ONR(onr))
+	require.Equal(t, "somedoc", onr.ObjectId)
+}
+
+func TestText(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		mode     Mode
+		text     string
+		expected string
+	}{
+		{
+			"disabled",
+			ModeNone,
+			"object document:somedoc not found",
+			"object document:somedoc not found",
+		},
+		{
+			"object references",
+			ModeTruncate,
+			"rpc error: code = FailedPrecondition desc = cannot write document:somedoc#viewer@user:tom: relationship exists",
+			"rpc error: code = FailedPrecondition desc = cannot write document:som…#viewer@user:t…: relationship exists",
+		},
+		{
+			"prefixed types",
+			ModeTruncate,
+			"tenant/document:somedocument",
+			"tenant/document:some…",
+		},
+		{
+			"wildcards and urls",
+			ModeHash,
+			"user:* cannot reach https://example.com:443",
+			"user:* cannot reach https://example.com:443",
+		},
+		{
+			"already redacted",
+			ModeHash,
+			"document:" + Fingerprint("somedoc"),
+			"document:" + Fingerprint("somedoc"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			withMode(t, tc.mode)
+			require.Equal(t, tc.expected, Text(tc.text))
+		})
+	}
+}
+
+type fieldsLogger struct {
+	fields []string
+}
+
+func (l *fieldsLogger) Log(_ grpclog.Level, _ string) {}
+
+func (l *fieldsLogger) With(fields ...string) grpclog.Logger {
+	l.fields = append(l.fields, fields...)
+	return l
+}
+
+func TestGRPCLogger(t *testing.T) {
+	withMode(t, ModeTruncate)
+
+	recorder := &fieldsLogger{}
+	GRPCLogger(recorder).With("grpc.code", "NotFound", "grpc.error", "object document:somedoc not found")
+	require.Equal(t, []string{"grpc.code", "NotFound", "grpc.error", "object document:som… not found"}, recorder.fields)
+}
+
+type recordingSpan struct {
+	trace.Span
+	description string
+	err         error
+}
+
+func (s *recordingSpan) SetStatus(_ codes.Code, description string) {
+	s.description = description
+}
+
+func (s *recordingSpan) RecordError(err error, _ ...trace.EventOption) {
+	s.err = err
+}
+
+type recordingTracer struct {
+	trace.Tracer
+	span *recordingSpan
+}
+
+func (t recordingTracer) Start(ctx context.Context, _ string, _ ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return ctx, t.span
+}
+
+type recordingTracerProvider struct {
+	trace.TracerProvider
+	span *recordingSpan
+}
+
+func (tp recordingTracerProvider) Tracer(_ string, _ ...trace.TracerOption) trace.Tracer {
+	return recordingTracer{span: tp.span}
+}
+
+func TestTracerProvider(t *testing.T) {
+	withMode(t, ModeHash)
+
+	recorded := &recordingSpan{}
+	ctx, span := TracerProvider(recordingTracerProvider{span: recorded}).Tracer("test").Start(context.Background(), "call")
+	require.Equal(t, span, trace.SpanFromContext(ctx))
+
+	span.SetStatus(codes.Error, "object document:somedoc not found")
+	span.RecordError(errors.New("object document:somedoc not found"))
+
+	expected := "object document:" + Fingerprint("somedoc") + " not found"
+	require.Equal(t, expected, recorded.description)
+	require.EqualError(t, recorded.err, expected)
+}
@@ -0,0 +1,90 @@
+package redaction
+
+import (
+	"context"
+	"errors"
+
+	grpclog "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/logging"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// grpcErrorField is the field of the logs of the gRPC logging middleware holding the error of
+// the call.
+const grpcErrorField = "grpc.error"
+
+// RedactLoggedErrors redacts the errors logged by zerolog, such as with `Err(err)`, in the whole
+// process.
+func RedactLoggedErrors() {
+	marshal := zerolog.ErrorMarshalFunc
+	zerolog.ErrorMarshalFunc = func(err error) interface{} {
+		if err == nil || !Enabled() {
+			return marshal(err)
+		}
+		return Text(err.Error())
+	}
+}
+
+// GRPCLogger returns a logger for the gRPC logging middleware which redacts the errors of the
+// logged calls.
+func GRPCLogger(logger grpclog.Logger) grpclog.Logger {
+	return grpcLogger{logger}
+}
+
+type grpcLogger struct {
+	grpclog.Logger
+}
+
+func (l grpcLogger) With(fields ...string) grpclog.Logger {
+	if Enabled() {
+		redacted := make([]string, len(fields))
+		copy(redacted, fields)
+		for i := 0; i+1 < len(redacted); i += 2 {
+			if redacted[i] == grpcErrorField {
+				redacted[i+1] = Text(redacted[i+1])
+			}
+		}
+		fields = redacted
+	}
+	return grpcLogger{l.Logger.With(fields...)}
+}
+
+// TracerProvider returns a tracer provider whose spans redact their status descriptions and
+// recorded errors, such as those of the spans of the gRPC tracing middleware.
+func TracerProvider(provider trace.TracerProvider) trace.TracerProvider {
+	return tracerProvider{provider}
+}
+
+type tracerProvider struct {
+	trace.TracerProvider
+}
+
+func (tp tracerProvider) Tracer(name string, opts ...trace.TracerOption) trace.Tracer {
+	return tracer{tp.TracerProvider.Tracer(name, opts...)}
+}
+
+type tracer struct {
+	trace.Tracer
+}
+
+func (t tracer) Start(ctx context.Context, spanName string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	ctx, inner := t.Tracer.Start(ctx, spanName, opts...)
+	wrapped := span{inner}
+	return trace.ContextWithSpan(ctx, wrapped), wrapped
+}
+
+type span struct {
+	trace.Span
+}
+
+func (s span) SetStatus(code codes.Code, description string) {
+	s.Span.SetStatus(code, Text(description))
+}
+
+func (s span) RecordError(err error, options ...trace.EventOption) {
+	if err != nil && Enabled() {
+		err = errors.New(Text(err.Error()))
+	}
+	s.Span.RecordError(err, options...)
+}
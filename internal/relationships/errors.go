@@ -1,6 +1,7 @@
 package relationships
 
 import (
+	"encoding/json"
 	"fmt"
 
 	"github.com/authzed/spicedb/internal/namespace"
@@ -79,7 +80,42 @@ func (err ErrCannotWriteToPermission) GRPCStatus() *status.Status {
 		spiceerrors.ForReason(
 			v1.ErrorReason_ERROR_REASON_CANNOT_UPDATE_PERMISSION,
 			map[string]string{
-				"caveat_name": err.update.Tuple.Caveat.CaveatName,
+				"definition_name": err.update.Tuple.ResourceAndRelation.Namespace,
+				"relation_name":   err.update.Tuple.ResourceAndRelation.Relation,
+			},
+		),
+	)
+}
+
+// ErrCannotWriteToSyntheticRelation indicates that a write was attempted on a synthetic relation.
+type ErrCannotWriteToSyntheticRelation struct {
+	error
+	update *core.RelationTupleUpdate
+}
+
+// NewCannotWriteToSyntheticRelationError constructs a new error for attempting to write to a
+// synthetic relation.
+func NewCannotWriteToSyntheticRelationError(update *core.RelationTupleUpdate) ErrCannotWriteToSyntheticRelation {
+	return ErrCannotWriteToSyntheticRelation{
+		error: fmt.Errorf(
+			"cannot write a relationship to synthetic relation `%s` under definition `%s`, as its relationships are computed from its source relations",
+			update.Tuple.ResourceAndRelation.Relation,
+			update.Tuple.ResourceAndRelation.Namespace,
+		),
+		update: update,
+	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrCannotWriteToSyntheticRelation) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_CANNOT_UPDATE_PERMISSION,
+			map[string]string{
+				"definition_name": err.update.Tuple.ResourceAndRelation.Namespace,
+				"relation_name":   err.update.Tuple.ResourceAndRelation.Relation,
 			},
 		),
 	)
@@ -116,3 +152,248 @@ func (err ErrCaveatNotFound) GRPCStatus() *status.Status {
 		),
 	)
 }
+
+// ErrInvalidCaveatContext indicates that a write was attempted with a caveat context which does
+// not match the parameters declared on the caveat.
+type ErrInvalidCaveatContext struct {
+	error
+	update *core.RelationTupleUpdate
+}
+
+// NewInvalidCaveatContextError constructs a new error for attempting to write a relationship with
+// an invalid caveat context.
+func NewInvalidCaveatContextError(update *core.RelationTupleUpdate, err error) ErrInvalidCaveatContext {
+	return ErrInvalidCaveatContext{
+		error: fmt.Errorf(
+			"invalid context for caveat `%s` on relationship `%s`: %w",
+			update.Tuple.Caveat.CaveatName,
+			tuple.String(update.Tuple),
+			err,
+		),
+		update: update,
+	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrInvalidCaveatContext) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_CAVEAT_PARAMETER_TYPE_ERROR,
+			map[string]string{
+				"caveat_name": err.update.Tuple.Caveat.CaveatName,
+			},
+		),
+	)
+}
+
+// ErrSubjectRelationRequired indicates that a write was attempted without a subject relation,
+// under strict validation, on a relation which allows a subject relation for the subject type.
+type ErrSubjectRelationRequired struct {
+	error
+	update *core.RelationTupleUpdate
+}
+
+// NewSubjectRelationRequiredError constructs a new error for attempting to write a relationship
+// without a subject relation when one is expected.
+func NewSubjectRelationRequiredError(update *core.RelationTupleUpdate) ErrSubjectRelationRequired {
+	return ErrSubjectRelationRequired{
+		error: fmt.Errorf(
+			"relationship `%s` has no subject relation, but relation `%s#%s` expects one for subjects of type `%s`",
+			tuple.String(update.Tuple),
+			update.Tuple.ResourceAndRelation.Namespace,
+			update.Tuple.ResourceAndRelation.Relation,
+			update.Tuple.Subject.Namespace,
+		),
+		update: update,
+	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrSubjectRelationRequired) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_INVALID_SUBJECT_TYPE,
+			map[string]string{
+				"definition_name": err.update.Tuple.ResourceAndRelation.Namespace,
+				"relation_name":   err.update.Tuple.ResourceAndRelation.Relation,
+				"subject_type":    err.update.Tuple.Subject.Namespace,
+			},
+		),
+	)
+}
+
+// ErrConflictingCaveat indicates that a write was attempted, under strict validation, of a
+// relationship which already exists with a different caveat.
+type ErrConflictingCaveat struct {
+	error
+	update *core.RelationTupleUpdate
+}
+
+// NewConflictingCaveatError constructs a new error for attempting to write a relationship which
+// already exists with a different caveat.
+func NewConflictingCaveatError(update *core.RelationTupleUpdate, existing *core.RelationTuple) ErrConflictingCaveat {
+	return ErrConflictingCaveat{
+		error: fmt.Errorf(
+			"relationship `%s` already exists with a different caveat (existing: %s, updated: %s)",
+			tuple.String(update.Tuple),
+			caveatString(existing.Caveat),
+			caveatString(update.Tuple.Caveat),
+		),
+		update: update,
+	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrConflictingCaveat) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.FailedPrecondition,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UPDATES_ON_SAME_RELATIONSHIP,
+			map[string]string{
+				"definition_name": err.update.Tuple.ResourceAndRelation.Namespace,
+				"relationship":    tuple.String(err.update.Tuple),
+			},
+		),
+	)
+}
+
+// ErrInvalidTemplate indicates that a relationship template declared in the schema is invalid.
+type ErrInvalidTemplate struct {
+	error
+	definitionName string
+	signature      string
+}
+
+// NewInvalidTemplateError constructs a new error for a relationship template declared on a
+// definition which is invalid.
+func NewInvalidTemplateError(definitionName string, signature string, err error) ErrInvalidTemplate {
+	return ErrInvalidTemplate{
+		error:          fmt.Errorf("invalid relationship template `%s` on definition `%s`: %w", signature, definitionName, err),
+		definitionName: definitionName,
+		signature:      signature,
+	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrInvalidTemplate) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_SCHEMA_PARSE_ERROR,
+			map[string]string{
+				"definition_name": err.definitionName,
+				"template":        err.signature,
+			},
+		),
+	)
+}
+
+// ErrTemplateNotFound indicates that a relationship template applied was not found in the schema.
+type ErrTemplateNotFound struct {
+	error
+	name string
+}
+
+// NewTemplateNotFoundError constructs a new error for applying a relationship template which is
+// not declared in the schema.
+func NewTemplateNotFoundError(name string) ErrTemplateNotFound {
+	return ErrTemplateNotFound{
+		error: fmt.Errorf("relationship template `%s` not found", name),
+		name:  name,
+	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrTemplateNotFound) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.FailedPrecondition,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"template": err.name,
+			},
+		),
+	)
+}
+
+// ErrInvalidTemplateInvocation indicates that a relationship template was applied with an invalid
+// invocation, such as with the wrong number of arguments.
+type ErrInvalidTemplateInvocation struct {
+	error
+	invocation string
+}
+
+// NewInvalidTemplateInvocationError constructs a new error for an invalid invocation of a
+// relationship template.
+func NewInvalidTemplateInvocationError(invocation string, err error) ErrInvalidTemplateInvocation {
+	return ErrInvalidTemplateInvocation{
+		error:      fmt.Errorf("invalid invocation `%s` of relationship template: %w", invocation, err),
+		invocation: invocation,
+	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrInvalidTemplateInvocation) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"invocation": err.invocation,
+			},
+		),
+	)
+}
+
+// ErrInvalidQuery indicates that a relationship query is invalid.
+type ErrInvalidQuery struct {
+	error
+	query string
+}
+
+// NewInvalidQueryError constructs a new error for an invalid relationship query.
+func NewInvalidQueryError(query string, err error) ErrInvalidQuery {
+	return ErrInvalidQuery{
+		error: fmt.Errorf("invalid relationship query `%s`: %w", query, err),
+		query: query,
+	}
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrInvalidQuery) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"query": err.query,
+			},
+		),
+	)
+}
+
+func caveatString(caveat *core.ContextualizedCaveat) string {
+	if caveat == nil || caveat.CaveatName == "" {
+		return "no caveat"
+	}
+
+	if len(caveat.Context.GetFields()) == 0 {
+		return fmt.Sprintf("`%s`", caveat.CaveatName)
+	}
+
+	contextJSON, err := json.Marshal(caveat.Context.AsMap())
+	if err != nil {
+		return fmt.Sprintf("`%s` with context", caveat.CaveatName)
+	}
+
+	return fmt.Sprintf("`%s` with context %s", caveat.CaveatName, contextJSON)
+}
@@ -0,0 +1,336 @@
+package relationships
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const (
+	// queryHopBatchSize is the maximum number of resource IDs of each datastore query made for
+	// the arrow hop of a relationship query.
+	queryHopBatchSize = 100
+
+	// maxQueryHopSubjects is the maximum number of distinct subjects of the relationships matched
+	// by the first pattern of a relationship query with an arrow hop.
+	maxQueryHopSubjects = 10_000
+)
+
+var queryObjectPattern = regexp.MustCompile(`^([a-z][a-z0-9_/]*[a-z0-9])(?::([^:#@\s,]+))?(?:#([a-z][a-z0-9_]*|\.\.\.))?$`)
+
+// RelationshipQuery is a query over relationships, written in the relationship query language:
+//
+//	resource_type[:resource_id][#relation][@subject_type[:subject_id][#subject_relation]]
+//	  [-> relation[@subject_type[:subject_id][#subject_relation]]]
+//	  [select field, ...]
+//	  [limit count]
+//
+// A query matches the relationships of its first pattern. With an arrow hop, it instead matches
+// the relationships of the relation of the hop on the subjects of those relationships, such as
+// the viewers of the parent folders of a document with `document:readme#parent -> viewer`.
+type RelationshipQuery struct {
+	// Resource is the pattern of the resources of the relationships matched first.
+	Resource ObjectPattern
+
+	// OptionalSubject is the pattern of the subjects of the relationships matched first, if any.
+	OptionalSubject *ObjectPattern
+
+	// OptionalHop is the arrow hop of the query, if any.
+	OptionalHop *QueryHop
+
+	// Select are the names of the fields of the matched relationships selected by the query, if
+	// any, as given.
+	Select []string
+
+	// Limit is the maximum number of relationships matched by the query, or zero if unlimited.
+	Limit uint64
+}
+
+// QueryHop is the arrow hop of a relationship query, from the subjects of the relationships
+// matched first to their relationships.
+type QueryHop struct {
+	// Relation is the relation of the relationships of the subjects.
+	Relation string
+
+	// OptionalSubject is the pattern of the subjects of the relationships of the subjects, if any.
+	OptionalSubject *ObjectPattern
+}
+
+// ObjectPattern matches objects of a type, and optionally an ID and a relation.
+type ObjectPattern struct {
+	ObjectType       string
+	OptionalObjectID string
+	OptionalRelation string
+}
+
+// ParseQuery parses a query written in the relationship query language.
+func ParseQuery(query string) (*RelationshipQuery, error) {
+	tokens := strings.Fields(strings.ReplaceAll(query, "->", " -> "))
+	if len(tokens) == 0 {
+		return nil, NewInvalidQueryError(query, errors.New("the query is empty"))
+	}
+
+	parsed := &RelationshipQuery{}
+	resource, subject, err := parseRelationshipPattern(tokens[0])
+	if err != nil {
+		return nil, NewInvalidQueryError(query, err)
+	}
+	parsed.Resource, parsed.OptionalSubject = *resource, subject
+	tokens = tokens[1:]
+
+	if len(tokens) > 0 && tokens[0] == "->" {
+		if len(tokens) == 1 {
+			return nil, NewInvalidQueryError(query, errors.New("expected a relation after `->`"))
+		}
+
+		relation, subject, err := parseRelationshipPattern(tokens[1])
+		if err != nil {
+			return nil, NewInvalidQueryError(query, err)
+		}
+		if relation.OptionalObjectID != "" || relation.OptionalRelation != "" {
+			return nil, NewInvalidQueryError(query, fmt.Errorf("expected a relation after `->`, found `%s`", tokens[1]))
+		}
+		parsed.OptionalHop = &QueryHop{Relation: relation.ObjectType, OptionalSubject: subject}
+		tokens = tokens[2:]
+	}
+
+	for len(tokens) > 0 {
+		switch tokens[0] {
+		case "select":
+			if parsed.Select != nil {
+				return nil, NewInvalidQueryError(query, errors.New("`select` is given more than once"))
+			}
+
+			end := 1
+			for end < len(tokens) && tokens[end] != "limit" {
+				end++
+			}
+			for _, field := range strings.Split(strings.Join(tokens[1:end], ""), ",") {
+				if field == "" {
+					return nil, NewInvalidQueryError(query, errors.New("expected a comma-separated list of fields after `select`"))
+				}
+				parsed.Select = append(parsed.Select, field)
+			}
+			tokens = tokens[end:]
+
+		case "limit":
+			if parsed.Limit != 0 {
+				return nil, NewInvalidQueryError(query, errors.New("`limit` is given more than once"))
+			}
+			if len(tokens) == 1 {
+				return nil, NewInvalidQueryError(query, errors.New("expected a count after `limit`"))
+			}
+
+			limit, err := strconv.ParseUint(tokens[1], 10, 64)
+			if err != nil || limit == 0 {
+				return nil, NewInvalidQueryError(query, fmt.Errorf("expected a positive count after `limit`, found `%s`", tokens[1]))
+			}
+			parsed.Limit = limit
+			tokens = tokens[2:]
+
+		default:
+			return nil, NewInvalidQueryError(query, fmt.Errorf("unexpected `%s`, expected `select` or `limit`", tokens[0]))
+		}
+	}
+	return parsed, nil
+}
+
+// parseRelationshipPattern parses a pattern of the form `resource[@subject]`.
+func parseRelationshipPattern(pattern string) (*ObjectPattern, *ObjectPattern, error) {
+	resourcePattern, subjectPattern, hasSubject := strings.Cut(pattern, "@")
+
+	resource, err := parseObjectPattern(resourcePattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !hasSubject {
+		return resource, nil, nil
+	}
+
+	subject, err := parseObjectPattern(subjectPattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resource, subject, nil
+}
+
+func parseObjectPattern(pattern string) (*ObjectPattern, error) {
+	groups := queryObjectPattern.FindStringSubmatch(pattern)
+	if groups == nil {
+		return nil, fmt.Errorf("invalid pattern `%s`, expected `type[:id][#relation]`", pattern)
+	}
+	return &ObjectPattern{ObjectType: groups[1], OptionalObjectID: groups[2], OptionalRelation: groups[3]}, nil
+}
+
+// Execute runs the query against the reader, calling yield with each relationship matched by the
+// query until its limit is reached. Subjects of the relationships matched first whose type does
+// not define the relation of the arrow hop are skipped.
+func (q *RelationshipQuery) Execute(ctx context.Context, reader datastore.Reader, yield func(*core.RelationTuple) error) error {
+	var yielded uint64
+	emit := func(tpl *core.RelationTuple) (bool, error) {
+		if err := yield(tpl); err != nil {
+			return false, err
+		}
+		yielded++
+		return q.Limit == 0 || yielded < q.Limit, nil
+	}
+
+	var opts []options.QueryOptionsOption
+	if q.OptionalHop == nil && q.Limit > 0 {
+		opts = append(opts, options.WithLimit(&q.Limit))
+	}
+
+	it, err := reader.QueryRelationships(ctx, relationshipsFilter(q.Resource, q.OptionalSubject), opts...)
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	if q.OptionalHop == nil {
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			more, err := emit(tpl)
+			if err != nil || !more {
+				return err
+			}
+		}
+		return it.Err()
+	}
+
+	var subjectTypes []string
+	subjectIDsByType := map[string][]string{}
+	seen := map[string]struct{}{}
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		if tpl.Subject.ObjectId == tuple.PublicWildcard {
+			continue
+		}
+
+		key := tpl.Subject.Namespace + ":" + tpl.Subject.ObjectId
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		if len(seen) == maxQueryHopSubjects {
+			return NewInvalidQueryError(q.String(), fmt.Errorf("the first pattern of the query matches more than %d subjects, which must be narrowed", maxQueryHopSubjects))
+		}
+		seen[key] = struct{}{}
+
+		if _, ok := subjectIDsByType[tpl.Subject.Namespace]; !ok {
+			subjectTypes = append(subjectTypes, tpl.Subject.Namespace)
+		}
+		subjectIDsByType[tpl.Subject.Namespace] = append(subjectIDsByType[tpl.Subject.Namespace], tpl.Subject.ObjectId)
+	}
+	if it.Err() != nil {
+		return it.Err()
+	}
+
+	for _, subjectType := range subjectTypes {
+		if err := namespace.CheckNamespaceAndRelation(ctx, subjectType, q.OptionalHop.Relation, false, reader); err != nil {
+			if errors.As(err, &namespace.ErrRelationNotFound{}) {
+				continue
+			}
+			return err
+		}
+
+		subjectIDs := subjectIDsByType[subjectType]
+		for len(subjectIDs) > 0 {
+			batch := subjectIDs
+			if len(batch) > queryHopBatchSize {
+				batch = batch[:queryHopBatchSize]
+			}
+			subjectIDs = subjectIDs[len(batch):]
+
+			filter := relationshipsFilter(ObjectPattern{ObjectType: subjectType, OptionalRelation: q.OptionalHop.Relation}, q.OptionalHop.OptionalSubject)
+			filter.OptionalResourceIds = batch
+
+			more, err := q.emitAll(ctx, reader, filter, emit)
+			if err != nil || !more {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (q *RelationshipQuery) emitAll(ctx context.Context, reader datastore.Reader, filter datastore.RelationshipsFilter, emit func(*core.RelationTuple) (bool, error)) (bool, error) {
+	it, err := reader.QueryRelationships(ctx, filter)
+	if err != nil {
+		return false, err
+	}
+	defer it.Close()
+
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		more, err := emit(tpl)
+		if err != nil || !more {
+			return false, err
+		}
+	}
+	return true, it.Err()
+}
+
+// String returns the query in the relationship query language.
+func (q *RelationshipQuery) String() string {
+	var sb strings.Builder
+	sb.WriteString(q.Resource.String())
+	if q.OptionalSubject != nil {
+		sb.WriteString("@" + q.OptionalSubject.String())
+	}
+	if q.OptionalHop != nil {
+		sb.WriteString(" -> " + q.OptionalHop.Relation)
+		if q.OptionalHop.OptionalSubject != nil {
+			sb.WriteString("@" + q.OptionalHop.OptionalSubject.String())
+		}
+	}
+	if len(q.Select) > 0 {
+		sb.WriteString(" select " + strings.Join(q.Select, ", "))
+	}
+	if q.Limit > 0 {
+		sb.WriteString(" limit " + strconv.FormatUint(q.Limit, 10))
+	}
+	return sb.String()
+}
+
+// String returns the pattern in the relationship query language.
+func (op ObjectPattern) String() string {
+	pattern := op.ObjectType
+	if op.OptionalObjectID != "" {
+		pattern += ":" + op.OptionalObjectID
+	}
+	if op.OptionalRelation != "" {
+		pattern += "#" + op.OptionalRelation
+	}
+	return pattern
+}
+
+func relationshipsFilter(resource ObjectPattern, subject *ObjectPattern) datastore.RelationshipsFilter {
+	filter := datastore.RelationshipsFilter{
+		ResourceType:             resource.ObjectType,
+		OptionalResourceRelation: resource.OptionalRelation,
+	}
+	if resource.OptionalObjectID != "" {
+		filter.OptionalResourceIds = []string{resource.OptionalObjectID}
+	}
+
+	if subject != nil {
+		filter.OptionalSubjectsFilter = &datastore.SubjectsFilter{SubjectType: subject.ObjectType}
+		if subject.OptionalObjectID != "" {
+			filter.OptionalSubjectsFilter.OptionalSubjectIds = []string{subject.OptionalObjectID}
+		}
+
+		switch subject.OptionalRelation {
+		case "":
+		case tuple.Ellipsis:
+			filter.OptionalSubjectsFilter.RelationFilter = filter.OptionalSubjectsFilter.RelationFilter.WithEllipsisRelation()
+		default:
+			filter.OptionalSubjectsFilter.RelationFilter = filter.OptionalSubjectsFilter.RelationFilter.WithNonEllipsisRelation(subject.OptionalRelation)
+		}
+	}
+	return filter
+}
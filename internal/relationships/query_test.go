@@ -0,0 +1,147 @@
+package relationships
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestParseQuery(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		expected      *RelationshipQuery
+		expectedError string
+	}{
+		{"resource type", "document", &RelationshipQuery{Resource: ObjectPattern{ObjectType: "document"}}, ""},
+		{
+			"full pattern",
+			"document:readme#viewer@group:eng#member",
+			&RelationshipQuery{
+				Resource:        ObjectPattern{ObjectType: "document", OptionalObjectID: "readme", OptionalRelation: "viewer"},
+				OptionalSubject: &ObjectPattern{ObjectType: "group", OptionalObjectID: "eng", OptionalRelation: "member"},
+			},
+			"",
+		},
+		{
+			"arrow hop",
+			"document:readme#parent->viewer@user:*",
+			&RelationshipQuery{
+				Resource:    ObjectPattern{ObjectType: "document", OptionalObjectID: "readme", OptionalRelation: "parent"},
+				OptionalHop: &QueryHop{Relation: "viewer", OptionalSubject: &ObjectPattern{ObjectType: "user", OptionalObjectID: "*"}},
+			},
+			"",
+		},
+		{
+			"select and limit",
+			"tenant/document#parent -> viewer select subject.id, resource limit 10",
+			&RelationshipQuery{
+				Resource:    ObjectPattern{ObjectType: "tenant/document", OptionalRelation: "parent"},
+				OptionalHop: &QueryHop{Relation: "viewer"},
+				Select:      []string{"subject.id", "resource"},
+				Limit:       10,
+			},
+			"",
+		},
+		{"empty", " ", nil, "the query is empty"},
+		{"invalid pattern", "document:readme:other", nil, "invalid pattern `document:readme:other`"},
+		{"missing hop", "document#parent ->", nil, "expected a relation after `->`"},
+		{"hop with object", "document#parent -> folder:x#viewer", nil, "expected a relation after `->`, found `folder:x#viewer`"},
+		{"empty select", "document select", nil, "expected a comma-separated list of fields after `select`"},
+		{"zero limit", "document limit 0", nil, "expected a positive count after `limit`, found `0`"},
+		{"repeated limit", "document limit 1 limit 2", nil, "`limit` is given more than once"},
+		{"unknown clause", "document where", nil, "unexpected `where`"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			query, err := ParseQuery(tc.query)
+			if tc.expectedError != "" {
+				require.ErrorContains(t, err, tc.expectedError)
+				require.ErrorAs(t, err, &ErrInvalidQuery{})
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, query)
+
+			reparsed, err := ParseQuery(query.String())
+			require.NoError(t, err)
+			require.Equal(t, query, reparsed)
+		})
+	}
+}
+
+func TestExecuteQuery(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		expected []string
+	}{
+		{
+			"single pattern",
+			"document:masterplan#parent",
+			[]string{"document:masterplan#parent@folder:strategy", "document:masterplan#parent@folder:plans"},
+		},
+		{
+			"subject pattern",
+			"folder:company#viewer@folder",
+			[]string{"folder:company#viewer@folder:auditors#viewer"},
+		},
+		{
+			"arrow hop",
+			"document:masterplan#parent -> viewer",
+			[]string{"folder:plans#viewer@user:chief_financial_officer"},
+		},
+		{
+			"arrow hop with subject pattern",
+			"document#parent -> viewer@user",
+			[]string{"folder:company#viewer@user:legal", "folder:plans#viewer@user:chief_financial_officer"},
+		},
+		{
+			"arrow hop to a relation undefined on the subjects",
+			"folder:company#owner -> parent",
+			nil,
+		},
+		{"limit", "document#parent -> viewer limit 1", []string{"folder:company#viewer@user:legal"}},
+		{"limit without hop", "document:masterplan#parent limit 1", []string{"document:masterplan#parent@folder:strategy"}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := require.New(t)
+
+			rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			req.NoError(err)
+			ds, rev := testfixtures.StandardDatastoreWithData(rawDS, req)
+
+			query, err := ParseQuery(tc.query)
+			req.NoError(err)
+
+			var found []string
+			err = query.Execute(context.Background(), ds.SnapshotReader(rev), func(tpl *core.RelationTuple) error {
+				found = append(found, tuple.String(tpl))
+				return nil
+			})
+			req.NoError(err)
+
+			var expected []string
+			for _, tpl := range tc.expected {
+				expected = append(expected, tuple.String(tuple.MustParse(tpl)))
+			}
+			if query.Limit > 0 {
+				req.Len(found, int(query.Limit))
+				return
+			}
+			req.ElementsMatch(expected, found)
+		})
+	}
+}
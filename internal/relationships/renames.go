@@ -0,0 +1,168 @@
+package relationships
+
+import (
+	"context"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+// renamedRelations caches the new names of relations being renamed, loaded from the datastore.
+type renamedRelations struct {
+	reader  datastore.Reader
+	renames map[string]map[string]string
+}
+
+func newRenamedRelations(reader datastore.Reader) *renamedRelations {
+	return &renamedRelations{reader: reader, renames: map[string]map[string]string{}}
+}
+
+// newName returns the new name of the relation, if it is being renamed.
+func (rr *renamedRelations) newName(ctx context.Context, namespaceName, relationName string) (string, bool, error) {
+	if relationName == "" || relationName == tuple.Ellipsis {
+		return "", false, nil
+	}
+
+	renames, ok := rr.renames[namespaceName]
+	if !ok {
+		nsDef, _, err := rr.reader.ReadNamespace(ctx, namespaceName)
+		if err != nil {
+			return "", false, err
+		}
+
+		renames = map[string]string{}
+		for _, relation := range nsDef.Relation {
+			if newName, ok := ns.GetRenaming(relation); ok {
+				renames[relation.Name] = newName
+			}
+		}
+		rr.renames[namespaceName] = renames
+	}
+
+	newName, ok := renames[relationName]
+	return newName, ok, nil
+}
+
+// WithRenamedRelationUpdates returns the given updates along with, for each update to a
+// relationship with a resource or subject relation which is being renamed, the same update to the
+// relationship using the new relation name(s), so that both relations are kept in sync until the
+// rename completes.
+func WithRenamedRelationUpdates(ctx context.Context, reader datastore.Reader, updates []*core.RelationTupleUpdate) ([]*core.RelationTupleUpdate, error) {
+	rr := newRenamedRelations(reader)
+
+	var renamedUpdates []*core.RelationTupleUpdate
+	for _, update := range updates {
+		renamed, ok, err := renamedRelationship(ctx, rr, update.Tuple)
+		if err != nil {
+			return nil, err
+		}
+
+		if !ok {
+			continue
+		}
+
+		// The renamed relationship may have been copied already, so creation is made idempotent.
+		operation := update.Operation
+		if operation == core.RelationTupleUpdate_CREATE {
+			operation = core.RelationTupleUpdate_TOUCH
+		}
+
+		renamedUpdates = append(renamedUpdates, &core.RelationTupleUpdate{
+			Operation: operation,
+			Tuple:     renamed,
+		})
+	}
+
+	if len(renamedUpdates) == 0 {
+		return updates, nil
+	}
+
+	// Skip any renamed updates which were also explicitly given.
+	existing := util.NewSet[string]()
+	for _, update := range updates {
+		existing.Add(tuple.String(update.Tuple))
+	}
+
+	withRenamed := append([]*core.RelationTupleUpdate{}, updates...)
+	for _, renamed := range renamedUpdates {
+		if existing.Add(tuple.String(renamed.Tuple)) {
+			withRenamed = append(withRenamed, renamed)
+		}
+	}
+	return withRenamed, nil
+}
+
+// renamedRelationship returns a copy of the relationship using the new names of its resource and
+// subject relations, if either is being renamed.
+func renamedRelationship(ctx context.Context, rr *renamedRelations, tpl *core.RelationTuple) (*core.RelationTuple, bool, error) {
+	newResourceRelation, isResourceRenamed, err := rr.newName(ctx, tpl.ResourceAndRelation.Namespace, tpl.ResourceAndRelation.Relation)
+	if err != nil {
+		return nil, false, err
+	}
+
+	newSubjectRelation, isSubjectRenamed, err := rr.newName(ctx, tpl.Subject.Namespace, tpl.Subject.Relation)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !isResourceRenamed && !isSubjectRenamed {
+		return nil, false, nil
+	}
+
+	renamed := tpl.CloneVT()
+	if isResourceRenamed {
+		renamed.ResourceAndRelation.Relation = newResourceRelation
+	}
+	if isSubjectRenamed {
+		renamed.Subject.Relation = newSubjectRelation
+	}
+	return renamed, true, nil
+}
+
+// RenamedRelationFilters returns the filters matching the relationships kept in sync with those
+// matched by the given filter, for any resource or subject relation in the filter which is being
+// renamed.
+func RenamedRelationFilters(ctx context.Context, reader datastore.Reader, filter *v1.RelationshipFilter) ([]*v1.RelationshipFilter, error) {
+	rr := newRenamedRelations(reader)
+
+	newResourceRelation, isResourceRenamed, err := rr.newName(ctx, filter.ResourceType, filter.OptionalRelation)
+	if err != nil {
+		return nil, err
+	}
+
+	newSubjectRelation, isSubjectRenamed := "", false
+	if subjectFilter := filter.OptionalSubjectFilter; subjectFilter != nil && subjectFilter.OptionalRelation != nil {
+		newSubjectRelation, isSubjectRenamed, err = rr.newName(ctx, subjectFilter.SubjectType, subjectFilter.OptionalRelation.Relation)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var filters []*v1.RelationshipFilter
+	withRenamed := func(renameResource, renameSubject bool) {
+		renamed := filter.CloneVT()
+		if renameResource {
+			renamed.OptionalRelation = newResourceRelation
+		}
+		if renameSubject {
+			renamed.OptionalSubjectFilter.OptionalRelation.Relation = newSubjectRelation
+		}
+		filters = append(filters, renamed)
+	}
+
+	if isResourceRenamed {
+		withRenamed(true, false)
+	}
+	if isSubjectRenamed {
+		withRenamed(false, true)
+	}
+	if isResourceRenamed && isSubjectRenamed {
+		withRenamed(true, true)
+	}
+	return filters, nil
+}
@@ -0,0 +1,198 @@
+package relationships
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+// SyntheticRelations are the synthetic relations defined in a schema, by definition name.
+type SyntheticRelations map[string][]SyntheticRelation
+
+// SyntheticRelation is a relation whose relationships are materialized from the union of the
+// relationships of its source relations, under the same definition.
+type SyntheticRelation struct {
+	// RelationName is the name of the synthetic relation.
+	RelationName string
+
+	// SourceRelationNames are the names of the relations from which the synthetic relation is
+	// materialized.
+	SourceRelationNames []string
+}
+
+// ReadSyntheticRelations returns the synthetic relations defined in the schema found in the reader.
+func ReadSyntheticRelations(ctx context.Context, reader datastore.Reader) (SyntheticRelations, error) {
+	nsDefs, err := namespace.ListLiveNamespaces(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	synthetic := SyntheticRelations{}
+	for _, nsDef := range nsDefs {
+		for _, relation := range nsDef.Relation {
+			if sources, ok := ns.GetSyntheticSources(relation); ok {
+				synthetic[nsDef.Name] = append(synthetic[nsDef.Name], SyntheticRelation{
+					RelationName:        relation.Name,
+					SourceRelationNames: sources,
+				})
+			}
+		}
+	}
+	return synthetic, nil
+}
+
+// String returns a canonical representation of the synthetic relations, which changes if and only
+// if the synthetic relations do.
+func (sr SyntheticRelations) String() string {
+	definitions := make([]string, 0, len(sr))
+	for nsName, relations := range sr {
+		for _, relation := range relations {
+			definitions = append(definitions, nsName+"#"+relation.RelationName+" = "+strings.Join(relation.SourceRelationNames, ns.SyntheticSeparator))
+		}
+	}
+	sort.Strings(definitions)
+	return strings.Join(definitions, "\n")
+}
+
+// AllSyntheticRelationUpdates returns the updates which, once written, make the relationships of
+// every given synthetic relation exactly the union of those of its source relations.
+func AllSyntheticRelationUpdates(ctx context.Context, reader datastore.Reader, synthetic SyntheticRelations) ([]*core.RelationTupleUpdate, error) {
+	nsNames := make([]string, 0, len(synthetic))
+	for nsName := range synthetic {
+		nsNames = append(nsNames, nsName)
+	}
+	sort.Strings(nsNames)
+
+	var updates []*core.RelationTupleUpdate
+	for _, nsName := range nsNames {
+		nsUpdates, err := syntheticRelationUpdates(ctx, reader, nsName, synthetic[nsName], nil)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, nsUpdates...)
+	}
+	return updates, nil
+}
+
+// SyntheticRelationUpdates returns the updates which, once written, make the relationships of the
+// synthetic relations of every resource affected by the given changes exactly the union of those
+// of its source relations.
+func SyntheticRelationUpdates(ctx context.Context, reader datastore.Reader, synthetic SyntheticRelations, changes []*core.RelationTupleUpdate) ([]*core.RelationTupleUpdate, error) {
+	// Collect the resources with changes to the source relations of a synthetic relation.
+	sourceRelations := util.NewSet[string]()
+	for nsName, relations := range synthetic {
+		for _, relation := range relations {
+			for _, sourceRelationName := range relation.SourceRelationNames {
+				sourceRelations.Add(nsName + "#" + sourceRelationName)
+			}
+		}
+	}
+
+	affectedResourceIDs := map[string]*util.Set[string]{}
+	for _, change := range changes {
+		resource := change.Tuple.ResourceAndRelation
+		if !sourceRelations.Has(resource.Namespace + "#" + resource.Relation) {
+			continue
+		}
+
+		if _, ok := affectedResourceIDs[resource.Namespace]; !ok {
+			affectedResourceIDs[resource.Namespace] = util.NewSet[string]()
+		}
+		affectedResourceIDs[resource.Namespace].Add(resource.ObjectId)
+	}
+
+	nsNames := make([]string, 0, len(affectedResourceIDs))
+	for nsName := range affectedResourceIDs {
+		nsNames = append(nsNames, nsName)
+	}
+	sort.Strings(nsNames)
+
+	var updates []*core.RelationTupleUpdate
+	for _, nsName := range nsNames {
+		resourceIDs := affectedResourceIDs[nsName].AsSlice()
+		sort.Strings(resourceIDs)
+
+		nsUpdates, err := syntheticRelationUpdates(ctx, reader, nsName, synthetic[nsName], resourceIDs)
+		if err != nil {
+			return nil, err
+		}
+		updates = append(updates, nsUpdates...)
+	}
+	return updates, nil
+}
+
+// syntheticRelationUpdates returns the updates required to materialize the given synthetic
+// relations of the definition, for the given resources or, if nil, for all resources.
+func syntheticRelationUpdates(
+	ctx context.Context,
+	reader datastore.Reader,
+	nsName string,
+	relations []SyntheticRelation,
+	resourceIDs []string,
+) ([]*core.RelationTupleUpdate, error) {
+	syntheticBySource := map[string][]string{}
+	syntheticNames := util.NewSet[string]()
+	for _, relation := range relations {
+		syntheticNames.Add(relation.RelationName)
+		for _, sourceRelationName := range relation.SourceRelationNames {
+			syntheticBySource[sourceRelationName] = append(syntheticBySource[sourceRelationName], relation.RelationName)
+		}
+	}
+
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:        nsName,
+		OptionalResourceIds: resourceIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	expected := map[string]*core.RelationTuple{}
+	existing := map[string]*core.RelationTuple{}
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		if syntheticNames.Has(tpl.ResourceAndRelation.Relation) {
+			existing[tuple.String(tpl)] = tpl
+			continue
+		}
+
+		for _, syntheticName := range syntheticBySource[tpl.ResourceAndRelation.Relation] {
+			materialized := &core.RelationTuple{
+				ResourceAndRelation: &core.ObjectAndRelation{
+					Namespace: nsName,
+					ObjectId:  tpl.ResourceAndRelation.ObjectId,
+					Relation:  syntheticName,
+				},
+				Subject: tpl.Subject.CloneVT(),
+			}
+			expected[tuple.String(materialized)] = materialized
+		}
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+
+	var updates []*core.RelationTupleUpdate
+	for key, tpl := range expected {
+		if _, ok := existing[key]; !ok {
+			updates = append(updates, tuple.Touch(tpl))
+		}
+	}
+	for key, tpl := range existing {
+		if _, ok := expected[key]; !ok {
+			updates = append(updates, tuple.Delete(tpl))
+		}
+	}
+
+	sort.Slice(updates, func(i, j int) bool {
+		return tuple.String(updates[i].Tuple) < tuple.String(updates[j].Tuple)
+	})
+	return updates, nil
+}
@@ -0,0 +1,212 @@
+package relationships
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	iv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+var (
+	templateNameRegex   = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+	templateSignature   = regexp.MustCompile(`^([^(\s]+)\s*\((.*)\)$`)
+	templatePlaceholder = regexp.MustCompile(`\{([^{}]*)\}`)
+)
+
+// Templates are the relationship templates declared in a schema, by name.
+type Templates map[string]Template
+
+// Template is a named set of relationships, parameterized by the object IDs they refer to,
+// declared in the doc comment of a definition with ns.TemplateMarker.
+type Template struct {
+	// Name is the name of the template.
+	Name string
+
+	// Parameters are the names of the parameters of the template, in order.
+	Parameters []string
+
+	// Relationships are the relationships created by the template, in which each parameter is
+	// referenced as `{parameter}`.
+	Relationships []string
+}
+
+// ReadTemplates returns the relationship templates declared in the schema found in the reader.
+func ReadTemplates(ctx context.Context, reader datastore.Reader) (Templates, error) {
+	nsDefs, err := namespace.ListLiveNamespaces(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseTemplates(nsDefs)
+}
+
+// ParseTemplates parses and validates the relationship templates declared in the given
+// definitions. Each relationship of a template must be to a relation of one of the definitions
+// and parameters may only be used as object IDs.
+func ParseTemplates(nsDefs []*core.NamespaceDefinition) (Templates, error) {
+	relations := util.NewSet[string]()
+	for _, nsDef := range nsDefs {
+		for _, relation := range nsDef.Relation {
+			if ns.GetRelationKind(relation) != iv1.RelationMetadata_PERMISSION {
+				relations.Add(nsDef.Name + "#" + relation.Name)
+			}
+		}
+	}
+
+	templates := Templates{}
+	for _, nsDef := range nsDefs {
+		for _, declaration := range ns.GetTemplateDeclarations(nsDef.Metadata) {
+			template, err := parseTemplate(declaration, relations)
+			if err != nil {
+				return nil, NewInvalidTemplateError(nsDef.Name, declaration[0], err)
+			}
+
+			if _, ok := templates[template.Name]; ok {
+				return nil, NewInvalidTemplateError(nsDef.Name, declaration[0], fmt.Errorf("template `%s` is declared more than once", template.Name))
+			}
+			templates[template.Name] = template
+		}
+	}
+	return templates, nil
+}
+
+// parseTemplate parses a template declaration, made of its signature followed by its
+// relationships.
+func parseTemplate(declaration []string, relations *util.Set[string]) (Template, error) {
+	match := templateSignature.FindStringSubmatch(declaration[0])
+	if match == nil || !templateNameRegex.MatchString(match[1]) {
+		return Template{}, fmt.Errorf("expected a signature of the form `name(parameter, ...)`")
+	}
+
+	template := Template{Name: match[1], Relationships: declaration[1:]}
+	parameters := util.NewSet[string]()
+	if strings.TrimSpace(match[2]) != "" {
+		for _, parameter := range strings.Split(match[2], ",") {
+			parameter = strings.TrimSpace(parameter)
+			if !templateNameRegex.MatchString(parameter) {
+				return Template{}, fmt.Errorf("invalid parameter name `%s`", parameter)
+			}
+			if !parameters.Add(parameter) {
+				return Template{}, fmt.Errorf("parameter `%s` is declared more than once", parameter)
+			}
+			template.Parameters = append(template.Parameters, parameter)
+		}
+	}
+
+	if len(template.Relationships) == 0 {
+		return Template{}, fmt.Errorf("template `%s` creates no relationships", template.Name)
+	}
+
+	// Expand the template with the name of each parameter as its argument, so that any use of a
+	// parameter other than as an object ID produces an invalid relationship.
+	tuples, err := template.Expand(template.Parameters)
+	if err != nil {
+		return Template{}, err
+	}
+
+	for _, tpl := range tuples {
+		if !relations.Has(tpl.ResourceAndRelation.Namespace + "#" + tpl.ResourceAndRelation.Relation) {
+			return Template{}, fmt.Errorf("relationship `%s` is not to a relation of the schema", tuple.String(tpl))
+		}
+	}
+	return template, nil
+}
+
+// Expand returns the relationships of the template for the given arguments, given in the order of
+// its parameters. Each argument must be a valid object ID.
+func (t Template) Expand(arguments []string) ([]*core.RelationTuple, error) {
+	if len(arguments) != len(t.Parameters) {
+		return nil, fmt.Errorf("template `%s` expects %d argument(s), got %d", t.Name, len(t.Parameters), len(arguments))
+	}
+
+	// Arguments are restricted to object IDs, so that they cannot change the structure of the
+	// relationships.
+	values := make(map[string]string, len(arguments))
+	for index, parameter := range t.Parameters {
+		if arguments[index] != tuple.PublicWildcard {
+			if err := tuple.ValidateResourceID(arguments[index]); err != nil {
+				return nil, fmt.Errorf("invalid argument for parameter `%s`: %w", parameter, err)
+			}
+		}
+		values[parameter] = arguments[index]
+	}
+
+	tuples := make([]*core.RelationTuple, 0, len(t.Relationships))
+	for _, relationship := range t.Relationships {
+		var missing []string
+		expanded := templatePlaceholder.ReplaceAllStringFunc(relationship, func(placeholder string) string {
+			parameter := strings.TrimSpace(placeholder[1 : len(placeholder)-1])
+			value, ok := values[parameter]
+			if !ok {
+				missing = append(missing, parameter)
+			}
+			return value
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("relationship `%s` references unknown parameter `%s`", relationship, missing[0])
+		}
+
+		tpl := tuple.Parse(expanded)
+		if tpl == nil {
+			return nil, fmt.Errorf("`%s` is not a valid relationship", expanded)
+		}
+		tuples = append(tuples, tpl)
+	}
+	return tuples, nil
+}
+
+// ParseTemplateInvocation parses an invocation of a template of the form
+// `name(argument, ...)`, returning the name of the template and its arguments.
+func ParseTemplateInvocation(value string) (string, []string, error) {
+	match := templateSignature.FindStringSubmatch(strings.TrimSpace(value))
+	if match == nil {
+		return "", nil, fmt.Errorf("expected an invocation of the form `name(argument, ...)`")
+	}
+
+	var arguments []string
+	if strings.TrimSpace(match[2]) != "" {
+		for _, argument := range strings.Split(match[2], ",") {
+			arguments = append(arguments, strings.TrimSpace(argument))
+		}
+	}
+	return match[1], arguments, nil
+}
+
+// Apply returns the updates touching the relationships of the given template invocations, each
+// of the form `name(argument, ...)`. Relationships created by more than one invocation are only
+// touched once.
+func (ts Templates) Apply(invocations []string) ([]*core.RelationTupleUpdate, error) {
+	seen := util.NewSet[string]()
+	var updates []*core.RelationTupleUpdate
+	for _, invocation := range invocations {
+		name, arguments, err := ParseTemplateInvocation(invocation)
+		if err != nil {
+			return nil, NewInvalidTemplateInvocationError(invocation, err)
+		}
+
+		template, ok := ts[name]
+		if !ok {
+			return nil, NewTemplateNotFoundError(name)
+		}
+
+		tuples, err := template.Expand(arguments)
+		if err != nil {
+			return nil, NewInvalidTemplateInvocationError(invocation, err)
+		}
+
+		for _, tpl := range tuples {
+			if seen.Add(tuple.String(tpl)) {
+				updates = append(updates, tuple.Touch(tpl))
+			}
+		}
+	}
+	return updates, nil
+}
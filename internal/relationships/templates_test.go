@@ -0,0 +1,142 @@
+package relationships
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestParseTemplates(t *testing.T) {
+	testCases := []struct {
+		name          string
+		templates     string
+		expected      Templates
+		expectedError string
+	}{
+		{"no templates", "", Templates{}, ""},
+		{
+			"valid template",
+			"// @template onboard_user(org, user)\n// org:{org}#member@user:{user}\n// org:{org}#viewer@user:*",
+			Templates{"onboard_user": {
+				Name:          "onboard_user",
+				Parameters:    []string{"org", "user"},
+				Relationships: []string{"org:{org}#member@user:{user}", "org:{org}#viewer@user:*"},
+			}},
+			"",
+		},
+		{
+			"template without parameters",
+			"// @template open_all()\n// org:all#viewer@user:*",
+			Templates{"open_all": {
+				Name:          "open_all",
+				Relationships: []string{"org:all#viewer@user:*"},
+			}},
+			"",
+		},
+		{"invalid signature", "// @template onboard_user\n// org:acme#member@user:tom", nil, "expected a signature of the form"},
+		{"invalid parameter", "// @template onboard_user(Org)\n// org:{Org}#member@user:tom", nil, "invalid parameter name `Org`"},
+		{"duplicate parameter", "// @template onboard_user(org, org)\n// org:{org}#member@user:tom", nil, "parameter `org` is declared more than once"},
+		{"no relationships", "// @template onboard_user(org)", nil, "creates no relationships"},
+		{"unknown parameter", "// @template onboard_user(org)\n// org:{org}#member@user:{user}", nil, "references unknown parameter `user`"},
+		{"parameter as relation", "// @template onboard_user(org, rel)\n// org:{org}#{rel}@user:tom", nil, "is not to a relation of the schema"},
+		{"invalid relationship", "// @template onboard_user(org)\n// org:{org}#member", nil, "`org:org#member` is not a valid relationship"},
+		{"permission", "// @template onboard_user(org)\n// org:{org}#view@user:tom", nil, "is not to a relation of the schema"},
+		{
+			"duplicate template",
+			"// @template onboard_user(org)\n// org:{org}#member@user:tom\n\n/** @template onboard_user(org)\n org:{org}#member@user:fred */",
+			nil,
+			"template `onboard_user` is declared more than once",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			empty := ""
+			compiled, err := compiler.Compile(compiler.InputSchema{
+				Source: input.Source("schema"),
+				SchemaString: `definition user {}
+
+				` + tc.templates + `
+				definition org {
+					relation member: user
+					relation viewer: user | user:*
+					permission view = member + viewer
+				}`,
+			}, &empty)
+			require.NoError(err)
+
+			templates, err := ParseTemplates(compiled.ObjectDefinitions)
+			if tc.expectedError != "" {
+				require.ErrorContains(err, tc.expectedError)
+				return
+			}
+			require.NoError(err)
+			require.Equal(tc.expected, templates)
+		})
+	}
+}
+
+func TestApplyTemplates(t *testing.T) {
+	templates := Templates{
+		"onboard_user": {
+			Name:          "onboard_user",
+			Parameters:    []string{"org", "user"},
+			Relationships: []string{"org:{org}#member@user:{user}", "team:{org}_all#member@user:{user}", "org:{org}#viewer@user:*"},
+		},
+	}
+
+	testCases := []struct {
+		name          string
+		invocations   []string
+		expected      []string
+		expectedError string
+	}{
+		{"no invocations", nil, nil, ""},
+		{
+			"single invocation",
+			[]string{"onboard_user(acme, tom)"},
+			[]string{"org:acme#member@user:tom", "team:acme_all#member@user:tom", "org:acme#viewer@user:*"},
+			"",
+		},
+		{
+			"overlapping invocations",
+			[]string{"onboard_user(acme, tom)", "onboard_user( acme , fred )"},
+			[]string{
+				"org:acme#member@user:tom", "team:acme_all#member@user:tom", "org:acme#viewer@user:*",
+				"org:acme#member@user:fred", "team:acme_all#member@user:fred",
+			},
+			"",
+		},
+		{"unknown template", []string{"offboard_user(acme, tom)"}, nil, "relationship template `offboard_user` not found"},
+		{"invalid invocation", []string{"onboard_user"}, nil, "expected an invocation of the form"},
+		{"missing argument", []string{"onboard_user(acme)"}, nil, "expects 2 argument(s), got 1"},
+		{"empty argument", []string{"onboard_user(acme, )"}, nil, "invalid argument for parameter `user`"},
+		{"argument changing the relationship", []string{"onboard_user(acme, tom#member)"}, nil, "invalid argument for parameter `user`"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			updates, err := templates.Apply(tc.invocations)
+			if tc.expectedError != "" {
+				require.ErrorContains(err, tc.expectedError)
+				return
+			}
+			require.NoError(err)
+
+			var applied []string
+			for _, update := range updates {
+				require.Equal(tuple.Touch(update.Tuple), update)
+				applied = append(applied, tuple.String(update.Tuple))
+			}
+			require.Equal(tc.expected, applied)
+		})
+	}
+}
@@ -2,6 +2,11 @@ package relationships
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
 
 	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/pkg/caveats"
@@ -12,12 +17,66 @@ import (
 	"github.com/authzed/spicedb/pkg/util"
 )
 
+// StrictValidationMode is a set of optional checks, performed on relationship updates in addition
+// to type checking.
+//
+// Caveat context containing parameters not declared on the caveat is always rejected.
+type StrictValidationMode uint8
+
+const (
+	// NoStrictValidation performs only the default checks.
+	NoStrictValidation StrictValidationMode = 0
+
+	// RequireSubjectRelation rejects relationships without a subject relation when the relation
+	// allows a subject relation for the subject type, such as `group:eng` under
+	// `relation viewer: group | group#member`.
+	RequireSubjectRelation StrictValidationMode = 1 << 0
+
+	// RejectConflictingCaveats rejects touching a relationship which already exists with a
+	// different caveat or caveat context.
+	RejectConflictingCaveats StrictValidationMode = 1 << 1
+)
+
+var strictValidationModeNames = map[string]StrictValidationMode{
+	"require-subject-relation":   RequireSubjectRelation,
+	"reject-conflicting-caveats": RejectConflictingCaveats,
+}
+
+// StrictValidationModeNames returns the names of the available strict validation checks.
+func StrictValidationModeNames() []string {
+	names := make([]string, 0, len(strictValidationModeNames))
+	for name := range strictValidationModeNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseStrictValidationMode returns the strict validation mode enabling each of the named checks.
+func ParseStrictValidationMode(names []string) (StrictValidationMode, error) {
+	mode := NoStrictValidation
+	for _, name := range names {
+		check, ok := strictValidationModeNames[name]
+		if !ok {
+			return NoStrictValidation, fmt.Errorf("unknown strict validation check `%s`; must be one of: %s", name, strings.Join(StrictValidationModeNames(), ", "))
+		}
+		mode |= check
+	}
+	return mode, nil
+}
+
+// Has returns whether the given check is enabled in the mode.
+func (m StrictValidationMode) Has(check StrictValidationMode) bool {
+	return m&check == check
+}
+
 // ValidateRelationshipUpdates performs validation on the given relationship updates, ensuring that
-// they can be applied against the datastore.
+// they can be applied against the datastore, along with any checks enabled by the strict mode.
 func ValidateRelationshipUpdates(
 	ctx context.Context,
-	rwt datastore.ReadWriteTransaction,
+	reader datastore.Reader,
 	updates []*core.RelationTupleUpdate,
+	strictMode StrictValidationMode,
 ) error {
 	// Load caveats, if any.
 	var referencedCaveatMap map[string]*core.CaveatDefinition
@@ -30,7 +89,7 @@ func ValidateRelationshipUpdates(
 	}
 
 	if !referencedCaveatNamesWithContext.IsEmpty() {
-		foundCaveats, err := rwt.ListCaveats(ctx, referencedCaveatNamesWithContext.AsSlice()...)
+		foundCaveats, err := reader.ListCaveats(ctx, referencedCaveatNamesWithContext.AsSlice()...)
 		if err != nil {
 			return err
 		}
@@ -59,7 +118,7 @@ func ValidateRelationshipUpdates(
 			update.Tuple.ResourceAndRelation.Namespace,
 			update.Tuple.ResourceAndRelation.Relation,
 			false,
-			rwt,
+			reader,
 		); err != nil {
 			return err
 		}
@@ -69,7 +128,7 @@ func ValidateRelationshipUpdates(
 			update.Tuple.Subject.Namespace,
 			update.Tuple.Subject.Relation,
 			true,
-			rwt,
+			reader,
 		); err != nil {
 			return err
 		}
@@ -78,7 +137,7 @@ func ValidateRelationshipUpdates(
 		_, ts, err := namespace.ReadNamespaceAndTypes(
 			ctx,
 			update.Tuple.ResourceAndRelation.Namespace,
-			rwt,
+			reader,
 		)
 		if err != nil {
 			return err
@@ -89,6 +148,11 @@ func ValidateRelationshipUpdates(
 			return NewCannotWriteToPermissionError(update)
 		}
 
+		// Validate that the relationship is not writing to a synthetic relation.
+		if ts.IsSynthetic(update.Tuple.ResourceAndRelation.Relation) {
+			return NewCannotWriteToSyntheticRelationError(update)
+		}
+
 		// Validate the subject against the allowed relation(s).
 		var relationToCheck *core.AllowedRelation
 		var caveat *core.AllowedCaveat
@@ -118,6 +182,19 @@ func ValidateRelationshipUpdates(
 			return NewInvalidSubjectTypeError(update, relationToCheck)
 		}
 
+		if strictMode.Has(RequireSubjectRelation) && update.Tuple.Subject.Relation == tuple.Ellipsis {
+			allowedRelations, err := ts.AllowedDirectRelationsAndWildcards(update.Tuple.ResourceAndRelation.Relation)
+			if err != nil {
+				return err
+			}
+
+			for _, allowed := range allowedRelations {
+				if allowed.Namespace == update.Tuple.Subject.Namespace && allowed.GetRelation() != "" && allowed.GetRelation() != tuple.Ellipsis {
+					return NewSubjectRelationRequiredError(update)
+				}
+			}
+		}
+
 		// Validate caveat and its context, if applicable.
 		// TODO(jschorr): once caveats are supported on all datastores, we should elide this check if the
 		// provided context is empty, as the allowed relation check above will ensure the caveat exists.
@@ -135,6 +212,12 @@ func ValidateRelationshipUpdates(
 				caveats.ErrorForUnknownParameters,
 			)
 			if err != nil {
+				return NewInvalidCaveatContextError(update, err)
+			}
+		}
+
+		if strictMode.Has(RejectConflictingCaveats) && update.Operation == core.RelationTupleUpdate_TOUCH {
+			if err := checkNoConflictingCaveat(ctx, reader, update); err != nil {
 				return err
 			}
 		}
@@ -143,6 +226,49 @@ func ValidateRelationshipUpdates(
 	return nil
 }
 
+// checkNoConflictingCaveat ensures that the relationship being touched does not already exist
+// with a different caveat.
+func checkNoConflictingCaveat(ctx context.Context, reader datastore.Reader, update *core.RelationTupleUpdate) error {
+	relationFilter := datastore.SubjectRelationFilter{}.WithEllipsisRelation()
+	if update.Tuple.Subject.Relation != tuple.Ellipsis {
+		relationFilter = datastore.SubjectRelationFilter{}.WithNonEllipsisRelation(update.Tuple.Subject.Relation)
+	}
+
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             update.Tuple.ResourceAndRelation.Namespace,
+		OptionalResourceIds:      []string{update.Tuple.ResourceAndRelation.ObjectId},
+		OptionalResourceRelation: update.Tuple.ResourceAndRelation.Relation,
+		OptionalSubjectsFilter: &datastore.SubjectsFilter{
+			SubjectType:        update.Tuple.Subject.Namespace,
+			OptionalSubjectIds: []string{update.Tuple.Subject.ObjectId},
+			RelationFilter:     relationFilter,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	defer it.Close()
+
+	for existing := it.Next(); existing != nil; existing = it.Next() {
+		if !caveatsEqual(existing.Caveat, update.Tuple.Caveat) {
+			return NewConflictingCaveatError(update, existing)
+		}
+	}
+	return it.Err()
+}
+
+func caveatsEqual(first *core.ContextualizedCaveat, second *core.ContextualizedCaveat) bool {
+	if first.GetCaveatName() != second.GetCaveatName() {
+		return false
+	}
+
+	if len(first.GetContext().GetFields()) == 0 && len(second.GetContext().GetFields()) == 0 {
+		return true
+	}
+
+	return proto.Equal(first.GetContext(), second.GetContext())
+}
+
 func hasNonEmptyCaveatContext(update *core.RelationTupleUpdate) bool {
 	return update.Tuple.Caveat != nil &&
 		update.Tuple.Caveat.CaveatName != "" &&
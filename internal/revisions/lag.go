@@ -0,0 +1,102 @@
+package revisions
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+)
+
+var replicationLagGauge = promauto.NewGauge(prometheus.GaugeOpts{
+	Namespace: "spicedb",
+	Subsystem: "datastore",
+	Name:      "replication_lag_seconds",
+	Help:      "How far the revision read by minimize_latency calls, such as from follower replicas, trails the head revision of the datastore.",
+})
+
+// LagMonitor periodically measures how far the optimized revision of a datastore, read by
+// minimize_latency calls, trails its head revision. This is the replication lag of the reads from
+// replicas, along with the follower read delay and revision quantization which allow them. It can
+// only be measured for the datastores whose revisions are timestamps.
+type LagMonitor struct {
+	ds       datastore.Datastore
+	settings Settings
+	interval time.Duration
+
+	// lagNanos is the last measured lag, or -1 if the lag has not been measured.
+	lagNanos atomic.Int64
+}
+
+// NewLagMonitor creates a new monitor of the replication lag of the datastore, measured at every
+// interval once started.
+func NewLagMonitor(ds datastore.Datastore, settings Settings, interval time.Duration) *LagMonitor {
+	m := &LagMonitor{ds: ds, settings: settings, interval: interval}
+	m.lagNanos.Store(-1)
+	return m
+}
+
+// Lag returns the last measured replication lag, if measured.
+func (m *LagMonitor) Lag() (time.Duration, bool) {
+	if m == nil {
+		return 0, false
+	}
+
+	lagNanos := m.lagNanos.Load()
+	if lagNanos < 0 {
+		return 0, false
+	}
+	return time.Duration(lagNanos), true
+}
+
+// Measure measures the replication lag, which is unknown if the revisions of the datastore are
+// not timestamps.
+func (m *LagMonitor) Measure(ctx context.Context) (time.Duration, bool, error) {
+	head, err := m.ds.HeadRevision(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	optimized, err := m.ds.OptimizedRevision(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+
+	headDecimal, headOK := head.(revision.Decimal)
+	optimizedDecimal, optimizedOK := optimized.(revision.Decimal)
+	if !m.settings.TimestampRevisions || !headOK || !optimizedOK {
+		return 0, false, nil
+	}
+
+	lag := time.Duration(headDecimal.IntPart() - optimizedDecimal.IntPart())
+	if lag < 0 {
+		lag = 0
+	}
+
+	m.lagNanos.Store(lag.Nanoseconds())
+	replicationLagGauge.Set(lag.Seconds())
+	return lag, true, nil
+}
+
+// Start measures the replication lag at every interval until the context is canceled.
+func (m *LagMonitor) Start(ctx context.Context) error {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, _, err := m.Measure(ctx); err != nil && ctx.Err() == nil {
+			log.Ctx(ctx).Warn().Err(err).Msg("failed to measure datastore replication lag")
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package revisions
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+)
+
+func TestLagMonitor(t *testing.T) {
+	require := require.New(t)
+
+	var nilMonitor *LagMonitor
+	_, ok := nilMonitor.Lag()
+	require.False(ok)
+
+	ds, err := memdb.NewMemdbDatastore(0, 5*time.Second, time.Hour)
+	require.NoError(err)
+
+	monitor := NewLagMonitor(ds, Settings{TimestampRevisions: true}, time.Second)
+	_, ok = monitor.Lag()
+	require.False(ok)
+
+	measured, ok, err := monitor.Measure(context.Background())
+	require.NoError(err)
+	require.True(ok)
+	require.GreaterOrEqual(measured, time.Duration(0))
+	require.LessOrEqual(measured, 5*time.Second)
+
+	lag, ok := monitor.Lag()
+	require.True(ok)
+	require.Equal(measured, lag)
+
+	// The lag of datastores whose revisions are not timestamps is unknown.
+	monitor = NewLagMonitor(ds, Settings{}, time.Second)
+	_, ok, err = monitor.Measure(context.Background())
+	require.NoError(err)
+	require.False(ok)
+	_, ok = monitor.Lag()
+	require.False(ok)
+}
@@ -0,0 +1,141 @@
+// Package revisions reports the revisions of a datastore, and the settings determining which of
+// them are used and kept, to reason about the staleness of API calls.
+package revisions
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/datastore/revision"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+// Settings are the configured settings of a datastore determining its revisions.
+type Settings struct {
+	// Engine is the engine of the datastore.
+	Engine string
+
+	// TimestampRevisions is whether the revisions of the datastore are timestamps, in nanoseconds
+	// since the Unix epoch.
+	TimestampRevisions bool
+
+	// RevisionQuantization is the interval to which the optimized revision is rounded.
+	RevisionQuantization time.Duration
+
+	// FollowerReadDelay is the delay subtracted from the optimized revision, if the datastore
+	// supports follower reads.
+	FollowerReadDelay time.Duration
+
+	// GCWindow is the age after which revisions are garbage collected.
+	GCWindow time.Duration
+}
+
+// Metadata describes the revisions of a datastore at a point in time.
+type Metadata struct {
+	// ObservedAt is the wall-clock time at which the revisions were read.
+	ObservedAt time.Time `json:"observedAt"`
+
+	// Engine is the engine of the datastore.
+	Engine string `json:"engine"`
+
+	// HeadRevision is the most recent revision, used by fully consistent calls.
+	HeadRevision Revision `json:"headRevision"`
+
+	// OptimizedRevision is the revision used by minimize latency calls.
+	OptimizedRevision Revision `json:"optimizedRevision"`
+
+	// RevisionQuantizationInterval is the interval to which the optimized revision is rounded.
+	RevisionQuantizationInterval string `json:"revisionQuantizationInterval"`
+
+	// FollowerReadDelay is the delay subtracted from the optimized revision, if the datastore
+	// supports follower reads.
+	FollowerReadDelay string `json:"followerReadDelay,omitempty"`
+
+	// GCWindow is the age after which revisions are garbage collected.
+	GCWindow string `json:"gcWindow"`
+
+	// GCWatermark is the wall-clock time before which revisions may have been garbage collected,
+	// and can no longer be read.
+	GCWatermark time.Time `json:"gcWatermark"`
+}
+
+// Revision describes a revision of a datastore.
+type Revision struct {
+	// Revision is the revision, as understood by the datastore.
+	Revision string `json:"revision"`
+
+	// ZedToken is a ZedToken for the revision.
+	ZedToken string `json:"zedToken"`
+
+	// Timestamp is the wall-clock time of the revision, for the datastores whose revisions are
+	// timestamps.
+	Timestamp *time.Time `json:"timestamp,omitempty"`
+}
+
+// Read reads the revision metadata of the datastore.
+func Read(ctx context.Context, ds datastore.Datastore, settings Settings) (*Metadata, error) {
+	observedAt := time.Now().UTC()
+
+	head, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	optimized, err := ds.OptimizedRevision(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := &Metadata{
+		ObservedAt:                   observedAt,
+		Engine:                       settings.Engine,
+		HeadRevision:                 describeRevision(settings, head),
+		OptimizedRevision:            describeRevision(settings, optimized),
+		RevisionQuantizationInterval: settings.RevisionQuantization.String(),
+		GCWindow:                     settings.GCWindow.String(),
+		GCWatermark:                  observedAt.Add(-settings.GCWindow),
+	}
+	if settings.FollowerReadDelay > 0 {
+		metadata.FollowerReadDelay = settings.FollowerReadDelay.String()
+	}
+	return metadata, nil
+}
+
+func describeRevision(settings Settings, rev datastore.Revision) Revision {
+	described := Revision{
+		Revision: rev.String(),
+		ZedToken: zedtoken.NewFromRevision(rev).Token,
+	}
+
+	if decimalRevision, ok := rev.(revision.Decimal); ok && settings.TimestampRevisions {
+		timestamp := time.Unix(0, decimalRevision.IntPart()).UTC()
+		described.Timestamp = &timestamp
+	}
+	return described
+}
+
+// NewHandler returns an http.Handler serving the JSON-encoded revision Metadata of the datastore.
+func NewHandler(ds datastore.Datastore, settings Settings) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		metadata, err := Read(r.Context(), ds, settings)
+		if err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to read revision metadata")
+			http.Error(w, "failed to read the revisions of the datastore", http.StatusServiceUnavailable)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(metadata); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write revision metadata")
+		}
+	})
+}
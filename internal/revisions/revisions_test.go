@@ -0,0 +1,72 @@
+package revisions
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func TestHandler(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 5*time.Second, time.Hour)
+	require.NoError(err)
+	ds, rev := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	handler := NewHandler(ds, Settings{
+		Engine:               "memory",
+		TimestampRevisions:   true,
+		RevisionQuantization: 5 * time.Second,
+		GCWindow:             time.Hour,
+	})
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/revisions", nil))
+	require.Equal(http.StatusOK, recorder.Code)
+	require.Equal("application/json", recorder.Header().Get("Content-Type"))
+
+	var metadata Metadata
+	require.NoError(json.Unmarshal(recorder.Body.Bytes(), &metadata))
+	require.Equal("memory", metadata.Engine)
+	require.Equal("5s", metadata.RevisionQuantizationInterval)
+	require.Equal("1h0m0s", metadata.GCWindow)
+	require.Empty(metadata.FollowerReadDelay)
+	require.Equal(metadata.ObservedAt.Add(-time.Hour), metadata.GCWatermark)
+
+	head, err := ds.RevisionFromString(metadata.HeadRevision.Revision)
+	require.NoError(err)
+	require.False(head.LessThan(rev))
+	require.Equal(zedtoken.NewFromRevision(head).Token, metadata.HeadRevision.ZedToken)
+	require.NotNil(metadata.HeadRevision.Timestamp)
+	require.WithinDuration(metadata.ObservedAt, *metadata.HeadRevision.Timestamp, time.Minute)
+
+	optimized, err := ds.RevisionFromString(metadata.OptimizedRevision.Revision)
+	require.NoError(err)
+	require.False(optimized.GreaterThan(head))
+	require.NotNil(metadata.OptimizedRevision.Timestamp)
+	require.Zero(metadata.OptimizedRevision.Timestamp.UnixNano() % int64(5*time.Second))
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/revisions", nil))
+	require.Equal(http.StatusMethodNotAllowed, recorder.Code)
+}
+
+func TestDescribeRevisionWithoutTimestamps(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	_, rev := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	described := describeRevision(Settings{Engine: "postgres"}, rev)
+	require.Equal(rev.String(), described.Revision)
+	require.Nil(described.Timestamp)
+}
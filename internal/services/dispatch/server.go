@@ -11,13 +11,15 @@ import (
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
-// RegisterGrpcServices registers an internal dispatch service with the specified server.
+// RegisterGrpcServices registers an internal dispatch service with the specified server. The
+// status of the dispatch service is reported by the given health server, through which the
+// peers of the dispatch cluster are told to stop dispatching to the server when it drains.
 func RegisterGrpcServices(
 	srv *grpc.Server,
 	d dispatch.Dispatcher,
+	healthSrv *grpcutil.AuthlessHealthServer,
 ) {
 	srv.RegisterService(&dispatchv1.DispatchService_ServiceDesc, dispatch_v1.NewDispatchServer(d))
-	healthSrv := grpcutil.NewAuthlessHealthServer()
 	healthSrv.SetServicesHealthy(&dispatchv1.DispatchService_ServiceDesc)
 	healthpb.RegisterHealthServer(srv, healthSrv)
 	reflection.Register(srv)
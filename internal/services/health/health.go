@@ -2,24 +2,65 @@ package health
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/authzed/grpcutil"
 	"github.com/cenkalti/backoff/v4"
+	"golang.org/x/exp/slices"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
 )
 
-const datastoreReadyTimeout = time.Millisecond * 500
+const (
+	datastoreReadyTimeout  = time.Millisecond * 500
+	watchCheckTimeout      = time.Millisecond * 500
+	subsystemCheckInterval = time.Second * 10
+)
+
+// The subsystems whose status is reported by the health service under their own service names,
+// so that probes and dashboards can tell failure modes apart.
+const (
+	// DatastoreSubsystem reports whether the datastore can be reached.
+	DatastoreSubsystem = "spicedb.datastore"
+
+	// MigrationsSubsystem reports whether the datastore is migrated to the head migration.
+	MigrationsSubsystem = "spicedb.datastore.migrations"
+
+	// WatchSubsystem reports whether watch streams can be opened on the datastore.
+	WatchSubsystem = "spicedb.datastore.watch"
+
+	// DispatchSubsystem reports whether the dispatcher, including its connections to the
+	// dispatch cluster, is ready.
+	DispatchSubsystem = "spicedb.dispatch"
+)
+
+// allSubsystems are the subsystems whose status is reported.
+var allSubsystems = []string{DatastoreSubsystem, MigrationsSubsystem, WatchSubsystem, DispatchSubsystem}
+
+// readinessSubsystems are the subsystems which must be serving for the registered services to
+// be reported as serving.
+var readinessSubsystems = []string{DatastoreSubsystem, MigrationsSubsystem, DispatchSubsystem}
 
 // NewHealthManager creates and returns a new health manager that checks the IsReady
 // status of the given dispatcher and datastore checker and sets the health check to
-// return healthy once both have gone to true.
+// return healthy once both have gone to true. The status of each subsystem keeps
+// being checked afterwards.
 func NewHealthManager(dispatcher dispatch.Dispatcher, dsc DatastoreChecker) Manager {
 	healthSvc := grpcutil.NewAuthlessHealthServer()
-	return &healthManager{healthSvc, dispatcher, dsc, map[string]struct{}{}}
+	return &healthManager{
+		healthSvc:    healthSvc,
+		dispatcher:   dispatcher,
+		dsc:          dsc,
+		serviceNames: map[string]struct{}{},
+		subsystems:   map[string]SubsystemStatus{},
+	}
 }
 
 // DatastoreChecker is an interface for determining if the datastore is ready for
@@ -29,6 +70,34 @@ type DatastoreChecker interface {
 	IsReady(ctx context.Context) (bool, error)
 }
 
+// DatastoreWatcher is implemented by the datastore checkers whose watch streams are checked.
+type DatastoreWatcher interface {
+	HeadRevision(ctx context.Context) (datastore.Revision, error)
+	Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error)
+}
+
+// SubsystemStatus is the last checked status of a subsystem.
+type SubsystemStatus struct {
+	Status healthpb.HealthCheckResponse_ServingStatus
+	Error  string
+}
+
+// MarshalJSON marshals the status with the name of its serving status.
+func (ss SubsystemStatus) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Status string `json:"status"`
+		Error  string `json:"error,omitempty"`
+	}{ss.Status.String(), ss.Error})
+}
+
+func servingStatus() SubsystemStatus {
+	return SubsystemStatus{Status: healthpb.HealthCheckResponse_SERVING}
+}
+
+func notServingStatus(err error) SubsystemStatus {
+	return SubsystemStatus{Status: healthpb.HealthCheckResponse_NOT_SERVING, Error: err.Error()}
+}
+
 // Manager is a system which manages the health service statuses.
 type Manager interface {
 	// RegisterReportedService registers the name of service under the same server
@@ -40,6 +109,12 @@ type Manager interface {
 
 	// Checker returns a function that can be run via an errgroup to perform the health checks.
 	Checker(ctx context.Context) func() error
+
+	// Subsystems returns the last checked status of each subsystem.
+	Subsystems() map[string]SubsystemStatus
+
+	// HTTPHandler returns a handler serving the status of the subsystems.
+	HTTPHandler() http.Handler
 }
 
 type healthManager struct {
@@ -47,6 +122,9 @@ type healthManager struct {
 	dispatcher   dispatch.Dispatcher
 	dsc          DatastoreChecker
 	serviceNames map[string]struct{}
+
+	mu         sync.RWMutex
+	subsystems map[string]SubsystemStatus
 }
 
 func (hm *healthManager) HealthSvc() *grpcutil.AuthlessHealthServer {
@@ -60,53 +138,186 @@ func (hm *healthManager) RegisterReportedService(serviceName string) {
 
 func (hm *healthManager) Checker(ctx context.Context) func() error {
 	return func() error {
-		// Run immediately for the initial check
+		// Until the services are ready, the checks back off up to the interval of the
+		// subsystem checks.
 		backoffInterval := backoff.NewExponentialBackOff()
+		backoffInterval.MaxInterval = subsystemCheckInterval
+		backoffInterval.MaxElapsedTime = 0
+
+		// Run immediately for the initial check
 		ticker := time.After(0)
+		ready := false
 
 		for {
 			select {
-			case _, ok := <-ticker:
-				if !ok {
-					log.Warn().Msg("backoff error while waiting for dispatcher or datastore health")
-					return nil
-				}
-
+			case <-ticker:
 			case <-ctx.Done():
-				log.Warn().Msg("datastore health context was canceled")
+				log.Debug().Msg("health check context was canceled")
 				return nil
 			}
 
-			isReady := hm.checkIsReady(ctx)
-			if isReady {
+			if hm.checkSubsystems(ctx) && !ready {
 				for serviceName := range hm.serviceNames {
 					hm.healthSvc.Server.SetServingStatus(serviceName, healthpb.HealthCheckResponse_SERVING)
 				}
-				return nil
+				ready = true
 			}
 
-			nextPush := backoffInterval.NextBackOff()
-			if nextPush == backoff.Stop {
-				log.Warn().Msg("exceed max attempts to check for dispatch or datastore ready")
-				return nil
+			if ready {
+				ticker = time.After(subsystemCheckInterval)
+			} else {
+				ticker = time.After(backoffInterval.NextBackOff())
 			}
-			ticker = time.After(nextPush)
 		}
 	}
 }
 
-func (hm *healthManager) checkIsReady(ctx context.Context) bool {
-	log.Debug().Msg("checking if datastore and dispatcher are ready")
+// checkSubsystems checks the status of each subsystem and returns whether those required for
+// the services to be ready are serving.
+func (hm *healthManager) checkSubsystems(ctx context.Context) bool {
+	log.Debug().Msg("checking the health of the datastore and dispatcher")
+
+	statuses := make(map[string]SubsystemStatus, 4)
+
+	dsCtx, cancel := context.WithTimeout(ctx, datastoreReadyTimeout)
+	dsReady, err := hm.dsc.IsReady(dsCtx)
+	cancel()
+	switch {
+	case err != nil:
+		log.Warn().Err(err).Msg("could not check if the datastore was ready")
+		statuses[DatastoreSubsystem] = notServingStatus(err)
+		statuses[MigrationsSubsystem] = SubsystemStatus{
+			Status: healthpb.HealthCheckResponse_UNKNOWN,
+			Error:  "the datastore could not be reached",
+		}
+	case !dsReady:
+		statuses[DatastoreSubsystem] = servingStatus()
+		statuses[MigrationsSubsystem] = notServingStatus(errors.New("the datastore is not migrated to the head migration"))
+	default:
+		statuses[DatastoreSubsystem] = servingStatus()
+		statuses[MigrationsSubsystem] = servingStatus()
+	}
 
-	ctx, cancel := context.WithTimeout(ctx, datastoreReadyTimeout)
+	if watcher, ok := hm.dsc.(DatastoreWatcher); ok {
+		if err != nil {
+			statuses[WatchSubsystem] = SubsystemStatus{
+				Status: healthpb.HealthCheckResponse_UNKNOWN,
+				Error:  "the datastore could not be reached",
+			}
+		} else if err := checkWatch(ctx, watcher); err != nil {
+			log.Warn().Err(err).Msg("could not open a watch stream on the datastore")
+			statuses[WatchSubsystem] = notServingStatus(err)
+		} else {
+			statuses[WatchSubsystem] = servingStatus()
+		}
+	}
+
+	if hm.dispatcher.IsReady() {
+		statuses[DispatchSubsystem] = servingStatus()
+	} else {
+		statuses[DispatchSubsystem] = notServingStatus(errors.New("the dispatcher is not connected to the dispatch cluster"))
+	}
+
+	hm.mu.Lock()
+	hm.subsystems = statuses
+	hm.mu.Unlock()
+
+	for name, status := range statuses {
+		hm.healthSvc.Server.SetServingStatus(name, status.Status)
+	}
+
+	ready := true
+	for _, name := range readinessSubsystems {
+		ready = ready && statuses[name].Status == healthpb.HealthCheckResponse_SERVING
+	}
+
+	log.Debug().Bool("datastoreReady", dsReady).Bool("ready", ready).Msg("completed subsystem health checks")
+	return ready
+}
+
+// checkWatch opens a watch stream at the head revision of the datastore, and returns the error
+// of the stream if it fails before the check times out.
+func checkWatch(ctx context.Context, watcher DatastoreWatcher) error {
+	ctx, cancel := context.WithTimeout(ctx, watchCheckTimeout)
 	defer cancel()
 
-	dsReady, err := hm.dsc.IsReady(ctx)
+	headRevision, err := watcher.HeadRevision(ctx)
 	if err != nil {
-		log.Warn().Err(err).Msg("could not check if the datastore was ready")
+		return err
+	}
+
+	changes, errs := watcher.Watch(ctx, headRevision, datastore.WatchOptions{})
+	for {
+		select {
+		case _, ok := <-changes:
+			if !ok {
+				changes = nil
+			}
+		case err := <-errs:
+			if ctx.Err() != nil {
+				// The watch was canceled by the end of the check.
+				return nil
+			}
+			return err
+		case <-ctx.Done():
+			return nil
+		}
 	}
+}
 
-	dispatchReady := hm.dispatcher.IsReady()
-	log.Debug().Bool("datastoreReady", dsReady).Bool("dispatchReady", dispatchReady).Msg("completed dispatcher and datastore readiness checks")
-	return dsReady && dispatchReady
+func (hm *healthManager) Subsystems() map[string]SubsystemStatus {
+	hm.mu.RLock()
+	defer hm.mu.RUnlock()
+
+	subsystems := make(map[string]SubsystemStatus, len(hm.subsystems))
+	for name, status := range hm.subsystems {
+		subsystems[name] = status
+	}
+	return subsystems
+}
+
+// HTTPHandler serves the status of the subsystems as JSON, along with an overall status which
+// is serving when the subsystems required for the services to be ready are serving. The
+// `subsystem` query parameter restricts the response to the given subsystem. The response
+// status is 503 when the reported status is not serving, so that the handler can be used for
+// probes.
+func (hm *healthManager) HTTPHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subsystems := hm.Subsystems()
+
+		var response struct {
+			Status     string                     `json:"status"`
+			Subsystems map[string]SubsystemStatus `json:"subsystems"`
+		}
+
+		serving := true
+		if name := r.URL.Query().Get("subsystem"); name != "" {
+			if !slices.Contains(allSubsystems, name) {
+				http.Error(w, "unknown subsystem "+name, http.StatusNotFound)
+				return
+			}
+			// Subsystems which have not been checked yet are reported with an unknown status.
+			status := subsystems[name]
+			serving = status.Status == healthpb.HealthCheckResponse_SERVING
+			response.Subsystems = map[string]SubsystemStatus{name: status}
+		} else {
+			for _, name := range readinessSubsystems {
+				serving = serving && subsystems[name].Status == healthpb.HealthCheckResponse_SERVING
+			}
+			response.Subsystems = subsystems
+		}
+
+		response.Status = healthpb.HealthCheckResponse_SERVING.String()
+		statusCode := http.StatusOK
+		if !serving {
+			response.Status = healthpb.HealthCheckResponse_NOT_SERVING.String()
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(statusCode)
+		if err := json.NewEncoder(w).Encode(response); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write health response")
+		}
+	})
 }
@@ -0,0 +1,185 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+type fakeDispatcher struct {
+	dispatch.Dispatcher
+	ready bool
+}
+
+func (fd fakeDispatcher) IsReady() bool { return fd.ready }
+
+type fakeChecker struct {
+	ready bool
+	err   error
+}
+
+func (fc fakeChecker) IsReady(_ context.Context) (bool, error) { return fc.ready, fc.err }
+
+type fakeWatcher struct {
+	datastore.Datastore
+	fakeChecker
+	watchErr error
+}
+
+func (fw fakeWatcher) IsReady(ctx context.Context) (bool, error) {
+	return fw.fakeChecker.IsReady(ctx)
+}
+
+func (fw fakeWatcher) Watch(ctx context.Context, afterRevision datastore.Revision, options datastore.WatchOptions) (<-chan *datastore.RevisionChanges, <-chan error) {
+	if fw.watchErr == nil {
+		return fw.Datastore.Watch(ctx, afterRevision, options)
+	}
+	errs := make(chan error, 1)
+	errs <- fw.watchErr
+	return nil, errs
+}
+
+func TestCheckSubsystems(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	t.Cleanup(func() { ds.Close() })
+
+	const (
+		serving    = healthpb.HealthCheckResponse_SERVING
+		notServing = healthpb.HealthCheckResponse_NOT_SERVING
+		unknown    = healthpb.HealthCheckResponse_UNKNOWN
+	)
+
+	testCases := []struct {
+		name          string
+		dsc           DatastoreChecker
+		dispatchReady bool
+		expectedReady bool
+		expected      map[string]healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{
+			"all serving",
+			fakeWatcher{ds, fakeChecker{ready: true}, nil},
+			true,
+			true,
+			map[string]healthpb.HealthCheckResponse_ServingStatus{
+				DatastoreSubsystem:  serving,
+				MigrationsSubsystem: serving,
+				WatchSubsystem:      serving,
+				DispatchSubsystem:   serving,
+			},
+		},
+		{
+			"unreachable datastore",
+			fakeWatcher{ds, fakeChecker{err: errors.New("connection refused")}, nil},
+			true,
+			false,
+			map[string]healthpb.HealthCheckResponse_ServingStatus{
+				DatastoreSubsystem:  notServing,
+				MigrationsSubsystem: unknown,
+				WatchSubsystem:      unknown,
+				DispatchSubsystem:   serving,
+			},
+		},
+		{
+			"missing migrations",
+			fakeChecker{ready: false},
+			true,
+			false,
+			map[string]healthpb.HealthCheckResponse_ServingStatus{
+				DatastoreSubsystem:  serving,
+				MigrationsSubsystem: notServing,
+				DispatchSubsystem:   serving,
+			},
+		},
+		{
+			"failing watch",
+			fakeWatcher{ds, fakeChecker{ready: true}, datastore.NewWatchDisabledErr("rangefeeds are disabled")},
+			true,
+			true,
+			map[string]healthpb.HealthCheckResponse_ServingStatus{
+				DatastoreSubsystem:  serving,
+				MigrationsSubsystem: serving,
+				WatchSubsystem:      notServing,
+				DispatchSubsystem:   serving,
+			},
+		},
+		{
+			"disconnected dispatch cluster",
+			fakeChecker{ready: true},
+			false,
+			false,
+			map[string]healthpb.HealthCheckResponse_ServingStatus{
+				DatastoreSubsystem:  serving,
+				MigrationsSubsystem: serving,
+				DispatchSubsystem:   notServing,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			hm := NewHealthManager(fakeDispatcher{ready: tc.dispatchReady}, tc.dsc).(*healthManager)
+			require.Equal(t, tc.expectedReady, hm.checkSubsystems(context.Background()))
+
+			subsystems := hm.Subsystems()
+			require.Len(t, subsystems, len(tc.expected))
+			for name, status := range tc.expected {
+				require.Equal(t, status, subsystems[name].Status, name)
+
+				resp, err := hm.HealthSvc().Check(context.Background(), &healthpb.HealthCheckRequest{Service: name})
+				require.NoError(t, err)
+				require.Equal(t, status, resp.Status, name)
+			}
+		})
+	}
+}
+
+func TestHTTPHandler(t *testing.T) {
+	hm := NewHealthManager(fakeDispatcher{ready: true}, fakeChecker{ready: false})
+
+	get := func(target string) (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		hm.HTTPHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, target, nil))
+		if recorder.Code == http.StatusNotFound {
+			return recorder.Code, nil
+		}
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return recorder.Code, body
+	}
+
+	// Subsystems are unknown until they are checked.
+	code, body := get("/health?subsystem=" + DispatchSubsystem)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "UNKNOWN", body["subsystems"].(map[string]interface{})[DispatchSubsystem].(map[string]interface{})["status"])
+
+	require.False(t, hm.(*healthManager).checkSubsystems(context.Background()))
+
+	code, body = get("/health")
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, "NOT_SERVING", body["status"])
+	require.Equal(t, map[string]interface{}{
+		"status": "NOT_SERVING",
+		"error":  "the datastore is not migrated to the head migration",
+	}, body["subsystems"].(map[string]interface{})[MigrationsSubsystem])
+
+	code, body = get("/health?subsystem=" + DispatchSubsystem)
+	require.Equal(t, http.StatusOK, code)
+	require.Equal(t, "SERVING", body["status"])
+
+	code, _ = get("/health?subsystem=spicedb.unknown")
+	require.Equal(t, http.StatusNotFound, code)
+}
@@ -0,0 +1,38 @@
+package services
+
+import (
+	"github.com/authzed/grpcutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+	reflectionv1 "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	reflectionv1alpha "google.golang.org/grpc/reflection/grpc_reflection_v1alpha"
+)
+
+// authlessReflectionRegistrar registers the servers of both versions of the reflection service
+// so that they skip the auth middleware.
+type authlessReflectionRegistrar struct {
+	reflection.GRPCServer
+}
+
+func (r authlessReflectionRegistrar) RegisterService(desc *grpc.ServiceDesc, impl any) {
+	switch impl := impl.(type) {
+	case reflectionv1.ServerReflectionServer:
+		r.GRPCServer.RegisterService(desc, &authlessReflectionV1{ServerReflectionServer: impl})
+	case reflectionv1alpha.ServerReflectionServer:
+		r.GRPCServer.RegisterService(desc, &authlessReflectionV1Alpha{ServerReflectionServer: impl})
+	default:
+		r.GRPCServer.RegisterService(desc, impl)
+	}
+}
+
+type authlessReflectionV1 struct {
+	grpcutil.IgnoreAuthMixin
+
+	reflectionv1.ServerReflectionServer
+}
+
+type authlessReflectionV1Alpha struct {
+	grpcutil.IgnoreAuthMixin
+
+	reflectionv1alpha.ServerReflectionServer
+}
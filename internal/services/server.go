@@ -2,14 +2,16 @@ package services
 
 import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
-	"github.com/authzed/grpcutil"
 	"google.golang.org/grpc"
 	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/services/health"
+	"github.com/authzed/spicedb/internal/services/shared"
 	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	jobsv1 "github.com/authzed/spicedb/pkg/proto/jobs/v1"
+	watchv1 "github.com/authzed/spicedb/pkg/proto/watch/v1"
 )
 
 // SchemaServiceOption defines the options for enabling or disabling the V1 Schema service.
@@ -32,6 +34,10 @@ const (
 	// mode for testing.
 	V1SchemaServiceAdditiveOnly SchemaServiceOption = 2
 
+	// V1SchemaServiceSoftDelete indicates that the V1 schema service is enabled, with object
+	// definitions removed from the schema soft-deleted while they still have relationships.
+	V1SchemaServiceSoftDelete SchemaServiceOption = 3
+
 	// WatchServiceDisabled indicates that the V1 watch service is disabled.
 	WatchServiceDisabled WatchServiceOption = 0
 
@@ -58,23 +64,38 @@ func RegisterGrpcServices(
 	schemaServiceOption SchemaServiceOption,
 	watchServiceOption WatchServiceOption,
 	caveatsOption CaveatsOption,
+	schemaLimits shared.SchemaLimits,
 	permSysConfig v1svc.PermissionsServerConfig,
+	permissionSets *shared.PermissionSetMaterializer,
 ) {
 	healthManager.RegisterReportedService(OverallServerHealthCheckKey)
 
 	v1.RegisterPermissionsServiceServer(srv, v1svc.NewPermissionsServer(dispatch, permSysConfig, caveatsOption == CaveatsEnabled))
 	healthManager.RegisterReportedService(v1.PermissionsService_ServiceDesc.ServiceName)
 
+	if permSysConfig.DeleteJobs != nil {
+		jobsv1.RegisterDeleteJobServiceServer(srv, v1svc.NewDeleteJobServer(permSysConfig))
+		healthManager.RegisterReportedService(jobsv1.DeleteJobService_ServiceDesc.ServiceName)
+	}
+
 	if watchServiceOption == WatchServiceEnabled {
-		v1.RegisterWatchServiceServer(srv, v1svc.NewWatchServer())
+		v1.RegisterWatchServiceServer(srv, v1svc.NewWatchServer(dispatch, permSysConfig.MaximumAPIDepth, permissionSets))
 		healthManager.RegisterReportedService(v1.WatchService_ServiceDesc.ServiceName)
+
+		watchv1.RegisterWatchServiceServer(srv, v1svc.NewWatchEventsServer())
+		healthManager.RegisterReportedService(watchv1.WatchService_ServiceDesc.ServiceName)
 	}
 
-	if schemaServiceOption == V1SchemaServiceEnabled || schemaServiceOption == V1SchemaServiceAdditiveOnly {
-		v1.RegisterSchemaServiceServer(srv, v1svc.NewSchemaServer(schemaServiceOption == V1SchemaServiceAdditiveOnly, caveatsOption == CaveatsEnabled))
+	if schemaServiceOption != V1SchemaServiceDisabled {
+		v1.RegisterSchemaServiceServer(srv, v1svc.NewSchemaServer(
+			schemaServiceOption == V1SchemaServiceAdditiveOnly,
+			caveatsOption == CaveatsEnabled,
+			schemaServiceOption == V1SchemaServiceSoftDelete,
+			schemaLimits,
+		))
 		healthManager.RegisterReportedService(v1.SchemaService_ServiceDesc.ServiceName)
 	}
 
 	healthpb.RegisterHealthServer(srv, healthManager.HealthSvc())
-	reflection.Register(grpcutil.NewAuthlessReflectionInterceptor(srv))
+	reflection.Register(authlessReflectionRegistrar{srv})
 }
@@ -0,0 +1,276 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+var deleteJobRelationshipsCounter = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "relationships",
+	Name:      "delete_job_deleted_total",
+	Help:      "The number of relationships deleted by asynchronous delete jobs.",
+})
+
+// deleteJobRetention is how long finished delete jobs are kept for their status to be queried.
+const deleteJobRetention = 24 * time.Hour
+
+// ErrDeleteJobsNotRunning is returned when a delete job is submitted while the delete jobs are not
+// running, such as when the server is shutting down.
+var ErrDeleteJobsNotRunning = errors.New("delete jobs are not running")
+
+// DeleteJobState is the state of an asynchronous delete job.
+type DeleteJobState string
+
+const (
+	// DeleteJobRunning indicates that the job is deleting relationships.
+	DeleteJobRunning DeleteJobState = "running"
+
+	// DeleteJobCompleted indicates that all matching relationships have been deleted.
+	DeleteJobCompleted DeleteJobState = "completed"
+
+	// DeleteJobCanceled indicates that the job was canceled, or interrupted by the server
+	// shutting down, before deleting all matching relationships.
+	DeleteJobCanceled DeleteJobState = "canceled"
+
+	// DeleteJobFailed indicates that the job stopped on an error.
+	DeleteJobFailed DeleteJobState = "failed"
+)
+
+// DeleteJobStatus is the status of an asynchronous delete job.
+type DeleteJobStatus struct {
+	// ID is the ID of the job.
+	ID string
+
+	// State is the current state of the job.
+	State DeleteJobState
+
+	// RelationshipsDeleted is the number of relationships deleted so far.
+	RelationshipsDeleted uint64
+
+	// SubmittedAt is the time at which the job was submitted.
+	SubmittedAt time.Time
+
+	// FinishedAt is the time at which the job finished, if it has.
+	FinishedAt time.Time
+
+	// Error is the error on which the job failed, if it has.
+	Error string
+}
+
+type deleteJob struct {
+	status  DeleteJobStatus
+	owner   string
+	ds      datastore.Datastore
+	filters []datastore.RelationshipsFilter
+	cancel  context.CancelFunc
+}
+
+// DeleteJobs runs asynchronous delete jobs, which delete the relationships matching filters in
+// batches, each in its own transaction and therefore at its own revision, pausing between batches
+// so as not to starve other writes. Watchers see each batch as it is deleted.
+//
+// Jobs belong to the caller which submitted them, and are only visible to it. The status of jobs
+// is kept in memory only: jobs interrupted by the server shutting down are not resumed, but can
+// safely be submitted again.
+type DeleteJobs struct {
+	batchSize  uint64
+	batchDelay time.Duration
+
+	lock    sync.RWMutex
+	ctx     context.Context
+	stopped bool
+	jobs    map[string]*deleteJob
+	wg      sync.WaitGroup
+}
+
+// NewDeleteJobs creates a new runner of delete jobs, deleting at most batchSize relationships per
+// transaction and waiting batchDelay between transactions.
+func NewDeleteJobs(batchSize uint64, batchDelay time.Duration) *DeleteJobs {
+	return &DeleteJobs{
+		batchSize:  batchSize,
+		batchDelay: batchDelay,
+		jobs:       make(map[string]*deleteJob),
+	}
+}
+
+// Start accepts jobs until the context is canceled, after which running jobs are canceled and
+// waited for.
+func (dj *DeleteJobs) Start(ctx context.Context) error {
+	dj.lock.Lock()
+	dj.ctx = ctx
+	dj.lock.Unlock()
+
+	log.Ctx(ctx).Info().
+		Uint64("batchSize", dj.batchSize).
+		Dur("batchDelay", dj.batchDelay).
+		Msg("delete job worker started")
+
+	<-ctx.Done()
+
+	dj.lock.Lock()
+	dj.stopped = true
+	dj.lock.Unlock()
+	dj.wg.Wait()
+
+	log.Ctx(ctx).Info().
+		Msg("shut down delete job worker")
+	return ctx.Err()
+}
+
+// Submit submits a job of the owner deleting all relationships matching any of the filters,
+// returning its initial status.
+func (dj *DeleteJobs) Submit(ds datastore.Datastore, filters []datastore.RelationshipsFilter, owner string) (DeleteJobStatus, error) {
+	dj.lock.Lock()
+	defer dj.lock.Unlock()
+
+	if dj.ctx == nil || dj.stopped {
+		return DeleteJobStatus{}, ErrDeleteJobsNotRunning
+	}
+
+	dj.pruneLocked()
+
+	ctx, cancel := context.WithCancel(dj.ctx)
+	job := &deleteJob{
+		status: DeleteJobStatus{
+			ID:          uuid.NewString(),
+			State:       DeleteJobRunning,
+			SubmittedAt: time.Now(),
+		},
+		owner:   owner,
+		ds:      ds,
+		filters: filters,
+		cancel:  cancel,
+	}
+	dj.jobs[job.status.ID] = job
+
+	dj.wg.Add(1)
+	go dj.run(ctx, job)
+
+	return job.status, nil
+}
+
+// Status returns the status of the job of the owner with the given ID, if known.
+func (dj *DeleteJobs) Status(id, owner string) (DeleteJobStatus, bool) {
+	dj.lock.RLock()
+	defer dj.lock.RUnlock()
+
+	job, ok := dj.jobs[id]
+	if !ok || job.owner != owner {
+		return DeleteJobStatus{}, false
+	}
+	return job.status, true
+}
+
+// Cancel cancels the job of the owner with the given ID, if known and still running, returning
+// its status. Relationships already deleted by the job stay deleted.
+func (dj *DeleteJobs) Cancel(id, owner string) (DeleteJobStatus, bool) {
+	dj.lock.Lock()
+	defer dj.lock.Unlock()
+
+	job, ok := dj.jobs[id]
+	if !ok || job.owner != owner {
+		return DeleteJobStatus{}, false
+	}
+
+	if job.status.State == DeleteJobRunning {
+		job.cancel()
+		dj.finishLocked(job, DeleteJobCanceled, nil)
+	}
+	return job.status, true
+}
+
+func (dj *DeleteJobs) run(ctx context.Context, job *deleteJob) {
+	defer dj.wg.Done()
+	defer job.cancel()
+
+	log.Ctx(ctx).Info().
+		Str("job", job.status.ID).
+		Msg("started delete job")
+
+	for _, filter := range job.filters {
+		for {
+			deleted, err := deleteRelationshipsBatch(ctx, job.ds, filter, nil, dj.batchSize)
+			if ctx.Err() != nil {
+				err = ctx.Err()
+			}
+			if err != nil {
+				dj.finish(job, err)
+				return
+			}
+
+			if deleted == 0 {
+				break
+			}
+
+			dj.lock.Lock()
+			job.status.RelationshipsDeleted += deleted
+			dj.lock.Unlock()
+			deleteJobRelationshipsCounter.Add(float64(deleted))
+
+			select {
+			case <-ctx.Done():
+				dj.finish(job, ctx.Err())
+				return
+			case <-time.After(dj.batchDelay):
+			}
+		}
+	}
+
+	dj.finish(job, nil)
+}
+
+func (dj *DeleteJobs) finish(job *deleteJob, err error) {
+	dj.lock.Lock()
+	defer dj.lock.Unlock()
+
+	// A job canceled by Cancel has already finished.
+	if job.status.State != DeleteJobRunning {
+		return
+	}
+
+	switch {
+	case err == nil:
+		dj.finishLocked(job, DeleteJobCompleted, nil)
+	case errors.Is(err, context.Canceled):
+		dj.finishLocked(job, DeleteJobCanceled, nil)
+	default:
+		dj.finishLocked(job, DeleteJobFailed, err)
+	}
+}
+
+func (dj *DeleteJobs) finishLocked(job *deleteJob, state DeleteJobState, err error) {
+	job.status.State = state
+	job.status.FinishedAt = time.Now()
+	if err != nil {
+		job.status.Error = err.Error()
+	}
+
+	event := log.Ctx(dj.ctx).Info()
+	if err != nil {
+		event = log.Ctx(dj.ctx).Warn().Err(err)
+	}
+	event.
+		Str("job", job.status.ID).
+		Str("state", string(state)).
+		Uint64("relationshipsDeleted", job.status.RelationshipsDeleted).
+		Msgf("delete job %s", state)
+}
+
+// pruneLocked forgets the jobs which finished longer ago than the retention period.
+func (dj *DeleteJobs) pruneLocked() {
+	for id, job := range dj.jobs {
+		if job.status.State != DeleteJobRunning && time.Since(job.status.FinishedAt) > deleteJobRetention {
+			delete(dj.jobs, id)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestDeleteJobs(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition document {
+			relation viewer: user
+			relation editor: user
+		}
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:tom"),
+		tuple.MustParse("document:first#viewer@user:fred"),
+		tuple.MustParse("document:second#viewer@user:tom"),
+		tuple.MustParse("document:second#editor@user:tom"),
+		tuple.MustParse("document:third#viewer@user:sarah"),
+	}, require)
+
+	jobs := NewDeleteJobs(2, time.Millisecond)
+	_, err = jobs.Submit(ds, nil, "key:owner")
+	require.ErrorIs(err, ErrDeleteJobsNotRunning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- jobs.Start(ctx)
+	}()
+
+	var submitted DeleteJobStatus
+	require.Eventually(func() bool {
+		submitted, err = jobs.Submit(ds, []datastore.RelationshipsFilter{{ResourceType: "document", OptionalResourceRelation: "viewer"}}, "key:owner")
+		return err == nil
+	}, time.Second, time.Millisecond)
+	require.Equal(DeleteJobRunning, submitted.State)
+
+	require.Eventually(func() bool {
+		status, ok := jobs.Status(submitted.ID, "key:owner")
+		return ok && status.State == DeleteJobCompleted
+	}, 5*time.Second, time.Millisecond)
+
+	status, _ := jobs.Status(submitted.ID, "key:owner")
+	require.Equal(uint64(4), status.RelationshipsDeleted)
+	require.Empty(status.Error)
+
+	headRevision, err := ds.HeadRevision(context.Background())
+	require.NoError(err)
+	it, err := ds.SnapshotReader(headRevision).QueryRelationships(context.Background(), datastore.RelationshipsFilter{ResourceType: "document"})
+	require.NoError(err)
+	defer it.Close()
+
+	var remaining []string
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		remaining = append(remaining, tuple.String(tpl))
+	}
+	require.NoError(it.Err())
+	require.Equal([]string{"document:second#editor@user:tom"}, remaining)
+
+	_, ok := jobs.Status("unknown", "key:owner")
+	require.False(ok)
+
+	// The jobs of other callers are not found.
+	_, ok = jobs.Status(submitted.ID, "key:other")
+	require.False(ok)
+
+	// A canceled job stops deleting, keeping its status.
+	slowJobs := NewDeleteJobs(1, time.Hour)
+	go func() {
+		_ = slowJobs.Start(ctx)
+	}()
+
+	var slow DeleteJobStatus
+	require.Eventually(func() bool {
+		slow, err = slowJobs.Submit(ds, []datastore.RelationshipsFilter{{ResourceType: "document"}}, "key:owner")
+		return err == nil
+	}, time.Second, time.Millisecond)
+
+	require.Eventually(func() bool {
+		status, _ := slowJobs.Status(slow.ID, "key:owner")
+		return status.RelationshipsDeleted == 1
+	}, 5*time.Second, time.Millisecond)
+
+	_, ok = slowJobs.Cancel(slow.ID, "key:other")
+	require.False(ok)
+
+	canceled, ok := slowJobs.Cancel(slow.ID, "key:owner")
+	require.True(ok)
+	require.Equal(DeleteJobCanceled, canceled.State)
+	require.Equal(uint64(1), canceled.RelationshipsDeleted)
+
+	cancel()
+	require.ErrorIs(<-done, context.Canceled)
+}
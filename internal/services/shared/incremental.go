@@ -0,0 +1,170 @@
+package shared
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"sync"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+)
+
+// IncrementalSchemaValidator validates schemas, only revalidating the object definitions which
+// changed, or which (transitively) depend on a definition which changed, since the last schema
+// it successfully validated. All other object definitions reuse the annotations computed when
+// they were last validated. The first schema validated is always fully validated.
+type IncrementalSchemaValidator struct {
+	lock      sync.RWMutex
+	validated map[string]validatedObjectDef
+}
+
+// validatedObjectDef is an object definition which was validated and annotated, along with the
+// fingerprint of it and its dependencies at the time.
+type validatedObjectDef struct {
+	fingerprint string
+	annotated   *core.NamespaceDefinition
+}
+
+// NewIncrementalSchemaValidator creates a new, empty, incremental schema validator.
+func NewIncrementalSchemaValidator() *IncrementalSchemaValidator {
+	return &IncrementalSchemaValidator{validated: map[string]validatedObjectDef{}}
+}
+
+// ValidateSchemaChanges validates the schema found in the compiled schema, as per
+// ValidateSchemaChangesWithSoftDelete, revalidating only the object definitions affected by
+// changes since the last schema validated.
+func (v *IncrementalSchemaValidator) ValidateSchemaChanges(ctx context.Context, compiled *compiler.CompiledSchema, additiveOnly bool, softDelete bool) (*ValidatedSchemaChanges, error) {
+	fingerprints := objectDefFingerprints(compiled)
+
+	v.lock.RLock()
+	previous := v.validated
+	v.lock.RUnlock()
+
+	unchanged := make(map[string]*core.NamespaceDefinition, len(compiled.ObjectDefinitions))
+	for _, nsdef := range compiled.ObjectDefinitions {
+		if existing, ok := previous[nsdef.Name]; ok && existing.fingerprint == fingerprints[nsdef.Name] {
+			unchanged[nsdef.Name] = existing.annotated
+		}
+	}
+
+	validated, err := validateSchemaChanges(ctx, compiled, additiveOnly, softDelete, unchanged)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Ctx(ctx).Debug().
+		Int("objectDefinitions", len(compiled.ObjectDefinitions)).
+		Int("revalidatedObjectDefinitions", len(compiled.ObjectDefinitions)-len(unchanged)).
+		Msg("incrementally validated schema")
+
+	current := make(map[string]validatedObjectDef, len(compiled.ObjectDefinitions))
+	for _, nsdef := range compiled.ObjectDefinitions {
+		current[nsdef.Name] = validatedObjectDef{
+			fingerprint: fingerprints[nsdef.Name],
+			annotated:   nsdef.CloneVT(),
+		}
+	}
+
+	v.lock.Lock()
+	v.validated = current
+	v.lock.Unlock()
+
+	return validated, nil
+}
+
+// copyAnnotations copies the annotations computed for the relations of a previously validated
+// object definition onto the equivalent relations of the given object definition *in place*.
+func copyAnnotations(annotated *core.NamespaceDefinition, nsdef *core.NamespaceDefinition) {
+	annotatedRelations := make(map[string]*core.Relation, len(annotated.Relation))
+	for _, relation := range annotated.Relation {
+		annotatedRelations[relation.Name] = relation
+	}
+
+	for _, relation := range nsdef.Relation {
+		if annotatedRelation, ok := annotatedRelations[relation.Name]; ok {
+			relation.AliasingRelation = annotatedRelation.AliasingRelation
+			relation.CanonicalCacheKey = annotatedRelation.CanonicalCacheKey
+		}
+	}
+}
+
+// objectDefFingerprints returns, for each object definition in the compiled schema, a fingerprint
+// of the definition and of every object and caveat definition on which it transitively depends,
+// ignoring source positions. Definitions referenced but not found in the schema are included in
+// the fingerprint as missing, so that adding them also changes it.
+func objectDefFingerprints(compiled *compiler.CompiledSchema) map[string]string {
+	sources := make(map[string]string, len(compiled.ObjectDefinitions)+len(compiled.CaveatDefinitions))
+	dependencies := make(map[string][]string, len(compiled.ObjectDefinitions))
+
+	for _, caveatDef := range compiled.CaveatDefinitions {
+		source, _ := generator.GenerateCaveatSource(caveatDef)
+		sources[caveatKey(caveatDef.Name)] = source
+	}
+
+	for _, nsdef := range compiled.ObjectDefinitions {
+		source, _ := generator.GenerateSource(nsdef)
+		sources[objectDefKey(nsdef.Name)] = source
+
+		var deps []string
+		for _, relation := range nsdef.Relation {
+			for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+				deps = append(deps, objectDefKey(allowed.Namespace))
+				if allowed.RequiredCaveat != nil {
+					deps = append(deps, caveatKey(allowed.RequiredCaveat.CaveatName))
+				}
+			}
+		}
+		dependencies[objectDefKey(nsdef.Name)] = deps
+	}
+
+	fingerprints := make(map[string]string, len(compiled.ObjectDefinitions))
+	for _, nsdef := range compiled.ObjectDefinitions {
+		// Collect the transitive dependencies of the definition, including itself.
+		reachable := map[string]struct{}{}
+		toVisit := []string{objectDefKey(nsdef.Name)}
+		for len(toVisit) > 0 {
+			key := toVisit[len(toVisit)-1]
+			toVisit = toVisit[:len(toVisit)-1]
+
+			if _, ok := reachable[key]; ok {
+				continue
+			}
+			reachable[key] = struct{}{}
+			toVisit = append(toVisit, dependencies[key]...)
+		}
+
+		keys := make([]string, 0, len(reachable))
+		for key := range reachable {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		hasher := sha256.New()
+		for _, key := range keys {
+			source, ok := sources[key]
+			if !ok {
+				source = "<missing>"
+			}
+
+			hasher.Write([]byte(key))
+			hasher.Write([]byte{0})
+			hasher.Write([]byte(source))
+			hasher.Write([]byte{0})
+		}
+		fingerprints[nsdef.Name] = hex.EncodeToString(hasher.Sum(nil))
+	}
+
+	return fingerprints
+}
+
+func objectDefKey(name string) string {
+	return "definition " + name
+}
+
+func caveatKey(name string) string {
+	return "caveat " + name
+}
@@ -0,0 +1,268 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+func compileSchema(require *require.Assertions, schema string) *compiler.CompiledSchema {
+	emptyDefaultPrefix := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: schema,
+	}, &emptyDefaultPrefix)
+	require.NoError(err)
+	return compiled
+}
+
+func TestObjectDefFingerprints(t *testing.T) {
+	require := require.New(t)
+
+	original := objectDefFingerprints(compileSchema(require, `
+		definition user {}
+
+		definition group {
+			relation member: user | group#member
+		}
+
+		definition document {
+			relation viewer: user | group#member
+			relation editor: user with onlyweekdays
+			permission view = viewer + editor
+		}
+
+		caveat onlyweekdays(day string) {
+			day != "saturday"
+		}
+	`))
+
+	// Moving definitions around does not change their fingerprints.
+	moved := objectDefFingerprints(compileSchema(require, `
+		caveat onlyweekdays(day string) {
+			day != "saturday"
+		}
+
+		definition document {
+			relation viewer: user | group#member
+			relation editor: user with onlyweekdays
+			permission view = viewer + editor
+		}
+
+		definition group {
+			relation member: user | group#member
+		}
+
+		definition user {}
+	`))
+	require.Equal(original, moved)
+
+	// Changing a definition changes its fingerprint and that of all definitions depending on it.
+	changed := objectDefFingerprints(compileSchema(require, `
+		definition user {
+			relation self: user
+		}
+
+		definition group {
+			relation member: user | group#member
+		}
+
+		definition document {
+			relation viewer: user | group#member
+			relation editor: user with onlyweekdays
+			permission view = viewer + editor
+		}
+
+		caveat onlyweekdays(day string) {
+			day != "saturday"
+		}
+	`))
+	require.NotEqual(original["user"], changed["user"])
+	require.NotEqual(original["group"], changed["group"])
+	require.NotEqual(original["document"], changed["document"])
+
+	// Changing a caveat changes the fingerprints of the definitions using it.
+	changedCaveat := objectDefFingerprints(compileSchema(require, `
+		definition user {}
+
+		definition group {
+			relation member: user | group#member
+		}
+
+		definition document {
+			relation viewer: user | group#member
+			relation editor: user with onlyweekdays
+			permission view = viewer + editor
+		}
+
+		caveat onlyweekdays(day string) {
+			day != "sunday"
+		}
+	`))
+	require.Equal(original["user"], changedCaveat["user"])
+	require.Equal(original["group"], changedCaveat["group"])
+	require.NotEqual(original["document"], changedCaveat["document"])
+}
+
+func TestIncrementalSchemaValidator(t *testing.T) {
+	tcs := []struct {
+		name          string
+		schemas       []string
+		expectedError string
+	}{
+		{
+			"unchanged schema",
+			[]string{
+				`definition user {}
+
+				definition document {
+					relation viewer: user
+					permission view = viewer
+				}`,
+				`definition user {}
+
+				definition document {
+					relation viewer: user
+					permission view = viewer
+				}`,
+			},
+			"",
+		},
+		{
+			"changed definition",
+			[]string{
+				`definition user {}
+
+				definition document {
+					relation viewer: user
+					permission view = viewer
+				}`,
+				`definition user {}
+
+				definition document {
+					relation viewer: user
+					relation editor: user
+					permission view = viewer + editor
+				}`,
+			},
+			"",
+		},
+		{
+			"invalid changed definition",
+			[]string{
+				`definition user {}
+
+				definition document {
+					relation viewer: user
+					permission view = viewer
+				}`,
+				`definition user {}
+
+				definition document {
+					relation viewer: user
+					permission view = viewer + editor
+				}`,
+			},
+			"relation/permission `editor` not found under definition `document`",
+		},
+		{
+			"unchanged definition invalidated by a changed dependency",
+			[]string{
+				`definition user {}
+
+				definition group {
+					relation member: user
+				}
+
+				definition document {
+					relation viewer: user | group#member
+				}`,
+				`definition user {}
+
+				definition group {
+					relation participant: user
+				}
+
+				definition document {
+					relation viewer: user | group#member
+				}`,
+			},
+			"relation/permission `member` not found under definition `group`",
+		},
+		{
+			"unchanged definition invalidated by a wildcard added to a dependency",
+			[]string{
+				`definition user {}
+
+				definition group {
+					relation member: user | team#member
+				}
+
+				definition team {
+					relation member: user
+				}
+
+				definition document {
+					relation viewer: group#member
+					relation parent: group
+					permission view = viewer + parent->member
+				}`,
+				`definition user {}
+
+				definition group {
+					relation member: user | team#member
+				}
+
+				definition team {
+					relation member: user:*
+				}
+
+				definition document {
+					relation viewer: group#member
+					relation parent: group
+					permission view = viewer + parent->member
+				}`,
+			},
+			"wildcard relations cannot be transitively included",
+		},
+	}
+
+	for _, tc := range tcs {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			validator := NewIncrementalSchemaValidator()
+			var err error
+			for index, schema := range tc.schemas {
+				compiled := compileSchema(require, schema)
+				_, err = validator.ValidateSchemaChanges(ctx, compiled, false, false)
+				if index < len(tc.schemas)-1 {
+					require.NoError(err)
+					continue
+				}
+
+				// Ensure the result is the same as that of a full validation.
+				fullyCompiled := compileSchema(require, schema)
+				_, fullErr := ValidateSchemaChanges(ctx, fullyCompiled, false)
+				if tc.expectedError != "" {
+					require.ErrorContains(err, tc.expectedError)
+					require.ErrorContains(fullErr, tc.expectedError)
+					return
+				}
+
+				require.NoError(err)
+				require.NoError(fullErr)
+				require.Len(compiled.ObjectDefinitions, len(fullyCompiled.ObjectDefinitions))
+				for defIndex, nsdef := range compiled.ObjectDefinitions {
+					require.True(nsdef.EqualVT(fullyCompiled.ObjectDefinitions[defIndex]), "mismatch in annotations for %s", nsdef.Name)
+				}
+			}
+		})
+	}
+}
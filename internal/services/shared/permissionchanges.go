@@ -0,0 +1,179 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+// PermissionChangeWatch identifies the subjects of a type whose permission on the resources of a
+// type is watched for changes.
+type PermissionChangeWatch struct {
+	Set         PermissionSet
+	SubjectType string
+}
+
+// ParsePermissionChangeWatch parses a permission change watch of the form
+// `resourcetype#permission@subjecttype`.
+func ParsePermissionChangeWatch(value string) (PermissionChangeWatch, error) {
+	set, subjectType, ok := strings.Cut(value, "@")
+	if !ok || subjectType == "" {
+		return PermissionChangeWatch{}, fmt.Errorf("invalid permission change watch %q, expected `resourcetype#permission@subjecttype`", value)
+	}
+
+	parsed, err := ParsePermissionSet(set)
+	if err != nil {
+		return PermissionChangeWatch{}, fmt.Errorf("invalid permission change watch %q, expected `resourcetype#permission@subjecttype`", value)
+	}
+	return PermissionChangeWatch{Set: parsed, SubjectType: subjectType}, nil
+}
+
+func (w PermissionChangeWatch) String() string {
+	return w.Set.String() + "@" + w.SubjectType
+}
+
+// PermissionChangeWatcher computes the changes to the permissions of subjects caused by the
+// changes found in the datastore's Watch stream. Unlike a PermissionSetMaterializer, it keeps no
+// permissions in memory: for each revision, the permissions of the subjects on the resources
+// reachable from the changed relationships are computed before and after the revision, and
+// compared.
+type PermissionChangeWatcher struct {
+	permissionSetComputer
+	ds datastore.Datastore
+}
+
+// NewPermissionChangeWatcher creates a new watcher of permission changes, whose permissions are
+// computed with the dispatcher.
+func NewPermissionChangeWatcher(ds datastore.Datastore, dispatcher dispatch.Dispatcher, maxDepth uint32) *PermissionChangeWatcher {
+	return &PermissionChangeWatcher{
+		permissionSetComputer: permissionSetComputer{dispatcher: dispatcher, maxDepth: maxDepth},
+		ds:                    ds,
+	}
+}
+
+// Watch follows the changes of the datastore made after the given revision and calls send with
+// the changes to the watched permissions at each revision at which any changed, until the context
+// is canceled or an error occurs. The changes of a permission change watch are reported with its
+// permission set, and only for subjects of its subject type.
+func (w *PermissionChangeWatcher) Watch(ctx context.Context, afterRevision datastore.Revision, watches []PermissionChangeWatch, send func(PermissionSetChanges) error) error {
+	ctx = datastoremw.ContextWithDatastore(ctx, w.ds)
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	previous := afterRevision
+	changesChan, errChan := w.ds.Watch(watchCtx, afterRevision, datastore.WatchOptions{
+		Content: datastore.WatchRelationships | datastore.WatchSchema,
+	})
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err := <-errChan:
+			return err
+
+		case revisionChanges, ok := <-changesChan:
+			if !ok {
+				return errors.New("watch stream closed")
+			}
+
+			if len(revisionChanges.Changes) == 0 && len(revisionChanges.SchemaChanges) == 0 {
+				continue
+			}
+
+			// A change to the schema may change any permission, and so all resources are compared.
+			var changes []*core.RelationTupleUpdate
+			if len(revisionChanges.SchemaChanges) == 0 {
+				changes = revisionChanges.Changes
+			}
+
+			computed, err := w.changesAt(ctx, previous, revisionChanges.Revision, watches, changes)
+			if err != nil {
+				return err
+			}
+			previous = revisionChanges.Revision
+
+			if len(computed) == 0 {
+				continue
+			}
+
+			sortPermissionSetChanges(computed)
+			if err := send(PermissionSetChanges{Revision: revisionChanges.Revision, Changes: computed}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// changesAt returns the changes to the watched permissions between the previous revision and the
+// given revision, for the resources affected by the given changes or, if there are none, for all
+// resources.
+func (w *PermissionChangeWatcher) changesAt(ctx context.Context, previous, revision datastore.Revision, watches []PermissionChangeWatch, changes []*core.RelationTupleUpdate) ([]PermissionSetChange, error) {
+	previousReader := w.ds.SnapshotReader(previous)
+	previousDefs, err := namespace.ListLiveNamespaces(ctx, previousReader)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := w.ds.SnapshotReader(revision)
+	nsDefs, err := namespace.ListLiveNamespaces(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	var computed []PermissionSetChange
+	for _, watch := range watches {
+		// Resources are affected by the changes through the relationships of either revision, so
+		// that the permissions lost through removed relationships are found.
+		affected := util.NewSet[string]()
+		for _, at := range []struct {
+			reader   datastore.Reader
+			revision datastore.Revision
+			nsDefs   []*core.NamespaceDefinition
+		}{{previousReader, previous, previousDefs}, {reader, revision, nsDefs}} {
+			var resourceIDs []string
+			if changes == nil {
+				resourceIDs, err = allResourceIDs(ctx, at.reader, watch.Set.ResourceType)
+			} else {
+				resourceIDs, err = w.affectedResourceIDs(ctx, at.revision, at.nsDefs, watch.Set, changes)
+			}
+			if err != nil {
+				return nil, err
+			}
+			affected.Extend(resourceIDs)
+		}
+
+		resourceIDs := affected.AsSlice()
+		before, err := w.lookupMembers(ctx, previous, previousDefs, watch.Set, watch.SubjectType, resourceIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		after, err := w.lookupMembers(ctx, revision, nsDefs, watch.Set, watch.SubjectType, resourceIDs)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, resourceID := range resourceIDs {
+			for member := range before[resourceID] {
+				if _, ok := after[resourceID][member]; !ok {
+					computed = append(computed, PermissionSetChange{Set: watch.Set, Member: member, Granted: false})
+				}
+			}
+			for member := range after[resourceID] {
+				if _, ok := before[resourceID][member]; !ok {
+					computed = append(computed, PermissionSetChange{Set: watch.Set, Member: member, Granted: true})
+				}
+			}
+		}
+	}
+	return computed, nil
+}
@@ -0,0 +1,117 @@
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestParsePermissionChangeWatch(t *testing.T) {
+	testCases := []struct {
+		value         string
+		expected      PermissionChangeWatch
+		expectedError bool
+	}{
+		{"document#view@user", PermissionChangeWatch{Set: PermissionSet{ResourceType: "document", Permission: "view"}, SubjectType: "user"}, false},
+		{"document#view", PermissionChangeWatch{}, true},
+		{"document#view@", PermissionChangeWatch{}, true},
+		{"document@user", PermissionChangeWatch{}, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			require := require.New(t)
+			watch, err := ParsePermissionChangeWatch(tc.value)
+			if tc.expectedError {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(tc.expected, watch)
+			require.Equal(tc.value, watch.String())
+		})
+	}
+}
+
+func TestPermissionChangeWatcher(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition team {
+			relation member: user
+		}
+
+		definition document {
+			relation owner: user
+			relation viewer: user | team | team#member
+			permission view = owner + viewer
+		}
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:first#owner@user:tom"),
+		tuple.MustParse("document:first#viewer@team:eng#member"),
+		tuple.MustParse("document:second#viewer@user:fred"),
+		tuple.MustParse("team:eng#member@user:sarah"),
+	}, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	received := make(chan PermissionSetChanges, 10)
+	done := make(chan error, 1)
+	view := PermissionSet{ResourceType: "document", Permission: "view"}
+	watcher := NewPermissionChangeWatcher(ds, graph.NewLocalOnlyDispatcher(10), 50)
+	go func() {
+		done <- watcher.Watch(ctx, revision, []PermissionChangeWatch{{Set: view, SubjectType: "user"}}, func(changes PermissionSetChanges) error {
+			received <- changes
+			return nil
+		})
+	}()
+
+	requireChanges := func(expected []PermissionSetChange) {
+		select {
+		case changes := <-received:
+			require.Equal(expected, changes.Changes)
+		case <-time.After(5 * time.Second):
+			require.Fail("timed out waiting for permission changes")
+		}
+	}
+
+	// Permissions lost through removed relationships are found, as are those gained.
+	writeRelationships(ctx, require, ds,
+		tuple.Delete(tuple.MustParse("document:first#viewer@team:eng#member")),
+		tuple.Touch(tuple.MustParse("team:eng#member@user:fred")),
+	)
+	requireChanges([]PermissionSetChange{
+		{Set: view, Member: PermissionSetMember{ResourceID: "first", SubjectType: "user", SubjectID: "sarah"}, Granted: false},
+	})
+
+	// Only subjects of the watched type are reported, and changes which do not change permissions
+	// are not.
+	writeRelationships(ctx, require, ds,
+		tuple.Touch(tuple.MustParse("document:second#viewer@team:eng")),
+		tuple.Touch(tuple.MustParse("document:second#owner@user:fred")),
+	)
+	writeRelationships(ctx, require, ds,
+		tuple.Touch(tuple.MustParse("document:first#viewer@team:eng#member")),
+	)
+	requireChanges([]PermissionSetChange{
+		{Set: view, Member: PermissionSetMember{ResourceID: "first", SubjectType: "user", SubjectID: "fred"}, Granted: true},
+		{Set: view, Member: PermissionSetMember{ResourceID: "first", SubjectType: "user", SubjectID: "sarah"}, Granted: true},
+	})
+
+	cancel()
+	require.ErrorIs(<-done, context.Canceled)
+}
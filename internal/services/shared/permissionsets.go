@@ -0,0 +1,606 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	log "github.com/authzed/spicedb/internal/logging"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+var (
+	permissionSetMembersGauge = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: "spicedb",
+		Subsystem: "permission_sets",
+		Name:      "members",
+		Help:      "The number of members of materialized permission sets, by permission set.",
+	}, []string{"set"})
+
+	permissionSetChangesCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "spicedb",
+		Subsystem: "permission_sets",
+		Name:      "changes_total",
+		Help:      "The number of changes to the members of materialized permission sets, by permission set.",
+	}, []string{"set"})
+)
+
+const (
+	// permissionSetBatchSize is the number of resources whose members are looked up together.
+	permissionSetBatchSize = 100
+
+	// permissionSetSubscriberBuffer is the number of revisions of changes buffered for a
+	// subscriber, beyond which the subscriber is dropped for falling behind.
+	permissionSetSubscriberBuffer = 100
+
+	// permissionSetRetryInterval is the delay before materializing again after an error.
+	permissionSetRetryInterval = 5 * time.Second
+)
+
+var (
+	// ErrPermissionSetsNotReady is returned when subscribing to permission sets before they have
+	// been materialized for the first time.
+	ErrPermissionSetsNotReady = errors.New("permission sets have not been materialized yet")
+
+	// ErrPermissionSetSubscriberDropped is the error of a subscription dropped because its
+	// changes were not received quickly enough.
+	ErrPermissionSetSubscriberDropped = errors.New("permission set subscription dropped for falling behind")
+)
+
+// PermissionSet identifies the set of subjects with a permission on the resources of a type.
+type PermissionSet struct {
+	ResourceType string
+	Permission   string
+}
+
+// ParsePermissionSet parses a permission set of the form `resourcetype#permission`.
+func ParsePermissionSet(value string) (PermissionSet, error) {
+	resourceType, permission, ok := strings.Cut(value, "#")
+	if !ok || resourceType == "" || permission == "" {
+		return PermissionSet{}, fmt.Errorf("invalid permission set %q, expected `resourcetype#permission`", value)
+	}
+	return PermissionSet{ResourceType: resourceType, Permission: permission}, nil
+}
+
+func (ps PermissionSet) String() string {
+	return ps.ResourceType + "#" + ps.Permission
+}
+
+// PermissionSetMember is a subject with the permission of a set on one of its resources.
+// Subjects whose permission is conditional on the context of a caveat are not members. A
+// wildcard subject, of ID `*`, grants the permission to all subjects of its type, except any
+// excluded by the permission, which are not materialized.
+type PermissionSetMember struct {
+	ResourceID  string
+	SubjectType string
+	SubjectID   string
+}
+
+// PermissionSetChange is a change to the members of a permission set.
+type PermissionSetChange struct {
+	Set    PermissionSet
+	Member PermissionSetMember
+
+	// Granted is true if the member was added to the set, and false if it was removed.
+	Granted bool
+}
+
+// PermissionSetChanges are the changes made to the members of permission sets at a revision.
+type PermissionSetChanges struct {
+	Revision datastore.Revision
+	Changes  []PermissionSetChange
+}
+
+// members are the members of a permission set, by resource ID.
+type members map[string]map[PermissionSetMember]struct{}
+
+// PermissionSetMaterializer maintains the members of permission sets in memory, by following the
+// changes found in the datastore's Watch stream, and publishes the changes to their members to
+// subscribers.
+type PermissionSetMaterializer struct {
+	permissionSetComputer
+	ds   datastore.Datastore
+	sets []PermissionSet
+
+	lock        sync.Mutex
+	members     map[PermissionSet]members
+	revision    datastore.Revision
+	subscribers map[*PermissionSetSubscription]struct{}
+}
+
+// NewPermissionSetMaterializer creates a new materializer of the given permission sets, whose
+// members are looked up with the dispatcher.
+func NewPermissionSetMaterializer(ds datastore.Datastore, dispatcher dispatch.Dispatcher, maxDepth uint32, sets []PermissionSet) *PermissionSetMaterializer {
+	return &PermissionSetMaterializer{
+		permissionSetComputer: permissionSetComputer{dispatcher: dispatcher, maxDepth: maxDepth},
+		ds:                    ds,
+		sets:                  sets,
+		members:               map[PermissionSet]members{},
+		subscribers:           map[*PermissionSetSubscription]struct{}{},
+	}
+}
+
+// Sets returns the permission sets materialized.
+func (m *PermissionSetMaterializer) Sets() []PermissionSet {
+	return m.sets
+}
+
+// Start materializes the permission sets until the context is canceled. The permission sets are
+// fully materialized on start, whenever the schema changes and whenever the Watch stream must be
+// restarted. In between, only the resources affected by the changes found in the Watch stream are
+// materialized.
+func (m *PermissionSetMaterializer) Start(ctx context.Context) error {
+	log.Ctx(ctx).Info().
+		Stringer("sets", permissionSetNames(m.sets)).
+		Msg("permission set materialization worker started")
+
+	ctx = datastoremw.ContextWithDatastore(ctx, m.ds)
+	for {
+		err := m.follow(ctx)
+		if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			log.Ctx(ctx).Info().
+				Msg("shutting down permission set materialization worker")
+			m.closeSubscribers(ctx.Err())
+			return ctx.Err()
+		}
+
+		log.Ctx(ctx).Warn().Err(err).
+			Msg("error materializing permission sets; restarting")
+
+		select {
+		case <-ctx.Done():
+			m.closeSubscribers(ctx.Err())
+			return ctx.Err()
+		case <-time.After(permissionSetRetryInterval):
+		}
+	}
+}
+
+// follow fully materializes the permission sets and then follows the Watch stream, returning on
+// the first error.
+func (m *PermissionSetMaterializer) follow(ctx context.Context) error {
+	revision, err := m.ds.HeadRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := m.materialize(ctx, revision, nil); err != nil {
+		return err
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changesChan, errChan := m.ds.Watch(watchCtx, revision, datastore.WatchOptions{
+		Content: datastore.WatchRelationships | datastore.WatchSchema,
+	})
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err := <-errChan:
+			return err
+
+		case revisionChanges, ok := <-changesChan:
+			if !ok {
+				return errors.New("watch stream closed")
+			}
+
+			if len(revisionChanges.SchemaChanges) > 0 {
+				if err := m.materialize(ctx, revisionChanges.Revision, nil); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if len(revisionChanges.Changes) > 0 {
+				if err := m.materialize(ctx, revisionChanges.Revision, revisionChanges.Changes); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
+
+// materialize materializes the permission sets at the given revision, for the resources affected
+// by the given changes or, if there are none, for all resources, and publishes the changes to
+// their members.
+func (m *PermissionSetMaterializer) materialize(ctx context.Context, revision datastore.Revision, changes []*core.RelationTupleUpdate) error {
+	reader := m.ds.SnapshotReader(revision)
+	nsDefs, err := namespace.ListLiveNamespaces(ctx, reader)
+	if err != nil {
+		return err
+	}
+
+	computed := make(map[PermissionSet]members, len(m.sets))
+	affected := make(map[PermissionSet][]string, len(m.sets))
+	for _, set := range m.sets {
+		var resourceIDs []string
+		if changes == nil {
+			resourceIDs, err = allResourceIDs(ctx, reader, set.ResourceType)
+		} else {
+			resourceIDs, err = m.affectedResourceIDs(ctx, revision, nsDefs, set, changes)
+		}
+		if err != nil {
+			return err
+		}
+
+		computed[set], err = m.lookupMembers(ctx, revision, nsDefs, set, "", resourceIDs)
+		if err != nil {
+			return err
+		}
+		affected[set] = resourceIDs
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var setChanges []PermissionSetChange
+	for _, set := range m.sets {
+		current, ok := m.members[set]
+		if !ok {
+			current = members{}
+			m.members[set] = current
+		}
+
+		resourceIDs := affected[set]
+		if changes == nil {
+			// Resources no longer found have lost all their members.
+			resourceIDs = append(resourceIDs, current.resourceIDs()...)
+		}
+
+		for _, resourceID := range resourceIDs {
+			for member := range current[resourceID] {
+				if _, ok := computed[set][resourceID][member]; !ok {
+					setChanges = append(setChanges, PermissionSetChange{Set: set, Member: member, Granted: false})
+				}
+			}
+			for member := range computed[set][resourceID] {
+				if _, ok := current[resourceID][member]; !ok {
+					setChanges = append(setChanges, PermissionSetChange{Set: set, Member: member, Granted: true})
+				}
+			}
+
+			if len(computed[set][resourceID]) > 0 {
+				current[resourceID] = computed[set][resourceID]
+			} else {
+				delete(current, resourceID)
+			}
+		}
+
+		permissionSetMembersGauge.WithLabelValues(set.String()).Set(float64(current.count()))
+	}
+	m.revision = revision
+
+	if len(setChanges) == 0 {
+		return nil
+	}
+
+	sortPermissionSetChanges(setChanges)
+	for _, change := range setChanges {
+		permissionSetChangesCounter.WithLabelValues(change.Set.String()).Inc()
+	}
+	m.publish(PermissionSetChanges{Revision: revision, Changes: setChanges})
+	return nil
+}
+
+// permissionSetComputer computes the members of permission sets with a dispatcher.
+type permissionSetComputer struct {
+	dispatcher dispatch.Dispatcher
+	maxDepth   uint32
+}
+
+// affectedResourceIDs returns the IDs of the resources of the permission set whose members may
+// have changed with the given changes: those reachable from any relation or permission of the
+// changed resources.
+func (m permissionSetComputer) affectedResourceIDs(ctx context.Context, revision datastore.Revision, nsDefs []*core.NamespaceDefinition, set PermissionSet, changes []*core.RelationTupleUpdate) ([]string, error) {
+	changedIDs := map[string]*util.Set[string]{}
+	for _, change := range changes {
+		resource := change.Tuple.ResourceAndRelation
+		if _, ok := changedIDs[resource.Namespace]; !ok {
+			changedIDs[resource.Namespace] = util.NewSet[string]()
+		}
+		changedIDs[resource.Namespace].Add(resource.ObjectId)
+	}
+
+	affected := util.NewSet[string]()
+	if changed, ok := changedIDs[set.ResourceType]; ok {
+		affected.Extend(changed.AsSlice())
+	}
+
+	for _, nsDef := range nsDefs {
+		changed, ok := changedIDs[nsDef.Name]
+		if !ok {
+			continue
+		}
+
+		for _, relation := range nsDef.Relation {
+			stream := dispatch.NewCollectingDispatchStream[*dispatchv1.DispatchReachableResourcesResponse](ctx)
+			err := m.dispatcher.DispatchReachableResources(&dispatchv1.DispatchReachableResourcesRequest{
+				Metadata: &dispatchv1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: m.maxDepth,
+				},
+				ResourceRelation: &core.RelationReference{Namespace: set.ResourceType, Relation: set.Permission},
+				SubjectRelation:  &core.RelationReference{Namespace: nsDef.Name, Relation: relation.Name},
+				SubjectIds:       changed.AsSlice(),
+			}, stream)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, result := range stream.Results() {
+				for _, resource := range result.Resources {
+					affected.Add(resource.ResourceId)
+				}
+			}
+		}
+	}
+
+	return affected.AsSlice(), nil
+}
+
+// lookupMembers returns the members of the permission set on the given resources, limited to the
+// subjects of the given type, if any.
+func (m permissionSetComputer) lookupMembers(ctx context.Context, revision datastore.Revision, nsDefs []*core.NamespaceDefinition, set PermissionSet, subjectType string, resourceIDs []string) (members, error) {
+	found := members{}
+	if !hasRelation(nsDefs, set.ResourceType, set.Permission) {
+		// The permission is not, or no longer, in the schema, and therefore has no members.
+		return found, nil
+	}
+
+	for start := 0; start < len(resourceIDs); start += permissionSetBatchSize {
+		end := start + permissionSetBatchSize
+		if end > len(resourceIDs) {
+			end = len(resourceIDs)
+		}
+
+		for _, nsDef := range nsDefs {
+			if subjectType != "" && nsDef.Name != subjectType {
+				continue
+			}
+
+			stream := dispatch.NewCollectingDispatchStream[*dispatchv1.DispatchLookupSubjectsResponse](ctx)
+			err := m.dispatcher.DispatchLookupSubjects(&dispatchv1.DispatchLookupSubjectsRequest{
+				Metadata: &dispatchv1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: m.maxDepth,
+				},
+				ResourceRelation: &core.RelationReference{Namespace: set.ResourceType, Relation: set.Permission},
+				ResourceIds:      resourceIDs[start:end],
+				SubjectRelation:  &core.RelationReference{Namespace: nsDef.Name, Relation: tuple.Ellipsis},
+			}, stream)
+			if err != nil {
+				return nil, err
+			}
+
+			for _, result := range stream.Results() {
+				for resourceID, subjects := range result.FoundSubjectsByResourceId {
+					for _, subject := range subjects.FoundSubjects {
+						if subject.CaveatExpression != nil {
+							continue
+						}
+
+						if _, ok := found[resourceID]; !ok {
+							found[resourceID] = map[PermissionSetMember]struct{}{}
+						}
+						found[resourceID][PermissionSetMember{
+							ResourceID:  resourceID,
+							SubjectType: nsDef.Name,
+							SubjectID:   subject.SubjectId,
+						}] = struct{}{}
+					}
+				}
+			}
+		}
+	}
+	return found, nil
+}
+
+// Subscribe subscribes to the changes to the members of the given permission sets, returning the
+// subscription along with the current members of the sets.
+func (m *PermissionSetMaterializer) Subscribe(sets []PermissionSet) (*PermissionSetSubscription, error) {
+	for _, set := range sets {
+		if !m.materializes(set) {
+			return nil, fmt.Errorf("permission set `%s` is not materialized", set)
+		}
+	}
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if m.revision == nil {
+		return nil, ErrPermissionSetsNotReady
+	}
+
+	subscription := &PermissionSetSubscription{
+		Revision: m.revision,
+		sets:     map[PermissionSet]struct{}{},
+		changes:  make(chan PermissionSetChanges, permissionSetSubscriberBuffer),
+	}
+	for _, set := range sets {
+		subscription.sets[set] = struct{}{}
+		for _, resourceMembers := range m.members[set] {
+			for member := range resourceMembers {
+				subscription.Members = append(subscription.Members, PermissionSetChange{Set: set, Member: member, Granted: true})
+			}
+		}
+	}
+	sortPermissionSetChanges(subscription.Members)
+
+	m.subscribers[subscription] = struct{}{}
+	return subscription, nil
+}
+
+// Unsubscribe ends the subscription.
+func (m *PermissionSetMaterializer) Unsubscribe(subscription *PermissionSetSubscription) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if _, ok := m.subscribers[subscription]; ok {
+		delete(m.subscribers, subscription)
+		close(subscription.changes)
+	}
+}
+
+func (m *PermissionSetMaterializer) materializes(set PermissionSet) bool {
+	for _, materialized := range m.sets {
+		if materialized == set {
+			return true
+		}
+	}
+	return false
+}
+
+// publish sends the changes to the subscribers, dropping those whose buffer is full. Must be
+// called with the lock held.
+func (m *PermissionSetMaterializer) publish(changes PermissionSetChanges) {
+	for subscription := range m.subscribers {
+		var subscribed []PermissionSetChange
+		for _, change := range changes.Changes {
+			if _, ok := subscription.sets[change.Set]; ok {
+				subscribed = append(subscribed, change)
+			}
+		}
+		if len(subscribed) == 0 {
+			continue
+		}
+
+		select {
+		case subscription.changes <- PermissionSetChanges{Revision: changes.Revision, Changes: subscribed}:
+		default:
+			subscription.err = ErrPermissionSetSubscriberDropped
+			delete(m.subscribers, subscription)
+			close(subscription.changes)
+		}
+	}
+}
+
+func (m *PermissionSetMaterializer) closeSubscribers(err error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	for subscription := range m.subscribers {
+		subscription.err = err
+		delete(m.subscribers, subscription)
+		close(subscription.changes)
+	}
+}
+
+// PermissionSetSubscription is a subscription to the changes to the members of permission sets.
+type PermissionSetSubscription struct {
+	// Revision is the revision of the members of the sets when subscribed.
+	Revision datastore.Revision
+
+	// Members are the members of the sets when subscribed, as granted changes.
+	Members []PermissionSetChange
+
+	sets    map[PermissionSet]struct{}
+	changes chan PermissionSetChanges
+	err     error
+}
+
+// Changes returns the channel of the changes made after the subscription, which is closed when
+// the subscription ends.
+func (s *PermissionSetSubscription) Changes() <-chan PermissionSetChanges {
+	return s.changes
+}
+
+// Err returns the error which ended the subscription, if any, once the channel of changes is
+// closed.
+func (s *PermissionSetSubscription) Err() error {
+	return s.err
+}
+
+func (ms members) resourceIDs() []string {
+	resourceIDs := make([]string, 0, len(ms))
+	for resourceID := range ms {
+		resourceIDs = append(resourceIDs, resourceID)
+	}
+	return resourceIDs
+}
+
+func (ms members) count() int {
+	count := 0
+	for _, resourceMembers := range ms {
+		count += len(resourceMembers)
+	}
+	return count
+}
+
+// allResourceIDs returns the IDs of all the resources of the type with relationships.
+func allResourceIDs(ctx context.Context, reader datastore.Reader, resourceType string) ([]string, error) {
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: resourceType})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	resourceIDs := util.NewSet[string]()
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		resourceIDs.Add(tpl.ResourceAndRelation.ObjectId)
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+	return resourceIDs.AsSlice(), nil
+}
+
+func hasRelation(nsDefs []*core.NamespaceDefinition, nsName, relationName string) bool {
+	for _, nsDef := range nsDefs {
+		if nsDef.Name != nsName {
+			continue
+		}
+		for _, relation := range nsDef.Relation {
+			if relation.Name == relationName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func sortPermissionSetChanges(changes []PermissionSetChange) {
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.Set != b.Set {
+			return a.Set.String() < b.Set.String()
+		}
+		if a.Member.ResourceID != b.Member.ResourceID {
+			return a.Member.ResourceID < b.Member.ResourceID
+		}
+		if a.Member.SubjectType != b.Member.SubjectType {
+			return a.Member.SubjectType < b.Member.SubjectType
+		}
+		if a.Member.SubjectID != b.Member.SubjectID {
+			return a.Member.SubjectID < b.Member.SubjectID
+		}
+		return !a.Granted && b.Granted
+	})
+}
+
+// permissionSetNames are permission sets logged by name.
+type permissionSetNames []PermissionSet
+
+func (names permissionSetNames) String() string {
+	formatted := make([]string, 0, len(names))
+	for _, set := range names {
+		formatted = append(formatted, set.String())
+	}
+	return strings.Join(formatted, ",")
+}
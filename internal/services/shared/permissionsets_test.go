@@ -0,0 +1,135 @@
+package shared
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestParsePermissionSet(t *testing.T) {
+	testCases := []struct {
+		value         string
+		expected      PermissionSet
+		expectedError bool
+	}{
+		{"document#view", PermissionSet{ResourceType: "document", Permission: "view"}, false},
+		{"tenant/document#view", PermissionSet{ResourceType: "tenant/document", Permission: "view"}, false},
+		{"document", PermissionSet{}, true},
+		{"document#", PermissionSet{}, true},
+		{"#view", PermissionSet{}, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			require := require.New(t)
+			set, err := ParsePermissionSet(tc.value)
+			if tc.expectedError {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(tc.expected, set)
+			require.Equal(tc.value, set.String())
+		})
+	}
+}
+
+func TestPermissionSetMaterializer(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition team {
+			relation member: user
+		}
+
+		definition document {
+			relation owner: user
+			relation viewer: user | user:* | team#member
+			permission view = owner + viewer
+		}
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:first#owner@user:tom"),
+		tuple.MustParse("document:first#viewer@team:eng#member"),
+		tuple.MustParse("document:second#viewer@user:fred"),
+		tuple.MustParse("team:eng#member@user:sarah"),
+	}, require)
+
+	view := PermissionSet{ResourceType: "document", Permission: "view"}
+	materializer := NewPermissionSetMaterializer(ds, graph.NewLocalOnlyDispatcher(10), 50, []PermissionSet{view})
+
+	_, err = materializer.Subscribe([]PermissionSet{view})
+	require.ErrorIs(err, ErrPermissionSetsNotReady)
+
+	_, err = materializer.Subscribe([]PermissionSet{{ResourceType: "document", Permission: "edit"}})
+	require.Error(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- materializer.Start(ctx)
+	}()
+
+	var subscription *PermissionSetSubscription
+	require.Eventually(func() bool {
+		subscription, err = materializer.Subscribe([]PermissionSet{view})
+		return err == nil
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Equal([]PermissionSetChange{
+		{Set: view, Member: PermissionSetMember{ResourceID: "first", SubjectType: "user", SubjectID: "sarah"}, Granted: true},
+		{Set: view, Member: PermissionSetMember{ResourceID: "first", SubjectType: "user", SubjectID: "tom"}, Granted: true},
+		{Set: view, Member: PermissionSetMember{ResourceID: "second", SubjectType: "user", SubjectID: "fred"}, Granted: true},
+	}, subscription.Members)
+
+	// Changes to other types of resources update the members of the resources reached from them.
+	writeRelationships(ctx, require, ds,
+		tuple.Touch(tuple.MustParse("team:eng#member@user:fred")),
+		tuple.Delete(tuple.MustParse("team:eng#member@user:sarah")),
+	)
+	requirePermissionSetChanges(require, subscription, []PermissionSetChange{
+		{Set: view, Member: PermissionSetMember{ResourceID: "first", SubjectType: "user", SubjectID: "fred"}, Granted: true},
+		{Set: view, Member: PermissionSetMember{ResourceID: "first", SubjectType: "user", SubjectID: "sarah"}, Granted: false},
+	})
+
+	// A subject granted the permission through several relations keeps it until none remain.
+	writeRelationships(ctx, require, ds,
+		tuple.Touch(tuple.MustParse("document:second#owner@user:fred")),
+		tuple.Delete(tuple.MustParse("document:second#viewer@user:fred")),
+		tuple.Touch(tuple.MustParse("document:third#viewer@user:*")),
+	)
+	requirePermissionSetChanges(require, subscription, []PermissionSetChange{
+		{Set: view, Member: PermissionSetMember{ResourceID: "third", SubjectType: "user", SubjectID: "*"}, Granted: true},
+	})
+
+	materializer.Unsubscribe(subscription)
+	_, ok := <-subscription.Changes()
+	require.False(ok)
+
+	cancel()
+	require.ErrorIs(<-done, context.Canceled)
+}
+
+func requirePermissionSetChanges(require *require.Assertions, subscription *PermissionSetSubscription, expected []PermissionSetChange) {
+	select {
+	case changes, ok := <-subscription.Changes():
+		require.True(ok, "subscription ended: %v", subscription.Err())
+		require.Equal(expected, changes.Changes)
+	case <-time.After(5 * time.Second):
+		require.Fail("timed out waiting for permission set changes")
+	}
+}
@@ -0,0 +1,252 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+var purgedRelationshipsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "schema",
+	Name:      "purged_relationships_total",
+	Help:      "The number of relationships deleted by the purge of soft-deleted object definitions.",
+}, []string{"definition"})
+
+// PurgeProgress is the progress of the purge of a single soft-deleted object definition.
+type PurgeProgress struct {
+	// DefinitionName is the name of the soft-deleted object definition.
+	DefinitionName string
+
+	// RelationshipsPurged is the number of relationships deleted so far.
+	RelationshipsPurged uint64
+
+	// StartedAt is the time at which this purger started purging the definition.
+	StartedAt time.Time
+
+	// Completed indicates whether all relationships have been deleted and the definition
+	// itself removed from the datastore.
+	Completed bool
+}
+
+// TombstonePurger deletes the relationships of object definitions soft-deleted from the schema,
+// in batches, before removing the definitions themselves from the datastore.
+type TombstonePurger struct {
+	ds        datastore.Datastore
+	batchSize uint64
+
+	lock     sync.RWMutex
+	progress map[string]*PurgeProgress
+}
+
+// NewTombstonePurger creates a new purger, deleting at most batchSize relationships per
+// transaction.
+func NewTombstonePurger(ds datastore.Datastore, batchSize uint64) *TombstonePurger {
+	return &TombstonePurger{
+		ds:        ds,
+		batchSize: batchSize,
+		progress:  make(map[string]*PurgeProgress),
+	}
+}
+
+// Start loops until the context is canceled, purging soft-deleted object definitions on the
+// provided interval.
+func (p *TombstonePurger) Start(ctx context.Context, interval time.Duration) error {
+	log.Ctx(ctx).Info().
+		Dur("interval", interval).
+		Uint64("batchSize", p.batchSize).
+		Msg("soft-deleted definition purge worker started")
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Ctx(ctx).Info().
+				Msg("shutting down soft-deleted definition purge worker")
+			return ctx.Err()
+
+		case <-time.After(interval):
+			if err := p.PurgeOnce(ctx); err != nil && !errors.Is(err, context.Canceled) {
+				log.Ctx(ctx).Warn().Err(err).
+					Msg("error attempting to purge soft-deleted definitions")
+			}
+		}
+	}
+}
+
+// PurgeOnce fully purges all object definitions which are soft-deleted at the head revision.
+func (p *TombstonePurger) PurgeOnce(ctx context.Context) error {
+	headRevision, err := p.ds.HeadRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	nsDefs, err := p.ds.SnapshotReader(headRevision).ListNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, nsDef := range nsDefs {
+		if !nspkg.IsTombstoned(nsDef) {
+			continue
+		}
+
+		if err := p.purge(ctx, nsDef.Name); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Progress returns the progress of the purge of each soft-deleted object definition seen by
+// this purger, sorted by definition name.
+func (p *TombstonePurger) Progress() []PurgeProgress {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	progress := make([]PurgeProgress, 0, len(p.progress))
+	for _, defProgress := range p.progress {
+		progress = append(progress, *defProgress)
+	}
+
+	sort.Slice(progress, func(i, j int) bool {
+		return progress[i].DefinitionName < progress[j].DefinitionName
+	})
+	return progress
+}
+
+func (p *TombstonePurger) purge(ctx context.Context, nsName string) error {
+	p.lock.Lock()
+	if _, ok := p.progress[nsName]; !ok {
+		p.progress[nsName] = &PurgeProgress{DefinitionName: nsName, StartedAt: time.Now()}
+	}
+	p.lock.Unlock()
+
+	for {
+		purged, err := deleteRelationshipsBatch(
+			ctx,
+			p.ds,
+			datastore.RelationshipsFilter{ResourceType: nsName},
+			&datastore.SubjectsFilter{SubjectType: nsName},
+			p.batchSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		if purged == 0 {
+			break
+		}
+
+		p.lock.Lock()
+		p.progress[nsName].RelationshipsPurged += purged
+		total := p.progress[nsName].RelationshipsPurged
+		p.lock.Unlock()
+
+		purgedRelationshipsCounter.WithLabelValues(nsName).Add(float64(purged))
+		log.Ctx(ctx).Debug().
+			Str("definition", nsName).
+			Uint64("relationshipsPurged", total).
+			Msg("purged batch of relationships for soft-deleted definition")
+	}
+
+	// Delete the definition itself, so long as it is still tombstoned and nothing was written
+	// for it in the meantime.
+	_, err := p.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		nsDef, _, err := rwt.ReadNamespace(ctx, nsName)
+		if err != nil {
+			return err
+		}
+
+		if !nspkg.IsTombstoned(nsDef) {
+			return nil
+		}
+
+		hasRelationships, err := relationshipsExist(ctx, rwt, nsName)
+		if err != nil || hasRelationships {
+			return err
+		}
+
+		return rwt.DeleteNamespaces(ctx, nsName)
+	})
+	if err != nil && !errors.As(err, &datastore.ErrNamespaceNotFound{}) {
+		return err
+	}
+
+	p.lock.Lock()
+	p.progress[nsName].Completed = true
+	total := p.progress[nsName].RelationshipsPurged
+	p.lock.Unlock()
+
+	log.Ctx(ctx).Info().
+		Str("definition", nsName).
+		Uint64("relationshipsPurged", total).
+		Msg("completed purge of soft-deleted definition")
+	return nil
+}
+
+// deleteRelationshipsBatch deletes up to batchSize relationships matching either of the given
+// filters, returning the number deleted. If the subjects filter is nil, only relationships
+// matching the resource filter are deleted.
+func deleteRelationshipsBatch(
+	ctx context.Context,
+	ds datastore.Datastore,
+	resourceFilter datastore.RelationshipsFilter,
+	subjectsFilter *datastore.SubjectsFilter,
+	batchSize uint64,
+) (uint64, error) {
+	var deleted uint64
+	_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		deleted = 0
+
+		// Relationships matching both filters are found by both queries.
+		var updates []*core.RelationTupleUpdate
+		seen := util.NewSet[string]()
+		collect := func(it datastore.RelationshipIterator, err error) error {
+			if err != nil {
+				return err
+			}
+			defer it.Close()
+
+			for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+				if !seen.Add(tuple.String(tpl)) {
+					continue
+				}
+
+				updates = append(updates, tuple.Delete(tpl))
+			}
+			return it.Err()
+		}
+
+		if err := collect(rwt.QueryRelationships(ctx, resourceFilter, options.WithLimit(&batchSize))); err != nil {
+			return err
+		}
+
+		if remaining := batchSize - uint64(len(updates)); remaining > 0 && subjectsFilter != nil {
+			if err := collect(rwt.ReverseQueryRelationships(ctx, *subjectsFilter, options.WithReverseLimit(&remaining))); err != nil {
+				return err
+			}
+		}
+
+		if len(updates) == 0 {
+			return nil
+		}
+
+		deleted = uint64(len(updates))
+		return rwt.WriteRelationships(ctx, updates)
+	})
+	return deleted, err
+}
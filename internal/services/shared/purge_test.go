@@ -0,0 +1,82 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestTombstonePurger(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition document {
+			relation parent: document
+			relation viewer: user
+		}
+
+		definition folder {
+			relation item: document
+		}
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:tom"),
+		tuple.MustParse("document:first#viewer@user:fred"),
+		tuple.MustParse("document:second#viewer@user:tom"),
+		tuple.MustParse("document:second#parent@document:first"),
+		tuple.MustParse("folder:root#item@document:first"),
+		tuple.MustParse("folder:root#item@document:second"),
+	}, require)
+
+	// Tombstone document.
+	ctx := context.Background()
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		nsDef, _, err := rwt.ReadNamespace(ctx, "document")
+		require.NoError(err)
+
+		tombstoned, err := nspkg.MarkTombstoned(nsDef)
+		require.NoError(err)
+		return rwt.WriteNamespaces(ctx, tombstoned)
+	})
+	require.NoError(err)
+
+	purger := NewTombstonePurger(ds, 2)
+	require.Empty(purger.Progress())
+	require.NoError(purger.PurgeOnce(ctx))
+
+	progress := purger.Progress()
+	require.Len(progress, 1)
+	require.Equal("document", progress[0].DefinitionName)
+	require.Equal(uint64(6), progress[0].RelationshipsPurged)
+	require.True(progress[0].Completed)
+
+	// Ensure the definition and all relationships within or referencing it were removed.
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	reader := ds.SnapshotReader(headRevision)
+
+	_, _, err = reader.ReadNamespace(ctx, "document")
+	require.ErrorAs(err, &datastore.ErrNamespaceNotFound{})
+
+	found, err := relationshipsExist(ctx, reader, "document")
+	require.NoError(err)
+	require.False(found)
+
+	_, _, err = reader.ReadNamespace(ctx, "user")
+	require.NoError(err)
+
+	// Purging again is a no-op.
+	require.NoError(purger.PurgeOnce(ctx))
+	require.Equal(progress, purger.Progress())
+}
@@ -0,0 +1,188 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	grpcauth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
+	"github.com/rs/zerolog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RelationshipQuotas are limits on the number of relationships stored for the resources of each
+// object type, enforced on the relationships written.
+type RelationshipQuotas struct {
+	// ByNamespace is the maximum number of relationships of each object type.
+	ByNamespace map[string]uint64
+
+	// ByKey is the maximum number of relationships of any object type which may be reached by
+	// writes made with each preshared key, overriding ByNamespace.
+	ByKey map[string]uint64
+}
+
+// RelationshipQuotaUsage is the number of relationships of an object type with a quota.
+type RelationshipQuotaUsage struct {
+	Namespace string
+	Count     uint64
+	Quota     uint64
+}
+
+func (u RelationshipQuotaUsage) String() string {
+	return fmt.Sprintf("%s=%d/%d", u.Namespace, u.Count, u.Quota)
+}
+
+// IsEmpty returns true if no quota is configured.
+func (rq RelationshipQuotas) IsEmpty() bool {
+	return len(rq.ByNamespace) == 0 && len(rq.ByKey) == 0
+}
+
+// quotaFor returns the quota on the relationships of the object type for the caller, if any.
+func (rq RelationshipQuotas) quotaFor(ctx context.Context, namespace string) (uint64, bool) {
+	if len(rq.ByKey) > 0 {
+		if key, err := grpcauth.AuthFromMD(ctx, "bearer"); err == nil {
+			if quota, ok := rq.ByKey[key]; ok {
+				return quota, true
+			}
+		}
+	}
+
+	quota, ok := rq.ByNamespace[namespace]
+	return quota, ok
+}
+
+// Check returns an error if applying the updates would bring the number of relationships of any
+// object type over its quota, and otherwise returns the usage of the quotas of the object types
+// updated once the updates are applied. The updates must not have been written yet.
+//
+// Relationships are counted by reading all those of the object types with a quota, whose number
+// is bounded by the quota.
+func (rq RelationshipQuotas) Check(ctx context.Context, reader datastore.Reader, updates []*core.RelationTupleUpdate) ([]RelationshipQuotaUsage, error) {
+	if rq.IsEmpty() {
+		return nil, nil
+	}
+
+	byNamespace := map[string][]*core.RelationTupleUpdate{}
+	for _, update := range updates {
+		namespace := update.Tuple.ResourceAndRelation.Namespace
+		byNamespace[namespace] = append(byNamespace[namespace], update)
+	}
+
+	namespaces := make([]string, 0, len(byNamespace))
+	for namespace := range byNamespace {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+
+	usage := make([]RelationshipQuotaUsage, 0, len(namespaces))
+	for _, namespace := range namespaces {
+		quota, ok := rq.quotaFor(ctx, namespace)
+		if !ok {
+			continue
+		}
+
+		existing, err := relationshipKeys(ctx, reader, namespace)
+		if err != nil {
+			return nil, err
+		}
+
+		count := uint64(len(existing))
+		added := uint64(0)
+		for _, update := range byNamespace[namespace] {
+			_, exists := existing[tuple.String(update.Tuple)]
+			switch {
+			case update.Operation == core.RelationTupleUpdate_DELETE && exists:
+				count--
+			case update.Operation != core.RelationTupleUpdate_DELETE && !exists:
+				count++
+				added++
+			}
+		}
+
+		// Writes which do not add relationships are always allowed, so that relationships can be
+		// updated and removed once over the quota.
+		if added > 0 && count > quota {
+			return nil, NewRelationshipQuotaExceededErr(namespace, count, quota)
+		}
+		usage = append(usage, RelationshipQuotaUsage{Namespace: namespace, Count: count, Quota: quota})
+	}
+	return usage, nil
+}
+
+// relationshipKeys returns the set of the relationships of the object type, by string.
+func relationshipKeys(ctx context.Context, reader datastore.Reader, namespace string) (map[string]struct{}, error) {
+	it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: namespace})
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+
+	keys := map[string]struct{}{}
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		keys[tuple.String(tpl)] = struct{}{}
+	}
+	if it.Err() != nil {
+		return nil, it.Err()
+	}
+	return keys, nil
+}
+
+// FormatRelationshipQuotaUsage formats the usage of quotas as a comma-separated list of
+// `namespace=count/quota`.
+func FormatRelationshipQuotaUsage(usage []RelationshipQuotaUsage) string {
+	formatted := make([]string, 0, len(usage))
+	for _, u := range usage {
+		formatted = append(formatted, u.String())
+	}
+	return strings.Join(formatted, ",")
+}
+
+// ErrRelationshipQuotaExceeded occurs when a write would bring the number of relationships of an
+// object type over its quota.
+type ErrRelationshipQuotaExceeded struct {
+	error
+	namespace string
+	count     uint64
+	quota     uint64
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrRelationshipQuotaExceeded) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("namespace", err.namespace).Uint64("count", err.count).Uint64("quota", err.quota)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrRelationshipQuotaExceeded) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.ResourceExhausted,
+		&errdetails.QuotaFailure{
+			Violations: []*errdetails.QuotaFailure_Violation{
+				{
+					Subject:     "relationships:" + err.namespace,
+					Description: "relationship_count=" + strconv.FormatUint(err.count, 10) + ", relationship_quota=" + strconv.FormatUint(err.quota, 10),
+				},
+			},
+		},
+	)
+}
+
+// NewRelationshipQuotaExceededErr creates a new error representing that a write would bring the
+// number of relationships of an object type to count, over its quota.
+func NewRelationshipQuotaExceededErr(namespace string, count uint64, quota uint64) ErrRelationshipQuotaExceeded {
+	return ErrRelationshipQuotaExceeded{
+		error:     fmt.Errorf("write would bring the number of relationships of `%s` to %d, which exceeds its quota of %d", namespace, count, quota),
+		namespace: namespace,
+		count:     count,
+		quota:     quota,
+	}
+}
@@ -0,0 +1,125 @@
+package shared
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestRelationshipQuotas(t *testing.T) {
+	quotas := RelationshipQuotas{
+		ByNamespace: map[string]uint64{"document": 3, "team": 1},
+		ByKey:       map[string]uint64{"bigkey": 10},
+	}
+
+	testCases := []struct {
+		name          string
+		key           string
+		updates       []*core.RelationTupleUpdate
+		expectedUsage []RelationshipQuotaUsage
+		expectedError string
+	}{
+		{
+			"within quota",
+			"",
+			[]*core.RelationTupleUpdate{
+				tuple.Create(tuple.MustParse("document:third#viewer@user:tom")),
+				tuple.Touch(tuple.MustParse("user:tom#friend@user:fred")),
+			},
+			[]RelationshipQuotaUsage{{Namespace: "document", Count: 3, Quota: 3}},
+			"",
+		},
+		{
+			"over quota",
+			"",
+			[]*core.RelationTupleUpdate{
+				tuple.Create(tuple.MustParse("document:third#viewer@user:tom")),
+				tuple.Create(tuple.MustParse("document:fourth#viewer@user:tom")),
+			},
+			nil,
+			"write would bring the number of relationships of `document` to 4, which exceeds its quota of 3",
+		},
+		{
+			"existing relationships are not counted again",
+			"",
+			[]*core.RelationTupleUpdate{
+				tuple.Touch(tuple.MustParse("document:first#viewer@user:tom")),
+				tuple.Touch(tuple.MustParse("document:third#viewer@user:tom")),
+				tuple.Delete(tuple.MustParse("document:missing#viewer@user:tom")),
+			},
+			[]RelationshipQuotaUsage{{Namespace: "document", Count: 3, Quota: 3}},
+			"",
+		},
+		{
+			"removals within an object type over quota",
+			"",
+			[]*core.RelationTupleUpdate{
+				tuple.Delete(tuple.MustParse("team:eng#member@user:tom")),
+			},
+			[]RelationshipQuotaUsage{{Namespace: "team", Count: 1, Quota: 1}},
+			"",
+		},
+		{
+			"quota of the key",
+			"bigkey",
+			[]*core.RelationTupleUpdate{
+				tuple.Create(tuple.MustParse("document:third#viewer@user:tom")),
+				tuple.Create(tuple.MustParse("document:fourth#viewer@user:tom")),
+				tuple.Create(tuple.MustParse("user:tom#friend@user:fred")),
+			},
+			[]RelationshipQuotaUsage{
+				{Namespace: "document", Count: 4, Quota: 10},
+				{Namespace: "user", Count: 1, Quota: 10},
+			},
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			ds, revision := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+				definition user {
+					relation friend: user
+				}
+
+				definition team {
+					relation member: user
+				}
+
+				definition document {
+					relation viewer: user
+				}
+			`, []*core.RelationTuple{
+				tuple.MustParse("document:first#viewer@user:tom"),
+				tuple.MustParse("document:second#viewer@user:tom"),
+				tuple.MustParse("team:eng#member@user:tom"),
+				tuple.MustParse("team:eng#member@user:fred"),
+			}, require)
+
+			ctx := context.Background()
+			if tc.key != "" {
+				ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+tc.key))
+			}
+
+			usage, err := quotas.Check(ctx, ds.SnapshotReader(revision), tc.updates)
+			if tc.expectedError != "" {
+				require.ErrorContains(err, tc.expectedError)
+				return
+			}
+			require.NoError(err)
+			require.Equal(tc.expectedUsage, usage)
+		})
+	}
+}
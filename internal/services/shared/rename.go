@@ -0,0 +1,379 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+// RelationRenamePhase is a phase of a relation rename.
+type RelationRenamePhase string
+
+const (
+	// RenameCopying indicates that the new relation has been added to the schema and the existing
+	// relationships are being copied to it.
+	RenameCopying RelationRenamePhase = "copying"
+
+	// RenameCleaningUp indicates that all references have been swapped to the new relation and the
+	// relationships of the existing relation are being deleted.
+	RenameCleaningUp RelationRenamePhase = "cleaning-up"
+
+	// RenameCompleted indicates that the rename has completed.
+	RenameCompleted RelationRenamePhase = "completed"
+)
+
+// RelationRenameProgress is the progress of a relation rename.
+type RelationRenameProgress struct {
+	// Phase is the current phase of the rename.
+	Phase RelationRenamePhase
+
+	// RelationshipsCopied is the number of relationships copied to the new relation.
+	RelationshipsCopied uint64
+
+	// RelationshipsDeleted is the number of relationships of the existing relation deleted.
+	RelationshipsDeleted uint64
+}
+
+// RenameRelation renames the relation `relationName` under definition `nsName` to `newName`,
+// without requiring any relationships to be rewritten by the caller:
+//
+//  1. The new relation is added to the schema alongside the existing relation, which is marked as
+//     being renamed; from then on, relationships written for the existing relation are also
+//     written for the new one.
+//  2. The existing relationships are copied to the new relation, in batches of batchSize.
+//  3. All references to the existing relation in the schema are swapped to the new relation, and
+//     the existing relation removed, atomically.
+//  4. The relationships of the existing relation are deleted, in batches of batchSize.
+//
+// If interrupted, the rename can be resumed by calling RenameRelation again with the same
+// arguments. The given function, if any, is called with the progress after each batch.
+func RenameRelation(
+	ctx context.Context,
+	ds datastore.Datastore,
+	nsName, relationName, newName string,
+	batchSize uint64,
+	onProgress func(RelationRenameProgress),
+) error {
+	if onProgress == nil {
+		onProgress = func(RelationRenameProgress) {}
+	}
+
+	progress := RelationRenameProgress{Phase: RenameCopying}
+
+	startedAt, alreadySwapped, err := startRelationRename(ctx, ds, nsName, relationName, newName)
+	if err != nil {
+		return err
+	}
+
+	if !alreadySwapped {
+		onProgress(progress)
+		log.Ctx(ctx).Info().
+			Str("definition", nsName).
+			Str("relation", relationName).
+			Str("newName", newName).
+			Msg("started relation rename; copying relationships")
+
+		// Copy the relationships existing when the rename started; any written since are kept in
+		// sync by the relationship writes themselves.
+		if err := copyRenamedRelationships(ctx, ds, startedAt, nsName, relationName, newName, batchSize, func(copied uint64) {
+			progress.RelationshipsCopied += copied
+			onProgress(progress)
+		}); err != nil {
+			return err
+		}
+
+		if err := finishRelationRename(ctx, ds, nsName, relationName); err != nil {
+			return err
+		}
+	}
+
+	progress.Phase = RenameCleaningUp
+	onProgress(progress)
+	log.Ctx(ctx).Info().
+		Str("definition", nsName).
+		Str("relation", relationName).
+		Uint64("relationshipsCopied", progress.RelationshipsCopied).
+		Msg("swapped references to renamed relation; deleting relationships")
+
+	for {
+		deleted, err := deleteRelationshipsBatch(
+			ctx,
+			ds,
+			datastore.RelationshipsFilter{ResourceType: nsName, OptionalResourceRelation: relationName},
+			&datastore.SubjectsFilter{SubjectType: nsName, RelationFilter: datastore.SubjectRelationFilter{}.WithNonEllipsisRelation(relationName)},
+			batchSize,
+		)
+		if err != nil {
+			return err
+		}
+
+		if deleted == 0 {
+			break
+		}
+
+		progress.RelationshipsDeleted += deleted
+		onProgress(progress)
+	}
+
+	progress.Phase = RenameCompleted
+	onProgress(progress)
+	log.Ctx(ctx).Info().
+		Str("definition", nsName).
+		Str("relation", relationName).
+		Str("newName", newName).
+		Uint64("relationshipsDeleted", progress.RelationshipsDeleted).
+		Msg("completed relation rename")
+	return nil
+}
+
+// startRelationRename adds the new relation to the schema, if not already added, returning the
+// revision at which it was added or whether the references were already swapped to it.
+func startRelationRename(ctx context.Context, ds datastore.Datastore, nsName, relationName, newName string) (datastore.Revision, bool, error) {
+	alreadySwapped := false
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		alreadySwapped = false
+
+		objectDefs, err := namespace.ListLiveNamespaces(ctx, rwt)
+		if err != nil {
+			return err
+		}
+
+		// If only the new relation exists, a previous rename was interrupted after the swap.
+		if isRenameSwapped(objectDefs, nsName, relationName, newName) {
+			alreadySwapped = true
+			return nil
+		}
+
+		changed, err := namespace.StartRelationRename(objectDefs, nsName, relationName, newName)
+		if err != nil || len(changed) == 0 {
+			return err
+		}
+
+		return writeRenamedDefinitions(ctx, rwt, objectDefs, changed)
+	})
+	return revision, alreadySwapped, err
+}
+
+// finishRelationRename swaps all references to the new relation, removing the existing relation.
+func finishRelationRename(ctx context.Context, ds datastore.Datastore, nsName, relationName string) error {
+	_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		objectDefs, err := namespace.ListLiveNamespaces(ctx, rwt)
+		if err != nil {
+			return err
+		}
+
+		changed, err := namespace.FinishRelationRename(objectDefs, nsName, relationName)
+		if err != nil {
+			return err
+		}
+
+		return writeRenamedDefinitions(ctx, rwt, objectDefs, changed)
+	})
+	return err
+}
+
+func isRenameSwapped(objectDefs []*core.NamespaceDefinition, nsName, relationName, newName string) bool {
+	for _, objectDef := range objectDefs {
+		if objectDef.Name != nsName {
+			continue
+		}
+
+		relationNames := util.NewSet[string]()
+		for _, relation := range objectDef.Relation {
+			relationNames.Add(relation.Name)
+		}
+		return relationNames.Has(newName) && !relationNames.Has(relationName)
+	}
+	return false
+}
+
+// writeRenamedDefinitions validates and annotates the changed definitions against the full schema,
+// before writing them.
+func writeRenamedDefinitions(ctx context.Context, rwt datastore.ReadWriteTransaction, objectDefs, changed []*core.NamespaceDefinition) error {
+	caveatDefs, err := rwt.ListCaveats(ctx)
+	if err != nil {
+		return err
+	}
+
+	changedByName := make(map[string]*core.NamespaceDefinition, len(changed))
+	for _, changedDef := range changed {
+		changedByName[changedDef.Name] = changedDef
+	}
+
+	updatedDefs := make([]*core.NamespaceDefinition, 0, len(objectDefs))
+	for _, objectDef := range objectDefs {
+		if changedDef, ok := changedByName[objectDef.Name]; ok {
+			updatedDefs = append(updatedDefs, changedDef)
+			continue
+		}
+		updatedDefs = append(updatedDefs, objectDef)
+	}
+
+	resolver := namespace.ResolverForPredefinedDefinitions(namespace.PredefinedElements{
+		Namespaces: updatedDefs,
+		Caveats:    caveatDefs,
+	})
+	for _, changedDef := range changed {
+		ts, err := namespace.NewNamespaceTypeSystem(changedDef, resolver)
+		if err != nil {
+			return err
+		}
+
+		vts, err := ts.Validate(ctx)
+		if err != nil {
+			return fmt.Errorf("renamed schema is invalid: %w", err)
+		}
+
+		if err := namespace.AnnotateNamespace(vts); err != nil {
+			return err
+		}
+	}
+
+	return rwt.WriteNamespaces(ctx, changed...)
+}
+
+// copyRenamedRelationships copies all relationships of the existing relation found at the given
+// revision to the new relation, calling onCopied after each batch.
+func copyRenamedRelationships(
+	ctx context.Context,
+	ds datastore.Datastore,
+	revision datastore.Revision,
+	nsName, relationName, newName string,
+	batchSize uint64,
+	onCopied func(uint64),
+) error {
+	renamed := func(tpl *core.RelationTuple) *core.RelationTuple {
+		renamed := tpl.CloneVT()
+		if renamed.ResourceAndRelation.Namespace == nsName && renamed.ResourceAndRelation.Relation == relationName {
+			renamed.ResourceAndRelation.Relation = newName
+		}
+		if renamed.Subject.Namespace == nsName && renamed.Subject.Relation == relationName {
+			renamed.Subject.Relation = newName
+		}
+		return renamed
+	}
+
+	copyBatch := func(batch []*core.RelationTuple) error {
+		var copied uint64
+		_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			// Only copy relationships which still exist, with their current caveats, as any deleted
+			// since the snapshot was taken would otherwise be resurrected.
+			current, err := currentRelationships(ctx, rwt, batch)
+			if err != nil {
+				return err
+			}
+
+			updates := make([]*core.RelationTupleUpdate, 0, len(current))
+			for _, tpl := range current {
+				updates = append(updates, tuple.Touch(renamed(tpl)))
+			}
+
+			copied = uint64(len(updates))
+			if len(updates) == 0 {
+				return nil
+			}
+			return rwt.WriteRelationships(ctx, updates)
+		})
+		if err != nil {
+			return err
+		}
+
+		onCopied(copied)
+		return nil
+	}
+
+	copyAll := func(it datastore.RelationshipIterator, err error) error {
+		if err != nil {
+			return err
+		}
+		defer it.Close()
+
+		batch := make([]*core.RelationTuple, 0, batchSize)
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			batch = append(batch, tpl)
+			if uint64(len(batch)) >= batchSize {
+				if err := copyBatch(batch); err != nil {
+					return err
+				}
+				batch = batch[:0]
+			}
+		}
+		if it.Err() != nil {
+			return it.Err()
+		}
+
+		if len(batch) > 0 {
+			return copyBatch(batch)
+		}
+		return nil
+	}
+
+	reader := ds.SnapshotReader(revision)
+	if err := copyAll(reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             nsName,
+		OptionalResourceRelation: relationName,
+	})); err != nil {
+		return err
+	}
+
+	return copyAll(reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+		SubjectType:    nsName,
+		RelationFilter: datastore.SubjectRelationFilter{}.WithNonEllipsisRelation(relationName),
+	}))
+}
+
+// currentRelationships returns those of the given relationships which still exist in the
+// transaction, as currently stored.
+func currentRelationships(ctx context.Context, rwt datastore.ReadWriteTransaction, tpls []*core.RelationTuple) ([]*core.RelationTuple, error) {
+	withoutCaveat := func(tpl *core.RelationTuple) string {
+		stripped := tpl.CloneVT()
+		stripped.Caveat = nil
+		return tuple.String(stripped)
+	}
+
+	// Group the relationships by resource relation, to load them with one query per group.
+	type resourceRelation struct {
+		namespace string
+		relation  string
+	}
+
+	wanted := util.NewSet[string]()
+	resourceIDsByRelation := map[resourceRelation][]string{}
+	for _, tpl := range tpls {
+		wanted.Add(withoutCaveat(tpl))
+
+		key := resourceRelation{tpl.ResourceAndRelation.Namespace, tpl.ResourceAndRelation.Relation}
+		resourceIDsByRelation[key] = append(resourceIDsByRelation[key], tpl.ResourceAndRelation.ObjectId)
+	}
+
+	current := make([]*core.RelationTuple, 0, len(tpls))
+	for key, resourceIDs := range resourceIDsByRelation {
+		it, err := rwt.QueryRelationships(ctx, datastore.RelationshipsFilter{
+			ResourceType:             key.namespace,
+			OptionalResourceRelation: key.relation,
+			OptionalResourceIds:      resourceIDs,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			if wanted.Has(withoutCaveat(tpl)) {
+				current = append(current, tpl)
+			}
+		}
+		it.Close()
+
+		if it.Err() != nil {
+			return nil, it.Err()
+		}
+	}
+
+	return current, nil
+}
@@ -0,0 +1,168 @@
+package shared
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const renameTestSchema = `
+	definition user {}
+
+	definition group {
+		relation member: user | group#member
+		permission membership = member
+	}
+
+	definition document {
+		relation viewer: user | group#member
+		permission view = viewer
+	}
+`
+
+var renameTestRelationships = []*core.RelationTuple{
+	tuple.MustParse("group:eng#member@user:tom"),
+	tuple.MustParse("group:eng#member@group:admins#member"),
+	tuple.MustParse("group:admins#member@user:sarah"),
+	tuple.MustParse("document:first#viewer@group:eng#member"),
+	tuple.MustParse("document:first#viewer@user:fred"),
+}
+
+func TestRenameRelation(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, renameTestSchema, renameTestRelationships, require)
+
+	ctx := context.Background()
+	var progress []RelationRenameProgress
+	require.NoError(RenameRelation(ctx, ds, "group", "member", "participant", 2, func(p RelationRenameProgress) {
+		progress = append(progress, p)
+	}))
+
+	require.Equal(RelationRenameProgress{Phase: RenameCopying}, progress[0])
+	require.Equal(RelationRenameProgress{
+		Phase:                RenameCompleted,
+		RelationshipsCopied:  5,
+		RelationshipsDeleted: 4,
+	}, progress[len(progress)-1])
+
+	requireGroupSource(ctx, require, ds, `definition group {
+	relation participant: user | group#participant
+	permission membership = participant
+}`)
+
+	requireRelationships(ctx, require, ds, []string{
+		"document:first#viewer@group:eng#participant",
+		"document:first#viewer@user:fred",
+		"group:admins#participant@user:sarah",
+		"group:eng#participant@group:admins#participant",
+		"group:eng#participant@user:tom",
+	})
+}
+
+func TestRenameRelationResumed(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, renameTestSchema, renameTestRelationships, require)
+
+	// Start the rename, as if it was then interrupted.
+	ctx := context.Background()
+	_, alreadySwapped, err := startRelationRename(ctx, ds, "group", "member", "participant")
+	require.NoError(err)
+	require.False(alreadySwapped)
+
+	// Ensure the schema cannot be written mid-rename.
+	emptyDefaultPrefix := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: renameTestSchema,
+	}, &emptyDefaultPrefix)
+	require.NoError(err)
+
+	validated, err := ValidateSchemaChanges(ctx, compiled, false)
+	require.NoError(err)
+
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		_, err := ApplySchemaChanges(ctx, rwt, validated)
+		return err
+	})
+	require.ErrorContains(err, "cannot write schema while relation `group#member` is being renamed to `participant`")
+
+	// Ensure relationships written mid-rename are written for both relations.
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		updates, err := relationships.WithRenamedRelationUpdates(ctx, rwt, []*core.RelationTupleUpdate{
+			tuple.Create(tuple.MustParse("group:eng#member@user:jill")),
+			tuple.Delete(tuple.MustParse("group:eng#member@user:tom")),
+		})
+		require.NoError(err)
+		require.Len(updates, 4)
+		return rwt.WriteRelationships(ctx, updates)
+	})
+	require.NoError(err)
+
+	// Resume the rename.
+	require.NoError(RenameRelation(ctx, ds, "group", "member", "participant", 100, nil))
+
+	requireRelationships(ctx, require, ds, []string{
+		"document:first#viewer@group:eng#participant",
+		"document:first#viewer@user:fred",
+		"group:admins#participant@user:sarah",
+		"group:eng#participant@group:admins#participant",
+		"group:eng#participant@user:jill",
+	})
+
+	// Resuming a completed rename is a no-op.
+	require.NoError(RenameRelation(ctx, ds, "group", "member", "participant", 100, nil))
+	requireGroupSource(ctx, require, ds, `definition group {
+	relation participant: user | group#participant
+	permission membership = participant
+}`)
+}
+
+func requireGroupSource(ctx context.Context, require *require.Assertions, ds datastore.Datastore, expected string) {
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	nsDef, _, err := ds.SnapshotReader(headRevision).ReadNamespace(ctx, "group")
+	require.NoError(err)
+
+	source, _ := generator.GenerateSource(nsDef)
+	require.Equal(expected, source)
+}
+
+func requireRelationships(ctx context.Context, require *require.Assertions, ds datastore.Datastore, expected []string) {
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	reader := ds.SnapshotReader(headRevision)
+	var found []string
+	for _, nsName := range []string{"group", "document"} {
+		it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: nsName})
+		require.NoError(err)
+
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			found = append(found, tuple.String(tpl))
+		}
+		require.NoError(it.Err())
+		it.Close()
+	}
+
+	sort.Strings(found)
+	require.Equal(expected, found)
+}
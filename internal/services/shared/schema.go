@@ -3,6 +3,7 @@ package shared
 import (
 	"context"
 
+	"golang.org/x/exp/slices"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -10,7 +11,9 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/options"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/relationships"
 	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
 	"github.com/authzed/spicedb/pkg/tuple"
@@ -23,11 +26,33 @@ type ValidatedSchemaChanges struct {
 	newCaveatDefNames *util.Set[string]
 	newObjectDefNames *util.Set[string]
 	additiveOnly      bool
+	softDelete        bool
 }
 
 // ValidateSchemaChanges validates the schema found in the compiled schema and returns a
 // ValidatedSchemaChanges, if fully validated.
 func ValidateSchemaChanges(ctx context.Context, compiled *compiler.CompiledSchema, additiveOnly bool) (*ValidatedSchemaChanges, error) {
+	return ValidateSchemaChangesWithSoftDelete(ctx, compiled, additiveOnly, false)
+}
+
+// ValidateSchemaChangesWithSoftDelete validates the schema found in the compiled schema and returns
+// a ValidatedSchemaChanges, if fully validated. If softDelete is true, object definitions removed
+// from the schema while relationships still exist for them are tombstoned, rather than rejected,
+// and their relationships left to be purged by a TombstonePurger.
+func ValidateSchemaChangesWithSoftDelete(ctx context.Context, compiled *compiler.CompiledSchema, additiveOnly bool, softDelete bool) (*ValidatedSchemaChanges, error) {
+	return validateSchemaChanges(ctx, compiled, additiveOnly, softDelete, nil)
+}
+
+// validateSchemaChanges validates the schema found in the compiled schema. Object definitions found
+// in unchanged are known to be valid, and are annotated from the given definition, rather than
+// being revalidated.
+func validateSchemaChanges(
+	ctx context.Context,
+	compiled *compiler.CompiledSchema,
+	additiveOnly bool,
+	softDelete bool,
+	unchanged map[string]*core.NamespaceDefinition,
+) (*ValidatedSchemaChanges, error) {
 	// 1) Validate the caveats defined.
 	newCaveatDefNames := util.NewSet[string]()
 	for _, caveatDef := range compiled.CaveatDefinitions {
@@ -39,13 +64,21 @@ func ValidateSchemaChanges(ctx context.Context, compiled *compiler.CompiledSchem
 	}
 
 	// 2) Validate the namespaces defined.
+	resolver := namespace.ResolverForPredefinedDefinitions(namespace.PredefinedElements{
+		Namespaces: compiled.ObjectDefinitions,
+		Caveats:    compiled.CaveatDefinitions,
+	})
+
 	newObjectDefNames := util.NewSet[string]()
 	for _, nsdef := range compiled.ObjectDefinitions {
-		ts, err := namespace.NewNamespaceTypeSystem(nsdef,
-			namespace.ResolverForPredefinedDefinitions(namespace.PredefinedElements{
-				Namespaces: compiled.ObjectDefinitions,
-				Caveats:    compiled.CaveatDefinitions,
-			}))
+		newObjectDefNames.Add(nsdef.Name)
+
+		if annotated, ok := unchanged[nsdef.Name]; ok {
+			copyAnnotations(annotated, nsdef)
+			continue
+		}
+
+		ts, err := namespace.NewNamespaceTypeSystem(nsdef, resolver)
 		if err != nil {
 			return nil, err
 		}
@@ -58,8 +91,11 @@ func ValidateSchemaChanges(ctx context.Context, compiled *compiler.CompiledSchem
 		if err := namespace.AnnotateNamespace(vts); err != nil {
 			return nil, err
 		}
+	}
 
-		newObjectDefNames.Add(nsdef.Name)
+	// 3) Validate the relationship templates declared.
+	if _, err := relationships.ParseTemplates(compiled.ObjectDefinitions); err != nil {
+		return nil, err
 	}
 
 	return &ValidatedSchemaChanges{
@@ -67,6 +103,7 @@ func ValidateSchemaChanges(ctx context.Context, compiled *compiler.CompiledSchem
 		newCaveatDefNames: newCaveatDefNames,
 		newObjectDefNames: newObjectDefNames,
 		additiveOnly:      additiveOnly,
+		softDelete:        softDelete,
 	}, nil
 }
 
@@ -81,6 +118,10 @@ type AppliedSchemaChanges struct {
 
 	// RemovedObjectDefNames contains the names of the removed object definitions.
 	RemovedObjectDefNames []string
+
+	// TombstonedObjectDefNames contains the names of the object definitions which were soft-deleted,
+	// and whose relationships are pending purge.
+	TombstonedObjectDefNames []string
 }
 
 // ApplySchemaChanges applies schema changes found in the validated changes struct, via the specified
@@ -108,6 +149,130 @@ func ApplySchemaChangesOverExisting(
 	existingCaveats []*core.CaveatDefinition,
 	existingObjectDefs []*core.NamespaceDefinition,
 ) (*AppliedSchemaChanges, error) {
+	// Ensure that the changes will not result in type errors or relationships left without
+	// associated schema, failing on the first problem found.
+	checked, err := checkSchemaChanges(ctx, rwt, validated, existingCaveats, existingObjectDefs, func(err error) error {
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Write the new caveats.
+	// TODO(jschorr): Only write updated caveats once the diff has been changed to support expressions.
+	if len(validated.compiled.CaveatDefinitions) > 0 {
+		if err := rwt.WriteCaveats(ctx, validated.compiled.CaveatDefinitions); err != nil {
+			return nil, err
+		}
+	}
+
+	// Write the new/changed namespaces.
+	if len(checked.objectDefsWithChanges) > 0 {
+		if err := rwt.WriteNamespaces(ctx, checked.objectDefsWithChanges...); err != nil {
+			return nil, err
+		}
+	}
+
+	if !validated.additiveOnly {
+		// Tombstone the soft-deleted namespaces.
+		if len(checked.tombstonedObjectDefs) > 0 {
+			if err := rwt.WriteNamespaces(ctx, checked.tombstonedObjectDefs...); err != nil {
+				return nil, err
+			}
+		}
+
+		// Delete the removed namespaces.
+		if checked.removedObjectDefNames.Len() > 0 {
+			if err := rwt.DeleteNamespaces(ctx, checked.removedObjectDefNames.AsSlice()...); err != nil {
+				return nil, err
+			}
+		}
+
+		// Delete the removed caveats.
+		if !checked.removedCaveatDefNames.IsEmpty() {
+			if err := rwt.DeleteCaveats(ctx, checked.removedCaveatDefNames.AsSlice()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	log.Ctx(ctx).Trace().
+		Interface("objectDefinitions", validated.compiled.ObjectDefinitions).
+		Interface("caveatDefinitions", validated.compiled.CaveatDefinitions).
+		Object("addedOrChangedObjectDefinitions", util.StringSet(validated.newObjectDefNames)).
+		Object("removedObjectDefinitions", util.StringSet(checked.removedObjectDefNames)).
+		Strs("tombstonedObjectDefinitions", checked.tombstonedObjectDefNames()).
+		Object("addedOrChangedCaveatDefinitions", util.StringSet(validated.newCaveatDefNames)).
+		Object("removedCaveatDefinitions", util.StringSet(checked.removedCaveatDefNames)).
+		Msg("completed schema update")
+
+	return &AppliedSchemaChanges{
+		TotalOperationCount:      uint32(len(validated.compiled.ObjectDefinitions) + len(validated.compiled.CaveatDefinitions) + checked.removedObjectDefNames.Len() + len(checked.tombstonedObjectDefs) + checked.removedCaveatDefNames.Len()),
+		NewObjectDefNames:        validated.newObjectDefNames.Subtract(checked.existingObjectDefNames).AsSlice(),
+		RemovedObjectDefNames:    checked.removedObjectDefNames.AsSlice(),
+		TombstonedObjectDefNames: checked.tombstonedObjectDefNames(),
+	}, nil
+}
+
+// DiagnoseSchemaChanges performs all of the checks made by ApplySchemaChanges against the
+// schema and relationships found in the given reader, without applying any changes. Unlike
+// ApplySchemaChanges, which stops at the first problem found, all problems are collected and
+// returned as diagnostics. The returned error is only set if the checks could not be performed.
+func DiagnoseSchemaChanges(ctx context.Context, reader datastore.Reader, validated *ValidatedSchemaChanges) ([]error, error) {
+	existingCaveats, err := reader.ListCaveats(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	existingObjectDefs, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []error
+	_, err = checkSchemaChanges(ctx, reader, validated, existingCaveats, existingObjectDefs, func(err error) error {
+		// Only errors with a status are problems with the schema changes themselves; all
+		// others are failures to perform the check.
+		if _, ok := status.FromError(err); ok {
+			diagnostics = append(diagnostics, err)
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return diagnostics, nil
+}
+
+type checkedSchemaChanges struct {
+	objectDefsWithChanges  []*core.NamespaceDefinition
+	existingObjectDefNames *util.Set[string]
+	removedObjectDefNames  *util.Set[string]
+	removedCaveatDefNames  *util.Set[string]
+	tombstonedObjectDefs   []*core.NamespaceDefinition
+}
+
+func (c *checkedSchemaChanges) tombstonedObjectDefNames() []string {
+	names := make([]string, 0, len(c.tombstonedObjectDefs))
+	for _, nsdef := range c.tombstonedObjectDefs {
+		names = append(names, nsdef.Name)
+	}
+	return names
+}
+
+// checkSchemaChanges ensures that the validated schema changes will not result in type errors
+// or relationships left without associated schema. Every problem found is passed to the report
+// function, which returns an error to stop checking or nil to continue.
+func checkSchemaChanges(
+	ctx context.Context,
+	reader datastore.Reader,
+	validated *ValidatedSchemaChanges,
+	existingCaveats []*core.CaveatDefinition,
+	existingObjectDefs []*core.NamespaceDefinition,
+	report func(error) error,
+) (*checkedSchemaChanges, error) {
 	// Build a map of existing caveats to determine those being removed, if any.
 	existingCaveatDefMap := make(map[string]*core.CaveatDefinition, len(existingCaveats))
 	existingCaveatDefNames := util.NewSet[string]()
@@ -119,31 +284,55 @@ func ApplySchemaChangesOverExisting(
 
 	// For each caveat definition, perform a diff and ensure the changes will not result in type errors.
 	for _, caveatDef := range validated.compiled.CaveatDefinitions {
-		if err := sanityCheckCaveatChanges(ctx, rwt, caveatDef, existingCaveatDefMap); err != nil {
+		if err := sanityCheckCaveatChanges(ctx, caveatDef, existingCaveatDefMap, report); err != nil {
 			return nil, err
 		}
 	}
 
 	removedCaveatDefNames := existingCaveatDefNames.Subtract(validated.newCaveatDefNames)
 
-	// Build a map of existing definitions to determine those being removed, if any.
+	// Build a map of existing definitions to determine those being removed, if any. Tombstoned
+	// definitions are no longer part of the schema, and are left for the purger to delete.
 	existingObjectDefMap := make(map[string]*core.NamespaceDefinition, len(existingObjectDefs))
 	existingObjectDefNames := util.NewSet[string]()
+	tombstonedObjectDefNames := util.NewSet[string]()
 	for _, existingDef := range existingObjectDefs {
+		if nspkg.IsTombstoned(existingDef) {
+			tombstonedObjectDefNames.Add(existingDef.Name)
+			continue
+		}
+
 		existingObjectDefMap[existingDef.Name] = existingDef
 		existingObjectDefNames.Add(existingDef.Name)
+
+		// Writing the schema mid-rename would lose the state of the rename.
+		for _, relation := range existingDef.Relation {
+			if newName, ok := nspkg.GetRenaming(relation); ok {
+				if err := report(status.Errorf(codes.FailedPrecondition, "cannot write schema while relation `%s#%s` is being renamed to `%s`", existingDef.Name, relation.Name, newName)); err != nil {
+					return nil, err
+				}
+			}
+		}
 	}
 
 	// For each definition, perform a diff and ensure the changes will not result in any
 	// breaking changes.
 	objectDefsWithChanges := make([]*core.NamespaceDefinition, 0, len(validated.compiled.ObjectDefinitions))
 	for _, nsdef := range validated.compiled.ObjectDefinitions {
-		diff, err := sanityCheckNamespaceChanges(ctx, rwt, nsdef, existingObjectDefMap)
+		if tombstonedObjectDefNames.Has(nsdef.Name) {
+			if err := report(status.Errorf(codes.FailedPrecondition, "cannot re-create object definition `%s`, as its relationships are still being purged", nsdef.Name)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		diff, err := sanityCheckNamespaceChanges(ctx, reader, nsdef, existingObjectDefMap, report)
 		if err != nil {
 			return nil, err
 		}
 
-		if len(diff.Deltas()) > 0 {
+		// Relationship templates are declared in doc comments, which are otherwise not diffed.
+		if len(diff.Deltas()) > 0 || templatesChanged(existingObjectDefMap[nsdef.Name], nsdef) {
 			objectDefsWithChanges = append(objectDefsWithChanges, nsdef)
 		}
 	}
@@ -157,69 +346,52 @@ func ApplySchemaChangesOverExisting(
 
 	// Ensure that deleting namespaces will not result in any relationships left without associated
 	// schema.
+	// If soft deletion is enabled, namespaces which still have relationships are instead tombstoned.
 	removedObjectDefNames := existingObjectDefNames.Subtract(validated.newObjectDefNames)
+	var tombstonedObjectDefs []*core.NamespaceDefinition
 	if !validated.additiveOnly {
 		if err := removedObjectDefNames.ForEach(func(nsdefName string) error {
-			return ensureNoRelationshipsExist(ctx, rwt, nsdefName)
-		}); err != nil {
-			return nil, err
-		}
-	}
-
-	// Write the new caveats.
-	// TODO(jschorr): Only write updated caveats once the diff has been changed to support expressions.
-	if len(validated.compiled.CaveatDefinitions) > 0 {
-		if err := rwt.WriteCaveats(ctx, validated.compiled.CaveatDefinitions); err != nil {
-			return nil, err
-		}
-	}
+			if !validated.softDelete {
+				return ensureNoRelationshipsExist(ctx, reader, nsdefName, report)
+			}
 
-	// Write the new/changed namespaces.
-	if len(objectDefsWithChanges) > 0 {
-		if err := rwt.WriteNamespaces(ctx, objectDefsWithChanges...); err != nil {
-			return nil, err
-		}
-	}
+			hasRelationships, err := relationshipsExist(ctx, reader, nsdefName)
+			if err != nil || !hasRelationships {
+				return err
+			}
 
-	if !validated.additiveOnly {
-		// Delete the removed namespaces.
-		if removedObjectDefNames.Len() > 0 {
-			if err := rwt.DeleteNamespaces(ctx, removedObjectDefNames.AsSlice()...); err != nil {
-				return nil, err
+			tombstoned, err := nspkg.MarkTombstoned(existingObjectDefMap[nsdefName])
+			if err != nil {
+				return err
 			}
+
+			tombstonedObjectDefs = append(tombstonedObjectDefs, tombstoned)
+			return nil
+		}); err != nil {
+			return nil, err
 		}
 
-		// Delete the removed caveats.
-		if !removedCaveatDefNames.IsEmpty() {
-			if err := rwt.DeleteCaveats(ctx, removedCaveatDefNames.AsSlice()); err != nil {
-				return nil, err
-			}
+		for _, tombstoned := range tombstonedObjectDefs {
+			removedObjectDefNames.Remove(tombstoned.Name)
 		}
 	}
 
-	log.Ctx(ctx).Trace().
-		Interface("objectDefinitions", validated.compiled.ObjectDefinitions).
-		Interface("caveatDefinitions", validated.compiled.CaveatDefinitions).
-		Object("addedOrChangedObjectDefinitions", util.StringSet(validated.newObjectDefNames)).
-		Object("removedObjectDefinitions", util.StringSet(removedObjectDefNames)).
-		Object("addedOrChangedCaveatDefinitions", util.StringSet(validated.newCaveatDefNames)).
-		Object("removedCaveatDefinitions", util.StringSet(removedCaveatDefNames)).
-		Msg("completed schema update")
-
-	return &AppliedSchemaChanges{
-		TotalOperationCount:   uint32(len(validated.compiled.ObjectDefinitions) + len(validated.compiled.CaveatDefinitions) + removedObjectDefNames.Len() + removedCaveatDefNames.Len()),
-		NewObjectDefNames:     validated.newObjectDefNames.Subtract(existingObjectDefNames).AsSlice(),
-		RemovedObjectDefNames: removedObjectDefNames.AsSlice(),
+	return &checkedSchemaChanges{
+		objectDefsWithChanges:  objectDefsWithChanges,
+		existingObjectDefNames: existingObjectDefNames,
+		removedObjectDefNames:  removedObjectDefNames,
+		removedCaveatDefNames:  removedCaveatDefNames,
+		tombstonedObjectDefs:   tombstonedObjectDefs,
 	}, nil
 }
 
 // sanityCheckCaveatChanges ensures that a caveat definition being written does not break
 // the types of the parameters that may already exist on relationships.
 func sanityCheckCaveatChanges(
-	ctx context.Context,
-	rwt datastore.ReadWriteTransaction,
+	_ context.Context,
 	caveatDef *core.CaveatDefinition,
 	existingDefs map[string]*core.CaveatDefinition,
+	report func(error) error,
 ) error {
 	// Ensure that the updated namespace does not break the existing tuple data.
 	existing := existingDefs[caveatDef.Name]
@@ -231,10 +403,14 @@ func sanityCheckCaveatChanges(
 	for _, delta := range diff.Deltas() {
 		switch delta.Type {
 		case caveats.RemovedParameter:
-			return status.Errorf(codes.InvalidArgument, "cannot remove parameter `%s` on caveat `%s`", delta.ParameterName, caveatDef.Name)
+			if err := report(status.Errorf(codes.InvalidArgument, "cannot remove parameter `%s` on caveat `%s`", delta.ParameterName, caveatDef.Name)); err != nil {
+				return err
+			}
 
 		case caveats.ParameterTypeChanged:
-			return status.Errorf(codes.InvalidArgument, "cannot change the type of parameter `%s` on caveat `%s`", delta.ParameterName, caveatDef.Name)
+			if err := report(status.Errorf(codes.InvalidArgument, "cannot change the type of parameter `%s` on caveat `%s`", delta.ParameterName, caveatDef.Name)); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -242,8 +418,8 @@ func sanityCheckCaveatChanges(
 }
 
 // ensureNoRelationshipsExist ensures that no relationships exist within the namespace with the given name.
-func ensureNoRelationshipsExist(ctx context.Context, rwt datastore.ReadWriteTransaction, namespaceName string) error {
-	qy, qyErr := rwt.QueryRelationships(
+func ensureNoRelationshipsExist(ctx context.Context, reader datastore.Reader, namespaceName string, report func(error) error) error {
+	qy, qyErr := reader.QueryRelationships(
 		ctx,
 		datastore.RelationshipsFilter{ResourceType: namespaceName},
 		options.WithLimit(options.LimitOne),
@@ -255,10 +431,12 @@ func ensureNoRelationshipsExist(ctx context.Context, rwt datastore.ReadWriteTran
 		"cannot delete object definition `%s`, as a relationship exists under it",
 		namespaceName,
 	); err != nil {
-		return err
+		if err := report(err); err != nil {
+			return err
+		}
 	}
 
-	qy, qyErr = rwt.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+	qy, qyErr = reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
 		SubjectType: namespaceName,
 	}, options.WithReverseLimit(options.LimitOne))
 	if err := errorIfTupleIteratorReturnsTuples(
@@ -268,20 +446,60 @@ func ensureNoRelationshipsExist(ctx context.Context, rwt datastore.ReadWriteTran
 		"cannot delete object definition `%s`, as a relationship references it",
 		namespaceName,
 	); err != nil {
-		return err
+		if err := report(err); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
+// relationshipsExist returns whether any relationships exist within, or reference, the namespace
+// with the given name.
+func relationshipsExist(ctx context.Context, reader datastore.Reader, namespaceName string) (bool, error) {
+	found := false
+	if err := ensureNoRelationshipsExist(ctx, reader, namespaceName, func(err error) error {
+		if _, ok := status.FromError(err); ok {
+			found = true
+			return nil
+		}
+		return err
+	}); err != nil {
+		return false, err
+	}
+	return found, nil
+}
+
+// templatesChanged returns whether the relationship templates declared on the existing object
+// definition, if any, differ from those declared on its updated definition.
+func templatesChanged(existing *core.NamespaceDefinition, updated *core.NamespaceDefinition) bool {
+	if existing == nil {
+		return false
+	}
+
+	existingTemplates := nspkg.GetTemplateDeclarations(existing.Metadata)
+	updatedTemplates := nspkg.GetTemplateDeclarations(updated.Metadata)
+	if len(existingTemplates) != len(updatedTemplates) {
+		return true
+	}
+
+	for index := range existingTemplates {
+		if !slices.Equal(existingTemplates[index], updatedTemplates[index]) {
+			return true
+		}
+	}
+	return false
+}
+
 // sanityCheckNamespaceChanges ensures that a namespace definition being written does not result
 // in breaking changes, such as relationships without associated defined schema object definitions
 // and relations.
 func sanityCheckNamespaceChanges(
 	ctx context.Context,
-	rwt datastore.ReadWriteTransaction,
+	reader datastore.Reader,
 	nsdef *core.NamespaceDefinition,
 	existingDefs map[string]*core.NamespaceDefinition,
+	report func(error) error,
 ) (*namespace.Diff, error) {
 	// Ensure that the updated namespace does not break the existing tuple data.
 	existing := existingDefs[nsdef.Name]
@@ -293,7 +511,7 @@ func sanityCheckNamespaceChanges(
 	for _, delta := range diff.Deltas() {
 		switch delta.Type {
 		case namespace.RemovedRelation:
-			qy, qyErr := rwt.QueryRelationships(ctx, datastore.RelationshipsFilter{
+			qy, qyErr := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{
 				ResourceType:             nsdef.Name,
 				OptionalResourceRelation: delta.RelationName,
 			})
@@ -304,11 +522,13 @@ func sanityCheckNamespaceChanges(
 				qyErr,
 				"cannot delete relation `%s` in object definition `%s`, as a relationship exists under it", delta.RelationName, nsdef.Name)
 			if err != nil {
-				return diff, err
+				if err := report(err); err != nil {
+					return diff, err
+				}
 			}
 
 			// Also check for right sides of tuples.
-			qy, qyErr = rwt.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
+			qy, qyErr = reader.ReverseQueryRelationships(ctx, datastore.SubjectsFilter{
 				SubjectType: nsdef.Name,
 				RelationFilter: datastore.SubjectRelationFilter{
 					NonEllipsisRelation: delta.RelationName,
@@ -320,7 +540,9 @@ func sanityCheckNamespaceChanges(
 				qyErr,
 				"cannot delete relation `%s` in object definition `%s`, as a relationship references it", delta.RelationName, nsdef.Name)
 			if err != nil {
-				return diff, err
+				if err := report(err); err != nil {
+					return diff, err
+				}
 			}
 
 		case namespace.RelationAllowedTypeRemoved:
@@ -340,7 +562,7 @@ func sanityCheckNamespaceChanges(
 				optionalCaveatName = delta.AllowedType.GetRequiredCaveat().CaveatName
 			}
 
-			qy, qyErr := rwt.QueryRelationships(
+			qy, qyErr := reader.QueryRelationships(
 				ctx,
 				datastore.RelationshipsFilter{
 					ResourceType:             nsdef.Name,
@@ -361,7 +583,9 @@ func sanityCheckNamespaceChanges(
 				"cannot remove allowed type `%s` from relation `%s` in object definition `%s`, as a relationship exists with it",
 				namespace.SourceForAllowedRelation(delta.AllowedType), delta.RelationName, nsdef.Name)
 			if err != nil {
-				return diff, err
+				if err := report(err); err != nil {
+					return diff, err
+				}
 			}
 		}
 	}
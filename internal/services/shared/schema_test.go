@@ -9,8 +9,11 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/memdb"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
 	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 func TestApplySchemaChanges(t *testing.T) {
@@ -53,3 +56,72 @@ func TestApplySchemaChanges(t *testing.T) {
 	})
 	require.NoError(err)
 }
+
+func TestApplySchemaChangesSoftDelete(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	// Write the initial schema, with relationships for document.
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition folder {}
+
+		definition document {
+			relation viewer: user
+			permission view = viewer
+		}
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:tom"),
+	}, require)
+
+	applySchema := func(schema string, softDelete bool) (*AppliedSchemaChanges, error) {
+		emptyDefaultPrefix := ""
+		compiled, err := compiler.Compile(compiler.InputSchema{
+			Source:       input.Source("schema"),
+			SchemaString: schema,
+		}, &emptyDefaultPrefix)
+		require.NoError(err)
+
+		validated, err := ValidateSchemaChangesWithSoftDelete(context.Background(), compiled, false, softDelete)
+		require.NoError(err)
+
+		var applied *AppliedSchemaChanges
+		_, err = ds.ReadWriteTx(context.Background(), func(rwt datastore.ReadWriteTransaction) error {
+			applied, err = ApplySchemaChanges(context.Background(), rwt, validated)
+			return err
+		})
+		return applied, err
+	}
+
+	// Removing document without soft deletion fails, as relationships exist for it.
+	_, err = applySchema(`definition user {}`, false)
+	require.ErrorContains(err, "cannot delete object definition `document`, as a relationship exists under it")
+
+	// With soft deletion, document is tombstoned and folder is deleted outright.
+	applied, err := applySchema(`definition user {}`, true)
+	require.NoError(err)
+	require.Equal([]string{"folder"}, applied.RemovedObjectDefNames)
+	require.Equal([]string{"document"}, applied.TombstonedObjectDefNames)
+
+	headRevision, err := ds.HeadRevision(context.Background())
+	require.NoError(err)
+
+	nsDef, _, err := ds.SnapshotReader(headRevision).ReadNamespace(context.Background(), "document")
+	require.NoError(err)
+	require.True(nspkg.IsTombstoned(nsDef))
+
+	// Tombstoned definitions are ignored by further changes, but cannot be re-created.
+	applied, err = applySchema(`definition user {}`, true)
+	require.NoError(err)
+	require.Empty(applied.RemovedObjectDefNames)
+	require.Empty(applied.TombstonedObjectDefNames)
+
+	_, err = applySchema(`
+		definition user {}
+
+		definition document {}
+	`, true)
+	require.ErrorContains(err, "cannot re-create object definition `document`, as its relationships are still being purged")
+}
@@ -0,0 +1,153 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/spiceerrors"
+)
+
+// SchemaLimits are limits on the size of the schemas which can be written. A limit of zero
+// disables it.
+type SchemaLimits struct {
+	// MaxSchemaSize is the maximum size of the schema text, in bytes.
+	MaxSchemaSize uint32
+
+	// MaxRelationsPerDefinition is the maximum number of relations and permissions under a single
+	// object definition.
+	MaxRelationsPerDefinition uint32
+
+	// MaxUnionTermsPerPermission is the maximum number of terms in any union found in the
+	// expression of a permission, with directly nested unions counted as a single union.
+	MaxUnionTermsPerPermission uint32
+}
+
+// CheckSchemaSize returns an error if the schema text exceeds the maximum schema size.
+func (sl SchemaLimits) CheckSchemaSize(schemaText string) error {
+	if sl.MaxSchemaSize > 0 && len(schemaText) > int(sl.MaxSchemaSize) {
+		return NewSchemaLimitExceededErr(
+			"max_schema_size",
+			fmt.Sprintf("schema is %d bytes, which exceeds the maximum allowed of %d bytes", len(schemaText), sl.MaxSchemaSize),
+			len(schemaText),
+			sl.MaxSchemaSize,
+		)
+	}
+	return nil
+}
+
+// CheckDefinitions returns an error if any of the object definitions of the compiled schema
+// exceed the limits on relations and union terms.
+func (sl SchemaLimits) CheckDefinitions(compiled *compiler.CompiledSchema) error {
+	for _, nsdef := range compiled.ObjectDefinitions {
+		if sl.MaxRelationsPerDefinition > 0 && len(nsdef.Relation) > int(sl.MaxRelationsPerDefinition) {
+			return NewSchemaLimitExceededErr(
+				"max_relations_per_definition",
+				fmt.Sprintf("definition `%s` has %d relations and permissions, which exceeds the maximum allowed of %d", nsdef.Name, len(nsdef.Relation), sl.MaxRelationsPerDefinition),
+				len(nsdef.Relation),
+				sl.MaxRelationsPerDefinition,
+			)
+		}
+
+		if sl.MaxUnionTermsPerPermission == 0 {
+			continue
+		}
+
+		for _, relation := range nsdef.Relation {
+			if relation.UsersetRewrite == nil {
+				continue
+			}
+
+			if terms := maxUnionTerms(relation.UsersetRewrite); terms > int(sl.MaxUnionTermsPerPermission) {
+				return NewSchemaLimitExceededErr(
+					"max_union_terms_per_permission",
+					fmt.Sprintf("permission `%s` under definition `%s` has a union of %d terms, which exceeds the maximum allowed of %d", relation.Name, nsdef.Name, terms, sl.MaxUnionTermsPerPermission),
+					terms,
+					sl.MaxUnionTermsPerPermission,
+				)
+			}
+		}
+	}
+	return nil
+}
+
+// maxUnionTerms returns the largest number of terms found in any union in the rewrite, counting
+// the terms of unions directly nested within a union as terms of the outer union.
+func maxUnionTerms(rewrite *core.UsersetRewrite) int {
+	var children []*core.SetOperation_Child
+	largest := 0
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		children = flattenUnion(rw.Union)
+		largest = len(children)
+	case *core.UsersetRewrite_Intersection:
+		children = rw.Intersection.Child
+	case *core.UsersetRewrite_Exclusion:
+		children = rw.Exclusion.Child
+	}
+
+	for _, child := range children {
+		if nested := child.GetUsersetRewrite(); nested != nil {
+			if terms := maxUnionTerms(nested); terms > largest {
+				largest = terms
+			}
+		}
+	}
+	return largest
+}
+
+// flattenUnion returns the children of the union, with any unions directly nested within it
+// replaced by their own children.
+func flattenUnion(union *core.SetOperation) []*core.SetOperation_Child {
+	flattened := make([]*core.SetOperation_Child, 0, len(union.Child))
+	for _, child := range union.Child {
+		if nested := child.GetUsersetRewrite().GetUnion(); nested != nil {
+			flattened = append(flattened, flattenUnion(nested)...)
+			continue
+		}
+		flattened = append(flattened, child)
+	}
+	return flattened
+}
+
+// ErrSchemaLimitExceeded occurs when a schema written exceeds one of the configured SchemaLimits.
+type ErrSchemaLimitExceeded struct {
+	error
+	limit          string
+	count          int
+	maximumAllowed uint32
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrSchemaLimitExceeded) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("limit", err.limit).Int("count", err.count).Uint32("maximumAllowed", err.maximumAllowed)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrSchemaLimitExceeded) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.InvalidArgument,
+		&errdetails.BadRequest{
+			FieldViolations: []*errdetails.BadRequest_FieldViolation{
+				{Field: "schema", Description: err.limit},
+			},
+		},
+	)
+}
+
+// NewSchemaLimitExceededErr creates a new error representing that a schema exceeds the named limit.
+func NewSchemaLimitExceededErr(limit string, message string, count int, maximumAllowed uint32) ErrSchemaLimitExceeded {
+	return ErrSchemaLimitExceeded{
+		error:          errors.New(message),
+		limit:          limit,
+		count:          count,
+		maximumAllowed: maximumAllowed,
+	}
+}
@@ -0,0 +1,115 @@
+package shared
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSchemaLimits(t *testing.T) {
+	testSchema := `
+		definition user {}
+
+		definition document {
+			relation one: user
+			relation two: user
+			relation three: user
+			relation four: user
+			relation five: user
+			permission flat = one + two + (three + four)
+			permission nested = one & (two + three + four + five)
+			permission excluded = (one + two) - (three + four)
+		}
+	`
+
+	testCases := []struct {
+		name          string
+		limits        SchemaLimits
+		expectedError string
+	}{
+		{
+			"no limits",
+			SchemaLimits{},
+			"",
+		},
+		{
+			"within limits",
+			SchemaLimits{MaxSchemaSize: 4096, MaxRelationsPerDefinition: 8, MaxUnionTermsPerPermission: 4},
+			"",
+		},
+		{
+			"schema too large",
+			SchemaLimits{MaxSchemaSize: 100},
+			"schema is 325 bytes, which exceeds the maximum allowed of 100 bytes",
+		},
+		{
+			"too many relations",
+			SchemaLimits{MaxRelationsPerDefinition: 7},
+			"definition `document` has 8 relations and permissions, which exceeds the maximum allowed of 7",
+		},
+		{
+			"too many union terms",
+			SchemaLimits{MaxUnionTermsPerPermission: 3},
+			"permission `flat` under definition `document` has a union of 4 terms, which exceeds the maximum allowed of 3",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			err := tc.limits.CheckSchemaSize(testSchema)
+			if err == nil {
+				err = tc.limits.CheckDefinitions(compileSchema(require, testSchema))
+			}
+
+			if tc.expectedError == "" {
+				require.NoError(err)
+				return
+			}
+
+			require.EqualError(err, tc.expectedError)
+			grpcErr, ok := status.FromError(err)
+			require.True(ok)
+			require.Equal(codes.InvalidArgument, grpcErr.Code())
+		})
+	}
+}
+
+func TestMaxUnionTerms(t *testing.T) {
+	testCases := []struct {
+		expression    string
+		expectedTerms int
+	}{
+		{"one", 1},
+		{"one & two", 0},
+		{"one + two + (three & (four + five + six))", 3},
+		{"one + (two + (three + four))", 4},
+		{"one - (two + three + four + five + six)", 5},
+		{"(one + two) & (three + four + five)", 3},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.expression, func(t *testing.T) {
+			require := require.New(t)
+			compiled := compileSchema(require, `
+				definition document {
+					relation one: document
+					relation two: document
+					relation three: document
+					relation four: document
+					relation five: document
+					relation six: document
+					permission expr = `+tc.expression+`
+				}
+			`)
+
+			rewrite := compiled.ObjectDefinitions[0].Relation[6].UsersetRewrite
+			require.Equal(tc.expectedTerms, maxUnionTerms(rewrite))
+		})
+	}
+}
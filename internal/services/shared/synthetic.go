@@ -0,0 +1,206 @@
+package shared
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+var materializedRelationshipsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "schema",
+	Name:      "materialized_relationships_total",
+	Help:      "The number of relationships of synthetic relations written or deleted by materialization.",
+}, []string{"operation"})
+
+// SyntheticRelationMaterializer materializes the relationships of synthetic relations from those
+// of their source relations, by following the changes found in the datastore's Watch stream.
+type SyntheticRelationMaterializer struct {
+	ds datastore.Datastore
+
+	// synthetic is the signature of the synthetic relations last fully materialized.
+	synthetic string
+}
+
+// NewSyntheticRelationMaterializer creates a new materializer.
+func NewSyntheticRelationMaterializer(ds datastore.Datastore) *SyntheticRelationMaterializer {
+	return &SyntheticRelationMaterializer{ds: ds}
+}
+
+// Start materializes synthetic relations until the context is canceled. All synthetic relations
+// are fully materialized on start, whenever they are found to have changed in the schema (which
+// is checked on the provided interval) and whenever the Watch stream must be restarted. In
+// between, only the resources changed in the Watch stream are materialized.
+func (m *SyntheticRelationMaterializer) Start(ctx context.Context, interval time.Duration) error {
+	log.Ctx(ctx).Info().
+		Dur("interval", interval).
+		Msg("synthetic relation materialization worker started")
+
+	for {
+		err := m.follow(ctx, interval)
+		if errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			log.Ctx(ctx).Info().
+				Msg("shutting down synthetic relation materialization worker")
+			return ctx.Err()
+		}
+
+		log.Ctx(ctx).Warn().Err(err).
+			Msg("error materializing synthetic relations; restarting")
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// follow fully materializes all synthetic relations and then follows the Watch stream, returning
+// on the first error.
+func (m *SyntheticRelationMaterializer) follow(ctx context.Context, interval time.Duration) error {
+	revision, err := m.MaterializeAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changesChan, errChan := m.ds.Watch(watchCtx, revision, datastore.WatchOptions{})
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+
+		case err := <-errChan:
+			return err
+
+		case <-ticker.C:
+			// Synthetic relations may have been added or changed in the schema, without any
+			// relationship changes in the Watch stream.
+			if err := m.materializeChanges(ctx, nil); err != nil {
+				return err
+			}
+
+		case revisionChanges, ok := <-changesChan:
+			if !ok {
+				return errors.New("watch stream closed")
+			}
+
+			if err := m.materializeChanges(ctx, revisionChanges.Changes); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// MaterializeAll fully materializes all synthetic relations, returning the revision at which they
+// were materialized.
+func (m *SyntheticRelationMaterializer) MaterializeAll(ctx context.Context) (datastore.Revision, error) {
+	var signature string
+	var updates []*core.RelationTupleUpdate
+	revision, err := m.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		var err error
+		signature, updates, err = m.materializationUpdates(ctx, rwt, nil, true)
+		if err != nil {
+			return err
+		}
+
+		return writeMaterializedUpdates(ctx, rwt, updates)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	recordMaterializedUpdates(updates)
+	m.synthetic = signature
+	return revision, nil
+}
+
+// materializeChanges materializes the resources affected by the given changes or, if the
+// synthetic relations have changed since last materialized, all resources.
+func (m *SyntheticRelationMaterializer) materializeChanges(ctx context.Context, changes []*core.RelationTupleUpdate) error {
+	// Check for any updates at the head revision first, to avoid a write transaction when none
+	// are required, as is the case for most changes.
+	headRevision, err := m.ds.HeadRevision(ctx)
+	if err != nil {
+		return err
+	}
+
+	signature, updates, err := m.materializationUpdates(ctx, m.ds.SnapshotReader(headRevision), changes, false)
+	if err != nil {
+		return err
+	}
+
+	if len(updates) > 0 {
+		_, err = m.ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			var err error
+			signature, updates, err = m.materializationUpdates(ctx, rwt, changes, false)
+			if err != nil {
+				return err
+			}
+
+			return writeMaterializedUpdates(ctx, rwt, updates)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if signature != m.synthetic {
+		log.Ctx(ctx).Info().
+			Msg("synthetic relations changed in schema; fully materialized")
+	}
+
+	recordMaterializedUpdates(updates)
+	m.synthetic = signature
+	return nil
+}
+
+// materializationUpdates returns the signature of the synthetic relations found in the reader
+// and the updates required to materialize the resources affected by the given changes or, if
+// all is true or the synthetic relations have changed since last materialized, all resources.
+func (m *SyntheticRelationMaterializer) materializationUpdates(
+	ctx context.Context,
+	reader datastore.Reader,
+	changes []*core.RelationTupleUpdate,
+	all bool,
+) (string, []*core.RelationTupleUpdate, error) {
+	synthetic, err := relationships.ReadSyntheticRelations(ctx, reader)
+	if err != nil {
+		return "", nil, err
+	}
+
+	signature := synthetic.String()
+	if all || signature != m.synthetic {
+		updates, err := relationships.AllSyntheticRelationUpdates(ctx, reader, synthetic)
+		return signature, updates, err
+	}
+
+	updates, err := relationships.SyntheticRelationUpdates(ctx, reader, synthetic, changes)
+	return signature, updates, err
+}
+
+func writeMaterializedUpdates(ctx context.Context, rwt datastore.ReadWriteTransaction, updates []*core.RelationTupleUpdate) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	return rwt.WriteRelationships(ctx, updates)
+}
+
+func recordMaterializedUpdates(updates []*core.RelationTupleUpdate) {
+	for _, update := range updates {
+		materializedRelationshipsCounter.WithLabelValues(update.Operation.String()).Inc()
+	}
+}
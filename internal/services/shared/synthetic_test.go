@@ -0,0 +1,129 @@
+package shared
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestSyntheticRelationMaterializer(t *testing.T) {
+	require := require.New(t)
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, `
+		definition user {}
+
+		definition team {
+			relation member: user
+		}
+
+		definition document {
+			relation admin: user
+			relation viewer: user | team#member
+			relation any_member: user | team#member = admin + viewer
+			permission view = any_member
+		}
+	`, []*core.RelationTuple{
+		tuple.MustParse("document:first#admin@user:tom"),
+		tuple.MustParse("document:first#viewer@user:tom"),
+		tuple.MustParse("document:first#viewer@team:eng#member"),
+		tuple.MustParse("document:second#viewer@user:fred"),
+	}, require)
+
+	ctx := context.Background()
+	materializer := NewSyntheticRelationMaterializer(ds)
+	_, err = materializer.MaterializeAll(ctx)
+	require.NoError(err)
+
+	requireSyntheticRelationships(ctx, require, ds, []string{
+		"document:first#any_member@team:eng#member",
+		"document:first#any_member@user:tom",
+		"document:second#any_member@user:fred",
+	})
+
+	// Follow the changes to the source relations.
+	followCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- materializer.Start(followCtx, 10*time.Millisecond)
+	}()
+
+	// Removing a relationship only removes the synthetic relationship once no source relation
+	// contains it.
+	writeRelationships(ctx, require, ds,
+		tuple.Delete(tuple.MustParse("document:first#admin@user:tom")),
+		tuple.Delete(tuple.MustParse("document:second#viewer@user:fred")),
+		tuple.Touch(tuple.MustParse("document:third#admin@user:sarah")),
+	)
+
+	require.Eventually(func() bool {
+		return syntheticRelationshipsEqual(ctx, require, ds, []string{
+			"document:first#any_member@team:eng#member",
+			"document:first#any_member@user:tom",
+			"document:third#any_member@user:sarah",
+		})
+	}, 5*time.Second, 10*time.Millisecond)
+
+	writeRelationships(ctx, require, ds,
+		tuple.Delete(tuple.MustParse("document:first#viewer@user:tom")),
+	)
+
+	require.Eventually(func() bool {
+		return syntheticRelationshipsEqual(ctx, require, ds, []string{
+			"document:first#any_member@team:eng#member",
+			"document:third#any_member@user:sarah",
+		})
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.ErrorIs(<-done, context.Canceled)
+}
+
+func writeRelationships(ctx context.Context, require *require.Assertions, ds datastore.Datastore, updates ...*core.RelationTupleUpdate) {
+	_, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(ctx, updates)
+	})
+	require.NoError(err)
+}
+
+func syntheticRelationships(ctx context.Context, require *require.Assertions, ds datastore.Datastore) []string {
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	it, err := ds.SnapshotReader(headRevision).QueryRelationships(ctx, datastore.RelationshipsFilter{
+		ResourceType:             "document",
+		OptionalResourceRelation: "any_member",
+	})
+	require.NoError(err)
+	defer it.Close()
+
+	var found []string
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		found = append(found, tuple.String(tpl))
+	}
+	require.NoError(it.Err())
+
+	sort.Strings(found)
+	return found
+}
+
+func syntheticRelationshipsEqual(ctx context.Context, require *require.Assertions, ds datastore.Datastore, expected []string) bool {
+	return assert.ObjectsAreEqual(expected, syntheticRelationships(ctx, require, ds))
+}
+
+func requireSyntheticRelationships(ctx context.Context, require *require.Assertions, ds datastore.Datastore, expected []string) {
+	require.Equal(expected, syntheticRelationships(ctx, require, ds))
+}
@@ -0,0 +1,98 @@
+package v0
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/development"
+	devinterface "github.com/authzed/spicedb/pkg/proto/developer/v1"
+	"github.com/authzed/spicedb/pkg/validationfile"
+)
+
+const (
+	schemaTestPath = "/schematest"
+
+	// maxSchemaTestSize is the maximum size of a test file accepted by the schema test endpoint.
+	maxSchemaTestSize = 1 << 20
+)
+
+type schemaTestError struct {
+	Source  string `json:"source"`
+	Kind    string `json:"kind"`
+	Message string `json:"message"`
+	Line    uint32 `json:"line,omitempty"`
+	Column  uint32 `json:"column,omitempty"`
+	Context string `json:"context,omitempty"`
+}
+
+type schemaTestResponse struct {
+	Passed   bool              `json:"passed"`
+	Failures []schemaTestError `json:"failures,omitempty"`
+	Report   string            `json:"report"`
+}
+
+// SchemaTestHandler returns an HTTP handler which runs the validation file POSTed to it
+// as a schema test, returning the result as JSON.
+func SchemaTestHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc(schemaTestPath, schemaTestHandler)
+	return mux
+}
+
+func schemaTestHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	contents, err := io.ReadAll(io.LimitReader(r.Body, maxSchemaTestSize+1))
+	if err != nil {
+		http.Error(w, "could not read request body", http.StatusBadRequest)
+		return
+	}
+	if len(contents) > maxSchemaTestSize {
+		http.Error(w, "test file is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	file, err := validationfile.DecodeValidationFile(contents)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if file.SchemaFile != "" {
+		http.Error(w, "`schemaFile` is not supported; the schema must be given inline", http.StatusBadRequest)
+		return
+	}
+
+	result, err := development.RunSchemaTest(r.Context(), file)
+	if err != nil {
+		log.Ctx(r.Context()).Debug().Err(err).Msg("Schema Test Error")
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	resp := schemaTestResponse{
+		Passed: result.Passed(),
+		Report: result.Report(),
+	}
+	for _, devErrs := range [][]*devinterface.DeveloperError{result.InputErrors, result.AssertionFailures, result.ValidationFailures} {
+		for _, devErr := range devErrs {
+			resp.Failures = append(resp.Failures, schemaTestError{
+				Source:  devErr.Source.String(),
+				Kind:    devErr.Kind.String(),
+				Message: devErr.Message,
+				Line:    devErr.Line,
+				Column:  devErr.Column,
+				Context: devErr.Context,
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		log.Ctx(r.Context()).Debug().Err(err).Msg("Couldn't write as json")
+	}
+}
@@ -0,0 +1,86 @@
+package v0
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchemaTestHandler(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedCode   int
+		expectedPassed bool
+		expectedBody   string
+	}{
+		{
+			name:   "passing",
+			method: http.MethodPost,
+			body: `schema: |-
+  definition user {}
+  definition document {
+    relation viewer: user
+  }
+relationships: document:first#viewer@user:tom
+assertions:
+  assertTrue:
+    - document:first#viewer@user:tom
+`,
+			expectedCode:   http.StatusOK,
+			expectedPassed: true,
+		},
+		{
+			name:   "failing",
+			method: http.MethodPost,
+			body: `schema: |-
+  definition user {}
+  definition document {
+    relation viewer: user
+  }
+assertions:
+  assertTrue:
+    - document:first#viewer@user:tom
+`,
+			expectedCode:   http.StatusOK,
+			expectedPassed: false,
+			expectedBody:   "ASSERTION_FAILED",
+		},
+		{
+			name:         "schema file",
+			method:       http.MethodPost,
+			body:         "schemaFile: /etc/passwd\n",
+			expectedCode: http.StatusBadRequest,
+			expectedBody: "`schemaFile` is not supported",
+		},
+		{
+			name:         "invalid method",
+			method:       http.MethodGet,
+			expectedCode: http.StatusMethodNotAllowed,
+		},
+	}
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+
+			rec := httptest.NewRecorder()
+			req := httptest.NewRequest(test.method, schemaTestPath, strings.NewReader(test.body))
+			SchemaTestHandler().ServeHTTP(rec, req)
+
+			require.Equal(test.expectedCode, rec.Code)
+			require.Contains(rec.Body.String(), test.expectedBody)
+
+			if test.expectedCode == http.StatusOK {
+				var resp schemaTestResponse
+				require.NoError(json.Unmarshal(rec.Body.Bytes(), &resp))
+				require.Equal(test.expectedPassed, resp.Passed)
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/graph/computed"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RequestAdditionalChecks, if specified in the request header of a CheckPermission call, gives
+// checks evaluated along with the check of the call, with the same caveat context and at exactly
+// the same revision, which is returned as the checked_at of the call, so that several checks can
+// be compared with each other. The results are returned in the AdditionalChecksHeader response
+// header. The header may be given more than once.
+// Value: a comma-separated list of checks of the form
+// `resourcetype:resourceid#permission@subjecttype:subjectid[#subjectrelation]`, such as
+// `document:first#view@user:tom,document:second#edit@user:tom`
+const RequestAdditionalChecks requestmeta.RequestMetadataHeaderKey = "io.spicedb.additionalchecks"
+
+// AdditionalChecksHeader is the response header holding the JSON-encoded list of
+// AdditionalCheckResult of the additional checks of a CheckPermission call, in the order in which
+// they were given.
+const AdditionalChecksHeader = "io.spicedb.respmeta.additionalchecks"
+
+// maxAdditionalChecks is the maximum number of additional checks of a CheckPermission call.
+const maxAdditionalChecks = 100
+
+// AdditionalCheckResult is the result of an additional check of a CheckPermission call.
+type AdditionalCheckResult struct {
+	// Check is the check, as given in the request header.
+	Check string `json:"check"`
+
+	// Permissionship is the name of the CheckPermissionResponse permissionship of the check, such
+	// as `PERMISSIONSHIP_HAS_PERMISSION`.
+	Permissionship string `json:"permissionship"`
+
+	// MissingRequiredContext are the caveat parameters missing from the context of the call, for a
+	// conditional permission.
+	MissingRequiredContext []string `json:"missingRequiredContext,omitempty"`
+}
+
+// additionalChecks returns the additional checks of a CheckPermission call, validated against
+// the schema, or nil if the call has none.
+func additionalChecks(ctx context.Context, reader datastore.Reader) ([]string, []*core.RelationTuple, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil, nil
+	}
+
+	values := headerValues(md, RequestAdditionalChecks)
+	if len(values) > maxAdditionalChecks {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "at most %d additional checks may be given, got %d", maxAdditionalChecks, len(values))
+	}
+
+	checks := make([]*core.RelationTuple, 0, len(values))
+	for _, value := range values {
+		check := tuple.Parse(value)
+		if check == nil || check.Caveat != nil {
+			return nil, nil, status.Errorf(codes.InvalidArgument, "invalid check %q, expected `resourcetype:resourceid#permission@subjecttype:subjectid[#subjectrelation]`", value)
+		}
+
+		if err := namespace.CheckNamespaceAndRelation(ctx, check.ResourceAndRelation.Namespace, check.ResourceAndRelation.Relation, false, reader); err != nil {
+			return nil, nil, err
+		}
+		if err := namespace.CheckNamespaceAndRelation(ctx, check.Subject.Namespace, check.Subject.Relation, true, reader); err != nil {
+			return nil, nil, err
+		}
+		checks = append(checks, check)
+	}
+	return values, checks, nil
+}
+
+// computeAdditionalChecks computes the additional checks of a CheckPermission call with its
+// parameters, returning their results and the metadata of all of their dispatches.
+func (ps *permissionServer) computeAdditionalChecks(
+	ctx context.Context,
+	dispatcher dispatch.Check,
+	params computed.CheckParameters,
+	values []string,
+	checks []*core.RelationTuple,
+) ([]AdditionalCheckResult, *dispatchv1.ResponseMeta, error) {
+	totalMetadata := &dispatchv1.ResponseMeta{}
+	results := make([]AdditionalCheckResult, 0, len(checks))
+	for i, check := range checks {
+		params.ResourceType = &core.RelationReference{
+			Namespace: check.ResourceAndRelation.Namespace,
+			Relation:  check.ResourceAndRelation.Relation,
+		}
+		params.Subject = check.Subject
+
+		cr, metadata, err := computed.ComputeCheck(ctx, dispatcher, params, check.ResourceAndRelation.ObjectId)
+		if metadata != nil {
+			totalMetadata.DispatchCount += metadata.DispatchCount
+			totalMetadata.CachedDispatchCount += metadata.CachedDispatchCount
+			if metadata.DepthRequired > totalMetadata.DepthRequired {
+				totalMetadata.DepthRequired = metadata.DepthRequired
+			}
+		}
+		if err != nil {
+			return nil, totalMetadata, fmt.Errorf("error checking %s: %w", values[i], err)
+		}
+
+		permissionship := v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION
+		switch cr.Membership {
+		case dispatchv1.ResourceCheckResult_MEMBER:
+			permissionship = v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION
+		case dispatchv1.ResourceCheckResult_CAVEATED_MEMBER:
+			permissionship = v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+		}
+
+		results = append(results, AdditionalCheckResult{
+			Check:                  values[i],
+			Permissionship:         permissionship.String(),
+			MissingRequiredContext: cr.MissingExprFields,
+		})
+	}
+	return results, totalMetadata, nil
+}
+
+// additionalChecksHeader returns the response header holding the results of the additional
+// checks.
+func additionalChecksHeader(results []AdditionalCheckResult) (metadata.MD, error) {
+	encoded, err := json.Marshal(results)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Pairs(AdditionalChecksHeader, string(encoded)), nil
+}
+
+func maxUint32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
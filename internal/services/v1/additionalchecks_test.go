@@ -0,0 +1,83 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+func TestCheckPermissionAdditionalChecks(t *testing.T) {
+	testCases := []struct {
+		name           string
+		checks         []string
+		expected       []v1svc.AdditionalCheckResult
+		expectedStatus codes.Code
+	}{
+		{"none", nil, nil, codes.OK},
+		{
+			"in order",
+			[]string{"document:healthplan#view@user:chief_financial_officer, document:masterplan#view@user:product_manager", "document:masterplan#view@user:villain"},
+			[]v1svc.AdditionalCheckResult{
+				{Check: "document:healthplan#view@user:chief_financial_officer", Permissionship: "PERMISSIONSHIP_HAS_PERMISSION"},
+				{Check: "document:masterplan#view@user:product_manager", Permissionship: "PERMISSIONSHIP_HAS_PERMISSION"},
+				{Check: "document:masterplan#view@user:villain", Permissionship: "PERMISSIONSHIP_NO_PERMISSION"},
+			},
+			codes.OK,
+		},
+		{"invalid check", []string{"document:masterplan#view"}, nil, codes.InvalidArgument},
+		{"unknown permission", []string{"document:masterplan#unknown@user:villain"}, nil, codes.FailedPrecondition},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+			t.Cleanup(cleanup)
+
+			md := metadata.MD{}
+			for _, checks := range tc.checks {
+				md.Append(string(v1svc.RequestAdditionalChecks), checks)
+			}
+
+			var header metadata.MD
+			resp, err := v1.NewPermissionsServiceClient(conn).CheckPermission(metadata.NewOutgoingContext(context.Background(), md), &v1.CheckPermissionRequest{
+				Consistency: &v1.Consistency{Requirement: &v1.Consistency_MinimizeLatency{MinimizeLatency: true}},
+				Resource:    obj("document", "masterplan"),
+				Permission:  "view",
+				Subject:     sub("user", "eng_lead", ""),
+			}, grpc.Header(&header))
+			if tc.expectedStatus != codes.OK {
+				grpcutil.RequireStatus(t, tc.expectedStatus, err)
+				return
+			}
+			require.NoError(err)
+			require.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, resp.Permissionship)
+			require.NotNil(resp.CheckedAt)
+
+			values := header.Get(v1svc.AdditionalChecksHeader)
+			if tc.expected == nil {
+				require.Empty(values)
+				return
+			}
+
+			require.Len(values, 1)
+			var results []v1svc.AdditionalCheckResult
+			require.NoError(json.Unmarshal([]byte(values[0]), &results))
+			require.Equal(tc.expected, results)
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package v1
+
+import (
+	"context"
+	"strings"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/graph/computed"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RequestLookupCandidates, if specified in the request header of a LookupResources call, restricts
+// the call to the given candidate resources, such as the results of a search, returning those on
+// which the subject has the permission in the order in which they were given. The candidates are
+// checked in bulk rather than looked up, which is far cheaper when there are few of them compared to
+// the resources reachable by the subject. The header may be given more than once.
+// Value: a comma-separated list of resource IDs, such as `firstdoc,seconddoc`
+const RequestLookupCandidates requestmeta.RequestMetadataHeaderKey = "io.spicedb.lookupcandidates"
+
+const (
+	// maxLookupCandidates is the maximum number of candidate resources of a LookupResources call.
+	maxLookupCandidates = 10_000
+
+	// lookupCandidatesBatchSize is the number of candidate resources checked together, and
+	// therefore the number of results computed before any is returned.
+	lookupCandidatesBatchSize = 100
+)
+
+// lookupCandidates returns the candidate resources of a LookupResources call, without
+// duplicates, or nil if the call has none.
+func lookupCandidates(ctx context.Context) ([]string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	values := md.Get(string(RequestLookupCandidates))
+	if len(values) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[string]struct{})
+	candidates := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, resourceID := range strings.Split(value, ",") {
+			resourceID = strings.TrimSpace(resourceID)
+			if err := tuple.ValidateResourceID(resourceID); err != nil {
+				return nil, status.Errorf(codes.InvalidArgument, "invalid candidate resource %q: %s", resourceID, err)
+			}
+
+			if _, ok := seen[resourceID]; ok {
+				continue
+			}
+			seen[resourceID] = struct{}{}
+			candidates = append(candidates, resourceID)
+		}
+	}
+
+	if len(candidates) > maxLookupCandidates {
+		return nil, status.Errorf(codes.InvalidArgument, "at most %d candidate resources may be given, got %d", maxLookupCandidates, len(candidates))
+	}
+	return candidates, nil
+}
+
+// lookupResourcesFromCandidates checks the subject of a LookupResources call against its
+// candidate resources in batches, streaming those with the permission in order.
+func (ps *permissionServer) lookupResourcesFromCandidates(
+	ctx context.Context,
+	dispatcher dispatch.Check,
+	req *v1.LookupResourcesRequest,
+	resp v1.PermissionsService_LookupResourcesServer,
+	limiter *streamLimiter,
+	atRevision datastore.Revision,
+	revisionReadAt *v1.ZedToken,
+	fieldMask responseFieldMask,
+	candidates []string,
+) error {
+	caveatContext, err := getCaveatContext(ctx, req.Context)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	params := computed.CheckParameters{
+		ResourceType: &core.RelationReference{
+			Namespace: req.ResourceObjectType,
+			Relation:  req.Permission,
+		},
+		Subject: &core.ObjectAndRelation{
+			Namespace: req.Subject.Object.ObjectType,
+			ObjectId:  req.Subject.Object.ObjectId,
+			Relation:  normalizeSubjectRelation(req.Subject),
+		},
+		CaveatContext: caveatContext,
+		AtRevision:    atRevision,
+		MaximumDepth:  ps.config.MaximumAPIDepth,
+	}
+
+	totalMetadata := &dispatchv1.ResponseMeta{}
+	defer usagemetrics.SetInContext(ctx, totalMetadata)
+
+	var conditional []string
+	for len(candidates) > 0 {
+		batch := candidates
+		if len(batch) > lookupCandidatesBatchSize {
+			batch = batch[:lookupCandidatesBatchSize]
+		}
+		candidates = candidates[len(batch):]
+
+		results, metadata, err := computed.ComputeBulkCheck(ctx, dispatcher, params, batch)
+		if metadata != nil {
+			totalMetadata.DispatchCount += metadata.DispatchCount
+			totalMetadata.CachedDispatchCount += metadata.CachedDispatchCount
+			if metadata.DepthRequired > totalMetadata.DepthRequired {
+				totalMetadata.DepthRequired = metadata.DepthRequired
+			}
+		}
+		if err != nil {
+			return rewriteError(ctx, err)
+		}
+
+		for _, resourceID := range batch {
+			var partial *v1.PartialCaveatInfo
+			permissionship := v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION
+			switch result := results[resourceID]; result.Membership {
+			case dispatchv1.ResourceCheckResult_MEMBER:
+			case dispatchv1.ResourceCheckResult_CAVEATED_MEMBER:
+				permissionship = v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_CONDITIONAL_PERMISSION
+				partial = &v1.PartialCaveatInfo{
+					MissingRequiredContext: result.MissingExprFields,
+				}
+				conditional = append(conditional, resourceID)
+			default:
+				continue
+			}
+
+			response := &v1.LookupResourcesResponse{
+				LookedUpAt:        revisionReadAt,
+				ResourceObjectId:  resourceID,
+				Permissionship:    permissionship,
+				PartialCaveatInfo: partial,
+			}
+			fieldMask.apply(response)
+
+			err := limiter.send(response)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if caveatProjectionRequested(ctx) {
+		if err := ps.setCaveatProjectionTrailer(ctx, dispatcher, req, resp, atRevision, conditional); err != nil {
+			return rewriteError(ctx, err)
+		}
+	}
+	return nil
+}
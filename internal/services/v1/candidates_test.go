@@ -0,0 +1,76 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+func TestLookupResourcesWithCandidates(t *testing.T) {
+	testCases := []struct {
+		name           string
+		candidates     []string
+		expected       []string
+		expectedStatus codes.Code
+	}{
+		{"in order", []string{"healthplan,companyplan,masterplan"}, []string{"healthplan", "masterplan"}, codes.OK},
+		{"reversed", []string{"masterplan,companyplan,healthplan"}, []string{"masterplan", "healthplan"}, codes.OK},
+		{"across headers", []string{"masterplan", "unknown, healthplan"}, []string{"masterplan", "healthplan"}, codes.OK},
+		{"duplicates", []string{"masterplan,healthplan,masterplan"}, []string{"masterplan", "healthplan"}, codes.OK},
+		{"none permitted", []string{"companyplan,specialplan"}, nil, codes.OK},
+		{"invalid", []string{"masterplan,,healthplan"}, nil, codes.InvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+			t.Cleanup(cleanup)
+			client := v1.NewPermissionsServiceClient(conn)
+
+			md := metadata.MD{}
+			for _, candidates := range tc.candidates {
+				md.Append(string(v1svc.RequestLookupCandidates), candidates)
+			}
+
+			stream, err := client.LookupResources(metadata.NewOutgoingContext(context.Background(), md), &v1.LookupResourcesRequest{
+				Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+				ResourceObjectType: "document",
+				Permission:         "view",
+				Subject:            sub("user", "chief_financial_officer", ""),
+			})
+			require.NoError(err)
+
+			var resourceIDs []string
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if tc.expectedStatus != codes.OK {
+					grpcutil.RequireStatus(t, tc.expectedStatus, err)
+					return
+				}
+				require.NoError(err)
+				require.Equal(v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION, resp.Permissionship)
+				resourceIDs = append(resourceIDs, resp.ResourceObjectId)
+			}
+			require.Equal(codes.OK, tc.expectedStatus)
+			require.Equal(tc.expected, resourceIDs)
+		})
+	}
+}
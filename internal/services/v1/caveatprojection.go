@@ -0,0 +1,152 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+// RequestLookupCaveatProjection, if specified in the request header of a LookupResources call,
+// asks SpiceDB to also return the caveats responsible for each conditional result, with the
+// context stored on their relationships, in the CaveatProjectionTrailer response trailer, so that
+// callers can collect the missing context without reading the relationships.
+// Value: `1`
+const RequestLookupCaveatProjection requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.lookupcaveatprojection"
+
+// CaveatProjectionTrailer is the response trailer holding the JSON-encoded list of
+// ProjectedResource describing the caveats of the conditional results of a LookupResources
+// call, in the order in which the results were returned.
+const CaveatProjectionTrailer = "io.spicedb.respmeta.caveatprojection"
+
+// ProjectedResource describes the caveats on which the permission of a conditional result of
+// LookupResources depends.
+type ProjectedResource struct {
+	// ResourceObjectID is the ID of the resource.
+	ResourceObjectID string `json:"resourceObjectId"`
+
+	// Caveats are the caveats of the relationships through which the subject has the permission,
+	// without duplicates.
+	Caveats []ProjectedCaveat `json:"caveats"`
+}
+
+// ProjectedCaveat describes a caveat of a relationship.
+type ProjectedCaveat struct {
+	// Name is the name of the caveat.
+	Name string `json:"name"`
+
+	// Context is the context written with the relationship, if any.
+	Context map[string]any `json:"context,omitempty"`
+}
+
+// caveatProjectionRequested returns whether the caveats of the conditional results of a
+// LookupResources call were requested.
+func caveatProjectionRequested(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	_, requested := md[string(RequestLookupCaveatProjection)]
+	return requested
+}
+
+// setCaveatProjectionTrailer checks the subject of a LookupResources call against its conditional
+// results in batches, without context, and returns the caveats found in the resulting caveat
+// expressions in the CaveatProjectionTrailer response trailer.
+func (ps *permissionServer) setCaveatProjectionTrailer(
+	ctx context.Context,
+	dispatcher dispatch.Check,
+	req *v1.LookupResourcesRequest,
+	resp v1.PermissionsService_LookupResourcesServer,
+	atRevision datastore.Revision,
+	conditional []string,
+) error {
+	projected := make([]ProjectedResource, 0, len(conditional))
+	for len(conditional) > 0 {
+		batch := conditional
+		if len(batch) > lookupCandidatesBatchSize {
+			batch = batch[:lookupCandidatesBatchSize]
+		}
+		conditional = conditional[len(batch):]
+
+		checkResp, err := dispatcher.DispatchCheck(ctx, &dispatchv1.DispatchCheckRequest{
+			ResourceRelation: &core.RelationReference{
+				Namespace: req.ResourceObjectType,
+				Relation:  req.Permission,
+			},
+			ResourceIds:    batch,
+			ResultsSetting: dispatchv1.DispatchCheckRequest_REQUIRE_ALL_RESULTS,
+			Subject: &core.ObjectAndRelation{
+				Namespace: req.Subject.Object.ObjectType,
+				ObjectId:  req.Subject.Object.ObjectId,
+				Relation:  normalizeSubjectRelation(req.Subject),
+			},
+			Metadata: &dispatchv1.ResolverMeta{
+				AtRevision:     atRevision.String(),
+				DepthRemaining: ps.config.MaximumAPIDepth,
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, resourceID := range batch {
+			caveats := []ProjectedCaveat{}
+			seen := make(map[string]struct{})
+			collectCaveats(checkResp.ResultsByResourceId[resourceID].GetExpression(), seen, &caveats)
+			projected = append(projected, ProjectedResource{ResourceObjectID: resourceID, Caveats: caveats})
+		}
+	}
+
+	encoded, err := json.Marshal(projected)
+	if err != nil {
+		return err
+	}
+	resp.SetTrailer(metadata.Pairs(CaveatProjectionTrailer, string(encoded)))
+	return nil
+}
+
+// collectCaveats appends the caveats found in the caveat expression which are not yet seen.
+func collectCaveats(expr *dispatchv1.CaveatExpression, seen map[string]struct{}, caveats *[]ProjectedCaveat) {
+	if expr == nil {
+		return
+	}
+
+	if caveat := expr.GetCaveat(); caveat != nil {
+		projected := projectCaveat(caveat)
+
+		// Caveats are identified by their name and context, which encodes to the same JSON
+		// whatever the order of its fields.
+		key, err := json.Marshal(projected)
+		if err != nil {
+			return
+		}
+		if _, ok := seen[string(key)]; ok {
+			return
+		}
+		seen[string(key)] = struct{}{}
+		*caveats = append(*caveats, projected)
+		return
+	}
+
+	for _, child := range expr.GetOperation().GetChildren() {
+		collectCaveats(child, seen, caveats)
+	}
+}
+
+// projectCaveat returns the description of the caveat of a relationship.
+func projectCaveat(caveat *core.ContextualizedCaveat) ProjectedCaveat {
+	projected := ProjectedCaveat{Name: caveat.CaveatName}
+	if len(caveat.Context.GetFields()) > 0 {
+		projected.Context = caveat.Context.AsMap()
+	}
+	return projected
+}
@@ -0,0 +1,117 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestLookupResourcesCaveatProjection(t *testing.T) {
+	testCases := []struct {
+		name       string
+		candidates string
+	}{
+		{"lookup", ""},
+		{"candidates", "first,second,third,fourth"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := require.New(t)
+
+			conn, cleanup, _, _ := testserver.NewTestServer(req, 0, memdb.DisableGC, true,
+				func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+					return testfixtures.DatastoreFromSchemaAndTestRelationships(ds, `
+						definition user {}
+
+						caveat ip_allowed(ip string, allowed string) {
+							ip == allowed
+						}
+
+						caveat on_weekday(day int) {
+							day < 6
+						}
+
+						definition document {
+							relation viewer: user | user with ip_allowed | user with on_weekday
+							relation editor: user with ip_allowed
+							permission view = viewer + editor
+						}
+					`, []*core.RelationTuple{
+						tuple.MustParse("document:first#viewer@user:tom"),
+						withCaveatContext(t, "document:second#viewer@user:tom", "ip_allowed", map[string]any{"allowed": "10.0.0.1"}),
+						tuple.WithCaveat(tuple.MustParse("document:third#viewer@user:tom"), "on_weekday"),
+						withCaveatContext(t, "document:third#editor@user:tom", "ip_allowed", map[string]any{"allowed": "10.0.0.2"}),
+					}, require)
+				})
+			t.Cleanup(cleanup)
+
+			md := metadata.Pairs(string(v1svc.RequestLookupCaveatProjection), "1")
+			if tc.candidates != "" {
+				md.Append(string(v1svc.RequestLookupCandidates), tc.candidates)
+			}
+
+			stream, err := v1.NewPermissionsServiceClient(conn).LookupResources(metadata.NewOutgoingContext(context.Background(), md), &v1.LookupResourcesRequest{
+				Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+				ResourceObjectType: "document",
+				Permission:         "view",
+				Subject:            sub("user", "tom", ""),
+			})
+			req.NoError(err)
+
+			for {
+				_, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				req.NoError(err)
+			}
+
+			values := stream.Trailer().Get(v1svc.CaveatProjectionTrailer)
+			req.Len(values, 1)
+
+			var projected []v1svc.ProjectedResource
+			req.NoError(json.Unmarshal([]byte(values[0]), &projected))
+			sort.Slice(projected, func(i, j int) bool { return projected[i].ResourceObjectID < projected[j].ResourceObjectID })
+			for _, resource := range projected {
+				sort.Slice(resource.Caveats, func(i, j int) bool { return resource.Caveats[i].Name < resource.Caveats[j].Name })
+			}
+
+			req.Equal([]v1svc.ProjectedResource{
+				{ResourceObjectID: "second", Caveats: []v1svc.ProjectedCaveat{
+					{Name: "ip_allowed", Context: map[string]any{"allowed": "10.0.0.1"}},
+				}},
+				{ResourceObjectID: "third", Caveats: []v1svc.ProjectedCaveat{
+					{Name: "ip_allowed", Context: map[string]any{"allowed": "10.0.0.2"}},
+					{Name: "on_weekday"},
+				}},
+			}, projected)
+		})
+	}
+}
+
+func withCaveatContext(t *testing.T, relationship string, caveatName string, context map[string]any) *core.RelationTuple {
+	caveatContext, err := structpb.NewStruct(context)
+	require.NoError(t, err)
+
+	tpl := tuple.MustParse(relationship)
+	tpl.Caveat = &core.ContextualizedCaveat{CaveatName: caveatName, Context: caveatContext}
+	return tpl
+}
@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"context"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/internal/checktraces"
+	"github.com/authzed/spicedb/internal/redaction"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// sampledCheckTrace returns the trace of a sampled CheckPermission call, to be shipped by the
+// configured check trace shipper.
+func sampledCheckTrace(
+	ctx context.Context,
+	req *v1.CheckPermissionRequest,
+	checkedAt *v1.ZedToken,
+	permissionship v1.CheckPermissionResponse_Permissionship,
+	duration time.Duration,
+	metadata *dispatch.ResponseMeta,
+	debug *v1.DebugInformation,
+) checktraces.Trace {
+	sampled := checktraces.Trace{
+		Time:                time.Now(),
+		Resource:            redaction.Text(tuple.StringObjectRef(req.Resource)) + "#" + req.Permission,
+		Subject:             redaction.Text(tuple.StringSubjectRef(req.Subject)),
+		Result:              permissionship.String(),
+		Revision:            checkedAt.GetToken(),
+		Duration:            duration,
+		DispatchCount:       metadata.DispatchCount,
+		CachedDispatchCount: metadata.CachedDispatchCount,
+		DepthRequired:       metadata.DepthRequired,
+		Debug:               redactedDebugInformation(debug),
+	}
+
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		traceID, spanID := spanContext.TraceID(), spanContext.SpanID()
+		sampled.TraceID, sampled.SpanID = traceID[:], spanID[:]
+	}
+	return sampled
+}
+
+// redactedDebugInformation returns the debug information of a check with the IDs of its objects
+// redacted, if redaction is enabled.
+func redactedDebugInformation(debug *v1.DebugInformation) *v1.DebugInformation {
+	if debug == nil || !redaction.Enabled() {
+		return debug
+	}
+
+	redacted := proto.Clone(debug).(*v1.DebugInformation)
+	redactCheckDebugTrace(redacted.Check)
+	return redacted
+}
+
+func redactCheckDebugTrace(check *v1.CheckDebugTrace) {
+	if check == nil {
+		return
+	}
+
+	if check.Resource != nil {
+		check.Resource.ObjectId = redaction.ID(check.Resource.ObjectId)
+	}
+	if check.Subject.GetObject() != nil {
+		check.Subject.Object.ObjectId = redaction.ID(check.Subject.Object.ObjectId)
+	}
+	for _, subProblem := range check.GetSubProblems().GetTraces() {
+		redactCheckDebugTrace(subProblem)
+	}
+}
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/redaction"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func TestSampledCheckTraceRedaction(t *testing.T) {
+	require := require.New(t)
+
+	redaction.SetMode(redaction.ModeHash)
+	defer redaction.SetMode(redaction.ModeNone)
+
+	subject := &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "tom"}}
+	debug := &v1.DebugInformation{
+		Check: &v1.CheckDebugTrace{
+			Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "first"},
+			Permission: "view",
+			Subject:    subject,
+			Resolution: &v1.CheckDebugTrace_SubProblems_{SubProblems: &v1.CheckDebugTrace_SubProblems{
+				Traces: []*v1.CheckDebugTrace{{
+					Resource:   &v1.ObjectReference{ObjectType: "folder", ObjectId: "parent"},
+					Permission: "view",
+					Subject:    subject,
+				}},
+			}},
+		},
+	}
+
+	sampled := sampledCheckTrace(context.Background(), &v1.CheckPermissionRequest{
+		Resource:   &v1.ObjectReference{ObjectType: "document", ObjectId: "first"},
+		Permission: "view",
+		Subject:    subject,
+	}, nil, v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, time.Millisecond, &dispatch.ResponseMeta{}, debug)
+
+	require.Equal("document:"+redaction.Fingerprint("first")+"#view", sampled.Resource)
+	require.Equal("user:"+redaction.Fingerprint("tom"), sampled.Subject)
+	require.Equal(redaction.Fingerprint("first"), sampled.Debug.Check.Resource.ObjectId)
+	require.Equal(redaction.Fingerprint("tom"), sampled.Debug.Check.Subject.Object.ObjectId)
+	require.Equal(redaction.Fingerprint("parent"), sampled.Debug.Check.GetSubProblems().Traces[0].Resource.ObjectId)
+
+	// The debug information returned to the caller is left as is.
+	require.Equal("first", debug.Check.Resource.ObjectId)
+	require.Equal("tom", subject.Object.ObjectId)
+}
@@ -0,0 +1,93 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RequestDeleteAdditionalFilters, if specified in the request header of a DeleteRelationships
+// call, gives relationship filters whose relationships are deleted along with those of the
+// relationship filter of the call, in the same transaction and subject to the same
+// preconditions. The header may be given more than once. The number of filters of a call is
+// limited to the maximum number of updates of a WriteRelationships call.
+// Value: a comma-separated list of filters of the form
+// `resourcetype[:resourceid][#relation][@subjecttype[:subjectid][#subjectrelation]]`, such as
+// `team:first#member@user:tom,team:second#member@user:tom`
+const RequestDeleteAdditionalFilters requestmeta.RequestMetadataHeaderKey = "io.spicedb.deleteadditionalfilters"
+
+// deleteFilters returns the relationship filters of a DeleteRelationships call: its relationship
+// filter, followed by any additional filters given in its header.
+func (ps *permissionServer) deleteFilters(ctx context.Context, req *v1.DeleteRelationshipsRequest) ([]*v1.RelationshipFilter, error) {
+	filters := []*v1.RelationshipFilter{req.RelationshipFilter}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return filters, nil
+	}
+
+	for _, value := range headerValues(md, RequestDeleteAdditionalFilters) {
+		filter, err := parseRelationshipFilter(value)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid relationship filter %q: %s", value, err)
+		}
+		filters = append(filters, filter)
+	}
+
+	if len(filters) > int(ps.config.MaxUpdatesPerWrite) {
+		return nil, status.Errorf(codes.InvalidArgument, "filter count of %d is greater than maximum allowed of %d", len(filters), ps.config.MaxUpdatesPerWrite)
+	}
+	return filters, nil
+}
+
+// parseRelationshipFilter parses a relationship filter of the form
+// `resourcetype[:resourceid][#relation][@subjecttype[:subjectid][#subjectrelation]]`.
+func parseRelationshipFilter(value string) (*v1.RelationshipFilter, error) {
+	resource, subject, hasSubject := strings.Cut(value, "@")
+
+	resourceType, resourceID, relation := parseFilterComponent(resource)
+	filter := &v1.RelationshipFilter{
+		ResourceType:       resourceType,
+		OptionalResourceId: resourceID,
+		OptionalRelation:   relation,
+	}
+
+	if hasSubject {
+		subjectType, subjectID, subjectRelation := parseFilterComponent(subject)
+		filter.OptionalSubjectFilter = &v1.SubjectFilter{
+			SubjectType:       subjectType,
+			OptionalSubjectId: subjectID,
+		}
+		if strings.Contains(subject, "#") {
+			if subjectRelation == tuple.Ellipsis {
+				subjectRelation = ""
+			}
+			filter.OptionalSubjectFilter.OptionalRelation = &v1.SubjectFilter_RelationFilter{
+				Relation: subjectRelation,
+			}
+		}
+	}
+
+	if err := filter.Validate(); err != nil {
+		return nil, err
+	}
+	if filter.ResourceType == "" || (filter.OptionalSubjectFilter != nil && filter.OptionalSubjectFilter.SubjectType == "") {
+		return nil, fmt.Errorf("object types must be given")
+	}
+	return filter, nil
+}
+
+// parseFilterComponent parses the `type[:id][#relation]` component of a relationship filter.
+func parseFilterComponent(value string) (string, string, string) {
+	object, relation, _ := strings.Cut(value, "#")
+	objectType, objectID, _ := strings.Cut(object, ":")
+	return objectType, objectID, relation
+}
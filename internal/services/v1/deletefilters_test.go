@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestParseRelationshipFilter(t *testing.T) {
+	testCases := []struct {
+		value         string
+		expected      *v1.RelationshipFilter
+		expectedError bool
+	}{
+		{"document", &v1.RelationshipFilter{ResourceType: "document"}, false},
+		{"document:first", &v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "first"}, false},
+		{"document#viewer", &v1.RelationshipFilter{ResourceType: "document", OptionalRelation: "viewer"}, false},
+		{
+			"document:first#viewer@user:tom",
+			&v1.RelationshipFilter{
+				ResourceType:          "document",
+				OptionalResourceId:    "first",
+				OptionalRelation:      "viewer",
+				OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: "tom"},
+			},
+			false,
+		},
+		{
+			"document#viewer@team#member",
+			&v1.RelationshipFilter{
+				ResourceType:     "document",
+				OptionalRelation: "viewer",
+				OptionalSubjectFilter: &v1.SubjectFilter{
+					SubjectType:      "team",
+					OptionalRelation: &v1.SubjectFilter_RelationFilter{Relation: "member"},
+				},
+			},
+			false,
+		},
+		{
+			"document@user#...",
+			&v1.RelationshipFilter{
+				ResourceType: "document",
+				OptionalSubjectFilter: &v1.SubjectFilter{
+					SubjectType:      "user",
+					OptionalRelation: &v1.SubjectFilter_RelationFilter{},
+				},
+			},
+			false,
+		},
+		{"", nil, true},
+		{":first", nil, true},
+		{"document@", nil, true},
+		{"document@:tom", nil, true},
+		{"Document", nil, true},
+		{"document:first doc", nil, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.value, func(t *testing.T) {
+			require := require.New(t)
+			filter, err := parseRelationshipFilter(tc.value)
+			if tc.expectedError {
+				require.Error(err)
+				return
+			}
+			require.NoError(err)
+			require.True(proto.Equal(tc.expected, filter), "expected %v, got %v", tc.expected, filter)
+		})
+	}
+}
@@ -0,0 +1,135 @@
+package v1
+
+import (
+	"context"
+	"errors"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/middleware"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/datastore"
+	jobsv1 "github.com/authzed/spicedb/pkg/proto/jobs/v1"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+type deleteJobServer struct {
+	jobsv1.UnimplementedDeleteJobServiceServer
+	shared.WithUnaryServiceSpecificInterceptor
+
+	jobs       *shared.DeleteJobs
+	maxFilters uint16
+}
+
+// NewDeleteJobServer creates a DeleteJobServiceServer instance, running the jobs with the
+// DeleteJobs of the config, which must not be nil. The jobs belong to the caller which submitted
+// them, as identified by auth.CallerFromContext.
+func NewDeleteJobServer(config PermissionsServerConfig) jobsv1.DeleteJobServiceServer {
+	return &deleteJobServer{
+		WithUnaryServiceSpecificInterceptor: shared.WithUnaryServiceSpecificInterceptor{
+			Unary: middleware.ChainUnaryServer(
+				grpcvalidate.UnaryServerInterceptor(true),
+				usagemetrics.UnaryServerInterceptor(),
+			),
+		},
+		jobs:       config.DeleteJobs,
+		maxFilters: defaultIfZero(config.MaxUpdatesPerWrite, 1000),
+	}
+}
+
+func (djs *deleteJobServer) SubmitDeleteJob(ctx context.Context, req *jobsv1.SubmitDeleteJobRequest) (*jobsv1.SubmitDeleteJobResponse, error) {
+	if len(req.RelationshipFilters) > int(djs.maxFilters) {
+		return nil, status.Errorf(codes.InvalidArgument, "filter count of %d is greater than maximum allowed of %d", len(req.RelationshipFilters), djs.maxFilters)
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+	headRevision, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	filters, err := deleteJobFilters(ctx, ds.SnapshotReader(headRevision), req.RelationshipFilters)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	jobStatus, err := djs.jobs.Submit(ds, filters, auth.CallerFromContext(ctx))
+	if errors.Is(err, shared.ErrDeleteJobsNotRunning) {
+		return nil, status.Errorf(codes.Unavailable, "%s", err)
+	} else if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &jobsv1.SubmitDeleteJobResponse{
+		Job:         deleteJobToProto(jobStatus),
+		SubmittedAt: zedtoken.NewFromRevisionForDatastore(headRevision, datastoremw.UniqueIDFromContext(ctx)),
+	}, nil
+}
+
+func (djs *deleteJobServer) GetDeleteJob(ctx context.Context, req *jobsv1.GetDeleteJobRequest) (*jobsv1.GetDeleteJobResponse, error) {
+	jobStatus, ok := djs.jobs.Status(req.JobId, auth.CallerFromContext(ctx))
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "delete job %s not found", req.JobId)
+	}
+	return &jobsv1.GetDeleteJobResponse{Job: deleteJobToProto(jobStatus)}, nil
+}
+
+func (djs *deleteJobServer) CancelDeleteJob(ctx context.Context, req *jobsv1.CancelDeleteJobRequest) (*jobsv1.CancelDeleteJobResponse, error) {
+	jobStatus, ok := djs.jobs.Cancel(req.JobId, auth.CallerFromContext(ctx))
+	if !ok {
+		return nil, status.Errorf(codes.NotFound, "delete job %s not found", req.JobId)
+	}
+	return &jobsv1.CancelDeleteJobResponse{Job: deleteJobToProto(jobStatus)}, nil
+}
+
+// deleteJobFilters checks the filters of a delete job against the schema, returning the
+// datastore filters deleting the relationships matching them, along with those of any relations
+// being renamed.
+func deleteJobFilters(ctx context.Context, reader datastore.Reader, publicFilters []*v1.RelationshipFilter) ([]datastore.RelationshipsFilter, error) {
+	filters := make([]datastore.RelationshipsFilter, 0, len(publicFilters))
+	for _, publicFilter := range publicFilters {
+		if err := checkFilterNamespaces(ctx, publicFilter, reader); err != nil {
+			return nil, err
+		}
+
+		// Keep any relations being renamed in sync with their new names.
+		renamedFilters, err := relationships.RenamedRelationFilters(ctx, reader, publicFilter)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, filter := range append([]*v1.RelationshipFilter{publicFilter}, renamedFilters...) {
+			filters = append(filters, datastore.RelationshipsFilterFromPublicFilter(filter))
+		}
+	}
+	return filters, nil
+}
+
+var deleteJobStates = map[shared.DeleteJobState]jobsv1.DeleteJob_State{
+	shared.DeleteJobRunning:   jobsv1.DeleteJob_RUNNING,
+	shared.DeleteJobCompleted: jobsv1.DeleteJob_COMPLETED,
+	shared.DeleteJobCanceled:  jobsv1.DeleteJob_CANCELED,
+	shared.DeleteJobFailed:    jobsv1.DeleteJob_FAILED,
+}
+
+func deleteJobToProto(jobStatus shared.DeleteJobStatus) *jobsv1.DeleteJob {
+	job := &jobsv1.DeleteJob{
+		JobId:                jobStatus.ID,
+		State:                deleteJobStates[jobStatus.State],
+		RelationshipsDeleted: jobStatus.RelationshipsDeleted,
+		SubmittedAt:          timestamppb.New(jobStatus.SubmittedAt),
+		Error:                jobStatus.Error,
+	}
+	if !jobStatus.FinishedAt.IsZero() {
+		job.FinishedAt = timestamppb.New(jobStatus.FinishedAt)
+	}
+	return job
+}
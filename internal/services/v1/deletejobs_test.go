@@ -0,0 +1,75 @@
+package v1_test
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	jobsv1 "github.com/authzed/spicedb/pkg/proto/jobs/v1"
+)
+
+func TestDeleteJobService(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+	client := jobsv1.NewDeleteJobServiceClient(conn)
+
+	ownerCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer owner")
+	otherCtx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "bearer other")
+
+	_, err := client.SubmitDeleteJob(ownerCtx, &jobsv1.SubmitDeleteJobRequest{})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, err = client.SubmitDeleteJob(ownerCtx, &jobsv1.SubmitDeleteJobRequest{
+		RelationshipFilters: []*v1.RelationshipFilter{{ResourceType: "unknown"}},
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	filter := &v1.RelationshipFilter{ResourceType: "document", OptionalRelation: "viewer"}
+	submitted, err := client.SubmitDeleteJob(ownerCtx, &jobsv1.SubmitDeleteJobRequest{
+		RelationshipFilters: []*v1.RelationshipFilter{filter},
+	})
+	require.NoError(err)
+	require.NotNil(submitted.SubmittedAt)
+	jobID := submitted.Job.JobId
+
+	require.Eventually(func() bool {
+		resp, err := client.GetDeleteJob(ownerCtx, &jobsv1.GetDeleteJobRequest{JobId: jobID})
+		require.NoError(err)
+		return resp.Job.State == jobsv1.DeleteJob_COMPLETED
+	}, 5*time.Second, 10*time.Millisecond)
+
+	stream, err := v1.NewPermissionsServiceClient(conn).ReadRelationships(context.Background(), &v1.ReadRelationshipsRequest{
+		Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+		RelationshipFilter: filter,
+	})
+	require.NoError(err)
+	_, err = stream.Recv()
+	require.ErrorIs(err, io.EOF)
+
+	// The jobs of other callers are not found, and so cannot be canceled by them.
+	_, err = client.GetDeleteJob(otherCtx, &jobsv1.GetDeleteJobRequest{JobId: jobID})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+	_, err = client.CancelDeleteJob(otherCtx, &jobsv1.CancelDeleteJobRequest{JobId: jobID})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+
+	canceled, err := client.CancelDeleteJob(ownerCtx, &jobsv1.CancelDeleteJobRequest{JobId: jobID})
+	require.NoError(err)
+	require.Equal(jobsv1.DeleteJob_COMPLETED, canceled.Job.State)
+	require.NotZero(canceled.Job.RelationshipsDeleted)
+	require.NotNil(canceled.Job.FinishedAt)
+
+	_, err = client.GetDeleteJob(ownerCtx, &jobsv1.GetDeleteJobRequest{JobId: "unknown"})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+}
@@ -0,0 +1,124 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+// DeprecationWarningHeader is the response header set, once per referenced element, when a request
+// references a relation or permission that has been marked as deprecated in the schema.
+const DeprecationWarningHeader = "io.spicedb.respmeta.deprecationwarning"
+
+// RequestReflectDeprecations, if specified in the request header of a ReadSchema call, asks
+// SpiceDB to also return the definitions, relations and permissions marked as deprecated in the
+// schema, in the DeprecationsReflectionHeader response header.
+// Value: `1`
+const RequestReflectDeprecations requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.reflectdeprecations"
+
+// DeprecationsReflectionHeader is the response header holding the JSON-encoded list of
+// ReflectedDeprecation describing the deprecated elements of the schema, sorted by definition
+// and then in the order of the relations and permissions within it.
+const DeprecationsReflectionHeader = "io.spicedb.respmeta.deprecations"
+
+// ReflectedDeprecation describes a definition, relation or permission marked as deprecated.
+type ReflectedDeprecation struct {
+	// Definition is the name of the deprecated definition, or of that of the deprecated relation
+	// or permission.
+	Definition string `json:"definition"`
+
+	// Relation is the name of the deprecated relation or permission. It is empty when the
+	// definition itself is deprecated.
+	Relation string `json:"relation,omitempty"`
+
+	// Reason is the reason given for the deprecation, which may be empty.
+	Reason string `json:"reason"`
+}
+
+// reflectedDeprecationsHeader returns the response header describing the deprecated elements of
+// the given definitions.
+func reflectedDeprecationsHeader(nsDefs []*core.NamespaceDefinition) (metadata.MD, error) {
+	sorted := make([]*core.NamespaceDefinition, len(nsDefs))
+	copy(sorted, nsDefs)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Name < sorted[j].Name })
+
+	reflected := make([]ReflectedDeprecation, 0)
+	for _, nsDef := range sorted {
+		if reason, ok := nspkg.GetDeprecation(nsDef.Metadata); ok {
+			reflected = append(reflected, ReflectedDeprecation{Definition: nsDef.Name, Reason: reason})
+		}
+
+		for _, relation := range nsDef.Relation {
+			if reason, ok := nspkg.GetDeprecation(relation.Metadata); ok {
+				reflected = append(reflected, ReflectedDeprecation{Definition: nsDef.Name, Relation: relation.Name, Reason: reason})
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(reflected)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Pairs(DeprecationsReflectionHeader, string(encoded)), nil
+}
+
+// checkDeprecated checks whether any of the given relations or permissions have been marked as
+// deprecated in the schema. If so, a warning is logged and returned to the caller in the
+// DeprecationWarningHeader header or, if the server is configured to reject such requests, an
+// error is returned.
+func (ps *permissionServer) checkDeprecated(ctx context.Context, ds datastore.Reader, relations ...*core.RelationReference) error {
+	encountered := util.NewSet[string]()
+	for _, relation := range relations {
+		if relation.Relation == datastore.Ellipsis {
+			continue
+		}
+
+		if !encountered.Add(tuple.StringRR(relation)) {
+			continue
+		}
+
+		nsDef, _, err := namespace.ReadLiveNamespace(ctx, ds, relation.Namespace)
+		if err != nil {
+			return err
+		}
+
+		reason, deprecated := nspkg.GetDeprecation(nsDef.Metadata)
+		if !deprecated {
+			for _, rel := range nsDef.Relation {
+				if rel.Name == relation.Relation {
+					reason, deprecated = nspkg.GetDeprecation(rel.Metadata)
+					break
+				}
+			}
+		}
+
+		if !deprecated {
+			continue
+		}
+
+		deprecationErr := NewDeprecatedRelationErr(relation.Namespace, relation.Relation, reason)
+		if ps.config.RejectDeprecatedRelations {
+			return deprecationErr
+		}
+
+		log.Ctx(ctx).Warn().Object("deprecated", deprecationErr).Msg("request references a deprecated relation or permission")
+
+		// NOTE: setting the header fails when not invoked within a gRPC server, which is fine
+		// since the warning has already been logged.
+		_ = grpc.SetHeader(ctx, metadata.Pairs(DeprecationWarningHeader, deprecationErr.Error()))
+	}
+
+	return nil
+}
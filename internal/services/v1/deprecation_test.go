@@ -0,0 +1,174 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const deprecationTestSchema = `
+	definition user {}
+
+	definition document {
+		// @deprecated use viewer instead
+		relation reader: user
+		relation viewer: user
+
+		/** @deprecated use view instead */
+		permission read = reader + viewer
+		permission view = reader + viewer
+	}
+`
+
+func TestDeprecatedRelations(t *testing.T) {
+	testCases := []struct {
+		name            string
+		rejectByConfig  bool
+		relation        string
+		permission      string
+		expectedWarning string
+	}{
+		{"non-deprecated", false, "viewer", "view", ""},
+		{"deprecated relation", false, "reader", "view", "relation/permission `document#reader` is deprecated: use viewer instead"},
+		{"deprecated permission", false, "viewer", "read", "relation/permission `document#read` is deprecated: use view instead"},
+		{"rejected non-deprecated", true, "viewer", "view", ""},
+		{"rejected deprecated relation", true, "reader", "view", "relation/permission `document#reader` is deprecated: use viewer instead"},
+		{"rejected deprecated permission", true, "viewer", "read", "relation/permission `document#read` is deprecated: use view instead"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := require.New(t)
+			conn, cleanup, _, _ := testserver.NewTestServerWithConfig(req, 0, memdb.DisableGC, true,
+				testserver.ServerConfig{
+					MaxUpdatesPerWrite:        1000,
+					MaxPreconditionsCount:     1000,
+					RejectDeprecatedRelations: tc.rejectByConfig,
+				},
+				func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+					return tf.DatastoreFromSchemaAndTestRelationships(ds, deprecationTestSchema, []*core.RelationTuple{
+						tuple.MustParse("document:first#reader@user:tom"),
+						tuple.MustParse("document:first#viewer@user:tom"),
+					}, require)
+				})
+			t.Cleanup(cleanup)
+
+			client := v1.NewPermissionsServiceClient(conn)
+
+			var header metadata.MD
+			writeResp, err := client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+				Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(
+					tuple.Touch(tuple.MustParse("document:second#" + tc.relation + "@user:sarah")),
+				)},
+			}, grpc.Header(&header))
+			requireDeprecationOutcome(t, tc.rejectByConfig, tc.expectedWarning, tc.relation == "reader", header, err)
+
+			header = nil
+			consistency := &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+			if writeResp != nil {
+				consistency = &v1.Consistency{Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: writeResp.WrittenAt}}
+			}
+
+			_, err = client.CheckPermission(context.Background(), &v1.CheckPermissionRequest{
+				Consistency: consistency,
+				Resource:    obj("document", "first"),
+				Permission:  tc.permission,
+				Subject:     sub("user", "tom", ""),
+			}, grpc.Header(&header))
+			requireDeprecationOutcome(t, tc.rejectByConfig, tc.expectedWarning, tc.permission == "read", header, err)
+		})
+	}
+}
+
+func TestDeleteDeprecatedRelationships(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(req, 0, memdb.DisableGC, true,
+		testserver.ServerConfig{
+			MaxUpdatesPerWrite:        1000,
+			MaxPreconditionsCount:     1000,
+			RejectDeprecatedRelations: true,
+		},
+		func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+			return tf.DatastoreFromSchemaAndTestRelationships(ds, deprecationTestSchema, []*core.RelationTuple{
+				tuple.MustParse("document:first#reader@user:tom"),
+			}, require)
+		})
+	t.Cleanup(cleanup)
+
+	// Migrating away from a deprecated relation deletes its relationships, which is never rejected.
+	var header metadata.MD
+	_, err := v1.NewPermissionsServiceClient(conn).WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Delete(tuple.MustParse("document:first#reader@user:tom"))),
+			tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("document:first#viewer@user:tom"))),
+		},
+	}, grpc.Header(&header))
+	req.NoError(err)
+	req.Empty(header.Get(v1svc.DeprecationWarningHeader))
+}
+
+func TestSchemaReadReflectsDeprecations(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServer(req, 0, memdb.DisableGC, true,
+		func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+			return tf.DatastoreFromSchemaAndTestRelationships(ds, deprecationTestSchema+`
+				// @deprecated
+				definition folder {}
+			`, nil, require)
+		})
+	t.Cleanup(cleanup)
+	client := v1.NewSchemaServiceClient(conn)
+
+	// Without the header, no reflection is returned.
+	var md metadata.MD
+	_, err := client.ReadSchema(context.Background(), &v1.ReadSchemaRequest{}, grpc.Header(&md))
+	req.NoError(err)
+	req.Empty(md.Get(v1svc.DeprecationsReflectionHeader))
+
+	ctx := requestmeta.AddRequestHeaders(context.Background(), v1svc.RequestReflectDeprecations)
+	_, err = client.ReadSchema(ctx, &v1.ReadSchemaRequest{}, grpc.Header(&md))
+	req.NoError(err)
+	req.Len(md.Get(v1svc.DeprecationsReflectionHeader), 1)
+
+	var reflected []v1svc.ReflectedDeprecation
+	req.NoError(json.Unmarshal([]byte(md.Get(v1svc.DeprecationsReflectionHeader)[0]), &reflected))
+	req.Equal([]v1svc.ReflectedDeprecation{
+		{Definition: "document", Relation: "reader", Reason: "use viewer instead"},
+		{Definition: "document", Relation: "read", Reason: "use view instead"},
+		{Definition: "folder"},
+	}, reflected)
+}
+
+func requireDeprecationOutcome(t *testing.T, rejectByConfig bool, expectedWarning string, applies bool, header metadata.MD, err error) {
+	if !applies || expectedWarning == "" {
+		require.NoError(t, err)
+		require.Empty(t, header.Get(v1svc.DeprecationWarningHeader))
+		return
+	}
+
+	if rejectByConfig {
+		grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+		require.ErrorContains(t, err, expectedWarning)
+		return
+	}
+
+	require.NoError(t, err)
+	require.Equal(t, []string{expectedWarning}, header.Get(v1svc.DeprecationWarningHeader))
+}
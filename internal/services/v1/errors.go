@@ -98,6 +98,47 @@ func NewExceedsMaximumPreconditionsErr(preconditionCount uint16, maxCountAllowed
 	}
 }
 
+// ErrStreamLimitExceeded occurs when a streaming call reaches a limit of the results streamed by
+// a call.
+type ErrStreamLimitExceeded struct {
+	error
+	limit   string
+	maximum uint64
+	cursor  string
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrStreamLimitExceeded) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("limit", err.limit).Uint64("maximum", err.maximum)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrStreamLimitExceeded) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.ResourceExhausted,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"limit":   err.limit,
+				"maximum": strconv.FormatUint(err.maximum, 10),
+				"cursor":  err.cursor,
+			},
+		),
+	)
+}
+
+// NewStreamLimitExceededErr creates a new error representing that a streaming call reached the
+// maximum of the given limit, either `results` or `bytes`, and can be continued with the cursor.
+func NewStreamLimitExceededErr(limit string, maximum uint64, cursor string) ErrStreamLimitExceeded {
+	return ErrStreamLimitExceeded{
+		error:   fmt.Errorf("the call reached the maximum of %d %s streamed per call, continue it with the cursor in the %s response trailer", maximum, limit, StreamCursorTrailer),
+		limit:   limit,
+		maximum: maximum,
+		cursor:  cursor,
+	}
+}
+
 // ErrPreconditionFailed occurs when the precondition to a write tuple call does not match.
 type ErrPreconditionFailed struct {
 	error
@@ -154,6 +195,51 @@ func (err ErrPreconditionFailed) GRPCStatus() *status.Status {
 	)
 }
 
+// ErrDeprecatedRelation occurs when a request references a relation or permission that has been
+// marked as deprecated in the schema, and the server is configured to reject such requests.
+type ErrDeprecatedRelation struct {
+	error
+	namespaceName string
+	relationName  string
+	reason        string
+}
+
+// NewDeprecatedRelationErr constructs a new deprecated relation error.
+func NewDeprecatedRelationErr(namespaceName string, relationName string, reason string) ErrDeprecatedRelation {
+	message := fmt.Sprintf("relation/permission `%s#%s` is deprecated", namespaceName, relationName)
+	if reason != "" {
+		message = fmt.Sprintf("%s: %s", message, reason)
+	}
+
+	return ErrDeprecatedRelation{
+		error:         errors.New(message),
+		namespaceName: namespaceName,
+		relationName:  relationName,
+		reason:        reason,
+	}
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrDeprecatedRelation) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("namespace", err.namespaceName).Str("relation", err.relationName)
+}
+
+// GRPCStatus implements retrieving the gRPC status for the error.
+func (err ErrDeprecatedRelation) GRPCStatus() *status.Status {
+	return spiceerrors.WithCodeAndDetails(
+		err,
+		codes.FailedPrecondition,
+		spiceerrors.ForReason(
+			v1.ErrorReason_ERROR_REASON_UNSPECIFIED,
+			map[string]string{
+				"definition_name":             err.namespaceName,
+				"relation_or_permission_name": err.relationName,
+				"deprecation_reason":          err.reason,
+			},
+		),
+	)
+}
+
 // ErrDuplicateRelationshipError indicates that an update was attempted on the same relationship.
 type ErrDuplicateRelationshipError struct {
 	error
@@ -215,6 +301,8 @@ func rewriteError(ctx context.Context, err error) error {
 
 	case errors.As(err, &datastore.ErrReadOnly{}):
 		return shared.ErrServiceReadOnly
+	case errors.As(err, &datastore.ErrOutsideTenant{}):
+		return status.Errorf(codes.PermissionDenied, "%s", err)
 	case errors.As(err, &datastore.ErrInvalidRevision{}):
 		return status.Errorf(codes.OutOfRange, "invalid zedtoken: %s", err)
 	case errors.As(err, &datastore.ErrReadOnly{}):
@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/metadata"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RequestExpandCaveats, if specified in the request header of an ExpandPermissionTree call, asks
+// SpiceDB to also return the caveats of the caveated relationships found in the tree in the
+// ExpandCaveatsHeader response header, so that callers can tell conditional members from
+// unconditional ones.
+// Value: `1`
+const RequestExpandCaveats requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.expandcaveats"
+
+// ExpandCaveatsHeader is the response header holding the JSON-encoded list of ExpandedCaveat
+// describing the caveats found in the tree returned by an ExpandPermissionTree call, in the order
+// in which they are found walking the tree depth first.
+const ExpandCaveatsHeader = "io.spicedb.respmeta.expandcaveats"
+
+// ExpandedCaveat describes the caveat of a relationship found in the tree returned by
+// ExpandPermissionTree. The members reached through the relationship are only members if the
+// caveat is satisfied.
+type ExpandedCaveat struct {
+	// Path is the position in the tree of the node holding the caveat, as the indexes of the
+	// children taken from the root to reach it.
+	Path []int `json:"path"`
+
+	// Subject is, for the caveat of a subject of a leaf, the subject of the form `type:id` or
+	// `type:id#relation`. It is empty for the caveat of the relationship through which the node
+	// itself was reached, such as the tupleset relationship of an arrow, which applies to all of
+	// its members.
+	Subject string `json:"subject,omitempty"`
+
+	// Caveat is the caveat of the relationship.
+	Caveat ProjectedCaveat `json:"caveat"`
+}
+
+// expandCaveatsRequested returns whether the caveats of the tree of an ExpandPermissionTree call
+// were requested.
+func expandCaveatsRequested(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+
+	_, requested := md[string(RequestExpandCaveats)]
+	return requested
+}
+
+// expandedCaveats appends the caveats found in the expanded node to those found, given the
+// translated tree of the node. Only the caveats of the subjects found in the leaves of the
+// translated tree are returned, so that a paginated call only returns those of its page.
+func expandedCaveats(node *core.RelationTupleTreeNode, tree *v1.PermissionRelationshipTree, path []int, found []ExpandedCaveat) []ExpandedCaveat {
+	if node.Caveat != nil {
+		found = append(found, ExpandedCaveat{Path: path, Caveat: projectCaveat(node.Caveat)})
+	}
+
+	switch t := node.NodeType.(type) {
+	case *core.RelationTupleTreeNode_IntermediateNode:
+		children := tree.GetIntermediate().GetChildren()
+		for index, child := range t.IntermediateNode.ChildNodes {
+			if index >= len(children) {
+				break
+			}
+
+			childPath := make([]int, len(path), len(path)+1)
+			copy(childPath, path)
+			found = expandedCaveats(child, children[index], append(childPath, index), found)
+		}
+
+	case *core.RelationTupleTreeNode_LeafNode:
+		returned := make(map[string]struct{}, len(tree.GetLeaf().GetSubjects()))
+		for _, subject := range tree.GetLeaf().GetSubjects() {
+			returned[subjectKey(subject)] = struct{}{}
+		}
+
+		for _, caveated := range t.LeafNode.CaveatedSubjects {
+			subject := caveated.Subject
+			key := subject.Namespace + ":" + subject.ObjectId + "#" + denormalizeSubjectRelation(subject.Relation)
+			if _, ok := returned[key]; !ok {
+				continue
+			}
+
+			found = append(found, ExpandedCaveat{
+				Path:    path,
+				Subject: tuple.StringONR(subject),
+				Caveat:  projectCaveat(caveated.Caveat),
+			})
+		}
+	}
+
+	return found
+}
+
+// expandCaveatsHeader returns the ExpandCaveatsHeader response header describing the caveats
+// found in the expanded tree.
+func expandCaveatsHeader(root *core.RelationTupleTreeNode, tree *v1.PermissionRelationshipTree) (metadata.MD, error) {
+	encoded, err := json.Marshal(expandedCaveats(root, tree, []int{}, []ExpandedCaveat{}))
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Pairs(ExpandCaveatsHeader, string(encoded)), nil
+}
@@ -0,0 +1,103 @@
+package v1_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestExpandPermissionTreeCaveats(t *testing.T) {
+	ipAllowed := v1svc.ExpandedCaveat{
+		Path:    []int{0},
+		Subject: "user:tom",
+		Caveat:  v1svc.ProjectedCaveat{Name: "ip_allowed", Context: map[string]any{"allowed": "10.0.0.1"}},
+	}
+	onWeekday := v1svc.ExpandedCaveat{
+		Path:   []int{1, 0},
+		Caveat: v1svc.ProjectedCaveat{Name: "on_weekday"},
+	}
+
+	testCases := []struct {
+		name     string
+		headers  []string
+		expected []v1svc.ExpandedCaveat
+	}{
+		{"not requested", nil, nil},
+		{"requested", []string{string(v1svc.RequestExpandCaveats), "1"}, []v1svc.ExpandedCaveat{ipAllowed, onWeekday}},
+		{
+			"paginated",
+			[]string{string(v1svc.RequestExpandCaveats), "1", string(v1svc.RequestExpandPageSize), "1"},
+			[]v1svc.ExpandedCaveat{onWeekday},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := require.New(t)
+
+			conn, cleanup, _, _ := testserver.NewTestServer(req, 0, memdb.DisableGC, true,
+				func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+					return testfixtures.DatastoreFromSchemaAndTestRelationships(ds, `
+						definition user {}
+
+						caveat ip_allowed(ip string, allowed string) {
+							ip == allowed
+						}
+
+						caveat on_weekday(day int) {
+							day < 6
+						}
+
+						definition org {
+							relation member: user
+						}
+
+						definition document {
+							relation viewer: user | user with ip_allowed
+							relation org: org with on_weekday
+							permission view = viewer + org->member
+						}
+					`, []*core.RelationTuple{
+						tuple.MustParse("document:first#viewer@user:sarah"),
+						withCaveatContext(t, "document:first#viewer@user:tom", "ip_allowed", map[string]any{"allowed": "10.0.0.1"}),
+						tuple.WithCaveat(tuple.MustParse("document:first#org@org:acme"), "on_weekday"),
+						tuple.MustParse("org:acme#member@user:amy"),
+					}, require)
+				})
+			t.Cleanup(cleanup)
+
+			var header metadata.MD
+			_, err := v1.NewPermissionsServiceClient(conn).ExpandPermissionTree(metadata.NewOutgoingContext(context.Background(), metadata.Pairs(tc.headers...)), &v1.ExpandPermissionTreeRequest{
+				Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+				Resource:    &v1.ObjectReference{ObjectType: "document", ObjectId: "first"},
+				Permission:  "view",
+			}, grpc.Header(&header))
+			req.NoError(err)
+
+			values := header.Get(v1svc.ExpandCaveatsHeader)
+			if tc.expected == nil {
+				req.Empty(values)
+				return
+			}
+			req.Len(values, 1)
+
+			var caveats []v1svc.ExpandedCaveat
+			req.NoError(json.Unmarshal([]byte(values[0]), &caveats))
+			req.Equal(tc.expected, caveats)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package v1
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+	"strconv"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+const (
+	// RequestExpandPageSize, if specified in the request header of an ExpandPermissionTree call,
+	// limits the number of subjects returned across all leaves of the tree. The structure of the
+	// tree is always returned in full, with the leaves holding the subjects of the page, so that
+	// the subjects of each page can be merged into the leaf at the same position in the tree.
+	// When more subjects remain, a cursor to the next page is returned in the ExpandCursorHeader
+	// response header.
+	// Value: a positive number of subjects, such as `1000`
+	RequestExpandPageSize requestmeta.RequestMetadataHeaderKey = "io.spicedb.expandpagesize"
+
+	// RequestExpandCursor, if specified in the request header of an ExpandPermissionTree call,
+	// continues the expansion of a previous page. The page is expanded at the revision of the
+	// first page, whatever the consistency of the request, and the resource and permission must
+	// be those of the first page.
+	// Value: the cursor returned in the ExpandCursorHeader response header
+	RequestExpandCursor requestmeta.RequestMetadataHeaderKey = "io.spicedb.expandcursor"
+)
+
+// ExpandCursorHeader is the response header holding the cursor to the next page of a paginated
+// ExpandPermissionTree call, set only when more subjects remain.
+const ExpandCursorHeader = "io.spicedb.respmeta.expandcursor"
+
+// expandCursor is the decoded form of the cursor to the next page of an ExpandPermissionTree call.
+type expandCursor struct {
+	// ExpandedAt is the token of the revision at which the first page was expanded.
+	ExpandedAt string `json:"t"`
+
+	// ObjectType, ObjectID and Permission are those of the expanded resource.
+	ObjectType string `json:"ot"`
+	ObjectID   string `json:"oi"`
+	Permission string `json:"p"`
+
+	// Offset is the number of subjects returned by the previous pages.
+	Offset int `json:"o"`
+}
+
+// expandPage is the requested page of an ExpandPermissionTree call.
+type expandPage struct {
+	size   int
+	cursor *expandCursor
+}
+
+// expandPageFromContext returns the page requested by an ExpandPermissionTree call, or nil if the
+// call is not paginated.
+func expandPageFromContext(ctx context.Context, req *v1.ExpandPermissionTreeRequest) (*expandPage, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	sizes := md.Get(string(RequestExpandPageSize))
+	cursors := md.Get(string(RequestExpandCursor))
+	if len(sizes) == 0 {
+		if len(cursors) > 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "an expand cursor requires a page size")
+		}
+		return nil, nil
+	}
+
+	size, err := strconv.Atoi(sizes[0])
+	if err != nil || size <= 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid expand page size %q, expected a positive number", sizes[0])
+	}
+
+	page := &expandPage{size: size}
+	if len(cursors) == 0 {
+		return page, nil
+	}
+
+	cursor, err := decodeExpandCursor(cursors[0])
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid expand cursor: %s", err)
+	}
+
+	if cursor.ObjectType != req.Resource.ObjectType || cursor.ObjectID != req.Resource.ObjectId || cursor.Permission != req.Permission {
+		return nil, status.Errorf(codes.InvalidArgument, "expand cursor was issued for `%s:%s#%s`", cursor.ObjectType, cursor.ObjectID, cursor.Permission)
+	}
+	page.cursor = &cursor
+	return page, nil
+}
+
+func decodeExpandCursor(encoded string) (expandCursor, error) {
+	decoded, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return expandCursor{}, err
+	}
+
+	var cursor expandCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return expandCursor{}, err
+	}
+	return cursor, nil
+}
+
+func encodeExpandCursor(cursor expandCursor) (string, error) {
+	encoded, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(encoded), nil
+}
+
+// expandCursorRevision returns the revision at which the first page of the cursor was expanded,
+// if it is still available.
+func expandCursorRevision(ctx context.Context, cursor *expandCursor) (datastore.Revision, *v1.ZedToken, error) {
+	return cursorRevision(ctx, cursor.ExpandedAt)
+}
+
+// cursorRevision returns the revision of the token held in a cursor, if it is still available.
+func cursorRevision(ctx context.Context, token string) (datastore.Revision, *v1.ZedToken, error) {
+	zedToken := &v1.ZedToken{Token: token}
+	ds := datastoremw.MustFromContext(ctx)
+	revision, err := zedtoken.DecodeRevisionForDatastore(zedToken, ds, datastoremw.UniqueIDFromContext(ctx))
+	if err != nil {
+		return datastore.NoRevision, nil, status.Errorf(codes.InvalidArgument, "invalid cursor revision: %s", err)
+	}
+
+	if err := ds.CheckRevision(ctx, revision); err != nil {
+		return datastore.NoRevision, nil, err
+	}
+	return revision, zedToken, nil
+}
+
+// offset returns the number of subjects returned by the previous pages.
+func (p *expandPage) offset() int {
+	if p.cursor == nil {
+		return 0
+	}
+	return p.cursor.Offset
+}
+
+// apply restricts the subjects of the leaves of the tree to those of the page, in the order in
+// which the leaves are found walking the tree depth first and sorted within each leaf, and returns the cursor to the next page,
+// if any subjects remain.
+func (p *expandPage) apply(tree *v1.PermissionRelationshipTree, req *v1.ExpandPermissionTreeRequest, expandedAt *v1.ZedToken) (string, error) {
+	offset := p.offset()
+	total := pageSubjects(tree, offset, offset+p.size, 0)
+	if total <= offset+p.size {
+		return "", nil
+	}
+
+	return encodeExpandCursor(expandCursor{
+		ExpandedAt: expandedAt.Token,
+		ObjectType: req.Resource.ObjectType,
+		ObjectID:   req.Resource.ObjectId,
+		Permission: req.Permission,
+		Offset:     offset + p.size,
+	})
+}
+
+// pageSubjects keeps, in the leaves of the tree, the subjects whose index among all subjects of
+// the tree is within [start, end), given the number of subjects found before the tree, and
+// returns the number of subjects found through the end of the tree.
+func pageSubjects(tree *v1.PermissionRelationshipTree, start, end, found int) int {
+	switch t := tree.TreeType.(type) {
+	case *v1.PermissionRelationshipTree_Intermediate:
+		for _, child := range t.Intermediate.Children {
+			found = pageSubjects(child, start, end, found)
+		}
+		return found
+
+	case *v1.PermissionRelationshipTree_Leaf:
+		// The subjects are sorted so that every page sees them in the same order.
+		subjects := t.Leaf.Subjects
+		sort.Slice(subjects, func(i, j int) bool {
+			return subjectKey(subjects[i]) < subjectKey(subjects[j])
+		})
+		first, last := clamp(start-found, len(subjects)), clamp(end-found, len(subjects))
+		t.Leaf.Subjects = subjects[first:last]
+		return found + len(subjects)
+
+	default:
+		return found
+	}
+}
+
+func subjectKey(subject *v1.SubjectReference) string {
+	return subject.Object.ObjectType + ":" + subject.Object.ObjectId + "#" + subject.OptionalRelation
+}
+
+// clamp returns the index bounded to [0, length].
+func clamp(index, length int) int {
+	if index < 0 {
+		return 0
+	}
+	if index > length {
+		return length
+	}
+	return index
+}
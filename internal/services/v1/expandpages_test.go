@@ -0,0 +1,104 @@
+package v1_test
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+func TestExpandPermissionTreePages(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+	client := v1.NewPermissionsServiceClient(conn)
+
+	expand := func(resourceID string, headers ...string) (*v1.ExpandPermissionTreeResponse, string, error) {
+		var header metadata.MD
+		resp, err := client.ExpandPermissionTree(metadata.NewOutgoingContext(context.Background(), metadata.Pairs(headers...)), &v1.ExpandPermissionTreeRequest{
+			Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+			Resource:    &v1.ObjectReference{ObjectType: "document", ObjectId: resourceID},
+			Permission:  "view",
+		}, grpc.Header(&header))
+
+		var cursor string
+		if values := header.Get(v1svc.ExpandCursorHeader); len(values) > 0 {
+			cursor = values[0]
+		}
+		return resp, cursor, err
+	}
+
+	full, cursor, err := expand("masterplan")
+	require.NoError(err)
+	require.Empty(cursor)
+	sortLeaves(full.TreeRoot)
+
+	pageSize := "1"
+	merged, cursor, err := expand("masterplan", string(v1svc.RequestExpandPageSize), pageSize)
+	require.NoError(err)
+	require.Equal(1, countLeafs(merged.TreeRoot))
+
+	pages := 1
+	for cursor != "" {
+		var page *v1.ExpandPermissionTreeResponse
+		page, cursor, err = expand("masterplan", string(v1svc.RequestExpandPageSize), pageSize, string(v1svc.RequestExpandCursor), cursor)
+		require.NoError(err)
+		require.LessOrEqual(countLeafs(page.TreeRoot), 1)
+		require.True(proto.Equal(merged.ExpandedAt, page.ExpandedAt), "pages must be expanded at the revision of the first page")
+
+		mergeLeaves(merged.TreeRoot, page.TreeRoot)
+		pages++
+	}
+	require.Equal(countLeafs(full.TreeRoot), pages)
+	require.True(proto.Equal(full.TreeRoot, merged.TreeRoot), "merged pages must match the full tree")
+
+	_, _, err = expand("masterplan", string(v1svc.RequestExpandPageSize), "0")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, cursor, err = expand("masterplan", string(v1svc.RequestExpandPageSize), pageSize)
+	require.NoError(err)
+	_, _, err = expand("healthplan", string(v1svc.RequestExpandPageSize), pageSize, string(v1svc.RequestExpandCursor), cursor)
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, _, err = expand("masterplan", string(v1svc.RequestExpandCursor), cursor)
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func sortLeaves(node *v1.PermissionRelationshipTree) {
+	switch t := node.TreeType.(type) {
+	case *v1.PermissionRelationshipTree_Leaf:
+		sort.Slice(t.Leaf.Subjects, func(i, j int) bool {
+			return t.Leaf.Subjects[i].Object.ObjectId < t.Leaf.Subjects[j].Object.ObjectId
+		})
+
+	case *v1.PermissionRelationshipTree_Intermediate:
+		for _, child := range t.Intermediate.Children {
+			sortLeaves(child)
+		}
+	}
+}
+
+func mergeLeaves(into *v1.PermissionRelationshipTree, page *v1.PermissionRelationshipTree) {
+	switch t := into.TreeType.(type) {
+	case *v1.PermissionRelationshipTree_Leaf:
+		t.Leaf.Subjects = append(t.Leaf.Subjects, page.GetLeaf().Subjects...)
+
+	case *v1.PermissionRelationshipTree_Intermediate:
+		for i, child := range t.Intermediate.Children {
+			mergeLeaves(child, page.GetIntermediate().Children[i])
+		}
+	}
+}
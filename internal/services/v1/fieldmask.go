@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"strings"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// RequestResponseFieldMask, if specified in the request header of a ReadRelationships or
+// LookupResources call, projects each of the streamed responses onto the given fields, clearing
+// all others, to reduce the cost of serializing high-volume streams. The header may be given more
+// than once.
+// Value: a comma-separated list of the paths of the fields of the response message to return, in
+// the FieldMask format, such as `resource_object_id` for LookupResources, or
+// `relationship.resource,relationship.subject.object` for ReadRelationships
+const RequestResponseFieldMask requestmeta.RequestMetadataHeaderKey = "io.spicedb.responsefieldmask"
+
+// responseFieldMask is the tree of the fields of a response message kept by a field mask, keyed by
+// field name. A nil subtree keeps the whole field.
+type responseFieldMask map[string]responseFieldMask
+
+// responseFieldMaskFromContext returns the field mask requested for the responses of the call,
+// validated against the response message, or nil if no field mask was requested.
+func responseFieldMaskFromContext(ctx context.Context, response proto.Message) (responseFieldMask, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	paths := headerValues(md, RequestResponseFieldMask)
+	if len(paths) == 0 {
+		return nil, nil
+	}
+	return newResponseFieldMask(response, paths)
+}
+
+// newResponseFieldMask returns the field mask of the given paths, validated against the response
+// message.
+func newResponseFieldMask(response proto.Message, paths []string) (responseFieldMask, error) {
+	fieldMask, err := fieldmaskpb.New(response, paths...)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid response field mask: %s", err)
+	}
+	fieldMask.Normalize()
+
+	mask := responseFieldMask{}
+	for _, path := range fieldMask.Paths {
+		node := mask
+		names := strings.Split(path, ".")
+		for _, name := range names[:len(names)-1] {
+			child, ok := node[name]
+			if !ok {
+				child = responseFieldMask{}
+				node[name] = child
+			}
+			node = child
+		}
+		node[names[len(names)-1]] = nil
+	}
+	return mask, nil
+}
+
+// apply clears the fields of the response which are not kept by the field mask. A nil field mask
+// keeps all fields.
+func (mask responseFieldMask) apply(response proto.Message) {
+	if mask == nil {
+		return
+	}
+	mask.prune(response.ProtoReflect())
+}
+
+func (mask responseFieldMask) prune(message protoreflect.Message) {
+	var cleared []protoreflect.FieldDescriptor
+	message.Range(func(fd protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		child, ok := mask[string(fd.Name())]
+		switch {
+		case !ok:
+			cleared = append(cleared, fd)
+		case child != nil:
+			// Paths only traverse singular message fields, as validated by fieldmaskpb.
+			child.prune(value.Message())
+		}
+		return true
+	})
+
+	for _, fd := range cleared {
+		message.Clear(fd)
+	}
+}
@@ -0,0 +1,149 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+func TestResponseFieldMask(t *testing.T) {
+	testCases := []struct {
+		name            string
+		readFieldMask   string
+		lookupFieldMask string
+		expectedStatus  codes.Code
+		verifyRead      func(*require.Assertions, *v1.ReadRelationshipsResponse)
+		verifyLookup    func(*require.Assertions, *v1.LookupResourcesResponse)
+	}{
+		{
+			"no field mask",
+			"",
+			"",
+			codes.OK,
+			func(require *require.Assertions, resp *v1.ReadRelationshipsResponse) {
+				require.NotNil(resp.ReadAt)
+				require.NotEmpty(resp.Relationship.Resource.ObjectId)
+				require.NotEmpty(resp.Relationship.Subject.Object.ObjectId)
+			},
+			func(require *require.Assertions, resp *v1.LookupResourcesResponse) {
+				require.NotNil(resp.LookedUpAt)
+				require.NotEmpty(resp.ResourceObjectId)
+				require.Equal(v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION, resp.Permissionship)
+			},
+		},
+		{
+			"top-level fields",
+			"relationship",
+			"resource_object_id",
+			codes.OK,
+			func(require *require.Assertions, resp *v1.ReadRelationshipsResponse) {
+				require.Nil(resp.ReadAt)
+				require.NotEmpty(resp.Relationship.Resource.ObjectId)
+				require.NotEmpty(resp.Relationship.Relation)
+			},
+			func(require *require.Assertions, resp *v1.LookupResourcesResponse) {
+				require.Nil(resp.LookedUpAt)
+				require.NotEmpty(resp.ResourceObjectId)
+				require.Equal(v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_UNSPECIFIED, resp.Permissionship)
+			},
+		},
+		{
+			"nested fields",
+			"relationship.resource.object_id,relationship.subject.object",
+			"looked_up_at",
+			codes.OK,
+			func(require *require.Assertions, resp *v1.ReadRelationshipsResponse) {
+				require.Nil(resp.ReadAt)
+				require.Empty(resp.Relationship.Resource.ObjectType)
+				require.NotEmpty(resp.Relationship.Resource.ObjectId)
+				require.Empty(resp.Relationship.Relation)
+				require.NotEmpty(resp.Relationship.Subject.Object.ObjectType)
+				require.NotEmpty(resp.Relationship.Subject.Object.ObjectId)
+			},
+			func(require *require.Assertions, resp *v1.LookupResourcesResponse) {
+				require.NotNil(resp.LookedUpAt)
+				require.Empty(resp.ResourceObjectId)
+			},
+		},
+		{"unknown field", "relationship.unknown", "resource_object_id.unknown", codes.InvalidArgument, nil, nil},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := require.New(t)
+
+			conn, cleanup, _, _ := testserver.NewTestServer(req, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+			t.Cleanup(cleanup)
+			client := v1.NewPermissionsServiceClient(conn)
+
+			withFieldMask := func(fieldMask string) context.Context {
+				if fieldMask == "" {
+					return context.Background()
+				}
+				return metadata.NewOutgoingContext(context.Background(), metadata.Pairs(string(v1svc.RequestResponseFieldMask), fieldMask))
+			}
+
+			readStream, err := client.ReadRelationships(withFieldMask(tc.readFieldMask), &v1.ReadRelationshipsRequest{
+				Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+				RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+			})
+			req.NoError(err)
+
+			var read int
+			for {
+				resp, err := readStream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if tc.expectedStatus != codes.OK {
+					grpcutil.RequireStatus(t, tc.expectedStatus, err)
+					break
+				}
+				req.NoError(err)
+				tc.verifyRead(req, resp)
+				read++
+			}
+
+			lookupStream, err := client.LookupResources(withFieldMask(tc.lookupFieldMask), &v1.LookupResourcesRequest{
+				Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+				ResourceObjectType: "document",
+				Permission:         "view",
+				Subject:            sub("user", "eng_lead", ""),
+			})
+			req.NoError(err)
+
+			var lookedUp int
+			for {
+				resp, err := lookupStream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if tc.expectedStatus != codes.OK {
+					grpcutil.RequireStatus(t, tc.expectedStatus, err)
+					break
+				}
+				req.NoError(err)
+				tc.verifyLookup(req, resp)
+				lookedUp++
+			}
+
+			if tc.expectedStatus == codes.OK {
+				req.Greater(read, 0)
+				req.Greater(lookedUp, 0)
+			}
+		})
+	}
+}
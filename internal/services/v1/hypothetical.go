@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"context"
+	"strings"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/datastore/proxy"
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RequestHypotheticalRelationships, if specified in the request header of a CheckPermission,
+// ExpandPermissionTree or LookupResources call, evaluates the call as if the given relationships
+// had been touched or deleted, without writing them. The header may be given once per
+// relationship. Results are computed at the revision of the call, without the dispatch cache and
+// without dispatching to other nodes, and the returned ZedToken is that of the revision.
+// Value: `+` to touch or `-` to delete, followed by a relationship, such as
+// `+document:firstdoc#viewer@user:tom`
+const RequestHypotheticalRelationships requestmeta.RequestMetadataHeaderKey = "io.spicedb.hypotheticals"
+
+// hypotheticalConcurrencyLimit is the concurrency limit of the dispatcher evaluating calls with
+// hypothetical relationships.
+const hypotheticalConcurrencyLimit = 10
+
+// hypotheticalDispatch returns the context and dispatcher with which to evaluate a call, which
+// read the hypothetical relationships of the call, if any.
+func (ps *permissionServer) hypotheticalDispatch(ctx context.Context, atRevision datastore.Revision) (context.Context, dispatch.Dispatcher, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ctx, ps.dispatch, nil
+	}
+
+	values := md.Get(string(RequestHypotheticalRelationships))
+	if len(values) == 0 {
+		return ctx, ps.dispatch, nil
+	}
+
+	if len(values) > int(ps.config.MaxUpdatesPerWrite) {
+		return ctx, nil, NewExceedsMaximumUpdatesErr(uint16(len(values)), ps.config.MaxUpdatesPerWrite)
+	}
+
+	updates, err := parseHypotheticalRelationships(values)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+	reader := ds.SnapshotReader(atRevision)
+	if err := relationships.ValidateRelationshipUpdates(ctx, reader, updates, ps.config.StrictRelationshipValidation); err != nil {
+		return ctx, nil, err
+	}
+
+	// Keep any relations being renamed in sync with their new names.
+	updates, err = relationships.WithRenamedRelationUpdates(ctx, reader, updates)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	// NOTE: the results depend on the hypothetical relationships, and so must neither be read from
+	// nor written to the dispatch cache, nor be computed by other nodes, which read the datastore
+	// as is.
+	ctx = datastoremw.ContextWithDatastore(ctx, proxy.NewHypotheticalDatastore(ds, updates))
+	return ctx, graph.NewLocalOnlyDispatcher(hypotheticalConcurrencyLimit), nil
+}
+
+func parseHypotheticalRelationships(values []string) ([]*core.RelationTupleUpdate, error) {
+	updates := make([]*core.RelationTupleUpdate, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if len(value) == 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "empty hypothetical relationship")
+		}
+
+		tpl := tuple.Parse(value[1:])
+		if tpl == nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid hypothetical relationship: %q", value)
+		}
+
+		switch value[0] {
+		case '+':
+			updates = append(updates, tuple.Touch(tpl))
+		case '-':
+			updates = append(updates, tuple.Delete(tpl))
+		default:
+			return nil, status.Errorf(codes.InvalidArgument, "hypothetical relationship must start with `+` or `-`: %q", value)
+		}
+	}
+	return updates, nil
+}
@@ -0,0 +1,160 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+func withHypotheticals(hypotheticals ...string) context.Context {
+	md := metadata.MD{}
+	for _, hypothetical := range hypotheticals {
+		md.Append(string(v1svc.RequestHypotheticalRelationships), hypothetical)
+	}
+	return metadata.NewOutgoingContext(context.Background(), md)
+}
+
+func TestHypotheticalCheckPermission(t *testing.T) {
+	testCases := []struct {
+		name           string
+		subject        string
+		hypotheticals  []string
+		expected       v1.CheckPermissionResponse_Permissionship
+		expectedStatus codes.Code
+	}{
+		{"no hypotheticals", "eng_lead", nil, v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, codes.OK},
+		{
+			"deleted",
+			"eng_lead",
+			[]string{"-document:masterplan#viewer@user:eng_lead"},
+			v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+			codes.OK,
+		},
+		{
+			"touched directly",
+			"villain",
+			[]string{"+document:masterplan#viewer@user:villain"},
+			v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+			codes.OK,
+		},
+		{
+			"touched through a parent",
+			"villain",
+			[]string{"+folder:strategy#viewer@user:villain"},
+			v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+			codes.OK,
+		},
+		{
+			"touched then deleted",
+			"villain",
+			[]string{"+folder:strategy#viewer@user:villain", "-folder:strategy#viewer@user:villain"},
+			v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+			codes.OK,
+		},
+		{"missing operation", "villain", []string{"document:masterplan#viewer@user:villain"}, 0, codes.InvalidArgument},
+		{"invalid relationship", "villain", []string{"+document:masterplan"}, 0, codes.InvalidArgument},
+		{"unknown relation", "villain", []string{"+document:masterplan#unknown@user:villain"}, 0, codes.FailedPrecondition},
+		{"permission", "villain", []string{"+document:masterplan#view@user:villain"}, 0, codes.InvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+			t.Cleanup(cleanup)
+			client := v1.NewPermissionsServiceClient(conn)
+
+			check := func(ctx context.Context) (*v1.CheckPermissionResponse, error) {
+				return client.CheckPermission(ctx, &v1.CheckPermissionRequest{
+					Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+					Resource:    obj("document", "masterplan"),
+					Permission:  "view",
+					Subject:     sub("user", tc.subject, ""),
+				})
+			}
+
+			before, err := check(context.Background())
+			require.NoError(err)
+
+			resp, err := check(withHypotheticals(tc.hypotheticals...))
+			if tc.expectedStatus == codes.OK {
+				require.NoError(err)
+				require.Equal(tc.expected, resp.Permissionship)
+			} else {
+				grpcutil.RequireStatus(t, tc.expectedStatus, err)
+			}
+
+			// Hypothetical relationships are never written.
+			after, err := check(context.Background())
+			require.NoError(err)
+			require.Equal(before.Permissionship, after.Permissionship)
+		})
+	}
+}
+
+func TestHypotheticalLookupResources(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+	client := v1.NewPermissionsServiceClient(conn)
+
+	stream, err := client.LookupResources(withHypotheticals("+folder:plans#viewer@user:villain"), &v1.LookupResourcesRequest{
+		Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+		ResourceObjectType: "document",
+		Permission:         "view",
+		Subject:            sub("user", "villain", ""),
+	})
+	require.NoError(err)
+
+	var resourceIDs []string
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		require.NoError(err)
+		resourceIDs = append(resourceIDs, resp.ResourceObjectId)
+	}
+	sort.Strings(resourceIDs)
+	require.Equal([]string{"healthplan", "masterplan"}, resourceIDs)
+}
+
+func TestHypotheticalExpandPermissionTree(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+	client := v1.NewPermissionsServiceClient(conn)
+
+	resp, err := client.ExpandPermissionTree(
+		withHypotheticals("+document:masterplan#viewer@user:villain", "-document:masterplan#viewer@user:eng_lead"),
+		&v1.ExpandPermissionTreeRequest{
+			Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+			Resource:    obj("document", "masterplan"),
+			Permission:  "viewer",
+		},
+	)
+	require.NoError(err)
+
+	var subjectIDs []string
+	for _, subject := range resp.TreeRoot.GetLeaf().GetSubjects() {
+		subjectIDs = append(subjectIDs, subject.Object.ObjectId)
+	}
+	require.Equal([]string{"villain"}, subjectIDs)
+}
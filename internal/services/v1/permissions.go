@@ -3,6 +3,8 @@ package v1
 import (
 	"context"
 	"fmt"
+	"sort"
+	"time"
 
 	"github.com/authzed/spicedb/pkg/datastore"
 
@@ -11,6 +13,7 @@ import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/jzelinskie/stringz"
 	"golang.org/x/sync/errgroup"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
@@ -22,6 +25,7 @@ import (
 	dispatchpkg "github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/graph"
 	"github.com/authzed/spicedb/internal/graph/computed"
+	log "github.com/authzed/spicedb/internal/logging"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
 	"github.com/authzed/spicedb/internal/namespace"
@@ -33,8 +37,20 @@ import (
 
 const maxCaveatContextBytes = 4096
 
+// RequestAllMissingContext, if specified in the request header of a CheckPermission call, makes
+// the partial_caveat_info of a conditional result list every caveat context parameter missing
+// from all of the caveats on which the permission depends, rather than only the parameters
+// missing from the first caveat evaluated, so that callers can request all of the required
+// context at once. The additional checks of the call are evaluated the same way.
+// Value: `1`
+const RequestAllMissingContext requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.allmissingcontext"
+
 func (ps *permissionServer) CheckPermission(ctx context.Context, req *v1.CheckPermissionRequest) (*v1.CheckPermissionResponse, error) {
 	atRevision, checkedAt := consistency.MustRevisionFromContext(ctx)
+	ctx, dispatcher, err := ps.hypotheticalDispatch(ctx, atRevision)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
 
 	caveatContext, err := getCaveatContext(ctx, req.Context)
@@ -66,39 +82,67 @@ func (ps *permissionServer) CheckPermission(ctx context.Context, req *v1.CheckPe
 		return nil, rewriteError(ctx, err)
 	}
 
+	err = ps.checkDeprecated(ctx, ds,
+		&core.RelationReference{Namespace: req.Resource.ObjectType, Relation: req.Permission},
+		&core.RelationReference{Namespace: req.Subject.Object.ObjectType, Relation: normalizeSubjectRelation(req.Subject)},
+	)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	checkValues, checks, err := additionalChecks(ctx, ds)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	ps.config.HotKeys.RecordCheck(req.Resource.ObjectType, tuple.StringObjectRef(req.Resource), tuple.StringSubjectRef(req.Subject))
+
 	isDebuggingEnabled := false
+	returnAllMissingContext := false
 	if md, ok := metadata.FromIncomingContext(ctx); ok {
 		_, isDebuggingEnabled = md[string(requestmeta.RequestDebugInformation)]
+		_, returnAllMissingContext = md[string(RequestAllMissingContext)]
 	}
 
-	cr, metadata, err := computed.ComputeCheck(ctx, ps.dispatch,
-		computed.CheckParameters{
-			ResourceType: &core.RelationReference{
-				Namespace: req.Resource.ObjectType,
-				Relation:  req.Permission,
-			},
-			Subject: &core.ObjectAndRelation{
-				Namespace: req.Subject.Object.ObjectType,
-				ObjectId:  req.Subject.Object.ObjectId,
-				Relation:  normalizeSubjectRelation(req.Subject),
-			},
-			CaveatContext:      caveatContext,
-			AtRevision:         atRevision,
-			MaximumDepth:       ps.config.MaximumAPIDepth,
-			IsDebuggingEnabled: isDebuggingEnabled,
+	// Sampled checks are evaluated with debugging, so that their traces can be shipped.
+	isSampled := ps.config.CheckTraces.Sample()
+
+	params := computed.CheckParameters{
+		ResourceType: &core.RelationReference{
+			Namespace: req.Resource.ObjectType,
+			Relation:  req.Permission,
 		},
-		req.Resource.ObjectId,
-	)
+		Subject: &core.ObjectAndRelation{
+			Namespace: req.Subject.Object.ObjectType,
+			ObjectId:  req.Subject.Object.ObjectId,
+			Relation:  normalizeSubjectRelation(req.Subject),
+		},
+		CaveatContext:           caveatContext,
+		AtRevision:              atRevision,
+		MaximumDepth:            ps.config.MaximumAPIDepth,
+		IsDebuggingEnabled:      isDebuggingEnabled || isSampled,
+		ReturnAllMissingContext: returnAllMissingContext,
+	}
+	startTime := time.Now()
+	cr, metadata, err := computed.ComputeCheck(ctx, dispatcher, params, req.Resource.ObjectId)
+	duration := time.Since(startTime)
 	usagemetrics.SetInContext(ctx, metadata)
 
-	if isDebuggingEnabled && metadata.DebugInfo != nil {
-		// Convert the dispatch debug information into API debug information and marshal into
-		// the footer.
-		converted, cerr := dispatchpkg.ConvertDispatchDebugInformation(ctx, metadata, ds)
+	var converted *v1.DebugInformation
+	if (isDebuggingEnabled || isSampled) && metadata.DebugInfo != nil {
+		// Convert the dispatch debug information into API debug information.
+		var cerr error
+		converted, cerr = dispatchpkg.ConvertDispatchDebugInformation(ctx, metadata, ds)
 		if cerr != nil {
-			return nil, rewriteError(ctx, cerr)
+			if isDebuggingEnabled {
+				return nil, rewriteError(ctx, cerr)
+			}
+			log.Ctx(ctx).Warn().Err(cerr).Msg("unable to convert sampled check trace")
 		}
+	}
 
+	if isDebuggingEnabled && converted != nil {
+		// Marshal the debug information into the footer.
 		marshaled, merr := protojson.Marshal(converted)
 		if merr != nil {
 			return nil, rewriteError(ctx, merr)
@@ -127,6 +171,30 @@ func (ps *permissionServer) CheckPermission(ctx context.Context, req *v1.CheckPe
 		}
 	}
 
+	if isSampled && converted != nil {
+		ps.config.CheckTraces.Ship(sampledCheckTrace(ctx, req, checkedAt, permissionship, duration, metadata, converted))
+	}
+
+	if len(checks) > 0 {
+		// The additional checks are evaluated at the same revision, and never with debugging.
+		params.IsDebuggingEnabled = false
+		results, checksMetadata, err := ps.computeAdditionalChecks(ctx, dispatcher, params, checkValues, checks)
+		usagemetrics.SetInContext(ctx, &dispatch.ResponseMeta{
+			DispatchCount:       metadata.DispatchCount + checksMetadata.DispatchCount,
+			CachedDispatchCount: metadata.CachedDispatchCount + checksMetadata.CachedDispatchCount,
+			DepthRequired:       maxUint32(metadata.DepthRequired, checksMetadata.DepthRequired),
+		})
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+
+		header, err := additionalChecksHeader(results)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+		_ = grpc.SetHeader(ctx, header)
+	}
+
 	return &v1.CheckPermissionResponse{
 		CheckedAt:         checkedAt,
 		Permissionship:    permissionship,
@@ -136,14 +204,29 @@ func (ps *permissionServer) CheckPermission(ctx context.Context, req *v1.CheckPe
 
 func (ps *permissionServer) ExpandPermissionTree(ctx context.Context, req *v1.ExpandPermissionTreeRequest) (*v1.ExpandPermissionTreeResponse, error) {
 	atRevision, expandedAt := consistency.MustRevisionFromContext(ctx)
+	page, err := expandPageFromContext(ctx, req)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+	if page != nil && page.cursor != nil {
+		atRevision, expandedAt, err = expandCursorRevision(ctx, page.cursor)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+	}
+
+	ctx, dispatcher, err := ps.hypotheticalDispatch(ctx, atRevision)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
 
-	err := namespace.CheckNamespaceAndRelation(ctx, req.Resource.ObjectType, req.Permission, false, ds)
+	err = namespace.CheckNamespaceAndRelation(ctx, req.Resource.ObjectType, req.Permission, false, ds)
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
 
-	resp, err := ps.dispatch.DispatchExpand(ctx, &dispatch.DispatchExpandRequest{
+	resp, err := dispatcher.DispatchExpand(ctx, &dispatch.DispatchExpandRequest{
 		Metadata: &dispatch.ResolverMeta{
 			AtRevision:     atRevision.String(),
 			DepthRemaining: ps.config.MaximumAPIDepth,
@@ -162,8 +245,27 @@ func (ps *permissionServer) ExpandPermissionTree(ctx context.Context, req *v1.Ex
 
 	// TODO(jschorr): Change to either using shared interfaces for nodes, or switch the internal
 	// dispatched expand to return V1 node types.
+	treeRoot := TranslateExpansionTree(resp.TreeNode)
+	if page != nil {
+		cursor, err := page.apply(treeRoot, req, expandedAt)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+		if cursor != "" {
+			_ = grpc.SetHeader(ctx, metadata.Pairs(ExpandCursorHeader, cursor))
+		}
+	}
+
+	if expandCaveatsRequested(ctx) {
+		header, err := expandCaveatsHeader(resp.TreeNode, treeRoot)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+		_ = grpc.SetHeader(ctx, header)
+	}
+
 	return &v1.ExpandPermissionTreeResponse{
-		TreeRoot:   TranslateExpansionTree(resp.TreeNode),
+		TreeRoot:   treeRoot,
 		ExpandedAt: expandedAt,
 	}, nil
 }
@@ -313,7 +415,14 @@ func TranslateExpansionTree(node *core.RelationTupleTreeNode) *v1.PermissionRela
 
 func (ps *permissionServer) LookupResources(req *v1.LookupResourcesRequest, resp v1.PermissionsService_LookupResourcesServer) error {
 	ctx := resp.Context()
-	atRevision, revisionReadAt := consistency.MustRevisionFromContext(ctx)
+	limiter, atRevision, revisionReadAt, err := ps.newStreamLimiter(ctx, resp, req)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+	ctx, dispatcher, err := ps.hypotheticalDispatch(ctx, atRevision)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
 
 	// Perform our preflight checks in parallel
@@ -340,8 +449,21 @@ func (ps *permissionServer) LookupResources(req *v1.LookupResourcesRequest, resp
 		return rewriteError(ctx, err)
 	}
 
+	fieldMask, err := responseFieldMaskFromContext(ctx, &v1.LookupResourcesResponse{})
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	candidates, err := lookupCandidates(ctx)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+	if candidates != nil {
+		return ps.lookupResourcesFromCandidates(ctx, dispatcher, req, resp, limiter, atRevision, revisionReadAt, fieldMask, candidates)
+	}
+
 	// TODO(jschorr): Change the internal dispatched lookup to also be streamed.
-	lookupResp, err := ps.dispatch.DispatchLookup(ctx, &dispatch.DispatchLookupRequest{
+	lookupResp, err := dispatcher.DispatchLookup(ctx, &dispatch.DispatchLookupRequest{
 		Metadata: &dispatch.ResolverMeta{
 			AtRevision:     atRevision.String(),
 			DepthRemaining: ps.config.MaximumAPIDepth,
@@ -363,6 +485,15 @@ func (ps *permissionServer) LookupResources(req *v1.LookupResourcesRequest, resp
 		return rewriteError(ctx, err)
 	}
 
+	// The resources of limited calls are sorted, so that the calls continuing them stream the
+	// remaining resources.
+	if limiter.limited() {
+		sort.Slice(lookupResp.ResolvedResources, func(i, j int) bool {
+			return lookupResp.ResolvedResources[i].ResourceId < lookupResp.ResolvedResources[j].ResourceId
+		})
+	}
+
+	var conditional []string
 	for _, found := range lookupResp.ResolvedResources {
 		var partial *v1.PartialCaveatInfo
 		permissionship := v1.LookupPermissionship_LOOKUP_PERMISSIONSHIP_HAS_PERMISSION
@@ -371,18 +502,28 @@ func (ps *permissionServer) LookupResources(req *v1.LookupResourcesRequest, resp
 			partial = &v1.PartialCaveatInfo{
 				MissingRequiredContext: found.MissingRequiredContext,
 			}
+			conditional = append(conditional, found.ResourceId)
 		}
 
-		err := resp.Send(&v1.LookupResourcesResponse{
+		response := &v1.LookupResourcesResponse{
 			LookedUpAt:        revisionReadAt,
 			ResourceObjectId:  found.ResourceId,
 			Permissionship:    permissionship,
 			PartialCaveatInfo: partial,
-		})
+		}
+		fieldMask.apply(response)
+
+		err := limiter.send(response)
 		if err != nil {
 			return err
 		}
 	}
+
+	if caveatProjectionRequested(ctx) {
+		if err := ps.setCaveatProjectionTrailer(ctx, dispatcher, req, resp, atRevision, conditional); err != nil {
+			return rewriteError(ctx, err)
+		}
+	}
 	return nil
 }
 
@@ -859,6 +859,74 @@ func TestCheckWithCaveats(t *testing.T) {
 	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
 }
 
+func TestCheckWithAllMissingContext(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, revision := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, false,
+		func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+			return tf.DatastoreFromSchemaAndTestRelationships(ds, `
+				caveat first_caveat(first int) {
+					first == 42
+				}
+
+				caveat second_caveat(second string, third string) {
+					second == 'hello' && third == 'world'
+				}
+
+				definition user {}
+
+				definition document {
+					relation viewer: user with first_caveat
+					relation editor: user with second_caveat
+					permission view = viewer & editor
+				}
+			`, []*core.RelationTuple{
+				tuple.WithCaveat(tuple.MustParse("document:first#viewer@user:tom"), "first_caveat"),
+				tuple.WithCaveat(tuple.MustParse("document:first#editor@user:tom"), "second_caveat"),
+			}, require)
+		})
+	client := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	request := &v1.CheckPermissionRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_AtLeastAsFresh{
+				AtLeastAsFresh: zedtoken.NewFromRevision(revision),
+			},
+		},
+		Resource:   obj("document", "first"),
+		Permission: "view",
+		Subject:    sub("user", "tom", ""),
+	}
+
+	// without the header, only the parameters of the first partially applied caveat are missing
+	checkResp, err := client.CheckPermission(context.Background(), request)
+	req.NoError(err)
+	req.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION, checkResp.Permissionship)
+	req.Len(checkResp.PartialCaveatInfo.MissingRequiredContext, 1)
+
+	ctx := requestmeta.AddRequestHeaders(context.Background(), v1svc.RequestAllMissingContext)
+	checkResp, err = client.CheckPermission(ctx, request)
+	req.NoError(err)
+	req.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION, checkResp.Permissionship)
+	req.Equal([]string{"first", "second", "third"}, checkResp.PartialCaveatInfo.MissingRequiredContext)
+
+	request.Context, err = structpb.NewStruct(map[string]any{"second": "hello"})
+	req.NoError(err)
+
+	checkResp, err = client.CheckPermission(ctx, request)
+	req.NoError(err)
+	req.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_CONDITIONAL_PERMISSION, checkResp.Permissionship)
+	req.Equal([]string{"first", "third"}, checkResp.PartialCaveatInfo.MissingRequiredContext)
+
+	// a caveat which can be fully applied determines the result
+	request.Context, err = structpb.NewStruct(map[string]any{"second": "goodbye"})
+	req.NoError(err)
+
+	checkResp, err = client.CheckPermission(ctx, request)
+	req.NoError(err)
+	req.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION, checkResp.Permissionship)
+}
+
 func TestLookupResourcesWithCaveats(t *testing.T) {
 	req := require.New(t)
 	conn, cleanup, _, revision := testserver.NewTestServer(req, testTimedeltas[0], memdb.DisableGC, true,
@@ -0,0 +1,111 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/namespace"
+	caveattypes "github.com/authzed/spicedb/pkg/caveats/types"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// RequestReflectCaveats, if specified in the request header of a ReadSchema call, asks SpiceDB to
+// also return a description of the caveats defined in the schema, in the CaveatsReflectionHeader
+// response header, so that clients can validate caveat contexts before writing relationships.
+// Value: `1`
+const RequestReflectCaveats requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.reflectcaveats"
+
+// CaveatsReflectionHeader is the response header holding the JSON-encoded list of
+// ReflectedCaveat describing the caveats defined in the schema, sorted by name.
+const CaveatsReflectionHeader = "io.spicedb.respmeta.caveats"
+
+// ReflectedCaveat describes a caveat defined in the schema.
+type ReflectedCaveat struct {
+	// Name is the name of the caveat.
+	Name string `json:"name"`
+
+	// Parameters are the parameters of the caveat, sorted by name.
+	Parameters []ReflectedCaveatParameter `json:"parameters"`
+
+	// AllowedOn are the relations and subject types with which the caveat may be written.
+	AllowedOn []ReflectedCaveatUse `json:"allowedOn"`
+}
+
+// ReflectedCaveatParameter describes a parameter of a caveat.
+type ReflectedCaveatParameter struct {
+	// Name is the name of the parameter.
+	Name string `json:"name"`
+
+	// Type is the type of the parameter, as written in the schema, such as `list<int>`.
+	Type string `json:"type"`
+}
+
+// ReflectedCaveatUse describes a relation and subject type with which a caveat may be written.
+type ReflectedCaveatUse struct {
+	// Definition is the name of the definition of the relation.
+	Definition string `json:"definition"`
+
+	// Relation is the name of the relation.
+	Relation string `json:"relation"`
+
+	// SubjectType is the subject type allowed with the caveat, such as `user`, `group#member` or
+	// `user:*`.
+	SubjectType string `json:"subjectType"`
+}
+
+// reflectedCaveatsHeader returns the response header describing the given caveats.
+func reflectedCaveatsHeader(caveatDefs []*core.CaveatDefinition, nsDefs []*core.NamespaceDefinition) (metadata.MD, error) {
+	reflected := make([]ReflectedCaveat, 0, len(caveatDefs))
+	byName := make(map[string]*ReflectedCaveat, len(caveatDefs))
+	for _, caveatDef := range caveatDefs {
+		parameters := make([]ReflectedCaveatParameter, 0, len(caveatDef.ParameterTypes))
+		for name, typeRef := range caveatDef.ParameterTypes {
+			varType, err := caveattypes.DecodeParameterType(typeRef)
+			if err != nil {
+				return nil, fmt.Errorf("invalid type for parameter %s of caveat %s: %w", name, caveatDef.Name, err)
+			}
+			parameters = append(parameters, ReflectedCaveatParameter{Name: name, Type: varType.String()})
+		}
+		sort.Slice(parameters, func(i, j int) bool { return parameters[i].Name < parameters[j].Name })
+
+		reflected = append(reflected, ReflectedCaveat{
+			Name:       caveatDef.Name,
+			Parameters: parameters,
+			AllowedOn:  []ReflectedCaveatUse{},
+		})
+	}
+	sort.Slice(reflected, func(i, j int) bool { return reflected[i].Name < reflected[j].Name })
+	for i := range reflected {
+		byName[reflected[i].Name] = &reflected[i]
+	}
+
+	for _, nsDef := range nsDefs {
+		for _, relation := range nsDef.Relation {
+			for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+				caveat, ok := byName[allowed.GetRequiredCaveat().GetCaveatName()]
+				if !ok {
+					continue
+				}
+
+				caveat.AllowedOn = append(caveat.AllowedOn, ReflectedCaveatUse{
+					Definition: nsDef.Name,
+					Relation:   relation.Name,
+					SubjectType: namespace.SourceForAllowedRelation(&core.AllowedRelation{
+						Namespace:          allowed.Namespace,
+						RelationOrWildcard: allowed.RelationOrWildcard,
+					}),
+				})
+			}
+		}
+	}
+
+	encoded, err := json.Marshal(reflected)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.Pairs(CaveatsReflectionHeader, string(encoded)), nil
+}
@@ -6,10 +6,14 @@ import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
 	"github.com/jzelinskie/stringz"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/checktraces"
 	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/hotkeys"
 	"github.com/authzed/spicedb/internal/middleware"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/handwrittenvalidation"
@@ -18,7 +22,7 @@ import (
 	"github.com/authzed/spicedb/internal/relationships"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/pkg/datastore"
-	"github.com/authzed/spicedb/pkg/middleware/consistency"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 	"github.com/authzed/spicedb/pkg/util"
@@ -38,8 +42,43 @@ type PermissionsServerConfig struct {
 	// MaximumAPIDepth is the default/starting depth remaining for API calls made
 	// to the permissions server.
 	MaximumAPIDepth uint32
+
+	// RejectDeprecatedRelations, if true, causes WriteRelationships and CheckPermission
+	// calls referencing relations or permissions marked as deprecated in the schema to be
+	// rejected, rather than merely warned about.
+	RejectDeprecatedRelations bool
+
+	// StrictRelationshipValidation holds the additional checks performed on the
+	// relationships written by WriteRelationships calls.
+	StrictRelationshipValidation relationships.StrictValidationMode
+
+	// DeleteJobs runs the delete jobs submitted to the DeleteJobService. If nil, the service is
+	// not registered.
+	DeleteJobs *shared.DeleteJobs
+
+	// RelationshipQuotas holds the quotas on the number of relationships which can be reached by
+	// WriteRelationships calls.
+	RelationshipQuotas shared.RelationshipQuotas
+
+	// StreamLimits holds the maximums enforced on the results streamed by a single
+	// ReadRelationships or LookupResources call.
+	StreamLimits StreamLimits
+
+	// CheckTraces samples CheckPermission calls and ships their debug traces. If nil, no calls
+	// are sampled.
+	CheckTraces *checktraces.Shipper
+
+	// HotKeys detects the resources, subjects and namespaces most frequently checked by
+	// CheckPermission calls. If nil, checks are not tracked.
+	HotKeys *hotkeys.Detector
 }
 
+// RelationshipQuotaUsageHeader is the response header of a WriteRelationships call which
+// updated relationships of object types with a quota, holding the usage of those quotas once the
+// write is applied.
+// Value: a comma-separated list of `namespace=count/quota`
+const RelationshipQuotaUsageHeader = "io.spicedb.respmeta.relationshipquotausage"
+
 // NewPermissionsServer creates a PermissionsServiceServer instance.
 func NewPermissionsServer(
 	dispatch dispatch.Dispatcher,
@@ -50,6 +89,14 @@ func NewPermissionsServer(
 		MaxPreconditionsCount: defaultIfZero(config.MaxPreconditionsCount, 1000),
 		MaxUpdatesPerWrite:    defaultIfZero(config.MaxUpdatesPerWrite, 1000),
 		MaximumAPIDepth:       defaultIfZero(config.MaximumAPIDepth, 50),
+
+		RejectDeprecatedRelations:    config.RejectDeprecatedRelations,
+		StrictRelationshipValidation: config.StrictRelationshipValidation,
+		DeleteJobs:                   config.DeleteJobs,
+		RelationshipQuotas:           config.RelationshipQuotas,
+		StreamLimits:                 config.StreamLimits,
+		CheckTraces:                  config.CheckTraces,
+		HotKeys:                      config.HotKeys,
 	}
 
 	return &permissionServer{
@@ -80,14 +127,14 @@ type permissionServer struct {
 	caveatsEnabled bool
 }
 
-func (ps *permissionServer) checkFilterComponent(ctx context.Context, objectType, optionalRelation string, ds datastore.Reader) error {
+func checkFilterComponent(ctx context.Context, objectType, optionalRelation string, ds datastore.Reader) error {
 	relationToTest := stringz.DefaultEmpty(optionalRelation, datastore.Ellipsis)
 	allowEllipsis := optionalRelation == ""
 	return namespace.CheckNamespaceAndRelation(ctx, objectType, relationToTest, allowEllipsis, ds)
 }
 
-func (ps *permissionServer) checkFilterNamespaces(ctx context.Context, filter *v1.RelationshipFilter, ds datastore.Reader) error {
-	if err := ps.checkFilterComponent(ctx, filter.ResourceType, filter.OptionalRelation, ds); err != nil {
+func checkFilterNamespaces(ctx context.Context, filter *v1.RelationshipFilter, ds datastore.Reader) error {
+	if err := checkFilterComponent(ctx, filter.ResourceType, filter.OptionalRelation, ds); err != nil {
 		return err
 	}
 
@@ -96,7 +143,7 @@ func (ps *permissionServer) checkFilterNamespaces(ctx context.Context, filter *v
 		if subjectFilter.OptionalRelation != nil {
 			subjectRelation = subjectFilter.OptionalRelation.Relation
 		}
-		if err := ps.checkFilterComponent(ctx, subjectFilter.SubjectType, subjectRelation, ds); err != nil {
+		if err := checkFilterComponent(ctx, subjectFilter.SubjectType, subjectRelation, ds); err != nil {
 			return err
 		}
 	}
@@ -106,10 +153,23 @@ func (ps *permissionServer) checkFilterNamespaces(ctx context.Context, filter *v
 
 func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest, resp v1.PermissionsService_ReadRelationshipsServer) error {
 	ctx := resp.Context()
-	atRevision, revisionReadAt := consistency.MustRevisionFromContext(ctx)
+	limiter, atRevision, revisionReadAt, err := ps.newStreamLimiter(ctx, resp, req)
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(atRevision)
 
-	if err := ps.checkFilterNamespaces(ctx, req.RelationshipFilter, ds); err != nil {
+	if err := checkFilterNamespaces(ctx, req.RelationshipFilter, ds); err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	fieldMask, err := responseFieldMaskFromContext(ctx, &v1.ReadRelationshipsResponse{})
+	if err != nil {
+		return rewriteError(ctx, err)
+	}
+
+	query, fieldMask, err := ps.relationshipQuery(ctx, req.RelationshipFilter, fieldMask, ds)
+	if err != nil {
 		return rewriteError(ctx, err)
 	}
 
@@ -117,6 +177,22 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 		DispatchCount: 1,
 	})
 
+	send := func(tpl *core.RelationTuple) error {
+		response := &v1.ReadRelationshipsResponse{
+			ReadAt:       revisionReadAt,
+			Relationship: tuple.ToRelationship(tpl),
+		}
+		fieldMask.apply(response)
+		return limiter.send(response)
+	}
+
+	if query != nil {
+		if err := query.Execute(ctx, ds, send); err != nil {
+			return rewriteError(ctx, err)
+		}
+		return nil
+	}
+
 	tupleIterator, err := ds.QueryRelationships(ctx, datastore.RelationshipsFilterFromPublicFilter(req.RelationshipFilter))
 	if err != nil {
 		return rewriteError(ctx, err)
@@ -124,10 +200,7 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 	defer tupleIterator.Close()
 
 	for tpl := tupleIterator.Next(); tpl != nil; tpl = tupleIterator.Next() {
-		err := resp.Send(&v1.ReadRelationshipsResponse{
-			ReadAt:       revisionReadAt,
-			Relationship: tuple.ToRelationship(tpl),
-		})
+		err := send(tpl)
 		if err != nil {
 			return err
 		}
@@ -176,21 +249,56 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 	}
 
 	// Execute the write operation(s).
+	var quotaUsage []shared.RelationshipQuotaUsage
 	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
 		// Validate the preconditions.
 		for _, precond := range req.OptionalPreconditions {
-			if err := ps.checkFilterNamespaces(ctx, precond.Filter, rwt); err != nil {
+			if err := checkFilterNamespaces(ctx, precond.Filter, rwt); err != nil {
 				return err
 			}
 		}
 
-		// Validate the updates.
+		// Add the relationships of any templates applied.
 		tupleUpdates := tuple.UpdateFromRelationshipUpdates(req.Updates)
-		err := relationships.ValidateRelationshipUpdates(ctx, rwt, tupleUpdates)
+		appliedUpdates, err := templateUpdates(ctx, rwt, tupleUpdates)
+		if err != nil {
+			return err
+		}
+
+		tupleUpdates = append(tupleUpdates, appliedUpdates...)
+		if len(tupleUpdates) > int(ps.config.MaxUpdatesPerWrite) {
+			return NewExceedsMaximumUpdatesErr(uint16(len(tupleUpdates)), ps.config.MaxUpdatesPerWrite)
+		}
+
+		// Validate the updates.
+		err = relationships.ValidateRelationshipUpdates(ctx, rwt, tupleUpdates, ps.config.StrictRelationshipValidation)
 		if err != nil {
 			return rewriteError(ctx, err)
 		}
 
+		// Deleting relationships under deprecated relations is how they are migrated away from,
+		// so only the relationships being written are checked.
+		referencedRelations := make([]*core.RelationReference, 0, len(tupleUpdates)*2)
+		for _, update := range tupleUpdates {
+			if update.Operation == core.RelationTupleUpdate_DELETE {
+				continue
+			}
+
+			referencedRelations = append(referencedRelations,
+				&core.RelationReference{
+					Namespace: update.Tuple.ResourceAndRelation.Namespace,
+					Relation:  update.Tuple.ResourceAndRelation.Relation,
+				},
+				&core.RelationReference{
+					Namespace: update.Tuple.Subject.Namespace,
+					Relation:  update.Tuple.Subject.Relation,
+				},
+			)
+		}
+		if err := ps.checkDeprecated(ctx, rwt, referencedRelations...); err != nil {
+			return err
+		}
+
 		usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
 			// One request per precondition and one request for the actual writes.
 			DispatchCount: uint32(len(req.OptionalPreconditions)) + 1,
@@ -200,14 +308,31 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 			return err
 		}
 
+		// Keep any relations being renamed in sync with their new names.
+		tupleUpdates, err = relationships.WithRenamedRelationUpdates(ctx, rwt, tupleUpdates)
+		if err != nil {
+			return err
+		}
+
+		quotaUsage, err = ps.config.RelationshipQuotas.Check(ctx, rwt, tupleUpdates)
+		if err != nil {
+			return err
+		}
+
 		return rwt.WriteRelationships(ctx, tupleUpdates)
 	})
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
 
+	if len(quotaUsage) > 0 {
+		// NOTE: setting the header fails when not invoked within a gRPC server, in which case the
+		// write has still been applied.
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RelationshipQuotaUsageHeader, shared.FormatRelationshipQuotaUsage(quotaUsage)))
+	}
+
 	return &v1.WriteRelationshipsResponse{
-		WrittenAt: zedtoken.NewFromRevision(revision),
+		WrittenAt: zedtoken.NewFromRevisionForDatastore(revision, datastoremw.UniqueIDFromContext(ctx)),
 	}, nil
 }
 
@@ -219,11 +344,18 @@ func (ps *permissionServer) DeleteRelationships(ctx context.Context, req *v1.Del
 		)
 	}
 
+	filters, err := ps.deleteFilters(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
 	ds := datastoremw.MustFromContext(ctx)
 
 	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
-		if err := ps.checkFilterNamespaces(ctx, req.RelationshipFilter, rwt); err != nil {
-			return err
+		for _, filter := range filters {
+			if err := checkFilterNamespaces(ctx, filter, rwt); err != nil {
+				return err
+			}
 		}
 
 		usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
@@ -235,13 +367,26 @@ func (ps *permissionServer) DeleteRelationships(ctx context.Context, req *v1.Del
 			return err
 		}
 
-		return rwt.DeleteRelationships(ctx, req.RelationshipFilter)
+		for _, filter := range filters {
+			// Keep any relations being renamed in sync with their new names.
+			renamedFilters, err := relationships.RenamedRelationFilters(ctx, rwt, filter)
+			if err != nil {
+				return err
+			}
+
+			for _, filter := range append([]*v1.RelationshipFilter{filter}, renamedFilters...) {
+				if err := rwt.DeleteRelationships(ctx, filter); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
 
 	return &v1.DeleteRelationshipsResponse{
-		DeletedAt: zedtoken.NewFromRevision(revision),
+		DeletedAt: zedtoken.NewFromRevisionForDatastore(revision, datastoremw.UniqueIDFromContext(ctx)),
 	}, nil
 }
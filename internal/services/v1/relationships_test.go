@@ -11,12 +11,16 @@ import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/grpcutil"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/types/known/structpb"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	tf "github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -983,6 +987,64 @@ func TestDeleteRelationshipsPreconditionsOverLimit(t *testing.T) {
 	require.Contains(err.Error(), "precondition count of 2 is greater than maximum allowed of 1")
 }
 
+func TestDeleteRelationshipsAdditionalFilters(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, _, revision := testserver.NewTestServerWithConfig(
+		require,
+		testTimedeltas[0],
+		memdb.DisableGC,
+		true,
+		testserver.ServerConfig{
+			MaxPreconditionsCount: 10,
+			MaxUpdatesPerWrite:    3,
+		},
+		tf.StandardDatastoreWithData,
+	)
+	client := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	deleteWithFilters := func(preconditions []*v1.Precondition, filters ...string) (*v1.DeleteRelationshipsResponse, error) {
+		md := metadata.MD{}
+		for _, filter := range filters {
+			md.Append(string(v1svc.RequestDeleteAdditionalFilters), filter)
+		}
+		return client.DeleteRelationships(metadata.NewOutgoingContext(context.Background(), md), &v1.DeleteRelationshipsRequest{
+			RelationshipFilter: &v1.RelationshipFilter{
+				ResourceType:       "folder",
+				OptionalResourceId: "auditors",
+			},
+			OptionalPreconditions: preconditions,
+		})
+	}
+
+	_, err := deleteWithFilters(nil, "folder:company#viewer@user:legal", "document@")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, err = deleteWithFilters(nil, "folder:company#viewer@user:legal", "document:masterplan", "document:healthplan")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.ErrorContains(err, "filter count of 4 is greater than maximum allowed of 3")
+
+	_, err = deleteWithFilters(nil, "unknown:first")
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	// A failed precondition deletes the relationships of none of the filters.
+	_, err = deleteWithFilters([]*v1.Precondition{{
+		Operation: v1.Precondition_OPERATION_MUST_MATCH,
+		Filter:    &v1.RelationshipFilter{ResourceType: "folder", OptionalResourceId: "unknown"},
+	}}, "folder:company#viewer@user:legal")
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	require.Equal(standardTuplesWithout(nil), readAll(require, client, zedtoken.NewFromRevision(revision)))
+
+	resp, err := deleteWithFilters(nil, "folder:company#viewer@user:legal", "document:masterplan#parent")
+	require.NoError(err)
+	require.Equal(standardTuplesWithout(map[string]struct{}{
+		"folder:auditors#viewer@user:auditor":        {},
+		"folder:company#viewer@user:legal":           {},
+		"document:masterplan#parent@folder:strategy": {},
+		"document:masterplan#parent@folder:plans":    {},
+	}), readAll(require, client, resp.DeletedAt))
+}
+
 func TestWriteRelationshipsPreconditionsOverLimit(t *testing.T) {
 	require := require.New(t)
 	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(
@@ -1065,6 +1127,120 @@ func TestWriteRelationshipsUpdatesOverLimit(t *testing.T) {
 	require.Contains(err.Error(), "update count of 2 is greater than maximum allowed of 1")
 }
 
+func TestWriteRelationshipsQuotas(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(
+		require,
+		testTimedeltas[0],
+		memdb.DisableGC,
+		true,
+		testserver.ServerConfig{
+			MaxPreconditionsCount: 10,
+			MaxUpdatesPerWrite:    10,
+			RelationshipQuotas:    map[string]string{"folder": "9"},
+		},
+		tf.StandardDatastoreWithData,
+	)
+	client := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	write := func(updates ...*v1.RelationshipUpdate) (metadata.MD, error) {
+		var header metadata.MD
+		_, err := client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+			Updates: updates,
+		}, grpc.Header(&header))
+		return header, err
+	}
+
+	// The standard data holds 8 relationships of folders.
+	header, err := write(&v1.RelationshipUpdate{
+		Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+		Relationship: rel("folder", "newfolder", "viewer", "user", "alice", ""),
+	})
+	require.NoError(err)
+	require.Equal([]string{"folder=9/9"}, header.Get(v1svc.RelationshipQuotaUsageHeader))
+
+	// Writes to object types without a quota are not limited.
+	header, err = write(&v1.RelationshipUpdate{
+		Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+		Relationship: rel("document", "newdoc", "viewer", "user", "alice", ""),
+	})
+	require.NoError(err)
+	require.Empty(header.Get(v1svc.RelationshipQuotaUsageHeader))
+
+	_, err = write(&v1.RelationshipUpdate{
+		Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+		Relationship: rel("folder", "newfolder", "viewer", "user", "bob", ""),
+	})
+	grpcutil.RequireStatus(t, codes.ResourceExhausted, err)
+	require.ErrorContains(err, "write would bring the number of relationships of `folder` to 10, which exceeds its quota of 9")
+
+	grpcStatus, _ := status.FromError(err)
+	require.Len(grpcStatus.Details(), 1)
+	require.Equal("relationships:folder", grpcStatus.Details()[0].(*errdetails.QuotaFailure).Violations[0].Subject)
+
+	// Touching existing relationships, or replacing them, does not grow their number.
+	header, err = write(
+		&v1.RelationshipUpdate{
+			Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+			Relationship: rel("folder", "newfolder", "viewer", "user", "alice", ""),
+		},
+		&v1.RelationshipUpdate{
+			Operation:    v1.RelationshipUpdate_OPERATION_DELETE,
+			Relationship: rel("folder", "isolated", "viewer", "user", "villain", ""),
+		},
+		&v1.RelationshipUpdate{
+			Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+			Relationship: rel("folder", "newfolder", "viewer", "user", "bob", ""),
+		},
+	)
+	require.NoError(err)
+	require.Equal([]string{"folder=9/9"}, header.Get(v1svc.RelationshipQuotaUsageHeader))
+}
+
+func TestZedTokensOfDatastore(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, ds, revision := testserver.NewTestServer(require, testTimedeltas[0], memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	stats, err := ds.Statistics(context.Background())
+	require.NoError(err)
+
+	// Tokens minted by the server record the unique ID of its datastore.
+	resp, err := client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+			Relationship: rel("document", "newdoc", "viewer", "user", "alice", ""),
+		}},
+	})
+	require.NoError(err)
+
+	decoded, err := zedtoken.Decode(resp.WrittenAt)
+	require.NoError(err)
+	require.Equal(stats.UniqueID, decoded.GetV1().GetDatastoreId())
+
+	read := func(token *v1.ZedToken) error {
+		stream, err := client.ReadRelationships(context.Background(), &v1.ReadRelationshipsRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtExactSnapshot{AtExactSnapshot: token},
+			},
+			RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+		})
+		require.NoError(err)
+		_, err = stream.Recv()
+		return err
+	}
+
+	// Tokens of the datastore are accepted, whether or not they record its unique ID.
+	require.NoError(read(resp.WrittenAt))
+	require.NoError(read(zedtoken.NewFromRevision(revision)))
+
+	err = read(zedtoken.NewFromRevisionForDatastore(revision, "otherdatastore"))
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.ErrorContains(err, "zedtoken was minted by datastore otherdatastore")
+}
+
 func readAll(require *require.Assertions, client v1.PermissionsServiceClient, token *v1.ZedToken) map[string]struct{} {
 	got := make(map[string]struct{})
 	namespaces := []string{"document", "folder"}
@@ -0,0 +1,102 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// RequestRelationshipQuery, if specified in the request header of a ReadRelationships call,
+// experimentally returns the relationships matched by a query written in the relationship query
+// language rather than by the relationship_filter of the request, which must only give the
+// resource type of the query. A query can join through one arrow hop, as in schema arrows, and
+// its `select` clause projects the returned relationships, as with RequestResponseFieldMask.
+// See relationships.RelationshipQuery for the syntax of queries.
+// Value: a query such as `document:readme#parent -> viewer@user select subject.id limit 100`
+const RequestRelationshipQuery requestmeta.RequestMetadataHeaderKey = "io.spicedb.experimental.relationshipquery"
+
+// relationshipQueryFields are the fields which may be selected by a relationship query, along with
+// their path in the ReadRelationshipsResponse.
+var relationshipQueryFields = map[string]string{
+	"read_at":          "read_at",
+	"resource":         "relationship.resource",
+	"resource.type":    "relationship.resource.object_type",
+	"resource.id":      "relationship.resource.object_id",
+	"relation":         "relationship.relation",
+	"subject":          "relationship.subject",
+	"subject.type":     "relationship.subject.object.object_type",
+	"subject.id":       "relationship.subject.object.object_id",
+	"subject.relation": "relationship.subject.optional_relation",
+	"caveat":           "relationship.optional_caveat",
+}
+
+// relationshipQuery returns the relationship query of a ReadRelationships call, validated against
+// its filter and the schema, along with the field mask of its selected fields, or nil if the call
+// has no query.
+func (ps *permissionServer) relationshipQuery(ctx context.Context, filter *v1.RelationshipFilter, fieldMask responseFieldMask, reader datastore.Reader) (*relationships.RelationshipQuery, responseFieldMask, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, fieldMask, nil
+	}
+
+	values := md.Get(string(RequestRelationshipQuery))
+	switch len(values) {
+	case 0:
+		return nil, fieldMask, nil
+	case 1:
+	default:
+		return nil, nil, status.Errorf(codes.InvalidArgument, "only one relationship query may be given")
+	}
+
+	query, err := relationships.ParseQuery(values[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if filter.ResourceType != query.Resource.ObjectType || filter.OptionalResourceId != "" || filter.OptionalRelation != "" || filter.OptionalSubjectFilter != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "the relationship filter of a call with a relationship query must only give the resource type `%s` of the query", query.Resource.ObjectType)
+	}
+
+	patterns := []*relationships.ObjectPattern{&query.Resource, query.OptionalSubject}
+	if query.OptionalHop != nil {
+		patterns = append(patterns, query.OptionalHop.OptionalSubject)
+	}
+	for _, pattern := range patterns {
+		if pattern == nil {
+			continue
+		}
+		if err := checkFilterComponent(ctx, pattern.ObjectType, pattern.OptionalRelation, reader); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if len(query.Select) == 0 {
+		return query, fieldMask, nil
+	}
+	if fieldMask != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "a relationship query with a `select` clause cannot be combined with a response field mask")
+	}
+
+	paths := make([]string, 0, len(query.Select))
+	for _, field := range query.Select {
+		path, ok := relationshipQueryFields[field]
+		if !ok {
+			return nil, nil, relationships.NewInvalidQueryError(values[0], fmt.Errorf("unknown field `%s`", field))
+		}
+		paths = append(paths, path)
+	}
+
+	fieldMask, err = newResponseFieldMask(&v1.ReadRelationshipsResponse{}, paths)
+	if err != nil {
+		return nil, nil, err
+	}
+	return query, fieldMask, nil
+}
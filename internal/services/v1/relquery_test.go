@@ -0,0 +1,160 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestReadRelationshipsWithQuery(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		filter         *v1.RelationshipFilter
+		fieldMask      string
+		expected       []string
+		expectedStatus codes.Code
+	}{
+		{
+			"arrow hop",
+			"document:masterplan#parent -> viewer",
+			&v1.RelationshipFilter{ResourceType: "document"},
+			"",
+			[]string{"folder:plans#viewer@user:chief_financial_officer"},
+			codes.OK,
+		},
+		{
+			"select",
+			"document:masterplan#parent select resource.id, subject.id",
+			&v1.RelationshipFilter{ResourceType: "document"},
+			"",
+			[]string{":masterplan#@:strategy", ":masterplan#@:plans"},
+			codes.OK,
+		},
+		{
+			"field mask",
+			"document:masterplan#parent",
+			&v1.RelationshipFilter{ResourceType: "document"},
+			"relationship.subject",
+			[]string{":#@folder:strategy", ":#@folder:plans"},
+			codes.OK,
+		},
+		{
+			"filter of another resource type",
+			"document:masterplan#parent",
+			&v1.RelationshipFilter{ResourceType: "folder"},
+			"",
+			nil,
+			codes.InvalidArgument,
+		},
+		{
+			"filter beyond the resource type",
+			"document:masterplan#parent",
+			&v1.RelationshipFilter{ResourceType: "document", OptionalRelation: "parent"},
+			"",
+			nil,
+			codes.InvalidArgument,
+		},
+		{
+			"invalid query",
+			"document:masterplan#parent ->",
+			&v1.RelationshipFilter{ResourceType: "document"},
+			"",
+			nil,
+			codes.InvalidArgument,
+		},
+		{
+			"unknown relation",
+			"document#unknown",
+			&v1.RelationshipFilter{ResourceType: "document"},
+			"",
+			nil,
+			codes.FailedPrecondition,
+		},
+		{
+			"unknown field",
+			"document select unknown",
+			&v1.RelationshipFilter{ResourceType: "document"},
+			"",
+			nil,
+			codes.InvalidArgument,
+		},
+		{
+			"select and field mask",
+			"document select subject",
+			&v1.RelationshipFilter{ResourceType: "document"},
+			"relationship.subject",
+			nil,
+			codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+			t.Cleanup(cleanup)
+
+			md := metadata.Pairs(string(v1svc.RequestRelationshipQuery), tc.query)
+			if tc.fieldMask != "" {
+				md.Append(string(v1svc.RequestResponseFieldMask), tc.fieldMask)
+			}
+
+			stream, err := v1.NewPermissionsServiceClient(conn).ReadRelationships(metadata.NewOutgoingContext(context.Background(), md), &v1.ReadRelationshipsRequest{
+				Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+				RelationshipFilter: tc.filter,
+			})
+			require.NoError(err)
+
+			var found []string
+			for {
+				resp, err := stream.Recv()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if tc.expectedStatus != codes.OK {
+					grpcutil.RequireStatus(t, tc.expectedStatus, err)
+					return
+				}
+				require.NoError(err)
+
+				rel := resp.Relationship
+				found = append(found, rel.Resource.GetObjectType()+":"+rel.Resource.GetObjectId()+"#"+rel.Relation+
+					"@"+rel.Subject.GetObject().GetObjectType()+":"+rel.Subject.GetObject().GetObjectId()+
+					subjectRelationSuffix(rel.Subject.GetOptionalRelation()))
+			}
+			require.Equal(codes.OK, tc.expectedStatus)
+
+			var expected []string
+			for _, rel := range tc.expected {
+				if parsed := tuple.ParseRel(rel); parsed != nil {
+					rel = tuple.StringRelationship(parsed)
+				}
+				expected = append(expected, rel)
+			}
+			require.ElementsMatch(expected, found)
+		})
+	}
+}
+
+func subjectRelationSuffix(relation string) string {
+	if relation == "" {
+		return ""
+	}
+	return "#" + relation
+}
@@ -3,16 +3,22 @@ package v1
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/authzed/authzed-go/pkg/requestmeta"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/auth"
 	log "github.com/authzed/spicedb/internal/logging"
 	"github.com/authzed/spicedb/internal/middleware/consistency"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/pkg/datastore"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
@@ -21,8 +27,16 @@ import (
 	"github.com/authzed/spicedb/pkg/schemadsl/input"
 )
 
-// NewSchemaServer creates a SchemaServiceServer instance.
-func NewSchemaServer(additiveOnly, caveatsEnabled bool) v1.SchemaServiceServer {
+// RequestSchemaDryRun, if specified in the request header of a WriteSchema call, asks SpiceDB
+// to compile, type check and check the schema for breaking changes against the current contents
+// of the datastore, without writing it. All problems found are returned in the error.
+// Value: `1`
+const RequestSchemaDryRun requestmeta.BoolRequestMetadataHeaderKey = "io.spicedb.requestschemadryrun"
+
+// NewSchemaServer creates a SchemaServiceServer instance. If softDelete is true, object definitions
+// removed from the schema while relationships still exist for them are soft-deleted. Schemas
+// exceeding the given limits are rejected.
+func NewSchemaServer(additiveOnly, caveatsEnabled, softDelete bool, limits shared.SchemaLimits) v1.SchemaServiceServer {
 	return &schemaServer{
 		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
 			Unary:  grpcvalidate.UnaryServerInterceptor(true),
@@ -30,6 +44,9 @@ func NewSchemaServer(additiveOnly, caveatsEnabled bool) v1.SchemaServiceServer {
 		},
 		additiveOnly:   additiveOnly,
 		caveatsEnabled: caveatsEnabled,
+		softDelete:     softDelete,
+		limits:         limits,
+		validator:      shared.NewIncrementalSchemaValidator(),
 	}
 }
 
@@ -39,13 +56,16 @@ type schemaServer struct {
 
 	additiveOnly   bool
 	caveatsEnabled bool
+	softDelete     bool
+	limits         shared.SchemaLimits
+	validator      *shared.IncrementalSchemaValidator
 }
 
 func (ss *schemaServer) ReadSchema(ctx context.Context, in *v1.ReadSchemaRequest) (*v1.ReadSchemaResponse, error) {
 	readRevision, _ := consistency.MustRevisionFromContext(ctx)
 	ds := datastoremw.MustFromContext(ctx).SnapshotReader(readRevision)
 
-	nsDefs, err := ds.ListNamespaces(ctx)
+	nsDefs, err := namespace.ListLiveNamespaces(ctx, ds)
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
@@ -70,6 +90,30 @@ func (ss *schemaServer) ReadSchema(ctx context.Context, in *v1.ReadSchemaRequest
 
 	schemaText, _ := generator.GenerateSchema(schemaDefinitions)
 
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if _, reflect := md[string(RequestReflectCaveats)]; reflect {
+			header, err := reflectedCaveatsHeader(caveatDefs, nsDefs)
+			if err != nil {
+				return nil, rewriteError(ctx, err)
+			}
+
+			if err := grpc.SetHeader(ctx, header); err != nil {
+				return nil, rewriteError(ctx, err)
+			}
+		}
+
+		if _, reflect := md[string(RequestReflectDeprecations)]; reflect {
+			header, err := reflectedDeprecationsHeader(nsDefs)
+			if err != nil {
+				return nil, rewriteError(ctx, err)
+			}
+
+			if err := grpc.SetHeader(ctx, header); err != nil {
+				return nil, rewriteError(ctx, err)
+			}
+		}
+	}
+
 	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
 		DispatchCount: uint32(len(nsDefs) + len(caveatDefs)),
 	})
@@ -84,6 +128,10 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 
 	ds := datastoremw.MustFromContext(ctx)
 
+	if err := ss.limits.CheckSchemaSize(in.GetSchema()); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
 	// Compile the schema into the namespace definitions.
 	emptyDefaultPrefix := ""
 	compiled, err := compiler.Compile(compiler.InputSchema{
@@ -99,14 +147,25 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 		return nil, fmt.Errorf("caveats are currently not supported")
 	}
 
-	// Do as much validation as we can before talking to the datastore.
-	validated, err := shared.ValidateSchemaChanges(ctx, compiled, ss.additiveOnly)
+	if err := ss.limits.CheckDefinitions(compiled); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	// Do as much validation as we can before talking to the datastore, only revalidating the
+	// definitions affected by changes since the last schema validated.
+	validated, err := ss.validator.ValidateSchemaChanges(ctx, compiled, ss.additiveOnly, ss.softDelete)
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
 
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if _, isDryRun := md[string(RequestSchemaDryRun)]; isDryRun {
+			return ss.dryRunWriteSchema(ctx, ds, validated)
+		}
+	}
+
 	// Update the schema.
-	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+	revision, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
 		applied, err := shared.ApplySchemaChanges(ctx, rwt, validated)
 		if err != nil {
 			return err
@@ -120,5 +179,42 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 		return nil, rewriteError(ctx, err)
 	}
 
+	// Schema changes are always logged with the principal which made them, so that they can be
+	// attributed even when the audit log is disabled.
+	log.Ctx(ctx).Info().
+		Str("principal", auth.CallerFromContext(ctx)).
+		Stringer("revision", revision).
+		Int("objectDefinitions", len(compiled.ObjectDefinitions)).
+		Int("caveatDefinitions", len(compiled.CaveatDefinitions)).
+		Msg("wrote schema")
+
+	return &v1.WriteSchemaResponse{}, nil
+}
+
+func (ss *schemaServer) dryRunWriteSchema(ctx context.Context, ds datastore.Datastore, validated *shared.ValidatedSchemaChanges) (*v1.WriteSchemaResponse, error) {
+	headRevision, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	diagnostics, err := shared.DiagnoseSchemaChanges(ctx, ds.SnapshotReader(headRevision), validated)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: 1,
+	})
+
+	if len(diagnostics) > 0 {
+		messages := make([]string, 0, len(diagnostics))
+		for _, diagnostic := range diagnostics {
+			messages = append(messages, status.Convert(diagnostic).Message())
+		}
+
+		return nil, status.Errorf(codes.InvalidArgument, "schema dry run found %d problem(s):\n%s", len(diagnostics), strings.Join(messages, "\n"))
+	}
+
+	log.Ctx(ctx).Trace().Msg("schema dry run completed without finding any problems")
 	return &v1.WriteSchemaResponse{}, nil
 }
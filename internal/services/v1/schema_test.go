@@ -2,16 +2,22 @@ package v1_test
 
 import (
 	"context"
+	"encoding/json"
 	"testing"
 
 	"google.golang.org/protobuf/types/known/structpb"
 
+	"github.com/authzed/authzed-go/pkg/requestmeta"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/grpcutil"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/services/shared"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	tf "github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -289,6 +295,67 @@ func TestSchemaDeleteDefinition(t *testing.T) {
 	require.Equal(t, `definition example/user {}`, readback.SchemaText)
 }
 
+func TestSchemaSoftDeleteDefinition(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(require.New(t), 0, memdb.DisableGC, true,
+		testserver.ServerConfig{
+			MaxUpdatesPerWrite:          1000,
+			MaxPreconditionsCount:       1000,
+			SchemaSoftDeleteDefinitions: true,
+		},
+		tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1.NewSchemaServiceClient(conn)
+	v1client := v1.NewPermissionsServiceClient(conn)
+
+	// Write a basic schema.
+	_, err := client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+	
+		definition example/document {
+			relation somerelation: example/user
+		}`,
+	})
+	require.NoError(t, err)
+
+	// Write a relationship for the document.
+	_, err = v1client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(tuple.Create(
+			tuple.MustParse("example/document:somedoc#somerelation@example/user:someuser#..."),
+		))},
+	})
+	require.NoError(t, err)
+
+	// Delete the `document` type, which should succeed despite the relationship.
+	_, err = client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition example/user {}`,
+	})
+	require.NoError(t, err)
+
+	// Ensure it is hidden from the schema and the permissions API.
+	readback, err := client.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
+	require.NoError(t, err)
+	require.Equal(t, `definition example/user {}`, readback.SchemaText)
+
+	_, err = v1client.CheckPermission(context.Background(), &v1.CheckPermissionRequest{
+		Consistency: &v1.Consistency{
+			Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true},
+		},
+		Resource:   obj("example/document", "somedoc"),
+		Permission: "somerelation",
+		Subject:    sub("example/user", "someuser", ""),
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	// Ensure it cannot be re-created until purged.
+	_, err = client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {}`,
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	require.ErrorContains(t, err, "its relationships are still being purged")
+}
+
 func TestSchemaRemoveWildcard(t *testing.T) {
 	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
 	t.Cleanup(cleanup)
@@ -549,3 +616,174 @@ func TestSchemaUnchangedNamespaces(t *testing.T) {
 
 	require.True(t, docRevision.GreaterThan(userRevision))
 }
+
+func TestSchemaWriteDryRun(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1.NewSchemaServiceClient(conn)
+	v1client := v1.NewPermissionsServiceClient(conn)
+
+	originalSchema := "definition example/document {\n\trelation anotherrelation: example/user\n\trelation somerelation: example/user\n}\n\ndefinition example/user {}"
+	_, err := client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: originalSchema,
+	})
+	require.NoError(t, err)
+
+	_, err = v1client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(
+				tuple.MustParse("example/document:somedoc#somerelation@example/user:someuser#..."),
+			)),
+			tuple.UpdateToRelationshipUpdate(tuple.Create(
+				tuple.MustParse("example/document:somedoc#anotherrelation@example/user:someuser#..."),
+			)),
+		},
+	})
+	require.NoError(t, err)
+
+	dryRunCtx := requestmeta.AddRequestHeaders(context.Background(), v1svc.RequestSchemaDryRun)
+
+	// A valid, non-breaking change should succeed without being written.
+	_, err = client.WriteSchema(dryRunCtx, &v1.WriteSchemaRequest{
+		Schema: originalSchema + "\n\ndefinition example/folder {}",
+	})
+	require.NoError(t, err)
+
+	readback, err := client.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
+	require.NoError(t, err)
+	require.Equal(t, originalSchema, readback.SchemaText)
+
+	// An invalid schema should fail to compile.
+	_, err = client.WriteSchema(dryRunCtx, &v1.WriteSchemaRequest{
+		Schema: `invalid example/user {}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// A breaking change should return all problems found.
+	_, err = client.WriteSchema(dryRunCtx, &v1.WriteSchemaRequest{
+		Schema: `definition example/document {}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.ErrorContains(t, err, "schema dry run found 3 problem(s)")
+	require.ErrorContains(t, err, "cannot delete relation `somerelation` in object definition `example/document`, as a relationship exists under it")
+	require.ErrorContains(t, err, "cannot delete relation `anotherrelation` in object definition `example/document`, as a relationship exists under it")
+	require.ErrorContains(t, err, "cannot delete object definition `example/user`, as a relationship references it")
+
+	readback, err = client.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
+	require.NoError(t, err)
+	require.Equal(t, originalSchema, readback.SchemaText)
+}
+
+func TestSchemaWriteLimits(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(require.New(t), 0, memdb.DisableGC, false,
+		testserver.ServerConfig{
+			MaxUpdatesPerWrite:    1000,
+			MaxPreconditionsCount: 1000,
+			SchemaLimits:          shared.SchemaLimits{MaxRelationsPerDefinition: 2, MaxUnionTermsPerPermission: 2},
+		},
+		tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1.NewSchemaServiceClient(conn)
+
+	// Write a schema within the limits.
+	_, err := client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition user {}
+
+		definition document {
+			relation viewer: user
+			permission view = viewer + nil
+		}`,
+	})
+	require.NoError(t, err)
+
+	// Write a schema with a union of too many terms.
+	_, err = client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition user {}
+
+		definition document {
+			relation viewer: user
+			permission view = viewer + nil + nil
+		}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.ErrorContains(t, err, "permission `view` under definition `document` has a union of 3 terms, which exceeds the maximum allowed of 2")
+
+	// Write a schema with too many relations.
+	_, err = client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition user {}
+
+		definition document {
+			relation viewer: user
+			relation editor: user
+			permission view = viewer + editor
+		}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.ErrorContains(t, err, "definition `document` has 3 relations and permissions, which exceeds the maximum allowed of 2")
+
+	// Ensure the schema was not changed.
+	resp, err := client.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
+	require.NoError(t, err)
+	require.NotContains(t, resp.SchemaText, "editor")
+}
+
+func TestSchemaReadReflectsCaveats(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1.NewSchemaServiceClient(conn)
+
+	_, err := client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `caveat unused(flag bool) {
+			flag
+		}
+
+		caveat ipallowed(labels list<string>, ip ipaddress) {
+			ip.in_cidr("10.0.0.0/8") && "internal" in labels
+		}
+
+		definition user {}
+
+		definition group {
+			relation member: user
+		}
+
+		definition document {
+			relation viewer: user | user with ipallowed | group#member with ipallowed | user:* with ipallowed
+			relation editor: user
+		}`,
+	})
+	require.NoError(t, err)
+
+	// Without the header, no reflection is returned.
+	var md metadata.MD
+	_, err = client.ReadSchema(context.Background(), &v1.ReadSchemaRequest{}, grpc.Header(&md))
+	require.NoError(t, err)
+	require.Empty(t, md.Get(v1svc.CaveatsReflectionHeader))
+
+	ctx := requestmeta.AddRequestHeaders(context.Background(), v1svc.RequestReflectCaveats)
+	_, err = client.ReadSchema(ctx, &v1.ReadSchemaRequest{}, grpc.Header(&md))
+	require.NoError(t, err)
+	require.Len(t, md.Get(v1svc.CaveatsReflectionHeader), 1)
+
+	var reflected []v1svc.ReflectedCaveat
+	require.NoError(t, json.Unmarshal([]byte(md.Get(v1svc.CaveatsReflectionHeader)[0]), &reflected))
+	require.Equal(t, []v1svc.ReflectedCaveat{
+		{
+			Name: "ipallowed",
+			Parameters: []v1svc.ReflectedCaveatParameter{
+				{Name: "ip", Type: "ipaddress"},
+				{Name: "labels", Type: "list<string>"},
+			},
+			AllowedOn: []v1svc.ReflectedCaveatUse{
+				{Definition: "document", Relation: "viewer", SubjectType: "user"},
+				{Definition: "document", Relation: "viewer", SubjectType: "group#member"},
+				{Definition: "document", Relation: "viewer", SubjectType: "user:*"},
+			},
+		},
+		{
+			Name:       "unused",
+			Parameters: []v1svc.ReflectedCaveatParameter{{Name: "flag", Type: "bool"}},
+			AllowedOn:  []v1svc.ReflectedCaveatUse{},
+		},
+	}, reflected)
+}
@@ -0,0 +1,180 @@
+package v1
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/middleware/consistency"
+)
+
+// StreamLimits are the maximums enforced on the results streamed by a single ReadRelationships
+// or LookupResources call. When a call reaches a maximum, it fails with an
+// ErrStreamLimitExceeded error, and a cursor continuing the call is returned in the
+// StreamCursorTrailer response trailer.
+type StreamLimits struct {
+	// MaxResults is the maximum number of results streamed by a call, or 0 for no maximum.
+	MaxResults uint64
+
+	// MaxBytes is the maximum size of the results streamed by a call, or 0 for no maximum. The
+	// first result is always streamed, whatever its size.
+	MaxBytes uint64
+}
+
+// RequestStreamCursor, if specified in the request header of a ReadRelationships or
+// LookupResources call, continues a previous call which reached a limit of the results streamed
+// by a call. The call reads at the revision of the first call, whatever the consistency of the
+// request, and its request must be that of the first call.
+// Value: the cursor returned in the StreamCursorTrailer response trailer
+const RequestStreamCursor requestmeta.RequestMetadataHeaderKey = "io.spicedb.streamcursor"
+
+// StreamCursorTrailer is the response trailer holding the cursor continuing a ReadRelationships
+// or LookupResources call which reached a limit of the results streamed by a call.
+const StreamCursorTrailer = "io.spicedb.respmeta.streamcursor"
+
+// streamCursor is the decoded form of the cursor continuing a streaming call.
+type streamCursor struct {
+	// ReadAt is the token of the revision at which the first call read its results.
+	ReadAt string `json:"t"`
+
+	// RequestHash is the hash of the request of the first call.
+	RequestHash string `json:"h"`
+
+	// Offset is the number of results streamed by the previous calls.
+	Offset uint64 `json:"o"`
+}
+
+// streamLimiter enforces the stream limits on the results of a call, skipping those streamed by
+// the previous calls it continues.
+type streamLimiter struct {
+	limits StreamLimits
+	stream grpc.ServerStream
+	cursor streamCursor
+
+	skipped uint64
+	results uint64
+	bytes   uint64
+}
+
+// newStreamLimiter returns the limiter of the results streamed by the call, along with the
+// revision at which to read them: that of the first call if the call continues it with a cursor,
+// or that of the consistency of the request otherwise.
+func (ps *permissionServer) newStreamLimiter(ctx context.Context, stream grpc.ServerStream, req proto.Message) (*streamLimiter, datastore.Revision, *v1.ZedToken, error) {
+	requestHash, err := hashRequest(req)
+	if err != nil {
+		return nil, datastore.NoRevision, nil, err
+	}
+
+	limiter := &streamLimiter{limits: ps.config.StreamLimits, stream: stream}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	cursors := md.Get(string(RequestStreamCursor))
+	if len(cursors) == 0 {
+		atRevision, readAt := consistency.MustRevisionFromContext(ctx)
+		limiter.cursor = streamCursor{ReadAt: readAt.Token, RequestHash: requestHash}
+		return limiter, atRevision, readAt, nil
+	}
+
+	cursor, err := decodeStreamCursor(cursors[0])
+	if err != nil {
+		return nil, datastore.NoRevision, nil, status.Errorf(codes.InvalidArgument, "invalid stream cursor: %s", err)
+	}
+	if cursor.RequestHash != requestHash {
+		return nil, datastore.NoRevision, nil, status.Errorf(codes.InvalidArgument, "stream cursor was issued for a different request")
+	}
+
+	atRevision, readAt, err := cursorRevision(ctx, cursor.ReadAt)
+	if err != nil {
+		return nil, datastore.NoRevision, nil, err
+	}
+	limiter.cursor = cursor
+	return limiter, atRevision, readAt, nil
+}
+
+// hashRequest returns the hash of the deterministic encoding of the request.
+func hashRequest(req proto.Message) (string, error) {
+	encoded, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(append([]byte(proto.MessageName(req)), encoded...))
+	return hex.EncodeToString(hash[:16]), nil
+}
+
+func decodeStreamCursor(encoded string) (streamCursor, error) {
+	decoded, err := base64.URLEncoding.DecodeString(encoded)
+	if err != nil {
+		return streamCursor{}, err
+	}
+
+	var cursor streamCursor
+	if err := json.Unmarshal(decoded, &cursor); err != nil {
+		return streamCursor{}, err
+	}
+	return cursor, nil
+}
+
+func encodeStreamCursor(cursor streamCursor) (string, error) {
+	encoded, err := json.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(encoded), nil
+}
+
+// limited returns whether the results of the call are limited or continue a previous call, in
+// which case they must be streamed in a deterministic order.
+func (l *streamLimiter) limited() bool {
+	return l.limits.MaxResults > 0 || l.limits.MaxBytes > 0 || l.cursor.Offset > 0
+}
+
+// send sends the result on the stream, unless it was streamed by a previous call. If the result
+// would exceed a limit, it is not sent: the cursor continuing the call from the result is set in
+// the StreamCursorTrailer response trailer, and an ErrStreamLimitExceeded error is returned.
+func (l *streamLimiter) send(result proto.Message) error {
+	if l.skipped < l.cursor.Offset {
+		l.skipped++
+		return nil
+	}
+
+	if l.limits.MaxResults > 0 && l.results >= l.limits.MaxResults {
+		return l.exceeded("results", l.limits.MaxResults)
+	}
+
+	if l.limits.MaxBytes > 0 {
+		size := uint64(proto.Size(result))
+		if l.results > 0 && l.bytes+size > l.limits.MaxBytes {
+			return l.exceeded("bytes", l.limits.MaxBytes)
+		}
+		l.bytes += size
+	}
+
+	if err := l.stream.SendMsg(result); err != nil {
+		return err
+	}
+	l.results++
+	return nil
+}
+
+func (l *streamLimiter) exceeded(limit string, maximum uint64) error {
+	next := l.cursor
+	next.Offset += l.results
+	encoded, err := encodeStreamCursor(next)
+	if err != nil {
+		return err
+	}
+
+	l.stream.SetTrailer(metadata.Pairs(StreamCursorTrailer, encoded))
+	return NewStreamLimitExceededErr(limit, maximum, encoded)
+}
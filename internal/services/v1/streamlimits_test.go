@@ -0,0 +1,175 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+// readStreamPage reads the results of a streaming call, returning the cursor continuing it if
+// it reached a stream limit.
+func readStreamPage[T any](stream interface {
+	Recv() (T, error)
+	Trailer() metadata.MD
+}, key func(T) string,
+) ([]string, string, error) {
+	var keys []string
+	for {
+		resp, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return keys, "", nil
+		}
+		if err != nil {
+			var cursor string
+			if values := stream.Trailer().Get(v1svc.StreamCursorTrailer); len(values) > 0 {
+				cursor = values[0]
+			}
+			return keys, cursor, err
+		}
+		keys = append(keys, key(resp))
+	}
+}
+
+func TestStreamLimits(t *testing.T) {
+	consistency := &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+
+	calls := []struct {
+		name string
+		call func(ctx context.Context, conn *grpc.ClientConn) ([]string, string, error)
+	}{
+		{
+			"ReadRelationships",
+			func(ctx context.Context, conn *grpc.ClientConn) ([]string, string, error) {
+				stream, err := v1.NewPermissionsServiceClient(conn).ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+					Consistency:        consistency,
+					RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+				})
+				if err != nil {
+					return nil, "", err
+				}
+				return readStreamPage[*v1.ReadRelationshipsResponse](stream, func(resp *v1.ReadRelationshipsResponse) string {
+					rel := resp.Relationship
+					return rel.Resource.ObjectId + "#" + rel.Relation + "@" + rel.Subject.Object.ObjectType + ":" + rel.Subject.Object.ObjectId
+				})
+			},
+		},
+		{
+			"LookupResources",
+			func(ctx context.Context, conn *grpc.ClientConn) ([]string, string, error) {
+				stream, err := v1.NewPermissionsServiceClient(conn).LookupResources(ctx, &v1.LookupResourcesRequest{
+					Consistency:        consistency,
+					ResourceObjectType: "document",
+					Permission:         "view",
+					Subject:            sub("user", "owner", ""),
+				})
+				if err != nil {
+					return nil, "", err
+				}
+				return readStreamPage[*v1.LookupResourcesResponse](stream, func(resp *v1.LookupResourcesResponse) string {
+					return resp.ResourceObjectId
+				})
+			},
+		},
+	}
+
+	limits := []struct {
+		name   string
+		config testserver.ServerConfig
+	}{
+		{"max results", testserver.ServerConfig{StreamingMaxResultsPerCall: 1}},
+		{"max bytes", testserver.ServerConfig{StreamingMaxBytesPerCall: "1B"}},
+	}
+
+	for _, tc := range calls {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			unlimitedConn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+			t.Cleanup(cleanup)
+
+			expected, cursor, err := tc.call(context.Background(), unlimitedConn)
+			require.NoError(t, err)
+			require.Empty(t, cursor)
+			require.Greater(t, len(expected), 1)
+			sort.Strings(expected)
+
+			for _, limit := range limits {
+				limit := limit
+				t.Run(limit.name, func(t *testing.T) {
+					require := require.New(t)
+
+					config := limit.config
+					config.MaxUpdatesPerWrite = 1000
+					config.MaxPreconditionsCount = 1000
+					conn, cleanup, _, _ := testserver.NewTestServerWithConfig(require, 0, memdb.DisableGC, true, config, testfixtures.StandardDatastoreWithData)
+					t.Cleanup(cleanup)
+
+					var all []string
+					ctx := context.Background()
+					for calls := 1; ; calls++ {
+						keys, cursor, err := tc.call(ctx, conn)
+						all = append(all, keys...)
+						if err == nil {
+							require.Empty(cursor)
+							require.Greater(calls, 1)
+							break
+						}
+
+						grpcutil.RequireStatus(t, codes.ResourceExhausted, err)
+						require.NotEmpty(keys)
+						require.NotEmpty(cursor)
+						ctx = metadata.NewOutgoingContext(context.Background(), metadata.Pairs(string(v1svc.RequestStreamCursor), cursor))
+					}
+
+					sort.Strings(all)
+					require.Equal(expected, all)
+				})
+			}
+		})
+	}
+}
+
+func TestStreamCursorForDifferentRequest(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(require, 0, memdb.DisableGC, true, testserver.ServerConfig{
+		MaxUpdatesPerWrite:         1000,
+		MaxPreconditionsCount:      1000,
+		StreamingMaxResultsPerCall: 1,
+	}, testfixtures.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+	client := v1.NewPermissionsServiceClient(conn)
+
+	read := func(ctx context.Context, resourceType string) (string, error) {
+		stream, err := client.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+			Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+			RelationshipFilter: &v1.RelationshipFilter{ResourceType: resourceType},
+		})
+		require.NoError(err)
+		_, cursor, err := readStreamPage[*v1.ReadRelationshipsResponse](stream, func(resp *v1.ReadRelationshipsResponse) string {
+			return resp.Relationship.Resource.ObjectId
+		})
+		return cursor, err
+	}
+
+	cursor, err := read(context.Background(), "document")
+	grpcutil.RequireStatus(t, codes.ResourceExhausted, err)
+	require.NotEmpty(cursor)
+
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs(string(v1svc.RequestStreamCursor), cursor))
+	_, err = read(ctx, "folder")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
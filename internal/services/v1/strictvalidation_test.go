@@ -0,0 +1,165 @@
+package v1_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const strictValidationTestSchema = `
+	definition user {}
+
+	definition group {
+		relation member: user
+	}
+
+	caveat only_on(day string) {
+		day == 'tuesday'
+	}
+
+	definition document {
+		relation viewer: user | user with only_on | group | group#member
+		relation editor: user | group
+	}
+`
+
+func TestWriteRelationshipsStrictValidation(t *testing.T) {
+	withCaveat := func(tpl *core.RelationTuple, context map[string]any) *core.RelationTuple {
+		tpl = tuple.WithCaveat(tpl, "only_on")
+		if context != nil {
+			tpl.Caveat.Context = mustStruct(context)
+		}
+		return tpl
+	}
+
+	testCases := []struct {
+		name             string
+		strictValidation []string
+		update           *core.RelationTupleUpdate
+		expectedCode     codes.Code
+		expectedError    string
+	}{
+		{
+			"direct group without strict validation",
+			nil,
+			tuple.Touch(tuple.MustParse("document:first#viewer@group:eng")),
+			codes.OK,
+			"",
+		},
+		{
+			"direct group with required subject relation",
+			[]string{"require-subject-relation"},
+			tuple.Touch(tuple.MustParse("document:first#viewer@group:eng")),
+			codes.InvalidArgument,
+			"has no subject relation, but relation `document#viewer` expects one for subjects of type `group`",
+		},
+		{
+			"subject set with required subject relation",
+			[]string{"require-subject-relation"},
+			tuple.Touch(tuple.MustParse("document:first#viewer@group:eng#member")),
+			codes.OK,
+			"",
+		},
+		{
+			"direct group without subject set allowed",
+			[]string{"require-subject-relation"},
+			tuple.Touch(tuple.MustParse("document:first#editor@group:eng")),
+			codes.OK,
+			"",
+		},
+		{
+			"undeclared caveat context",
+			nil,
+			tuple.Touch(withCaveat(tuple.MustParse("document:first#viewer@user:sarah"), map[string]any{"unknown": "value"})),
+			codes.InvalidArgument,
+			"unknown parameter `unknown`",
+		},
+		{
+			"conflicting caveat without strict validation",
+			nil,
+			tuple.Touch(withCaveat(tuple.MustParse("document:first#viewer@user:tom"), nil)),
+			codes.OK,
+			"",
+		},
+		{
+			"conflicting caveat",
+			[]string{"reject-conflicting-caveats"},
+			tuple.Touch(withCaveat(tuple.MustParse("document:first#viewer@user:tom"), nil)),
+			codes.FailedPrecondition,
+			"already exists with a different caveat (existing: no caveat, updated: `only_on`)",
+		},
+		{
+			"conflicting caveat context",
+			[]string{"reject-conflicting-caveats"},
+			tuple.Touch(withCaveat(tuple.MustParse("document:first#viewer@user:fred"), map[string]any{"day": "monday"})),
+			codes.FailedPrecondition,
+			"already exists with a different caveat (existing: `only_on` with context {\"day\":\"tuesday\"}, updated: `only_on` with context {\"day\":\"monday\"})",
+		},
+		{
+			"same caveat",
+			[]string{"reject-conflicting-caveats"},
+			tuple.Touch(withCaveat(tuple.MustParse("document:first#viewer@user:fred"), map[string]any{"day": "tuesday"})),
+			codes.OK,
+			"",
+		},
+		{
+			"same lack of caveat",
+			[]string{"reject-conflicting-caveats", "require-subject-relation"},
+			tuple.Touch(tuple.MustParse("document:first#viewer@user:tom")),
+			codes.OK,
+			"",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			req := require.New(t)
+			conn, cleanup, _, _ := testserver.NewTestServerWithConfig(req, 0, memdb.DisableGC, true,
+				testserver.ServerConfig{
+					MaxUpdatesPerWrite:           1000,
+					MaxPreconditionsCount:        1000,
+					StrictRelationshipValidation: tc.strictValidation,
+				},
+				func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+					return tf.DatastoreFromSchemaAndTestRelationships(ds, strictValidationTestSchema, []*core.RelationTuple{
+						tuple.MustParse("document:first#viewer@user:tom"),
+						withCaveat(tuple.MustParse("document:first#viewer@user:fred"), map[string]any{"day": "tuesday"}),
+					}, require)
+				})
+			t.Cleanup(cleanup)
+
+			client := v1.NewPermissionsServiceClient(conn)
+			_, err := client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+				Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(tc.update)},
+			})
+			if tc.expectedCode == codes.OK {
+				req.NoError(err)
+				return
+			}
+
+			grpcutil.RequireStatus(t, tc.expectedCode, err)
+			req.ErrorContains(err, tc.expectedError)
+		})
+	}
+}
+
+func mustStruct(values map[string]any) *structpb.Struct {
+	s, err := structpb.NewStruct(values)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
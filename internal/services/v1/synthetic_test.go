@@ -0,0 +1,72 @@
+package v1_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/services/shared"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func TestSyntheticRelations(t *testing.T) {
+	req := require.New(t)
+	conn, cleanup, _, revision := testserver.NewTestServer(req, 0, memdb.DisableGC, true,
+		func(ds datastore.Datastore, require *require.Assertions) (datastore.Datastore, datastore.Revision) {
+			ds, _ = tf.DatastoreFromSchemaAndTestRelationships(ds, `
+				definition user {}
+
+				definition document {
+					relation admin: user
+					relation viewer: user
+					relation any_member: user = admin + viewer
+					permission view = any_member
+				}
+			`, []*core.RelationTuple{
+				tuple.MustParse("document:first#admin@user:tom"),
+				tuple.MustParse("document:first#viewer@user:fred"),
+			}, require)
+
+			revision, err := shared.NewSyntheticRelationMaterializer(ds).MaterializeAll(context.Background())
+			require.NoError(err)
+			return ds, revision
+		})
+	t.Cleanup(cleanup)
+
+	// Relationships of synthetic relations cannot be written directly.
+	_, err := v1.NewPermissionsServiceClient(conn).WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("document:first#any_member@user:sarah"))),
+		},
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	req.ErrorContains(err, "cannot write a relationship to synthetic relation `any_member` under definition `document`")
+
+	// The materialized relationships are used to compute permissions.
+	for subjectID, expected := range map[string]v1.CheckPermissionResponse_Permissionship{
+		"tom":   v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+		"fred":  v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION,
+		"sarah": v1.CheckPermissionResponse_PERMISSIONSHIP_NO_PERMISSION,
+	} {
+		resp, err := v1.NewPermissionsServiceClient(conn).CheckPermission(context.Background(), &v1.CheckPermissionRequest{
+			Consistency: &v1.Consistency{
+				Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: zedtoken.NewFromRevision(revision)},
+			},
+			Resource:   obj("document", "first"),
+			Permission: "view",
+			Subject:    sub("user", subjectID, ""),
+		})
+		req.NoError(err)
+		req.Equal(expected, resp.Permissionship, "unexpected permissionship for %s", subjectID)
+	}
+}
@@ -0,0 +1,59 @@
+package v1
+
+import (
+	"context"
+
+	"github.com/authzed/authzed-go/pkg/requestmeta"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/util"
+)
+
+// RequestApplyTemplates, if specified in the request header of a WriteRelationships call, applies
+// relationship templates declared in the schema: the relationships created by each template are
+// touched along with the updates of the call, in the same transaction and subject to the same
+// preconditions and validation. The header may be given more than once, once per template
+// applied.
+// Value: an invocation of a template of the form `name(argument, ...)`, such as
+// `onboard_user(acme, tom)`
+const RequestApplyTemplates requestmeta.RequestMetadataHeaderKey = "io.spicedb.applytemplate"
+
+// templateUpdates returns the updates touching the relationships of the templates applied in the
+// header of a WriteRelationships call, which must not also be updated by the call itself.
+func templateUpdates(ctx context.Context, reader datastore.Reader, updates []*core.RelationTupleUpdate) ([]*core.RelationTupleUpdate, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil, nil
+	}
+
+	invocations := md.Get(string(RequestApplyTemplates))
+	if len(invocations) == 0 {
+		return nil, nil
+	}
+
+	templates, err := relationships.ReadTemplates(ctx, reader)
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := templates.Apply(invocations)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := util.NewSet[string]()
+	for _, update := range updates {
+		updated.Add(tuple.String(update.Tuple))
+	}
+
+	for _, update := range applied {
+		if updated.Has(tuple.String(update.Tuple)) {
+			return nil, NewDuplicateRelationshipErr(tuple.UpdateToRelationshipUpdate(update))
+		}
+	}
+	return applied, nil
+}
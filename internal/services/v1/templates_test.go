@@ -0,0 +1,169 @@
+package v1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
+	tf "github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const templatesSchema = `definition user {}
+
+/**
+ * @template onboard_user(org, user)
+ *   org:{org}#member@user:{user}
+ *   team:{org}_everyone#member@user:{user}
+ */
+definition org {
+	relation member: user
+}
+
+definition team {
+	relation member: user
+}`
+
+func TestApplyTemplates(t *testing.T) {
+	require := require.New(t)
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(
+		require,
+		0,
+		memdb.DisableGC,
+		true,
+		testserver.ServerConfig{
+			MaxPreconditionsCount: 10,
+			MaxUpdatesPerWrite:    5,
+		},
+		tf.EmptyDatastore,
+	)
+	t.Cleanup(cleanup)
+	schemaClient := v1.NewSchemaServiceClient(conn)
+	client := v1.NewPermissionsServiceClient(conn)
+
+	// Templates must be valid to be written.
+	_, err := schemaClient.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition user {}
+
+		/** @template onboard_user(org, user)
+		 *   org:{org}#admin@user:{user}
+		 */
+		definition org {
+			relation member: user
+		}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.ErrorContains(err, "relationship `org:org#admin@user:user` is not to a relation of the schema")
+
+	_, err = schemaClient.WriteSchema(context.Background(), &v1.WriteSchemaRequest{Schema: templatesSchema})
+	require.NoError(err)
+
+	readResp, err := schemaClient.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
+	require.NoError(err)
+	require.Contains(readResp.SchemaText, "@template onboard_user(org, user)")
+
+	apply := func(invocations ...string) (*v1.WriteRelationshipsResponse, error) {
+		md := metadata.MD{}
+		for _, invocation := range invocations {
+			md.Append(string(v1svc.RequestApplyTemplates), invocation)
+		}
+		return client.WriteRelationships(metadata.NewOutgoingContext(context.Background(), md), &v1.WriteRelationshipsRequest{
+			Updates: []*v1.RelationshipUpdate{
+				tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.MustParse("org:acme#member@user:owner"))),
+			},
+		})
+	}
+
+	_, err = apply("unknown(acme)")
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	_, err = apply("onboard_user(acme)")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.ErrorContains(err, "expects 2 argument(s), got 1")
+
+	_, err = apply("onboard_user(acme, tom@user:fred)")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	_, err = apply("onboard_user(acme, owner)")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.ErrorContains(err, "found more than one update with relationship `org:acme#member@user:owner`")
+
+	_, err = apply("onboard_user(acme, tom)", "onboard_user(acme, fred)", "onboard_user(acme, sarah)")
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	resp, err := apply("onboard_user(acme, tom)", "onboard_user(acme, fred)", "onboard_user(acme, tom)")
+	require.NoError(err)
+	require.ElementsMatch([]string{
+		"org:acme#member@user:owner",
+		"org:acme#member@user:tom",
+		"org:acme#member@user:fred",
+		"team:acme_everyone#member@user:tom",
+		"team:acme_everyone#member@user:fred",
+	}, readRelationshipsOf(require, client, resp.WrittenAt, "org", "team"))
+
+	// Changes to templates are written, even if the definitions are otherwise unchanged.
+	_, err = schemaClient.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition user {}
+
+		/**
+		 * @template onboard_user(org, user)
+		 *   org:{org}#member@user:{user}
+		 */
+		definition org {
+			relation member: user
+		}
+
+		definition team {
+			relation member: user
+		}`,
+	})
+	require.NoError(err)
+
+	_, err = client.WriteRelationships(
+		metadata.AppendToOutgoingContext(context.Background(), string(v1svc.RequestApplyTemplates), "onboard_user(other, tom)"),
+		&v1.WriteRelationshipsRequest{},
+	)
+	require.NoError(err)
+
+	resp, err = apply()
+	require.NoError(err)
+	require.ElementsMatch([]string{
+		"org:acme#member@user:owner",
+		"org:acme#member@user:tom",
+		"org:acme#member@user:fred",
+		"org:other#member@user:tom",
+		"team:acme_everyone#member@user:tom",
+		"team:acme_everyone#member@user:fred",
+	}, readRelationshipsOf(require, client, resp.WrittenAt, "org", "team"))
+}
+
+func readRelationshipsOf(require *require.Assertions, client v1.PermissionsServiceClient, token *v1.ZedToken, resourceTypes ...string) []string {
+	var got []string
+	for _, resourceType := range resourceTypes {
+		stream, err := client.ReadRelationships(context.Background(), &v1.ReadRelationshipsRequest{
+			Consistency:        &v1.Consistency{Requirement: &v1.Consistency_AtLeastAsFresh{AtLeastAsFresh: token}},
+			RelationshipFilter: &v1.RelationshipFilter{ResourceType: resourceType},
+		})
+		require.NoError(err)
+
+		for {
+			rel, err := stream.Recv()
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			require.NoError(err)
+			got = append(got, tuple.MustRelString(rel.Relationship))
+		}
+	}
+	return got
+}
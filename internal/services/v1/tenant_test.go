@@ -0,0 +1,107 @@
+package v1_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+)
+
+func TestTenantIsolation(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServerWithConfig(require, 0, memdb.DisableGC, false,
+		testserver.ServerConfig{
+			MaxUpdatesPerWrite:    1000,
+			MaxPreconditionsCount: 1000,
+			TenantsByKey:          map[string]string{"acmekey": "acme", "otherkey": "other"},
+		},
+		testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	schemaClient := v1.NewSchemaServiceClient(conn)
+	permissionsClient := v1.NewPermissionsServiceClient(conn)
+
+	withKey := func(key string) context.Context {
+		return metadata.NewOutgoingContext(context.Background(), metadata.Pairs("authorization", "bearer "+key))
+	}
+	acme, other := withKey("acmekey"), withKey("otherkey")
+
+	acmeSchema := `caveat acme/is_weekday(day string) {
+  day != "saturday" && day != "sunday"
+}
+
+definition acme/document {
+	relation viewer: acme/user | acme/user with acme/is_weekday
+	permission view = viewer
+}
+
+definition acme/user {}`
+
+	_, err := schemaClient.WriteSchema(acme, &v1.WriteSchemaRequest{Schema: acmeSchema})
+	require.NoError(err)
+
+	_, err = schemaClient.WriteSchema(other, &v1.WriteSchemaRequest{Schema: `definition other/user {}`})
+	require.NoError(err)
+
+	_, err = schemaClient.WriteSchema(other, &v1.WriteSchemaRequest{Schema: `definition acme/user {}`})
+	grpcutil.RequireStatus(t, codes.PermissionDenied, err)
+
+	resp, err := schemaClient.ReadSchema(acme, &v1.ReadSchemaRequest{})
+	require.NoError(err)
+	require.Contains(resp.SchemaText, "definition acme/document")
+	require.NotContains(resp.SchemaText, "other/user")
+
+	resp, err = schemaClient.ReadSchema(other, &v1.ReadSchemaRequest{})
+	require.NoError(err)
+	require.Equal("definition other/user {}", resp.SchemaText)
+
+	// Writing its own schema does not delete the definitions of another tenant.
+	_, err = schemaClient.WriteSchema(other, &v1.WriteSchemaRequest{Schema: `definition other/group {}`})
+	require.NoError(err)
+
+	_, err = permissionsClient.WriteRelationships(acme, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			update(v1.RelationshipUpdate_OPERATION_CREATE, "acme/document", "first", "viewer", "acme/user", "tom"),
+		},
+	})
+	require.NoError(err)
+
+	checkResp, err := permissionsClient.CheckPermission(acme, &v1.CheckPermissionRequest{
+		Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+		Resource:    obj("acme/document", "first"),
+		Permission:  "view",
+		Subject:     sub("acme/user", "tom", ""),
+	})
+	require.NoError(err)
+	require.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, checkResp.Permissionship)
+
+	_, err = permissionsClient.CheckPermission(other, &v1.CheckPermissionRequest{
+		Consistency: &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+		Resource:    obj("acme/document", "first"),
+		Permission:  "view",
+		Subject:     sub("acme/user", "tom", ""),
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	_, err = permissionsClient.WriteRelationships(other, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			update(v1.RelationshipUpdate_OPERATION_TOUCH, "acme/document", "first", "viewer", "acme/user", "sarah"),
+		},
+	})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	// Calls made without the key of a tenant are not restricted.
+	resp, err = schemaClient.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
+	require.NoError(err)
+	require.Contains(resp.SchemaText, "definition acme/document")
+	require.Contains(resp.SchemaText, "definition other/group")
+}
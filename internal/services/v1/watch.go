@@ -1,34 +1,87 @@
 package v1
 
 import (
+	"context"
 	"errors"
+	"strings"
 
+	"github.com/authzed/authzed-go/pkg/requestmeta"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/dispatch"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	tenantmw "github.com/authzed/spicedb/internal/middleware/tenant"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/pkg/datastore"
-	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
+const (
+	// RequestWatchResourceRelations, if specified in the request header of a Watch call, limits
+	// the changes returned to those of relationships with one of the given resource relations.
+	// Value: a comma-separated list of relation names
+	RequestWatchResourceRelations requestmeta.RequestMetadataHeaderKey = "io.spicedb.watchresourcerelations"
+
+	// RequestWatchResourceIDPrefix, if specified in the request header of a Watch call, limits
+	// the changes returned to those of relationships whose resource ID has the given prefix.
+	// Value: the resource ID prefix
+	RequestWatchResourceIDPrefix requestmeta.RequestMetadataHeaderKey = "io.spicedb.watchresourceidprefix"
+
+	// RequestWatchSubjectTypes, if specified in the request header of a Watch call, limits the
+	// changes returned to those of relationships with one of the given subject types.
+	// Value: a comma-separated list of object types
+	RequestWatchSubjectTypes requestmeta.RequestMetadataHeaderKey = "io.spicedb.watchsubjecttypes"
+
+	// RequestWatchPermissionSets, if specified in the request header of a Watch call, asks
+	// SpiceDB to watch the members of the given materialized permission sets instead of
+	// relationships. The current members are first sent as touched relationships whose relation
+	// is the permission, after which each member gained is sent as a touched relationship and
+	// each member lost as a deleted one. Watching permission sets cannot be resumed from a
+	// cursor.
+	// Value: a comma-separated list of permission sets of the form `resourcetype#permission`
+	RequestWatchPermissionSets requestmeta.RequestMetadataHeaderKey = "io.spicedb.watchpermissionsets"
+
+	// RequestWatchPermissionChanges, if specified in the request header of a Watch call, asks
+	// SpiceDB to watch the permissions of subjects of the given types on the resources of the
+	// given types, computed from the changes to relationships and the schema, instead of the
+	// relationships themselves. Each permission gained by a subject is sent as a touched
+	// relationship whose relation is the permission, and each permission lost as a deleted one.
+	// Permissions conditional on the context of a caveat are not reported.
+	// Value: a comma-separated list of watches of the form `resourcetype#permission@subjecttype`
+	RequestWatchPermissionChanges requestmeta.RequestMetadataHeaderKey = "io.spicedb.watchpermissionchanges"
+)
+
+// permissionSetMembersPerResponse is the number of members of permission sets sent in each
+// response when sending the current members.
+const permissionSetMembersPerResponse = 1000
+
 type watchServer struct {
 	v1.UnimplementedWatchServiceServer
 	shared.WithStreamServiceSpecificInterceptor
+
+	dispatcher     dispatch.Dispatcher
+	maxDepth       uint32
+	permissionSets *shared.PermissionSetMaterializer
 }
 
-// NewWatchServer creates an instance of the watch server.
-func NewWatchServer() v1.WatchServiceServer {
+// NewWatchServer creates an instance of the watch server. Changes to permissions, watched with the
+// RequestWatchPermissionChanges header, are computed with the dispatcher. The permission sets, if
+// any, can be watched with the RequestWatchPermissionSets header.
+func NewWatchServer(dispatcher dispatch.Dispatcher, maxDepth uint32, permissionSets *shared.PermissionSetMaterializer) v1.WatchServiceServer {
 	s := &watchServer{
 		WithStreamServiceSpecificInterceptor: shared.WithStreamServiceSpecificInterceptor{
 			Stream: grpcvalidate.StreamServerInterceptor(true),
 		},
+		dispatcher:     dispatcher,
+		maxDepth:       defaultIfZero(maxDepth, 50),
+		permissionSets: permissionSets,
 	}
 	return s
 }
@@ -37,44 +90,92 @@ func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchS
 	ctx := stream.Context()
 	ds := datastoremw.MustFromContext(ctx)
 
-	objectTypesMap := make(map[string]struct{})
-	for _, objectType := range req.GetOptionalObjectTypes() {
-		objectTypesMap[objectType] = struct{}{}
+	var permissionChanges []string
+	if md, ok := metadata.FromIncomingContext(stream.Context()); ok {
+		if sets := headerValues(md, RequestWatchPermissionSets); len(sets) > 0 {
+			if req.OptionalStartCursor != nil && req.OptionalStartCursor.Token != "" {
+				return status.Errorf(codes.InvalidArgument, "watching permission sets cannot be resumed from a start cursor")
+			}
+			return ws.watchPermissionSets(sets, stream)
+		}
+		permissionChanges = headerValues(md, RequestWatchPermissionChanges)
 	}
 
-	var afterRevision datastore.Revision
-	if req.OptionalStartCursor != nil && req.OptionalStartCursor.Token != "" {
-		decodedRevision, err := zedtoken.DecodeRevision(req.OptionalStartCursor, ds)
-		if err != nil {
-			return status.Errorf(codes.InvalidArgument, "failed to decode start revision: %s", err)
+	afterRevision, err := watchStartRevision(ctx, ds, req.OptionalStartCursor)
+	if err != nil {
+		return err
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: 1,
+	})
+
+	if len(permissionChanges) > 0 {
+		return ws.watchPermissionChanges(ctx, ds, afterRevision, permissionChanges, stream)
+	}
+
+	datastoreID := datastoremw.UniqueIDFromContext(ctx)
+	return watchDatastore(ctx, ds, afterRevision, datastore.WatchOptions{
+		Filter: watchFilter(ctx, req.OptionalObjectTypes),
+	}, func(update *datastore.RevisionChanges) error {
+		if len(update.Changes) == 0 {
+			return nil
+		}
+		return stream.Send(&v1.WatchResponse{
+			Updates:        tuple.UpdatesToRelationshipUpdates(update.Changes),
+			ChangesThrough: zedtoken.NewFromRevisionForDatastore(update.Revision, datastoreID),
+		})
+	})
+}
+
+// watchFilter returns the filter of the relationships watched by a Watch call, given the object
+// types of its request and the filters of its request headers.
+func watchFilter(ctx context.Context, objectTypes []string) datastore.WatchFilter {
+	filter := datastore.WatchFilter{OptionalResourceTypes: objectTypes}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		filter.OptionalResourceRelations = headerValues(md, RequestWatchResourceRelations)
+		if prefixes := md.Get(string(RequestWatchResourceIDPrefix)); len(prefixes) > 0 {
+			filter.OptionalResourceIDPrefix = prefixes[0]
 		}
+		filter.OptionalSubjectTypes = headerValues(md, RequestWatchSubjectTypes)
+	}
+	return filter
+}
 
-		afterRevision = decodedRevision
-	} else {
-		var err error
-		afterRevision, err = ds.OptimizedRevision(ctx)
+// watchStartRevision returns the revision after which a Watch call returns changes, which is
+// that of its start cursor, if any, or the current revision.
+func watchStartRevision(ctx context.Context, ds datastore.Datastore, startCursor *v1.ZedToken) (datastore.Revision, error) {
+	if startCursor != nil && startCursor.Token != "" {
+		decodedRevision, err := zedtoken.DecodeRevisionForDatastore(startCursor, ds, datastoremw.UniqueIDFromContext(ctx))
 		if err != nil {
-			return status.Errorf(codes.Unavailable, "failed to start watch: %s", err)
+			return datastore.NoRevision, status.Errorf(codes.InvalidArgument, "failed to decode start revision: %s", err)
 		}
+		return decodedRevision, nil
 	}
 
-	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
-		DispatchCount: 1,
-	})
+	afterRevision, err := ds.OptimizedRevision(ctx)
+	if err != nil {
+		return datastore.NoRevision, status.Errorf(codes.Unavailable, "failed to start watch: %s", err)
+	}
+	return afterRevision, nil
+}
 
-	updates, errchan := ds.Watch(ctx, afterRevision)
+// watchDatastore watches the changes made to the datastore after the revision, passing each
+// change returned by the datastore to send, until the watch or send fails.
+func watchDatastore(
+	ctx context.Context,
+	ds datastore.Datastore,
+	afterRevision datastore.Revision,
+	options datastore.WatchOptions,
+	send func(update *datastore.RevisionChanges) error,
+) error {
+	updates, errchan := ds.Watch(ctx, afterRevision, options)
 	for {
 		select {
 		case update, ok := <-updates:
 			if ok {
-				filtered := filterUpdates(objectTypesMap, update.Changes)
-				if len(filtered) > 0 {
-					if err := stream.Send(&v1.WatchResponse{
-						Updates:        filtered,
-						ChangesThrough: zedtoken.NewFromRevision(update.Revision),
-					}); err != nil {
-						return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
-					}
+				if err := send(update); err != nil {
+					return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
 				}
 			}
 		case err := <-errchan:
@@ -83,6 +184,8 @@ func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchS
 				return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
 			case errors.As(err, &datastore.ErrWatchDisconnected{}):
 				return status.Errorf(codes.ResourceExhausted, "watch disconnected: %s", err)
+			case errors.As(err, &datastore.ErrWatchDisabled{}):
+				return status.Errorf(codes.FailedPrecondition, "%s", err)
 			default:
 				return status.Errorf(codes.Internal, "watch error: %s", err)
 			}
@@ -90,21 +193,154 @@ func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchS
 	}
 }
 
-func filterUpdates(objectTypes map[string]struct{}, candidates []*core.RelationTupleUpdate) []*v1.RelationshipUpdate {
-	updates := tuple.UpdatesToRelationshipUpdates(candidates)
+// watchPermissionSets sends the current members of the given permission sets, and then the
+// changes to their members.
+func (ws *watchServer) watchPermissionSets(values []string, stream v1.WatchService_WatchServer) error {
+	if ws.permissionSets == nil {
+		return status.Errorf(codes.FailedPrecondition, "no permission sets are materialized")
+	}
+
+	sets := make([]shared.PermissionSet, 0, len(values))
+	for _, value := range values {
+		set, err := shared.ParsePermissionSet(value)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "%s", err)
+		}
+		// The permission sets are materialized outside of any tenant, so the calls of a tenant may
+		// only watch the sets of its own object types.
+		if tenant, ok := tenantmw.FromContext(stream.Context()); ok && !strings.HasPrefix(set.ResourceType, tenant+"/") {
+			return rewriteError(stream.Context(), datastore.NewOutsideTenantErr(tenant, set.ResourceType))
+		}
+		sets = append(sets, set)
+	}
+
+	subscription, err := ws.permissionSets.Subscribe(sets)
+	switch {
+	case errors.Is(err, shared.ErrPermissionSetsNotReady):
+		return status.Errorf(codes.Unavailable, "%s", err)
+	case err != nil:
+		return status.Errorf(codes.FailedPrecondition, "%s", err)
+	}
+	defer ws.permissionSets.Unsubscribe(subscription)
 
-	if len(objectTypes) == 0 {
-		return updates
+	usagemetrics.SetInContext(stream.Context(), &dispatchv1.ResponseMeta{
+		DispatchCount: 1,
+	})
+
+	datastoreID := datastoremw.UniqueIDFromContext(stream.Context())
+	changesThrough := zedtoken.NewFromRevisionForDatastore(subscription.Revision, datastoreID)
+	members := subscription.Members
+	for len(members) > 0 {
+		count := permissionSetMembersPerResponse
+		if count > len(members) {
+			count = len(members)
+		}
+		if err := stream.Send(&v1.WatchResponse{
+			Updates:        permissionSetUpdates(members[:count]),
+			ChangesThrough: changesThrough,
+		}); err != nil {
+			return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		members = members[count:]
 	}
 
-	var filtered []*v1.RelationshipUpdate
-	for _, update := range updates {
-		objectType := update.GetRelationship().GetResource().GetObjectType()
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.Errorf(codes.Canceled, "watch canceled by user: %s", stream.Context().Err())
+		case changes, ok := <-subscription.Changes():
+			if !ok {
+				if errors.Is(subscription.Err(), shared.ErrPermissionSetSubscriberDropped) {
+					return status.Errorf(codes.ResourceExhausted, "watch disconnected: %s", subscription.Err())
+				}
+				return status.Errorf(codes.Unavailable, "permission set materialization stopped: %s", subscription.Err())
+			}
+			if err := stream.Send(&v1.WatchResponse{
+				Updates:        permissionSetUpdates(changes.Changes),
+				ChangesThrough: zedtoken.NewFromRevisionForDatastore(changes.Revision, datastoreID),
+			}); err != nil {
+				return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+			}
+		}
+	}
+}
+
+// watchPermissionChanges sends the changes to the permissions of the given permission change
+// watches made after the given revision.
+func (ws *watchServer) watchPermissionChanges(ctx context.Context, ds datastore.Datastore, afterRevision datastore.Revision, values []string, stream v1.WatchService_WatchServer) error {
+	watches := make([]shared.PermissionChangeWatch, 0, len(values))
+	for _, value := range values {
+		watch, err := shared.ParsePermissionChangeWatch(value)
+		if err != nil {
+			return status.Errorf(codes.InvalidArgument, "%s", err)
+		}
+		watches = append(watches, watch)
+	}
+
+	datastoreID := datastoremw.UniqueIDFromContext(ctx)
+	watcher := shared.NewPermissionChangeWatcher(ds, ws.dispatcher, ws.maxDepth)
+	err := watcher.Watch(ctx, afterRevision, watches, func(changes shared.PermissionSetChanges) error {
+		if err := stream.Send(&v1.WatchResponse{
+			Updates:        permissionSetUpdates(changes.Changes),
+			ChangesThrough: zedtoken.NewFromRevisionForDatastore(changes.Revision, datastoreID),
+		}); err != nil {
+			return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, context.Canceled):
+		return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+	case errors.As(err, &datastore.ErrWatchCanceled{}):
+		return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+	case errors.As(err, &datastore.ErrWatchDisconnected{}):
+		return status.Errorf(codes.ResourceExhausted, "watch disconnected: %s", err)
+	case errors.As(err, &datastore.ErrWatchDisabled{}):
+		return status.Errorf(codes.FailedPrecondition, "%s", err)
+	default:
+		return rewriteError(ctx, err)
+	}
+}
 
-		if _, ok := objectTypes[objectType]; ok {
-			filtered = append(filtered, update)
+// permissionSetUpdates returns the changes to the members of permission sets as relationship
+// updates whose relation is the permission of the set.
+func permissionSetUpdates(changes []shared.PermissionSetChange) []*v1.RelationshipUpdate {
+	updates := make([]*v1.RelationshipUpdate, 0, len(changes))
+	for _, change := range changes {
+		operation := v1.RelationshipUpdate_OPERATION_TOUCH
+		if !change.Granted {
+			operation = v1.RelationshipUpdate_OPERATION_DELETE
 		}
+		updates = append(updates, &v1.RelationshipUpdate{
+			Operation: operation,
+			Relationship: &v1.Relationship{
+				Resource: &v1.ObjectReference{
+					ObjectType: change.Set.ResourceType,
+					ObjectId:   change.Member.ResourceID,
+				},
+				Relation: change.Set.Permission,
+				Subject: &v1.SubjectReference{
+					Object: &v1.ObjectReference{
+						ObjectType: change.Member.SubjectType,
+						ObjectId:   change.Member.SubjectID,
+					},
+				},
+			},
+		})
 	}
+	return updates
+}
 
-	return filtered
+// headerValues returns the comma-separated values of the header with the given key.
+func headerValues(md metadata.MD, key requestmeta.RequestMetadataHeaderKey) []string {
+	var values []string
+	for _, value := range md.Get(string(key)) {
+		for _, item := range strings.Split(value, ",") {
+			if trimmed := strings.TrimSpace(item); trimmed != "" {
+				values = append(values, trimmed)
+			}
+		}
+	}
+	return values
 }
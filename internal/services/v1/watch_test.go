@@ -10,13 +10,16 @@ import (
 	"testing"
 	"time"
 
+	"github.com/authzed/authzed-go/pkg/requestmeta"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/grpcutil"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
 	"github.com/authzed/spicedb/pkg/tuple"
@@ -53,6 +56,7 @@ func TestWatch(t *testing.T) {
 	testCases := []struct {
 		name              string
 		objectTypesFilter []string
+		headers           map[requestmeta.RequestMetadataHeaderKey]string
 		startCursor       *v1.ZedToken
 		mutations         []*v1.RelationshipUpdate
 		expectedCode      codes.Code
@@ -86,6 +90,27 @@ func TestWatch(t *testing.T) {
 				update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "document2", "viewer", "user", "user1"),
 			},
 		},
+		{
+			name:              "watch with header filters",
+			expectedCode:      codes.OK,
+			objectTypesFilter: []string{"document", "folder"},
+			headers: map[requestmeta.RequestMetadataHeaderKey]string{
+				v1svc.RequestWatchResourceRelations: "viewer, owner",
+				v1svc.RequestWatchResourceIDPrefix:  "doc",
+				v1svc.RequestWatchSubjectTypes:      "user",
+			},
+			mutations: []*v1.RelationshipUpdate{
+				update(v1.RelationshipUpdate_OPERATION_CREATE, "document", "document1", "viewer", "user", "user1"),
+				update(v1.RelationshipUpdate_OPERATION_CREATE, "document", "document1", "editor", "user", "user1"),
+				update(v1.RelationshipUpdate_OPERATION_CREATE, "document", "masterplan", "viewer", "user", "user1"),
+				update(v1.RelationshipUpdate_OPERATION_CREATE, "folder", "docs", "owner", "user", "user1"),
+				update(v1.RelationshipUpdate_OPERATION_DELETE, "folder", "auditors", "viewer", "user", "auditor"),
+			},
+			expectedUpdates: []*v1.RelationshipUpdate{
+				update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "document1", "viewer", "user", "user1"),
+				update(v1.RelationshipUpdate_OPERATION_TOUCH, "folder", "docs", "owner", "user", "user1"),
+			},
+		},
 		{
 			name:         "invalid zedtoken",
 			startCursor:  &v1.ZedToken{Token: "bad-token"},
@@ -114,6 +139,10 @@ func TestWatch(t *testing.T) {
 			ctx, cancel := context.WithCancel(context.Background())
 			defer cancel()
 
+			if tc.headers != nil {
+				ctx = requestmeta.SetRequestHeaders(ctx, tc.headers)
+			}
+
 			stream, err := client.Watch(ctx, &v1.WatchRequest{
 				OptionalObjectTypes: tc.objectTypesFilter,
 				OptionalStartCursor: cursor,
@@ -192,3 +221,115 @@ func sortUpdates(in []*v1.RelationshipUpdate) []*v1.RelationshipUpdate {
 
 	return out
 }
+
+func TestWatchPermissionSets(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, revision := testserver.NewTestServerWithConfig(require, 0, memdb.DisableGC, true,
+		testserver.ServerConfig{
+			MaxUpdatesPerWrite:         1000,
+			MaxPreconditionsCount:      1000,
+			MaterializedPermissionSets: []string{"document#edit"},
+			TenantsByKey:               map[string]string{"acmekey": "acme"},
+		},
+		testfixtures.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watch := func(sets string, req *v1.WatchRequest) (*v1.WatchResponse, v1.WatchService_WatchClient, error) {
+		watchCtx := requestmeta.SetRequestHeaders(ctx, map[requestmeta.RequestMetadataHeaderKey]string{
+			v1svc.RequestWatchPermissionSets: sets,
+		})
+		stream, err := v1.NewWatchServiceClient(conn).Watch(watchCtx, req)
+		require.NoError(err)
+
+		resp, err := stream.Recv()
+		return resp, stream, err
+	}
+
+	_, _, err := watch("document#view", &v1.WatchRequest{})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+
+	_, _, err = watch("document#edit", &v1.WatchRequest{OptionalStartCursor: zedtoken.NewFromRevision(revision)})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// The calls of a tenant cannot watch the permission sets of other object types.
+	tenantCtx := requestmeta.SetRequestHeaders(metadata.AppendToOutgoingContext(ctx, "authorization", "bearer acmekey"), map[requestmeta.RequestMetadataHeaderKey]string{
+		v1svc.RequestWatchPermissionSets: "document#edit",
+	})
+	tenantStream, err := v1.NewWatchServiceClient(conn).Watch(tenantCtx, &v1.WatchRequest{})
+	require.NoError(err)
+	_, err = tenantStream.Recv()
+	grpcutil.RequireStatus(t, codes.PermissionDenied, err)
+
+	// The permission sets are materialized in the background once the server has started.
+	var resp *v1.WatchResponse
+	var stream v1.WatchService_WatchClient
+	require.Eventually(func() bool {
+		resp, stream, err = watch("document#edit", &v1.WatchRequest{})
+		return status.Code(err) != codes.Unavailable
+	}, 5*time.Second, 10*time.Millisecond)
+	require.NoError(err)
+	require.Equal([]*v1.RelationshipUpdate{
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "masterplan", "edit", "user", "product_manager"),
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "specialplan", "edit", "user", "multiroleguy"),
+	}, resp.Updates)
+
+	_, err = v1.NewPermissionsServiceClient(conn).WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			update(v1.RelationshipUpdate_OPERATION_CREATE, "document", "healthplan", "owner", "user", "tom"),
+			update(v1.RelationshipUpdate_OPERATION_DELETE, "document", "specialplan", "editor", "user", "multiroleguy"),
+		},
+	})
+	require.NoError(err)
+
+	resp, err = stream.Recv()
+	require.NoError(err)
+	require.Equal([]*v1.RelationshipUpdate{
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "healthplan", "edit", "user", "tom"),
+		update(v1.RelationshipUpdate_OPERATION_DELETE, "document", "specialplan", "edit", "user", "multiroleguy"),
+	}, resp.Updates)
+}
+
+func TestWatchPermissionChanges(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, revision := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+	t.Cleanup(cleanup)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	watch := func(watches string) (v1.WatchService_WatchClient, error) {
+		watchCtx := requestmeta.SetRequestHeaders(ctx, map[requestmeta.RequestMetadataHeaderKey]string{
+			v1svc.RequestWatchPermissionChanges: watches,
+		})
+		return v1.NewWatchServiceClient(conn).Watch(watchCtx, &v1.WatchRequest{OptionalStartCursor: zedtoken.NewFromRevision(revision)})
+	}
+
+	stream, err := watch("document#edit")
+	require.NoError(err)
+	_, err = stream.Recv()
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	stream, err = watch("document#edit@user")
+	require.NoError(err)
+
+	_, err = v1.NewPermissionsServiceClient(conn).WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			update(v1.RelationshipUpdate_OPERATION_CREATE, "document", "healthplan", "owner", "user", "tom"),
+			update(v1.RelationshipUpdate_OPERATION_DELETE, "document", "specialplan", "editor", "user", "multiroleguy"),
+		},
+	})
+	require.NoError(err)
+
+	resp, err := stream.Recv()
+	require.NoError(err)
+	require.Equal([]*v1.RelationshipUpdate{
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "healthplan", "edit", "user", "tom"),
+		update(v1.RelationshipUpdate_OPERATION_DELETE, "document", "specialplan", "edit", "user", "multiroleguy"),
+	}, resp.Updates)
+	require.NotNil(resp.ChangesThrough)
+}
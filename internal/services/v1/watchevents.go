@@ -0,0 +1,112 @@
+package v1
+
+import (
+	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/validator"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	watchv1 "github.com/authzed/spicedb/pkg/proto/watch/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+type watchEventsServer struct {
+	watchv1.UnimplementedWatchServiceServer
+	shared.WithStreamServiceSpecificInterceptor
+}
+
+// NewWatchEventsServer creates an instance of the watch service returning the changes to the
+// schema, along with those to relationships, and checkpoints as distinct events.
+func NewWatchEventsServer() watchv1.WatchServiceServer {
+	return &watchEventsServer{
+		WithStreamServiceSpecificInterceptor: shared.WithStreamServiceSpecificInterceptor{
+			Stream: grpcvalidate.StreamServerInterceptor(true),
+		},
+	}
+}
+
+func (ws *watchEventsServer) Watch(req *watchv1.WatchRequest, stream watchv1.WatchService_WatchServer) error {
+	ctx := stream.Context()
+	ds := datastoremw.MustFromContext(ctx)
+
+	afterRevision, err := watchStartRevision(ctx, ds, req.OptionalStartCursor)
+	if err != nil {
+		return err
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: 1,
+	})
+
+	content := datastore.WatchRelationships
+	if req.IncludeSchemaChanges {
+		content |= datastore.WatchSchema
+	}
+
+	datastoreID := datastoremw.UniqueIDFromContext(ctx)
+	return watchDatastore(ctx, ds, afterRevision, datastore.WatchOptions{
+		Content:            content,
+		Filter:             watchFilter(ctx, req.OptionalObjectTypes),
+		CheckpointInterval: req.CheckpointInterval.AsDuration(),
+	}, func(update *datastore.RevisionChanges) error {
+		changesThrough := zedtoken.NewFromRevisionForDatastore(update.Revision, datastoreID)
+		if update.IsCheckpoint {
+			return stream.Send(&watchv1.WatchResponse{
+				Event:          &watchv1.WatchResponse_Checkpoint{Checkpoint: &watchv1.Checkpoint{}},
+				ChangesThrough: changesThrough,
+			})
+		}
+
+		if len(update.SchemaChanges) > 0 {
+			if err := stream.Send(&watchv1.WatchResponse{
+				Event: &watchv1.WatchResponse_SchemaChanged{
+					SchemaChanged: &watchv1.SchemaChanged{Changes: definitionChanges(update.SchemaChanges)},
+				},
+				ChangesThrough: changesThrough,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if len(update.Changes) > 0 {
+			return stream.Send(&watchv1.WatchResponse{
+				Event: &watchv1.WatchResponse_RelationshipsChanged{
+					RelationshipsChanged: &watchv1.RelationshipsChanged{Updates: tuple.UpdatesToRelationshipUpdates(update.Changes)},
+				},
+				ChangesThrough: changesThrough,
+			})
+		}
+		return nil
+	})
+}
+
+// definitionChanges returns the changes to the definitions of the schema, with the definitions
+// before and after each change as schema.
+func definitionChanges(changes []*datastore.SchemaChange) []*watchv1.DefinitionChange {
+	converted := make([]*watchv1.DefinitionChange, 0, len(changes))
+	for _, change := range changes {
+		converted = append(converted, &watchv1.DefinitionChange{
+			Name:          change.Name(),
+			OldDefinition: definitionSource(change.OldDefinition),
+			NewDefinition: definitionSource(change.NewDefinition),
+		})
+	}
+	return converted
+}
+
+// definitionSource returns the schema of an object or caveat definition, or empty if nil.
+func definitionSource(definition datastore.SchemaDefinition) string {
+	var source string
+	switch definition := definition.(type) {
+	case *core.NamespaceDefinition:
+		source, _ = generator.GenerateSource(definition)
+	case *core.CaveatDefinition:
+		source, _ = generator.GenerateCaveatSource(definition)
+	}
+	return source
+}
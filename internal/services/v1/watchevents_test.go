@@ -0,0 +1,127 @@
+package v1_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	watchv1 "github.com/authzed/spicedb/pkg/proto/watch/v1"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func TestWatchSchemaChangeEvents(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, revision := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	stream, err := watchv1.NewWatchServiceClient(conn).Watch(ctx, &watchv1.WatchRequest{
+		OptionalStartCursor:  zedtoken.NewFromRevision(revision),
+		IncludeSchemaChanges: true,
+	})
+	require.NoError(err)
+
+	writeSchema := func(schema string) {
+		_, err := v1.NewSchemaServiceClient(conn).WriteSchema(context.Background(), &v1.WriteSchemaRequest{Schema: schema})
+		require.NoError(err)
+	}
+
+	writeSchema(`definition user {}
+
+	definition document {
+		relation viewer: user
+	}`)
+
+	resp, err := stream.Recv()
+	require.NoError(err)
+	require.NotEqual(zedtoken.NewFromRevision(revision).Token, resp.ChangesThrough.Token)
+	require.Equal([]*watchv1.DefinitionChange{
+		{Name: "document", NewDefinition: "definition document {\n\trelation viewer: user\n}"},
+		{Name: "user", NewDefinition: "definition user {}"},
+	}, resp.GetSchemaChanged().GetChanges())
+
+	writeSchema(`definition user {}
+
+	definition document {
+		relation viewer: user
+		relation editor: user
+	}`)
+
+	resp, err = stream.Recv()
+	require.NoError(err)
+	require.Equal([]*watchv1.DefinitionChange{
+		{
+			Name:          "document",
+			OldDefinition: "definition document {\n\trelation viewer: user\n}",
+			NewDefinition: "definition document {\n\trelation viewer: user\n\trelation editor: user\n}",
+		},
+	}, resp.GetSchemaChanged().GetChanges())
+
+	_, err = v1.NewPermissionsServiceClient(conn).WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			update(v1.RelationshipUpdate_OPERATION_CREATE, "document", "document1", "viewer", "user", "user1"),
+		},
+	})
+	require.NoError(err)
+
+	resp, err = stream.Recv()
+	require.NoError(err)
+	require.Nil(resp.GetSchemaChanged())
+	require.Equal([]*v1.RelationshipUpdate{
+		update(v1.RelationshipUpdate_OPERATION_TOUCH, "document", "document1", "viewer", "user", "user1"),
+	}, resp.GetRelationshipsChanged().GetUpdates())
+}
+
+func TestWatchCheckpointEvents(t *testing.T) {
+	testCases := []struct {
+		name         string
+		interval     *durationpb.Duration
+		expectedCode codes.Code
+	}{
+		{"valid interval", durationpb.New(1 * time.Second), codes.OK},
+		{"interval too short", durationpb.New(10 * time.Millisecond), codes.InvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, _, revision := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.StandardDatastoreWithData)
+			t.Cleanup(cleanup)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			stream, err := watchv1.NewWatchServiceClient(conn).Watch(ctx, &watchv1.WatchRequest{
+				OptionalStartCursor: zedtoken.NewFromRevision(revision),
+				CheckpointInterval:  tc.interval,
+			})
+			require.NoError(err)
+
+			resp, err := stream.Recv()
+			if tc.expectedCode != codes.OK {
+				grpcutil.RequireStatus(t, tc.expectedCode, err)
+				return
+			}
+
+			require.NoError(err)
+			require.NotNil(resp.GetCheckpoint())
+			decoded, err := zedtoken.Decode(resp.ChangesThrough)
+			require.NoError(err)
+			require.Equal(revision.String(), decoded.GetV1().Revision)
+		})
+	}
+}
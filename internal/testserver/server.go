@@ -12,6 +12,8 @@ import (
 	"github.com/authzed/spicedb/internal/middleware/consistency"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/servicespecific"
+	"github.com/authzed/spicedb/internal/middleware/tenant"
+	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/pkg/cmd/server"
 	"github.com/authzed/spicedb/pkg/cmd/util"
 	"github.com/authzed/spicedb/pkg/datastore"
@@ -20,8 +22,17 @@ import (
 
 // ServerConfig is configuration for the test server.
 type ServerConfig struct {
-	MaxUpdatesPerWrite    uint16
-	MaxPreconditionsCount uint16
+	MaxUpdatesPerWrite           uint16
+	MaxPreconditionsCount        uint16
+	RejectDeprecatedRelations    bool
+	StrictRelationshipValidation []string
+	SchemaSoftDeleteDefinitions  bool
+	SchemaLimits                 shared.SchemaLimits
+	MaterializedPermissionSets   []string
+	RelationshipQuotas           map[string]string
+	TenantsByKey                 map[string]string
+	StreamingMaxResultsPerCall   uint64
+	StreamingMaxBytesPerCall     string
 }
 
 // NewTestServer creates a new test server, using defaults for the config.
@@ -57,11 +68,21 @@ func NewTestServerWithConfig(require *require.Assertions,
 		server.WithDispatchMaxDepth(50),
 		server.WithMaximumPreconditionCount(config.MaxPreconditionsCount),
 		server.WithMaximumUpdatesPerWrite(config.MaxUpdatesPerWrite),
+		server.WithRejectDeprecatedRelations(config.RejectDeprecatedRelations),
+		server.SetStrictRelationshipValidation(config.StrictRelationshipValidation),
+		server.SetMaterializedPermissionSets(config.MaterializedPermissionSets),
+		server.SetRelationshipQuotas(config.RelationshipQuotas),
+		server.WithStreamingMaxResultsPerCall(config.StreamingMaxResultsPerCall),
+		server.WithStreamingMaxBytesPerCall(config.StreamingMaxBytesPerCall),
 		server.WithGRPCServer(util.GRPCServerConfig{
 			Network: util.BufferedNetwork,
 			Enabled: true,
 		}),
 		server.WithSchemaPrefixesRequired(schemaPrefixRequired),
+		server.WithSchemaSoftDeleteDefinitions(config.SchemaSoftDeleteDefinitions),
+		server.WithSchemaMaxSize(config.SchemaLimits.MaxSchemaSize),
+		server.WithSchemaMaxRelationsPerDefinition(config.SchemaLimits.MaxRelationsPerDefinition),
+		server.WithSchemaMaxUnionTermsPerPermission(config.SchemaLimits.MaxUnionTermsPerPermission),
 		server.WithGRPCAuthFunc(func(ctx context.Context) (context.Context, error) {
 			return ctx, nil
 		}),
@@ -72,14 +93,18 @@ func NewTestServerWithConfig(require *require.Assertions,
 		server.WithExperimentalCaveatsEnabled(true),
 	).Complete(ctx)
 	require.NoError(err)
+	tenants, err := tenant.NewRegistry(config.TenantsByKey, "")
+	require.NoError(err)
 	srv.SetMiddleware([]grpc.UnaryServerInterceptor{
 		logging.UnaryServerInterceptor(),
 		datastoremw.UnaryServerInterceptor(ds),
+		tenant.UnaryServerInterceptor(tenants),
 		consistency.UnaryServerInterceptor(),
 		servicespecific.UnaryServerInterceptor,
 	}, []grpc.StreamServerInterceptor{
 		logging.StreamServerInterceptor(),
 		datastoremw.StreamServerInterceptor(ds),
+		tenant.StreamServerInterceptor(tenants),
 		consistency.StreamServerInterceptor(),
 		servicespecific.StreamServerInterceptor,
 	})
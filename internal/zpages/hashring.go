@@ -0,0 +1,186 @@
+package zpages
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/authzed/spicedb/internal/dispatch/keys"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/pkg/consistent"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatch "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// HashringTopology is the topology of the dispatch hashring at a time, along with the owners of
+// a key, if requested.
+type HashringTopology struct {
+	Time              time.Time        `json:"time"`
+	ReplicationFactor uint16           `json:"replicationFactor"`
+	Spread            uint8            `json:"spread"`
+	Members           []HashringMember `json:"members"`
+	Key               *KeyOwnership    `json:"key,omitempty"`
+}
+
+// HashringMember is a member of the dispatch hashring, along with the hashes of its virtual nodes
+// and the fraction of the hash space it owns.
+type HashringMember struct {
+	Key          string   `json:"key"`
+	Fraction     float64  `json:"fraction"`
+	VirtualNodes []uint64 `json:"virtualNodes"`
+}
+
+// KeyOwnership describes the members to which the check of a permission of a resource for a
+// subject, as dispatched by CheckPermission, is dispatched. The first owner is the member owning
+// the hash of the key; the check is balanced among all of the owners.
+type KeyOwnership struct {
+	ResourceType string   `json:"resourceType"`
+	ResourceID   string   `json:"resourceId"`
+	Permission   string   `json:"permission"`
+	Subject      string   `json:"subject"`
+	Revision     string   `json:"revision"`
+	Hash         uint64   `json:"hash"`
+	Owners       []string `json:"owners"`
+}
+
+// errNoHashring is returned when no dispatch hashring was built, such as when dispatching to
+// other nodes of the cluster is disabled.
+var errNoHashring = errors.New("no dispatch hashring was built")
+
+// Topology returns the current topology of the dispatch hashring, along with the owners of the
+// check of the key given in the query, if any.
+func (s Sources) Topology(ctx context.Context, now time.Time, query url.Values) (*HashringTopology, error) {
+	if s.Hashring == nil || s.Hashring() == nil {
+		return nil, errNoHashring
+	}
+	hashring := s.Hashring()
+
+	spread := uint8(1)
+	if s.Spread != nil && s.Spread() > 0 {
+		spread = s.Spread()
+	}
+
+	ownership := hashring.Ownership()
+	fractions := make(map[string]float64, len(ownership))
+	for _, memberOwnership := range ownership {
+		fractions[memberOwnership.Key] = memberOwnership.Fraction
+	}
+
+	topology := &HashringTopology{
+		Time:              now.UTC(),
+		ReplicationFactor: hashring.ReplicationFactor(),
+		Spread:            spread,
+	}
+	for _, member := range hashring.VirtualNodes() {
+		topology.Members = append(topology.Members, HashringMember{
+			Key:          member.Key,
+			Fraction:     fractions[member.Key],
+			VirtualNodes: member.VirtualNodes,
+		})
+	}
+
+	if query.Get("resource_type") == "" && query.Get("resource_id") == "" && query.Get("permission") == "" {
+		return topology, nil
+	}
+
+	key, err := s.keyOwnership(ctx, hashring, spread, query)
+	if err != nil {
+		return nil, err
+	}
+	topology.Key = key
+	return topology, nil
+}
+
+// invalidKeyError is returned when the key given in the query is invalid.
+type invalidKeyError struct {
+	error
+}
+
+func (s Sources) keyOwnership(ctx context.Context, hashring *consistent.Hashring, spread uint8, query url.Values) (*KeyOwnership, error) {
+	ownership := &KeyOwnership{
+		ResourceType: query.Get("resource_type"),
+		ResourceID:   query.Get("resource_id"),
+		Permission:   query.Get("permission"),
+		Subject:      query.Get("subject"),
+	}
+	if ownership.ResourceType == "" || ownership.ResourceID == "" || ownership.Permission == "" || ownership.Subject == "" {
+		return nil, invalidKeyError{errors.New("resource_type, resource_id, permission and subject are all required to find the owners of a key")}
+	}
+
+	subject := tuple.ParseSubjectONR(ownership.Subject)
+	if subject == nil {
+		return nil, invalidKeyError{fmt.Errorf("invalid subject %q", ownership.Subject)}
+	}
+
+	revision, err := s.revision(ctx, query.Get("revision"))
+	if err != nil {
+		return nil, err
+	}
+	ownership.Revision = revision.String()
+
+	key, err := (&keys.CanonicalKeyHandler{}).CheckDispatchKey(ctx, &dispatch.DispatchCheckRequest{
+		ResourceRelation: &core.RelationReference{Namespace: ownership.ResourceType, Relation: ownership.Permission},
+		ResourceIds:      []string{ownership.ResourceID},
+		ResultsSetting:   dispatch.DispatchCheckRequest_ALLOW_SINGLE_RESULT,
+		Subject:          subject,
+		Metadata:         &dispatch.ResolverMeta{AtRevision: ownership.Revision},
+	})
+	if err != nil {
+		return nil, err
+	}
+	ownership.Hash = hashring.Hash(key)
+
+	owners, err := hashring.FindN(key, spread)
+	if err != nil {
+		return nil, err
+	}
+	for _, owner := range owners {
+		ownership.Owners = append(ownership.Owners, owner.Key())
+	}
+	return ownership, nil
+}
+
+// revision returns the given revision, or the revision of minimize_latency calls if none is
+// given, as the dispatch key of a check depends on its revision.
+func (s Sources) revision(ctx context.Context, requested string) (datastore.Revision, error) {
+	if s.Datastore == nil {
+		return nil, invalidKeyError{errors.New("a revision is required to find the owners of a key")}
+	}
+
+	if requested == "" {
+		return s.Datastore.OptimizedRevision(ctx)
+	}
+
+	revision, err := s.Datastore.RevisionFromString(requested)
+	if err != nil {
+		return nil, invalidKeyError{fmt.Errorf("invalid revision %q: %w", requested, err)}
+	}
+	return revision, nil
+}
+
+func (s Sources) serveTopology(w http.ResponseWriter, r *http.Request) {
+	topology, err := s.Topology(r.Context(), time.Now(), r.URL.Query())
+	switch {
+	case errors.Is(err, errNoHashring):
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	case errors.As(err, &invalidKeyError{}):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	case err != nil:
+		log.Ctx(r.Context()).Warn().Err(err).Msg("failed to find the owners of a dispatch key")
+		http.Error(w, "failed to find the owners of the key", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(topology); err != nil {
+		log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write hashring topology")
+	}
+}
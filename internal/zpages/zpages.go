@@ -0,0 +1,138 @@
+// Package zpages serves the live state of a server, such as the membership of the dispatch
+// hashring and the calls in flight, to speed up debugging in production.
+package zpages
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	"github.com/authzed/spicedb/internal/auth"
+	log "github.com/authzed/spicedb/internal/logging"
+	"github.com/authzed/spicedb/internal/middleware/inflight"
+	"github.com/authzed/spicedb/pkg/cache"
+	"github.com/authzed/spicedb/pkg/consistent"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// watchMethod is the full method of the calls opening watch streams.
+var watchMethod = "/" + v1.WatchService_ServiceDesc.ServiceName + "/Watch"
+
+// Sources are the parts of the server whose state is served. Each is optional.
+type Sources struct {
+	// Hashring returns the hashring by which subproblems are dispatched to the other nodes of the
+	// cluster, or nil if none was built.
+	Hashring func() *consistent.Hashring
+
+	// InFlight tracks the calls being handled.
+	InFlight *inflight.Tracker
+
+	// Caches are the caches of the server, by name.
+	Caches map[string]cache.Cache
+
+	// QueueDepths return the number of items waiting in each of the queues of the background
+	// workers of the server, by name.
+	QueueDepths map[string]func() int
+
+	// Spread returns the number of members of the hashring among which each dispatch is balanced.
+	Spread func() uint8
+
+	// Datastore resolves the revisions of the keys whose owners on the hashring are requested.
+	Datastore datastore.Datastore
+}
+
+// State is the state of a server at a time.
+type State struct {
+	Time             time.Time                    `json:"time"`
+	Hashring         []consistent.MemberOwnership `json:"hashring,omitempty"`
+	InFlightByMethod map[string]int               `json:"inFlightByMethod,omitempty"`
+	WatchStreams     []inflight.Call              `json:"watchStreams,omitempty"`
+	Caches           map[string]CacheState        `json:"caches,omitempty"`
+	QueueDepths      map[string]int               `json:"queueDepths,omitempty"`
+}
+
+// CacheState is the occupancy of a cache, whose statistics are only kept if its metrics are
+// enabled.
+type CacheState struct {
+	CostUsed uint64 `json:"costUsed"`
+	Hits     uint64 `json:"hits"`
+	Misses   uint64 `json:"misses"`
+}
+
+// Snapshot returns the current state of the sources.
+func (s Sources) Snapshot(now time.Time) State {
+	state := State{Time: now.UTC()}
+
+	if s.Hashring != nil {
+		if hashring := s.Hashring(); hashring != nil {
+			state.Hashring = hashring.Ownership()
+		}
+	}
+
+	if s.InFlight != nil {
+		state.InFlightByMethod = s.InFlight.CountsByMethod()
+		for _, call := range s.InFlight.Calls() {
+			if call.Method == watchMethod {
+				state.WatchStreams = append(state.WatchStreams, call)
+			}
+		}
+	}
+
+	if len(s.Caches) > 0 {
+		state.Caches = make(map[string]CacheState, len(s.Caches))
+		for name, c := range s.Caches {
+			metrics := c.GetMetrics()
+			var costUsed uint64
+			if added, evicted := metrics.CostAdded(), metrics.CostEvicted(); added > evicted {
+				costUsed = added - evicted
+			}
+			state.Caches[name] = CacheState{
+				CostUsed: costUsed,
+				Hits:     metrics.Hits(),
+				Misses:   metrics.Misses(),
+			}
+		}
+	}
+
+	if len(s.QueueDepths) > 0 {
+		state.QueueDepths = make(map[string]int, len(s.QueueDepths))
+		for name, depth := range s.QueueDepths {
+			state.QueueDepths[name] = depth()
+		}
+	}
+	return state
+}
+
+// NewHandler returns an http.Handler serving the JSON-encoded current State of the sources at
+// `/debug/zpages`, and the HashringTopology at `/debug/zpages/hashring`, authenticated with one
+// of the preshared keys within its validity window. If no keys are given, the handler must be
+// authenticated by the caller.
+//
+// The owners of the check of a permission of a resource for a subject are included in the
+// topology when given the `resource_type`, `resource_id`, `permission` and `subject` query
+// parameters, at the revision of the `revision` parameter or that of minimize_latency calls.
+func NewHandler(sources Sources, presharedKeys []string, validity map[string]auth.PresharedKeyValidity) http.Handler {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+			return
+		}
+
+		if strings.HasSuffix(r.URL.Path, "/hashring") {
+			sources.serveTopology(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(sources.Snapshot(time.Now())); err != nil {
+			log.Ctx(r.Context()).Warn().Err(err).Msg("failed to write zpages")
+		}
+	})
+	if len(presharedKeys) == 0 {
+		return handler
+	}
+	return auth.RequireHTTPPresharedKey(presharedKeys, validity, handler)
+}
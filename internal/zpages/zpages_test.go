@@ -0,0 +1,140 @@
+package zpages
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/middleware/inflight"
+	"github.com/authzed/spicedb/pkg/cache"
+	"github.com/authzed/spicedb/pkg/consistent"
+)
+
+type testMember string
+
+func (m testMember) Key() string {
+	return string(m)
+}
+
+type testServerStream struct {
+	grpc.ServerStream
+}
+
+func (testServerStream) Context() context.Context {
+	return context.Background()
+}
+
+func TestHandler(t *testing.T) {
+	require := require.New(t)
+
+	hashring := consistent.NewHashring(xxhash.Sum64, 20)
+	require.NoError(hashring.Add(testMember("node1:50053")))
+	require.NoError(hashring.Add(testMember("node2:50053")))
+
+	tracker := inflight.NewTracker()
+	sources := Sources{
+		Hashring:    func() *consistent.Hashring { return hashring },
+		InFlight:    tracker,
+		Caches:      map[string]cache.Cache{"dispatch": cache.NoopCache()},
+		QueueDepths: map[string]func() int{"audit": func() int { return 3 }},
+	}
+	handler := NewHandler(sources, []string{"somekey"}, nil)
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/zpages", nil))
+	require.Equal(http.StatusUnauthorized, recorder.Code)
+
+	recorder = httptest.NewRecorder()
+	err := inflight.StreamServerInterceptor(tracker)(nil, testServerStream{}, &grpc.StreamServerInfo{FullMethod: watchMethod}, func(srv interface{}, stream grpc.ServerStream) error {
+		request := httptest.NewRequest(http.MethodGet, "/debug/zpages", nil)
+		request.Header.Set("Authorization", "Bearer somekey")
+		handler.ServeHTTP(recorder, request)
+		return nil
+	})
+	require.NoError(err)
+	require.Equal(http.StatusOK, recorder.Code)
+
+	var state State
+	require.NoError(json.Unmarshal(recorder.Body.Bytes(), &state))
+	require.Len(state.Hashring, 2)
+	require.Equal("node1:50053", state.Hashring[0].Key)
+	require.InDelta(1.0, state.Hashring[0].Fraction+state.Hashring[1].Fraction, 1e-9)
+	require.Equal(map[string]int{watchMethod: 1}, state.InFlightByMethod)
+	require.Len(state.WatchStreams, 1)
+	require.Equal(map[string]CacheState{"dispatch": {}}, state.Caches)
+	require.Equal(map[string]int{"audit": 3}, state.QueueDepths)
+}
+
+func TestHashringHandler(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	hashring := consistent.NewHashring(xxhash.Sum64, 20)
+	require.NoError(hashring.Add(testMember("node1:50053")))
+	require.NoError(hashring.Add(testMember("node2:50053")))
+	require.NoError(hashring.Add(testMember("node3:50053")))
+
+	sources := Sources{
+		Hashring:  func() *consistent.Hashring { return hashring },
+		Spread:    func() uint8 { return 2 },
+		Datastore: rawDS,
+	}
+	handler := NewHandler(sources, []string{"somekey"}, nil)
+
+	get := func(target string) *httptest.ResponseRecorder {
+		recorder := httptest.NewRecorder()
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.Header.Set("Authorization", "Bearer somekey")
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := get("/debug/zpages/hashring")
+	require.Equal(http.StatusOK, recorder.Code)
+
+	var topology HashringTopology
+	require.NoError(json.Unmarshal(recorder.Body.Bytes(), &topology))
+	require.Equal(uint16(20), topology.ReplicationFactor)
+	require.Equal(uint8(2), topology.Spread)
+	require.Len(topology.Members, 3)
+	require.Equal("node1:50053", topology.Members[0].Key)
+	require.Len(topology.Members[0].VirtualNodes, 20)
+	require.Nil(topology.Key)
+
+	recorder = get("/debug/zpages/hashring?resource_type=document&resource_id=somedoc&permission=view&subject=user:tom")
+	require.Equal(http.StatusOK, recorder.Code)
+
+	topology = HashringTopology{}
+	require.NoError(json.Unmarshal(recorder.Body.Bytes(), &topology))
+	require.NotNil(topology.Key)
+	require.NotEmpty(topology.Key.Revision)
+	require.Len(topology.Key.Owners, 2)
+	require.NotEqual(topology.Key.Owners[0], topology.Key.Owners[1])
+
+	// The owners of a key at a given revision are stable.
+	recorder = get("/debug/zpages/hashring?resource_type=document&resource_id=somedoc&permission=view&subject=user:tom&revision=" + topology.Key.Revision)
+	require.Equal(http.StatusOK, recorder.Code)
+
+	var atRevision HashringTopology
+	require.NoError(json.Unmarshal(recorder.Body.Bytes(), &atRevision))
+	require.Equal(topology.Key, atRevision.Key)
+
+	recorder = get("/debug/zpages/hashring?resource_type=document&resource_id=somedoc")
+	require.Equal(http.StatusBadRequest, recorder.Code)
+
+	recorder = get("/debug/zpages/hashring?resource_type=document&resource_id=somedoc&permission=view&subject=user:tom&revision=invalid")
+	require.Equal(http.StatusBadRequest, recorder.Code)
+
+	handler = NewHandler(Sources{}, []string{"somekey"}, nil)
+	recorder = get("/debug/zpages/hashring")
+	require.Equal(http.StatusNotFound, recorder.Code)
+}
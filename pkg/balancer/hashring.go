@@ -3,6 +3,7 @@ package balancer
 import (
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"google.golang.org/grpc/balancer"
@@ -29,6 +30,26 @@ const (
 
 var logger = grpclog.Component("consistenthashring")
 
+// currentHashring is the hashring most recently built by a consistent hashring balancer.
+var currentHashring atomic.Pointer[consistent.Hashring]
+
+// currentSpread is the spread of the consistent hashring balancer which most recently built a
+// hashring.
+var currentSpread atomic.Uint32
+
+// CurrentHashring returns the hashring most recently built by a consistent hashring balancer
+// from the ready connections to its backends, or nil if none was built, such as to introspect
+// the membership of the hashring while debugging.
+func CurrentHashring() *consistent.Hashring {
+	return currentHashring.Load()
+}
+
+// CurrentSpread returns the number of members of the current hashring among which each request
+// is randomly balanced, or zero if no hashring was built.
+func CurrentSpread() uint8 {
+	return uint8(currentSpread.Load())
+}
+
 // NewConsistentHashringBuilder creates a new balancer.Builder that
 // will create a consistent hashring balancer with the given config.
 // Before making a connection, register it with grpc with:
@@ -74,6 +95,8 @@ func (b *consistentHashringPickerBuilder) Build(info base.PickerBuildInfo) balan
 			return base.NewErrPicker(err)
 		}
 	}
+	currentHashring.Store(hashring)
+	currentSpread.Store(uint32(b.spread))
 	return &consistentHashringPicker{
 		hashring: hashring,
 		spread:   b.spread,
@@ -53,6 +53,10 @@ type Cache interface {
 	// Set sets a value for the key in the cache, with the given cost.
 	Set(key interface{}, entry interface{}, cost int64) bool
 
+	// SetMaxCost changes the capacity of the cache. If the capacity shrinks, entries are evicted
+	// as new entries are set.
+	SetMaxCost(maxCost int64)
+
 	// Wait waits for the cache to process and apply updates.
 	Wait()
 
@@ -65,6 +69,17 @@ type Cache interface {
 	zerolog.LogObjectMarshaler
 }
 
+// EvictionFunc is called with the value and cost of an entry removed from a cache, which was
+// either evicted to make room for other entries, or rejected by the admission policy of the cache
+// when it was set.
+type EvictionFunc func(entry interface{}, cost int64, rejected bool)
+
+// EvictionNotifier is implemented by the caches which report the entries they remove.
+type EvictionNotifier interface {
+	// NotifyEvictions sets the function called for each entry removed from the cache.
+	NotifyEvictions(onEvict EvictionFunc)
+}
+
 // Metrics defines metrics exported by the cache.
 type Metrics interface {
 	// Hits is the number of cache hits.
@@ -91,6 +106,7 @@ var _ Cache = (*noopCache)(nil)
 
 func (no *noopCache) Get(key interface{}) (interface{}, bool)                 { return nil, false }
 func (no *noopCache) Set(key interface{}, entry interface{}, cost int64) bool { return false }
+func (no *noopCache) SetMaxCost(maxCost int64)                                {}
 func (no *noopCache) Wait()                                                   {}
 func (no *noopCache) Close()                                                  {}
 func (no *noopCache) GetMetrics() Metrics                                     { return &noopMetrics{} }
@@ -4,6 +4,8 @@
 package cache
 
 import (
+	"sync/atomic"
+
 	"github.com/outcaste-io/ristretto"
 	"github.com/outcaste-io/ristretto/z"
 	"github.com/rs/zerolog"
@@ -13,6 +15,13 @@ import (
 
 // NewCache creates a new ristretto cache from the given config.
 func NewCache(config *Config) (Cache, error) {
+	onEvict := &atomic.Pointer[EvictionFunc]{}
+	notify := func(item *ristretto.Item, rejected bool) {
+		if fn := onEvict.Load(); fn != nil {
+			(*fn)(item.Value, item.Cost, rejected)
+		}
+	}
+
 	cache, err := ristretto.NewCache(&ristretto.Config{
 		NumCounters: config.NumCounters,
 		MaxCost:     config.MaxCost,
@@ -25,19 +34,28 @@ func NewCache(config *Config) (Cache, error) {
 			}
 			return dispatchCacheKey.AsUInt64s()
 		},
+		OnEvict:  func(item *ristretto.Item) { notify(item, false) },
+		OnReject: func(item *ristretto.Item) { notify(item, true) },
 	})
 	if err != nil {
 		return nil, err
 	}
-	return wrapped{config, cache}, nil
+	return wrapped{config, cache, onEvict}, nil
 }
 
 type wrapped struct {
 	config *Config
 	*ristretto.Cache
+	onEvict *atomic.Pointer[EvictionFunc]
 }
 
-var _ Cache = (*wrapped)(nil)
+var (
+	_ Cache            = (*wrapped)(nil)
+	_ EvictionNotifier = (*wrapped)(nil)
+)
+
+func (w wrapped) NotifyEvictions(onEvict EvictionFunc) { w.onEvict.Store(&onEvict) }
 
 func (w wrapped) GetMetrics() Metrics                   { return w.Cache.Metrics }
+func (w wrapped) SetMaxCost(maxCost int64)              { w.Cache.UpdateMaxCost(maxCost) }
 func (w wrapped) MarshalZerologObject(e *zerolog.Event) { e.EmbedObject(w.config) }
@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/backup"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+const defaultRestoreBatchSize = 1000
+
+func RegisterBackupFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().String("zedtoken", "", "zedtoken at whose revision the backup is taken (if empty, the head revision of the datastore)")
+}
+
+func NewBackupCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "backup <archive path>",
+		Short: "writes the schema and relationships of a datastore to a backup archive",
+		Long: "Streams the schema and all of the relationships of the datastore, as of a single revision, to a compressed and checksummed " +
+			"archive which can be restored into a datastore of any engine with the restore command. The archive is written to standard " +
+			"output if its path is -",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token, err := cmd.Flags().GetString("zedtoken")
+			if err != nil {
+				return err
+			}
+
+			ds, err := datastore.NewDatastore(cmd.Context(), config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to create datastore: %w", err)
+			}
+			defer ds.Close()
+
+			revision, err := ds.HeadRevision(cmd.Context())
+			if err != nil {
+				return fmt.Errorf("failed to read head revision: %w", err)
+			}
+			if token != "" {
+				_, revision, err = zedtoken.Translate(cmd.Context(), &v1.ZedToken{Token: token}, ds)
+				if err != nil {
+					return fmt.Errorf("invalid zedtoken: %w", err)
+				}
+			}
+
+			var out io.Writer = cmd.OutOrStdout()
+			if args[0] != "-" {
+				f, err := os.Create(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to create backup archive: %w", err)
+				}
+				defer f.Close()
+				out = f
+			}
+
+			manifest, err := backup.Write(cmd.Context(), ds, revision, out)
+			if err != nil {
+				return fmt.Errorf("failed to back up datastore: %w", err)
+			}
+
+			if args[0] != "-" {
+				fmt.Fprintf(cmd.OutOrStdout(), "backed up %d definitions, %d caveats and %d relationships at revision %s\nsha256: %s\n",
+					manifest.Namespaces, manifest.Caveats, manifest.Relationships, manifest.Revision, manifest.SHA256)
+			}
+			return nil
+		},
+		Args: cobra.ExactArgs(1),
+	}
+}
+
+func RegisterRestoreFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Uint64("batch-size", defaultRestoreBatchSize, "number of relationships written per transaction")
+}
+
+func NewRestoreCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "restore <archive path>",
+		Short: "restores the schema and relationships of a backup archive into a datastore",
+		Long: "Verifies the checksum of a backup archive and restores its schema and relationships into the datastore, which must be empty or already hold the schema of the archive. " +
+			"If interrupted, the restore can be resumed by running the same command again",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize, err := cmd.Flags().GetUint64("batch-size")
+			if err != nil {
+				return err
+			}
+
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open backup archive: %w", err)
+			}
+			defer f.Close()
+
+			ds, err := datastore.NewDatastore(cmd.Context(), config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to create datastore: %w", err)
+			}
+			defer ds.Close()
+
+			manifest, err := backup.Restore(cmd.Context(), ds, f, batchSize, func(progress backup.RestoreProgress) {
+				fmt.Fprintf(cmd.OutOrStdout(), "%d relationships restored\n", progress.RelationshipsWritten)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to restore backup archive: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "restored %d definitions, %d caveats and %d relationships backed up at revision %s\n",
+				manifest.Namespaces, manifest.Caveats, manifest.Relationships, manifest.Revision)
+			return nil
+		},
+		Args: cobra.ExactArgs(1),
+	}
+}
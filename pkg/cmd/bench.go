@@ -0,0 +1,160 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/grpcutil"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/authzed/spicedb/internal/bench"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+func RegisterBenchFlags(cmd *cobra.Command) {
+	cmd.Flags().String("endpoint", "localhost:50051", "address of the gRPC API of the SpiceDB cluster")
+	cmd.Flags().String("token", "", "preshared key or token authenticating the calls")
+	cmd.Flags().Bool("insecure", false, "connect without TLS")
+	cmd.Flags().String("ca-path", "", "path of the CA certificates verifying the TLS certificate of the cluster (if empty, the system certificates are used)")
+
+	cmd.Flags().Duration("duration", time.Minute, "how long calls are made for")
+	cmd.Flags().Float64("rps", 100, "target rate of calls per second")
+	cmd.Flags().Int("concurrency", 16, "maximum number of calls in flight at once")
+	cmd.Flags().StringToInt("mix", map[string]int{string(bench.Check): 80, string(bench.Lookup): 10, string(bench.Write): 10}, "relative weight of each operation (check, lookup and write) among the calls")
+
+	cmd.Flags().String("resource-type", "resource", "object definition of the resources")
+	cmd.Flags().String("relation", "viewer", "relation written between the resources and the subjects")
+	cmd.Flags().String("permission", "view", "permission checked and looked up")
+	cmd.Flags().String("subject-type", "user", "object definition of the subjects")
+	cmd.Flags().Uint64("resources", 10_000, "number of distinct resources named by calls")
+	cmd.Flags().Uint64("subjects", 1_000, "number of distinct subjects named by calls")
+	cmd.Flags().Float64("zipf-s", 1.1, "exponent, greater than 1, of the zipfian distributions of the resources and subjects; higher values concentrate calls on fewer keys")
+	cmd.Flags().Int64("seed", 0, "seed of the choice of calls (if zero, a seed is chosen from the current time)")
+}
+
+func NewBenchCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "bench",
+		Short: "generates load against a SpiceDB cluster and reports its latencies",
+		Long: "Makes a mix of CheckPermission, LookupResources and WriteRelationships calls, naming resources and subjects drawn from " +
+			"zipfian distributions, against a SpiceDB cluster at a target rate, and reports the latency percentiles of each kind of call " +
+			"along with the dispatches they made and how many were answered from the dispatch cache. The schema of the cluster must " +
+			"define the resource type, with the relation to the subject type and the permission. Write calls touch relationships of " +
+			"resources and subjects whose IDs are prefixed with bench_",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := benchConfig(cmd)
+			if err != nil {
+				return err
+			}
+			if err := config.Validate(); err != nil {
+				return err
+			}
+
+			dialOpts, err := benchDialOptions(cmd)
+			if err != nil {
+				return err
+			}
+			endpoint, err := cmd.Flags().GetString("endpoint")
+			if err != nil {
+				return err
+			}
+			conn, err := grpc.DialContext(cmd.Context(), endpoint, dialOpts...)
+			if err != nil {
+				return fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+			}
+			defer conn.Close()
+
+			fmt.Fprintf(cmd.OutOrStdout(), "running %s of calls at %.1f per second against %s (seed %d)\n\n", config.Duration, config.RPS, endpoint, config.Seed)
+			report, err := bench.Run(cmd.Context(), v1.NewPermissionsServiceClient(conn), config)
+			if err != nil {
+				return err
+			}
+			return report.Write(cmd.OutOrStdout())
+		},
+		Args: cobra.NoArgs,
+	}
+}
+
+func benchConfig(cmd *cobra.Command) (config bench.Config, err error) {
+	if config.Duration, err = cmd.Flags().GetDuration("duration"); err != nil {
+		return
+	}
+	if config.RPS, err = cmd.Flags().GetFloat64("rps"); err != nil {
+		return
+	}
+	if config.Concurrency, err = cmd.Flags().GetInt("concurrency"); err != nil {
+		return
+	}
+	if config.ResourceType, err = cmd.Flags().GetString("resource-type"); err != nil {
+		return
+	}
+	if config.Relation, err = cmd.Flags().GetString("relation"); err != nil {
+		return
+	}
+	if config.Permission, err = cmd.Flags().GetString("permission"); err != nil {
+		return
+	}
+	if config.SubjectType, err = cmd.Flags().GetString("subject-type"); err != nil {
+		return
+	}
+	if config.Resources, err = cmd.Flags().GetUint64("resources"); err != nil {
+		return
+	}
+	if config.Subjects, err = cmd.Flags().GetUint64("subjects"); err != nil {
+		return
+	}
+	if config.ZipfS, err = cmd.Flags().GetFloat64("zipf-s"); err != nil {
+		return
+	}
+	if config.Seed, err = cmd.Flags().GetInt64("seed"); err != nil {
+		return
+	}
+	if config.Seed == 0 {
+		config.Seed = time.Now().UnixNano()
+	}
+
+	mix, err := cmd.Flags().GetStringToInt("mix")
+	if err != nil {
+		return
+	}
+	config.Mix = make(map[bench.Operation]int, len(mix))
+	for op, weight := range mix {
+		config.Mix[bench.Operation(op)] = weight
+	}
+	return
+}
+
+func benchDialOptions(cmd *cobra.Command) ([]grpc.DialOption, error) {
+	token, err := cmd.Flags().GetString("token")
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := cmd.Flags().GetBool("insecure")
+	if err != nil {
+		return nil, err
+	}
+	caPath, err := cmd.Flags().GetString("ca-path")
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case plaintext:
+		return []grpc.DialOption{
+			grpc.WithTransportCredentials(insecure.NewCredentials()),
+			grpcutil.WithInsecureBearerToken(token),
+		}, nil
+	case caPath != "":
+		if _, err := os.Stat(caPath); err != nil {
+			return nil, fmt.Errorf("invalid CA path: %w", err)
+		}
+		return []grpc.DialOption{grpcutil.WithCustomCerts(caPath, grpcutil.VerifyCA), grpcutil.WithBearerToken(token)}, nil
+	default:
+		return []grpc.DialOption{grpcutil.WithSystemCerts(grpcutil.VerifyCA), grpcutil.WithBearerToken(token)}, nil
+	}
+}
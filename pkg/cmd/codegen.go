@@ -0,0 +1,78 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/schemadsl/codegen"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+func RegisterCodegenFlags(cmd *cobra.Command) {
+	languages := make([]string, 0, len(codegen.Languages))
+	for _, language := range codegen.Languages {
+		languages = append(languages, string(language))
+	}
+
+	cmd.Flags().String("language", string(codegen.Go), fmt.Sprintf("language of the generated code (one of: %s)", strings.Join(languages, ", ")))
+	cmd.Flags().String("go-package", "authz", "package name of the generated Go source")
+	cmd.Flags().StringP("output", "o", "", "file to which the generated code is written; stdout if empty")
+}
+
+func NewCodegenCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:     "codegen <schema file>",
+		Short:   "generates client constants and helpers from a schema",
+		Long:    "Generates constants for the object types, relations, permissions and caveats in a schema, along with typed helpers for building references, so that application code need not use string literals",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE:    codegenRunE,
+		Args:    cobra.ExactArgs(1),
+	}
+}
+
+func codegenRunE(cmd *cobra.Command, args []string) error {
+	schemaBytes, err := os.ReadFile(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to read schema file: %w", err)
+	}
+
+	empty := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source(args[0]),
+		SchemaString: string(schemaBytes),
+	}, &empty)
+	if err != nil {
+		return err
+	}
+
+	language, err := cmd.Flags().GetString("language")
+	if err != nil {
+		return err
+	}
+
+	goPackage, err := cmd.Flags().GetString("go-package")
+	if err != nil {
+		return err
+	}
+
+	generated, err := codegen.Generate(compiled, codegen.Language(language), codegen.Options{GoPackage: goPackage})
+	if err != nil {
+		return err
+	}
+
+	output, err := cmd.Flags().GetString("output")
+	if err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = fmt.Fprint(cmd.OutOrStdout(), generated)
+		return err
+	}
+	return os.WriteFile(output, []byte(generated), 0o644)
+}
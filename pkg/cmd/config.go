@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+// NewConfigCommand returns the parent of the commands operating on the configuration of the serve
+// command.
+func NewConfigCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "config",
+		Short: "configuration file operations",
+		Long:  "Operations on the configuration of the serve command, as layered from its config file, environment variables and flags",
+	}
+}
+
+func RegisterPrintEffectiveConfigFlags(cmd *cobra.Command, config *server.Config) {
+	RegisterServeFlags(cmd, config)
+	cmd.Flags().Bool("include-defaults", false, "also print the settings left at their defaults")
+	cmd.Flags().Bool("show-secrets", false, "print the values of the settings holding preshared keys, connection URIs and headers rather than redacting them")
+}
+
+func NewPrintEffectiveConfigCommand(programName string, config *server.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "print-effective",
+		Short: "prints the effective configuration of the serve command",
+		Long: "Validates the config file and prints, as a YAML config file, the settings with which the serve command would run given the same " +
+			"config file, environment variables and flags, each commented with whether it was set by the config file (config), an " +
+			"environment variable (env) or a flag (flag). Settings holding secrets are redacted unless --show-secrets is given",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			includeDefaults, err := cmd.Flags().GetBool("include-defaults")
+			if err != nil {
+				return err
+			}
+			showSecrets, err := cmd.Flags().GetBool("show-secrets")
+			if err != nil {
+				return err
+			}
+			return server.WriteEffectiveConfig(cmd.OutOrStdout(), cmd.Flags(), includeDefaults, showSecrets, "include-defaults", "show-secrets")
+		},
+		Args: cobra.NoArgs,
+	}
+}
@@ -0,0 +1,90 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/datagen"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+const defaultGenerateBatchSize = 1000
+
+func RegisterGenerateFlags(cmd *cobra.Command, dsConfig *datastore.Config, config *datagen.Config) {
+	datastore.RegisterDatastoreFlags(cmd, dsConfig)
+	cmd.Flags().Uint64("batch-size", defaultGenerateBatchSize, "number of relationships written per transaction")
+	cmd.Flags().Bool("dry-run", false, "print the generated schema and the counts of the generated dataset without writing anything")
+
+	cmd.Flags().StringVar(&config.Prefix, "prefix", "", "prefix of the names of the generated definitions and caveats (e.g. demo makes demo/user)")
+	cmd.Flags().Uint64Var(&config.Tenants, "tenants", 10, "number of tenants among which the objects are partitioned")
+	cmd.Flags().Float64Var(&config.TenantSkew, "tenant-skew", 1, "exponent of the zipfian distribution of the sizes of the tenants (if zero, the tenants are the same size)")
+	cmd.Flags().Uint64Var(&config.Depth, "depth", 3, fmt.Sprintf("number of levels of resource definitions, each the parent of the next (at most %d)", datagen.MaxDepth))
+	cmd.Flags().Uint64Var(&config.FanOut, "fan-out", 10, "number of children of each resource in the level below it")
+	cmd.Flags().Uint64Var(&config.Roots, "roots", 100, "number of resources at the top level, over all of the tenants")
+	cmd.Flags().Uint64Var(&config.Users, "users", 1000, "number of users, over all of the tenants")
+	cmd.Flags().Uint64Var(&config.Groups, "groups", 100, "number of groups, over all of the tenants")
+	cmd.Flags().Uint64Var(&config.GroupSize, "group-size", 10, "number of users which are members of each group")
+	cmd.Flags().Float64Var(&config.NestedGroupRatio, "nested-group-ratio", 0.1, "fraction of the groups which are members of another group")
+	cmd.Flags().Uint64Var(&config.Grants, "grants", 3, "number of users and groups granted a role directly on each resource")
+	cmd.Flags().Float64Var(&config.CaveatRatio, "caveat-ratio", 0.1, "fraction of the grants to users which are conditioned on a caveat")
+	cmd.Flags().Int64Var(&config.Seed, "seed", 1, "seed of the generated dataset; the same flags and seed always generate the same dataset")
+}
+
+func NewGenerateCommand(programName string, dsConfig *datastore.Config, config *datagen.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "generate",
+		Short: "loads a synthetic schema and relationships into the datastore",
+		Long: "Generates a schema of users, nested groups, a caveat and a hierarchy of resource definitions, along with relationships " +
+			"among them of the configured depth, fan-out, caveat ratio and tenant skew, and loads them into the datastore, such as " +
+			"for reproducible performance testing and demos. Definitions of the same names are replaced. As relationships are touched, " +
+			"an interrupted load can be resumed by running the same command again",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize, err := cmd.Flags().GetUint64("batch-size")
+			if err != nil {
+				return err
+			}
+			dryRun, err := cmd.Flags().GetBool("dry-run")
+			if err != nil {
+				return err
+			}
+			if err := config.Validate(); err != nil {
+				return err
+			}
+
+			if dryRun {
+				stats, err := datagen.Generate(*config, func(*core.RelationTuple) error { return nil })
+				if err != nil {
+					return err
+				}
+				fmt.Fprintln(cmd.OutOrStdout(), datagen.Schema(*config))
+				printGenerateStats(cmd, stats)
+				return nil
+			}
+
+			ds, err := datastore.NewDatastore(cmd.Context(), dsConfig.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to create datastore: %w", err)
+			}
+			defer ds.Close()
+
+			stats, err := datagen.Load(cmd.Context(), ds, *config, batchSize, func(written uint64) {
+				fmt.Fprintf(cmd.OutOrStdout(), "%d relationships written\n", written)
+			})
+			if err != nil {
+				return err
+			}
+			printGenerateStats(cmd, stats)
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+}
+
+func printGenerateStats(cmd *cobra.Command, stats datagen.Stats) {
+	fmt.Fprintf(cmd.OutOrStdout(), "%d users, %d groups and %d resources, with %d relationships of which %d are caveated\n",
+		stats.Users, stats.Groups, stats.Resources, stats.Relationships, stats.CaveatedRelationships)
+}
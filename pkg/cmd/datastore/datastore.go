@@ -8,6 +8,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/internal/datastore/crdb"
 	"github.com/authzed/spicedb/internal/datastore/memdb"
 	"github.com/authzed/spicedb/internal/datastore/mysql"
@@ -51,6 +52,7 @@ type Config struct {
 	MaxOpenConns           int
 	MinOpenConns           int
 	SplitQueryCount        uint16
+	SlowQueryThreshold     time.Duration
 	ReadOnly               bool
 	EnableDatastoreMetrics bool
 	DisableStats           bool
@@ -75,6 +77,7 @@ type Config struct {
 	// Postgres
 	HealthCheckPeriod  time.Duration
 	GCInterval         time.Duration
+	GCIntervalOverride *common.GCIntervalOverride
 	GCMaxOperationTime time.Duration
 
 	// Spanner
@@ -116,6 +119,7 @@ func RegisterDatastoreFlags(cmd *cobra.Command, opts *Config) {
 	// See crdb doc for info about follower reads and how it is configured: https://www.cockroachlabs.com/docs/stable/follower-reads.html
 	cmd.Flags().DurationVar(&opts.FollowerReadDelay, "datastore-follower-read-delay-duration", 4_800*time.Millisecond, "amount of time to subtract from non-sync revision timestamps to ensure they are sufficiently in the past to enable follower reads (cockroach driver only)")
 	cmd.Flags().Uint16Var(&opts.SplitQueryCount, "datastore-query-userset-batch-size", 1024, "number of usersets after which a relationship query will be split into multiple queries")
+	cmd.Flags().DurationVar(&opts.SlowQueryThreshold, "datastore-slow-query-threshold", 0, "duration beyond which relationship queries are logged as slow, along with their SQL, API method and revision (0 disables; not applicable to the memory driver)")
 	cmd.Flags().IntVar(&opts.MaxRetries, "datastore-max-tx-retries", 10, "number of times a retriable transaction should be retried")
 	cmd.Flags().StringVar(&opts.OverlapStrategy, "datastore-tx-overlap-strategy", "static", `strategy to generate transaction overlap keys ("prefix", "static", "insecure") (cockroach driver only)`)
 	cmd.Flags().StringVar(&opts.OverlapKey, "datastore-tx-overlap-key", "key", "static key to touch when writing to ensure transactions overlap (only used if --datastore-tx-overlap-strategy=static is set; cockroach driver only)")
@@ -237,6 +241,7 @@ func newCRDBDatastore(opts Config) (datastore.Datastore, error) {
 		crdb.MaxOpenConns(opts.MaxOpenConns),
 		crdb.MinOpenConns(opts.MinOpenConns),
 		crdb.SplitAtUsersetCount(opts.SplitQueryCount),
+		crdb.SlowQueryThreshold(opts.SlowQueryThreshold),
 		crdb.FollowerReadDelay(opts.FollowerReadDelay),
 		crdb.MaxRetries(uint8(opts.MaxRetries)),
 		crdb.OverlapKey(opts.OverlapKey),
@@ -257,8 +262,10 @@ func newPostgresDatastore(opts Config) (datastore.Datastore, error) {
 		postgres.MaxOpenConns(opts.MaxOpenConns),
 		postgres.MinOpenConns(opts.MinOpenConns),
 		postgres.SplitAtUsersetCount(opts.SplitQueryCount),
+		postgres.SlowQueryThreshold(opts.SlowQueryThreshold),
 		postgres.HealthCheckPeriod(opts.HealthCheckPeriod),
 		postgres.GCInterval(opts.GCInterval),
+		postgres.GCIntervalOverride(opts.GCIntervalOverride),
 		postgres.GCMaxOperationTime(opts.GCMaxOperationTime),
 		postgres.EnableTracing(),
 		postgres.WatchBufferLength(opts.WatchBufferLength),
@@ -279,6 +286,7 @@ func newSpannerDatastore(opts Config) (datastore.Datastore, error) {
 		spanner.CredentialsFile(opts.SpannerCredentialsFile),
 		spanner.WatchBufferLength(opts.WatchBufferLength),
 		spanner.EmulatorHost(opts.SpannerEmulatorHost),
+		spanner.SlowQueryThreshold(opts.SlowQueryThreshold),
 	)
 }
 
@@ -287,6 +295,7 @@ func newMySQLDatastore(opts Config) (datastore.Datastore, error) {
 		mysql.GCInterval(opts.GCInterval),
 		mysql.GCWindow(opts.GCWindow),
 		mysql.GCInterval(opts.GCInterval),
+		mysql.GCIntervalOverride(opts.GCIntervalOverride),
 		mysql.GCEnabled(!opts.ReadOnly),
 		mysql.GCMaxOperationTime(opts.GCMaxOperationTime),
 		mysql.ConnMaxIdleTime(opts.MaxIdleTime),
@@ -299,6 +308,7 @@ func newMySQLDatastore(opts Config) (datastore.Datastore, error) {
 		mysql.MaxRetries(uint8(opts.MaxRetries)),
 		mysql.OverrideLockWaitTimeout(1),
 		mysql.SplitAtUsersetCount(opts.SplitQueryCount),
+		mysql.SlowQueryThreshold(opts.SlowQueryThreshold),
 	}
 	return mysql.NewMySQLDatastore(opts.URI, mysqlOpts...)
 }
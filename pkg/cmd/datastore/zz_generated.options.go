@@ -1,7 +1,10 @@
 // Code generated by github.com/ecordell/optgen. DO NOT EDIT.
 package datastore
 
-import "time"
+import (
+	common "github.com/authzed/spicedb/internal/datastore/common"
+	"time"
+)
 
 type ConfigOption func(c *Config)
 
@@ -27,6 +30,7 @@ func (c *Config) ToOption() ConfigOption {
 		to.MaxOpenConns = c.MaxOpenConns
 		to.MinOpenConns = c.MinOpenConns
 		to.SplitQueryCount = c.SplitQueryCount
+		to.SlowQueryThreshold = c.SlowQueryThreshold
 		to.ReadOnly = c.ReadOnly
 		to.EnableDatastoreMetrics = c.EnableDatastoreMetrics
 		to.DisableStats = c.DisableStats
@@ -43,6 +47,7 @@ func (c *Config) ToOption() ConfigOption {
 		to.OverlapStrategy = c.OverlapStrategy
 		to.HealthCheckPeriod = c.HealthCheckPeriod
 		to.GCInterval = c.GCInterval
+		to.GCIntervalOverride = c.GCIntervalOverride
 		to.GCMaxOperationTime = c.GCMaxOperationTime
 		to.SpannerCredentialsFile = c.SpannerCredentialsFile
 		to.SpannerEmulatorHost = c.SpannerEmulatorHost
@@ -130,6 +135,13 @@ func WithSplitQueryCount(splitQueryCount uint16) ConfigOption {
 	}
 }
 
+// WithSlowQueryThreshold returns an option that can set SlowQueryThreshold on a Config
+func WithSlowQueryThreshold(slowQueryThreshold time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.SlowQueryThreshold = slowQueryThreshold
+	}
+}
+
 // WithReadOnly returns an option that can set ReadOnly on a Config
 func WithReadOnly(readOnly bool) ConfigOption {
 	return func(c *Config) {
@@ -249,6 +261,13 @@ func WithGCInterval(gCInterval time.Duration) ConfigOption {
 	}
 }
 
+// WithGCIntervalOverride returns an option that can set GCIntervalOverride on a Config
+func WithGCIntervalOverride(gCIntervalOverride *common.GCIntervalOverride) ConfigOption {
+	return func(c *Config) {
+		c.GCIntervalOverride = gCIntervalOverride
+	}
+}
+
 // WithGCMaxOperationTime returns an option that can set GCMaxOperationTime on a Config
 func WithGCMaxOperationTime(gCMaxOperationTime time.Duration) ConfigOption {
 	return func(c *Config) {
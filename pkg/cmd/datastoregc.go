@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	pkgdatastore "github.com/authzed/spicedb/pkg/datastore"
+)
+
+const (
+	defaultGCBatchSize  = 1000
+	defaultGCTimeBudget = 10 * time.Minute
+)
+
+// NewDatastoreCommand returns the parent of the commands operating on the datastore out-of-band
+// of the serving processes.
+func NewDatastoreCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "datastore",
+		Short: "datastore maintenance operations",
+		Long:  "Maintenance operations run against the configured datastore out-of-band of the serving processes",
+	}
+}
+
+func RegisterGCFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Uint64("batch-size", defaultGCBatchSize, "maximum number of rows deleted by each statement")
+	cmd.Flags().Duration("time-budget", defaultGCTimeBudget, "time after which no further batch is started (if zero, the pass runs to completion)")
+	cmd.Flags().StringSlice("namespace", nil, "object definitions whose relationships are collected (if empty, all of the data beyond the window is collected)")
+}
+
+func NewGCCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "gc",
+		Short: "runs a single bounded garbage collection pass against the datastore",
+		Long: "Runs a single garbage collection pass, deleting the data older than --datastore-gc-window in batches of --batch-size rows, " +
+			"until done or until --time-budget has elapsed, such as to catch up on a garbage collection backlog during a maintenance window. " +
+			"With --namespace, only the relationships and deleted definitions of the given object definitions are collected. " +
+			"Only the postgres and mysql engines are supported",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize, err := cmd.Flags().GetUint64("batch-size")
+			if err != nil {
+				return err
+			}
+			if batchSize == 0 {
+				return errors.New("batch size must be greater than zero")
+			}
+			timeBudget, err := cmd.Flags().GetDuration("time-budget")
+			if err != nil {
+				return err
+			}
+			namespaces, err := cmd.Flags().GetStringSlice("namespace")
+			if err != nil {
+				return err
+			}
+			if config.ReadOnly {
+				return errors.New("cannot garbage collect a read-only datastore")
+			}
+
+			// The pass runs in this process alone, without the garbage collection worker of the
+			// datastore or the proxies which would hide its garbage collector.
+			ds, err := datastore.NewDatastore(cmd.Context(),
+				config.ToOption(),
+				datastore.WithGCInterval(0),
+				datastore.WithRequestHedgingEnabled(false),
+				datastore.SetBootstrapFiles(nil),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create datastore: %w", err)
+			}
+			defer ds.Close()
+
+			gc, ok := ds.(common.BoundedGarbageCollector)
+			if !ok {
+				return fmt.Errorf("the %s datastore engine does not support running garbage collection out-of-band", config.Engine)
+			}
+
+			opts := common.DeletionOptions{BatchSize: batchSize, Namespaces: namespaces}
+			if timeBudget > 0 {
+				opts.Deadline = time.Now().Add(timeBudget)
+			}
+
+			start := time.Now()
+			watermark, collected, err := common.CollectOnce(cmd.Context(), gc, config.GCWindow, opts)
+			if watermark == pkgdatastore.NoRevision && err == nil {
+				fmt.Fprintf(cmd.OutOrStdout(), "no data older than the garbage collection window of %s\n", config.GCWindow)
+				return nil
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "deleted %d relationships, %d transactions and %d namespaces in %d batches in %s\n",
+				collected.Relationships, collected.Transactions, collected.Namespaces, collected.Batches, time.Since(start).Round(time.Millisecond))
+			switch {
+			case errors.Is(err, common.ErrGCDeadlineExceeded):
+				fmt.Fprintln(cmd.OutOrStdout(), "the time budget was exhausted before the pass completed; run the command again to continue")
+				return nil
+			case err != nil:
+				return fmt.Errorf("failed to garbage collect datastore: %w", err)
+			default:
+				fmt.Fprintf(cmd.OutOrStdout(), "collected all of the data before revision %s\n", watermark)
+				return nil
+			}
+		},
+		Args: cobra.NoArgs,
+	}
+}
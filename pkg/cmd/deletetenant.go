@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/middleware/tenant"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/schemautil"
+)
+
+func RegisterDeleteTenantFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().String("tenant-registry-path", "", "tenant registry file of the server, from which the tenant is removed if it was created through the tenant management API")
+}
+
+func NewDeleteTenantCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "delete-tenant <tenant>",
+		Short: "deletes the definitions, caveats and relationships of a tenant",
+		Long: "Deletes, in a single transaction, all of the object definitions, along with their relationships, and caveats " +
+			"whose names are prefixed with the name of the tenant. The keys of a tenant created through the tenant management API " +
+			"are revoked first when --tenant-registry-path is given, while the preshared keys of the tenant must be removed from " +
+			"--tenant-by-key separately",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := tenant.ValidateTenantName(args[0]); err != nil {
+				return err
+			}
+
+			registryPath, err := cmd.Flags().GetString("tenant-registry-path")
+			if err != nil {
+				return err
+			}
+			if registryPath != "" {
+				registry, err := tenant.NewRegistry(nil, registryPath)
+				if err != nil {
+					return err
+				}
+				if _, err := registry.Delete(args[0]); err != nil {
+					return fmt.Errorf("failed to revoke the keys of tenant: %w", err)
+				}
+			}
+
+			ds, err := datastore.NewDatastore(cmd.Context(), config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to create datastore: %w", err)
+			}
+			defer ds.Close()
+
+			deletedDefinitions, deletedCaveats, err := schemautil.DeleteTenant(cmd.Context(), ds, args[0])
+			if err != nil {
+				return fmt.Errorf("failed to delete tenant: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "deleted %d definitions and %d caveats of tenant %s\n", deletedDefinitions, deletedCaveats, args[0])
+			return nil
+		},
+		Args: cobra.ExactArgs(1),
+	}
+}
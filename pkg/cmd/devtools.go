@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"os/signal"
 	"time"
@@ -91,7 +92,10 @@ func runfunc(cmd *cobra.Command, _ []string) error {
 	}()
 
 	// start the http download api
-	downloadHTTP := httpDownloadServiceBuilder(cobrahttp.WithHandler(v0svc.DownloadHandler(shareStore)))
+	downloadMux := http.NewServeMux()
+	downloadMux.Handle("/", v0svc.DownloadHandler(shareStore))
+	downloadMux.Handle("/schematest", v0svc.SchemaTestHandler())
+	downloadHTTP := httpDownloadServiceBuilder(cobrahttp.WithHandler(downloadMux))
 	downloadSrv := downloadHTTP.ServerFromFlags(cmd)
 	downloadSrv.ReadHeaderTimeout = 5 * time.Second
 	go func() {
@@ -0,0 +1,205 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	crdbmigrations "github.com/authzed/spicedb/internal/datastore/crdb/migrations"
+	mysqlmigrations "github.com/authzed/spicedb/internal/datastore/mysql/migrations"
+	"github.com/authzed/spicedb/internal/datastore/postgres/migrations"
+	spannermigrations "github.com/authzed/spicedb/internal/datastore/spanner/migrations"
+	"github.com/authzed/spicedb/internal/doctor"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+)
+
+// RegisterDoctorFlags registers the flags of the serve command, so that the doctor command checks
+// the environment of a node with the same flags and environment variables, along with its own.
+func RegisterDoctorFlags(cmd *cobra.Command, config *server.Config) {
+	RegisterServeFlags(cmd, config)
+	cmd.Flags().Duration("doctor-timeout", 30*time.Second, "maximum amount of time each check may take")
+	cmd.Flags().Duration("doctor-max-clock-skew", time.Second, "maximum difference between the clocks of this host and of the datastore")
+	cmd.Flags().Duration("doctor-cert-expiry-warning", 30*24*time.Hour, "period before the expiry of a certificate within which a warning is reported")
+}
+
+func NewDoctorCommand(programName string, config *server.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "checks the environment in which SpiceDB runs",
+		Long: "Checks, with the same flags as the serve command, the connectivity to the datastore, its migration revision and indexes, " +
+			"the clock skew of this host, the health of garbage collection, the reachability of the dispatch cluster and the validity of " +
+			"the TLS certificates, and prints what to do about each problem found. Fails if any check fails",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			timeout, err := cmd.Flags().GetDuration("doctor-timeout")
+			if err != nil {
+				return err
+			}
+			maxClockSkew, err := cmd.Flags().GetDuration("doctor-max-clock-skew")
+			if err != nil {
+				return err
+			}
+			expiryWarning, err := cmd.Flags().GetDuration("doctor-cert-expiry-warning")
+			if err != nil {
+				return err
+			}
+
+			var report doctor.Report
+			report = append(report, checkTLSFindings(config, expiryWarning)...)
+			report = append(report, checkDatastoreFindings(cmd.Context(), &config.DatastoreConfig, timeout, maxClockSkew)...)
+
+			ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+			report = append(report, doctor.CheckDispatchUpstream(ctx, config.DispatchUpstreamAddr, config.DispatchUpstreamCAPath))
+			cancel()
+
+			if err := report.Write(cmd.OutOrStdout()); err != nil {
+				return err
+			}
+			if failures := report.Count(doctor.StatusFailure); failures > 0 {
+				return fmt.Errorf("%d of %d check(s) failed", failures, len(report))
+			}
+			return nil
+		},
+		Args: cobra.NoArgs,
+	}
+}
+
+func checkTLSFindings(config *server.Config, expiryWarning time.Duration) []doctor.Finding {
+	now := time.Now()
+
+	var findings []doctor.Finding
+	for _, srv := range []struct {
+		name string
+		cert string
+		key  string
+	}{
+		{"gRPC", config.GRPCServer.TLSCertPath, config.GRPCServer.TLSKeyPath},
+		{"dispatch", config.DispatchServer.TLSCertPath, config.DispatchServer.TLSKeyPath},
+		{"HTTP gateway", config.HTTPGateway.TLSCertPath, config.HTTPGateway.TLSKeyPath},
+		{"metrics", config.MetricsAPI.TLSCertPath, config.MetricsAPI.TLSKeyPath},
+		{"dashboard", config.DashboardAPI.TLSCertPath, config.DashboardAPI.TLSKeyPath},
+		{"GraphQL", config.GraphQLAPI.TLSCertPath, config.GraphQLAPI.TLSKeyPath},
+	} {
+		if srv.cert != "" {
+			findings = append(findings, doctor.CheckCertificate(srv.name, srv.cert, srv.key, now, expiryWarning))
+		}
+	}
+
+	for _, ca := range []struct {
+		purpose string
+		path    string
+	}{
+		{"the clients of the gRPC server", config.GRPCServer.ClientCAPath},
+		{"the dispatch cluster", config.DispatchUpstreamCAPath},
+	} {
+		if ca.path != "" {
+			findings = append(findings, doctor.CheckCA(ca.purpose, ca.path, now, expiryWarning))
+		}
+	}
+
+	if len(findings) == 0 {
+		findings = append(findings, doctor.Finding{Check: "tls", Status: doctor.StatusSkipped, Message: "no TLS certificate is configured"})
+	}
+	return findings
+}
+
+func checkDatastoreFindings(ctx context.Context, config *datastore.Config, timeout, maxClockSkew time.Duration) []doctor.Finding {
+	// The checks read the datastore alone, without the garbage collection worker of the datastore
+	// or the proxies which would hide its garbage collector.
+	connectCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ds, err := datastore.NewDatastore(connectCtx,
+		config.ToOption(),
+		datastore.WithGCInterval(0),
+		datastore.WithReadOnly(false),
+		datastore.WithRequestHedgingEnabled(false),
+		datastore.SetBootstrapFiles(nil),
+	)
+	if err != nil {
+		return []doctor.Finding{{
+			Check:   "datastore",
+			Status:  doctor.StatusFailure,
+			Message: fmt.Sprintf("unable to connect to the %s datastore: %s", config.Engine, err),
+			Remedy:  "check --datastore-engine, --datastore-conn-uri and that the datastore is reachable from this host",
+		}}
+	}
+	defer ds.Close()
+
+	findings := []doctor.Finding{doctor.CheckDatastore(connectCtx, ds, config.Engine)}
+	findings = append(findings, checkMigrationFindings(ctx, config, timeout)...)
+
+	clockCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	findings = append(findings, doctor.CheckClockSkew(clockCtx, ds, maxClockSkew))
+
+	if config.ReadOnly || config.GCInterval == 0 {
+		findings = append(findings, doctor.Finding{
+			Check:   "garbage collection",
+			Status:  doctor.StatusWarning,
+			Message: "garbage collection is disabled on the nodes running with these flags",
+			Remedy:  "ensure that other nodes run garbage collection, or schedule `spicedb datastore gc`",
+		})
+	} else {
+		gcCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		// A pass may start an interval after the previous one and run for up to the maximum
+		// operation time, so the oldest data may remain for that long beyond the window.
+		tolerance := 2 * (config.GCInterval + config.GCMaxOperationTime)
+		findings = append(findings, doctor.CheckGC(gcCtx, ds, config.GCWindow, tolerance))
+	}
+	return findings
+}
+
+func checkMigrationFindings(ctx context.Context, config *datastore.Config, timeout time.Duration) []doctor.Finding {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	unreachable := func(err error) []doctor.Finding {
+		return []doctor.Finding{{
+			Check:   "migrations",
+			Status:  doctor.StatusFailure,
+			Message: fmt.Sprintf("unable to create migration driver for %s: %s", config.Engine, err),
+			Remedy:  "check --datastore-conn-uri and that the datastore is reachable from this host",
+		}}
+	}
+
+	switch config.Engine {
+	case datastore.PostgresEngine:
+		driver, err := migrations.NewAlembicPostgresDriver(config.URI)
+		if err != nil {
+			return unreachable(err)
+		}
+		defer driver.Close(ctx)
+		return doctor.CheckMigrations(ctx, driver, migrations.DatabaseMigrations)
+
+	case datastore.CockroachEngine:
+		driver, err := crdbmigrations.NewCRDBDriver(config.URI)
+		if err != nil {
+			return unreachable(err)
+		}
+		defer driver.Close(ctx)
+		return doctor.CheckMigrations(ctx, driver, crdbmigrations.CRDBMigrations)
+
+	case datastore.MySQLEngine:
+		driver, err := mysqlmigrations.NewMySQLDriverFromDSN(config.URI, config.TablePrefix)
+		if err != nil {
+			return unreachable(err)
+		}
+		defer driver.Close(ctx)
+		return doctor.CheckMigrations(ctx, driver, mysqlmigrations.Manager)
+
+	case datastore.SpannerEngine:
+		driver, err := spannermigrations.NewSpannerDriver(config.URI, config.SpannerCredentialsFile, config.SpannerEmulatorHost)
+		if err != nil {
+			return unreachable(err)
+		}
+		defer driver.Close(ctx)
+		return doctor.CheckMigrations(ctx, driver, spannermigrations.SpannerMigrations)
+
+	default:
+		return []doctor.Finding{{Check: "migrations", Status: doctor.StatusSkipped, Message: fmt.Sprintf("the %s datastore is not migrated", config.Engine)}}
+	}
+}
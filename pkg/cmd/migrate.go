@@ -3,6 +3,7 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
 	"time"
 
 	"github.com/fatih/color"
@@ -27,13 +28,17 @@ func RegisterMigrateFlags(cmd *cobra.Command) {
 	cmd.Flags().String("datastore-mysql-table-prefix", "", "prefix to add to the name of all mysql database tables")
 	cmd.Flags().Uint64("migration-backfill-batch-size", 1000, "number of items to migrate per iteration of a datastore backfill")
 	cmd.Flags().Duration("migration-timeout", 1*time.Hour, "defines a timeout for the execution of the migration, set to 1 hour by default")
+	cmd.Flags().Bool("plan", false, "print the migrations which would run, with their statements, estimated lock impact and destructive changes, without applying them")
+	cmd.Flags().Bool("dry-run", false, "check the connection to the datastore and the permissions required to migrate it, without applying any migration")
 }
 
 func NewMigrateCommand(programName string) *cobra.Command {
 	return &cobra.Command{
-		Use:     "migrate [revision]",
-		Short:   "execute datastore schema migrations",
-		Long:    fmt.Sprintf("Executes datastore schema migrations for the datastore.\nThe special value \"%s\" can be used to migrate to the latest revision.", color.YellowString(migrate.Head)),
+		Use:   "migrate [revision]",
+		Short: "execute datastore schema migrations",
+		Long: fmt.Sprintf("Executes datastore schema migrations for the datastore.\nThe special value \"%s\" can be used to migrate to the latest revision.\n"+
+			"With --plan, prints the migrations which would run, with their statements, estimated lock impact and destructive changes; "+
+			"with --dry-run, checks the connection and permissions. Neither applies any migration.", color.YellowString(migrate.Head)),
 		PreRunE: server.DefaultPreRunE(programName),
 		RunE:    migrateRun,
 		Args:    cobra.ExactArgs(1),
@@ -45,6 +50,11 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 	dbURL := cobrautil.MustGetStringExpanded(cmd, "datastore-conn-uri")
 	timeout := cobrautil.MustGetDuration(cmd, "migration-timeout")
 	migrationBatachSize := cobrautil.MustGetUint64(cmd, "migration-backfill-batch-size")
+	mode := migrationMode{
+		out:    cmd.OutOrStdout(),
+		plan:   cobrautil.MustGetBool(cmd, "plan"),
+		dryRun: cobrautil.MustGetBool(cmd, "dry-run"),
+	}
 
 	if datastoreEngine == "cockroachdb" {
 		log.Info().Msg("migrating cockroachdb datastore")
@@ -54,7 +64,7 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("unable to create migration driver for %s: %w", datastoreEngine, err)
 		}
-		return runMigration(cmd.Context(), migrationDriver, crdbmigrations.CRDBMigrations, args[0], timeout, migrationBatachSize)
+		return runMigration(cmd.Context(), migrationDriver, crdbmigrations.CRDBMigrations, migrate.CockroachDB, args[0], timeout, migrationBatachSize, mode)
 	} else if datastoreEngine == "postgres" {
 		log.Info().Msg("migrating postgres datastore")
 
@@ -63,7 +73,7 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("unable to create migration driver for %s: %w", datastoreEngine, err)
 		}
-		return runMigration(cmd.Context(), migrationDriver, migrations.DatabaseMigrations, args[0], timeout, migrationBatachSize)
+		return runMigration(cmd.Context(), migrationDriver, migrations.DatabaseMigrations, migrate.Postgres, args[0], timeout, migrationBatachSize, mode)
 	} else if datastoreEngine == "spanner" {
 		log.Info().Msg("migrating spanner datastore")
 
@@ -77,7 +87,7 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("unable to create migration driver for %s: %w", datastoreEngine, err)
 		}
-		return runMigration(cmd.Context(), migrationDriver, spannermigrations.SpannerMigrations, args[0], timeout, migrationBatachSize)
+		return runMigration(cmd.Context(), migrationDriver, spannermigrations.SpannerMigrations, migrate.Spanner, args[0], timeout, migrationBatachSize, mode)
 	} else if datastoreEngine == "mysql" {
 		log.Info().Msg("migrating mysql datastore")
 
@@ -91,24 +101,42 @@ func migrateRun(cmd *cobra.Command, args []string) error {
 		if err != nil {
 			return fmt.Errorf("unable to create migration driver for %s: %w", datastoreEngine, err)
 		}
-		return runMigration(cmd.Context(), migrationDriver, mysqlmigrations.Manager, args[0], timeout, migrationBatachSize)
+		return runMigration(cmd.Context(), migrationDriver, mysqlmigrations.Manager, migrate.MySQL, args[0], timeout, migrationBatachSize, mode)
 	}
 
 	return fmt.Errorf("cannot migrate datastore engine type: %s", datastoreEngine)
 }
 
+// migrationMode determines whether migrations are applied, or only planned or checked.
+type migrationMode struct {
+	out    io.Writer
+	plan   bool
+	dryRun bool
+}
+
 func runMigration[D migrate.Driver[C, T], C any, T any](
 	ctx context.Context,
 	driver D,
 	manager *migrate.Manager[D, C, T],
+	dialect migrate.Dialect,
 	targetRevision string,
 	timeout time.Duration,
 	backfillBatchSize uint64,
+	mode migrationMode,
 ) error {
-	log.Info().Str("targetRevision", targetRevision).Msg("running migrations")
 	ctxWithBatch := context.WithValue(ctx, migrate.BackfillBatchSize, backfillBatchSize)
 	ctx, cancel := context.WithTimeout(ctxWithBatch, timeout)
 	defer cancel()
+
+	if mode.plan || mode.dryRun {
+		err := planMigration(ctx, driver, manager, dialect, targetRevision, mode)
+		if closeErr := driver.Close(ctx); closeErr != nil && err == nil {
+			err = fmt.Errorf("unable to close migration driver: %w", closeErr)
+		}
+		return err
+	}
+
+	log.Info().Str("targetRevision", targetRevision).Msg("running migrations")
 	if err := manager.Run(ctx, driver, targetRevision, migrate.LiveRun); err != nil {
 		return fmt.Errorf("unable to migrate to `%s` revision: %w", targetRevision, err)
 	}
@@ -119,6 +147,77 @@ func runMigration[D migrate.Driver[C, T], C any, T any](
 	return nil
 }
 
+// planMigration prints the migrations which would run to bring the datastore to the target
+// revision, along with their statements if planning, after checking the permissions of the driver
+// if a dry run.
+func planMigration[D migrate.Driver[C, T], C any, T any](
+	ctx context.Context,
+	driver D,
+	manager *migrate.Manager[D, C, T],
+	dialect migrate.Dialect,
+	targetRevision string,
+	mode migrationMode,
+) error {
+	current, plans, err := manager.Plan(ctx, driver, targetRevision, dialect)
+	if err != nil {
+		return fmt.Errorf("unable to plan migration to `%s` revision: %w", targetRevision, err)
+	}
+
+	if mode.dryRun {
+		checker, ok := any(driver).(migrate.PermissionChecker)
+		if !ok {
+			fmt.Fprintf(mode.out, "permissions: not checked for %s\n", dialect)
+		} else if err := checker.CheckPermissions(ctx); err != nil {
+			return fmt.Errorf("missing permissions to migrate: %w", err)
+		} else {
+			fmt.Fprintln(mode.out, "permissions: ok")
+		}
+	}
+
+	if current == migrate.None {
+		current = "(empty datastore)"
+	}
+	fmt.Fprintf(mode.out, "current revision: %s\ntarget revision: %s\n%d migration(s) would run\n", current, targetRevision, len(plans))
+	if !mode.plan {
+		for _, plan := range plans {
+			fmt.Fprintf(mode.out, "  %s\n", plan.Version)
+		}
+		return nil
+	}
+
+	var warnings []string
+	for _, plan := range plans {
+		fmt.Fprintf(mode.out, "\n== %s (from %q)\n", plan.Version, plan.Replaces)
+		if plan.NonAtomic {
+			fmt.Fprintln(mode.out, "-- runs partly outside of a transaction: a failure may leave the migration partially applied")
+		}
+		if !plan.Described {
+			fmt.Fprintln(mode.out, "-- statements not described")
+			warnings = append(warnings, fmt.Sprintf("the statements of %s are not described and were not analyzed", plan.Version))
+			continue
+		}
+
+		for _, stmt := range plan.Statements {
+			fmt.Fprintf(mode.out, "-- lock impact: %s", stmt.Lock)
+			if stmt.Destructive {
+				fmt.Fprint(mode.out, ", DESTRUCTIVE")
+			}
+			fmt.Fprintf(mode.out, "\n%s\n", stmt.Statement)
+		}
+		if plan.Destructive() {
+			warnings = append(warnings, fmt.Sprintf("%s deletes data; take a backup before migrating", plan.Version))
+		}
+	}
+
+	if len(warnings) > 0 {
+		fmt.Fprintln(mode.out)
+		for _, warning := range warnings {
+			fmt.Fprintf(mode.out, "%s %s\n", color.YellowString("WARNING:"), warning)
+		}
+	}
+	return nil
+}
+
 func RegisterHeadFlags(cmd *cobra.Command) {
 	cmd.Flags().String("datastore-engine", "postgres", fmt.Sprintf(`type of datastore to initialize (%s)`, datastore.EngineOptions()))
 }
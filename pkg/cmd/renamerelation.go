@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/schemautil"
+)
+
+const defaultRenameBatchSize = 1000
+
+func RegisterRenameRelationFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+	cmd.Flags().Uint64("batch-size", defaultRenameBatchSize, "number of relationships copied or deleted per transaction")
+}
+
+func NewRenameRelationCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "rename-relation <definition> <relation> <new name>",
+		Short: "renames a relation, migrating its relationships",
+		Long: "Renames a relation by adding the new relation to the schema, copying the existing relationships to it while keeping both in sync, " +
+			"atomically swapping all references to the new relation and finally deleting the relationships of the existing relation. " +
+			"If interrupted, the rename can be resumed by running the same command again",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			batchSize, err := cmd.Flags().GetUint64("batch-size")
+			if err != nil {
+				return err
+			}
+			if batchSize == 0 {
+				return fmt.Errorf("batch size must be greater than zero")
+			}
+
+			ds, err := datastore.NewDatastore(cmd.Context(), config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to create datastore: %w", err)
+			}
+			defer ds.Close()
+
+			return schemautil.RenameRelation(cmd.Context(), ds, args[0], args[1], args[2], batchSize, func(progress shared.RelationRenameProgress) {
+				fmt.Fprintf(cmd.OutOrStdout(), "%s: %d relationships copied, %d relationships deleted\n",
+					progress.Phase, progress.RelationshipsCopied, progress.RelationshipsDeleted)
+			})
+		},
+		Args: cobra.ExactArgs(3),
+	}
+}
@@ -39,6 +39,20 @@ func (cc *CacheConfig) Complete() (cache.Cache, error) {
 		return cache.NoopCache(), nil
 	}
 
+	maxCost, err := cc.maxCost()
+	if err != nil {
+		return nil, err
+	}
+
+	return cache.NewCache(&cache.Config{
+		MaxCost:     maxCost,
+		NumCounters: cc.NumCounters,
+		Metrics:     cc.Metrics,
+	})
+}
+
+// maxCost parses the max cost of the cache, in bytes or as a percent of the available memory.
+func (cc *CacheConfig) maxCost() (int64, error) {
 	var (
 		maxCost uint64
 		err     error
@@ -50,14 +64,9 @@ func (cc *CacheConfig) Complete() (cache.Cache, error) {
 		maxCost, err = humanize.ParseBytes(cc.MaxCost)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("error parsing cache max memory: `%s`: %w", cc.MaxCost, err)
+		return 0, fmt.Errorf("error parsing cache max memory: `%s`: %w", cc.MaxCost, err)
 	}
-
-	return cache.NewCache(&cache.Config{
-		MaxCost:     int64(maxCost),
-		NumCounters: cc.NumCounters,
-		Metrics:     cc.Metrics,
-	})
+	return int64(maxCost), nil
 }
 
 func parsePercent(str string, freeMem uint64) (uint64, error) {
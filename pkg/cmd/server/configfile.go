@@ -0,0 +1,397 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jzelinskie/cobrautil/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileFlag is the flag naming the config file whose settings are the defaults of the flags.
+const ConfigFileFlag = "config"
+
+// The sources from which a flag takes its value, from the lowest precedence to the highest.
+const (
+	FlagSourceDefault = "default"
+	FlagSourceConfig  = "config"
+	FlagSourceEnv     = "env"
+	FlagSourceFlag    = "flag"
+)
+
+const flagSourceAnnotation = "spicedb-flag-source"
+
+// FlagSource returns the source from which the flag took its value.
+func FlagSource(f *pflag.Flag) string {
+	if source := f.Annotations[flagSourceAnnotation]; len(source) > 0 {
+		return source[0]
+	}
+	return FlagSourceDefault
+}
+
+func markFlagSources(flags *pflag.FlagSet, source string) {
+	flags.VisitAll(func(f *pflag.Flag) {
+		if f.Changed && len(f.Annotations[flagSourceAnnotation]) == 0 {
+			_ = flags.SetAnnotation(f.Name, flagSourceAnnotation, []string{source})
+		}
+	})
+}
+
+// syncLayeredFlagsPreRunE returns a Cobra run func setting the flags which were not given on the
+// command line from the environment variables prefixed with the program name and then, if the
+// command has a config file flag which is set, from the settings of the config file, such that
+// flags take precedence over environment variables, which take precedence over the config file.
+func syncLayeredFlagsPreRunE(programName string) cobrautil.CobraRunFunc {
+	syncEnv := cobrautil.SyncViperPreRunE(programName)
+	return func(cmd *cobra.Command, args []string) error {
+		if cobrautil.IsBuiltinCommand(cmd) {
+			return nil
+		}
+
+		flags := cmd.Flags()
+		markFlagSources(flags, FlagSourceFlag)
+		if err := syncEnv(cmd, args); err != nil {
+			return err
+		}
+		markFlagSources(flags, FlagSourceEnv)
+
+		configFile := flags.Lookup(ConfigFileFlag)
+		if configFile == nil || configFile.Value.String() == "" {
+			return nil
+		}
+		return ApplyConfigFile(flags, configFile.Value.String())
+	}
+}
+
+// ApplyConfigFile sets the flags which are not yet set to the settings of the YAML config file at
+// the given path. The keys of the file are the names of the flags, either in full or split at
+// hyphens into nested mappings, such that `datastore-engine: postgres` is equivalent to
+// `datastore: {engine: postgres}`. Lists set the flags taking lists and mappings those taking
+// key=value pairs. The file is rejected, without setting any flag, if it has a key which is not a
+// flag or a value which is invalid for its flag.
+func ApplyConfigFile(flags *pflag.FlagSet, path string) error {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	var settings map[string]any
+	if err := yaml.Unmarshal(contents, &settings); err != nil {
+		return fmt.Errorf("unable to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]any, len(settings))
+	if err := flattenSettings(flags, "", settings, values); err != nil {
+		return fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	// Every setting is checked against a copy of its flag before any flag is set.
+	for name, value := range values {
+		if err := setFlagValue(flags.Lookup(name), value, true); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	}
+	for name, value := range values {
+		f := flags.Lookup(name)
+		if f.Changed {
+			continue
+		}
+		if err := setFlagValue(f, value, false); err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		f.Changed = true
+		_ = flags.SetAnnotation(name, flagSourceAnnotation, []string{FlagSourceConfig})
+	}
+	return nil
+}
+
+func flattenSettings(flags *pflag.FlagSet, prefix string, settings map[string]any, values map[string]any) error {
+	for key, value := range settings {
+		name := key
+		if prefix != "" {
+			name = prefix + "-" + key
+		}
+
+		switch f := flags.Lookup(name); {
+		case name == ConfigFileFlag:
+			return errors.New("a config file cannot name another config file")
+
+		case f != nil:
+			if _, ok := values[name]; ok {
+				return fmt.Errorf("%s is set more than once", name)
+			}
+			values[name] = value
+
+		default:
+			nested, ok := value.(map[string]any)
+			if !ok {
+				return unknownSettingError(flags, name)
+			}
+			if err := flattenSettings(flags, name, nested, values); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func normalizeSettingName(name string) string {
+	return strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(name))
+}
+
+func unknownSettingError(flags *pflag.FlagSet, name string) error {
+	normalized := normalizeSettingName(name)
+	var suggestion string
+	flags.VisitAll(func(f *pflag.Flag) {
+		if normalizeSettingName(f.Name) == normalized {
+			suggestion = f.Name
+		}
+	})
+	if suggestion != "" {
+		return fmt.Errorf("unknown setting %s; did you mean %s?", name, suggestion)
+	}
+	return fmt.Errorf("unknown setting %s", name)
+}
+
+// setFlagValue sets the flag to the decoded YAML value or, if check is true, only checks that the
+// value is valid for the flag.
+func setFlagValue(f *pflag.Flag, value any, check bool) error {
+	target := f.Value
+	if check {
+		target = newFlagValue(f)
+		if target == nil {
+			return nil
+		}
+	}
+
+	switch value := value.(type) {
+	case nil:
+		return fmt.Errorf("%s has no value", f.Name)
+
+	case []any:
+		slice, ok := target.(pflag.SliceValue)
+		if !ok {
+			return fmt.Errorf("%s takes a single value rather than a list", f.Name)
+		}
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			if _, ok := item.(map[string]any); ok {
+				return fmt.Errorf("%s takes a list of single values", f.Name)
+			}
+			items = append(items, fmt.Sprint(item))
+		}
+		if err := slice.Replace(items); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", f.Name, err)
+		}
+		return nil
+
+	case map[string]any:
+		if !strings.HasPrefix(target.Type(), "stringTo") {
+			return fmt.Errorf("%s takes a single value rather than a mapping", f.Name)
+		}
+		keys := make([]string, 0, len(value))
+		for key := range value {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		// Each pair is set on its own, as the values of the first replace the default of the
+		// flag and those of the next are added to them.
+		for _, key := range keys {
+			if err := target.Set(fmt.Sprintf("%s=%v", key, value[key])); err != nil {
+				return fmt.Errorf("invalid value for %s: %w", f.Name, err)
+			}
+		}
+		return nil
+
+	default:
+		if err := target.Set(fmt.Sprint(value)); err != nil {
+			return fmt.Errorf("invalid value for %s: %w", f.Name, err)
+		}
+		return nil
+	}
+}
+
+// newFlagValue returns a new value of the type of the flag, set to its default, or nil if the type
+// is not known.
+func newFlagValue(f *pflag.Flag) pflag.Value {
+	check := pflag.NewFlagSet("check", pflag.ContinueOnError)
+	switch f.Value.Type() {
+	case "string":
+		check.String(f.Name, "", "")
+	case "bool":
+		check.Bool(f.Name, false, "")
+	case "int":
+		check.Int(f.Name, 0, "")
+	case "int32":
+		check.Int32(f.Name, 0, "")
+	case "int64":
+		check.Int64(f.Name, 0, "")
+	case "uint":
+		check.Uint(f.Name, 0, "")
+	case "uint16":
+		check.Uint16(f.Name, 0, "")
+	case "uint32":
+		check.Uint32(f.Name, 0, "")
+	case "uint64":
+		check.Uint64(f.Name, 0, "")
+	case "float32":
+		check.Float32(f.Name, 0, "")
+	case "float64":
+		check.Float64(f.Name, 0, "")
+	case "duration":
+		check.Duration(f.Name, 0, "")
+	case "stringSlice":
+		check.StringSlice(f.Name, nil, "")
+	case "stringArray":
+		check.StringArray(f.Name, nil, "")
+	case "uintSlice":
+		check.UintSlice(f.Name, nil, "")
+	case "stringToString":
+		check.StringToString(f.Name, nil, "")
+	case "stringToInt":
+		check.StringToInt(f.Name, nil, "")
+	default:
+		return nil
+	}
+	return check.Lookup(f.Name).Value
+}
+
+// sensitiveFlag returns whether the value of the flag is or holds a secret.
+func sensitiveFlag(name string) bool {
+	return strings.Contains(name, "preshared-key") ||
+		strings.HasSuffix(name, "-by-key") ||
+		strings.HasSuffix(name, "conn-uri") ||
+		strings.HasSuffix(name, "-headers") ||
+		name == "http-signing-keys"
+}
+
+const redacted = "<redacted>"
+
+// WriteEffectiveConfig writes the values of the flags as a YAML config file, with the source of
+// each value in a comment, such that the file sets the same values when given as the config file.
+// Flags left at their defaults are only written if includeDefaults is set, and the values of flags
+// holding secrets are redacted unless showSecrets is set. Flags whose names are excluded are
+// never written.
+func WriteEffectiveConfig(w io.Writer, flags *pflag.FlagSet, includeDefaults, showSecrets bool, excluded ...string) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	var err error
+	flags.VisitAll(func(f *pflag.Flag) {
+		if err != nil || f.Name == ConfigFileFlag || f.Hidden {
+			return
+		}
+		for _, name := range excluded {
+			if f.Name == name {
+				return
+			}
+		}
+
+		source := FlagSource(f)
+		if source == FlagSourceDefault && !includeDefaults {
+			return
+		}
+
+		var value *yaml.Node
+		value, err = flagValueNode(flags, f, showSecrets)
+		if err != nil {
+			return
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: f.Name, LineComment: source}, value)
+	})
+	if err != nil {
+		return err
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if len(doc.Content) == 0 {
+		doc = &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+	}
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	if err := encoder.Close(); err != nil {
+		return err
+	}
+	_, err = w.Write(out.Bytes())
+	return err
+}
+
+func flagValueNode(flags *pflag.FlagSet, f *pflag.Flag, showSecrets bool) (*yaml.Node, error) {
+	secret := !showSecrets && sensitiveFlag(f.Name)
+	scalar := func(value string) *yaml.Node {
+		if secret {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: redacted}
+		}
+		if f.Value.Type() == "string" {
+			return &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: value}
+		}
+		return &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+	}
+
+	switch f.Value.Type() {
+	case "stringToString", "stringToInt":
+		pairs := map[string]string{}
+		if f.Value.Type() == "stringToString" {
+			values, err := flags.GetStringToString(f.Name)
+			if err != nil {
+				return nil, err
+			}
+			pairs = values
+		} else {
+			values, err := flags.GetStringToInt(f.Name)
+			if err != nil {
+				return nil, err
+			}
+			for key, value := range values {
+				pairs[key] = fmt.Sprint(value)
+			}
+		}
+
+		keys := make([]string, 0, len(pairs))
+		for key := range pairs {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		node := &yaml.Node{Kind: yaml.MappingNode}
+		if len(keys) == 0 {
+			node.Style = yaml.FlowStyle
+		}
+		for i, key := range keys {
+			keyNode := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}
+			if secret {
+				// The keys of the mappings of sensitive flags may be secrets themselves.
+				keyNode.Value = fmt.Sprintf("%s%d", redacted, i)
+			}
+			node.Content = append(node.Content, keyNode, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: pairs[key]})
+			if secret {
+				node.Content[len(node.Content)-1].Value = redacted
+			}
+		}
+		return node, nil
+
+	default:
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			node := &yaml.Node{Kind: yaml.SequenceNode}
+			items := slice.GetSlice()
+			if len(items) == 0 {
+				node.Style = yaml.FlowStyle
+			}
+			for _, item := range items {
+				itemNode := scalar(item)
+				itemNode.Tag = "!!str"
+				node.Content = append(node.Content, itemNode)
+			}
+			return node, nil
+		}
+		return scalar(f.Value.String()), nil
+	}
+}
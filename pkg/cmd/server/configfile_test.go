@@ -0,0 +1,164 @@
+package server
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/require"
+)
+
+type layeredTestConfig struct {
+	engine       string
+	gcWindow     time.Duration
+	limit        uint16
+	enabled      bool
+	keys         []string
+	tenantsByKey map[string]string
+}
+
+func newLayeredTestCommand(config *layeredTestConfig) *cobra.Command {
+	cmd := &cobra.Command{Use: "test"}
+	cmd.Flags().String(ConfigFileFlag, "", "")
+	cmd.Flags().StringVar(&config.engine, "datastore-engine", "memory", "")
+	cmd.Flags().DurationVar(&config.gcWindow, "datastore-gc-window", time.Hour, "")
+	cmd.Flags().Uint16Var(&config.limit, "dispatch-concurrency-limit", 50, "")
+	cmd.Flags().BoolVar(&config.enabled, "dispatch-cluster-enabled", false, "")
+	cmd.Flags().StringSliceVar(&config.keys, "grpc-preshared-key", nil, "")
+	cmd.Flags().StringToStringVar(&config.tenantsByKey, "tenant-by-key", nil, "")
+	return cmd
+}
+
+func writeConfigFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	return path
+}
+
+func runLayered(t *testing.T, args ...string) (*cobra.Command, *layeredTestConfig, error) {
+	t.Helper()
+	config := &layeredTestConfig{}
+	cmd := newLayeredTestCommand(config)
+	require.NoError(t, cmd.ParseFlags(args))
+	return cmd, config, syncLayeredFlagsPreRunE("spicedbtest")(cmd, nil)
+}
+
+func TestConfigFileLayering(t *testing.T) {
+	path := writeConfigFile(t, `
+datastore:
+  engine: postgres
+  gc-window: 2h
+dispatch-concurrency-limit: 10
+dispatch-cluster-enabled: true
+grpc-preshared-key: [first, second]
+tenant-by-key:
+  first: acme
+`)
+	t.Setenv("SPICEDBTEST_DATASTORE_GC_WINDOW", "3h")
+
+	cmd, config, err := runLayered(t, "--config", path, "--dispatch-concurrency-limit", "20")
+	require.NoError(t, err)
+
+	require.Equal(t, "postgres", config.engine)
+	require.Equal(t, 3*time.Hour, config.gcWindow)
+	require.Equal(t, uint16(20), config.limit)
+	require.True(t, config.enabled)
+	require.Equal(t, []string{"first", "second"}, config.keys)
+	require.Equal(t, map[string]string{"first": "acme"}, config.tenantsByKey)
+
+	for name, source := range map[string]string{
+		ConfigFileFlag:               FlagSourceFlag,
+		"datastore-engine":           FlagSourceConfig,
+		"datastore-gc-window":        FlagSourceEnv,
+		"dispatch-concurrency-limit": FlagSourceFlag,
+		"tenant-by-key":              FlagSourceConfig,
+	} {
+		require.Equal(t, source, FlagSource(cmd.Flags().Lookup(name)), name)
+	}
+}
+
+func TestConfigFileFromEnv(t *testing.T) {
+	t.Setenv("SPICEDBTEST_CONFIG", writeConfigFile(t, "datastore-engine: mysql\n"))
+
+	cmd, config, err := runLayered(t)
+	require.NoError(t, err)
+	require.Equal(t, "mysql", config.engine)
+	require.Equal(t, FlagSourceEnv, FlagSource(cmd.Flags().Lookup(ConfigFileFlag)))
+}
+
+func TestConfigFileInvalid(t *testing.T) {
+	testCases := []struct {
+		name          string
+		contents      string
+		expectedError string
+	}{
+		{"unknown setting", "datastore-engin: postgres\n", "unknown setting datastore-engin"},
+		{"unknown nested setting", "datastore:\n  engin: postgres\n", "unknown setting datastore-engin"},
+		{"misspelled setting", "datastoreEngine: postgres\n", "did you mean datastore-engine?"},
+		{"set twice", "datastore-engine: postgres\ndatastore:\n  engine: mysql\n", "datastore-engine is set more than once"},
+		{"invalid value", "dispatch-concurrency-limit: many\n", "invalid value for dispatch-concurrency-limit"},
+		{"list for single value", "datastore-engine: [postgres]\n", "datastore-engine takes a single value rather than a list"},
+		{"mapping for single value", "datastore-gc-window: {hours: 2}\n", "datastore-gc-window takes a single value rather than a mapping"},
+		{"no value", "datastore-engine:\n", "datastore-engine has no value"},
+		{"nested config file", "config: other.yaml\n", "cannot name another config file"},
+		{"not yaml", "datastore-engine: [\n", "unable to parse config file"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			// The valid settings of a rejected file are not applied either.
+			_, config, err := runLayered(t, "--config", writeConfigFile(t, "dispatch-cluster-enabled: true\n"+tc.contents))
+			require.ErrorContains(t, err, tc.expectedError)
+			require.False(t, config.enabled)
+		})
+	}
+
+	_, _, err := runLayered(t, "--config", filepath.Join(t.TempDir(), "missing.yaml"))
+	require.ErrorContains(t, err, "unable to read config file")
+}
+
+func TestWriteEffectiveConfig(t *testing.T) {
+	path := writeConfigFile(t, `
+datastore-engine: postgres
+grpc-preshared-key: [first]
+tenant-by-key: {first: acme}
+`)
+	cmd, _, err := runLayered(t, "--config", path, "--dispatch-concurrency-limit", "20")
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, WriteEffectiveConfig(&out, cmd.Flags(), false, false))
+	require.Equal(t, `datastore-engine: postgres # config
+dispatch-concurrency-limit: 20 # flag
+grpc-preshared-key: # config
+  - <redacted>
+tenant-by-key: # config
+  <redacted>0: <redacted>
+`, out.String())
+
+	out.Reset()
+	require.NoError(t, WriteEffectiveConfig(&out, cmd.Flags(), true, true, "dispatch-cluster-enabled"))
+	require.Equal(t, `datastore-engine: postgres # config
+datastore-gc-window: 1h0m0s # default
+dispatch-concurrency-limit: 20 # flag
+grpc-preshared-key: # config
+  - first
+tenant-by-key: # config
+  first: acme
+`, out.String())
+
+	// The printed configuration sets the same values when given as the config file.
+	effective := writeConfigFile(t, out.String())
+	_, config, err := runLayered(t, "--config", effective)
+	require.NoError(t, err)
+	require.Equal(t, "postgres", config.engine)
+	require.Equal(t, time.Hour, config.gcWindow)
+	require.Equal(t, uint16(20), config.limit)
+	require.Equal(t, []string{"first"}, config.keys)
+	require.Equal(t, map[string]string{"first": "acme"}, config.tenantsByKey)
+}
@@ -17,17 +17,30 @@ import (
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
+	"github.com/spf13/cobra"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/logging"
+	accountingmw "github.com/authzed/spicedb/internal/middleware/accounting"
+	admissionmw "github.com/authzed/spicedb/internal/middleware/admission"
 	consistencymw "github.com/authzed/spicedb/internal/middleware/consistency"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	dispatchmw "github.com/authzed/spicedb/internal/middleware/dispatcher"
+	"github.com/authzed/spicedb/internal/middleware/handlingtime"
+	inflightmw "github.com/authzed/spicedb/internal/middleware/inflight"
+	loadshedmw "github.com/authzed/spicedb/internal/middleware/loadshed"
+	quotamw "github.com/authzed/spicedb/internal/middleware/quota"
+	ratelimitmw "github.com/authzed/spicedb/internal/middleware/ratelimit"
+	scopemw "github.com/authzed/spicedb/internal/middleware/scope"
 	"github.com/authzed/spicedb/internal/middleware/serverversion"
 	"github.com/authzed/spicedb/internal/middleware/servicespecific"
+	tenantmw "github.com/authzed/spicedb/internal/middleware/tenant"
+	"github.com/authzed/spicedb/internal/redaction"
 	"github.com/authzed/spicedb/pkg/datastore"
 	logmw "github.com/authzed/spicedb/pkg/middleware/logging"
 	"github.com/authzed/spicedb/pkg/middleware/requestid"
@@ -52,35 +65,102 @@ func ServeExample(programName string) string {
 	)
 }
 
-// DefaultPreRunE sets up viper, zerolog, and OpenTelemetry flag handling for a
-// command.
+// DefaultPreRunE sets up config file, viper, zerolog, and OpenTelemetry flag handling
+// for a command.
 func DefaultPreRunE(programName string) cobrautil.CobraRunFunc {
-	return cobrautil.CommandStack(
-		cobrautil.SyncViperPreRunE(programName),
-		cobrazerolog.New(
-			cobrazerolog.WithTarget(func(logger zerolog.Logger) {
-				logging.SetGlobalLogger(logger)
-			}),
-		).RunE(),
+	syncFlags := syncLayeredFlagsPreRunE(programName)
+	configureLogging := cobrazerolog.New(
+		cobrazerolog.WithTarget(func(logger zerolog.Logger) {
+			// The level is applied globally rather than to the logger, so that it applies to
+			// every copy of the logger when it is changed by the runtime config.
+			zerolog.SetGlobalLevel(logger.GetLevel())
+			logging.SetGlobalLogger(logger.Level(zerolog.TraceLevel))
+		}),
+	).RunE()
+	rest := cobrautil.CommandStack(
 		cobraotel.New("spicedb",
 			cobraotel.WithLogger(zerologr.New(&logging.Logger)),
 		).RunE(),
 		releases.CheckAndLogRunE(),
 	)
+
+	return func(cmd *cobra.Command, args []string) error {
+		// An invalid config file is only reported once logging is configured, so that the error
+		// is logged.
+		syncErr := syncFlags(cmd, args)
+		if err := configureLogging(cmd, args); err != nil {
+			return err
+		}
+		if syncErr != nil {
+			return syncErr
+		}
+		return rest(cmd, args)
+	}
+}
+
+// MetricsHandlerOptions holds the optional handlers of the metrics server, each of which is only
+// served when set. The debug handlers, along with the history of the datastore GC runs, are only
+// served when EnableDebugEndpoints is set.
+type MetricsHandlerOptions struct {
+	TelemetryRegistry    *prometheus.Registry
+	EnableDebugEndpoints bool
+	Revisions            http.Handler
+	Quota                http.Handler
+	Usage                http.Handler
+	HotKeys              http.Handler
+	Reload               http.Handler
+	ZPages               http.Handler
+	Health               http.Handler
 }
 
 // MetricsHandler sets up an HTTP server that handles serving Prometheus
 // metrics and pprof endpoints.
 func MetricsHandler(telemetryRegistry *prometheus.Registry) http.Handler {
+	return MetricsHandlerWithOptions(MetricsHandlerOptions{TelemetryRegistry: telemetryRegistry})
+}
+
+// MetricsHandlerWithOptions sets up an HTTP server that handles serving Prometheus
+// metrics, pprof endpoints and the optional handlers of the given options.
+func MetricsHandlerWithOptions(opts MetricsHandlerOptions) http.Handler {
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
+	// Metrics are served in the OpenMetrics format when requested, which is the only format
+	// exposing the trace exemplars of the handling time histogram.
+	mux.Handle("/metrics", promhttp.InstrumentMetricHandler(
+		prometheus.DefaultRegisterer,
+		promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	))
 	mux.HandleFunc("/debug/pprof/", pprof.Index)
 	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
 	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
 	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
 	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
-	if telemetryRegistry != nil {
-		mux.Handle("/telemetry", promhttp.HandlerFor(telemetryRegistry, promhttp.HandlerOpts{}))
+	if opts.TelemetryRegistry != nil {
+		mux.Handle("/telemetry", promhttp.HandlerFor(opts.TelemetryRegistry, promhttp.HandlerOpts{}))
+	}
+	if opts.EnableDebugEndpoints {
+		mux.Handle("/debug/gc", common.NewGCHistoryHandler())
+		if opts.Revisions != nil {
+			mux.Handle("/debug/revisions", opts.Revisions)
+		}
+		if opts.Quota != nil {
+			mux.Handle("/debug/quota", opts.Quota)
+		}
+		if opts.Usage != nil {
+			mux.Handle("/debug/usage", opts.Usage)
+		}
+		if opts.HotKeys != nil {
+			mux.Handle("/debug/hotkeys", opts.HotKeys)
+		}
+		if opts.Reload != nil {
+			mux.Handle("/debug/reload", opts.Reload)
+		}
+	}
+	if opts.ZPages != nil {
+		mux.Handle("/debug/zpages", opts.ZPages)
+		mux.Handle("/debug/zpages/hashring", opts.ZPages)
+	}
+	if opts.Health != nil {
+		mux.Handle("/health", opts.Health)
 	}
 	return mux
 }
@@ -100,51 +180,116 @@ var defaultGRPCLogOptions = []grpclog.Option{
 	}),
 }
 
+// grpcLogger returns the logger of the gRPC logging middleware, which redacts the IDs in the
+// errors of the logged calls when redaction is enabled.
+func grpcLogger(logger zerolog.Logger) grpclog.Logger {
+	return redaction.GRPCLogger(grpczerolog.InterceptorLogger(logger))
+}
+
+// otelgrpcOptions returns the options of the gRPC tracing middleware, whose spans redact the IDs
+// in the errors of the traced calls when redaction is enabled.
+func otelgrpcOptions() []otelgrpc.Option {
+	return []otelgrpc.Option{otelgrpc.WithTracerProvider(redaction.TracerProvider(otel.GetTracerProvider()))}
+}
+
+// MiddlewareOptions holds the dependencies of the default middleware of the API server. The
+// middleware of each unset dependency passes calls through.
+type MiddlewareOptions struct {
+	Logger                zerolog.Logger
+	AuthFunc              grpcauth.AuthFunc
+	EnableVersionResponse bool
+	Dispatcher            dispatch.Dispatcher
+	Datastore             datastore.Datastore
+	Tenants               *tenantmw.Registry
+	Shedder               *loadshedmw.Shedder
+	Admission             *admissionmw.Controller
+	Scopes                scopemw.Scopes
+	RateLimiter           *ratelimitmw.Limiter
+	QuotaTracker          *quotamw.Tracker
+	Accountant            *accountingmw.Accountant
+	InFlight              *inflightmw.Tracker
+	ConsistencyOptions    []consistencymw.Option
+}
+
 func DefaultMiddleware(logger zerolog.Logger, authFunc grpcauth.AuthFunc, enableVersionResponse bool, dispatcher dispatch.Dispatcher, ds datastore.Datastore) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	return DefaultMiddlewareWithOptions(MiddlewareOptions{
+		Logger:                logger,
+		AuthFunc:              authFunc,
+		EnableVersionResponse: enableVersionResponse,
+		Dispatcher:            dispatcher,
+		Datastore:             ds,
+	})
+}
+
+// DefaultMiddlewareWithOptions returns the default middleware of the API server with the
+// dependencies of the given options.
+func DefaultMiddlewareWithOptions(opts MiddlewareOptions) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
 	return []grpc.UnaryServerInterceptor{
 			requestid.UnaryServerInterceptor(requestid.GenerateIfMissing(true)),
 			logmw.UnaryServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
-			grpclog.UnaryServerInterceptor(grpczerolog.InterceptorLogger(logger), defaultGRPCLogOptions...),
-			otelgrpc.UnaryServerInterceptor(),
-			grpcauth.UnaryServerInterceptor(authFunc),
+			grpclog.UnaryServerInterceptor(grpcLogger(opts.Logger), defaultGRPCLogOptions...),
+			otelgrpc.UnaryServerInterceptor(otelgrpcOptions()...),
+			grpcauth.UnaryServerInterceptor(opts.AuthFunc),
 			grpcprom.UnaryServerInterceptor,
-			dispatchmw.UnaryServerInterceptor(dispatcher),
-			datastoremw.UnaryServerInterceptor(ds),
-			consistencymw.UnaryServerInterceptor(),
+			handlingtime.UnaryServerInterceptor(),
+			inflightmw.UnaryServerInterceptor(opts.InFlight),
+			loadshedmw.UnaryServerInterceptor(opts.Shedder),
+			scopemw.UnaryServerInterceptor(opts.Scopes),
+			ratelimitmw.UnaryServerInterceptor(opts.RateLimiter),
+			admissionmw.UnaryServerInterceptor(opts.Admission),
+			dispatchmw.UnaryServerInterceptor(opts.Dispatcher),
+			datastoremw.UnaryServerInterceptor(opts.Datastore),
+			tenantmw.UnaryServerInterceptor(opts.Tenants),
+			quotamw.UnaryServerInterceptor(opts.QuotaTracker),
+			accountingmw.UnaryServerInterceptor(opts.Accountant),
+			consistencymw.UnaryServerInterceptor(opts.ConsistencyOptions...),
 			servicespecific.UnaryServerInterceptor,
-			serverversion.UnaryServerInterceptor(enableVersionResponse),
+			serverversion.UnaryServerInterceptor(opts.EnableVersionResponse),
 		}, []grpc.StreamServerInterceptor{
 			requestid.StreamServerInterceptor(requestid.GenerateIfMissing(true)),
 			logmw.StreamServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
-			grpclog.StreamServerInterceptor(grpczerolog.InterceptorLogger(logger), defaultGRPCLogOptions...),
-			otelgrpc.StreamServerInterceptor(),
-			grpcauth.StreamServerInterceptor(authFunc),
+			grpclog.StreamServerInterceptor(grpcLogger(opts.Logger), defaultGRPCLogOptions...),
+			otelgrpc.StreamServerInterceptor(otelgrpcOptions()...),
+			grpcauth.StreamServerInterceptor(opts.AuthFunc),
 			grpcprom.StreamServerInterceptor,
-			dispatchmw.StreamServerInterceptor(dispatcher),
-			datastoremw.StreamServerInterceptor(ds),
-			consistencymw.StreamServerInterceptor(),
+			handlingtime.StreamServerInterceptor(),
+			inflightmw.StreamServerInterceptor(opts.InFlight),
+			loadshedmw.StreamServerInterceptor(opts.Shedder),
+			scopemw.StreamServerInterceptor(opts.Scopes),
+			ratelimitmw.StreamServerInterceptor(opts.RateLimiter),
+			admissionmw.StreamServerInterceptor(opts.Admission),
+			dispatchmw.StreamServerInterceptor(opts.Dispatcher),
+			datastoremw.StreamServerInterceptor(opts.Datastore),
+			tenantmw.StreamServerInterceptor(opts.Tenants),
+			quotamw.StreamServerInterceptor(opts.QuotaTracker),
+			accountingmw.StreamServerInterceptor(opts.Accountant),
+			consistencymw.StreamServerInterceptor(opts.ConsistencyOptions...),
 			servicespecific.StreamServerInterceptor,
-			serverversion.StreamServerInterceptor(enableVersionResponse),
+			serverversion.StreamServerInterceptor(opts.EnableVersionResponse),
 		}
 }
 
-func DefaultDispatchMiddleware(logger zerolog.Logger, authFunc grpcauth.AuthFunc, ds datastore.Datastore) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+func DefaultDispatchMiddleware(logger zerolog.Logger, authFunc grpcauth.AuthFunc, ds datastore.Datastore, shedder *loadshedmw.Shedder) ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
 	return []grpc.UnaryServerInterceptor{
 			requestid.UnaryServerInterceptor(requestid.GenerateIfMissing(true)),
 			logmw.UnaryServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
-			grpclog.UnaryServerInterceptor(grpczerolog.InterceptorLogger(logger), defaultGRPCLogOptions...),
-			otelgrpc.UnaryServerInterceptor(),
+			grpclog.UnaryServerInterceptor(grpcLogger(logger), defaultGRPCLogOptions...),
+			otelgrpc.UnaryServerInterceptor(otelgrpcOptions()...),
 			grpcauth.UnaryServerInterceptor(authFunc),
 			grpcprom.UnaryServerInterceptor,
+			handlingtime.UnaryServerInterceptor(),
+			loadshedmw.UnaryServerInterceptor(shedder),
 			datastoremw.UnaryServerInterceptor(ds),
 			servicespecific.UnaryServerInterceptor,
 		}, []grpc.StreamServerInterceptor{
 			requestid.StreamServerInterceptor(requestid.GenerateIfMissing(true)),
 			logmw.StreamServerInterceptor(logmw.ExtractMetadataField("x-request-id", "requestID")),
-			grpclog.StreamServerInterceptor(grpczerolog.InterceptorLogger(logger), defaultGRPCLogOptions...),
-			otelgrpc.StreamServerInterceptor(),
+			grpclog.StreamServerInterceptor(grpcLogger(logger), defaultGRPCLogOptions...),
+			otelgrpc.StreamServerInterceptor(otelgrpcOptions()...),
 			grpcauth.StreamServerInterceptor(authFunc),
 			grpcprom.StreamServerInterceptor,
+			handlingtime.StreamServerInterceptor(),
+			loadshedmw.StreamServerInterceptor(shedder),
 			datastoremw.StreamServerInterceptor(ds),
 			servicespecific.StreamServerInterceptor,
 		}
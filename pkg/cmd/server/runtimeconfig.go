@@ -0,0 +1,220 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog"
+	"gopkg.in/yaml.v3"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/dispatch"
+	log "github.com/authzed/spicedb/internal/logging"
+	ratelimitmw "github.com/authzed/spicedb/internal/middleware/ratelimit"
+	"github.com/authzed/spicedb/pkg/cache"
+)
+
+// RuntimeConfig is the part of the configuration which can be changed while the server runs. It
+// is read from the runtime config file, whose settings override the flags, when the server starts
+// and whenever the file is reloaded. Settings missing from the file take the values of the flags.
+type RuntimeConfig struct {
+	LogLevel                    string            `yaml:"logLevel"`
+	RateLimit                   *string           `yaml:"rateLimit"`
+	RateLimitByKey              map[string]string `yaml:"rateLimitByKey"`
+	RateLimitByMethod           map[string]string `yaml:"rateLimitByMethod"`
+	RateLimitByClientIP         *string           `yaml:"rateLimitByClientIP"`
+	NamespaceCacheMaxCost       string            `yaml:"namespaceCacheMaxCost"`
+	DispatchCacheMaxCost        string            `yaml:"dispatchCacheMaxCost"`
+	ClusterDispatchCacheMaxCost string            `yaml:"clusterDispatchCacheMaxCost"`
+	DatastoreGCInterval         time.Duration     `yaml:"datastoreGCInterval"`
+	DispatchConcurrencyLimit    uint16            `yaml:"dispatchConcurrencyLimit"`
+}
+
+// LoadRuntimeConfig reads the runtime config file at the given path.
+func LoadRuntimeConfig(path string) (RuntimeConfig, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return RuntimeConfig{}, fmt.Errorf("unable to read runtime config: %w", err)
+	}
+
+	var rc RuntimeConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(contents))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&rc); err != nil && !errors.Is(err, io.EOF) {
+		return RuntimeConfig{}, fmt.Errorf("unable to parse runtime config: %w", err)
+	}
+	return rc, nil
+}
+
+// override returns a copy of the config with the settings of the runtime config overriding its
+// own.
+func (rc RuntimeConfig) override(c Config) Config {
+	if rc.RateLimit != nil {
+		c.RateLimit = *rc.RateLimit
+	}
+	if rc.RateLimitByKey != nil {
+		c.RateLimitByKey = rc.RateLimitByKey
+	}
+	if rc.RateLimitByMethod != nil {
+		c.RateLimitByMethod = rc.RateLimitByMethod
+	}
+	if rc.RateLimitByClientIP != nil {
+		c.RateLimitByClientIP = *rc.RateLimitByClientIP
+	}
+	if rc.NamespaceCacheMaxCost != "" {
+		c.NamespaceCacheConfig.MaxCost = rc.NamespaceCacheMaxCost
+	}
+	if rc.DispatchCacheMaxCost != "" {
+		c.DispatchCacheConfig.MaxCost = rc.DispatchCacheMaxCost
+	}
+	if rc.ClusterDispatchCacheMaxCost != "" {
+		c.ClusterDispatchCacheConfig.MaxCost = rc.ClusterDispatchCacheMaxCost
+	}
+	if rc.DispatchConcurrencyLimit != 0 {
+		c.DispatchConcurrencyLimit = rc.DispatchConcurrencyLimit
+	}
+	return c
+}
+
+// runtimeReloader applies the runtime config file to the components of a running server. The
+// components which are nil, such as the rate limiter when the middleware is replaced, are left
+// unchanged. Caches disabled when the server starts stay disabled.
+type runtimeReloader struct {
+	path       string
+	config     Config
+	startLevel zerolog.Level
+
+	limiter              *ratelimitmw.Limiter
+	namespaceCache       cache.Cache
+	dispatchCache        cache.Cache
+	clusterDispatchCache cache.Cache
+	dispatcher           dispatch.Dispatcher
+	gcIntervalOverride   *common.GCIntervalOverride
+
+	mu sync.Mutex
+
+	// gcInterval is the interval of the garbage collection set by the runtime config, if any.
+	gcInterval time.Duration
+}
+
+// Reload reads the runtime config file and applies it. If the file is invalid, nothing is applied.
+func (r *runtimeReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	rc, err := LoadRuntimeConfig(r.path)
+	if err != nil {
+		return err
+	}
+	c := rc.override(r.config)
+
+	level := r.startLevel
+	if rc.LogLevel != "" {
+		level, err = zerolog.ParseLevel(rc.LogLevel)
+		if err != nil {
+			return fmt.Errorf("invalid log level in runtime config: %w", err)
+		}
+	}
+
+	var rateLimitOpts []ratelimitmw.Option
+	if r.limiter != nil {
+		rateLimitOpts, err = c.rateLimitOptions()
+		if err != nil {
+			return err
+		}
+	}
+
+	type resize struct {
+		cache   cache.Cache
+		maxCost int64
+	}
+	var resizes []resize
+	for _, cc := range []struct {
+		cache  cache.Cache
+		config CacheConfig
+	}{
+		{r.namespaceCache, c.NamespaceCacheConfig},
+		{r.dispatchCache, c.DispatchCacheConfig},
+		{r.clusterDispatchCache, c.ClusterDispatchCacheConfig},
+	} {
+		if cc.cache == nil {
+			continue
+		}
+		maxCost, err := cc.config.maxCost()
+		if err != nil {
+			return err
+		}
+		resizes = append(resizes, resize{cc.cache, maxCost})
+	}
+
+	if rc.DatastoreGCInterval < 0 {
+		return errors.New("the datastore garbage collection interval must be positive")
+	}
+
+	zerolog.SetGlobalLevel(level)
+	if r.limiter != nil {
+		r.limiter.Reconfigure(rateLimitOpts...)
+	}
+	for _, resize := range resizes {
+		resize.cache.SetMaxCost(resize.maxCost)
+	}
+	if r.gcIntervalOverride != nil && rc.DatastoreGCInterval != r.gcInterval {
+		// A zero interval restores the interval of the flags.
+		r.gcIntervalOverride.Set(rc.DatastoreGCInterval)
+		r.gcInterval = rc.DatastoreGCInterval
+	}
+	if limited, ok := r.dispatcher.(dispatch.ConcurrencyLimited); ok && c.DispatchConcurrencyLimit > 0 {
+		limited.SetConcurrencyLimit(c.DispatchConcurrencyLimit)
+	}
+
+	log.Info().
+		Str("path", r.path).
+		Stringer("logLevel", level).
+		Uint16("dispatchConcurrencyLimit", c.DispatchConcurrencyLimit).
+		Msg("applied runtime config")
+	return nil
+}
+
+// Start reloads the runtime config file whenever the process receives SIGHUP, until the context
+// is canceled.
+func (r *runtimeReloader) Start(ctx context.Context) error {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	defer signal.Stop(signals)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-signals:
+			if err := r.Reload(); err != nil {
+				log.Ctx(ctx).Error().Err(err).Str("path", r.path).Msg("failed to reload runtime config")
+			}
+		}
+	}
+}
+
+// ServeHTTP reloads the runtime config file on POST requests. The runtime config is not served,
+// as it can hold preshared keys.
+func (r *runtimeReloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "the runtime config is reloaded with a POST request", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.Reload(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
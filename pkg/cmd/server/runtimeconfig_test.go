@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/dispatch"
+	ratelimitmw "github.com/authzed/spicedb/internal/middleware/ratelimit"
+	"github.com/authzed/spicedb/pkg/cache"
+	datastorecfg "github.com/authzed/spicedb/pkg/cmd/datastore"
+)
+
+type testCache struct {
+	cache.Cache
+	maxCost int64
+}
+
+func (c *testCache) SetMaxCost(maxCost int64) { c.maxCost = maxCost }
+
+type testDispatcher struct {
+	dispatch.Dispatcher
+	concurrencyLimit uint16
+}
+
+func (d *testDispatcher) SetConcurrencyLimit(limit uint16) { d.concurrencyLimit = limit }
+
+func TestRuntimeConfigReload(t *testing.T) {
+	startLevel := zerolog.GlobalLevel()
+	t.Cleanup(func() { zerolog.SetGlobalLevel(startLevel) })
+
+	path := filepath.Join(t.TempDir(), "runtime.yaml")
+	writeConfig := func(contents string) {
+		require.NoError(t, os.WriteFile(path, []byte(contents), 0o600))
+	}
+
+	method := "/authzed.api.v1.PermissionsService/CheckPermission"
+	now := time.Now()
+	limiter := ratelimitmw.NewLimiter()
+	namespaceCache := &testCache{Cache: cache.NoopCache()}
+	dispatcher := &testDispatcher{}
+	reloader := &runtimeReloader{
+		path: path,
+		config: Config{
+			PresharedKey:             []string{"somekey"},
+			NamespaceCacheConfig:     CacheConfig{MaxCost: "16MiB"},
+			DatastoreConfig:          datastorecfg.Config{GCInterval: 3 * time.Minute},
+			DispatchConcurrencyLimit: 50,
+		},
+		startLevel:         zerolog.InfoLevel,
+		limiter:            limiter,
+		namespaceCache:     namespaceCache,
+		dispatcher:         dispatcher,
+		gcIntervalOverride: common.NewGCIntervalOverride(),
+	}
+
+	writeConfig(`
+logLevel: debug
+rateLimit: "10:20"
+rateLimitByKey:
+  somekey: "1:1"
+namespaceCacheMaxCost: 1MiB
+datastoreGCInterval: 10m
+dispatchConcurrencyLimit: 10
+`)
+	require.NoError(t, reloader.Reload())
+	require.Equal(t, zerolog.DebugLevel, zerolog.GlobalLevel())
+	require.Equal(t, uint32(1), limiter.Take("somekey", method, now).Limit)
+	require.Equal(t, uint32(20), limiter.Take("otherkey", method, now).Limit)
+	require.Equal(t, int64(1<<20), namespaceCache.maxCost)
+	require.Equal(t, 10*time.Minute, reloader.gcInterval)
+	require.Equal(t, uint16(10), dispatcher.concurrencyLimit)
+
+	// An invalid runtime config is not applied.
+	writeConfig(`
+logLevel: warn
+rateLimit: fast
+`)
+	require.ErrorContains(t, reloader.Reload(), "invalid rate limit")
+	require.Equal(t, zerolog.DebugLevel, zerolog.GlobalLevel())
+
+	writeConfig(`
+dispatchCacheSize: 1MiB
+`)
+	require.ErrorContains(t, reloader.Reload(), "not found")
+
+	// The settings missing from the runtime config take the values of the flags.
+	writeConfig("")
+	require.NoError(t, reloader.Reload())
+	require.Equal(t, zerolog.InfoLevel, zerolog.GlobalLevel())
+	require.Nil(t, limiter.Take("somekey", method, now))
+	require.Equal(t, int64(16<<20), namespaceCache.maxCost)
+	require.Equal(t, time.Duration(0), reloader.gcInterval)
+	require.Equal(t, uint16(50), dispatcher.concurrencyLimit)
+
+	writeConfig(`
+rateLimitByKey:
+  unknownkey: "1:1"
+`)
+	require.ErrorContains(t, reloader.Reload(), "not a preshared key")
+}
+
+func TestRuntimeConfigReloadHandler(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "runtime.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("rateLimit: \"10\"\n"), 0o600))
+
+	limiter := ratelimitmw.NewLimiter()
+	reloader := &runtimeReloader{path: path, startLevel: zerolog.GlobalLevel(), limiter: limiter}
+
+	recorder := httptest.NewRecorder()
+	reloader.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/reload", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, recorder.Code)
+	require.Nil(t, limiter.Take("somekey", "/authzed.api.v1.PermissionsService/CheckPermission", time.Now()))
+
+	recorder = httptest.NewRecorder()
+	reloader.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/reload", nil))
+	require.Equal(t, http.StatusNoContent, recorder.Code)
+	require.NotNil(t, limiter.Take("somekey", "/authzed.api.v1.PermissionsService/CheckPermission", time.Now()))
+
+	require.NoError(t, os.WriteFile(path, []byte("logLevel: loud\n"), 0o600))
+	recorder = httptest.NewRecorder()
+	reloader.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/debug/reload", nil))
+	require.Equal(t, http.StatusBadRequest, recorder.Code)
+	require.Contains(t, recorder.Body.String(), "invalid log level")
+}
@@ -2,49 +2,134 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
-	"sync"
+	"path/filepath"
+	"strconv"
 	"time"
 
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/grpcutil"
+	"github.com/dustin/go-humanize"
 	grpc_auth "github.com/grpc-ecosystem/go-grpc-middleware/v2/interceptors/auth"
-	grpcprom "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/cors"
 	"github.com/rs/zerolog"
 	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"golang.org/x/exp/maps"
+	"golang.org/x/exp/slices"
 	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 
+	"github.com/authzed/spicedb/internal/audit"
 	"github.com/authzed/spicedb/internal/auth"
+	"github.com/authzed/spicedb/internal/changestream"
+	"github.com/authzed/spicedb/internal/checktraces"
 	"github.com/authzed/spicedb/internal/dashboard"
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/internal/datastore/proxy"
 	"github.com/authzed/spicedb/internal/dispatch"
 	clusterdispatch "github.com/authzed/spicedb/internal/dispatch/cluster"
 	combineddispatch "github.com/authzed/spicedb/internal/dispatch/combined"
+	"github.com/authzed/spicedb/internal/dispatch/remote"
+	"github.com/authzed/spicedb/internal/fips"
 	"github.com/authzed/spicedb/internal/gateway"
+	"github.com/authzed/spicedb/internal/graphql"
+	"github.com/authzed/spicedb/internal/grpcweb"
+	"github.com/authzed/spicedb/internal/hotkeys"
 	log "github.com/authzed/spicedb/internal/logging"
+	accountingmw "github.com/authzed/spicedb/internal/middleware/accounting"
+	admissionmw "github.com/authzed/spicedb/internal/middleware/admission"
+	auditmw "github.com/authzed/spicedb/internal/middleware/audit"
+	authfailuremw "github.com/authzed/spicedb/internal/middleware/authfailure"
+	consistencymw "github.com/authzed/spicedb/internal/middleware/consistency"
+	disabledmethodsmw "github.com/authzed/spicedb/internal/middleware/disabledmethods"
+	inflightmw "github.com/authzed/spicedb/internal/middleware/inflight"
+	loadshedmw "github.com/authzed/spicedb/internal/middleware/loadshed"
+	netpolicymw "github.com/authzed/spicedb/internal/middleware/netpolicy"
+	quotamw "github.com/authzed/spicedb/internal/middleware/quota"
+	ratelimitmw "github.com/authzed/spicedb/internal/middleware/ratelimit"
+	requestlogmw "github.com/authzed/spicedb/internal/middleware/requestlog"
+	scopemw "github.com/authzed/spicedb/internal/middleware/scope"
+	tenantmw "github.com/authzed/spicedb/internal/middleware/tenant"
+	"github.com/authzed/spicedb/internal/otlpmetrics"
+	"github.com/authzed/spicedb/internal/profiling"
+	"github.com/authzed/spicedb/internal/redaction"
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/internal/revisions"
 	"github.com/authzed/spicedb/internal/services"
 	dispatchSvc "github.com/authzed/spicedb/internal/services/dispatch"
 	"github.com/authzed/spicedb/internal/services/health"
+	"github.com/authzed/spicedb/internal/services/shared"
 	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	"github.com/authzed/spicedb/internal/telemetry"
+	"github.com/authzed/spicedb/internal/zpages"
 	"github.com/authzed/spicedb/pkg/balancer"
+	"github.com/authzed/spicedb/pkg/cache"
 	datastorecfg "github.com/authzed/spicedb/pkg/cmd/datastore"
 	"github.com/authzed/spicedb/pkg/cmd/util"
 	"github.com/authzed/spicedb/pkg/datastore"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/releases"
 )
 
+// defaultSchemaPurgeBatchSize is the number of relationships of a soft-deleted object definition
+// deleted per transaction when purging.
+const defaultSchemaPurgeBatchSize = 1000
+
+// defaultDeleteJobBatchSize is the number of relationships deleted per transaction by delete jobs
+// when no batch size is configured.
+const defaultDeleteJobBatchSize = 1000
+
+const (
+	// defaultChangeStreamRetryDelay is the delay before a change stream restarts after failing to
+	// publish changes.
+	defaultChangeStreamRetryDelay = 5 * time.Second
+
+	// defaultChangeStreamKafkaTimeout is the timeout for connections and requests to Kafka.
+	defaultChangeStreamKafkaTimeout = 10 * time.Second
+
+	// defaultChangeStreamNATSTimeout is the timeout for connections to NATS and for the
+	// acknowledgement of events.
+	defaultChangeStreamNATSTimeout = 10 * time.Second
+
+	// defaultChangeStreamWebhookBackoff is the delay before the second attempt at a webhook
+	// delivery, doubling for each attempt after.
+	defaultChangeStreamWebhookBackoff = 1 * time.Second
+
+	// defaultChangeStreamWebhookTimeout is the timeout for each attempt at a webhook delivery.
+	defaultChangeStreamWebhookTimeout = 10 * time.Second
+)
+
+// defaultAuditSinkTimeout is the timeout for connections and writes to the syslog and Kafka
+// audit sinks.
+const defaultAuditSinkTimeout = 10 * time.Second
+
 //go:generate go run github.com/ecordell/optgen -output zz_generated.options.go . Config
 type Config struct {
 	// API config
-	GRPCServer             util.GRPCServerConfig
-	GRPCAuthFunc           grpc_auth.AuthFunc
-	PresharedKey           []string
-	ShutdownGracePeriod    time.Duration
-	DisableVersionResponse bool
+	GRPCServer                util.GRPCServerConfig
+	GRPCAuthFunc              grpc_auth.AuthFunc
+	PresharedKey              []string
+	PresharedKeyValidity      map[string]string
+	OIDCIssuer                string
+	OIDCAudience              string
+	OIDCScopeClaim            string
+	OIDCJWKSRefreshInterval   time.Duration
+	AuthFailureMaxAttempts    int
+	AuthFailureWindow         time.Duration
+	AuthFailureLockout        time.Duration
+	AuthFailureLockoutIPs     bool
+	FIPSRequired              bool
+	ShutdownGracePeriod       time.Duration
+	DisableVersionResponse    bool
+	GRPCWebEnabled            bool
+	GRPCWebCorsAllowedOrigins []string
+	GRPCAllowedNetworks       []string
 
 	// GRPC Gateway config
 	HTTPGateway                    util.HTTPServerConfig
@@ -52,6 +137,12 @@ type Config struct {
 	HTTPGatewayUpstreamTLSCertPath string
 	HTTPGatewayCorsEnabled         bool
 	HTTPGatewayCorsAllowedOrigins  []string
+	HTTPGatewayCorsAllowedHeaders  []string
+	HTTPGatewayCorsExposedHeaders  []string
+	HTTPGatewayCorsMaxAge          time.Duration
+	HTTPGatewayAuthCookie          string
+	HTTPGatewaySigningKeys         map[string]string
+	HTTPGatewaySigningMaxClockSkew time.Duration
 
 	// Datastore
 	DatastoreConfig datastorecfg.Config
@@ -61,12 +152,20 @@ type Config struct {
 	NamespaceCacheConfig CacheConfig
 
 	// Schema options
-	SchemaPrefixesRequired bool
+	SchemaPrefixesRequired           bool
+	SchemaSoftDeleteDefinitions      bool
+	SchemaPurgeInterval              time.Duration
+	SchemaSyntheticRelationInterval  time.Duration
+	SchemaMaxSize                    uint32
+	SchemaMaxRelationsPerDefinition  uint32
+	SchemaMaxUnionTermsPerPermission uint32
 
 	// Dispatch options
 	DispatchServer               util.GRPCServerConfig
 	DispatchMaxDepth             uint32
 	DispatchConcurrencyLimit     uint16
+	DispatchDrainDelay           time.Duration
+	DispatchAllowedNetworks      []string
 	DispatchUpstreamAddr         string
 	DispatchUpstreamCAPath       string
 	DispatchClientMetricsPrefix  string
@@ -77,15 +176,113 @@ type Config struct {
 	ClusterDispatchCacheConfig CacheConfig
 
 	// API Behavior
-	DisableV1SchemaAPI         bool
-	V1SchemaAdditiveOnly       bool
-	MaximumUpdatesPerWrite     uint16
-	MaximumPreconditionCount   uint16
-	ExperimentalCaveatsEnabled bool
+	DisableV1SchemaAPI           bool
+	V1SchemaAdditiveOnly         bool
+	MaximumUpdatesPerWrite       uint16
+	MaximumPreconditionCount     uint16
+	StreamingMaxResultsPerCall   uint64
+	StreamingMaxBytesPerCall     string
+	ExperimentalCaveatsEnabled   bool
+	MaterializedPermissionSets   []string
+	RejectDeprecatedRelations    bool
+	StrictRelationshipValidation []string
+	DeleteJobBatchSize           uint64
+	DeleteJobBatchDelay          time.Duration
+	DefaultConsistency           string
+	DefaultConsistencyByMethod   map[string]string
+	DefaultConsistencyByKey      map[string]string
+	ReadYourWritesSessionTTL     time.Duration
+	ReadYourWritesMaxSessions    int
+	ReadYourWritesSessionHeader  string
+	MaxReadStaleness             time.Duration
+	ReplicationLagInterval       time.Duration
+	RelationshipQuotas           map[string]string
+	RelationshipQuotasByKey      map[string]string
+	TenantsByKey                 map[string]string
+	TenantRegistryPath           string
+	ScopesByKey                  map[string]string
+	ScopesByClientIdentity       map[string]string
+	RateLimit                    string
+	RateLimitByKey               map[string]string
+	RateLimitByMethod            map[string]string
+	RateLimitByClientIP          string
+	QuotaWindow                  time.Duration
+	QuotaBudget                  string
+	QuotaBudgetByKey             map[string]string
+	QuotaOverBudgetAction        string
+	UsageAccountingEnabled       bool
+	UsageExportFile              string
+	UsageExportInterval          time.Duration
+	LoadSheddingCPUThreshold     float64
+	LoadSheddingMaxGoroutines    int
+	LoadSheddingMaxInFlight      int
+	LoadSheddingRetryAfter       time.Duration
+	AdmissionLatencyThreshold    time.Duration
+	AdmissionErrorRateThreshold  float64
+	AdmissionExpensiveMethods    []string
+	DisabledAPIMethods           []string
 
 	// Additional Services
-	DashboardAPI util.HTTPServerConfig
-	MetricsAPI   util.HTTPServerConfig
+	DashboardAPI                  util.HTTPServerConfig
+	MetricsAPI                    util.HTTPServerConfig
+	MetricsAllowedNetworks        []string
+	MetricsRequireTLS             bool
+	MetricsAuth                   string
+	MetricsBasicAuthFile          string
+	MetricsZPagesEnabled          bool
+	MetricsDebugEndpointsEnabled  bool
+	MetricsOTLPEndpoint           string
+	MetricsOTLPProtocol           string
+	MetricsOTLPInsecure           bool
+	MetricsOTLPHeaders            map[string]string
+	MetricsOTLPInterval           time.Duration
+	MetricsOTLPTemporality        string
+	MetricsOTLPResourceAttributes map[string]string
+	GraphQLAPI                    util.HTTPServerConfig
+
+	GraphQLPermissionsEnabled bool
+
+	// Change streams
+	ChangeStreamCheckpointDir     string
+	ChangeStreamKafkaBrokers      []string
+	ChangeStreamKafkaTopic        string
+	ChangeStreamKafkaPartitionBy  string
+	ChangeStreamNATSURLs          []string
+	ChangeStreamNATSSubjectPrefix string
+	ChangeStreamNATSSubjects      map[string]string
+	ChangeStreamNATSIncludeSchema bool
+	ChangeStreamWebhooksFile      string
+	ChangeStreamWebhookAttempts   int
+
+	// Audit logging
+	AuditLogFile          string
+	AuditLogSyslogAddress string
+	AuditLogKafkaBrokers  []string
+	AuditLogKafkaTopic    string
+	AuditLogSampleRate    float64
+	AuditLogRedact        []string
+
+	// Request logging
+	RequestLogSampleRate         float64
+	RequestLogSampleRateByMethod map[string]string
+	RequestLogRedact             []string
+
+	// Telemetry redaction
+	TelemetryRedactionMode string
+
+	// Check traces
+	CheckTraceSampleRate   float64
+	CheckTraceOTLPEndpoint string
+	CheckTraceOTLPProtocol string
+	CheckTraceOTLPInsecure bool
+	CheckTraceOTLPHeaders  map[string]string
+
+	// Hot keys
+	HotKeysTopK     int
+	HotKeysHalfLife time.Duration
+
+	// Runtime config
+	RuntimeConfigPath string
 
 	// Middleware for grpc
 	UnaryMiddleware     []grpc.UnaryServerInterceptor
@@ -100,16 +297,56 @@ type Config struct {
 	TelemetryCAOverridePath  string
 	TelemetryEndpoint        string
 	TelemetryInterval        time.Duration
+
+	// Continuous profiling
+	ProfilingPushEndpoint        string
+	ProfilingPushApplicationName string
+	ProfilingPushInterval        time.Duration
+	ProfilingPushHeaders         map[string]string
+	ProfilingPushLabels          map[string]string
 }
 
 // Complete validates the config and fills out defaults.
 // if there is no error, a completedServerConfig (with limited options for
 // mutation) is returned.
 func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
-	if len(c.PresharedKey) < 1 && c.GRPCAuthFunc == nil {
-		return nil, fmt.Errorf("a preshared key must be provided to authenticate API requests")
+	if err := fips.Verify(c.FIPSRequired); err != nil {
+		return nil, err
+	}
+	log.Info().Bool("fips", fips.Enabled()).Msg("cryptography mode")
+
+	redactionMode, err := redaction.ParseMode(c.TelemetryRedactionMode)
+	if err != nil {
+		return nil, err
+	}
+	redaction.SetMode(redactionMode)
+	if redaction.Enabled() {
+		redaction.RedactLoggedErrors()
+		log.Info().Str("mode", string(redactionMode)).Msg("redacting object and subject IDs from logs and traces")
 	}
 
+	if len(c.PresharedKey) < 1 && c.GRPCAuthFunc == nil && c.OIDCIssuer == "" && len(c.ScopesByClientIdentity) == 0 {
+		return nil, fmt.Errorf("a preshared key, an OIDC issuer or client identities must be provided to authenticate API requests")
+	}
+	if len(c.ScopesByClientIdentity) > 0 && c.GRPCServer.TLSClientCAPath == "" {
+		return nil, fmt.Errorf("a client CA must be provided to authenticate API requests with client certificates")
+	}
+
+	presharedKeyValidity, err := c.presharedKeyValidity()
+	if err != nil {
+		return nil, err
+	}
+
+	tenants, err := c.tenants()
+	if err != nil {
+		return nil, err
+	}
+	scopes, err := c.scopes()
+	if err != nil {
+		return nil, err
+	}
+
+	dispatchAuthFunc := c.GRPCAuthFunc
 	if c.GRPCAuthFunc == nil {
 		log.Trace().Int("preshared-keys-count", len(c.PresharedKey)).Msg("using gRPC auth with preshared key(s)")
 		for index, presharedKey := range c.PresharedKey {
@@ -120,15 +357,57 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 			log.Trace().Int(fmt.Sprintf("preshared-key-%d-length", index+1), len(presharedKey)).Msg("preshared key configured")
 		}
 
-		c.GRPCAuthFunc = auth.RequirePresharedKey(c.PresharedKey)
+		if c.OIDCIssuer != "" {
+			verifier, err := auth.NewOIDCVerifier(ctx, auth.OIDCConfig{
+				Issuer:              c.OIDCIssuer,
+				Audience:            c.OIDCAudience,
+				JWKSRefreshInterval: c.OIDCJWKSRefreshInterval,
+			})
+			if err != nil {
+				return nil, fmt.Errorf("failed to configure OIDC authentication: %w", err)
+			}
+			log.Info().Str("issuer", c.OIDCIssuer).Str("audience", c.OIDCAudience).Msg("using gRPC auth with OIDC tokens")
+
+			c.GRPCAuthFunc = auth.RequirePresharedKeyOrOIDCToken(c.PresharedKey, presharedKeyValidity, verifier)
+		} else if len(c.PresharedKey) > 0 {
+			c.GRPCAuthFunc = auth.RequirePresharedKey(c.PresharedKey, presharedKeyValidity)
+		}
+
+		if len(c.ScopesByClientIdentity) > 0 {
+			identities := maps.Keys(c.ScopesByClientIdentity)
+			log.Info().Strs("identities", identities).Msg("using gRPC auth with client certificates")
+
+			c.GRPCAuthFunc = auth.RequireClientCertificateOrToken(identities, c.GRPCAuthFunc)
+		}
+
+		// The keys of the tenants created through the tenant management API are not preshared.
+		if c.GRPCAuthFunc != nil {
+			c.GRPCAuthFunc = tenants.AuthFunc(c.GRPCAuthFunc)
+		}
+
+		// Dispatch requests are only authenticated with preshared keys, as they are made between
+		// the nodes of the cluster.
+		if len(c.PresharedKey) > 0 {
+			dispatchAuthFunc = auth.RequirePresharedKey(c.PresharedKey, presharedKeyValidity)
+		} else if c.DispatchServer.Enabled || c.DispatchUpstreamAddr != "" {
+			return nil, fmt.Errorf("a preshared key must be provided to authenticate dispatch requests")
+		} else {
+			dispatchAuthFunc = c.GRPCAuthFunc
+		}
 	} else {
 		log.Trace().Msg("using preconfigured auth function")
 	}
 
+	// The runtime config can change the interval of the garbage collection of the datastore.
+	var gcIntervalOverride *common.GCIntervalOverride
+	if c.RuntimeConfigPath != "" && c.Datastore == nil {
+		gcIntervalOverride = common.NewGCIntervalOverride()
+	}
+
 	ds := c.Datastore
 	if ds == nil {
 		var err error
-		ds, err = datastorecfg.NewDatastore(context.Background(), c.DatastoreConfig.ToOption())
+		ds, err = datastorecfg.NewDatastore(context.Background(), c.DatastoreConfig.ToOption(), datastorecfg.WithGCIntervalOverride(gcIntervalOverride))
 		if err != nil {
 			return nil, fmt.Errorf("failed to create datastore: %w", err)
 		}
@@ -140,11 +419,19 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 	}
 	log.Info().EmbedObject(nscc).Msg("configured namespace cache")
 
+	admission, err := c.admissionController()
+	if err != nil {
+		return nil, err
+	}
+	if admission != nil {
+		// The queries are timed beneath the namespace cache, so that its hits are not observed.
+		ds = proxy.NewTimingDatastore(ds, admission)
+	}
+
 	ds = proxy.NewCachingDatastoreProxy(ds, nscc)
 	ds = proxy.NewObservableDatastoreProxy(ds)
 
-	enableGRPCHistogram()
-
+	var dispatchCache, clusterDispatchCache cache.Cache
 	dispatcher := c.Dispatcher
 	if dispatcher == nil {
 		var err error
@@ -153,10 +440,14 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 			return nil, fmt.Errorf("failed to create dispatcher: %w", cerr)
 		}
 		log.Info().EmbedObject(cc).Msg("configured dispatch cache")
+		dispatchCache = cc
 
 		dispatchPresharedKey := ""
 		if len(c.PresharedKey) > 0 {
 			dispatchPresharedKey = c.PresharedKey[0]
+			if key, ok := auth.ValidPresharedKey(c.PresharedKey, presharedKeyValidity, time.Now()); ok {
+				dispatchPresharedKey = key
+			}
 		}
 
 		dispatcher, err = combineddispatch.NewDispatcher(
@@ -164,8 +455,9 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 			combineddispatch.UpstreamCAPath(c.DispatchUpstreamCAPath),
 			combineddispatch.GrpcPresharedKey(dispatchPresharedKey),
 			combineddispatch.GrpcDialOpts(
-				grpc.WithUnaryInterceptor(otelgrpc.UnaryClientInterceptor()),
-				grpc.WithDefaultServiceConfig(balancer.BalancerServiceConfig),
+				grpc.WithChainUnaryInterceptor(otelgrpc.UnaryClientInterceptor(), loadshedmw.UnaryClientInterceptor()),
+				grpc.WithStreamInterceptor(loadshedmw.StreamClientInterceptor()),
+				grpc.WithDefaultServiceConfig(remote.ServiceConfig),
 			),
 			combineddispatch.PrometheusSubsystem(c.DispatchClientMetricsPrefix),
 			combineddispatch.Cache(cc),
@@ -176,12 +468,33 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 		}
 	}
 
+	shedder, err := c.loadShedder()
+	if err != nil {
+		return nil, err
+	}
+
+	// The audit logger is created before the servers, so that they record the calls they reject
+	// from networks which are not allowed.
+	auditLogger, err := c.auditLogger()
+	if err != nil {
+		return nil, err
+	}
+
+	apiAllowlist, err := netpolicymw.NewAllowlist("grpc", c.GRPCAllowedNetworks, auditLogger)
+	if err != nil {
+		return nil, err
+	}
+	dispatchAllowlist, err := netpolicymw.NewAllowlist("dispatch", c.DispatchAllowedNetworks, auditLogger)
+	if err != nil {
+		return nil, err
+	}
+	metricsAllowlist, err := netpolicymw.NewAllowlist("metrics", c.MetricsAllowedNetworks, auditLogger)
+	if err != nil {
+		return nil, err
+	}
+
 	if len(c.DispatchUnaryMiddleware) == 0 && len(c.DispatchStreamingMiddleware) == 0 {
-		if c.GRPCAuthFunc == nil {
-			c.DispatchUnaryMiddleware, c.DispatchStreamingMiddleware = DefaultDispatchMiddleware(log.Logger, auth.RequirePresharedKey(c.PresharedKey), ds)
-		} else {
-			c.DispatchUnaryMiddleware, c.DispatchStreamingMiddleware = DefaultDispatchMiddleware(log.Logger, c.GRPCAuthFunc, ds)
-		}
+		c.DispatchUnaryMiddleware, c.DispatchStreamingMiddleware = DefaultDispatchMiddleware(log.Logger, dispatchAuthFunc, ds, shedder)
 	}
 
 	var cachingClusterDispatch dispatch.Dispatcher
@@ -191,6 +504,7 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 			return nil, fmt.Errorf("failed to configure cluster dispatch: %w", cerr)
 		}
 		log.Info().EmbedObject(cdcc).Msg("configured cluster dispatch cache")
+		clusterDispatchCache = cdcc
 
 		var err error
 		cachingClusterDispatch, err = clusterdispatch.NewClusterDispatcher(
@@ -203,10 +517,13 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 		}
 	}
 
+	dispatchHealthServer := grpcutil.NewAuthlessHealthServer()
 	dispatchGrpcServer, err := c.DispatchServer.Complete(zerolog.InfoLevel,
 		func(server *grpc.Server) {
-			dispatchSvc.RegisterGrpcServices(server, cachingClusterDispatch)
+			dispatchSvc.RegisterGrpcServices(server, cachingClusterDispatch, dispatchHealthServer)
 		},
+		grpc.ChainUnaryInterceptor(netpolicymw.UnaryServerInterceptor(dispatchAllowlist)),
+		grpc.ChainStreamInterceptor(netpolicymw.StreamServerInterceptor(dispatchAllowlist)),
 		grpc.ChainUnaryInterceptor(c.DispatchUnaryMiddleware...),
 		grpc.ChainStreamInterceptor(c.DispatchStreamingMiddleware...),
 	)
@@ -224,6 +541,45 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 		v1SchemaServiceOption = services.V1SchemaServiceDisabled
 	} else if c.V1SchemaAdditiveOnly {
 		v1SchemaServiceOption = services.V1SchemaServiceAdditiveOnly
+	} else if c.SchemaSoftDeleteDefinitions {
+		v1SchemaServiceOption = services.V1SchemaServiceSoftDelete
+	}
+
+	var tombstonePurger *shared.TombstonePurger
+	if c.SchemaSoftDeleteDefinitions && c.SchemaPurgeInterval > 0 {
+		tombstonePurger = shared.NewTombstonePurger(ds, defaultSchemaPurgeBatchSize)
+	}
+
+	var syntheticMaterializer *shared.SyntheticRelationMaterializer
+	if c.SchemaSyntheticRelationInterval > 0 {
+		if datastoreFeatures.Watch.Enabled {
+			syntheticMaterializer = shared.NewSyntheticRelationMaterializer(ds)
+		} else {
+			log.Warn().Str("reason", datastoreFeatures.Watch.Reason).Msg("synthetic relation materialization disabled; underlying datastore does not support watch")
+		}
+	}
+
+	var permissionSets *shared.PermissionSetMaterializer
+	if len(c.MaterializedPermissionSets) > 0 {
+		if datastoreFeatures.Watch.Enabled {
+			sets := make([]shared.PermissionSet, 0, len(c.MaterializedPermissionSets))
+			for _, value := range c.MaterializedPermissionSets {
+				set, err := shared.ParsePermissionSet(value)
+				if err != nil {
+					return nil, err
+				}
+				sets = append(sets, set)
+			}
+			log.Warn().Strs("sets", c.MaterializedPermissionSets).Msg("experimental materialization of permission sets enabled")
+			permissionSets = shared.NewPermissionSetMaterializer(ds, dispatcher, c.DispatchMaxDepth, sets)
+		} else {
+			log.Warn().Str("reason", datastoreFeatures.Watch.Reason).Msg("permission set materialization disabled; underlying datastore does not support watch")
+		}
+	}
+
+	changeStreamers, err := c.changeStreamers(ds, datastoreFeatures)
+	if err != nil {
+		return nil, err
 	}
 
 	watchServiceOption := services.WatchServiceEnabled
@@ -232,14 +588,127 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 		watchServiceOption = services.WatchServiceDisabled
 	}
 
+	authThrottle := authfailuremw.NewThrottle(authfailuremw.Config{
+		MaxFailures:      c.AuthFailureMaxAttempts,
+		Window:           c.AuthFailureWindow,
+		Lockout:          c.AuthFailureLockout,
+		LockOutClientIPs: c.AuthFailureLockoutIPs,
+	}, auditLogger)
+
+	var limiter *ratelimitmw.Limiter
+	var quotaTracker *quotamw.Tracker
+	var accountant *accountingmw.Accountant
+	var inFlight *inflightmw.Tracker
+	authenticateMetrics, err := c.metricsAuthenticator(presharedKeyValidity)
+	if err != nil {
+		return nil, err
+	}
+	if c.MetricsZPagesEnabled {
+		if authenticateMetrics == nil && len(c.PresharedKey) == 0 {
+			return nil, fmt.Errorf("the zpages of the metrics server require a preshared key or --metrics-auth to authenticate requests")
+		}
+		inFlight = inflightmw.NewTracker()
+	}
+	lagMonitor, err := c.lagMonitor(ds)
+	if err != nil {
+		return nil, err
+	}
 	if len(c.UnaryMiddleware) == 0 && len(c.StreamingMiddleware) == 0 {
-		c.UnaryMiddleware, c.StreamingMiddleware = DefaultMiddleware(log.Logger, c.GRPCAuthFunc, !c.DisableVersionResponse, dispatcher, ds)
+		c.GRPCAuthFunc = authThrottle.AuthFunc(c.GRPCAuthFunc)
+		consistencyOpts, err := c.consistencyOptions(lagMonitor)
+		if err != nil {
+			return nil, err
+		}
+		limiter, err = c.rateLimiter()
+		if err != nil {
+			return nil, err
+		}
+		quotaTracker, err = c.quotaTracker()
+		if err != nil {
+			return nil, err
+		}
+		accountant, err = c.accountant()
+		if err != nil {
+			return nil, err
+		}
+		c.UnaryMiddleware, c.StreamingMiddleware = DefaultMiddlewareWithOptions(MiddlewareOptions{
+			Logger:                log.Logger,
+			AuthFunc:              c.GRPCAuthFunc,
+			EnableVersionResponse: !c.DisableVersionResponse,
+			Dispatcher:            dispatcher,
+			Datastore:             ds,
+			Tenants:               tenants,
+			Shedder:               shedder,
+			Admission:             admission,
+			Scopes:                scopes,
+			RateLimiter:           limiter,
+			QuotaTracker:          quotaTracker,
+			Accountant:            accountant,
+			InFlight:              inFlight,
+			ConsistencyOptions:    consistencyOpts,
+		})
+	}
+
+	if auditLogger != nil {
+		// The audit middleware runs last, so that the revision chosen for the call is known.
+		c.UnaryMiddleware = append(c.UnaryMiddleware, auditmw.UnaryServerInterceptor(auditLogger))
+		c.StreamingMiddleware = append(c.StreamingMiddleware, auditmw.StreamServerInterceptor(auditLogger))
+	}
+
+	requestLogger, err := c.requestLogger()
+	if err != nil {
+		return nil, err
+	}
+	if requestLogger != nil {
+		// The request logging middleware runs last, so that the revision chosen for the call is
+		// known.
+		c.UnaryMiddleware = append(c.UnaryMiddleware, requestlogmw.UnaryServerInterceptor(requestLogger))
+		c.StreamingMiddleware = append(c.StreamingMiddleware, requestlogmw.StreamServerInterceptor(requestLogger))
+	}
+
+	strictValidation, err := relationships.ParseStrictValidationMode(c.StrictRelationshipValidation)
+	if err != nil {
+		return nil, fmt.Errorf("invalid strict relationship validation: %w", err)
+	}
+
+	deleteJobBatchSize := c.DeleteJobBatchSize
+	if deleteJobBatchSize == 0 {
+		deleteJobBatchSize = defaultDeleteJobBatchSize
+	}
+	deleteJobs := shared.NewDeleteJobs(deleteJobBatchSize, c.DeleteJobBatchDelay)
+
+	relationshipQuotas, err := c.relationshipQuotas()
+	if err != nil {
+		return nil, err
+	}
+
+	streamLimits, err := c.streamLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	checkTraces, err := c.checkTraceShipper()
+	if err != nil {
+		return nil, err
+	}
+
+	hotKeys, err := c.hotKeyDetector()
+	if err != nil {
+		return nil, err
 	}
 
 	permSysConfig := v1svc.PermissionsServerConfig{
 		MaxPreconditionsCount: c.MaximumPreconditionCount,
 		MaxUpdatesPerWrite:    c.MaximumUpdatesPerWrite,
 		MaximumAPIDepth:       c.DispatchMaxDepth,
+
+		RejectDeprecatedRelations:    c.RejectDeprecatedRelations,
+		StrictRelationshipValidation: strictValidation,
+		DeleteJobs:                   deleteJobs,
+		RelationshipQuotas:           relationshipQuotas,
+		StreamLimits:                 streamLimits,
+		CheckTraces:                  checkTraces,
+		HotKeys:                      hotKeys,
 	}
 
 	caveatsOption := services.CaveatsDisabled
@@ -248,6 +717,18 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 		caveatsOption = services.CaveatsEnabled
 	}
 
+	if c.GRPCWebEnabled {
+		c.GRPCServer.HTTPHandler = c.grpcWebHandler
+	}
+
+	disabledMethods, err := disabledmethodsmw.Parse(c.DisabledAPIMethods)
+	if err != nil {
+		return nil, err
+	}
+	if len(disabledMethods) > 0 {
+		log.Info().Strs("methods", maps.Keys(disabledMethods)).Msg("disabled API methods")
+	}
+
 	healthManager := health.NewHealthManager(dispatcher, ds)
 	grpcServer, err := c.GRPCServer.Complete(zerolog.InfoLevel,
 		func(server *grpc.Server) {
@@ -258,15 +739,31 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 				v1SchemaServiceOption,
 				watchServiceOption,
 				caveatsOption,
+				shared.SchemaLimits{
+					MaxSchemaSize:              c.SchemaMaxSize,
+					MaxRelationsPerDefinition:  c.SchemaMaxRelationsPerDefinition,
+					MaxUnionTermsPerPermission: c.SchemaMaxUnionTermsPerPermission,
+				},
 				permSysConfig,
+				permissionSets,
 			)
 		},
+		grpc.ChainUnaryInterceptor(
+			netpolicymw.UnaryServerInterceptor(apiAllowlist),
+			authfailuremw.UnaryServerInterceptor(authThrottle),
+			disabledmethodsmw.UnaryServerInterceptor(disabledMethods),
+		),
+		grpc.ChainStreamInterceptor(
+			netpolicymw.StreamServerInterceptor(apiAllowlist),
+			authfailuremw.StreamServerInterceptor(authThrottle),
+			disabledmethodsmw.StreamServerInterceptor(disabledMethods),
+		),
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create gRPC server: %w", err)
 	}
 
-	gatewayServer, gatewayCloser, err := c.initializeGateway(ctx)
+	gatewayServer, gatewayCloser, err := c.initializeGateway(ctx, apiAllowlist)
 	if err != nil {
 		return nil, err
 	}
@@ -281,6 +778,31 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 		return nil, fmt.Errorf("failed to initialize dashboard server: %w", err)
 	}
 
+	var graphQLHandler http.Handler
+	var graphQLConn *grpc.ClientConn
+	if c.GraphQLAPI.Enabled {
+		if len(c.PresharedKey) == 0 {
+			return nil, fmt.Errorf("the GraphQL API requires a preshared key to authenticate requests")
+		}
+
+		mux := http.NewServeMux()
+		// The schema is read outside of the gRPC server, so the scopes of the keys are checked here.
+		mux.Handle("/", scopemw.HTTPHandler(scopes, "/authzed.api.v1.SchemaService/ReadSchema", graphql.NewSchemaHandler(ds, c.PresharedKey, presharedKeyValidity, tenants)))
+		if c.GraphQLPermissionsEnabled {
+			graphQLConn, err = grpcServer.DialContext(ctx)
+			if err != nil {
+				return nil, fmt.Errorf("failed to connect the GraphQL API to the gRPC server: %w", err)
+			}
+			mux.Handle("/permissions", graphql.NewPermissionsHandler(v1.NewPermissionsServiceClient(graphQLConn)))
+		}
+		graphQLHandler = mux
+	}
+
+	graphQLServer, err := c.GraphQLAPI.Complete(zerolog.InfoLevel, graphQLHandler)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize GraphQL server: %w", err)
+	}
+
 	registry, err := telemetry.RegisterTelemetryCollector(c.DatastoreConfig.Engine, ds)
 	if err != nil {
 		log.Warn().Err(err).Msg("unable to initialize telemetry collector")
@@ -301,22 +823,163 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 		}
 	}
 
-	metricsServer, err := c.MetricsAPI.Complete(zerolog.InfoLevel, MetricsHandler(registry))
+	var metricsExporter *otlpmetrics.Exporter
+	if c.MetricsOTLPEndpoint != "" {
+		resourceAttributes := make(map[string]string, len(c.MetricsOTLPResourceAttributes)+1)
+		if version, err := releases.CurrentVersion(); err == nil {
+			resourceAttributes["service.version"] = version
+		}
+		for key, value := range c.MetricsOTLPResourceAttributes {
+			resourceAttributes[key] = value
+		}
+
+		metricsExporter, err = otlpmetrics.NewExporter(otlpmetrics.Config{
+			Endpoint:           c.MetricsOTLPEndpoint,
+			Protocol:           c.MetricsOTLPProtocol,
+			Insecure:           c.MetricsOTLPInsecure,
+			Headers:            c.MetricsOTLPHeaders,
+			Interval:           c.MetricsOTLPInterval,
+			Temporality:        c.MetricsOTLPTemporality,
+			ResourceAttributes: resourceAttributes,
+		}, prometheus.DefaultGatherer)
+		if err != nil {
+			return nil, fmt.Errorf("unable to initialize OTLP metrics exporter: %w", err)
+		}
+	}
+
+	var profilingPusher *profiling.Pusher
+	if c.ProfilingPushEndpoint != "" {
+		profilingPusher, err = profiling.NewPusher(profiling.Config{
+			Endpoint:        c.ProfilingPushEndpoint,
+			ApplicationName: c.ProfilingPushApplicationName,
+			Interval:        c.ProfilingPushInterval,
+			Headers:         c.ProfilingPushHeaders,
+			Labels:          c.profilingLabels(),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("unable to initialize continuous profiling: %w", err)
+		}
+	}
+
+	revisionsHandler := revisions.NewHandler(ds, c.revisionSettings())
+
+	var quotaHandler http.Handler
+	if quotaTracker != nil {
+		quotaHandler = quotamw.NewHandler(quotaTracker)
+	}
+
+	var usageHandler http.Handler
+	if accountant != nil {
+		usageHandler = accountingmw.NewHandler(accountant)
+	}
+
+	var hotKeysHandler http.Handler
+	if hotKeys != nil {
+		hotKeysHandler = hotkeys.NewHandler(hotKeys)
+	}
+
+	var zpagesHandler http.Handler
+	if c.MetricsZPagesEnabled {
+		// The zpages are authenticated along with the rest of the metrics server when --metrics-auth
+		// is set.
+		zpagesKeys := c.PresharedKey
+		if authenticateMetrics != nil {
+			zpagesKeys = nil
+		}
+		zpagesHandler = zpages.NewHandler(c.zpagesSources(ds, inFlight, nscc, dispatchCache, clusterDispatchCache, auditLogger, checkTraces), zpagesKeys, presharedKeyValidity)
+	}
+
+	var reloader *runtimeReloader
+	var reloadHandler http.Handler
+	if c.RuntimeConfigPath != "" {
+		reloader = &runtimeReloader{
+			path:                 c.RuntimeConfigPath,
+			config:               *c,
+			startLevel:           zerolog.GlobalLevel(),
+			limiter:              limiter,
+			namespaceCache:       nscc,
+			dispatchCache:        dispatchCache,
+			clusterDispatchCache: clusterDispatchCache,
+			dispatcher:           dispatcher,
+			gcIntervalOverride:   gcIntervalOverride,
+		}
+		if err := reloader.Reload(); err != nil {
+			return nil, err
+		}
+		reloadHandler = reloader
+	}
+
+	var tenantsAPIHandler http.Handler
+	if c.TenantRegistryPath != "" {
+		adminKeys := c.adminPresharedKeys()
+		if len(adminKeys) == 0 {
+			return nil, fmt.Errorf("the tenant management API requires a preshared key restricted to neither a tenant nor a scope")
+		}
+		tenantsAPIHandler = auth.RequireHTTPPresharedKey(adminKeys, presharedKeyValidity, tenantsHandler{tenants: tenants, ds: ds})
+	}
+
+	metricsServer, err := c.MetricsAPI.Complete(zerolog.InfoLevel, netpolicymw.HTTPHandler(
+		metricsAllowlist,
+		authenticatedMetricsHandler(
+			MetricsHandlerWithOptions(MetricsHandlerOptions{
+				TelemetryRegistry:    registry,
+				EnableDebugEndpoints: c.MetricsDebugEndpointsEnabled,
+				Revisions:            revisionsHandler,
+				Quota:                quotaHandler,
+				Usage:                usageHandler,
+				HotKeys:              hotKeysHandler,
+				Reload:               reloadHandler,
+				ZPages:               zpagesHandler,
+				Health:               healthManager.HTTPHandler(),
+			}),
+			healthManager.HTTPHandler(),
+			tenantsAPIHandler,
+			authenticateMetrics,
+		),
+	))
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize metrics server: %w", err)
 	}
 
+	// The dispatch service is only drained when the dispatch server is enabled.
+	if !c.DispatchServer.Enabled {
+		dispatchHealthServer = nil
+	}
+
 	return &completedServerConfig{
-		gRPCServer:          grpcServer,
-		dispatchGRPCServer:  dispatchGrpcServer,
-		gatewayServer:       gatewayServer,
-		metricsServer:       metricsServer,
-		dashboardServer:     dashboardServer,
-		unaryMiddleware:     c.UnaryMiddleware,
-		streamingMiddleware: c.StreamingMiddleware,
-		presharedKeys:       c.PresharedKey,
-		telemetryReporter:   reporter,
-		healthManager:       healthManager,
+		gRPCServer:            grpcServer,
+		dispatchGRPCServer:    dispatchGrpcServer,
+		dispatchHealthServer:  dispatchHealthServer,
+		dispatchDrainDelay:    c.DispatchDrainDelay,
+		gatewayServer:         gatewayServer,
+		metricsServer:         metricsServer,
+		dashboardServer:       dashboardServer,
+		graphQLServer:         graphQLServer,
+		unaryMiddleware:       c.UnaryMiddleware,
+		streamingMiddleware:   c.StreamingMiddleware,
+		presharedKeys:         c.PresharedKey,
+		presharedKeyValidity:  presharedKeyValidity,
+		telemetryReporter:     reporter,
+		metricsExporter:       metricsExporter,
+		profilingPusher:       profilingPusher,
+		healthManager:         healthManager,
+		tombstonePurger:       tombstonePurger,
+		purgeInterval:         c.SchemaPurgeInterval,
+		syntheticMaterializer: syntheticMaterializer,
+		syntheticInterval:     c.SchemaSyntheticRelationInterval,
+		permissionSets:        permissionSets,
+		changeStreamers:       changeStreamers,
+		deleteJobs:            deleteJobs,
+		auditLogger:           auditLogger,
+		checkTraces:           checkTraces,
+		hotKeys:               hotKeys,
+		lagMonitor:            lagMonitor,
+		accountant:            accountant,
+		usageExportFile:       c.UsageExportFile,
+		usageExportInterval:   c.UsageExportInterval,
+		runtimeReloader:       reloader,
+		tenants:               tenants,
+		tenantRegistryPath:    c.TenantRegistryPath,
 		closeFunc: func() error {
 			if err := ds.Close(); err != nil {
 				return err
@@ -334,15 +997,25 @@ func (c *Config) Complete(ctx context.Context) (RunnableServer, error) {
 					return err
 				}
 			}
+			if graphQLConn != nil {
+				if err := graphQLConn.Close(); err != nil {
+					return err
+				}
+			}
 			return nil
 		},
 	}, nil
 }
 
 // initializeGateway Configures the gateway to serve HTTP
-func (c *Config) initializeGateway(ctx context.Context) (util.RunnableHTTPServer, io.Closer, error) {
+func (c *Config) initializeGateway(ctx context.Context, allowlist *netpolicymw.Allowlist) (util.RunnableHTTPServer, io.Closer, error) {
+	if !c.HTTPGateway.Enabled {
+		gatewayServer, err := c.HTTPGateway.Complete(zerolog.InfoLevel, nil)
+		return gatewayServer, nil, err
+	}
+
 	if len(c.HTTPGatewayUpstreamAddr) == 0 {
-		c.HTTPGatewayUpstreamAddr = c.GRPCServer.Address
+		c.HTTPGatewayUpstreamAddr = c.GRPCServer.DialTarget()
 	} else {
 		log.Info().Str("upstream", c.HTTPGatewayUpstreamAddr).Msg("Overriding REST gateway upstream")
 	}
@@ -360,105 +1033,854 @@ func (c *Config) initializeGateway(ctx context.Context) (util.RunnableHTTPServer
 	}
 	gatewayHandler = closeableGatewayHandler
 
+	if len(c.HTTPGatewaySigningKeys) > 0 {
+		for keyID, key := range c.HTTPGatewaySigningKeys {
+			if !slices.Contains(c.PresharedKey, key) {
+				return nil, nil, fmt.Errorf("REST gateway signing key %s is not a preshared key", keyID)
+			}
+		}
+		log.Info().Strs("keyIDs", maps.Keys(c.HTTPGatewaySigningKeys)).Msg("accepting REST gateway requests signed with HMAC")
+		gatewayHandler = gateway.NewSignatureVerifier(c.HTTPGatewaySigningKeys, c.HTTPGatewaySigningMaxClockSkew).Handler(gatewayHandler)
+	}
+
+	if c.HTTPGatewayAuthCookie != "" {
+		if c.HTTPGatewayCorsEnabled && slices.Contains(c.HTTPGatewayCorsAllowedOrigins, "*") {
+			return nil, nil, fmt.Errorf("the REST gateway cannot accept bearer tokens in cookies with CORS allowing all origins; restrict --http-cors-allowed-origins to the origins of trusted apps")
+		}
+
+		var allowedOrigins []string
+		if c.HTTPGatewayCorsEnabled {
+			allowedOrigins = c.HTTPGatewayCorsAllowedOrigins
+		}
+		log.Info().Str("cookie", c.HTTPGatewayAuthCookie).Msg("accepting REST gateway bearer tokens in cookies")
+		gatewayHandler = gateway.CookieAuthHandler(c.HTTPGatewayAuthCookie, allowedOrigins, gatewayHandler)
+	}
+
 	if c.HTTPGatewayCorsEnabled {
 		log.Info().Strs("origins", c.HTTPGatewayCorsAllowedOrigins).Msg("Setting REST gateway CORS policy")
+		allowedHeaders := c.HTTPGatewayCorsAllowedHeaders
+		if len(allowedHeaders) == 0 {
+			allowedHeaders = defaultHTTPGatewayCorsAllowedHeaders
+		}
+		// Credentials are never allowed from all origins, as any site could then use those of its visitors.
 		gatewayHandler = cors.New(cors.Options{
 			AllowedOrigins:   c.HTTPGatewayCorsAllowedOrigins,
-			AllowCredentials: true,
-			AllowedHeaders:   []string{"Authorization", "Content-Type"},
+			AllowCredentials: !slices.Contains(c.HTTPGatewayCorsAllowedOrigins, "*"),
+			AllowedHeaders:   allowedHeaders,
+			ExposedHeaders:   c.HTTPGatewayCorsExposedHeaders,
+			MaxAge:           int(c.HTTPGatewayCorsMaxAge.Seconds()),
 			Debug:            log.Debug().Enabled(),
 		}).Handler(gatewayHandler)
 	}
 
-	if c.HTTPGateway.Enabled {
-		log.Info().Str("upstream", c.HTTPGatewayUpstreamAddr).Msg("starting REST gateway")
-	}
+	log.Info().Str("upstream", c.HTTPGatewayUpstreamAddr).Msg("starting REST gateway")
 
-	gatewayServer, err := c.HTTPGateway.Complete(zerolog.InfoLevel, gatewayHandler)
+	gatewayServer, err := c.HTTPGateway.Complete(zerolog.InfoLevel, netpolicymw.HTTPHandler(allowlist, gatewayHandler))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to initialize rest gateway: %w", err)
 	}
 	return gatewayServer, closeableGatewayHandler, nil
 }
 
-// RunnableServer is a spicedb service set ready to run
-type RunnableServer interface {
-	Run(ctx context.Context) error
-	Middleware() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor)
-	SetMiddleware(unaryInterceptors []grpc.UnaryServerInterceptor, streamingInterceptors []grpc.StreamServerInterceptor) RunnableServer
-	GRPCDialContext(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error)
-	DispatchNetDialContext(ctx context.Context, s string) (net.Conn, error)
-}
+// defaultHTTPGatewayCorsAllowedHeaders are the request headers allowed by the CORS policy of the
+// REST gateway if none is configured.
+var defaultHTTPGatewayCorsAllowedHeaders = []string{"Authorization", "Content-Type"}
 
-// completedServerConfig holds the full configuration to run a spicedb server,
-// but is assumed have already been validated via `Complete()` on Config.
-// It offers limited options for mutation before Run() starts the services.
-type completedServerConfig struct {
-	gRPCServer         util.RunnableGRPCServer
-	dispatchGRPCServer util.RunnableGRPCServer
-	gatewayServer      util.RunnableHTTPServer
-	metricsServer      util.RunnableHTTPServer
-	dashboardServer    util.RunnableHTTPServer
-	telemetryReporter  telemetry.Reporter
-	healthManager      health.Manager
+// grpcWebHandler returns the handler serving gRPC-Web and Connect requests alongside gRPC
+// requests on the gRPC port.
+func (c *Config) grpcWebHandler(server *grpc.Server) (http.Handler, error) {
+	handler, err := grpcweb.NewHandler(server)
+	if err != nil {
+		return nil, err
+	}
+	if len(c.GRPCWebCorsAllowedOrigins) == 0 {
+		return handler, nil
+	}
 
-	unaryMiddleware     []grpc.UnaryServerInterceptor
-	streamingMiddleware []grpc.StreamServerInterceptor
-	presharedKeys       []string
-	closeFunc           func() error
+	log.Info().Strs("origins", c.GRPCWebCorsAllowedOrigins).Msg("Setting gRPC-Web CORS policy")
+	return cors.New(cors.Options{
+		AllowedOrigins:   c.GRPCWebCorsAllowedOrigins,
+		AllowCredentials: true,
+		AllowedMethods:   []string{http.MethodPost},
+		AllowedHeaders:   []string{"Authorization", "Content-Type", "X-Grpc-Web", "X-User-Agent", "Grpc-Timeout", "Connect-Protocol-Version", "Connect-Timeout-Ms"},
+		ExposedHeaders:   []string{"Grpc-Status", "Grpc-Message", "Grpc-Status-Details-Bin"},
+		Debug:            log.Debug().Enabled(),
+	}).Handler(handler), nil
 }
 
-func (c *completedServerConfig) Middleware() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
-	return c.unaryMiddleware, c.streamingMiddleware
-}
+// changeStreamers creates a change streamer for each configured change stream publisher.
+func (c *Config) changeStreamers(ds datastore.Datastore, features *datastore.Features) ([]*changestream.Streamer, error) {
+	type publisherContent struct {
+		publisher changestream.Publisher
+		content   datastore.WatchContent
+	}
 
-func (c *completedServerConfig) SetMiddleware(unaryInterceptors []grpc.UnaryServerInterceptor, streamingInterceptors []grpc.StreamServerInterceptor) RunnableServer {
-	c.unaryMiddleware = unaryInterceptors
-	c.streamingMiddleware = streamingInterceptors
-	return c
-}
+	var publishers []publisherContent
+	if len(c.ChangeStreamKafkaBrokers) > 0 {
+		partitionBy, ok := changestream.PartitionByNames[c.ChangeStreamKafkaPartitionBy]
+		if !ok {
+			return nil, fmt.Errorf("unknown kafka change stream partitioning: %s", c.ChangeStreamKafkaPartitionBy)
+		}
 
-func (c *completedServerConfig) GRPCDialContext(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
-	if len(c.presharedKeys) == 0 {
-		return c.gRPCServer.DialContext(ctx, opts...)
-	}
-	if c.gRPCServer.Insecure() {
-		opts = append(opts, grpcutil.WithInsecureBearerToken(c.presharedKeys[0]))
-	} else {
-		opts = append(opts, grpcutil.WithBearerToken(c.presharedKeys[0]))
+		publisher, err := changestream.NewKafkaPublisher(changestream.KafkaConfig{
+			Brokers:     c.ChangeStreamKafkaBrokers,
+			Topic:       c.ChangeStreamKafkaTopic,
+			PartitionBy: partitionBy,
+			Timeout:     defaultChangeStreamKafkaTimeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize kafka change stream: %w", err)
+		}
+		publishers = append(publishers, publisherContent{publisher, datastore.WatchRelationships})
 	}
-	return c.gRPCServer.DialContext(ctx, opts...)
-}
-
-func (c *completedServerConfig) DispatchNetDialContext(ctx context.Context, s string) (net.Conn, error) {
-	return c.dispatchGRPCServer.NetDialContext(ctx, s)
-}
 
-func (c *completedServerConfig) Run(ctx context.Context) error {
-	g, ctx := errgroup.WithContext(ctx)
+	if len(c.ChangeStreamNATSURLs) > 0 {
+		publisher, err := changestream.NewNATSPublisher(changestream.NATSConfig{
+			URLs:          c.ChangeStreamNATSURLs,
+			SubjectPrefix: c.ChangeStreamNATSSubjectPrefix,
+			Subjects:      c.ChangeStreamNATSSubjects,
+			Timeout:       defaultChangeStreamNATSTimeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize nats change stream: %w", err)
+		}
 
-	stopOnCancel := func(stopFn func()) func() error {
-		return func() error {
-			<-ctx.Done()
-			stopFn()
-			return nil
+		content := datastore.WatchRelationships
+		if c.ChangeStreamNATSIncludeSchema {
+			content |= datastore.WatchSchema
 		}
+		publishers = append(publishers, publisherContent{publisher, content})
 	}
 
-	stopOnCancelWithErr := func(stopFn func() error) func() error {
-		return func() error {
-			<-ctx.Done()
-			return stopFn()
+	if c.ChangeStreamWebhooksFile != "" {
+		endpoints, err := changestream.LoadWebhookEndpoints(c.ChangeStreamWebhooksFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize webhook change stream: %w", err)
+		}
+
+		publisher, err := changestream.NewWebhookPublisher(changestream.WebhookConfig{
+			Endpoints:      endpoints,
+			MaxAttempts:    c.ChangeStreamWebhookAttempts,
+			InitialBackoff: defaultChangeStreamWebhookBackoff,
+			Timeout:        defaultChangeStreamWebhookTimeout,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize webhook change stream: %w", err)
 		}
+		publishers = append(publishers, publisherContent{publisher, datastore.WatchRelationships})
 	}
 
-	grpcServer := c.gRPCServer.WithOpts(grpc.ChainUnaryInterceptor(c.unaryMiddleware...), grpc.ChainStreamInterceptor(c.streamingMiddleware...))
-	g.Go(c.healthManager.Checker(ctx))
-	g.Go(grpcServer.Listen(ctx))
-	g.Go(stopOnCancel(grpcServer.GracefulStop))
+	if len(publishers) > 0 && !features.Watch.Enabled {
+		return nil, fmt.Errorf("change streams require a datastore supporting watch: %s", features.Watch.Reason)
+	}
+
+	streamers := make([]*changestream.Streamer, 0, len(publishers))
+	for _, pc := range publishers {
+		publisher := pc.publisher
+		checkpoints := changestream.NewMemoryCheckpointStore()
+		if c.ChangeStreamCheckpointDir != "" {
+			checkpoints = changestream.NewFileCheckpointStore(filepath.Join(c.ChangeStreamCheckpointDir, publisher.Name()+".checkpoint"))
+		} else {
+			log.Warn().Str("publisher", publisher.Name()).Msg("no change stream checkpoint directory configured; changes made while the server is stopped will not be published")
+		}
+		streamers = append(streamers, changestream.NewStreamer(ds, publisher, checkpoints, pc.content, defaultChangeStreamRetryDelay))
+	}
+	return streamers, nil
+}
+
+// revisionSettings returns the settings of the datastore determining its revisions.
+func (c *Config) revisionSettings() revisions.Settings {
+	return revisions.Settings{
+		Engine:               c.DatastoreConfig.Engine,
+		TimestampRevisions:   c.DatastoreConfig.Engine != datastorecfg.PostgresEngine && c.DatastoreConfig.Engine != datastorecfg.MySQLEngine,
+		RevisionQuantization: c.DatastoreConfig.RevisionQuantization,
+		FollowerReadDelay:    c.followerReadDelay(),
+		GCWindow:             c.DatastoreConfig.GCWindow,
+	}
+}
+
+// lagMonitor returns the monitor of the replication lag of the datastore, if enabled. The lag can
+// only be measured for the datastores whose revisions are timestamps.
+func (c *Config) lagMonitor(ds datastore.Datastore) (*revisions.LagMonitor, error) {
+	settings := c.revisionSettings()
+	if c.ReplicationLagInterval <= 0 || !settings.TimestampRevisions {
+		if c.MaxReadStaleness > 0 {
+			return nil, fmt.Errorf("bounding the staleness of reads requires a positive replication lag interval and a datastore whose revisions are timestamps")
+		}
+		return nil, nil
+	}
+	return revisions.NewLagMonitor(ds, settings, c.ReplicationLagInterval), nil
+}
+
+// consistencyOptions returns the options of the consistency middleware for the configured
+// default consistencies, read-your-writes sessions and staleness bound.
+func (c *Config) consistencyOptions(lagMonitor *revisions.LagMonitor) ([]consistencymw.Option, error) {
+	var opts []consistencymw.Option
+	if c.DefaultConsistency != "" {
+		consistency, err := consistencymw.ParseDefaultConsistency(c.DefaultConsistency)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, consistencymw.WithDefaultConsistency(consistency))
+	}
+
+	for method, name := range c.DefaultConsistencyByMethod {
+		consistency, err := consistencymw.ParseDefaultConsistency(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default consistency for method %s: %w", method, err)
+		}
+		opts = append(opts, consistencymw.WithMethodDefaultConsistency(method, consistency))
+	}
+
+	for key, name := range c.DefaultConsistencyByKey {
+		if !slices.Contains(c.PresharedKey, key) {
+			return nil, errors.New("default consistency configured for a key which is not a preshared key")
+		}
+
+		consistency, err := consistencymw.ParseDefaultConsistency(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid default consistency for key: %w", err)
+		}
+		opts = append(opts, consistencymw.WithKeyDefaultConsistency(key, consistency))
+	}
+
+	if c.ReadYourWritesSessionTTL > 0 {
+		if c.ReadYourWritesMaxSessions <= 0 {
+			return nil, errors.New("the maximum number of read-your-writes sessions must be positive")
+		}
+		opts = append(opts, consistencymw.WithSessions(consistencymw.NewSessions(c.ReadYourWritesSessionTTL, c.ReadYourWritesMaxSessions)))
+		if c.ReadYourWritesSessionHeader != "" {
+			opts = append(opts, consistencymw.WithSessionHeader(c.ReadYourWritesSessionHeader))
+		}
+	}
+
+	if c.MaxReadStaleness > 0 {
+		opts = append(opts, consistencymw.WithMaxStaleness(c.MaxReadStaleness, lagMonitor.Lag))
+	}
+	return opts, nil
+}
+
+// relationshipQuotas returns the configured quotas on the number of relationships.
+func (c *Config) relationshipQuotas() (shared.RelationshipQuotas, error) {
+	quotas := shared.RelationshipQuotas{
+		ByNamespace: make(map[string]uint64, len(c.RelationshipQuotas)),
+		ByKey:       make(map[string]uint64, len(c.RelationshipQuotasByKey)),
+	}
+	for namespace, value := range c.RelationshipQuotas {
+		quota, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return quotas, fmt.Errorf("invalid relationship quota for %s: %w", namespace, err)
+		}
+		quotas.ByNamespace[namespace] = quota
+	}
+
+	for key, value := range c.RelationshipQuotasByKey {
+		if !slices.Contains(c.PresharedKey, key) {
+			return quotas, errors.New("relationship quota configured for a key which is not a preshared key")
+		}
+
+		quota, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return quotas, fmt.Errorf("invalid relationship quota for key: %w", err)
+		}
+		quotas.ByKey[key] = quota
+	}
+	return quotas, nil
+}
+
+// followerReadDelay returns the follower read delay of the datastore, if it supports follower
+// reads.
+// profilingLabels returns the labels of the pushed profiles: the version of the server and its
+// role, which is `api-dispatch` if it also serves the dispatch cluster, overridden by the
+// configured labels.
+func (c *Config) profilingLabels() map[string]string {
+	labels := map[string]string{"role": "api"}
+	if c.DispatchServer.Enabled {
+		labels["role"] = "api-dispatch"
+	}
+	if version, err := releases.CurrentVersion(); err == nil {
+		labels["version"] = version
+	}
+	for key, value := range c.ProfilingPushLabels {
+		labels[key] = value
+	}
+	return labels
+}
+
+func (c *Config) followerReadDelay() time.Duration {
+	if c.DatastoreConfig.Engine != datastorecfg.CockroachEngine {
+		return 0
+	}
+	return c.DatastoreConfig.FollowerReadDelay
+}
+
+// metricsAuthenticator returns the function wrapping the handlers of the metrics server with the
+// authentication of --metrics-auth, or nil if requests to the metrics server are unauthenticated.
+func (c *Config) metricsAuthenticator(presharedKeyValidity map[string]auth.PresharedKeyValidity) (func(http.Handler) http.Handler, error) {
+	if c.MetricsRequireTLS && (c.MetricsAPI.TLSCertPath == "" || c.MetricsAPI.TLSKeyPath == "") {
+		return nil, fmt.Errorf("the metrics server requires TLS, but no TLS certificate and key were given")
+	}
 
+	switch c.MetricsAuth {
+	case "", "none":
+		if c.MetricsBasicAuthFile != "" {
+			return nil, fmt.Errorf("a basic auth file was given for the metrics server, but --metrics-auth is not basic")
+		}
+		return nil, nil
+
+	case "basic":
+		if c.MetricsBasicAuthFile == "" {
+			return nil, fmt.Errorf("basic auth of the metrics server requires a basic auth file")
+		}
+		credentials, err := auth.LoadBasicAuthFile(c.MetricsBasicAuthFile)
+		if err != nil {
+			return nil, err
+		}
+		log.Info().Int("users", len(credentials)).Msg("metrics server requires basic auth")
+		return func(next http.Handler) http.Handler {
+			return auth.RequireHTTPBasicAuth(credentials, next)
+		}, nil
+
+	case "preshared-key":
+		if len(c.PresharedKey) == 0 {
+			return nil, fmt.Errorf("preshared key auth of the metrics server requires a preshared key")
+		}
+		log.Info().Msg("metrics server requires a preshared key")
+		return func(next http.Handler) http.Handler {
+			return auth.RequireHTTPPresharedKey(c.PresharedKey, presharedKeyValidity, next)
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown metrics auth %q, expected one of none, basic and preshared-key", c.MetricsAuth)
+	}
+}
+
+// authenticatedMetricsHandler wraps the handler of the metrics server with the given authentication,
+// if any, except for the health endpoint, which is left unauthenticated for probes, and for the
+// tenant management API, if any, which authenticates its requests itself.
+func authenticatedMetricsHandler(handler, healthHandler, tenantsHandler http.Handler, authenticate func(http.Handler) http.Handler) http.Handler {
+	if authenticate == nil && tenantsHandler == nil {
+		return handler
+	}
+
+	mux := http.NewServeMux()
+	if authenticate == nil {
+		mux.Handle("/", handler)
+	} else {
+		mux.Handle("/", authenticate(handler))
+		mux.Handle("/health", healthHandler)
+	}
+	if tenantsHandler != nil {
+		mux.Handle("/tenants", tenantsHandler)
+		mux.Handle("/tenants/", tenantsHandler)
+	}
+	return mux
+}
+
+// presharedKeyValidity returns the validity window of each preshared key which has one.
+func (c *Config) presharedKeyValidity() (map[string]auth.PresharedKeyValidity, error) {
+	validity := make(map[string]auth.PresharedKeyValidity, len(c.PresharedKeyValidity))
+	for key, value := range c.PresharedKeyValidity {
+		if !slices.Contains(c.PresharedKey, key) {
+			return nil, errors.New("validity configured for a key which is not a preshared key")
+		}
+
+		keyValidity, err := auth.ParsePresharedKeyValidity(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid validity for key %s: %w", auth.PresharedKeyCaller(key), err)
+		}
+		validity[key] = keyValidity
+
+		log.Info().
+			Str("key", auth.PresharedKeyCaller(key)).
+			Time("created", keyValidity.Created).
+			Time("expires", keyValidity.Expires).
+			Msg("preshared key validity configured")
+	}
+	return validity, nil
+}
+
+// adminPresharedKeys returns the preshared keys restricted to neither a tenant nor a scope, which
+// can manage the tenants.
+func (c *Config) adminPresharedKeys() []string {
+	var adminKeys []string
+	for _, key := range c.PresharedKey {
+		if _, ok := c.TenantsByKey[key]; ok {
+			continue
+		}
+		if _, ok := c.ScopesByKey[key]; ok {
+			continue
+		}
+		adminKeys = append(adminKeys, key)
+	}
+	return adminKeys
+}
+
+// tenants returns the registry of the tenants to which the preshared keys and the keys of the
+// tenants created through the tenant management API are restricted.
+func (c *Config) tenants() (*tenantmw.Registry, error) {
+	for key, tenant := range c.TenantsByKey {
+		if !slices.Contains(c.PresharedKey, key) {
+			return nil, errors.New("tenant configured for a key which is not a preshared key")
+		}
+
+		if err := tenantmw.ValidateTenantName(tenant); err != nil {
+			return nil, err
+		}
+	}
+	return tenantmw.NewRegistry(c.TenantsByKey, c.TenantRegistryPath)
+}
+
+// scopes returns the scopes of the preshared keys, OIDC tokens and client identities restricted
+// to a scope.
+func (c *Config) scopes() (scopemw.Scopes, error) {
+	scopes := scopemw.Scopes{
+		ByKey:            make(map[string]scopemw.Scope, len(c.ScopesByKey)),
+		OIDCClaim:        c.OIDCScopeClaim,
+		ByClientIdentity: make(map[string]scopemw.Scope, len(c.ScopesByClientIdentity)),
+	}
+	for key, value := range c.ScopesByKey {
+		if !slices.Contains(c.PresharedKey, key) {
+			return scopemw.Scopes{}, errors.New("scope configured for a key which is not a preshared key")
+		}
+
+		scope, err := scopemw.ParseScope(value)
+		if err != nil {
+			return scopemw.Scopes{}, fmt.Errorf("invalid scope for key: %w", err)
+		}
+		scopes.ByKey[key] = scope
+	}
+	for identity, value := range c.ScopesByClientIdentity {
+		scope, err := scopemw.ParseScope(value)
+		if err != nil {
+			return scopemw.Scopes{}, fmt.Errorf("invalid scope for client identity %s: %w", identity, err)
+		}
+		scopes.ByClientIdentity[identity] = scope
+	}
+	return scopes, nil
+}
+
+// rateLimiter returns the limiter of the rate of calls for the configured rate limits.
+func (c *Config) rateLimiter() (*ratelimitmw.Limiter, error) {
+	opts, err := c.rateLimitOptions()
+	if err != nil {
+		return nil, err
+	}
+	return ratelimitmw.NewLimiter(opts...), nil
+}
+
+// rateLimitOptions returns the options of the limiter of the rate of calls for the configured
+// rate limits.
+func (c *Config) rateLimitOptions() ([]ratelimitmw.Option, error) {
+	var opts []ratelimitmw.Option
+	if c.RateLimit != "" {
+		limit, err := ratelimitmw.ParseLimit(c.RateLimit)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, ratelimitmw.WithDefaultLimit(limit))
+	}
+
+	for key, value := range c.RateLimitByKey {
+		if !slices.Contains(c.PresharedKey, key) {
+			return nil, errors.New("rate limit configured for a key which is not a preshared key")
+		}
+
+		limit, err := ratelimitmw.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit for key: %w", err)
+		}
+		opts = append(opts, ratelimitmw.WithKeyLimit(key, limit))
+	}
+
+	for method, value := range c.RateLimitByMethod {
+		limit, err := ratelimitmw.ParseLimit(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit for method %s: %w", method, err)
+		}
+		opts = append(opts, ratelimitmw.WithMethodLimit(method, limit))
+	}
+
+	if c.RateLimitByClientIP != "" {
+		limit, err := ratelimitmw.ParseLimit(c.RateLimitByClientIP)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit by client IP address: %w", err)
+		}
+		opts = append(opts, ratelimitmw.WithClientIPLimit(limit))
+	}
+	return opts, nil
+}
+
+// accountant returns the accountant of the usage of callers, or nil if usage accounting is
+// disabled. Exporting the usage to a file enables usage accounting.
+func (c *Config) accountant() (*accountingmw.Accountant, error) {
+	if !c.UsageAccountingEnabled && c.UsageExportFile == "" {
+		return nil, nil
+	}
+	if c.UsageExportFile != "" && c.UsageExportInterval <= 0 {
+		return nil, errors.New("the usage export interval must be positive")
+	}
+	return accountingmw.NewAccountant(), nil
+}
+
+// quotaTracker returns the tracker of the usage of callers for the configured quota budgets, or
+// nil if no budget is configured.
+func (c *Config) quotaTracker() (*quotamw.Tracker, error) {
+	if c.QuotaBudget == "" && len(c.QuotaBudgetByKey) == 0 {
+		return nil, nil
+	}
+
+	if c.QuotaWindow <= 0 {
+		return nil, errors.New("the quota window must be positive")
+	}
+
+	action, err := quotamw.ParseAction(c.QuotaOverBudgetAction)
+	if err != nil {
+		return nil, err
+	}
+	opts := []quotamw.Option{quotamw.WithWindow(c.QuotaWindow), quotamw.WithOverBudgetAction(action)}
+
+	if c.QuotaBudget != "" {
+		budget, err := quotamw.ParseBudget(c.QuotaBudget)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, quotamw.WithDefaultBudget(budget))
+	}
+
+	for key, value := range c.QuotaBudgetByKey {
+		if !slices.Contains(c.PresharedKey, key) {
+			return nil, errors.New("quota budget configured for a key which is not a preshared key")
+		}
+
+		budget, err := quotamw.ParseBudget(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid quota budget for key: %w", err)
+		}
+		opts = append(opts, quotamw.WithCallerBudget(auth.PresharedKeyCaller(key), budget))
+	}
+	return quotamw.NewTracker(opts...), nil
+}
+
+// streamLimits returns the maximums enforced on the results streamed by a single call.
+func (c *Config) streamLimits() (v1svc.StreamLimits, error) {
+	limits := v1svc.StreamLimits{MaxResults: c.StreamingMaxResultsPerCall}
+	if c.StreamingMaxBytesPerCall != "" {
+		maxBytes, err := humanize.ParseBytes(c.StreamingMaxBytesPerCall)
+		if err != nil {
+			return v1svc.StreamLimits{}, fmt.Errorf("invalid maximum bytes streamed per call: %w", err)
+		}
+		limits.MaxBytes = maxBytes
+	}
+	return limits, nil
+}
+
+// admissionController returns the controller throttling the expensive calls while the datastore
+// is degraded, or nil if no threshold of the datastore queries is configured.
+func (c *Config) admissionController() (*admissionmw.Controller, error) {
+	if c.AdmissionLatencyThreshold < 0 || c.AdmissionErrorRateThreshold < 0 {
+		return nil, errors.New("admission control thresholds cannot be negative")
+	}
+	if c.AdmissionLatencyThreshold == 0 && c.AdmissionErrorRateThreshold == 0 {
+		return nil, nil
+	}
+
+	log.Info().
+		Dur("latency-threshold", c.AdmissionLatencyThreshold).
+		Float64("error-rate-threshold", c.AdmissionErrorRateThreshold).
+		Strs("expensive-methods", c.AdmissionExpensiveMethods).
+		Msg("configured datastore admission control")
+
+	opts := []admissionmw.Option{
+		admissionmw.WithLatencyThreshold(c.AdmissionLatencyThreshold),
+		admissionmw.WithErrorRateThreshold(c.AdmissionErrorRateThreshold),
+	}
+	if len(c.AdmissionExpensiveMethods) > 0 {
+		opts = append(opts, admissionmw.WithExpensiveMethods(c.AdmissionExpensiveMethods))
+	}
+	return admissionmw.NewController(opts...), nil
+}
+
+// loadShedder returns the shedder of calls for the configured load shedding thresholds, or nil if
+// no threshold is configured.
+func (c *Config) loadShedder() (*loadshedmw.Shedder, error) {
+	if c.LoadSheddingCPUThreshold < 0 || c.LoadSheddingMaxGoroutines < 0 || c.LoadSheddingMaxInFlight < 0 {
+		return nil, errors.New("load shedding thresholds cannot be negative")
+	}
+	if c.LoadSheddingCPUThreshold == 0 && c.LoadSheddingMaxGoroutines == 0 && c.LoadSheddingMaxInFlight == 0 {
+		return nil, nil
+	}
+
+	log.Info().
+		Float64("cpu-threshold", c.LoadSheddingCPUThreshold).
+		Int("max-goroutines", c.LoadSheddingMaxGoroutines).
+		Int("max-in-flight", c.LoadSheddingMaxInFlight).
+		Msg("configured load shedding")
+
+	opts := []loadshedmw.Option{
+		loadshedmw.WithCPUThreshold(c.LoadSheddingCPUThreshold),
+		loadshedmw.WithGoroutineThreshold(c.LoadSheddingMaxGoroutines),
+		loadshedmw.WithInFlightThreshold(c.LoadSheddingMaxInFlight),
+	}
+	if c.LoadSheddingRetryAfter > 0 {
+		opts = append(opts, loadshedmw.WithRetryAfter(c.LoadSheddingRetryAfter))
+	}
+	return loadshedmw.NewShedder(opts...), nil
+}
+
+// requestLogger creates the logger of a sample of the API calls, or returns nil if no call is
+// sampled.
+func (c *Config) requestLogger() (*requestlogmw.Logger, error) {
+	if c.RequestLogSampleRate == 0 && len(c.RequestLogSampleRateByMethod) == 0 {
+		return nil, nil
+	}
+
+	if c.RequestLogSampleRate < 0 || c.RequestLogSampleRate > 1 {
+		return nil, fmt.Errorf("request log sample rate must be between 0 and 1, got %v", c.RequestLogSampleRate)
+	}
+
+	redaction, err := audit.ParseRedaction(c.RequestLogRedact)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []requestlogmw.Option{requestlogmw.WithSampleRate(c.RequestLogSampleRate), requestlogmw.WithRedaction(redaction)}
+	for method, value := range c.RequestLogSampleRateByMethod {
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return nil, fmt.Errorf("request log sample rate of method %s must be between 0 and 1, got %q", method, value)
+		}
+		opts = append(opts, requestlogmw.WithMethodSampleRate(method, rate))
+	}
+	return requestlogmw.NewLogger(log.Logger, opts...), nil
+}
+
+// checkTraceShipper creates the shipper of the traces of sampled checks, or returns nil if checks
+// are not sampled.
+func (c *Config) checkTraceShipper() (*checktraces.Shipper, error) {
+	if c.CheckTraceSampleRate == 0 {
+		return nil, nil
+	}
+	if c.CheckTraceSampleRate < 0 || c.CheckTraceSampleRate > 1 {
+		return nil, fmt.Errorf("check trace sample rate must be between 0 and 1, got %v", c.CheckTraceSampleRate)
+	}
+
+	resourceAttributes := make(map[string]string, 1)
+	if version, err := releases.CurrentVersion(); err == nil {
+		resourceAttributes["service.version"] = version
+	}
+
+	sink, err := checktraces.NewOTLPSink(checktraces.OTLPConfig{
+		Endpoint:           c.CheckTraceOTLPEndpoint,
+		Protocol:           c.CheckTraceOTLPProtocol,
+		Insecure:           c.CheckTraceOTLPInsecure,
+		Headers:            c.CheckTraceOTLPHeaders,
+		ResourceAttributes: resourceAttributes,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize check trace sink: %w", err)
+	}
+	return checktraces.NewShipper(c.CheckTraceSampleRate, sink), nil
+}
+
+// hotKeyDetector returns the detector of the most frequently checked keys, or nil if hot key
+// detection is disabled.
+func (c *Config) hotKeyDetector() (*hotkeys.Detector, error) {
+	if c.HotKeysTopK == 0 {
+		return nil, nil
+	}
+	if c.HotKeysTopK < 0 {
+		return nil, fmt.Errorf("the number of hot keys tracked must be positive, got %d", c.HotKeysTopK)
+	}
+	if c.HotKeysHalfLife <= 0 {
+		return nil, errors.New("the hot keys half-life must be positive")
+	}
+	return hotkeys.NewDetector(c.HotKeysTopK, c.HotKeysHalfLife), nil
+}
+
+// zpagesSources returns the parts of the server whose live state is served by the zpages of the
+// metrics server. The task queues are those of the background workers recording audit events
+// and shipping check traces.
+func (c *Config) zpagesSources(ds datastore.Datastore, inFlight *inflightmw.Tracker, namespaceCache, dispatchCache, clusterDispatchCache cache.Cache, auditLogger *audit.Logger, checkTraces *checktraces.Shipper) zpages.Sources {
+	sources := zpages.Sources{
+		Hashring:    balancer.CurrentHashring,
+		InFlight:    inFlight,
+		Caches:      map[string]cache.Cache{"namespace": namespaceCache},
+		QueueDepths: map[string]func() int{},
+		Spread:      balancer.CurrentSpread,
+		Datastore:   ds,
+	}
+	if dispatchCache != nil {
+		sources.Caches["dispatch"] = dispatchCache
+	}
+	if clusterDispatchCache != nil {
+		sources.Caches["cluster_dispatch"] = clusterDispatchCache
+	}
+	if auditLogger != nil {
+		sources.QueueDepths["audit_events"] = auditLogger.QueueDepth
+	}
+	if checkTraces != nil {
+		sources.QueueDepths["check_traces"] = checkTraces.QueueDepth
+	}
+	return sources
+}
+
+// auditLogger creates the audit logger writing to the configured sinks, or returns nil if no
+// audit sink is configured.
+func (c *Config) auditLogger() (*audit.Logger, error) {
+	var sinks []audit.Sink
+	if c.AuditLogFile != "" {
+		sink, err := audit.NewFileSink(c.AuditLogFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize file audit sink: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if c.AuditLogSyslogAddress != "" {
+		sink, err := audit.NewSyslogSink(c.AuditLogSyslogAddress, defaultAuditSinkTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize syslog audit sink: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if len(c.AuditLogKafkaBrokers) > 0 {
+		sink, err := audit.NewKafkaSink(c.AuditLogKafkaBrokers, c.AuditLogKafkaTopic, defaultAuditSinkTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize kafka audit sink: %w", err)
+		}
+		sinks = append(sinks, sink)
+	}
+
+	if len(sinks) == 0 {
+		return nil, nil
+	}
+
+	if c.AuditLogSampleRate < 0 || c.AuditLogSampleRate > 1 {
+		return nil, fmt.Errorf("audit log sample rate must be between 0 and 1, got %v", c.AuditLogSampleRate)
+	}
+
+	redaction, err := audit.ParseRedaction(c.AuditLogRedact)
+	if err != nil {
+		return nil, err
+	}
+
+	return audit.NewLogger(audit.Config{
+		SampleRate: c.AuditLogSampleRate,
+		Redaction:  redaction,
+	}, sinks...), nil
+}
+
+// RunnableServer is a spicedb service set ready to run
+type RunnableServer interface {
+	Run(ctx context.Context) error
+	Middleware() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor)
+	SetMiddleware(unaryInterceptors []grpc.UnaryServerInterceptor, streamingInterceptors []grpc.StreamServerInterceptor) RunnableServer
+	GRPCDialContext(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error)
+	DispatchNetDialContext(ctx context.Context, s string) (net.Conn, error)
+}
+
+// completedServerConfig holds the full configuration to run a spicedb server,
+// but is assumed have already been validated via `Complete()` on Config.
+// It offers limited options for mutation before Run() starts the services.
+type completedServerConfig struct {
+	gRPCServer            util.RunnableGRPCServer
+	dispatchGRPCServer    util.RunnableGRPCServer
+	dispatchHealthServer  *grpcutil.AuthlessHealthServer
+	dispatchDrainDelay    time.Duration
+	gatewayServer         util.RunnableHTTPServer
+	metricsServer         util.RunnableHTTPServer
+	dashboardServer       util.RunnableHTTPServer
+	graphQLServer         util.RunnableHTTPServer
+	telemetryReporter     telemetry.Reporter
+	metricsExporter       *otlpmetrics.Exporter
+	profilingPusher       *profiling.Pusher
+	healthManager         health.Manager
+	tombstonePurger       *shared.TombstonePurger
+	purgeInterval         time.Duration
+	syntheticMaterializer *shared.SyntheticRelationMaterializer
+	syntheticInterval     time.Duration
+	permissionSets        *shared.PermissionSetMaterializer
+	changeStreamers       []*changestream.Streamer
+	deleteJobs            *shared.DeleteJobs
+	auditLogger           *audit.Logger
+	checkTraces           *checktraces.Shipper
+	hotKeys               *hotkeys.Detector
+	lagMonitor            *revisions.LagMonitor
+	accountant            *accountingmw.Accountant
+	usageExportFile       string
+	usageExportInterval   time.Duration
+	runtimeReloader       *runtimeReloader
+	tenants               *tenantmw.Registry
+	tenantRegistryPath    string
+
+	unaryMiddleware      []grpc.UnaryServerInterceptor
+	streamingMiddleware  []grpc.StreamServerInterceptor
+	presharedKeys        []string
+	presharedKeyValidity map[string]auth.PresharedKeyValidity
+	closeFunc            func() error
+}
+
+func (c *completedServerConfig) Middleware() ([]grpc.UnaryServerInterceptor, []grpc.StreamServerInterceptor) {
+	return c.unaryMiddleware, c.streamingMiddleware
+}
+
+func (c *completedServerConfig) SetMiddleware(unaryInterceptors []grpc.UnaryServerInterceptor, streamingInterceptors []grpc.StreamServerInterceptor) RunnableServer {
+	c.unaryMiddleware = unaryInterceptors
+	c.streamingMiddleware = streamingInterceptors
+	return c
+}
+
+func (c *completedServerConfig) GRPCDialContext(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	if len(c.presharedKeys) == 0 {
+		return c.gRPCServer.DialContext(ctx, opts...)
+	}
+	presharedKey, ok := auth.ValidPresharedKey(c.presharedKeys, c.presharedKeyValidity, time.Now())
+	if !ok {
+		presharedKey = c.presharedKeys[0]
+	}
+	if c.gRPCServer.Insecure() {
+		opts = append(opts, grpcutil.WithInsecureBearerToken(presharedKey))
+	} else {
+		opts = append(opts, grpcutil.WithBearerToken(presharedKey))
+	}
+	return c.gRPCServer.DialContext(ctx, opts...)
+}
+
+func (c *completedServerConfig) DispatchNetDialContext(ctx context.Context, s string) (net.Conn, error) {
+	return c.dispatchGRPCServer.NetDialContext(ctx, s)
+}
+
+func (c *completedServerConfig) Run(ctx context.Context) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	stopOnCancel := func(stopFn func()) func() error {
+		return func() error {
+			<-ctx.Done()
+			stopFn()
+			return nil
+		}
+	}
+
+	stopOnCancelWithErr := func(stopFn func() error) func() error {
+		return func() error {
+			<-ctx.Done()
+			return stopFn()
+		}
+	}
+
+	grpcServer := c.gRPCServer.WithOpts(grpc.ChainUnaryInterceptor(c.unaryMiddleware...), grpc.ChainStreamInterceptor(c.streamingMiddleware...))
+	g.Go(c.healthManager.Checker(ctx))
+	g.Go(grpcServer.Listen(ctx))
+	g.Go(stopOnCancel(grpcServer.GracefulStop))
+
+	dispatchDrained := make(chan struct{})
 	g.Go(c.dispatchGRPCServer.Listen(ctx))
-	g.Go(stopOnCancel(c.dispatchGRPCServer.GracefulStop))
+	g.Go(stopOnCancel(func() {
+		c.drainDispatch()
+		close(dispatchDrained)
+	}))
 
 	g.Go(c.gatewayServer.ListenAndServe)
 	g.Go(stopOnCancel(c.gatewayServer.Close))
@@ -469,9 +1891,131 @@ func (c *completedServerConfig) Run(ctx context.Context) error {
 	g.Go(c.dashboardServer.ListenAndServe)
 	g.Go(stopOnCancel(c.dashboardServer.Close))
 
+	g.Go(c.graphQLServer.ListenAndServe)
+	g.Go(stopOnCancel(c.graphQLServer.Close))
+
 	g.Go(func() error { return c.telemetryReporter(ctx) })
 
-	g.Go(stopOnCancelWithErr(c.closeFunc))
+	if c.metricsExporter != nil {
+		g.Go(func() error { return c.metricsExporter.Run(ctx) })
+	}
+
+	if c.profilingPusher != nil {
+		g.Go(func() error { return c.profilingPusher.Run(ctx) })
+	}
+
+	if c.tombstonePurger != nil {
+		g.Go(func() error {
+			if err := c.tombstonePurger.Start(ctx, c.purgeInterval); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.syntheticMaterializer != nil {
+		g.Go(func() error {
+			if err := c.syntheticMaterializer.Start(ctx, c.syntheticInterval); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.permissionSets != nil {
+		g.Go(func() error {
+			if err := c.permissionSets.Start(ctx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	g.Go(func() error {
+		if err := c.deleteJobs.Start(ctx); !errors.Is(err, context.Canceled) {
+			return err
+		}
+		return nil
+	})
+
+	for _, streamer := range c.changeStreamers {
+		streamer := streamer
+		g.Go(func() error {
+			if err := streamer.Start(ctx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.auditLogger != nil {
+		g.Go(func() error {
+			if err := c.auditLogger.Start(ctx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.accountant != nil && c.usageExportFile != "" {
+		g.Go(func() error {
+			if err := c.accountant.StartExport(ctx, c.usageExportFile, c.usageExportInterval); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.checkTraces != nil {
+		g.Go(func() error {
+			if err := c.checkTraces.Start(ctx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.hotKeys != nil {
+		g.Go(func() error {
+			if err := c.hotKeys.Start(ctx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.lagMonitor != nil {
+		g.Go(func() error {
+			if err := c.lagMonitor.Start(ctx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.runtimeReloader != nil {
+		g.Go(func() error {
+			if err := c.runtimeReloader.Start(ctx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	if c.tenantRegistryPath != "" {
+		g.Go(func() error {
+			if err := c.tenants.Start(ctx); !errors.Is(err, context.Canceled) {
+				return err
+			}
+			return nil
+		})
+	}
+
+	// The datastore and dispatchers are closed once the dispatches in flight have finished.
+	g.Go(stopOnCancelWithErr(func() error {
+		<-dispatchDrained
+		return c.closeFunc()
+	}))
 
 	if err := g.Wait(); err != nil {
 		log.Warn().Err(err).Msg("error shutting down server")
@@ -481,16 +2025,14 @@ func (c *completedServerConfig) Run(ctx context.Context) error {
 	return nil
 }
 
-var promOnce sync.Once
-
-// enableGRPCHistogram enables the standard time history for gRPC requests,
-// ensuring that it is only enabled once
-func enableGRPCHistogram() {
-	// EnableHandlingTimeHistogram is not thread safe and only needs to happen
-	// once
-	promOnce.Do(func() {
-		grpcprom.EnableHandlingTimeHistogram(grpcprom.WithHistogramBuckets(
-			[]float64{.006, .010, .018, .024, .032, .042, .056, .075, .100, .178, .316, .562, 1.000},
-		))
-	})
+// drainDispatch reports the dispatch service as not serving, so that the peers of the dispatch
+// cluster remove this node from their hashrings, waits for them to do so, and then stops the
+// dispatch server once the dispatches in flight have finished.
+func (c *completedServerConfig) drainDispatch() {
+	if c.dispatchHealthServer != nil {
+		log.Info().Dur("delay", c.dispatchDrainDelay).Msg("draining dispatch server")
+		c.dispatchHealthServer.SetServingStatus(dispatchv1.DispatchService_ServiceDesc.ServiceName, healthpb.HealthCheckResponse_NOT_SERVING)
+		time.Sleep(c.dispatchDrainDelay)
+	}
+	c.dispatchGRPCServer.GracefulStop()
 }
@@ -2,13 +2,20 @@ package server
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/cmd/util"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 
 	"github.com/stretchr/testify/require"
 	"go.uber.org/goleak"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestServerGracefulTermination(t *testing.T) {
@@ -29,3 +36,124 @@ func TestServerGracefulTermination(t *testing.T) {
 	cancel()
 	<-ch
 }
+
+func TestServerDrainsDispatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	ds, err := memdb.NewMemdbDatastore(0, 1*time.Second, 10*time.Second)
+	require.NoError(t, err)
+	c := ConfigWithOptions(&Config{},
+		WithPresharedKey("psk"),
+		WithDatastore(ds),
+		WithDispatchServer(util.GRPCServerConfig{Enabled: true, Network: util.BufferedNetwork}),
+		WithDispatchDrainDelay(100*time.Millisecond),
+	)
+	rs, err := c.Complete(ctx)
+	require.NoError(t, err)
+
+	ch := make(chan error, 1)
+	go func() {
+		ch <- rs.Run(ctx)
+	}()
+
+	conn, err := grpc.DialContext(context.Background(), util.BufferedNetwork,
+		grpc.WithContextDialer(rs.DispatchNetDialContext),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	watch, err := healthpb.NewHealthClient(conn).Watch(watchCtx, &healthpb.HealthCheckRequest{
+		Service: dispatchv1.DispatchService_ServiceDesc.ServiceName,
+	})
+	require.NoError(t, err)
+
+	resp, err := watch.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	// The dispatch service is reported as not serving before the dispatch server stops.
+	cancel()
+	resp, err = watch.Recv()
+	require.NoError(t, err)
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+
+	// Peers end their health checks when the dispatch server stops, which the graceful stop waits
+	// for.
+	cancelWatch()
+	require.NoError(t, <-ch)
+}
+
+func TestMetricsAuth(t *testing.T) {
+	c := ConfigWithOptions(&Config{}, WithPresharedKey("psk"), WithMetricsAuth("preshared-key"))
+	authenticate, err := c.metricsAuthenticator(nil)
+	require.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := authenticatedMetricsHandler(MetricsHandlerWithOptions(MetricsHandlerOptions{TelemetryRegistry: DisableTelemetryHandler, Health: ok}), ok, nil, authenticate)
+
+	for _, tc := range []struct {
+		path         string
+		token        string
+		expectedCode int
+	}{
+		{"/metrics", "", http.StatusUnauthorized},
+		{"/debug/pprof/", "", http.StatusUnauthorized},
+		{"/debug/pprof/", "psk", http.StatusOK},
+		{"/metrics", "psk", http.StatusOK},
+		{"/health", "", http.StatusOK},
+	} {
+		t.Run(tc.path+"/"+tc.token, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.path, nil)
+			if tc.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tc.token)
+			}
+
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, req)
+			require.Equal(t, tc.expectedCode, recorder.Code)
+		})
+	}
+
+	for _, invalid := range []*Config{
+		ConfigWithOptions(&Config{}, WithMetricsAuth("preshared-key")),
+		ConfigWithOptions(&Config{}, WithMetricsAuth("basic")),
+		ConfigWithOptions(&Config{}, WithMetricsAuth("magic")),
+		ConfigWithOptions(&Config{}, WithMetricsBasicAuthFile("htpasswd")),
+		ConfigWithOptions(&Config{}, WithMetricsRequireTLS(true)),
+	} {
+		_, err := invalid.metricsAuthenticator(nil)
+		require.Error(t, err)
+	}
+}
+
+func TestMetricsDebugEndpoints(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	for _, tc := range []struct {
+		name         string
+		handler      http.Handler
+		expectedCode int
+	}{
+		{"legacy", MetricsHandler(DisableTelemetryHandler), http.StatusNotFound},
+		{"disabled", MetricsHandlerWithOptions(MetricsHandlerOptions{Revisions: ok, Reload: ok}), http.StatusNotFound},
+		{"enabled", MetricsHandlerWithOptions(MetricsHandlerOptions{EnableDebugEndpoints: true, Revisions: ok, Reload: ok}), http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, path := range []string{"/debug/gc", "/debug/revisions", "/debug/reload"} {
+				recorder := httptest.NewRecorder()
+				tc.handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, path, nil))
+				require.Equal(t, tc.expectedCode, recorder.Code, path)
+			}
+
+			recorder := httptest.NewRecorder()
+			tc.handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/debug/pprof/", nil))
+			require.Equal(t, http.StatusOK, recorder.Code)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	tenantmw "github.com/authzed/spicedb/internal/middleware/tenant"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/schemautil"
+)
+
+// tenantsHandler serves the tenant management API of the metrics server:
+//
+//   - GET /tenants lists the names of the tenants.
+//   - POST /tenants with a `name` form value creates a tenant and returns its key, which is only
+//     returned once.
+//   - DELETE /tenants/<name> revokes the keys of a tenant and then deletes its definitions,
+//     caveats and relationships, so that it can be retried until the deletion succeeds.
+type tenantsHandler struct {
+	tenants *tenantmw.Registry
+	ds      datastore.Datastore
+}
+
+type createdTenant struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type deletedTenant struct {
+	Name               string `json:"name"`
+	DeletedDefinitions int    `json:"deletedDefinitions"`
+	DeletedCaveats     int    `json:"deletedCaveats"`
+}
+
+func (h tenantsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/tenants"), "/")
+	switch {
+	case name == "" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string][]string{"tenants": h.tenants.Tenants()})
+
+	case name == "" && r.Method == http.MethodPost:
+		name := r.FormValue("name")
+		if err := tenantmw.ValidateTenantName(name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		key, err := h.tenants.Create(name)
+		switch {
+		case errors.Is(err, tenantmw.ErrTenantExists):
+			http.Error(w, err.Error(), http.StatusConflict)
+		case err != nil:
+			log.Ctx(r.Context()).Error().Err(err).Str("tenant", name).Msg("failed to create tenant")
+			http.Error(w, "failed to create tenant", http.StatusInternalServerError)
+		default:
+			log.Ctx(r.Context()).Info().Str("tenant", name).Msg("created tenant")
+			writeJSON(w, http.StatusCreated, createdTenant{Name: name, Key: key})
+		}
+
+	case name != "" && r.Method == http.MethodDelete:
+		if err := tenantmw.ValidateTenantName(name); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The keys are revoked first, so that the tenant cannot write while its data is deleted.
+		if _, err := h.tenants.Delete(name); err != nil {
+			if errors.Is(err, tenantmw.ErrStaticTenant) {
+				http.Error(w, err.Error(), http.StatusConflict)
+				return
+			}
+			log.Ctx(r.Context()).Error().Err(err).Str("tenant", name).Msg("failed to delete tenant")
+			http.Error(w, "failed to delete tenant", http.StatusInternalServerError)
+			return
+		}
+
+		deletedDefinitions, deletedCaveats, err := schemautil.DeleteTenant(r.Context(), h.ds, name)
+		if err != nil {
+			log.Ctx(r.Context()).Error().Err(err).Str("tenant", name).Msg("failed to delete the data of tenant")
+			http.Error(w, "failed to delete the data of tenant", http.StatusInternalServerError)
+			return
+		}
+		log.Ctx(r.Context()).Info().Str("tenant", name).Msg("deleted tenant")
+		writeJSON(w, http.StatusOK, deletedTenant{Name: name, DeletedDefinitions: deletedDefinitions, DeletedCaveats: deletedCaveats})
+
+	case name == "":
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "tenants are listed with a GET request and created with a POST request", http.StatusMethodNotAllowed)
+
+	default:
+		w.Header().Set("Allow", http.MethodDelete)
+		http.Error(w, "tenants are deleted with a DELETE request", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, value any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(value)
+}
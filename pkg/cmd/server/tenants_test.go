@@ -0,0 +1,69 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	tenantmw "github.com/authzed/spicedb/internal/middleware/tenant"
+	"github.com/authzed/spicedb/internal/testfixtures"
+)
+
+func TestTenantsHandler(t *testing.T) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ds, _ := testfixtures.DatastoreFromSchemaAndTestRelationships(rawDS, "definition globex/user {}\n\ndefinition globex/document {\n\trelation viewer: globex/user\n}\n\ndefinition user {}", nil, require.New(t))
+
+	tenants, err := tenantmw.NewRegistry(map[string]string{"statickey": "acme"}, filepath.Join(t.TempDir(), "tenants.json"))
+	require.NoError(t, err)
+	handler := tenantsHandler{tenants: tenants, ds: ds}
+
+	serve := func(method, target string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := serve(http.MethodPost, "/tenants", url.Values{"name": {"globex"}})
+	require.Equal(t, http.StatusCreated, recorder.Code)
+	var created createdTenant
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	require.Equal(t, "globex", created.Name)
+	tenant, ok := tenants.TenantOfKey(created.Key)
+	require.True(t, ok)
+	require.Equal(t, "globex", tenant)
+
+	require.Equal(t, http.StatusConflict, serve(http.MethodPost, "/tenants", url.Values{"name": {"globex"}}).Code)
+	require.Equal(t, http.StatusBadRequest, serve(http.MethodPost, "/tenants", url.Values{"name": {"../etc"}}).Code)
+
+	recorder = serve(http.MethodGet, "/tenants", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"tenants":["acme","globex"]}`, recorder.Body.String())
+
+	require.Equal(t, http.StatusConflict, serve(http.MethodDelete, "/tenants/acme", nil).Code)
+	require.Equal(t, http.StatusMethodNotAllowed, serve(http.MethodGet, "/tenants/globex", nil).Code)
+
+	// Deleting a tenant revokes its keys and deletes its definitions, but not the others.
+	recorder = serve(http.MethodDelete, "/tenants/globex", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	require.JSONEq(t, `{"name":"globex","deletedDefinitions":2,"deletedCaveats":0}`, recorder.Body.String())
+	_, ok = tenants.TenantOfKey(created.Key)
+	require.False(t, ok)
+
+	headRevision, err := ds.HeadRevision(context.Background())
+	require.NoError(t, err)
+	nsDefs, err := ds.SnapshotReader(headRevision).ListNamespaces(context.Background())
+	require.NoError(t, err)
+	require.Len(t, nsDefs, 1)
+	require.Equal(t, "user", nsDefs[0].Name)
+}
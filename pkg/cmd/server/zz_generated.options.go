@@ -28,20 +28,47 @@ func (c *Config) ToOption() ConfigOption {
 		to.GRPCServer = c.GRPCServer
 		to.GRPCAuthFunc = c.GRPCAuthFunc
 		to.PresharedKey = c.PresharedKey
+		to.PresharedKeyValidity = c.PresharedKeyValidity
+		to.OIDCIssuer = c.OIDCIssuer
+		to.OIDCAudience = c.OIDCAudience
+		to.OIDCScopeClaim = c.OIDCScopeClaim
+		to.OIDCJWKSRefreshInterval = c.OIDCJWKSRefreshInterval
+		to.AuthFailureMaxAttempts = c.AuthFailureMaxAttempts
+		to.AuthFailureWindow = c.AuthFailureWindow
+		to.AuthFailureLockout = c.AuthFailureLockout
+		to.AuthFailureLockoutIPs = c.AuthFailureLockoutIPs
+		to.FIPSRequired = c.FIPSRequired
 		to.ShutdownGracePeriod = c.ShutdownGracePeriod
 		to.DisableVersionResponse = c.DisableVersionResponse
+		to.GRPCWebEnabled = c.GRPCWebEnabled
+		to.GRPCWebCorsAllowedOrigins = c.GRPCWebCorsAllowedOrigins
+		to.GRPCAllowedNetworks = c.GRPCAllowedNetworks
 		to.HTTPGateway = c.HTTPGateway
 		to.HTTPGatewayUpstreamAddr = c.HTTPGatewayUpstreamAddr
 		to.HTTPGatewayUpstreamTLSCertPath = c.HTTPGatewayUpstreamTLSCertPath
 		to.HTTPGatewayCorsEnabled = c.HTTPGatewayCorsEnabled
 		to.HTTPGatewayCorsAllowedOrigins = c.HTTPGatewayCorsAllowedOrigins
+		to.HTTPGatewayCorsAllowedHeaders = c.HTTPGatewayCorsAllowedHeaders
+		to.HTTPGatewayCorsExposedHeaders = c.HTTPGatewayCorsExposedHeaders
+		to.HTTPGatewayCorsMaxAge = c.HTTPGatewayCorsMaxAge
+		to.HTTPGatewayAuthCookie = c.HTTPGatewayAuthCookie
+		to.HTTPGatewaySigningKeys = c.HTTPGatewaySigningKeys
+		to.HTTPGatewaySigningMaxClockSkew = c.HTTPGatewaySigningMaxClockSkew
 		to.DatastoreConfig = c.DatastoreConfig
 		to.Datastore = c.Datastore
 		to.NamespaceCacheConfig = c.NamespaceCacheConfig
 		to.SchemaPrefixesRequired = c.SchemaPrefixesRequired
+		to.SchemaSoftDeleteDefinitions = c.SchemaSoftDeleteDefinitions
+		to.SchemaPurgeInterval = c.SchemaPurgeInterval
+		to.SchemaSyntheticRelationInterval = c.SchemaSyntheticRelationInterval
+		to.SchemaMaxSize = c.SchemaMaxSize
+		to.SchemaMaxRelationsPerDefinition = c.SchemaMaxRelationsPerDefinition
+		to.SchemaMaxUnionTermsPerPermission = c.SchemaMaxUnionTermsPerPermission
 		to.DispatchServer = c.DispatchServer
 		to.DispatchMaxDepth = c.DispatchMaxDepth
 		to.DispatchConcurrencyLimit = c.DispatchConcurrencyLimit
+		to.DispatchDrainDelay = c.DispatchDrainDelay
+		to.DispatchAllowedNetworks = c.DispatchAllowedNetworks
 		to.DispatchUpstreamAddr = c.DispatchUpstreamAddr
 		to.DispatchUpstreamCAPath = c.DispatchUpstreamCAPath
 		to.DispatchClientMetricsPrefix = c.DispatchClientMetricsPrefix
@@ -53,9 +80,92 @@ func (c *Config) ToOption() ConfigOption {
 		to.V1SchemaAdditiveOnly = c.V1SchemaAdditiveOnly
 		to.MaximumUpdatesPerWrite = c.MaximumUpdatesPerWrite
 		to.MaximumPreconditionCount = c.MaximumPreconditionCount
+		to.StreamingMaxResultsPerCall = c.StreamingMaxResultsPerCall
+		to.StreamingMaxBytesPerCall = c.StreamingMaxBytesPerCall
 		to.ExperimentalCaveatsEnabled = c.ExperimentalCaveatsEnabled
+		to.MaterializedPermissionSets = c.MaterializedPermissionSets
+		to.RejectDeprecatedRelations = c.RejectDeprecatedRelations
+		to.StrictRelationshipValidation = c.StrictRelationshipValidation
+		to.DeleteJobBatchSize = c.DeleteJobBatchSize
+		to.DeleteJobBatchDelay = c.DeleteJobBatchDelay
+		to.DefaultConsistency = c.DefaultConsistency
+		to.DefaultConsistencyByMethod = c.DefaultConsistencyByMethod
+		to.DefaultConsistencyByKey = c.DefaultConsistencyByKey
+		to.ReadYourWritesSessionTTL = c.ReadYourWritesSessionTTL
+		to.ReadYourWritesMaxSessions = c.ReadYourWritesMaxSessions
+		to.ReadYourWritesSessionHeader = c.ReadYourWritesSessionHeader
+		to.MaxReadStaleness = c.MaxReadStaleness
+		to.ReplicationLagInterval = c.ReplicationLagInterval
+		to.RelationshipQuotas = c.RelationshipQuotas
+		to.RelationshipQuotasByKey = c.RelationshipQuotasByKey
+		to.TenantsByKey = c.TenantsByKey
+		to.TenantRegistryPath = c.TenantRegistryPath
+		to.ScopesByKey = c.ScopesByKey
+		to.ScopesByClientIdentity = c.ScopesByClientIdentity
+		to.RateLimit = c.RateLimit
+		to.RateLimitByKey = c.RateLimitByKey
+		to.RateLimitByMethod = c.RateLimitByMethod
+		to.RateLimitByClientIP = c.RateLimitByClientIP
+		to.QuotaWindow = c.QuotaWindow
+		to.QuotaBudget = c.QuotaBudget
+		to.QuotaBudgetByKey = c.QuotaBudgetByKey
+		to.QuotaOverBudgetAction = c.QuotaOverBudgetAction
+		to.UsageAccountingEnabled = c.UsageAccountingEnabled
+		to.UsageExportFile = c.UsageExportFile
+		to.UsageExportInterval = c.UsageExportInterval
+		to.LoadSheddingCPUThreshold = c.LoadSheddingCPUThreshold
+		to.LoadSheddingMaxGoroutines = c.LoadSheddingMaxGoroutines
+		to.LoadSheddingMaxInFlight = c.LoadSheddingMaxInFlight
+		to.LoadSheddingRetryAfter = c.LoadSheddingRetryAfter
+		to.AdmissionLatencyThreshold = c.AdmissionLatencyThreshold
+		to.AdmissionErrorRateThreshold = c.AdmissionErrorRateThreshold
+		to.AdmissionExpensiveMethods = c.AdmissionExpensiveMethods
+		to.DisabledAPIMethods = c.DisabledAPIMethods
 		to.DashboardAPI = c.DashboardAPI
 		to.MetricsAPI = c.MetricsAPI
+		to.MetricsAllowedNetworks = c.MetricsAllowedNetworks
+		to.MetricsRequireTLS = c.MetricsRequireTLS
+		to.MetricsAuth = c.MetricsAuth
+		to.MetricsBasicAuthFile = c.MetricsBasicAuthFile
+		to.MetricsZPagesEnabled = c.MetricsZPagesEnabled
+		to.MetricsDebugEndpointsEnabled = c.MetricsDebugEndpointsEnabled
+		to.MetricsOTLPEndpoint = c.MetricsOTLPEndpoint
+		to.MetricsOTLPProtocol = c.MetricsOTLPProtocol
+		to.MetricsOTLPInsecure = c.MetricsOTLPInsecure
+		to.MetricsOTLPHeaders = c.MetricsOTLPHeaders
+		to.MetricsOTLPInterval = c.MetricsOTLPInterval
+		to.MetricsOTLPTemporality = c.MetricsOTLPTemporality
+		to.MetricsOTLPResourceAttributes = c.MetricsOTLPResourceAttributes
+		to.GraphQLAPI = c.GraphQLAPI
+		to.GraphQLPermissionsEnabled = c.GraphQLPermissionsEnabled
+		to.ChangeStreamCheckpointDir = c.ChangeStreamCheckpointDir
+		to.ChangeStreamKafkaBrokers = c.ChangeStreamKafkaBrokers
+		to.ChangeStreamKafkaTopic = c.ChangeStreamKafkaTopic
+		to.ChangeStreamKafkaPartitionBy = c.ChangeStreamKafkaPartitionBy
+		to.ChangeStreamNATSURLs = c.ChangeStreamNATSURLs
+		to.ChangeStreamNATSSubjectPrefix = c.ChangeStreamNATSSubjectPrefix
+		to.ChangeStreamNATSSubjects = c.ChangeStreamNATSSubjects
+		to.ChangeStreamNATSIncludeSchema = c.ChangeStreamNATSIncludeSchema
+		to.ChangeStreamWebhooksFile = c.ChangeStreamWebhooksFile
+		to.ChangeStreamWebhookAttempts = c.ChangeStreamWebhookAttempts
+		to.AuditLogFile = c.AuditLogFile
+		to.AuditLogSyslogAddress = c.AuditLogSyslogAddress
+		to.AuditLogKafkaBrokers = c.AuditLogKafkaBrokers
+		to.AuditLogKafkaTopic = c.AuditLogKafkaTopic
+		to.AuditLogSampleRate = c.AuditLogSampleRate
+		to.AuditLogRedact = c.AuditLogRedact
+		to.RequestLogSampleRate = c.RequestLogSampleRate
+		to.RequestLogSampleRateByMethod = c.RequestLogSampleRateByMethod
+		to.RequestLogRedact = c.RequestLogRedact
+		to.TelemetryRedactionMode = c.TelemetryRedactionMode
+		to.CheckTraceSampleRate = c.CheckTraceSampleRate
+		to.CheckTraceOTLPEndpoint = c.CheckTraceOTLPEndpoint
+		to.CheckTraceOTLPProtocol = c.CheckTraceOTLPProtocol
+		to.CheckTraceOTLPInsecure = c.CheckTraceOTLPInsecure
+		to.CheckTraceOTLPHeaders = c.CheckTraceOTLPHeaders
+		to.HotKeysTopK = c.HotKeysTopK
+		to.HotKeysHalfLife = c.HotKeysHalfLife
+		to.RuntimeConfigPath = c.RuntimeConfigPath
 		to.UnaryMiddleware = c.UnaryMiddleware
 		to.StreamingMiddleware = c.StreamingMiddleware
 		to.DispatchUnaryMiddleware = c.DispatchUnaryMiddleware
@@ -64,6 +174,11 @@ func (c *Config) ToOption() ConfigOption {
 		to.TelemetryCAOverridePath = c.TelemetryCAOverridePath
 		to.TelemetryEndpoint = c.TelemetryEndpoint
 		to.TelemetryInterval = c.TelemetryInterval
+		to.ProfilingPushEndpoint = c.ProfilingPushEndpoint
+		to.ProfilingPushApplicationName = c.ProfilingPushApplicationName
+		to.ProfilingPushInterval = c.ProfilingPushInterval
+		to.ProfilingPushHeaders = c.ProfilingPushHeaders
+		to.ProfilingPushLabels = c.ProfilingPushLabels
 	}
 }
 
@@ -103,6 +218,86 @@ func SetPresharedKey(presharedKey []string) ConfigOption {
 	}
 }
 
+// WithPresharedKeyValidity returns an option that can append PresharedKeyValiditys to Config.PresharedKeyValidity
+func WithPresharedKeyValidity(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.PresharedKeyValidity == nil {
+			c.PresharedKeyValidity = map[string]string{}
+		}
+		c.PresharedKeyValidity[key] = value
+	}
+}
+
+// SetPresharedKeyValidity returns an option that can set PresharedKeyValidity on a Config
+func SetPresharedKeyValidity(presharedKeyValidity map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.PresharedKeyValidity = presharedKeyValidity
+	}
+}
+
+// WithOIDCIssuer returns an option that can set OIDCIssuer on a Config
+func WithOIDCIssuer(oIDCIssuer string) ConfigOption {
+	return func(c *Config) {
+		c.OIDCIssuer = oIDCIssuer
+	}
+}
+
+// WithOIDCAudience returns an option that can set OIDCAudience on a Config
+func WithOIDCAudience(oIDCAudience string) ConfigOption {
+	return func(c *Config) {
+		c.OIDCAudience = oIDCAudience
+	}
+}
+
+// WithOIDCScopeClaim returns an option that can set OIDCScopeClaim on a Config
+func WithOIDCScopeClaim(oIDCScopeClaim string) ConfigOption {
+	return func(c *Config) {
+		c.OIDCScopeClaim = oIDCScopeClaim
+	}
+}
+
+// WithOIDCJWKSRefreshInterval returns an option that can set OIDCJWKSRefreshInterval on a Config
+func WithOIDCJWKSRefreshInterval(oIDCJWKSRefreshInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.OIDCJWKSRefreshInterval = oIDCJWKSRefreshInterval
+	}
+}
+
+// WithAuthFailureMaxAttempts returns an option that can set AuthFailureMaxAttempts on a Config
+func WithAuthFailureMaxAttempts(authFailureMaxAttempts int) ConfigOption {
+	return func(c *Config) {
+		c.AuthFailureMaxAttempts = authFailureMaxAttempts
+	}
+}
+
+// WithAuthFailureWindow returns an option that can set AuthFailureWindow on a Config
+func WithAuthFailureWindow(authFailureWindow time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.AuthFailureWindow = authFailureWindow
+	}
+}
+
+// WithAuthFailureLockout returns an option that can set AuthFailureLockout on a Config
+func WithAuthFailureLockout(authFailureLockout time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.AuthFailureLockout = authFailureLockout
+	}
+}
+
+// WithAuthFailureLockoutIPs returns an option that can set AuthFailureLockoutIPs on a Config
+func WithAuthFailureLockoutIPs(authFailureLockoutIPs bool) ConfigOption {
+	return func(c *Config) {
+		c.AuthFailureLockoutIPs = authFailureLockoutIPs
+	}
+}
+
+// WithFIPSRequired returns an option that can set FIPSRequired on a Config
+func WithFIPSRequired(fIPSRequired bool) ConfigOption {
+	return func(c *Config) {
+		c.FIPSRequired = fIPSRequired
+	}
+}
+
 // WithShutdownGracePeriod returns an option that can set ShutdownGracePeriod on a Config
 func WithShutdownGracePeriod(shutdownGracePeriod time.Duration) ConfigOption {
 	return func(c *Config) {
@@ -117,6 +312,41 @@ func WithDisableVersionResponse(disableVersionResponse bool) ConfigOption {
 	}
 }
 
+// WithGRPCWebEnabled returns an option that can set GRPCWebEnabled on a Config
+func WithGRPCWebEnabled(gRPCWebEnabled bool) ConfigOption {
+	return func(c *Config) {
+		c.GRPCWebEnabled = gRPCWebEnabled
+	}
+}
+
+// WithGRPCWebCorsAllowedOrigins returns an option that can append GRPCWebCorsAllowedOriginss to Config.GRPCWebCorsAllowedOrigins
+func WithGRPCWebCorsAllowedOrigins(gRPCWebCorsAllowedOrigins string) ConfigOption {
+	return func(c *Config) {
+		c.GRPCWebCorsAllowedOrigins = append(c.GRPCWebCorsAllowedOrigins, gRPCWebCorsAllowedOrigins)
+	}
+}
+
+// SetGRPCWebCorsAllowedOrigins returns an option that can set GRPCWebCorsAllowedOrigins on a Config
+func SetGRPCWebCorsAllowedOrigins(gRPCWebCorsAllowedOrigins []string) ConfigOption {
+	return func(c *Config) {
+		c.GRPCWebCorsAllowedOrigins = gRPCWebCorsAllowedOrigins
+	}
+}
+
+// WithGRPCAllowedNetworks returns an option that can append GRPCAllowedNetworkss to Config.GRPCAllowedNetworks
+func WithGRPCAllowedNetworks(gRPCAllowedNetworks string) ConfigOption {
+	return func(c *Config) {
+		c.GRPCAllowedNetworks = append(c.GRPCAllowedNetworks, gRPCAllowedNetworks)
+	}
+}
+
+// SetGRPCAllowedNetworks returns an option that can set GRPCAllowedNetworks on a Config
+func SetGRPCAllowedNetworks(gRPCAllowedNetworks []string) ConfigOption {
+	return func(c *Config) {
+		c.GRPCAllowedNetworks = gRPCAllowedNetworks
+	}
+}
+
 // WithHTTPGateway returns an option that can set HTTPGateway on a Config
 func WithHTTPGateway(hTTPGateway util.HTTPServerConfig) ConfigOption {
 	return func(c *Config) {
@@ -159,6 +389,72 @@ func SetHTTPGatewayCorsAllowedOrigins(hTTPGatewayCorsAllowedOrigins []string) Co
 	}
 }
 
+// WithHTTPGatewayCorsAllowedHeaders returns an option that can append HTTPGatewayCorsAllowedHeaderss to Config.HTTPGatewayCorsAllowedHeaders
+func WithHTTPGatewayCorsAllowedHeaders(hTTPGatewayCorsAllowedHeaders string) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewayCorsAllowedHeaders = append(c.HTTPGatewayCorsAllowedHeaders, hTTPGatewayCorsAllowedHeaders)
+	}
+}
+
+// SetHTTPGatewayCorsAllowedHeaders returns an option that can set HTTPGatewayCorsAllowedHeaders on a Config
+func SetHTTPGatewayCorsAllowedHeaders(hTTPGatewayCorsAllowedHeaders []string) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewayCorsAllowedHeaders = hTTPGatewayCorsAllowedHeaders
+	}
+}
+
+// WithHTTPGatewayCorsExposedHeaders returns an option that can append HTTPGatewayCorsExposedHeaderss to Config.HTTPGatewayCorsExposedHeaders
+func WithHTTPGatewayCorsExposedHeaders(hTTPGatewayCorsExposedHeaders string) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewayCorsExposedHeaders = append(c.HTTPGatewayCorsExposedHeaders, hTTPGatewayCorsExposedHeaders)
+	}
+}
+
+// SetHTTPGatewayCorsExposedHeaders returns an option that can set HTTPGatewayCorsExposedHeaders on a Config
+func SetHTTPGatewayCorsExposedHeaders(hTTPGatewayCorsExposedHeaders []string) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewayCorsExposedHeaders = hTTPGatewayCorsExposedHeaders
+	}
+}
+
+// WithHTTPGatewayCorsMaxAge returns an option that can set HTTPGatewayCorsMaxAge on a Config
+func WithHTTPGatewayCorsMaxAge(hTTPGatewayCorsMaxAge time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewayCorsMaxAge = hTTPGatewayCorsMaxAge
+	}
+}
+
+// WithHTTPGatewayAuthCookie returns an option that can set HTTPGatewayAuthCookie on a Config
+func WithHTTPGatewayAuthCookie(hTTPGatewayAuthCookie string) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewayAuthCookie = hTTPGatewayAuthCookie
+	}
+}
+
+// WithHTTPGatewaySigningKeys returns an option that can append HTTPGatewaySigningKeyss to Config.HTTPGatewaySigningKeys
+func WithHTTPGatewaySigningKeys(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.HTTPGatewaySigningKeys == nil {
+			c.HTTPGatewaySigningKeys = map[string]string{}
+		}
+		c.HTTPGatewaySigningKeys[key] = value
+	}
+}
+
+// SetHTTPGatewaySigningKeys returns an option that can set HTTPGatewaySigningKeys on a Config
+func SetHTTPGatewaySigningKeys(hTTPGatewaySigningKeys map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewaySigningKeys = hTTPGatewaySigningKeys
+	}
+}
+
+// WithHTTPGatewaySigningMaxClockSkew returns an option that can set HTTPGatewaySigningMaxClockSkew on a Config
+func WithHTTPGatewaySigningMaxClockSkew(hTTPGatewaySigningMaxClockSkew time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.HTTPGatewaySigningMaxClockSkew = hTTPGatewaySigningMaxClockSkew
+	}
+}
+
 // WithDatastoreConfig returns an option that can set DatastoreConfig on a Config
 func WithDatastoreConfig(datastoreConfig datastore.Config) ConfigOption {
 	return func(c *Config) {
@@ -187,6 +483,48 @@ func WithSchemaPrefixesRequired(schemaPrefixesRequired bool) ConfigOption {
 	}
 }
 
+// WithSchemaSoftDeleteDefinitions returns an option that can set SchemaSoftDeleteDefinitions on a Config
+func WithSchemaSoftDeleteDefinitions(schemaSoftDeleteDefinitions bool) ConfigOption {
+	return func(c *Config) {
+		c.SchemaSoftDeleteDefinitions = schemaSoftDeleteDefinitions
+	}
+}
+
+// WithSchemaPurgeInterval returns an option that can set SchemaPurgeInterval on a Config
+func WithSchemaPurgeInterval(schemaPurgeInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.SchemaPurgeInterval = schemaPurgeInterval
+	}
+}
+
+// WithSchemaSyntheticRelationInterval returns an option that can set SchemaSyntheticRelationInterval on a Config
+func WithSchemaSyntheticRelationInterval(schemaSyntheticRelationInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.SchemaSyntheticRelationInterval = schemaSyntheticRelationInterval
+	}
+}
+
+// WithSchemaMaxSize returns an option that can set SchemaMaxSize on a Config
+func WithSchemaMaxSize(schemaMaxSize uint32) ConfigOption {
+	return func(c *Config) {
+		c.SchemaMaxSize = schemaMaxSize
+	}
+}
+
+// WithSchemaMaxRelationsPerDefinition returns an option that can set SchemaMaxRelationsPerDefinition on a Config
+func WithSchemaMaxRelationsPerDefinition(schemaMaxRelationsPerDefinition uint32) ConfigOption {
+	return func(c *Config) {
+		c.SchemaMaxRelationsPerDefinition = schemaMaxRelationsPerDefinition
+	}
+}
+
+// WithSchemaMaxUnionTermsPerPermission returns an option that can set SchemaMaxUnionTermsPerPermission on a Config
+func WithSchemaMaxUnionTermsPerPermission(schemaMaxUnionTermsPerPermission uint32) ConfigOption {
+	return func(c *Config) {
+		c.SchemaMaxUnionTermsPerPermission = schemaMaxUnionTermsPerPermission
+	}
+}
+
 // WithDispatchServer returns an option that can set DispatchServer on a Config
 func WithDispatchServer(dispatchServer util.GRPCServerConfig) ConfigOption {
 	return func(c *Config) {
@@ -208,6 +546,27 @@ func WithDispatchConcurrencyLimit(dispatchConcurrencyLimit uint16) ConfigOption
 	}
 }
 
+// WithDispatchDrainDelay returns an option that can set DispatchDrainDelay on a Config
+func WithDispatchDrainDelay(dispatchDrainDelay time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.DispatchDrainDelay = dispatchDrainDelay
+	}
+}
+
+// WithDispatchAllowedNetworks returns an option that can append DispatchAllowedNetworkss to Config.DispatchAllowedNetworks
+func WithDispatchAllowedNetworks(dispatchAllowedNetworks string) ConfigOption {
+	return func(c *Config) {
+		c.DispatchAllowedNetworks = append(c.DispatchAllowedNetworks, dispatchAllowedNetworks)
+	}
+}
+
+// SetDispatchAllowedNetworks returns an option that can set DispatchAllowedNetworks on a Config
+func SetDispatchAllowedNetworks(dispatchAllowedNetworks []string) ConfigOption {
+	return func(c *Config) {
+		c.DispatchAllowedNetworks = dispatchAllowedNetworks
+	}
+}
+
 // WithDispatchUpstreamAddr returns an option that can set DispatchUpstreamAddr on a Config
 func WithDispatchUpstreamAddr(dispatchUpstreamAddr string) ConfigOption {
 	return func(c *Config) {
@@ -285,6 +644,20 @@ func WithMaximumPreconditionCount(maximumPreconditionCount uint16) ConfigOption
 	}
 }
 
+// WithStreamingMaxResultsPerCall returns an option that can set StreamingMaxResultsPerCall on a Config
+func WithStreamingMaxResultsPerCall(streamingMaxResultsPerCall uint64) ConfigOption {
+	return func(c *Config) {
+		c.StreamingMaxResultsPerCall = streamingMaxResultsPerCall
+	}
+}
+
+// WithStreamingMaxBytesPerCall returns an option that can set StreamingMaxBytesPerCall on a Config
+func WithStreamingMaxBytesPerCall(streamingMaxBytesPerCall string) ConfigOption {
+	return func(c *Config) {
+		c.StreamingMaxBytesPerCall = streamingMaxBytesPerCall
+	}
+}
+
 // WithExperimentalCaveatsEnabled returns an option that can set ExperimentalCaveatsEnabled on a Config
 func WithExperimentalCaveatsEnabled(experimentalCaveatsEnabled bool) ConfigOption {
 	return func(c *Config) {
@@ -292,100 +665,942 @@ func WithExperimentalCaveatsEnabled(experimentalCaveatsEnabled bool) ConfigOptio
 	}
 }
 
-// WithDashboardAPI returns an option that can set DashboardAPI on a Config
-func WithDashboardAPI(dashboardAPI util.HTTPServerConfig) ConfigOption {
+// WithMaterializedPermissionSets returns an option that can append MaterializedPermissionSetss to Config.MaterializedPermissionSets
+func WithMaterializedPermissionSets(materializedPermissionSets string) ConfigOption {
 	return func(c *Config) {
-		c.DashboardAPI = dashboardAPI
+		c.MaterializedPermissionSets = append(c.MaterializedPermissionSets, materializedPermissionSets)
 	}
 }
 
-// WithMetricsAPI returns an option that can set MetricsAPI on a Config
-func WithMetricsAPI(metricsAPI util.HTTPServerConfig) ConfigOption {
+// SetMaterializedPermissionSets returns an option that can set MaterializedPermissionSets on a Config
+func SetMaterializedPermissionSets(materializedPermissionSets []string) ConfigOption {
 	return func(c *Config) {
-		c.MetricsAPI = metricsAPI
+		c.MaterializedPermissionSets = materializedPermissionSets
 	}
 }
 
-// WithUnaryMiddleware returns an option that can append UnaryMiddlewares to Config.UnaryMiddleware
-func WithUnaryMiddleware(unaryMiddleware grpc.UnaryServerInterceptor) ConfigOption {
+// WithRejectDeprecatedRelations returns an option that can set RejectDeprecatedRelations on a Config
+func WithRejectDeprecatedRelations(rejectDeprecatedRelations bool) ConfigOption {
 	return func(c *Config) {
-		c.UnaryMiddleware = append(c.UnaryMiddleware, unaryMiddleware)
+		c.RejectDeprecatedRelations = rejectDeprecatedRelations
 	}
 }
 
-// SetUnaryMiddleware returns an option that can set UnaryMiddleware on a Config
-func SetUnaryMiddleware(unaryMiddleware []grpc.UnaryServerInterceptor) ConfigOption {
+// WithStrictRelationshipValidation returns an option that can append StrictRelationshipValidations to Config.StrictRelationshipValidation
+func WithStrictRelationshipValidation(strictRelationshipValidation string) ConfigOption {
 	return func(c *Config) {
-		c.UnaryMiddleware = unaryMiddleware
+		c.StrictRelationshipValidation = append(c.StrictRelationshipValidation, strictRelationshipValidation)
 	}
 }
 
-// WithStreamingMiddleware returns an option that can append StreamingMiddlewares to Config.StreamingMiddleware
-func WithStreamingMiddleware(streamingMiddleware grpc.StreamServerInterceptor) ConfigOption {
+// SetStrictRelationshipValidation returns an option that can set StrictRelationshipValidation on a Config
+func SetStrictRelationshipValidation(strictRelationshipValidation []string) ConfigOption {
 	return func(c *Config) {
-		c.StreamingMiddleware = append(c.StreamingMiddleware, streamingMiddleware)
+		c.StrictRelationshipValidation = strictRelationshipValidation
 	}
 }
 
-// SetStreamingMiddleware returns an option that can set StreamingMiddleware on a Config
-func SetStreamingMiddleware(streamingMiddleware []grpc.StreamServerInterceptor) ConfigOption {
+// WithDeleteJobBatchSize returns an option that can set DeleteJobBatchSize on a Config
+func WithDeleteJobBatchSize(deleteJobBatchSize uint64) ConfigOption {
 	return func(c *Config) {
-		c.StreamingMiddleware = streamingMiddleware
+		c.DeleteJobBatchSize = deleteJobBatchSize
 	}
 }
 
-// WithDispatchUnaryMiddleware returns an option that can append DispatchUnaryMiddlewares to Config.DispatchUnaryMiddleware
-func WithDispatchUnaryMiddleware(dispatchUnaryMiddleware grpc.UnaryServerInterceptor) ConfigOption {
+// WithDeleteJobBatchDelay returns an option that can set DeleteJobBatchDelay on a Config
+func WithDeleteJobBatchDelay(deleteJobBatchDelay time.Duration) ConfigOption {
 	return func(c *Config) {
-		c.DispatchUnaryMiddleware = append(c.DispatchUnaryMiddleware, dispatchUnaryMiddleware)
+		c.DeleteJobBatchDelay = deleteJobBatchDelay
 	}
 }
 
-// SetDispatchUnaryMiddleware returns an option that can set DispatchUnaryMiddleware on a Config
-func SetDispatchUnaryMiddleware(dispatchUnaryMiddleware []grpc.UnaryServerInterceptor) ConfigOption {
+// WithDefaultConsistency returns an option that can set DefaultConsistency on a Config
+func WithDefaultConsistency(defaultConsistency string) ConfigOption {
 	return func(c *Config) {
-		c.DispatchUnaryMiddleware = dispatchUnaryMiddleware
+		c.DefaultConsistency = defaultConsistency
 	}
 }
 
-// WithDispatchStreamingMiddleware returns an option that can append DispatchStreamingMiddlewares to Config.DispatchStreamingMiddleware
-func WithDispatchStreamingMiddleware(dispatchStreamingMiddleware grpc.StreamServerInterceptor) ConfigOption {
+// WithDefaultConsistencyByMethod returns an option that can append DefaultConsistencyByMethods to Config.DefaultConsistencyByMethod
+func WithDefaultConsistencyByMethod(key string, value string) ConfigOption {
 	return func(c *Config) {
-		c.DispatchStreamingMiddleware = append(c.DispatchStreamingMiddleware, dispatchStreamingMiddleware)
+		if c.DefaultConsistencyByMethod == nil {
+			c.DefaultConsistencyByMethod = map[string]string{}
+		}
+		c.DefaultConsistencyByMethod[key] = value
 	}
 }
 
-// SetDispatchStreamingMiddleware returns an option that can set DispatchStreamingMiddleware on a Config
-func SetDispatchStreamingMiddleware(dispatchStreamingMiddleware []grpc.StreamServerInterceptor) ConfigOption {
+// SetDefaultConsistencyByMethod returns an option that can set DefaultConsistencyByMethod on a Config
+func SetDefaultConsistencyByMethod(defaultConsistencyByMethod map[string]string) ConfigOption {
 	return func(c *Config) {
-		c.DispatchStreamingMiddleware = dispatchStreamingMiddleware
+		c.DefaultConsistencyByMethod = defaultConsistencyByMethod
 	}
 }
 
-// WithSilentlyDisableTelemetry returns an option that can set SilentlyDisableTelemetry on a Config
-func WithSilentlyDisableTelemetry(silentlyDisableTelemetry bool) ConfigOption {
+// WithDefaultConsistencyByKey returns an option that can append DefaultConsistencyByKeys to Config.DefaultConsistencyByKey
+func WithDefaultConsistencyByKey(key string, value string) ConfigOption {
 	return func(c *Config) {
-		c.SilentlyDisableTelemetry = silentlyDisableTelemetry
+		if c.DefaultConsistencyByKey == nil {
+			c.DefaultConsistencyByKey = map[string]string{}
+		}
+		c.DefaultConsistencyByKey[key] = value
 	}
 }
 
-// WithTelemetryCAOverridePath returns an option that can set TelemetryCAOverridePath on a Config
-func WithTelemetryCAOverridePath(telemetryCAOverridePath string) ConfigOption {
+// SetDefaultConsistencyByKey returns an option that can set DefaultConsistencyByKey on a Config
+func SetDefaultConsistencyByKey(defaultConsistencyByKey map[string]string) ConfigOption {
 	return func(c *Config) {
-		c.TelemetryCAOverridePath = telemetryCAOverridePath
+		c.DefaultConsistencyByKey = defaultConsistencyByKey
 	}
 }
 
-// WithTelemetryEndpoint returns an option that can set TelemetryEndpoint on a Config
-func WithTelemetryEndpoint(telemetryEndpoint string) ConfigOption {
+// WithReadYourWritesSessionTTL returns an option that can set ReadYourWritesSessionTTL on a Config
+func WithReadYourWritesSessionTTL(readYourWritesSessionTTL time.Duration) ConfigOption {
 	return func(c *Config) {
-		c.TelemetryEndpoint = telemetryEndpoint
+		c.ReadYourWritesSessionTTL = readYourWritesSessionTTL
 	}
 }
 
-// WithTelemetryInterval returns an option that can set TelemetryInterval on a Config
-func WithTelemetryInterval(telemetryInterval time.Duration) ConfigOption {
+// WithReadYourWritesMaxSessions returns an option that can set ReadYourWritesMaxSessions on a Config
+func WithReadYourWritesMaxSessions(readYourWritesMaxSessions int) ConfigOption {
 	return func(c *Config) {
-		c.TelemetryInterval = telemetryInterval
+		c.ReadYourWritesMaxSessions = readYourWritesMaxSessions
+	}
+}
+
+// WithReadYourWritesSessionHeader returns an option that can set ReadYourWritesSessionHeader on a Config
+func WithReadYourWritesSessionHeader(readYourWritesSessionHeader string) ConfigOption {
+	return func(c *Config) {
+		c.ReadYourWritesSessionHeader = readYourWritesSessionHeader
+	}
+}
+
+// WithMaxReadStaleness returns an option that can set MaxReadStaleness on a Config
+func WithMaxReadStaleness(maxReadStaleness time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.MaxReadStaleness = maxReadStaleness
+	}
+}
+
+// WithReplicationLagInterval returns an option that can set ReplicationLagInterval on a Config
+func WithReplicationLagInterval(replicationLagInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.ReplicationLagInterval = replicationLagInterval
+	}
+}
+
+// WithRelationshipQuotas returns an option that can append RelationshipQuotass to Config.RelationshipQuotas
+func WithRelationshipQuotas(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.RelationshipQuotas == nil {
+			c.RelationshipQuotas = map[string]string{}
+		}
+		c.RelationshipQuotas[key] = value
+	}
+}
+
+// SetRelationshipQuotas returns an option that can set RelationshipQuotas on a Config
+func SetRelationshipQuotas(relationshipQuotas map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.RelationshipQuotas = relationshipQuotas
+	}
+}
+
+// WithRelationshipQuotasByKey returns an option that can append RelationshipQuotasByKeys to Config.RelationshipQuotasByKey
+func WithRelationshipQuotasByKey(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.RelationshipQuotasByKey == nil {
+			c.RelationshipQuotasByKey = map[string]string{}
+		}
+		c.RelationshipQuotasByKey[key] = value
+	}
+}
+
+// SetRelationshipQuotasByKey returns an option that can set RelationshipQuotasByKey on a Config
+func SetRelationshipQuotasByKey(relationshipQuotasByKey map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.RelationshipQuotasByKey = relationshipQuotasByKey
+	}
+}
+
+// WithTenantsByKey returns an option that can append TenantsByKeys to Config.TenantsByKey
+func WithTenantsByKey(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.TenantsByKey == nil {
+			c.TenantsByKey = map[string]string{}
+		}
+		c.TenantsByKey[key] = value
+	}
+}
+
+// SetTenantsByKey returns an option that can set TenantsByKey on a Config
+func SetTenantsByKey(tenantsByKey map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.TenantsByKey = tenantsByKey
+	}
+}
+
+// WithTenantRegistryPath returns an option that can set TenantRegistryPath on a Config
+func WithTenantRegistryPath(tenantRegistryPath string) ConfigOption {
+	return func(c *Config) {
+		c.TenantRegistryPath = tenantRegistryPath
+	}
+}
+
+// WithScopesByKey returns an option that can append ScopesByKeys to Config.ScopesByKey
+func WithScopesByKey(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.ScopesByKey == nil {
+			c.ScopesByKey = map[string]string{}
+		}
+		c.ScopesByKey[key] = value
+	}
+}
+
+// SetScopesByKey returns an option that can set ScopesByKey on a Config
+func SetScopesByKey(scopesByKey map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.ScopesByKey = scopesByKey
+	}
+}
+
+// WithScopesByClientIdentity returns an option that can append ScopesByClientIdentitys to Config.ScopesByClientIdentity
+func WithScopesByClientIdentity(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.ScopesByClientIdentity == nil {
+			c.ScopesByClientIdentity = map[string]string{}
+		}
+		c.ScopesByClientIdentity[key] = value
+	}
+}
+
+// SetScopesByClientIdentity returns an option that can set ScopesByClientIdentity on a Config
+func SetScopesByClientIdentity(scopesByClientIdentity map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.ScopesByClientIdentity = scopesByClientIdentity
+	}
+}
+
+// WithRateLimit returns an option that can set RateLimit on a Config
+func WithRateLimit(rateLimit string) ConfigOption {
+	return func(c *Config) {
+		c.RateLimit = rateLimit
+	}
+}
+
+// WithRateLimitByKey returns an option that can append RateLimitByKeys to Config.RateLimitByKey
+func WithRateLimitByKey(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.RateLimitByKey == nil {
+			c.RateLimitByKey = map[string]string{}
+		}
+		c.RateLimitByKey[key] = value
+	}
+}
+
+// SetRateLimitByKey returns an option that can set RateLimitByKey on a Config
+func SetRateLimitByKey(rateLimitByKey map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.RateLimitByKey = rateLimitByKey
+	}
+}
+
+// WithRateLimitByMethod returns an option that can append RateLimitByMethods to Config.RateLimitByMethod
+func WithRateLimitByMethod(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.RateLimitByMethod == nil {
+			c.RateLimitByMethod = map[string]string{}
+		}
+		c.RateLimitByMethod[key] = value
+	}
+}
+
+// SetRateLimitByMethod returns an option that can set RateLimitByMethod on a Config
+func SetRateLimitByMethod(rateLimitByMethod map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.RateLimitByMethod = rateLimitByMethod
+	}
+}
+
+// WithRateLimitByClientIP returns an option that can set RateLimitByClientIP on a Config
+func WithRateLimitByClientIP(rateLimitByClientIP string) ConfigOption {
+	return func(c *Config) {
+		c.RateLimitByClientIP = rateLimitByClientIP
+	}
+}
+
+// WithQuotaWindow returns an option that can set QuotaWindow on a Config
+func WithQuotaWindow(quotaWindow time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.QuotaWindow = quotaWindow
+	}
+}
+
+// WithQuotaBudget returns an option that can set QuotaBudget on a Config
+func WithQuotaBudget(quotaBudget string) ConfigOption {
+	return func(c *Config) {
+		c.QuotaBudget = quotaBudget
+	}
+}
+
+// WithQuotaBudgetByKey returns an option that can append QuotaBudgetByKeys to Config.QuotaBudgetByKey
+func WithQuotaBudgetByKey(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.QuotaBudgetByKey == nil {
+			c.QuotaBudgetByKey = map[string]string{}
+		}
+		c.QuotaBudgetByKey[key] = value
+	}
+}
+
+// SetQuotaBudgetByKey returns an option that can set QuotaBudgetByKey on a Config
+func SetQuotaBudgetByKey(quotaBudgetByKey map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.QuotaBudgetByKey = quotaBudgetByKey
+	}
+}
+
+// WithQuotaOverBudgetAction returns an option that can set QuotaOverBudgetAction on a Config
+func WithQuotaOverBudgetAction(quotaOverBudgetAction string) ConfigOption {
+	return func(c *Config) {
+		c.QuotaOverBudgetAction = quotaOverBudgetAction
+	}
+}
+
+// WithUsageAccountingEnabled returns an option that can set UsageAccountingEnabled on a Config
+func WithUsageAccountingEnabled(usageAccountingEnabled bool) ConfigOption {
+	return func(c *Config) {
+		c.UsageAccountingEnabled = usageAccountingEnabled
+	}
+}
+
+// WithUsageExportFile returns an option that can set UsageExportFile on a Config
+func WithUsageExportFile(usageExportFile string) ConfigOption {
+	return func(c *Config) {
+		c.UsageExportFile = usageExportFile
+	}
+}
+
+// WithUsageExportInterval returns an option that can set UsageExportInterval on a Config
+func WithUsageExportInterval(usageExportInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.UsageExportInterval = usageExportInterval
+	}
+}
+
+// WithLoadSheddingCPUThreshold returns an option that can set LoadSheddingCPUThreshold on a Config
+func WithLoadSheddingCPUThreshold(loadSheddingCPUThreshold float64) ConfigOption {
+	return func(c *Config) {
+		c.LoadSheddingCPUThreshold = loadSheddingCPUThreshold
+	}
+}
+
+// WithLoadSheddingMaxGoroutines returns an option that can set LoadSheddingMaxGoroutines on a Config
+func WithLoadSheddingMaxGoroutines(loadSheddingMaxGoroutines int) ConfigOption {
+	return func(c *Config) {
+		c.LoadSheddingMaxGoroutines = loadSheddingMaxGoroutines
+	}
+}
+
+// WithLoadSheddingMaxInFlight returns an option that can set LoadSheddingMaxInFlight on a Config
+func WithLoadSheddingMaxInFlight(loadSheddingMaxInFlight int) ConfigOption {
+	return func(c *Config) {
+		c.LoadSheddingMaxInFlight = loadSheddingMaxInFlight
+	}
+}
+
+// WithLoadSheddingRetryAfter returns an option that can set LoadSheddingRetryAfter on a Config
+func WithLoadSheddingRetryAfter(loadSheddingRetryAfter time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.LoadSheddingRetryAfter = loadSheddingRetryAfter
+	}
+}
+
+// WithAdmissionLatencyThreshold returns an option that can set AdmissionLatencyThreshold on a Config
+func WithAdmissionLatencyThreshold(admissionLatencyThreshold time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionLatencyThreshold = admissionLatencyThreshold
+	}
+}
+
+// WithAdmissionErrorRateThreshold returns an option that can set AdmissionErrorRateThreshold on a Config
+func WithAdmissionErrorRateThreshold(admissionErrorRateThreshold float64) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionErrorRateThreshold = admissionErrorRateThreshold
+	}
+}
+
+// WithAdmissionExpensiveMethods returns an option that can append AdmissionExpensiveMethodss to Config.AdmissionExpensiveMethods
+func WithAdmissionExpensiveMethods(admissionExpensiveMethods string) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionExpensiveMethods = append(c.AdmissionExpensiveMethods, admissionExpensiveMethods)
+	}
+}
+
+// SetAdmissionExpensiveMethods returns an option that can set AdmissionExpensiveMethods on a Config
+func SetAdmissionExpensiveMethods(admissionExpensiveMethods []string) ConfigOption {
+	return func(c *Config) {
+		c.AdmissionExpensiveMethods = admissionExpensiveMethods
+	}
+}
+
+// WithDisabledAPIMethods returns an option that can append DisabledAPIMethodss to Config.DisabledAPIMethods
+func WithDisabledAPIMethods(disabledAPIMethods string) ConfigOption {
+	return func(c *Config) {
+		c.DisabledAPIMethods = append(c.DisabledAPIMethods, disabledAPIMethods)
+	}
+}
+
+// SetDisabledAPIMethods returns an option that can set DisabledAPIMethods on a Config
+func SetDisabledAPIMethods(disabledAPIMethods []string) ConfigOption {
+	return func(c *Config) {
+		c.DisabledAPIMethods = disabledAPIMethods
+	}
+}
+
+// WithDashboardAPI returns an option that can set DashboardAPI on a Config
+func WithDashboardAPI(dashboardAPI util.HTTPServerConfig) ConfigOption {
+	return func(c *Config) {
+		c.DashboardAPI = dashboardAPI
+	}
+}
+
+// WithMetricsAPI returns an option that can set MetricsAPI on a Config
+func WithMetricsAPI(metricsAPI util.HTTPServerConfig) ConfigOption {
+	return func(c *Config) {
+		c.MetricsAPI = metricsAPI
+	}
+}
+
+// WithMetricsAllowedNetworks returns an option that can append MetricsAllowedNetworkss to Config.MetricsAllowedNetworks
+func WithMetricsAllowedNetworks(metricsAllowedNetworks string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsAllowedNetworks = append(c.MetricsAllowedNetworks, metricsAllowedNetworks)
+	}
+}
+
+// SetMetricsAllowedNetworks returns an option that can set MetricsAllowedNetworks on a Config
+func SetMetricsAllowedNetworks(metricsAllowedNetworks []string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsAllowedNetworks = metricsAllowedNetworks
+	}
+}
+
+// WithMetricsRequireTLS returns an option that can set MetricsRequireTLS on a Config
+func WithMetricsRequireTLS(metricsRequireTLS bool) ConfigOption {
+	return func(c *Config) {
+		c.MetricsRequireTLS = metricsRequireTLS
+	}
+}
+
+// WithMetricsAuth returns an option that can set MetricsAuth on a Config
+func WithMetricsAuth(metricsAuth string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsAuth = metricsAuth
+	}
+}
+
+// WithMetricsBasicAuthFile returns an option that can set MetricsBasicAuthFile on a Config
+func WithMetricsBasicAuthFile(metricsBasicAuthFile string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsBasicAuthFile = metricsBasicAuthFile
+	}
+}
+
+// WithMetricsZPagesEnabled returns an option that can set MetricsZPagesEnabled on a Config
+func WithMetricsZPagesEnabled(metricsZPagesEnabled bool) ConfigOption {
+	return func(c *Config) {
+		c.MetricsZPagesEnabled = metricsZPagesEnabled
+	}
+}
+
+// WithMetricsDebugEndpointsEnabled returns an option that can set MetricsDebugEndpointsEnabled on a Config
+func WithMetricsDebugEndpointsEnabled(metricsDebugEndpointsEnabled bool) ConfigOption {
+	return func(c *Config) {
+		c.MetricsDebugEndpointsEnabled = metricsDebugEndpointsEnabled
+	}
+}
+
+// WithMetricsOTLPEndpoint returns an option that can set MetricsOTLPEndpoint on a Config
+func WithMetricsOTLPEndpoint(metricsOTLPEndpoint string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsOTLPEndpoint = metricsOTLPEndpoint
+	}
+}
+
+// WithMetricsOTLPProtocol returns an option that can set MetricsOTLPProtocol on a Config
+func WithMetricsOTLPProtocol(metricsOTLPProtocol string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsOTLPProtocol = metricsOTLPProtocol
+	}
+}
+
+// WithMetricsOTLPInsecure returns an option that can set MetricsOTLPInsecure on a Config
+func WithMetricsOTLPInsecure(metricsOTLPInsecure bool) ConfigOption {
+	return func(c *Config) {
+		c.MetricsOTLPInsecure = metricsOTLPInsecure
+	}
+}
+
+// WithMetricsOTLPHeaders returns an option that can append MetricsOTLPHeaderss to Config.MetricsOTLPHeaders
+func WithMetricsOTLPHeaders(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.MetricsOTLPHeaders == nil {
+			c.MetricsOTLPHeaders = map[string]string{}
+		}
+		c.MetricsOTLPHeaders[key] = value
+	}
+}
+
+// SetMetricsOTLPHeaders returns an option that can set MetricsOTLPHeaders on a Config
+func SetMetricsOTLPHeaders(metricsOTLPHeaders map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsOTLPHeaders = metricsOTLPHeaders
+	}
+}
+
+// WithMetricsOTLPInterval returns an option that can set MetricsOTLPInterval on a Config
+func WithMetricsOTLPInterval(metricsOTLPInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.MetricsOTLPInterval = metricsOTLPInterval
+	}
+}
+
+// WithMetricsOTLPTemporality returns an option that can set MetricsOTLPTemporality on a Config
+func WithMetricsOTLPTemporality(metricsOTLPTemporality string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsOTLPTemporality = metricsOTLPTemporality
+	}
+}
+
+// WithMetricsOTLPResourceAttributes returns an option that can append MetricsOTLPResourceAttributess to Config.MetricsOTLPResourceAttributes
+func WithMetricsOTLPResourceAttributes(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.MetricsOTLPResourceAttributes == nil {
+			c.MetricsOTLPResourceAttributes = map[string]string{}
+		}
+		c.MetricsOTLPResourceAttributes[key] = value
+	}
+}
+
+// SetMetricsOTLPResourceAttributes returns an option that can set MetricsOTLPResourceAttributes on a Config
+func SetMetricsOTLPResourceAttributes(metricsOTLPResourceAttributes map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.MetricsOTLPResourceAttributes = metricsOTLPResourceAttributes
+	}
+}
+
+// WithGraphQLAPI returns an option that can set GraphQLAPI on a Config
+func WithGraphQLAPI(graphQLAPI util.HTTPServerConfig) ConfigOption {
+	return func(c *Config) {
+		c.GraphQLAPI = graphQLAPI
+	}
+}
+
+// WithGraphQLPermissionsEnabled returns an option that can set GraphQLPermissionsEnabled on a Config
+func WithGraphQLPermissionsEnabled(graphQLPermissionsEnabled bool) ConfigOption {
+	return func(c *Config) {
+		c.GraphQLPermissionsEnabled = graphQLPermissionsEnabled
+	}
+}
+
+// WithChangeStreamCheckpointDir returns an option that can set ChangeStreamCheckpointDir on a Config
+func WithChangeStreamCheckpointDir(changeStreamCheckpointDir string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamCheckpointDir = changeStreamCheckpointDir
+	}
+}
+
+// WithChangeStreamKafkaBrokers returns an option that can append ChangeStreamKafkaBrokerss to Config.ChangeStreamKafkaBrokers
+func WithChangeStreamKafkaBrokers(changeStreamKafkaBrokers string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamKafkaBrokers = append(c.ChangeStreamKafkaBrokers, changeStreamKafkaBrokers)
+	}
+}
+
+// SetChangeStreamKafkaBrokers returns an option that can set ChangeStreamKafkaBrokers on a Config
+func SetChangeStreamKafkaBrokers(changeStreamKafkaBrokers []string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamKafkaBrokers = changeStreamKafkaBrokers
+	}
+}
+
+// WithChangeStreamKafkaTopic returns an option that can set ChangeStreamKafkaTopic on a Config
+func WithChangeStreamKafkaTopic(changeStreamKafkaTopic string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamKafkaTopic = changeStreamKafkaTopic
+	}
+}
+
+// WithChangeStreamKafkaPartitionBy returns an option that can set ChangeStreamKafkaPartitionBy on a Config
+func WithChangeStreamKafkaPartitionBy(changeStreamKafkaPartitionBy string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamKafkaPartitionBy = changeStreamKafkaPartitionBy
+	}
+}
+
+// WithChangeStreamNATSURLs returns an option that can append ChangeStreamNATSURLss to Config.ChangeStreamNATSURLs
+func WithChangeStreamNATSURLs(changeStreamNATSURLs string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamNATSURLs = append(c.ChangeStreamNATSURLs, changeStreamNATSURLs)
+	}
+}
+
+// SetChangeStreamNATSURLs returns an option that can set ChangeStreamNATSURLs on a Config
+func SetChangeStreamNATSURLs(changeStreamNATSURLs []string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamNATSURLs = changeStreamNATSURLs
+	}
+}
+
+// WithChangeStreamNATSSubjectPrefix returns an option that can set ChangeStreamNATSSubjectPrefix on a Config
+func WithChangeStreamNATSSubjectPrefix(changeStreamNATSSubjectPrefix string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamNATSSubjectPrefix = changeStreamNATSSubjectPrefix
+	}
+}
+
+// WithChangeStreamNATSSubjects returns an option that can append ChangeStreamNATSSubjectss to Config.ChangeStreamNATSSubjects
+func WithChangeStreamNATSSubjects(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.ChangeStreamNATSSubjects == nil {
+			c.ChangeStreamNATSSubjects = map[string]string{}
+		}
+		c.ChangeStreamNATSSubjects[key] = value
+	}
+}
+
+// SetChangeStreamNATSSubjects returns an option that can set ChangeStreamNATSSubjects on a Config
+func SetChangeStreamNATSSubjects(changeStreamNATSSubjects map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamNATSSubjects = changeStreamNATSSubjects
+	}
+}
+
+// WithChangeStreamNATSIncludeSchema returns an option that can set ChangeStreamNATSIncludeSchema on a Config
+func WithChangeStreamNATSIncludeSchema(changeStreamNATSIncludeSchema bool) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamNATSIncludeSchema = changeStreamNATSIncludeSchema
+	}
+}
+
+// WithChangeStreamWebhooksFile returns an option that can set ChangeStreamWebhooksFile on a Config
+func WithChangeStreamWebhooksFile(changeStreamWebhooksFile string) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamWebhooksFile = changeStreamWebhooksFile
+	}
+}
+
+// WithChangeStreamWebhookAttempts returns an option that can set ChangeStreamWebhookAttempts on a Config
+func WithChangeStreamWebhookAttempts(changeStreamWebhookAttempts int) ConfigOption {
+	return func(c *Config) {
+		c.ChangeStreamWebhookAttempts = changeStreamWebhookAttempts
+	}
+}
+
+// WithAuditLogFile returns an option that can set AuditLogFile on a Config
+func WithAuditLogFile(auditLogFile string) ConfigOption {
+	return func(c *Config) {
+		c.AuditLogFile = auditLogFile
+	}
+}
+
+// WithAuditLogSyslogAddress returns an option that can set AuditLogSyslogAddress on a Config
+func WithAuditLogSyslogAddress(auditLogSyslogAddress string) ConfigOption {
+	return func(c *Config) {
+		c.AuditLogSyslogAddress = auditLogSyslogAddress
+	}
+}
+
+// WithAuditLogKafkaBrokers returns an option that can append AuditLogKafkaBrokerss to Config.AuditLogKafkaBrokers
+func WithAuditLogKafkaBrokers(auditLogKafkaBrokers string) ConfigOption {
+	return func(c *Config) {
+		c.AuditLogKafkaBrokers = append(c.AuditLogKafkaBrokers, auditLogKafkaBrokers)
+	}
+}
+
+// SetAuditLogKafkaBrokers returns an option that can set AuditLogKafkaBrokers on a Config
+func SetAuditLogKafkaBrokers(auditLogKafkaBrokers []string) ConfigOption {
+	return func(c *Config) {
+		c.AuditLogKafkaBrokers = auditLogKafkaBrokers
+	}
+}
+
+// WithAuditLogKafkaTopic returns an option that can set AuditLogKafkaTopic on a Config
+func WithAuditLogKafkaTopic(auditLogKafkaTopic string) ConfigOption {
+	return func(c *Config) {
+		c.AuditLogKafkaTopic = auditLogKafkaTopic
+	}
+}
+
+// WithAuditLogSampleRate returns an option that can set AuditLogSampleRate on a Config
+func WithAuditLogSampleRate(auditLogSampleRate float64) ConfigOption {
+	return func(c *Config) {
+		c.AuditLogSampleRate = auditLogSampleRate
+	}
+}
+
+// WithAuditLogRedact returns an option that can append AuditLogRedacts to Config.AuditLogRedact
+func WithAuditLogRedact(auditLogRedact string) ConfigOption {
+	return func(c *Config) {
+		c.AuditLogRedact = append(c.AuditLogRedact, auditLogRedact)
+	}
+}
+
+// SetAuditLogRedact returns an option that can set AuditLogRedact on a Config
+func SetAuditLogRedact(auditLogRedact []string) ConfigOption {
+	return func(c *Config) {
+		c.AuditLogRedact = auditLogRedact
+	}
+}
+
+// WithRequestLogSampleRate returns an option that can set RequestLogSampleRate on a Config
+func WithRequestLogSampleRate(requestLogSampleRate float64) ConfigOption {
+	return func(c *Config) {
+		c.RequestLogSampleRate = requestLogSampleRate
+	}
+}
+
+// WithRequestLogSampleRateByMethod returns an option that can append RequestLogSampleRateByMethods to Config.RequestLogSampleRateByMethod
+func WithRequestLogSampleRateByMethod(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.RequestLogSampleRateByMethod == nil {
+			c.RequestLogSampleRateByMethod = map[string]string{}
+		}
+		c.RequestLogSampleRateByMethod[key] = value
+	}
+}
+
+// SetRequestLogSampleRateByMethod returns an option that can set RequestLogSampleRateByMethod on a Config
+func SetRequestLogSampleRateByMethod(requestLogSampleRateByMethod map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.RequestLogSampleRateByMethod = requestLogSampleRateByMethod
+	}
+}
+
+// WithRequestLogRedact returns an option that can append RequestLogRedacts to Config.RequestLogRedact
+func WithRequestLogRedact(requestLogRedact string) ConfigOption {
+	return func(c *Config) {
+		c.RequestLogRedact = append(c.RequestLogRedact, requestLogRedact)
+	}
+}
+
+// SetRequestLogRedact returns an option that can set RequestLogRedact on a Config
+func SetRequestLogRedact(requestLogRedact []string) ConfigOption {
+	return func(c *Config) {
+		c.RequestLogRedact = requestLogRedact
+	}
+}
+
+// WithTelemetryRedactionMode returns an option that can set TelemetryRedactionMode on a Config
+func WithTelemetryRedactionMode(telemetryRedactionMode string) ConfigOption {
+	return func(c *Config) {
+		c.TelemetryRedactionMode = telemetryRedactionMode
+	}
+}
+
+// WithCheckTraceSampleRate returns an option that can set CheckTraceSampleRate on a Config
+func WithCheckTraceSampleRate(checkTraceSampleRate float64) ConfigOption {
+	return func(c *Config) {
+		c.CheckTraceSampleRate = checkTraceSampleRate
+	}
+}
+
+// WithCheckTraceOTLPEndpoint returns an option that can set CheckTraceOTLPEndpoint on a Config
+func WithCheckTraceOTLPEndpoint(checkTraceOTLPEndpoint string) ConfigOption {
+	return func(c *Config) {
+		c.CheckTraceOTLPEndpoint = checkTraceOTLPEndpoint
+	}
+}
+
+// WithCheckTraceOTLPProtocol returns an option that can set CheckTraceOTLPProtocol on a Config
+func WithCheckTraceOTLPProtocol(checkTraceOTLPProtocol string) ConfigOption {
+	return func(c *Config) {
+		c.CheckTraceOTLPProtocol = checkTraceOTLPProtocol
+	}
+}
+
+// WithCheckTraceOTLPInsecure returns an option that can set CheckTraceOTLPInsecure on a Config
+func WithCheckTraceOTLPInsecure(checkTraceOTLPInsecure bool) ConfigOption {
+	return func(c *Config) {
+		c.CheckTraceOTLPInsecure = checkTraceOTLPInsecure
+	}
+}
+
+// WithCheckTraceOTLPHeaders returns an option that can append CheckTraceOTLPHeaderss to Config.CheckTraceOTLPHeaders
+func WithCheckTraceOTLPHeaders(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.CheckTraceOTLPHeaders == nil {
+			c.CheckTraceOTLPHeaders = map[string]string{}
+		}
+		c.CheckTraceOTLPHeaders[key] = value
+	}
+}
+
+// SetCheckTraceOTLPHeaders returns an option that can set CheckTraceOTLPHeaders on a Config
+func SetCheckTraceOTLPHeaders(checkTraceOTLPHeaders map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.CheckTraceOTLPHeaders = checkTraceOTLPHeaders
+	}
+}
+
+// WithHotKeysTopK returns an option that can set HotKeysTopK on a Config
+func WithHotKeysTopK(hotKeysTopK int) ConfigOption {
+	return func(c *Config) {
+		c.HotKeysTopK = hotKeysTopK
+	}
+}
+
+// WithHotKeysHalfLife returns an option that can set HotKeysHalfLife on a Config
+func WithHotKeysHalfLife(hotKeysHalfLife time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.HotKeysHalfLife = hotKeysHalfLife
+	}
+}
+
+// WithRuntimeConfigPath returns an option that can set RuntimeConfigPath on a Config
+func WithRuntimeConfigPath(runtimeConfigPath string) ConfigOption {
+	return func(c *Config) {
+		c.RuntimeConfigPath = runtimeConfigPath
+	}
+}
+
+// WithUnaryMiddleware returns an option that can append UnaryMiddlewares to Config.UnaryMiddleware
+func WithUnaryMiddleware(unaryMiddleware grpc.UnaryServerInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.UnaryMiddleware = append(c.UnaryMiddleware, unaryMiddleware)
+	}
+}
+
+// SetUnaryMiddleware returns an option that can set UnaryMiddleware on a Config
+func SetUnaryMiddleware(unaryMiddleware []grpc.UnaryServerInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.UnaryMiddleware = unaryMiddleware
+	}
+}
+
+// WithStreamingMiddleware returns an option that can append StreamingMiddlewares to Config.StreamingMiddleware
+func WithStreamingMiddleware(streamingMiddleware grpc.StreamServerInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.StreamingMiddleware = append(c.StreamingMiddleware, streamingMiddleware)
+	}
+}
+
+// SetStreamingMiddleware returns an option that can set StreamingMiddleware on a Config
+func SetStreamingMiddleware(streamingMiddleware []grpc.StreamServerInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.StreamingMiddleware = streamingMiddleware
+	}
+}
+
+// WithDispatchUnaryMiddleware returns an option that can append DispatchUnaryMiddlewares to Config.DispatchUnaryMiddleware
+func WithDispatchUnaryMiddleware(dispatchUnaryMiddleware grpc.UnaryServerInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.DispatchUnaryMiddleware = append(c.DispatchUnaryMiddleware, dispatchUnaryMiddleware)
+	}
+}
+
+// SetDispatchUnaryMiddleware returns an option that can set DispatchUnaryMiddleware on a Config
+func SetDispatchUnaryMiddleware(dispatchUnaryMiddleware []grpc.UnaryServerInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.DispatchUnaryMiddleware = dispatchUnaryMiddleware
+	}
+}
+
+// WithDispatchStreamingMiddleware returns an option that can append DispatchStreamingMiddlewares to Config.DispatchStreamingMiddleware
+func WithDispatchStreamingMiddleware(dispatchStreamingMiddleware grpc.StreamServerInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.DispatchStreamingMiddleware = append(c.DispatchStreamingMiddleware, dispatchStreamingMiddleware)
+	}
+}
+
+// SetDispatchStreamingMiddleware returns an option that can set DispatchStreamingMiddleware on a Config
+func SetDispatchStreamingMiddleware(dispatchStreamingMiddleware []grpc.StreamServerInterceptor) ConfigOption {
+	return func(c *Config) {
+		c.DispatchStreamingMiddleware = dispatchStreamingMiddleware
+	}
+}
+
+// WithSilentlyDisableTelemetry returns an option that can set SilentlyDisableTelemetry on a Config
+func WithSilentlyDisableTelemetry(silentlyDisableTelemetry bool) ConfigOption {
+	return func(c *Config) {
+		c.SilentlyDisableTelemetry = silentlyDisableTelemetry
+	}
+}
+
+// WithTelemetryCAOverridePath returns an option that can set TelemetryCAOverridePath on a Config
+func WithTelemetryCAOverridePath(telemetryCAOverridePath string) ConfigOption {
+	return func(c *Config) {
+		c.TelemetryCAOverridePath = telemetryCAOverridePath
+	}
+}
+
+// WithTelemetryEndpoint returns an option that can set TelemetryEndpoint on a Config
+func WithTelemetryEndpoint(telemetryEndpoint string) ConfigOption {
+	return func(c *Config) {
+		c.TelemetryEndpoint = telemetryEndpoint
+	}
+}
+
+// WithTelemetryInterval returns an option that can set TelemetryInterval on a Config
+func WithTelemetryInterval(telemetryInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.TelemetryInterval = telemetryInterval
+	}
+}
+
+// WithProfilingPushEndpoint returns an option that can set ProfilingPushEndpoint on a Config
+func WithProfilingPushEndpoint(profilingPushEndpoint string) ConfigOption {
+	return func(c *Config) {
+		c.ProfilingPushEndpoint = profilingPushEndpoint
+	}
+}
+
+// WithProfilingPushApplicationName returns an option that can set ProfilingPushApplicationName on a Config
+func WithProfilingPushApplicationName(profilingPushApplicationName string) ConfigOption {
+	return func(c *Config) {
+		c.ProfilingPushApplicationName = profilingPushApplicationName
+	}
+}
+
+// WithProfilingPushInterval returns an option that can set ProfilingPushInterval on a Config
+func WithProfilingPushInterval(profilingPushInterval time.Duration) ConfigOption {
+	return func(c *Config) {
+		c.ProfilingPushInterval = profilingPushInterval
+	}
+}
+
+// WithProfilingPushHeaders returns an option that can append ProfilingPushHeaderss to Config.ProfilingPushHeaders
+func WithProfilingPushHeaders(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.ProfilingPushHeaders == nil {
+			c.ProfilingPushHeaders = map[string]string{}
+		}
+		c.ProfilingPushHeaders[key] = value
+	}
+}
+
+// SetProfilingPushHeaders returns an option that can set ProfilingPushHeaders on a Config
+func SetProfilingPushHeaders(profilingPushHeaders map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.ProfilingPushHeaders = profilingPushHeaders
+	}
+}
+
+// WithProfilingPushLabels returns an option that can append ProfilingPushLabelss to Config.ProfilingPushLabels
+func WithProfilingPushLabels(key string, value string) ConfigOption {
+	return func(c *Config) {
+		if c.ProfilingPushLabels == nil {
+			c.ProfilingPushLabels = map[string]string{}
+		}
+		c.ProfilingPushLabels[key] = value
+	}
+}
+
+// SetProfilingPushLabels returns an option that can set ProfilingPushLabels on a Config
+func SetProfilingPushLabels(profilingPushLabels map[string]string) ConfigOption {
+	return func(c *Config) {
+		c.ProfilingPushLabels = profilingPushLabels
 	}
 }
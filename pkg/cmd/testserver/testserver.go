@@ -18,6 +18,7 @@ import (
 	"github.com/authzed/spicedb/internal/middleware/servicespecific"
 	"github.com/authzed/spicedb/internal/services"
 	"github.com/authzed/spicedb/internal/services/health"
+	"github.com/authzed/spicedb/internal/services/shared"
 	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	"github.com/authzed/spicedb/pkg/cmd/util"
 )
@@ -62,11 +63,13 @@ func (c *Config) Complete() (RunnableTestServer, error) {
 			services.V1SchemaServiceEnabled,
 			services.WatchServiceEnabled,
 			services.CaveatsEnabled,
+			shared.SchemaLimits{},
 			v1svc.PermissionsServerConfig{
 				MaxPreconditionsCount: c.MaximumPreconditionCount,
 				MaxUpdatesPerWrite:    c.MaximumUpdatesPerWrite,
 				MaximumAPIDepth:       maxDepth,
 			},
+			nil,
 		)
 	}
 	gRPCSrv, err := c.GRPCServer.Complete(zerolog.InfoLevel, registerServices,
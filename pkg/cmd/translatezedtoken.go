@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/pkg/cmd/datastore"
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/zedtoken"
+)
+
+func RegisterTranslateZedTokenFlags(cmd *cobra.Command, config *datastore.Config) {
+	datastore.RegisterDatastoreFlags(cmd, config)
+}
+
+func NewTranslateZedTokenCommand(programName string, config *datastore.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "translate-zedtoken <zedtoken>",
+		Short: "validates a zedtoken against a datastore and translates it into a portable zedtoken",
+		Long: "Validates that a zedtoken, minted by any deployment sharing the datastore, can be used against the datastore, " +
+			"and prints it as a portable zedtoken recording the unique ID of the datastore, which deployments of other datastores reject. " +
+			"Fails if the zedtoken was minted for another datastore or if its revision can no longer be read",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ds, err := datastore.NewDatastore(cmd.Context(), config.ToOption())
+			if err != nil {
+				return fmt.Errorf("failed to create datastore: %w", err)
+			}
+			defer ds.Close()
+
+			translated, revision, err := zedtoken.Translate(cmd.Context(), &v1.ZedToken{Token: args[0]}, ds)
+			if err != nil {
+				return fmt.Errorf("invalid zedtoken: %w", err)
+			}
+
+			fmt.Fprintf(cmd.OutOrStdout(), "%s\nrevision: %s\n", translated.Token, revision)
+			return nil
+		},
+		Args: cobra.ExactArgs(1),
+	}
+}
@@ -0,0 +1,97 @@
+package util
+
+import (
+	"net"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+var rejectedConnsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "grpc_server",
+	Name:      "rejected_connections_total",
+	Help:      "The connections closed as soon as they were accepted because their client had reached its maximum number of connections, by server.",
+}, []string{"server"})
+
+// perClientLimitListener is a listener which closes the connections accepted from a client, by
+// IP address, which already has the maximum number of connections open.
+type perClientLimitListener struct {
+	net.Listener
+	server  string
+	maxConn int
+
+	mu    sync.Mutex
+	conns map[string]int
+}
+
+func newPerClientLimitListener(l net.Listener, server string, maxConns int) net.Listener {
+	return &perClientLimitListener{
+		Listener: l,
+		server:   server,
+		maxConn:  maxConns,
+		conns:    make(map[string]int),
+	}
+}
+
+func (l *perClientLimitListener) Accept() (net.Conn, error) {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			return nil, err
+		}
+
+		tcpAddr, ok := conn.RemoteAddr().(*net.TCPAddr)
+		if !ok {
+			return conn, nil
+		}
+
+		client := tcpAddr.IP.String()
+		if !l.acquire(client) {
+			rejectedConnsCounter.WithLabelValues(l.server).Inc()
+			log.Debug().Str("server", l.server).Str("client", client).Int("maxConnsPerClient", l.maxConn).Msg("closed connection from client with too many connections")
+			_ = conn.Close()
+			continue
+		}
+
+		return &perClientLimitConn{Conn: conn, release: func() { l.release(client) }}, nil
+	}
+}
+
+func (l *perClientLimitListener) acquire(client string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conns[client] >= l.maxConn {
+		return false
+	}
+	l.conns[client]++
+	return true
+}
+
+func (l *perClientLimitListener) release(client string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.conns[client]--
+	if l.conns[client] <= 0 {
+		delete(l.conns, client)
+	}
+}
+
+// perClientLimitConn is a connection counted towards the maximum of its client until it is
+// closed.
+type perClientLimitConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *perClientLimitConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,66 @@
+package util
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPerClientLimitListener(t *testing.T) {
+	require := require.New(t)
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	l := newPerClientLimitListener(inner, "test", 1)
+	t.Cleanup(func() { _ = l.Close() })
+
+	accepted := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	first, err := net.Dial("tcp", inner.Addr().String())
+	require.NoError(err)
+	firstServer := <-accepted
+
+	// The second connection from the same client is closed by the listener.
+	second, err := net.Dial("tcp", inner.Addr().String())
+	require.NoError(err)
+	require.NoError(second.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	_, err = second.Read(make([]byte, 1))
+	require.ErrorIs(err, io.EOF)
+
+	// Once the first connection is closed, the client can connect again.
+	require.NoError(first.Close())
+	require.NoError(firstServer.Close())
+	_ = firstServer.Close()
+	require.Zero(l.(*perClientLimitListener).clients(), "closing a connection twice releases it once")
+
+	third, err := net.Dial("tcp", inner.Addr().String())
+	require.NoError(err)
+	defer third.Close()
+
+	select {
+	case conn := <-accepted:
+		require.NoError(conn.Close())
+	case <-time.After(5 * time.Second):
+		require.Fail("the connection was not accepted after the first was closed")
+	}
+}
+
+// clients returns the number of clients holding open connections.
+func (l *perClientLimitListener) clients() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.conns)
+}
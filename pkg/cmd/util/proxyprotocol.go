@@ -0,0 +1,183 @@
+package util
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/authzed/spicedb/internal/clientaddr"
+	log "github.com/authzed/spicedb/internal/logging"
+)
+
+// proxyProtocolHeaderTimeout is how long a connection may take to send its PROXY protocol header
+// before it is closed.
+const proxyProtocolHeaderTimeout = 10 * time.Second
+
+var rejectedProxyProtocolConnsCounter = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "proxy_protocol",
+	Name:      "rejected_connections_total",
+	Help:      "The connections closed because they did not start with a valid PROXY protocol header, by server.",
+}, []string{"server"})
+
+// proxyProtocolListener is a listener whose connections start with a PROXY protocol header, of
+// version 1 or 2, sent by the load balancer in front of the server, and whose remote and local
+// addresses are those of the original connection of the client to the load balancer.
+//
+// The headers are read as soon as the connections are accepted, by a goroutine per connection,
+// so that the connections are returned by Accept with their original addresses and a client slow
+// to send its header does not hold up the others.
+type proxyProtocolListener struct {
+	net.Listener
+	server  string
+	trusted clientaddr.Networks
+	timeout time.Duration
+
+	conns     chan net.Conn
+	errs      chan error
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// validateProxyProtocol returns an error if the PROXY protocol is enabled without any trusted
+// network, as any client could then claim any address.
+func validateProxyProtocol(enabled bool, trustedCIDRs []string, flagPrefix string) error {
+	if enabled && len(trustedCIDRs) == 0 {
+		return fmt.Errorf("--%s-proxy-protocol requires --%s-proxy-protocol-trusted-networks", flagPrefix, flagPrefix)
+	}
+	return nil
+}
+
+// newProxyProtocolListener returns a listener reading the PROXY protocol header of the
+// connections accepted from the trusted networks, closing those which do not send it within the
+// timeout. The connections from other networks are used as is.
+func newProxyProtocolListener(l net.Listener, server string, trustedCIDRs []string, timeout time.Duration) (net.Listener, error) {
+	if len(trustedCIDRs) == 0 {
+		return nil, errors.New("at least one PROXY protocol trusted network is required")
+	}
+
+	trusted, err := clientaddr.ParseNetworks(trustedCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid PROXY protocol trusted network: %w", err)
+	}
+
+	pl := &proxyProtocolListener{
+		Listener: l,
+		server:   server,
+		trusted:  trusted,
+		timeout:  timeout,
+		conns:    make(chan net.Conn),
+		errs:     make(chan error),
+		done:     make(chan struct{}),
+	}
+	go pl.acceptLoop()
+	return pl, nil
+}
+
+func (l *proxyProtocolListener) acceptLoop() {
+	for {
+		conn, err := l.Listener.Accept()
+		if err != nil {
+			select {
+			case l.errs <- err:
+			case <-l.done:
+				return
+			}
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			continue
+		}
+		go l.handshake(conn)
+	}
+}
+
+// handshake reads the header of the connection, if it is from a trusted network, and hands the
+// connection to Accept, or closes it if its header is invalid.
+func (l *proxyProtocolListener) handshake(conn net.Conn) {
+	if l.trusted.Contains(conn.RemoteAddr()) {
+		proxied, err := readProxyProtocolHeader(conn, l.timeout)
+		if err != nil {
+			rejectedProxyProtocolConnsCounter.WithLabelValues(l.server).Inc()
+			log.Debug().Err(err).Str("server", l.server).Str("peer", conn.RemoteAddr().String()).Msg("closed connection without a valid PROXY protocol header")
+			_ = conn.Close()
+			return
+		}
+		conn = proxied
+	}
+
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
+
+func (l *proxyProtocolListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case err := <-l.errs:
+		return nil, err
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+func (l *proxyProtocolListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+// proxyProtocolConn is a connection whose addresses are those given by its PROXY protocol header.
+type proxyProtocolConn struct {
+	net.Conn
+	reader *bufio.Reader
+	remote net.Addr
+	local  net.Addr
+}
+
+func (c *proxyProtocolConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *proxyProtocolConn) RemoteAddr() net.Addr {
+	return c.remote
+}
+
+func (c *proxyProtocolConn) LocalAddr() net.Addr {
+	return c.local
+}
+
+// readProxyProtocolHeader reads the PROXY protocol header, of version 1 or 2, at the start of the
+// connection, and returns the connection with the TCP addresses of the header. Connections whose
+// header carries no such address, such as the health checks of the load balancer, keep their
+// own addresses.
+func readProxyProtocolHeader(conn net.Conn, timeout time.Duration) (net.Conn, error) {
+	if err := conn.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	header, err := proxyproto.Read(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PROXY protocol header: %w", err)
+	}
+
+	if err := conn.SetReadDeadline(time.Time{}); err != nil {
+		return nil, err
+	}
+
+	proxied := &proxyProtocolConn{Conn: conn, reader: reader, remote: conn.RemoteAddr(), local: conn.LocalAddr()}
+	if source, destination, ok := header.TCPAddrs(); ok && header.Command.IsProxy() {
+		proxied.remote, proxied.local = source, destination
+	}
+	return proxied, nil
+}
@@ -0,0 +1,166 @@
+package util
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/pires/go-proxyproto"
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadProxyProtocolHeader(t *testing.T) {
+	v2Header := func(command proxyproto.ProtocolVersionAndCommand, source, destination net.Addr) []byte {
+		header := proxyproto.HeaderProxyFromAddrs(2, source, destination)
+		header.Command = command
+		formatted, err := header.Format()
+		require.NoError(t, err)
+		return formatted
+	}
+
+	client := &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 41202}
+	server := &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 443}
+
+	testCases := []struct {
+		name                string
+		header              []byte
+		expectedSource      string
+		expectedDestination string
+		expectedErr         string
+	}{
+		{"v1 TCP4", []byte("PROXY TCP4 203.0.113.7 10.0.0.1 41202 443\r\n"), "203.0.113.7:41202", "10.0.0.1:443", ""},
+		{"v1 TCP6", []byte("PROXY TCP6 2001:db8::7 2001:db8::1 41202 443\r\n"), "[2001:db8::7]:41202", "[2001:db8::1]:443", ""},
+		{"v1 UNKNOWN", []byte("PROXY UNKNOWN\r\n"), "", "", ""},
+		{"v1 invalid address", []byte("PROXY TCP4 somehost 10.0.0.1 41202 443\r\n"), "", "", "failed to read PROXY protocol header"},
+		{"v2 TCP", v2Header(proxyproto.PROXY, client, server), "203.0.113.7:41202", "10.0.0.1:443", ""},
+		{"v2 LOCAL", v2Header(proxyproto.LOCAL, client, server), "", "", ""},
+		{"no header", []byte("GET / HTTP/1.1\r\n"), "", "", "failed to read PROXY protocol header"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			clientConn, serverConn := net.Pipe()
+			defer clientConn.Close()
+			defer serverConn.Close()
+
+			go func() {
+				_, _ = clientConn.Write(append(append([]byte{}, tc.header...), "payload"...))
+			}()
+
+			conn, err := readProxyProtocolHeader(serverConn, time.Second)
+			if tc.expectedErr != "" {
+				require.ErrorContains(t, err, tc.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+
+			if tc.expectedSource == "" {
+				require.Equal(t, serverConn.RemoteAddr(), conn.RemoteAddr())
+				require.Equal(t, serverConn.LocalAddr(), conn.LocalAddr())
+			} else {
+				require.Equal(t, tc.expectedSource, conn.RemoteAddr().String())
+				require.Equal(t, tc.expectedDestination, conn.LocalAddr().String())
+			}
+
+			read := make([]byte, len("payload"))
+			_, err = io.ReadFull(conn, read)
+			require.NoError(t, err)
+			require.Equal(t, "payload", string(read))
+		})
+	}
+}
+
+func TestProxyProtocolListener(t *testing.T) {
+	require := require.New(t)
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	l, err := newProxyProtocolListener(inner, "test", []string{"127.0.0.0/8"}, 500*time.Millisecond)
+	require.NoError(err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	accepted := make(chan net.Conn)
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			accepted <- conn
+		}
+	}()
+
+	// A client which never sends its header does not hold up the others, and is closed.
+	slow, err := net.Dial("tcp", inner.Addr().String())
+	require.NoError(err)
+	defer slow.Close()
+
+	client, err := net.Dial("tcp", inner.Addr().String())
+	require.NoError(err)
+	defer client.Close()
+	_, err = client.Write([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 41202 443\r\nhello"))
+	require.NoError(err)
+
+	conn := <-accepted
+	defer conn.Close()
+	require.Equal("203.0.113.7:41202", conn.RemoteAddr().String())
+	require.Equal("10.0.0.1:443", conn.LocalAddr().String())
+
+	read := make([]byte, 5)
+	_, err = io.ReadFull(conn, read)
+	require.NoError(err)
+	require.Equal("hello", string(read))
+
+	require.NoError(slow.SetReadDeadline(time.Now().Add(5 * time.Second)))
+	_, err = slow.Read(make([]byte, 1))
+	require.ErrorIs(err, io.EOF)
+
+	require.NoError(l.Close())
+	_, ok := <-accepted
+	require.False(ok)
+}
+
+func TestProxyProtocolListenerUntrustedNetwork(t *testing.T) {
+	require := require.New(t)
+
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(err)
+	l, err := newProxyProtocolListener(inner, "test", []string{"10.0.0.0/8"}, proxyProtocolHeaderTimeout)
+	require.NoError(err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	// Connections from untrusted networks keep their own address, and their data is untouched.
+	client, err := net.Dial("tcp", inner.Addr().String())
+	require.NoError(err)
+	defer client.Close()
+	_, err = client.Write([]byte("PROXY TCP4 203.0.113.7 10.0.0.1 41202 443\r\n"))
+	require.NoError(err)
+
+	conn, err := l.Accept()
+	require.NoError(err)
+	defer conn.Close()
+	require.Equal(client.LocalAddr().String(), conn.RemoteAddr().String())
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(err)
+	require.Equal("PROXY TCP4 203.0.113.7 10.0.0.1 41202 443\r\n", line)
+
+	_, err = newProxyProtocolListener(inner, "test", []string{"notanetwork"}, proxyProtocolHeaderTimeout)
+	require.ErrorContains(err, "invalid PROXY protocol trusted network")
+
+	_, err = newProxyProtocolListener(inner, "test", nil, proxyProtocolHeaderTimeout)
+	require.ErrorContains(err, "at least one PROXY protocol trusted network is required")
+}
+
+func TestProxyProtocolRequiresTrustedNetworks(t *testing.T) {
+	_, err := (&GRPCServerConfig{Enabled: true, ProxyProtocol: true, flagPrefix: "grpc"}).Complete(zerolog.InfoLevel, nil)
+	require.EqualError(t, err, "--grpc-proxy-protocol requires --grpc-proxy-protocol-trusted-networks")
+
+	_, err = (&HTTPServerConfig{Enabled: true, ProxyProtocol: true, flagPrefix: "http"}).Complete(zerolog.InfoLevel, nil)
+	require.EqualError(t, err, "--http-proxy-protocol requires --http-proxy-protocol-trusted-networks")
+}
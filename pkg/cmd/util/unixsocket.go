@@ -0,0 +1,75 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"time"
+)
+
+// isUnixNetwork returns whether the network is that of Unix domain sockets.
+func isUnixNetwork(network string) bool {
+	return network == "unix" || network == "unixpacket"
+}
+
+// listen listens on the address of the network. Unix domain sockets are created with the given
+// permissions, in octal, if any, replacing the socket left at their path by a server which is no
+// longer running.
+func listen(network, address, socketMode string) (net.Listener, error) {
+	if !isUnixNetwork(network) {
+		if socketMode != "" {
+			return nil, fmt.Errorf("socket permissions require a Unix domain socket, not the %s network", network)
+		}
+		return net.Listen(network, address)
+	}
+
+	var mode fs.FileMode
+	if socketMode != "" {
+		parsed, err := strconv.ParseUint(socketMode, 8, 32)
+		if err != nil || parsed > uint64(fs.ModePerm) {
+			return nil, fmt.Errorf("invalid socket permissions %q: expected octal permissions such as 0660", socketMode)
+		}
+		mode = fs.FileMode(parsed)
+	}
+
+	if err := removeStaleSocket(network, address); err != nil {
+		return nil, err
+	}
+
+	l, err := net.Listen(network, address)
+	if err != nil {
+		return nil, err
+	}
+	if socketMode != "" {
+		if err := os.Chmod(address, mode); err != nil {
+			_ = l.Close()
+			return nil, fmt.Errorf("failed to set the permissions of socket %s: %w", address, err)
+		}
+	}
+	return l, nil
+}
+
+// removeStaleSocket removes the socket at the path if no server is listening on it anymore, such
+// as after the server was killed. Files which are not sockets are left untouched.
+func removeStaleSocket(network, path string) error {
+	info, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("cannot listen on %s: file exists and is not a socket", path)
+	}
+
+	conn, err := net.DialTimeout(network, path, time.Second)
+	if err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("cannot listen on %s: socket is in use by another server", path)
+	}
+	return os.Remove(path)
+}
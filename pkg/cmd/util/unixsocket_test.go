@@ -0,0 +1,90 @@
+package util
+
+import (
+	"context"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// socketDir returns a temporary directory whose path is short enough for Unix domain sockets.
+func socketDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "spicedb")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	return dir
+}
+
+func TestListenUnixSocket(t *testing.T) {
+	require := require.New(t)
+	path := filepath.Join(socketDir(t), "api.sock")
+
+	l, err := listen("unix", path, "0600")
+	require.NoError(err)
+	info, err := os.Stat(path)
+	require.NoError(err)
+	require.Equal(os.FileMode(0o600), info.Mode().Perm())
+
+	// A socket in use by another server is not replaced.
+	_, err = listen("unix", path, "")
+	require.ErrorContains(err, "socket is in use by another server")
+
+	// The socket left by a server which is no longer running is replaced.
+	l.(*net.UnixListener).SetUnlinkOnClose(false)
+	require.NoError(l.Close())
+	l, err = listen("unix", path, "")
+	require.NoError(err)
+	require.NoError(l.Close())
+
+	notSocket := filepath.Join(socketDir(t), "file")
+	require.NoError(os.WriteFile(notSocket, nil, 0o600))
+	_, err = listen("unix", notSocket, "")
+	require.ErrorContains(err, "is not a socket")
+
+	_, err = listen("unix", path, "rw-rw----")
+	require.ErrorContains(err, "invalid socket permissions")
+
+	_, err = listen("tcp", "127.0.0.1:0", "0660")
+	require.ErrorContains(err, "require a Unix domain socket")
+}
+
+func TestGRPCOverUnixSocket(t *testing.T) {
+	require := require.New(t)
+
+	config := &GRPCServerConfig{
+		Enabled:        true,
+		Network:        "unix",
+		Address:        filepath.Join(socketDir(t), "grpc.sock"),
+		UnixSocketMode: "0660",
+	}
+	require.Equal("unix:"+config.Address, config.DialTarget())
+
+	s, err := config.Complete(zerolog.InfoLevel, func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	})
+	require.NoError(err)
+
+	done := make(chan error)
+	go func() {
+		done <- s.Listen(context.Background())()
+	}()
+
+	conn, err := s.DialContext(context.Background(), grpc.WithTransportCredentials(insecure.NewCredentials()), grpc.WithBlock())
+	require.NoError(err)
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(err)
+	require.Equal(healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	s.GracefulStop()
+	require.NoError(<-done)
+}
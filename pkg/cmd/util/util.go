@@ -13,6 +13,8 @@ import (
 	"github.com/jzelinskie/stringz"
 	"github.com/rs/zerolog"
 	"github.com/spf13/pflag"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
@@ -43,6 +45,49 @@ type GRPCServerConfig struct {
 	ClientCAPath string
 	MaxWorkers   uint32
 
+	// MaxConnAgeGrace is how long the calls in flight on a connection which reached MaxConnAge
+	// may continue before the connection is closed, or 0 to wait for them indefinitely.
+	MaxConnAgeGrace time.Duration
+
+	// KeepaliveTime is how long a connection may be idle before the server pings the client, and
+	// KeepaliveTimeout how long the server waits for the ping to be acknowledged before closing
+	// the connection. Zero values use the gRPC defaults of 2 hours and 20 seconds.
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+
+	// KeepaliveMinTime is the minimum interval between the pings of a client, whose connection
+	// is closed if it pings more often, or 0 for the gRPC default of 5 minutes.
+	// KeepalivePermitWithoutStream allows clients to ping while they have no call in flight.
+	KeepaliveMinTime             time.Duration
+	KeepalivePermitWithoutStream bool
+
+	// MaxConnsPerClient is the maximum number of connections open concurrently from the same IP
+	// address, beyond which new connections are closed as soon as they are accepted, or 0 for no
+	// maximum.
+	MaxConnsPerClient int
+
+	// ProxyProtocol reads the PROXY protocol header, of version 1 or 2, sent by the load balancer
+	// in front of the server at the start of each connection from ProxyProtocolTrustedNetworks,
+	// which must be set, so that the server sees the original addresses of the clients.
+	ProxyProtocol                bool
+	ProxyProtocolTrustedNetworks []string
+
+	// UnixSocketMode, if set, is the permissions, in octal such as 0660, of the Unix domain socket
+	// created when Network is "unix" or "unixpacket".
+	UnixSocketMode string
+
+	// TLSClientCAPath, if set, is the path of the CA certificates verifying the client
+	// certificates required by the server, which are reloaded when they change.
+	TLSClientCAPath string
+
+	// TLSClientCertOptional accepts connections without a client certificate, when
+	// TLSClientCAPath is set.
+	TLSClientCertOptional bool
+
+	// HTTPHandler, if set, serves the gRPC server through the returned handler using net/http,
+	// rather than directly, so that the handler can serve other protocols on the same port.
+	HTTPHandler func(server *grpc.Server) (http.Handler, error)
+
 	flagPrefix string
 }
 
@@ -51,7 +96,18 @@ type GRPCServerConfig struct {
 // - "$PREFIX-addr"
 // - "$PREFIX-tls-cert-path"
 // - "$PREFIX-tls-key-path"
+// - "$PREFIX-tls-client-ca-path"
+// - "$PREFIX-tls-client-cert-optional"
 // - "$PREFIX-max-conn-age"
+// - "$PREFIX-max-conn-age-grace"
+// - "$PREFIX-keepalive-time"
+// - "$PREFIX-keepalive-timeout"
+// - "$PREFIX-keepalive-min-time"
+// - "$PREFIX-keepalive-permit-without-stream"
+// - "$PREFIX-max-conns-per-client"
+// - "$PREFIX-proxy-protocol"
+// - "$PREFIX-proxy-protocol-trusted-networks"
+// - "$PREFIX-unix-socket-mode"
 func RegisterGRPCServerFlags(flags *pflag.FlagSet, config *GRPCServerConfig, flagPrefix, serviceName, defaultAddr string, defaultEnabled bool) {
 	flagPrefix = stringz.DefaultEmpty(flagPrefix, "grpc")
 	serviceName = stringz.DefaultEmpty(serviceName, "grpc")
@@ -59,10 +115,20 @@ func RegisterGRPCServerFlags(flags *pflag.FlagSet, config *GRPCServerConfig, fla
 	config.flagPrefix = flagPrefix
 
 	flags.StringVar(&config.Address, flagPrefix+"-addr", defaultAddr, "address to listen on to serve "+serviceName)
-	flags.StringVar(&config.Network, flagPrefix+"-network", "tcp", "network type to serve "+serviceName+` ("tcp", "tcp4", "tcp6", "unix", "unixpacket")`)
+	flags.StringVar(&config.Network, flagPrefix+"-network", "tcp", "network type to serve "+serviceName+` ("tcp", "tcp4", "tcp6", "unix", "unixpacket"), where the address of the "unix" networks is the path of the socket`)
+	flags.StringVar(&config.UnixSocketMode, flagPrefix+"-unix-socket-mode", "", "permissions, in octal, of the Unix domain socket serving "+serviceName+` (e.g. 0660; empty uses the umask)`)
 	flags.StringVar(&config.TLSCertPath, flagPrefix+"-tls-cert-path", "", "local path to the TLS certificate used to serve "+serviceName)
 	flags.StringVar(&config.TLSKeyPath, flagPrefix+"-tls-key-path", "", "local path to the TLS key used to serve "+serviceName)
+	flags.StringVar(&config.TLSClientCAPath, flagPrefix+"-tls-client-ca-path", "", "local path to the CA certificate(s) verifying the client certificates required to connect to "+serviceName+", reloaded when changed (requires TLS)")
+	flags.BoolVar(&config.TLSClientCertOptional, flagPrefix+"-tls-client-cert-optional", false, "also accept connections to "+serviceName+" without a client certificate, such as those of the HTTP gateway and the GraphQL API")
 	flags.DurationVar(&config.MaxConnAge, flagPrefix+"-max-conn-age", 30*time.Second, "how long a connection serving "+serviceName+" should be able to live")
+	flags.DurationVar(&config.MaxConnAgeGrace, flagPrefix+"-max-conn-age-grace", 0, "how long the calls in flight on a connection serving "+serviceName+" which reached its maximum age may continue before it is closed (0 waits for them indefinitely)")
+	flags.DurationVar(&config.KeepaliveTime, flagPrefix+"-keepalive-time", 2*time.Hour, "how long a connection serving "+serviceName+" may be idle before the server pings the client")
+	flags.DurationVar(&config.KeepaliveTimeout, flagPrefix+"-keepalive-timeout", 20*time.Second, "how long the server waits for a ping of a client of "+serviceName+" to be acknowledged before closing the connection")
+	flags.DurationVar(&config.KeepaliveMinTime, flagPrefix+"-keepalive-min-time", 5*time.Minute, "minimum interval between the keepalive pings of a client of "+serviceName+", whose connection is closed if it pings more often")
+	flags.BoolVar(&config.KeepalivePermitWithoutStream, flagPrefix+"-keepalive-permit-without-stream", false, "allow clients of "+serviceName+" to send keepalive pings while they have no call in flight")
+	flags.IntVar(&config.MaxConnsPerClient, flagPrefix+"-max-conns-per-client", 0, "maximum number of connections to "+serviceName+" open concurrently from the same IP address, beyond which new connections are closed (0 for no maximum)")
+	registerProxyProtocolFlags(flags, &config.ProxyProtocol, &config.ProxyProtocolTrustedNetworks, flagPrefix, serviceName)
 	flags.BoolVar(&config.Enabled, flagPrefix+"-enabled", defaultEnabled, "enable "+serviceName+" gRPC server")
 	flags.Uint32Var(&config.MaxWorkers, flagPrefix+"-max-workers", 0, "set the number of workers for this server (0 value means 1 worker per request)")
 }
@@ -77,18 +143,29 @@ func (c *GRPCServerConfig) Complete(level zerolog.Level, svcRegistrationFn func(
 	if !c.Enabled {
 		return &disabledGrpcServer{}, nil
 	}
+	if err := validateProxyProtocol(c.ProxyProtocol, c.ProxyProtocolTrustedNetworks, c.flagPrefix); err != nil {
+		return nil, err
+	}
 	if c.BufferSize == 0 {
 		c.BufferSize = 1024 * 1024
 	}
 	opts = append(opts, grpc.KeepaliveParams(keepalive.ServerParameters{
-		MaxConnectionAge: c.MaxConnAge,
+		MaxConnectionAge:      c.MaxConnAge,
+		MaxConnectionAgeGrace: c.MaxConnAgeGrace,
+		Time:                  c.KeepaliveTime,
+		Timeout:               c.KeepaliveTimeout,
+	}), grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             c.KeepaliveMinTime,
+		PermitWithoutStream: c.KeepalivePermitWithoutStream,
 	}), grpc.NumStreamWorkers(c.MaxWorkers))
 
-	tlsOpts, certWatcher, err := c.tlsOpts()
+	tlsConfig, certWatcher, err := c.tlsConfig()
 	if err != nil {
 		return nil, err
 	}
-	opts = append(opts, tlsOpts...)
+	if tlsConfig != nil && c.HTTPHandler == nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
 
 	clientCreds, err := c.clientCreds()
 	if err != nil {
@@ -107,17 +184,23 @@ func (c *GRPCServerConfig) Complete(level zerolog.Level, svcRegistrationFn func(
 		Bool("insecure", c.TLSCertPath == "" && c.TLSKeyPath == "").
 		Msg("grpc server started serving")
 
+	serve := func(srv *grpc.Server) (func() error, func()) {
+		return func() error { return srv.Serve(l) }, srv.GracefulStop
+	}
+	if c.HTTPHandler != nil {
+		serve = c.serveHTTP(l, tlsConfig)
+	}
+
 	srv := grpc.NewServer(opts...)
 	svcRegistrationFn(srv)
+	listenFunc, stopFunc := serve(srv)
 	return &completedGRPCServer{
 		opts:              opts,
-		listener:          l,
 		svcRegistrationFn: svcRegistrationFn,
-		listenFunc: func() error {
-			return srv.Serve(l)
-		},
-		dial:    dial,
-		netDial: netDial,
+		serve:             serve,
+		listenFunc:        listenFunc,
+		dial:              dial,
+		netDial:           netDial,
 		prestopFunc: func() {
 			log.WithLevel(level).
 				Str("addr", c.Address).
@@ -125,7 +208,7 @@ func (c *GRPCServerConfig) Complete(level zerolog.Level, svcRegistrationFn func(
 				Str("service", c.flagPrefix).
 				Msg("grpc server stopped serving")
 		},
-		stopFunc:    srv.GracefulStop,
+		stopFunc:    stopFunc,
 		creds:       clientCreds,
 		certWatcher: certWatcher,
 	}, nil
@@ -144,34 +227,156 @@ func (c *GRPCServerConfig) listenerAndDialer() (net.Listener, DialFunc, NetDialF
 				return bl.DialContext(ctx)
 			}, nil
 	}
-	l, err := net.Listen(c.Network, c.Address)
+	l, err := listen(c.Network, c.Address, c.UnixSocketMode)
 	if err != nil {
 		return nil, nil, nil, err
 	}
+	if c.ProxyProtocol {
+		// The header is read first, so that connections are limited by their original client.
+		proxied, err := newProxyProtocolListener(l, stringz.DefaultEmpty(c.flagPrefix, "grpc"), c.ProxyProtocolTrustedNetworks, proxyProtocolHeaderTimeout)
+		if err != nil {
+			_ = l.Close()
+			return nil, nil, nil, err
+		}
+		l = proxied
+	}
+	if c.MaxConnsPerClient > 0 {
+		l = newPerClientLimitListener(l, stringz.DefaultEmpty(c.flagPrefix, "grpc"), c.MaxConnsPerClient)
+	}
 	return l, func(ctx context.Context, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
-		return grpc.DialContext(ctx, c.Address, opts...)
+		return grpc.DialContext(ctx, c.DialTarget(), opts...)
 	}, nil, nil
 }
 
-func (c *GRPCServerConfig) tlsOpts() ([]grpc.ServerOption, *certwatcher.CertWatcher, error) {
+// DialTarget returns the gRPC target dialing the server, which is its address, or the address
+// of its socket for Unix domain sockets.
+func (c *GRPCServerConfig) DialTarget() string {
+	if isUnixNetwork(c.Network) {
+		return "unix:" + c.Address
+	}
+	return c.Address
+}
+
+func (c *GRPCServerConfig) tlsConfig() (*tls.Config, *certwatcher.CertWatcher, error) {
 	switch {
 	case c.TLSCertPath == "" && c.TLSKeyPath == "":
+		if c.TLSClientCAPath != "" {
+			return nil, nil, fmt.Errorf("--%s-tls-client-ca-path requires TLS", c.flagPrefix)
+		}
 		return nil, nil, nil
 	case c.TLSCertPath != "" && c.TLSKeyPath != "":
 		watcher, err := certwatcher.New(c.TLSCertPath, c.TLSKeyPath)
 		if err != nil {
 			return nil, nil, err
 		}
-		creds := credentials.NewTLS(&tls.Config{
+		tlsConfig := &tls.Config{
 			GetCertificate: watcher.GetCertificate,
 			MinVersion:     tls.VersionTLS12,
-		})
-		return []grpc.ServerOption{grpc.Creds(creds)}, watcher, nil
+		}
+		if c.TLSClientCAPath != "" {
+			if err := c.requireClientCerts(tlsConfig); err != nil {
+				return nil, nil, err
+			}
+		}
+		return tlsConfig, watcher, nil
 	default:
 		return nil, nil, nil
 	}
 }
 
+// requireClientCerts configures the TLS config to require client certificates verified against
+// the client CA certificates, which are reloaded when they change.
+//
+// The client certificates are verified by VerifyPeerCertificate rather than through ClientCAs, so
+// that the reloaded CA certificates apply to all the clones of the TLS config.
+func (c *GRPCServerConfig) requireClientCerts(tlsConfig *tls.Config) error {
+	caWatcher, err := x509util.NewCertPoolWatcher(c.TLSClientCAPath)
+	if err != nil {
+		return fmt.Errorf("failed to load the client CA certificates: %w", err)
+	}
+
+	tlsConfig.ClientAuth = tls.RequireAnyClientCert
+	if c.TLSClientCertOptional {
+		tlsConfig.ClientAuth = tls.RequestClientCert
+	}
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return nil
+		}
+
+		certs := make([]*x509.Certificate, 0, len(rawCerts))
+		for _, rawCert := range rawCerts {
+			cert, err := x509.ParseCertificate(rawCert)
+			if err != nil {
+				return err
+			}
+			certs = append(certs, cert)
+		}
+
+		pool, err := caWatcher.Pool()
+		if err != nil {
+			log.Warn().Err(err).Str("service", c.flagPrefix).Msg("failed to reload the client CA certificates")
+		}
+
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err = certs[0].Verify(x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		})
+		return err
+	}
+	return nil
+}
+
+// serveHTTP returns a function serving a gRPC server through the configured HTTP handler, over
+// HTTP/1.1 and HTTP/2 with TLS, or over HTTP/1.1 and HTTP/2 without TLS (h2c) otherwise.
+func (c *GRPCServerConfig) serveHTTP(l net.Listener, tlsConfig *tls.Config) func(srv *grpc.Server) (func() error, func()) {
+	return func(srv *grpc.Server) (func() error, func()) {
+		handler, err := c.HTTPHandler(srv)
+		if err != nil {
+			return func() error { return fmt.Errorf("failed to create http handler: %w", err) }, srv.Stop
+		}
+
+		httpSrv := &http.Server{
+			Handler:           handler,
+			ReadHeaderTimeout: 5 * time.Second,
+		}
+
+		listener := l
+		if tlsConfig != nil {
+			tlsConfig = tlsConfig.Clone()
+			tlsConfig.NextProtos = []string{"h2", "http/1.1"}
+			listener = tls.NewListener(l, tlsConfig)
+		} else {
+			httpSrv.Handler = h2c.NewHandler(httpSrv.Handler, &http2.Server{})
+		}
+
+		listenFunc := func() error {
+			if err := http2.ConfigureServer(httpSrv, &http2.Server{}); err != nil {
+				return err
+			}
+			if err := httpSrv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				return err
+			}
+			return nil
+		}
+
+		// Calls served through net/http cannot be drained by the gRPC server, so they are
+		// drained by the HTTP server before the gRPC server is stopped.
+		stopFunc := func() {
+			if err := httpSrv.Shutdown(context.Background()); err != nil {
+				log.Warn().Err(err).Str("service", c.flagPrefix).Msg("error shutting down http server")
+			}
+			srv.Stop()
+		}
+		return listenFunc, stopFunc
+	}
+}
+
 func (c *GRPCServerConfig) clientCreds() (credentials.TransportCredentials, error) {
 	switch {
 	case c.TLSCertPath == "" && c.TLSKeyPath == "":
@@ -205,8 +410,8 @@ type RunnableGRPCServer interface {
 
 type completedGRPCServer struct {
 	opts              []grpc.ServerOption
-	listener          net.Listener
 	svcRegistrationFn func(*grpc.Server)
+	serve             func(*grpc.Server) (func() error, func())
 	listenFunc        func() error
 	prestopFunc       func()
 	stopFunc          func()
@@ -221,10 +426,7 @@ func (c *completedGRPCServer) WithOpts(opts ...grpc.ServerOption) RunnableGRPCSe
 	c.opts = append(c.opts, opts...)
 	srv := grpc.NewServer(c.opts...)
 	c.svcRegistrationFn(srv)
-	c.listenFunc = func() error {
-		return srv.Serve(c.listener)
-	}
-	c.stopFunc = srv.GracefulStop
+	c.listenFunc, c.stopFunc = c.serve(srv)
 	return c
 }
 
@@ -296,10 +498,20 @@ func (d *disabledGrpcServer) GracefulStop() {}
 
 type HTTPServerConfig struct {
 	Address     string
+	Network     string
 	TLSCertPath string
 	TLSKeyPath  string
 	Enabled     bool
 
+	// UnixSocketMode, if set, is the permissions, in octal such as 0660, of the Unix domain socket
+	// created when Network is "unix".
+	UnixSocketMode string
+
+	// ProxyProtocol reads the PROXY protocol header sent by the load balancer in front of the
+	// server, as for GRPCServerConfig.
+	ProxyProtocol                bool
+	ProxyProtocolTrustedNetworks []string
+
 	flagPrefix string
 }
 
@@ -307,6 +519,9 @@ func (c *HTTPServerConfig) Complete(level zerolog.Level, handler http.Handler) (
 	if !c.Enabled {
 		return &disabledHTTPServer{}, nil
 	}
+	if err := validateProxyProtocol(c.ProxyProtocol, c.ProxyProtocolTrustedNetworks, c.flagPrefix); err != nil {
+		return nil, err
+	}
 	srv := &http.Server{
 		Addr:              c.Address,
 		Handler:           handler,
@@ -321,7 +536,11 @@ func (c *HTTPServerConfig) Complete(level zerolog.Level, handler http.Handler) (
 				Str("service", c.flagPrefix).
 				Bool("insecure", c.TLSCertPath == "" && c.TLSKeyPath == "").
 				Msg("http server started serving")
-			return srv.ListenAndServe()
+			listener, err := c.listen(srv.Addr)
+			if err != nil {
+				return err
+			}
+			return srv.Serve(listener)
 		}
 
 	case c.TLSCertPath != "" && c.TLSKeyPath != "":
@@ -330,13 +549,14 @@ func (c *HTTPServerConfig) Complete(level zerolog.Level, handler http.Handler) (
 			return nil, err
 		}
 
-		listener, err := tls.Listen("tcp", srv.Addr, &tls.Config{
-			GetCertificate: watcher.GetCertificate,
-			MinVersion:     tls.VersionTLS12,
-		})
+		listener, err := c.listen(srv.Addr)
 		if err != nil {
 			return nil, err
 		}
+		listener = tls.NewListener(listener, &tls.Config{
+			GetCertificate: watcher.GetCertificate,
+			MinVersion:     tls.VersionTLS12,
+		})
 		serveFunc = func() error {
 			log.WithLevel(level).
 				Str("addr", srv.Addr).
@@ -369,6 +589,25 @@ func (c *HTTPServerConfig) Complete(level zerolog.Level, handler http.Handler) (
 	}, nil
 }
 
+// listen listens on the address, reading the PROXY protocol header of the connections if
+// enabled.
+func (c *HTTPServerConfig) listen(addr string) (net.Listener, error) {
+	l, err := listen(stringz.DefaultEmpty(c.Network, "tcp"), addr, c.UnixSocketMode)
+	if err != nil {
+		return nil, err
+	}
+	if !c.ProxyProtocol {
+		return l, nil
+	}
+
+	proxied, err := newProxyProtocolListener(l, stringz.DefaultEmpty(c.flagPrefix, "http"), c.ProxyProtocolTrustedNetworks, proxyProtocolHeaderTimeout)
+	if err != nil {
+		_ = l.Close()
+		return nil, err
+	}
+	return proxied, nil
+}
+
 type RunnableHTTPServer interface {
 	ListenAndServe() error
 	Close()
@@ -397,15 +636,27 @@ func (c *completedHTTPServer) Close() {
 // - "$PREFIX-tls-cert-path"
 // - "$PREFIX-tls-key-path"
 // - "$PREFIX-enabled"
+// - "$PREFIX-proxy-protocol"
+// - "$PREFIX-proxy-protocol-trusted-networks"
+// - "$PREFIX-network"
+// - "$PREFIX-unix-socket-mode"
 func RegisterHTTPServerFlags(flags *pflag.FlagSet, config *HTTPServerConfig, flagPrefix, serviceName, defaultAddr string, defaultEnabled bool) {
 	flagPrefix = stringz.DefaultEmpty(flagPrefix, "http")
 	serviceName = stringz.DefaultEmpty(serviceName, "http")
 	defaultAddr = stringz.DefaultEmpty(defaultAddr, ":8443")
 	config.flagPrefix = flagPrefix
 	flags.StringVar(&config.Address, flagPrefix+"-addr", defaultAddr, "address to listen on to serve "+serviceName)
+	flags.StringVar(&config.Network, flagPrefix+"-network", "tcp", "network type to serve "+serviceName+` ("tcp", "tcp4", "tcp6", "unix"), where the address of the "unix" network is the path of the socket`)
+	flags.StringVar(&config.UnixSocketMode, flagPrefix+"-unix-socket-mode", "", "permissions, in octal, of the Unix domain socket serving "+serviceName+` (e.g. 0660; empty uses the umask)`)
 	flags.StringVar(&config.TLSCertPath, flagPrefix+"-tls-cert-path", "", "local path to the TLS certificate used to serve "+serviceName)
 	flags.StringVar(&config.TLSKeyPath, flagPrefix+"-tls-key-path", "", "local path to the TLS key used to serve "+serviceName)
 	flags.BoolVar(&config.Enabled, flagPrefix+"-enabled", defaultEnabled, "enable "+serviceName+" http server")
+	registerProxyProtocolFlags(flags, &config.ProxyProtocol, &config.ProxyProtocolTrustedNetworks, flagPrefix, serviceName)
+}
+
+func registerProxyProtocolFlags(flags *pflag.FlagSet, enabled *bool, trustedNetworks *[]string, flagPrefix, serviceName string) {
+	flags.BoolVar(enabled, flagPrefix+"-proxy-protocol", false, "read the PROXY protocol header (v1 or v2) sent by the load balancer in front of "+serviceName+", so that it sees the original addresses of its clients")
+	flags.StringSliceVar(trustedNetworks, flagPrefix+"-proxy-protocol-trusted-networks", nil, "networks, in CIDR notation, of the load balancers sending the PROXY protocol header to "+serviceName+"; required with --"+flagPrefix+"-proxy-protocol; connections from other networks are served without a header")
 }
 
 type disabledHTTPServer struct{}
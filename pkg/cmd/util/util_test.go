@@ -2,10 +2,16 @@ package util
 
 import (
 	"context"
+	"io"
+	"net"
+	"net/http"
 	"testing"
 
 	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
 func TestDisabledGRPC(t *testing.T) {
@@ -22,3 +28,50 @@ func TestDisabledHTTP(t *testing.T) {
 	require.NoError(t, s.ListenAndServe())
 	s.Close()
 }
+
+func TestGRPCThroughHTTPHandler(t *testing.T) {
+	require := require.New(t)
+
+	s, err := (&GRPCServerConfig{
+		Enabled: true,
+		Network: BufferedNetwork,
+		HTTPHandler: func(server *grpc.Server) (http.Handler, error) {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.ProtoMajor == 1 {
+					_, _ = w.Write([]byte("http/1.1"))
+					return
+				}
+				server.ServeHTTP(w, r)
+			}), nil
+		},
+	}).Complete(zerolog.InfoLevel, func(server *grpc.Server) {
+		healthpb.RegisterHealthServer(server, health.NewServer())
+	})
+	require.NoError(err)
+
+	done := make(chan error)
+	go func() {
+		done <- s.Listen(context.Background())()
+	}()
+
+	conn, err := s.DialContext(context.Background(), grpc.WithBlock())
+	require.NoError(err)
+	defer conn.Close()
+
+	resp, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(err)
+	require.Equal(healthpb.HealthCheckResponse_SERVING, resp.Status)
+
+	client := &http.Client{Transport: &http.Transport{DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+		return s.NetDialContext(ctx, "")
+	}}}
+	httpResp, err := client.Get("http://buffnet/")
+	require.NoError(err)
+	defer httpResp.Body.Close()
+	body, err := io.ReadAll(httpResp.Body)
+	require.NoError(err)
+	require.Equal("http/1.1", string(body))
+
+	s.GracefulStop()
+	require.NoError(<-done)
+}
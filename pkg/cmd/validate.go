@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/authzed/spicedb/pkg/cmd/server"
+	"github.com/authzed/spicedb/pkg/development"
+)
+
+func NewValidateCommand(programName string) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate <file>...",
+		Short: "validates schema and validation files",
+		Long: "Validates schema files (ending in .zed) and validation files, as used by the playground: compiles the schema, loads the " +
+			"relationships, runs the assertions and compares the expected relations with those computed, printing the differences. " +
+			"Exits with an error if any file fails, so that schema changes can be gated in CI without a running server",
+		PreRunE: server.DefaultPreRunE(programName),
+		RunE:    validateRunE,
+		Args:    cobra.MinimumNArgs(1),
+	}
+}
+
+func validateRunE(cmd *cobra.Command, args []string) error {
+	failed := 0
+	for _, filePath := range args {
+		var result *development.SchemaTestResult
+		var err error
+		if filepath.Ext(filePath) == ".zed" {
+			result, err = development.RunSchemaFile(cmd.Context(), filePath)
+		} else {
+			result, err = development.RunSchemaTestFile(cmd.Context(), filePath)
+		}
+		if err != nil {
+			fmt.Fprintf(cmd.OutOrStdout(), "%s: FAIL\n%s\n", filePath, err)
+			failed++
+			continue
+		}
+
+		fmt.Fprintf(cmd.OutOrStdout(), "%s: %s", filePath, result.Report())
+		if !result.Passed() {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d file(s) failed validation", failed, len(args))
+	}
+	return nil
+}
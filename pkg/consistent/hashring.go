@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
 	"sort"
 	"sync"
 )
@@ -199,3 +200,109 @@ func (h *Hashring) Members() []Member {
 	}
 	return membersCopy
 }
+
+// HashRange is an inclusive range of key hashes.
+type HashRange struct {
+	Start uint64 `json:"start"`
+	End   uint64 `json:"end"`
+}
+
+// MemberOwnership is the part of the hash space owned by a member of the Hashring, which is the
+// first member found by FindN for the keys whose hashes fall within its ranges.
+type MemberOwnership struct {
+	Key      string      `json:"key"`
+	Fraction float64     `json:"fraction"`
+	Ranges   []HashRange `json:"ranges"`
+}
+
+// hashSpace is the number of distinct key hashes.
+const hashSpace = float64(1 << 64)
+
+// Ownership returns the part of the hash space owned by each member of the Hashring, ordered by
+// member key. The ranges of a member are ordered and adjacent ranges are merged.
+func (h *Hashring) Ownership() []MemberOwnership {
+	h.RLock()
+	defer h.RUnlock()
+
+	byKey := make(map[string]*MemberOwnership, len(h.nodes))
+	own := func(nodeKey string, owned HashRange) {
+		ownership, ok := byKey[nodeKey]
+		if !ok {
+			ownership = &MemberOwnership{Key: nodeKey}
+			byKey[nodeKey] = ownership
+		}
+
+		ownership.Fraction += (float64(owned.End-owned.Start) + 1) / hashSpace
+		if last := len(ownership.Ranges) - 1; last >= 0 && ownership.Ranges[last].End+1 == owned.Start {
+			ownership.Ranges[last].End = owned.End
+			return
+		}
+		ownership.Ranges = append(ownership.Ranges, owned)
+	}
+
+	// Each virtual node owns the hashes after the previous virtual node up to its own, and the
+	// first virtual node also owns the hashes after the last one, as the ring wraps around.
+	for i, vnode := range h.virtualNodes {
+		start := uint64(0)
+		if i > 0 {
+			previous := h.virtualNodes[i-1].hashvalue
+			if previous == vnode.hashvalue {
+				continue
+			}
+			start = previous + 1
+		}
+		own(vnode.members.nodeKey, HashRange{Start: start, End: vnode.hashvalue})
+	}
+	if len(h.virtualNodes) > 0 {
+		if last := h.virtualNodes[len(h.virtualNodes)-1].hashvalue; last < math.MaxUint64 {
+			own(h.virtualNodes[0].members.nodeKey, HashRange{Start: last + 1, End: math.MaxUint64})
+		}
+	}
+
+	ownership := make([]MemberOwnership, 0, len(byKey))
+	for _, memberOwnership := range byKey {
+		ownership = append(ownership, *memberOwnership)
+	}
+	sort.Slice(ownership, func(i, j int) bool {
+		return ownership[i].Key < ownership[j].Key
+	})
+	return ownership
+}
+
+// MemberVirtualNodes are the hashes of the virtual nodes of a member of the Hashring.
+type MemberVirtualNodes struct {
+	Key          string   `json:"key"`
+	VirtualNodes []uint64 `json:"virtualNodes"`
+}
+
+// VirtualNodes returns the hashes of the virtual nodes of each member of the Hashring, ordered by
+// member key. The hashes of a member are in ascending order.
+func (h *Hashring) VirtualNodes() []MemberVirtualNodes {
+	h.RLock()
+	defer h.RUnlock()
+
+	members := make([]MemberVirtualNodes, 0, len(h.nodes))
+	for nodeKey, nodeInfo := range h.nodes {
+		hashes := make([]uint64, 0, len(nodeInfo.virtualNodes))
+		for _, vnode := range nodeInfo.virtualNodes {
+			hashes = append(hashes, vnode.hashvalue)
+		}
+		sort.Slice(hashes, func(i, j int) bool { return hashes[i] < hashes[j] })
+		members = append(members, MemberVirtualNodes{Key: nodeKey, VirtualNodes: hashes})
+	}
+	sort.Slice(members, func(i, j int) bool {
+		return members[i].Key < members[j].Key
+	})
+	return members
+}
+
+// ReplicationFactor returns the number of virtual nodes of each member of the Hashring.
+func (h *Hashring) ReplicationFactor() uint16 {
+	return h.replicationFactor
+}
+
+// Hash returns the hash of a key, whose position on the Hashring determines the members found
+// for it by FindN.
+func (h *Hashring) Hash(key []byte) uint64 {
+	return h.hasher(key)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math"
 	"math/rand"
+	"sort"
 	"strconv"
 	"testing"
 
@@ -304,3 +305,91 @@ type member int
 func (m member) Key() string {
 	return fmt.Sprintf("member-%d", m)
 }
+
+func TestOwnership(t *testing.T) {
+	require := require.New(t)
+
+	ring := NewHashring(xxhash.Sum64, 20)
+	require.Empty(ring.Ownership())
+
+	for _, key := range []string{"key1", "key2", "key3"} {
+		require.NoError(ring.Add(testNode{key, nil}))
+	}
+
+	ownership := ring.Ownership()
+	require.Len(ownership, 3)
+
+	var total float64
+	var ranges []HashRange
+	owners := map[HashRange]string{}
+	for _, memberOwnership := range ownership {
+		total += memberOwnership.Fraction
+		for _, owned := range memberOwnership.Ranges {
+			ranges = append(ranges, owned)
+			owners[owned] = memberOwnership.Key
+		}
+	}
+	require.InDelta(1.0, total, 1e-9)
+
+	// The ranges of all members cover the hash space without overlapping.
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Start < ranges[j].Start })
+	require.Equal(uint64(0), ranges[0].Start)
+	require.Equal(uint64(math.MaxUint64), ranges[len(ranges)-1].End)
+	for i := 1; i < len(ranges); i++ {
+		require.Equal(ranges[i-1].End+1, ranges[i].Start)
+	}
+
+	// The owner of the hash of a key is the member found for the key.
+	for i := 0; i < 1000; i++ {
+		key := []byte(strconv.Itoa(rand.Int()))
+		keyHash := xxhash.Sum64(key)
+		index := sort.Search(len(ranges), func(i int) bool { return ranges[i].End >= keyHash })
+
+		found, err := ring.FindN(key, 1)
+		require.NoError(err)
+		require.Equal(found[0].Key(), owners[ranges[index]])
+	}
+}
+
+func TestVirtualNodes(t *testing.T) {
+	require := require.New(t)
+
+	ring := NewHashring(xxhash.Sum64, 20)
+	require.Equal(uint16(20), ring.ReplicationFactor())
+	require.Empty(ring.VirtualNodes())
+
+	for _, key := range []string{"key2", "key1"} {
+		require.NoError(ring.Add(testNode{key, nil}))
+	}
+
+	members := ring.VirtualNodes()
+	require.Len(members, 2)
+	require.Equal("key1", members[0].Key)
+	require.Equal("key2", members[1].Key)
+	for _, member := range members {
+		require.Len(member.VirtualNodes, 20)
+		require.True(sort.SliceIsSorted(member.VirtualNodes, func(i, j int) bool {
+			return member.VirtualNodes[i] < member.VirtualNodes[j]
+		}))
+	}
+
+	// The member found for a key owns the first virtual node at or after the hash of the key.
+	key := []byte("somekey")
+	keyHash := ring.Hash(key)
+	require.Equal(xxhash.Sum64(key), keyHash)
+
+	var owner string
+	ownerHash := uint64(math.MaxUint64)
+	for _, member := range members {
+		for _, vnodeHash := range member.VirtualNodes {
+			if vnodeHash >= keyHash && vnodeHash <= ownerHash {
+				owner, ownerHash = member.Key, vnodeHash
+			}
+		}
+	}
+	if owner != "" {
+		found, err := ring.FindN(key, 1)
+		require.NoError(err)
+		require.Equal(owner, found[0].Key())
+	}
+}
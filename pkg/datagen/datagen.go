@@ -0,0 +1,184 @@
+// Package datagen generates synthetic schemas and relationship graphs of configurable shape, such as
+// for performance testing and demos. The output depends only on the configuration, including its
+// seed, so that a dataset can be reproduced exactly.
+//
+// The generated schema defines users, groups of users and nested groups, a caveat restricting
+// access to a range of hours, and a hierarchy of resource definitions, each level of which is the
+// parent of the next and passes its permissions down to it. Objects are partitioned among tenants
+// by the prefix of their IDs, with sizes following a zipfian distribution when skewed.
+package datagen
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+// MaxDepth is the maximum number of levels of resource definitions.
+const MaxDepth = 10
+
+// levelNames are the names of the resource definitions at each level, beyond which levels are
+// named by their index.
+var levelNames = []string{"organization", "project", "folder", "document", "section", "paragraph"}
+
+// Config configures the generated schema and relationships.
+type Config struct {
+	// Prefix, if not empty, prefixes the names of the definitions and caveats, followed by a
+	// slash, so that the generated schema does not collide with another.
+	Prefix string
+
+	// Tenants is the number of tenants among which the objects are partitioned, and TenantSkew the
+	// exponent of the zipfian distribution of their sizes; zero distributes objects uniformly,
+	// and higher values concentrate them in the first tenants.
+	Tenants    uint64
+	TenantSkew float64
+
+	// Depth is the number of levels of resource definitions, and FanOut the number of children of
+	// each resource in the level below it.
+	Depth  uint64
+	FanOut uint64
+
+	// Roots is the number of resources at the top level, over all of the tenants.
+	Roots uint64
+
+	// Users and Groups are the numbers of users and groups over all of the tenants, GroupSize the
+	// number of users which are members of each group, and NestedGroupRatio the fraction of the
+	// groups which are also members of another group of their tenant.
+	Users            uint64
+	Groups           uint64
+	GroupSize        uint64
+	NestedGroupRatio float64
+
+	// Grants is the number of users and groups granted a role directly on each resource.
+	Grants uint64
+
+	// CaveatRatio is the fraction of the grants to users which are conditioned on the caveat.
+	CaveatRatio float64
+
+	// Seed seeds the choice of the members and grants.
+	Seed int64
+}
+
+// Validate returns an error if the configuration is invalid.
+func (c Config) Validate() error {
+	switch {
+	case c.Tenants == 0:
+		return errors.New("the number of tenants must be greater than zero")
+	case c.TenantSkew < 0:
+		return errors.New("the tenant skew must not be negative")
+	case c.Depth == 0 || c.Depth > MaxDepth:
+		return fmt.Errorf("the depth must be between 1 and %d", MaxDepth)
+	case c.Depth > 1 && c.FanOut == 0:
+		return errors.New("the fan-out must be greater than zero when the depth is greater than 1")
+	case c.Roots < c.Tenants:
+		return errors.New("there must be at least as many roots as tenants")
+	case c.Users < c.Tenants:
+		return errors.New("there must be at least as many users as tenants")
+	case c.GroupSize == 0 && c.Groups > 0:
+		return errors.New("the group size must be greater than zero when there are groups")
+	case c.NestedGroupRatio < 0 || c.NestedGroupRatio > 1:
+		return errors.New("the nested group ratio must be between 0 and 1")
+	case c.CaveatRatio < 0 || c.CaveatRatio > 1:
+		return errors.New("the caveat ratio must be between 0 and 1")
+	}
+	return nil
+}
+
+// Resources returns the number of resources generated over all of the levels.
+func (c Config) Resources() uint64 {
+	total, level := uint64(0), c.Roots
+	for i := uint64(0); i < c.Depth; i++ {
+		total += level
+		level *= c.FanOut
+	}
+	return total
+}
+
+func (c Config) name(definition string) string {
+	if c.Prefix == "" {
+		return definition
+	}
+	return c.Prefix + "/" + definition
+}
+
+func levelName(level uint64) string {
+	if level < uint64(len(levelNames)) {
+		return levelNames[level]
+	}
+	return fmt.Sprintf("level%d", level)
+}
+
+// Schema returns the text of the generated schema.
+func Schema(config Config) string {
+	var sb strings.Builder
+	caveat := config.name("within_hours")
+	user := config.name("user")
+	group := config.name("group")
+
+	fmt.Fprintf(&sb, "caveat %s(hour int, start_hour int, end_hour int) {\n", caveat)
+	sb.WriteString("\thour >= start_hour && hour < end_hour\n")
+	sb.WriteString("}\n\n")
+
+	fmt.Fprintf(&sb, "definition %s {}\n\n", user)
+
+	fmt.Fprintf(&sb, "definition %s {\n", group)
+	fmt.Fprintf(&sb, "\trelation member: %s | %s#member\n", user, group)
+	sb.WriteString("}\n")
+
+	for level := uint64(0); level < config.Depth; level++ {
+		fmt.Fprintf(&sb, "\ndefinition %s {\n", config.name(levelName(level)))
+		if level > 0 {
+			fmt.Fprintf(&sb, "\trelation parent: %s\n", config.name(levelName(level-1)))
+		}
+		fmt.Fprintf(&sb, "\trelation editor: %s | %s#member\n", user, group)
+		fmt.Fprintf(&sb, "\trelation viewer: %s | %s with %s | %s#member\n", user, user, caveat, group)
+		if level > 0 {
+			sb.WriteString("\tpermission edit = editor + parent->edit\n")
+			sb.WriteString("\tpermission view = viewer + edit + parent->view\n")
+		} else {
+			sb.WriteString("\tpermission edit = editor\n")
+			sb.WriteString("\tpermission view = viewer + edit\n")
+		}
+		sb.WriteString("}\n")
+	}
+	return sb.String()
+}
+
+// Compile returns the compiled definitions of the generated schema.
+func Compile(config Config) (*compiler.CompiledSchema, error) {
+	empty := ""
+	return compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("generated"),
+		SchemaString: Schema(config),
+	}, &empty)
+}
+
+// distribute splits the total among the tenants, giving each at least one and the rest in
+// proportion to the zipfian weights of the tenants, so that the first tenant is the largest.
+func distribute(total, tenants uint64, skew float64) []uint64 {
+	counts := make([]uint64, tenants)
+	weights := make([]float64, tenants)
+	var totalWeight float64
+	for i := range weights {
+		weights[i] = 1 / math.Pow(float64(i+1), skew)
+		totalWeight += weights[i]
+	}
+
+	remaining := total - tenants
+	assigned := uint64(0)
+	for i := range counts {
+		share := uint64(float64(remaining) * weights[i] / totalWeight)
+		counts[i] = 1 + share
+		assigned += share
+	}
+	// The remainders of the rounding go to the largest tenants.
+	for i := uint64(0); assigned < remaining; i = (i + 1) % tenants {
+		counts[i]++
+		assigned++
+	}
+	return counts
+}
@@ -0,0 +1,177 @@
+package datagen
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/relationships"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func testConfig() Config {
+	return Config{
+		Tenants:          3,
+		TenantSkew:       1.2,
+		Depth:            3,
+		FanOut:           2,
+		Roots:            6,
+		Users:            30,
+		Groups:           6,
+		GroupSize:        4,
+		NestedGroupRatio: 0.5,
+		Grants:           2,
+		CaveatRatio:      0.5,
+		Seed:             42,
+	}
+}
+
+func TestSchemaCompiles(t *testing.T) {
+	for _, depth := range []uint64{1, 3, MaxDepth} {
+		for _, prefix := range []string{"", "bench"} {
+			config := testConfig()
+			config.Depth = depth
+			config.Prefix = prefix
+
+			compiled, err := Compile(config)
+			require.NoError(t, err)
+			require.Len(t, compiled.CaveatDefinitions, 1)
+			require.Len(t, compiled.ObjectDefinitions, 2+int(depth))
+			require.Equal(t, config.name("within_hours"), compiled.CaveatDefinitions[0].Name)
+		}
+	}
+}
+
+func TestDistribute(t *testing.T) {
+	testCases := []struct {
+		name     string
+		total    uint64
+		tenants  uint64
+		skew     float64
+		expected []uint64
+	}{
+		{"uniform", 9, 3, 0, []uint64{3, 3, 3}},
+		{"uniform with remainder", 10, 3, 0, []uint64{4, 3, 3}},
+		{"skewed", 100, 4, 1, []uint64{48, 24, 16, 12}},
+		{"one each", 3, 3, 2, []uint64{1, 1, 1}},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require.Equal(t, tc.expected, distribute(tc.total, tc.tenants, tc.skew))
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, testConfig().Validate())
+
+	for _, modify := range []func(*Config){
+		func(c *Config) { c.Tenants = 0 },
+		func(c *Config) { c.Depth = 0 },
+		func(c *Config) { c.Depth = MaxDepth + 1 },
+		func(c *Config) { c.FanOut = 0 },
+		func(c *Config) { c.Roots = 2 },
+		func(c *Config) { c.Users = 2 },
+		func(c *Config) { c.GroupSize = 0 },
+		func(c *Config) { c.CaveatRatio = 1.5 },
+		func(c *Config) { c.NestedGroupRatio = -1 },
+	} {
+		config := testConfig()
+		modify(&config)
+		require.Error(t, config.Validate())
+	}
+}
+
+func generateAll(t *testing.T, config Config) ([]string, Stats) {
+	t.Helper()
+
+	var generated []string
+	stats, err := Generate(config, func(tpl *core.RelationTuple) error {
+		generated = append(generated, tuple.String(tpl))
+		return nil
+	})
+	require.NoError(t, err)
+	return generated, stats
+}
+
+func TestGenerate(t *testing.T) {
+	config := testConfig()
+	generated, stats := generateAll(t, config)
+
+	require.Equal(t, uint64(len(generated)), stats.Relationships)
+	require.Equal(t, config.Users, stats.Users)
+	require.Equal(t, config.Groups, stats.Groups)
+	require.Equal(t, config.Resources(), stats.Resources)
+	require.Equal(t, uint64(6+12+24), stats.Resources)
+	require.Positive(t, stats.CaveatedRelationships)
+
+	// Every resource has its grants, every group its members and every resource below the top
+	// level its parent, along with some nested groups.
+	minimum := stats.Resources*config.Grants + stats.Groups*config.GroupSize + (stats.Resources - config.Roots)
+	require.GreaterOrEqual(t, stats.Relationships, minimum)
+	require.Less(t, stats.Relationships, minimum+stats.Groups)
+
+	unique := map[string]struct{}{}
+	for _, rel := range generated {
+		unique[rel] = struct{}{}
+	}
+	require.Len(t, unique, len(generated))
+
+	again, _ := generateAll(t, config)
+	require.Equal(t, generated, again)
+
+	config.Seed++
+	reseeded, _ := generateAll(t, config)
+	require.NotEqual(t, generated, reseeded)
+
+	config.CaveatRatio = 0
+	_, uncaveated := generateAll(t, config)
+	require.Zero(t, uncaveated.CaveatedRelationships)
+}
+
+func TestLoad(t *testing.T) {
+	ctx := context.Background()
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	defer ds.Close()
+
+	config := testConfig()
+	config.Prefix = "demo"
+
+	var progress []uint64
+	stats, err := Load(ctx, ds, config, 10, func(written uint64) { progress = append(progress, written) })
+	require.NoError(t, err)
+	require.Equal(t, stats.Relationships, progress[len(progress)-1])
+	require.Len(t, progress, int((stats.Relationships+9)/10))
+
+	// Loading again touches the same relationships.
+	_, err = Load(ctx, ds, config, 100, nil)
+	require.NoError(t, err)
+
+	revision, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+	reader := ds.SnapshotReader(revision)
+
+	namespaces, err := reader.ListNamespaces(ctx)
+	require.NoError(t, err)
+	require.Len(t, namespaces, 2+int(config.Depth))
+
+	var updates []*core.RelationTupleUpdate
+	for _, namespace := range namespaces {
+		it, err := reader.QueryRelationships(ctx, datastore.RelationshipsFilter{ResourceType: namespace.Name})
+		require.NoError(t, err)
+		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+			updates = append(updates, tuple.Touch(tpl))
+		}
+		require.NoError(t, it.Err())
+		it.Close()
+	}
+	require.Equal(t, stats.Relationships, uint64(len(updates)))
+	require.NoError(t, relationships.ValidateRelationshipUpdates(ctx, reader, updates, relationships.NoStrictValidation))
+}
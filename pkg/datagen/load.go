@@ -0,0 +1,105 @@
+package datagen
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/authzed/spicedb/internal/logging"
+	dsctx "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// Load writes the generated schema to the datastore in a single transaction, replacing any
+// definitions of the same names, followed by the generated relationships in transactions of
+// batchSize each. As relationships are touched rather than created, loading the same
+// configuration again is idempotent, such that an interrupted load can be resumed. The given
+// function, if any, is called with the number of relationships written after each transaction.
+func Load(ctx context.Context, ds datastore.Datastore, config Config, batchSize uint64, onProgress func(written uint64)) (Stats, error) {
+	if batchSize == 0 {
+		return Stats{}, fmt.Errorf("batch size must be greater than zero")
+	}
+	if err := config.Validate(); err != nil {
+		return Stats{}, err
+	}
+	if onProgress == nil {
+		onProgress = func(uint64) {}
+	}
+
+	if err := writeSchema(ctx, ds, config); err != nil {
+		return Stats{}, fmt.Errorf("failed to write generated schema: %w", err)
+	}
+
+	var written uint64
+	batch := make([]*core.RelationTupleUpdate, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if _, err := ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(ctx, batch)
+		}); err != nil {
+			return fmt.Errorf("failed to write generated relationships: %w", err)
+		}
+		written += uint64(len(batch))
+		batch = batch[:0]
+		onProgress(written)
+		return nil
+	}
+
+	stats, err := Generate(config, func(tpl *core.RelationTuple) error {
+		batch = append(batch, tuple.Touch(tpl))
+		if uint64(len(batch)) >= batchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+
+	log.Ctx(ctx).Info().
+		Uint64("resources", stats.Resources).
+		Uint64("relationships", stats.Relationships).
+		Msg("loaded generated dataset")
+	return stats, nil
+}
+
+func writeSchema(ctx context.Context, ds datastore.Datastore, config Config) error {
+	compiled, err := Compile(config)
+	if err != nil {
+		return err
+	}
+
+	_, err = ds.ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteCaveats(ctx, compiled.CaveatDefinitions); err != nil {
+			return err
+		}
+
+		for _, objectDef := range compiled.ObjectDefinitions {
+			ts, err := namespace.NewNamespaceTypeSystem(objectDef,
+				namespace.ResolverForDatastoreReader(rwt).WithPredefinedElements(namespace.PredefinedElements{
+					Namespaces: compiled.ObjectDefinitions,
+					Caveats:    compiled.CaveatDefinitions,
+				}))
+			if err != nil {
+				return err
+			}
+
+			vts, err := ts.Validate(dsctx.ContextWithDatastore(ctx, ds))
+			if err != nil {
+				return err
+			}
+			if err := namespace.AnnotateNamespace(vts); err != nil {
+				return err
+			}
+		}
+		return rwt.WriteNamespaces(ctx, compiled.ObjectDefinitions...)
+	})
+	return err
+}
@@ -0,0 +1,197 @@
+package datagen
+
+import (
+	"fmt"
+	"math/rand"
+
+	"google.golang.org/protobuf/types/known/structpb"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// editorRatio is the fraction of the grants which are of the editor rather than the viewer role.
+const editorRatio = 0.25
+
+// Stats are the counts of the generated objects and relationships.
+type Stats struct {
+	Users     uint64
+	Groups    uint64
+	Resources uint64
+
+	Relationships         uint64
+	CaveatedRelationships uint64
+}
+
+// Generate generates the relationships of the configuration, calling emit with each in turn, and
+// returns the counts of what was generated. Generation stops at the first error returned by emit.
+func Generate(config Config, emit func(*core.RelationTuple) error) (Stats, error) {
+	if err := config.Validate(); err != nil {
+		return Stats{}, err
+	}
+
+	g := &generator{
+		config: config,
+		rand:   rand.New(rand.NewSource(config.Seed)),
+		emit:   emit,
+	}
+
+	users := distribute(config.Users, config.Tenants, config.TenantSkew)
+	roots := distribute(config.Roots, config.Tenants, config.TenantSkew)
+	groups := make([]uint64, config.Tenants)
+	if config.Groups >= config.Tenants {
+		groups = distribute(config.Groups, config.Tenants, config.TenantSkew)
+	} else {
+		// With fewer groups than tenants, only the largest tenants have groups.
+		for i := uint64(0); i < config.Groups; i++ {
+			groups[i] = 1
+		}
+	}
+
+	for tenant := uint64(0); tenant < config.Tenants; tenant++ {
+		if err := g.tenant(tenant, users[tenant], groups[tenant], roots[tenant]); err != nil {
+			return g.stats, err
+		}
+	}
+	return g.stats, nil
+}
+
+type generator struct {
+	config Config
+	rand   *rand.Rand
+	emit   func(*core.RelationTuple) error
+	stats  Stats
+}
+
+func objectID(tenant uint64, kind string, index uint64) string {
+	return fmt.Sprintf("t%d_%s%d", tenant, kind, index)
+}
+
+func (g *generator) write(tpl *core.RelationTuple) error {
+	g.stats.Relationships++
+	if tpl.Caveat != nil {
+		g.stats.CaveatedRelationships++
+	}
+	return g.emit(tpl)
+}
+
+func (g *generator) tenant(tenant, users, groups, roots uint64) error {
+	g.stats.Users += users
+	g.stats.Groups += groups
+
+	groupType := g.config.name("group")
+	userType := g.config.name("user")
+	for group := uint64(0); group < groups; group++ {
+		resource := &core.ObjectAndRelation{Namespace: groupType, ObjectId: objectID(tenant, "group", group), Relation: "member"}
+		for _, user := range sample(g.rand, users, g.config.GroupSize) {
+			if err := g.write(&core.RelationTuple{
+				ResourceAndRelation: resource,
+				Subject:             &core.ObjectAndRelation{Namespace: userType, ObjectId: objectID(tenant, "user", user), Relation: tuple.Ellipsis},
+			}); err != nil {
+				return err
+			}
+		}
+
+		// Groups are only nested within groups of lower indexes, so that no cycle is formed.
+		if group > 0 && g.rand.Float64() < g.config.NestedGroupRatio {
+			if err := g.write(&core.RelationTuple{
+				ResourceAndRelation: &core.ObjectAndRelation{Namespace: groupType, ObjectId: objectID(tenant, "group", uint64(g.rand.Int63n(int64(group)))), Relation: "member"},
+				Subject:             resource,
+			}); err != nil {
+				return err
+			}
+		}
+	}
+
+	resources := roots
+	for level := uint64(0); level < g.config.Depth; level++ {
+		resourceType := g.config.name(levelName(level))
+		parentType := ""
+		if level > 0 {
+			parentType = g.config.name(levelName(level - 1))
+		}
+
+		for index := uint64(0); index < resources; index++ {
+			id := objectID(tenant, "", index)
+			g.stats.Resources++
+			if level > 0 {
+				if err := g.write(&core.RelationTuple{
+					ResourceAndRelation: &core.ObjectAndRelation{Namespace: resourceType, ObjectId: id, Relation: "parent"},
+					Subject:             &core.ObjectAndRelation{Namespace: parentType, ObjectId: objectID(tenant, "", index/g.config.FanOut), Relation: tuple.Ellipsis},
+				}); err != nil {
+					return err
+				}
+			}
+			if err := g.grants(tenant, resourceType, id, users, groups); err != nil {
+				return err
+			}
+		}
+		resources *= g.config.FanOut
+	}
+	return nil
+}
+
+// grants writes the grants of a resource to users and groups of its tenant.
+func (g *generator) grants(tenant uint64, resourceType, id string, users, groups uint64) error {
+	for _, subject := range sample(g.rand, users+groups, g.config.Grants) {
+		relation := "viewer"
+		if g.rand.Float64() < editorRatio {
+			relation = "editor"
+		}
+
+		tpl := &core.RelationTuple{ResourceAndRelation: &core.ObjectAndRelation{Namespace: resourceType, ObjectId: id, Relation: relation}}
+		if subject < users {
+			tpl.Subject = &core.ObjectAndRelation{Namespace: g.config.name("user"), ObjectId: objectID(tenant, "user", subject), Relation: tuple.Ellipsis}
+			if relation == "viewer" && g.rand.Float64() < g.config.CaveatRatio {
+				caveat, err := g.caveat()
+				if err != nil {
+					return err
+				}
+				tpl.Caveat = caveat
+			}
+		} else {
+			tpl.Subject = &core.ObjectAndRelation{Namespace: g.config.name("group"), ObjectId: objectID(tenant, "group", subject-users), Relation: "member"}
+		}
+
+		if err := g.write(tpl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// caveat returns the caveat restricting a grant to a random range of hours.
+func (g *generator) caveat() (*core.ContextualizedCaveat, error) {
+	start := g.rand.Intn(24)
+	end := start + 1 + g.rand.Intn(24-start)
+	context, err := structpb.NewStruct(map[string]any{"start_hour": start, "end_hour": end})
+	if err != nil {
+		return nil, err
+	}
+	return &core.ContextualizedCaveat{CaveatName: g.config.name("within_hours"), Context: context}, nil
+}
+
+// sample returns k distinct integers chosen at random from [0, n), or all of them if k >= n, in
+// an order determined by the source alone.
+func sample(r *rand.Rand, n, k uint64) []uint64 {
+	if k >= n {
+		all := make([]uint64, n)
+		for i := range all {
+			all[i] = uint64(i)
+		}
+		return all
+	}
+
+	// Floyd's algorithm chooses k distinct integers with k draws.
+	chosen := make([]uint64, 0, k)
+	seen := make(map[uint64]struct{}, k)
+	for j := n - k; j < n; j++ {
+		candidate := uint64(r.Int63n(int64(j + 1)))
+		if _, ok := seen[candidate]; ok {
+			candidate = j
+		}
+		seen[candidate] = struct{}{}
+		chosen = append(chosen, candidate)
+	}
+	return chosen
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"golang.org/x/exp/slices"
 
 	"github.com/authzed/spicedb/internal/datastore/options"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -44,6 +46,38 @@ const FilterMaximumIDCount = 100
 type RevisionChanges struct {
 	Revision Revision
 	Changes  []*core.RelationTupleUpdate
+
+	// SchemaChanges are the changes made to the definitions of the schema in the transaction,
+	// sorted by name. Only returned by watches whose content includes WatchSchema.
+	SchemaChanges []*SchemaChange
+
+	// IsCheckpoint is true if the changes are a checkpoint, which carries no changes and
+	// indicates that all changes up to and including the revision have been returned. Only
+	// returned by watches with a CheckpointInterval.
+	IsCheckpoint bool
+}
+
+// SchemaDefinition is a definition found in a schema, which is either a
+// *core.NamespaceDefinition or a *core.CaveatDefinition.
+type SchemaDefinition interface {
+	GetName() string
+}
+
+// SchemaChange is a change to a single definition of the schema.
+type SchemaChange struct {
+	// OldDefinition is the definition before the change, or nil if it was created.
+	OldDefinition SchemaDefinition
+
+	// NewDefinition is the definition after the change, or nil if it was deleted.
+	NewDefinition SchemaDefinition
+}
+
+// Name returns the name of the definition changed.
+func (sc *SchemaChange) Name() string {
+	if sc.NewDefinition != nil {
+		return sc.NewDefinition.GetName()
+	}
+	return sc.OldDefinition.GetName()
 }
 
 // RelationshipsFilter is a filter for relationships.
@@ -148,6 +182,109 @@ func (sf SubjectRelationFilter) IsEmpty() bool {
 	return !sf.IncludeEllipsisRelation && sf.NonEllipsisRelation == ""
 }
 
+// SortSchemaChanges sorts the schema changes by the names of their definitions.
+func SortSchemaChanges(changes []*SchemaChange) {
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name() < changes[j].Name() })
+}
+
+// WatchContent is a set of flags indicating the kinds of changes returned by a watch.
+type WatchContent int
+
+const (
+	// WatchRelationships indicates that changes to relationships are returned.
+	WatchRelationships WatchContent = 1 << iota
+
+	// WatchSchema indicates that changes to the definitions of the schema are returned.
+	WatchSchema
+)
+
+// WatchOptions are the options for a call to Watch.
+type WatchOptions struct {
+	// Content is the kinds of changes returned. If zero, only changes to relationships are
+	// returned.
+	Content WatchContent
+
+	// Filter is the filter to apply to the relationship changes returned. Datastores push the
+	// filter down into the queries they make for changes where possible.
+	Filter WatchFilter
+
+	// CheckpointInterval, if non-zero, is the interval after which a checkpoint is returned if
+	// no other changes have been returned, so that callers can tell a quiet watch from a dead
+	// one and persist the revision of the checkpoint to resume from.
+	CheckpointInterval time.Duration
+}
+
+// IncludesRelationships returns true if changes to relationships are returned.
+func (wo WatchOptions) IncludesRelationships() bool {
+	return wo.Content == 0 || wo.Content&WatchRelationships != 0
+}
+
+// IncludesSchema returns true if changes to the definitions of the schema are returned.
+func (wo WatchOptions) IncludesSchema() bool {
+	return wo.Content&WatchSchema != 0
+}
+
+// CheckpointDue returns true if a checkpoint should be returned by a watch which last returned
+// changes at the given time.
+func (wo WatchOptions) CheckpointDue(lastReturned time.Time) bool {
+	return wo.CheckpointInterval > 0 && time.Since(lastReturned) >= wo.CheckpointInterval
+}
+
+// WatchFilter is a filter for the relationship changes returned by Watch. A change is returned
+// only if its relationship matches every non-empty field of the filter.
+type WatchFilter struct {
+	// OptionalResourceTypes are the namespaces/types of the resources allowed. If empty, any
+	// resource type is allowed.
+	OptionalResourceTypes []string
+
+	// OptionalResourceRelations are the relations of the resources allowed. If empty, any
+	// relation is allowed.
+	OptionalResourceRelations []string
+
+	// OptionalResourceIDPrefix is the prefix required of the IDs of the resources. If empty, any
+	// resource ID is allowed.
+	OptionalResourceIDPrefix string
+
+	// OptionalSubjectTypes are the namespaces/types of the subjects allowed. If empty, any
+	// subject type is allowed.
+	OptionalSubjectTypes []string
+}
+
+// IsEmpty returns true if the watch filter allows all relationship changes.
+func (wf WatchFilter) IsEmpty() bool {
+	return len(wf.OptionalResourceTypes) == 0 &&
+		len(wf.OptionalResourceRelations) == 0 &&
+		wf.OptionalResourceIDPrefix == "" &&
+		len(wf.OptionalSubjectTypes) == 0
+}
+
+// Matches returns true if the relationship matches the watch filter.
+func (wf WatchFilter) Matches(tpl *core.RelationTuple) bool {
+	return matchesAny(wf.OptionalResourceTypes, tpl.ResourceAndRelation.Namespace) &&
+		matchesAny(wf.OptionalResourceRelations, tpl.ResourceAndRelation.Relation) &&
+		strings.HasPrefix(tpl.ResourceAndRelation.ObjectId, wf.OptionalResourceIDPrefix) &&
+		matchesAny(wf.OptionalSubjectTypes, tpl.Subject.Namespace)
+}
+
+// FilterChanges returns the changes whose relationships match the watch filter.
+func (wf WatchFilter) FilterChanges(changes []*core.RelationTupleUpdate) []*core.RelationTupleUpdate {
+	if wf.IsEmpty() {
+		return changes
+	}
+
+	filtered := make([]*core.RelationTupleUpdate, 0, len(changes))
+	for _, change := range changes {
+		if wf.Matches(change.Tuple) {
+			filtered = append(filtered, change)
+		}
+	}
+	return filtered
+}
+
+func matchesAny(allowed []string, value string) bool {
+	return len(allowed) == 0 || slices.Contains(allowed, value)
+}
+
 type Reader interface {
 	CaveatReader
 	// QueryRelationships reads relationships, starting from the resource side.
@@ -221,10 +358,11 @@ type Datastore interface {
 	// used by the specific datastore implementation.
 	RevisionFromString(serialized string) (Revision, error)
 
-	// Watch notifies the caller about all changes to tuples.
+	// Watch notifies the caller about all changes to tuples matching the filter of the
+	// options.
 	//
 	// All events following afterRevision will be sent to the caller.
-	Watch(ctx context.Context, afterRevision Revision) (<-chan *RevisionChanges, <-chan error)
+	Watch(ctx context.Context, afterRevision Revision, options WatchOptions) (<-chan *RevisionChanges, <-chan error)
 
 	// IsReady returns whether the datastore is ready to accept data. Datastores that require
 	// database schema creation will return false until the migrations have been run to create
@@ -238,6 +376,10 @@ type Datastore interface {
 	// Statistics returns relevant values about the data contained in this cluster.
 	Statistics(ctx context.Context) (Stats, error)
 
+	// UniqueID returns the unique ID of the datastore, as found in its Stats, without computing
+	// the other statistics. The unique ID never changes.
+	UniqueID(ctx context.Context) (string, error)
+
 	// Close closes the data store.
 	Close() error
 }
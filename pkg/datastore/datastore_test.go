@@ -5,6 +5,8 @@ import (
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
 func TestRelationshipsFilterFromPublicFilter(t *testing.T) {
@@ -95,3 +97,39 @@ func TestRelationshipsFilterFromPublicFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestWatchFilterMatches(t *testing.T) {
+	tpl := &core.RelationTuple{
+		ResourceAndRelation: &core.ObjectAndRelation{Namespace: "document", ObjectId: "doc_1", Relation: "viewer"},
+		Subject:             &core.ObjectAndRelation{Namespace: "user", ObjectId: "tom", Relation: "..."},
+	}
+
+	tests := []struct {
+		name     string
+		filter   WatchFilter
+		expected bool
+	}{
+		{"empty filter", WatchFilter{}, true},
+		{"matching resource type", WatchFilter{OptionalResourceTypes: []string{"folder", "document"}}, true},
+		{"other resource type", WatchFilter{OptionalResourceTypes: []string{"folder"}}, false},
+		{"matching relation", WatchFilter{OptionalResourceRelations: []string{"viewer"}}, true},
+		{"other relation", WatchFilter{OptionalResourceRelations: []string{"editor"}}, false},
+		{"matching resource ID prefix", WatchFilter{OptionalResourceIDPrefix: "doc_"}, true},
+		{"other resource ID prefix", WatchFilter{OptionalResourceIDPrefix: "docs"}, false},
+		{"matching subject type", WatchFilter{OptionalSubjectTypes: []string{"user"}}, true},
+		{"other subject type", WatchFilter{OptionalSubjectTypes: []string{"group"}}, false},
+		{
+			"all fields must match",
+			WatchFilter{OptionalResourceTypes: []string{"document"}, OptionalSubjectTypes: []string{"group"}},
+			false,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.expected, test.filter.Matches(tpl))
+			require.Equal(t, test.name == "empty filter", test.filter.IsEmpty())
+		})
+	}
+}
@@ -43,6 +43,24 @@ type ErrWatchDisabled struct{ error }
 // read-only mode.
 type ErrReadOnly struct{ error }
 
+// ErrOutsideTenant occurs when an operation made on behalf of a tenant refers to a definition,
+// caveat or relationship outside of the tenant.
+type ErrOutsideTenant struct {
+	error
+	tenant string
+	name   string
+}
+
+// Tenant is the tenant on whose behalf the operation was made.
+func (err ErrOutsideTenant) Tenant() string {
+	return err.tenant
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrOutsideTenant) MarshalZerologObject(e *zerolog.Event) {
+	e.Err(err.error).Str("tenant", err.tenant).Str("name", err.name)
+}
+
 // InvalidRevisionReason is the reason the revision could not be used.
 type InvalidRevisionReason int
 
@@ -122,6 +140,16 @@ func NewReadonlyErr() error {
 	}
 }
 
+// NewOutsideTenantErr constructs an error for when an operation made on behalf of a tenant refers
+// to the named definition or caveat, which is outside of the tenant.
+func NewOutsideTenantErr(tenant string, name string) error {
+	return ErrOutsideTenant{
+		error:  fmt.Errorf("`%s` is outside of tenant `%s`, whose definitions and caveats must be prefixed with `%s/`", name, tenant, tenant),
+		tenant: tenant,
+		name:   name,
+	}
+}
+
 // NewInvalidRevisionErr constructs a new invalid revision error.
 func NewInvalidRevisionErr(revision Revision, reason InvalidRevisionReason) error {
 	switch reason {
@@ -314,7 +314,7 @@ func expectTupleChange(t *testing.T, ds datastore.Datastore, revBeforeWrite data
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	chanRevisionChanges, chanErr := ds.Watch(ctx, revBeforeWrite)
+	chanRevisionChanges, chanErr := ds.Watch(ctx, revBeforeWrite, datastore.WatchOptions{})
 	require.Zero(t, len(chanErr))
 
 	changeWait := time.NewTimer(waitForChangesTimeout)
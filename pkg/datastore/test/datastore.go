@@ -55,6 +55,10 @@ func All(t *testing.T, tester DatastoreTester) {
 
 	t.Run("TestWatch", func(t *testing.T) { WatchTest(t, tester) })
 	t.Run("TestWatchCancel", func(t *testing.T) { WatchCancelTest(t, tester) })
+	t.Run("TestWatchWithFilter", func(t *testing.T) { WatchWithFilterTest(t, tester) })
+	t.Run("TestWatchWithFilterEmptyRevisions", func(t *testing.T) { WatchWithFilterEmptyRevisionsTest(t, tester) })
+	t.Run("TestWatchSchema", func(t *testing.T) { WatchSchemaTest(t, tester) })
+	t.Run("TestWatchCheckpoint", func(t *testing.T) { WatchCheckpointTest(t, tester) })
 
 	t.Run("TestStats", func(t *testing.T) { StatsTest(t, tester) })
 
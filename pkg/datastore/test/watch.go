@@ -58,7 +58,7 @@ func WatchTest(t *testing.T, tester DatastoreTester) {
 			lowestRevision, err := ds.HeadRevision(ctx)
 			require.NoError(err)
 
-			changes, errchan := ds.Watch(ctx, lowestRevision)
+			changes, errchan := ds.Watch(ctx, lowestRevision, datastore.WatchOptions{})
 			require.Zero(len(errchan))
 
 			var testUpdates [][]*core.RelationTupleUpdate
@@ -110,7 +110,7 @@ func WatchTest(t *testing.T, tester DatastoreTester) {
 			verifyUpdates(require, testUpdates, changes, errchan, tc.expectFallBehind)
 
 			// Test the catch-up case
-			changes, errchan = ds.Watch(ctx, lowestRevision)
+			changes, errchan = ds.Watch(ctx, lowestRevision, datastore.WatchOptions{})
 			verifyUpdates(require, testUpdates, changes, errchan, tc.expectFallBehind)
 		})
 	}
@@ -177,7 +177,7 @@ func WatchCancelTest(t *testing.T, tester DatastoreTester) {
 	startWatchRevision := setupDatastore(ds, require)
 
 	ctx, cancel := context.WithCancel(context.Background())
-	changes, errchan := ds.Watch(ctx, startWatchRevision)
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{})
 	require.Zero(len(errchan))
 
 	_, err = common.WriteTuples(ctx, ds, core.RelationTupleUpdate_CREATE, makeTestTuple("test", "test"))
@@ -214,3 +214,258 @@ func WatchCancelTest(t *testing.T, tester DatastoreTester) {
 		}
 	}
 }
+
+// WatchWithFilterTest tests whether or not the changes returned by a watch are limited to those
+// matching its filter for a particular datastore.
+func WatchWithFilterTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{
+		Filter: datastore.WatchFilter{
+			OptionalResourceTypes:     []string{testResourceNamespace},
+			OptionalResourceRelations: []string{testReaderRelation},
+			OptionalResourceIDPrefix:  "doc_",
+			OptionalSubjectTypes:      []string{testUserNamespace},
+		},
+	})
+	require.Zero(len(errchan))
+
+	batches := [][]*core.RelationTupleUpdate{
+		{
+			tuple.Touch(tuple.MustParse("test/resource:doc_1#reader@test/user:tom")),
+			tuple.Touch(tuple.MustParse("test/resource:doc_1#writer@test/user:tom")),
+			tuple.Touch(tuple.MustParse("test/resource:other_1#reader@test/user:tom")),
+		},
+		{
+			// The underscore of the prefix must not be treated as a wildcard.
+			tuple.Touch(tuple.MustParse("test/resource:docs1#reader@test/user:tom")),
+			tuple.Touch(tuple.MustParse("test/resource:doc_2#reader@test/group:admins")),
+			tuple.Touch(tuple.MustParse("test/other:doc_3#reader@test/user:tom")),
+		},
+		{
+			tuple.Touch(tuple.MustParse("test/resource:doc_4#reader@test/user:fred")),
+		},
+		{
+			tuple.Delete(tuple.MustParse("test/resource:doc_1#reader@test/user:tom")),
+			tuple.Delete(tuple.MustParse("test/resource:doc_1#writer@test/user:tom")),
+		},
+	}
+	for _, batch := range batches {
+		_, err := common.UpdateTuplesInDatastore(ctx, ds, batch...)
+		require.NoError(err)
+	}
+
+	expected := [][]*core.RelationTupleUpdate{
+		{tuple.Touch(tuple.MustParse("test/resource:doc_1#reader@test/user:tom"))},
+		{tuple.Touch(tuple.MustParse("test/resource:doc_4#reader@test/user:fred"))},
+		{tuple.Delete(tuple.MustParse("test/resource:doc_1#reader@test/user:tom"))},
+	}
+
+	for _, expectedChanges := range expected {
+		var received *datastore.RevisionChanges
+		for received == nil {
+			changeWait := time.NewTimer(waitForChangesTimeout)
+			select {
+			case change, ok := <-changes:
+				require.True(ok, "unexpected disconnect")
+				require.NotEmpty(change.Changes, "revision reported without any matching changes")
+				received = change
+			case <-changeWait.C:
+				require.Fail("Timed out", "waiting for changes: %s", expectedChanges)
+			}
+		}
+
+		missingExpected := strset.Difference(setOfChanges(expectedChanges), setOfChanges(received.Changes))
+		unexpected := strset.Difference(setOfChanges(received.Changes), setOfChanges(expectedChanges))
+		require.True(missingExpected.IsEmpty(), "expected changes missing: %s", missingExpected)
+		require.True(unexpected.IsEmpty(), "unexpected changes: %s", unexpected)
+	}
+}
+
+// WatchWithFilterEmptyRevisionsTest tests whether or not the revisions without any change matching
+// the filter of a watch are skipped, rather than returned without any changes, for a particular
+// datastore.
+func WatchWithFilterEmptyRevisionsTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{
+		Filter:             datastore.WatchFilter{OptionalResourceIDPrefix: "doc_"},
+		CheckpointInterval: 100 * time.Millisecond,
+	})
+	require.Zero(len(errchan))
+
+	for i := 0; i < 5; i++ {
+		_, err := common.UpdateTuplesInDatastore(ctx, ds,
+			tuple.Touch(tuple.MustParse(fmt.Sprintf("test/resource:other_%d#reader@test/user:tom", i))),
+		)
+		require.NoError(err)
+	}
+
+	matchingRevision, err := common.UpdateTuplesInDatastore(ctx, ds, tuple.Touch(tuple.MustParse("test/resource:doc_1#reader@test/user:tom")))
+	require.NoError(err)
+
+	for {
+		changeWait := time.NewTimer(waitForChangesTimeout)
+		select {
+		case change, ok := <-changes:
+			require.True(ok, "unexpected disconnect")
+			if change.IsCheckpoint {
+				continue
+			}
+
+			require.NotEmpty(change.Changes, "revision %s reported without any matching changes", change.Revision)
+			require.True(change.Revision.Equal(matchingRevision), "unexpected revision %s", change.Revision)
+			return
+		case err := <-errchan:
+			require.NoError(err)
+		case <-changeWait.C:
+			require.Fail("Timed out", "waiting for changes")
+		}
+	}
+}
+
+// WatchSchemaTest tests whether or not changes to the definitions of the schema are returned by
+// watches asking for them, for datastores supporting doing so.
+func WatchSchemaTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{Content: datastore.WatchSchema})
+	if len(errchan) > 0 {
+		err := <-errchan
+		if errors.As(err, &datastore.ErrWatchDisabled{}) {
+			t.Skipf("watching schema changes is not supported: %s", err)
+		}
+		require.NoError(err)
+	}
+
+	testCaveat := createCoreCaveat(t)
+	writes := []func(rwt datastore.ReadWriteTransaction) error{
+		func(rwt datastore.ReadWriteTransaction) error {
+			if err := rwt.WriteNamespaces(ctx, testNamespace); err != nil {
+				return err
+			}
+			return rwt.WriteCaveats(ctx, []*core.CaveatDefinition{testCaveat})
+		},
+		func(rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteNamespaces(ctx, updatedNamespace)
+		},
+		func(rwt datastore.ReadWriteTransaction) error {
+			if err := rwt.DeleteNamespaces(ctx, updatedNamespace.Name); err != nil {
+				return err
+			}
+			return rwt.DeleteCaveats(ctx, []string{testCaveat.Name})
+		},
+	}
+	for _, write := range writes {
+		_, err := ds.ReadWriteTx(ctx, write)
+		require.NoError(err)
+	}
+
+	expected := [][]*datastore.SchemaChange{
+		{
+			{NewDefinition: testNamespace},
+			{NewDefinition: testCaveat},
+		},
+		{
+			{OldDefinition: testNamespace, NewDefinition: updatedNamespace},
+		},
+		{
+			{OldDefinition: updatedNamespace},
+			{OldDefinition: testCaveat},
+		},
+	}
+
+	for _, expectedChanges := range expected {
+		datastore.SortSchemaChanges(expectedChanges)
+
+		var received *datastore.RevisionChanges
+		for received == nil {
+			changeWait := time.NewTimer(waitForChangesTimeout)
+			select {
+			case change, ok := <-changes:
+				require.True(ok, "unexpected disconnect")
+
+				// Datastores can report revisions without any changes.
+				if len(change.SchemaChanges) > 0 {
+					received = change
+				}
+			case err := <-errchan:
+				require.NoError(err)
+			case <-changeWait.C:
+				require.Fail("Timed out", "waiting for schema changes")
+			}
+		}
+
+		require.Empty(received.Changes)
+		require.Empty(cmp.Diff(expectedChanges, received.SchemaChanges, protocmp.Transform()))
+	}
+}
+
+// WatchCheckpointTest tests whether or not checkpoints are returned by watches asking for them
+// while no changes occur.
+func WatchCheckpointTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, datastore.WatchOptions{CheckpointInterval: 100 * time.Millisecond})
+	require.Zero(len(errchan))
+
+	waitForCheckpoint := func(atLeast datastore.Revision) {
+		for {
+			changeWait := time.NewTimer(waitForChangesTimeout)
+			select {
+			case change, ok := <-changes:
+				require.True(ok, "unexpected disconnect")
+				if !change.IsCheckpoint {
+					continue
+				}
+
+				require.Empty(change.Changes)
+				require.False(change.Revision.LessThan(atLeast), "checkpoint %s before %s", change.Revision, atLeast)
+				return
+			case err := <-errchan:
+				require.NoError(err)
+			case <-changeWait.C:
+				require.Fail("Timed out", "waiting for checkpoint")
+			}
+		}
+	}
+
+	waitForCheckpoint(startWatchRevision)
+
+	writtenRevision, err := common.UpdateTuplesInDatastore(ctx, ds, tuple.Touch(tuple.MustParse("test/resource:doc_1#reader@test/user:tom")))
+	require.NoError(err)
+
+	waitForCheckpoint(writtenRevision)
+}
@@ -74,3 +74,41 @@ definition document {
 	require.Error(t, err)
 	require.Contains(t, err.Error(), "invalid resource id")
 }
+
+func TestDevelopmentAccessPaths(t *testing.T) {
+	defer goleak.VerifyNone(t, goleak.IgnoreTopFunction("github.com/golang/glog.(*loggingT).flushDaemon"), goleak.IgnoreCurrent())
+
+	devCtx, devErrs, err := NewDevContext(context.Background(), &devinterface.RequestContext{
+		Schema: `definition user {}
+
+definition folder {
+	relation viewer: user
+}
+
+definition document {
+	relation parent: folder
+	relation viewer: user
+	permission view = viewer + parent->viewer
+}
+`,
+	})
+	require.NoError(t, err)
+	require.Nil(t, devErrs)
+	defer devCtx.Dispose()
+
+	paths, err := AccessPaths(devCtx,
+		&core.RelationReference{Namespace: "document", Relation: "view"},
+		&core.RelationReference{Namespace: "user", Relation: tuple.Ellipsis},
+	)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"document#view -> document#viewer -> user",
+		"document#view -> parent->folder#viewer -> user",
+	}, paths)
+
+	_, err = AccessPaths(devCtx,
+		&core.RelationReference{Namespace: "unknown", Relation: "view"},
+		&core.RelationReference{Namespace: "user", Relation: tuple.Ellipsis},
+	)
+	require.Error(t, err)
+}
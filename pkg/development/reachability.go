@@ -0,0 +1,31 @@
+package development
+
+import (
+	"github.com/authzed/spicedb/internal/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// AccessPaths returns all paths through the schema in the development context by which a
+// subject of the given type can be granted the given resource relation or permission, each in
+// a human-readable form such as `document#view -> document#viewer -> group#member -> user`.
+//
+// Paths found under an intersection or exclusion are marked as `[conditional]`, as they only
+// grant access when the other branches of the expression allow it.
+func AccessPaths(devContext *DevContext, resourceType *core.RelationReference, subjectType *core.RelationReference) ([]string, error) {
+	ctx := devContext.Ctx
+	_, ts, err := namespace.ReadNamespaceAndTypes(ctx, resourceType.Namespace, devContext.Datastore.SnapshotReader(devContext.Revision))
+	if err != nil {
+		return nil, err
+	}
+
+	paths, err := namespace.ReachabilityGraphFor(ts.AsValidated()).AccessPathsForSubjectToResource(ctx, subjectType, resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	rendered := make([]string, 0, len(paths))
+	for _, path := range paths {
+		rendered = append(rendered, path.String())
+	}
+	return rendered, nil
+}
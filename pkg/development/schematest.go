@@ -0,0 +1,228 @@
+package development
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	devinterface "github.com/authzed/spicedb/pkg/proto/developer/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/validationfile"
+	"github.com/authzed/spicedb/pkg/validationfile/blocks"
+)
+
+// SchemaTestResult is the result of running a schema test file.
+type SchemaTestResult struct {
+	// InputErrors are the errors found in the schema or relationships of the test, which
+	// prevented the assertions and expected relations from being run.
+	InputErrors []*devinterface.DeveloperError
+
+	// AssertionFailures are the assertions which did not hold.
+	AssertionFailures []*devinterface.DeveloperError
+
+	// ValidationFailures are the expected relations which did not match those computed.
+	ValidationFailures []*devinterface.DeveloperError
+
+	// ValidationDiffs are the differences between the expected and computed subjects, keyed
+	// by the object and relation, for each object and relation whose expected subjects did not
+	// match.
+	ValidationDiffs map[string][]DiffLine
+}
+
+// DiffLine is a single line of a difference between expected and computed subjects.
+type DiffLine struct {
+	// Kind is `-` for a line only expected, `+` for a line only computed, and ` ` for a line
+	// both expected and computed.
+	Kind rune
+
+	// Line is the validation string.
+	Line string
+}
+
+// Passed returns true if the test ran and had no failures.
+func (str *SchemaTestResult) Passed() bool {
+	return len(str.InputErrors) == 0 && len(str.AssertionFailures) == 0 && len(str.ValidationFailures) == 0
+}
+
+// Report returns a human-readable report of the failures of the test, if any, including the
+// differences between the expected and computed subjects.
+func (str *SchemaTestResult) Report() string {
+	if str.Passed() {
+		return "PASS\n"
+	}
+
+	var sb strings.Builder
+	if len(str.InputErrors) > 0 {
+		fmt.Fprintf(&sb, "FAIL: %d input error(s)\n", len(str.InputErrors))
+		writeDeveloperErrors(&sb, str.InputErrors)
+		return sb.String()
+	}
+
+	fmt.Fprintf(&sb, "FAIL: %d assertion failure(s), %d validation failure(s)\n", len(str.AssertionFailures), len(str.ValidationFailures))
+	writeDeveloperErrors(&sb, str.AssertionFailures)
+	writeDeveloperErrors(&sb, str.ValidationFailures)
+
+	onrStrings := make([]string, 0, len(str.ValidationDiffs))
+	for onrString := range str.ValidationDiffs {
+		onrStrings = append(onrStrings, onrString)
+	}
+	sort.Strings(onrStrings)
+
+	for _, onrString := range onrStrings {
+		fmt.Fprintf(&sb, "\n--- expected\n+++ computed\n@@ %s @@\n", onrString)
+		for _, line := range str.ValidationDiffs[onrString] {
+			fmt.Fprintf(&sb, "%c %s\n", line.Kind, line.Line)
+		}
+	}
+
+	return sb.String()
+}
+
+func writeDeveloperErrors(sb *strings.Builder, devErrs []*devinterface.DeveloperError) {
+	for _, devErr := range devErrs {
+		if devErr.Line > 0 {
+			fmt.Fprintf(sb, "\n%s:%d:%d: %s\n", strings.ToLower(devErr.Source.String()), devErr.Line, devErr.Column, devErr.Message)
+		} else {
+			fmt.Fprintf(sb, "\n%s: %s\n", strings.ToLower(devErr.Source.String()), devErr.Message)
+		}
+	}
+}
+
+// RunSchemaTestFile reads the validation file at the given path, resolving its `schemaFile`
+// if any, and runs it as a schema test.
+func RunSchemaTestFile(ctx context.Context, filePath string) (*SchemaTestResult, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := validationfile.DecodeValidationFile(contents)
+	if err != nil {
+		return nil, fmt.Errorf("error when parsing test file %s: %w", filePath, err)
+	}
+
+	if err := file.ResolveSchemaFile(filePath); err != nil {
+		return nil, fmt.Errorf("error when loading schema for test file %s: %w", filePath, err)
+	}
+
+	return RunSchemaTest(ctx, file)
+}
+
+// RunSchemaFile reads the schema file at the given path and validates its schema, as a schema test
+// without relationships, assertions or expected relations.
+func RunSchemaFile(ctx context.Context, filePath string) (*SchemaTestResult, error) {
+	contents, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return RunSchemaTest(ctx, &validationfile.ValidationFile{
+		Schema: blocks.ParsedSchema{Schema: string(contents)},
+	})
+}
+
+// RunSchemaTest runs the assertions and expected relations found in the validation file
+// against its schema and relationships.
+func RunSchemaTest(ctx context.Context, file *validationfile.ValidationFile) (*SchemaTestResult, error) {
+	relationships := make([]*core.RelationTuple, 0, len(file.Relationships.Relationships))
+	for _, rel := range file.Relationships.Relationships {
+		relationships = append(relationships, tuple.MustFromRelationship(rel))
+	}
+
+	devContext, devErrs, err := NewDevContext(ctx, &devinterface.RequestContext{
+		Schema:        file.Schema.Schema,
+		Relationships: relationships,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if devErrs != nil {
+		return &SchemaTestResult{InputErrors: devErrs.InputErrors}, nil
+	}
+	defer devContext.Dispose()
+
+	assertionFailures, err := RunAllAssertions(devContext, &file.Assertions)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SchemaTestResult{
+		AssertionFailures: assertionFailures,
+		ValidationDiffs:   map[string][]DiffLine{},
+	}
+	if file.ExpectedRelations.ValidationMap == nil {
+		return result, nil
+	}
+
+	membershipSet, validationFailures, err := RunValidation(devContext, &file.ExpectedRelations)
+	if err != nil {
+		return nil, err
+	}
+	result.ValidationFailures = validationFailures
+	if len(validationFailures) == 0 {
+		return result, nil
+	}
+
+	subjectsByONR := membershipSet.SubjectsByONR()
+	for onrKey, expectedSubjects := range file.ExpectedRelations.ValidationMap {
+		onrString := tuple.StringONR(onrKey.ObjectAndRelation)
+
+		expected := make([]string, 0, len(expectedSubjects))
+		for _, expectedSubject := range expectedSubjects {
+			expected = append(expected, normalizeValidationString(string(expectedSubject.ValidationString)))
+		}
+		sort.Strings(expected)
+
+		var computed []string
+		if foundSubjects, ok := subjectsByONR[onrString]; ok {
+			computed = validationStrings(foundSubjects)
+		}
+
+		if diff := diffLines(expected, computed); diff != nil {
+			result.ValidationDiffs[onrString] = diff
+		}
+	}
+
+	return result, nil
+}
+
+// normalizeValidationString normalizes the whitespace of a validation string, so that it can be
+// compared with one generated.
+func normalizeValidationString(vs string) string {
+	return strings.Join(strings.Fields(vs), " ")
+}
+
+// diffLines returns the differences between the sorted expected and computed lines, or nil if
+// they are the same.
+func diffLines(expected []string, computed []string) []DiffLine {
+	var diff []DiffLine
+	hasDifference := false
+
+	i, j := 0, 0
+	for i < len(expected) || j < len(computed) {
+		switch {
+		case j >= len(computed) || (i < len(expected) && expected[i] < computed[j]):
+			diff = append(diff, DiffLine{'-', expected[i]})
+			hasDifference = true
+			i++
+
+		case i >= len(expected) || computed[j] < expected[i]:
+			diff = append(diff, DiffLine{'+', computed[j]})
+			hasDifference = true
+			j++
+
+		default:
+			diff = append(diff, DiffLine{' ', expected[i]})
+			i++
+			j++
+		}
+	}
+
+	if !hasDifference {
+		return nil
+	}
+	return diff
+}
@@ -0,0 +1,202 @@
+package development
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/validationfile"
+)
+
+func TestRunSchemaTest(t *testing.T) {
+	testCases := []struct {
+		name           string
+		contents       string
+		expectedPassed bool
+		expectedReport string
+	}{
+		{
+			"passing",
+			`schema: |-
+  definition user {}
+
+  definition document {
+    relation viewer: user
+    relation banned: user
+    permission view = viewer - banned
+  }
+relationships: |-
+  document:first#viewer@user:tom
+  document:first#viewer@user:fred
+  document:first#banned@user:fred
+assertions:
+  assertTrue:
+    - document:first#view@user:tom
+  assertFalse:
+    - document:first#view@user:fred
+validation:
+  document:first#view:
+    - "[user:tom] is <document:first#viewer>"
+`,
+			true,
+			"PASS\n",
+		},
+		{
+			"failing assertion and validation",
+			`schema: |-
+  definition user {}
+
+  definition document {
+    relation viewer: user
+    relation editor: user
+    permission view = viewer + editor
+  }
+relationships: |-
+  document:first#viewer@user:tom
+  document:first#editor@user:sarah
+assertions:
+  assertTrue:
+    - document:first#view@user:fred
+validation:
+  document:first#view:
+    - "[user:tom] is <document:first#viewer>"
+    - "[user:fred]   is <document:first#viewer>"
+`,
+			false,
+			`FAIL: 1 assertion failure(s), 2 validation failure(s)
+
+assertion:14:7: Expected relation or permission document:first#view@user:fred to exist
+`,
+		},
+		{
+			"invalid relationship",
+			`schema: |-
+  definition user {}
+
+  definition document {
+    relation viewer: user
+  }
+relationships: |-
+  document:first#editor@user:tom
+`,
+			false,
+			"FAIL: 1 input error(s)\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			file, err := validationfile.DecodeValidationFile([]byte(tc.contents))
+			require.NoError(err)
+
+			result, err := RunSchemaTest(context.Background(), file)
+			require.NoError(err)
+			require.Equal(tc.expectedPassed, result.Passed())
+			require.Contains(result.Report(), tc.expectedReport)
+		})
+	}
+}
+
+func TestRunSchemaTestValidationDiff(t *testing.T) {
+	require := require.New(t)
+
+	file, err := validationfile.DecodeValidationFile([]byte(`schema: |-
+  definition user {}
+
+  definition document {
+    relation viewer: user
+    relation editor: user
+    permission view = viewer + editor
+  }
+relationships: |-
+  document:first#viewer@user:tom
+  document:first#editor@user:sarah
+validation:
+  document:first#view:
+    - "[user:tom] is <document:first#viewer>"
+    - "[user:fred] is <document:first#viewer>"
+`))
+	require.NoError(err)
+
+	result, err := RunSchemaTest(context.Background(), file)
+	require.NoError(err)
+	require.False(result.Passed())
+	require.Equal(map[string][]DiffLine{
+		"document:first#view": {
+			{'-', "[user:fred] is <document:first#viewer>"},
+			{'+', "[user:sarah] is <document:first#editor>"},
+			{' ', "[user:tom] is <document:first#viewer>"},
+		},
+	}, result.ValidationDiffs)
+	require.Contains(result.Report(), `--- expected
++++ computed
+@@ document:first#view @@
+- [user:fred] is <document:first#viewer>
++ [user:sarah] is <document:first#editor>
+  [user:tom] is <document:first#viewer>
+`)
+}
+
+func TestRunSchemaTestFile(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	require.NoError(os.WriteFile(filepath.Join(dir, "schema.zed"), []byte(`definition user {}
+
+definition document {
+	relation viewer: user
+}`), 0o600))
+	require.NoError(os.WriteFile(filepath.Join(dir, "schema_test.yaml"), []byte(`schemaFile: schema.zed
+relationships: |-
+  document:first#viewer@user:tom
+assertions:
+  assertTrue:
+    - document:first#viewer@user:tom
+`), 0o600))
+
+	result, err := RunSchemaTestFile(context.Background(), filepath.Join(dir, "schema_test.yaml"))
+	require.NoError(err)
+	require.True(result.Passed(), result.Report())
+
+	require.NoError(os.WriteFile(filepath.Join(dir, "both_test.yaml"), []byte(`schemaFile: schema.zed
+schema: definition user {}
+`), 0o600))
+
+	_, err = RunSchemaTestFile(context.Background(), filepath.Join(dir, "both_test.yaml"))
+	require.ErrorContains(err, "only one of `schema` and `schemaFile` may be specified")
+}
+
+func TestRunSchemaFile(t *testing.T) {
+	require := require.New(t)
+
+	dir := t.TempDir()
+	require.NoError(os.WriteFile(filepath.Join(dir, "valid.zed"), []byte(`definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = viewer
+}`), 0o600))
+
+	result, err := RunSchemaFile(context.Background(), filepath.Join(dir, "valid.zed"))
+	require.NoError(err)
+	require.True(result.Passed(), result.Report())
+
+	require.NoError(os.WriteFile(filepath.Join(dir, "invalid.zed"), []byte(`definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = editor
+}`), 0o600))
+
+	result, err = RunSchemaFile(context.Background(), filepath.Join(dir, "invalid.zed"))
+	require.NoError(err)
+	require.False(result.Passed())
+	require.Contains(result.Report(), "FAIL: 1 input error(s)")
+	require.Contains(result.Report(), "editor")
+}
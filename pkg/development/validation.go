@@ -209,19 +209,7 @@ func GenerateValidation(membershipSet *developmentmembership.Set) (string, error
 	sort.Strings(onrStrings)
 
 	for _, onrString := range onrStrings {
-		foundSubjects := subjectsByONR[onrString]
-		var strs []string
-		for _, fs := range foundSubjects.ListFound() {
-			strs = append(strs,
-				fmt.Sprintf("[%s] is %s",
-					fs.ToValidationString(),
-					strings.Join(wrapRelationships(tuple.StringsONRs(fs.Relationships())), "/"),
-				))
-		}
-
-		// Sort to ensure stability of output.
-		sort.Strings(strs)
-		validationMap[onrString] = strs
+		validationMap[onrString] = validationStrings(subjectsByONR[onrString])
 	}
 
 	contents, err := yaml.Marshal(validationMap)
@@ -231,3 +219,19 @@ func GenerateValidation(membershipSet *developmentmembership.Set) (string, error
 
 	return string(contents), nil
 }
+
+// validationStrings returns the sorted validation strings for the found subjects.
+func validationStrings(foundSubjects developmentmembership.FoundSubjects) []string {
+	var strs []string
+	for _, fs := range foundSubjects.ListFound() {
+		strs = append(strs,
+			fmt.Sprintf("[%s] is %s",
+				fs.ToValidationString(),
+				strings.Join(wrapRelationships(tuple.StringsONRs(fs.Relationships())), "/"),
+			))
+	}
+
+	// Sort to ensure stability of output.
+	sort.Strings(strs)
+	return strs
+}
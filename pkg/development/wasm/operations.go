@@ -18,7 +18,7 @@ import (
 func runOperation(devContext *development.DevContext, operation *devinterface.Operation) (*devinterface.OperationResult, error) {
 	switch {
 	case operation.FormatSchemaParameters != nil:
-		formatted, _ := generator.GenerateSchema(devContext.CompiledSchema.OrderedDefinitions)
+		formatted, _ := generator.GenerateSchemaWithAliases(devContext.CompiledSchema.OrderedDefinitions, devContext.CompiledSchema.TypeAliases)
 		trimmed := strings.TrimSpace(formatted)
 		return &devinterface.OperationResult{
 			FormatSchemaResult: &devinterface.FormatSchemaResult{
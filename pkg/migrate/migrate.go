@@ -63,7 +63,8 @@ type migration[C any, T any] struct {
 // a database connection handler. This makes it possible for MigrationFunc to run without
 // having to abstract each connection handler behind a common interface.
 type Manager[D Driver[C, T], C any, T any] struct {
-	migrations map[string]migration[C, T]
+	migrations   map[string]migration[C, T]
+	descriptions map[string]func(D) []string
 }
 
 // NewManager creates a new empty instance of a migration manager.
@@ -99,25 +100,12 @@ func (m *Manager[D, C, T]) Register(version, replaces string, up MigrationFunc[C
 // Run will actually perform the necessary migrations to bring the backing datastore
 // from its current revision to the specified revision.
 func (m *Manager[D, C, T]) Run(ctx context.Context, driver D, throughRevision string, dryRun RunType) error {
-	requestedRevision := throughRevision
-	starting, err := driver.Version(ctx)
+	_, toRun, err := m.migrationsToRun(ctx, driver, throughRevision)
 	if err != nil {
-		return fmt.Errorf("unable to compute target revision: %w", err)
-	}
-
-	if strings.ToLower(throughRevision) == Head {
-		throughRevision, err = m.HeadRevision()
-		if err != nil {
-			return fmt.Errorf("unable to compute head revision: %w", err)
-		}
-	}
-
-	toRun, err := collectMigrationsInRange(starting, throughRevision, m.migrations)
-	if err != nil {
-		return fmt.Errorf("unable to compute migration list: %w", err)
+		return err
 	}
 	if len(toRun) == 0 {
-		log.Info().Str("targetRevision", requestedRevision).Msg("server already at requested revision")
+		log.Info().Str("targetRevision", throughRevision).Msg("server already at requested revision")
 	}
 
 	if !dryRun {
@@ -168,6 +156,28 @@ func (m *Manager[D, C, T]) Run(ctx context.Context, driver D, throughRevision st
 	return nil
 }
 
+// migrationsToRun returns the current version of the backing datastore and the migrations to run,
+// in order, to bring it to the specified revision.
+func (m *Manager[D, C, T]) migrationsToRun(ctx context.Context, driver D, throughRevision string) (string, []migration[C, T], error) {
+	starting, err := driver.Version(ctx)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to compute target revision: %w", err)
+	}
+
+	if strings.ToLower(throughRevision) == Head {
+		throughRevision, err = m.HeadRevision()
+		if err != nil {
+			return "", nil, fmt.Errorf("unable to compute head revision: %w", err)
+		}
+	}
+
+	toRun, err := collectMigrationsInRange(starting, throughRevision, m.migrations)
+	if err != nil {
+		return "", nil, fmt.Errorf("unable to compute migration list: %w", err)
+	}
+	return starting, toRun, nil
+}
+
 func (m *Manager[D, C, T]) HeadRevision() (string, error) {
 	candidates := make(map[string]struct{}, len(m.migrations))
 	for candidate := range m.migrations {
@@ -0,0 +1,222 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Dialect is the SQL dialect of a datastore, which determines how its schema changes lock the
+// tables they change.
+type Dialect string
+
+const (
+	// Postgres takes table locks for most schema changes.
+	Postgres Dialect = "postgres"
+
+	// MySQL performs most schema changes online, but takes table locks when rebuilding tables.
+	MySQL Dialect = "mysql"
+
+	// CockroachDB performs all schema changes online.
+	CockroachDB Dialect = "cockroachdb"
+
+	// Spanner performs all schema changes online.
+	Spanner Dialect = "spanner"
+)
+
+// LockImpact is the estimated impact of a statement on the reads and writes made concurrently by
+// SpiceDB.
+type LockImpact string
+
+const (
+	// LockNone does not block reads or writes.
+	LockNone LockImpact = "none"
+
+	// LockRows locks the rows which the statement writes, blocking their concurrent writes.
+	LockRows LockImpact = "locks written rows"
+
+	// LockWrites blocks writes to the table for the duration of the statement.
+	LockWrites LockImpact = "blocks writes"
+
+	// LockReadsAndWrites blocks both reads and writes of the table for the duration of the
+	// statement.
+	LockReadsAndWrites LockImpact = "blocks reads and writes"
+)
+
+// StatementPlan is a statement which a migration would execute, along with its estimated impact.
+type StatementPlan struct {
+	// Statement is the statement, as described by the migration.
+	Statement string
+
+	// Lock is the estimated impact of the statement on concurrent reads and writes.
+	Lock LockImpact
+
+	// Destructive indicates that the statement deletes data, such as tables, columns or rows.
+	Destructive bool
+}
+
+// MigrationPlan is a migration which would run to bring a datastore to a revision.
+type MigrationPlan struct {
+	// Version is the version to which the migration migrates.
+	Version string
+
+	// Replaces is the version from which the migration migrates.
+	Replaces string
+
+	// NonAtomic indicates that some of the statements of the migration run outside of a
+	// transaction, such that they are not rolled back if the migration fails.
+	NonAtomic bool
+
+	// Described indicates that the statements of the migration were described when it was
+	// registered; otherwise Statements is empty.
+	Described bool
+
+	// Statements are the statements which the migration would execute, in order.
+	Statements []StatementPlan
+}
+
+// Destructive returns whether any of the statements of the migration deletes data.
+func (mp MigrationPlan) Destructive() bool {
+	for _, stmt := range mp.Statements {
+		if stmt.Destructive {
+			return true
+		}
+	}
+	return false
+}
+
+// Describe records the statements executed by the migration to a version, which are shown when
+// planning migrations. The statements may depend on the driver, such as on its table names.
+func (m *Manager[D, C, T]) Describe(version string, statements func(driver D) []string) error {
+	if _, ok := m.migrations[version]; !ok {
+		return fmt.Errorf("unable to describe unknown revision: %s", version)
+	}
+
+	if m.descriptions == nil {
+		m.descriptions = make(map[string]func(D) []string)
+	}
+	m.descriptions[version] = statements
+	return nil
+}
+
+// Plan returns the version of the backing datastore and the migrations which Run would execute
+// to bring it to the specified revision, without executing any of them.
+func (m *Manager[D, C, T]) Plan(ctx context.Context, driver D, throughRevision string, dialect Dialect) (string, []MigrationPlan, error) {
+	starting, toRun, err := m.migrationsToRun(ctx, driver, throughRevision)
+	if err != nil {
+		return "", nil, err
+	}
+
+	plans := make([]MigrationPlan, 0, len(toRun))
+	for _, migration := range toRun {
+		plan := MigrationPlan{
+			Version:   migration.version,
+			Replaces:  migration.replaces,
+			NonAtomic: migration.up != nil,
+		}
+
+		if describe, ok := m.descriptions[migration.version]; ok {
+			plan.Described = true
+			for _, stmt := range describe(driver) {
+				lock, destructive := EstimateImpact(dialect, stmt)
+				plan.Statements = append(plan.Statements, StatementPlan{
+					Statement:   strings.TrimSpace(stmt),
+					Lock:        lock,
+					Destructive: destructive,
+				})
+			}
+		}
+
+		plans = append(plans, plan)
+	}
+	return starting, plans, nil
+}
+
+var (
+	whitespace          = regexp.MustCompile(`\s+`)
+	destructivePrefixes = []string{"DROP TABLE", "TRUNCATE", "DELETE"}
+)
+
+// EstimateImpact estimates the impact of a statement on the reads and writes made concurrently
+// with it, in the given dialect, and whether it deletes data. The estimate is conservative and
+// based only on the kind of the statement, not on the size of the tables.
+func EstimateImpact(dialect Dialect, stmt string) (LockImpact, bool) {
+	normalized := strings.ToUpper(strings.TrimSpace(whitespace.ReplaceAllString(stmt, " ")))
+
+	destructive := strings.Contains(normalized, "DROP COLUMN")
+	for _, prefix := range destructivePrefixes {
+		destructive = destructive || strings.HasPrefix(normalized, prefix)
+	}
+
+	switch {
+	case strings.HasPrefix(normalized, "INSERT"),
+		strings.HasPrefix(normalized, "UPDATE"),
+		strings.HasPrefix(normalized, "DELETE"):
+		return LockRows, destructive
+
+	case strings.HasPrefix(normalized, "CREATE TABLE"):
+		return LockNone, destructive
+	}
+
+	switch dialect {
+	case CockroachDB, Spanner:
+		// Schema changes are performed online, backfilling in the background.
+		return LockNone, destructive
+
+	case MySQL:
+		switch {
+		case strings.HasPrefix(normalized, "DROP TABLE"):
+			return LockReadsAndWrites, destructive
+		case strings.Contains(normalized, "PRIMARY KEY"),
+			strings.Contains(normalized, " CHANGE "),
+			strings.Contains(normalized, " MODIFY "):
+			// Changing the primary key or the type of a column rebuilds the table.
+			return LockWrites, destructive
+		default:
+			return LockNone, destructive
+		}
+
+	default:
+		switch {
+		case strings.Contains(normalized, " CONCURRENTLY "):
+			return LockNone, destructive
+		case strings.HasPrefix(normalized, "CREATE INDEX"),
+			strings.HasPrefix(normalized, "CREATE UNIQUE INDEX"):
+			return LockWrites, destructive
+		case strings.HasPrefix(normalized, "ALTER TABLE"),
+			strings.HasPrefix(normalized, "DROP"):
+			return LockReadsAndWrites, destructive
+		default:
+			return LockNone, destructive
+		}
+	}
+}
+
+// PermissionChecker is implemented by the drivers which can check, without changing the
+// datastore, that they have the permissions required to run migrations.
+type PermissionChecker interface {
+	// CheckPermissions returns an error if the driver lacks any permission required to run
+	// migrations.
+	CheckPermissions(ctx context.Context) error
+}
+
+// IndexChecker is implemented by the drivers which can check that the indexes created by the
+// migrations up to the head revision are present in the datastore.
+type IndexChecker interface {
+	// MissingIndexes returns the indexes, named as table.index, which the migrations up to the
+	// head revision create but which are missing from the datastore.
+	MissingIndexes(ctx context.Context) ([]string, error)
+}
+
+// MissingIndexes returns those of the expected indexes which are not present, with both named as
+// table.index.
+func MissingIndexes(expected []string, present map[string]struct{}) []string {
+	var missing []string
+	for _, index := range expected {
+		if _, ok := present[index]; !ok {
+			missing = append(missing, index)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,86 @@
+package migrate
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlan(t *testing.T) {
+	req := require.New(t)
+	m := NewManager[*fakeDriver, fakeConnPool, fakeTx]()
+	req.NoError(m.Register("1", "", noNonatomicMigration, noTxMigration))
+	req.NoError(m.Register("2", "1", func(ctx context.Context, conn fakeConnPool) error {
+		panic("planning must not execute migrations")
+	}, noTxMigration))
+	req.NoError(m.Register("3", "2", noNonatomicMigration, noTxMigration))
+
+	req.NoError(m.Describe("2", func(*fakeDriver) []string {
+		return []string{"CREATE INDEX CONCURRENTLY ix_foo ON foo (bar)", "  ALTER TABLE foo DROP COLUMN baz  "}
+	}))
+	req.Error(m.Describe("4", func(*fakeDriver) []string { return nil }))
+
+	current, plans, err := m.Plan(context.Background(), &fakeDriver{currentVersion: "1"}, Head, Postgres)
+	req.NoError(err)
+	req.Equal("1", current)
+	req.Equal([]MigrationPlan{
+		{
+			Version:   "2",
+			Replaces:  "1",
+			NonAtomic: true,
+			Described: true,
+			Statements: []StatementPlan{
+				{Statement: "CREATE INDEX CONCURRENTLY ix_foo ON foo (bar)", Lock: LockNone},
+				{Statement: "ALTER TABLE foo DROP COLUMN baz", Lock: LockReadsAndWrites, Destructive: true},
+			},
+		},
+		{Version: "3", Replaces: "2"},
+	}, plans)
+	req.True(plans[0].Destructive())
+	req.False(plans[1].Destructive())
+
+	_, _, err = m.Plan(context.Background(), &fakeDriver{currentVersion: "1"}, "10", Postgres)
+	req.Error(err)
+}
+
+func TestEstimateImpact(t *testing.T) {
+	testCases := []struct {
+		dialect             Dialect
+		stmt                string
+		expectedLock        LockImpact
+		expectedDestructive bool
+	}{
+		{Postgres, "CREATE TABLE foo (id INT)", LockNone, false},
+		{Postgres, "CREATE INDEX ix_foo ON foo (bar)", LockWrites, false},
+		{Postgres, "CREATE UNIQUE INDEX CONCURRENTLY IF NOT EXISTS ix_foo\n\t\tON foo (bar)", LockNone, false},
+		{Postgres, "ALTER TABLE foo ADD COLUMN bar INT", LockReadsAndWrites, false},
+		{Postgres, "ALTER TABLE foo\n\t\tDROP COLUMN bar", LockReadsAndWrites, true},
+		{Postgres, "DROP INDEX ix_foo", LockReadsAndWrites, false},
+		{Postgres, "DROP TABLE foo", LockReadsAndWrites, true},
+		{Postgres, "DELETE FROM foo WHERE bar = 1", LockRows, true},
+		{Postgres, "UPDATE foo SET bar = 1", LockRows, false},
+		{Postgres, "insert into foo (bar) values (1)", LockRows, false},
+		{CockroachDB, "ALTER TABLE foo ADD COLUMN bar INT", LockNone, false},
+		{CockroachDB, "CREATE INDEX ix_foo ON foo (bar)", LockNone, false},
+		{Spanner, "ALTER TABLE foo DROP COLUMN bar", LockNone, true},
+		{MySQL, "ALTER TABLE foo ADD COLUMN bar INT", LockNone, false},
+		{MySQL, "ALTER TABLE foo DROP PRIMARY KEY;", LockWrites, false},
+		{MySQL, "DROP TABLE foo", LockReadsAndWrites, true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(string(tc.dialect)+"/"+tc.stmt, func(t *testing.T) {
+			lock, destructive := EstimateImpact(tc.dialect, tc.stmt)
+			require.Equal(t, tc.expectedLock, lock)
+			require.Equal(t, tc.expectedDestructive, destructive)
+		})
+	}
+}
+
+func TestMissingIndexes(t *testing.T) {
+	present := map[string]struct{}{"relation_tuple.ix_a": {}, "caveat.ix_c": {}}
+	require.Equal(t, []string{"relation_tuple.ix_b"}, MissingIndexes([]string{"relation_tuple.ix_a", "relation_tuple.ix_b", "caveat.ix_c"}, present))
+	require.Empty(t, MissingIndexes([]string{"caveat.ix_c"}, present))
+}
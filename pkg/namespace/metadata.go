@@ -1,6 +1,10 @@
 package namespace
 
 import (
+	"bufio"
+	"fmt"
+	"strings"
+
 	"google.golang.org/protobuf/types/known/anypb"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -54,6 +58,225 @@ func GetComments(metadata *core.Metadata) []string {
 	return comments
 }
 
+// GetUserComments returns the comments found within the given metadata message which were written
+// in the schema, excluding any markers added by SpiceDB. Only comments written in the schema
+// include their delimiters.
+func GetUserComments(metadata *core.Metadata) []string {
+	comments := []string{}
+	for _, comment := range GetComments(metadata) {
+		if strings.HasPrefix(comment, "/*") || strings.HasPrefix(comment, "//") {
+			comments = append(comments, comment)
+		}
+	}
+	return comments
+}
+
+// DeprecatedMarker is the marker which, when found at the start of a line within the doc comment
+// of a definition, relation or permission, marks that element as deprecated. Any text following
+// the marker is used as the reason for the deprecation.
+const DeprecatedMarker = "@deprecated"
+
+// GetDeprecation returns whether the given metadata marks its element as deprecated and, if so,
+// the reason given for the deprecation (which may be empty).
+func GetDeprecation(metadata *core.Metadata) (string, bool) {
+	for _, comment := range GetComments(metadata) {
+		for _, line := range commentLines(comment) {
+			if line == DeprecatedMarker || strings.HasPrefix(line, DeprecatedMarker+" ") {
+				return strings.TrimSpace(strings.TrimPrefix(line, DeprecatedMarker)), true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// TemplateMarker is the marker which, when found at the start of a line within the doc comment
+// of a definition, declares a relationship template. The marker is followed by the signature of
+// the template, such as `onboard_user(org, user)`, and the relationships created by the template
+// are given on the following lines, up to the next empty line or marker.
+const TemplateMarker = "@template"
+
+// GetTemplateDeclarations returns the relationship templates declared within the given metadata,
+// each as its signature followed by the relationships it creates. A declaration may span
+// consecutive line comments, but ends with its block comment.
+func GetTemplateDeclarations(metadata *core.Metadata) [][]string {
+	var lines []string
+	for _, comment := range GetUserComments(metadata) {
+		lines = append(lines, commentLines(comment)...)
+		if strings.HasPrefix(comment, "/*") {
+			lines = append(lines, "")
+		}
+	}
+
+	var declarations [][]string
+	var current []string
+	for _, line := range append(lines, "") {
+		switch {
+		case line == TemplateMarker || strings.HasPrefix(line, TemplateMarker+" "):
+			if current != nil {
+				declarations = append(declarations, current)
+			}
+			current = []string{strings.TrimSpace(strings.TrimPrefix(line, TemplateMarker))}
+
+		case line == "" || strings.HasPrefix(line, "@"):
+			if current != nil {
+				declarations = append(declarations, current)
+			}
+			current = nil
+
+		case current != nil:
+			current = append(current, line)
+		}
+	}
+	return declarations
+}
+
+// commentLines returns the lines of the given comment, without their comment delimiters and
+// surrounding whitespace.
+func commentLines(comment string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(comment))
+	for scanner.Scan() {
+		line := strings.TrimSuffix(strings.TrimSpace(scanner.Text()), "*/")
+		line = strings.TrimPrefix(line, "//")
+		line = strings.TrimPrefix(line, "/**")
+		line = strings.TrimPrefix(line, "/*")
+		line = strings.TrimPrefix(line, "*")
+		line = strings.TrimSpace(line)
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// TombstoneMarker is the doc comment added to an object definition which has been soft-deleted
+// from the schema and whose relationships are pending purge. As comments compiled from a schema
+// always include their delimiters, the marker cannot be produced by a user-written comment.
+const TombstoneMarker = "@tombstoned"
+
+// IsTombstoned returns whether the given object definition has been soft-deleted from the schema.
+func IsTombstoned(nsDef *core.NamespaceDefinition) bool {
+	for _, comment := range GetComments(nsDef.Metadata) {
+		if comment == TombstoneMarker {
+			return true
+		}
+	}
+	return false
+}
+
+// MarkTombstoned returns a copy of the given object definition, marked as soft-deleted from the
+// schema.
+func MarkTombstoned(nsDef *core.NamespaceDefinition) (*core.NamespaceDefinition, error) {
+	marked := nsDef.CloneVT()
+	if IsTombstoned(marked) {
+		return marked, nil
+	}
+
+	metadata, err := AddComment(marked.Metadata, TombstoneMarker)
+	if err != nil {
+		return nil, err
+	}
+
+	marked.Metadata = metadata
+	return marked, nil
+}
+
+// RenamingMarker is the doc comment prefix added to a relation which is being renamed, followed by
+// the new name of the relation. Like TombstoneMarker, it cannot be produced by a user-written
+// comment.
+const RenamingMarker = "@renaming "
+
+// GetRenaming returns the new name of the given relation, if it is being renamed.
+func GetRenaming(relation *core.Relation) (string, bool) {
+	for _, comment := range GetComments(relation.Metadata) {
+		if strings.HasPrefix(comment, RenamingMarker) {
+			return strings.TrimPrefix(comment, RenamingMarker), true
+		}
+	}
+	return "", false
+}
+
+// MarkRenaming marks the given relation *in place* as being renamed to the new name.
+func MarkRenaming(relation *core.Relation, newName string) error {
+	if _, ok := GetRenaming(relation); ok {
+		return fmt.Errorf("relation `%s` is already being renamed", relation.Name)
+	}
+
+	metadata, err := AddComment(relation.Metadata, RenamingMarker+newName)
+	if err != nil {
+		return err
+	}
+
+	relation.Metadata = metadata
+	return nil
+}
+
+// ClearRenaming removes any mark that the given relation is being renamed, *in place*.
+func ClearRenaming(relation *core.Relation) {
+	removeMarkerComments(relation.Metadata, RenamingMarker)
+}
+
+// removeMarkerComments removes the comments starting with the given marker from the metadata
+// *in place*.
+func removeMarkerComments(metadata *core.Metadata, marker string) {
+	if metadata == nil {
+		return
+	}
+
+	filtered := make([]*anypb.Any, 0, len(metadata.MetadataMessage))
+	for _, msg := range metadata.MetadataMessage {
+		var dc iv1.DocComment
+		if err := msg.UnmarshalTo(&dc); err == nil && strings.HasPrefix(dc.Comment, marker) {
+			continue
+		}
+		filtered = append(filtered, msg)
+	}
+	metadata.MetadataMessage = filtered
+}
+
+// SyntheticMarker is the doc comment prefix added to a synthetic relation, followed by the names of
+// the relations whose relationships are materialized into it, separated by SyntheticSeparator.
+// Like TombstoneMarker, it cannot be produced by a user-written comment.
+const SyntheticMarker = "@synthetic "
+
+// SyntheticSeparator separates the names of the source relations of a synthetic relation.
+const SyntheticSeparator = " + "
+
+// GetSyntheticSources returns the names of the relations from which the given relation is
+// materialized, if it is a synthetic relation.
+func GetSyntheticSources(relation *core.Relation) ([]string, bool) {
+	for _, comment := range GetComments(relation.Metadata) {
+		if strings.HasPrefix(comment, SyntheticMarker) {
+			return strings.Split(strings.TrimPrefix(comment, SyntheticMarker), SyntheticSeparator), true
+		}
+	}
+	return nil, false
+}
+
+// MarkSynthetic marks the given relation *in place* as a synthetic relation, materialized from
+// the relationships of the given source relations.
+func MarkSynthetic(relation *core.Relation, sourceRelationNames []string) error {
+	if len(sourceRelationNames) == 0 {
+		return fmt.Errorf("synthetic relation `%s` must have at least one source relation", relation.Name)
+	}
+
+	if _, ok := GetSyntheticSources(relation); ok {
+		return fmt.Errorf("relation `%s` is already synthetic", relation.Name)
+	}
+
+	metadata, err := AddComment(relation.Metadata, SyntheticMarker+strings.Join(sourceRelationNames, SyntheticSeparator))
+	if err != nil {
+		return err
+	}
+
+	relation.Metadata = metadata
+	return nil
+}
+
+// ClearSynthetic removes any mark that the given relation is synthetic, *in place*.
+func ClearSynthetic(relation *core.Relation) {
+	removeMarkerComments(relation.Metadata, SyntheticMarker)
+}
+
 // AddComment adds a comment to the given metadata message.
 func AddComment(metadata *core.Metadata, comment string) (*core.Metadata, error) {
 	if metadata == nil {
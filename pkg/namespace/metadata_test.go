@@ -61,3 +61,156 @@ func TestMetadata(t *testing.T) {
 
 	require.Equal(iv1.RelationMetadata_PERMISSION, GetRelationKind(ns.Relation[0]))
 }
+
+func TestGetDeprecation(t *testing.T) {
+	tests := []struct {
+		name               string
+		comments           []string
+		expectedDeprecated bool
+		expectedReason     string
+	}{
+		{"no comments", nil, false, ""},
+		{"unrelated comment", []string{"// some comment"}, false, ""},
+		{"marker without reason", []string{"// @deprecated"}, true, ""},
+		{"marker with reason", []string{"// @deprecated use `viewer` instead"}, true, "use `viewer` instead"},
+		{"marker within block comment", []string{"/**\n* the reader\n* @deprecated use `viewer`\n*/"}, true, "use `viewer`"},
+		{"marker on single line block comment", []string{"/* @deprecated */"}, true, ""},
+		{"marker in later comment", []string{"// first", "// @deprecated old"}, true, "old"},
+		{"marker as part of a word", []string{"// @deprecatedish"}, false, ""},
+		{"marker not at start of line", []string{"// this is not @deprecated"}, false, ""},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var metadata *core.Metadata
+			for _, comment := range tc.comments {
+				var err error
+				metadata, err = AddComment(metadata, comment)
+				require.NoError(err)
+			}
+
+			reason, deprecated := GetDeprecation(metadata)
+			require.Equal(tc.expectedDeprecated, deprecated)
+			require.Equal(tc.expectedReason, reason)
+		})
+	}
+}
+
+func TestGetTemplateDeclarations(t *testing.T) {
+	tests := []struct {
+		name     string
+		comments []string
+		expected [][]string
+	}{
+		{"no comments", nil, nil},
+		{"unrelated comment", []string{"// some comment"}, nil},
+		{
+			"template in block comment",
+			[]string{"/**\n * some definition\n *\n * @template onboard_user(org, user)\n *   org:{org}#member@user:{user}\n *   team:{org}_all#member@user:{user}\n */"},
+			[][]string{{"onboard_user(org, user)", "org:{org}#member@user:{user}", "team:{org}_all#member@user:{user}"}},
+		},
+		{
+			"template over line comments",
+			[]string{"// @template add_admin(org, user)", "// org:{org}#admin@user:{user}", "// other comment"},
+			[][]string{{"add_admin(org, user)", "org:{org}#admin@user:{user}", "other comment"}},
+		},
+		{
+			"templates ended by empty lines and markers",
+			[]string{"/*\n @template first(org)\n org:{org}#member@user:a\n\n unrelated\n @template second(org)\n org:{org}#member@user:b\n @deprecated\n*/"},
+			[][]string{{"first(org)", "org:{org}#member@user:a"}, {"second(org)", "org:{org}#member@user:b"}},
+		},
+		{
+			"templates ended by their block comment",
+			[]string{"/* @template first(org) */", "// org:{org}#member@user:a"},
+			[][]string{{"first(org)"}},
+		},
+		{"marker added by SpiceDB", []string{"@template first(org)"}, nil},
+	}
+
+	for _, tc := range tests {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var metadata *core.Metadata
+			for _, comment := range tc.comments {
+				var err error
+				metadata, err = AddComment(metadata, comment)
+				require.NoError(err)
+			}
+
+			require.Equal(tc.expected, GetTemplateDeclarations(metadata))
+		})
+	}
+}
+
+func TestTombstoned(t *testing.T) {
+	require := require.New(t)
+
+	metadata, err := AddComment(nil, "// @tombstoned")
+	require.NoError(err)
+
+	nsDef := &core.NamespaceDefinition{Name: "document", Metadata: metadata}
+	require.False(IsTombstoned(nsDef))
+
+	marked, err := MarkTombstoned(nsDef)
+	require.NoError(err)
+	require.True(IsTombstoned(marked))
+	require.False(IsTombstoned(nsDef))
+	require.Len(GetComments(marked.Metadata), 2)
+	require.Equal([]string{"// @tombstoned"}, GetUserComments(marked.Metadata))
+
+	markedAgain, err := MarkTombstoned(marked)
+	require.NoError(err)
+	require.True(IsTombstoned(markedAgain))
+	require.Len(GetComments(markedAgain.Metadata), 2)
+}
+
+func TestRenaming(t *testing.T) {
+	require := require.New(t)
+
+	metadata, err := AddComment(nil, "// @renaming reader")
+	require.NoError(err)
+
+	relation := &core.Relation{Name: "viewer", Metadata: metadata}
+	_, ok := GetRenaming(relation)
+	require.False(ok)
+
+	require.NoError(MarkRenaming(relation, "reader"))
+	newName, ok := GetRenaming(relation)
+	require.True(ok)
+	require.Equal("reader", newName)
+	require.Error(MarkRenaming(relation, "another"))
+
+	ClearRenaming(relation)
+	_, ok = GetRenaming(relation)
+	require.False(ok)
+	require.Equal([]string{"// @renaming reader"}, GetComments(relation.Metadata))
+}
+
+func TestSynthetic(t *testing.T) {
+	require := require.New(t)
+
+	metadata, err := AddComment(nil, "// @synthetic admin + editor")
+	require.NoError(err)
+
+	relation := &core.Relation{Name: "any_member", Metadata: metadata}
+	_, ok := GetSyntheticSources(relation)
+	require.False(ok)
+
+	require.Error(MarkSynthetic(relation, nil))
+	require.NoError(MarkSynthetic(relation, []string{"admin", "editor", "viewer"}))
+
+	sources, ok := GetSyntheticSources(relation)
+	require.True(ok)
+	require.Equal([]string{"admin", "editor", "viewer"}, sources)
+	require.Error(MarkSynthetic(relation, []string{"admin"}))
+
+	ClearSynthetic(relation)
+	_, ok = GetSyntheticSources(relation)
+	require.False(ok)
+	require.Equal([]string{"// @synthetic admin + editor"}, GetComments(relation.Metadata))
+}
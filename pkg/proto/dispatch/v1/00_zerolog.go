@@ -5,15 +5,15 @@ import (
 
 	"github.com/rs/zerolog"
 
-	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/internal/redaction"
 )
 
 // MarshalZerologObject implements zerolog object marshalling.
 func (cr *DispatchCheckRequest) MarshalZerologObject(e *zerolog.Event) {
 	e.Object("metadata", cr.Metadata)
 	e.Str("resource-type", fmt.Sprintf("%s#%s", cr.ResourceRelation.Namespace, cr.ResourceRelation.Relation))
-	e.Str("subject", tuple.StringONR(cr.Subject))
-	e.Array("resource-ids", strArray(cr.ResourceIds))
+	e.Str("subject", redaction.ONR(cr.Subject))
+	e.Array("resource-ids", strArray(redaction.IDs(cr.ResourceIds)))
 }
 
 // MarshalZerologObject implements zerolog object marshalling.
@@ -22,7 +22,7 @@ func (cr *DispatchCheckResponse) MarshalZerologObject(e *zerolog.Event) {
 
 	results := zerolog.Dict()
 	for resourceID, result := range cr.ResultsByResourceId {
-		results.Str(resourceID, ResourceCheckResult_Membership_name[int32(result.Membership)])
+		results.Str(redaction.ID(resourceID), ResourceCheckResult_Membership_name[int32(result.Membership)])
 	}
 	e.Dict("results", results)
 }
@@ -30,7 +30,7 @@ func (cr *DispatchCheckResponse) MarshalZerologObject(e *zerolog.Event) {
 // MarshalZerologObject implements zerolog object marshalling.
 func (er *DispatchExpandRequest) MarshalZerologObject(e *zerolog.Event) {
 	e.Object("metadata", er.Metadata)
-	e.Str("expand", tuple.StringONR(er.ResourceAndRelation))
+	e.Str("expand", redaction.ONR(er.ResourceAndRelation))
 	e.Stringer("mode", er.ExpansionMode)
 }
 
@@ -43,7 +43,7 @@ func (cr *DispatchExpandResponse) MarshalZerologObject(e *zerolog.Event) {
 func (lr *DispatchLookupRequest) MarshalZerologObject(e *zerolog.Event) {
 	e.Object("metadata", lr.Metadata)
 	e.Str("object", fmt.Sprintf("%s#%s", lr.ObjectRelation.Namespace, lr.ObjectRelation.Relation))
-	e.Str("subject", tuple.StringONR(lr.Subject))
+	e.Str("subject", redaction.ONR(lr.Subject))
 	e.Interface("context", lr.Context)
 	e.Uint32("limit", lr.Limit)
 }
@@ -53,7 +53,7 @@ func (lr *DispatchReachableResourcesRequest) MarshalZerologObject(e *zerolog.Eve
 	e.Object("metadata", lr.Metadata)
 	e.Str("resource-type", fmt.Sprintf("%s#%s", lr.ResourceRelation.Namespace, lr.ResourceRelation.Relation))
 	e.Str("subject-type", fmt.Sprintf("%s#%s", lr.SubjectRelation.Namespace, lr.SubjectRelation.Relation))
-	e.Array("subject-ids", strArray(lr.SubjectIds))
+	e.Array("subject-ids", strArray(redaction.IDs(lr.SubjectIds)))
 }
 
 // MarshalZerologObject implements zerolog object marshalling.
@@ -61,7 +61,7 @@ func (ls *DispatchLookupSubjectsRequest) MarshalZerologObject(e *zerolog.Event)
 	e.Object("metadata", ls.Metadata)
 	e.Str("resource-type", fmt.Sprintf("%s#%s", ls.ResourceRelation.Namespace, ls.ResourceRelation.Relation))
 	e.Str("subject-type", fmt.Sprintf("%s#%s", ls.SubjectRelation.Namespace, ls.SubjectRelation.Relation))
-	e.Array("resource-ids", strArray(ls.ResourceIds))
+	e.Array("resource-ids", strArray(redaction.IDs(ls.ResourceIds)))
 }
 
 type strArray []string
@@ -0,0 +1,297 @@
+// Package codegen generates constants and typed helpers for client code from a compiled
+// schema, so that applications need not refer to object types, relations and permissions
+// via string literals.
+package codegen
+
+import (
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	iv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+)
+
+// Language is a language for which code can be generated.
+type Language string
+
+const (
+	// Go generates a Go source file.
+	Go Language = "go"
+
+	// TypeScript generates a TypeScript module.
+	TypeScript Language = "typescript"
+
+	// Python generates a Python module.
+	Python Language = "python"
+)
+
+// Languages are all the languages for which code can be generated.
+var Languages = []Language{Go, TypeScript, Python}
+
+// Options are options for generating code.
+type Options struct {
+	// GoPackage is the name of the package of the generated Go source. Defaults to `authz`.
+	GoPackage string
+}
+
+type objectType struct {
+	name        string
+	ident       string
+	relations   []*core.Relation
+	permissions []*core.Relation
+}
+
+type caveat struct {
+	name  string
+	ident string
+}
+
+type model struct {
+	objectTypes []objectType
+	caveats     []caveat
+}
+
+// Generate generates code in the given language for the object definitions and caveats found
+// in the compiled schema.
+func Generate(compiled *compiler.CompiledSchema, language Language, options Options) (string, error) {
+	m, err := buildModel(compiled)
+	if err != nil {
+		return "", err
+	}
+
+	switch language {
+	case Go:
+		return generateGo(m, options)
+	case TypeScript:
+		return generateTypeScript(m), nil
+	case Python:
+		return generatePython(m), nil
+	default:
+		return "", fmt.Errorf("unsupported language `%s`", language)
+	}
+}
+
+func buildModel(compiled *compiler.CompiledSchema) (model, error) {
+	m := model{}
+	identsUsed := map[string]string{}
+	claim := func(ident string, name string) error {
+		if existing, ok := identsUsed[ident]; ok {
+			return fmt.Errorf("`%s` and `%s` both generate the identifier `%s`", existing, name, ident)
+		}
+		identsUsed[ident] = name
+		return nil
+	}
+
+	for _, nsDef := range compiled.ObjectDefinitions {
+		ot := objectType{name: nsDef.Name, ident: identifierFor(nsDef.Name)}
+		if err := claim(ot.ident, nsDef.Name); err != nil {
+			return model{}, err
+		}
+
+		for _, relation := range nsDef.Relation {
+			if namespace.GetRelationKind(relation) == iv1.RelationMetadata_PERMISSION {
+				ot.permissions = append(ot.permissions, relation)
+			} else {
+				ot.relations = append(ot.relations, relation)
+			}
+		}
+		m.objectTypes = append(m.objectTypes, ot)
+	}
+
+	for _, caveatDef := range compiled.CaveatDefinitions {
+		c := caveat{name: caveatDef.Name, ident: identifierFor(caveatDef.Name)}
+		if err := claim("Caveat"+c.ident, caveatDef.Name); err != nil {
+			return model{}, err
+		}
+		m.caveats = append(m.caveats, c)
+	}
+
+	sort.Slice(m.objectTypes, func(i, j int) bool { return m.objectTypes[i].name < m.objectTypes[j].name })
+	sort.Slice(m.caveats, func(i, j int) bool { return m.caveats[i].name < m.caveats[j].name })
+	return m, nil
+}
+
+// identifierFor returns an upper camel case identifier for the given schema name, which may
+// contain a prefix, such as `some_org/some_type`.
+func identifierFor(name string) string {
+	var sb strings.Builder
+	upperNext := true
+	for _, r := range name {
+		if r == '_' || r == '/' {
+			upperNext = true
+			continue
+		}
+
+		if upperNext {
+			sb.WriteRune(unicode.ToUpper(r))
+			upperNext = false
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// constantFor returns an upper snake case identifier for the given schema name.
+func constantFor(name string) string {
+	return strings.ToUpper(strings.ReplaceAll(name, "/", "_"))
+}
+
+func generateGo(m model, options Options) (string, error) {
+	packageName := options.GoPackage
+	if packageName == "" {
+		packageName = "authz"
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "// Code generated by spicedb codegen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&sb, "package %s\n\n", packageName)
+
+	sb.WriteString("// ObjectRef is a reference to an object of a type defined in the schema.\n")
+	sb.WriteString("type ObjectRef struct {\n\tObjectType string\n\tObjectID   string\n}\n\n")
+
+	sb.WriteString("// SubjectRef is a reference to a subject, optionally with a relation.\n")
+	sb.WriteString("type SubjectRef struct {\n\tObject           ObjectRef\n\tOptionalRelation string\n}\n\n")
+
+	sb.WriteString("// Object types defined in the schema.\nconst (\n")
+	for _, ot := range m.objectTypes {
+		fmt.Fprintf(&sb, "\t%sType = %q\n", ot.ident, ot.name)
+	}
+	sb.WriteString(")\n\n")
+
+	if len(m.caveats) > 0 {
+		sb.WriteString("// Caveats defined in the schema.\nconst (\n")
+		for _, c := range m.caveats {
+			fmt.Fprintf(&sb, "\tCaveat%s = %q\n", c.ident, c.name)
+		}
+		sb.WriteString(")\n\n")
+	}
+
+	for _, ot := range m.objectTypes {
+		if len(ot.relations)+len(ot.permissions) > 0 {
+			fmt.Fprintf(&sb, "// Relations and permissions of `%s`.\nconst (\n", ot.name)
+			for _, relation := range ot.relations {
+				fmt.Fprintf(&sb, "\t%sRelation%s = %q\n", ot.ident, identifierFor(relation.Name), relation.Name)
+			}
+			for _, permission := range ot.permissions {
+				fmt.Fprintf(&sb, "\t%sPermission%s = %q\n", ot.ident, identifierFor(permission.Name), permission.Name)
+			}
+			sb.WriteString(")\n\n")
+		}
+
+		fmt.Fprintf(&sb, "// %s returns a reference to the `%s` with the given ID.\n", ot.ident, ot.name)
+		fmt.Fprintf(&sb, "func %s(objectID string) ObjectRef {\n\treturn ObjectRef{ObjectType: %sType, ObjectID: objectID}\n}\n\n", ot.ident, ot.ident)
+
+		fmt.Fprintf(&sb, "// %sSubject returns a subject reference to the `%s` with the given ID.\n", ot.ident, ot.name)
+		fmt.Fprintf(&sb, "func %sSubject(objectID string) SubjectRef {\n\treturn SubjectRef{Object: %s(objectID)}\n}\n\n", ot.ident, ot.ident)
+
+		for _, relation := range ot.relations {
+			relIdent := identifierFor(relation.Name)
+			fmt.Fprintf(&sb, "// %s%sSubject returns a subject reference to the `%s` relation of the `%s` with the given ID.\n", ot.ident, relIdent, relation.Name, ot.name)
+			fmt.Fprintf(&sb, "func %s%sSubject(objectID string) SubjectRef {\n\treturn SubjectRef{Object: %s(objectID), OptionalRelation: %sRelation%s}\n}\n\n", ot.ident, relIdent, ot.ident, ot.ident, relIdent)
+		}
+	}
+
+	formatted, err := format.Source([]byte(sb.String()))
+	if err != nil {
+		return "", fmt.Errorf("could not format generated Go source: %w", err)
+	}
+	return string(formatted), nil
+}
+
+func generateTypeScript(m model) string {
+	var sb strings.Builder
+	sb.WriteString("// Code generated by spicedb codegen. DO NOT EDIT.\n\n")
+	sb.WriteString("export interface ObjectRef {\n  objectType: string;\n  objectId: string;\n}\n\n")
+	sb.WriteString("export interface SubjectRef {\n  object: ObjectRef;\n  optionalRelation?: string;\n}\n\n")
+
+	sb.WriteString("export const ObjectTypes = {\n")
+	for _, ot := range m.objectTypes {
+		fmt.Fprintf(&sb, "  %s: %q,\n", ot.ident, ot.name)
+	}
+	sb.WriteString("} as const;\n")
+
+	if len(m.caveats) > 0 {
+		sb.WriteString("\nexport const Caveats = {\n")
+		for _, c := range m.caveats {
+			fmt.Fprintf(&sb, "  %s: %q,\n", c.ident, c.name)
+		}
+		sb.WriteString("} as const;\n")
+	}
+
+	for _, ot := range m.objectTypes {
+		fmt.Fprintf(&sb, "\n/** Relations, permissions and helpers for `%s`. */\n", ot.name)
+		fmt.Fprintf(&sb, "export const %s = {\n", ot.ident)
+		fmt.Fprintf(&sb, "  type: %q,\n", ot.name)
+
+		sb.WriteString("  relations: {\n")
+		for _, relation := range ot.relations {
+			fmt.Fprintf(&sb, "    %s: %q,\n", identifierFor(relation.Name), relation.Name)
+		}
+		sb.WriteString("  },\n")
+
+		sb.WriteString("  permissions: {\n")
+		for _, permission := range ot.permissions {
+			fmt.Fprintf(&sb, "    %s: %q,\n", identifierFor(permission.Name), permission.Name)
+		}
+		sb.WriteString("  },\n")
+
+		fmt.Fprintf(&sb, "  ref: (objectId: string): ObjectRef => ({ objectType: %q, objectId }),\n", ot.name)
+		fmt.Fprintf(&sb, "  subject: (objectId: string, optionalRelation?: string): SubjectRef => ({\n")
+		fmt.Fprintf(&sb, "    object: { objectType: %q, objectId },\n", ot.name)
+		sb.WriteString("    optionalRelation,\n")
+		sb.WriteString("  }),\n")
+		sb.WriteString("} as const;\n")
+	}
+
+	return sb.String()
+}
+
+func generatePython(m model) string {
+	var sb strings.Builder
+	sb.WriteString("# Code generated by spicedb codegen. DO NOT EDIT.\n\n")
+	sb.WriteString("from typing import NamedTuple, Optional\n\n\n")
+	sb.WriteString("class ObjectRef(NamedTuple):\n    object_type: str\n    object_id: str\n\n\n")
+	sb.WriteString("class SubjectRef(NamedTuple):\n    object: ObjectRef\n    optional_relation: Optional[str] = None\n\n\n")
+
+	sb.WriteString("class ObjectTypes:\n")
+	if len(m.objectTypes) == 0 {
+		sb.WriteString("    pass\n")
+	}
+	for _, ot := range m.objectTypes {
+		fmt.Fprintf(&sb, "    %s = %q\n", constantFor(ot.name), ot.name)
+	}
+
+	if len(m.caveats) > 0 {
+		sb.WriteString("\n\nclass Caveats:\n")
+		for _, c := range m.caveats {
+			fmt.Fprintf(&sb, "    %s = %q\n", constantFor(c.name), c.name)
+		}
+	}
+
+	for _, ot := range m.objectTypes {
+		fmt.Fprintf(&sb, "\n\nclass %s:\n", ot.ident)
+		fmt.Fprintf(&sb, "    \"\"\"Relations, permissions and helpers for `%s`.\"\"\"\n\n", ot.name)
+		fmt.Fprintf(&sb, "    TYPE = %q\n", ot.name)
+		for _, relation := range ot.relations {
+			fmt.Fprintf(&sb, "    RELATION_%s = %q\n", constantFor(relation.Name), relation.Name)
+		}
+		for _, permission := range ot.permissions {
+			fmt.Fprintf(&sb, "    PERMISSION_%s = %q\n", constantFor(permission.Name), permission.Name)
+		}
+
+		sb.WriteString("\n    @staticmethod\n")
+		sb.WriteString("    def ref(object_id: str) -> ObjectRef:\n")
+		fmt.Fprintf(&sb, "        return ObjectRef(%q, object_id)\n", ot.name)
+		sb.WriteString("\n    @staticmethod\n")
+		sb.WriteString("    def subject(object_id: str, optional_relation: Optional[str] = None) -> SubjectRef:\n")
+		fmt.Fprintf(&sb, "        return SubjectRef(ObjectRef(%q, object_id), optional_relation)\n", ot.name)
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,117 @@
+package codegen
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+const testSchema = `definition user {}
+
+caveat only_on_tuesday(day_of_week string) {
+	day_of_week == 'tuesday'
+}
+
+definition some_org/document {
+	relation viewer: user | user with only_on_tuesday
+	relation parent_folder: user
+	permission can_view = viewer
+}`
+
+func TestGenerate(t *testing.T) {
+	testCases := []struct {
+		language Language
+		options  Options
+		expected []string
+	}{
+		{
+			Go,
+			Options{GoPackage: "myauthz"},
+			[]string{
+				"// Code generated by spicedb codegen. DO NOT EDIT.",
+				"package myauthz",
+				`SomeOrgDocumentType = "some_org/document"`,
+				`UserType            = "user"`,
+				`CaveatOnlyOnTuesday = "only_on_tuesday"`,
+				`SomeOrgDocumentRelationViewer       = "viewer"`,
+				`SomeOrgDocumentRelationParentFolder = "parent_folder"`,
+				`SomeOrgDocumentPermissionCanView    = "can_view"`,
+				"func SomeOrgDocument(objectID string) ObjectRef {",
+				"func SomeOrgDocumentViewerSubject(objectID string) SubjectRef {",
+				"func UserSubject(objectID string) SubjectRef {",
+			},
+		},
+		{
+			TypeScript,
+			Options{},
+			[]string{
+				`SomeOrgDocument: "some_org/document",`,
+				`OnlyOnTuesday: "only_on_tuesday",`,
+				"export const SomeOrgDocument = {",
+				`ParentFolder: "parent_folder",`,
+				`CanView: "can_view",`,
+				`ref: (objectId: string): ObjectRef => ({ objectType: "some_org/document", objectId }),`,
+			},
+		},
+		{
+			Python,
+			Options{},
+			[]string{
+				`SOME_ORG_DOCUMENT = "some_org/document"`,
+				`ONLY_ON_TUESDAY = "only_on_tuesday"`,
+				"class SomeOrgDocument:",
+				`RELATION_PARENT_FOLDER = "parent_folder"`,
+				`PERMISSION_CAN_VIEW = "can_view"`,
+				`return ObjectRef("some_org/document", object_id)`,
+			},
+		},
+	}
+
+	empty := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: testSchema,
+	}, &empty)
+	require.NoError(t, err)
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(string(tc.language), func(t *testing.T) {
+			require := require.New(t)
+
+			generated, err := Generate(compiled, tc.language, tc.options)
+			require.NoError(err)
+
+			for _, expected := range tc.expected {
+				require.Contains(generated, expected)
+			}
+		})
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	require := require.New(t)
+
+	empty := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source: input.Source("schema"),
+		SchemaString: `definition some_org/user {}
+		definition some/org_user {}`,
+	}, &empty)
+	require.NoError(err)
+
+	_, err = Generate(compiled, Go, Options{})
+	require.ErrorContains(err, "both generate the identifier `SomeOrgUser`")
+
+	compiled, err = compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: testSchema,
+	}, &empty)
+	require.NoError(err)
+
+	_, err = Generate(compiled, Language("rust"), Options{})
+	require.ErrorContains(err, "unsupported language `rust`")
+}
@@ -39,6 +39,22 @@ type CompiledSchema struct {
 	// OrderedDefinitions holds the object and caveat definitions in the schema, in the
 	// order in which they were found.
 	OrderedDefinitions []SchemaDefinition
+
+	// TypeAliases holds the type aliases defined in the schema, in the order in which
+	// they were found. Aliases are expanded into the allowed types of the relations
+	// which reference them, and are only retained here so that they can be preserved
+	// when generating source for the schema.
+	TypeAliases []*TypeAlias
+}
+
+// TypeAlias is a named set of allowed subject types, defined in the schema via
+// `alias somealias = sometype | anothertype`.
+type TypeAlias struct {
+	// Name is the name of the alias.
+	Name string
+
+	// AllowedRelations are the allowed subject types to which the alias expands.
+	AllowedRelations []*core.AllowedRelation
 }
 
 // Compile compilers the input schema into a set of namespace definition protos.
@@ -55,6 +71,7 @@ func Compile(schema InputSchema, objectTypePrefix *string) (*CompiledSchema, err
 		objectTypePrefix: objectTypePrefix,
 		mapper:           mapper,
 		schemaString:     schema.SchemaString,
+		typeAliases:      map[string]*TypeAlias{},
 	}, root)
 	if err != nil {
 		var errorWithNode errorWithNode
@@ -716,6 +716,150 @@ func TestCompile(t *testing.T) {
 					`someMap.isSubtreeOf(anotherMap)`),
 			},
 		},
+		{
+			"type alias",
+			&someTenant,
+			`alias subject = user | team#member
+
+			definition resource {
+				relation viewer: subject | user:*
+			}`,
+			``,
+			[]SchemaDefinition{
+				namespace.Namespace("sometenant/resource",
+					namespace.Relation("viewer", nil,
+						namespace.AllowedRelation("sometenant/user", "..."),
+						namespace.AllowedRelation("sometenant/team", "member"),
+						namespace.AllowedPublicNamespace("sometenant/user"),
+					),
+				),
+			},
+		},
+		{
+			"type alias defined after use",
+			&someTenant,
+			`definition resource {
+				relation viewer: subject
+			}
+
+			alias subject = user`,
+			``,
+			[]SchemaDefinition{
+				namespace.Namespace("sometenant/resource",
+					namespace.Relation("viewer", nil,
+						namespace.AllowedRelation("sometenant/user", "..."),
+					),
+				),
+			},
+		},
+		{
+			"type alias with wildcard",
+			&someTenant,
+			`alias subject = user
+
+			definition resource {
+				relation viewer: subject:*
+			}`,
+			"type alias `subject` cannot be used with a relation, wildcard or caveat",
+			[]SchemaDefinition{},
+		},
+		{
+			"type alias referencing alias",
+			&someTenant,
+			`alias subject = user
+			alias other = subject | team`,
+			"type alias `other` cannot reference another type alias `subject`",
+			[]SchemaDefinition{},
+		},
+		{
+			"type alias name conflict",
+			&someTenant,
+			`alias user = team
+
+			definition user {}`,
+			"found name reused between a definition and a type alias: user",
+			[]SchemaDefinition{},
+		},
+		{
+			"alias used as an identifier",
+			&someTenant,
+			`definition alias {}
+
+			definition resource {
+				relation alias: alias
+				permission view = alias
+			}`,
+			"",
+			[]SchemaDefinition{
+				namespace.Namespace("sometenant/alias"),
+				namespace.Namespace("sometenant/resource",
+					namespace.Relation("alias", nil,
+						namespace.AllowedRelation("sometenant/alias", "..."),
+					),
+					namespace.Relation("view",
+						namespace.Union(
+							namespace.ComputedUserset("alias"),
+						),
+					),
+				),
+			},
+		},
+		{
+			"synthetic relation",
+			&someTenant,
+			`definition resource {
+				relation admin: user
+				relation viewer: user | team#member
+				relation any_member: user | team#member = admin + viewer
+			}`,
+			``,
+			[]SchemaDefinition{
+				namespace.Namespace("sometenant/resource",
+					namespace.Relation("admin", nil,
+						namespace.AllowedRelation("sometenant/user", "..."),
+					),
+					namespace.Relation("viewer", nil,
+						namespace.AllowedRelation("sometenant/user", "..."),
+						namespace.AllowedRelation("sometenant/team", "member"),
+					),
+					syntheticRelation("any_member", []string{"admin", "viewer"},
+						namespace.AllowedRelation("sometenant/user", "..."),
+						namespace.AllowedRelation("sometenant/team", "member"),
+					),
+				),
+			},
+		},
+		{
+			"synthetic relation with intersection",
+			&someTenant,
+			`definition resource {
+				relation admin: user
+				relation viewer: user
+				relation any_member: user = admin & viewer
+			}`,
+			"synthetic relation `any_member` may only be computed from a union of relations",
+			[]SchemaDefinition{},
+		},
+		{
+			"synthetic relation with arrow",
+			&someTenant,
+			`definition resource {
+				relation parent: resource
+				relation any_member: user = parent->any_member
+			}`,
+			"synthetic relation `any_member` may only be computed from a union of relations",
+			[]SchemaDefinition{},
+		},
+		{
+			"synthetic relation with duplicate source",
+			&someTenant,
+			`definition resource {
+				relation admin: user
+				relation any_member: user = admin + admin
+			}`,
+			"relation `admin` is referenced more than once in synthetic relation `any_member`",
+			[]SchemaDefinition{},
+		},
 	}
 
 	for _, test := range tests {
@@ -794,3 +938,11 @@ func filterSourcePositions(m protoreflect.Message) {
 		return true
 	})
 }
+
+func syntheticRelation(name string, sourceRelationNames []string, allowedRelations ...*core.AllowedRelation) *core.Relation {
+	relation := namespace.Relation(name, nil, allowedRelations...)
+	if err := namespace.MarkSynthetic(relation, sourceRelationNames); err != nil {
+		panic(err)
+	}
+	return relation
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/authzed/spicedb/pkg/util"
 
 	"github.com/jzelinskie/stringz"
+	"google.golang.org/protobuf/proto"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
@@ -23,6 +24,7 @@ type translationContext struct {
 	objectTypePrefix *string
 	mapper           input.PositionMapper
 	schemaString     string
+	typeAliases      map[string]*TypeAlias
 }
 
 func (tctx translationContext) prefixedPath(definitionName string) (string, error) {
@@ -51,9 +53,47 @@ func translate(tctx translationContext, root *dslNode) (*CompiledSchema, error)
 
 	names := util.NewSet[string]()
 
+	// Type aliases are translated first, as they can be referenced by any definition,
+	// regardless of where they are found in the schema.
+	var aliasNodes []*dslNode
+	aliasNames := util.NewSet[string]()
+	for _, childNode := range root.GetChildren() {
+		if childNode.GetType() == dslshape.NodeTypeTypeAlias {
+			aliasName, err := childNode.GetString(dslshape.NodeTypeAliasPredicateName)
+			if err != nil {
+				return nil, childNode.ErrorWithSourcef(aliasName, "invalid alias name: %w", err)
+			}
+
+			aliasNames.Add(aliasName)
+			aliasNodes = append(aliasNodes, childNode)
+		}
+	}
+
+	var typeAliases []*TypeAlias
+	for _, aliasNode := range aliasNodes {
+		alias, err := translateTypeAlias(tctx, aliasNode, aliasNames)
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := tctx.typeAliases[alias.Name]; ok {
+			return nil, aliasNode.ErrorWithSourcef(alias.Name, "found name reused between multiple type aliases: %s", alias.Name)
+		}
+
+		tctx.typeAliases[alias.Name] = alias
+		typeAliases = append(typeAliases, alias)
+	}
+
 	for _, definitionNode := range root.GetChildren() {
-		var definition SchemaDefinition
+		if definitionNode.GetType() == dslshape.NodeTypeTypeAlias {
+			continue
+		}
+
+		if definitionName, err := definitionNode.GetString(dslshape.NodeDefinitionPredicateName); err == nil && aliasNames.Has(definitionName) {
+			return nil, definitionNode.ErrorWithSourcef(definitionName, "found name reused between a definition and a type alias: %s", definitionName)
+		}
 
+		var definition SchemaDefinition
 		switch definitionNode.GetType() {
 		case dslshape.NodeTypeCaveatDefinition:
 			def, err := translateCaveatDefinition(tctx, definitionNode)
@@ -85,6 +125,36 @@ func translate(tctx translationContext, root *dslNode) (*CompiledSchema, error)
 		CaveatDefinitions:  caveatDefinitions,
 		ObjectDefinitions:  objectDefinitions,
 		OrderedDefinitions: orderedDefinitions,
+		TypeAliases:        typeAliases,
+	}, nil
+}
+
+func translateTypeAlias(tctx translationContext, aliasNode *dslNode, aliasNames *util.Set[string]) (*TypeAlias, error) {
+	aliasName, err := aliasNode.GetString(dslshape.NodeTypeAliasPredicateName)
+	if err != nil {
+		return nil, aliasNode.ErrorWithSourcef(aliasName, "invalid alias name: %w", err)
+	}
+
+	allowedRelations := []*core.AllowedRelation{}
+	for _, typeRef := range aliasNode.List(dslshape.NodeTypeAliasPredicateType) {
+		for _, specificRef := range typeRef.List(dslshape.NodeTypeReferencePredicateType) {
+			typePath, err := specificRef.GetString(dslshape.NodeSpecificReferencePredicateType)
+			if err == nil && aliasNames.Has(typePath) {
+				return nil, specificRef.ErrorWithSourcef(typePath, "type alias `%s` cannot reference another type alias `%s`", aliasName, typePath)
+			}
+		}
+
+		translated, err := translateAllowedRelations(tctx, typeRef)
+		if err != nil {
+			return nil, err
+		}
+
+		allowedRelations = append(allowedRelations, translated...)
+	}
+
+	return &TypeAlias{
+		Name:             aliasName,
+		AllowedRelations: allowedRelations,
 	}, nil
 }
 
@@ -333,9 +403,59 @@ func translateRelation(tctx translationContext, relationNode *dslNode) (*core.Re
 		return nil, relationNode.Errorf("error in relation %s: %w", relationName, err)
 	}
 
+	if expressionNode, err := relationNode.Lookup(dslshape.NodeRelationPredicateSyntheticExpression); err == nil {
+		sourceRelationNames, err := translateSyntheticExpression(expressionNode, relationName, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := namespace.MarkSynthetic(relation, sourceRelationNames); err != nil {
+			return nil, relationNode.Errorf("error in relation %s: %w", relationName, err)
+		}
+	}
+
 	return relation, nil
 }
 
+// translateSyntheticExpression returns the names of the source relations found in the expression
+// of a synthetic relation, appended to those given, which must be a union of relations.
+func translateSyntheticExpression(expressionNode *dslNode, relationName string, sourceRelationNames []string) ([]string, error) {
+	switch expressionNode.GetType() {
+	case dslshape.NodeTypeUnionExpression:
+		leftChild, err := expressionNode.Lookup(dslshape.NodeExpressionPredicateLeftExpr)
+		if err != nil {
+			return nil, err
+		}
+
+		rightChild, err := expressionNode.Lookup(dslshape.NodeExpressionPredicateRightExpr)
+		if err != nil {
+			return nil, err
+		}
+
+		sourceRelationNames, err = translateSyntheticExpression(leftChild, relationName, sourceRelationNames)
+		if err != nil {
+			return nil, err
+		}
+		return translateSyntheticExpression(rightChild, relationName, sourceRelationNames)
+
+	case dslshape.NodeTypeIdentifier:
+		sourceRelationName, err := expressionNode.GetString(dslshape.NodeIdentiferPredicateValue)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, existing := range sourceRelationNames {
+			if existing == sourceRelationName {
+				return nil, expressionNode.Errorf("relation `%s` is referenced more than once in synthetic relation `%s`", sourceRelationName, relationName)
+			}
+		}
+		return append(sourceRelationNames, sourceRelationName), nil
+
+	default:
+		return nil, expressionNode.Errorf("synthetic relation `%s` may only be computed from a union of relations", relationName)
+	}
+}
+
 func translatePermission(tctx translationContext, permissionNode *dslNode) (*core.Relation, error) {
 	permissionName, err := permissionNode.GetString(dslshape.NodePredicateName)
 	if err != nil {
@@ -511,6 +631,10 @@ func translateAllowedRelations(tctx translationContext, typeRefNode *dslNode) ([
 		return references, nil
 
 	case dslshape.NodeTypeSpecificTypeReference:
+		if expanded, ok, err := expandTypeAlias(tctx, typeRefNode); ok || err != nil {
+			return expanded, err
+		}
+
 		ref, err := translateSpecificTypeReference(tctx, typeRefNode)
 		if err != nil {
 			return []*core.AllowedRelation{}, err
@@ -522,6 +646,34 @@ func translateAllowedRelations(tctx translationContext, typeRefNode *dslNode) ([
 	}
 }
 
+// expandTypeAlias returns the allowed relations for the type alias referenced by the given
+// type reference node, if any.
+func expandTypeAlias(tctx translationContext, typeRefNode *dslNode) ([]*core.AllowedRelation, bool, error) {
+	typePath, err := typeRefNode.GetString(dslshape.NodeSpecificReferencePredicateType)
+	if err != nil {
+		return nil, false, typeRefNode.Errorf("invalid type name: %w", err)
+	}
+
+	alias, ok := tctx.typeAliases[typePath]
+	if !ok {
+		return nil, false, nil
+	}
+
+	if typeRefNode.Has(dslshape.NodeSpecificReferencePredicateWildcard) ||
+		typeRefNode.Has(dslshape.NodeSpecificReferencePredicateRelation) ||
+		len(typeRefNode.List(dslshape.NodeSpecificReferencePredicateCaveat)) > 0 {
+		return nil, true, typeRefNode.ErrorWithSourcef(typePath, "type alias `%s` cannot be used with a relation, wildcard or caveat", typePath)
+	}
+
+	expanded := make([]*core.AllowedRelation, 0, len(alias.AllowedRelations))
+	for _, allowedRelation := range alias.AllowedRelations {
+		cloned := proto.Clone(allowedRelation).(*core.AllowedRelation)
+		cloned.SourcePosition = getSourcePosition(typeRefNode, tctx.mapper)
+		expanded = append(expanded, cloned)
+	}
+	return expanded, true, nil
+}
+
 func translateSpecificTypeReference(tctx translationContext, typeRefNode *dslNode) (*core.AllowedRelation, error) {
 	typePath, err := typeRefNode.GetString(dslshape.NodeSpecificReferencePredicateType)
 	if err != nil {
@@ -35,6 +35,8 @@ const (
 	NodeTypeNilExpression // A nil keyword
 
 	NodeTypeCaveatTypeReference // A type reference for a caveat parameter.
+
+	NodeTypeTypeAlias // A type alias.
 )
 
 const (
@@ -118,6 +120,16 @@ const (
 	// The child type(s) for the type reference.
 	NodeCaveatTypeReferencePredicateChildTypes = "child-types"
 
+	//
+	// NodeTypeTypeAlias
+	//
+
+	// The name of the type alias.
+	NodeTypeAliasPredicateName = "alias-name"
+
+	// The type reference to which the alias expands.
+	NodeTypeAliasPredicateType = "alias-type"
+
 	//
 	// NodeTypeRelation + NodeTypePermission
 	//
@@ -132,6 +144,9 @@ const (
 	// The allowed types for the relation.
 	NodeRelationPredicateAllowedTypes = "allowed-types"
 
+	// The expression from which a synthetic relation is computed.
+	NodeRelationPredicateSyntheticExpression = "synthetic-expression"
+
 	//
 	// NodeTypeTypeReference
 	//
@@ -27,11 +27,12 @@ func _() {
 	_ = x[NodeTypeIdentifier-16]
 	_ = x[NodeTypeNilExpression-17]
 	_ = x[NodeTypeCaveatTypeReference-18]
+	_ = x[NodeTypeTypeAlias-19]
 }
 
-const _NodeType_name = "NodeTypeErrorNodeTypeFileNodeTypeCommentNodeTypeDefinitionNodeTypeCaveatDefinitionNodeTypeCaveatParameterNodeTypeCaveatExpessionNodeTypeRelationNodeTypePermissionNodeTypeTypeReferenceNodeTypeSpecificTypeReferenceNodeTypeCaveatReferenceNodeTypeUnionExpressionNodeTypeIntersectExpressionNodeTypeExclusionExpressionNodeTypeArrowExpressionNodeTypeIdentifierNodeTypeNilExpressionNodeTypeCaveatTypeReference"
+const _NodeType_name = "NodeTypeErrorNodeTypeFileNodeTypeCommentNodeTypeDefinitionNodeTypeCaveatDefinitionNodeTypeCaveatParameterNodeTypeCaveatExpessionNodeTypeRelationNodeTypePermissionNodeTypeTypeReferenceNodeTypeSpecificTypeReferenceNodeTypeCaveatReferenceNodeTypeUnionExpressionNodeTypeIntersectExpressionNodeTypeExclusionExpressionNodeTypeArrowExpressionNodeTypeIdentifierNodeTypeNilExpressionNodeTypeCaveatTypeReferenceNodeTypeTypeAlias"
 
-var _NodeType_index = [...]uint16{0, 13, 25, 40, 58, 82, 105, 128, 144, 162, 183, 212, 235, 258, 285, 312, 335, 353, 374, 401}
+var _NodeType_index = [...]uint16{0, 13, 25, 40, 58, 82, 105, 128, 144, 162, 183, 212, 235, 258, 285, 312, 335, 353, 374, 401, 418}
 
 func (i NodeType) String() string {
 	if i < 0 || i >= NodeType(len(_NodeType_index)-1) {
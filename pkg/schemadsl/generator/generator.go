@@ -27,8 +27,21 @@ const MaxSingleLineCommentLength = 70 // 80 - the comment parts and some padding
 
 // GenerateSchema generates a DSL view of the given schema.
 func GenerateSchema(definitions []compiler.SchemaDefinition) (string, bool) {
-	generated := make([]string, 0, len(definitions))
+	return GenerateSchemaWithAliases(definitions, nil)
+}
+
+// GenerateSchemaWithAliases generates a DSL view of the given schema, including the given
+// type aliases. Any run of allowed types on a relation matching the expansion of an alias
+// is emitted as a reference to the alias.
+func GenerateSchemaWithAliases(definitions []compiler.SchemaDefinition, typeAliases []*compiler.TypeAlias) (string, bool) {
+	generated := make([]string, 0, len(definitions)+len(typeAliases))
 	result := true
+	for _, alias := range typeAliases {
+		generatedAlias, ok := GenerateTypeAliasSource(alias)
+		result = result && ok
+		generated = append(generated, generatedAlias)
+	}
+
 	for _, definition := range definitions {
 		switch def := definition.(type) {
 		case *core.CaveatDefinition:
@@ -37,7 +50,7 @@ func GenerateSchema(definitions []compiler.SchemaDefinition) (string, bool) {
 			generated = append(generated, generatedCaveat)
 
 		case *core.NamespaceDefinition:
-			generatedSchema, ok := GenerateSource(def)
+			generatedSchema, ok := generateSourceWithAliases(def, typeAliases)
 			result = result && ok
 			generated = append(generated, generatedSchema)
 
@@ -62,13 +75,48 @@ func GenerateCaveatSource(caveat *core.CaveatDefinition) (string, bool) {
 	return generator.buf.String(), !generator.hasIssue
 }
 
+// GenerateTypeAliasSource generates a DSL view of the given type alias.
+func GenerateTypeAliasSource(alias *compiler.TypeAlias) (string, bool) {
+	generator := &sourceGenerator{
+		indentationLevel: 0,
+		hasNewline:       true,
+		hasBlankline:     true,
+		hasNewScope:      true,
+	}
+
+	generator.append("alias ")
+	generator.append(alias.Name)
+	generator.append(" = ")
+	generator.emitAllowedRelations(alias.AllowedRelations)
+	return generator.buf.String(), !generator.hasIssue
+}
+
 // GenerateSource generates a DSL view of the given namespace definition.
 func GenerateSource(namespace *core.NamespaceDefinition) (string, bool) {
+	return generateSourceWithAliases(namespace, nil)
+}
+
+// GenerateRewriteSource generates a DSL view of the given rewrite, as found on the right-hand side
+// of a permission.
+func GenerateRewriteSource(rewrite *core.UsersetRewrite) (string, bool) {
+	generator := &sourceGenerator{
+		indentationLevel: 0,
+		hasNewline:       true,
+		hasBlankline:     true,
+		hasNewScope:      true,
+	}
+
+	generator.emitRewrite(rewrite)
+	return generator.buf.String(), !generator.hasIssue
+}
+
+func generateSourceWithAliases(namespace *core.NamespaceDefinition, typeAliases []*compiler.TypeAlias) (string, bool) {
 	generator := &sourceGenerator{
 		indentationLevel: 0,
 		hasNewline:       true,
 		hasBlankline:     true,
 		hasNewScope:      true,
+		typeAliases:      typeAliases,
 	}
 
 	generator.emitNamespace(namespace)
@@ -164,13 +212,12 @@ func (sg *sourceGenerator) emitRelation(relation *core.Relation) {
 		if relation.TypeInformation == nil || relation.TypeInformation.AllowedDirectRelations == nil || len(relation.TypeInformation.AllowedDirectRelations) == 0 {
 			sg.appendIssue("missing allowed types")
 		} else {
-			for index, allowedRelation := range relation.TypeInformation.AllowedDirectRelations {
-				if index > 0 {
-					sg.append(" | ")
-				}
+			sg.emitAllowedRelations(relation.TypeInformation.AllowedDirectRelations)
+		}
 
-				sg.emitAllowedRelation(allowedRelation)
-			}
+		if sourceRelationNames, ok := namespace.GetSyntheticSources(relation); ok {
+			sg.append(" = ")
+			sg.append(strings.Join(sourceRelationNames, " + "))
 		}
 	}
 
@@ -182,6 +229,52 @@ func (sg *sourceGenerator) emitRelation(relation *core.Relation) {
 	sg.appendLine()
 }
 
+func (sg *sourceGenerator) emitAllowedRelations(allowedRelations []*core.AllowedRelation) {
+	for index := 0; index < len(allowedRelations); index++ {
+		if index > 0 {
+			sg.append(" | ")
+		}
+
+		if alias := sg.matchingTypeAlias(allowedRelations[index:]); alias != nil {
+			sg.append(alias.Name)
+			index += len(alias.AllowedRelations) - 1
+			continue
+		}
+
+		sg.emitAllowedRelation(allowedRelations[index])
+	}
+}
+
+// matchingTypeAlias returns the first type alias whose expansion is a prefix of the
+// given allowed relations, if any.
+func (sg *sourceGenerator) matchingTypeAlias(allowedRelations []*core.AllowedRelation) *compiler.TypeAlias {
+	for _, alias := range sg.typeAliases {
+		if len(alias.AllowedRelations) == 0 || len(alias.AllowedRelations) > len(allowedRelations) {
+			continue
+		}
+
+		matches := true
+		for index, aliased := range alias.AllowedRelations {
+			if !sameAllowedRelation(aliased, allowedRelations[index]) {
+				matches = false
+				break
+			}
+		}
+
+		if matches {
+			return alias
+		}
+	}
+	return nil
+}
+
+func sameAllowedRelation(first *core.AllowedRelation, second *core.AllowedRelation) bool {
+	return first.Namespace == second.Namespace &&
+		first.GetRelation() == second.GetRelation() &&
+		(first.GetPublicWildcard() != nil) == (second.GetPublicWildcard() != nil) &&
+		first.GetRequiredCaveat().GetCaveatName() == second.GetRequiredCaveat().GetCaveatName()
+}
+
 func (sg *sourceGenerator) emitAllowedRelation(allowedRelation *core.AllowedRelation) {
 	sg.append(allowedRelation.Namespace)
 	if allowedRelation.GetRelation() != "" && allowedRelation.GetRelation() != Ellipsis {
@@ -266,11 +359,13 @@ func (sg *sourceGenerator) emitSetOpChild(setOpChild *core.SetOperation_Child) {
 }
 
 func (sg *sourceGenerator) emitComments(metadata *core.Metadata) {
-	if len(namespace.GetComments(metadata)) > 0 {
+	// Markers added by SpiceDB are not emitted.
+	comments := namespace.GetUserComments(metadata)
+	if len(comments) > 0 {
 		sg.ensureBlankLineOrNewScope()
 	}
 
-	for _, comment := range namespace.GetComments(metadata) {
+	for _, comment := range comments {
 		sg.appendComment(comment)
 	}
 }
@@ -2,6 +2,8 @@ package generator
 
 import (
 	"strings"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
 )
 
 type sourceGenerator struct {
@@ -12,6 +14,8 @@ type sourceGenerator struct {
 	hasIssue           bool            // Whether there is a translation issue.
 	hasNewScope        bool            // Whether there is a new scope at the end of the buffer.
 	existingLineLength int             // Length of the existing line.
+
+	typeAliases []*compiler.TypeAlias // The type aliases to emit in place of their expansions.
 }
 
 // ensureBlankLineOrNewScope ensures that there is a blank line or new scope at the tail of the buffer. If not,
@@ -347,6 +347,27 @@ definition foos/document {
 	permission read = reader + writer + another
 	permission write = writer
 	permission minus = (rela - relb) - relc
+}`,
+		},
+		{
+			"synthetic relation",
+			`definition foos/test {
+				relation admin: foos/user
+				relation viewer: foos/user | foos/team#member
+
+				// any member of the resource
+				relation any_member: foos/user | foos/team#member = admin + viewer
+				relation admins: foos/user = admin
+				permission view = any_member
+			}`,
+			`definition foos/test {
+	relation admin: foos/user
+	relation viewer: foos/user | foos/team#member
+
+	// any member of the resource
+	relation any_member: foos/user | foos/team#member = admin + viewer
+	relation admins: foos/user = admin
+	permission view = any_member
 }`,
 		},
 	}
@@ -365,3 +386,65 @@ definition foos/document {
 		})
 	}
 }
+
+func TestFormattingWithAliases(t *testing.T) {
+	type formattingTest struct {
+		name     string
+		input    string
+		expected string
+	}
+
+	tests := []formattingTest{
+		{
+			"no aliases",
+			`definition foos/test {
+				relation viewer: foos/user | foos/team#member
+			}`,
+			`definition foos/test {
+	relation viewer: foos/user | foos/team#member
+}`,
+		},
+		{
+			"type alias",
+			`alias subject = foos/user | foos/team#member
+			definition foos/test {
+				relation viewer: subject | foos/user:*
+				relation editor: foos/bot | subject
+				relation owner: foos/user
+			}`,
+			`alias subject = foos/user | foos/team#member
+
+definition foos/test {
+	relation viewer: subject | foos/user:*
+	relation editor: foos/bot | subject
+	relation owner: foos/user
+}`,
+		},
+		{
+			"expansion without alias reference",
+			`alias subject = foos/user | foos/team#member
+			definition foos/test {
+				relation viewer: foos/user | foos/team#member
+			}`,
+			`alias subject = foos/user | foos/team#member
+
+definition foos/test {
+	relation viewer: subject
+}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			compiled, err := compiler.Compile(compiler.InputSchema{
+				Source:       input.Source(test.name),
+				SchemaString: test.input,
+			}, nil)
+			require.NoError(err)
+
+			source, _ := GenerateSchemaWithAliases(compiled.OrderedDefinitions, compiled.TypeAliases)
+			require.Equal(test.expected, source)
+		})
+	}
+}
@@ -0,0 +1,145 @@
+// Package importer converts authorization models written for other
+// Zanzibar-inspired systems into SpiceDB schema.
+package importer
+
+import (
+	"fmt"
+
+	"github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+// DirectRelationSuffix is the suffix appended to the name of a relation that is
+// split out of a source relation which mixes directly assigned subjects with
+// computed subjects.
+const DirectRelationSuffix = "_direct"
+
+// Result is the result of importing an external authorization model.
+type Result struct {
+	// Schema is the generated SpiceDB schema DSL.
+	Schema string
+
+	// Compiled is the compiled form of the generated schema.
+	Compiled *compiler.CompiledSchema
+
+	// Warnings holds human-readable notes about parts of the source model that
+	// could not be translated one-to-one, such as relations that were split.
+	Warnings []string
+}
+
+type importContext struct {
+	definitions []compiler.SchemaDefinition
+	warnings    []string
+}
+
+func (ic *importContext) warnf(format string, args ...any) {
+	ic.warnings = append(ic.warnings, fmt.Sprintf(format, args...))
+}
+
+// addRelation adds the relation with the given name, rewrite and allowed subject types
+// to the relations list. Since the schema DSL distinguishes between relations
+// (directly assigned subjects) and permissions (computed subjects), a source relation
+// which mixes both is split into a relation holding the direct subjects and a permission,
+// with the original name, which includes them.
+func (ic *importContext) addRelation(
+	nsName string,
+	relName string,
+	rewrite *core.UsersetRewrite,
+	allowedTypes []*core.AllowedRelation,
+	relations []*core.Relation,
+) ([]*core.Relation, error) {
+	if rewrite == nil || isOnlyThis(rewrite) {
+		if len(allowedTypes) == 0 {
+			return nil, fmt.Errorf("relation `%s#%s` has no allowed subject types", nsName, relName)
+		}
+		return append(relations, namespace.Relation(relName, nil, allowedTypes...)), nil
+	}
+
+	if !containsThis(rewrite) {
+		return append(relations, namespace.Relation(relName, rewrite)), nil
+	}
+
+	if len(allowedTypes) == 0 {
+		return nil, fmt.Errorf("relation `%s#%s` has no allowed subject types", nsName, relName)
+	}
+
+	directName := relName + DirectRelationSuffix
+	ic.warnf(
+		"relation `%s#%s` mixes direct and computed subjects: directly assigned subjects are now stored under `%s#%s`; existing relationships must be rewritten",
+		nsName, relName, nsName, directName,
+	)
+
+	return append(relations,
+		namespace.Relation(directName, nil, allowedTypes...),
+		namespace.Relation(relName, replaceThis(rewrite, directName)),
+	), nil
+}
+
+func (ic *importContext) result() (*Result, error) {
+	source, _ := generator.GenerateSchema(ic.definitions)
+
+	emptyDefaultPrefix := ""
+	compiled, err := compiler.Compile(compiler.InputSchema{
+		Source:       input.Source("schema"),
+		SchemaString: source,
+	}, &emptyDefaultPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("imported model does not form a valid schema: %w", err)
+	}
+
+	return &Result{
+		Schema:   source,
+		Compiled: compiled,
+		Warnings: ic.warnings,
+	}, nil
+}
+
+func isOnlyThis(rewrite *core.UsersetRewrite) bool {
+	union := rewrite.GetUnion()
+	if union == nil || len(union.Child) != 1 {
+		return false
+	}
+	return union.Child[0].GetXThis() != nil
+}
+
+func containsThis(rewrite *core.UsersetRewrite) bool {
+	for _, child := range setOperationOf(rewrite).GetChild() {
+		if child.GetXThis() != nil {
+			return true
+		}
+		if nested := child.GetUsersetRewrite(); nested != nil && containsThis(nested) {
+			return true
+		}
+	}
+	return false
+}
+
+// replaceThis rewrites all `_this` references found in the rewrite into computed
+// usersets over the given relation.
+func replaceThis(rewrite *core.UsersetRewrite, relation string) *core.UsersetRewrite {
+	for _, child := range setOperationOf(rewrite).GetChild() {
+		switch {
+		case child.GetXThis() != nil:
+			child.ChildType = namespace.ComputedUserset(relation).ChildType
+		case child.GetUsersetRewrite() != nil:
+			replaceThis(child.GetUsersetRewrite(), relation)
+		}
+	}
+	return rewrite
+}
+
+func setOperationOf(rewrite *core.UsersetRewrite) *core.SetOperation {
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		return rw.Union
+	case *core.UsersetRewrite_Intersection:
+		return rw.Intersection
+	case *core.UsersetRewrite_Exclusion:
+		return rw.Exclusion
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,217 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFromOpenFGAModel(t *testing.T) {
+	tests := []struct {
+		name             string
+		model            string
+		expectedSchema   string
+		expectedWarnings int
+		expectedError    string
+	}{
+		{
+			"basic",
+			`{
+				"schema_version": "1.1",
+				"type_definitions": [
+					{"type": "user"},
+					{
+						"type": "document",
+						"relations": {
+							"owner": {"this": {}},
+							"viewer": {"computedUserset": {"relation": "owner"}}
+						},
+						"metadata": {"relations": {"owner": {"directly_related_user_types": [{"type": "user"}]}}}
+					}
+				]
+			}`,
+			`definition user {}
+
+definition document {
+	relation owner: user
+	permission viewer = owner
+}`,
+			0,
+			"",
+		},
+		{
+			"mixed direct and computed",
+			`{
+				"schema_version": "1.1",
+				"type_definitions": [
+					{"type": "user"},
+					{
+						"type": "group",
+						"relations": {"member": {"this": {}}},
+						"metadata": {"relations": {"member": {"directly_related_user_types": [{"type": "user"}]}}}
+					},
+					{
+						"type": "folder",
+						"relations": {"viewer": {"this": {}}},
+						"metadata": {"relations": {"viewer": {"directly_related_user_types": [{"type": "user"}]}}}
+					},
+					{
+						"type": "document",
+						"relations": {
+							"parent": {"this": {}},
+							"banned": {"this": {}},
+							"viewer": {
+								"difference": {
+									"base": {"union": {"child": [
+										{"this": {}},
+										{"tupleToUserset": {"tupleset": {"relation": "parent"}, "computedUserset": {"relation": "viewer"}}}
+									]}},
+									"subtract": {"computedUserset": {"relation": "banned"}}
+								}
+							}
+						},
+						"metadata": {"relations": {
+							"parent": {"directly_related_user_types": [{"type": "folder"}]},
+							"banned": {"directly_related_user_types": [{"type": "user"}]},
+							"viewer": {"directly_related_user_types": [{"type": "user"}, {"type": "user", "wildcard": {}}, {"type": "group", "relation": "member"}]}
+						}}
+					}
+				]
+			}`,
+			`definition user {}
+
+definition group {
+	relation member: user
+}
+
+definition folder {
+	relation viewer: user
+}
+
+definition document {
+	relation banned: user
+	relation parent: folder
+	relation viewer_direct: user | user:* | group#member
+	permission viewer = viewer_direct + parent->viewer - banned
+}`,
+			1,
+			"",
+		},
+		{
+			"conditions",
+			`{
+				"schema_version": "1.1",
+				"type_definitions": [
+					{"type": "user"},
+					{
+						"type": "document",
+						"relations": {"viewer": {"this": {}}},
+						"metadata": {"relations": {"viewer": {"directly_related_user_types": [{"type": "user", "condition": "in_range"}]}}}
+					}
+				],
+				"conditions": {
+					"in_range": {
+						"name": "in_range",
+						"expression": "x < max",
+						"parameters": {
+							"x": {"type_name": "TYPE_NAME_INT"},
+							"max": {"type_name": "TYPE_NAME_INT"}
+						}
+					}
+				}
+			}`,
+			`caveat in_range(max int, x int) {
+	x < max
+}
+
+definition user {}
+
+definition document {
+	relation viewer: user with in_range
+}`,
+			0,
+			"",
+		},
+		{
+			"missing types",
+			`{
+				"schema_version": "1.1",
+				"type_definitions": [{"type": "document", "relations": {"viewer": {"this": {}}}}]
+			}`,
+			"",
+			0,
+			"relation `document#viewer` has no allowed subject types",
+		},
+		{
+			"unknown condition type",
+			`{
+				"schema_version": "1.1",
+				"type_definitions": [],
+				"conditions": {"c": {"name": "c", "expression": "true", "parameters": {"x": {"type_name": "TYPE_NAME_UNKNOWN"}}}}
+			}`,
+			"",
+			0,
+			"unsupported parameter type `TYPE_NAME_UNKNOWN`",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require := require.New(t)
+			result, err := FromOpenFGAModel([]byte(tt.model))
+			if tt.expectedError != "" {
+				require.ErrorContains(err, tt.expectedError)
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(tt.expectedSchema, strings.TrimSpace(result.Schema))
+			require.Len(result.Warnings, tt.expectedWarnings)
+		})
+	}
+}
+
+func TestFromZanzibarNamespaceConfigs(t *testing.T) {
+	require := require.New(t)
+
+	result, err := FromZanzibarNamespaceConfigs([]string{
+		`name: "doc"
+		relation { name: "owner" }
+		relation {
+			name: "editor"
+			userset_rewrite {
+				union {
+					child { _this {} }
+					child { computed_userset { relation: "owner" } }
+				}
+			}
+		}
+		relation {
+			name: "viewer"
+			userset_rewrite {
+				union {
+					child { computed_userset { relation: "editor" } }
+				}
+			}
+		}`,
+	}, []string{"user"})
+	require.NoError(err)
+	require.Equal(`definition doc {
+	relation owner: user
+	relation editor_direct: user
+	permission editor = editor_direct + owner
+	permission viewer = editor
+}
+
+definition user {}`, strings.TrimSpace(result.Schema))
+	require.Len(result.Warnings, 1)
+	require.Len(result.Compiled.ObjectDefinitions, 2)
+
+	_, err = FromZanzibarNamespaceConfigs([]string{`name: "doc" relation { name: "owner" }`}, nil)
+	require.ErrorContains(err, "relation `doc#owner` has no allowed subject types")
+
+	_, err = FromZanzibarNamespaceConfigs([]string{`name: `}, nil)
+	require.ErrorContains(err, "could not parse namespace config #1")
+}
@@ -0,0 +1,272 @@
+package importer
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"golang.org/x/exp/maps"
+
+	"github.com/authzed/spicedb/pkg/caveats"
+	caveattypes "github.com/authzed/spicedb/pkg/caveats/types"
+	"github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// openFGAModel is the JSON form of an OpenFGA authorization model, as returned by the
+// OpenFGA API or produced by `fga model transform`.
+type openFGAModel struct {
+	SchemaVersion   string                      `json:"schema_version"`
+	TypeDefinitions []openFGATypeDefinition     `json:"type_definitions"`
+	Conditions      map[string]openFGACondition `json:"conditions"`
+}
+
+type openFGATypeDefinition struct {
+	Type      string                     `json:"type"`
+	Relations map[string]*openFGAUserset `json:"relations"`
+	Metadata  *struct {
+		Relations map[string]struct {
+			DirectlyRelatedUserTypes []openFGARelationReference `json:"directly_related_user_types"`
+		} `json:"relations"`
+	} `json:"metadata"`
+}
+
+type openFGARelationReference struct {
+	Type      string    `json:"type"`
+	Relation  string    `json:"relation"`
+	Wildcard  *struct{} `json:"wildcard"`
+	Condition string    `json:"condition"`
+}
+
+type openFGAUserset struct {
+	This            *struct{} `json:"this"`
+	ComputedUserset *struct {
+		Relation string `json:"relation"`
+	} `json:"computedUserset"`
+	TupleToUserset *struct {
+		Tupleset struct {
+			Relation string `json:"relation"`
+		} `json:"tupleset"`
+		ComputedUserset struct {
+			Relation string `json:"relation"`
+		} `json:"computedUserset"`
+	} `json:"tupleToUserset"`
+	Union *struct {
+		Child []*openFGAUserset `json:"child"`
+	} `json:"union"`
+	Intersection *struct {
+		Child []*openFGAUserset `json:"child"`
+	} `json:"intersection"`
+	Difference *struct {
+		Base     *openFGAUserset `json:"base"`
+		Subtract *openFGAUserset `json:"subtract"`
+	} `json:"difference"`
+}
+
+type openFGACondition struct {
+	Name       string                          `json:"name"`
+	Expression string                          `json:"expression"`
+	Parameters map[string]openFGAParameterType `json:"parameters"`
+}
+
+type openFGAParameterType struct {
+	TypeName     string                 `json:"type_name"`
+	GenericTypes []openFGAParameterType `json:"generic_types"`
+}
+
+var openFGATypeNames = map[string]string{
+	"TYPE_NAME_ANY":       "any",
+	"TYPE_NAME_BOOL":      "bool",
+	"TYPE_NAME_STRING":    "string",
+	"TYPE_NAME_INT":       "int",
+	"TYPE_NAME_UINT":      "uint",
+	"TYPE_NAME_DOUBLE":    "double",
+	"TYPE_NAME_DURATION":  "duration",
+	"TYPE_NAME_TIMESTAMP": "timestamp",
+	"TYPE_NAME_MAP":       "map",
+	"TYPE_NAME_LIST":      "list",
+	"TYPE_NAME_IPADDRESS": "ipaddress",
+}
+
+// FromOpenFGAModel converts an OpenFGA authorization model, in its JSON form, into a
+// SpiceDB schema. Conditions are translated into caveats.
+func FromOpenFGAModel(modelJSON []byte) (*Result, error) {
+	var model openFGAModel
+	if err := json.Unmarshal(modelJSON, &model); err != nil {
+		return nil, fmt.Errorf("could not parse OpenFGA model: %w", err)
+	}
+
+	ic := &importContext{}
+
+	conditionNames := maps.Keys(model.Conditions)
+	sort.Strings(conditionNames)
+	for _, conditionName := range conditionNames {
+		caveat, err := convertOpenFGACondition(conditionName, model.Conditions[conditionName])
+		if err != nil {
+			return nil, err
+		}
+		ic.definitions = append(ic.definitions, caveat)
+	}
+
+	for _, typeDef := range model.TypeDefinitions {
+		relationNames := maps.Keys(typeDef.Relations)
+		sort.Strings(relationNames)
+
+		relations := make([]*core.Relation, 0, len(relationNames))
+		for _, relationName := range relationNames {
+			rewrite, err := convertOpenFGAUserset(typeDef.Type, relationName, typeDef.Relations[relationName])
+			if err != nil {
+				return nil, err
+			}
+
+			var allowedTypes []*core.AllowedRelation
+			if typeDef.Metadata != nil {
+				for _, ref := range typeDef.Metadata.Relations[relationName].DirectlyRelatedUserTypes {
+					allowedTypes = append(allowedTypes, convertOpenFGAReference(ref))
+				}
+			}
+
+			relations, err = ic.addRelation(typeDef.Type, relationName, rewrite, allowedTypes, relations)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ic.definitions = append(ic.definitions, namespace.Namespace(typeDef.Type, relations...))
+	}
+
+	return ic.result()
+}
+
+func convertOpenFGAReference(ref openFGARelationReference) *core.AllowedRelation {
+	var allowed *core.AllowedRelation
+	switch {
+	case ref.Wildcard != nil:
+		allowed = namespace.AllowedPublicNamespace(ref.Type)
+	case ref.Relation != "":
+		allowed = namespace.AllowedRelation(ref.Type, ref.Relation)
+	default:
+		allowed = namespace.AllowedRelation(ref.Type, "...")
+	}
+
+	if ref.Condition != "" {
+		allowed.RequiredCaveat = namespace.AllowedCaveat(ref.Condition)
+	}
+	return allowed
+}
+
+func convertOpenFGAUserset(typeName, relationName string, userset *openFGAUserset) (*core.UsersetRewrite, error) {
+	if userset == nil {
+		return nil, nil
+	}
+
+	child, err := convertOpenFGAChild(typeName, relationName, userset)
+	if err != nil {
+		return nil, err
+	}
+
+	if rewrite := child.GetUsersetRewrite(); rewrite != nil {
+		return rewrite, nil
+	}
+	return namespace.Union(child), nil
+}
+
+func convertOpenFGAChild(typeName, relationName string, userset *openFGAUserset) (*core.SetOperation_Child, error) {
+	convertChildren := func(children []*openFGAUserset) ([]*core.SetOperation_Child, error) {
+		if len(children) == 0 {
+			return nil, fmt.Errorf("relation `%s#%s` has a set operation without children", typeName, relationName)
+		}
+
+		converted := make([]*core.SetOperation_Child, 0, len(children))
+		for _, child := range children {
+			if child == nil {
+				return nil, fmt.Errorf("relation `%s#%s` has an empty userset", typeName, relationName)
+			}
+
+			c, err := convertOpenFGAChild(typeName, relationName, child)
+			if err != nil {
+				return nil, err
+			}
+			converted = append(converted, c)
+		}
+		return converted, nil
+	}
+
+	switch {
+	case userset.This != nil:
+		return &core.SetOperation_Child{ChildType: &core.SetOperation_Child_XThis{XThis: &core.SetOperation_Child_This{}}}, nil
+
+	case userset.ComputedUserset != nil:
+		return namespace.ComputedUserset(userset.ComputedUserset.Relation), nil
+
+	case userset.TupleToUserset != nil:
+		return namespace.TupleToUserset(
+			userset.TupleToUserset.Tupleset.Relation,
+			userset.TupleToUserset.ComputedUserset.Relation,
+		), nil
+
+	case userset.Union != nil:
+		children, err := convertChildren(userset.Union.Child)
+		if err != nil {
+			return nil, err
+		}
+		return namespace.Rewrite(namespace.Union(children[0], children[1:]...)), nil
+
+	case userset.Intersection != nil:
+		children, err := convertChildren(userset.Intersection.Child)
+		if err != nil {
+			return nil, err
+		}
+		return namespace.Rewrite(namespace.Intersection(children[0], children[1:]...)), nil
+
+	case userset.Difference != nil:
+		children, err := convertChildren([]*openFGAUserset{userset.Difference.Base, userset.Difference.Subtract})
+		if err != nil {
+			return nil, err
+		}
+		return namespace.Rewrite(namespace.Exclusion(children[0], children[1])), nil
+
+	default:
+		return nil, fmt.Errorf("relation `%s#%s` has an unsupported or empty userset", typeName, relationName)
+	}
+}
+
+func convertOpenFGACondition(name string, condition openFGACondition) (*core.CaveatDefinition, error) {
+	parameters := make(map[string]caveattypes.VariableType, len(condition.Parameters))
+	for paramName, paramType := range condition.Parameters {
+		varType, err := convertOpenFGAParameterType(paramType)
+		if err != nil {
+			return nil, fmt.Errorf("condition `%s` parameter `%s`: %w", name, paramName, err)
+		}
+		parameters[paramName] = *varType
+	}
+
+	env, err := caveats.EnvForVariables(parameters)
+	if err != nil {
+		return nil, fmt.Errorf("condition `%s`: %w", name, err)
+	}
+
+	caveat, err := namespace.CaveatDefinition(env, name, condition.Expression)
+	if err != nil {
+		return nil, fmt.Errorf("condition `%s` could not be converted into a caveat: %w", name, err)
+	}
+	return caveat, nil
+}
+
+func convertOpenFGAParameterType(paramType openFGAParameterType) (*caveattypes.VariableType, error) {
+	typeName, ok := openFGATypeNames[paramType.TypeName]
+	if !ok {
+		return nil, fmt.Errorf("unsupported parameter type `%s`", paramType.TypeName)
+	}
+
+	childTypes := make([]caveattypes.VariableType, 0, len(paramType.GenericTypes))
+	for _, generic := range paramType.GenericTypes {
+		childType, err := convertOpenFGAParameterType(generic)
+		if err != nil {
+			return nil, err
+		}
+		childTypes = append(childTypes, *childType)
+	}
+
+	return caveattypes.BuildType(typeName, childTypes)
+}
@@ -0,0 +1,57 @@
+package importer
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/prototext"
+
+	"github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// FromZanzibarNamespaceConfigs converts one or more Zanzibar-style namespace
+// configurations, in protobuf text format, into a SpiceDB schema.
+//
+// Zanzibar namespace configurations do not declare which subject types may be
+// written to a relation, so the types found in `type_information`, if any, are
+// used and otherwise the given default subject types (such as `user`) are allowed.
+// Default subject types without a namespace configuration of their own are added
+// as empty definitions.
+func FromZanzibarNamespaceConfigs(configs []string, defaultSubjectTypes []string) (*Result, error) {
+	ic := &importContext{}
+	defined := make(map[string]struct{}, len(configs))
+	for index, config := range configs {
+		nsDef := &core.NamespaceDefinition{}
+		if err := prototext.Unmarshal([]byte(config), nsDef); err != nil {
+			return nil, fmt.Errorf("could not parse namespace config #%d: %w", index+1, err)
+		}
+
+		relations := make([]*core.Relation, 0, len(nsDef.Relation))
+		for _, relation := range nsDef.Relation {
+			allowedTypes := relation.GetTypeInformation().GetAllowedDirectRelations()
+			if len(allowedTypes) == 0 {
+				for _, subjectType := range defaultSubjectTypes {
+					allowedTypes = append(allowedTypes, namespace.AllowedRelation(subjectType, "..."))
+				}
+			}
+
+			var err error
+			relations, err = ic.addRelation(nsDef.Name, relation.Name, relation.UsersetRewrite, allowedTypes, relations)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		ic.definitions = append(ic.definitions, namespace.Namespace(nsDef.Name, relations...))
+		defined[nsDef.Name] = struct{}{}
+	}
+
+	for _, subjectType := range defaultSubjectTypes {
+		if _, ok := defined[subjectType]; !ok {
+			ic.definitions = append(ic.definitions, namespace.Namespace(subjectType))
+			defined[subjectType] = struct{}{}
+		}
+	}
+
+	return ic.result()
+}
@@ -51,9 +51,10 @@ Loop:
 			break Loop
 		}
 
-		// The top level of the DSL is a set of definitions and caveats:
+		// The top level of the DSL is a set of definitions, caveats and type aliases:
 		// definition foobar { ... }
 		// caveat somecaveat (...) { ... }
+		// alias somealias = sometype | anothertype
 
 		switch {
 		case p.isKeyword("definition"):
@@ -62,6 +63,9 @@ Loop:
 		case p.isKeyword("caveat"):
 			rootNode.Connect(dslshape.NodePredicateChild, p.consumeCaveat())
 
+		case p.isContextualKeyword("alias"):
+			rootNode.Connect(dslshape.NodePredicateChild, p.consumeTypeAlias())
+
 		default:
 			p.emitErrorf("Unexpected token at root level: %v", p.currentToken.Kind)
 			break Loop
@@ -71,6 +75,32 @@ Loop:
 	return rootNode
 }
 
+// consumeTypeAlias attempts to consume a single type alias.
+// ```alias somealias = sometype | anothertype```
+func (p *sourceParser) consumeTypeAlias() AstNode {
+	aliasNode := p.startNode(dslshape.NodeTypeTypeAlias)
+	defer p.finishNode()
+
+	// alias ...
+	p.consumeContextualKeyword("alias")
+	aliasName, ok := p.consumeIdentifier()
+	if !ok {
+		return aliasNode
+	}
+
+	aliasNode.Decorate(dslshape.NodeTypeAliasPredicateName, aliasName)
+
+	// =
+	_, ok = p.consume(lexer.TokenTypeEquals)
+	if !ok {
+		return aliasNode
+	}
+
+	// Aliased type(s).
+	aliasNode.Connect(dslshape.NodeTypeAliasPredicateType, p.consumeTypeReference())
+	return aliasNode
+}
+
 // consumeCaveat attempts to consume a single caveat definition.
 // ```caveat somecaveat(param1 type, param2 type) { ... }```
 func (p *sourceParser) consumeCaveat() AstNode {
@@ -274,8 +304,9 @@ func (p *sourceParser) consumeDefinition() AstNode {
 	return defNode
 }
 
-// consumeRelation consumes a relation.
+// consumeRelation consumes a relation, which may be synthetic.
 // ```relation foo: sometype```
+// ```relation foo: sometype = bar + baz```
 func (p *sourceParser) consumeRelation() AstNode {
 	relNode := p.startNode(dslshape.NodeTypeRelation)
 	defer p.finishNode()
@@ -298,6 +329,11 @@ func (p *sourceParser) consumeRelation() AstNode {
 	// Relation allowed type(s).
 	relNode.Connect(dslshape.NodeRelationPredicateAllowedTypes, p.consumeTypeReference())
 
+	// = (optional, for synthetic relations)
+	if _, ok := p.tryConsume(lexer.TokenTypeEquals); ok {
+		relNode.Connect(dslshape.NodeRelationPredicateSyntheticExpression, p.consumeComputeExpression())
+	}
+
 	return relNode
 }
 
@@ -169,6 +169,13 @@ func (p *sourceParser) isKeyword(keyword string) bool {
 	return p.isToken(lexer.TokenTypeKeyword) && p.currentToken.Value == keyword
 }
 
+// isContextualKeyword returns true if the current token is an identifier matching the keyword
+// given. Contextual keywords are only keywords where the parser expects them, and remain valid
+// identifiers everywhere else.
+func (p *sourceParser) isContextualKeyword(keyword string) bool {
+	return p.isToken(lexer.TokenTypeIdentifier) && p.currentToken.Value == keyword
+}
+
 // emitErrorf creates a new error node and attachs it as a child of the current
 // node.
 func (p *sourceParser) emitErrorf(format string, args ...interface{}) {
@@ -198,6 +205,17 @@ func (p *sourceParser) tryConsumeKeyword(keyword string) bool {
 	return true
 }
 
+// consumeContextualKeyword consumes an expected contextual keyword or adds an error node.
+func (p *sourceParser) consumeContextualKeyword(keyword string) bool {
+	if !p.isContextualKeyword(keyword) {
+		p.emitErrorf("Expected keyword %s, found token %v", keyword, p.currentToken.Kind)
+		return false
+	}
+
+	p.consumeToken()
+	return true
+}
+
 // cosumeIdentifier consumes an expected identifier token or adds an error node.
 func (p *sourceParser) consumeIdentifier() (string, bool) {
 	token, ok := p.tryConsume(lexer.TokenTypeIdentifier)
@@ -113,6 +113,8 @@ func TestParser(t *testing.T) {
 		{"complex caveat test", "complexcaveat"},
 		{"empty caveat test", "emptycaveat"},
 		{"unclosed caveat test", "unclosedcaveat"},
+		{"type alias test", "typealias"},
+		{"synthetic relation test", "synthetic"},
 	}
 
 	for _, test := range parserTests {
@@ -5,6 +5,7 @@ import (
 
 	"github.com/authzed/spicedb/pkg/datastore"
 
+	"github.com/authzed/spicedb/internal/datastore/proxy"
 	"github.com/authzed/spicedb/internal/services/shared"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
@@ -27,3 +28,55 @@ func ApplySchemaChanges(
 ) (*shared.AppliedSchemaChanges, error) {
 	return shared.ApplySchemaChangesOverExisting(ctx, rwt, validated, existingCaveats, existingObjectDefs)
 }
+
+// RenameRelation renames a relation, migrating its relationships to the new name in batches of
+// batchSize before swapping all references to it in the schema. See shared.RenameRelation.
+func RenameRelation(
+	ctx context.Context,
+	ds datastore.Datastore,
+	definitionName, relationName, newName string,
+	batchSize uint64,
+	onProgress func(shared.RelationRenameProgress),
+) error {
+	return shared.RenameRelation(ctx, ds, definitionName, relationName, newName, batchSize, onProgress)
+}
+
+// DeleteTenant deletes, in a single transaction, all of the object definitions, along with their
+// relationships, and caveats of a tenant, returning the number of each deleted.
+func DeleteTenant(ctx context.Context, ds datastore.Datastore, tenant string) (int, int, error) {
+	var deletedDefinitions, deletedCaveats int
+	_, err := proxy.NewTenantDatastore(ds, tenant).ReadWriteTx(ctx, func(rwt datastore.ReadWriteTransaction) error {
+		nsDefs, err := rwt.ListNamespaces(ctx)
+		if err != nil {
+			return err
+		}
+		caveats, err := rwt.ListCaveats(ctx)
+		if err != nil {
+			return err
+		}
+
+		nsNames := make([]string, 0, len(nsDefs))
+		for _, nsDef := range nsDefs {
+			nsNames = append(nsNames, nsDef.Name)
+		}
+		if len(nsNames) > 0 {
+			if err := rwt.DeleteNamespaces(ctx, nsNames...); err != nil {
+				return err
+			}
+		}
+
+		caveatNames := make([]string, 0, len(caveats))
+		for _, caveat := range caveats {
+			caveatNames = append(caveatNames, caveat.Name)
+		}
+		if len(caveatNames) > 0 {
+			if err := rwt.DeleteCaveats(ctx, caveatNames); err != nil {
+				return err
+			}
+		}
+
+		deletedDefinitions, deletedCaveats = len(nsNames), len(caveatNames)
+		return nil
+	})
+	return deletedDefinitions, deletedCaveats, err
+}
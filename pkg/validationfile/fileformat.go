@@ -1,6 +1,10 @@
 package validationfile
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
+
 	yamlv3 "gopkg.in/yaml.v3"
 
 	"github.com/authzed/spicedb/pkg/validationfile/blocks"
@@ -22,6 +26,11 @@ type ValidationFile struct {
 	// Schema is the schema.
 	Schema blocks.ParsedSchema `yaml:"schema"`
 
+	// SchemaFile is the path of a file containing the schema, relative to the
+	// validation file. Allows for test files colocated with a schema file. May
+	// not be specified along with `schema`.
+	SchemaFile string `yaml:"schemaFile"`
+
 	// Relationships are the relationships specified in the validation file.
 	Relationships blocks.ParsedRelationships `yaml:"relationships"`
 
@@ -41,6 +50,30 @@ type ValidationFile struct {
 	ValidationTuples []string `yaml:"validation_tuples"`
 }
 
+// ResolveSchemaFile reads and compiles the schema found in the `schemaFile`, if any,
+// resolving its path relative to the directory of the validation file at the given path.
+func (vf *ValidationFile) ResolveSchemaFile(validationFilePath string) error {
+	if vf.SchemaFile == "" {
+		return nil
+	}
+
+	if vf.Schema.Schema != "" {
+		return fmt.Errorf("only one of `schema` and `schemaFile` may be specified")
+	}
+
+	schemaPath := vf.SchemaFile
+	if !filepath.IsAbs(schemaPath) {
+		schemaPath = filepath.Join(filepath.Dir(validationFilePath), schemaPath)
+	}
+
+	contents, err := os.ReadFile(schemaPath)
+	if err != nil {
+		return fmt.Errorf("error when reading schema file %s: %w", schemaPath, err)
+	}
+
+	return vf.Schema.UnmarshalYAML(&yamlv3.Node{Kind: yamlv3.ScalarNode, Value: string(contents)})
+}
+
 // ParseAssertionsBlock parses the given contents as an assertions block.
 func ParseAssertionsBlock(contents []byte) (*blocks.Assertions, error) {
 	return blocks.ParseAssertionsBlock(contents)
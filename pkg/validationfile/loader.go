@@ -69,6 +69,10 @@ func PopulateFromFilesContents(ctx context.Context, ds datastore.Datastore, file
 			return nil, datastore.NoRevision, fmt.Errorf("error when parsing config file %s: %w", filePath, err)
 		}
 
+		if err := parsed.ResolveSchemaFile(filePath); err != nil {
+			return nil, datastore.NoRevision, fmt.Errorf("error when loading schema for config file %s: %w", filePath, err)
+		}
+
 		files = append(files, *parsed)
 
 		// Disallow legacy sections.
@@ -134,12 +138,27 @@ func PopulateFromFilesContents(ctx context.Context, ds datastore.Datastore, file
 			}
 		}
 
-		err = relationships.ValidateRelationshipUpdates(ctx, rwt, updates)
+		err = relationships.ValidateRelationshipUpdates(ctx, rwt, updates, relationships.NoStrictValidation)
+		if err != nil {
+			return err
+		}
+
+		if err := rwt.WriteRelationships(ctx, updates); err != nil {
+			return err
+		}
+
+		// Materialize the relationships of any synthetic relations.
+		synthetic, err := relationships.ReadSyntheticRelations(ctx, rwt)
 		if err != nil {
 			return err
 		}
 
-		return rwt.WriteRelationships(ctx, updates)
+		materialized, err := relationships.AllSyntheticRelationUpdates(ctx, rwt, synthetic)
+		if err != nil || len(materialized) == 0 {
+			return err
+		}
+
+		return rwt.WriteRelationships(ctx, materialized)
 	})
 	return &PopulatedValidationFile{schema, objectDefs, tuples, files}, revision, err
 }
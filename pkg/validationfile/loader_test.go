@@ -64,6 +64,15 @@ func TestPopulateFromFiles(t *testing.T) {
 			},
 			expectedError: "",
 		},
+		{
+			name:      "schema file",
+			filePaths: []string{"testdata/schema_file.yaml"},
+			want: []string{
+				"example/project:pied_piper#owner@example/user:milburga",
+				"example/project:pied_piper#reader@example/user:tarben",
+			},
+			expectedError: "",
+		},
 		{
 			name:          "missing schema",
 			filePaths:     []string{"testdata/just_rels.yaml"},
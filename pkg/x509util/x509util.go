@@ -5,6 +5,8 @@ import (
 	"errors"
 	"io/fs"
 	"os"
+	"sync"
+	"time"
 )
 
 // CustomCertPool creates a x509.CertPool from a filepath string.
@@ -59,3 +61,87 @@ func dirContents(dirPath string) ([][]byte, error) {
 
 	return allContents, nil
 }
+
+// certPoolCheckInterval is the minimum interval between checks for changes to the files of a
+// CertPoolWatcher.
+const certPoolCheckInterval = time.Second
+
+// CertPoolWatcher holds a x509.CertPool created from a filepath string, which is created again
+// whenever the files at the path change.
+type CertPoolWatcher struct {
+	caPath string
+
+	mu        sync.Mutex
+	pool      *x509.CertPool
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+// NewCertPoolWatcher creates a watcher of the x509.CertPool of the path, as in CustomCertPool.
+func NewCertPoolWatcher(caPath string) (*CertPoolWatcher, error) {
+	modTime, err := latestModTime(caPath)
+	if err != nil {
+		return nil, err
+	}
+
+	pool, err := CustomCertPool(caPath)
+	if err != nil {
+		return nil, err
+	}
+	return &CertPoolWatcher{caPath: caPath, pool: pool, modTime: modTime, checkedAt: time.Now()}, nil
+}
+
+// Pool returns the current x509.CertPool of the path. If the files at the path changed, the pool
+// is created again; if that fails, the previous pool is returned along with the error.
+func (w *CertPoolWatcher) Pool() (*x509.CertPool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(w.checkedAt) < certPoolCheckInterval {
+		return w.pool, nil
+	}
+	w.checkedAt = now
+
+	modTime, err := latestModTime(w.caPath)
+	if err != nil {
+		return w.pool, err
+	}
+	if modTime.Equal(w.modTime) {
+		return w.pool, nil
+	}
+
+	pool, err := CustomCertPool(w.caPath)
+	if err != nil {
+		return w.pool, err
+	}
+	w.pool, w.modTime = pool, modTime
+	return w.pool, nil
+}
+
+// latestModTime returns the latest modification time of the path, or of the files in it if it
+// is a directory.
+func latestModTime(path string) (time.Time, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	latest := fi.ModTime()
+	if fi.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return time.Time{}, err
+		}
+		for _, entry := range entries {
+			info, err := entry.Info()
+			if err != nil {
+				return time.Time{}, err
+			}
+			if info.ModTime().After(latest) {
+				latest = info.ModTime()
+			}
+		}
+	}
+	return latest, nil
+}
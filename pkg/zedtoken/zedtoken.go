@@ -2,6 +2,7 @@
 package zedtoken
 
 import (
+	"context"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -24,12 +25,39 @@ const (
 // zedtoken argument to Decode
 var ErrNilZedToken = errors.New("zedtoken pointer was nil")
 
+// ErrDatastoreMismatch occurs when a zedtoken records the revision of a datastore other than the
+// one it is used against.
+type ErrDatastoreMismatch struct {
+	error
+	TokenDatastoreID string
+	DatastoreID      string
+}
+
+// NewDatastoreMismatchErr creates a new error representing that a zedtoken of the datastore with
+// the unique ID tokenDatastoreID was used against the datastore with the unique ID datastoreID.
+func NewDatastoreMismatchErr(tokenDatastoreID, datastoreID string) ErrDatastoreMismatch {
+	return ErrDatastoreMismatch{
+		error:            fmt.Errorf("zedtoken was minted by datastore %s, not by datastore %s", tokenDatastoreID, datastoreID),
+		TokenDatastoreID: tokenDatastoreID,
+		DatastoreID:      datastoreID,
+	}
+}
+
 // NewFromRevision generates an encoded zedtoken from an integral revision.
 func NewFromRevision(revision datastore.Revision) *v1.ZedToken {
+	return NewFromRevisionForDatastore(revision, "")
+}
+
+// NewFromRevisionForDatastore generates an encoded zedtoken from a revision of the datastore with
+// the given unique ID. As revisions are only meaningful to their datastore, the resulting token
+// can be used against any deployment sharing the datastore, and rejected by others. If the ID is
+// empty, the token is the same as the one generated by NewFromRevision.
+func NewFromRevisionForDatastore(revision datastore.Revision, datastoreID string) *v1.ZedToken {
 	toEncode := &zedtoken.DecodedZedToken{
 		VersionOneof: &zedtoken.DecodedZedToken_V1{
 			V1: &zedtoken.DecodedZedToken_V1ZedToken{
-				Revision: revision.String(),
+				Revision:    revision.String(),
+				DatastoreId: datastoreID,
 			},
 		},
 	}
@@ -90,6 +118,47 @@ func DecodeRevision(encoded *v1.ZedToken, ds revisionDecoder) (datastore.Revisio
 	}
 }
 
+// DecodeRevisionForDatastore converts and extracts the revision from a zedtoken or legacy zookie
+// used against the datastore with the given unique ID, returning an ErrDatastoreMismatch if the
+// token records the unique ID of another datastore. Tokens which do not record the unique ID of
+// their datastore are accepted, as are all tokens if the unique ID is empty.
+func DecodeRevisionForDatastore(encoded *v1.ZedToken, ds revisionDecoder, datastoreID string) (datastore.Revision, error) {
+	decoded, err := Decode(encoded)
+	if err != nil {
+		return datastore.NoRevision, err
+	}
+
+	tokenDatastoreID := decoded.GetV1().GetDatastoreId()
+	if datastoreID != "" && tokenDatastoreID != "" && tokenDatastoreID != datastoreID {
+		return datastore.NoRevision, NewDatastoreMismatchErr(tokenDatastoreID, datastoreID)
+	}
+
+	return DecodeRevision(encoded, ds)
+}
+
+// Translate validates a zedtoken against the datastore, and returns it as a token recording the
+// unique ID of the datastore, along with its revision. The token is valid if it does not record
+// the unique ID of another datastore and its revision can still be read from the datastore. This
+// allows tokens minted by any deployment sharing the datastore, including those of versions which
+// do not record the unique ID, to be checked and made portable.
+func Translate(ctx context.Context, encoded *v1.ZedToken, ds datastore.Datastore) (*v1.ZedToken, datastore.Revision, error) {
+	datastoreID, err := ds.UniqueID(ctx)
+	if err != nil {
+		return nil, datastore.NoRevision, fmt.Errorf("unable to read the unique ID of the datastore: %w", err)
+	}
+
+	revision, err := DecodeRevisionForDatastore(encoded, ds, datastoreID)
+	if err != nil {
+		return nil, datastore.NoRevision, err
+	}
+
+	if err := ds.CheckRevision(ctx, revision); err != nil {
+		return nil, datastore.NoRevision, err
+	}
+
+	return NewFromRevisionForDatastore(revision, datastoreID), revision, nil
+}
+
 type revisionDecoder interface {
 	RevisionFromString(string) (datastore.Revision, error)
 }
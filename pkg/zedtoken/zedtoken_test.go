@@ -1,6 +1,7 @@
 package zedtoken
 
 import (
+	"context"
 	"fmt"
 	"testing"
 
@@ -8,6 +9,7 @@ import (
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 
+	"github.com/authzed/spicedb/internal/datastore/memdb"
 	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/datastore/revision"
 )
@@ -135,3 +137,66 @@ func TestDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestZedTokenForDatastore(t *testing.T) {
+	for _, rev := range encodeRevisionTests {
+		t.Run(rev.String(), func(t *testing.T) {
+			require := require.New(t)
+			encoded := NewFromRevisionForDatastore(rev, "somedatastore")
+
+			// Tokens recording their datastore can be decoded by those ignoring it.
+			decodedRev, err := DecodeRevision(encoded, revision.DecimalDecoder{})
+			require.NoError(err)
+			require.True(rev.Equal(decodedRev))
+
+			decoded, err := Decode(encoded)
+			require.NoError(err)
+			require.Equal("somedatastore", decoded.GetV1().GetDatastoreId())
+
+			for _, datastoreID := range []string{"somedatastore", ""} {
+				decodedRev, err = DecodeRevisionForDatastore(encoded, revision.DecimalDecoder{}, datastoreID)
+				require.NoError(err)
+				require.True(rev.Equal(decodedRev))
+			}
+
+			_, err = DecodeRevisionForDatastore(encoded, revision.DecimalDecoder{}, "otherdatastore")
+			require.ErrorAs(err, &ErrDatastoreMismatch{})
+
+			// Tokens not recording their datastore are accepted by any datastore.
+			decodedRev, err = DecodeRevisionForDatastore(NewFromRevision(rev), revision.DecimalDecoder{}, "otherdatastore")
+			require.NoError(err)
+			require.True(rev.Equal(decodedRev))
+			require.Equal(NewFromRevision(rev).Token, NewFromRevisionForDatastore(rev, "").Token)
+		})
+	}
+}
+
+func TestTranslate(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	stats, err := ds.Statistics(ctx)
+	require.NoError(err)
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	translated, translatedRev, err := Translate(ctx, NewFromRevision(head), ds)
+	require.NoError(err)
+	require.True(head.Equal(translatedRev))
+	require.Equal(NewFromRevisionForDatastore(head, stats.UniqueID).Token, translated.Token)
+
+	// Translating a portable token returns the same token.
+	again, _, err := Translate(ctx, translated, ds)
+	require.NoError(err)
+	require.Equal(translated.Token, again.Token)
+
+	_, _, err = Translate(ctx, NewFromRevisionForDatastore(head, "otherdatastore"), ds)
+	require.ErrorAs(err, &ErrDatastoreMismatch{})
+
+	_, _, err = Translate(ctx, &v1.ZedToken{Token: "abc"}, ds)
+	require.Error(err)
+}